@@ -145,6 +145,17 @@ const (
 	CoreFeatureSIMD
 )
 
+// The below are the values of experimental features, e.g.
+// experimental.CoreFeaturesThreads, which are defined here to name them in
+// String.
+const (
+	coreFeatureThreads         = CoreFeatureSIMD << 1
+	coreFeatureCustomPageSizes = CoreFeatureSIMD << 2
+	coreFeatureWideArithmetic  = CoreFeatureSIMD << 3
+	coreFeatureRelaxedSIMD     = CoreFeatureSIMD << 4
+	coreFeatureExtendedConst   = CoreFeatureSIMD << 5
+)
+
 // SetEnabled enables or disables the feature or group of features.
 func (f CoreFeatures) SetEnabled(feature CoreFeatures, val bool) CoreFeatures {
 	if val {
@@ -207,6 +218,21 @@ func featureName(f CoreFeatures) string {
 	case CoreFeatureSIMD:
 		// match https://github.com/WebAssembly/spec/blob/wg-2.0.draft1/proposals/simd/SIMD.md
 		return "simd"
+	case coreFeatureThreads:
+		// match https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+		return "threads"
+	case coreFeatureCustomPageSizes:
+		// match https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md
+		return "custom-page-sizes"
+	case coreFeatureWideArithmetic:
+		// match https://github.com/WebAssembly/wide-arithmetic/blob/main/proposals/wide-arithmetic/Overview.md
+		return "wide-arithmetic"
+	case coreFeatureRelaxedSIMD:
+		// match https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+		return "relaxed-simd"
+	case coreFeatureExtendedConst:
+		// match https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+		return "extended-const"
 	}
 	return ""
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/tetratelabs/wazero/internal/internalapi"
 )
@@ -158,8 +159,15 @@ type Module interface {
 
 	// ExportedFunctionDefinitions returns all the exported function
 	// definitions in this module, keyed on export name.
+	//
+	// Note: Iteration order of the result is random, so range over
+	// ExportedFunctionNames when order matters.
 	ExportedFunctionDefinitions() map[string]FunctionDefinition
 
+	// ExportedFunctionNames returns the export names of all functions in this
+	// module, in the order of its export section.
+	ExportedFunctionNames() []string
+
 	// TODO: Table
 
 	// ExportedMemory returns a memory exported from this module or nil if it wasn't.
@@ -173,10 +181,18 @@ type Module interface {
 	// ExportedMemoryDefinitions returns all the exported memory definitions
 	// in this module, keyed on export name.
 	//
-	// Note: As of WebAssembly Core Specification 2.0, there can be at most one
-	// memory.
+	// # Notes
+	//
+	//   - As of WebAssembly Core Specification 2.0, there can be at most one
+	//     memory, though it can be exported under multiple names.
+	//   - Iteration order of the result is random, so range over
+	//     ExportedMemoryNames when order matters.
 	ExportedMemoryDefinitions() map[string]MemoryDefinition
 
+	// ExportedMemoryNames returns the export names of all memories in this
+	// module, in the order of its export section.
+	ExportedMemoryNames() []string
+
 	// ExportedGlobal a global exported from this module or nil if it wasn't.
 	ExportedGlobal(name string) Global
 
@@ -209,9 +225,55 @@ type Module interface {
 	// the module successfully will not result it one.
 	IsClosed() bool
 
+	// State returns whether this module is safe to reuse after a failed
+	// function call.
+	//
+	// A module starts as ModuleStateHealthy. It becomes ModuleStatePoisoned
+	// when a call to one of its functions ends in a trap, such as
+	// `unreachable`, an out-of-bounds memory or table access, an integer
+	// divide by zero, a call stack overflow, or a panic in a host function.
+	// As a trap can interrupt a function midway, the module's memory and
+	// globals may be inconsistent, so it should not be reused.
+	//
+	// Errors that occur before any code runs, such as passing the wrong
+	// number of parameters, do not change the state. A sys.ExitError does not
+	// poison the module, as it results in ModuleStateClosed instead.
+	//
+	// Note: wazero.ModuleConfig WithCloseOnTrap closes a module as soon as it
+	// would become poisoned.
+	State() ModuleState
+
 	internalapi.WazeroOnly
 }
 
+// ModuleState is the result of Module.State.
+type ModuleState byte
+
+const (
+	// ModuleStateHealthy means no function call of the module trapped, and
+	// it is not closed.
+	ModuleStateHealthy ModuleState = iota
+	// ModuleStatePoisoned means a function call of the module trapped, so
+	// its state may be corrupted. The module should be closed.
+	ModuleStatePoisoned
+	// ModuleStateClosed means the module is closed, as reported by
+	// Module.IsClosed.
+	ModuleStateClosed
+)
+
+// String implements fmt.Stringer
+func (s ModuleState) String() string {
+	switch s {
+	case ModuleStateHealthy:
+		return "healthy"
+	case ModuleStatePoisoned:
+		return "poisoned"
+	case ModuleStateClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("unknown(%d)", s)
+}
+
 // Closer closes a resource.
 //
 // # Notes
@@ -275,13 +337,17 @@ type ExportDefinition interface {
 type MemoryDefinition interface {
 	ExportDefinition
 
-	// Min returns the possibly zero initial count of 64KB pages.
+	// Min returns the possibly zero initial count of pages.
 	Min() uint32
 
-	// Max returns the possibly zero max count of 64KB pages, or false if
+	// Max returns the possibly zero max count of pages, or false if
 	// unbounded.
 	Max() (uint32, bool)
 
+	// PageSize returns the size of a page in bytes, which is 64KB unless
+	// declared otherwise, as defined by experimental.CoreFeaturesCustomPageSizes.
+	PageSize() uint32
+
 	internalapi.WazeroOnly
 }
 
@@ -383,6 +449,11 @@ type Function interface {
 	// the api.Module from which this api.Function is derived will be made closed. See the documentation of
 	// WithCloseOnContextDone on wazero.RuntimeConfig for detail. See examples in context_done_example_test.go for
 	// the end-to-end demonstrations of how these terminations can be performed.
+	//
+	// Call allocates the params and results slices on each invocation. When a
+	// function is called at a high rate, use CallWithStack instead, which
+	// doesn't allocate when the stack slice is reused across calls, or
+	// experimental/typedcall CallContext, which pre-binds such a stack.
 	Call(ctx context.Context, params ...uint64) ([]uint64, error)
 
 	// CallWithStack is an optimized variation of Call that saves memory
@@ -413,9 +484,37 @@ type Function interface {
 	//     whether the callee is a host or wasm defined function.
 	CallWithStack(ctx context.Context, stack []uint64) error
 
+	// CallWithOptions is like Call, except it applies the given CallOptions
+	// to this invocation.
+	//
+	// When CallOptions.Timeout elapses before the function returns, the
+	// exporting Module is closed with sys.ExitCodeDeadlineExceeded, and the
+	// error returned is a sys.ExitError with that code. In other words, a
+	// nil error means the Module is safe to reuse, while a timeout always
+	// leaves it closed.
+	//
+	// Note: Guest code is only interrupted while running when the Runtime
+	// was configured with RuntimeConfig.WithCloseOnContextDone. Otherwise,
+	// the timeout is observed once the function returns.
+	CallWithOptions(ctx context.Context, opts CallOptions, params ...uint64) ([]uint64, error)
+
 	internalapi.WazeroOnly
 }
 
+// CallOptions are per-invocation settings for Function.CallWithOptions.
+type CallOptions struct {
+	// Timeout is the maximum duration of the call. Zero means no timeout,
+	// though the context.Context passed to the call still applies.
+	Timeout time.Duration
+
+	// OnTimeout, if set, is called once after a call was stopped due to
+	// Timeout, and before CallWithOptions returns. This is useful for
+	// releasing resources associated with the now closed Module.
+	//
+	// Note: Do not call functions of the closed Module from this callback.
+	OnTimeout func()
+}
+
 // GoModuleFunction is a Function implemented in Go instead of a wasm binary.
 // The Module parameter is the calling module, used to access memory or
 // exported functions. See GoModuleFunc for an example.
@@ -0,0 +1,224 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wasmDir adds the WASI commands in a directory as workloads, such as
+// Coremark or SQLite's speedtest1, which need a C toolchain to build. The
+// arguments of "name.wasm" are read from "name.args", if present.
+var wasmDir = flag.String("wasmdir", "", "directory of additional WASI commands to benchmark")
+
+// kernels are the workloads compiled from testdata, by directory name.
+var kernels = []string{"fib", "sieve", "matmul", "sha256", "json"}
+
+type workload struct {
+	name string
+	bin  []byte
+	args []string
+}
+
+var workloads []workload
+
+// TestMain compiles the kernels with the runner's Go, as the binaries are
+// too big to check into the source tree.
+func TestMain(m *testing.M) {
+	flag.Parse()
+
+	dir, err := os.MkdirTemp("", "wazero-bench")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = loadWorkloads(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		// Notably our scratch containers don't have go, so don't fail tests.
+		log.Println("Skipping benchmarks due to:", err)
+		os.Exit(0)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func loadWorkloads(dir string) error {
+	for _, k := range kernels {
+		out := filepath.Join(dir, k+".wasm")
+		cmd := exec.Command("go", "build", "-o", out, "./testdata/"+k)
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if o, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("go build %s: %v\n%s", k, err, o)
+		}
+		bin, err := os.ReadFile(out)
+		if err != nil {
+			return err
+		}
+		workloads = append(workloads, workload{name: k, bin: bin})
+	}
+
+	if *wasmDir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(*wasmDir, "*.wasm"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		bin, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		w := workload{name: strings.TrimSuffix(filepath.Base(p), ".wasm"), bin: bin}
+		if args, err := os.ReadFile(strings.TrimSuffix(p, ".wasm") + ".args"); err == nil {
+			w.args = strings.Fields(string(args))
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		workloads = append(workloads, w)
+	}
+	return nil
+}
+
+type engine struct {
+	name   string
+	config func() wazero.RuntimeConfig
+}
+
+// engines are the engines to compare. Only APIs available in older versions
+// are used, so that versions can be compared with benchdiff.
+func engines() []engine {
+	ret := []engine{{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter}}
+	if runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64" {
+		ret = append(ret, engine{name: "compiler", config: wazero.NewRuntimeConfigCompiler})
+	}
+	return ret
+}
+
+// TestWorkloads ensures the workloads run successfully, as benchmarks would
+// otherwise measure failures.
+func TestWorkloads(t *testing.T) {
+	for _, e := range engines() {
+		if testing.Short() && e.name == "interpreter" {
+			continue // interpreting the Go runtime is slow.
+		}
+		e := e
+		t.Run(e.name, func(t *testing.T) {
+			for _, w := range workloads {
+				w := w
+				t.Run(w.name, func(t *testing.T) {
+					ctx := context.Background()
+					r, compiled := compileWorkload(t, e, w)
+					defer r.Close(ctx)
+
+					var stdout bytes.Buffer
+					if err := runWorkload(ctx, r, compiled, w, &stdout); err != nil {
+						t.Fatal(err)
+					}
+					if stdout.Len() == 0 {
+						t.Fatal("expected output")
+					}
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkCompile measures the compilation of each workload.
+func BenchmarkCompile(b *testing.B) {
+	for _, e := range engines() {
+		e := e
+		b.Run(e.name, func(b *testing.B) {
+			for _, w := range workloads {
+				w := w
+				b.Run(w.name, func(b *testing.B) {
+					ctx := context.Background()
+					for i := 0; i < b.N; i++ {
+						r := wazero.NewRuntimeWithConfig(ctx, e.config())
+						if _, err := r.CompileModule(ctx, w.bin); err != nil {
+							b.Fatal(err)
+						}
+						_ = r.Close(ctx)
+					}
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkRun measures running each workload to completion, including
+// instantiation, but not compilation.
+func BenchmarkRun(b *testing.B) {
+	for _, e := range engines() {
+		e := e
+		b.Run(e.name, func(b *testing.B) {
+			for _, w := range workloads {
+				w := w
+				b.Run(w.name, func(b *testing.B) {
+					ctx := context.Background()
+					r, compiled := compileWorkload(b, e, w)
+					defer r.Close(ctx)
+
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if err := runWorkload(ctx, r, compiled, w, nil); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func compileWorkload(tb testing.TB, e engine, w workload) (wazero.Runtime, wazero.CompiledModule) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, e.config())
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	compiled, err := r.CompileModule(ctx, w.bin)
+	if err != nil {
+		_ = r.Close(ctx)
+		tb.Fatal(err)
+	}
+	return r, compiled
+}
+
+// runWorkload runs the workload as a WASI command, returning an error unless
+// it exits successfully.
+func runWorkload(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule, w workload, stdout *bytes.Buffer) error {
+	var stderr bytes.Buffer
+	config := wazero.NewModuleConfig().
+		WithName(""). // anonymous, so that it can be instantiated again.
+		WithArgs(append([]string{w.name}, w.args...)...).
+		WithStderr(&stderr)
+	if stdout != nil {
+		config = config.WithStdout(stdout)
+	}
+	mod, err := r.InstantiateModule(ctx, compiled, config)
+	if mod != nil {
+		_ = mod.Close(ctx)
+	}
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("%v\n%s", err, stderr.Bytes())
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Command benchdiff compares the results of `go test -bench` between two
+// versions of wazero, and fails when any benchmark regressed.
+//
+// For example, to compare the current tree with the "main" branch:
+//
+//	make bench.compare bench_base=main
+//
+// Or, given the output of `go test -bench` of each version:
+//
+//	go run ./cmd/benchdiff -threshold 5 old.txt new.txt
+//
+// Results of each benchmark are summarized by their median, so running
+// benchmarks with -count > 1 reduces noise.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+func main() {
+	os.Exit(doMain(os.Stdout, os.Stderr, os.Args[1:]))
+}
+
+// doMain is separated out for the purpose of unit testing.
+func doMain(stdOut, stdErr io.Writer, args []string) int {
+	flags := flag.NewFlagSet("benchdiff", flag.ContinueOnError)
+	flags.SetOutput(stdErr)
+
+	var threshold float64
+	flags.Float64Var(&threshold, "threshold", 5,
+		"Percentage of ns/op increase above which a benchmark regressed.")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(stdErr, "usage: benchdiff [-threshold percent] old.txt new.txt")
+		return 2
+	}
+
+	old, err := parseFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stdErr, err)
+		return 1
+	}
+	cur, err := parseFile(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(stdErr, err)
+		return 1
+	}
+
+	if regressions := report(stdOut, old, cur, threshold); regressions > 0 {
+		fmt.Fprintf(stdErr, "%d benchmark(s) regressed more than %.1f%%\n", regressions, threshold)
+		return 1
+	}
+	return 0
+}
+
+// results are the ns/op samples of each benchmark, by name.
+type results map[string][]float64
+
+func parseFile(path string) (results, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
+// parse reads the benchmark lines of `go test -bench` output, e.g.
+// "BenchmarkRun/compiler/fib-8   10   102345 ns/op", ignoring others.
+func parse(r io.Reader) (results, error) {
+	ret := results{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		for i := 2; i+1 < len(fields); i += 2 {
+			if fields[i+1] != "ns/op" {
+				continue
+			}
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ns/op in %q: %w", s.Text(), err)
+			}
+			name := trimProcs(fields[0])
+			ret[name] = append(ret[name], v)
+		}
+	}
+	return ret, s.Err()
+}
+
+// trimProcs removes the GOMAXPROCS suffix, e.g. "-8", so that results of
+// hosts with different CPU counts can be compared.
+func trimProcs(name string) string {
+	if i := strings.LastIndexByte(name, '-'); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			return name[:i]
+		}
+	}
+	return name
+}
+
+func median(samples []float64) float64 {
+	s := append([]float64(nil), samples...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// report writes a table comparing the medians of benchmarks in both results,
+// and returns the count which regressed more than `threshold` percent.
+func report(w io.Writer, old, cur results, threshold float64) (regressions int) {
+	names := make([]string, 0, len(cur))
+	for name := range cur {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\told ns/op\tnew ns/op\tdelta\t")
+
+	var logRatios float64
+	var compared int
+	for _, name := range names {
+		n := median(cur[name])
+		samples, ok := old[name]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t-\t%.0f\tnew\t\n", name, n)
+			continue
+		}
+		o := median(samples)
+		delta := (n - o) / o * 100
+		compared++
+		logRatios += math.Log(n / o)
+
+		note := ""
+		if delta > threshold {
+			note = "REGRESSION"
+			regressions++
+		}
+		fmt.Fprintf(tw, "%s\t%.0f\t%.0f\t%+.2f%%\t%s\n", name, o, n, delta, note)
+	}
+	var removed []string
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		fmt.Fprintf(tw, "%s\t%.0f\t-\tremoved\t\n", name, median(old[name]))
+	}
+	if compared > 0 {
+		fmt.Fprintf(tw, "[geomean]\t\t\t%+.2f%%\t\n", (math.Exp(logRatios/float64(compared))-1)*100)
+	}
+	_ = tw.Flush()
+
+	// Trim the padding of empty notes.
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			fmt.Fprintln(w, strings.TrimRight(line, " \n"))
+		}
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const oldOutput = `goos: linux
+goarch: amd64
+pkg: github.com/tetratelabs/wazero/bench
+BenchmarkRun/compiler/fib-8     	      10	    100000 ns/op
+BenchmarkRun/compiler/fib-8     	      10	    120000 ns/op
+BenchmarkRun/compiler/fib-8     	      10	    110000 ns/op
+BenchmarkRun/compiler/json-8    	       5	    200000 ns/op	  1024 B/op	      3 allocs/op
+BenchmarkRun/compiler/sieve-8   	       5	    300000 ns/op
+PASS
+`
+
+func TestDoMain(t *testing.T) {
+	tests := []struct {
+		name           string
+		newOutput      string
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name: "no regression",
+			newOutput: `BenchmarkRun/compiler/fib-16   	      10	    111000 ns/op
+BenchmarkRun/compiler/json-16  	       5	    150000 ns/op	  1024 B/op	      3 allocs/op
+BenchmarkRun/compiler/sieve-16 	       5	    300000 ns/op
+`,
+			expectedStdout: `name                         old ns/op  new ns/op  delta
+BenchmarkRun/compiler/fib    110000     111000     +0.91%
+BenchmarkRun/compiler/json   200000     150000     -25.00%
+BenchmarkRun/compiler/sieve  300000     300000     +0.00%
+[geomean]                                          -8.87%
+`,
+		},
+		{
+			name: "regression",
+			newOutput: `BenchmarkRun/compiler/fib-8     	      10	    132000 ns/op
+BenchmarkRun/compiler/matmul-8  	      10	    50000 ns/op
+`,
+			expectedCode: 1,
+			expectedStdout: `name                          old ns/op  new ns/op  delta
+BenchmarkRun/compiler/fib     110000     132000     +20.00%  REGRESSION
+BenchmarkRun/compiler/matmul  -          50000      new
+BenchmarkRun/compiler/json    200000     -          removed
+BenchmarkRun/compiler/sieve   300000     -          removed
+[geomean]                                           +20.00%
+`,
+			expectedStderr: "1 benchmark(s) regressed more than 5.0%\n",
+		},
+		{
+			name:      "threshold",
+			newOutput: "BenchmarkRun/compiler/fib-8 10 132000 ns/op\n",
+			args:      []string{"-threshold", "25"},
+			expectedStdout: `name                         old ns/op  new ns/op  delta
+BenchmarkRun/compiler/fib    110000     132000     +20.00%
+BenchmarkRun/compiler/json   200000     -          removed
+BenchmarkRun/compiler/sieve  300000     -          removed
+[geomean]                                          +20.00%
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldPath, newPath := filepath.Join(dir, "old.txt"), filepath.Join(dir, "new.txt")
+			if err := os.WriteFile(oldPath, []byte(oldOutput), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newPath, []byte(tc.newOutput), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var stdout, stderr bytes.Buffer
+			code := doMain(&stdout, &stderr, append(tc.args, oldPath, newPath))
+			if code != tc.expectedCode {
+				t.Errorf("expected code %d, but was %d", tc.expectedCode, code)
+			}
+			if stdout.String() != tc.expectedStdout {
+				t.Errorf("expected stdout:\n%s\nbut was:\n%s", tc.expectedStdout, stdout.String())
+			}
+			if stderr.String() != tc.expectedStderr {
+				t.Errorf("expected stderr %q, but was %q", tc.expectedStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestDoMain_usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := doMain(&stdout, &stderr, []string{"old.txt"}); code != 2 {
+		t.Errorf("expected code 2, but was %d", code)
+	}
+	if !strings.Contains(stderr.String(), "usage: benchdiff") {
+		t.Errorf("expected usage, but was %q", stderr.String())
+	}
+}
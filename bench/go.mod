@@ -0,0 +1,7 @@
+module github.com/tetratelabs/wazero/bench
+
+go 1.19
+
+require github.com/tetratelabs/wazero v0.0.0
+
+replace github.com/tetratelabs/wazero => ../
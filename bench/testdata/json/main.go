@@ -0,0 +1,39 @@
+// json measures a typical workload of Go programs, parsing and encoding JSON
+// with reflection.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type item struct {
+	ID    int               `json:"id"`
+	Name  string            `json:"name"`
+	Tags  []string          `json:"tags"`
+	Price float64           `json:"price"`
+	Attrs map[string]string `json:"attrs"`
+}
+
+func main() {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i := 0; i < 2000; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `{"id":%d,"name":"item %d","tags":["a","b","c"],"price":%d.25,"attrs":{"color":"red","size":"%d"}}`, i, i, i, i%10)
+	}
+	sb.WriteByte(']')
+
+	var items []item
+	if err := json.Unmarshal([]byte(sb.String()), &items); err != nil {
+		panic(err)
+	}
+	out, err := json.Marshal(items)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(items), len(out))
+}
@@ -0,0 +1,26 @@
+// matmul measures floating point arithmetic, with a matrix multiplication.
+package main
+
+import "fmt"
+
+func main() {
+	const n = 96
+	a, b, c := make([]float64, n*n), make([]float64, n*n), make([]float64, n*n)
+	for i := range a {
+		a[i] = float64(i%7) * 0.5
+		b[i] = float64(i%11) * 0.25
+	}
+	for i := 0; i < n; i++ {
+		for k := 0; k < n; k++ {
+			aik := a[i*n+k]
+			for j := 0; j < n; j++ {
+				c[i*n+j] += aik * b[k*n+j]
+			}
+		}
+	}
+	var sum float64
+	for _, v := range c {
+		sum += v
+	}
+	fmt.Println(sum)
+}
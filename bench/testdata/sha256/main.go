@@ -0,0 +1,19 @@
+// sha256 measures integer arithmetic, hashing a buffer repeatedly.
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+func main() {
+	buf := make([]byte, 64*1024)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	var sum [sha256.Size]byte
+	for i := 0; i < 32; i++ {
+		sum = sha256.Sum256(append(buf, sum[:]...))
+	}
+	fmt.Printf("%x\n", sum)
+}
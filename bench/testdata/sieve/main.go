@@ -0,0 +1,20 @@
+// sieve measures memory access and branches, with the sieve of Eratosthenes.
+package main
+
+import "fmt"
+
+func main() {
+	const n = 2_000_000
+	composite := make([]bool, n)
+	count := 0
+	for i := 2; i < n; i++ {
+		if composite[i] {
+			continue
+		}
+		count++
+		for j := i * i; j < n; j += i {
+			composite[j] = true
+		}
+	}
+	fmt.Println(count)
+}
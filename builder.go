@@ -2,6 +2,7 @@ package wazero
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -132,6 +133,13 @@ type HostFunctionBuilder interface {
 	// Note: When defined, names must be provided for all results.
 	WithResultNames(names ...string) HostFunctionBuilder
 
+	// RequireCapability only allows calls from modules which were granted the
+	// capability token, e.g. "net.dial", via ModuleConfig.WithCapabilities.
+	// Otherwise, the call fails with an error matching ErrCapabilityDenied.
+	//
+	// Note: Tokens are opaque strings, so their meaning is up to the host.
+	RequireCapability(token string) HostFunctionBuilder
+
 	// Export exports this to the HostModuleBuilder as the given name, e.g.
 	// "random_get"
 	Export(name string) HostModuleBuilder
@@ -185,6 +193,19 @@ type HostModuleBuilder interface {
 	// NewFunctionBuilder begins the definition of a host function.
 	NewFunctionBuilder() HostFunctionBuilder
 
+	// RequireCapability is like HostFunctionBuilder.RequireCapability, except
+	// for a function already exported. This allows requiring capabilities of
+	// functions exported by other packages, such as WASI.
+	//
+	// Here's an example:
+	//
+	//	b := r.NewHostModuleBuilder(wasi_snapshot_preview1.ModuleName)
+	//	wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(b)
+	//	b.RequireCapability("sock_accept", "net.accept")
+	//
+	// Note: Compile fails if no function is exported as `exportName`.
+	RequireCapability(exportName, token string) HostModuleBuilder
+
 	// Compile returns a CompiledModule that can be instantiated by Runtime.
 	Compile(context.Context) (CompiledModule, error)
 
@@ -218,6 +239,9 @@ type hostModuleBuilder struct {
 	moduleName     string
 	exportNames    []string
 	nameToHostFunc map[string]*wasm.HostFunc
+	// capabilities are the tokens required by RequireCapability, by export
+	// name.
+	capabilities map[string]string
 }
 
 // NewHostModuleBuilder implements Runtime.NewHostModuleBuilder
@@ -236,6 +260,7 @@ type hostFunctionBuilder struct {
 	name        string
 	paramNames  []string
 	resultNames []string
+	capability  string
 }
 
 // WithGoFunction implements HostFunctionBuilder.WithGoFunction
@@ -274,6 +299,12 @@ func (h *hostFunctionBuilder) WithResultNames(names ...string) HostFunctionBuild
 	return h
 }
 
+// RequireCapability implements HostFunctionBuilder.RequireCapability
+func (h *hostFunctionBuilder) RequireCapability(token string) HostFunctionBuilder {
+	h.capability = token
+	return h
+}
+
 // Export implements HostFunctionBuilder.Export
 func (h *hostFunctionBuilder) Export(exportName string) HostModuleBuilder {
 	var hostFn *wasm.HostFunc
@@ -294,6 +325,7 @@ func (h *hostFunctionBuilder) Export(exportName string) HostModuleBuilder {
 	if len(h.resultNames) != 0 {
 		hostFn.ResultNames = h.resultNames
 	}
+	hostFn.Capability = h.capability
 
 	h.b.ExportHostFunc(hostFn)
 	return h.b
@@ -312,9 +344,36 @@ func (b *hostModuleBuilder) NewFunctionBuilder() HostFunctionBuilder {
 	return &hostFunctionBuilder{b: b}
 }
 
+// RequireCapability implements HostModuleBuilder.RequireCapability
+func (b *hostModuleBuilder) RequireCapability(exportName, token string) HostModuleBuilder {
+	if b.capabilities == nil {
+		b.capabilities = map[string]string{}
+	}
+	b.capabilities[exportName] = token
+	return b
+}
+
 // Compile implements HostModuleBuilder.Compile
 func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error) {
-	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, b.nameToHostFunc, b.r.enabledFeatures)
+	nameToHostFunc := b.nameToHostFunc
+	if len(b.capabilities) > 0 {
+		// Copy as functions may be shared, e.g. by WASI.
+		nameToHostFunc = make(map[string]*wasm.HostFunc, len(b.nameToHostFunc))
+		for name, fn := range b.nameToHostFunc {
+			nameToHostFunc[name] = fn
+		}
+		for name, token := range b.capabilities {
+			fn, ok := nameToHostFunc[name]
+			if !ok {
+				return nil, fmt.Errorf("func[%s.%s] requires capability %q, but is not exported", b.moduleName, name, token)
+			}
+			withCapability := *fn
+			withCapability.Capability = token
+			nameToHostFunc[name] = &withCapability
+		}
+	}
+
+	module, err := wasm.NewHostModule(b.moduleName, b.exportNames, nameToHostFunc, b.r.enabledFeatures)
 	if err != nil {
 		return nil, err
 	} else if err = module.Validate(b.r.enabledFeatures); err != nil {
@@ -338,6 +397,9 @@ func (b *hostModuleBuilder) Compile(ctx context.Context) (CompiledModule, error)
 	}
 	c.typeIDs = typeIDs
 
+	if b.r.leaks != nil {
+		b.r.leaks.trackCompiled(c)
+	}
 	return c, nil
 }
 
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -420,3 +421,94 @@ func requireHostModuleEquals(t *testing.T, expected, actual *wasm.Module) {
 		require.Nil(t, actualCode.LocalTypes)
 	}
 }
+
+func TestHostFunctionBuilder_RequireCapability(t *testing.T) {
+	// guest calls the imported function "host.dial" from its export "call".
+	guest := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "host", Name: "dial", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "call", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	tests := []struct {
+		name        string
+		build       func(HostModuleBuilder, func()) HostModuleBuilder
+		config      ModuleConfig
+		expectedErr bool
+	}{
+		{
+			name: "function granted",
+			build: func(b HostModuleBuilder, fn func()) HostModuleBuilder {
+				return b.NewFunctionBuilder().WithFunc(fn).RequireCapability("net.dial").Export("dial")
+			},
+			config: NewModuleConfig().WithCapabilities("net.listen").WithCapabilities("net.dial"),
+		},
+		{
+			name: "function denied",
+			build: func(b HostModuleBuilder, fn func()) HostModuleBuilder {
+				return b.NewFunctionBuilder().WithFunc(fn).RequireCapability("net.dial").Export("dial")
+			},
+			config:      NewModuleConfig().WithCapabilities("net.listen"),
+			expectedErr: true,
+		},
+		{
+			name: "module granted",
+			build: func(b HostModuleBuilder, fn func()) HostModuleBuilder {
+				return b.NewFunctionBuilder().WithFunc(fn).Export("dial").RequireCapability("dial", "net.dial")
+			},
+			config: NewModuleConfig().WithCapabilities("net.dial"),
+		},
+		{
+			name: "module denied",
+			build: func(b HostModuleBuilder, fn func()) HostModuleBuilder {
+				return b.NewFunctionBuilder().WithGoModuleFunction(api.GoModuleFunc(func(context.Context, api.Module, []uint64) {
+					fn()
+				}), nil, nil).Export("dial").RequireCapability("dial", "net.dial")
+			},
+			config:      NewModuleConfig(),
+			expectedErr: true,
+		},
+		{
+			name: "not required",
+			build: func(b HostModuleBuilder, fn func()) HostModuleBuilder {
+				return b.NewFunctionBuilder().WithFunc(fn).Export("dial")
+			},
+			config: NewModuleConfig(),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			var called bool
+			_, err := tc.build(r.NewHostModuleBuilder("host"), func() { called = true }).Instantiate(testCtx)
+			require.NoError(t, err)
+
+			mod, err := r.InstantiateWithConfig(testCtx, guest, tc.config)
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("call").Call(testCtx)
+			if tc.expectedErr {
+				require.ErrorIs(t, err, ErrCapabilityDenied)
+				require.Contains(t, err.Error(), `capability denied: host.dial requires "net.dial"`)
+				require.False(t, called)
+			} else {
+				require.NoError(t, err)
+				require.True(t, called)
+			}
+		})
+	}
+}
+
+func TestHostModuleBuilder_RequireCapability_notExported(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("host").RequireCapability("dial", "net.dial").Compile(testCtx)
+	require.EqualError(t, err, `func[host.dial] requires capability "net.dial", but is not exported`)
+}
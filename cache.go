@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	goruntime "runtime"
 	"sync"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/filecache"
@@ -52,6 +53,71 @@ func NewCompilationCacheWithDir(dirname string) (CompilationCache, error) {
 	return c, err
 }
 
+// NewCompilationCacheWithDirLimits is like NewCompilationCacheWithDir, except
+// the directory is bounded to `maxBytes` and `maxEntries`. When adding an
+// entry exceeds either limit, the least recently used entries are deleted. A
+// limit of zero is not enforced.
+//
+// The time an entry was last used is its modification time, so it is shared
+// by processes using the same directory.
+func NewCompilationCacheWithDirLimits(dirname string, maxBytes int64, maxEntries int) (CompilationCache, error) {
+	if maxBytes < 0 || maxEntries < 0 {
+		return nil, fmt.Errorf("invalid limits: maxBytes=%d maxEntries=%d", maxBytes, maxEntries)
+	}
+	c := &cache{maxBytes: maxBytes, maxEntries: maxEntries}
+	err := c.ensuresFileCache(dirname, version.GetWazeroVersion())
+	return c, err
+}
+
+// PurgeCompilationCache deletes the entries of the directory or memory limit
+// of the given CompilationCache for which `keep` returns false. For example, this
+// can delete entries for modules which are no longer deployed.
+//
+// `keep` is called with the hex encoded key of each entry, which is the same
+// as experimental.CompilationCacheKey String, and the time it was last used.
+//
+// Here's an example that deletes entries unused for a week:
+//
+//	weekAgo := time.Now().Add(-7 * 24 * time.Hour)
+//	err := wazero.PurgeCompilationCache(c, func(_ string, lastUsed time.Time) bool {
+//		return lastUsed.After(weekAgo)
+//	})
+//
+// # Notes
+//
+//   - This does nothing when the CompilationCache has no directory or memory
+//     limit, i.e. it was created with NewCompilationCache.
+//   - Modules already compiled by a Runtime remain in memory.
+//   - This returns an error if the CompilationCache wasn't created by this
+//     package.
+func PurgeCompilationCache(c CompilationCache, keep func(key string, lastUsed time.Time) bool) error {
+	cc, ok := c.(*cache)
+	if !ok || cc == nil {
+		return fmt.Errorf("unsupported CompilationCache: %T", c)
+	}
+	if p, ok := cc.fileCache.(interface {
+		Purge(func(string, time.Time) bool) error
+	}); ok {
+		return p.Purge(keep)
+	}
+	return nil
+}
+
+// NewCompilationCacheWithMemoryLimit is like NewCompilationCacheWithDir,
+// except the compiled code is held in memory, up to a total of `bytes`. When
+// adding a module exceeds the limit, the least recently used modules are
+// deleted.
+//
+// This is useful when there is no writable directory, for example in a
+// read-only container, but the same wasm is compiled repeatedly, e.g. by
+// Runtime instances which are closed and recreated.
+//
+// Note: Like NewCompilationCacheWithDir, this only applies to modules
+// compiled by the compiler, e.g. configured with NewRuntimeConfigCompiler.
+func NewCompilationCacheWithMemoryLimit(bytes int64) CompilationCache {
+	return &cache{fileCache: filecache.NewMemory(bytes)}
+}
+
 // cache implements Cache interface.
 type cache struct {
 	// eng is the engine for this cache. If the cache is configured, the engine is shared across multiple instances of
@@ -59,6 +125,10 @@ type cache struct {
 	engs      [engineKindCount]wasm.Engine
 	fileCache filecache.Cache
 	initOnces [engineKindCount]sync.Once
+
+	// maxBytes and maxEntries bound fileCache when positive.
+	maxBytes   int64
+	maxEntries int
 }
 
 func (c *cache) initEngine(ek engineKind, ne newEngine, ctx context.Context, features api.CoreFeatures) wasm.Engine {
@@ -97,7 +167,7 @@ func (c *cache) ensuresFileCache(dir string, wazeroVersion string) error {
 		return err
 	}
 
-	c.fileCache = filecache.New(dirname)
+	c.fileCache = filecache.NewWithLimits(dirname, c.maxBytes, c.maxEntries)
 	return nil
 }
 
@@ -8,9 +8,12 @@ import (
 	"path"
 	goruntime "runtime"
 	"testing"
+	"time"
 
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/version"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -79,7 +82,9 @@ func TestCompilationCache(t *testing.T) {
 		// Close the cache, and ensure the engine is closed.
 		err = cacheInst.Close(ctx)
 		require.NoError(t, err)
-		require.Equal(t, uint32(0), eng.CompiledModuleCount())
+		if platform.CompilerSupported() { // the interpreter doesn't release modules on close.
+			require.Equal(t, uint32(0), eng.CompiledModuleCount())
+		}
 	})
 
 	// Even when cache is configured, compiled host modules must be different as that's the way
@@ -152,6 +157,63 @@ func getCacheSharedRuntimes(ctx context.Context, t *testing.T) (foo, bar *runtim
 	return
 }
 
+func TestCompilationCache_listener(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	type event struct {
+		typ      experimental.CompilationCacheEventType
+		inMemory bool
+	}
+	var events []event
+	var keys []experimental.CompilationCacheKey
+	ctx := experimental.WithCompilationCacheListener(context.Background(),
+		experimental.CompilationCacheListenerFunc(func(_ context.Context, e experimental.CompilationCacheEvent) {
+			require.NoError(t, e.Err)
+			events = append(events, event{e.Type, e.InMemory})
+			keys = append(keys, e.Key)
+		}))
+
+	dir := t.TempDir()
+	compile := func(r Runtime) []event {
+		events = nil
+		_, err := r.CompileModule(ctx, facWasm)
+		require.NoError(t, err)
+		return events
+	}
+
+	c, err := NewCompilationCacheWithDir(dir)
+	require.NoError(t, err)
+	r := NewRuntimeWithConfig(ctx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+	require.Equal(t, []event{
+		{experimental.CompilationCacheEventMiss, true},
+		{experimental.CompilationCacheEventMiss, false},
+		{experimental.CompilationCacheEventStore, true},
+		{experimental.CompilationCacheEventStore, false},
+	}, compile(r))
+	require.NoError(t, r.Close(ctx))
+	require.NoError(t, c.Close(ctx))
+
+	// A new cache in the same directory loads the module from the file.
+	c, err = NewCompilationCacheWithDir(dir)
+	require.NoError(t, err)
+	defer c.Close(ctx)
+	r = NewRuntimeWithConfig(ctx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+	defer r.Close(ctx)
+	require.Equal(t, []event{
+		{experimental.CompilationCacheEventMiss, true},
+		{experimental.CompilationCacheEventHit, false},
+	}, compile(r))
+	require.Equal(t, []event{
+		{experimental.CompilationCacheEventHit, true},
+	}, compile(r))
+
+	// The key is the file name of the entry.
+	_, err = os.Stat(path.Join(dir, "wazero-"+version.GetWazeroVersion()+"-"+goruntime.GOARCH+"-"+goruntime.GOOS, keys[0].String()))
+	require.NoError(t, err)
+}
+
 func TestCache_ensuresFileCache(t *testing.T) {
 	const version = "dev"
 	// We expect to create a version-specific subdirectory.
@@ -202,6 +264,101 @@ func TestCache_ensuresFileCache(t *testing.T) {
 	})
 }
 
+func TestNewCompilationCacheWithDirLimits(t *testing.T) {
+	_, err := NewCompilationCacheWithDirLimits(t.TempDir(), -1, 0)
+	require.EqualError(t, err, "invalid limits: maxBytes=-1 maxEntries=0")
+
+	c, err := NewCompilationCacheWithDirLimits(t.TempDir(), 0, 1)
+	require.NoError(t, err)
+	defer c.Close(testCtx)
+
+	if !platform.CompilerSupported() {
+		return
+	}
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+	defer r.Close(testCtx)
+	_, err = r.CompileModule(testCtx, facWasm)
+	require.NoError(t, err)
+	_, err = r.CompileModule(testCtx, memGrowWasm)
+	require.NoError(t, err)
+
+	// Only the most recently compiled module remains in the directory.
+	var keys []string
+	require.NoError(t, PurgeCompilationCache(c, func(key string, _ time.Time) bool {
+		keys = append(keys, key)
+		return false
+	}))
+	require.Equal(t, 1, len(keys))
+
+	// Purging deleted the entry.
+	keys = nil
+	require.NoError(t, PurgeCompilationCache(c, func(key string, _ time.Time) bool {
+		keys = append(keys, key)
+		return true
+	}))
+	require.Equal(t, 0, len(keys))
+}
+
+func TestNewCompilationCacheWithMemoryLimit(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	var hits int
+	ctx := experimental.WithCompilationCacheListener(testCtx,
+		experimental.CompilationCacheListenerFunc(func(_ context.Context, e experimental.CompilationCacheEvent) {
+			if e.Type == experimental.CompilationCacheEventHit && !e.InMemory {
+				hits++
+			}
+		}))
+
+	c := NewCompilationCacheWithMemoryLimit(1 << 20)
+	defer c.Close(ctx)
+
+	// Closing the compiled module deletes it from the engine, but not from
+	// the cache.
+	r := NewRuntimeWithConfig(ctx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+	compiled, err := r.CompileModule(ctx, facWasm)
+	require.NoError(t, err)
+	require.NoError(t, compiled.Close(ctx))
+	require.NoError(t, r.Close(ctx))
+	require.Zero(t, hits)
+
+	r = NewRuntimeWithConfig(ctx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+	defer r.Close(ctx)
+	_, err = r.CompileModule(ctx, facWasm)
+	require.NoError(t, err)
+	require.Equal(t, 1, hits)
+
+	var keys []string
+	require.NoError(t, PurgeCompilationCache(c, func(key string, _ time.Time) bool {
+		keys = append(keys, key)
+		return true
+	}))
+	require.Equal(t, 1, len(keys))
+}
+
+func TestPurgeCompilationCache_noDir(t *testing.T) {
+	require.NoError(t, PurgeCompilationCache(NewCompilationCache(), func(string, time.Time) bool {
+		t.Fatal("unexpected call")
+		return true
+	}))
+}
+
+type unsupportedCache struct{}
+
+// Close implements the same method as documented on api.Closer.
+func (unsupportedCache) Close(context.Context) error { return nil }
+
+func TestPurgeCompilationCache_unsupported(t *testing.T) {
+	keep := func(string, time.Time) bool {
+		t.Fatal("unexpected call")
+		return true
+	}
+	require.EqualError(t, PurgeCompilationCache(unsupportedCache{}, keep), "unsupported CompilationCache: wazero.unsupportedCache")
+	require.EqualError(t, PurgeCompilationCache(nil, keep), "unsupported CompilationCache: <nil>")
+}
+
 // requireContainsDir ensures the directory was created in the correct path,
 // as file.Abs can return slightly different answers for a temp directory. For
 // example, /var/folders/... vs /private/var/folders/...
@@ -223,7 +380,7 @@ func requireChdirToTemp(t *testing.T) (string, string) {
 
 func TestCache_Close(t *testing.T) {
 	t.Run("all engines", func(t *testing.T) {
-		c := &cache{engs: [engineKindCount]wasm.Engine{&mockEngine{}, &mockEngine{}}}
+		c := &cache{engs: [engineKindCount]wasm.Engine{&mockEngine{}, &mockEngine{}, &mockEngine{}}}
 		err := c.Close(testCtx)
 		require.NoError(t, err)
 		for i := engineKind(0); i < engineKindCount; i++ {
@@ -0,0 +1,456 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+// minimizeCheckEnv is set when the CLI runs itself to check a candidate of
+// minimization. Checking in another process means a crash of the engine is
+// an outcome to preserve, not the end of minimization.
+const minimizeCheckEnv = "WAZERO_MINIMIZE_CHECK"
+
+// minimizeOptions are the options of "minimize" which are passed to checks.
+type minimizeOptions struct {
+	invoke         string
+	params         string
+	diff           bool
+	useInterpreter bool
+	timeout        time.Duration
+}
+
+func (o *minimizeOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.invoke, "invoke", "",
+		"Name of the exported function whose invocation fails. Required.")
+	flags.StringVar(&o.params, "params", "",
+		"Comma-separated parameters of the invocation, e.g. \"1,2\". Missing ones are zero.")
+	flags.BoolVar(&o.diff, "diff", false,
+		"Preserves a different outcome between the interpreter and the compiler, instead of a trap.")
+	flags.BoolVar(&o.useInterpreter, "interpreter", false,
+		"Interprets WebAssembly modules instead of compiling them into native code. Ignored with -diff.")
+	flags.DurationVar(&o.timeout, "timeout", 10*time.Second,
+		"Duration after which the invocation of a candidate is canceled.")
+}
+
+func (o *minimizeOptions) args() []string {
+	return []string{
+		"-invoke", o.invoke,
+		"-params", o.params,
+		"-diff=" + strconv.FormatBool(o.diff),
+		"-interpreter=" + strconv.FormatBool(o.useInterpreter),
+		"-timeout", o.timeout.String(),
+	}
+}
+
+func doMinimize(args []string, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("minimize", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var opts minimizeOptions
+	opts.register(flags)
+
+	var outPath string
+	flags.StringVar(&outPath, "o", "", "Path to write the minimized wasm file.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printMinimizeUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printMinimizeUsage(stdErr, flags)
+		return 1
+	}
+
+	// Allow options after the path, e.g. "module.wasm -o min.wasm".
+	wasmPath := flags.Arg(0)
+	_ = flags.Parse(flags.Args()[1:])
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stdErr, "unexpected arguments: %v\n", flags.Args())
+		printMinimizeUsage(stdErr, flags)
+		return 1
+	}
+
+	if outPath == "" {
+		fmt.Fprintln(stdErr, "missing path to output wasm file")
+		printMinimizeUsage(stdErr, flags)
+		return 1
+	}
+
+	if opts.invoke == "" {
+		fmt.Fprintln(stdErr, "missing function to invoke")
+		printMinimizeUsage(stdErr, flags)
+		return 1
+	}
+
+	if _, err := parseParams(opts.params); err != nil {
+		fmt.Fprintf(stdErr, "invalid params: %v\n", err)
+		return 1
+	}
+
+	if opts.diff && !platform.CompilerSupported() {
+		fmt.Fprintln(stdErr, "-diff requires the compiler, which isn't supported on this platform")
+		return 1
+	}
+
+	bin, err := os.ReadFile(wasmPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	m, err := wasmbin.Decode(bin)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error decoding wasm binary: %v\n", err)
+		return 1
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(stdErr, "error finding the executable to check candidates: %v\n", err)
+		return 1
+	}
+	check := func(bin []byte) string {
+		return checkCandidate(exe, &opts, bin)
+	}
+
+	expected := check(wasmbin.Encode(m))
+	if !opts.failed(expected) {
+		if o := check(bin); opts.failed(o) {
+			fmt.Fprintf(stdErr, "error minimizing: the failure isn't preserved by encoding the module again: %s\n", o)
+		} else {
+			fmt.Fprintf(stdErr, "error minimizing: the invocation doesn't fail: %s\n", o)
+		}
+		return 1
+	}
+
+	z := &minimizer{m: m, invoke: opts.invoke, preserves: func(m *wasmbin.Module) bool {
+		o := check(wasmbin.Encode(m))
+		if opts.diff {
+			return opts.failed(o)
+		}
+		return o == expected
+	}}
+	z.run()
+
+	minimized := wasmbin.Encode(z.m)
+	if err = os.WriteFile(outPath, minimized, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdErr, "minimized from %d to %d bytes, after checking %d candidates, preserving:\n%s\n",
+		len(bin), len(minimized), z.candidates, expected)
+	return 0
+}
+
+// failed returns true if the outcome of a check is a failure to preserve.
+func (o *minimizeOptions) failed(outcome string) bool {
+	if outcome == "" { // invalid
+		return false
+	}
+	if o.diff {
+		lines := strings.SplitN(outcome, "\n", 2)
+		return len(lines) == 1 || lines[0] != lines[1]
+	}
+	return !strings.HasPrefix(outcome, "return:")
+}
+
+// checkCandidate returns the outcome of the candidate, checked by running the
+// executable, so that the engine crashing is also an outcome.
+func checkCandidate(exe string, opts *minimizeOptions, bin []byte) string {
+	// Give the check time to cancel the invocation itself, before killing it.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*opts.timeout+5*time.Second)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, exe, opts.args()...)
+	cmd.Env = append(os.Environ(), minimizeCheckEnv+"=1")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(bin), &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "crash: timeout"
+		}
+		// The first line of a crash is the reason, e.g. "fatal error: ..."
+		reason, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		if reason == "" {
+			reason = err.Error()
+		}
+		return "crash: " + reason
+	}
+	return strings.TrimSuffix(stdout.String(), "\n")
+}
+
+// doMinimizeCheck is run in another process, by checkCandidate. It writes the
+// outcome of the module read from stdin to stdout.
+func doMinimizeCheck(args []string, stdIn io.Reader, stdOut, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("minimize", flag.ContinueOnError)
+	flags.SetOutput(stdErr)
+
+	var opts minimizeOptions
+	opts.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	bin, err := io.ReadAll(stdIn)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	if opts.diff {
+		interpreter := minimizeOutcome(wazero.NewRuntimeConfigInterpreter(), &opts, bin)
+		if interpreter == "" {
+			return 0 // invalid
+		}
+		compiler := minimizeOutcome(wazero.NewRuntimeConfigCompiler(), &opts, bin)
+		fmt.Fprintf(stdOut, "%s\n%s\n", interpreter, compiler)
+		return 0
+	}
+
+	config := wazero.NewRuntimeConfig()
+	if opts.useInterpreter {
+		config = wazero.NewRuntimeConfigInterpreter()
+	}
+	if outcome := minimizeOutcome(config, &opts, bin); outcome != "" {
+		fmt.Fprintln(stdOut, outcome)
+	}
+	return 0
+}
+
+// minimizeOutcome returns the outcome of invoking the function, or empty if
+// the module is invalid or doesn't export the function.
+func minimizeOutcome(config wazero.RuntimeConfig, opts *minimizeOptions, bin []byte) string {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	r := wazero.NewRuntimeWithConfig(ctx, config.WithCloseOnContextDone(true))
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, bin)
+	if err != nil {
+		return ""
+	}
+	fnDef, ok := compiled.ExportedFunctions()[opts.invoke]
+	if !ok {
+		return ""
+	}
+
+	if detectImports(compiled.ImportedFunctions()) == modeWasi {
+		wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	}
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return "instantiate: " + firstLine(err)
+	}
+
+	params, _ := parseParams(opts.params)
+	for len(params) < len(fnDef.ParamTypes()) {
+		params = append(params, 0)
+	}
+	results, err := mod.ExportedFunction(opts.invoke).Call(ctx, params...)
+	if err != nil {
+		return "trap: " + firstLine(err)
+	}
+	return fmt.Sprintf("return: %v", results)
+}
+
+// firstLine returns the first line of the error, excluding the stack trace.
+func firstLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
+
+func parseParams(s string) (ret []uint64, err error) {
+	if s == "" {
+		return
+	}
+	for _, p := range strings.Split(s, ",") {
+		v, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return
+}
+
+// minimizer shrinks a module, while the failure is preserved.
+//
+// Note: Only the module name is kept from the "name" section, as
+// wasmbin.Module doesn't have the names of functions.
+type minimizer struct {
+	// m is the smallest module which preserves the failure so far.
+	m      *wasmbin.Module
+	invoke string
+	// preserves returns true if the candidate still fails the same way.
+	preserves  func(candidate *wasmbin.Module) bool
+	candidates int
+}
+
+// try replaces m with the candidate, if it preserves the failure.
+func (z *minimizer) try(candidate *wasmbin.Module) bool {
+	z.candidates++
+	if z.preserves(candidate) {
+		z.m = candidate
+		return true
+	}
+	return false
+}
+
+// run applies each reduction until none makes progress.
+func (z *minimizer) run() {
+	for progress := true; progress; {
+		size := len(wasmbin.Encode(z.m))
+		z.dropExports()
+		z.dropStart()
+		for i := range z.m.Functions {
+			z.reduceFunction(i)
+		}
+		z.dropTrailingFunctions()
+		z.dropSegments()
+		z.dropTrailingGlobals()
+		progress = len(wasmbin.Encode(z.m)) < size
+	}
+}
+
+func (z *minimizer) dropExports() {
+	for i := len(z.m.Exports) - 1; i >= 0; i-- {
+		if e := z.m.Exports[i]; e.Type == api.ExternTypeFunc && e.Name == z.invoke {
+			continue
+		}
+		c := cloneModule(z.m)
+		c.Exports = append(c.Exports[:i:i], c.Exports[i+1:]...)
+		z.try(c)
+	}
+}
+
+func (z *minimizer) dropStart() {
+	if z.m.Start != nil {
+		c := cloneModule(z.m)
+		c.Start = nil
+		z.try(c)
+	}
+}
+
+// stubBody is the smallest valid body of any function.
+var stubBody = []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}
+
+// reduceFunction stubs the function, or removes as many of its instructions
+// and locals as possible.
+func (z *minimizer) reduceFunction(i int) {
+	if code := &z.m.Functions[i]; bytes.Equal(code.Body, stubBody) && len(code.Locals) == 0 {
+		return
+	}
+	if z.try(withCode(z.m, i, nil, stubBody)) {
+		return
+	}
+
+	// Remove chunks of instructions, halving their size, like delta debugging.
+	// This keeps the last "end", and candidates which split a block are
+	// invalid, so are rejected.
+	if insts, err := wasmbin.DecodeInstructions(z.m.Functions[i].Body); err == nil {
+		for n := len(insts) - 1; n > 0; n /= 2 {
+			for j := 0; j+n < len(insts); {
+				candidate := append(append([]wasmbin.Instruction{}, insts[:j]...), insts[j+n:]...)
+				if z.try(withCode(z.m, i, z.m.Functions[i].Locals, wasmbin.EncodeInstructions(candidate))) {
+					insts = candidate
+				} else {
+					j += n
+				}
+			}
+		}
+	}
+
+	// Removing the last local doesn't change the index of the others.
+	for code := &z.m.Functions[i]; len(code.Locals) > 0; code = &z.m.Functions[i] {
+		if !z.try(withCode(z.m, i, code.Locals[:len(code.Locals)-1], code.Body)) {
+			break
+		}
+	}
+}
+
+// dropTrailingFunctions removes the last function, which doesn't change the
+// index of the others, until it is referenced.
+func (z *minimizer) dropTrailingFunctions() {
+	for n := len(z.m.Functions); n > 0; n = len(z.m.Functions) {
+		c := cloneModule(z.m)
+		c.Functions = c.Functions[:n-1]
+		if !z.try(c) {
+			return
+		}
+	}
+}
+
+func (z *minimizer) dropSegments() {
+	// wasmbin.Encode counts data segments, if the data count section is
+	// needed.
+	for i := len(z.m.Data) - 1; i >= 0; i-- {
+		c := cloneModule(z.m)
+		c.Data = append(c.Data[:i:i], c.Data[i+1:]...)
+		z.try(c)
+	}
+	for i := len(z.m.Elements) - 1; i >= 0; i-- {
+		c := cloneModule(z.m)
+		c.Elements = append(c.Elements[:i:i], c.Elements[i+1:]...)
+		z.try(c)
+	}
+}
+
+func (z *minimizer) dropTrailingGlobals() {
+	for n := len(z.m.Globals); n > 0; n = len(z.m.Globals) {
+		c := cloneModule(z.m)
+		c.Globals = c.Globals[:n-1]
+		if !z.try(c) {
+			return
+		}
+	}
+}
+
+// cloneModule copies the parts of the module, which the minimizer changes.
+func cloneModule(m *wasmbin.Module) *wasmbin.Module {
+	ret := *m
+	ret.Globals = append([]wasmbin.Global(nil), m.Globals...)
+	ret.Exports = append([]wasmbin.Export(nil), m.Exports...)
+	ret.Elements = append([]wasmbin.ElementSegment(nil), m.Elements...)
+	ret.Functions = append([]wasmbin.Function(nil), m.Functions...)
+	ret.Data = append([]wasmbin.DataSegment(nil), m.Data...)
+	return &ret
+}
+
+func withCode(m *wasmbin.Module, i int, locals []api.ValueType, body []byte) *wasmbin.Module {
+	ret := cloneModule(m)
+	ret.Functions[i] = wasmbin.Function{TypeIndex: m.Functions[i].TypeIndex, Locals: locals, Body: body}
+	return ret
+}
+
+func printMinimizeUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero minimize <options> <path to wasm file> -o <path to output wasm file>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
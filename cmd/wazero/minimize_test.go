@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+// minimizeTestModule exports "fail", which divides the result of a call by
+// zero, and "other", which returns 42. The start function sets a global, and
+// a data segment initializes the memory.
+var minimizeTestModule = &wasmbin.Module{
+	Name: "test",
+	Types: []wasmbin.FunctionType{
+		{},
+		{Results: []api.ValueType{api.ValueTypeI32}},
+	},
+	Functions: []wasmbin.Function{
+		{TypeIndex: 0, Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeGlobalSet, 0, wasm.OpcodeEnd}},
+		{
+			TypeIndex: 1,
+			Locals:    []api.ValueType{api.ValueTypeI64},
+			Body: []byte{
+				wasm.OpcodeI32Const, 1, wasm.OpcodeDrop,
+				wasm.OpcodeCall, 2, wasm.OpcodeI32Const, 0, wasm.OpcodeI32DivS,
+				wasm.OpcodeEnd,
+			},
+		},
+		{TypeIndex: 1, Body: []byte{wasm.OpcodeI32Const, 5, wasm.OpcodeEnd}},
+		{TypeIndex: 1, Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+	},
+	Memory: &wasmbin.Memory{Min: 1, Max: 1, HasMax: true},
+	Globals: []wasmbin.Global{{
+		GlobalType: wasmbin.GlobalType{Type: api.ValueTypeI32, Mutable: true},
+		Init:       wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: []byte{0}},
+	}},
+	Exports: []wasmbin.Export{
+		{Name: "fail", Type: api.ExternTypeFunc, Index: 1},
+		{Name: "other", Type: api.ExternTypeFunc, Index: 3},
+	},
+	Start: func() *uint32 { i := uint32(0); return &i }(),
+	Data: []wasmbin.DataSegment{{
+		Offset: wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: []byte{0}},
+		Init:   []byte("hello"),
+	}},
+}
+
+func TestMinimizer(t *testing.T) {
+	opts := &minimizeOptions{invoke: "fail", timeout: time.Minute}
+	outcome := func(m *wasmbin.Module) string {
+		return minimizeOutcome(wazero.NewRuntimeConfigInterpreter(), opts, wasmbin.Encode(m))
+	}
+	expected := outcome(minimizeTestModule)
+	require.Equal(t, "trap: wasm error: integer divide by zero", expected)
+
+	z := &minimizer{m: minimizeTestModule, invoke: "fail", preserves: func(m *wasmbin.Module) bool {
+		return outcome(m) == expected
+	}}
+	z.run()
+
+	require.Equal(t, []wasmbin.Export{{Name: "fail", Type: api.ExternTypeFunc, Index: 1}}, z.m.Exports)
+	require.Nil(t, z.m.Start)
+	require.Equal(t, 0, len(z.m.Data))
+	require.Equal(t, 0, len(z.m.Globals))
+	require.Equal(t, []wasmbin.Function{
+		{TypeIndex: 0, Body: stubBody},
+		{TypeIndex: 1, Locals: []api.ValueType{}, Body: []byte{wasm.OpcodeCall, 2, wasm.OpcodeI32Const, 0, wasm.OpcodeI32DivS, wasm.OpcodeEnd}},
+		{TypeIndex: 1, Body: []byte{wasm.OpcodeI32Const, 5, wasm.OpcodeEnd}},
+	}, z.m.Functions)
+	require.Equal(t, "test", z.m.Name)
+
+	// The original isn't changed.
+	require.Equal(t, 4, len(minimizeTestModule.Functions))
+}
+
+func TestParseParams(t *testing.T) {
+	params, err := parseParams("")
+	require.NoError(t, err)
+	require.Nil(t, params)
+
+	params, err = parseParams("1, 2,18446744073709551615")
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2, 18446744073709551615}, params)
+
+	_, err = parseParams("-1")
+	require.Error(t, err)
+}
@@ -27,9 +27,13 @@ import (
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/version"
 	"github.com/tetratelabs/wazero/sys"
+	"github.com/tetratelabs/wazero/wasmbin"
 )
 
 func main() {
+	if os.Getenv(minimizeCheckEnv) != "" {
+		os.Exit(doMinimizeCheck(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
 	os.Exit(doMain(os.Stdout, os.Stderr))
 }
 
@@ -57,6 +61,14 @@ func doMain(stdOut io.Writer, stdErr logging.Writer) int {
 	switch subCmd {
 	case "compile":
 		return doCompile(flag.Args()[1:], stdErr)
+	case "downgrade":
+		return doDowngrade(flag.Args()[1:], stdErr)
+	case "extract":
+		return doExtract(flag.Args()[1:], stdErr)
+	case "minimize":
+		return doMinimize(flag.Args()[1:], stdErr)
+	case "preinit":
+		return doPreinit(flag.Args()[1:], stdErr)
 	case "run":
 		return doRun(flag.Args()[1:], stdOut, stdErr)
 	case "version":
@@ -150,6 +162,242 @@ func doCompile(args []string, stdErr io.Writer) int {
 	return 0
 }
 
+func doPreinit(args []string, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("preinit", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var invoke string
+	flags.StringVar(&invoke, "invoke", "wizer.initialize",
+		"Name of the exported function which initializes the module.")
+
+	var outPath string
+	flags.StringVar(&outPath, "o", "", "Path to write the pre-initialized wasm file.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printPreinitUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printPreinitUsage(stdErr, flags)
+		return 1
+	}
+
+	// Allow options after the path, e.g. "module.wasm -o preinit.wasm".
+	wasmPath := flags.Arg(0)
+	_ = flags.Parse(flags.Args()[1:])
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stdErr, "unexpected arguments: %v\n", flags.Args())
+		printPreinitUsage(stdErr, flags)
+		return 1
+	}
+
+	if outPath == "" {
+		fmt.Fprintln(stdErr, "missing path to output wasm file")
+		printPreinitUsage(stdErr, flags)
+		return 1
+	}
+
+	wasm, err := os.ReadFile(wasmPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	rt := wazero.NewRuntime(ctx)
+	defer rt.Close(ctx)
+
+	guest, err := rt.CompileModule(ctx, wasm)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error compiling wasm binary: %v\n", err)
+		return 1
+	}
+
+	if detectImports(guest.ImportedFunctions()) == modeWasi {
+		wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	}
+
+	// Don't run "_start", as it would run the program instead of only
+	// initializing it.
+	preinitialized, err := wazero.PreInitialize(ctx, rt, wasm, wazero.NewModuleConfig().
+		WithStartFunctions().WithStdout(stdErr).WithStderr(stdErr), invoke)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error pre-initializing wasm binary: %v\n", err)
+		return 1
+	}
+
+	if err = os.WriteFile(outPath, preinitialized, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func doDowngrade(args []string, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("downgrade", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var keep string
+	flags.StringVar(&keep, "keep", "",
+		"Comma-separated features whose instructions are kept, e.g. \"bulk-memory-operations\". "+
+			"By default, instructions of all of "+strings.ReplaceAll(wasmbin.DowngradableFeatures.String(), "|", ", ")+
+			" are replaced, so that the module only needs WebAssembly 1.0.")
+
+	var outPath string
+	flags.StringVar(&outPath, "o", "", "Path to write the downgraded wasm file.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printDowngradeUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printDowngradeUsage(stdErr, flags)
+		return 1
+	}
+
+	// Allow options after the path, e.g. "module.wasm -o downgraded.wasm".
+	wasmPath := flags.Arg(0)
+	_ = flags.Parse(flags.Args()[1:])
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stdErr, "unexpected arguments: %v\n", flags.Args())
+		printDowngradeUsage(stdErr, flags)
+		return 1
+	}
+
+	if outPath == "" {
+		fmt.Fprintln(stdErr, "missing path to output wasm file")
+		printDowngradeUsage(stdErr, flags)
+		return 1
+	}
+
+	features := api.CoreFeaturesV2 &^ wasmbin.DowngradableFeatures
+	if keep != "" {
+		for _, name := range strings.Split(keep, ",") {
+			feature := downgradableFeature(name)
+			if feature == 0 {
+				fmt.Fprintf(stdErr, "invalid feature to keep: %s\n", name)
+				return 1
+			}
+			features |= feature
+		}
+	}
+
+	wasm, err := os.ReadFile(wasmPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	downgraded, err := wasmbin.Downgrade(wasm, features)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error downgrading wasm binary: %v\n", err)
+		return 1
+	}
+
+	if err = os.WriteFile(outPath, downgraded, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func doExtract(args []string, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var exports string
+	flags.StringVar(&exports, "exports", "",
+		"Comma-separated exports to extract, e.g. \"handle,memory\". "+
+			"Only what they transitively use is kept, including imports.")
+
+	var outPath string
+	flags.StringVar(&outPath, "o", "", "Path to write the extracted wasm file.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printExtractUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printExtractUsage(stdErr, flags)
+		return 1
+	}
+
+	// Allow options after the path, e.g. "module.wasm -o extracted.wasm".
+	wasmPath := flags.Arg(0)
+	_ = flags.Parse(flags.Args()[1:])
+
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stdErr, "unexpected arguments: %v\n", flags.Args())
+		printExtractUsage(stdErr, flags)
+		return 1
+	}
+
+	if exports == "" {
+		fmt.Fprintln(stdErr, "missing exports to extract")
+		printExtractUsage(stdErr, flags)
+		return 1
+	}
+
+	if outPath == "" {
+		fmt.Fprintln(stdErr, "missing path to output wasm file")
+		printExtractUsage(stdErr, flags)
+		return 1
+	}
+
+	wasm, err := os.ReadFile(wasmPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	extracted, err := wasmbin.Extract(wasm, strings.Split(exports, ",")...)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error extracting wasm binary: %v\n", err)
+		return 1
+	}
+
+	if err = os.WriteFile(outPath, extracted, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// downgradableFeature returns the feature of wasmbin.DowngradableFeatures
+// with the name, or zero if there is none.
+func downgradableFeature(name string) api.CoreFeatures {
+	for i := 0; i < 64; i++ {
+		feature := api.CoreFeatures(1 << i)
+		if wasmbin.DowngradableFeatures.IsEnabled(feature) && feature.String() == name {
+			return feature
+		}
+	}
+	return 0
+}
+
 func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 	flags := flag.NewFlagSet("run", flag.ExitOnError)
 	flags.SetOutput(stdErr)
@@ -329,10 +577,8 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		_, err = rt.InstantiateModule(ctx, guest, conf)
 	case modeWasiUnstable:
 		// Instantiate the current WASI functions under the wasi_unstable
-		// instead of wasi_snapshot_preview1.
-		wasiBuilder := rt.NewHostModuleBuilder("wasi_unstable")
-		wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(wasiBuilder)
-		_, err = wasiBuilder.Instantiate(ctx)
+		// instead of wasi_snapshot_preview1, adapting those which differ.
+		_, err = wasi_snapshot_preview1.NewUnstableBuilder(rt).Instantiate(ctx)
 		if err == nil {
 			// Instantiate our binary, but using the old import names.
 			_, err = rt.InstantiateModule(ctx, guest, conf)
@@ -469,7 +715,7 @@ func detectImports(imports []api.FunctionDefinition) importMode {
 		switch moduleName {
 		case wasi_snapshot_preview1.ModuleName:
 			return modeWasi
-		case "wasi_unstable":
+		case wasi_snapshot_preview1.UnstableModuleName:
 			return modeWasiUnstable
 		case "go", "gojs":
 			return modeGo
@@ -509,6 +755,10 @@ func printUsage(stdErr io.Writer) {
 	fmt.Fprintln(stdErr)
 	fmt.Fprintln(stdErr, "Commands:")
 	fmt.Fprintln(stdErr, "  compile\tPre-compiles a WebAssembly binary")
+	fmt.Fprintln(stdErr, "  downgrade\tDowngrades a WebAssembly binary to WebAssembly 1.0")
+	fmt.Fprintln(stdErr, "  extract\tExtracts exports of a WebAssembly binary into a smaller one")
+	fmt.Fprintln(stdErr, "  minimize\tMinimizes a WebAssembly binary which fails")
+	fmt.Fprintln(stdErr, "  preinit\tPre-initializes a WebAssembly binary")
 	fmt.Fprintln(stdErr, "  run\t\tRuns a WebAssembly binary")
 	fmt.Fprintln(stdErr, "  version\tDisplays the version of wazero CLI")
 }
@@ -522,6 +772,33 @@ func printCompileUsage(stdErr io.Writer, flags *flag.FlagSet) {
 	flags.PrintDefaults()
 }
 
+func printDowngradeUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero downgrade <options> <path to wasm file> -o <path to output wasm file>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
+func printExtractUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero extract -exports <exports> <path to wasm file> -o <path to output wasm file>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
+func printPreinitUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero preinit <options> <path to wasm file> -o <path to output wasm file>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
 func printRunUsage(stdErr io.Writer, flags *flag.FlagSet) {
 	fmt.Fprintln(stdErr, "wazero CLI")
 	fmt.Fprintln(stdErr)
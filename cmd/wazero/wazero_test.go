@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"flag"
 	"fmt"
@@ -14,14 +15,18 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/logging"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/version"
+	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/sys"
+	"github.com/tetratelabs/wazero/wasmbin"
 )
 
 //go:embed testdata/infinite_loop.wasm
@@ -49,6 +54,11 @@ var wasmCatTinygo []byte
 var wasmWasiUnstable []byte
 
 func TestMain(m *testing.M) {
+	// "minimize" checks candidates by running the test binary, like the CLI.
+	if os.Getenv(minimizeCheckEnv) != "" {
+		os.Exit(doMinimizeCheck(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	// For some reason, riscv64 fails to see directory listings.
 	if a := runtime.GOARCH; a == "riscv64" {
 		log.Println("main: skipping due to not yet supported GOARCH:", a)
@@ -572,6 +582,338 @@ Consider switching to GOOS=wasip1.
 	}
 }
 
+func TestPreinit(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// init sets the exported global to 7, and _start, which must not run, traps.
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 7, wasm.OpcodeGlobalSet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "init", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "_start", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "g", Type: api.ExternTypeGlobal, Index: 0},
+		},
+	}), 0o600))
+	outPath := filepath.Join(tmpDir, "preinit.wasm")
+
+	exitCode, _, stderr := runMain(t, "", []string{"preinit", "--invoke", "init", wasmPath, "-o", outPath})
+	require.Equal(t, 0, exitCode, stderr)
+
+	preinitialized, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	mod, err := r.InstantiateWithConfig(ctx, preinitialized, wazero.NewModuleConfig().WithStartFunctions())
+	require.NoError(t, err)
+	require.Nil(t, mod.ExportedFunction("init"))
+	require.Equal(t, uint64(7), mod.ExportedGlobal("g").Get())
+}
+
+func TestPreinit_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWasiArg, 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{},
+		},
+		{
+			message: "missing path to output wasm file",
+			args:    []string{wasmPath},
+		},
+		{
+			message: "unexpected arguments: [extra]",
+			args:    []string{wasmPath, "extra"},
+		},
+		{
+			message: "function[wizer.initialize] is not exported",
+			args:    []string{wasmPath, "-o", filepath.Join(tmpDir, "out.wasm")},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"preinit"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// extend(x) sign-extends the lower 8 bits, and fill(n) fills n bytes with 1.
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+			{Params: []wasm.ValueType{wasm.ValueTypeI32}},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Extend8S, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 0, wasm.OpcodeEnd,
+			}},
+		},
+		MemorySection: &wasm.Memory{Min: 1},
+		ExportSection: []wasm.Export{
+			{Name: "extend", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "fill", Type: api.ExternTypeFunc, Index: 1},
+		},
+	}), 0o600))
+	outPath := filepath.Join(tmpDir, "downgraded.wasm")
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV1))
+	defer r.Close(ctx)
+
+	exitCode, _, stderr := runMain(t, "", []string{"downgrade", wasmPath, "-o", outPath})
+	require.Equal(t, 0, exitCode, stderr)
+	downgraded, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(ctx, downgraded)
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("extend").Call(ctx, 0x80)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0xffffff80), results[0])
+	_, err = mod.ExportedFunction("fill").Call(ctx, 3)
+	require.NoError(t, err)
+	b, _ := mod.Memory().Read(0, 4)
+	require.Equal(t, []byte{1, 1, 1, 0}, b)
+
+	// Bulk memory operations are kept, so the result needs that feature.
+	exitCode, _, stderr = runMain(t, "", []string{"downgrade", "-keep", "bulk-memory-operations", wasmPath, "-o", outPath})
+	require.Equal(t, 0, exitCode, stderr)
+	downgraded, err = os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	_, err = r.CompileModule(ctx, downgraded)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `memory.fill invalid as feature "bulk-memory-operations" is disabled`)
+}
+
+func TestDowngrade_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWasiArg, 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{},
+		},
+		{
+			message: "missing path to output wasm file",
+			args:    []string{wasmPath},
+		},
+		{
+			message: "unexpected arguments: [extra]",
+			args:    []string{wasmPath, "extra"},
+		},
+		{
+			message: "invalid feature to keep: simd",
+			args:    []string{"-keep", "simd", wasmPath, "-o", filepath.Join(tmpDir, "out.wasm")},
+		},
+		{
+			message: "error reading wasm binary",
+			args:    []string{filepath.Join(tmpDir, "missing.wasm"), "-o", filepath.Join(tmpDir, "out.wasm")},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"downgrade"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// one() calls the imported env.a, and two() has no imports.
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "a", Type: api.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 2, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "one", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "two", Type: api.ExternTypeFunc, Index: 2},
+		},
+	}), 0o600))
+	outPath := filepath.Join(tmpDir, "extracted.wasm")
+
+	exitCode, _, stderr := runMain(t, "", []string{"extract", "-exports", "two", wasmPath, "-o", outPath})
+	require.Equal(t, 0, exitCode, stderr)
+	extracted, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	// The extracted module instantiates without env.a.
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	mod, err := r.Instantiate(ctx, extracted)
+	require.NoError(t, err)
+	require.Nil(t, mod.ExportedFunction("one"))
+	results, err := mod.ExportedFunction("two").Call(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2}, results)
+}
+
+func TestExtract_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWasiArg, 0o600))
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{},
+		},
+		{
+			message: "missing exports to extract",
+			args:    []string{wasmPath, "-o", outPath},
+		},
+		{
+			message: "missing path to output wasm file",
+			args:    []string{"-exports", "_start", wasmPath},
+		},
+		{
+			message: "unexpected arguments: [extra]",
+			args:    []string{wasmPath, "extra"},
+		},
+		{
+			message: "error reading wasm binary",
+			args:    []string{"-exports", "_start", filepath.Join(tmpDir, "missing.wasm"), "-o", outPath},
+		},
+		{
+			message: `error extracting wasm binary: export "missing" not found`,
+			args:    []string{"-exports", "missing", wasmPath, "-o", outPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"extract"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmbin.Encode(minimizeTestModule), 0o600))
+	outPath := filepath.Join(tmpDir, "min.wasm")
+
+	exitCode, _, stderr := runMain(t, "", []string{"minimize", "--invoke", "fail", wasmPath, "-o", outPath})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Contains(t, stderr, "preserving:\ntrap: wasm error: integer divide by zero")
+
+	minimized, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	require.True(t, len(minimized) < len(wasmbin.Encode(minimizeTestModule)))
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	mod, err := r.Instantiate(ctx, minimized)
+	require.NoError(t, err)
+	require.Nil(t, mod.ExportedFunction("other"))
+	_, err = mod.ExportedFunction("fail").Call(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "integer divide by zero")
+}
+
+func TestMinimize_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmbin.Encode(minimizeTestModule), 0o600))
+	outPath := filepath.Join(tmpDir, "min.wasm")
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{},
+		},
+		{
+			message: "missing path to output wasm file",
+			args:    []string{wasmPath},
+		},
+		{
+			message: "missing function to invoke",
+			args:    []string{wasmPath, "-o", outPath},
+		},
+		{
+			message: "invalid params: strconv.ParseUint: parsing \"a\": invalid syntax",
+			args:    []string{"-invoke", "fail", "-params", "a", wasmPath, "-o", outPath},
+		},
+		{
+			message: "the invocation doesn't fail: return: [42]",
+			args:    []string{"-invoke", "other", wasmPath, "-o", outPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"minimize"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
 func TestVersion(t *testing.T) {
 	exitCode, stdout, stderr := runMain(t, "", []string{"version"})
 	require.Equal(t, 0, exitCode)
@@ -784,6 +1126,10 @@ Usage:
 
 Commands:
   compile	Pre-compiles a WebAssembly binary
+  downgrade	Downgrades a WebAssembly binary to WebAssembly 1.0
+  extract	Extracts exports of a WebAssembly binary into a smaller one
+  minimize	Minimizes a WebAssembly binary which fails
+  preinit	Pre-initializes a WebAssembly binary
   run		Runs a WebAssembly binary
   version	Displays the version of wazero CLI
 `, stderr)
@@ -2,6 +2,7 @@ package wazero
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"io"
@@ -12,7 +13,6 @@ import (
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
-	"github.com/tetratelabs/wazero/internal/engine/compiler"
 	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/internalapi"
@@ -168,6 +168,64 @@ type RuntimeConfig interface {
 	// When the invocations of api.Function are closed due to this, sys.ExitError is raised to the callers and
 	// the api.Module from which the functions are derived is made closed.
 	WithCloseOnContextDone(bool) RuntimeConfig
+
+	// WithCooperativeYield makes the compiled functions periodically yield to
+	// the Go scheduler in loops. Defaults to false.
+	//
+	// The native code generated by the compiler cannot be preempted by the Go
+	// scheduler. Therefore, a long-running loop without host calls occupies
+	// its OS thread, which can starve other goroutines, and delays the "stop
+	// the world" phases of the garbage collector until the call returns.
+	// When enabled, a cheap check is inserted at loop headers, which
+	// periodically yields via runtime.Gosched, so that the preemption and GC
+	// latencies remain bounded.
+	//
+	// These are the same checks as inserted by WithCloseOnContextDone, so
+	// enabling that also enables this. Conversely, this doesn't close the
+	// module when the context.Context is done.
+	WithCooperativeYield(bool) RuntimeConfig
+
+	// WithPolicy configures constraints, in addition to validity, that a
+	// module must satisfy to be compiled. Defaults to nil, which allows any
+	// valid module.
+	//
+	// This example only allows modules that import from WASI:
+	//
+	//	policy := wazero.NewPolicy().WithAllowedImports("wasi_snapshot_preview1")
+	//	rConfig = wazero.NewRuntimeConfig().WithPolicy(policy)
+	//
+	// Violations are reported by Runtime.CompileModule as a *PolicyError.
+	WithPolicy(Policy) RuntimeConfig
+
+	// WithMaxInstances limits the count of guest modules instantiated by the
+	// Runtime which are not yet closed. Defaults to zero, which is unlimited.
+	// Host modules are not counted.
+	//
+	// When the limit is reached, Runtime.InstantiateModule fails with
+	// ErrMaxInstancesExceeded before instantiating anything, and calls
+	// onExceed, if not nil, with the name of the module. This protects
+	// multi-tenant servers from instantiation storms, e.g.:
+	//
+	//	rConfig = wazero.NewRuntimeConfig().WithMaxInstances(1000,
+	//		func(ctx context.Context, moduleName string) {
+	//			log.Printf("rejected instantiation of module[%s]", moduleName)
+	//		})
+	//
+	// Rejections are also counted by experimental.Metrics.
+	WithMaxInstances(n int, onExceed func(ctx context.Context, moduleName string)) RuntimeConfig
+
+	// WithExecutableMemoryForbidden guarantees that no executable memory is
+	// mapped, for hosts which forbid generating code at runtime, such as iOS.
+	// Defaults to false.
+	//
+	// When enabled, modules are interpreted, even if this was created by
+	// NewRuntimeConfigCompiler, and native code in a CompilationCache is
+	// never loaded. Instead, the interpreted form of modules is stored in the
+	// CompilationCache, and loaded from it as data. This only affects the
+	// Runtime: others in the same process may still use the compiler. To
+	// assert that no Runtime in the process generates code, see
+	// experimental.ForbidExecutableMemory.
+	WithExecutableMemoryForbidden(bool) RuntimeConfig
 }
 
 // NewRuntimeConfig returns a RuntimeConfig using the compiler if it is supported in this environment,
@@ -188,6 +246,11 @@ type runtimeConfig struct {
 	cache                 CompilationCache
 	storeCustomSections   bool
 	ensureTermination     bool
+	cooperativeYield      bool
+	policy                *policy
+	maxInstances          int
+	onMaxInstances        func(ctx context.Context, moduleName string)
+	execForbidden         bool
 }
 
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
@@ -203,6 +266,9 @@ type engineKind int
 const (
 	engineKindCompiler engineKind = iota
 	engineKindInterpreter
+	// engineKindInterpreterExecForbidden is the interpreter of runtimes
+	// configured with RuntimeConfig.WithExecutableMemoryForbidden.
+	engineKindInterpreterExecForbidden
 	engineKindCount
 )
 
@@ -217,13 +283,17 @@ const (
 // part. wazero automatically performs ahead-of-time compilation as needed when
 // Runtime.CompileModule is invoked.
 //
+// When the build tag "wazero_nocompiler" excludes the compiler, for example
+// to reduce the size of binaries which only need the interpreter, this
+// interprets WebAssembly modules instead, like NewRuntimeConfigInterpreter.
+//
 // Warning: This panics at runtime if the runtime.GOOS or runtime.GOARCH does not
 // support Compiler. Use NewRuntimeConfig to safely detect and fallback to
 // NewRuntimeConfigInterpreter if needed.
 func NewRuntimeConfigCompiler() RuntimeConfig {
 	ret := engineLessConfig.clone()
 	ret.engineKind = engineKindCompiler
-	ret.newEngine = compiler.NewEngine
+	ret.newEngine = newCompilerEngine
 	return ret
 }
 
@@ -255,6 +325,39 @@ func (c *runtimeConfig) WithCloseOnContextDone(ensure bool) RuntimeConfig {
 	return ret
 }
 
+// WithCooperativeYield implements RuntimeConfig.WithCooperativeYield
+func (c *runtimeConfig) WithCooperativeYield(yield bool) RuntimeConfig {
+	ret := c.clone()
+	ret.cooperativeYield = yield
+	return ret
+}
+
+// WithPolicy implements RuntimeConfig.WithPolicy
+func (c *runtimeConfig) WithPolicy(p Policy) RuntimeConfig {
+	ret := c.clone()
+	if p == nil {
+		ret.policy = nil
+	} else {
+		ret.policy = p.(*policy)
+	}
+	return ret
+}
+
+// WithMaxInstances implements RuntimeConfig.WithMaxInstances
+func (c *runtimeConfig) WithMaxInstances(n int, onExceed func(ctx context.Context, moduleName string)) RuntimeConfig {
+	ret := c.clone()
+	ret.maxInstances = n
+	ret.onMaxInstances = onExceed
+	return ret
+}
+
+// WithExecutableMemoryForbidden implements RuntimeConfig.WithExecutableMemoryForbidden
+func (c *runtimeConfig) WithExecutableMemoryForbidden(forbidden bool) RuntimeConfig {
+	ret := c.clone()
+	ret.execForbidden = forbidden
+	return ret
+}
+
 // WithMemoryLimitPages implements RuntimeConfig.WithMemoryLimitPages
 func (c *runtimeConfig) WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig {
 	ret := c.clone()
@@ -310,7 +413,8 @@ type CompiledModule interface {
 	Name() string
 
 	// ImportedFunctions returns all the imported functions
-	// (api.FunctionDefinition) in this module or nil if there are none.
+	// (api.FunctionDefinition) in this module or nil if there are none. These
+	// are in the order of the import section, which is their index order.
 	//
 	// Note: Unlike ExportedFunctions, there is no unique constraint on
 	// imports.
@@ -318,10 +422,18 @@ type CompiledModule interface {
 
 	// ExportedFunctions returns all the exported functions
 	// (api.FunctionDefinition) in this module keyed on export name.
+	//
+	// Note: Iteration order of the result is random, so range over
+	// ExportedFunctionNames when order matters.
 	ExportedFunctions() map[string]api.FunctionDefinition
 
+	// ExportedFunctionNames returns the export names of all functions in this
+	// module, in the order of its export section.
+	ExportedFunctionNames() []string
+
 	// ImportedMemories returns all the imported memories
-	// (api.MemoryDefinition) in this module or nil if there are none.
+	// (api.MemoryDefinition) in this module or nil if there are none. These
+	// are in the order of the import section.
 	//
 	// ## Notes
 	//   - As of WebAssembly Core Specification 2.0, there can be at most one
@@ -332,12 +444,23 @@ type CompiledModule interface {
 	// ExportedMemories returns all the exported memories
 	// (api.MemoryDefinition) in this module keyed on export name.
 	//
-	// Note: As of WebAssembly Core Specification 2.0, there can be at most one
-	// memory.
+	// # Notes
+	//
+	//   - As of WebAssembly Core Specification 2.0, there can be at most one
+	//     memory, though it can be exported under multiple names.
+	//   - Iteration order of the result is random, so range over
+	//     ExportedMemoryNames when order matters.
 	ExportedMemories() map[string]api.MemoryDefinition
 
-	// CustomSections returns all the custom sections
-	// (api.CustomSection) in this module keyed on the section name.
+	// ExportedMemoryNames returns the export names of all memories in this
+	// module, in the order of its export section.
+	ExportedMemoryNames() []string
+
+	// CustomSections returns all the custom sections (api.CustomSection) in
+	// this module, in the order they appear in the binary.
+	//
+	// Note: Section names are not unique, so the result can include multiple
+	// sections of the same name.
 	CustomSections() []api.CustomSection
 
 	// Close releases all the allocated resources for this CompiledModule.
@@ -357,6 +480,11 @@ type compiledModule struct {
 	// closeWithModule prevents leaking compiled code when a module is compiled implicitly.
 	closeWithModule bool
 	typeIDs         []wasm.FunctionTypeID
+
+	// leaks is non-nil when this is tracked by experimental.WithLeakDetection
+	// under leakID.
+	leaks  *leakTracker
+	leakID uint64
 }
 
 // Name implements CompiledModule.Name
@@ -369,6 +497,9 @@ func (c *compiledModule) Name() (moduleName string) {
 
 // Close implements CompiledModule.Close
 func (c *compiledModule) Close(context.Context) error {
+	if c.leaks != nil {
+		c.leaks.untrackCompiled(c)
+	}
 	c.compiledEngine.DeleteCompiledModule(c.module)
 	// It is possible the underlying may need to return an error later, but in any case this matches api.Module.Close.
 	return nil
@@ -384,6 +515,11 @@ func (c *compiledModule) ExportedFunctions() map[string]api.FunctionDefinition {
 	return c.module.ExportedFunctions()
 }
 
+// ExportedFunctionNames implements CompiledModule.ExportedFunctionNames
+func (c *compiledModule) ExportedFunctionNames() []string {
+	return c.module.ExportNames(wasm.ExternTypeFunc)
+}
+
 // ImportedMemories implements CompiledModule.ImportedMemories
 func (c *compiledModule) ImportedMemories() []api.MemoryDefinition {
 	return c.module.ImportedMemories()
@@ -394,6 +530,11 @@ func (c *compiledModule) ExportedMemories() map[string]api.MemoryDefinition {
 	return c.module.ExportedMemories()
 }
 
+// ExportedMemoryNames implements CompiledModule.ExportedMemoryNames
+func (c *compiledModule) ExportedMemoryNames() []string {
+	return c.module.ExportNames(wasm.ExternTypeMemory)
+}
+
 // CustomSections implements CompiledModule.CustomSections
 func (c *compiledModule) CustomSections() []api.CustomSection {
 	ret := make([]api.CustomSection, len(c.module.CustomSections))
@@ -505,6 +646,27 @@ type ModuleConfig interface {
 	//   - See /RATIONALE.md for motivation of this feature.
 	WithStartFunctions(...string) ModuleConfig
 
+	// WithInitializers configures functions to call in order after the start
+	// functions, for example to initialize a reactor module. Defaults to none.
+	// See ReactorInitializers for the conventional steps.
+	//
+	// # Notes
+	//
+	//   - Unlike start functions, each step can have a timeout, and can be
+	//     required to exist.
+	//   - Instantiation fails at the first error, closing the module. As for
+	//     start functions, an exit code of zero isn't an error.
+	WithInitializers(...Initializer) ModuleConfig
+
+	// WithCapabilities grants capability tokens, e.g. "net.dial", in addition
+	// to any already granted. Host functions which require a capability, via
+	// HostFunctionBuilder.RequireCapability, fail unless it was granted.
+	// Defaults to none.
+	//
+	// Capabilities replace ambient authority: a host function is only usable
+	// by modules explicitly allowed to, regardless of what they import.
+	WithCapabilities(tokens ...string) ModuleConfig
+
 	// WithStderr configures where standard error (file descriptor 2) is written. Defaults to io.Discard.
 	//
 	// This writer is most commonly used by the functions like "fd_write" in "wasi_snapshot_preview1" although it could
@@ -531,6 +693,21 @@ type ModuleConfig interface {
 	// See https://linux.die.net/man/3/stdin
 	WithStdin(io.Reader) ModuleConfig
 
+	// WithStdinGenerator configures standard input (file descriptor 0) to be
+	// read from chunks returned by `next`, instead of an io.Reader. `next` is
+	// called when the guest reads and previous chunks were consumed, and
+	// returns io.EOF to end input. This replaces any WithStdin.
+	//
+	// This is useful for REPL-style guests, where each chunk can be produced
+	// in response to prior output, e.g. a line typed by the user.
+	//
+	// # Notes
+	//
+	//   - `next` blocks the guest while it runs, and may be called from any
+	//     goroutine calling the guest.
+	//   - Empty chunks are skipped, as a zero-length read means EOF.
+	WithStdinGenerator(next func() ([]byte, error)) ModuleConfig
+
 	// WithStdout configures where standard output (file descriptor 1) is written. Defaults to io.Discard.
 	//
 	// This writer is most commonly used by the functions like "fd_write" in "wasi_snapshot_preview1" although it could
@@ -635,16 +812,202 @@ type ModuleConfig interface {
 	// Note: The caller is responsible to close any io.Reader they supply: It
 	// is not closed on api.Module Close.
 	WithRandSource(io.Reader) ModuleConfig
+
+	// WithRandSeed configures a deterministic, but cryptographically strong,
+	// source of random bytes for each module instance, derived from the
+	// `seed` and the name configured by WithName. This replaces any
+	// WithRandSource.
+	//
+	// Unlike an io.Reader passed to WithRandSource, the source isn't shared
+	// between instances: each instance with the same seed and name reads the
+	// same bytes, regardless of others. This is useful to reproduce the
+	// behavior of guests, for example in tests or replay.
+	WithRandSeed(seed []byte) ModuleConfig
+
+	// WithSysRandSource uses crypto/rand.Reader, the random source of the
+	// operating system, which may be backed by a hardware RNG.
+	//
+	// See WithRandSource
+	WithSysRandSource() ModuleConfig
+
+	// WithRandLimit limits the random bytes the module can read with
+	// "random_get" in wasi_snapshot_preview1. A call for more than
+	// `maxBytesPerCall` bytes fails with EINVAL, and calls wait when they
+	// read more than `bytesPerSecond`. Defaults to no limit, and a value less
+	// than one removes the corresponding limit.
+	//
+	// Use experimental.WithRandomHook to audit calls.
+	WithRandLimit(maxBytesPerCall, bytesPerSecond int64) ModuleConfig
+
+	// WithCloseOnTrap closes the module when a call to one of its functions
+	// traps, instead of leaving it in api.ModuleStatePoisoned. Defaults to
+	// false.
+	//
+	// This is useful when modules are pooled, as a closed module is never
+	// reused by mistake. The call that trapped still returns the trap error,
+	// while any later call returns a sys.ExitError with the exit code
+	// sys.ExitCodeTrapped.
+	//
+	// See api.Module State for the definition of a trap.
+	WithCloseOnTrap(bool) ModuleConfig
+
+	// WithLabel attaches a label to the module, such as a tenant or request
+	// ID, replacing any existing value of the key. Defaults to none.
+	//
+	// Labels aren't visible to the guest. Instead, they attribute what the
+	// module does on the host side:
+	//   - experimental.Labels returns them, e.g. to a FunctionListener.
+	//   - The structured and audit listeners of experimental/logging include
+	//     them in each record.
+	//   - Errors of calls that trap list them after the wasm stack trace.
+	//   - experimental.Metrics lists each active instance with labels.
+	WithLabel(key, value string) ModuleConfig
+
+	// WithIORateLimit limits the bytes per second the module reads and
+	// writes with "fd_read", "fd_pread", "fd_write" and "fd_pwrite" in
+	// wasi_snapshot_preview1, so that one module can't hog the IO bandwidth
+	// of the host. Up to `burst` bytes can be transferred at once, and a
+	// `burst` less than one defaults to `bytesPerSecond`. Defaults to no
+	// limit, and a `bytesPerSecond` less than one removes the limit.
+	//
+	// When the limit is exceeded, these functions wait until enough time has
+	// passed to make up for the bytes transferred, or until the
+	// context.Context of the call is done. A single read or write larger
+	// than `burst` isn't split, but delays the next.
+	//
+	// Use experimental.GetRateLimitMetrics to observe the effect.
+	WithIORateLimit(bytesPerSecond, burst int64) ModuleConfig
+
+	// WithHostCallRateLimit limits the calls per second the module makes to
+	// host functions, such as those of wasi_snapshot_preview1. Up to `burst`
+	// calls can be made at once, and a `burst` less than one defaults to
+	// `callsPerSecond`. Defaults to no limit, and a `callsPerSecond` less
+	// than one removes the limit.
+	//
+	// When the limit is exceeded, calls to host functions wait until it
+	// allows them, or until the context.Context of the call is done.
+	//
+	// Use experimental.GetRateLimitMetrics to observe the effect.
+	WithHostCallRateLimit(callsPerSecond, burst int64) ModuleConfig
+
+	// WithTerminal makes standard input, output and error emulate a terminal
+	// (TTY) with the given size, so that REPL-style guests, such as Python
+	// or Lua, behave as if they were run interactively. Defaults to no
+	// terminal, and zero `rows` or `cols` disables it.
+	//
+	// Stdio report themselves as character devices, which wasi-libc and
+	// Emscripten treat as `isatty`. Emscripten guests can also get and set
+	// the window size and termios attributes, e.g. raw mode, with `ioctl`
+	// (TIOCGWINSZ, TIOCSWINSZ, TCGETS and TCSETS), when using
+	// imports/emscripten.NewFunctionExporterForModule.
+	//
+	// # Notes
+	//
+	//   - Termios attributes are only recorded. The host doesn't echo or line
+	//     buffer input, so the guest sees what is configured by WithStdin or
+	//     WithStdinGenerator as-is.
+	//   - This doesn't make stdio host files, e.g. os.Stdin, a terminal.
+	WithTerminal(rows, cols uint16) ModuleConfig
+
+	// WithSignalHandler configures the name of the function the guest
+	// exports to handle emulated signals, which has the signature
+	// `(param $sig i32)`. Signal numbers are those of wasi_snapshot_preview1,
+	// e.g. SIGINT (2) and SIGTERM (15). Defaults to none.
+	//
+	// The handler is called synchronously by "proc_raise" in
+	// wasi_snapshot_preview1, and with signals the host raises with
+	// experimental.RaiseSignal, when the guest next calls a host function.
+	// Without a handler, or for SIGKILL, signals whose default action is to
+	// terminate the process exit the module with code 128+sig, and others
+	// are ignored.
+	//
+	// This allows CLI programs which handle signals, such as SIGINT, to run
+	// without aborting, and hosts to request a graceful shutdown.
+	//
+	// Note: A signal raised by the host isn't delivered while the guest
+	// doesn't call host functions, e.g. while it blocks in one or computes.
+	// Use a context.Context to interrupt those instead.
+	WithSignalHandler(exportName string) ModuleConfig
+
+	// WithDeferredImports allows the module to import functions from the
+	// given modules before they are instantiated. Defaults to none.
+	//
+	// Imports from these modules, when not yet instantiated, are bound to
+	// them once they are: calling such a function before then traps. This
+	// allows mutually importing modules, such as the output of toolchains
+	// which split a program into modules, to instantiate one after the
+	// other. Imports from modules already instantiated resolve as usual.
+	//
+	// Here's an example, where "main" and "lazy" import from each other:
+	//	mainMod, _ := r.InstantiateModule(ctx, main, wazero.NewModuleConfig().
+	//		WithName("main").WithDeferredImports("lazy"))
+	//	lazyMod, _ := r.InstantiateModule(ctx, lazy, wazero.NewModuleConfig().
+	//		WithName("lazy"))
+	//
+	// # Notes
+	//
+	//   - Only functions can be imported this way. Instantiation fails if the
+	//     module imports memory, a table or a global from a module which
+	//     isn't instantiated yet.
+	//   - Signatures of deferred imports are checked when first called,
+	//     instead of on instantiation. Runtime.Link checks them eagerly.
+	//   - Calls to deferred imports are slower than calls to imports resolved
+	//     on instantiation.
+	WithDeferredImports(moduleNames ...string) ModuleConfig
+
+	// WithOptionalImports allows the module to instantiate when function
+	// imports matching any of the patterns are missing, binding them to a
+	// stub instead. Defaults to none, so that missing imports fail
+	// instantiation.
+	//
+	// Patterns use the syntax of path.Match, and are matched against the
+	// module and name of the import, joined by a dot, e.g. "env.*" or
+	// "*.emscripten_*". An import is missing when its module isn't
+	// instantiated, or doesn't export a function of that name.
+	//
+	// Each call adds a rule, so that imports can be stubbed differently:
+	// the stub of the first rule which matches an import applies. This
+	// allows modules with large import surfaces, such as the JavaScript
+	// library Emscripten imports from, to start when the host only
+	// implements what they actually call. For example:
+	//
+	//	config := wazero.NewModuleConfig().
+	//		WithOptionalImports(wazero.OptionalImportZero, "env.emscripten_notify_*").
+	//		WithOptionalImports(wazero.OptionalImportTrap, "env.*")
+	//
+	// Note: Only function imports can be optional. A missing memory, table
+	// or global import still fails instantiation.
+	WithOptionalImports(stub OptionalImportStub, patterns ...string) ModuleConfig
+}
+
+// OptionalImportStub is what a missing import, allowed by
+// ModuleConfig.WithOptionalImports, does when called.
+type OptionalImportStub uint8
+
+const (
+	// OptionalImportTrap traps with an error naming the missing import.
+	OptionalImportTrap OptionalImportStub = iota
+	// OptionalImportZero returns the zero value of each result, if any.
+	OptionalImportZero
+)
+
+// optionalImport is a rule added by ModuleConfig.WithOptionalImports.
+type optionalImport struct {
+	pattern string
+	stub    OptionalImportStub
 }
 
 type moduleConfig struct {
 	name               string
 	nameSet            bool
 	startFunctions     []string
+	initializers       []Initializer
+	capabilities       []string
 	stdin              io.Reader
 	stdout             io.Writer
 	stderr             io.Writer
 	randSource         io.Reader
+	randSeed           []byte
 	walltime           sys.Walltime
 	walltimeResolution sys.ClockResolution
 	nanotime           sys.Nanotime
@@ -660,6 +1023,37 @@ type moduleConfig struct {
 	fsConfig FSConfig
 	// sockConfig is the network listener configuration for ABI like WASI.
 	sockConfig *internalsock.Config
+	// closeOnTrap closes the module instead of poisoning it.
+	closeOnTrap bool
+	// labels are nil until WithLabel is called.
+	labels map[string]string
+	// ioRateLimit and hostCallRateLimit are zero when not limited.
+	ioRateLimit, hostCallRateLimit rateLimit
+	// randMaxPerCall and randRateLimit are zero when not limited.
+	randMaxPerCall int64
+	randRateLimit  rateLimit
+	// terminalRows and terminalCols are zero when stdio isn't a terminal.
+	terminalRows, terminalCols uint16
+	// signalHandler is the name of the guest's signal handler export, if any.
+	signalHandler string
+	// deferredImports are the names of modules whose function imports can
+	// be bound after instantiation.
+	deferredImports []string
+	// optionalImports are the rules of missing imports to stub, in order.
+	optionalImports []optionalImport
+}
+
+// rateLimit is the configuration of an internalsys.RateLimit.
+type rateLimit struct {
+	perSecond, burst int64
+}
+
+// newRateLimit returns nil when there's no limit.
+func (l rateLimit) newRateLimit() *internalsys.RateLimit {
+	if l.perSecond < 1 {
+		return nil
+	}
+	return internalsys.NewRateLimit(l.perSecond, l.burst)
 }
 
 // NewModuleConfig returns a ModuleConfig that can be used for configuring module instantiation.
@@ -677,6 +1071,12 @@ func (c *moduleConfig) clone() *moduleConfig {
 	for key, value := range c.environKeys {
 		ret.environKeys[key] = value
 	}
+	if c.labels != nil {
+		ret.labels = make(map[string]string, len(c.labels))
+		for key, value := range c.labels {
+			ret.labels[key] = value
+		}
+	}
 	return &ret
 }
 
@@ -742,6 +1142,20 @@ func (c *moduleConfig) WithStartFunctions(startFunctions ...string) ModuleConfig
 	return ret
 }
 
+// WithInitializers implements ModuleConfig.WithInitializers
+func (c *moduleConfig) WithInitializers(initializers ...Initializer) ModuleConfig {
+	ret := c.clone()
+	ret.initializers = initializers
+	return ret
+}
+
+// WithCapabilities implements ModuleConfig.WithCapabilities
+func (c *moduleConfig) WithCapabilities(tokens ...string) ModuleConfig {
+	ret := c.clone()
+	ret.capabilities = append(append([]string{}, c.capabilities...), tokens...)
+	return ret
+}
+
 // WithStderr implements ModuleConfig.WithStderr
 func (c *moduleConfig) WithStderr(stderr io.Writer) ModuleConfig {
 	ret := c.clone()
@@ -756,6 +1170,13 @@ func (c *moduleConfig) WithStdin(stdin io.Reader) ModuleConfig {
 	return ret
 }
 
+// WithStdinGenerator implements ModuleConfig.WithStdinGenerator
+func (c *moduleConfig) WithStdinGenerator(next func() ([]byte, error)) ModuleConfig {
+	ret := c.clone()
+	ret.stdin = internalsys.NewGeneratorReader(next)
+	return ret
+}
+
 // WithStdout implements ModuleConfig.WithStdout
 func (c *moduleConfig) WithStdout(stdout io.Writer) ModuleConfig {
 	ret := c.clone()
@@ -816,6 +1237,91 @@ func (c *moduleConfig) WithSysNanosleep() ModuleConfig {
 func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	ret := c.clone()
 	ret.randSource = source
+	ret.randSeed = nil
+	return ret
+}
+
+// WithRandSeed implements ModuleConfig.WithRandSeed
+func (c *moduleConfig) WithRandSeed(seed []byte) ModuleConfig {
+	ret := c.clone()
+	ret.randSource = nil
+	ret.randSeed = append(make([]byte, 0, len(seed)), seed...)
+	return ret
+}
+
+// WithSysRandSource implements ModuleConfig.WithSysRandSource
+func (c *moduleConfig) WithSysRandSource() ModuleConfig {
+	return c.WithRandSource(rand.Reader)
+}
+
+// WithRandLimit implements ModuleConfig.WithRandLimit
+func (c *moduleConfig) WithRandLimit(maxBytesPerCall, bytesPerSecond int64) ModuleConfig {
+	ret := c.clone()
+	ret.randMaxPerCall = maxBytesPerCall
+	ret.randRateLimit = rateLimit{perSecond: bytesPerSecond}
+	return ret
+}
+
+// WithCloseOnTrap implements ModuleConfig.WithCloseOnTrap
+func (c *moduleConfig) WithCloseOnTrap(closeOnTrap bool) ModuleConfig {
+	ret := c.clone()
+	ret.closeOnTrap = closeOnTrap
+	return ret
+}
+
+// WithLabel implements ModuleConfig.WithLabel
+func (c *moduleConfig) WithLabel(key, value string) ModuleConfig {
+	ret := c.clone()
+	if ret.labels == nil {
+		ret.labels = map[string]string{}
+	}
+	ret.labels[key] = value
+	return ret
+}
+
+// WithIORateLimit implements ModuleConfig.WithIORateLimit
+func (c *moduleConfig) WithIORateLimit(bytesPerSecond, burst int64) ModuleConfig {
+	ret := c.clone()
+	ret.ioRateLimit = rateLimit{perSecond: bytesPerSecond, burst: burst}
+	return ret
+}
+
+// WithHostCallRateLimit implements ModuleConfig.WithHostCallRateLimit
+func (c *moduleConfig) WithHostCallRateLimit(callsPerSecond, burst int64) ModuleConfig {
+	ret := c.clone()
+	ret.hostCallRateLimit = rateLimit{perSecond: callsPerSecond, burst: burst}
+	return ret
+}
+
+// WithTerminal implements ModuleConfig.WithTerminal
+func (c *moduleConfig) WithTerminal(rows, cols uint16) ModuleConfig {
+	ret := c.clone()
+	ret.terminalRows, ret.terminalCols = rows, cols
+	return ret
+}
+
+// WithSignalHandler implements ModuleConfig.WithSignalHandler
+func (c *moduleConfig) WithSignalHandler(exportName string) ModuleConfig {
+	ret := c.clone()
+	ret.signalHandler = exportName
+	return ret
+}
+
+// WithDeferredImports implements ModuleConfig.WithDeferredImports
+func (c *moduleConfig) WithDeferredImports(moduleNames ...string) ModuleConfig {
+	ret := c.clone()
+	ret.deferredImports = append([]string(nil), moduleNames...)
+	return ret
+}
+
+// WithOptionalImports implements ModuleConfig.WithOptionalImports
+func (c *moduleConfig) WithOptionalImports(stub OptionalImportStub, patterns ...string) ModuleConfig {
+	ret := c.clone()
+	ret.optionalImports = make([]optionalImport, 0, len(c.optionalImports)+len(patterns))
+	ret.optionalImports = append(ret.optionalImports, c.optionalImports...)
+	for _, pattern := range patterns {
+		ret.optionalImports = append(ret.optionalImports, optionalImport{pattern: pattern, stub: stub})
+	}
 	return ret
 }
 
@@ -848,29 +1354,72 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 
 	var fs []experimentalsys.FS
 	var guestPaths []string
+	var rights []*internalsys.Rights
+	var splice, ioUring, noSync bool
 	if f, ok := c.fsConfig.(*fsConfig); ok {
-		fs, guestPaths = f.preopens()
+		if fs, guestPaths, rights, err = f.preopens(); err != nil {
+			return
+		}
+		splice = f.splice
+		ioUring = f.ioUring
+		noSync = f.noSync
 	}
 
 	var listeners []*net.TCPListener
 	if n := c.sockConfig; n != nil {
 		if listeners, err = n.BuildTCPListeners(); err != nil {
+			closeFS(fs)
 			return
 		}
 	}
 
-	return internalsys.NewContext(
+	randSource := c.randSource
+	if c.randSeed != nil {
+		randSource = platform.NewSeededRandSource(c.randSeed, c.name)
+	}
+
+	sysCtx, err = internalsys.NewContext(
 		math.MaxUint32,
 		c.args,
 		environ,
 		c.stdin,
 		c.stdout,
 		c.stderr,
-		c.randSource,
+		randSource,
 		c.walltime, c.walltimeResolution,
 		c.nanotime, c.nanotimeResolution,
 		c.nanosleep, c.osyield,
 		fs, guestPaths,
 		listeners,
 	)
+	if err != nil {
+		closeFS(fs)
+		return
+	}
+	sysCtx.GrantCapabilities(c.capabilities...)
+	sysCtx.SetRateLimits(c.ioRateLimit.newRateLimit(), c.hostCallRateLimit.newRateLimit())
+	sysCtx.SetRandLimits(c.randMaxPerCall, c.randRateLimit.newRateLimit())
+	sysCtx.SetSignalHandler(c.signalHandler)
+	if splice {
+		sysCtx.FS().EnableSplice()
+	}
+	if ioUring {
+		sysCtx.FS().EnableIOUring()
+	}
+	if noSync {
+		sysCtx.FS().DisableSync()
+	}
+	if c.terminalRows > 0 && c.terminalCols > 0 {
+		sysCtx.FS().EnableTerminal(c.terminalRows, c.terminalCols)
+	}
+	for i, r := range rights {
+		if r == nil {
+			continue
+		}
+		// Pre-opens are inserted in order, after stdio.
+		if f, ok := sysCtx.FS().LookupFile(internalsys.FdPreopen + int32(i)); ok {
+			f.Rights = r
+		}
+	}
+	return
 }
@@ -0,0 +1,8 @@
+// This is the opposite constraint of config_nocompiler.go
+//go:build !wazero_nocompiler && !tinygo && !wasm
+
+package wazero
+
+import "github.com/tetratelabs/wazero/internal/engine/compiler"
+
+var newCompilerEngine newEngine = compiler.NewEngine
@@ -0,0 +1,12 @@
+// The build tag "wazero_nocompiler" excludes the compiler, reducing the size
+// of binaries which only use the interpreter. TinyGo always excludes it, as
+// it can't compile the assembler, and so does GOARCH=wasm, as a guest can't
+// execute the machine code it would compile.
+//go:build wazero_nocompiler || tinygo || wasm
+
+package wazero
+
+import "github.com/tetratelabs/wazero/internal/engine/interpreter"
+
+// newCompilerEngine is the interpreter, as the compiler isn't linked.
+var newCompilerEngine newEngine = interpreter.NewEngine
@@ -75,6 +75,26 @@ func TestRuntimeConfig(t *testing.T) {
 			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCloseOnContextDone(true) },
 			expected: &runtimeConfig{ensureTermination: true},
 		},
+		{
+			name:     "WithCooperativeYield",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCooperativeYield(true) },
+			expected: &runtimeConfig{cooperativeYield: true},
+		},
+		{
+			name:     "WithPolicy",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithPolicy(NewPolicy().WithMaxFunctions(1)) },
+			expected: &runtimeConfig{policy: &policy{maxFunctions: 1}},
+		},
+		{
+			name:     "WithMaxInstances",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithMaxInstances(2, nil) },
+			expected: &runtimeConfig{maxInstances: 2},
+		},
+		{
+			name:     "WithExecutableMemoryForbidden",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithExecutableMemoryForbidden(true) },
+			expected: &runtimeConfig{execForbidden: true},
+		},
 	}
 
 	for _, tt := range tests {
@@ -326,7 +346,7 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 				config := base.WithFS(testFS)
 				return config, func(t *testing.T, sys *internalsys.Context) {
 					rootfs := sys.FS().RootFS()
-					require.Equal(t, &sysfs.AdaptFS{FS: testFS}, rootfs)
+					require.Equal(t, &sysfs.AdaptFS{FS: testFS, Dev: sysfs.SyntheticDev("")}, rootfs)
 				}
 			},
 		},
@@ -337,7 +357,7 @@ func TestModuleConfig_toSysContext(t *testing.T) {
 				config := base.WithFS(testFS).WithFS(testFS2)
 				return config, func(t *testing.T, sys *internalsys.Context) {
 					rootfs := sys.FS().RootFS()
-					require.Equal(t, &sysfs.AdaptFS{FS: testFS2}, rootfs)
+					require.Equal(t, &sysfs.AdaptFS{FS: testFS2, Dev: sysfs.SyntheticDev("")}, rootfs)
 				}
 			},
 		},
@@ -573,6 +593,26 @@ func TestModuleConfig_toSysContext_Errors(t *testing.T) {
 			input:       NewModuleConfig().WithEnv("", "a"),
 			expectedErr: "environ invalid: empty key",
 		},
+		{
+			name:        "WithPreopenOrder not mounted",
+			input:       NewModuleConfig().WithFSConfig(NewFSConfig().WithDirMount(".", "/").WithPreopenOrder("/tmp")),
+			expectedErr: `preopen order: "/tmp" is not mounted`,
+		},
+		{
+			name:        "WithPreopenOrder repeated",
+			input:       NewModuleConfig().WithFSConfig(NewFSConfig().WithDirMount(".", "/").WithPreopenOrder("/", ".")),
+			expectedErr: `preopen order: "." is repeated`,
+		},
+		{
+			name:        "WithPreopenRights not mounted",
+			input:       NewModuleConfig().WithFSConfig(NewFSConfig().WithPreopenRights("/tmp", 0, 0)),
+			expectedErr: `preopen rights: "/tmp" is not mounted`,
+		},
+		{
+			name:        "WithMountQuota not mounted",
+			input:       NewModuleConfig().WithFSConfig(NewFSConfig().WithMountQuota("/tmp", MountQuota{MaxBytes: 1})),
+			expectedErr: `mount quota: "/tmp" is not mounted`,
+		},
 	}
 	for _, tt := range tests {
 		tc := tt
@@ -1,5 +1,5 @@
 // This is the opposite constraint of config_supported.go
-//go:build !(amd64 || arm64) || !(darwin || linux || freebsd || windows)
+//go:build !(amd64 || arm64) || !(darwin || linux || freebsd || windows) || wazero_nocompiler || tinygo
 
 package wazero
 
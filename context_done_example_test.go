@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
 )
 
 // infiniteLoopWasm exports a function named "infinite_loop" that never exits.
@@ -122,3 +123,41 @@ func ExampleRuntimeConfig_WithCloseOnContextDone_moduleClose() {
 	// Output:
 	//	module closed with exit_code(1)
 }
+
+// ExampleRuntimeConfig_WithCloseOnContextDone_callTimeout demonstrates how to
+// ensure the termination of infinite loop function with api.CallOptions
+// passed to api.Function CallWithOptions.
+func ExampleRuntimeConfig_WithCloseOnContextDone_callTimeout() {
+	ctx := context.Background()
+
+	r := wazero.NewRuntimeWithConfig(ctx,
+		// Enables the WithCloseOnContextDone option.
+		wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer r.Close(ctx)
+
+	moduleInstance, err := r.InstantiateWithConfig(ctx, infiniteLoopWasm,
+		wazero.NewModuleConfig().WithName("malicious_wasm"))
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	infiniteLoop := moduleInstance.ExportedFunction("infinite_loop")
+
+	// Invoke the infinite loop with a timeout scoped to this call.
+	_, err = infiniteLoop.CallWithOptions(ctx, api.CallOptions{
+		Timeout: time.Second,
+		OnTimeout: func() {
+			fmt.Println("timed out")
+		},
+	})
+
+	// The timeout triggers the termination of infinite loop, and closes the
+	// module as it is no longer safe to reuse.
+	fmt.Println(err)
+	fmt.Println(moduleInstance.IsClosed())
+
+	// Output:
+	// timed out
+	// module closed with context deadline exceeded
+	// true
+}
@@ -0,0 +1,50 @@
+package wazero
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+// eliminateDeadCode returns the binary with only the exports and what they
+// transitively use. Calls to function imports which a module already
+// instantiated in the runtime doesn't export are replaced with traps, so
+// their imports are pruned too.
+//
+// See experimental.WithDeadCodeElimination
+func (r *runtime) eliminateDeadCode(binary []byte, exports []string) ([]byte, error) {
+	pruned, err := wasmbin.ExtractWithOptions(binary, wasmbin.ExtractOptions{
+		Features:    r.enabledFeatures,
+		Unavailable: r.isUnavailableImport,
+	}, exports...)
+	if err != nil {
+		return nil, fmt.Errorf("dead code elimination: %w", err)
+	}
+	m, err := wasmbin.DecodeWithFeatures(pruned, r.enabledFeatures)
+	if err != nil {
+		return nil, fmt.Errorf("dead code elimination: %w", err)
+	}
+	// An unavailable import is only kept when used otherwise than called,
+	// e.g. by "ref.func", so instantiation would fail.
+	for i := range m.Imports {
+		imp := &m.Imports[i]
+		if imp.Type == api.ExternTypeFunc && r.isUnavailableImport(imp.Module, imp.Name) {
+			return nil, fmt.Errorf("dead code elimination: %s.%s is referenced, but not exported by module[%s]",
+				imp.Module, imp.Name, imp.Module)
+		}
+	}
+	return pruned, nil
+}
+
+// isUnavailableImport returns true if the function import names a module
+// instantiated in the runtime which doesn't export it. Imports of other
+// modules are assumed to be instantiated before the module which uses them.
+func (r *runtime) isUnavailableImport(module, name string) bool {
+	mod := r.store.Module(module)
+	if mod == nil {
+		return false
+	}
+	_, ok := mod.ExportedFunctionDefinitions()[name]
+	return !ok
+}
@@ -0,0 +1,110 @@
+package wazero
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+func TestRuntime_CompileModule_DeadCodeElimination(t *testing.T) {
+	// used() returns 1, and unused() calls the imported env.missing.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []api.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "missing", Type: api.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "used", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "unused", Type: api.ExternTypeFunc, Index: 2},
+		},
+	})
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	ctx := experimental.WithDeadCodeElimination(testCtx, "used")
+	compiled, err := r.CompileModule(ctx, bin)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(compiled.ImportedFunctions()))
+	require.Equal(t, 1, len(compiled.ExportedFunctions()))
+
+	// The module instantiates without env.missing.
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("used").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1}, results)
+
+	t.Run("unknown export", func(t *testing.T) {
+		_, err := r.CompileModule(experimental.WithDeadCodeElimination(testCtx, "run"), bin)
+		require.EqualError(t, err, `dead code elimination: export "run" not found`)
+	})
+
+	t.Run("import not exported by the registered module", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		defer r.Close(testCtx)
+
+		_, err := r.NewHostModuleBuilder("env").
+			NewFunctionBuilder().WithFunc(func(context.Context) uint32 { return 2 }).Export("other").
+			Instantiate(testCtx)
+		require.NoError(t, err)
+
+		// The call to env.missing traps instead, so its import is pruned.
+		compiled, err := r.CompileModule(experimental.WithDeadCodeElimination(testCtx, "unused"), bin)
+		require.NoError(t, err)
+		require.Equal(t, 0, len(compiled.ImportedFunctions()))
+
+		mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+		require.NoError(t, err)
+		_, err = mod.ExportedFunction("unused").Call(testCtx)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "wasm error: unreachable")
+
+		// env.missing can't be pruned when referenced by "ref.func".
+		refFunc := wasmbin.Encode(&wasmbin.Module{
+			Types:   []wasmbin.FunctionType{{Results: []api.ValueType{wasm.ValueTypeI32}}, {}},
+			Imports: []wasmbin.Import{{Module: "env", Name: "missing", Type: api.ExternTypeFunc}},
+			Functions: []wasmbin.Function{{TypeIndex: 1, Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{
+				{Opcode: wasm.OpcodeRefFunc, Immediates: wasmbin.EncodeUint32(0)},
+				{Opcode: wasm.OpcodeDrop},
+				{Opcode: wasm.OpcodeEnd},
+			})}},
+			Exports: []wasmbin.Export{{Name: "ref", Type: api.ExternTypeFunc, Index: 1}},
+			Elements: []wasmbin.ElementSegment{{
+				Type: wasmbin.RefTypeFuncref,
+				Mode: wasmbin.ElementModeDeclarative,
+				Init: []wasmbin.Instruction{{Opcode: wasm.OpcodeRefFunc, Immediates: wasmbin.EncodeUint32(0)}},
+			}},
+		})
+		_, err = r.CompileModule(experimental.WithDeadCodeElimination(testCtx, "ref"), refFunc)
+		require.EqualError(t, err, "dead code elimination: env.missing is referenced, but not exported by module[env]")
+	})
+
+	t.Run("enabled features", func(t *testing.T) {
+		// The memory is shared, as defined by the threads proposal.
+		shared := wasmbin.Encode(&wasmbin.Module{
+			Memory:  &wasmbin.Memory{Min: 1, Max: 1, HasMax: true, Shared: true},
+			Exports: []wasmbin.Export{{Name: "memory", Type: api.ExternTypeMemory}},
+		})
+		ctx := experimental.WithDeadCodeElimination(testCtx, "memory")
+
+		_, err := r.CompileModule(ctx, shared)
+		require.EqualError(t, err, "dead code elimination: section memory: shared memory invalid as feature \"threads\" is disabled")
+
+		r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads))
+		defer r.Close(testCtx)
+
+		compiled, err := r.CompileModule(ctx, shared)
+		require.NoError(t, err)
+		require.True(t, compiled.ExportedMemories()["memory"] != nil)
+	})
+}
@@ -0,0 +1,69 @@
+package wazero
+
+import (
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// Sentinel errors, which can be matched with errors.Is, to branch on the
+// cause of a failure. Use errors.As with the corresponding error types for
+// details.
+var (
+	// ErrInvalidModule is returned by Runtime.CompileModule when the binary
+	// can't be decoded or is invalid. See InvalidModuleError.
+	ErrInvalidModule = wasm.ErrInvalidModule
+
+	// ErrUnsatisfiedImport is returned by Runtime.InstantiateModule when an
+	// import is of a module which isn't instantiated, or of an export which
+	// doesn't exist. See UnsatisfiedImportError.
+	ErrUnsatisfiedImport = wasm.ErrUnsatisfiedImport
+
+	// ErrMemoryOutOfBounds is returned by api.Function Call when the guest
+	// accessed memory out of bounds. See MemoryOutOfBoundsError.
+	ErrMemoryOutOfBounds error = wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess
+
+	// ErrCapabilityDenied is returned by api.Function Call when a host
+	// function was called by a module which wasn't granted its capability.
+	// See HostFunctionBuilder.RequireCapability.
+	ErrCapabilityDenied = wasm.ErrCapabilityDenied
+
+	// ErrMaxInstancesExceeded is returned by Runtime.InstantiateModule when
+	// the limit of RuntimeConfig.WithMaxInstances is reached.
+	ErrMaxInstancesExceeded = wasm.ErrMaxInstancesExceeded
+)
+
+// InvalidModuleError has the section and byte offset of the binary which
+// failed decoding, when known.
+//
+// Here's an example:
+//
+//	var invalid *wazero.InvalidModuleError
+//	if _, err := r.CompileModule(ctx, bin); errors.As(err, &invalid) {
+//		log.Printf("invalid %s section at offset %d: %v", invalid.Section, invalid.Offset, invalid.Err)
+//	}
+type InvalidModuleError = wasm.InvalidModuleError
+
+// UnsatisfiedImportError lists all the imports which can't be resolved,
+// instead of only the first.
+type UnsatisfiedImportError = wasm.UnsatisfiedImportError
+
+// UnsatisfiedImport is an import listed by UnsatisfiedImportError.
+type UnsatisfiedImport = wasm.UnsatisfiedImport
+
+// MemoryOutOfBoundsError has the effective address of an out of bounds memory
+// access.
+//
+// Note: This isn't returned for bulk memory or atomic instructions, where the
+// error only matches ErrMemoryOutOfBounds.
+type MemoryOutOfBoundsError = wasmruntime.OutOfBoundsMemoryAccessError
+
+// FunctionValidationError has the function, byte offset and instruction of a
+// function body which failed validation. It is wrapped by InvalidModuleError.
+//
+// Here's an example:
+//
+//	var invalid *wazero.FunctionValidationError
+//	if _, err := r.CompileModule(ctx, bin); errors.As(err, &invalid) {
+//		log.Println(invalid.Explain())
+//	}
+type FunctionValidationError = wasm.FunctionValidationError
@@ -0,0 +1,147 @@
+package wazero
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestInvalidModuleError(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	tests := []struct {
+		name            string
+		binary          []byte
+		expectedSection string
+		expectedOffset  int64
+		expectedErr     string
+	}{
+		{
+			name:           "invalid magic",
+			binary:         []byte{1, 2, 3, 4},
+			expectedOffset: -1,
+			expectedErr:    "invalid magic number",
+		},
+		{
+			name: "invalid section",
+			binary: append(binaryencoding.EncodeModule(&wasm.Module{}),
+				wasm.SectionIDType, 1, 1), // one type, but no type.
+			expectedSection: "type",
+			expectedOffset:  11,
+			expectedErr:     "section type: read 0-th type: read leading byte: EOF",
+		},
+		{
+			name: "invalid module",
+			binary: binaryencoding.EncodeModule(&wasm.Module{
+				ExportSection: []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 0}},
+			}),
+			expectedOffset: -1,
+			expectedErr:    "unknown function for export[\"f\"]",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := r.CompileModule(testCtx, tc.binary)
+			require.EqualError(t, err, tc.expectedErr)
+			require.ErrorIs(t, err, ErrInvalidModule)
+
+			var invalid *InvalidModuleError
+			require.True(t, errors.As(err, &invalid))
+			require.Equal(t, tc.expectedSection, invalid.Section)
+			require.Equal(t, tc.expectedOffset, invalid.Offset)
+		})
+	}
+}
+
+func TestFunctionValidationError(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}}},
+	}))
+	require.ErrorIs(t, err, ErrInvalidModule)
+
+	var invalid *FunctionValidationError
+	require.True(t, errors.As(err, &invalid))
+	require.Equal(t, wasm.Index(0), invalid.FunctionIndex)
+	require.Equal(t, "i32.add", invalid.Instruction)
+	// The code section has the count, the body size and the local count
+	// before the body, which starts with the 2 bytes of i32.const.
+	require.Equal(t, int64(5), invalid.Offset)
+}
+
+func TestUnsatisfiedImportError(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func() {}).Export("a").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	_, err = r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{}},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: "a", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: "b", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "other", Name: "c", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+	}))
+	require.EqualError(t, err, "\"b\" is not exported in module \"env\" (and 1 other unsatisfied imports)")
+	require.ErrorIs(t, err, ErrUnsatisfiedImport)
+
+	var unsatisfied *UnsatisfiedImportError
+	require.True(t, errors.As(err, &unsatisfied))
+	require.Equal(t, []UnsatisfiedImport{
+		{Module: "env", Name: "b", Type: wasm.ExternTypeFunc},
+		{Module: "other", Name: "c", Type: wasm.ExternTypeFunc},
+	}, unsatisfied.Imports)
+	require.Equal(t, "func[other.c]", unsatisfied.Imports[1].String())
+}
+
+func TestMemoryOutOfBoundsError(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Load, 0x2, 0x8, // align=2, offset=8
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{{Name: "load", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	configs := map[string]RuntimeConfig{"interpreter": NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = NewRuntimeConfigCompiler()
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			mod, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+
+			for _, base := range []uint64{65530, 0xffffffff} {
+				_, err = mod.ExportedFunction("load").Call(testCtx, base)
+				require.ErrorIs(t, err, ErrMemoryOutOfBounds)
+
+				var oob *MemoryOutOfBoundsError
+				require.True(t, errors.As(err, &oob))
+				require.Equal(t, base+8, oob.Address)
+			}
+		})
+	}
+}
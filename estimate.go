@@ -0,0 +1,74 @@
+package wazero
+
+import (
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// InstantiationEstimate is the estimated memory cost of instantiating a
+// CompiledModule. See EstimateInstantiation.
+type InstantiationEstimate struct {
+	// MemoryBytes is the size of the memory defined by the module, which is
+	// allocated on instantiation. This is the capacity of the memory, so it
+	// is the maximum size when RuntimeConfig.WithMemoryCapacityFromMax is
+	// enabled. Growing the memory later allocates more.
+	MemoryBytes uint64
+
+	// TableBytes is the size of the tables defined by the module.
+	TableBytes uint64
+
+	// InstanceBytes is the estimated size of the other state of an instance,
+	// e.g. globals, the metadata of the functions, and the arguments and
+	// environment variables of the ModuleConfig.
+	InstanceBytes uint64
+
+	// SharedCodeBytes is the size of the compiled code, or zero if unknown.
+	// This is shared by all the instances of the CompiledModule, so it is not
+	// a cost of instantiating it once more.
+	SharedCodeBytes uint64
+}
+
+// PrivateBytes returns the total bytes allocated for each instance, which
+// excludes SharedCodeBytes.
+func (e InstantiationEstimate) PrivateBytes() uint64 {
+	return e.MemoryBytes + e.TableBytes + e.InstanceBytes
+}
+
+// EstimateInstantiation returns the estimated memory cost of instantiating
+// the compiled module with the config, without instantiating it. This allows
+// schedulers to bin-pack tenants before instantiating them. For example:
+//
+//	estimate := wazero.EstimateInstantiation(compiled, config)
+//	if used+estimate.PrivateBytes() > budget {
+//		return errTooManyTenants
+//	}
+//	mod, err := r.InstantiateModule(ctx, compiled, config)
+//
+// Notes:
+//   - The result is an estimate, which doesn't include the allocations made
+//     by the start functions or the host functions, nor the Go runtime
+//     overhead, e.g. of the garbage collector.
+//   - Imported memories, tables and globals are not counted, as they are
+//     owned by the exporting module.
+//   - config can be nil, in which case the default is used.
+func EstimateInstantiation(compiled CompiledModule, config ModuleConfig) InstantiationEstimate {
+	c := compiled.(*compiledModule)
+	var ret InstantiationEstimate
+	ret.MemoryBytes, ret.TableBytes, ret.InstanceBytes = c.module.EstimateInstanceSize()
+
+	if e, ok := c.compiledEngine.(wasm.SizeEstimator); ok {
+		ret.InstanceBytes += e.ModuleEngineSize(c.module)
+		ret.SharedCodeBytes, _ = e.CompiledSize(c.module)
+	}
+
+	if config != nil {
+		mc := config.(*moduleConfig)
+		for _, arg := range mc.args {
+			ret.InstanceBytes += uint64(len(arg))
+		}
+		// environ is pair-indexed, and each pair is joined with '='.
+		for i := 0; i+1 < len(mc.environ); i += 2 {
+			ret.InstanceBytes += uint64(len(mc.environ[i]) + 1 + len(mc.environ[i+1]))
+		}
+	}
+	return ret
+}
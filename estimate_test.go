@@ -0,0 +1,59 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestEstimateInstantiation(t *testing.T) {
+	max := uint32(4)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		MemorySection:   &wasm.Memory{Min: 2, Max: max, IsMaxEncoded: true},
+		TableSection:    []wasm.Table{{Type: wasm.RefTypeFuncref, Min: 10}},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		}},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			compiled, err := r.CompileModule(testCtx, bin)
+			require.NoError(t, err)
+
+			estimate := EstimateInstantiation(compiled, nil)
+			require.Equal(t, uint64(2*wasm.MemoryPageSize), estimate.MemoryBytes)
+			require.Equal(t, uint64(10*8), estimate.TableBytes)
+			require.True(t, estimate.InstanceBytes > 0)
+			require.True(t, estimate.SharedCodeBytes > 0)
+			require.Equal(t, estimate.MemoryBytes+estimate.TableBytes+estimate.InstanceBytes, estimate.PrivateBytes())
+
+			// Arguments and environment variables are copied per instance.
+			withArgs := EstimateInstantiation(compiled, NewModuleConfig().WithArgs("a", "bc").WithEnv("d", "e"))
+			require.Equal(t, estimate.InstanceBytes+uint64(len("abc")+len("d=e")), withArgs.InstanceBytes)
+
+			// The capacity of the memory is allocated.
+			r = NewRuntimeWithConfig(testCtx, tc.config.WithMemoryCapacityFromMax(true))
+			defer r.Close(testCtx)
+			compiled, err = r.CompileModule(testCtx, bin)
+			require.NoError(t, err)
+			require.Equal(t, uint64(max*wasm.MemoryPageSize), EstimateInstantiation(compiled, nil).MemoryBytes)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package aot emits the machine code which the compiler generates for a
+// module as a shared library, and compiles the module from it later, for
+// hosts which forbid generating code at runtime, but allow mapping files as
+// executable memory.
+//
+// Here's an example that emits a library at build time:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigCompiler())
+//	err := aot.Emit(ctx, r, guestWasm, f)
+//
+// Then, on the constrained host, compiles the module without generating
+// code:
+//
+//	compiled, err := aot.CompileModule(ctx, r, guestWasm, "guest.so")
+//
+// The library is an ELF shared object, so it can be signed or inspected with
+// the usual tools, or loaded with dlopen. Besides the code, it exports the
+// symbols "wazero_module_meta" and "wazero_module_text". However, the code
+// uses the calling convention of wazero, not the C one, so it can only be
+// called by a wazero.Runtime.
+//
+// # Notes
+//
+//   - Only the runtime of wazero.NewRuntimeConfigCompiler is supported, so
+//     neither the interpreter, nor the optimizing compiler, nor a runtime
+//     configured wazero.RuntimeConfig WithExecutableMemoryForbidden, which
+//     interprets modules, can emit or load libraries.
+//   - A library can only be loaded by the same version of wazero, with the
+//     same GOARCH, and the same runtime configuration, e.g.
+//     wazero.RuntimeConfig WithCloseOnContextDone, as it was emitted with.
+//   - Modules with intrinsics, whose code is defined in Go, aren't supported.
+//   - Loading libraries is supported on GOOS linux, freebsd and darwin.
+//   - The code of a library is mapped read-only and executable from its
+//     file, so it is never writable. The library also includes the
+//     trampolines which call the imported host functions, so loading a
+//     module doesn't compile code. However, the runtime still compiles the
+//     trampolines of a host module whose functions are called from Go, or
+//     imported by a module which wasn't loaded from a library, and of host
+//     functions implemented in native code, as in experimental.NativeFunction.
+//
+// Note: This is experimental, and may change or be removed.
+package aot
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/internal/engine/compiler"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// errNotCompiler is returned when the runtime doesn't use the compiler.
+var errNotCompiler = errors.New("aot: the runtime doesn't use the compiler")
+
+// checkRuntime returns errNotCompiler if the runtime can't emit or load
+// libraries.
+func checkRuntime(r wazero.Runtime) error {
+	if e, ok := r.(interface{ Engine() wasm.Engine }); !ok || !compiler.IsAOTSupported(e.Engine()) {
+		return errNotCompiler
+	}
+	return nil
+}
+
+// Emit compiles the binary with the runtime, which must use the compiler,
+// and writes the resulting shared library to `w`.
+//
+// The compiled module is closed before returning, so `r` should be dedicated
+// to emitting libraries.
+func Emit(ctx context.Context, r wazero.Runtime, binary []byte, w io.Writer) error {
+	machine, err := machine()
+	if err != nil {
+		return err
+	} else if err = checkRuntime(r); err != nil {
+		return err
+	}
+
+	var lib *library
+	ctx = context.WithValue(ctx, compiler.AOTEmitKey{}, compiler.AOTEmitter(func(id wasm.ModuleID, header, code []byte) {
+		lib = &library{
+			id:     id,
+			header: append([]byte(nil), header...),
+			code:   append([]byte(nil), code...),
+		}
+	}))
+	compiled, err := r.CompileModule(ctx, binary)
+	if err != nil {
+		return err
+	}
+	defer compiled.Close(ctx)
+
+	if lib == nil {
+		return errNotCompiler
+	}
+	_, err = w.Write(lib.encode(machine))
+	return err
+}
+
+// CompileModule compiles the binary with the runtime, which must use the
+// compiler, mapping the code of the library at `path`, written by Emit for
+// the same binary, instead of generating code.
+func CompileModule(ctx context.Context, r wazero.Runtime, binary []byte, path string) (wazero.CompiledModule, error) {
+	machine, err := machine()
+	if err != nil {
+		return nil, err
+	} else if err = checkRuntime(r); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// The mapping of the code remains valid after closing the file.
+	defer f.Close()
+
+	lib, text, err := decode(f, machine)
+	if err != nil {
+		return nil, fmt.Errorf("aot: invalid library %s: %w", path, err)
+	}
+
+	ctx = context.WithValue(ctx, compiler.AOTLoadKey{}, &compiler.AOTLibrary{
+		Name:   path,
+		ID:     lib.id,
+		Header: lib.header,
+		MapCode: func() ([]byte, error) {
+			code, err := platform.MmapFileRX(f, int64(text.Offset), int(text.Size))
+			if err != nil {
+				return nil, fmt.Errorf("aot: error mapping %s: %w", path, err)
+			}
+			return code, nil
+		},
+	})
+	return r.CompileModule(ctx, binary)
+}
+
+// machine returns the ELF machine of runtime.GOARCH.
+func machine() (elf.Machine, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return elf.EM_X86_64, nil
+	case "arm64":
+		return elf.EM_AARCH64, nil
+	default:
+		return 0, fmt.Errorf("aot: unsupported GOARCH %s", runtime.GOARCH)
+	}
+}
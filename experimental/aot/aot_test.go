@@ -0,0 +1,222 @@
+package aot_test
+
+import (
+	"bytes"
+	"context"
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/aot"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var testCtx = context.Background()
+
+// addWasm exports "add", which returns the sum of its two i32 params.
+var addWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{
+		Params:  []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32},
+		Results: []wasm.ValueType{wasm.ValueTypeI32},
+	}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "add", Type: wasm.ExternTypeFunc, Index: 0}},
+	NameSection:   &wasm.NameSection{FunctionNames: wasm.NameMap{{Index: 0, Name: "add"}}},
+})
+
+// callAddWasm exports "call_add", which calls "add" imported from "env".
+var callAddWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{
+		Params:  []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32},
+		Results: []wasm.ValueType{wasm.ValueTypeI32},
+	}},
+	ImportSection:   []wasm.Import{{Module: "env", Name: "add", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "call_add", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+func TestEmit_CompileModule(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	path := filepath.Join(t.TempDir(), "add.so")
+	emitTo(t, path, addWasm)
+
+	// The library is a valid shared object, exporting its symbols.
+	ef, err := elf.Open(path)
+	require.NoError(t, err)
+	symbols, err := ef.DynamicSymbols()
+	require.NoError(t, err)
+	require.Equal(t, 2, len(symbols))
+	require.Equal(t, "wazero_module_meta", symbols[0].Name)
+	require.Equal(t, "wazero_module_text", symbols[1].Name)
+	require.NoError(t, ef.Close())
+
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+	defer r.Close(testCtx)
+
+	compiled, err := aot.CompileModule(testCtx, r, addWasm, path)
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("add").Call(testCtx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, results)
+
+	if runtime.GOOS == "linux" {
+		// The code is mapped from the library, read-only and executable.
+		maps, err := os.ReadFile("/proc/self/maps")
+		require.NoError(t, err)
+		var perms []string
+		for _, line := range strings.Split(string(maps), "\n") {
+			if strings.HasSuffix(line, " "+path) {
+				perms = append(perms, strings.Fields(line)[1])
+			}
+		}
+		require.Equal(t, []string{"r-xp"}, perms)
+	}
+}
+
+func TestCompileModule_hostFunction(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	path := filepath.Join(t.TempDir(), "call_add.so")
+	emitTo(t, path, callAddWasm)
+
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+	defer r.Close(testCtx)
+
+	// The trampoline of the host function is loaded from the library, so no
+	// code is compiled.
+	release := experimental.ForbidExecutableMemory()
+	defer release()
+
+	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+		WithFunc(func(x, y uint32) uint32 { return x + y }).Export("add").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	compiled, err := aot.CompileModule(testCtx, r, callAddWasm, path)
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("call_add").Call(testCtx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, results)
+}
+
+func TestEmit_Errors(t *testing.T) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	var buf bytes.Buffer
+	err := aot.Emit(testCtx, r, addWasm, &buf)
+	require.Error(t, err)
+	if platform.CompilerSupported() {
+		require.EqualError(t, err, "aot: the runtime doesn't use the compiler")
+
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		err = aot.Emit(intrinsicCtx, r, addWasm, &buf)
+		require.EqualError(t, err, "aot: func[.add] is an intrinsic")
+	}
+}
+
+// intrinsicCtx replaces "add" of addWasm with an intrinsic.
+var intrinsicCtx = experimental.WithIntrinsics(testCtx, experimental.Intrinsic{
+	Name:        "add",
+	ParamTypes:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+	ResultTypes: []api.ValueType{api.ValueTypeI32},
+	Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+		stack[0] = uint64(uint32(stack[0]) + uint32(stack[1]))
+	}),
+})
+
+func TestCompileModule_Errors(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "add.so")
+	emitTo(t, path, addWasm)
+	notLibrary := filepath.Join(dir, "add.wasm")
+	require.NoError(t, os.WriteFile(notLibrary, addWasm, 0o600))
+
+	t.Run("not compiler", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigInterpreter())
+		defer r.Close(testCtx)
+
+		_, err := aot.CompileModule(testCtx, r, addWasm, path)
+		require.EqualError(t, err, "aot: the runtime doesn't use the compiler")
+	})
+
+	t.Run("executable memory forbidden", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler().WithExecutableMemoryForbidden(true))
+		defer r.Close(testCtx)
+
+		_, err := aot.CompileModule(testCtx, r, addWasm, path)
+		require.EqualError(t, err, "aot: the runtime doesn't use the compiler")
+	})
+
+	t.Run("close on context done", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler().WithCloseOnContextDone(true))
+		defer r.Close(testCtx)
+
+		_, err := aot.CompileModule(testCtx, r, addWasm, path)
+		require.EqualError(t, err, "aot: "+path+" was emitted by a runtime configured WithCloseOnContextDone(false)")
+	})
+
+	t.Run("intrinsic", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		_, err := aot.CompileModule(intrinsicCtx, r, addWasm, path)
+		require.EqualError(t, err, "aot: func[.add] is an intrinsic")
+	})
+
+	t.Run("different module", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		other := binaryencoding.EncodeModule(&wasm.Module{NameSection: &wasm.NameSection{ModuleName: "other"}})
+		_, err := aot.CompileModule(testCtx, r, other, path)
+		require.EqualError(t, err, "aot: "+path+" was emitted for a different module")
+	})
+
+	t.Run("not a library", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+		defer r.Close(testCtx)
+
+		_, err := aot.CompileModule(testCtx, r, addWasm, notLibrary)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "aot: invalid library "+notLibrary)
+	})
+}
+
+func emitTo(t *testing.T, path string, binary []byte) {
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigCompiler())
+	defer r.Close(testCtx)
+
+	var buf bytes.Buffer
+	require.NoError(t, aot.Emit(testCtx, r, binary, &buf))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+}
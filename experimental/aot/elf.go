@@ -0,0 +1,241 @@
+package aot
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+const (
+	// segmentAlign aligns segments for the largest page size of supported
+	// platforms, so that the code can be mapped directly from the file.
+	segmentAlign = 0x10000
+
+	symbolMeta = "wazero_module_meta"
+	symbolText = "wazero_module_text"
+
+	sectionMeta = ".wazero"
+	sectionText = ".text"
+)
+
+// library is the content of a shared library written by Emit.
+type library struct {
+	id wasm.ModuleID
+	// header is written by compiler.AOTEmitter before the code.
+	header []byte
+	code   []byte
+}
+
+// Indexes of the sections encoded by library.encode.
+const (
+	shNull = iota
+	shHash
+	shDynsym
+	shDynstr
+	shMeta
+	shText
+	shDynamic
+	shShstrtab
+	shCount
+)
+
+// encode returns an ELF shared object, with three segments: read-only
+// metadata, including the symbols, the executable code, and the dynamic
+// section, which the dynamic linker writes on load.
+func (l *library) encode(machine elf.Machine) []byte {
+	meta := append(l.id[:len(l.id):len(l.id)], l.header...)
+
+	dynstr := []byte{0}
+	metaName := addString(&dynstr, symbolMeta)
+	textName := addString(&dynstr, symbolText)
+
+	shstrtab := []byte{0}
+	var shNames [shCount]uint32
+	for i, name := range [shCount]string{"", ".hash", ".dynsym", ".dynstr", sectionMeta, sectionText, ".dynamic", ".shstrtab"} {
+		if name != "" {
+			shNames[i] = addString(&shstrtab, name)
+		}
+	}
+
+	const (
+		ehdrSize  = 64
+		phdrSize  = 56
+		phdrCount = 5
+		symSize   = 24
+		symCount  = 3 // including the null symbol
+		dynSize   = 16
+		dynCount  = 6
+	)
+
+	// Lay out the file, where each address is the same as its offset.
+	hashOff := uint64(ehdrSize + phdrSize*phdrCount)
+	hashSize := uint64(4 * (2 + 1 + symCount)) // nbucket, nchain, one bucket, chains
+	dynsymOff := alignUp(hashOff+hashSize, 8)
+	dynstrOff := dynsymOff + symSize*symCount
+	metaOff := alignUp(dynstrOff+uint64(len(dynstr)), 8)
+	readOnlyEnd := metaOff + uint64(len(meta))
+	textOff := alignUp(readOnlyEnd, segmentAlign)
+	dynamicOff := alignUp(textOff+uint64(len(l.code)), segmentAlign)
+	shstrtabOff := dynamicOff + dynSize*dynCount
+	shOff := alignUp(shstrtabOff+uint64(len(shstrtab)), 8)
+
+	var buf bytes.Buffer
+	write := func(data interface{}) {
+		_ = binary.Write(&buf, binary.LittleEndian, data)
+	}
+	padTo := func(off uint64) {
+		buf.Write(make([]byte, off-uint64(buf.Len())))
+	}
+
+	ident := [elf.EI_NIDENT]byte{0x7f, 'E', 'L', 'F', byte(elf.ELFCLASS64), byte(elf.ELFDATA2LSB), byte(elf.EV_CURRENT)}
+	write(&elf.Header64{
+		Ident:     ident,
+		Type:      uint16(elf.ET_DYN),
+		Machine:   uint16(machine),
+		Version:   uint32(elf.EV_CURRENT),
+		Phoff:     ehdrSize,
+		Shoff:     shOff,
+		Ehsize:    ehdrSize,
+		Phentsize: phdrSize,
+		Phnum:     phdrCount,
+		Shentsize: 64,
+		Shnum:     shCount,
+		Shstrndx:  shShstrtab,
+	})
+
+	dynamicSize := uint64(dynSize * dynCount)
+	write([phdrCount]elf.Prog64{
+		{Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R), Filesz: readOnlyEnd, Memsz: readOnlyEnd, Align: segmentAlign},
+		{
+			Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_X),
+			Off: textOff, Vaddr: textOff, Paddr: textOff,
+			Filesz: uint64(len(l.code)), Memsz: uint64(len(l.code)), Align: segmentAlign,
+		},
+		{
+			Type: uint32(elf.PT_LOAD), Flags: uint32(elf.PF_R | elf.PF_W),
+			Off: dynamicOff, Vaddr: dynamicOff, Paddr: dynamicOff,
+			Filesz: dynamicSize, Memsz: dynamicSize, Align: segmentAlign,
+		},
+		{
+			Type: uint32(elf.PT_DYNAMIC), Flags: uint32(elf.PF_R | elf.PF_W),
+			Off: dynamicOff, Vaddr: dynamicOff, Paddr: dynamicOff,
+			Filesz: dynamicSize, Memsz: dynamicSize, Align: 8,
+		},
+		// Without this, the dynamic linker would make the stack executable.
+		{Type: uint32(elf.PT_GNU_STACK), Flags: uint32(elf.PF_R | elf.PF_W), Align: 16},
+	})
+
+	// A single bucket chains the symbols, from the last one.
+	write([2 + 1 + symCount]uint32{1, symCount, symCount - 1, 0, 0, 1})
+
+	padTo(dynsymOff)
+	global := byte(elf.STB_GLOBAL)<<4 | byte(elf.STT_OBJECT)
+	write([symCount]elf.Sym64{
+		{},
+		{Name: metaName, Info: global, Shndx: shMeta, Value: metaOff, Size: uint64(len(meta))},
+		{Name: textName, Info: byte(elf.STB_GLOBAL)<<4 | byte(elf.STT_FUNC), Shndx: shText, Value: textOff, Size: uint64(len(l.code))},
+	})
+	buf.Write(dynstr)
+
+	padTo(metaOff)
+	buf.Write(meta)
+
+	padTo(textOff)
+	buf.Write(l.code)
+
+	padTo(dynamicOff)
+	write([dynCount]elf.Dyn64{
+		{Tag: int64(elf.DT_HASH), Val: hashOff},
+		{Tag: int64(elf.DT_STRTAB), Val: dynstrOff},
+		{Tag: int64(elf.DT_SYMTAB), Val: dynsymOff},
+		{Tag: int64(elf.DT_STRSZ), Val: uint64(len(dynstr))},
+		{Tag: int64(elf.DT_SYMENT), Val: symSize},
+		{Tag: int64(elf.DT_NULL)},
+	})
+	buf.Write(shstrtab)
+
+	padTo(shOff)
+	alloc := uint64(elf.SHF_ALLOC)
+	write([shCount]elf.Section64{
+		{},
+		{
+			Name: shNames[shHash], Type: uint32(elf.SHT_HASH), Flags: alloc,
+			Addr: hashOff, Off: hashOff, Size: hashSize, Link: shDynsym, Addralign: 8, Entsize: 4,
+		},
+		{
+			Name: shNames[shDynsym], Type: uint32(elf.SHT_DYNSYM), Flags: alloc,
+			Addr: dynsymOff, Off: dynsymOff, Size: symSize * symCount, Link: shDynstr, Info: 1, Addralign: 8, Entsize: symSize,
+		},
+		{
+			Name: shNames[shDynstr], Type: uint32(elf.SHT_STRTAB), Flags: alloc,
+			Addr: dynstrOff, Off: dynstrOff, Size: uint64(len(dynstr)), Addralign: 1,
+		},
+		{
+			Name: shNames[shMeta], Type: uint32(elf.SHT_PROGBITS), Flags: alloc,
+			Addr: metaOff, Off: metaOff, Size: uint64(len(meta)), Addralign: 8,
+		},
+		{
+			Name: shNames[shText], Type: uint32(elf.SHT_PROGBITS), Flags: alloc | uint64(elf.SHF_EXECINSTR),
+			Addr: textOff, Off: textOff, Size: uint64(len(l.code)), Addralign: 16,
+		},
+		{
+			Name: shNames[shDynamic], Type: uint32(elf.SHT_DYNAMIC), Flags: alloc | uint64(elf.SHF_WRITE),
+			Addr: dynamicOff, Off: dynamicOff, Size: dynamicSize, Link: shDynstr, Addralign: 8, Entsize: dynSize,
+		},
+		{
+			Name: shNames[shShstrtab], Type: uint32(elf.SHT_STRTAB),
+			Off: shstrtabOff, Size: uint64(len(shstrtab)), Addralign: 1,
+		},
+	})
+	return buf.Bytes()
+}
+
+// decode reads the metadata of a library written by library.encode, and
+// returns the section of its code, which is left in the file to be mapped.
+func decode(f *os.File, machine elf.Machine) (*library, *elf.Section, error) {
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ef.Class != elf.ELFCLASS64 || ef.Type != elf.ET_DYN || ef.Machine != machine {
+		return nil, nil, fmt.Errorf("not a %s shared library", machine)
+	}
+
+	metaSection, text := ef.Section(sectionMeta), ef.Section(sectionText)
+	if metaSection == nil || text == nil {
+		return nil, nil, errors.New("not emitted by aot.Emit")
+	}
+	if text.Offset%uint64(os.Getpagesize()) != 0 {
+		return nil, nil, fmt.Errorf("code isn't aligned to the page size %d", os.Getpagesize())
+	}
+	meta, err := io.ReadAll(metaSection.Open())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lib := &library{}
+	if len(meta) < len(lib.id) {
+		return nil, nil, errors.New("metadata too short")
+	}
+	copy(lib.id[:], meta)
+	lib.header = meta[len(lib.id):]
+	return lib, text, nil
+}
+
+// addString appends the NUL-terminated string to the string table, returning
+// its offset.
+func addString(table *[]byte, s string) uint32 {
+	offset := uint32(len(*table))
+	*table = append(append(*table, s...), 0)
+	return offset
+}
+
+func alignUp(n, align uint64) uint64 {
+	return (n + align - 1) &^ (align - 1)
+}
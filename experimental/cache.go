@@ -0,0 +1,125 @@
+package experimental
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// CompilationCacheListenerKey is a context.Context Value key. Its associated
+// value should be a CompilationCacheListener.
+//
+// See WithCompilationCacheListener
+type CompilationCacheListenerKey struct{}
+
+// WithCompilationCacheListener registers the given CompilationCacheListener
+// into the given context.Context. It is notified of the activity of the
+// compilation cache when the result is passed to wazero.Runtime
+// CompileModule.
+//
+// Here's an example that logs why a module was compiled again:
+//
+//	ctx = experimental.WithCompilationCacheListener(ctx,
+//		experimental.CompilationCacheListenerFunc(func(ctx context.Context, e experimental.CompilationCacheEvent) {
+//			if e.Type == experimental.CompilationCacheEventEvict {
+//				log.Printf("evicted %s: %s", e.Key, e.Reason)
+//			}
+//		}))
+//	compiled, _ := r.CompileModule(ctx, wasm)
+func WithCompilationCacheListener(ctx context.Context, listener CompilationCacheListener) context.Context {
+	return context.WithValue(ctx, CompilationCacheListenerKey{}, listener)
+}
+
+// CompilationCacheListener is notified of CompilationCacheEvent.
+//
+// Note: This is called synchronously while compiling, so implementations
+// should return quickly, e.g. by incrementing counters.
+type CompilationCacheListener interface {
+	// OnCompilationCacheEvent is called with the context.Context passed to
+	// wazero.Runtime CompileModule.
+	OnCompilationCacheEvent(ctx context.Context, event CompilationCacheEvent)
+}
+
+// CompilationCacheListenerFunc is a function that implements
+// CompilationCacheListener.
+type CompilationCacheListenerFunc func(ctx context.Context, event CompilationCacheEvent)
+
+// OnCompilationCacheEvent implements CompilationCacheListener.
+func (f CompilationCacheListenerFunc) OnCompilationCacheEvent(ctx context.Context, event CompilationCacheEvent) {
+	f(ctx, event)
+}
+
+// CompilationCacheEventType is the type of a CompilationCacheEvent.
+type CompilationCacheEventType uint8
+
+const (
+	// CompilationCacheEventHit is when a compiled module was found in the
+	// cache, so the module was not compiled.
+	CompilationCacheEventHit CompilationCacheEventType = iota + 1
+
+	// CompilationCacheEventMiss is when a compiled module was not found in
+	// the cache, so the module is compiled.
+	CompilationCacheEventMiss
+
+	// CompilationCacheEventStore is when a compiled module was added to the
+	// cache.
+	CompilationCacheEventStore
+
+	// CompilationCacheEventEvict is when a compiled module was removed from
+	// the cache, for example, because it was written by a different version
+	// of wazero, or to keep the cache within the limits it was created with.
+	// The Key of the latter is of the removed module, not the one compiled.
+	CompilationCacheEventEvict
+)
+
+// String returns the name of the event type, e.g. "hit".
+func (t CompilationCacheEventType) String() string {
+	switch t {
+	case CompilationCacheEventHit:
+		return "hit"
+	case CompilationCacheEventMiss:
+		return "miss"
+	case CompilationCacheEventStore:
+		return "store"
+	case CompilationCacheEventEvict:
+		return "evict"
+	}
+	return "unknown"
+}
+
+// CompilationCacheKey is the key of a compiled module in the cache.
+//
+// This is computed from the wasm binary and the settings which affect
+// compilation, such as whether function listeners are used. A module which is
+// compiled again despite an unchanged binary is likely compiled with
+// different settings.
+type CompilationCacheKey [32]byte
+
+// String returns the hex encoding of the key, which is also the file name of
+// the entry in a directory cache.
+func (k CompilationCacheKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// CompilationCacheEvent describes the activity of the compilation cache for
+// a module.
+type CompilationCacheEvent struct {
+	// Type is the type of the event.
+	Type CompilationCacheEventType
+
+	// Key is the key of the module in the cache.
+	Key CompilationCacheKey
+
+	// InMemory is true when the event is about the in-memory cache of the
+	// engine, and false when it is about the cache shared between runtimes,
+	// e.g. by wazero.NewCompilationCacheWithDir or
+	// wazero.NewCompilationCacheWithMemoryLimit.
+	InMemory bool
+
+	// Reason explains why an entry was evicted, or why a persisted entry
+	// could not be used, e.g. "written by a different version of wazero".
+	// This is empty when the event is self-explanatory.
+	Reason string
+
+	// Err is the error the cache returned, if any.
+	Err error
+}
@@ -0,0 +1,66 @@
+package experimental
+
+import "context"
+
+// callValuesKey is a context.Context Value key. Its associated value is a
+// callValues.
+type callValuesKey struct{}
+
+// callValues is never modified once in a context.Context, so it is safe to
+// read from any goroutine.
+type callValues map[interface{}]interface{}
+
+// WithCallValue returns a context.Context which carries `val` for `key`, to
+// be read with CallValue by host functions and listeners during calls made
+// with the result, e.g. api.Function Call.
+//
+// This is like context.WithValue, except it documents the guarantees wazero
+// makes about the context.Context of a call:
+//
+//   - Host functions (api.GoModuleFunction and api.GoFunction) called by the
+//     guest, directly or indirectly, receive the context.Context of the call,
+//     or one derived from it. This is regardless of the engine, even though
+//     the compiler calls host functions from native code.
+//   - FunctionListener Before, After and Abort receive the same
+//     context.Context as the host functions of the call.
+//   - A host function which calls a guest function with the context.Context
+//     it received, e.g. to call back into the guest, propagates the values to
+//     nested host functions and listeners.
+//   - Values are immutable, so they can be read from goroutines started by
+//     the call. A future asynchronous mode, which could resume a call on
+//     another goroutine, will keep these guarantees.
+//
+// Values set with WithCallValue are looked up in a single map, so the cost of
+// CallValue doesn't depend on the count of values, unlike context.Value.
+//
+// Here's an example that passes a request ID to a logging host function:
+//
+//	type requestIDKey struct{}
+//
+//	ctx = experimental.WithCallValue(ctx, requestIDKey{}, id)
+//	_, err := mod.ExportedFunction("handle").Call(ctx)
+//
+//	// In the host function:
+//	func log(ctx context.Context, mod api.Module, stack []uint64) {
+//		id, _ := experimental.CallValue(ctx, requestIDKey{}).(string)
+//		// ...
+//	}
+//
+// Note: The key must be comparable, and like context.WithValue, should be of
+// an unexported type to avoid collisions.
+func WithCallValue(ctx context.Context, key, val interface{}) context.Context {
+	parent, _ := ctx.Value(callValuesKey{}).(callValues)
+	values := make(callValues, len(parent)+1)
+	for k, v := range parent {
+		values[k] = v
+	}
+	values[key] = val
+	return context.WithValue(ctx, callValuesKey{}, values)
+}
+
+// CallValue returns the value associated with `key` by WithCallValue, or nil
+// if there is none.
+func CallValue(ctx context.Context, key interface{}) interface{} {
+	values, _ := ctx.Value(callValuesKey{}).(callValues)
+	return values[key]
+}
@@ -0,0 +1,96 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+type callValueKey struct{}
+
+func TestWithCallValue(t *testing.T) {
+	ctx := experimental.WithCallValue(context.Background(), callValueKey{}, "a")
+	ctx = experimental.WithCallValue(ctx, 1, "b")
+	ctx2 := experimental.WithCallValue(ctx, callValueKey{}, "c")
+
+	require.Equal(t, "a", experimental.CallValue(ctx, callValueKey{}))
+	require.Equal(t, "b", experimental.CallValue(ctx, 1))
+	require.Equal(t, "c", experimental.CallValue(ctx2, callValueKey{}))
+	require.Equal(t, "b", experimental.CallValue(ctx2, 1))
+	require.Nil(t, experimental.CallValue(context.Background(), callValueKey{}))
+}
+
+// TestWithCallValue_propagation ensures call values reach host functions and
+// listeners, including those nested in a host function calling the guest.
+func TestWithCallValue_propagation(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			var seen []string
+			record := func(where string, ctx context.Context) {
+				v, _ := experimental.CallValue(ctx, callValueKey{}).(string)
+				seen = append(seen, where+":"+v)
+			}
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module) {
+				record("outer", ctx)
+				_, err := mod.ExportedFunction("inner").Call(ctx)
+				require.NoError(t, err)
+			}).Export("outer").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context) {
+				record("leaf", ctx)
+			}).Export("leaf").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			bin := binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection: []wasm.FunctionType{{}},
+				ImportSection: []wasm.Import{
+					{Module: "env", Name: "outer", Type: wasm.ExternTypeFunc, DescFunc: 0},
+					{Module: "env", Name: "leaf", Type: wasm.ExternTypeFunc, DescFunc: 0},
+				},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection: []wasm.Code{
+					{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+				},
+				ExportSection: []wasm.Export{
+					{Name: "run", Type: wasm.ExternTypeFunc, Index: 2},
+					{Name: "inner", Type: wasm.ExternTypeFunc, Index: 3},
+				},
+			})
+			compileCtx := context.WithValue(ctx, experimental.FunctionListenerFactoryKey{},
+				experimental.FunctionListenerFactoryFunc(func(def api.FunctionDefinition) experimental.FunctionListener {
+					return experimental.FunctionListenerFunc(func(ctx context.Context, _ api.Module, def api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+						record("before "+def.ExportNames()[0], ctx)
+					})
+				}))
+			compiled, err := r.CompileModule(compileCtx, bin)
+			require.NoError(t, err)
+			mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+			require.NoError(t, err)
+
+			callCtx := experimental.WithCallValue(ctx, callValueKey{}, "v")
+			_, err = mod.ExportedFunction("run").Call(callCtx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"before run:v", "outer:v", "before inner:v", "leaf:v"}, seen)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package experimental
+
+import "context"
+
+// DeadCodeEliminationKey is a context.Context Value key. Its associated value
+// should be a []string of export names.
+//
+// See WithDeadCodeElimination
+type DeadCodeEliminationKey struct{}
+
+// WithDeadCodeElimination registers the exports the embedder will call into
+// the given context.Context. Modules compiled with the result, e.g. by
+// Runtime CompileModule, only keep these exports and what they transitively
+// use. This reduces the compile time and code size of large binaries, such as
+// Emscripten outputs, which define many functions only some exports need.
+//
+// Here's an example that only keeps the "_start" function:
+//
+//	ctx = experimental.WithDeadCodeElimination(ctx, "_start")
+//	compiled, err := r.CompileModule(ctx, wasm)
+//
+// Imports are pruned too, so the result can be instantiated without the host
+// functions of unreachable code. The host modules already instantiated in the
+// runtime prune further: calls to a function import which names one of them,
+// but which it doesn't export, are replaced with traps, instead of failing
+// at instantiation. CompileModule fails if such an import is used otherwise,
+// e.g. by "ref.func".
+//
+// # Notes
+//
+//   - CompileModule fails if an export doesn't exist.
+//   - The binary is decoded with the features of the runtime, as configured
+//     by wazero.RuntimeConfig WithCoreFeatures.
+//   - The memory, data segments and start function are always kept.
+//   - Function names are removed, so Intrinsic and stack traces can't use
+//     them. See wasmbin.Extract for details.
+func WithDeadCodeElimination(ctx context.Context, exports ...string) context.Context {
+	return context.WithValue(ctx, DeadCodeEliminationKey{}, exports)
+}
@@ -0,0 +1,217 @@
+// Package dynconfig includes an experimental host module, which allows a
+// trusted guest to read configuration values that the host can change while
+// it runs, unlike the environment variables of wazero.ModuleConfig, which
+// are a snapshot taken when the module is instantiated.
+//
+// The guest imports the functions "config_get" and "config_watch" from the
+// module "wazero_config":
+//
+//	(import "wazero_config" "config_get"
+//	  (func $config_get (param $key i32) (param $key_len i32) (param $buf i32) (param $buf_len i32) (param $result.value_len i32) (result (;errno;) i32)))
+//	(import "wazero_config" "config_watch"
+//	  (func $config_watch (param $result.fd i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1:
+//
+//   - config_get writes the value of the key to buf, and its length to
+//     result.value_len. The result is ENOENT if the key isn't set, or ERANGE
+//     if buf_len is too small, in which case only result.value_len is
+//     written, so that the guest can retry with a larger buffer.
+//   - config_watch opens a file descriptor which becomes readable when a
+//     value changes. The guest can wait for it with "poll_oneoff", and reads
+//     it with "fd_read" to reset it, like eventfd on Linux: eight bytes are
+//     read, which are the little-endian count of changes since the previous
+//     read. Close it with "fd_close".
+//
+// Only modules granted Capability with wazero.ModuleConfig WithCapabilities
+// can call these functions. Others fail with wazero.ErrCapabilityDenied.
+//
+// Note: This is experimental, and may change or be removed.
+package dynconfig
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name guests import "config_get" and
+// "config_watch" from.
+const ModuleName = "wazero_config"
+
+// Capability is the token a module must be granted, with
+// wazero.ModuleConfig WithCapabilities, to call the functions in ModuleName.
+const Capability = "config.read"
+
+// Store holds the configuration values guests read. It is safe for
+// concurrent use, so the host can change values while guests run.
+type Store struct {
+	mux      sync.RWMutex
+	values   map[string]string
+	watchers []*internalsys.EventFile
+}
+
+// NewStore returns a Store with a copy of the initial values, which may be
+// nil.
+func NewStore(initial map[string]string) *Store {
+	values := make(map[string]string, len(initial))
+	for k, v := range initial {
+		values[k] = v
+	}
+	return &Store{values: values}
+}
+
+// Get returns the value of the key, and whether it was set.
+func (s *Store) Get(key string) (string, bool) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set sets the value of the key, notifying watching guests if it changed.
+func (s *Store) Set(key, value string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if v, ok := s.values[key]; ok && v == value {
+		return
+	}
+	s.values[key] = value
+	s.notify()
+}
+
+// Delete removes the key, notifying watching guests if it was set.
+func (s *Store) Delete(key string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.values[key]; !ok {
+		return
+	}
+	delete(s.values, key)
+	s.notify()
+}
+
+// notify must be called while holding the write lock. This drops watchers
+// the guest closed.
+func (s *Store) notify() {
+	watchers := s.watchers[:0]
+	for _, w := range s.watchers {
+		if w.Notify() {
+			watchers = append(watchers, w)
+		}
+	}
+	for i := len(watchers); i < len(s.watchers); i++ {
+		s.watchers[i] = nil // for GC
+	}
+	s.watchers = watchers
+}
+
+func (s *Store) watch() *internalsys.EventFile {
+	f := internalsys.NewEventFile()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.watchers = append(s.watchers, f)
+	return f
+}
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime, store *Store) {
+	if _, err := Instantiate(ctx, r, store); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime, reading
+// values from the store.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime, store *Store) (api.Closer, error) {
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(store.configGet),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("key", "key_len", "buf", "buf_len", "result.value_len").
+		WithResultNames("errno").
+		RequireCapability(Capability).
+		Export("config_get").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(store.configWatch),
+			[]api.ValueType{api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("result.fd").
+		WithResultNames("errno").
+		RequireCapability(Capability).
+		Export("config_watch").
+		Instantiate(ctx)
+}
+
+func (s *Store) configGet(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(s.configGetFn(mod, stack))
+}
+
+func (s *Store) configGetFn(mod api.Module, params []uint64) wasip1.Errno {
+	key, keyLen := uint32(params[0]), uint32(params[1])
+	buf, bufLen := uint32(params[2]), uint32(params[3])
+	resultValueLen := uint32(params[4])
+
+	mem := mod.Memory()
+	keyBytes, ok := mem.Read(key, keyLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	value, ok := s.Get(string(keyBytes))
+	if !ok {
+		return wasip1.ErrnoNoent
+	}
+	if !mem.WriteUint32Le(resultValueLen, uint32(len(value))) {
+		return wasip1.ErrnoFault
+	}
+	if uint32(len(value)) > bufLen {
+		return wasip1.ErrnoRange
+	}
+	if !mem.WriteString(buf, value) {
+		return wasip1.ErrnoFault
+	}
+	return wasip1.ErrnoSuccess
+}
+
+func (s *Store) configWatch(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(s.configWatchFn(mod, stack))
+}
+
+func (s *Store) configWatchFn(mod api.Module, params []uint64) wasip1.Errno {
+	resultFd := uint32(params[0])
+
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+
+	// Check the result can be written before opening the descriptor.
+	mem := mod.Memory()
+	if _, ok := mem.ReadUint32Le(resultFd); !ok {
+		return wasip1.ErrnoFault
+	}
+
+	f := s.watch()
+	fd, errno := sysCtx.FS().InsertFile(f, "event")
+	if errno != 0 {
+		_ = f.Close()
+		return wasip1.ToErrno(errno)
+	}
+	mem.WriteUint32Le(resultFd, uint32(fd))
+	return wasip1.ErrnoSuccess
+}
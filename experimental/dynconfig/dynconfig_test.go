@@ -0,0 +1,146 @@
+package dynconfig_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/dynconfig"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// dynconfigWasm exports functions "config_get" and "config_watch", which
+// call the imported ones, and its memory.
+var dynconfigWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+		{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: dynconfig.ModuleName, Name: "config_get", DescFunc: 0},
+		{Type: wasm.ExternTypeFunc, Module: dynconfig.ModuleName, Name: "config_watch", DescFunc: 1},
+	},
+	FunctionSection: []wasm.Index{0, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3, wasm.OpcodeLocalGet, 4,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	ExportSection: []wasm.Export{
+		{Name: "config_get", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "config_watch", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestInstantiate(t *testing.T) {
+	store := dynconfig.NewStore(map[string]string{"log.level": "info"})
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	dynconfig.MustInstantiate(testCtx, r, store)
+
+	t.Run("denied", func(t *testing.T) {
+		mod, err := r.InstantiateWithConfig(testCtx, dynconfigWasm, wazero.NewModuleConfig().WithName("untrusted"))
+		require.NoError(t, err)
+
+		_, err = mod.ExportedFunction("config_watch").Call(testCtx, 0)
+		require.ErrorIs(t, err, wazero.ErrCapabilityDenied)
+	})
+
+	mod, err := r.InstantiateWithConfig(testCtx, dynconfigWasm, wazero.NewModuleConfig().
+		WithCapabilities(dynconfig.Capability))
+	require.NoError(t, err)
+	mem := mod.Memory()
+
+	const key, buf, resultLen = 0, 64, 128
+	get := func(k string, bufLen uint32) (wasip1.Errno, uint32) {
+		require.True(t, mem.WriteString(key, k))
+		require.True(t, mem.WriteUint32Le(resultLen, 0))
+		results, err := mod.ExportedFunction("config_get").Call(testCtx, key, uint64(len(k)), buf, uint64(bufLen), resultLen)
+		require.NoError(t, err)
+		n, ok := mem.ReadUint32Le(resultLen)
+		require.True(t, ok)
+		return wasip1.Errno(results[0]), n
+	}
+
+	t.Run("config_get", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			key           string
+			bufLen        uint32
+			expectedErrno wasip1.Errno
+			expectedLen   uint32
+		}{
+			{name: "found", key: "log.level", bufLen: 32, expectedLen: 4},
+			{name: "buffer too small", key: "log.level", bufLen: 3, expectedErrno: wasip1.ErrnoRange, expectedLen: 4},
+			{name: "not found", key: "missing", bufLen: 32, expectedErrno: wasip1.ErrnoNoent},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				errno, n := get(tc.key, tc.bufLen)
+				require.Equal(t, tc.expectedErrno, errno)
+				require.Equal(t, tc.expectedLen, n)
+			})
+		}
+
+		value, ok := mem.Read(buf, 4)
+		require.True(t, ok)
+		require.Equal(t, "info", string(value))
+	})
+
+	t.Run("config_watch", func(t *testing.T) {
+		const resultFd = 256
+		results, err := mod.ExportedFunction("config_watch").Call(testCtx, resultFd)
+		require.NoError(t, err)
+		require.Equal(t, wasip1.ErrnoSuccess, wasip1.Errno(results[0]))
+		fd, ok := mem.ReadUint32Le(resultFd)
+		require.True(t, ok)
+
+		f, ok := mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(int32(fd))
+		require.True(t, ok)
+		ready, errno := f.File.Poll(fsapi.POLLIN, 0)
+		require.EqualErrno(t, 0, errno)
+		require.False(t, ready)
+
+		store.Set("log.level", "info") // unchanged
+		store.Set("log.level", "debug")
+		store.Delete("missing") // unchanged
+		store.Delete("log.level")
+
+		ready, errno = f.File.Poll(fsapi.POLLIN, 0)
+		require.EqualErrno(t, 0, errno)
+		require.True(t, ready)
+
+		count := make([]byte, 8)
+		n, errno := f.File.Read(count)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 8, n)
+		require.Equal(t, []byte{2, 0, 0, 0, 0, 0, 0, 0}, count)
+
+		getErrno, _ := get("log.level", 32)
+		require.Equal(t, wasip1.ErrnoNoent, getErrno)
+
+		// Closing the descriptor stops notifications.
+		require.EqualErrno(t, 0, mod.(*wasm.ModuleInstance).Sys.FS().CloseFile(int32(fd)))
+		store.Set("log.level", "warn")
+	})
+}
@@ -0,0 +1,23 @@
+package experimental
+
+import "github.com/tetratelabs/wazero/internal/platform"
+
+// ForbidExecutableMemory makes mapping writable, executable memory fail in
+// the whole process until `release` is called, as a runtime assertion for
+// hosts which forbid generating code, such as iOS. Calling `release` more
+// than once has no effect.
+//
+// While this is in effect, a runtime using the compiler fails to compile
+// modules, with an error, instead of generating code. Runtimes configured
+// with wazero.RuntimeConfig WithExecutableMemoryForbidden aren't affected,
+// and neither are modules loaded by experimental/aot, whose code is mapped
+// read-only from a file.
+//
+// Here's an example which asserts that no code is generated:
+//
+//	release := experimental.ForbidExecutableMemory()
+//	defer release()
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithExecutableMemoryForbidden(true))
+func ForbidExecutableMemory() (release func()) {
+	return platform.ForbidExecutableMemory()
+}
@@ -0,0 +1,93 @@
+package experimental
+
+import "github.com/tetratelabs/wazero/api"
+
+// CoreFeaturesThreads enables threads instructions ("threads"), which are
+// shared memories and atomic instructions, including memory.atomic.wait32,
+// memory.atomic.wait64 and memory.atomic.notify.
+//
+// Here's an example of enabling it:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads))
+//
+// # Notes
+//
+//   - The instruction list is too long to enumerate in godoc.
+//     See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+//   - Guests don't spawn threads: a host runs each thread by calling exports
+//     of its own module instance, on its own goroutine. The instances share
+//     memory by importing the same shared memory, e.g. one exported by a
+//     module instantiated before them.
+//   - A shared memory is allocated with capacity for its maximum pages, so
+//     that it never moves. Consider lowering the maximum of binaries which
+//     declare a theoretical one, such as 4GiB.
+//   - The compiler implements atomic instructions by calling into Go, so
+//     they are slower than other memory instructions.
+const CoreFeaturesThreads = api.CoreFeatureSIMD << 1
+
+// CoreFeaturesCustomPageSizes enables memories to declare a page size other
+// than 64KiB ("custom-page-sizes"), which is a power of two, e.g. 1 byte or
+// 4KiB. This allows guests with a small footprint, as memory grows by pages.
+//
+// Here's an example of enabling it:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesCustomPageSizes))
+//
+// # Notes
+//
+//   - The page size of a memory applies to memory.size and memory.grow, as
+//     well as the limits of its type, e.g. api.MemoryDefinition Min. The
+//     limit of RuntimeConfig.WithMemoryLimitPages is still in 64KiB pages.
+//   - RuntimeConfig.WithMemoryCapacityFromMax doesn't apply to memories with
+//     a custom page size, which are allocated with their minimum size.
+//   - See https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md
+const CoreFeaturesCustomPageSizes = api.CoreFeatureSIMD << 2
+
+// CoreFeaturesWideArithmetic enables 128-bit integer arithmetic on pairs of
+// i64 values ("wide-arithmetic"), which are the instructions i64.add128,
+// i64.sub128, i64.mul_wide_s and i64.mul_wide_u. These are useful for
+// cryptography and arbitrary precision arithmetic.
+//
+// Here's an example of enabling it:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesWideArithmetic))
+//
+// See https://github.com/WebAssembly/wide-arithmetic/blob/main/proposals/wide-arithmetic/Overview.md
+const CoreFeaturesWideArithmetic = api.CoreFeatureSIMD << 3
+
+// CoreFeaturesRelaxedSIMD enables vector instructions whose results may
+// depend on the hardware ("relaxed-simd"), such as f32x4.relaxed_madd and
+// i16x8.relaxed_dot_i8x16_i7x16_s. Machine learning kernels use them for
+// performance. This requires api.CoreFeatureSIMD.
+//
+// Here's an example of enabling it:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD))
+//
+// # Notes
+//
+//   - Results are deterministic, as each instruction is implemented with the
+//     equivalent SIMD instructions, e.g. f32x4.relaxed_madd is a multiply
+//     then an add, without fusing, and f32x4.relaxed_min is f32x4.min.
+//     This is one of the choices the proposal allows, so it is the same on
+//     all platforms and engines, but it isn't faster than the equivalent.
+//   - See https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+const CoreFeaturesRelaxedSIMD = api.CoreFeatureSIMD << 4
+
+// CoreFeaturesExtendedConst enables the instructions i32.add, i32.sub,
+// i32.mul, i64.add, i64.sub and i64.mul in constant expressions
+// ("extended-const"), such as global initializers and the offsets of data and
+// element segments. LLVM uses these when dynamic linking is enabled, e.g. to
+// add a global base address to an offset.
+//
+// Here's an example of enabling it:
+//
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+//		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesExtendedConst))
+//
+// See https://github.com/WebAssembly/extended-const/blob/main/proposals/extended-const/Overview.md
+const CoreFeaturesExtendedConst = api.CoreFeatureSIMD << 5
@@ -0,0 +1,59 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ImportedGlobalsKey is a context.Context Value key. Its associated value
+// should be an ImportedGlobals.
+//
+// See WithImportedGlobals
+type ImportedGlobalsKey struct{}
+
+// ImportedGlobals provides the values of immutable globals which a module
+// imports, but no instantiated module exports. For example, this implements
+// the imported string constants of the JS string builtins proposal.
+//
+// See the package experimental/jsstring
+type ImportedGlobals interface {
+	// ImportedGlobal returns the value of the immutable global `name`,
+	// imported from `moduleName` with the type `valueType`, or false if it
+	// isn't provided.
+	//
+	// The value is encoded the same as api.Global Get, e.g. api.EncodeF64.
+	ImportedGlobal(moduleName, name string, valueType api.ValueType) (value uint64, ok bool)
+}
+
+// ImportedGlobalsFunc is a convenience for defining ImportedGlobals with a
+// function.
+type ImportedGlobalsFunc func(moduleName, name string, valueType api.ValueType) (value uint64, ok bool)
+
+// ImportedGlobal implements ImportedGlobals.ImportedGlobal
+func (f ImportedGlobalsFunc) ImportedGlobal(moduleName, name string, valueType api.ValueType) (uint64, bool) {
+	return f(moduleName, name, valueType)
+}
+
+// WithImportedGlobals registers the given ImportedGlobals into the given
+// context.Context. It applies to modules instantiated with the result.
+//
+// Here's an example that provides the version of the host to guests:
+//
+//	ctx = experimental.WithImportedGlobals(ctx, experimental.ImportedGlobalsFunc(
+//		func(moduleName, name string, valueType api.ValueType) (uint64, bool) {
+//			if moduleName == "host" && name == "version" && valueType == api.ValueTypeI32 {
+//				return 3, true
+//			}
+//			return 0, false
+//		}))
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+//
+// Note: The globals are owned by the module instantiated, so they are
+// released when it's closed.
+func WithImportedGlobals(ctx context.Context, globals ImportedGlobals) context.Context {
+	if globals != nil {
+		return context.WithValue(ctx, ImportedGlobalsKey{}, globals)
+	}
+	return ctx
+}
@@ -0,0 +1,161 @@
+// Package hostbatch allows a guest to make many small host function calls in
+// a single crossing of the guest-host boundary.
+//
+// Chatty guest-host interfaces, e.g. a guest emitting a host call per log
+// field or per drawing primitive, spend most of their time crossing the
+// boundary rather than in the host functions themselves. With this package,
+// the guest packs the calls into a buffer in its memory, and calls a single
+// exported function which dispatches all of them on the Go side.
+//
+// # Buffer format
+//
+// The buffer is a sequence of records, each of which is a sequence of
+// little-endian uint64 slots:
+//
+//	[function index] [slot 0] [slot 1] ... [slot N-1]
+//
+// where N is max(Func.ParamCount, Func.ResultCount) of the function at the
+// index. The guest writes the parameters to the slots, and the results are
+// written back to the leading slots of the same record, like
+// api.Function CallWithStack.
+package hostbatch
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Func is a host function which can be called in a batch.
+type Func struct {
+	// ParamCount is the number of uint64 params Fn reads from the stack.
+	ParamCount uint32
+
+	// ResultCount is the number of uint64 results Fn writes to the stack.
+	ResultCount uint32
+
+	// Fn is the host function. The stack passed to it has the length of
+	// max(ParamCount, ResultCount).
+	Fn api.GoModuleFunction
+}
+
+// Dispatcher dispatches the batched calls to the host functions. The function
+// index in a record is the index of the Func passed to NewDispatcher.
+type Dispatcher struct {
+	funcs    []Func
+	maxSlots uint32
+	// stacks pools the stacks passed to the host functions, so that
+	// dispatching doesn't allocate. This isn't a single stack as batches
+	// can be dispatched concurrently, or reentrantly by a host function.
+	stacks sync.Pool
+}
+
+// NewDispatcher returns a Dispatcher of the given host functions.
+func NewDispatcher(funcs ...Func) *Dispatcher {
+	d := &Dispatcher{funcs: funcs}
+	for i := range funcs {
+		if n := funcs[i].slots(); n > d.maxSlots {
+			d.maxSlots = n
+		}
+	}
+	d.stacks.New = func() interface{} {
+		stack := make([]uint64, d.maxSlots)
+		return &stack
+	}
+	return d
+}
+
+func (f *Func) slots() uint32 {
+	if f.ParamCount > f.ResultCount {
+		return f.ParamCount
+	}
+	return f.ResultCount
+}
+
+// Export exports the function `name` of the signature (ptr i32, count i32) -> (i32)
+// to the builder, which dispatches `count` records in the buffer at `ptr` of
+// the calling module's memory. See Dispatch for the result.
+//
+// For example, this exports "env.call_batch":
+//
+//	d := hostbatch.NewDispatcher(funcs...)
+//	_, err := d.Export(r.NewHostModuleBuilder("env"), "call_batch").Instantiate(ctx)
+func (d *Dispatcher) Export(builder wazero.HostModuleBuilder, name string) wazero.HostModuleBuilder {
+	return builder.NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(d.call), []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, []api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("ptr", "count").
+		WithResultNames("dispatched").
+		Export(name)
+}
+
+func (d *Dispatcher) call(ctx context.Context, mod api.Module, stack []uint64) {
+	ptr, count := api.DecodeU32(stack[0]), api.DecodeU32(stack[1])
+	var dispatched uint32
+	if mem := mod.Memory(); mem != nil && ptr <= mem.Size() {
+		// The buffer is a view of the memory, so the results are written back to it.
+		buf, _ := mem.Read(ptr, mem.Size()-ptr)
+		dispatched = d.dispatch(ctx, mod, buf, count, mem, ptr)
+	}
+	stack[0] = api.EncodeU32(dispatched)
+}
+
+// Dispatch calls the host functions of `count` records packed in `buf`, in
+// order, and writes their results back to `buf`. The module `mod` is passed
+// to the host functions as the caller.
+//
+// This returns the number of dispatched records. It is less than `count` when
+// a record has an unknown function index or exceeds `buf`, in which case that
+// record and the rest are not dispatched.
+//
+// Dispatch can also be used by the host to run a buffer packed by the guest
+// without it calling the function exported by Export. In that case, `buf`
+// must not be a view of a memory which the host functions can grow, as
+// growing it can move its contents.
+func (d *Dispatcher) Dispatch(ctx context.Context, mod api.Module, buf []byte, count uint32) uint32 {
+	return d.dispatch(ctx, mod, buf, count, nil, 0)
+}
+
+// dispatch implements Dispatch. When `mem` is not nil, `buf` is the view of
+// `mem` at `ptr`, which is read again after each call, as the host function
+// may have grown the memory, which can move its contents.
+func (d *Dispatcher) dispatch(ctx context.Context, mod api.Module, buf []byte, count uint32, mem api.Memory, ptr uint32) uint32 {
+	stackPtr := d.stacks.Get().(*[]uint64)
+	defer d.stacks.Put(stackPtr)
+	stack := *stackPtr
+
+	var offset uint64
+	for i := uint32(0); i < count; i++ {
+		if offset+8 > uint64(len(buf)) {
+			return i
+		}
+		index := binary.LittleEndian.Uint64(buf[offset:])
+		if index >= uint64(len(d.funcs)) {
+			return i
+		}
+		f := &d.funcs[index]
+		n := f.slots()
+		slots := offset + 8
+		end := slots + uint64(n)*8
+		if end > uint64(len(buf)) {
+			return i
+		}
+
+		fStack := stack[:n]
+		for j := range fStack {
+			fStack[j] = binary.LittleEndian.Uint64(buf[slots+uint64(j)*8:])
+		}
+		f.Fn.Call(ctx, mod, fStack)
+		if mem != nil {
+			// Memory never shrinks, so the record is still in bounds.
+			buf, _ = mem.Read(ptr, mem.Size()-ptr)
+		}
+		for j := uint32(0); j < f.ResultCount; j++ {
+			binary.LittleEndian.PutUint64(buf[slots+uint64(j)*8:], fStack[j])
+		}
+		offset = end
+	}
+	return count
+}
@@ -0,0 +1,195 @@
+package hostbatch_test
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/hostbatch"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func newDispatcher(logged *[]uint64) *hostbatch.Dispatcher {
+	return hostbatch.NewDispatcher(
+		hostbatch.Func{ParamCount: 2, ResultCount: 1, Fn: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = stack[0] + stack[1]
+		})},
+		hostbatch.Func{ParamCount: 1, Fn: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			*logged = append(*logged, stack[0])
+		})},
+		hostbatch.Func{ResultCount: 2, Fn: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0], stack[1] = 1, 2
+		})},
+	)
+}
+
+func pack(records ...[]uint64) (buf []byte) {
+	for _, r := range records {
+		for _, v := range r {
+			buf = binary.LittleEndian.AppendUint64(buf, v)
+		}
+	}
+	return
+}
+
+// callBatchWasm imports "env.call_batch", and exports it as "run", with its
+// memory.
+var callBatchWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	ImportSection:   []wasm.Import{{Module: "env", Name: "call_batch", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	FunctionSection: []wasm.Index{0},
+	MemorySection:   &wasm.Memory{Min: 1},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{
+		{Name: "run", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestDispatcher_Export(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	var logged []uint64
+	_, err := newDispatcher(&logged).Export(r.NewHostModuleBuilder("env"), "call_batch").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	m, err := r.Instantiate(testCtx, callBatchWasm)
+	require.NoError(t, err)
+
+	const ptr = 16
+	buf := pack([]uint64{0, 40, 2}, []uint64{1, 100}, []uint64{2, 0, 0}, []uint64{1, 200})
+	require.True(t, m.Memory().Write(ptr, buf))
+
+	res, err := m.ExportedFunction("run").Call(testCtx, ptr, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), res[0])
+	require.Equal(t, []uint64{100, 200}, logged)
+
+	actual, ok := m.Memory().Read(ptr, uint32(len(buf)))
+	require.True(t, ok)
+	require.Equal(t, pack([]uint64{0, 42, 2}, []uint64{1, 100}, []uint64{2, 1, 2}, []uint64{1, 200}), actual)
+
+	// Out of memory bounds.
+	res, err = m.ExportedFunction("run").Call(testCtx, uint64(m.Memory().Size()-8), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), res[0])
+	res, err = m.ExportedFunction("run").Call(testCtx, uint64(m.Memory().Size()+8), 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), res[0])
+}
+
+func TestDispatcher_Export_memoryGrow(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	// The function grows the memory, which moves its contents, before its
+	// result is written back.
+	d := hostbatch.NewDispatcher(hostbatch.Func{ResultCount: 1, Fn: api.GoModuleFunc(func(_ context.Context, mod api.Module, stack []uint64) {
+		pages, _ := mod.Memory().Grow(10)
+		stack[0] = uint64(pages)
+	})})
+	_, err := d.Export(r.NewHostModuleBuilder("env"), "call_batch").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	m, err := r.Instantiate(testCtx, callBatchWasm)
+	require.NoError(t, err)
+
+	const ptr = 16
+	buf := pack([]uint64{0, 0}, []uint64{0, 0})
+	require.True(t, m.Memory().Write(ptr, buf))
+
+	res, err := m.ExportedFunction("run").Call(testCtx, ptr, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), res[0])
+
+	actual, ok := m.Memory().Read(ptr, uint32(len(buf)))
+	require.True(t, ok)
+	require.Equal(t, pack([]uint64{0, 1}, []uint64{0, 11}), actual)
+}
+
+func TestDispatcher_Dispatch_allocs(t *testing.T) {
+	var logged []uint64
+	d := newDispatcher(&logged)
+	buf := pack([]uint64{0, 1, 2}, []uint64{2, 0, 0})
+	allocs := testing.AllocsPerRun(100, func() {
+		d.Dispatch(testCtx, nil, buf, 2)
+	})
+	require.Equal(t, float64(0), allocs)
+}
+
+func TestDispatcher_Dispatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		buf, expected []byte
+		count         uint32
+		expectedCount uint32
+		expectedLog   []uint64
+	}{
+		{
+			name:          "empty",
+			expectedCount: 0,
+		},
+		{
+			name:          "all",
+			buf:           pack([]uint64{1, 1}, []uint64{0, 1, 2}),
+			count:         2,
+			expected:      pack([]uint64{1, 1}, []uint64{0, 3, 2}),
+			expectedCount: 2,
+			expectedLog:   []uint64{1},
+		},
+		{
+			name:          "partial count",
+			buf:           pack([]uint64{1, 1}, []uint64{1, 2}),
+			count:         1,
+			expected:      pack([]uint64{1, 1}, []uint64{1, 2}),
+			expectedCount: 1,
+			expectedLog:   []uint64{1},
+		},
+		{
+			name:          "unknown function",
+			buf:           pack([]uint64{1, 1}, []uint64{3, 2}, []uint64{1, 3}),
+			count:         3,
+			expected:      pack([]uint64{1, 1}, []uint64{3, 2}, []uint64{1, 3}),
+			expectedCount: 1,
+			expectedLog:   []uint64{1},
+		},
+		{
+			name:          "truncated slots",
+			buf:           pack([]uint64{1, 1}, []uint64{0, 1}),
+			count:         2,
+			expected:      pack([]uint64{1, 1}, []uint64{0, 1}),
+			expectedCount: 1,
+			expectedLog:   []uint64{1},
+		},
+		{
+			name:          "truncated index",
+			buf:           pack([]uint64{1, 1})[:12],
+			count:         1,
+			expected:      pack([]uint64{1, 1})[:12],
+			expectedCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var logged []uint64
+			d := newDispatcher(&logged)
+			require.Equal(t, tc.expectedCount, d.Dispatch(testCtx, nil, tc.buf, tc.count))
+			require.Equal(t, tc.expected, tc.buf)
+			require.Equal(t, tc.expectedLog, logged)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// IntrinsicsKey is a context.Context Value key. Its associated value should
+// be a []Intrinsic.
+//
+// See WithIntrinsics
+type IntrinsicsKey struct{}
+
+// Intrinsic replaces a well-known guest function, such as "memcpy" of
+// wasi-libc, with a Go implementation which is faster than running the
+// guest's code.
+//
+// A function defined by the guest is replaced when its name in the name
+// section is Name, and its signature is ParamTypes and ResultTypes. When
+// BodyHash is set, the SHA-256 of its code must also match, so that only the
+// exact function the Go implementation was verified against is replaced.
+//
+// See the package experimental/intrinsics for implementations of functions
+// in wasi-libc.
+type Intrinsic struct {
+	// Name is the name of the function in the name section, e.g. "memcpy".
+	Name string
+
+	// ParamTypes and ResultTypes are the signature of the function.
+	ParamTypes, ResultTypes []api.ValueType
+
+	// BodyHash is the SHA-256 of the function's code, or nil to match any.
+	// See intrinsics.BodyHash
+	BodyHash []byte
+
+	// Func is called instead of the guest function, with the module which
+	// defines it, even when another module calls it, e.g. via an import or a
+	// table. As it replaces guest code, host call rate limits don't apply, and
+	// signals aren't delivered before the call.
+	Func api.GoModuleFunction
+}
+
+// WithIntrinsics registers the intrinsics into the given context.Context.
+// They apply to modules compiled with the result, e.g. Runtime
+// CompileModule.
+//
+// Here's an example that replaces string functions of wasi-libc:
+//
+//	ctx = experimental.WithIntrinsics(ctx, intrinsics.LibC...)
+//	compiled, err := r.CompileModule(ctx, wasm)
+//
+// # Notes
+//
+//   - Binaries stripped of their name section have nothing to replace.
+//   - Functions replaced still exist, e.g. they can be exported or called
+//     indirectly, but run the Go implementation.
+//   - Engines which can't call Go functions defined by guests, such as the
+//     optimizing compiler, run the guest's code instead.
+//   - The compilation of a module with functions replaced isn't reused, e.g.
+//     by a wazero.CompilationCache, as the Go implementations can't be
+//     compared.
+func WithIntrinsics(ctx context.Context, intrinsics ...Intrinsic) context.Context {
+	return context.WithValue(ctx, IntrinsicsKey{}, intrinsics)
+}
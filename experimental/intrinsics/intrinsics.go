@@ -0,0 +1,144 @@
+// Package intrinsics includes experimental.Intrinsic implementations of
+// well-known guest functions, such as the string and math functions of
+// wasi-libc.
+//
+// Guests compiled without the bulk memory feature copy and fill memory one
+// word at a time, so replacing these functions with Go implementations
+// speeds up guests which move large buffers.
+//
+// Here's an example that replaces them, only if their code is exactly the
+// one in a known build of wasi-libc:
+//
+//	hash, err := intrinsics.BodyHash(known, "memcpy")
+//	// handle error
+//	memcpy := intrinsics.Memcpy
+//	memcpy.BodyHash = hash
+//	ctx = experimental.WithIntrinsics(ctx, memcpy)
+//
+// Note: This is experimental, and may change or be removed.
+package intrinsics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+const i32 = api.ValueTypeI32
+
+// Memcpy replaces "memcpy", which copies `n` bytes from `src` to `dst`, and
+// returns `dst`.
+//
+//	void *memcpy(void *dst, const void *src, size_t n);
+var Memcpy = experimental.Intrinsic{
+	Name:        "memcpy",
+	ParamTypes:  []api.ValueType{i32, i32, i32},
+	ResultTypes: []api.ValueType{i32},
+	Func:        api.GoModuleFunc(memmove),
+}
+
+// Memmove replaces "memmove", which is like "memcpy", except `src` and `dst`
+// may overlap.
+//
+//	void *memmove(void *dst, const void *src, size_t n);
+var Memmove = experimental.Intrinsic{
+	Name:        "memmove",
+	ParamTypes:  []api.ValueType{i32, i32, i32},
+	ResultTypes: []api.ValueType{i32},
+	Func:        api.GoModuleFunc(memmove),
+}
+
+// Memset replaces "memset", which fills `n` bytes at `dst` with the byte
+// `c`, and returns `dst`.
+//
+//	void *memset(void *dst, int c, size_t n);
+var Memset = experimental.Intrinsic{
+	Name:        "memset",
+	ParamTypes:  []api.ValueType{i32, i32, i32},
+	ResultTypes: []api.ValueType{i32},
+	Func:        api.GoModuleFunc(memset),
+}
+
+// Strlen replaces "strlen", which returns the length of the NUL-terminated
+// string at `s`.
+//
+//	size_t strlen(const char *s);
+var Strlen = experimental.Intrinsic{
+	Name:        "strlen",
+	ParamTypes:  []api.ValueType{i32},
+	ResultTypes: []api.ValueType{i32},
+	Func:        api.GoModuleFunc(strlen),
+}
+
+// LibC are the intrinsics of wasi-libc in this package.
+var LibC = []experimental.Intrinsic{Memcpy, Memmove, Memset, Strlen}
+
+// BodyHash returns the hash of the function named `name` in the binary, to
+// set experimental.Intrinsic BodyHash. The binary is typically a build of the
+// library which defines the function.
+func BodyHash(wasmBinary []byte, name string) ([]byte, error) {
+	m, err := binary.DecodeModule(wasmBinary, api.CoreFeaturesV2, wasm.MemoryLimitPages, false, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if m.NameSection != nil {
+		for _, n := range m.NameSection.FunctionNames {
+			if n.Name == name && n.Index >= m.ImportFunctionCount {
+				return m.CodeSection[n.Index-m.ImportFunctionCount].BodyHash(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("function %q not defined", name)
+}
+
+func memmove(_ context.Context, mod api.Module, stack []uint64) {
+	dst, src, n := uint32(stack[0]), uint32(stack[1]), uint32(stack[2])
+	if n > 0 {
+		mem := mod.Memory()
+		to, ok := mem.Read(dst, n)
+		if !ok {
+			panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		}
+		from, ok := mem.Read(src, n)
+		if !ok {
+			panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		}
+		copy(to, from)
+	}
+	stack[0] = uint64(dst)
+}
+
+func memset(_ context.Context, mod api.Module, stack []uint64) {
+	dst, c, n := uint32(stack[0]), byte(stack[1]), uint32(stack[2])
+	if n > 0 {
+		to, ok := mod.Memory().Read(dst, n)
+		if !ok {
+			panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		}
+		for i := range to {
+			to[i] = c
+		}
+	}
+	stack[0] = uint64(dst)
+}
+
+func strlen(_ context.Context, mod api.Module, stack []uint64) {
+	s := uint32(stack[0])
+	mem := mod.Memory()
+	size := mem.Size()
+	if s >= size {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	buf, _ := mem.Read(s, size-s)
+	n := bytes.IndexByte(buf, 0)
+	if n < 0 {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	stack[0] = uint64(n)
+}
@@ -0,0 +1,297 @@
+package intrinsics_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/intrinsics"
+	"github.com/tetratelabs/wazero/experimental/wazerotest"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// guest defines "memset" and "strlen" as unreachable, so calling its export
+// "run" only succeeds when both are replaced.
+var guest = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{i32, i32, i32}, Results: []wasm.ValueType{i32}},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		{Results: []wasm.ValueType{i32}},
+	},
+	MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	FunctionSection: []wasm.Index{0, 1, 2},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		// memset(16, 'a', 3); return strlen(16)
+		{Body: []byte{
+			wasm.OpcodeI32Const, 16, wasm.OpcodeI32Const, 'a', wasm.OpcodeI32Const, 3, wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeCall, 1,
+			wasm.OpcodeEnd,
+		}},
+	},
+	ExportSection: []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 2}},
+	NameSection: &wasm.NameSection{FunctionNames: wasm.NameMap{
+		{Index: 0, Name: "memset"},
+		{Index: 1, Name: "strlen"},
+		{Index: 2, Name: "run"},
+	}},
+})
+
+const i32 = wasm.ValueTypeI32
+
+func TestIntrinsics(t *testing.T) {
+	hash, err := intrinsics.BodyHash(guest, "memset")
+	require.NoError(t, err)
+
+	memset := intrinsics.Memset
+	memset.BodyHash = hash
+	otherMemset := intrinsics.Memset
+	otherMemset.BodyHash = []byte{1, 2, 3}
+	strlen64 := intrinsics.Strlen
+	strlen64.ParamTypes = []wasm.ValueType{wasm.ValueTypeI64}
+
+	tests := []struct {
+		name        string
+		intrinsics  []experimental.Intrinsic
+		expectedErr bool
+	}{
+		{name: "libc", intrinsics: intrinsics.LibC},
+		{name: "body hash", intrinsics: []experimental.Intrinsic{memset, intrinsics.Strlen}},
+		{name: "body hash mismatch", intrinsics: []experimental.Intrinsic{otherMemset, intrinsics.Strlen}, expectedErr: true},
+		{name: "signature mismatch", intrinsics: []experimental.Intrinsic{intrinsics.Memset, strlen64}, expectedErr: true},
+		{name: "none", expectedErr: true},
+	}
+
+	for _, c := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			for _, tt := range tests {
+				tc := tt
+				t.Run(tc.name, func(t *testing.T) {
+					r := wazero.NewRuntimeWithConfig(testCtx, config)
+					defer r.Close(testCtx)
+
+					ctx := testCtx
+					if tc.intrinsics != nil {
+						ctx = experimental.WithIntrinsics(ctx, tc.intrinsics...)
+					}
+					compiled, err := r.CompileModule(ctx, guest)
+					require.NoError(t, err)
+					mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+					require.NoError(t, err)
+
+					results, err := mod.ExportedFunction("run").Call(testCtx)
+					if tc.expectedErr {
+						require.Error(t, err)
+						require.Contains(t, err.Error(), "unreachable")
+					} else {
+						require.NoError(t, err)
+						require.Equal(t, []uint64{3}, results)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestIntrinsics_recompile ensures compiling the same binary with another
+// intrinsic doesn't reuse the code compiled with the first.
+func TestIntrinsics_recompile(t *testing.T) {
+	strlen1 := intrinsics.Strlen
+	strlen1.Func = api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+		stack[0] = 1
+	})
+
+	for _, c := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			for _, tc := range []struct {
+				strlen   experimental.Intrinsic
+				expected uint64
+			}{
+				{strlen: intrinsics.Strlen, expected: 3},
+				{strlen: strlen1, expected: 1},
+			} {
+				compiled, err := r.CompileModule(experimental.WithIntrinsics(testCtx, intrinsics.Memset, tc.strlen), guest)
+				require.NoError(t, err)
+				mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(""))
+				require.NoError(t, err)
+
+				results, err := mod.ExportedFunction("run").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{tc.expected}, results)
+			}
+		})
+	}
+}
+
+// TestIntrinsics_otherModule ensures an intrinsic runs against the module
+// which defines it, when another module calls it.
+func TestIntrinsics_otherModule(t *testing.T) {
+	i32Const16 := wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{16}}
+	definer := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		DataSection:     []wasm.DataSegment{{OffsetExpression: i32Const16, Init: []byte("abc\x00")}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "strlen", Type: wasm.ExternTypeFunc, Index: 0}},
+		NameSection:     &wasm.NameSection{FunctionNames: wasm.NameMap{{Index: 0, Name: "strlen"}}},
+	})
+	// caller returns strlen(16) of its own memory, which has a longer string.
+	caller := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+			{Results: []wasm.ValueType{i32}},
+		},
+		ImportSection:   []wasm.Import{{Module: "definer", Name: "strlen", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		DataSection:     []wasm.DataSegment{{OffsetExpression: i32Const16, Init: []byte("abcdef\x00")}},
+		FunctionSection: []wasm.Index{1},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 16, wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	for _, c := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			compiled, err := r.CompileModule(experimental.WithIntrinsics(testCtx, intrinsics.Strlen), definer)
+			require.NoError(t, err)
+			_, err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("definer"))
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(testCtx, caller)
+			require.NoError(t, err)
+			results, err := mod.ExportedFunction("run").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{3}, results)
+		})
+	}
+}
+
+func TestIntrinsics_Func(t *testing.T) {
+	const size = wazerotest.PageSize
+
+	tests := []struct {
+		name        string
+		intrinsic   experimental.Intrinsic
+		params      []uint64
+		expected    uint64
+		expectedMem string
+		expectedErr bool
+	}{
+		{name: "memcpy", intrinsic: intrinsics.Memcpy, params: []uint64{4, 0, 3}, expected: 4, expectedMem: "abcdabch"},
+		{name: "memcpy zero", intrinsic: intrinsics.Memcpy, params: []uint64{size + 1, size + 1, 0}, expected: size + 1, expectedMem: "abcdefgh"},
+		{name: "memcpy out of bounds", intrinsic: intrinsics.Memcpy, params: []uint64{0, size - 1, 2}, expectedErr: true},
+		{name: "memmove overlap", intrinsic: intrinsics.Memmove, params: []uint64{1, 0, 4}, expected: 1, expectedMem: "aabcdfgh"},
+		{name: "memset", intrinsic: intrinsics.Memset, params: []uint64{2, 'z', 2}, expected: 2, expectedMem: "abzzefgh"},
+		{name: "memset out of bounds", intrinsic: intrinsics.Memset, params: []uint64{size, 'z', 1}, expectedErr: true},
+		{name: "strlen", intrinsic: intrinsics.Strlen, params: []uint64{5}, expected: 3, expectedMem: "abcdefgh"},
+		{name: "strlen out of bounds", intrinsic: intrinsics.Strlen, params: []uint64{size}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mem := wazerotest.NewFixedMemory(size)
+			copy(mem.Bytes, "abcdefgh")
+			mod := wazerotest.NewModule(mem)
+
+			stack := append([]uint64(nil), tc.params...)
+			if tc.expectedErr {
+				err := require.CapturePanic(func() { tc.intrinsic.Func.Call(testCtx, mod, stack) })
+				require.Equal(t, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess, err)
+				return
+			}
+			tc.intrinsic.Func.Call(testCtx, mod, stack)
+			require.Equal(t, tc.expected, stack[0])
+			require.Equal(t, tc.expectedMem, string(mem.Bytes[:len(tc.expectedMem)]))
+		})
+	}
+}
+
+func TestLibM(t *testing.T) {
+	f32, f64 := api.EncodeF32, api.EncodeF64
+
+	tests := []struct {
+		name     string
+		params   []uint64
+		expected uint64
+	}{
+		{name: "sqrt", params: []uint64{f64(2)}, expected: f64(math.Sqrt2)},
+		{name: "sqrtf", params: []uint64{f32(2)}, expected: f32(math.Sqrt2)},
+		{name: "trunc", params: []uint64{f64(-1.5)}, expected: f64(-1)},
+		{name: "truncf", params: []uint64{f32(1.5)}, expected: f32(1)},
+		{name: "floor", params: []uint64{f64(-1.5)}, expected: f64(-2)},
+		{name: "floorf", params: []uint64{f32(1.5)}, expected: f32(1)},
+		{name: "ceil", params: []uint64{f64(1.2)}, expected: f64(2)},
+		{name: "ceilf", params: []uint64{f32(-1.2)}, expected: f32(-1)},
+		{name: "round", params: []uint64{f64(2.5)}, expected: f64(3)},
+		{name: "roundf", params: []uint64{f32(-2.5)}, expected: f32(-3)},
+		{name: "rint", params: []uint64{f64(2.5)}, expected: f64(2)},
+		{name: "rintf", params: []uint64{f32(-0.5)}, expected: f32(float32(math.Copysign(0, -1)))},
+		{name: "fmod", params: []uint64{f64(7), f64(3)}, expected: f64(1)},
+		{name: "fmodf", params: []uint64{f32(-7), f32(3)}, expected: f32(-1)},
+		{name: "fma", params: []uint64{f64(2), f64(3), f64(1)}, expected: f64(7)},
+		// A multiplication then an addition rounds to zero.
+		{name: "fma", params: []uint64{f64(1 + 0x1p-52), f64(1 - 0x1p-52), f64(-1)}, expected: f64(-0x1p-104)},
+	}
+
+	byName := map[string]experimental.Intrinsic{}
+	for _, in := range intrinsics.LibM {
+		byName[in.Name] = in
+	}
+	require.Equal(t, len(intrinsics.LibM), len(byName))
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			in, ok := byName[tc.name]
+			require.True(t, ok)
+			require.Equal(t, len(in.ParamTypes), len(tc.params))
+
+			stack := append([]uint64(nil), tc.params...)
+			in.Func.Call(testCtx, nil, stack)
+			require.Equal(t, tc.expected, stack[0])
+		})
+	}
+}
+
+func TestBodyHash_notDefined(t *testing.T) {
+	_, err := intrinsics.BodyHash(guest, "memcpy")
+	require.EqualError(t, err, `function "memcpy" not defined`)
+}
@@ -0,0 +1,98 @@
+package intrinsics
+
+import (
+	"context"
+	"math"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+const (
+	f32 = api.ValueTypeF32
+	f64 = api.ValueTypeF64
+)
+
+// LibM are the intrinsics of the math functions of wasi-libc, for guests
+// compiled without builtins, e.g. with -fno-builtin, which call them instead
+// of using the instruction of Wasm, or software implementations where Wasm
+// has none, such as "fma".
+//
+// Only functions whose results are exact, or correctly rounded, are
+// included, so that results are the same as the guest's implementation,
+// except for the bits of NaN. The Go implementations use the instructions of
+// the host where available, e.g. FMA on amd64 with FMA3, or arm64.
+var LibM = []experimental.Intrinsic{
+	f64Unop("sqrt", math.Sqrt),
+	f32Unop("sqrtf", math.Sqrt),
+	f64Unop("trunc", math.Trunc),
+	f32Unop("truncf", math.Trunc),
+	f64Unop("floor", math.Floor),
+	f32Unop("floorf", math.Floor),
+	f64Unop("ceil", math.Ceil),
+	f32Unop("ceilf", math.Ceil),
+	f64Unop("round", math.Round),
+	f32Unop("roundf", math.Round),
+	// rint rounds in the current rounding mode, which is always to nearest,
+	// ties to even, in Wasm.
+	f64Unop("rint", math.RoundToEven),
+	f32Unop("rintf", math.RoundToEven),
+	f64Binop("fmod", math.Mod),
+	f32Binop("fmodf", math.Mod),
+	{
+		Name:        "fma",
+		ParamTypes:  []api.ValueType{f64, f64, f64},
+		ResultTypes: []api.ValueType{f64},
+		Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			x, y, z := api.DecodeF64(stack[0]), api.DecodeF64(stack[1]), api.DecodeF64(stack[2])
+			stack[0] = api.EncodeF64(math.FMA(x, y, z))
+		}),
+	},
+}
+
+func f64Unop(name string, fn func(float64) float64) experimental.Intrinsic {
+	return experimental.Intrinsic{
+		Name:        name,
+		ParamTypes:  []api.ValueType{f64},
+		ResultTypes: []api.ValueType{f64},
+		Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = api.EncodeF64(fn(api.DecodeF64(stack[0])))
+		}),
+	}
+}
+
+// f32Unop is like f64Unop, except `fn` is computed in 64-bit. This is exact
+// for the functions in LibM, as float32 values are exactly representable.
+func f32Unop(name string, fn func(float64) float64) experimental.Intrinsic {
+	return experimental.Intrinsic{
+		Name:        name,
+		ParamTypes:  []api.ValueType{f32},
+		ResultTypes: []api.ValueType{f32},
+		Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = api.EncodeF32(float32(fn(float64(api.DecodeF32(stack[0])))))
+		}),
+	}
+}
+
+func f64Binop(name string, fn func(float64, float64) float64) experimental.Intrinsic {
+	return experimental.Intrinsic{
+		Name:        name,
+		ParamTypes:  []api.ValueType{f64, f64},
+		ResultTypes: []api.ValueType{f64},
+		Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = api.EncodeF64(fn(api.DecodeF64(stack[0]), api.DecodeF64(stack[1])))
+		}),
+	}
+}
+
+// f32Binop is like f32Unop, except for binary functions.
+func f32Binop(name string, fn func(float64, float64) float64) experimental.Intrinsic {
+	return experimental.Intrinsic{
+		Name:        name,
+		ParamTypes:  []api.ValueType{f32, f32},
+		ResultTypes: []api.ValueType{f32},
+		Func: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) {
+			stack[0] = api.EncodeF32(float32(fn(float64(api.DecodeF32(stack[0])), float64(api.DecodeF32(stack[1])))))
+		}),
+	}
+}
@@ -0,0 +1,106 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// IntrospectionKey is a context.Context Value key. Its associated value should
+// be set with WithIntrospection.
+type IntrospectionKey struct{}
+
+// introspectionEnabled is the value of IntrospectionKey until a call replaces
+// it with its call engine.
+type introspectionEnabled struct{}
+
+// introspector is implemented by call engines.
+type introspector interface {
+	Caller() api.FunctionDefinition
+	Stack() GuestStack
+}
+
+// WithIntrospection returns a context.Context which enables Caller and Stack
+// in host functions called during calls made with the result, e.g.
+// api.Function Call.
+//
+// This costs a context.Context per call, not per host function call, and
+// unlike a FunctionListener, doesn't capture the stack.
+func WithIntrospection(ctx context.Context) context.Context {
+	return context.WithValue(ctx, IntrospectionKey{}, introspectionEnabled{})
+}
+
+// Caller returns the definition of the wasm function which called the
+// current host function, or nil if there is none, e.g. when called directly
+// by api.Function Call, or WithIntrospection wasn't used.
+//
+// This is useful to implement policies per caller, or to attribute logs. For
+// example:
+//
+//	func log(ctx context.Context, mod api.Module, stack []uint64) {
+//		if caller := experimental.Caller(ctx); caller != nil {
+//			fmt.Println("log called by", caller.DebugName())
+//		}
+//		// ...
+//	}
+//
+// # Notes
+//
+//   - This is only valid in a host function (api.GoModuleFunction or
+//     api.GoFunction), while it is called.
+//   - This is supported by the interpreter and compiler, but not yet by the
+//     optimizing compiler in development (wazevo), which returns nil.
+func Caller(ctx context.Context) api.FunctionDefinition {
+	if i, ok := ctx.Value(IntrospectionKey{}).(introspector); ok {
+		return i.Caller()
+	}
+	return nil
+}
+
+// GuestStack describes the guest stack of the call which called the current
+// host function. See Stack.
+type GuestStack struct {
+	// Used and Limit are the current and maximum size of the stack, in units
+	// specific to the engine: call frames in the interpreter, and 8-byte
+	// values in the compiler. The call traps with a stack overflow error
+	// when Used exceeds Limit.
+	//
+	// Note: The compiler grows its stack in steps, so its Limit is a lower
+	// bound, which may increase as the stack grows.
+	Used, Limit uint64
+
+	// Depth is the count of calls in progress into the guest, which is 1
+	// unless a host function called the guest, e.g. to call back into it.
+	//
+	// Each such call has its own stack, so a host function which recursively
+	// calls the guest should also limit Depth, as the Go stack is finite.
+	Depth int
+}
+
+// Remaining returns the size of the stack left before a stack overflow, in
+// the units of Used.
+func (s GuestStack) Remaining() uint64 {
+	if s.Used >= s.Limit {
+		return 0
+	}
+	return s.Limit - s.Used
+}
+
+// Stack returns the guest stack of the call which called the current host
+// function, or false if WithIntrospection wasn't used.
+//
+// This allows a host function which recursively calls the guest, e.g. per
+// node of a syntax tree, to stop gracefully instead of trapping. For example:
+//
+//	if s, ok := experimental.Stack(ctx); ok && (s.Remaining() < s.Limit/4 || s.Depth > 100) {
+//		return errTooDeep
+//	}
+//
+// Note: Like Caller, this is only valid in a host function while it is
+// called, and returns false on engines which don't support it.
+func Stack(ctx context.Context) (GuestStack, bool) {
+	if i, ok := ctx.Value(IntrospectionKey{}).(introspector); ok {
+		return i.Stack(), true
+	}
+	return GuestStack{}, false
+}
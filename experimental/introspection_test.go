@@ -0,0 +1,141 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestCaller(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			var seen []string
+			record := func(where string, ctx context.Context) {
+				caller := "<nil>"
+				if def := experimental.Caller(ctx); def != nil {
+					caller = def.DebugName()
+				}
+				seen = append(seen, where+":"+caller)
+			}
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module) {
+				record("outer", ctx)
+				_, err := mod.ExportedFunction("inner").Call(ctx)
+				require.NoError(t, err)
+				record("outer after inner", ctx)
+			}).Export("outer").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context) {
+				record("leaf", ctx)
+			}).Export("leaf").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			bin := binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection: []wasm.FunctionType{{}},
+				ImportSection: []wasm.Import{
+					{Module: "env", Name: "outer", Type: wasm.ExternTypeFunc, DescFunc: 0},
+					{Module: "env", Name: "leaf", Type: wasm.ExternTypeFunc, DescFunc: 0},
+				},
+				FunctionSection: []wasm.Index{0, 0},
+				CodeSection: []wasm.Code{
+					{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+				},
+				ExportSection: []wasm.Export{
+					{Name: "run", Type: wasm.ExternTypeFunc, Index: 2},
+					{Name: "inner", Type: wasm.ExternTypeFunc, Index: 3},
+				},
+				NameSection: &wasm.NameSection{
+					ModuleName:    "guest",
+					FunctionNames: wasm.NameMap{{Index: 2, Name: "run"}, {Index: 3, Name: "inner"}},
+				},
+			})
+			mod, err := r.Instantiate(ctx, bin)
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("run").Call(experimental.WithIntrospection(ctx))
+			require.NoError(t, err)
+			require.Equal(t, []string{"outer:guest.run", "leaf:guest.inner", "outer after inner:guest.run"}, seen)
+
+			t.Run("disabled", func(t *testing.T) {
+				seen = nil
+				_, err = mod.ExportedFunction("inner").Call(ctx)
+				require.NoError(t, err)
+				require.Equal(t, []string{"leaf:<nil>"}, seen)
+			})
+		})
+	}
+}
+
+func TestStack(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			var stacks []experimental.GuestStack
+
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			// recurse calls back into the guest until the depth is 3.
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func(ctx context.Context, mod api.Module) {
+				s, ok := experimental.Stack(ctx)
+				require.True(t, ok)
+				stacks = append(stacks, s)
+				if s.Depth < 3 {
+					_, err := mod.ExportedFunction("run").Call(ctx)
+					require.NoError(t, err)
+				}
+			}).Export("recurse").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			bin := binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				ImportSection:   []wasm.Import{{Module: "env", Name: "recurse", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+				ExportSection:   []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+			})
+			mod, err := r.Instantiate(ctx, bin)
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("run").Call(experimental.WithIntrospection(ctx))
+			require.NoError(t, err)
+			require.Equal(t, 3, len(stacks))
+			for i, s := range stacks {
+				require.Equal(t, i+1, s.Depth)
+				require.True(t, s.Used > 0)
+				require.True(t, s.Remaining() > 0)
+				require.Equal(t, s.Limit-s.Used, s.Remaining())
+			}
+
+			_, ok := experimental.Stack(ctx)
+			require.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+// Package jsstring implements the JS string builtins proposal, which lets
+// guests, such as those compiled by Kotlin/Wasm, use strings of the host
+// instead of shipping a string runtime.
+//
+// Strings are externref values referencing Go strings held by Strings. The
+// builtins are host functions of the module "wasm:js-string", and string
+// constants are imported as immutable externref globals, e.g.
+// (import "'" "hello" (global externref)).
+//
+// Here's an example which instantiates a guest using both:
+//
+//	strs := jsstring.NewStrings()
+//	defer strs.Close(ctx)
+//	if _, err := strs.Instantiate(ctx, r); err != nil {
+//		return err
+//	}
+//	ctx = strs.WithImportedStringConstants(ctx, "'")
+//	mod, err := r.InstantiateModule(ctx, compiled, config)
+//
+// # Notes
+//
+//   - fromCharCodeArray and intoCharCodeArray are not implemented, as they
+//     use arrays of the GC proposal, which wazero doesn't support.
+//   - Imported string constants have the type externref, as wazero doesn't
+//     support typed references, such as (ref extern).
+//   - Strings are UTF-16 in the proposal, but backed by Go strings in WTF-8,
+//     so that strings without lone surrogates are valid UTF-8.
+//
+// See https://github.com/WebAssembly/js-string-builtins
+package jsstring
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// BuiltinsModuleName is the module name the builtins are imported from.
+const BuiltinsModuleName = "wasm:js-string"
+
+var (
+	errNotString        = errors.New("not a string")
+	errOutOfBounds      = errors.New("string index out of bounds")
+	errInvalidCodePoint = errors.New("invalid code point")
+)
+
+// Strings holds the Go strings referenced by externref values. It is safe
+// for concurrent use.
+//
+// Strings are interned: equal strings have the same externref, which is
+// never zero, the null externref.
+//
+// A string referenced by Ref is held until Close. A string the builtins
+// return to a guest is held until every module instance which created it is
+// closed, or until Close. Strings are released later, when more are created.
+//
+// Note: A module instance which uses a string created by another, e.g. via
+// an exported global, traps if it uses it after the other is closed.
+type Strings struct {
+	mu      sync.Mutex
+	strings map[uint64]*jsString
+	refs    map[string]uint64
+	// last is the last externref value, which is never reused, even when the
+	// string it referenced was released.
+	last uint64
+
+	// created are the strings each module instance created with the
+	// builtins, which are released once it's closed.
+	created map[api.Module]map[uint64]struct{}
+	// releaseAt is the count of strings at which release checks if module
+	// instances were closed, which doubles each time, so that creating
+	// strings takes constant time.
+	releaseAt int
+}
+
+// minReleaseAt is the minimum of Strings.releaseAt.
+const minReleaseAt = 64
+
+// jsString is a string referenced by an externref.
+type jsString struct {
+	s string
+	// units are the UTF-16 code units of s, decoded on demand.
+	units []uint16
+	// creators is the count of module instances which created the string.
+	creators int
+	// pinned is true when the string was referenced by Ref, so is held until
+	// Close.
+	pinned bool
+}
+
+// NewStrings returns a new Strings.
+func NewStrings() *Strings {
+	return &Strings{
+		strings:   map[uint64]*jsString{},
+		refs:      map[string]uint64{},
+		created:   map[api.Module]map[uint64]struct{}{},
+		releaseAt: minReleaseAt,
+	}
+}
+
+// Ref returns the externref of the string `str`, which guests can use with
+// the builtins, until Close. `str` is WTF-8, e.g. any valid UTF-8.
+func (s *Strings) Ref(str string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref, js := s.ref(str)
+	js.pinned = true
+	return ref
+}
+
+// create returns the externref of the string `str`, which the builtins
+// return to the module instance `mod`.
+func (s *Strings) create(mod api.Module, str string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref, js := s.ref(str)
+	created, ok := s.created[mod]
+	if !ok {
+		created = map[uint64]struct{}{}
+		s.created[mod] = created
+	}
+	if _, ok = created[ref]; !ok {
+		created[ref] = struct{}{}
+		js.creators++
+	}
+	return ref
+}
+
+// ref returns the externref of the string `str`, adding it if needed.
+func (s *Strings) ref(str string) (uint64, *jsString) {
+	if ref, ok := s.refs[str]; ok {
+		return ref, s.strings[ref]
+	}
+	if len(s.strings) >= s.releaseAt {
+		s.release()
+		if s.releaseAt = 2 * len(s.strings); s.releaseAt < minReleaseAt {
+			s.releaseAt = minReleaseAt
+		}
+	}
+	s.last++
+	js := &jsString{s: str}
+	s.strings[s.last] = js
+	s.refs[str] = s.last
+	return s.last, js
+}
+
+// release releases the strings created by module instances which were
+// closed, unless another one created them or they are pinned.
+func (s *Strings) release() {
+	for mod, created := range s.created {
+		if !mod.IsClosed() {
+			continue
+		}
+		for ref := range created {
+			js := s.strings[ref]
+			if js.creators--; js.creators == 0 && !js.pinned {
+				delete(s.strings, ref)
+				delete(s.refs, js.s)
+			}
+		}
+		delete(s.created, mod)
+	}
+}
+
+// Close releases all strings, so that their externref values are no longer
+// strings, e.g. String returns false. Strings referenced afterward have new
+// externref values.
+//
+// Note: Guests which still use released externref values trap.
+func (s *Strings) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strings = map[uint64]*jsString{}
+	s.refs = map[string]uint64{}
+	s.created = map[api.Module]map[uint64]struct{}{}
+	s.releaseAt = minReleaseAt
+	return nil
+}
+
+// String returns the string referenced by the externref `ref`, or false if
+// it isn't a string of this.
+func (s *Strings) String(ref uint64) (string, bool) {
+	if js := s.get(ref); js != nil {
+		return js.s, true
+	}
+	return "", false
+}
+
+// get returns the string referenced by `ref`, or nil if there's none.
+func (s *Strings) get(ref uint64) *jsString {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.strings[ref]
+}
+
+// mustGet returns the string referenced by `ref`, or panics to trap if it
+// isn't a string.
+func (s *Strings) mustGet(ref uint64) *jsString {
+	js := s.get(ref)
+	if js == nil {
+		panic(errNotString)
+	}
+	return js
+}
+
+// mustGetUnits returns the UTF-16 code units of the string referenced by
+// `ref`, or panics to trap if it isn't a string.
+func (s *Strings) mustGetUnits(ref uint64) []uint16 {
+	js := s.mustGet(ref)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if js.units == nil {
+		js.units = decodeUTF16(js.s)
+	}
+	return js.units
+}
+
+// WithImportedStringConstants returns a context which provides the imported
+// string constants of modules instantiated with it: immutable externref
+// globals imported from `moduleName`, such as "'", named by their string.
+//
+// See experimental.WithImportedGlobals
+func (s *Strings) WithImportedStringConstants(ctx context.Context, moduleName string) context.Context {
+	return experimental.WithImportedGlobals(ctx, experimental.ImportedGlobalsFunc(
+		func(importModuleName, name string, valueType api.ValueType) (uint64, bool) {
+			if importModuleName != moduleName || valueType != api.ValueTypeExternref {
+				return 0, false
+			}
+			return s.Ref(name), true
+		}))
+}
+
+// Instantiate instantiates the builtins in the runtime, as the module
+// BuiltinsModuleName.
+func (s *Strings) Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	ref, i32 := api.ValueTypeExternref, api.ValueTypeI32
+	b := r.NewHostModuleBuilder(BuiltinsModuleName)
+	export := func(name string, fn api.GoModuleFunc, params, results []api.ValueType) {
+		b.NewFunctionBuilder().WithGoModuleFunction(fn, params, results).Export(name)
+	}
+	export("cast", s.cast, []api.ValueType{ref}, []api.ValueType{ref})
+	export("test", s.test, []api.ValueType{ref}, []api.ValueType{i32})
+	export("fromCharCode", s.fromCharCode, []api.ValueType{i32}, []api.ValueType{ref})
+	export("fromCodePoint", s.fromCodePoint, []api.ValueType{i32}, []api.ValueType{ref})
+	export("charCodeAt", s.charCodeAt, []api.ValueType{ref, i32}, []api.ValueType{i32})
+	export("codePointAt", s.codePointAt, []api.ValueType{ref, i32}, []api.ValueType{i32})
+	export("length", s.length, []api.ValueType{ref}, []api.ValueType{i32})
+	export("concat", s.concat, []api.ValueType{ref, ref}, []api.ValueType{ref})
+	export("substring", s.substring, []api.ValueType{ref, i32, i32}, []api.ValueType{ref})
+	export("equals", s.equals, []api.ValueType{ref, ref}, []api.ValueType{i32})
+	export("compare", s.compare, []api.ValueType{ref, ref}, []api.ValueType{i32})
+	return b.Instantiate(ctx)
+}
+
+// cast returns the string, or traps if it isn't one.
+func (s *Strings) cast(_ context.Context, _ api.Module, stack []uint64) {
+	s.mustGet(stack[0])
+}
+
+// test returns 1 if the externref is a string, otherwise 0.
+func (s *Strings) test(_ context.Context, _ api.Module, stack []uint64) {
+	stack[0] = boolToUint64(s.get(stack[0]) != nil)
+}
+
+// fromCharCode returns the string of the UTF-16 code unit in the low 16 bits
+// of the param.
+func (s *Strings) fromCharCode(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = s.create(mod, string(appendCodePoint(nil, rune(uint16(stack[0])))))
+}
+
+// fromCodePoint returns the string of the code point, which can be a
+// surrogate, or traps if it's larger than U+10FFFF.
+func (s *Strings) fromCodePoint(_ context.Context, mod api.Module, stack []uint64) {
+	c := uint32(stack[0])
+	if c > 0x10ffff {
+		panic(errInvalidCodePoint)
+	}
+	stack[0] = s.create(mod, string(appendCodePoint(nil, rune(c))))
+}
+
+// charCodeAt returns the UTF-16 code unit at the index, or traps if it's out
+// of bounds.
+func (s *Strings) charCodeAt(_ context.Context, _ api.Module, stack []uint64) {
+	units := s.mustGetUnits(stack[0])
+	i := uint32(stack[1])
+	if uint64(i) >= uint64(len(units)) {
+		panic(errOutOfBounds)
+	}
+	stack[0] = uint64(units[i])
+}
+
+// codePointAt returns the code point starting at the index, which is a lone
+// surrogate unless it's the start of a surrogate pair, or traps if the index
+// is out of bounds.
+func (s *Strings) codePointAt(_ context.Context, _ api.Module, stack []uint64) {
+	units := s.mustGetUnits(stack[0])
+	i := uint32(stack[1])
+	if uint64(i) >= uint64(len(units)) {
+		panic(errOutOfBounds)
+	}
+	c := uint32(units[i])
+	if c >= surrogateMin && c < lowSurrogateMin && int(i)+1 < len(units) {
+		if lo := uint32(units[i+1]); lo >= lowSurrogateMin && lo <= surrogateMax {
+			c = (c-surrogateMin)<<10 + (lo - lowSurrogateMin) + supplementaryMin
+		}
+	}
+	stack[0] = uint64(c)
+}
+
+// length returns the count of UTF-16 code units.
+func (s *Strings) length(_ context.Context, _ api.Module, stack []uint64) {
+	stack[0] = uint64(len(s.mustGetUnits(stack[0])))
+}
+
+// concat returns the concatenation of both strings.
+func (s *Strings) concat(_ context.Context, mod api.Module, stack []uint64) {
+	x, y := s.mustGet(stack[0]), s.mustGet(stack[1])
+	// A lone high surrogate followed by a lone low surrogate is a surrogate
+	// pair, which WTF-8 encodes as the code point they represent.
+	if endsWithHighSurrogate(x.s) && startsWithLowSurrogate(y.s) {
+		xu, yu := s.mustGetUnits(stack[0]), s.mustGetUnits(stack[1])
+		units := make([]uint16, 0, len(xu)+len(yu))
+		stack[0] = s.create(mod, encodeUTF16(append(append(units, xu...), yu...)))
+		return
+	}
+	stack[0] = s.create(mod, x.s+y.s)
+}
+
+// substring returns the UTF-16 code units between the start and end
+// indexes, clamped to the length of the string. This returns an empty string
+// if start is after end or the end of the string.
+func (s *Strings) substring(_ context.Context, mod api.Module, stack []uint64) {
+	units := s.mustGetUnits(stack[0])
+	start, end := uint64(uint32(stack[1])), uint64(uint32(stack[2]))
+	length := uint64(len(units))
+	if end > length {
+		end = length
+	}
+	if start > end {
+		stack[0] = s.create(mod, "")
+		return
+	}
+	stack[0] = s.create(mod, encodeUTF16(units[start:end]))
+}
+
+// equals returns 1 if both are the same string or both are null, otherwise
+// 0. This traps if either is neither a string nor null.
+func (s *Strings) equals(_ context.Context, _ api.Module, stack []uint64) {
+	x, y := stack[0], stack[1]
+	if x != 0 {
+		s.mustGet(x)
+	}
+	if y != 0 {
+		s.mustGet(y)
+	}
+	// Strings are interned, so equal strings have equal externref values.
+	stack[0] = boolToUint64(x == y)
+}
+
+// compare returns -1, 0 or 1 if the first string is less than, equal to or
+// greater than the second, comparing UTF-16 code units.
+func (s *Strings) compare(_ context.Context, _ api.Module, stack []uint64) {
+	x, y := s.mustGetUnits(stack[0]), s.mustGetUnits(stack[1])
+	var ret int32
+	for i := 0; ret == 0; i++ {
+		switch {
+		case i == len(x) && i == len(y):
+			stack[0] = 0
+			return
+		case i == len(x):
+			ret = -1
+		case i == len(y):
+			ret = 1
+		case x[i] < y[i]:
+			ret = -1
+		case x[i] > y[i]:
+			ret = 1
+		}
+	}
+	stack[0] = uint64(uint32(ret))
+}
+
+func boolToUint64(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
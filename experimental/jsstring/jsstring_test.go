@@ -0,0 +1,242 @@
+package jsstring
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var testCtx = context.Background()
+
+func TestWTF8(t *testing.T) {
+	tests := []struct {
+		name  string
+		units []uint16
+		str   string
+	}{
+		{name: "empty", units: []uint16{}, str: ""},
+		{name: "ascii", units: []uint16{'h', 'i'}, str: "hi"},
+		{name: "bmp", units: []uint16{0xe9}, str: "é"},
+		{name: "surrogate pair", units: []uint16{0xd83d, 0xde00}, str: "😀"},
+		{name: "lone high surrogate", units: []uint16{'a', 0xd83d}, str: "a\xed\xa0\xbd"},
+		{name: "lone low surrogate", units: []uint16{0xde00, 'a'}, str: "\xed\xb8\x80a"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.str, encodeUTF16(tc.units))
+			require.Equal(t, tc.units, decodeUTF16(tc.str))
+		})
+	}
+}
+
+func TestStrings_Ref(t *testing.T) {
+	s := NewStrings()
+	ref := s.Ref("hello")
+	require.NotEqual(t, uint64(0), ref)
+	require.Equal(t, ref, s.Ref("hello"))
+	require.NotEqual(t, ref, s.Ref("world"))
+
+	str, ok := s.String(ref)
+	require.True(t, ok)
+	require.Equal(t, "hello", str)
+
+	_, ok = s.String(0)
+	require.False(t, ok)
+	_, ok = s.String(100)
+	require.False(t, ok)
+}
+
+func TestStrings_Close(t *testing.T) {
+	s := NewStrings()
+	hello := s.Ref("hello")
+	require.NoError(t, s.Close(testCtx))
+
+	_, ok := s.String(hello)
+	require.False(t, ok)
+
+	// Released externref values aren't reused, even for the same string.
+	again := s.Ref("hello")
+	require.NotEqual(t, hello, again)
+	str, ok := s.String(again)
+	require.True(t, ok)
+	require.Equal(t, "hello", str)
+}
+
+func TestStrings_release(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	s := NewStrings()
+	_, err := s.Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// fromCharCode calls the builtin, which returns the string of a UTF-16
+	// code unit.
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeExternref}}},
+		ImportSection: []wasm.Import{
+			{Module: BuiltinsModuleName, Name: "fromCharCode", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "fromCharCode", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+	a, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+	b, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+
+	fromCharCode := func(mod api.Module, c byte) uint64 {
+		results, err := mod.ExportedFunction("fromCharCode").Call(testCtx, uint64(c))
+		require.NoError(t, err)
+		return results[0]
+	}
+	created := fromCharCode(a, 'a')
+	shared := fromCharCode(a, 'b')
+	require.Equal(t, shared, fromCharCode(b, 'b'))
+	pinned := s.Ref("c")
+	require.Equal(t, pinned, fromCharCode(a, 'c'))
+
+	require.NoError(t, a.Close(testCtx))
+	// Strings are released when more are created, after their creators are
+	// closed.
+	for i := 0; i < minReleaseAt; i++ {
+		s.Ref(strconv.Itoa(i))
+	}
+
+	_, ok := s.String(created)
+	require.False(t, ok)
+	_, ok = s.String(shared)
+	require.True(t, ok)
+	_, ok = s.String(pinned)
+	require.True(t, ok)
+}
+
+func TestStrings_Instantiate(t *testing.T) {
+	// The builtins are called directly from Go, instead of from a guest, so
+	// this uses the interpreter: the compiler only supports calling host
+	// functions from guests.
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	s := NewStrings()
+	builtins, err := s.Instantiate(testCtx, r)
+	require.NoError(t, err)
+	mod := builtins.(api.Module)
+
+	hi, pile := s.Ref("hi"), s.Ref("💩")
+	high, low := s.Ref("\xed\xa0\xbd"), s.Ref("\xed\xb8\x80")
+
+	tests := []struct {
+		name, fn    string
+		params      []uint64
+		expected    []uint64
+		expectedStr string
+		expectedErr string
+	}{
+		{name: "cast", fn: "cast", params: []uint64{hi}, expected: []uint64{hi}},
+		{name: "cast null", fn: "cast", params: []uint64{0}, expectedErr: errNotString.Error()},
+		{name: "test", fn: "test", params: []uint64{hi}, expected: []uint64{1}},
+		{name: "test not string", fn: "test", params: []uint64{1000}, expected: []uint64{0}},
+		{name: "fromCharCode", fn: "fromCharCode", params: []uint64{0x10061}, expectedStr: "a"},
+		{name: "fromCodePoint", fn: "fromCodePoint", params: []uint64{0x1f4a9}, expectedStr: "💩"},
+		{name: "fromCodePoint surrogate", fn: "fromCodePoint", params: []uint64{0xd83d}, expectedStr: "\xed\xa0\xbd"},
+		{name: "fromCodePoint invalid", fn: "fromCodePoint", params: []uint64{0x110000}, expectedErr: errInvalidCodePoint.Error()},
+		{name: "charCodeAt", fn: "charCodeAt", params: []uint64{pile, 1}, expected: []uint64{0xdca9}},
+		{name: "charCodeAt out of bounds", fn: "charCodeAt", params: []uint64{pile, 2}, expectedErr: errOutOfBounds.Error()},
+		{name: "codePointAt pair", fn: "codePointAt", params: []uint64{pile, 0}, expected: []uint64{0x1f4a9}},
+		{name: "codePointAt low surrogate", fn: "codePointAt", params: []uint64{pile, 1}, expected: []uint64{0xdca9}},
+		{name: "codePointAt out of bounds", fn: "codePointAt", params: []uint64{hi, 0xffffffff}, expectedErr: errOutOfBounds.Error()},
+		{name: "length", fn: "length", params: []uint64{pile}, expected: []uint64{2}},
+		{name: "length not string", fn: "length", params: []uint64{0}, expectedErr: errNotString.Error()},
+		{name: "concat", fn: "concat", params: []uint64{hi, pile}, expectedStr: "hi💩"},
+		{name: "concat surrogates", fn: "concat", params: []uint64{high, low}, expectedStr: "😀"},
+		{name: "substring", fn: "substring", params: []uint64{pile, 0, 1}, expectedStr: "\xed\xa0\xbd"},
+		{name: "substring clamped", fn: "substring", params: []uint64{hi, 1, 100}, expectedStr: "i"},
+		{name: "substring start after end", fn: "substring", params: []uint64{hi, 2, 1}, expectedStr: ""},
+		{name: "equals", fn: "equals", params: []uint64{hi, s.Ref("hi")}, expected: []uint64{1}},
+		{name: "equals different", fn: "equals", params: []uint64{hi, pile}, expected: []uint64{0}},
+		{name: "equals null", fn: "equals", params: []uint64{0, 0}, expected: []uint64{1}},
+		{name: "equals string and null", fn: "equals", params: []uint64{hi, 0}, expected: []uint64{0}},
+		{name: "equals not string", fn: "equals", params: []uint64{hi, 1000}, expectedErr: errNotString.Error()},
+		{name: "compare less", fn: "compare", params: []uint64{s.Ref("h"), hi}, expected: []uint64{api.EncodeI32(-1)}},
+		{name: "compare equal", fn: "compare", params: []uint64{hi, hi}, expected: []uint64{0}},
+		{name: "compare greater", fn: "compare", params: []uint64{pile, hi}, expected: []uint64{1}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expectedStr != "" || tc.expected == nil {
+				str, ok := s.String(results[0])
+				require.True(t, ok)
+				require.Equal(t, tc.expectedStr, str)
+			} else {
+				require.Equal(t, tc.expected, results)
+			}
+		})
+	}
+}
+
+func TestStrings_WithImportedStringConstants(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	s := NewStrings()
+	_, err := s.Instantiate(testCtx, r)
+	require.NoError(t, err)
+
+	// greet returns the concatenation of the string constants "hello, " and
+	// "world".
+	ref := wasm.ValueTypeExternref
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{ref, ref}, Results: []wasm.ValueType{ref}},
+			{Results: []wasm.ValueType{ref}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: BuiltinsModuleName, Name: "concat", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "'", Name: "hello, ", Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: ref}},
+			{Module: "'", Name: "world", Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: ref}},
+		},
+		FunctionSection: []wasm.Index{1},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeGlobalGet, 0,
+			wasm.OpcodeGlobalGet, 1,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "greet", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+
+	ctx := s.WithImportedStringConstants(testCtx, "'")
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("greet").Call(testCtx)
+	require.NoError(t, err)
+	str, ok := s.String(results[0])
+	require.True(t, ok)
+	require.Equal(t, "hello, world", str)
+}
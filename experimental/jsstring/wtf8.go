@@ -0,0 +1,88 @@
+package jsstring
+
+import "unicode/utf8"
+
+// Strings are UTF-16 in the JS string builtins proposal, so they can contain
+// lone surrogates, which aren't valid in UTF-8. They are backed by Go strings
+// in WTF-8, which is UTF-8 extended to encode lone surrogates as if they were
+// code points. So, strings without lone surrogates are valid UTF-8.
+//
+// See https://simonsapin.github.io/wtf-8/
+
+const (
+	surrogateMin     = 0xd800
+	lowSurrogateMin  = 0xdc00
+	surrogateMax     = 0xdfff
+	supplementaryMin = 0x10000
+)
+
+// encodeUTF16 returns the WTF-8 string of the UTF-16 code units `u`.
+func encodeUTF16(u []uint16) string {
+	b := make([]byte, 0, len(u))
+	for i := 0; i < len(u); i++ {
+		c := rune(u[i])
+		if c >= surrogateMin && c < lowSurrogateMin && i+1 < len(u) {
+			if lo := rune(u[i+1]); lo >= lowSurrogateMin && lo <= surrogateMax {
+				c = (c-surrogateMin)<<10 + (lo - lowSurrogateMin) + supplementaryMin
+				i++
+			}
+		}
+		b = appendCodePoint(b, c)
+	}
+	return string(b)
+}
+
+// appendCodePoint appends the WTF-8 encoding of `c`, which can be a
+// surrogate, to `b`.
+func appendCodePoint(b []byte, c rune) []byte {
+	if c >= surrogateMin && c <= surrogateMax {
+		// utf8.EncodeRune encodes surrogates as utf8.RuneError.
+		return append(b, 0xe0|byte(c>>12), 0x80|byte(c>>6)&0x3f, 0x80|byte(c)&0x3f)
+	}
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], c)
+	return append(b, buf[:n]...)
+}
+
+// decodeUTF16 returns the UTF-16 code units of the WTF-8 string `s`. Bytes
+// which aren't WTF-8 decode as U+FFFD.
+func decodeUTF16(s string) []uint16 {
+	u := make([]uint16, 0, len(s))
+	for i := 0; i < len(s); {
+		c, size := decodeCodePoint(s[i:])
+		i += size
+		if c >= supplementaryMin {
+			c -= supplementaryMin
+			u = append(u, uint16(surrogateMin+c>>10), uint16(lowSurrogateMin+c&0x3ff))
+		} else {
+			u = append(u, uint16(c))
+		}
+	}
+	return u
+}
+
+// decodeCodePoint decodes the first code point of the WTF-8 string `s`, which
+// can be a surrogate, and returns its size in bytes.
+func decodeCodePoint(s string) (rune, int) {
+	c, size := utf8.DecodeRuneInString(s)
+	// utf8.DecodeRuneInString rejects surrogates, which are encoded as
+	// 0xed 0xa0-0xbf 0x80-0xbf.
+	if c == utf8.RuneError && size == 1 && len(s) >= 3 && s[0] == 0xed &&
+		s[1]&0xe0 == 0xa0 && s[2]&0xc0 == 0x80 {
+		return rune(s[0]&0x0f)<<12 | rune(s[1]&0x3f)<<6 | rune(s[2]&0x3f), 3
+	}
+	return c, size
+}
+
+// endsWithHighSurrogate returns true if the WTF-8 string `s` ends with a lone
+// high surrogate, encoded as 0xed 0xa0-0xaf 0x80-0xbf.
+func endsWithHighSurrogate(s string) bool {
+	n := len(s)
+	return n >= 3 && s[n-3] == 0xed && s[n-2]&0xf0 == 0xa0 && s[n-1]&0xc0 == 0x80
+}
+
+// startsWithLowSurrogate returns true if the WTF-8 string `s` starts with a
+// lone low surrogate, encoded as 0xed 0xb0-0xbf 0x80-0xbf.
+func startsWithLowSurrogate(s string) bool {
+	return len(s) >= 3 && s[0] == 0xed && s[1]&0xf0 == 0xb0 && s[2]&0xc0 == 0x80
+}
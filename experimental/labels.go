@@ -0,0 +1,23 @@
+package experimental
+
+import "github.com/tetratelabs/wazero/api"
+
+// GetLabels returns the labels of the module, set with wazero.ModuleConfig
+// WithLabel, or nil if it has none.
+//
+// This allows a FunctionListener or host function to attribute what a module
+// does, e.g. to a tenant:
+//
+//	func (l *tenantListener) Before(ctx context.Context, mod api.Module, def api.FunctionDefinition, _ []uint64, _ experimental.StackIterator) {
+//		l.calls[experimental.GetLabels(mod)["tenant"]]++
+//	}
+//
+// Note: The result must not be modified.
+func GetLabels(mod api.Module) map[string]string {
+	if m, ok := mod.(interface {
+		ModuleLabels() map[string]string
+	}); ok {
+		return m.ModuleLabels()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// LeakDetectionKey is a context.Context Value key. Its associated value should
+// be a LeakHandler, which may be nil.
+//
+// See WithLeakDetection
+type LeakDetectionKey struct{}
+
+// LeakHandler is called by wazero.Runtime Close with the Leaks outstanding
+// before the runtime closes them, in the order they were created. It isn't
+// called when there are no leaks.
+type LeakHandler func(ctx context.Context, leaks []Leak)
+
+// WithLeakDetection enables tracking of compiled modules and module instances
+// which aren't closed, by a wazero.Runtime created with the result, e.g. by
+// wazero.NewRuntimeWithConfig.
+//
+// Leak detection is disabled by default, as it captures the stack of each
+// compilation and instantiation. The handler, if not nil, is called with the
+// outstanding leaks when the runtime is closed. Otherwise, use GetLeaks.
+//
+// Here's an example that fails a test on leaked executable memory:
+//
+//	ctx = experimental.WithLeakDetection(ctx, func(_ context.Context, leaks []experimental.Leak) {
+//		for _, l := range leaks {
+//			t.Errorf("%s was not closed, created at:\n%s", l.Module, l.Stack)
+//		}
+//	})
+//	r := wazero.NewRuntime(ctx)
+//	defer r.Close(ctx)
+//
+// Note: Modules compiled implicitly, e.g. by wazero.Runtime Instantiate, are
+// closed with their instance, so only the instance is tracked.
+func WithLeakDetection(ctx context.Context, handler LeakHandler) context.Context {
+	return context.WithValue(ctx, LeakDetectionKey{}, handler)
+}
+
+// Leak is a compiled module or module instance which is not yet closed.
+type Leak struct {
+	// Module is the name of the module instance, or of the compiled module
+	// as defined in its name section.
+	Module string
+
+	// Instance is true when this is a module instance, as opposed to a
+	// wazero.CompiledModule.
+	Instance bool
+
+	// Collected is true when this is a wazero.CompiledModule which was
+	// garbage collected without being closed. Its code remains in use until
+	// the wazero.Runtime is closed.
+	Collected bool
+
+	// Stack is the Go stack trace of the compilation or instantiation, as
+	// formatted by runtime/debug.Stack.
+	Stack []byte
+}
+
+// GetLeaks returns the Leaks outstanding in the given wazero.Runtime, in the
+// order they were created, or false if it wasn't created with
+// WithLeakDetection.
+func GetLeaks(r api.Closer) ([]Leak, bool) {
+	if l, ok := r.(interface {
+		Leaks() ([]Leak, bool)
+	}); ok {
+		return l.Leaks()
+	}
+	return nil, false
+}
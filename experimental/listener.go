@@ -2,6 +2,7 @@ package experimental
 
 import (
 	"context"
+	"path"
 
 	"github.com/tetratelabs/wazero/api"
 )
@@ -169,6 +170,77 @@ func (multi multiFunctionListenerFactory) NewFunctionListener(def api.FunctionDe
 	}
 }
 
+// FunctionListenerScope selects the functions a FunctionListenerFactory is
+// asked for listeners. See ScopedFunctionListenerFactory.
+type FunctionListenerScope struct {
+	// ModuleNames are the names of the modules which define the functions,
+	// e.g. "wasi_snapshot_preview1". When empty, functions of all modules are
+	// selected.
+	//
+	// Note: The name of a guest module is the one in its name section, not
+	// the one it is instantiated with, as listeners are created when the
+	// module is compiled.
+	ModuleNames []string
+
+	// FunctionNames are patterns in the syntax of path.Match, matched
+	// against the name and export names of each function, e.g. "_start" or
+	// "fd_*". When empty, all functions of the selected modules are selected.
+	FunctionNames []string
+}
+
+// ScopedFunctionListenerFactory constructs a FunctionListenerFactory which
+// only returns listeners from the factory for functions in the scope.
+//
+// This is useful to avoid the overhead of listeners on every guest function,
+// when only host modules or entry points are of interest. To use different
+// factories for different scopes, combine them with
+// MultiFunctionListenerFactory.
+func ScopedFunctionListenerFactory(factory FunctionListenerFactory, scope FunctionListenerScope) FunctionListenerFactory {
+	return &scopedFunctionListenerFactory{factory: factory, scope: scope}
+}
+
+type scopedFunctionListenerFactory struct {
+	factory FunctionListenerFactory
+	scope   FunctionListenerScope
+}
+
+// NewFunctionListener implements FunctionListenerFactory.NewFunctionListener
+func (s *scopedFunctionListenerFactory) NewFunctionListener(def api.FunctionDefinition) FunctionListener {
+	if !s.scope.includes(def) {
+		return nil
+	}
+	return s.factory.NewFunctionListener(def)
+}
+
+func (s *FunctionListenerScope) includes(def api.FunctionDefinition) bool {
+	if len(s.ModuleNames) > 0 {
+		found := false
+		for _, name := range s.ModuleNames {
+			if name == def.ModuleName() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.FunctionNames) == 0 {
+		return true
+	}
+	for _, pattern := range s.FunctionNames {
+		if ok, _ := path.Match(pattern, def.Name()); ok {
+			return true
+		}
+		for _, export := range def.ExportNames() {
+			if ok, _ := path.Match(pattern, export); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type multiFunctionListener struct {
 	lstns []FunctionListener
 	stack stackIterator
@@ -0,0 +1,126 @@
+package experimental
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// FunctionListenerSampler returns true if a call should be passed to the
+// listeners of a SampledFunctionListenerFactory. It is called before each
+// function call, so must be cheap and safe for concurrent use.
+type FunctionListenerSampler func(ctx context.Context, mod api.Module, def api.FunctionDefinition) bool
+
+// SampleOneIn returns a FunctionListenerSampler which samples one of every n
+// calls, starting with the first. n of zero or one samples every call.
+func SampleOneIn(n uint32) FunctionListenerSampler {
+	if n <= 1 {
+		return func(context.Context, api.Module, api.FunctionDefinition) bool { return true }
+	}
+	var calls uint32
+	return func(context.Context, api.Module, api.FunctionDefinition) bool {
+		return (atomic.AddUint32(&calls, 1)-1)%n == 0
+	}
+}
+
+// SampleInterval returns a FunctionListenerSampler which samples at most one
+// call per interval, bounding the overhead of listeners regardless of the
+// call rate.
+func SampleInterval(interval time.Duration) FunctionListenerSampler {
+	var next int64 // unix nanos of when the next call can be sampled.
+	return func(context.Context, api.Module, api.FunctionDefinition) bool {
+		now := time.Now().UnixNano()
+		n := atomic.LoadInt64(&next)
+		return now >= n && atomic.CompareAndSwapInt64(&next, n, now+int64(interval))
+	}
+}
+
+// SampledFunctionListenerFactory constructs a FunctionListenerFactory which
+// only passes the calls chosen by the sampler to the listeners of the
+// factory, to bound their overhead.
+//
+// The decision is made in Before, and the same decision is used for the
+// matching After or Abort, even when calls are nested or recursive. In other
+// words, a listener never sees After for a call it didn't see Before for.
+// The decision for each call is independent, so a sampled call may be
+// nested in one that isn't.
+//
+// Note: Decisions are tracked per module instance, so calls made
+// concurrently into different module instances are paired correctly.
+// Concurrent calls into the same module instance are not supported.
+func SampledFunctionListenerFactory(factory FunctionListenerFactory, sampler FunctionListenerSampler) FunctionListenerFactory {
+	return &sampledFunctionListenerFactory{factory: factory, sampler: sampler, stacks: map[api.Module][]bool{}}
+}
+
+type sampledFunctionListenerFactory struct {
+	factory FunctionListenerFactory
+	sampler FunctionListenerSampler
+
+	mu sync.Mutex
+	// stacks are the sampling decisions of the calls in progress, by the
+	// module instance passed to the listener.
+	stacks map[api.Module][]bool
+}
+
+// NewFunctionListener implements FunctionListenerFactory.NewFunctionListener
+func (f *sampledFunctionListenerFactory) NewFunctionListener(def api.FunctionDefinition) FunctionListener {
+	l := f.factory.NewFunctionListener(def)
+	if l == nil {
+		return nil
+	}
+	return &sampledFunctionListener{f: f, delegate: l}
+}
+
+func (f *sampledFunctionListenerFactory) push(mod api.Module, sampled bool) {
+	f.mu.Lock()
+	f.stacks[mod] = append(f.stacks[mod], sampled)
+	f.mu.Unlock()
+}
+
+func (f *sampledFunctionListenerFactory) pop(mod api.Module) (sampled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stack := f.stacks[mod]
+	i := len(stack) - 1
+	if i < 0 {
+		return false // unbalanced, e.g. After without Before.
+	}
+	sampled = stack[i]
+	if i == 0 {
+		delete(f.stacks, mod) // don't retain closed modules.
+	} else {
+		f.stacks[mod] = stack[:i]
+	}
+	return
+}
+
+type sampledFunctionListener struct {
+	f        *sampledFunctionListenerFactory
+	delegate FunctionListener
+}
+
+// Before implements FunctionListener.Before
+func (l *sampledFunctionListener) Before(ctx context.Context, mod api.Module, def api.FunctionDefinition, params []uint64, si StackIterator) {
+	sampled := l.f.sampler(ctx, mod, def)
+	l.f.push(mod, sampled)
+	if sampled {
+		l.delegate.Before(ctx, mod, def, params, si)
+	}
+}
+
+// After implements FunctionListener.After
+func (l *sampledFunctionListener) After(ctx context.Context, mod api.Module, def api.FunctionDefinition, results []uint64) {
+	if l.f.pop(mod) {
+		l.delegate.After(ctx, mod, def, results)
+	}
+}
+
+// Abort implements FunctionListener.Abort
+func (l *sampledFunctionListener) Abort(ctx context.Context, mod api.Module, def api.FunctionDefinition, err error) {
+	if l.f.pop(mod) {
+		l.delegate.Abort(ctx, mod, def, err)
+	}
+}
@@ -0,0 +1,101 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+type pairRecorder struct {
+	befores, afters []uint64
+}
+
+func (r *pairRecorder) Before(_ context.Context, _ api.Module, _ api.FunctionDefinition, params []uint64, _ experimental.StackIterator) {
+	r.befores = append(r.befores, params[0])
+}
+
+func (r *pairRecorder) After(_ context.Context, _ api.Module, _ api.FunctionDefinition, results []uint64) {
+	r.afters = append(r.afters, results[0])
+}
+
+func (r *pairRecorder) Abort(context.Context, api.Module, api.FunctionDefinition, error) {}
+
+func TestSampledFunctionListenerFactory(t *testing.T) {
+	// recurse(n) returns n after calling recurse(n-1) unless n is zero.
+	i32 := api.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{i32}, Results: []api.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeIf, 0x40, // blocktype empty
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub,
+			wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "recurse", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	tests := []struct {
+		name            string
+		sampler         experimental.FunctionListenerSampler
+		befores, afters []uint64
+	}{
+		{
+			name:    "all",
+			sampler: experimental.SampleOneIn(1),
+			befores: []uint64{4, 3, 2, 1, 0},
+			afters:  []uint64{0, 1, 2, 3, 4},
+		},
+		{
+			name:    "one in two",
+			sampler: experimental.SampleOneIn(2),
+			befores: []uint64{4, 2, 0},
+			afters:  []uint64{0, 2, 4},
+		},
+		{
+			name:    "one in three",
+			sampler: experimental.SampleOneIn(3),
+			befores: []uint64{4, 1},
+			afters:  []uint64{1, 4},
+		},
+		{
+			name:    "interval",
+			sampler: experimental.SampleInterval(time.Hour),
+			befores: []uint64{4},
+			afters:  []uint64{4},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &pairRecorder{}
+			factory := experimental.SampledFunctionListenerFactory(
+				experimental.FunctionListenerFactoryFunc(func(api.FunctionDefinition) experimental.FunctionListener {
+					return recorder
+				}), tc.sampler)
+			ctx := context.WithValue(context.Background(), experimental.FunctionListenerFactoryKey{}, factory)
+
+			r := wazero.NewRuntime(ctx)
+			defer r.Close(ctx)
+
+			mod, err := r.Instantiate(ctx, bin)
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("recurse").Call(ctx, 4)
+			require.NoError(t, err)
+			require.Equal(t, tc.befores, recorder.befores)
+			require.Equal(t, tc.afters, recorder.afters)
+		})
+	}
+}
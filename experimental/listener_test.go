@@ -107,6 +107,76 @@ func TestFunctionListenerFactory(t *testing.T) {
 	require.Equal(t, []string{"test.fn2", "test.fn2", "test.fn1"}, factory.afterNames) // after is in the reverse order.
 }
 
+func TestScopedFunctionListenerFactory(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "host", Name: "log"}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "_start", Type: wasm.ExternTypeFunc, Index: 1}},
+		NameSection: &wasm.NameSection{
+			ModuleName:    "test",
+			FunctionNames: wasm.NameMap{{Index: 1, Name: "main"}, {Index: 2, Name: "gc"}},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		scope    experimental.FunctionListenerScope
+		expected []string
+	}{
+		{
+			name:     "all",
+			expected: []string{"host.log", "test.main", "test.gc"},
+		},
+		{
+			name:     "module",
+			scope:    experimental.FunctionListenerScope{ModuleNames: []string{"host"}},
+			expected: []string{"host.log"},
+		},
+		{
+			name:     "export name",
+			scope:    experimental.FunctionListenerScope{FunctionNames: []string{"_start"}},
+			expected: []string{"test.main"},
+		},
+		{
+			name: "module and pattern",
+			scope: experimental.FunctionListenerScope{
+				ModuleNames:   []string{"host", "test"},
+				FunctionNames: []string{"l*", "g?"},
+			},
+			expected: []string{"host.log", "test.gc"},
+		},
+		{
+			name:  "no match",
+			scope: experimental.FunctionListenerScope{ModuleNames: []string{"wasi_snapshot_preview1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var names []string
+			factory := experimental.ScopedFunctionListenerFactory(
+				experimental.FunctionListenerFactoryFunc(func(def api.FunctionDefinition) experimental.FunctionListener {
+					names = append(names, def.DebugName())
+					return nil
+				}), tc.scope)
+			ctx := context.WithValue(context.Background(), experimental.FunctionListenerFactoryKey{}, factory)
+
+			r := wazero.NewRuntime(ctx)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("host").NewFunctionBuilder().WithFunc(func() {}).Export("log").Instantiate(ctx)
+			require.NoError(t, err)
+			_, err = r.CompileModule(ctx, bin)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, names)
+		})
+	}
+}
+
 func TestMultiFunctionListenerFactory(t *testing.T) {
 	module := wazerotest.NewModule(nil,
 		wazerotest.NewFunction(func(ctx context.Context, mod api.Module, value int32) {}),
@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/logging"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	wasilogging "github.com/tetratelabs/wazero/internal/wasip1/logging"
+)
+
+// AuditRecord is a guest-visible side effect written by
+// NewAuditListenerFactory, encoded as a line of JSON.
+type AuditRecord struct {
+	// Module is the name of the module instance which called Function.
+	Module string `json:"module"`
+
+	// Labels are the labels of Module, if any. See experimental.GetLabels.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Function is the debug name of the host function, e.g.
+	// "wasi_snapshot_preview1.path_open".
+	Function string `json:"function"`
+
+	// Params are the formatted parameters by name, e.g. "path" -> "a.txt".
+	Params map[string]string `json:"params,omitempty"`
+
+	// Results are the formatted results by name, e.g. "errno" -> "ESUCCESS".
+	Results map[string]string `json:"results,omitempty"`
+}
+
+// auditedFunctions are the WASI functions which mutate the filesystem, use
+// sockets, or read the environment or clocks.
+var auditedFunctions = map[string]struct{}{
+	wasip1.FdAllocateName:           {},
+	wasip1.FdFilestatSetSizeName:    {},
+	wasip1.FdFilestatSetTimesName:   {},
+	wasip1.FdPwriteName:             {},
+	wasip1.FdWriteName:              {},
+	wasip1.PathCreateDirectoryName:  {},
+	wasip1.PathFilestatSetTimesName: {},
+	wasip1.PathLinkName:             {},
+	wasip1.PathOpenName:             {},
+	wasip1.PathRemoveDirectoryName:  {},
+	wasip1.PathRenameName:           {},
+	wasip1.PathSymlinkName:          {},
+	wasip1.PathUnlinkFileName:       {},
+	wasip1.SockAcceptName:           {},
+	wasip1.SockRecvName:             {},
+	wasip1.SockSendName:             {},
+	wasip1.SockShutdownName:         {},
+	wasip1.EnvironGetName:           {},
+	wasip1.EnvironSizesGetName:      {},
+	wasip1.ClockResGetName:          {},
+	wasip1.ClockTimeGetName:         {},
+}
+
+// NewAuditListenerFactory is an experimental.FunctionListenerFactory that
+// writes an AuditRecord to the writer for each WASI call which has a
+// guest-visible side effect: filesystem mutations, including opening files,
+// socket operations, and reads of environment variables or clocks.
+//
+// Unlike NewHostLoggingListenerFactory, each record is a line of JSON, so
+// the output can be retained as evidence of what a third-party module did.
+// Records are written after the call returns, so they include its results.
+//
+// Note: Like LogScopeFilesystem, writes to the console are not audited.
+func NewAuditListenerFactory(w io.Writer) experimental.FunctionListenerFactory {
+	return &auditListenerFactory{w: w}
+}
+
+type auditListenerFactory struct {
+	// mu serializes writes to w.
+	mu     sync.Mutex
+	w      io.Writer
+	frames moduleStacks[[]uint64]
+}
+
+// NewFunctionListener implements the same method as documented on
+// experimental.FunctionListener.
+func (f *auditListenerFactory) NewFunctionListener(fnd api.FunctionDefinition) experimental.FunctionListener {
+	if fnd.ModuleName() != wasip1.InternalModuleName {
+		return nil
+	}
+	if _, ok := auditedFunctions[fnd.Name()]; !ok {
+		return nil
+	}
+	pSampler, pLoggers, rLoggers := wasilogging.Config(fnd)
+	return &auditListener{f: f, pSampler: pSampler, pLoggers: pLoggers, rLoggers: rLoggers}
+}
+
+// auditListener implements experimental.FunctionListener to write an
+// AuditRecord after each function call.
+type auditListener struct {
+	f        *auditListenerFactory
+	pSampler logging.ParamSampler
+	pLoggers []logging.ParamLogger
+	rLoggers []logging.ResultLogger
+}
+
+// Before implements the same method as documented on
+// experimental.FunctionListener.
+func (l *auditListener) Before(ctx context.Context, mod api.Module, _ api.FunctionDefinition, params []uint64, _ experimental.StackIterator) {
+	if s := l.pSampler; s != nil && !s(ctx, mod, params) {
+		params = nil
+	} else {
+		params = append([]uint64{}, params...)
+	}
+	l.f.frames.push(mod, params)
+}
+
+// After implements the same method as documented on
+// experimental.FunctionListener.
+func (l *auditListener) After(ctx context.Context, mod api.Module, def api.FunctionDefinition, results []uint64) {
+	params := l.f.frames.pop(mod)
+	if params == nil {
+		return
+	}
+
+	r := &AuditRecord{Module: mod.Name(), Labels: experimental.GetLabels(mod), Function: def.DebugName()}
+	var buf bytes.Buffer
+	for _, pLogger := range l.pLoggers {
+		buf.Reset()
+		pLogger(ctx, mod, &buf, params)
+		r.Params = addAuditValue(r.Params, buf.String())
+	}
+	for _, rLogger := range l.rLoggers {
+		buf.Reset()
+		rLogger(ctx, mod, &buf, params, results)
+		r.Results = addAuditValue(r.Results, buf.String())
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	l.f.mu.Lock()
+	defer l.f.mu.Unlock()
+	l.f.w.Write(append(line, '\n')) //nolint
+}
+
+// Abort implements the same method as documented on
+// experimental.FunctionListener.
+func (l *auditListener) Abort(_ context.Context, mod api.Module, _ api.FunctionDefinition, _ error) {
+	l.f.frames.pop(mod)
+}
+
+// addAuditValue adds a value formatted like "name=value" to the map.
+func addAuditValue(m map[string]string, formatted string) map[string]string {
+	name, value, ok := strings.Cut(formatted, "=")
+	if !ok {
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	m[name] = value
+	return m
+}
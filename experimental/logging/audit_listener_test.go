@@ -0,0 +1,101 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestNewAuditListenerFactory(t *testing.T) {
+	var out bytes.Buffer
+	ctx := context.WithValue(testCtx, experimental.FunctionListenerFactoryKey{},
+		logging.NewAuditListenerFactory(&out))
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	i32 := api.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []api.ValueType{i32, i32}, Results: []api.ValueType{i32}},
+			{},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "wasi_snapshot_preview1", Name: "environ_sizes_get", Type: api.ExternTypeFunc, DescFunc: 0},
+			{Module: "wasi_snapshot_preview1", Name: "random_get", Type: api.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{1},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 4, wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+			// random_get isn't audited.
+			wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 4, wasm.OpcodeCall, 1, wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1},
+		ExportSection: []wasm.Export{
+			{Name: "run", Type: api.ExternTypeFunc, Index: 2},
+			{Name: "memory", Type: api.ExternTypeMemory, Index: 0},
+		},
+	})
+
+	mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().
+		WithName("guest").WithEnv("a", "b").WithLabel("tenant", "acme"))
+	require.NoError(t, err)
+
+	_, err = mod.ExportedFunction("run").Call(ctx)
+	require.NoError(t, err)
+
+	var record logging.AuditRecord
+	require.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	require.Equal(t, logging.AuditRecord{
+		Module:   "guest",
+		Labels:   map[string]string{"tenant": "acme"},
+		Function: "wasi_snapshot_preview1.environ_sizes_get",
+		Params:   map[string]string{"result.environc": "0", "result.environv_len": "4"},
+		Results:  map[string]string{"errno": "ESUCCESS"},
+	}, record)
+}
+
+func TestNewAuditListenerFactory_interleaved(t *testing.T) {
+	var out bytes.Buffer
+	factory := logging.NewAuditListenerFactory(&out)
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{MemorySection: &wasm.Memory{Min: 1}})
+	a, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+	b, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+
+	def := r.Module(wasi_snapshot_preview1.ModuleName).ExportedFunctionDefinitions()["clock_time_get"]
+	l := factory.NewFunctionListener(def)
+
+	// Calls into different modules, e.g. on different goroutines, don't pop
+	// the parameters of each other.
+	l.Before(testCtx, a, def, []uint64{0, 0, 0}, nil) // realtime
+	l.Before(testCtx, b, def, []uint64{1, 0, 0}, nil) // monotonic
+	l.After(testCtx, a, def, []uint64{0})
+	l.After(testCtx, b, def, []uint64{0})
+
+	dec := json.NewDecoder(&out)
+	for _, expected := range []struct{ module, id string }{{"a", "realtime"}, {"b", "monotonic"}} {
+		var record logging.AuditRecord
+		require.NoError(t, dec.Decode(&record))
+		require.Equal(t, expected.module, record.Module)
+		require.Equal(t, expected.id, record.Params["id"])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"io"
+	"sync"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
@@ -78,6 +79,17 @@ func NewHostLoggingListenerFactory(w Writer, scopes logging.LogScopes) experimen
 	return &loggingListenerFactory{w: toInternalWriter(w), hostOnly: true, scopes: scopes}
 }
 
+// NewFilteredLoggingListenerFactory is an
+// experimental.FunctionListenerFactory that logs functions in the scopes to
+// the writer, constrained by the rules.
+//
+// This is an alternative to NewLoggingListenerFactory that can be left
+// enabled in production, as the rules can exclude noisy functions and redact
+// sensitive values such as paths or memory contents.
+func NewFilteredLoggingListenerFactory(w Writer, scopes logging.LogScopes, rules Rules) experimental.FunctionListenerFactory {
+	return &loggingListenerFactory{w: toInternalWriter(w), scopes: scopes, rules: rules}
+}
+
 func toInternalWriter(w Writer) logging.Writer {
 	if w, ok := w.(logging.Writer); ok {
 		return w
@@ -89,6 +101,7 @@ type loggingListenerFactory struct {
 	w        logging.Writer
 	hostOnly bool
 	scopes   logging.LogScopes
+	rules    Rules
 	stack    logStack
 }
 
@@ -106,59 +119,117 @@ func (f *loggingListenerFactory) NewFunctionListener(fnd api.FunctionDefinition)
 		return nil
 	}
 
-	var pLoggers []logging.ParamLogger
-	var pSampler logging.ParamSampler
-	var rLoggers []logging.ResultLogger
+	if !f.rules.includes(fnd) {
+		return nil
+	}
+	pSampler, pLoggers, rLoggers, ok := loggerConfig(fnd, f.scopes)
+	if !ok {
+		return nil
+	}
+	pLoggers, rLoggers = f.rules.redact(fnd, pLoggers, rLoggers)
+
+	var before, after string
+	if fnd.GoFunction() != nil {
+		before = "==> " + fnd.DebugName()
+		after = "<=="
+	} else {
+		before = "--> " + fnd.DebugName()
+		after = "<--"
+	}
+	return &loggingListener{
+		w:            f.w,
+		beforePrefix: before,
+		afterPrefix:  after,
+		pLoggers:     pLoggers,
+		pSampler:     pSampler,
+		rLoggers:     rLoggers,
+		stack:        &f.stack,
+	}
+}
+
+// loggerConfig returns the parameter and result loggers of the function, or
+// false if it is not in the log scopes.
+func loggerConfig(fnd api.FunctionDefinition, scopes logging.LogScopes) (
+	pSampler logging.ParamSampler,
+	pLoggers []logging.ParamLogger,
+	rLoggers []logging.ResultLogger,
+	ok bool,
+) {
 	switch fnd.ModuleName() {
 	case wasip1.InternalModuleName:
-		if !wasilogging.IsInLogScope(fnd, f.scopes) {
-			return nil
+		if !wasilogging.IsInLogScope(fnd, scopes) {
+			return
 		}
 		pSampler, pLoggers, rLoggers = wasilogging.Config(fnd)
 	case "go", "gojs":
-		if !gologging.IsInLogScope(fnd, f.scopes) {
-			return nil
+		if !gologging.IsInLogScope(fnd, scopes) {
+			return
 		}
-		pSampler, pLoggers, rLoggers = gologging.Config(fnd, f.scopes)
+		pSampler, pLoggers, rLoggers = gologging.Config(fnd, scopes)
 	case "env":
 		// env is difficult because the same module name is used for different
 		// ABI.
 		pLoggers, rLoggers = logging.Config(fnd)
 		switch fnd.Name() {
 		case "emscripten_notify_memory_growth":
-			if !logging.LogScopeMemory.IsEnabled(f.scopes) {
-				return nil
+			if !logging.LogScopeMemory.IsEnabled(scopes) {
+				return
 			}
 		default:
-			if !aslogging.IsInLogScope(fnd, f.scopes) {
-				return nil
+			if !aslogging.IsInLogScope(fnd, scopes) {
+				return
 			}
 		}
 	default:
 		// We don't know the scope of the function, so compare against all.
-		if f.scopes != logging.LogScopeAll {
-			return nil
+		if scopes != logging.LogScopeAll {
+			return
 		}
 		pLoggers, rLoggers = logging.Config(fnd)
 	}
+	ok = true
+	return
+}
 
-	var before, after string
-	if fnd.GoFunction() != nil {
-		before = "==> " + fnd.DebugName()
-		after = "<=="
-	} else {
-		before = "--> " + fnd.DebugName()
-		after = "<--"
+// moduleStacks holds a stack of frames per calling module instance, so that
+// calls into different instances, e.g. one per goroutine, don't pop the
+// frames of each other.
+//
+// Note: Listeners can't otherwise tell calls apart, so concurrent calls into
+// the same module instance share a stack.
+type moduleStacks[T any] struct {
+	mu     sync.Mutex
+	stacks map[api.Module][]T
+}
+
+func (s *moduleStacks[T]) push(mod api.Module, frame T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stacks == nil {
+		s.stacks = map[api.Module][]T{}
 	}
-	return &loggingListener{
-		w:            f.w,
-		beforePrefix: before,
-		afterPrefix:  after,
-		pLoggers:     pLoggers,
-		pSampler:     pSampler,
-		rLoggers:     rLoggers,
-		stack:        &f.stack,
+	s.stacks[mod] = append(s.stacks[mod], frame)
+}
+
+// pop returns the last frame pushed for the module, or the zero value if
+// there is none.
+func (s *moduleStacks[T]) pop(mod api.Module) (frame T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stack := s.stacks[mod]
+	i := len(stack) - 1
+	if i < 0 {
+		return
+	}
+	frame = stack[i]
+	if i == 0 { // Don't retain the module after its last frame.
+		delete(s.stacks, mod)
+	} else {
+		var zero T
+		stack[i] = zero
+		s.stacks[mod] = stack[:i]
 	}
+	return
 }
 
 type logStack struct {
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/logging"
+)
+
+// redacted is written in place of a value matched by Rules.Redact.
+const redacted = "<redacted>"
+
+// Rules constrain which functions are logged and which of their values are
+// written, so that a listener can stay enabled without leaking sensitive
+// data.
+//
+// Patterns use the syntax of path.Match and are matched against the debug
+// name of the function, e.g. "wasi_snapshot_preview1.path_open". Malformed
+// patterns never match.
+//
+// For example, this logs filesystem functions except writes, and never logs
+// paths or how many bytes were read:
+//
+//	rules := logging.Rules{
+//		Include: []string{"wasi_snapshot_preview1.fd_*", "wasi_snapshot_preview1.path_*"},
+//		Exclude: []string{"wasi_snapshot_preview1.fd_write"},
+//		Redact:  []string{"*.path", "wasi_snapshot_preview1.fd_read.nread"},
+//	}
+type Rules struct {
+	// Include are the patterns of functions to log. When empty, all functions
+	// in scope are logged.
+	Include []string
+
+	// Exclude are the patterns of functions to never log, even if they match
+	// Include.
+	Exclude []string
+
+	// Redact are the patterns of values to replace with "<redacted>". Each is
+	// matched against the function name joined by a dot with the name of the
+	// parameter or result, as it is logged. For example, the parameter "path"
+	// of path_open is "wasi_snapshot_preview1.path_open.path", and the result
+	// "nread" of fd_read is "wasi_snapshot_preview1.fd_read.nread".
+	Redact []string
+}
+
+// includes returns true if the function should be logged.
+func (r *Rules) includes(fnd api.FunctionDefinition) bool {
+	name := fnd.DebugName()
+	if len(r.Include) > 0 && !matchAny(r.Include, name) {
+		return false
+	}
+	return !matchAny(r.Exclude, name)
+}
+
+// redact wraps the loggers of the function so that values matching Redact
+// are replaced with "<redacted>".
+func (r *Rules) redact(fnd api.FunctionDefinition, pLoggers []logging.ParamLogger, rLoggers []logging.ResultLogger) ([]logging.ParamLogger, []logging.ResultLogger) {
+	if len(r.Redact) == 0 {
+		return pLoggers, rLoggers
+	}
+	prefix := fnd.DebugName() + "."
+
+	redactedP := make([]logging.ParamLogger, len(pLoggers))
+	for i, pLogger := range pLoggers {
+		pLogger := pLogger
+		redactedP[i] = func(ctx context.Context, mod api.Module, w logging.Writer, params []uint64) {
+			var buf bytes.Buffer
+			pLogger(ctx, mod, &buf, params)
+			r.writeValue(w, prefix, buf.String())
+		}
+	}
+	redactedR := make([]logging.ResultLogger, len(rLoggers))
+	for i, rLogger := range rLoggers {
+		rLogger := rLogger
+		redactedR[i] = func(ctx context.Context, mod api.Module, w logging.Writer, params, results []uint64) {
+			var buf bytes.Buffer
+			rLogger(ctx, mod, &buf, params, results)
+			r.writeValue(w, prefix, buf.String())
+		}
+	}
+	return redactedP, redactedR
+}
+
+// writeValue writes the value formatted like "name=value", redacting it if
+// the name matches.
+func (r *Rules) writeValue(w logging.Writer, prefix, formatted string) {
+	if name, _, ok := strings.Cut(formatted, "="); ok && matchAny(r.Redact, prefix+name) {
+		w.WriteString(name)     //nolint
+		w.WriteByte('=')        //nolint
+		w.WriteString(redacted) //nolint
+		return
+	}
+	w.WriteString(formatted) //nolint
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
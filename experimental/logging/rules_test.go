@@ -0,0 +1,184 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestNewFilteredLoggingListenerFactory(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    logging.Rules
+		expected string
+	}{
+		{
+			name: "no rules",
+			expected: `--> .$2()
+	==> host.add(secret=1,y=2)
+	<== sum=3
+	==> host.sub(secret=1,y=2)
+	<== diff=-1
+<--
+`,
+		},
+		{
+			name:  "include",
+			rules: logging.Rules{Include: []string{"host.*"}},
+			expected: `==> host.add(secret=1,y=2)
+<== sum=3
+==> host.sub(secret=1,y=2)
+<== diff=-1
+`,
+		},
+		{
+			name:  "exclude",
+			rules: logging.Rules{Include: []string{"host.*"}, Exclude: []string{"host.sub"}},
+			expected: `==> host.add(secret=1,y=2)
+<== sum=3
+`,
+		},
+		{
+			name:  "malformed pattern",
+			rules: logging.Rules{Include: []string{"host.["}},
+		},
+		{
+			name:  "redact",
+			rules: logging.Rules{Include: []string{"host.*"}, Redact: []string{"*.secret", "host.sub.diff"}},
+			expected: `==> host.add(secret=<redacted>,y=2)
+<== sum=3
+==> host.sub(secret=<redacted>,y=2)
+<== diff=<redacted>
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			ctx := context.WithValue(testCtx, experimental.FunctionListenerFactoryKey{},
+				logging.NewFilteredLoggingListenerFactory(&out, logging.LogScopeAll, tc.rules))
+
+			r := wazero.NewRuntime(ctx)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("host").
+				NewFunctionBuilder().
+				WithFunc(func(x, y uint32) uint32 { return x + y }).
+				WithParameterNames("secret", "y").
+				WithResultNames("sum").
+				Export("add").
+				NewFunctionBuilder().
+				WithFunc(func(x, y uint32) uint32 { return x - y }).
+				WithParameterNames("secret", "y").
+				WithResultNames("diff").
+				Export("sub").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			i32 := api.ValueTypeI32
+			bin := binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection: []wasm.FunctionType{
+					{Params: []api.ValueType{i32, i32}, Results: []api.ValueType{i32}},
+					{},
+				},
+				ImportSection: []wasm.Import{
+					{Module: "host", Name: "add", Type: api.ExternTypeFunc, DescFunc: 0},
+					{Module: "host", Name: "sub", Type: api.ExternTypeFunc, DescFunc: 0},
+				},
+				FunctionSection: []wasm.Index{1},
+				CodeSection: []wasm.Code{{Body: []byte{
+					wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+					wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeCall, 1, wasm.OpcodeDrop,
+					wasm.OpcodeEnd,
+				}}},
+				ExportSection: []wasm.Export{{Name: "run", Type: api.ExternTypeFunc, Index: 2}},
+			})
+			mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().WithName("guest"))
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("run").Call(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+// TestRules_documentedExample ensures the example in the Rules documentation
+// matches the names logged by wasi_snapshot_preview1.
+func TestRules_documentedExample(t *testing.T) {
+	rules := logging.Rules{
+		Include: []string{"wasi_snapshot_preview1.fd_*", "wasi_snapshot_preview1.path_*"},
+		Exclude: []string{"wasi_snapshot_preview1.fd_write"},
+		Redact:  []string{"*.path", "wasi_snapshot_preview1.fd_read.nread"},
+	}
+
+	var out bytes.Buffer
+	ctx := context.WithValue(testCtx, experimental.FunctionListenerFactoryKey{},
+		logging.NewFilteredLoggingListenerFactory(&out, logging.LogScopeAll, rules))
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+
+	i32, i64 := api.ValueTypeI32, api.ValueTypeI64
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []api.ValueType{i32, i32, i32, i32, i32, i64, i64, i32, i32}, Results: []api.ValueType{i32}},
+			{Params: []api.ValueType{i32, i32, i32, i32}, Results: []api.ValueType{i32}},
+			{},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "wasi_snapshot_preview1", Name: "path_open", Type: api.ExternTypeFunc, DescFunc: 0},
+			{Module: "wasi_snapshot_preview1", Name: "fd_read", Type: api.ExternTypeFunc, DescFunc: 1},
+			{Module: "wasi_snapshot_preview1", Name: "fd_write", Type: api.ExternTypeFunc, DescFunc: 1},
+		},
+		MemorySection:   &wasm.Memory{Min: 1, Max: 1},
+		FunctionSection: []wasm.Index{2},
+		CodeSection: []wasm.Code{{Body: []byte{
+			// path_open(fd=3, dirflags=0, path=0, path_len=4, oflags=0, rights=0, inheriting=0, fdflags=0, result.fd=16)
+			wasm.OpcodeI32Const, 3, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 4,
+			wasm.OpcodeI32Const, 0, wasm.OpcodeI64Const, 0, wasm.OpcodeI64Const, 0, wasm.OpcodeI32Const, 0,
+			wasm.OpcodeI32Const, 16, wasm.OpcodeCall, 0, wasm.OpcodeDrop,
+			// fd_read(fd=result.fd, iovs=24, iovs_len=1, result.nread=40)
+			wasm.OpcodeI32Const, 16, wasm.OpcodeI32Load, 2, 0,
+			wasm.OpcodeI32Const, 24, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 40,
+			wasm.OpcodeCall, 1, wasm.OpcodeDrop,
+			// fd_write(fd=1, iovs=24, iovs_len=1, result.nwritten=40)
+			wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 24, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 40,
+			wasm.OpcodeCall, 2, wasm.OpcodeDrop,
+			wasm.OpcodeEnd,
+		}}},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("file")},
+			// iovs[0] = {buf=64, buf_len=8}
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{24}}, Init: []byte{64, 0, 0, 0, 8, 0, 0, 0}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "run", Type: api.ExternTypeFunc, Index: 3},
+			{Name: "memory", Type: api.ExternTypeMemory, Index: 0},
+		},
+	})
+	fsConfig := wazero.NewFSConfig().WithFSMount(fstest.MapFS{"file": {Data: []byte("secret")}}, "/")
+	mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().WithName("guest").WithFSConfig(fsConfig))
+	require.NoError(t, err)
+
+	_, err = mod.ExportedFunction("run").Call(ctx)
+	require.NoError(t, err)
+	require.Equal(t, `==> wasi_snapshot_preview1.path_open(fd=3,dirflags=,path=<redacted>,oflags=,fs_rights_base=,fs_rights_inheriting=,fdflags=)
+<== (opened_fd=4,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=24,iovs_len=1)
+<== (nread=<redacted>,errno=ESUCCESS)
+`, out.String())
+}
@@ -0,0 +1,243 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/logging"
+)
+
+// Attr is a formatted parameter or result of a function call, e.g. "path"
+// -> "a.txt". It maps directly to a string attribute of structured logging
+// libraries, such as slog.String.
+type Attr struct {
+	Key, Value string
+}
+
+// Record is a function call logged by NewStructuredLoggingListenerFactory.
+//
+// For example, a Logger could write it to a log/slog.Logger like this:
+//
+//	attrs := []slog.Attr{
+//		slog.String("module", r.Module),
+//		slog.String("function", r.Function),
+//		slog.Duration("duration", r.Duration),
+//	}
+//	for _, p := range r.Params {
+//		attrs = append(attrs, slog.String("param."+p.Key, p.Value))
+//	}
+//	if r.Err != nil {
+//		attrs = append(attrs, slog.Any("error", r.Err))
+//	}
+//	logger.LogAttrs(ctx, slog.LevelInfo, "wasm call", attrs...)
+type Record struct {
+	// Time is when the function was called.
+	Time time.Time
+
+	// Module is the name of the module instance which called Function.
+	Module string
+
+	// Labels are the labels of Module, or nil. See experimental.GetLabels.
+	Labels map[string]string
+
+	// Function is the debug name of the function, e.g.
+	// "wasi_snapshot_preview1.path_open".
+	Function string
+
+	// Params are the formatted parameters in order, after redaction.
+	// Unnamed parameters are keyed by their index, e.g. "0".
+	Params []Attr
+
+	// Results are the formatted results in order. This is empty when Err is
+	// not nil.
+	Results []Attr
+
+	// Duration is how long the function took, including any functions it
+	// called.
+	Duration time.Duration
+
+	// Err is the error the function aborted with, or nil if it returned.
+	Err error
+}
+
+// Logger receives a Record for each function call logged by
+// NewStructuredLoggingListenerFactory.
+//
+// Note: Records are passed in the order calls return, so nested calls are
+// logged before their caller.
+type Logger interface {
+	// Log is called after the function returns or aborts. The record must
+	// not be retained after this returns.
+	Log(ctx context.Context, r *Record)
+}
+
+// LoggerFunc is a convenience for defining a Logger as a function.
+type LoggerFunc func(ctx context.Context, r *Record)
+
+// Log implements Logger.Log
+func (f LoggerFunc) Log(ctx context.Context, r *Record) {
+	f(ctx, r)
+}
+
+// StructuredConfig configures NewStructuredLoggingListenerFactory.
+type StructuredConfig struct {
+	// Scopes are the host function groups to log. Defaults to LogScopeAll
+	// when zero.
+	Scopes LogScopes
+
+	// HostOnly logs only exported and host functions, like
+	// NewHostLoggingListenerFactory.
+	HostOnly bool
+
+	// Rules constrain which functions are logged and redact their values.
+	Rules Rules
+
+	// Redact, when not nil, returns the value to log in place of the
+	// formatted parameter `key` of the function. e.g. "<redacted>".
+	//
+	// Note: This is called after Rules.Redact, for cases patterns can't
+	// express, such as redacting a value based on its content.
+	Redact func(fnd api.FunctionDefinition, key, value string) string
+
+	// SampleRate logs one of every SampleRate calls to each function,
+	// starting with the first. Defaults to logging every call when zero or
+	// one.
+	//
+	// Note: A sampled-out call doesn't prevent the functions it calls from
+	// being logged.
+	SampleRate uint32
+}
+
+// NewStructuredLoggingListenerFactory is an
+// experimental.FunctionListenerFactory that passes a Record to the logger
+// for each function call.
+//
+// Unlike NewLoggingListenerFactory, which writes human-readable text, this
+// is intended for production use: the logger decides how to encode records,
+// sensitive parameters can be redacted, and calls can be sampled.
+func NewStructuredLoggingListenerFactory(logger Logger, config StructuredConfig) experimental.FunctionListenerFactory {
+	if config.Scopes == LogScopeNone {
+		config.Scopes = LogScopeAll
+	}
+	return &structuredListenerFactory{logger: logger, config: config}
+}
+
+type structuredListenerFactory struct {
+	logger Logger
+	config StructuredConfig
+	frames moduleStacks[*structuredFrame]
+}
+
+// structuredFrame is the state of a call between Before and After, or nil
+// if the call isn't sampled.
+type structuredFrame struct {
+	start  time.Time
+	params []uint64
+}
+
+// NewFunctionListener implements the same method as documented on
+// experimental.FunctionListener.
+func (f *structuredListenerFactory) NewFunctionListener(fnd api.FunctionDefinition) experimental.FunctionListener {
+	if f.config.HostOnly && fnd.GoFunction() == nil && len(fnd.ExportNames()) == 0 {
+		return nil
+	}
+	if !f.config.Rules.includes(fnd) {
+		return nil
+	}
+	pSampler, pLoggers, rLoggers, ok := loggerConfig(fnd, f.config.Scopes)
+	if !ok {
+		return nil
+	}
+	pLoggers, rLoggers = f.config.Rules.redact(fnd, pLoggers, rLoggers)
+	return &structuredListener{f: f, pSampler: pSampler, pLoggers: pLoggers, rLoggers: rLoggers}
+}
+
+// structuredListener implements experimental.FunctionListener to pass a
+// Record to the Logger after each function call.
+type structuredListener struct {
+	f        *structuredListenerFactory
+	pSampler logging.ParamSampler
+	pLoggers []logging.ParamLogger
+	rLoggers []logging.ResultLogger
+	calls    uint32
+}
+
+// sample returns true if the next call should be logged.
+func (l *structuredListener) sample() bool {
+	rate := l.f.config.SampleRate
+	if rate <= 1 {
+		return true
+	}
+	return (atomic.AddUint32(&l.calls, 1)-1)%rate == 0
+}
+
+// Before implements the same method as documented on
+// experimental.FunctionListener.
+func (l *structuredListener) Before(ctx context.Context, mod api.Module, _ api.FunctionDefinition, params []uint64, _ experimental.StackIterator) {
+	if s := l.pSampler; (s != nil && !s(ctx, mod, params)) || !l.sample() {
+		l.f.frames.push(mod, nil)
+		return
+	}
+	l.f.frames.push(mod, &structuredFrame{start: time.Now(), params: append([]uint64{}, params...)})
+}
+
+// After implements the same method as documented on
+// experimental.FunctionListener.
+func (l *structuredListener) After(ctx context.Context, mod api.Module, def api.FunctionDefinition, results []uint64) {
+	if frame := l.f.frames.pop(mod); frame != nil {
+		l.log(ctx, mod, def, frame, results, nil)
+	}
+}
+
+// Abort implements the same method as documented on
+// experimental.FunctionListener.
+func (l *structuredListener) Abort(ctx context.Context, mod api.Module, def api.FunctionDefinition, err error) {
+	if frame := l.f.frames.pop(mod); frame != nil {
+		l.log(ctx, mod, def, frame, nil, err)
+	}
+}
+
+func (l *structuredListener) log(ctx context.Context, mod api.Module, def api.FunctionDefinition, frame *structuredFrame, results []uint64, err error) {
+	r := &Record{
+		Time:     frame.start,
+		Module:   mod.Name(),
+		Labels:   experimental.GetLabels(mod),
+		Function: def.DebugName(),
+		Duration: time.Since(frame.start),
+		Err:      err,
+	}
+
+	var buf bytes.Buffer
+	for i, pLogger := range l.pLoggers {
+		buf.Reset()
+		pLogger(ctx, mod, &buf, frame.params)
+		a := toAttr(i, buf.String())
+		if redact := l.f.config.Redact; redact != nil {
+			a.Value = redact(def, a.Key, a.Value)
+		}
+		r.Params = append(r.Params, a)
+	}
+	if err == nil {
+		for i, rLogger := range l.rLoggers {
+			buf.Reset()
+			rLogger(ctx, mod, &buf, frame.params, results)
+			r.Results = append(r.Results, toAttr(i, buf.String()))
+		}
+	}
+	l.f.logger.Log(ctx, r)
+}
+
+// toAttr parses a value formatted like "name=value". Unnamed values are keyed
+// by their index, e.g. "0".
+func toAttr(i int, formatted string) Attr {
+	if key, value, ok := strings.Cut(formatted, "="); ok {
+		return Attr{Key: key, Value: value}
+	}
+	return Attr{Key: strconv.Itoa(i), Value: formatted}
+}
@@ -0,0 +1,213 @@
+package logging_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestNewStructuredLoggingListenerFactory(t *testing.T) {
+	errBoom := errors.New("boom")
+	redact := func(_ api.FunctionDefinition, key, value string) string {
+		if key == "secret" {
+			return "<redacted>"
+		}
+		return value
+	}
+
+	tests := []struct {
+		name     string
+		config   logging.StructuredConfig
+		function string
+		calls    int
+		expected []logging.Record
+	}{
+		{
+			name:     "add",
+			function: "add",
+			calls:    1,
+			expected: []logging.Record{{
+				Module:   "guest",
+				Function: "host.add",
+				Params:   []logging.Attr{{Key: "secret", Value: "1"}, {Key: "y", Value: "2"}},
+				Results:  []logging.Attr{{Key: "sum", Value: "3"}},
+			}},
+		},
+		{
+			name:     "redacted",
+			config:   logging.StructuredConfig{Redact: redact},
+			function: "add",
+			calls:    1,
+			expected: []logging.Record{{
+				Module:   "guest",
+				Function: "host.add",
+				Params:   []logging.Attr{{Key: "secret", Value: "<redacted>"}, {Key: "y", Value: "2"}},
+				Results:  []logging.Attr{{Key: "sum", Value: "3"}},
+			}},
+		},
+		{
+			name:     "sampled",
+			config:   logging.StructuredConfig{SampleRate: 2},
+			function: "add",
+			calls:    3,
+			expected: []logging.Record{
+				{
+					Module:   "guest",
+					Function: "host.add",
+					Params:   []logging.Attr{{Key: "secret", Value: "1"}, {Key: "y", Value: "2"}},
+					Results:  []logging.Attr{{Key: "sum", Value: "3"}},
+				},
+				{
+					Module:   "guest",
+					Function: "host.add",
+					Params:   []logging.Attr{{Key: "secret", Value: "1"}, {Key: "y", Value: "2"}},
+					Results:  []logging.Attr{{Key: "sum", Value: "3"}},
+				},
+			},
+		},
+		{
+			name:     "aborted",
+			function: "fail",
+			calls:    1,
+			expected: []logging.Record{{
+				Module:   "guest",
+				Function: "host.fail",
+				Err:      errBoom,
+			}},
+		},
+		{
+			name:     "out of scope",
+			config:   logging.StructuredConfig{Scopes: logging.LogScopeRandom},
+			function: "add",
+			calls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var records []logging.Record
+			logger := logging.LoggerFunc(func(_ context.Context, r *logging.Record) {
+				require.False(t, r.Time.IsZero())
+				require.True(t, r.Duration >= 0)
+				if strings.HasPrefix(r.Function, "host.") { // skip the guest callers
+					records = append(records, *r)
+				}
+			})
+			ctx := context.WithValue(testCtx, experimental.FunctionListenerFactoryKey{},
+				logging.NewStructuredLoggingListenerFactory(logger, tc.config))
+
+			r := wazero.NewRuntime(ctx)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("host").
+				NewFunctionBuilder().
+				WithFunc(func(x, y uint32) uint32 { return x + y }).
+				WithParameterNames("secret", "y").
+				WithResultNames("sum").
+				Export("add").
+				NewFunctionBuilder().
+				WithFunc(func() { panic(errBoom) }).
+				Export("fail").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			i32 := api.ValueTypeI32
+			bin := binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection: []wasm.FunctionType{
+					{Params: []api.ValueType{i32, i32}, Results: []api.ValueType{i32}},
+					{},
+				},
+				ImportSection: []wasm.Import{
+					{Module: "host", Name: "add", Type: api.ExternTypeFunc, DescFunc: 0},
+					{Module: "host", Name: "fail", Type: api.ExternTypeFunc, DescFunc: 1},
+				},
+				FunctionSection: []wasm.Index{1, 1},
+				CodeSection: []wasm.Code{
+					{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeCall, 0, wasm.OpcodeDrop, wasm.OpcodeEnd}},
+					{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+				},
+				ExportSection: []wasm.Export{
+					{Name: "add", Type: api.ExternTypeFunc, Index: 2},
+					{Name: "fail", Type: api.ExternTypeFunc, Index: 3},
+				},
+			})
+			mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().WithName("guest"))
+			require.NoError(t, err)
+
+			for i := 0; i < tc.calls; i++ {
+				_, err = mod.ExportedFunction(tc.function).Call(ctx)
+				if tc.function == "fail" {
+					require.ErrorIs(t, err, errBoom)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+
+			require.Equal(t, len(tc.expected), len(records))
+			for i, expected := range tc.expected {
+				actual := records[i]
+				require.Equal(t, expected.Module, actual.Module)
+				require.Equal(t, expected.Function, actual.Function)
+				require.Equal(t, expected.Params, actual.Params)
+				require.Equal(t, expected.Results, actual.Results)
+				if expected.Err != nil {
+					require.ErrorIs(t, actual.Err, expected.Err)
+				} else {
+					require.NoError(t, actual.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStructuredLoggingListenerFactory_interleaved(t *testing.T) {
+	var records []logging.Record
+	logger := logging.LoggerFunc(func(_ context.Context, r *logging.Record) {
+		records = append(records, *r)
+	})
+	factory := logging.NewStructuredLoggingListenerFactory(logger, logging.StructuredConfig{})
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().
+		WithFunc(func(x, y uint32) uint32 { return x + y }).
+		WithParameterNames("x", "y").
+		Export("add").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{})
+	a, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+	b, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+
+	def := host.ExportedFunctionDefinitions()["add"]
+	l := factory.NewFunctionListener(def)
+
+	// Calls from different modules, e.g. on different goroutines, don't pop
+	// the frames of each other.
+	l.Before(testCtx, a, def, []uint64{1, 2}, nil)
+	l.Before(testCtx, b, def, []uint64{3, 4}, nil)
+	l.After(testCtx, a, def, []uint64{3})
+	l.Abort(testCtx, b, def, errors.New("boom"))
+
+	require.Equal(t, 2, len(records))
+	require.Equal(t, "a", records[0].Module)
+	require.Equal(t, []logging.Attr{{Key: "x", Value: "1"}, {Key: "y", Value: "2"}}, records[0].Params)
+	require.Equal(t, "b", records[1].Module)
+	require.Equal(t, []logging.Attr{{Key: "x", Value: "3"}, {Key: "y", Value: "4"}}, records[1].Params)
+	require.EqualError(t, records[1].Err, "boom")
+}
@@ -0,0 +1,347 @@
+// Package mailbox includes an experimental host module, which allows module
+// instances in the same runtime to send each other messages, like actors,
+// instead of inventing protocols over shared memory. Each instance has a
+// mailbox, addressed by its module name, which queues the bytes of messages
+// sent to it in order.
+//
+// The guest imports the functions "mailbox_send", "mailbox_recv" and
+// "mailbox_watch" from the module "wazero_mailbox":
+//
+//	(import "wazero_mailbox" "mailbox_send"
+//	  (func $mailbox_send (param $to i32) (param $to_len i32) (param $msg i32) (param $msg_len i32) (result (;errno;) i32)))
+//	(import "wazero_mailbox" "mailbox_recv"
+//	  (func $mailbox_recv (param $from i32) (param $from_len i32) (param $buf i32) (param $buf_len i32) (param $result.from_len i32) (param $result.msg_len i32) (result (;errno;) i32)))
+//	(import "wazero_mailbox" "mailbox_watch"
+//	  (func $mailbox_watch (param $result.fd i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1:
+//
+//   - mailbox_send queues a copy of the message in the mailbox of the module
+//     named `to`. The result is ENOENT if there is no such module, or EAGAIN
+//     if its mailbox is full.
+//   - mailbox_recv waits for the oldest message in the caller's mailbox, and
+//     writes the name of its sender to `from`, and the message to `buf`, and
+//     their lengths to result.from_len and result.msg_len. The result is
+//     ERANGE if either buffer is too small, in which case only the lengths
+//     are written, and the message stays queued, so that the guest can retry
+//     with larger buffers. The result is EINTR if the context.Context of the
+//     call is done while waiting.
+//   - mailbox_watch opens a file descriptor which is readable while messages
+//     are queued for the caller. The guest can wait for it with
+//     "poll_oneoff", alongside other files, before calling mailbox_recv, so
+//     that it doesn't block. Read it like the watch descriptor of
+//     experimental/dynconfig, and close it with "fd_close". Like a
+//     level-triggered poll, it stays readable after it is read, until all
+//     messages are received.
+//
+// Messages from the host, sent with Hub.Send, have an empty sender name.
+//
+// Note: This is experimental, and may change or be removed.
+package mailbox
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name guests import "mailbox_send",
+// "mailbox_recv" and "mailbox_watch" from.
+const ModuleName = "wazero_mailbox"
+
+// Hub holds the mailboxes of module instances. It is safe for concurrent
+// use.
+type Hub struct {
+	capacity int
+
+	mux   sync.Mutex
+	boxes map[string]*mailbox
+}
+
+// NewHub returns a Hub whose mailboxes each queue up to `capacity` messages.
+func NewHub(capacity int) *Hub {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Hub{capacity: capacity, boxes: map[string]*mailbox{}}
+}
+
+type message struct {
+	from string
+	data []byte
+}
+
+type mailbox struct {
+	messages []message
+	watchers []*internalsys.EventFile
+	// changed is closed and replaced when a message is queued, to wake
+	// receivers.
+	changed chan struct{}
+}
+
+// box returns the mailbox of the module name, creating it if needed. This
+// must be called while holding the lock.
+func (h *Hub) box(name string) *mailbox {
+	b, ok := h.boxes[name]
+	if !ok {
+		b = &mailbox{changed: make(chan struct{})}
+		h.boxes[name] = b
+	}
+	return b
+}
+
+// Send queues a copy of the message from the host in the mailbox of the
+// module name, which needn't be instantiated yet. The result is sys.EAGAIN
+// if the mailbox is full.
+func (h *Hub) Send(to string, msg []byte) error {
+	if errno := h.send("", to, msg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (h *Hub) send(from, to string, msg []byte) experimentalsys.Errno {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	b := h.box(to)
+	if len(b.messages) >= h.capacity {
+		return experimentalsys.EAGAIN
+	}
+	b.messages = append(b.messages, message{from: from, data: append([]byte(nil), msg...)})
+	close(b.changed)
+	b.changed = make(chan struct{})
+
+	// Notify watchers, dropping those the guest closed.
+	watchers := b.watchers[:0]
+	for _, w := range b.watchers {
+		if w.Notify() {
+			watchers = append(watchers, w)
+		}
+	}
+	for i := len(watchers); i < len(b.watchers); i++ {
+		b.watchers[i] = nil // for GC
+	}
+	b.watchers = watchers
+	return 0
+}
+
+// Len returns the count of messages queued in the mailbox of the module
+// name.
+func (h *Hub) Len(name string) int {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if b, ok := h.boxes[name]; ok {
+		return len(b.messages)
+	}
+	return 0
+}
+
+// Remove discards the mailbox of the module name, for example, after the
+// module closed, so that a module later instantiated with the same name
+// doesn't receive its messages.
+func (h *Hub) Remove(name string) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if b, ok := h.boxes[name]; ok {
+		for _, w := range b.watchers {
+			_ = w.Close()
+		}
+		delete(h.boxes, name)
+	}
+}
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime, hub *Hub) {
+	if _, err := Instantiate(ctx, r, hub); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime, passing
+// messages between the modules of the runtime via the hub.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime, hub *Hub) (api.Closer, error) {
+	m := &mailboxes{hub: hub, r: r}
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(m.mailboxSend),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("to", "to_len", "msg", "msg_len").
+		WithResultNames("errno").
+		Export("mailbox_send").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(m.mailboxRecv),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("from", "from_len", "buf", "buf_len", "result.from_len", "result.msg_len").
+		WithResultNames("errno").
+		Export("mailbox_recv").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(m.mailboxWatch),
+			[]api.ValueType{api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("result.fd").
+		WithResultNames("errno").
+		Export("mailbox_watch").
+		Instantiate(ctx)
+}
+
+// mailboxes are the host functions of a Hub in one runtime.
+type mailboxes struct {
+	hub *Hub
+	r   wazero.Runtime
+}
+
+func (m *mailboxes) mailboxSend(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(m.mailboxSendFn(mod, stack))
+}
+
+func (m *mailboxes) mailboxSendFn(mod api.Module, params []uint64) wasip1.Errno {
+	to, toLen := uint32(params[0]), uint32(params[1])
+	msg, msgLen := uint32(params[2]), uint32(params[3])
+
+	mem := mod.Memory()
+	toBytes, ok := mem.Read(to, toLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	msgBytes, ok := mem.Read(msg, msgLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	if m.r.Module(string(toBytes)) == nil {
+		return wasip1.ErrnoNoent
+	}
+	return wasip1.ToErrno(m.hub.send(mod.Name(), string(toBytes), msgBytes))
+}
+
+func (m *mailboxes) mailboxRecv(ctx context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(m.mailboxRecvFn(ctx, mod, stack))
+}
+
+func (m *mailboxes) mailboxRecvFn(ctx context.Context, mod api.Module, params []uint64) wasip1.Errno {
+	from, fromLen := uint32(params[0]), uint32(params[1])
+	buf, bufLen := uint32(params[2]), uint32(params[3])
+	resultFromLen, resultMsgLen := uint32(params[4]), uint32(params[5])
+
+	mem := mod.Memory()
+	// Check the results can be written before waiting.
+	if _, ok := mem.ReadUint32Le(resultFromLen); !ok {
+		return wasip1.ErrnoFault
+	}
+	if _, ok := mem.ReadUint32Le(resultMsgLen); !ok {
+		return wasip1.ErrnoFault
+	}
+
+	h := m.hub
+	for {
+		h.mux.Lock()
+		b := h.box(mod.Name())
+		if len(b.messages) == 0 {
+			changed := b.changed
+			h.mux.Unlock()
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return wasip1.ErrnoIntr
+			}
+		}
+
+		next := b.messages[0]
+		mem.WriteUint32Le(resultFromLen, uint32(len(next.from)))
+		mem.WriteUint32Le(resultMsgLen, uint32(len(next.data)))
+		if uint32(len(next.from)) > fromLen || uint32(len(next.data)) > bufLen {
+			h.mux.Unlock()
+			return wasip1.ErrnoRange
+		}
+		if !mem.WriteString(from, next.from) || !mem.Write(buf, next.data) {
+			h.mux.Unlock()
+			return wasip1.ErrnoFault
+		}
+		b.messages[0] = message{} // for GC
+		b.messages = b.messages[1:]
+		if len(b.messages) == 0 {
+			for _, w := range b.watchers {
+				w.Reset()
+			}
+		}
+		h.mux.Unlock()
+		return wasip1.ErrnoSuccess
+	}
+}
+
+func (m *mailboxes) mailboxWatch(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(m.mailboxWatchFn(mod, stack))
+}
+
+func (m *mailboxes) mailboxWatchFn(mod api.Module, params []uint64) wasip1.Errno {
+	resultFd := uint32(params[0])
+
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+
+	// Check the result can be written before opening the descriptor.
+	mem := mod.Memory()
+	if _, ok := mem.ReadUint32Le(resultFd); !ok {
+		return wasip1.ErrnoFault
+	}
+
+	f := &watcher{EventFile: internalsys.NewEventFile(), hub: m.hub, name: mod.Name()}
+	h := m.hub
+	h.mux.Lock()
+	b := h.box(mod.Name())
+	b.watchers = append(b.watchers, f.EventFile)
+	// Like a level-triggered poll, queued messages make it readable.
+	if len(b.messages) > 0 {
+		f.Notify()
+	}
+	h.mux.Unlock()
+
+	fd, errno := sysCtx.FS().InsertFile(f, "mailbox")
+	if errno != 0 {
+		_ = f.Close()
+		return wasip1.ToErrno(errno)
+	}
+	mem.WriteUint32Le(resultFd, uint32(fd))
+	return wasip1.ErrnoSuccess
+}
+
+// watcher is the file opened by mailbox_watch. It is readable while messages
+// are queued in the mailbox of the module `name`: mailbox_recv resets it
+// when the last is received, and reading it re-signals it while any remain.
+type watcher struct {
+	*internalsys.EventFile
+	hub  *Hub
+	name string
+}
+
+// Read implements the same method as documented on sys.File
+func (w *watcher) Read(buf []byte) (int, experimentalsys.Errno) {
+	n, errno := w.EventFile.Read(buf)
+	if errno == 0 {
+		w.hub.mux.Lock()
+		if b, ok := w.hub.boxes[w.name]; ok && len(b.messages) > 0 {
+			w.Notify()
+		}
+		w.hub.mux.Unlock()
+	}
+	return n, errno
+}
@@ -0,0 +1,276 @@
+package mailbox_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/mailbox"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// mailboxWasm exports functions "mailbox_send", "mailbox_recv" and
+// "mailbox_watch", which call the imported ones, and its memory.
+var mailboxWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+		{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: mailbox.ModuleName, Name: "mailbox_send", DescFunc: 0},
+		{Type: wasm.ExternTypeFunc, Module: mailbox.ModuleName, Name: "mailbox_recv", DescFunc: 1},
+		{Type: wasm.ExternTypeFunc, Module: mailbox.ModuleName, Name: "mailbox_watch", DescFunc: 2},
+	},
+	FunctionSection: []wasm.Index{0, 1, 2},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3, wasm.OpcodeLocalGet, 4, wasm.OpcodeLocalGet, 5,
+			wasm.OpcodeCall, 1,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 2, wasm.OpcodeEnd}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	ExportSection: []wasm.Export{
+		{Name: "mailbox_send", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "mailbox_recv", Type: wasm.ExternTypeFunc, Index: 4},
+		{Name: "mailbox_watch", Type: wasm.ExternTypeFunc, Index: 5},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+const (
+	to, msg                     = 0, 32
+	from, buf                   = 64, 96
+	resultFromLen, resultMsgLen = 128, 132
+	resultFd                    = 136
+)
+
+type actor struct {
+	t   *testing.T
+	mod api.Module
+}
+
+func (a *actor) send(name, m string) wasip1.Errno {
+	mem := a.mod.Memory()
+	require.True(a.t, mem.WriteString(to, name))
+	require.True(a.t, mem.WriteString(msg, m))
+	results, err := a.mod.ExportedFunction("mailbox_send").Call(testCtx, to, uint64(len(name)), msg, uint64(len(m)))
+	require.NoError(a.t, err)
+	return wasip1.Errno(results[0])
+}
+
+func (a *actor) recv(ctx context.Context, fromLen, bufLen uint32) (wasip1.Errno, string, string) {
+	results, err := a.mod.ExportedFunction("mailbox_recv").Call(ctx, from, uint64(fromLen), buf, uint64(bufLen), resultFromLen, resultMsgLen)
+	require.NoError(a.t, err)
+	mem := a.mod.Memory()
+	fl, _ := mem.ReadUint32Le(resultFromLen)
+	ml, _ := mem.ReadUint32Le(resultMsgLen)
+	f, _ := mem.Read(from, fl)
+	m, _ := mem.Read(buf, ml)
+	return wasip1.Errno(results[0]), string(f), string(m)
+}
+
+func TestInstantiate(t *testing.T) {
+	hub := mailbox.NewHub(2)
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mailbox.MustInstantiate(testCtx, r, hub)
+
+	instantiate := func(name string) *actor {
+		mod, err := r.InstantiateWithConfig(testCtx, mailboxWasm, wazero.NewModuleConfig().WithName(name))
+		require.NoError(t, err)
+		return &actor{t, mod}
+	}
+	a, b := instantiate("a"), instantiate("b")
+
+	require.Equal(t, wasip1.ErrnoNoent, a.send("c", "hello"))
+
+	// Watch before messages arrive.
+	results, err := b.mod.ExportedFunction("mailbox_watch").Call(testCtx, resultFd)
+	require.NoError(t, err)
+	require.Equal(t, uint64(wasip1.ErrnoSuccess), results[0])
+	fd, _ := b.mod.Memory().ReadUint32Le(resultFd)
+	watch, ok := b.mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(int32(fd))
+	require.True(t, ok)
+	ready, errno := watch.File.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+
+	require.Equal(t, wasip1.ErrnoSuccess, a.send("b", "hello"))
+	require.NoError(t, hub.Send("b", []byte("from host")))
+	require.Equal(t, wasip1.ErrnoAgain, a.send("b", "full"))
+	require.Equal(t, 2, hub.Len("b"))
+
+	ready, errno = watch.File.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// Reading the watch doesn't reset it while messages are queued.
+	var count [8]byte
+	_, errno = watch.File.Read(count[:])
+	require.EqualErrno(t, 0, errno)
+	ready, errno = watch.File.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// Too small: only the lengths are written.
+	errno2, _, _ := b.recv(testCtx, 1, 1)
+	require.Equal(t, wasip1.ErrnoRange, errno2)
+	fl, _ := b.mod.Memory().ReadUint32Le(resultFromLen)
+	ml, _ := b.mod.Memory().ReadUint32Le(resultMsgLen)
+	require.Equal(t, uint32(1), fl)
+	require.Equal(t, uint32(5), ml)
+
+	errno2, f, m := b.recv(testCtx, 32, 32)
+	require.Equal(t, wasip1.ErrnoSuccess, errno2)
+	require.Equal(t, "a", f)
+	require.Equal(t, "hello", m)
+
+	// The watch is still readable, as a message remains.
+	ready, errno = watch.File.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	errno2, f, m = b.recv(testCtx, 32, 32)
+	require.Equal(t, wasip1.ErrnoSuccess, errno2)
+	require.Equal(t, "", f)
+	require.Equal(t, "from host", m)
+
+	// Receiving the last message resets the watch.
+	ready, errno = watch.File.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+
+	t.Run("blocking", func(t *testing.T) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			require.Equal(t, wasip1.ErrnoSuccess, b.send("a", "reply"))
+		}()
+		errno, f, m := a.recv(testCtx, 32, 32)
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+		require.Equal(t, "b", f)
+		require.Equal(t, "reply", m)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(testCtx, 10*time.Millisecond)
+		defer cancel()
+		errno, _, _ := a.recv(ctx, 32, 32)
+		require.Equal(t, wasip1.ErrnoIntr, errno)
+	})
+}
+
+// pollWasm exports "mailbox_watch" and "poll_oneoff", which call the
+// imported ones, and its memory.
+var pollWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}},
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: mailbox.ModuleName, Name: "mailbox_watch", DescFunc: 0},
+		{Type: wasm.ExternTypeFunc, Module: wasi_snapshot_preview1.ModuleName, Name: "poll_oneoff", DescFunc: 1},
+	},
+	FunctionSection: []wasm.Index{0, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeCall, 1,
+			wasm.OpcodeEnd,
+		}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	ExportSection: []wasm.Export{
+		{Name: "mailbox_watch", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "poll_oneoff", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestInstantiate_pollOneoff(t *testing.T) {
+	hub := mailbox.NewHub(1)
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+	mailbox.MustInstantiate(testCtx, r, hub)
+	mod, err := r.InstantiateWithConfig(testCtx, pollWasm, wazero.NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("mailbox_watch").Call(testCtx, resultFd)
+	require.NoError(t, err)
+	require.Equal(t, uint64(wasip1.ErrnoSuccess), results[0])
+	fd, _ := mod.Memory().ReadUint32Le(resultFd)
+
+	// Subscribe to read the watch, or a relative clock which expires at once.
+	const in, out, resultNevents = 0, 128, 256
+	mem := mod.Memory()
+	var subs [96]byte
+	subs[0] = 1 // userdata
+	subs[8] = wasip1.EventTypeFdRead
+	subs[16] = byte(fd)
+	subs[48] = 2 // userdata
+	subs[56] = wasip1.EventTypeClock
+	require.True(t, mem.Write(in, subs[:]))
+
+	// poll returns the userdata of each event which is ready.
+	poll := func() (ready []byte) {
+		results, err := mod.ExportedFunction("poll_oneoff").Call(testCtx, in, out, 2, resultNevents)
+		require.NoError(t, err)
+		require.Equal(t, uint64(wasip1.ErrnoSuccess), results[0])
+		nevents, _ := mem.ReadUint32Le(resultNevents)
+		for i := uint32(0); i < nevents; i++ {
+			userdata, _ := mem.ReadByte(out + i*32)
+			ready = append(ready, userdata)
+		}
+		return
+	}
+
+	// An empty mailbox isn't readable.
+	require.Equal(t, []byte{2}, poll())
+
+	require.NoError(t, hub.Send("a", []byte("hello")))
+	require.Equal(t, []byte{1, 2}, poll())
+}
+
+func TestHub_Remove(t *testing.T) {
+	hub := mailbox.NewHub(1)
+	require.NoError(t, hub.Send("a", []byte("hello")))
+	require.ErrorIs(t, hub.Send("a", []byte("full")), experimentalsys.EAGAIN)
+
+	hub.Remove("a")
+	require.Equal(t, 0, hub.Len("a"))
+	require.NoError(t, hub.Send("a", []byte("hello")))
+}
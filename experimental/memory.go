@@ -0,0 +1,191 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// MemoryGrowthWatchdogKey is a context.Context Value key. Its associated value
+// should be a MemoryGrowthWatchdog.
+//
+// See WithMemoryGrowthWatchdog
+type MemoryGrowthWatchdogKey struct{}
+
+// MemoryGrowthWatchdog traps a guest that grows its memory faster than the
+// configured thresholds, with the error "memory growth rate exceeded".
+//
+// Limiting the maximum memory, e.g. with wazero.RuntimeConfig
+// WithMemoryLimitPages, bounds how much memory a guest can allocate. This
+// catches allocation bombs earlier, based on how quickly they allocate.
+//
+// Thresholds are measured with the host's monotonic clock over windows of one
+// second. A zero threshold is not enforced.
+//
+// Note: This only applies to the `memory.grow` instruction of a module that
+// defines its memory. Calls to api.Memory Grow from the host are not counted.
+type MemoryGrowthWatchdog struct {
+	// MaxGrowsPerSecond is the maximum count of `memory.grow` instructions,
+	// which grow by at least one page, allowed per second.
+	MaxGrowsPerSecond uint32
+
+	// MaxPagesPerSecond is the maximum count of pages (64KiB) a memory may
+	// grow by per second.
+	MaxPagesPerSecond uint32
+}
+
+// WithMemoryGrowthWatchdog registers the given MemoryGrowthWatchdog into the
+// given context.Context. It applies to modules instantiated with the result.
+//
+// Here's an example that traps a guest growing its memory by more than 16MiB
+// per second:
+//
+//	ctx = experimental.WithMemoryGrowthWatchdog(ctx, experimental.MemoryGrowthWatchdog{
+//		MaxPagesPerSecond: 256,
+//	})
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+func WithMemoryGrowthWatchdog(ctx context.Context, watchdog MemoryGrowthWatchdog) context.Context {
+	return context.WithValue(ctx, MemoryGrowthWatchdogKey{}, watchdog)
+}
+
+// SharedDataSegmentsKey is a context.Context Value key. Its associated value
+// should be a boolean.
+//
+// See WithSharedDataSegments
+type SharedDataSegmentsKey struct{}
+
+// WithSharedDataSegments enables sharing of data segments between instances
+// of the same wazero.CompiledModule instantiated with the result.
+//
+// Without this, each instance copies the data segments of its module into a
+// new memory. When enabled, the initial memory is written once, and each
+// instance maps it copy-on-write: pages holding data are shared until an
+// instance writes to them. This reduces the resident memory of deployments
+// with many instances of a module with large read-only data.
+//
+// Here's an example:
+//
+//	ctx = experimental.WithSharedDataSegments(ctx)
+//	for i := 0; i < 100; i++ {
+//		mod, _ := r.InstantiateModule(ctx, compiled, config.WithName(""))
+//		...
+//	}
+//	stats, _ := experimental.GetSharedDataSegmentsStats(mod)
+//
+// # Notes
+//
+//   - This only applies to a memory defined by the module, and to the leading
+//     active data segments with a constant offset. Other segments are copied
+//     as usual. Passive data segments are never copied.
+//   - This is only supported on darwin, freebsd and linux. Otherwise, or if
+//     the memory image couldn't be created, instantiation falls back to
+//     copying data segments.
+//   - Growing the memory beyond its capacity (see wazero.RuntimeConfig
+//     WithMemoryCapacityFromMax) copies it, losing any sharing. This can't
+//     happen in combination with WithPinnedMemory.
+//   - The memory is unmapped once unreachable, as opposed to when the module
+//     is closed. Do not retain slices returned by api.Memory Read after that.
+func WithSharedDataSegments(ctx context.Context) context.Context {
+	return context.WithValue(ctx, SharedDataSegmentsKey{}, true)
+}
+
+// SharedDataSegmentsStats are the statistics of a memory shared between
+// instances of the same module.
+//
+// See GetSharedDataSegmentsStats
+type SharedDataSegmentsStats struct {
+	// Instances is the count of open modules sharing the memory image.
+	Instances uint32
+
+	// SharedBytes is the size of the pages holding data in the memory image.
+	SharedBytes uint64
+
+	// SavedBytes estimates the memory saved compared to each instance
+	// copying data segments. This is SharedBytes for each instance but the
+	// first, not accounting for pages copied on write.
+	SavedBytes uint64
+}
+
+// GetSharedDataSegmentsStats returns the SharedDataSegmentsStats of the
+// memory of the module, or false if it doesn't share data segments.
+//
+// See WithSharedDataSegments
+func GetSharedDataSegmentsStats(mod api.Module) (SharedDataSegmentsStats, bool) {
+	if m, ok := mod.(interface {
+		SharedDataSegmentsStats() (SharedDataSegmentsStats, bool)
+	}); ok {
+		return m.SharedDataSegmentsStats()
+	}
+	return SharedDataSegmentsStats{}, false
+}
+
+// PinnedMemoryKey is a context.Context Value key. Its associated value should
+// be a boolean.
+//
+// See WithPinnedMemory
+type PinnedMemoryKey struct{}
+
+// WithPinnedMemory allocates the memory of modules instantiated with the
+// result outside the Go heap, with mmap, so that its address never changes.
+// This allows passing guest memory directly to syscalls, such as readv, or to
+// cgo libraries without copying it. Use MemoryBaseAddress to get its address.
+//
+// Here's an example:
+//
+//	ctx = experimental.WithPinnedMemory(ctx)
+//	mod, _ := r.InstantiateModule(ctx, compiled, config)
+//	base, ok := experimental.MemoryBaseAddress(mod.Memory())
+//
+// # Notes
+//
+//   - This only applies to a memory defined by the module, not imported.
+//   - The maximum size of the memory is reserved up-front. Pages are only
+//     allocated by the OS once written, but this still uses address space.
+//   - This is only supported on darwin, freebsd and linux. Otherwise, the
+//     memory is allocated as usual, and MemoryBaseAddress returns false.
+//   - The memory is unmapped once unreachable, as opposed to when the module
+//     is closed. Do not use its address after that.
+func WithPinnedMemory(ctx context.Context) context.Context {
+	return context.WithValue(ctx, PinnedMemoryKey{}, true)
+}
+
+// MemoryBaseAddress returns the address of the first byte of the memory, or
+// false if it wasn't allocated with WithPinnedMemory. The address is stable
+// for the life of the memory, including on api.Memory Grow.
+func MemoryBaseAddress(mem api.Memory) (uintptr, bool) {
+	if m, ok := mem.(interface{ BaseAddress() (uintptr, bool) }); ok {
+		return m.BaseAddress()
+	}
+	return 0, false
+}
+
+// InstanceArenaKey is a context.Context Value key. Its associated value
+// should be a boolean.
+//
+// See WithInstanceArena
+type InstanceArenaKey struct{}
+
+// WithInstanceArena enables reuse of the internal structures of modules
+// instantiated with the result, such as globals and tables, by the next
+// instance of the same wazero.CompiledModule after they are closed.
+//
+// This reduces allocations, and so GC CPU, in services which instantiate
+// thousands of short-lived modules per second. For example:
+//
+//	ctx = experimental.WithInstanceArena(ctx)
+//	for req := range requests {
+//		mod, _ := r.InstantiateModule(ctx, compiled, config)
+//		// ...
+//		_ = mod.Close(ctx) // releases its structures for the next instance
+//	}
+//
+// Notes:
+//   - A module must not be closed while its functions are called, as the
+//     next instance reuses its structures. A module closed asynchronously,
+//     e.g. on context done with wazero.RuntimeConfig WithCloseOnContextDone,
+//     doesn't release its structures, as its functions may still run.
+//   - Modules which export globals or tables don't reuse structures, as
+//     these may outlive the instance in modules which import them.
+func WithInstanceArena(ctx context.Context) context.Context {
+	return context.WithValue(ctx, InstanceArenaKey{}, true)
+}
@@ -0,0 +1,169 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+func TestWithMemoryGrowthWatchdog(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 0, Max: 100, IsMaxEncoded: true},
+		ExportSection: []wasm.Export{{Name: "grow", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	for _, c := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "compiler", config: wazero.NewRuntimeConfig()},
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			ctx := experimental.WithMemoryGrowthWatchdog(testCtx, experimental.MemoryGrowthWatchdog{
+				MaxPagesPerSecond: 10,
+			})
+			mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig())
+			require.NoError(t, err)
+
+			grow := mod.ExportedFunction("grow")
+			results, err := grow.Call(testCtx, 10)
+			require.NoError(t, err)
+			require.Equal(t, uint64(0), results[0])
+
+			_, err = grow.Call(testCtx, 1)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeMemoryGrowthRateExceeded)
+			require.Equal(t, api.ModuleStatePoisoned, mod.State())
+			require.Equal(t, uint32(10), mod.Memory().Size()/65536)
+		})
+	}
+}
+
+func TestWithSharedDataSegments(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		MemorySection: &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hello")},
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{3}}, Init: []byte("p!")},
+		},
+		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0}},
+	})
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	ctx := experimental.WithSharedDataSegments(testCtx)
+	var mods []api.Module
+	for i := 0; i < 3; i++ {
+		mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(""))
+		require.NoError(t, err)
+		mods = append(mods, mod)
+	}
+
+	stats, ok := experimental.GetSharedDataSegmentsStats(mods[0])
+	if !ok {
+		t.Skip("shared data segments unsupported on this platform")
+	}
+	require.Equal(t, uint32(3), stats.Instances)
+	require.True(t, stats.SharedBytes > 0)
+	require.Equal(t, 2*stats.SharedBytes, stats.SavedBytes)
+
+	// Writes to one instance are not visible to the others.
+	require.True(t, mods[0].Memory().WriteString(0, "jello"))
+	buf, _ := mods[0].Memory().Read(0, 5)
+	require.Equal(t, "jello", string(buf))
+	buf, _ = mods[1].Memory().Read(0, 5)
+	require.Equal(t, "help!", string(buf))
+
+	// Growing the memory keeps its contents.
+	_, ok = mods[1].Memory().Grow(1)
+	require.True(t, ok)
+	buf, _ = mods[1].Memory().Read(0, 5)
+	require.Equal(t, "help!", string(buf))
+
+	require.NoError(t, mods[2].Close(testCtx))
+	stats, _ = experimental.GetSharedDataSegmentsStats(mods[0])
+	require.Equal(t, uint32(2), stats.Instances)
+	require.Equal(t, stats.SharedBytes, stats.SavedBytes)
+
+	// Without the context key, data segments are copied.
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(""))
+	require.NoError(t, err)
+	_, ok = experimental.GetSharedDataSegmentsStats(mod)
+	require.False(t, ok)
+}
+
+func TestWithPinnedMemory(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []api.ValueType{api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Max: 10, IsMaxEncoded: true},
+		DataSection: []wasm.DataSegment{
+			{OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, Init: []byte("hello")},
+		},
+		ExportSection: []wasm.Export{{Name: "grow", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	for _, c := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+		ctx    context.Context
+	}{
+		{name: "compiler", config: wazero.NewRuntimeConfig(), ctx: testCtx},
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter(), ctx: testCtx},
+		{name: "shared data segments", config: wazero.NewRuntimeConfig(), ctx: experimental.WithSharedDataSegments(testCtx)},
+	} {
+		tc := c
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			mod, err := r.InstantiateWithConfig(testCtx, bin, wazero.NewModuleConfig().WithName("unpinned"))
+			require.NoError(t, err)
+			_, ok := experimental.MemoryBaseAddress(mod.Memory())
+			require.False(t, ok)
+
+			mod, err = r.InstantiateWithConfig(experimental.WithPinnedMemory(tc.ctx), bin, wazero.NewModuleConfig())
+			require.NoError(t, err)
+			base, ok := experimental.MemoryBaseAddress(mod.Memory())
+			if !ok {
+				t.Skip("pinned memory unsupported on this platform")
+			}
+
+			buf, _ := mod.Memory().Read(0, 5)
+			require.Equal(t, "hello", string(buf))
+			require.Equal(t, base, uintptr(unsafe.Pointer(&buf[0])))
+
+			// The address doesn't change when the memory grows.
+			_, err = mod.ExportedFunction("grow").Call(testCtx, 9)
+			require.NoError(t, err)
+			require.Equal(t, uint32(10*65536), mod.Memory().Size())
+			newBase, ok := experimental.MemoryBaseAddress(mod.Memory())
+			require.True(t, ok)
+			require.Equal(t, base, newBase)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package experimental
+
+import (
+	"context"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// MetricsKey is a context.Context Value key. Its associated value should be
+// a boolean.
+//
+// See WithMetrics
+type MetricsKey struct{}
+
+// WithMetrics enables collection of Metrics by a wazero.Runtime created with
+// the result, e.g. by wazero.NewRuntimeWithConfig.
+//
+// Metrics are disabled by default, as counting calls of exported functions
+// adds overhead to each call.
+//
+// Here's an example that exports metrics, e.g. for Prometheus:
+//
+//	ctx = experimental.WithMetrics(ctx)
+//	r := wazero.NewRuntime(ctx)
+//	defer r.Close(ctx)
+//
+//	// Later, e.g. in a prometheus.Collector:
+//	if m, ok := experimental.GetMetrics(r); ok {
+//		ch <- prometheus.MustNewConstMetric(callsDesc, prometheus.CounterValue, float64(m.Calls))
+//	}
+func WithMetrics(ctx context.Context) context.Context {
+	return context.WithValue(ctx, MetricsKey{}, true)
+}
+
+// Metrics is a snapshot of the counters and gauges of a wazero.Runtime.
+//
+// Counters only increase over the lifetime of the runtime, and durations are
+// the sum of all measured operations. Gauges are measured when the snapshot
+// is taken.
+type Metrics struct {
+	// Compilations is the count of modules successfully compiled with
+	// wazero.Runtime CompileModule, including those loaded from a cache.
+	Compilations uint64
+
+	// CompilationTime is the total time spent in successful compilations.
+	CompilationTime time.Duration
+
+	// CacheHits is the count of Compilations loaded from a cache, such as a
+	// wazero.CompilationCache, instead of being compiled.
+	CacheHits uint64
+
+	// CacheMisses is the count of Compilations compiled by the engine.
+	CacheMisses uint64
+
+	// Instantiations is the count of modules successfully instantiated with
+	// wazero.Runtime InstantiateModule, including host modules.
+	Instantiations uint64
+
+	// InstantiationTime is the total time spent in successful
+	// instantiations, including the start function.
+	InstantiationTime time.Duration
+
+	// RejectedInstantiations is the count of instantiations which failed
+	// due to wazero.RuntimeConfig WithMaxInstances.
+	RejectedInstantiations uint64
+
+	// ActiveInstances is the count of instantiated guest modules which are
+	// not yet closed. Host modules are not included.
+	ActiveInstances uint64
+
+	// Calls is the count of calls of exported functions made from the host,
+	// including those which failed.
+	//
+	// Note: Calls between guest functions and calls from guest to host
+	// functions are not counted.
+	Calls uint64
+
+	// CallTime is the total time spent in Calls.
+	CallTime time.Duration
+
+	// MemoryPages is the count of pages of memory in use by the
+	// ActiveInstances. A page is 64KiB, unless a memory has a custom page
+	// size, as defined by CoreFeaturesCustomPageSizes, in which case
+	// MemoryBytes is more meaningful.
+	MemoryPages uint64
+
+	// MemoryBytes is the size in bytes of the memory in use by the
+	// ActiveInstances, regardless of their page sizes.
+	MemoryBytes uint64
+
+	// Instances are the ActiveInstances which have labels, set with
+	// wazero.ModuleConfig WithLabel, so that gauges can be attributed, e.g.
+	// to a tenant.
+	Instances []InstanceMetrics
+}
+
+// InstanceMetrics are the gauges of an active instance in Metrics.
+type InstanceMetrics struct {
+	// Module is the name of the module instance.
+	Module string
+
+	// Labels are the labels of the module instance. See GetLabels.
+	Labels map[string]string
+
+	// MemoryPages is the count of pages of memory in use by the module
+	// instance. A page is 64KiB, unless the memory has a custom page size, as
+	// defined by CoreFeaturesCustomPageSizes.
+	MemoryPages uint64
+
+	// MemoryBytes is the size in bytes of the memory in use by the module
+	// instance.
+	MemoryBytes uint64
+}
+
+// GetMetrics returns a snapshot of the Metrics of the given wazero.Runtime,
+// or false if it wasn't created with WithMetrics.
+func GetMetrics(r api.Closer) (Metrics, bool) {
+	if m, ok := r.(interface {
+		Metrics() (Metrics, bool)
+	}); ok {
+		return m.Metrics()
+	}
+	return Metrics{}, false
+}
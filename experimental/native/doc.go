@@ -0,0 +1,26 @@
+// Package native allows trusted embedders to export host functions written in
+// machine code, e.g. Go assembly, which the compiler calls directly instead of
+// calling Go. This is for host APIs where even the cost of calling a Go
+// function is too high.
+//
+// This is unsafe: the machine code has full access to the process, and
+// mistakes crash it or corrupt memory. So, the API is only available when
+// building with the tag "wazero_unsafe_native" on amd64 or arm64, e.g.
+//
+//	go build -tags wazero_unsafe_native .
+//
+// # Calling convention
+//
+// A native function implements an api.GoFunction: it receives the address of
+// the same stack, where params are read and results are written, in AX on
+// amd64 and R0 on arm64. It must preserve the callee-saved registers BX, BP
+// and R12-R15 on amd64, and R19-R29 on arm64, and return with RET.
+//
+// It runs on the goroutine's stack without a stack check, so it must be a leaf
+// which needs little stack, like a Go assembly function declared NOSPLIT with
+// a small frame. It can't call Go or block, as the goroutine can't be
+// preempted.
+//
+// Note: Currently, only the compiler on amd64 calls native functions directly.
+// Other engines, and the compiler on arm64, call them via Go.
+package native
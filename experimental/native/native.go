@@ -0,0 +1,65 @@
+//go:build wazero_unsafe_native && (amd64 || arm64)
+
+package native
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Func is a native function, which implements api.GoFunction. See the
+// package documentation for the calling convention.
+//
+// Here's an example which exports a function written in Go assembly, adding
+// the two params in stack[0] and stack[1]. The address of its machine code is
+// returned by addAddress, also written in assembly, e.g. on amd64:
+//
+//	TEXT ·add(SB), NOSPLIT|NOFRAME, $0-0
+//		MOVQ (AX), CX
+//		ADDQ 8(AX), CX
+//		MOVQ CX, (AX)
+//		RET
+//
+//	TEXT ·addAddress(SB), NOSPLIT, $0-8
+//		MOVQ $·add(SB), AX
+//		MOVQ AX, ret+0(FP)
+//		RET
+//
+// Then, it's exported like any other api.GoFunction:
+//
+//	i64 := api.ValueTypeI64
+//	_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+//		WithGoFunction(native.NewFunc(addAddress()), []api.ValueType{i64, i64}, []api.ValueType{i64}).
+//		Export("add").
+//		Instantiate(ctx)
+type Func struct {
+	address uintptr
+}
+
+// NewFunc returns a Func which calls the machine code at address.
+func NewFunc(address uintptr) Func {
+	return Func{address: address}
+}
+
+// Call implements api.GoFunction.Call
+func (f Func) Call(_ context.Context, stack []uint64) {
+	var sp *uint64
+	if len(stack) > 0 {
+		sp = &stack[0]
+	}
+	callNative(f.address, sp)
+}
+
+// NativeFunctionAddress implements wasm.NativeFunction.NativeFunctionAddress
+func (f Func) NativeFunctionAddress() uintptr {
+	return f.address
+}
+
+// callNative calls the machine code at address with the stack as documented
+// on Func.
+//
+//go:noescape
+func callNative(address uintptr, stack *uint64)
+
+var _ api.GoFunction = Func{}
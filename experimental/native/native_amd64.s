@@ -0,0 +1,10 @@
+//go:build wazero_unsafe_native
+
+#include "textflag.h"
+
+// callNative(address uintptr, stack *uint64)
+TEXT ·callNative(SB), NOSPLIT, $0-16
+	MOVQ address+0(FP), CX
+	MOVQ stack+8(FP), AX
+	CALL CX
+	RET
@@ -0,0 +1,10 @@
+//go:build wazero_unsafe_native
+
+#include "textflag.h"
+
+// callNative(address uintptr, stack *uint64)
+TEXT ·callNative(SB), NOSPLIT, $16-16
+	MOVD address+0(FP), R1
+	MOVD stack+8(FP), R0
+	CALL (R1)
+	RET
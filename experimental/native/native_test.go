@@ -0,0 +1,103 @@
+//go:build wazero_unsafe_native && (amd64 || arm64)
+
+package native_test
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/native"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var testCtx = context.Background()
+
+// addMachineCode writes stack[0]+stack[1] to stack[0].
+func addMachineCode() []byte {
+	if runtime.GOARCH == "amd64" {
+		return []byte{
+			0x48, 0x8b, 0x08, // MOVQ (AX), CX
+			0x48, 0x03, 0x48, 0x08, // ADDQ 8(AX), CX
+			0x48, 0x89, 0x08, // MOVQ CX, (AX)
+			0xc3, // RET
+		}
+	}
+	return []byte{
+		0x01, 0x00, 0x40, 0xf9, // LDR X1, [X0]
+		0x02, 0x04, 0x40, 0xf9, // LDR X2, [X0, #8]
+		0x21, 0x00, 0x02, 0x8b, // ADD X1, X1, X2
+		0x01, 0x00, 0x00, 0xf9, // STR X1, [X0]
+		0xc0, 0x03, 0x5f, 0xd6, // RET
+	}
+}
+
+// requireAdd returns a native.Func of addMachineCode.
+func requireAdd(t *testing.T) native.Func {
+	code := addMachineCode()
+	executable, err := platform.MmapCodeSegment(len(code))
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, platform.MunmapCodeSegment(executable)) })
+	copy(executable, code)
+	if runtime.GOARCH == "arm64" {
+		require.NoError(t, platform.MprotectRX(executable))
+	}
+	return native.NewFunc(uintptr(unsafe.Pointer(&executable[0])))
+}
+
+func TestFunc_Call(t *testing.T) {
+	add := requireAdd(t)
+
+	stack := []uint64{1, 41}
+	add.Call(testCtx, stack)
+	require.Equal(t, uint64(42), stack[0])
+}
+
+func TestFunc_hostFunction(t *testing.T) {
+	add := requireAdd(t)
+
+	i64 := api.ValueTypeI64
+	// guest exports "add", which calls the imported "env.add".
+	guest := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "add", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "add", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+				WithGoFunction(add, []api.ValueType{i64, i64}, []api.ValueType{i64}).
+				Export("add").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(testCtx, guest)
+			require.NoError(t, err)
+
+			for i := uint64(0); i < 3; i++ {
+				results, err := mod.ExportedFunction("add").Call(testCtx, i, 40)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{i + 40}, results)
+			}
+		})
+	}
+}
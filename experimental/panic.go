@@ -0,0 +1,79 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// PanicError is returned by a call which recovered an unexpected panic, such
+// as a nil pointer dereference or panic("BUG"), instead of a trap. This is
+// usually a bug in wazero or in a host function, so the module instance is
+// poisoned, as if it trapped, but other instances of the runtime are
+// unaffected.
+//
+// Here's an example that stops using a module after such an error:
+//
+//	if _, err := fn.Call(ctx); err != nil {
+//		var pe *experimental.PanicError
+//		if errors.As(err, &pe) {
+//			_ = mod.Close(ctx)
+//		}
+//	}
+//
+// Note: A host function that intentionally panics with an error, e.g. a
+// sys.ExitError, doesn't result in a PanicError.
+type PanicError struct {
+	// Module is the name of the module instance whose function was called.
+	Module string
+
+	// Recovered is the value recovered from the panic.
+	Recovered interface{}
+
+	// Stack is the Go stack trace of the panic, as formatted by
+	// runtime/debug.Stack.
+	Stack []byte
+
+	// Err is the error of the call, which includes the wasm stack trace.
+	Err error
+}
+
+// Error implements error.
+func (e *PanicError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns Err.
+func (e *PanicError) Unwrap() error {
+	return e.Err
+}
+
+// PanicHandler is called when a call returns a PanicError, before the call
+// returns. `mod` is the module instance whose function was called, which is
+// poisoned once this returns.
+//
+// See WithPanicHandler
+type PanicHandler func(ctx context.Context, mod api.Module, err *PanicError)
+
+// PanicHandlerKey is a context.Context Value key. Its associated value should
+// be a PanicHandler.
+//
+// See WithPanicHandler
+type PanicHandlerKey struct{}
+
+// WithPanicHandler registers the handler into the given context.Context, so
+// that diagnostics of a PanicError can be captured, e.g. for a bug report.
+//
+// When the result is passed to wazero.NewRuntimeWithConfig, the handler
+// applies to every module of the runtime. When passed to wazero.Runtime
+// InstantiateModule, it applies to that module instead.
+//
+// Here's an example that logs the Go stack trace:
+//
+//	ctx = experimental.WithPanicHandler(ctx, func(ctx context.Context, mod api.Module, err *experimental.PanicError) {
+//		log.Printf("module[%s] panicked: %v\n%s", mod.Name(), err.Recovered, err.Stack)
+//	})
+//	r := wazero.NewRuntime(ctx)
+func WithPanicHandler(ctx context.Context, handler PanicHandler) context.Context {
+	return context.WithValue(ctx, PanicHandlerKey{}, handler)
+}
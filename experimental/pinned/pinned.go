@@ -0,0 +1,183 @@
+// Package pinned executes function calls on a dedicated goroutine locked to
+// an OS thread.
+//
+// By default, a call into Wasm runs on the calling goroutine, which the Go
+// scheduler can migrate between OS threads, or preempt in favor of other
+// goroutines. This adds jitter which latency-critical guests, e.g. real-time
+// audio or video processing, may not tolerate. Calling such guests through a
+// Thread keeps their execution on the same OS thread, whose scheduling
+// priority or CPU affinity can be configured via the Thread's start hook.
+//
+// Note: This is experimental, and likely to change. Do not expose this in
+// shared libraries as it can cause version locks.
+package pinned
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/internalapi"
+)
+
+// ErrClosed is returned by the calls on a closed Thread.
+var ErrClosed = errors.New("pinned thread closed")
+
+// Thread is a goroutine locked to a dedicated OS thread, which executes the
+// calls of the functions wrapped by Function one at a time.
+type Thread struct {
+	// mux serializes the calls, so that each caller receives the completion of its own call.
+	mux   sync.Mutex
+	calls chan func()
+	// done receives the completion of each call, with the value it panicked
+	// with, if any.
+	done chan interface{}
+	// closed is closed when the thread exited.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewThread starts a Thread. `onStart`, if not nil, is called on the locked
+// OS thread before any call, and can be used to set its scheduling priority
+// or CPU affinity. If it returns an error, the Thread is not started, and the
+// error is returned.
+//
+// The OS thread is terminated on Close rather than returned to the Go
+// runtime, so that changes made by `onStart` don't leak to other goroutines.
+func NewThread(onStart func() error) (*Thread, error) {
+	t := &Thread{
+		calls:  make(chan func()),
+		done:   make(chan interface{}),
+		closed: make(chan struct{}),
+	}
+	started := make(chan error)
+	go t.run(onStart, started)
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Thread) run(onStart func() error, started chan<- error) {
+	// Exiting the goroutine without UnlockOSThread terminates the OS thread.
+	runtime.LockOSThread()
+	defer close(t.closed)
+
+	if onStart != nil {
+		if err := onStart(); err != nil {
+			started <- err
+			return
+		}
+	}
+	close(started)
+
+	for fn := range t.calls {
+		if fn == nil { // Close was called.
+			return
+		}
+		t.done <- recoverCall(fn)
+	}
+}
+
+// recoverCall calls fn, returning the value it panicked with, if any, so
+// that a panic is passed to the caller instead of crashing the process.
+func recoverCall(fn func()) (recovered interface{}) {
+	defer func() {
+		recovered = recover()
+	}()
+	fn()
+	return
+}
+
+// threadKey is a context.Context key of the Thread executing a call.
+type threadKey struct{}
+
+// do executes fn on the thread and waits for its completion. If fn panics,
+// so does do, in the calling goroutine.
+//
+// The context passed to fn identifies the thread, so that a call made with
+// it while the thread executes fn, e.g. by a host function, is reentrant,
+// and executed inline, instead of deadlocking.
+func (t *Thread) do(ctx context.Context, fn func(context.Context)) error {
+	if ctx.Value(threadKey{}) == t {
+		fn(ctx)
+		return nil
+	}
+	ctx = context.WithValue(ctx, threadKey{}, t)
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	select {
+	case t.calls <- func() { fn(ctx) }:
+		if recovered := <-t.done; recovered != nil {
+			panic(recovered)
+		}
+		return nil
+	case <-t.closed:
+		return ErrClosed
+	}
+}
+
+// Close stops the thread once the current call, if any, completes. The
+// subsequent calls return ErrClosed.
+func (t *Thread) Close() {
+	t.closeOnce.Do(func() {
+		// Acquiring the lock waits for the current call.
+		t.mux.Lock()
+		defer t.mux.Unlock()
+		t.calls <- nil
+	})
+	<-t.closed
+}
+
+// Function returns an api.Function whose calls execute `fn` on this Thread.
+//
+// A call made with the context.Context of a call executing on this Thread,
+// e.g. by a host function it calls, executes inline, as the Thread is busy.
+// A panic during a call is passed to the caller.
+//
+// For example, this calls "process" of the module `mod` on a pinned thread:
+//
+//	process := t.Function(mod.ExportedFunction("process"))
+//	results, err := process.Call(ctx, ptr, size)
+func (t *Thread) Function(fn api.Function) api.Function {
+	return &function{t: t, fn: fn}
+}
+
+// function implements api.Function by delegating the calls to the Thread.
+type function struct {
+	internalapi.WazeroOnlyType
+	t  *Thread
+	fn api.Function
+}
+
+// Definition implements the same method as documented on api.Function.
+func (f *function) Definition() api.FunctionDefinition {
+	return f.fn.Definition()
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *function) Call(ctx context.Context, params ...uint64) (results []uint64, err error) {
+	if doErr := f.t.do(ctx, func(ctx context.Context) { results, err = f.fn.Call(ctx, params...) }); doErr != nil {
+		return nil, doErr
+	}
+	return
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *function) CallWithStack(ctx context.Context, stack []uint64) (err error) {
+	if doErr := f.t.do(ctx, func(ctx context.Context) { err = f.fn.CallWithStack(ctx, stack) }); doErr != nil {
+		return doErr
+	}
+	return
+}
+
+// CallWithOptions implements the same method as documented on api.Function.
+func (f *function) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) (results []uint64, err error) {
+	if doErr := f.t.do(ctx, func(ctx context.Context) { results, err = f.fn.CallWithOptions(ctx, opts, params...) }); doErr != nil {
+		return nil, doErr
+	}
+	return
+}
@@ -0,0 +1,168 @@
+package pinned_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/pinned"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// addWasm exports "add" which returns the sum of its two i32 params, and
+// "unreachable" which traps.
+var addWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{},
+	},
+	FunctionSection: []wasm.Index{0, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "add", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "unreachable", Type: wasm.ExternTypeFunc, Index: 1},
+	},
+})
+
+// callAddWasm imports "env.add", and exports "call_add" which calls it.
+var callAddWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{wasm.ValueTypeI32, wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+	},
+	ImportSection: []wasm.Import{
+		{Module: "env", Name: "add", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "call_add", Type: wasm.ExternTypeFunc, Index: 1},
+	},
+})
+
+func TestNewThread_OnStartError(t *testing.T) {
+	expectedErr := errors.New("setpriority failed")
+	_, err := pinned.NewThread(func() error { return expectedErr })
+	require.Equal(t, expectedErr, err)
+}
+
+func TestThread_Function(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	m, err := r.Instantiate(testCtx, addWasm)
+	require.NoError(t, err)
+
+	var started bool
+	th, err := pinned.NewThread(func() error {
+		started = true
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, started)
+
+	add := th.Function(m.ExportedFunction("add"))
+	require.Equal(t, m.ExportedFunction("add").Definition(), add.Definition())
+
+	t.Run("Call", func(t *testing.T) {
+		res, err := add.Call(testCtx, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{3}, res)
+	})
+
+	t.Run("CallWithStack", func(t *testing.T) {
+		stack := []uint64{3, 4}
+		require.NoError(t, add.CallWithStack(testCtx, stack))
+		require.Equal(t, uint64(7), stack[0])
+	})
+
+	t.Run("CallWithOptions", func(t *testing.T) {
+		res, err := add.CallWithOptions(testCtx, api.CallOptions{}, 5, 6)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{11}, res)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := th.Function(m.ExportedFunction("unreachable")).Call(testCtx)
+		require.Contains(t, err.Error(), "wasm error: unreachable")
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := uint64(0); i < 10; i++ {
+			i := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Each goroutine needs its own api.Function as they are not goroutine-safe.
+				f := th.Function(m.ExportedFunction("add"))
+				for j := uint64(0); j < 100; j++ {
+					res, err := f.Call(testCtx, i, j)
+					require.NoError(t, err)
+					require.Equal(t, []uint64{i + j}, res)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("reentrant", func(t *testing.T) {
+		// env.add calls "add" on the thread, which is busy calling call_add.
+		_, err := r.NewHostModuleBuilder("env").NewFunctionBuilder().
+			WithFunc(func(ctx context.Context, x, y uint32) uint32 {
+				res, err := add.Call(ctx, uint64(x), uint64(y))
+				require.NoError(t, err)
+				return uint32(res[0])
+			}).Export("add").Instantiate(testCtx)
+		require.NoError(t, err)
+
+		callAdd, err := r.Instantiate(testCtx, callAddWasm)
+		require.NoError(t, err)
+
+		res, err := th.Function(callAdd.ExportedFunction("call_add")).Call(testCtx, 7, 8)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{15}, res)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		f := th.Function(panicFunction{})
+		err := require.CapturePanic(func() { _, _ = f.Call(testCtx) })
+		require.EqualError(t, err, "BUG")
+
+		// The thread is still usable.
+		res, err := add.Call(testCtx, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{3}, res)
+	})
+
+	th.Close()
+	th.Close() // idempotent
+
+	_, err = add.Call(testCtx, 1, 2)
+	require.Equal(t, pinned.ErrClosed, err)
+	require.Equal(t, pinned.ErrClosed, add.CallWithStack(testCtx, []uint64{1, 2}))
+	_, err = add.CallWithOptions(testCtx, api.CallOptions{}, 1, 2)
+	require.Equal(t, pinned.ErrClosed, err)
+}
+
+// panicFunction is an api.Function whose Call panics.
+type panicFunction struct {
+	api.Function
+}
+
+// Call implements the same method as documented on api.Function.
+func (panicFunction) Call(context.Context, ...uint64) ([]uint64, error) {
+	panic(errors.New("BUG"))
+}
@@ -0,0 +1,216 @@
+// Package pipe includes in-memory pipes, which connect module instances in
+// the same host, for example, into a pipeline, and an experimental host
+// module which allows a guest to open them as file descriptors.
+//
+// A Pipe buffers a limited number of bytes: writing blocks while the buffer
+// is full, so a fast writer can't outpace a slow reader. Like pipes on a
+// host, reading returns EOF once the write end is closed and the buffer
+// drained, and writing fails with EPIPE once the read end is closed.
+//
+// Here's an example that connects the stdout of one module to the stdin of
+// another:
+//
+//	p := pipe.New(64 * 1024)
+//	go r.InstantiateModule(ctx, upstream, wazero.NewModuleConfig().WithName("up").WithStdout(p.Writer()))
+//	_, err := r.InstantiateModule(ctx, downstream, wazero.NewModuleConfig().WithName("down").WithStdin(p.Reader()))
+//
+// When an end is used as stdio, it is closed when the module closes, so the
+// downstream module reads EOF once the upstream one exits.
+//
+// # Host module
+//
+// Guests can also open pipes added to a Registry, importing the function
+// "pipe_open" from the module "wazero_pipe":
+//
+//	(import "wazero_pipe" "pipe_open"
+//	  (func $pipe_open (param $name i32) (param $name_len i32) (param $end i32) (param $result.fd i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1. `end` is zero for the read end, or one for the
+// write end, whose file descriptor is written to result.fd. The result is
+// ENOENT if no pipe has the name, and EBUSY if the end is already open. The
+// guest can wait for the read end with "poll_oneoff", and close either end
+// with "fd_close".
+//
+// Note: This is experimental, and may change or be removed.
+package pipe
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name guests import "pipe_open" from.
+const ModuleName = "wazero_pipe"
+
+// Pipe is an in-memory pipe. Each end can be used once: either by the host,
+// for example as stdio of a module, or opened by a guest with "pipe_open".
+type Pipe struct {
+	r *internalsys.PipeReader
+	w *internalsys.PipeWriter
+
+	mux                 sync.Mutex
+	readOpen, writeOpen bool
+	reader              io.ReadCloser
+	writer              io.WriteCloser
+}
+
+// New returns a Pipe which buffers up to `capacity` bytes.
+func New(capacity int) *Pipe {
+	r, w := internalsys.NewPipe(capacity)
+	return &Pipe{r: r, w: w, reader: r.IO(), writer: w.IO()}
+}
+
+// Reader returns the read end, for example, for wazero.ModuleConfig
+// WithStdin. Subsequent calls return the same reader.
+func (p *Pipe) Reader() io.ReadCloser {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.readOpen = true
+	return p.reader
+}
+
+// Writer returns the write end, for example, for wazero.ModuleConfig
+// WithStdout. Subsequent calls return the same writer.
+func (p *Pipe) Writer() io.WriteCloser {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.writeOpen = true
+	return p.writer
+}
+
+// open marks the end as open, returning false if it already was.
+func (p *Pipe) open(write bool) bool {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	opened := &p.readOpen
+	if write {
+		opened = &p.writeOpen
+	}
+	if *opened {
+		return false
+	}
+	*opened = true
+	return true
+}
+
+// Registry holds the pipes guests can open by name. It is safe for
+// concurrent use.
+type Registry struct {
+	mux   sync.RWMutex
+	pipes map[string]*Pipe
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{pipes: map[string]*Pipe{}}
+}
+
+// Add adds the pipe under the name, replacing any pipe with the same name.
+func (r *Registry) Add(name string, p *Pipe) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.pipes[name] = p
+}
+
+// Remove removes the pipe of the name, if any. Ends already open remain so.
+func (r *Registry) Remove(name string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	delete(r.pipes, name)
+}
+
+func (r *Registry) get(name string) (*Pipe, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	p, ok := r.pipes[name]
+	return p, ok
+}
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime, registry *Registry) {
+	if _, err := Instantiate(ctx, r, registry); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime, opening
+// pipes from the registry.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime, registry *Registry) (api.Closer, error) {
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(registry.pipeOpen),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("name", "name_len", "end", "result.fd").
+		WithResultNames("errno").
+		Export("pipe_open").
+		Instantiate(ctx)
+}
+
+const (
+	endRead  = 0
+	endWrite = 1
+)
+
+func (r *Registry) pipeOpen(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(r.pipeOpenFn(mod, stack))
+}
+
+func (r *Registry) pipeOpenFn(mod api.Module, params []uint64) wasip1.Errno {
+	name, nameLen := uint32(params[0]), uint32(params[1])
+	end, resultFd := uint32(params[2]), uint32(params[3])
+
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+	if end != endRead && end != endWrite {
+		return wasip1.ErrnoInval
+	}
+
+	mem := mod.Memory()
+	nameBytes, ok := mem.Read(name, nameLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	// Check the result can be written before opening the descriptor.
+	if _, ok = mem.ReadUint32Le(resultFd); !ok {
+		return wasip1.ErrnoFault
+	}
+
+	p, ok := r.get(string(nameBytes))
+	if !ok {
+		return wasip1.ErrnoNoent
+	}
+	if !p.open(end == endWrite) {
+		return wasip1.ErrnoBusy
+	}
+
+	var f fsapi.File = p.r
+	if end == endWrite {
+		f = p.w
+	}
+	fd, errno := sysCtx.FS().InsertFile(f, "pipe")
+	if errno != 0 {
+		return wasip1.ToErrno(errno)
+	}
+	mem.WriteUint32Le(resultFd, uint32(fd))
+	return wasip1.ErrnoSuccess
+}
@@ -0,0 +1,118 @@
+package pipe_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/pipe"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// pipeWasm exports the function "pipe_open", which calls the imported one,
+// and its memory.
+var pipeWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: pipe.ModuleName, Name: "pipe_open", DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	ExportSection: []wasm.Export{
+		{Name: "pipe_open", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestInstantiate(t *testing.T) {
+	registry := pipe.NewRegistry()
+	p := pipe.New(16)
+	registry.Add("logs", p)
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	pipe.MustInstantiate(testCtx, r, registry)
+
+	// The writer is the stdout of one module, and the reader is opened by
+	// another.
+	up, err := r.InstantiateWithConfig(testCtx, pipeWasm, wazero.NewModuleConfig().WithName("up").WithStdout(p.Writer()))
+	require.NoError(t, err)
+	down, err := r.InstantiateWithConfig(testCtx, pipeWasm, wazero.NewModuleConfig().WithName("down"))
+	require.NoError(t, err)
+
+	const name, resultFd = 0, 16
+	open := func(mod api.Module, n string, end uint32) (wasip1.Errno, int32) {
+		mem := mod.Memory()
+		require.True(t, mem.WriteString(name, n))
+		results, err := mod.ExportedFunction("pipe_open").Call(testCtx, name, uint64(len(n)), uint64(end), resultFd)
+		require.NoError(t, err)
+		fd, _ := mem.ReadUint32Le(resultFd)
+		return wasip1.Errno(results[0]), int32(fd)
+	}
+
+	errno, _ := open(down, "missing", 0)
+	require.Equal(t, wasip1.ErrnoNoent, errno)
+	errno, _ = open(down, "logs", 2)
+	require.Equal(t, wasip1.ErrnoInval, errno)
+	errno, _ = open(down, "logs", 1)
+	require.Equal(t, wasip1.ErrnoBusy, errno) // used as stdout
+
+	errno, fd := open(down, "logs", 0)
+	require.Equal(t, wasip1.ErrnoSuccess, errno)
+	errno, _ = open(down, "logs", 0)
+	require.Equal(t, wasip1.ErrnoBusy, errno)
+
+	upStdout, ok := up.(*wasm.ModuleInstance).Sys.FS().LookupFile(1)
+	require.True(t, ok)
+	n, werrno := upStdout.File.Write([]byte("hello"))
+	require.EqualErrno(t, 0, werrno)
+	require.Equal(t, 5, n)
+
+	// Closing the upstream module closes its stdout, so downstream reads EOF
+	// after the buffered data.
+	require.NoError(t, up.Close(testCtx))
+
+	f, ok := down.(*wasm.ModuleInstance).Sys.FS().LookupFile(fd)
+	require.True(t, ok)
+	buf := make([]byte, 16)
+	n, werrno = f.File.Read(buf)
+	require.EqualErrno(t, 0, werrno)
+	require.Equal(t, "hello", string(buf[:n]))
+	n, werrno = f.File.Read(buf)
+	require.EqualErrno(t, 0, werrno)
+	require.Equal(t, 0, n)
+}
+
+func TestPipe(t *testing.T) {
+	p := pipe.New(2)
+
+	go func() {
+		_, _ = p.Writer().Write([]byte("backpressure"))
+		_ = p.Writer().Close()
+	}()
+
+	b, err := io.ReadAll(p.Reader())
+	require.NoError(t, err)
+	require.Equal(t, "backpressure", string(b))
+}
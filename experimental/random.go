@@ -0,0 +1,35 @@
+package experimental
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// RandomHookKey is a context.Context Value key. Its associated value should
+// be a RandomHook.
+//
+// See WithRandomHook
+type RandomHookKey struct{}
+
+// RandomHook is called after a guest requests `n` random bytes, for example,
+// in WASI `random_get`, so that the host can audit the use of randomness.
+// `err` is nil on success, or the error returned to the guest, such as when
+// `n` exceeds the limit of wazero.ModuleConfig WithRandLimit.
+//
+// The `ctx` is the context.Context of the call which requested the bytes.
+// The random bytes themselves aren't passed, so they can't leak via the hook.
+type RandomHook func(ctx context.Context, mod api.Module, n uint32, err error)
+
+// WithRandomHook registers the given RandomHook into the given
+// context.Context. It applies to function calls made with the result.
+//
+// Here's an example that logs each request:
+//
+//	ctx = experimental.WithRandomHook(ctx, func(_ context.Context, mod api.Module, n uint32, err error) {
+//		log.Printf("%s read %d random bytes: %v", mod.Name(), n, err)
+//	})
+//	_, err := mod.ExportedFunction("run").Call(ctx)
+func WithRandomHook(ctx context.Context, hook RandomHook) context.Context {
+	return context.WithValue(ctx, RandomHookKey{}, hook)
+}
@@ -0,0 +1,52 @@
+package experimental
+
+import (
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// RateLimitMetrics is a snapshot of the counters of the rate limits of a
+// module, configured with wazero.ModuleConfig WithIORateLimit and
+// WithHostCallRateLimit. Counters of a limit which isn't configured are zero.
+type RateLimitMetrics struct {
+	// IOBytes is the count of bytes read or written by the functions subject
+	// to the IO rate limit.
+	IOBytes uint64
+
+	// IOThrottled is the count of reads or writes which waited for the IO
+	// rate limit.
+	IOThrottled uint64
+
+	// IOThrottledTime is the total time reads or writes waited for the IO
+	// rate limit.
+	IOThrottledTime time.Duration
+
+	// HostCalls is the count of calls from the module to host functions.
+	HostCalls uint64
+
+	// HostCallsThrottled is the count of HostCalls which waited for the
+	// host call rate limit.
+	HostCallsThrottled uint64
+
+	// HostCallsThrottledTime is the total time HostCalls waited for the host
+	// call rate limit.
+	HostCallsThrottledTime time.Duration
+}
+
+// GetRateLimitMetrics returns a snapshot of the RateLimitMetrics of the given
+// module, or false if it has no rate limits or is closed.
+//
+// Here's an example that reports how long a module was throttled:
+//
+//	if m, ok := experimental.GetRateLimitMetrics(mod); ok {
+//		log.Printf("%s throttled for %s", mod.Name(), m.IOThrottledTime+m.HostCallsThrottledTime)
+//	}
+func GetRateLimitMetrics(mod api.Module) (RateLimitMetrics, bool) {
+	if m, ok := mod.(interface {
+		RateLimitMetrics() (RateLimitMetrics, bool)
+	}); ok {
+		return m.RateLimitMetrics()
+	}
+	return RateLimitMetrics{}, false
+}
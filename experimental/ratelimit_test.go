@@ -0,0 +1,92 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestGetRateLimitMetrics(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	// guest calls env.leaf three times.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "leaf", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeCall, 0, wasm.OpcodeCall, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "run", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func() {}).Export("leaf").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			tests := []struct {
+				name              string
+				config            wazero.ModuleConfig
+				expectedOk        bool
+				expectedThrottled bool
+			}{
+				{
+					name:   "no limit",
+					config: wazero.NewModuleConfig(),
+				},
+				{
+					name:       "under the limit",
+					config:     wazero.NewModuleConfig().WithHostCallRateLimit(1000, 3),
+					expectedOk: true,
+				},
+				{
+					name:              "over the limit",
+					config:            wazero.NewModuleConfig().WithHostCallRateLimit(1000, 1),
+					expectedOk:        true,
+					expectedThrottled: true,
+				},
+			}
+
+			for _, tt := range tests {
+				tc := tt
+				t.Run(tc.name, func(t *testing.T) {
+					mod, err := r.InstantiateWithConfig(ctx, bin, tc.config.WithName(""))
+					require.NoError(t, err)
+					defer mod.Close(ctx)
+
+					_, err = mod.ExportedFunction("run").Call(ctx)
+					require.NoError(t, err)
+
+					m, ok := experimental.GetRateLimitMetrics(mod)
+					require.Equal(t, tc.expectedOk, ok)
+					if !ok {
+						return
+					}
+					require.Equal(t, uint64(3), m.HostCalls)
+					// Only check if throttled, as sleeps can take longer than
+					// requested, replenishing more than needed.
+					require.Equal(t, tc.expectedThrottled, m.HostCallsThrottled > 0)
+					require.Equal(t, tc.expectedThrottled, m.HostCallsThrottledTime > 0)
+					require.Zero(t, m.IOBytes)
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package experimental
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Signal numbers, as defined by wasi_snapshot_preview1, for use with
+// RaiseSignal. Other valid numbers, up to SIGSYS (30), can also be raised.
+const (
+	SIGHUP   uint32 = 1
+	SIGINT   uint32 = 2
+	SIGQUIT  uint32 = 3
+	SIGKILL  uint32 = 9
+	SIGUSR1  uint32 = 10
+	SIGUSR2  uint32 = 12
+	SIGTERM  uint32 = 15
+	SIGWINCH uint32 = 27
+)
+
+// RaiseSignal raises a signal in the module, for example SIGTERM to request
+// a graceful shutdown. This is safe to call from any goroutine, including
+// while the module runs.
+//
+// If the module exports the signal handler configured with
+// wazero.ModuleConfig WithSignalHandler, the signal is delivered to it when
+// the guest next calls a host function. Raising a signal again before it is
+// delivered has no effect. Otherwise, or for SIGKILL, a signal whose default
+// action is to terminate the process closes the module with exit code
+// 128+sig, like api.Module CloseWithExitCode, and others are ignored.
+//
+// This returns an error if `sig` isn't a valid signal number.
+func RaiseSignal(ctx context.Context, mod api.Module, sig uint32) error {
+	if m, ok := mod.(interface {
+		RaiseSignal(context.Context, uint32) error
+	}); ok {
+		return m.RaiseSignal(ctx, sig)
+	}
+	return errors.New("module doesn't support signals")
+}
@@ -0,0 +1,119 @@
+package experimental_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestRaiseSignal(t *testing.T) {
+	configs := map[string]wazero.RuntimeConfig{"interpreter": wazero.NewRuntimeConfigInterpreter()}
+	if platform.CompilerSupported() {
+		configs["compiler"] = wazero.NewRuntimeConfigCompiler()
+	}
+
+	// guest exports "tick", which calls env.leaf, "raise", which calls
+	// proc_raise, and "on_signal", which stores the signal at offset zero.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{},
+			{Params: []wasm.ValueType{wasm.ValueTypeI32}},
+			{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: "leaf", Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: wasi_snapshot_preview1.ModuleName, Name: "proc_raise", Type: wasm.ExternTypeFunc, DescFunc: 2},
+		},
+		FunctionSection: []wasm.Index{0, 1, 2},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeI32Const, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Store, 0x2, 0x0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{
+			{Name: "tick", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "on_signal", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "raise", Type: wasm.ExternTypeFunc, Index: 4},
+		},
+	})
+
+	for name, config := range configs {
+		config := config
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			wasi_snapshot_preview1.MustInstantiate(ctx, r)
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func() {}).Export("leaf").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			lastSignal := func(mod api.Module) uint32 {
+				sig, ok := mod.Memory().ReadUint32Le(0)
+				require.True(t, ok)
+				return sig
+			}
+			requireExitCode := func(err error, exitCode uint32) {
+				sysErr, ok := err.(*sys.ExitError)
+				require.True(t, ok, err)
+				require.Equal(t, exitCode, sysErr.ExitCode())
+			}
+
+			t.Run("handler", func(t *testing.T) {
+				mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().
+					WithName("").WithSignalHandler("on_signal"))
+				require.NoError(t, err)
+				defer mod.Close(ctx)
+
+				// proc_raise calls the handler synchronously.
+				results, err := mod.ExportedFunction("raise").Call(ctx, uint64(experimental.SIGINT))
+				require.NoError(t, err)
+				require.Equal(t, uint64(0), results[0])
+				require.Equal(t, experimental.SIGINT, lastSignal(mod))
+
+				// The host's signal is delivered on the next host call.
+				require.NoError(t, experimental.RaiseSignal(ctx, mod, experimental.SIGTERM))
+				require.Equal(t, experimental.SIGINT, lastSignal(mod))
+				_, err = mod.ExportedFunction("tick").Call(ctx)
+				require.NoError(t, err)
+				require.Equal(t, experimental.SIGTERM, lastSignal(mod))
+
+				require.Error(t, experimental.RaiseSignal(ctx, mod, 31))
+
+				// SIGKILL can't be handled.
+				require.NoError(t, experimental.RaiseSignal(ctx, mod, experimental.SIGKILL))
+				_, err = mod.ExportedFunction("tick").Call(ctx)
+				requireExitCode(err, 128+experimental.SIGKILL)
+			})
+
+			t.Run("default actions", func(t *testing.T) {
+				mod, err := r.InstantiateWithConfig(ctx, bin, wazero.NewModuleConfig().WithName(""))
+				require.NoError(t, err)
+				defer mod.Close(ctx)
+
+				require.NoError(t, experimental.RaiseSignal(ctx, mod, experimental.SIGWINCH))
+				_, err = mod.ExportedFunction("tick").Call(ctx)
+				require.NoError(t, err)
+
+				require.NoError(t, experimental.RaiseSignal(ctx, mod, experimental.SIGTERM))
+				_, err = mod.ExportedFunction("tick").Call(ctx)
+				requireExitCode(err, 128+experimental.SIGTERM)
+			})
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package experimental
+
+import (
+	"context"
+	"time"
+)
+
+// SleepHookKey is a context.Context Value key. Its associated value should be
+// a SleepHook.
+//
+// See WithSleepHook
+type SleepHookKey struct{}
+
+// SleepHook is called before a guest sleeps for the duration `d`, for
+// example, in WASI `poll_oneoff` with a clock subscription. It returns the
+// duration to sleep instead: `d` allows the sleep, a shorter duration
+// shortens it and zero vetoes it. Longer durations are ignored.
+//
+// The `ctx` is the context.Context of the call which sleeps. Sleeps are also
+// shortened to the deadline of `ctx`, if any, regardless of this hook.
+type SleepHook func(ctx context.Context, d time.Duration) time.Duration
+
+// WithSleepHook registers the given SleepHook into the given
+// context.Context. It applies to function calls made with the result.
+//
+// Here's an example that limits guest sleeps to one second:
+//
+//	ctx = experimental.WithSleepHook(ctx, func(_ context.Context, d time.Duration) time.Duration {
+//		if d > time.Second {
+//			return time.Second
+//		}
+//		return d
+//	})
+//	_, err := mod.ExportedFunction("run").Call(ctx)
+func WithSleepHook(ctx context.Context, hook SleepHook) context.Context {
+	return context.WithValue(ctx, SleepHookKey{}, hook)
+}
@@ -0,0 +1,341 @@
+// Package spawn includes an experimental host module, which allows a guest to
+// spawn child processes, which the host emulates, for example by
+// instantiating another module. This allows software made of multiple
+// processes to run without changes to how it spawns them.
+//
+// The guest imports the functions "proc_spawn" and "proc_wait" from the
+// module "wazero_spawn":
+//
+//	(import "wazero_spawn" "proc_spawn"
+//	  (func $proc_spawn (param $path i32) (param $path_len i32) (param $argv i32) (param $argv_len i32) (param $result.pid i32) (param $result.fds i32) (result (;errno;) i32)))
+//	(import "wazero_spawn" "proc_wait"
+//	  (func $proc_wait (param $pid i32) (param $result.exit_code i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1:
+//
+//   - proc_spawn spawns the program at path. argv is a buffer of argv_len
+//     bytes holding the NUL-terminated arguments, including the program name,
+//     like the result of "args_get". It writes the child's process ID to
+//     result.pid, and three little-endian uint32 file descriptors to
+//     result.fds: the write end of the child's stdin, and the read ends of
+//     its stdout and stderr. The result is ENOSYS if the host doesn't spawn
+//     processes.
+//   - proc_wait waits for the child to exit, and writes its exit code to
+//     result.exit_code. The result is ECHILD if pid isn't a child of the
+//     caller, or was already waited for.
+//
+// The pipes are in memory, and buffer up to PipeCapacity bytes: writing
+// blocks while the buffer is full. The guest can wait for its ends with
+// "poll_oneoff", or make them non-blocking with "fd_fdstat_set_flags". Close
+// the stdin of a child, with "fd_close", to signal the end of its input.
+//
+// The host services spawns with the Spawner of the context.Context of the
+// call, set with WithSpawner. The same Spawner is also used for `system` in
+// imports/emscripten.
+//
+// Note: This is experimental, and may change or be removed.
+package spawn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// ModuleName is the module name guests import "proc_spawn" and "proc_wait"
+// from.
+const ModuleName = "wazero_spawn"
+
+// PipeCapacity is the count of bytes buffered by each stdio pipe of a child,
+// which is the default capacity of a pipe on Linux.
+const PipeCapacity = 64 * 1024
+
+// Request is a request by a guest to spawn a child process.
+type Request struct {
+	// Parent is the module spawning the child.
+	Parent api.Module
+
+	// Path is the path of the program, e.g. "/bin/ls" or "sh".
+	Path string
+
+	// Args are the arguments of the program, including its name as Args[0].
+	Args []string
+
+	// Stdin, Stdout and Stderr are the standard input and output of the
+	// child, connected to the parent.
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Spawner starts the child process of a request, and returns a function which
+// waits for it to exit and returns its exit code. An error fails the spawn,
+// for example, sys.ENOENT when the program isn't found.
+//
+// The child must not use the stdio of the request after it exits. If the
+// spawn fails after the Spawner returns, the stdio of the request is closed
+// and `ctx` is canceled, so the child should exit as soon as possible.
+type Spawner func(ctx context.Context, req Request) (wait func() uint32, err error)
+
+// SpawnerKey is a context.Context Value key. Its associated value should be
+// a Spawner.
+//
+// See WithSpawner
+type SpawnerKey struct{}
+
+// WithSpawner registers the given Spawner into the given context.Context. It
+// applies to function calls made with the result.
+func WithSpawner(ctx context.Context, spawner Spawner) context.Context {
+	return context.WithValue(ctx, SpawnerKey{}, spawner)
+}
+
+// NewModuleSpawner returns a Spawner which runs the compiled module of the
+// request Path in `programs` as the child, instantiating it with `config`
+// and the stdio and arguments of the request. The module must be compiled
+// with the runtime `r`, which must have any host modules it imports, such as
+// wasi_snapshot_preview1. The request fails with sys.ENOENT if the program
+// isn't in `programs`.
+//
+// The exit code is that of the child's sys.ExitError, or zero if it returned
+// normally. If it fails otherwise, the error is written to its stderr and
+// the exit code is one.
+func NewModuleSpawner(r wazero.Runtime, programs map[string]wazero.CompiledModule, config wazero.ModuleConfig) Spawner {
+	return func(ctx context.Context, req Request) (func() uint32, error) {
+		compiled, ok := programs[req.Path]
+		if !ok {
+			return nil, experimentalsys.ENOENT
+		}
+		childConfig := config.WithName("").WithArgs(req.Args...).
+			WithStdin(req.Stdin).WithStdout(req.Stdout).WithStderr(req.Stderr)
+
+		done := make(chan uint32, 1)
+		go func() {
+			mod, err := r.InstantiateModule(ctx, compiled, childConfig)
+			if err == nil {
+				_ = mod.Close(ctx)
+				done <- 0
+			} else if exitErr, ok := err.(*sys.ExitError); ok {
+				done <- exitErr.ExitCode()
+			} else {
+				_, _ = fmt.Fprintln(req.Stderr, err)
+				done <- 1
+			}
+		}()
+		return func() uint32 { return <-done }, nil
+	}
+}
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	p := &processes{children: map[uint32]*child{}}
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(p.procSpawn),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("path", "path_len", "argv", "argv_len", "result.pid", "result.fds").
+		WithResultNames("errno").
+		Export("proc_spawn").
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(p.procWait),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("pid", "result.exit_code").
+		WithResultNames("errno").
+		Export("proc_wait").
+		Instantiate(ctx)
+}
+
+// processes are the children spawned by guests of one ModuleName module.
+type processes struct {
+	mux      sync.Mutex
+	lastPid  uint32
+	children map[uint32]*child
+}
+
+type child struct {
+	parent   api.Module
+	done     chan struct{}
+	exitCode uint32
+}
+
+func (p *processes) procSpawn(ctx context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(p.procSpawnFn(ctx, mod, stack))
+}
+
+func (p *processes) procSpawnFn(ctx context.Context, mod api.Module, params []uint64) wasip1.Errno {
+	path, pathLen := uint32(params[0]), uint32(params[1])
+	argv, argvLen := uint32(params[2]), uint32(params[3])
+	resultPid, resultFds := uint32(params[4]), uint32(params[5])
+
+	spawner, ok := ctx.Value(SpawnerKey{}).(Spawner)
+	if !ok || spawner == nil {
+		return wasip1.ErrnoNosys
+	}
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+
+	mem := mod.Memory()
+	pathBytes, ok := mem.Read(path, pathLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	argvBytes, ok := mem.Read(argv, argvLen)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	args, errno := splitArgs(string(pathBytes), argvBytes)
+	if errno != 0 {
+		return errno
+	}
+	// Check the results can be written before spawning.
+	if _, ok = mem.Read(resultPid, 4); !ok {
+		return wasip1.ErrnoFault
+	}
+	if _, ok = mem.Read(resultFds, 12); !ok {
+		return wasip1.ErrnoFault
+	}
+
+	stdinR, stdinW := internalsys.NewPipe(PipeCapacity)
+	stdoutR, stdoutW := internalsys.NewPipe(PipeCapacity)
+	stderrR, stderrW := internalsys.NewPipe(PipeCapacity)
+	closeChild := func() {
+		_ = stdinR.Close()
+		_ = stdoutW.Close()
+		_ = stderrW.Close()
+	}
+	childCtx, cancel := context.WithCancel(ctx)
+	wait, err := spawner(childCtx, Request{
+		Parent: mod,
+		Path:   string(pathBytes),
+		Args:   args,
+		Stdin:  stdinR.IO(),
+		Stdout: stdoutW.IO(),
+		Stderr: stderrW.IO(),
+	})
+	if err != nil {
+		cancel()
+		closeChild()
+		return wasip1.ToErrno(experimentalsys.UnwrapOSError(err))
+	}
+
+	c := &child{parent: mod, done: make(chan struct{})}
+	go func() {
+		c.exitCode = wait()
+		cancel()
+		closeChild()
+		close(c.done)
+	}()
+
+	fsc := sysCtx.FS()
+	var fds [3]int32
+	for i, f := range []struct {
+		file fsapi.File
+		name string
+	}{
+		{stdinW, "stdin"},
+		{stdoutR, "stdout"},
+		{stderrR, "stderr"},
+	} {
+		fd, errno := fsc.InsertFile(f.file, f.name)
+		if errno != 0 {
+			// Close the parent's ends, including those already inserted,
+			// and kill the child, which is then reaped by the goroutine
+			// above.
+			for _, fd := range fds[:i] {
+				_ = fsc.CloseFile(fd)
+			}
+			_, _, _ = stdinW.Close(), stdoutR.Close(), stderrR.Close()
+			cancel()
+			closeChild()
+			return wasip1.ToErrno(errno)
+		}
+		fds[i] = fd
+	}
+
+	p.mux.Lock()
+	p.lastPid++
+	pid := p.lastPid
+	p.children[pid] = c
+	p.mux.Unlock()
+
+	mem.WriteUint32Le(resultPid, pid)
+	for i, fd := range fds {
+		mem.WriteUint32Le(resultFds+uint32(i)*4, uint32(fd))
+	}
+	return wasip1.ErrnoSuccess
+}
+
+// splitArgs splits the NUL-terminated arguments, defaulting to the path as
+// the program name.
+func splitArgs(path string, argv []byte) ([]string, wasip1.Errno) {
+	if len(argv) == 0 {
+		return []string{path}, 0
+	}
+	if argv[len(argv)-1] != 0 {
+		return nil, wasip1.ErrnoInval
+	}
+	var args []string
+	for _, arg := range bytes.Split(argv[:len(argv)-1], []byte{0}) {
+		args = append(args, string(arg))
+	}
+	return args, 0
+}
+
+func (p *processes) procWait(ctx context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(p.procWaitFn(ctx, mod, stack))
+}
+
+func (p *processes) procWaitFn(ctx context.Context, mod api.Module, params []uint64) wasip1.Errno {
+	pid, resultExitCode := uint32(params[0]), uint32(params[1])
+
+	p.mux.Lock()
+	c, ok := p.children[pid]
+	p.mux.Unlock()
+	if !ok || c.parent != mod {
+		return wasip1.ErrnoChild
+	}
+
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return wasip1.ErrnoIntr
+	}
+
+	if !mod.Memory().WriteUint32Le(resultExitCode, c.exitCode) {
+		return wasip1.ErrnoFault
+	}
+	p.mux.Lock()
+	delete(p.children, pid)
+	p.mux.Unlock()
+	return wasip1.ErrnoSuccess
+}
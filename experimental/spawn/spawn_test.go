@@ -0,0 +1,204 @@
+package spawn_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/spawn"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// parentWasm exports functions "proc_spawn" and "proc_wait", which call the
+// imported ones, and its memory.
+var parentWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{
+			Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+			Results: []api.ValueType{api.ValueTypeI32},
+		},
+		{Params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: spawn.ModuleName, Name: "proc_spawn", DescFunc: 0},
+		{Type: wasm.ExternTypeFunc, Module: spawn.ModuleName, Name: "proc_wait", DescFunc: 1},
+	},
+	FunctionSection: []wasm.Index{0, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2,
+			wasm.OpcodeLocalGet, 3, wasm.OpcodeLocalGet, 4, wasm.OpcodeLocalGet, 5,
+			wasm.OpcodeCall, 0,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	ExportSection: []wasm.Export{
+		{Name: "proc_spawn", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "proc_wait", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+// childWasm exits with code 7 from its "_start" function.
+var childWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []api.ValueType{api.ValueTypeI32}},
+		{},
+	},
+	ImportSection: []wasm.Import{
+		{Type: wasm.ExternTypeFunc, Module: wasi_snapshot_preview1.ModuleName, Name: wasip1.ProcExitName, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeI32Const, 7, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "_start", Type: wasm.ExternTypeFunc, Index: 1},
+	},
+})
+
+const (
+	path       = 0
+	argv       = 32
+	resultPid  = 64
+	resultFds  = 68
+	resultCode = 80
+)
+
+func TestInstantiate(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+	spawn.MustInstantiate(testCtx, r)
+
+	child, err := r.CompileModule(testCtx, childWasm)
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateWithConfig(testCtx, parentWasm, wazero.NewModuleConfig().WithName("parent"))
+	require.NoError(t, err)
+	mem := mod.Memory()
+
+	spawnProc := func(ctx context.Context, p, args string) (wasip1.Errno, uint32, [3]uint32) {
+		require.True(t, mem.WriteString(path, p))
+		require.True(t, mem.WriteString(argv, args))
+		results, err := mod.ExportedFunction("proc_spawn").Call(ctx, path, uint64(len(p)), argv, uint64(len(args)), resultPid, resultFds)
+		require.NoError(t, err)
+		pid, _ := mem.ReadUint32Le(resultPid)
+		var fds [3]uint32
+		for i := range fds {
+			fds[i], _ = mem.ReadUint32Le(resultFds + uint32(i)*4)
+		}
+		return wasip1.Errno(results[0]), pid, fds
+	}
+	waitProc := func(ctx context.Context, pid uint32) (wasip1.Errno, uint32) {
+		results, err := mod.ExportedFunction("proc_wait").Call(ctx, uint64(pid), resultCode)
+		require.NoError(t, err)
+		code, _ := mem.ReadUint32Le(resultCode)
+		return wasip1.Errno(results[0]), code
+	}
+
+	t.Run("no spawner", func(t *testing.T) {
+		errno, _, _ := spawnProc(testCtx, "child", "")
+		require.Equal(t, wasip1.ErrnoNosys, errno)
+	})
+
+	t.Run("module spawner", func(t *testing.T) {
+		ctx := spawn.WithSpawner(testCtx, spawn.NewModuleSpawner(r,
+			map[string]wazero.CompiledModule{"child": child}, wazero.NewModuleConfig()))
+
+		errno, _, _ := spawnProc(ctx, "missing", "")
+		require.Equal(t, wasip1.ErrnoNoent, errno)
+
+		errno, pid, fds := spawnProc(ctx, "child", "child\x00-v\x00")
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+		require.Equal(t, [3]uint32{3, 4, 5}, fds)
+
+		errno, code := waitProc(ctx, pid)
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+		require.Equal(t, uint32(7), code)
+
+		// Already waited for.
+		errno, _ = waitProc(ctx, pid)
+		require.Equal(t, wasip1.ErrnoChild, errno)
+	})
+
+	t.Run("pipes", func(t *testing.T) {
+		var req spawn.Request
+		ctx := spawn.WithSpawner(testCtx, func(_ context.Context, r spawn.Request) (func() uint32, error) {
+			req = r
+			done := make(chan struct{})
+			go func() {
+				_, _ = io.Copy(r.Stdout, r.Stdin)
+				close(done)
+			}()
+			return func() uint32 { <-done; return 0 }, nil
+		})
+
+		errno, pid, fds := spawnProc(ctx, "cat", "")
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+		require.Equal(t, []string{"cat"}, req.Args)
+		require.Equal(t, mod, req.Parent)
+
+		errno, _, _ = spawnProc(ctx, "cat", "no-nul")
+		require.Equal(t, wasip1.ErrnoInval, errno)
+
+		fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+		stdin, ok := fsc.LookupFile(int32(fds[0]))
+		require.True(t, ok)
+		stdout, ok := fsc.LookupFile(int32(fds[1]))
+		require.True(t, ok)
+
+		// The parent's ends can be polled.
+		ready, errno2 := stdout.File.Poll(fsapi.POLLIN, 0)
+		require.EqualErrno(t, 0, errno2)
+		require.False(t, ready)
+
+		_, errno2 = stdin.File.Write([]byte("hello"))
+		require.EqualErrno(t, 0, errno2)
+		ready, errno2 = stdout.File.Poll(fsapi.POLLIN, -1)
+		require.EqualErrno(t, 0, errno2)
+		require.True(t, ready)
+
+		buf := make([]byte, 5)
+		n, errno2 := stdout.File.Read(buf)
+		require.EqualErrno(t, 0, errno2)
+		require.Equal(t, "hello", string(buf[:n]))
+		require.EqualErrno(t, 0, fsc.CloseFile(int32(fds[0])))
+
+		errno, code := waitProc(ctx, pid)
+		require.Equal(t, wasip1.ErrnoSuccess, errno)
+		require.Equal(t, uint32(0), code)
+
+		// The child closes its stdout when it exits.
+		n, errno2 = stdout.File.Read(buf)
+		require.EqualErrno(t, 0, errno2)
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("spawner error", func(t *testing.T) {
+		ctx := spawn.WithSpawner(testCtx, func(context.Context, spawn.Request) (func() uint32, error) {
+			return nil, experimentalsys.EACCES
+		})
+		errno, _, _ := spawnProc(ctx, "child", "")
+		require.Equal(t, wasip1.ErrnoAcces, errno)
+	})
+
+	t.Run("not a child", func(t *testing.T) {
+		errno, _ := waitProc(testCtx, 42)
+		require.Equal(t, wasip1.ErrnoChild, errno)
+	})
+}
@@ -0,0 +1,89 @@
+// Package splice includes an experimental host module, which allows a guest
+// to copy data between file descriptors without copying it through its
+// memory. For example, a guest can send a file to a socket, or copy a file,
+// without a loop of "fd_read" and "fd_write".
+//
+// The guest imports the function "fd_splice" from the module "wazero_splice":
+//
+//	(import "wazero_splice" "fd_splice"
+//	  (func $fd_splice (param $fd_out i32) (param $fd_in i32) (param $len i64) (param $result.nspliced i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1:
+//
+//   - fd_out: file descriptor to write to
+//   - fd_in: file descriptor to read from, at its current offset
+//   - len: maximum count of bytes to copy
+//   - result.nspliced: offset in memory to write the count of bytes copied,
+//     as uint64le, which is zero at the end of `fd_in`
+//
+// The result is a WASI errno, notably ENOSYS when the host doesn't allow this
+// or doesn't support it for the given file descriptors. The guest should fall
+// back to copying with "fd_read" and "fd_write" in this case.
+//
+// The host allows this with wazero.FSConfig WithSplice.
+//
+// Note: This is experimental, and may change or be removed.
+package splice
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name guests import "fd_splice" from.
+const ModuleName = "wazero_splice"
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(fdSplice),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("fd_out", "fd_in", "len", "result.nspliced").
+		WithResultNames("errno").
+		Export("fd_splice").
+		Instantiate(ctx)
+}
+
+func fdSplice(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(fdSpliceFn(mod, stack))
+}
+
+func fdSpliceFn(mod api.Module, params []uint64) wasip1.Errno {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+	outFd, inFd := int32(params[0]), int32(params[1])
+	n, resultNspliced := int64(params[2]), uint32(params[3])
+
+	nspliced, errno := sysCtx.FS().Splice(outFd, inFd, n)
+	if errno != 0 {
+		return wasip1.ToErrno(errno)
+	}
+	if !mod.Memory().WriteUint64Le(resultNspliced, uint64(nspliced)) {
+		return wasip1.ErrnoFault
+	}
+	return wasip1.ErrnoSuccess
+}
@@ -0,0 +1,87 @@
+package splice_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/splice"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// spliceWasm exports a function "fd_splice", which calls the imported one.
+var spliceWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{
+		Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI32},
+		Results: []api.ValueType{api.ValueTypeI32},
+	}},
+	ImportSection:   []wasm.Import{{Type: wasm.ExternTypeFunc, Module: splice.ModuleName, Name: "fd_splice", DescFunc: 0}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeEnd,
+	}}},
+	MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+	ExportSection: []wasm.Export{{Name: "fd_splice", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+func TestInstantiate(t *testing.T) {
+	tests := []struct {
+		name          string
+		splice        bool
+		expectedErrno wasip1.Errno
+		expectedOut   string
+	}{
+		{name: "enabled", splice: true, expectedErrno: wasip1.ErrnoSuccess, expectedOut: "waz"},
+		{name: "disabled", splice: false, expectedErrno: wasip1.ErrnoNosys, expectedOut: ""},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			require.NoError(t, os.WriteFile(path.Join(tmpDir, "in"), []byte("wazero"), 0o600))
+
+			r := wazero.NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			splice.MustInstantiate(testCtx, r)
+
+			fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithSplice(tc.splice)
+			mod, err := r.InstantiateWithConfig(testCtx, spliceWasm, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+			require.NoError(t, err)
+
+			fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+			inFd, errno := fsc.OpenFile(fsc.RootFS(), "in", experimentalsys.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			outFd, errno := fsc.OpenFile(fsc.RootFS(), "out", experimentalsys.O_WRONLY|experimentalsys.O_CREAT, 0o600)
+			require.EqualErrno(t, 0, errno)
+
+			resultNspliced := uint32(8)
+			results, err := mod.ExportedFunction("fd_splice").Call(testCtx, uint64(outFd), uint64(inFd), 3, uint64(resultNspliced))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedErrno, wasip1.Errno(results[0]))
+
+			if tc.expectedErrno == wasip1.ErrnoSuccess {
+				nspliced, ok := mod.Memory().ReadUint64Le(resultNspliced)
+				require.True(t, ok)
+				require.Equal(t, uint64(3), nspliced)
+			}
+
+			out, err := os.ReadFile(path.Join(tmpDir, "out"))
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedOut, string(out))
+		})
+	}
+}
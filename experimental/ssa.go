@@ -0,0 +1,144 @@
+package experimental
+
+import "context"
+
+// SSAInspectorKey is a context.Context Value key. Its associated value should
+// be a SSAInspector.
+//
+// See WithSSAInspector
+type SSAInspectorKey struct{}
+
+// WithSSAInspector registers the given SSAInspector into the given
+// context.Context. It applies to modules compiled with the result, e.g. via
+// wazero.Runtime CompileModule.
+//
+// This is intended for compiler research and IR-level golden tests, so there
+// are no compatibility guarantees on the text format or opcode names.
+//
+// Note: Only the optimizing compiler, which lowers Wasm to an SSA form, calls
+// the SSAInspector. Other compilers, including the interpreter, ignore it.
+// Modules compiled with an SSAInspector bypass the compilation cache, so it
+// is called on each compilation, and its edits never apply to a module
+// compiled without it.
+func WithSSAInspector(ctx context.Context, inspector SSAInspector) context.Context {
+	if inspector != nil {
+		return context.WithValue(ctx, SSAInspectorKey{}, inspector)
+	}
+	return ctx
+}
+
+// SSAPhase is the point in the compilation of a function when an SSAInspector
+// is called.
+type SSAPhase byte
+
+const (
+	// SSAPhaseLowered is right after a function is lowered from Wasm to SSA,
+	// before optimization passes. Edits made here are subject to the passes,
+	// e.g. instructions no longer used are eliminated.
+	SSAPhaseLowered SSAPhase = iota
+	// SSAPhaseOptimized is after optimization passes, right before the SSA is
+	// lowered to machine code. Edits made here are not optimized further.
+	SSAPhaseOptimized
+)
+
+// String implements fmt.Stringer.
+func (p SSAPhase) String() string {
+	switch p {
+	case SSAPhaseLowered:
+		return "lowered"
+	case SSAPhaseOptimized:
+		return "optimized"
+	}
+	return "unknown"
+}
+
+// SSAInspector is called with the SSA form of each function compiled by the
+// optimizing compiler, once per SSAPhase.
+//
+// Functions of a module are compiled sequentially, so an implementation needs
+// no synchronization unless it's shared between concurrent compilations.
+type SSAInspector interface {
+	// InspectSSA is called with the function in the given phase. fn is only
+	// valid during the call.
+	InspectSSA(phase SSAPhase, fn SSAFunction)
+}
+
+// SSAInspectorFunc is a convenience for defining a SSAInspector with a
+// function.
+type SSAInspectorFunc func(phase SSAPhase, fn SSAFunction)
+
+// InspectSSA implements SSAInspector.InspectSSA
+func (f SSAInspectorFunc) InspectSSA(phase SSAPhase, fn SSAFunction) {
+	f(phase, fn)
+}
+
+// SSAFunction is a function in SSA form, made of basic blocks of
+// instructions.
+type SSAFunction interface {
+	// Index is the index of the function in the module, including imported
+	// functions.
+	Index() uint32
+
+	// Format returns the text format of the function, the same as printed by
+	// the compiler's debug options.
+	Format() string
+
+	// Visit calls visitor with each instruction of each basic block, in
+	// order. The visitor may edit the given instruction, e.g. with
+	// SSAInstruction.ReplaceWithConstant.
+	Visit(visitor func(block SSABlock, inst SSAInstruction))
+}
+
+// SSABlock is a basic block of a SSAFunction.
+type SSABlock interface {
+	// Name returns the name of the block, e.g. "blk0".
+	Name() string
+
+	// Params returns the parameters of the block.
+	Params() []SSAValue
+}
+
+// SSAInstruction is an instruction of a SSABlock.
+type SSAInstruction interface {
+	// Opcode returns the name of the opcode, e.g. "Iadd".
+	Opcode() string
+
+	// Format returns the text format of the instruction, e.g.
+	// "v3:i32 = Iadd v1, v2".
+	Format() string
+
+	// Args returns the values used by the instruction, including the
+	// arguments passed to the target block of a branch.
+	Args() []SSAValue
+
+	// Results returns the values defined by the instruction.
+	Results() []SSAValue
+
+	// Constant returns the bits of the value of a constant instruction and
+	// true, or false if the instruction isn't a constant. Floating-point
+	// constants are in their IEEE 754 binary representation.
+	Constant() (bits uint64, ok bool)
+
+	// ReplaceWithConstant replaces the instruction with a constant of the
+	// type of its result, which keeps the same SSAValue. The bits are
+	// interpreted as in Constant.
+	//
+	// This returns false without editing anything if the instruction has side
+	// effects, e.g. may trap, or doesn't define exactly one integer or
+	// floating-point value.
+	ReplaceWithConstant(bits uint64) bool
+}
+
+// SSAValue is a value defined by a SSAInstruction, or a parameter of a
+// SSABlock.
+type SSAValue interface {
+	// Format returns the text format of the value, e.g. "v3".
+	Format() string
+
+	// Type returns the name of the type, e.g. "i32".
+	Type() string
+
+	// Definition returns the instruction which defines the value, or nil if
+	// the value is a parameter of a SSABlock.
+	Definition() SSAInstruction
+}
@@ -0,0 +1,84 @@
+// Package syncrange includes an experimental host module, which allows a
+// guest to batch writing files to storage. For example, a database can start
+// writing each page of its log as it fills it, so that the "fd_datasync" of
+// a commit has less to wait for.
+//
+// The guest imports the function "fd_sync_range" from the module
+// "wazero_syncrange":
+//
+//	(import "wazero_syncrange" "fd_sync_range"
+//	  (func $fd_sync_range (param $fd i32) (param $offset i64) (param $len i64) (param $flags i32) (result (;errno;) i32)))
+//
+// The parameters and results use the same conventions as the functions in
+// wasi_snapshot_preview1, and the same semantics as sync_file_range on Linux:
+//
+//   - fd: file descriptor to write to storage
+//   - offset: offset in the file of the range
+//   - len: length of the range, or zero for through the end of the file
+//   - flags: bitwise OR of SYNC_FILE_RANGE_WAIT_BEFORE (1),
+//     SYNC_FILE_RANGE_WRITE (2) and SYNC_FILE_RANGE_WAIT_AFTER (4)
+//
+// The result is a WASI errno. This doesn't flush metadata or the storage
+// device's cache, so the guest must still call "fd_datasync" or "fd_sync"
+// for durability. Where sync_file_range isn't supported, flags including
+// SYNC_FILE_RANGE_WAIT_AFTER sync the whole file's data, and others succeed
+// without doing anything.
+//
+// This succeeds without doing anything when the host disabled syncing with
+// wazero.FSConfig WithSync.
+//
+// Note: This is experimental, and may change or be removed.
+package syncrange
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModuleName is the module name guests import "fd_sync_range" from.
+const ModuleName = "wazero_syncrange"
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the module ModuleName is not
+// already instantiated, and don't need to unload it.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates the ModuleName module into the runtime.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	return r.NewHostModuleBuilder(ModuleName).
+		NewFunctionBuilder().
+		WithGoModuleFunction(api.GoModuleFunc(fdSyncRange),
+			[]api.ValueType{api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI64, api.ValueTypeI32},
+			[]api.ValueType{api.ValueTypeI32}).
+		WithParameterNames("fd", "offset", "len", "flags").
+		WithResultNames("errno").
+		Export("fd_sync_range").
+		Instantiate(ctx)
+}
+
+func fdSyncRange(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(fdSyncRangeFn(mod, stack))
+}
+
+func fdSyncRangeFn(mod api.Module, params []uint64) wasip1.Errno {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return wasip1.ErrnoNosys
+	}
+	fd, offset, n, flags := int32(params[0]), int64(params[1]), int64(params[2]), int(uint32(params[3]))
+	return wasip1.ToErrno(sysCtx.FS().SyncRange(fd, offset, n, flags))
+}
@@ -0,0 +1,90 @@
+package syncrange_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/syncrange"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// syncRangeWasm exports a function "fd_sync_range", which calls the imported
+// one.
+var syncRangeWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{
+		Params:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeI64, api.ValueTypeI32},
+		Results: []api.ValueType{api.ValueTypeI32},
+	}},
+	ImportSection:   []wasm.Import{{Type: wasm.ExternTypeFunc, Module: syncrange.ModuleName, Name: "fd_sync_range", DescFunc: 0}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+		wasm.OpcodeCall, 0,
+		wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "fd_sync_range", Type: wasm.ExternTypeFunc, Index: 1}},
+})
+
+func TestInstantiate(t *testing.T) {
+	const (
+		waitBefore = 0x1
+		write      = 0x2
+		waitAfter  = 0x4
+	)
+
+	tests := []struct {
+		name          string
+		disabled      bool
+		badFd         bool
+		offset, len   int64
+		flags         uint64
+		expectedErrno wasip1.Errno
+	}{
+		{name: "write", flags: write, expectedErrno: wasip1.ErrnoSuccess},
+		{name: "write range", offset: 2, len: 3, flags: write, expectedErrno: wasip1.ErrnoSuccess},
+		{name: "wait", flags: waitBefore | write | waitAfter, expectedErrno: wasip1.ErrnoSuccess},
+		{name: "disabled", disabled: true, flags: waitBefore | write | waitAfter, expectedErrno: wasip1.ErrnoSuccess},
+		{name: "negative offset", offset: -1, flags: write, expectedErrno: wasip1.ErrnoInval},
+		{name: "invalid flags", flags: 8, expectedErrno: wasip1.ErrnoInval},
+		{name: "EBADF", badFd: true, flags: write, expectedErrno: wasip1.ErrnoBadf},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			require.NoError(t, os.WriteFile(path.Join(tmpDir, "db"), []byte("wazero"), 0o600))
+
+			r := wazero.NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			syncrange.MustInstantiate(testCtx, r)
+
+			fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/").WithSync(!tc.disabled)
+			mod, err := r.InstantiateWithConfig(testCtx, syncRangeWasm, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+			require.NoError(t, err)
+
+			fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+			fd, errno := fsc.OpenFile(fsc.RootFS(), "db", experimentalsys.O_RDWR, 0)
+			require.EqualErrno(t, 0, errno)
+			if tc.badFd {
+				fd = 42
+			}
+
+			results, err := mod.ExportedFunction("fd_sync_range").Call(testCtx, uint64(fd), uint64(tc.offset), uint64(tc.len), tc.flags)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedErrno, wasip1.Errno(results[0]))
+		})
+	}
+}
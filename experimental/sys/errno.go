@@ -33,13 +33,16 @@ const (
 	ELOOP
 	ENAMETOOLONG
 	ENOENT
+	ENOSPC
 	ENOSYS
 	ENOTDIR
 	ERANGE
 	ENOTEMPTY
 	ENOTSOCK
 	ENOTSUP
+	ENOTTY
 	EPERM
+	EPIPE
 	EROFS
 
 	// NOTE ENOTCAPABLE is defined in wasip1, but not in POSIX. wasi-libc
@@ -76,6 +79,8 @@ func (e Errno) Error() string {
 		return "filename too long"
 	case ENOENT:
 		return "no such file or directory"
+	case ENOSPC:
+		return "no space left on device"
 	case ENOSYS:
 		return "functionality not supported"
 	case ENOTDIR:
@@ -88,8 +93,12 @@ func (e Errno) Error() string {
 		return "not a socket"
 	case ENOTSUP:
 		return "not supported (may be the same value as [EOPNOTSUPP])"
+	case ENOTTY:
+		return "inappropriate ioctl for device"
 	case EPERM:
 		return "operation not permitted"
+	case EPIPE:
+		return "broken pipe"
 	case EROFS:
 		return "read-only file system"
 	default:
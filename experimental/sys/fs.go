@@ -106,8 +106,9 @@ type FS interface {
 	// A zero Errno is success. The below are expected otherwise:
 	//   - ENOSYS: the implementation does not support this function.
 	//   - EINVAL: `path` is invalid.
-	//   - EEXIST: `path` exists and is a directory.
-	//   - ENOTDIR: `path` exists and is a file.
+	//   - EEXIST: `path` exists.
+	//   - ENOENT: a parent of `path` doesn't exist.
+	//   - ENOTDIR: a parent of `path` is a file.
 	//
 	// # Notes
 	//
@@ -36,6 +36,8 @@ func syscallToErrno(err error) (Errno, bool) {
 		return ENAMETOOLONG, true
 	case syscall.ENOENT:
 		return ENOENT, true
+	case syscall.ENOSPC:
+		return ENOSPC, true
 	case syscall.ENOSYS:
 		return ENOSYS, true
 	case syscall.ENOTDIR:
@@ -48,8 +50,12 @@ func syscallToErrno(err error) (Errno, bool) {
 		return ENOTSOCK, true
 	case syscall.ENOTSUP:
 		return ENOTSUP, true
+	case syscall.ENOTTY:
+		return ENOTTY, true
 	case syscall.EPERM:
 		return EPERM, true
+	case syscall.EPIPE:
+		return EPIPE, true
 	case syscall.EROFS:
 		return EROFS, true
 	default:
@@ -86,6 +92,8 @@ func (e Errno) Unwrap() error {
 		return syscall.ENAMETOOLONG
 	case ENOENT:
 		return syscall.ENOENT
+	case ENOSPC:
+		return syscall.ENOSPC
 	case ENOSYS:
 		return syscall.ENOSYS
 	case ENOTDIR:
@@ -96,8 +104,12 @@ func (e Errno) Unwrap() error {
 		return syscall.ENOTSOCK
 	case ENOTSUP:
 		return syscall.ENOTSUP
+	case ENOTTY:
+		return syscall.ENOTTY
 	case EPERM:
 		return syscall.EPERM
+	case EPIPE:
+		return syscall.EPIPE
 	case EROFS:
 		return syscall.EROFS
 	default:
@@ -0,0 +1,39 @@
+package experimental
+
+import "context"
+
+// TraceKey is a context.Context Value key. Its associated value should be a
+// boolean.
+//
+// See WithTrace
+type TraceKey struct{}
+
+// WithTrace enables the annotation of the execution trace, as collected by
+// runtime/trace, with the activity of a wazero.Runtime created with the
+// result, e.g. by wazero.NewRuntimeWithConfig. This allows `go tool trace` to
+// show wazero alongside the goroutines of the host when diagnosing latency.
+//
+// When enabled and the trace is being collected, the following are recorded:
+//
+//   - A "wazero.CompileModule" task for each wazero.Runtime CompileModule.
+//   - A "wazero.InstantiateModule" task for each wazero.Runtime
+//     InstantiateModule, with the module name logged in the "module" category.
+//   - A "wazero.Call <module>.<function>" region for each call of an
+//     api.Function returned by api.Module ExportedFunction, where <module> is
+//     the name of the module, and <function> is the name of the export.
+//
+// Here's an example:
+//
+//	ctx = experimental.WithTrace(ctx)
+//	r := wazero.NewRuntime(ctx)
+//	defer r.Close(ctx)
+//
+//	_ = trace.Start(f)
+//	defer trace.Stop()
+//	// Compile, instantiate and call functions as usual.
+//
+// Note: Tracing is disabled by default. When enabled, the overhead while the
+// trace is not being collected is a check per operation.
+func WithTrace(ctx context.Context) context.Context {
+	return context.WithValue(ctx, TraceKey{}, true)
+}
@@ -0,0 +1,71 @@
+package typedcall
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// CallContext is a reusable call to an api.Function of any signature. Its
+// params and results share a stack pre-bound to the function, so CallEx
+// allocates neither, unlike api.Function Call.
+//
+// For example, the following calls searchFn repeatedly without allocating:
+//
+//	cc := typedcall.NewCallContext(searchFn)
+//	for _, search := range searchParams {
+//		copy(cc.Params(), search)
+//		results, err := cc.CallEx(ctx)
+//		if err != nil {
+//			return err
+//		}
+//		// use results[0] before the next call overwrites it
+//	}
+//
+// Like api.Function, a CallContext is not goroutine-safe.
+type CallContext struct {
+	fn                  api.Function
+	stack               []uint64
+	paramLen, resultLen int
+}
+
+// NewCallContext returns a CallContext bound to `fn`.
+func NewCallContext(fn api.Function) *CallContext {
+	def := fn.Definition()
+	paramLen, resultLen := len(def.ParamTypes()), len(def.ResultTypes())
+	stackLen := paramLen
+	if stackLen < resultLen {
+		stackLen = resultLen
+	}
+	// Always allocate at least one slot, so that Params and the results of
+	// CallEx are never nil.
+	if stackLen == 0 {
+		stackLen = 1
+	}
+	return &CallContext{fn: fn, stack: make([]uint64, stackLen), paramLen: paramLen, resultLen: resultLen}
+}
+
+// Function returns the function this is bound to.
+func (c *CallContext) Function() api.Function {
+	return c.fn
+}
+
+// Params returns the params of the next call to CallEx, which the caller
+// writes before calling it. These are encoded as documented on api.ValueType.
+//
+// The contents are undefined after CallEx, so set all params before each call.
+func (c *CallContext) Params() []uint64 {
+	return c.stack[:c.paramLen]
+}
+
+// CallEx calls the function with the current Params, as documented on
+// api.Function CallWithStack.
+//
+// The results are a view of the pre-bound stack, which is valid until the
+// next call to CallEx or write to Params. Copy them to retain them longer.
+func (c *CallContext) CallEx(ctx context.Context) ([]uint64, error) {
+	if err := c.fn.CallWithStack(ctx, c.stack); err != nil {
+		return nil, err
+	}
+	return c.stack[:c.resultLen], nil
+}
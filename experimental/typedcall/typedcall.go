@@ -0,0 +1,178 @@
+// Package typedcall provides Go functions calling api.Function of common
+// signatures with typed parameters and results.
+//
+// The returned functions marshal the parameters and results on a stack
+// pre-bound to the function, via api.Function CallWithStack. Therefore, unlike
+// api.Function Call, they neither allocate nor encode values into a variadic
+// []uint64 on each call. This matters when calling into Wasm at a high rate,
+// e.g. millions of calls per second.
+//
+// CallContext does the same for functions of any other signature, in terms of
+// the encoded []uint64 params and results.
+//
+// Like api.Function, the returned functions are not goroutine-safe.
+package typedcall
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+const (
+	i32 = api.ValueTypeI32
+	i64 = api.ValueTypeI64
+	f32 = api.ValueTypeF32
+	f64 = api.ValueTypeF64
+)
+
+// NoneToNone returns a function calling `fn` of the signature () -> ().
+func NoneToNone(fn api.Function) (func(ctx context.Context) error, error) {
+	if err := checkSignature(fn, nil, nil); err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) error {
+		return fn.CallWithStack(ctx, nil)
+	}, nil
+}
+
+// I32ToNone returns a function calling `fn` of the signature (i32) -> ().
+func I32ToNone(fn api.Function) (func(ctx context.Context, x uint32) error, error) {
+	if err := checkSignature(fn, []api.ValueType{i32}, nil); err != nil {
+		return nil, err
+	}
+	var stack [1]uint64
+	return func(ctx context.Context, x uint32) error {
+		stack[0] = api.EncodeU32(x)
+		return fn.CallWithStack(ctx, stack[:])
+	}, nil
+}
+
+// NoneToI32 returns a function calling `fn` of the signature () -> (i32).
+func NoneToI32(fn api.Function) (func(ctx context.Context) (uint32, error), error) {
+	if err := checkSignature(fn, nil, []api.ValueType{i32}); err != nil {
+		return nil, err
+	}
+	var stack [1]uint64
+	return func(ctx context.Context) (uint32, error) {
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return api.DecodeU32(stack[0]), nil
+	}, nil
+}
+
+// I32ToI32 returns a function calling `fn` of the signature (i32) -> (i32).
+func I32ToI32(fn api.Function) (func(ctx context.Context, x uint32) (uint32, error), error) {
+	if err := checkSignature(fn, []api.ValueType{i32}, []api.ValueType{i32}); err != nil {
+		return nil, err
+	}
+	var stack [1]uint64
+	return func(ctx context.Context, x uint32) (uint32, error) {
+		stack[0] = api.EncodeU32(x)
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return api.DecodeU32(stack[0]), nil
+	}, nil
+}
+
+// I32I32ToI32 returns a function calling `fn` of the signature (i32, i32) -> (i32).
+func I32I32ToI32(fn api.Function) (func(ctx context.Context, x, y uint32) (uint32, error), error) {
+	if err := checkSignature(fn, []api.ValueType{i32, i32}, []api.ValueType{i32}); err != nil {
+		return nil, err
+	}
+	var stack [2]uint64
+	return func(ctx context.Context, x, y uint32) (uint32, error) {
+		stack[0], stack[1] = api.EncodeU32(x), api.EncodeU32(y)
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return api.DecodeU32(stack[0]), nil
+	}, nil
+}
+
+// I64ToI64 returns a function calling `fn` of the signature (i64) -> (i64).
+func I64ToI64(fn api.Function) (func(ctx context.Context, x uint64) (uint64, error), error) {
+	if err := checkSignature(fn, []api.ValueType{i64}, []api.ValueType{i64}); err != nil {
+		return nil, err
+	}
+	var stack [1]uint64
+	return func(ctx context.Context, x uint64) (uint64, error) {
+		stack[0] = x
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return stack[0], nil
+	}, nil
+}
+
+// I64I64ToI64 returns a function calling `fn` of the signature (i64, i64) -> (i64).
+func I64I64ToI64(fn api.Function) (func(ctx context.Context, x, y uint64) (uint64, error), error) {
+	if err := checkSignature(fn, []api.ValueType{i64, i64}, []api.ValueType{i64}); err != nil {
+		return nil, err
+	}
+	var stack [2]uint64
+	return func(ctx context.Context, x, y uint64) (uint64, error) {
+		stack[0], stack[1] = x, y
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return stack[0], nil
+	}, nil
+}
+
+// F32F32ToF32 returns a function calling `fn` of the signature (f32, f32) -> (f32).
+func F32F32ToF32(fn api.Function) (func(ctx context.Context, x, y float32) (float32, error), error) {
+	if err := checkSignature(fn, []api.ValueType{f32, f32}, []api.ValueType{f32}); err != nil {
+		return nil, err
+	}
+	var stack [2]uint64
+	return func(ctx context.Context, x, y float32) (float32, error) {
+		stack[0], stack[1] = api.EncodeF32(x), api.EncodeF32(y)
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return api.DecodeF32(stack[0]), nil
+	}, nil
+}
+
+// F64F64ToF64 returns a function calling `fn` of the signature (f64, f64) -> (f64).
+func F64F64ToF64(fn api.Function) (func(ctx context.Context, x, y float64) (float64, error), error) {
+	if err := checkSignature(fn, []api.ValueType{f64, f64}, []api.ValueType{f64}); err != nil {
+		return nil, err
+	}
+	var stack [2]uint64
+	return func(ctx context.Context, x, y float64) (float64, error) {
+		stack[0], stack[1] = api.EncodeF64(x), api.EncodeF64(y)
+		if err := fn.CallWithStack(ctx, stack[:]); err != nil {
+			return 0, err
+		}
+		return api.DecodeF64(stack[0]), nil
+	}, nil
+}
+
+// checkSignature returns an error if `fn` doesn't have the given param and result types.
+func checkSignature(fn api.Function, params, results []api.ValueType) error {
+	def := fn.Definition()
+	if !equalTypes(def.ParamTypes(), params) || !equalTypes(def.ResultTypes(), results) {
+		expected := &wasm.FunctionType{Params: params, Results: results}
+		actual := &wasm.FunctionType{Params: def.ParamTypes(), Results: def.ResultTypes()}
+		return fmt.Errorf("function %s has signature %s, but expected %s", def.DebugName(), actual, expected)
+	}
+	return nil
+}
+
+func equalTypes(a, b []api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
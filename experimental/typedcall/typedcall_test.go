@@ -0,0 +1,201 @@
+package typedcall_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental/typedcall"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func TestTypedCall(t *testing.T) {
+	const i32, i64, f32, f64 = wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{},
+			{Params: []wasm.ValueType{i32}},
+			{Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}},
+			{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}},
+			{Params: []wasm.ValueType{f32, f32}, Results: []wasm.ValueType{f32}},
+			{Params: []wasm.ValueType{f64, f64}, Results: []wasm.ValueType{f64}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 2, 3, 4, 5, 6, 7, 8},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: i32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+		}},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeGlobalSet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Sub, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Add, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI64Sub, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF32Div, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF64Div, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "none_none", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "i32_none", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "none_i32", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "i32_i32", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "i32i32_i32", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "i64_i64", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "i64i64_i64", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "f32f32_f32", Type: wasm.ExternTypeFunc, Index: 7},
+			{Name: "f64f64_f64", Type: wasm.ExternTypeFunc, Index: 8},
+		},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			m, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+
+			noneNone, err := typedcall.NoneToNone(m.ExportedFunction("none_none"))
+			require.NoError(t, err)
+			require.NoError(t, noneNone(testCtx))
+
+			i32None, err := typedcall.I32ToNone(m.ExportedFunction("i32_none"))
+			require.NoError(t, err)
+			require.NoError(t, i32None(testCtx, 0xffffffff))
+
+			noneI32, err := typedcall.NoneToI32(m.ExportedFunction("none_i32"))
+			require.NoError(t, err)
+			v32, err := noneI32(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, uint32(0xffffffff), v32)
+
+			i32I32, err := typedcall.I32ToI32(m.ExportedFunction("i32_i32"))
+			require.NoError(t, err)
+			v32, err = i32I32(testCtx, 0xffffffff)
+			require.NoError(t, err)
+			require.Equal(t, uint32(0), v32)
+
+			i32i32I32, err := typedcall.I32I32ToI32(m.ExportedFunction("i32i32_i32"))
+			require.NoError(t, err)
+			v32, err = i32i32I32(testCtx, 1, 2)
+			require.NoError(t, err)
+			require.Equal(t, uint32(0xffffffff), v32)
+
+			i64I64, err := typedcall.I64ToI64(m.ExportedFunction("i64_i64"))
+			require.NoError(t, err)
+			v64, err := i64I64(testCtx, 41)
+			require.NoError(t, err)
+			require.Equal(t, uint64(42), v64)
+
+			i64i64I64, err := typedcall.I64I64ToI64(m.ExportedFunction("i64i64_i64"))
+			require.NoError(t, err)
+			v64, err = i64i64I64(testCtx, 50, 8)
+			require.NoError(t, err)
+			require.Equal(t, uint64(42), v64)
+
+			f32f32F32, err := typedcall.F32F32ToF32(m.ExportedFunction("f32f32_f32"))
+			require.NoError(t, err)
+			f, err := f32f32F32(testCtx, 1, 4)
+			require.NoError(t, err)
+			require.Equal(t, float32(0.25), f)
+
+			f64f64F64, err := typedcall.F64F64ToF64(m.ExportedFunction("f64f64_f64"))
+			require.NoError(t, err)
+			d, err := f64f64F64(testCtx, 1, 8)
+			require.NoError(t, err)
+			require.Equal(t, 0.125, d)
+
+			_, err = typedcall.I64ToI64(m.ExportedFunction("i32_i32"))
+			require.EqualError(t, err, "function .$3 has signature i32_i32, but expected i64_i64")
+
+			_, err = typedcall.NoneToNone(m.ExportedFunction("none_i32"))
+			require.EqualError(t, err, "function .$2 has signature v_i32, but expected v_v")
+		})
+	}
+}
+
+func TestCallContext(t *testing.T) {
+	const i32, i64 = wasm.ValueTypeI32, wasm.ValueTypeI64
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{},
+			{Params: []wasm.ValueType{i64, i32}, Results: []wasm.ValueType{i32, i64, i64}},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI64Const, 1, wasm.OpcodeI64Add,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "none_none", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "i64i32_i32i64i64", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+		{name: "default", config: wazero.NewRuntimeConfig()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := wazero.NewRuntimeWithConfig(testCtx, tc.config)
+			defer r.Close(testCtx)
+
+			m, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+
+			noneNone := typedcall.NewCallContext(m.ExportedFunction("none_none"))
+			require.Equal(t, 0, len(noneNone.Params()))
+			results, err := noneNone.CallEx(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, 0, len(results))
+
+			fn := m.ExportedFunction("i64i32_i32i64i64")
+			cc := typedcall.NewCallContext(fn)
+			require.Equal(t, fn, cc.Function())
+			for i := uint64(0); i < 3; i++ {
+				params := cc.Params()
+				require.Equal(t, 2, len(params))
+				params[0], params[1] = i, 10+i
+				results, err = cc.CallEx(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{10 + i, i, i + 1}, results)
+			}
+
+			// Neither engine allocates when calling via a CallContext.
+			allocs := testing.AllocsPerRun(100, func() {
+				params := cc.Params()
+				params[0], params[1] = 1, 2
+				if _, err := cc.CallEx(testCtx); err != nil {
+					t.Fatal(err)
+				}
+			})
+			require.Equal(t, 0.0, allocs)
+		})
+	}
+}
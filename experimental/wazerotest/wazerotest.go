@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/internalapi"
@@ -44,8 +45,10 @@ type Module struct {
 	once                        sync.Once
 	exportedFunctions           map[string]api.Function
 	exportedFunctionDefinitions map[string]api.FunctionDefinition
+	exportedFunctionNames       []string
 	exportedGlobals             map[string]api.Global
 	exportedMemoryDefinitions   map[string]api.MemoryDefinition
+	exportedMemoryNames         []string
 }
 
 // NewModule constructs a Module object with the given memory and function list.
@@ -84,6 +87,12 @@ func (m *Module) ExportedFunctionDefinitions() map[string]api.FunctionDefinition
 	return m.exportedFunctionDefinitions
 }
 
+// ExportedFunctionNames implements the same method as documented on api.Module.
+func (m *Module) ExportedFunctionNames() []string {
+	m.once.Do(m.initialize)
+	return m.exportedFunctionNames
+}
+
 // ExportedMemory implements the same method as documented on api.Module.
 func (m *Module) ExportedMemory(name string) api.Memory {
 	if m.ExportMemory != nil && name == "memory" {
@@ -99,6 +108,12 @@ func (m *Module) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
 	return m.exportedMemoryDefinitions
 }
 
+// ExportedMemoryNames implements the same method as documented on api.Module.
+func (m *Module) ExportedMemoryNames() []string {
+	m.once.Do(m.initialize)
+	return m.exportedMemoryNames
+}
+
 // ExportedGlobal implements the same method as documented on api.Module.
 func (m *Module) ExportedGlobal(name string) api.Global {
 	m.once.Do(m.initialize)
@@ -122,6 +137,17 @@ func (m *Module) IsClosed() bool {
 	return exited
 }
 
+// State implements the same method as documented on api.Module.
+//
+// Note: As functions of a test module are implemented in Go and do not trap,
+// this is never api.ModuleStatePoisoned.
+func (m *Module) State() api.ModuleState {
+	if m.IsClosed() {
+		return api.ModuleStateClosed
+	}
+	return api.ModuleStateHealthy
+}
+
 // NumGlobal implements the same method as documented on experimental.InternalModule.
 func (m *Module) NumGlobal() int {
 	return len(m.Globals)
@@ -159,6 +185,7 @@ func (m *Module) initialize() {
 		for _, exportName := range function.ExportNames {
 			m.exportedFunctions[exportName] = function
 			m.exportedFunctionDefinitions[exportName] = function.Definition()
+			m.exportedFunctionNames = append(m.exportedFunctionNames, exportName)
 		}
 		function.module = m
 		function.index = index
@@ -173,6 +200,7 @@ func (m *Module) initialize() {
 	if m.ExportMemory != nil {
 		m.ExportMemory.module = m
 		m.exportedMemoryDefinitions["memory"] = m.ExportMemory.Definition()
+		m.exportedMemoryNames = []string{"memory"}
 	}
 }
 
@@ -403,6 +431,29 @@ func (f *Function) CallWithStack(ctx context.Context, stack []uint64) error {
 	return nil
 }
 
+func (f *Function) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) ([]uint64, error) {
+	var timedOut atomic.Bool
+	if opts.Timeout > 0 {
+		timer := time.AfterFunc(opts.Timeout, func() {
+			if !f.module.IsClosed() {
+				timedOut.Store(true)
+				_ = f.module.CloseWithExitCode(ctx, sys.ExitCodeDeadlineExceeded)
+			}
+		})
+		defer timer.Stop()
+	}
+	results, err := f.Call(ctx, params...)
+	if timedOut.Load() {
+		if opts.OnTimeout != nil {
+			opts.OnTimeout()
+		}
+		if err == nil {
+			err = sys.NewExitError(sys.ExitCodeDeadlineExceeded)
+		}
+	}
+	return results, err
+}
+
 type functionDefinition struct {
 	internalapi.WazeroOnlyType
 	function *Function
@@ -664,6 +715,10 @@ func (def memoryDefinition) Max() (uint32, bool) {
 	return def.memory.Max, def.memory.Max != 0
 }
 
+func (def memoryDefinition) PageSize() uint32 {
+	return PageSize
+}
+
 var (
 	_ api.Module   = (*Module)(nil)
 	_ api.Function = (*Function)(nil)
@@ -1,6 +1,8 @@
 package wazero
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
@@ -131,6 +133,112 @@ type FSConfig interface {
 	//
 	// See sys.NewStat_t for examples.
 	WithFSMount(fs fs.FS, guestPath string) FSConfig
+
+	// WithTempDirMount assigns a new, empty host directory to any paths
+	// beginning at `guestPath`, for the temporary files of the guest, e.g.
+	// "/tmp".
+	//
+	// Each module instance gets its own directory, created in os.TempDir
+	// when it is instantiated and removed with its files when it is closed.
+	//
+	// When `maxBytes` is positive, writes that would grow the files in the
+	// directory past that many bytes fail with ENOSPC. Zero means no limit
+	// other than that of the host. This is the same as MountQuota.MaxBytes,
+	// which WithMountQuota can override along with other limits.
+	//
+	// If the same `guestPath` was assigned before, this overrides its value,
+	// retaining the original precedence. See the documentation of FSConfig for
+	// more details on `guestPath`.
+	WithTempDirMount(guestPath string, maxBytes int64) FSConfig
+
+	// WithSplice allows the guest to copy data between file descriptors
+	// without copying it through its memory, when enabled. Defaults to false.
+	//
+	// Guests access this capability with the "fd_splice" function of the
+	// experimental/splice host module. On Linux, copies between files use
+	// copy_file_range and copies from a file to a socket use sendfile.
+	//
+	// Note: This is experimental, and may change or be removed.
+	WithSplice(enabled bool) FSConfig
+
+	// WithIOUring makes files opened by the guest read and write with
+	// io_uring on Linux, when enabled. Defaults to false.
+	//
+	// Each module instance has its own io_uring instance. When the platform
+	// or kernel doesn't support io_uring, or it is disallowed, e.g. by
+	// seccomp, files use syscalls as usual.
+	//
+	// Note: This is experimental, and may change or be removed.
+	WithIOUring(enabled bool) FSConfig
+
+	// WithSync makes "fd_sync" and "fd_datasync" write files to storage with
+	// the host's fsync and fdatasync, when enabled. Defaults to true.
+	//
+	// Disabling this makes them succeed without writing, which speeds up
+	// tests of guests that sync often, such as databases. Don't disable it
+	// where data must survive a crash of the host.
+	//
+	// This also applies to the "fd_sync_range" function of the
+	// experimental/syncrange host module.
+	WithSync(enabled bool) FSConfig
+
+	// WithPreopenOrder assigns the first pre-opened file descriptors to the
+	// mounts at `guestPaths`, in the given order. Mounts not listed follow in
+	// the order they were added. Each call replaces any previous order.
+	//
+	// Pre-opens are numbered consecutively from file descriptor 3, as
+	// wasi-libc stops looking for them at the first missing one. For example,
+	// given mounts "/" then "/tmp", WithPreopenOrder("/tmp") assigns 3 to
+	// "/tmp" and 4 to "/". Some guests, such as Zig, use the first pre-open
+	// as their working directory.
+	//
+	// Instantiation fails if a `guestPath` isn't mounted or is repeated.
+	WithPreopenOrder(guestPaths ...string) FSConfig
+
+	// WithPreopenRights overrides the rights reported by `fd_fdstat_get` for
+	// the mount at `guestPath`, which otherwise are all rights applicable to
+	// a directory. `base` and `inheriting` are in the bit layout of `rights`
+	// in wasi_snapshot_preview1.
+	//
+	// Rights are informational: they don't restrict what the guest can do.
+	// This is for guests which inspect rights, for example before trying to
+	// open a file beneath a pre-open. wasi-libc no longer reads them.
+	//
+	// Instantiation fails if `guestPath` isn't mounted.
+	//
+	// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+	WithPreopenRights(guestPath string, base, inheriting uint64) FSConfig
+
+	// WithMountQuota limits what the guest can write to the mount at
+	// `guestPath`, to keep untrusted guests from filling host disks. Writes,
+	// truncation and file creation that would exceed the quota fail with
+	// ENOSPC.
+	//
+	// Usage is per module instance and starts at zero, so files that existed
+	// before instantiation don't count towards the quota. Space is released
+	// when files are truncated or deleted. Each call for the same `guestPath`
+	// replaces any previous quota.
+	//
+	// Instantiation fails if `guestPath` isn't mounted.
+	//
+	// Note: Files in a mount with a quota don't use io_uring (WithIOUring).
+	WithMountQuota(guestPath string, quota MountQuota) FSConfig
+}
+
+// MountQuota limits what the guest can write to a mount. Zero fields are
+// unlimited.
+//
+// See FSConfig.WithMountQuota
+type MountQuota struct {
+	// MaxBytes limits how many bytes regular files can grow, in total.
+	MaxBytes int64
+
+	// MaxFiles limits how many files, directories and symbolic links can be
+	// created.
+	MaxFiles int64
+
+	// MaxFileSize limits the size in bytes of each regular file written.
+	MaxFileSize int64
 }
 
 type fsConfig struct {
@@ -142,6 +250,36 @@ type fsConfig struct {
 	// guestPathToFS are the normalized paths to the currently configured
 	// filesystems, used for de-duplicating.
 	guestPathToFS map[string]int
+	// splice is true when the guest may copy between file descriptors
+	// without copying through its memory.
+	splice bool
+	// ioUring is true when files opened by the guest use io_uring.
+	ioUring bool
+	// noSync is true when syncing files does nothing. See WithSync.
+	noSync bool
+	// preopenOrder are the user-supplied guest paths of the filesystems to
+	// pre-open first. See WithPreopenOrder.
+	preopenOrder []string
+	// preopenRights are the rights of pre-opens, keyed by normalized guest
+	// path. See WithPreopenRights.
+	preopenRights map[string]preopenRights
+	// mountQuotas are the quotas of mounts, keyed by normalized guest path.
+	// See WithMountQuota.
+	mountQuotas map[string]mountQuota
+}
+
+// mountQuota is the quota configured for a guest path.
+type mountQuota struct {
+	// guestPath is the user-supplied path, retained for error messages.
+	guestPath string
+	quota     sysfs.Quota
+}
+
+// preopenRights are the rights configured for a guest path.
+type preopenRights struct {
+	// guestPath is the user-supplied path, retained for error messages.
+	guestPath string
+	rights    sys.Rights
 }
 
 // NewFSConfig returns a FSConfig that can be used for configuring module instantiation.
@@ -160,6 +298,18 @@ func (c *fsConfig) clone() *fsConfig {
 	for key, value := range c.guestPathToFS {
 		ret.guestPathToFS[key] = value
 	}
+	if c.preopenRights != nil {
+		ret.preopenRights = make(map[string]preopenRights, len(c.preopenRights))
+		for key, value := range c.preopenRights {
+			ret.preopenRights[key] = value
+		}
+	}
+	if c.mountQuotas != nil {
+		ret.mountQuotas = make(map[string]mountQuota, len(c.mountQuotas))
+		for key, value := range c.mountQuotas {
+			ret.mountQuotas[key] = value
+		}
+	}
 	return &ret
 }
 
@@ -177,11 +327,23 @@ func (c *fsConfig) WithReadOnlyDirMount(dir, guestPath string) FSConfig {
 func (c *fsConfig) WithFSMount(fs fs.FS, guestPath string) FSConfig {
 	var adapted experimentalsys.FS
 	if fs != nil {
-		adapted = &sysfs.AdaptFS{FS: fs}
+		adapted = &sysfs.AdaptFS{FS: fs, Dev: sysfs.SyntheticDev(sys.StripPrefixesAndTrailingSlash(guestPath))}
 	}
 	return c.WithSysFSMount(adapted, guestPath)
 }
 
+// WithTempDirMount implements FSConfig.WithTempDirMount
+func (c *fsConfig) WithTempDirMount(guestPath string, maxBytes int64) FSConfig {
+	return c.WithSysFSMount(&tempDirMount{maxBytes: maxBytes}, guestPath)
+}
+
+// tempDirMount is replaced by a new sysfs.TempFS for each module instance.
+// See WithTempDirMount.
+type tempDirMount struct {
+	experimentalsys.UnimplementedFS
+	maxBytes int64
+}
+
 // WithSysFSMount implements sysfs.FSConfig
 func (c *fsConfig) WithSysFSMount(fs experimentalsys.FS, guestPath string) FSConfig {
 	if _, ok := fs.(experimentalsys.UnimplementedFS); ok {
@@ -200,16 +362,150 @@ func (c *fsConfig) WithSysFSMount(fs experimentalsys.FS, guestPath string) FSCon
 	return ret
 }
 
+// WithSplice implements FSConfig.WithSplice
+func (c *fsConfig) WithSplice(enabled bool) FSConfig {
+	ret := c.clone()
+	ret.splice = enabled
+	return ret
+}
+
+// WithIOUring implements FSConfig.WithIOUring
+func (c *fsConfig) WithIOUring(enabled bool) FSConfig {
+	ret := c.clone()
+	ret.ioUring = enabled
+	return ret
+}
+
+// WithSync implements FSConfig.WithSync
+func (c *fsConfig) WithSync(enabled bool) FSConfig {
+	ret := c.clone()
+	ret.noSync = !enabled
+	return ret
+}
+
+// WithPreopenOrder implements FSConfig.WithPreopenOrder
+func (c *fsConfig) WithPreopenOrder(guestPaths ...string) FSConfig {
+	ret := c.clone()
+	ret.preopenOrder = append([]string(nil), guestPaths...)
+	return ret
+}
+
+// WithPreopenRights implements FSConfig.WithPreopenRights
+func (c *fsConfig) WithPreopenRights(guestPath string, base, inheriting uint64) FSConfig {
+	ret := c.clone()
+	if ret.preopenRights == nil {
+		ret.preopenRights = map[string]preopenRights{}
+	}
+	ret.preopenRights[sys.StripPrefixesAndTrailingSlash(guestPath)] = preopenRights{
+		guestPath: guestPath,
+		rights:    sys.Rights{Base: base, Inheriting: inheriting},
+	}
+	return ret
+}
+
+// WithMountQuota implements FSConfig.WithMountQuota
+func (c *fsConfig) WithMountQuota(guestPath string, quota MountQuota) FSConfig {
+	ret := c.clone()
+	if ret.mountQuotas == nil {
+		ret.mountQuotas = map[string]mountQuota{}
+	}
+	ret.mountQuotas[sys.StripPrefixesAndTrailingSlash(guestPath)] = mountQuota{
+		guestPath: guestPath,
+		quota:     sysfs.Quota(quota),
+	}
+	return ret
+}
+
 // preopens returns the possible nil index-correlated preopened filesystems
-// with guest paths.
-func (c *fsConfig) preopens() ([]experimentalsys.FS, []string) {
+// with guest paths, in the order of their file descriptors. `rights` is nil
+// unless WithPreopenRights was used, and otherwise has nil entries for
+// pre-opens with default rights.
+//
+// Note: This creates the directories of WithTempDirMount, so callers must
+// close any filesystem implementing io.Closer, when not handed to a module.
+func (c *fsConfig) preopens() (fs []experimentalsys.FS, guestPaths []string, rights []*sys.Rights, err error) {
+	for cleaned, r := range c.preopenRights {
+		if _, ok := c.guestPathToFS[cleaned]; !ok {
+			return nil, nil, nil, fmt.Errorf("preopen rights: %q is not mounted", r.guestPath)
+		}
+	}
+	for cleaned, q := range c.mountQuotas {
+		if _, ok := c.guestPathToFS[cleaned]; !ok {
+			return nil, nil, nil, fmt.Errorf("mount quota: %q is not mounted", q.guestPath)
+		}
+	}
+
+	// First, the mounts in the configured order, then the remaining ones.
 	preopenCount := len(c.fs)
+	order := make([]int, 0, preopenCount)
+	ordered := make([]bool, preopenCount)
+	for _, guestPath := range c.preopenOrder {
+		i, ok := c.guestPathToFS[sys.StripPrefixesAndTrailingSlash(guestPath)]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("preopen order: %q is not mounted", guestPath)
+		} else if ordered[i] {
+			return nil, nil, nil, fmt.Errorf("preopen order: %q is repeated", guestPath)
+		}
+		ordered[i] = true
+		order = append(order, i)
+	}
 	if preopenCount == 0 {
-		return nil, nil
+		return
+	}
+	for i := range c.fs {
+		if !ordered[i] {
+			order = append(order, i)
+		}
+	}
+
+	fs = make([]experimentalsys.FS, preopenCount)
+	guestPaths = make([]string, preopenCount)
+	for fd, i := range order {
+		cleaned := sys.StripPrefixesAndTrailingSlash(c.guestPaths[i])
+		if fs[fd], err = c.preopenFS(i, cleaned); err != nil {
+			closeFS(fs)
+			return nil, nil, nil, err
+		}
+		guestPaths[fd] = c.guestPaths[i]
+		if r, ok := c.preopenRights[cleaned]; ok {
+			if rights == nil {
+				rights = make([]*sys.Rights, preopenCount)
+			}
+			rights[fd] = &r.rights
+		}
+	}
+	return
+}
+
+// preopenFS returns the filesystem of the mount at index `i`, which is new
+// for WithTempDirMount, and enforces any quota.
+func (c *fsConfig) preopenFS(i int, cleaned string) (experimentalsys.FS, error) {
+	fs := c.fs[i]
+	var quota sysfs.Quota
+	if t, ok := fs.(*tempDirMount); ok {
+		tempFS, err := sysfs.NewTempFS()
+		if err != nil {
+			return nil, fmt.Errorf("temp dir mount %q: %w", c.guestPaths[i], err)
+		}
+		fs, quota.MaxBytes = tempFS, t.maxBytes
+	}
+	if q, ok := c.mountQuotas[cleaned]; ok {
+		if q.quota.MaxBytes == 0 {
+			q.quota.MaxBytes = quota.MaxBytes // inherit from WithTempDirMount
+		}
+		quota = q.quota
+	}
+	if quota != (sysfs.Quota{}) {
+		fs = sysfs.NewQuotaFS(fs, quota)
+	}
+	return fs, nil
+}
+
+// closeFS closes any filesystems returned by preopens which need it.
+func closeFS(fs []experimentalsys.FS) {
+	for _, f := range fs {
+		if closer, ok := f.(io.Closer); ok {
+			_ = closer.Close()
+		}
 	}
-	fs := make([]experimentalsys.FS, len(c.fs))
-	copy(fs, c.fs)
-	guestPaths := make([]string, len(c.guestPaths))
-	copy(guestPaths, c.guestPaths)
-	return fs, guestPaths
 }
@@ -1,12 +1,17 @@
 package wazero
 
 import (
+	"context"
+	"os"
 	"testing"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	testfs "github.com/tetratelabs/wazero/internal/testing/fs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
 // TestFSConfig only tests the cases that change the inputs to sysfs.ValidatePreopens.
@@ -21,6 +26,7 @@ func TestFSConfig(t *testing.T) {
 		input              FSConfig
 		expectedFS         []sys.FS
 		expectedGuestPaths []string
+		expectedRights     []*internalsys.Rights
 	}{
 		{
 			name:  "empty",
@@ -29,13 +35,13 @@ func TestFSConfig(t *testing.T) {
 		{
 			name:               "WithFSMount",
 			input:              base.WithFSMount(testFS, "/"),
-			expectedFS:         []sys.FS{&sysfs.AdaptFS{FS: testFS}},
+			expectedFS:         []sys.FS{&sysfs.AdaptFS{FS: testFS, Dev: sysfs.SyntheticDev("")}},
 			expectedGuestPaths: []string{"/"},
 		},
 		{
 			name:               "WithFSMount overwrites",
 			input:              base.WithFSMount(testFS, "/").WithFSMount(testFS2, "/"),
-			expectedFS:         []sys.FS{&sysfs.AdaptFS{FS: testFS2}},
+			expectedFS:         []sys.FS{&sysfs.AdaptFS{FS: testFS2, Dev: sysfs.SyntheticDev("")}},
 			expectedGuestPaths: []string{"/"},
 		},
 		{
@@ -54,15 +60,31 @@ func TestFSConfig(t *testing.T) {
 			expectedFS:         []sys.FS{&sysfs.ReadFS{FS: sysfs.DirFS(".")}, sysfs.DirFS("/tmp")},
 			expectedGuestPaths: []string{"/", "/tmp"},
 		},
+		{
+			name:               "WithPreopenOrder",
+			input:              base.WithReadOnlyDirMount(".", "/").WithDirMount("/tmp", "/tmp").WithPreopenOrder("tmp"),
+			expectedFS:         []sys.FS{sysfs.DirFS("/tmp"), &sysfs.ReadFS{FS: sysfs.DirFS(".")}},
+			expectedGuestPaths: []string{"/tmp", "/"},
+		},
+		{
+			name: "WithPreopenRights",
+			input: base.WithReadOnlyDirMount(".", "/").WithDirMount("/tmp", "/tmp").
+				WithPreopenRights("/tmp/", 1, 2).WithPreopenOrder("/tmp"),
+			expectedFS:         []sys.FS{sysfs.DirFS("/tmp"), &sysfs.ReadFS{FS: sysfs.DirFS(".")}},
+			expectedGuestPaths: []string{"/tmp", "/"},
+			expectedRights:     []*internalsys.Rights{{Base: 1, Inheriting: 2}, nil},
+		},
 	}
 
 	for _, tt := range tests {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			fs, guestPaths := tc.input.(*fsConfig).preopens()
+			fs, guestPaths, rights, err := tc.input.(*fsConfig).preopens()
+			require.NoError(t, err)
 			require.Equal(t, tc.expectedFS, fs)
 			require.Equal(t, tc.expectedGuestPaths, guestPaths)
+			require.Equal(t, tc.expectedRights, rights)
 		})
 	}
 }
@@ -83,4 +105,121 @@ func TestFSConfig_clone(t *testing.T) {
 
 	// Ensure the guestPaths slice is not shared
 	require.Zero(t, len(cloned.guestPaths))
+
+	// Ensure the preopenRights map is not shared
+	withRights := fc.WithPreopenRights("/", 1, 2).(*fsConfig)
+	require.Nil(t, fc.preopenRights)
+	withRights.clone().preopenRights["/"] = preopenRights{}
+	require.Equal(t, 1, len(withRights.preopenRights))
+	require.Equal(t, uint64(1), withRights.preopenRights[""].rights.Base)
+
+	// Ensure the mountQuotas map is not shared
+	withQuota := fc.WithMountQuota("/", MountQuota{MaxFiles: 1}).(*fsConfig)
+	require.Nil(t, fc.mountQuotas)
+	withQuota.clone().mountQuotas["/"] = mountQuota{}
+	require.Equal(t, 1, len(withQuota.mountQuotas))
+	require.Equal(t, int64(1), withQuota.mountQuotas[""].quota.MaxFiles)
+}
+
+func TestFSConfig_WithSplice(t *testing.T) {
+	base := NewFSConfig()
+	fc := base.WithSplice(true).(*fsConfig)
+	require.True(t, fc.splice)
+	require.False(t, base.(*fsConfig).splice) // the source wasn't modified
+	require.False(t, fc.WithSplice(false).(*fsConfig).splice)
+}
+
+func TestFSConfig_WithIOUring(t *testing.T) {
+	base := NewFSConfig()
+	fc := base.WithIOUring(true).(*fsConfig)
+	require.True(t, fc.ioUring)
+	require.False(t, base.(*fsConfig).ioUring) // the source wasn't modified
+	require.False(t, fc.WithIOUring(false).(*fsConfig).ioUring)
+}
+
+func TestFSConfig_WithTempDirMount(t *testing.T) {
+	ctx := context.Background()
+	r := NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	fc := NewFSConfig().WithTempDirMount("/tmp", 1024)
+	config := NewModuleConfig().WithFSConfig(fc)
+
+	// Each module instance has its own directory.
+	var dirs []string
+	for _, name := range []string{"a", "b"} {
+		mod, err := r.InstantiateWithConfig(ctx, binaryencoding.EncodeModule(&wasm.Module{}), config.WithName(name))
+		require.NoError(t, err)
+		defer mod.Close(ctx)
+
+		f, ok := mod.(*wasm.ModuleInstance).Sys.FS().LookupFile(internalsys.FdPreopen)
+		require.True(t, ok)
+		require.Equal(t, "/tmp", f.Name)
+		quotaFS, ok := f.FS.(*sysfs.QuotaFS)
+		require.True(t, ok)
+		dirs = append(dirs, quotaFS.String())
+	}
+	require.NotEqual(t, dirs[0], dirs[1])
+
+	// Closing the module removes its directory.
+	require.NoError(t, r.Module("a").Close(ctx))
+	_, err := os.Stat(dirs[0])
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(dirs[1])
+	require.NoError(t, err)
+}
+
+func TestFSConfig_WithMountQuota(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		input    FSConfig
+		maxBytes int64
+	}{
+		{
+			name:     "dir mount",
+			input:    NewFSConfig().WithDirMount(tmpDir, "/").WithMountQuota("/", MountQuota{MaxBytes: 1, MaxFiles: 2, MaxFileSize: 3}),
+			maxBytes: 1,
+		},
+		{
+			name:     "temp dir mount inherits MaxBytes",
+			input:    NewFSConfig().WithTempDirMount("/tmp", 10).WithMountQuota("/tmp/", MountQuota{MaxFiles: 2}),
+			maxBytes: 10,
+		},
+		{
+			name:     "temp dir mount overrides MaxBytes",
+			input:    NewFSConfig().WithTempDirMount("/tmp", 10).WithMountQuota("/tmp", MountQuota{MaxBytes: 20}),
+			maxBytes: 20,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			fs, _, _, err := tc.input.(*fsConfig).preopens()
+			require.NoError(t, err)
+			defer closeFS(fs)
+
+			require.Equal(t, 1, len(fs))
+			quotaFS, ok := fs[0].(*sysfs.QuotaFS)
+			require.True(t, ok)
+
+			// Prove the quota is enforced by writing just past MaxBytes.
+			f, errno := quotaFS.OpenFile("file", sys.O_RDWR|sys.O_CREAT, 0o600)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+			_, errno = f.Pwrite([]byte{1}, tc.maxBytes)
+			require.EqualErrno(t, sys.ENOSPC, errno)
+		})
+	}
+}
+
+func TestFSConfig_WithSync(t *testing.T) {
+	base := NewFSConfig()
+	require.False(t, base.(*fsConfig).noSync) // enabled by default
+	fc := base.WithSync(false).(*fsConfig)
+	require.True(t, fc.noSync)
+	require.False(t, base.(*fsConfig).noSync) // the source wasn't modified
+	require.False(t, fc.WithSync(true).(*fsConfig).noSync)
 }
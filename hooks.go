@@ -0,0 +1,95 @@
+package wazero
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// BeforeInstantiateHook is called by Runtime.InstantiateModule before the
+// module is instantiated. It returns the context and configuration to
+// instantiate with, which are passed to the next hook, or an error to fail
+// instantiation.
+//
+// Here's an example that names each instance after the compiled module:
+//
+//	r.OnBeforeInstantiate(func(ctx context.Context, compiled wazero.CompiledModule, config wazero.ModuleConfig) (context.Context, wazero.ModuleConfig, error) {
+//		return ctx, config.WithName(compiled.Name() + "-" + nextID()), nil
+//	})
+//
+// See Runtime.OnBeforeInstantiate
+type BeforeInstantiateHook func(ctx context.Context, compiled CompiledModule, config ModuleConfig) (context.Context, ModuleConfig, error)
+
+// AfterInstantiateHook is called by Runtime.InstantiateModule with the module
+// after its start functions and initializers succeeded. An error closes the
+// module and fails instantiation.
+//
+// Here's an example that seeds memory of each guest:
+//
+//	r.OnAfterInstantiate(func(ctx context.Context, mod api.Module) error {
+//		if mem := mod.Memory(); mem != nil && !mem.Write(0, seed) {
+//			return errors.New("memory too small for seed")
+//		}
+//		return nil
+//	})
+//
+// See Runtime.OnAfterInstantiate
+type AfterInstantiateHook func(ctx context.Context, mod api.Module) error
+
+// instantiateHooks are the hooks registered on a runtime.
+type instantiateHooks struct {
+	mux    sync.RWMutex
+	before []BeforeInstantiateHook
+	after  []AfterInstantiateHook
+}
+
+// OnBeforeInstantiate implements Runtime.OnBeforeInstantiate.
+func (r *runtime) OnBeforeInstantiate(hook BeforeInstantiateHook) {
+	r.hooks.mux.Lock()
+	defer r.hooks.mux.Unlock()
+	r.hooks.before = append(r.hooks.before, hook)
+}
+
+// OnAfterInstantiate implements Runtime.OnAfterInstantiate.
+func (r *runtime) OnAfterInstantiate(hook AfterInstantiateHook) {
+	r.hooks.mux.Lock()
+	defer r.hooks.mux.Unlock()
+	r.hooks.after = append(r.hooks.after, hook)
+}
+
+// snapshot returns the hooks registered so far, so that hooks registered
+// during an instantiation don't apply to it.
+func (h *instantiateHooks) snapshot() (before []BeforeInstantiateHook, after []AfterInstantiateHook) {
+	h.mux.RLock()
+	defer h.mux.RUnlock()
+	return h.before[:len(h.before):len(h.before)], h.after[:len(h.after):len(h.after)]
+}
+
+// callBeforeInstantiate calls each hook in registration order, failing at
+// first error.
+func callBeforeInstantiate(ctx context.Context, compiled CompiledModule, config ModuleConfig, hooks []BeforeInstantiateHook) (context.Context, ModuleConfig, error) {
+	for i, hook := range hooks {
+		var err error
+		if ctx, config, err = hook(ctx, compiled, config); err != nil {
+			return nil, nil, fmt.Errorf("before instantiate hook[%d] failed: %w", i, err)
+		}
+	}
+	return ctx, config, nil
+}
+
+// callAfterInstantiate calls each hook in registration order, failing at
+// first error.
+func callAfterInstantiate(ctx context.Context, mod api.Module, hooks []AfterInstantiateHook) error {
+	for i, hook := range hooks {
+		if err := hook(ctx, mod); err != nil {
+			if se, ok := err.(*sys.ExitError); ok {
+				return se // Don't wrap an exit error
+			}
+			return fmt.Errorf("module[%s] after instantiate hook[%d] failed: %w", mod.Name(), i, err)
+		}
+	}
+	return nil
+}
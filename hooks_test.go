@@ -0,0 +1,147 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+type hookKey struct{}
+
+func TestRuntime_OnInstantiate(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	var calls []string
+	r.OnBeforeInstantiate(func(ctx context.Context, compiled CompiledModule, config ModuleConfig) (context.Context, ModuleConfig, error) {
+		calls = append(calls, "before1 "+compiled.Name())
+		return context.WithValue(ctx, hookKey{}, "value"), config.WithName("renamed"), nil
+	})
+	r.OnBeforeInstantiate(func(ctx context.Context, compiled CompiledModule, config ModuleConfig) (context.Context, ModuleConfig, error) {
+		calls = append(calls, "before2 "+config.(*moduleConfig).name)
+		return ctx, config, nil
+	})
+	r.OnAfterInstantiate(func(ctx context.Context, mod api.Module) error {
+		calls = append(calls, "after "+mod.Name()+" "+ctx.Value(hookKey{}).(string))
+		return nil
+	})
+
+	mod, err := r.Instantiate(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", mod.Name())
+	require.Equal(t, []string{"before1 0", "before2 renamed", "after renamed value"}, calls)
+}
+
+func TestRuntime_OnInstantiate_internalModules(t *testing.T) {
+	globals := experimental.ImportedGlobalsFunc(func(string, string, api.ValueType) (uint64, bool) {
+		return 1, true
+	})
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		binary []byte
+		config ModuleConfig
+	}{
+		{
+			name:   "optional imports",
+			ctx:    testCtx,
+			binary: optionalImports,
+			config: NewModuleConfig().WithName("a").WithOptionalImports(OptionalImportZero, "env.*"),
+		},
+		{
+			name:   "deferred imports",
+			ctx:    testCtx,
+			binary: linkA,
+			config: NewModuleConfig().WithName("a").WithDeferredImports("b"),
+		},
+		{
+			name:   "imported globals",
+			ctx:    experimental.WithImportedGlobals(testCtx, globals),
+			binary: importedGlobals,
+			config: NewModuleConfig().WithName("a"),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			var calls []string
+			r.OnBeforeInstantiate(func(ctx context.Context, _ CompiledModule, config ModuleConfig) (context.Context, ModuleConfig, error) {
+				calls = append(calls, "before "+config.(*moduleConfig).name)
+				return ctx, config, nil
+			})
+			r.OnAfterInstantiate(func(_ context.Context, mod api.Module) error {
+				calls = append(calls, "after "+mod.Name())
+				return nil
+			})
+
+			compiled, err := r.CompileModule(testCtx, tc.binary)
+			require.NoError(t, err)
+			_, err = r.InstantiateModule(tc.ctx, compiled, tc.config)
+			require.NoError(t, err)
+
+			// The hooks aren't called for the stubs or proxies of imports.
+			require.Equal(t, []string{"before a", "after a"}, calls)
+		})
+	}
+}
+
+func TestRuntime_OnInstantiate_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      BeforeInstantiateHook
+		after       AfterInstantiateHook
+		expectedErr string
+	}{
+		{
+			name: "before",
+			before: func(ctx context.Context, _ CompiledModule, config ModuleConfig) (context.Context, ModuleConfig, error) {
+				return ctx, config, errors.New("boom")
+			},
+			expectedErr: "before instantiate hook[0] failed: boom",
+		},
+		{
+			name: "after",
+			after: func(context.Context, api.Module) error {
+				return errors.New("boom")
+			},
+			expectedErr: "module[0] after instantiate hook[0] failed: boom",
+		},
+		{
+			name: "after exit",
+			after: func(context.Context, api.Module) error {
+				return sys.NewExitError(2)
+			},
+			expectedErr: "module closed with exit_code(2)",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			if tc.before != nil {
+				r.OnBeforeInstantiate(tc.before)
+			}
+			if tc.after != nil {
+				r.OnAfterInstantiate(tc.after)
+			}
+
+			_, err := r.Instantiate(testCtx, binaryNamedZero)
+			require.EqualError(t, err, tc.expectedErr)
+
+			// The module isn't left in the runtime, so it can be instantiated again.
+			require.Nil(t, r.Module("0"))
+		})
+	}
+}
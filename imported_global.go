@@ -0,0 +1,118 @@
+package wazero
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// instantiateImportedGlobals instantiates a module of globals for each module
+// name which `module` imports immutable globals from, which are missing and
+// provided by `provider`. Like stubs of optional imports, the modules are
+// anonymous, added to `fallbacks` and appended to `proxies` to be closed with
+// the module.
+func (r *runtime) instantiateImportedGlobals(
+	ctx context.Context,
+	module *wasm.Module,
+	provider experimentalapi.ImportedGlobals,
+	fallbacks map[string]*wasm.ModuleInstance,
+	proxies *closers,
+) (map[string]*wasm.ModuleInstance, error) {
+	for moduleName, imports := range module.ImportPerModule {
+		if _, ok := fallbacks[moduleName]; ok {
+			continue // Already stubbed by optional imports.
+		}
+		imported := r.Module(moduleName)
+
+		var m *wasm.Module
+		var values []uint64
+		for _, i := range imports {
+			if i.Type != wasm.ExternTypeGlobal || i.DescGlobal.Mutable {
+				continue
+			} else if imported != nil && imported.ExportedGlobal(i.Name) != nil {
+				continue
+			}
+			valType := i.DescGlobal.ValType
+			init, ok := zeroConstantExpression(valType)
+			if !ok {
+				continue
+			}
+			value, ok := provider.ImportedGlobal(moduleName, i.Name, valType)
+			if !ok {
+				continue
+			}
+			if m == nil {
+				m = &wasm.Module{Exports: map[string]*wasm.Export{}}
+			}
+			m.GlobalSection = append(m.GlobalSection, wasm.Global{Type: i.DescGlobal, Init: init})
+			m.ExportSection = append(m.ExportSection, wasm.Export{
+				Type: wasm.ExternTypeGlobal, Name: i.Name, Index: wasm.Index(len(values)),
+			})
+			values = append(values, value)
+		}
+		if m == nil {
+			continue
+		}
+
+		mod, err := r.instantiateGlobals(ctx, m, values)
+		if err != nil {
+			return nil, err
+		}
+		*proxies = append(*proxies, detachedModule{mod})
+		if fallbacks == nil {
+			fallbacks = map[string]*wasm.ModuleInstance{}
+		}
+		fallbacks[moduleName] = mod
+	}
+	return fallbacks, nil
+}
+
+// instantiateGlobals instantiates the module `m`, which only exports its
+// globals, without a name, then sets the globals to `values`.
+func (r *runtime) instantiateGlobals(ctx context.Context, m *wasm.Module, values []uint64) (*wasm.ModuleInstance, error) {
+	for i := range m.ExportSection {
+		exp := &m.ExportSection[i]
+		m.Exports[exp.Name] = exp
+	}
+	// Like host modules, use the address as the ID, as the module isn't
+	// decoded from a binary.
+	m.AssignModuleID([]byte(fmt.Sprintf("@@@@@@@@%p", m)), false, false)
+	if err := m.Validate(r.enabledFeatures); err != nil {
+		return nil, err
+	}
+	if err := r.store.Engine.CompileModule(ctx, m, nil, false); err != nil {
+		return nil, err
+	}
+
+	compiled := &compiledModule{module: m, compiledEngine: r.store.Engine, closeWithModule: true}
+	mod, err := r.instantiateModule(ctx, compiled, NewModuleConfig().WithName(""), false)
+	if err != nil {
+		return nil, err
+	}
+	inst := mod.(*wasm.ModuleInstance)
+	for i, v := range values {
+		inst.Globals[i].Val = v
+	}
+	return inst, nil
+}
+
+// zeroConstantExpression returns the constant expression of the zero value
+// of `valType`, or false if globals of the type can't be provided.
+func zeroConstantExpression(valType api.ValueType) (wasm.ConstantExpression, bool) {
+	switch valType {
+	case api.ValueTypeI32:
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}}, true
+	case api.ValueTypeI64:
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeI64Const, Data: []byte{0}}, true
+	case api.ValueTypeF32:
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeF32Const, Data: make([]byte, 4)}, true
+	case api.ValueTypeF64:
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeF64Const, Data: make([]byte, 8)}, true
+	case api.ValueTypeExternref:
+		return wasm.ConstantExpression{Opcode: wasm.OpcodeRefNull, Data: []byte{wasm.RefTypeExternref}}, true
+	}
+	return wasm.ConstantExpression{}, false
+}
@@ -0,0 +1,132 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// importedGlobals imports the immutable globals "host"."version" and
+// "host"."ref", and exports functions which return them.
+var importedGlobals = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Results: []wasm.ValueType{wasm.ValueTypeExternref}},
+	},
+	ImportSection: []wasm.Import{
+		{Module: "host", Name: "version", Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeI32}},
+		{Module: "host", Name: "ref", Type: wasm.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: wasm.ValueTypeExternref}},
+	},
+	FunctionSection: []wasm.Index{0, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeGlobalGet, 1, wasm.OpcodeEnd}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "version", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "ref", Type: wasm.ExternTypeFunc, Index: 1},
+	},
+})
+
+func TestWithImportedGlobals(t *testing.T) {
+	provideAll := experimental.ImportedGlobalsFunc(func(moduleName, name string, valueType api.ValueType) (uint64, bool) {
+		if moduleName != "host" {
+			return 0, false
+		}
+		switch name {
+		case "version":
+			return 3, valueType == api.ValueTypeI32
+		case "ref":
+			return 0xcafe, valueType == api.ValueTypeExternref
+		}
+		return 0, false
+	})
+
+	tests := []struct {
+		name            string
+		provider        experimental.ImportedGlobals
+		expectedErr     string
+		expectedVersion uint64
+	}{
+		{
+			name:            "provided",
+			provider:        provideAll,
+			expectedVersion: 3,
+		},
+		{
+			name:        "not provided",
+			expectedErr: `module[host] not instantiated`,
+		},
+		{
+			name: "partially provided",
+			provider: experimental.ImportedGlobalsFunc(func(moduleName, name string, valueType api.ValueType) (uint64, bool) {
+				if name == "ref" {
+					return 0, false
+				}
+				return provideAll(moduleName, name, valueType)
+			}),
+			expectedErr: `module[host] not instantiated`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			ctx := experimental.WithImportedGlobals(testCtx, tc.provider)
+			mod, err := r.InstantiateModule(ctx, mustCompile(t, r, importedGlobals), NewModuleConfig())
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			results, err := mod.ExportedFunction("version").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{tc.expectedVersion}, results)
+
+			results, err = mod.ExportedFunction("ref").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{0xcafe}, results)
+
+			// The globals don't take the name of the module they stand in for.
+			require.Nil(t, r.Module("host"))
+			require.NoError(t, mod.Close(testCtx))
+		})
+	}
+}
+
+func TestWithImportedGlobals_instantiated(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	// A module which exports the globals takes precedence.
+	_, err := r.InstantiateWithConfig(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		GlobalSection: []wasm.Global{
+			{Type: wasm.GlobalType{ValType: wasm.ValueTypeI32}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{4}}},
+			{Type: wasm.GlobalType{ValType: wasm.ValueTypeExternref}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeRefNull, Data: []byte{wasm.RefTypeExternref}}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "version", Type: wasm.ExternTypeGlobal, Index: 0},
+			{Name: "ref", Type: wasm.ExternTypeGlobal, Index: 1},
+		},
+	}), NewModuleConfig().WithName("host"))
+	require.NoError(t, err)
+
+	ctx := experimental.WithImportedGlobals(testCtx, experimental.ImportedGlobalsFunc(func(string, string, api.ValueType) (uint64, bool) {
+		return 3, true
+	}))
+	mod, err := r.InstantiateModule(ctx, mustCompile(t, r, importedGlobals), NewModuleConfig())
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("version").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4}, results)
+}
@@ -10,6 +10,38 @@
 //
 // See wasi_snapshot_preview1.Instantiate and
 // https://github.com/emscripten-core/emscripten/wiki/WebAssembly-Standalone
+//
+// # Memory-mapped files
+//
+// NewFunctionExporterForModule defines `_mmap_js`, `_munmap_js` and
+// `_msync_js` when imported, which emulate `mmap` of files opened with WASI.
+// The guest must export "emscripten_builtin_memalign", used to allocate the
+// mapping. The file is read into it eagerly, and changes to a `MAP_SHARED`
+// mapping with `PROT_WRITE` are written back on `msync` or `munmap`.
+//
+// # File locking
+//
+// NewFunctionExporterForModule defines `__syscall_flock` when imported,
+// which places an advisory lock on the host file, using `flock`, or
+// `LockFileEx` on Windows. This allows guests such as SQLite, built with
+// flock-based locking, to safely share a mounted file between module
+// instances or processes. Files which can't be locked, such as those in an
+// fs.FS, succeed without locking, like the default in Emscripten.
+//
+// # Terminal
+//
+// NewFunctionExporterForModule defines `__syscall_ioctl` when imported,
+// which gets and sets the window size and termios attributes of stdio
+// configured with wazero.ModuleConfig WithTerminal. Other files return
+// ENOTTY, so `isatty` is false for them, like the default in Emscripten.
+//
+// # Processes
+//
+// NewFunctionExporterForModule defines `system` when imported, which runs the
+// command with the spawn.Spawner of the context.Context of the call, set with
+// experimental/spawn WithSpawner, as "sh -c command". The child inherits the
+// stdio of the caller. Without a spawn.Spawner, it fails like the default in
+// Emscripten.
 package emscripten
 
 import (
@@ -108,6 +140,26 @@ func NewFunctionExporterForModule(guest wazero.CompiledModule) (FunctionExporter
 			ret = append(ret, internal.ThrowLongjmp)
 			continue
 		}
+		switch importName {
+		case internal.FunctionMmapJs:
+			ret = append(ret, internal.NewMmapJs(fn.ParamTypes()))
+			continue
+		case internal.FunctionMunmapJs:
+			ret = append(ret, internal.NewMunmapJs(fn.ParamTypes()))
+			continue
+		case internal.FunctionMsyncJs:
+			ret = append(ret, internal.NewMsyncJs(fn.ParamTypes()))
+			continue
+		case internal.FunctionFlock:
+			ret = append(ret, internal.Flock)
+			continue
+		case internal.FunctionIoctl:
+			ret = append(ret, internal.Ioctl)
+			continue
+		case internal.FunctionSystem:
+			ret = append(ret, internal.System)
+			continue
+		}
 		if !strings.HasPrefix(importName, internal.InvokePrefix) {
 			continue // not invoke, and maybe not emscripten
 		}
@@ -4,16 +4,22 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/experimental/spawn"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	internal "github.com/tetratelabs/wazero/internal/emscripten"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -560,3 +566,377 @@ func TestInstantiateForModule(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFunctionExporterForModule_mmap(t *testing.T) {
+	mmapType := wasm.FunctionType{
+		Params:  []wasm.ValueType{i32, i32, i32, i32, i64, i32, i32},
+		Results: []wasm.ValueType{i32},
+	}
+	msyncType := wasm.FunctionType{
+		Params:  []wasm.ValueType{i32, i32, i32, i32, i32, i64},
+		Results: []wasm.ValueType{i32},
+	}
+	// forward returns the body of a function calling `funcIdx` with all of
+	// its `n` parameters, as imports can't be called directly.
+	forward := func(funcIdx byte, n byte) []byte {
+		var body []byte
+		for i := byte(0); i < n; i++ {
+			body = append(body, wasm.OpcodeLocalGet, i)
+		}
+		return append(body, wasm.OpcodeCall, funcIdx, wasm.OpcodeEnd)
+	}
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			mmapType,
+			msyncType,
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: internal.FunctionMmapJs, Type: wasm.ExternTypeFunc, DescFunc: 0},
+			{Module: "env", Name: internal.FunctionMsyncJs, Type: wasm.ExternTypeFunc, DescFunc: 1},
+			{Module: "env", Name: internal.FunctionMunmapJs, Type: wasm.ExternTypeFunc, DescFunc: 1},
+		},
+		FunctionSection: []wasm.Index{2, 0, 1, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 0x80, 0x80, 0x04, wasm.OpcodeEnd}}, // 65536
+			{Body: forward(0, 7)},
+			{Body: forward(1, 6)},
+			{Body: forward(2, 6)},
+		},
+		MemorySection: &wasm.Memory{Min: 2, Max: 2, IsMaxEncoded: true},
+		ExportSection: []wasm.Export{
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+			{Name: "emscripten_builtin_memalign", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "mmap", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "msync", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "munmap", Type: wasm.ExternTypeFunc, Index: 6},
+		},
+	})
+
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "data")
+	require.NoError(t, os.WriteFile(dataPath, []byte("wazero"), 0o600))
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	_, err = InstantiateForModule(testCtx, r, compiled)
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/")))
+	require.NoError(t, err)
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	fd, errno := fsc.OpenFile(fsc.RootFS(), "data", experimentalsys.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+
+	const (
+		protReadWrite = 0x3
+		mapShared     = 0x1
+		mapPrivate    = 0x2
+		ptr           = 65536
+	)
+	mem := mod.Memory()
+
+	t.Run("EBADF", func(t *testing.T) {
+		results, err := mod.ExportedFunction("mmap").Call(testCtx, 8, protReadWrite, mapShared, 42, 0, 0, 4)
+		require.NoError(t, err)
+		require.Equal(t, -int32(wasip1.ErrnoBadf), int32(results[0]))
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		results, err := mod.ExportedFunction("mmap").Call(testCtx, 8, protReadWrite, mapShared, uint64(fd), 0, 0, 4)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		allocated, _ := mem.ReadUint32Le(0)
+		require.Equal(t, uint32(1), allocated)
+		addr, _ := mem.ReadUint32Le(4)
+		require.Equal(t, uint32(ptr), addr)
+		// The file is read, and the bytes after its end are zero.
+		buf, _ := mem.Read(ptr, 8)
+		require.Equal(t, []byte("wazero\x00\x00"), buf)
+
+		buf[0] = 'W'
+		results, err = mod.ExportedFunction("msync").Call(testCtx, ptr, 6, protReadWrite, mapShared, uint64(fd), 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		b, err := os.ReadFile(dataPath)
+		require.NoError(t, err)
+		require.Equal(t, "Wazero", string(b))
+	})
+
+	t.Run("private", func(t *testing.T) {
+		results, err := mod.ExportedFunction("mmap").Call(testCtx, 6, protReadWrite, mapPrivate, uint64(fd), 0, 0, 4)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		buf, _ := mem.Read(ptr, 6)
+		require.Equal(t, "Wazero", string(buf))
+
+		// Changes to a private mapping aren't written back.
+		buf[0] = 'w'
+		results, err = mod.ExportedFunction("munmap").Call(testCtx, ptr, 6, protReadWrite, mapPrivate, uint64(fd), 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		b, err := os.ReadFile(dataPath)
+		require.NoError(t, err)
+		require.Equal(t, "Wazero", string(b))
+	})
+
+	t.Run("past end of file", func(t *testing.T) {
+		results, err := mod.ExportedFunction("mmap").Call(testCtx, 8, protReadWrite, mapShared, uint64(fd), 0, 0, 4)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		// Writing back the whole mapping doesn't grow the file, as only the
+		// bytes which were in the file when mapped are written.
+		buf, _ := mem.Read(ptr, 8)
+		copy(buf, "WAZERO!!")
+		results, err = mod.ExportedFunction("munmap").Call(testCtx, ptr, 8, protReadWrite, mapShared, uint64(fd), 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), results[0])
+
+		b, err := os.ReadFile(dataPath)
+		require.NoError(t, err)
+		require.Equal(t, "WAZERO", string(b))
+	})
+}
+
+func TestNewFunctionExporterForModule_flock(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "windows": // supported
+	default:
+		t.Skip("file locks are not supported on " + runtime.GOOS)
+	}
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: internal.FunctionFlock, Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "flock", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db"), nil, 0o600))
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	_, err = InstantiateForModule(testCtx, r, compiled)
+	require.NoError(t, err)
+
+	// Each module instance opens the same file, like two connections to a
+	// database.
+	fsConfig := wazero.NewFSConfig().WithDirMount(dir, "/")
+	instantiate := func(name string) (api.Function, int32) {
+		mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().
+			WithName(name).WithFSConfig(fsConfig))
+		require.NoError(t, err)
+
+		fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+		fd, errno := fsc.OpenFile(fsc.RootFS(), "db", experimentalsys.O_RDWR, 0)
+		require.EqualErrno(t, 0, errno)
+		return mod.ExportedFunction("flock"), fd
+	}
+	flock1, fd1 := instantiate("1")
+	flock2, fd2 := instantiate("2")
+
+	const (
+		lockSh = 0x1
+		lockEx = 0x2
+		lockNb = 0x4
+		lockUn = 0x8
+	)
+	requireFlock := func(flock api.Function, fd int32, how uint64, expected wasip1.Errno) {
+		results, err := flock.Call(testCtx, uint64(fd), how)
+		require.NoError(t, err)
+		require.Equal(t, -int32(expected), int32(results[0]))
+	}
+
+	requireFlock(flock1, 42, lockEx, wasip1.ErrnoBadf)
+	requireFlock(flock1, fd1, 0, wasip1.ErrnoInval)
+
+	requireFlock(flock1, fd1, lockEx, 0)
+	requireFlock(flock2, fd2, lockSh|lockNb, wasip1.ErrnoAgain)
+	requireFlock(flock1, fd1, lockUn, 0)
+	requireFlock(flock2, fd2, lockSh|lockNb, 0)
+	requireFlock(flock1, fd1, lockSh|lockNb, 0)
+	requireFlock(flock1, fd1, lockEx|lockNb, wasip1.ErrnoAgain)
+}
+
+func TestNewFunctionExporterForModule_ioctl(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32, i32, i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: internal.FunctionIoctl, Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{
+			{Name: "ioctl", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		},
+	})
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	_, err = InstantiateForModule(testCtx, r, compiled)
+	require.NoError(t, err)
+
+	const (
+		tcgets     = 0x5401
+		tcsets     = 0x5402
+		tiocgwinsz = 0x5413
+		tiocswinsz = 0x5414
+
+		varargs = 8  // where the pointer to the argument is stored
+		argp    = 16 // where the argument is
+	)
+
+	instantiate := func(config wazero.ModuleConfig) (func(fd int32, op uint32) int32, api.Memory) {
+		mod, err := r.InstantiateModule(testCtx, compiled, config)
+		require.NoError(t, err)
+
+		mem := mod.Memory()
+		require.True(t, mem.WriteUint32Le(varargs, argp))
+		ioctl := mod.ExportedFunction("ioctl")
+		return func(fd int32, op uint32) int32 {
+			results, err := ioctl.Call(testCtx, uint64(fd), uint64(op), varargs)
+			require.NoError(t, err)
+			return int32(results[0])
+		}, mem
+	}
+
+	t.Run("not a terminal", func(t *testing.T) {
+		ioctl, _ := instantiate(wazero.NewModuleConfig().WithName("notty"))
+
+		require.Equal(t, -int32(wasip1.ErrnoNotty), ioctl(0, tiocgwinsz))
+		require.Equal(t, -int32(wasip1.ErrnoBadf), ioctl(42, tiocgwinsz))
+	})
+
+	t.Run("terminal", func(t *testing.T) {
+		ioctl, mem := instantiate(wazero.NewModuleConfig().WithName("tty").WithTerminal(24, 80))
+
+		// Window size
+		require.Equal(t, int32(0), ioctl(1, tiocgwinsz))
+		winsize, ok := mem.Read(argp, 4)
+		require.True(t, ok)
+		require.Equal(t, []byte{24, 0, 80, 0}, winsize)
+
+		copy(winsize, []byte{50, 0, 132, 0})
+		require.Equal(t, int32(0), ioctl(1, tiocswinsz))
+		copy(winsize, make([]byte, 4))
+		require.Equal(t, int32(0), ioctl(2, tiocgwinsz)) // shared by stdio
+		require.Equal(t, []byte{50, 0, 132, 0}, winsize)
+
+		// Termios: clear ICANON and ECHO, like cfmakeraw.
+		const icanon, echo = 0x2, 0x8
+		require.Equal(t, int32(0), ioctl(0, tcgets))
+		lflag, ok := mem.ReadUint32Le(argp + 12)
+		require.True(t, ok)
+		require.Equal(t, uint32(icanon|echo), lflag&(icanon|echo))
+
+		require.True(t, mem.WriteUint32Le(argp+12, lflag&^(icanon|echo)))
+		require.Equal(t, int32(0), ioctl(0, tcsets))
+		require.True(t, mem.WriteUint32Le(argp+12, 0))
+		require.Equal(t, int32(0), ioctl(0, tcgets))
+		lflag, ok = mem.ReadUint32Le(argp + 12)
+		require.True(t, ok)
+		require.Equal(t, uint32(0), lflag&(icanon|echo))
+
+		require.Equal(t, -int32(wasip1.ErrnoInval), ioctl(0, 0x541b))
+	})
+}
+
+func TestNewFunctionExporterForModule_system(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+		},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: internal.FunctionSystem, Type: wasm.ExternTypeFunc, DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{
+			{Name: "system", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		},
+	})
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	_, err = InstantiateForModule(testCtx, r, compiled)
+	require.NoError(t, err)
+
+	var stdout bytes.Buffer
+	mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithStdout(&stdout))
+	require.NoError(t, err)
+
+	const command = 16
+	require.True(t, mod.Memory().WriteString(command, "echo hello\x00"))
+
+	var requests []spawn.Request
+	spawner := func(_ context.Context, req spawn.Request) (func() uint32, error) {
+		requests = append(requests, req)
+		_, _ = req.Stdout.Write([]byte(req.Args[2] + "\n"))
+		return func() uint32 { return 3 }, nil
+	}
+
+	system := func(ctx context.Context, command uint32) int32 {
+		results, err := mod.ExportedFunction("system").Call(ctx, uint64(command))
+		require.NoError(t, err)
+		return int32(results[0])
+	}
+
+	t.Run("no spawner", func(t *testing.T) {
+		require.Equal(t, int32(0), system(testCtx, 0))
+		require.Equal(t, int32(-1), system(testCtx, command))
+	})
+
+	t.Run("spawner", func(t *testing.T) {
+		ctx := spawn.WithSpawner(testCtx, spawner)
+		require.Equal(t, int32(1), system(ctx, 0))
+		require.Equal(t, int32(3<<8), system(ctx, command))
+		require.Equal(t, 1, len(requests))
+		require.Equal(t, "sh", requests[0].Path)
+		require.Equal(t, []string{"sh", "-c", "echo hello"}, requests[0].Args)
+		require.Equal(t, "echo hello\n", stdout.String())
+	})
+
+	t.Run("spawn fails", func(t *testing.T) {
+		ctx := spawn.WithSpawner(testCtx, func(context.Context, spawn.Request) (func() uint32, error) {
+			return nil, experimentalsys.ENOENT
+		})
+		require.Equal(t, int32(-1), system(ctx, command))
+	})
+}
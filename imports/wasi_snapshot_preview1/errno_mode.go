@@ -0,0 +1,138 @@
+package wasi_snapshot_preview1
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ErrnoMode selects how errors which WASI doesn't define a mapping for are
+// returned to the guest. This exists because versions of wasi-libc and other
+// guests depend on specific errno values.
+//
+// See Builder.WithErrnoMode
+type ErrnoMode uint8
+
+const (
+	// ErrnoModeLinux returns the same errno Linux would for the equivalent
+	// system call. This is the default.
+	ErrnoModeLinux ErrnoMode = iota
+
+	// ErrnoModeStrict returns errno values derived from the capability model
+	// of the WASI snapshot-01 specification, where they differ from Linux:
+	//
+	//   - ENOTCAPABLE instead of EPERM when a path escapes its pre-opened
+	//     directory, for functions named "path_*". EPERM returned for other
+	//     reasons, e.g. by the host file system, is unchanged.
+	//   - EBADF instead of EISDIR when reading or writing a directory, as its
+	//     file descriptor doesn't have the rights to do so. This applies to
+	//     "fd_read", "fd_pread", "fd_write" and "fd_pwrite".
+	//
+	// Note: ENOTCAPABLE was removed from wasi-libc, so only use this mode
+	// for guests compiled against older versions, or that otherwise expect
+	// these values.
+	ErrnoModeStrict
+)
+
+// String implements fmt.Stringer
+func (m ErrnoMode) String() string {
+	switch m {
+	case ErrnoModeLinux:
+		return "linux"
+	case ErrnoModeStrict:
+		return "strict"
+	default:
+		return "unknown"
+	}
+}
+
+// errnoModeExporter applies the ErrnoMode to each exported function.
+type errnoModeExporter struct {
+	wasm.HostFuncExporter
+	mode ErrnoMode
+}
+
+// ExportHostFunc implements wasm.HostFuncExporter.ExportHostFunc
+func (e *errnoModeExporter) ExportHostFunc(fn *wasm.HostFunc) {
+	e.HostFuncExporter.ExportHostFunc(withErrnoMode(fn, e.mode))
+}
+
+// withErrnoMode returns a copy of the host function which remaps its errno
+// according to the mode, or the same function for ErrnoModeLinux.
+func withErrnoMode(fn *wasm.HostFunc, mode ErrnoMode) *wasm.HostFunc {
+	if mode != ErrnoModeStrict {
+		return fn
+	}
+	goFunc, ok := fn.Code.GoFunc.(api.GoModuleFunction)
+	if !ok {
+		return fn
+	}
+
+	ret := *fn
+	switch name := fn.Name; {
+	case strings.HasPrefix(name, "path_"):
+		paths := preopenPathParams(fn.ParamNames)
+		if len(paths) == 0 {
+			return fn
+		}
+		ret.Code.GoFunc = api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			// EPERM is also returned for reasons other than the capability
+			// model, e.g. by the host. Hence, only remap it when a path
+			// escapes, which must be checked before results overwrite the
+			// stack.
+			escapes := escapesPreopen(mod.Memory(), stack, paths)
+			goFunc.Call(ctx, mod, stack)
+			if escapes && stack[0] == uint64(wasip1.ErrnoPerm) {
+				stack[0] = uint64(wasip1.ErrnoNotcapable)
+			}
+		})
+	case name == wasip1.FdReadName, name == wasip1.FdPreadName,
+		name == wasip1.FdWriteName, name == wasip1.FdPwriteName:
+		ret.Code.GoFunc = api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {
+			goFunc.Call(ctx, mod, stack)
+			if stack[0] == uint64(wasip1.ErrnoIsdir) {
+				stack[0] = uint64(wasip1.ErrnoBadf)
+			}
+		})
+	default:
+		return fn
+	}
+	return &ret
+}
+
+// preopenPathParams returns the indexes of the path and path length params
+// which are resolved against a directory file descriptor.
+//
+// Note: the old_path of path_symlink isn't included, as it is the contents of
+// the link, which isn't resolved.
+func preopenPathParams(paramNames []string) (ret [][2]int) {
+	for i, name := range paramNames {
+		if i == 0 && name == "old_path" { // path_symlink
+			continue
+		}
+		switch name {
+		case "path", "old_path", "new_path":
+			if i+1 < len(paramNames) && paramNames[i+1] == name+"_len" {
+				ret = append(ret, [2]int{i, i + 1})
+			}
+		}
+	}
+	return
+}
+
+// escapesPreopen returns true if any of the paths at the given param indexes
+// escapes its directory, as atPath rejects.
+func escapesPreopen(mem api.Memory, params []uint64, paths [][2]int) bool {
+	for _, p := range paths {
+		b, ok := mem.Read(uint32(params[p[0]]), uint32(params[p[1]]))
+		if ok && !fs.ValidPath(path.Clean(string(b))) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package wasi_snapshot_preview1_test
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+func TestBuilder_WithErrnoMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+
+	tests := []struct {
+		mode                      wasi_snapshot_preview1.ErrnoMode
+		escapeErrno, readDirErrno wasip1.Errno
+		expectedString            string
+	}{
+		{
+			mode:           wasi_snapshot_preview1.ErrnoModeLinux,
+			escapeErrno:    wasip1.ErrnoPerm,
+			readDirErrno:   wasip1.ErrnoIsdir,
+			expectedString: "linux",
+		},
+		{
+			mode:           wasi_snapshot_preview1.ErrnoModeStrict,
+			escapeErrno:    wasip1.ErrnoNotcapable,
+			readDirErrno:   wasip1.ErrnoBadf,
+			expectedString: "strict",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.expectedString, func(t *testing.T) {
+			require.Equal(t, tc.expectedString, tc.mode.String())
+
+			r := wazero.NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			wasiCompiled, err := wasi_snapshot_preview1.NewBuilder(r).WithErrnoMode(tc.mode).Compile(testCtx)
+			require.NoError(t, err)
+			fsConfig := wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(&permFS{sysfs.DirFS(tmpDir)}, "/")
+			config := wazero.NewModuleConfig().WithFSConfig(fsConfig)
+			_, err = r.InstantiateModule(testCtx, wasiCompiled, config)
+			require.NoError(t, err)
+
+			proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiCompiled))
+			require.NoError(t, err)
+			mod, err := r.InstantiateModule(testCtx, proxyCompiled, config)
+			require.NoError(t, err)
+
+			// path_open of a path which escapes the pre-opened directory.
+			escape := "../file"
+			require.True(t, mod.Memory().WriteString(0, escape))
+			requireErrnoResult(t, tc.escapeErrno, mod, wasip1.PathOpenName,
+				uint64(sys.FdPreopen), 0, 0, uint64(len(escape)), 0, 0, 0, 0, 64)
+
+			// EPERM which isn't due to an escaping path isn't affected.
+			dir := "dir"
+			require.True(t, mod.Memory().WriteString(0, dir))
+			requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.PathCreateDirectoryName,
+				uint64(sys.FdPreopen), 0, uint64(len(dir)))
+
+			// path_symlink whose link escapes the pre-opened directory.
+			target, link := "file", "../link"
+			require.True(t, mod.Memory().WriteString(0, target))
+			require.True(t, mod.Memory().WriteString(16, link))
+			requireErrnoResult(t, tc.escapeErrno, mod, wasip1.PathSymlinkName,
+				0, uint64(len(target)), uint64(sys.FdPreopen), 16, uint64(len(link)))
+
+			// The contents of the link aren't resolved, so can escape.
+			target, link = "../file", "link"
+			require.True(t, mod.Memory().WriteString(0, target))
+			require.True(t, mod.Memory().WriteString(16, link))
+			requireErrnoResult(t, wasip1.ErrnoPerm, mod, wasip1.PathSymlinkName,
+				0, uint64(len(target)), uint64(sys.FdPreopen), 16, uint64(len(link)))
+
+			// path_open which succeeds isn't affected.
+			file := "file"
+			require.True(t, mod.Memory().WriteString(0, file))
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PathOpenName,
+				uint64(sys.FdPreopen), 0, 0, uint64(len(file)), 0, 0, 0, 0, 64)
+
+			// fd_read of the pre-opened directory.
+			iovs := []byte{
+				128, 0, 0, 0, // iovs[0].offset
+				4, 0, 0, 0, // iovs[0].length
+			}
+			require.True(t, mod.Memory().Write(32, iovs))
+			requireErrnoResult(t, tc.readDirErrno, mod, wasip1.FdReadName,
+				uint64(sys.FdPreopen), 32, 1, 64)
+
+			// Unrelated functions aren't affected.
+			requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdCloseName, 42)
+		})
+	}
+}
+
+// permFS returns EPERM on Mkdir and Symlink, like a host denying the
+// operation.
+type permFS struct {
+	experimentalsys.FS
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (*permFS) Mkdir(string, fs.FileMode) experimentalsys.Errno {
+	return experimentalsys.EPERM
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (*permFS) Symlink(string, string) experimentalsys.Errno {
+	return experimentalsys.EPERM
+}
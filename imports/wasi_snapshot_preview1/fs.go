@@ -10,9 +10,11 @@ import (
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	socketapi "github.com/tetratelabs/wazero/internal/sock"
 	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	sysapi "github.com/tetratelabs/wazero/sys"
@@ -82,8 +84,10 @@ func fdAllocateFn(_ context.Context, mod api.Module, params []uint64) experiment
 		return experimentalsys.EBADF
 	}
 
+	// Both are filesize (u64), but file sizes are signed in POSIX, so neither
+	// they nor their sum may exceed math.MaxInt64.
 	tail := int64(offset + length)
-	if tail < 0 {
+	if int64(offset) < 0 || int64(length) < 0 || tail < 0 {
 		return experimentalsys.EINVAL
 	}
 
@@ -134,12 +138,7 @@ func fdDatasyncFn(_ context.Context, mod api.Module, params []uint64) experiment
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	fd := int32(params[0])
 
-	// Check to see if the file descriptor is available
-	if f, ok := fsc.LookupFile(fd); !ok {
-		return experimentalsys.EBADF
-	} else {
-		return f.File.Datasync()
-	}
+	return fsc.Datasync(fd)
 }
 
 // fdFdstatGet is the WASI function named FdFdstatGetName which returns the
@@ -210,23 +209,27 @@ func fdFdstatGetFn(_ context.Context, mod api.Module, params []uint64) experimen
 		fdflags |= wasip1.FD_NONBLOCK
 	}
 
-	var fsRightsBase uint32
-	var fsRightsInheriting uint32
+	var fsRightsBase uint64
+	var fsRightsInheriting uint64
 	fileType := getExtendedWasiFiletype(f.File, st.Mode)
 
-	switch fileType {
-	case wasip1.FILETYPE_DIRECTORY:
+	switch {
+	case f.Rights != nil:
+		// The rights were configured, e.g. by wazero.FSConfig WithPreopenRights.
+		fsRightsBase = f.Rights.Base
+		fsRightsInheriting = f.Rights.Inheriting
+	case fileType == wasip1.FILETYPE_DIRECTORY:
 		// To satisfy wasi-testsuite, we must advertise that directories cannot
 		// be given seek permission (RIGHT_FD_SEEK).
-		fsRightsBase = dirRightsBase
-		fsRightsInheriting = fileRightsBase | dirRightsBase
-	case wasip1.FILETYPE_CHARACTER_DEVICE:
+		fsRightsBase = uint64(dirRightsBase)
+		fsRightsInheriting = uint64(fileRightsBase | dirRightsBase)
+	case fileType == wasip1.FILETYPE_CHARACTER_DEVICE:
 		// According to wasi-libc,
 		// > A tty is a character device that we can't seek or tell on.
 		// See https://github.com/WebAssembly/wasi-libc/blob/a6f871343313220b76009827ed0153586361c0d5/libc-bottom-half/sources/isatty.c#L13-L18
-		fsRightsBase = fileRightsBase &^ wasip1.RIGHT_FD_SEEK &^ wasip1.RIGHT_FD_TELL
+		fsRightsBase = uint64(fileRightsBase &^ wasip1.RIGHT_FD_SEEK &^ wasip1.RIGHT_FD_TELL)
 	default:
-		fsRightsBase = fileRightsBase
+		fsRightsBase = uint64(fileRightsBase)
 	}
 
 	writeFdstat(buf, fileType, fdflags, fsRightsBase, fsRightsInheriting)
@@ -285,13 +288,13 @@ const dirRightsBase = wasip1.RIGHT_FD_DATASYNC |
 	wasip1.RIGHT_PATH_REMOVE_DIRECTORY |
 	wasip1.RIGHT_PATH_UNLINK_FILE
 
-func writeFdstat(buf []byte, fileType uint8, fdflags uint16, fsRightsBase, fsRightsInheriting uint32) {
+func writeFdstat(buf []byte, fileType uint8, fdflags uint16, fsRightsBase, fsRightsInheriting uint64) {
 	b := (*[24]byte)(buf)
 	le.PutUint16(b[0:], uint16(fileType))
 	le.PutUint16(b[2:], fdflags)
 	le.PutUint32(b[4:], 0)
-	le.PutUint64(b[8:], uint64(fsRightsBase))
-	le.PutUint64(b[16:], uint64(fsRightsInheriting))
+	le.PutUint64(b[8:], fsRightsBase)
+	le.PutUint64(b[16:], fsRightsInheriting)
 }
 
 // fdFdstatSetFlags is the WASI function named FdFdstatSetFlagsName which
@@ -386,14 +389,14 @@ var fdFilestatGet = newHostFunc(wasip1.FdFilestatGetName, fdFilestatGetFn, []api
 // fdFilestatGetFn cannot currently use proxyResultParams because filestat is
 // larger than api.ValueTypeI64 (i64 == 8 bytes, but filestat is 64).
 func fdFilestatGetFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
-	return fdFilestatGetFunc(mod, int32(params[0]), uint32(params[1]))
+	return fdFilestatGetFunc(mod, int32(params[0]), uint32(params[1]), false)
 }
 
-func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32) experimentalsys.Errno {
+func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32, unstable bool) experimentalsys.Errno {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
 	// Ensure we can write the filestat
-	buf, ok := mod.Memory().Read(resultBuf, 64)
+	buf, ok := mod.Memory().Read(resultBuf, filestatSize(unstable))
 	if !ok {
 		return experimentalsys.EFAULT
 	}
@@ -409,7 +412,7 @@ func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32) experimentals
 	}
 
 	filetype := getExtendedWasiFiletype(f.File, st.Mode)
-	return writeFilestat(buf, &st, filetype)
+	return writeFilestat(buf, &st, filetype, unstable)
 }
 
 func getExtendedWasiFiletype(file experimentalsys.File, fm fs.FileMode) (ftype uint8) {
@@ -444,7 +447,19 @@ func getWasiFiletype(fm fs.FileMode) uint8 {
 	}
 }
 
-func writeFilestat(buf []byte, st *sysapi.Stat_t, ftype uint8) (errno experimentalsys.Errno) {
+// filestatSize returns the size of a filestat, which is smaller in
+// wasi_unstable, as its nlink is 32-bit.
+func filestatSize(unstable bool) uint32 {
+	if unstable {
+		return 56
+	}
+	return 64
+}
+
+func writeFilestat(buf []byte, st *sysapi.Stat_t, ftype uint8, unstable bool) (errno experimentalsys.Errno) {
+	if unstable {
+		return writeUnstableFilestat(buf, st, ftype)
+	}
 	le.PutUint64(buf, st.Dev)
 	le.PutUint64(buf[8:], st.Ino)
 	le.PutUint64(buf[16:], uint64(ftype))
@@ -464,7 +479,7 @@ var fdFilestatSetSize = newHostFunc(wasip1.FdFilestatSetSizeName, fdFilestatSetS
 
 func fdFilestatSetSizeFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
 	fd := int32(params[0])
-	size := uint32(params[1])
+	size := int64(params[1])
 
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
@@ -472,7 +487,7 @@ func fdFilestatSetSizeFn(_ context.Context, mod api.Module, params []uint64) exp
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
 	} else {
-		return f.File.Truncate(int64(size))
+		return f.File.Truncate(size)
 	}
 }
 
@@ -563,8 +578,8 @@ var fdPread = newHostFunc(
 	"fd", "iovs", "iovs_len", "offset", "result.nread",
 )
 
-func fdPreadFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
-	return fdReadOrPread(mod, params, true)
+func fdPreadFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return fdReadOrPread(ctx, mod, params, true)
 }
 
 // fdPrestatGet is the WASI function named FdPrestatGetName which returns
@@ -686,8 +701,8 @@ var fdPwrite = newHostFunc(
 	"fd", "iovs", "iovs_len", "offset", "result.nwritten",
 )
 
-func fdPwriteFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
-	return fdWriteOrPwrite(mod, params, true)
+func fdPwriteFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return fdWriteOrPwrite(ctx, mod, params, true)
 }
 
 // fdRead is the WASI function named FdReadName which reads from a file
@@ -762,13 +777,14 @@ func (w *preader) Read(buf []byte) (n int, errno experimentalsys.Errno) {
 	return n, err
 }
 
-func fdReadFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
-	return fdReadOrPread(mod, params, false)
+func fdReadFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return fdReadOrPread(ctx, mod, params, false)
 }
 
-func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsys.Errno {
+func fdReadOrPread(ctx context.Context, mod api.Module, params []uint64, isPread bool) experimentalsys.Errno {
 	mem := mod.Memory()
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
 	fd := int32(params[0])
 	iovs := uint32(params[1])
@@ -776,18 +792,32 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsy
 
 	var resultNread uint32
 	var reader func(buf []byte) (n int, errno experimentalsys.Errno)
+	var vectoredReader func(bufs [][]byte) (n int, errno experimentalsys.Errno)
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
 	} else if isPread {
 		offset := int64(params[3])
 		reader = (&preader{f: f.File, offset: offset}).Read
+		if vf, ok := vectoredFile(mem, f.File, iovsCount); ok {
+			vectoredReader = func(bufs [][]byte) (int, experimentalsys.Errno) { return vf.Preadv(bufs, offset) }
+		}
 		resultNread = uint32(params[4])
 	} else {
 		reader = f.File.Read
+		if vf, ok := vectoredFile(mem, f.File, iovsCount); ok {
+			vectoredReader = vf.Readv
+		}
 		resultNread = uint32(params[3])
 	}
 
-	nread, errno := readv(mem, iovs, iovsCount, reader)
+	var nread uint32
+	var errno experimentalsys.Errno
+	if vectoredReader != nil {
+		nread, errno = vectoredIO(mem, iovs, iovsCount, vectoredReader)
+	} else {
+		nread, errno = readv(mem, iovs, iovsCount, reader)
+	}
+	sysCtx.ThrottleIO(ctx, int64(nread))
 	if errno != 0 {
 		return errno
 	}
@@ -910,19 +940,12 @@ func fdReaddirFn(_ context.Context, mod api.Module, params []uint64) experimenta
 
 	// Now, write entries to the underlying buffer.
 	if bufToWrite > 0 {
-
-		// d_next is the index of the next file in the list, so it should
-		// always be one higher than the requested cookie.
-		d_next := cookie + 1
-		// ^^ yes this can overflow to negative, which means our implementation
-		// doesn't support writing greater than max int64 entries.
-
 		buf, ok := mem.Read(buf, bufToWrite)
 		if !ok {
 			return experimentalsys.EFAULT
 		}
 
-		writeDirents(buf, dirents, d_next, direntCount, truncatedLen)
+		writeDirents(buf, dirents, direntCount, truncatedLen)
 	}
 
 	// bufused == bufLen means more dirents exist, which is the case when one
@@ -993,7 +1016,10 @@ func maxDirents(dirents []experimentalsys.Dirent, bufLen uint32) (bufToWrite uin
 // writeDirents writes the directory entries to the buffer, which is pre-sized
 // based on maxDirents.	truncatedEntryLen means the last is written without its
 // name.
-func writeDirents(buf []byte, dirents []experimentalsys.Dirent, d_next uint64, direntCount int, truncatedLen uint32) {
+//
+// The d_next of each entry is its sys.DirentCookie, which stays valid when
+// other entries are added or removed, unlike its index.
+func writeDirents(buf []byte, dirents []experimentalsys.Dirent, direntCount int, truncatedLen uint32) {
 	pos := uint32(0)
 	skipNameI := -1
 
@@ -1010,8 +1036,7 @@ func writeDirents(buf []byte, dirents []experimentalsys.Dirent, d_next uint64, d
 	for i := 0; i < direntCount; i++ {
 		e := dirents[i]
 		nameLen := uint32(len(e.Name))
-		writeDirent(buf[pos:], d_next, e.Ino, nameLen, e.Type)
-		d_next++
+		writeDirent(buf[pos:], sys.DirentCookie(e.Name), e.Ino, nameLen, e.Type)
 		pos += wasip1.DirentSize
 
 		if i != skipNameI {
@@ -1141,12 +1166,7 @@ func fdSyncFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	fd := int32(params[0])
 
-	// Check to see if the file descriptor is available
-	if f, ok := fsc.LookupFile(fd); !ok {
-		return experimentalsys.EBADF
-	} else {
-		return f.File.Sync()
-	}
+	return fsc.Sync(fd)
 }
 
 // fdTell is the WASI function named FdTellName which returns the current
@@ -1229,8 +1249,8 @@ var fdWrite = newHostFunc(
 	"fd", "iovs", "iovs_len", "result.nwritten",
 )
 
-func fdWriteFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
-	return fdWriteOrPwrite(mod, params, false)
+func fdWriteFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return fdWriteOrPwrite(ctx, mod, params, false)
 }
 
 // pwriter tracks an offset across multiple writes.
@@ -1250,9 +1270,10 @@ func (w *pwriter) Write(buf []byte) (n int, errno experimentalsys.Errno) {
 	return n, err
 }
 
-func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimentalsys.Errno {
+func fdWriteOrPwrite(ctx context.Context, mod api.Module, params []uint64, isPwrite bool) experimentalsys.Errno {
 	mem := mod.Memory()
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
 	fd := int32(params[0])
 	iovs := uint32(params[1])
@@ -1260,18 +1281,32 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimenta
 
 	var resultNwritten uint32
 	var writer func(buf []byte) (n int, errno experimentalsys.Errno)
+	var vectoredWriter func(bufs [][]byte) (n int, errno experimentalsys.Errno)
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
 	} else if isPwrite {
 		offset := int64(params[3])
 		writer = (&pwriter{f: f.File, offset: offset}).Write
+		if vf, ok := vectoredFile(mem, f.File, iovsCount); ok {
+			vectoredWriter = func(bufs [][]byte) (int, experimentalsys.Errno) { return vf.Pwritev(bufs, offset) }
+		}
 		resultNwritten = uint32(params[4])
 	} else {
 		writer = f.File.Write
+		if vf, ok := vectoredFile(mem, f.File, iovsCount); ok {
+			vectoredWriter = vf.Writev
+		}
 		resultNwritten = uint32(params[3])
 	}
 
-	nwritten, errno := writev(mem, iovs, iovsCount, writer)
+	var nwritten uint32
+	var errno experimentalsys.Errno
+	if vectoredWriter != nil {
+		nwritten, errno = vectoredIO(mem, iovs, iovsCount, vectoredWriter)
+	} else {
+		nwritten, errno = writev(mem, iovs, iovsCount, writer)
+	}
+	sysCtx.ThrottleIO(ctx, int64(nwritten))
 	if errno != 0 {
 		return errno
 	}
@@ -1309,6 +1344,59 @@ func writev(mem api.Memory, iovs uint32, iovsCount uint32, writer func(buf []byt
 	return nwritten, 0
 }
 
+// maxVectoredIovs is the most iovecs passed to one host call, which is the
+// minimum IOV_MAX of supported platforms.
+const maxVectoredIovs = 1024
+
+// vectoredFile returns `f` as a sysfs.VectoredFile when IO with `iovsCount`
+// iovecs can be delegated to one host call, instead of one per iovec.
+//
+// This requires the memory to be pinned, as the host is passed addresses of
+// guest memory. See experimental.WithPinnedMemory
+func vectoredFile(mem api.Memory, f experimentalsys.File, iovsCount uint32) (sysfs.VectoredFile, bool) {
+	if iovsCount < 2 || iovsCount > maxVectoredIovs {
+		return nil, false // no benefit or too many to pass at once.
+	}
+	vf, ok := f.(sysfs.VectoredFile)
+	if !ok {
+		return nil, false
+	}
+	if _, pinned := experimental.MemoryBaseAddress(mem); !pinned {
+		return nil, false
+	}
+	return vf, true
+}
+
+// vectoredIO is like readv or writev, except it passes all iovecs to one
+// call of `vectored`.
+func vectoredIO(mem api.Memory, iovs uint32, iovsCount uint32, vectored func(bufs [][]byte) (n int, errno experimentalsys.Errno)) (uint32, experimentalsys.Errno) {
+	iovsStop := iovsCount << 3 // iovsCount * 8
+	iovsBuf, ok := mem.Read(iovs, iovsStop)
+	if !ok {
+		return 0, experimentalsys.EFAULT
+	}
+
+	bufs := make([][]byte, 0, iovsCount)
+	for iovsPos := uint32(0); iovsPos < iovsStop; iovsPos += 8 {
+		offset := le.Uint32(iovsBuf[iovsPos:])
+		l := le.Uint32(iovsBuf[iovsPos+4:])
+
+		b, ok := mem.Read(offset, l)
+		if !ok {
+			return 0, experimentalsys.EFAULT
+		}
+		bufs = append(bufs, b)
+	}
+
+	n, errno := vectored(bufs)
+	if errno == experimentalsys.ENOSYS {
+		return 0, experimentalsys.EBADF // e.g. unimplemented for read or write
+	} else if errno != 0 {
+		return 0, errno
+	}
+	return uint32(n), 0
+}
+
 // pathCreateDirectory is the WASI function named PathCreateDirectoryName which
 // creates a directory.
 //
@@ -1390,6 +1478,10 @@ var pathFilestatGet = newHostFunc(
 )
 
 func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return pathFilestatGetFunc(mod, params, false)
+}
+
+func pathFilestatGetFunc(mod api.Module, params []uint64, unstable bool) experimentalsys.Errno {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
 	fd := int32(params[0])
@@ -1416,13 +1508,13 @@ func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) exper
 
 	// Write the stat result to memory
 	resultBuf := uint32(params[4])
-	buf, ok := mod.Memory().Read(resultBuf, 64)
+	buf, ok := mod.Memory().Read(resultBuf, filestatSize(unstable))
 	if !ok {
 		return experimentalsys.EFAULT
 	}
 
 	filetype := getWasiFiletype(st.Mode)
-	return writeFilestat(buf, &st, filetype)
+	return writeFilestat(buf, &st, filetype, unstable)
 }
 
 // pathFilestatSetTimes is the WASI function named PathFilestatSetTimesName
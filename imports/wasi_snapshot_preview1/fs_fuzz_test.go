@@ -0,0 +1,446 @@
+package wasi_snapshot_preview1_test
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// FuzzFS runs sequences of WASI file system calls against a directory mount,
+// and compares the errnos and results with modelFS. This catches errno and
+// ordering bugs in internal/sysfs, notably those specific to a platform.
+//
+// The input is a sequence of three bytes per call: the call, then two
+// arguments, usually indexes of fuzzPaths. Files are opened non-blocking, and
+// each call fails after fuzzCallTimeout, so that a call which blocks fails the
+// input instead of stalling the fuzzer. Run it with:
+//
+//	go test -run=NONE -fuzz=FuzzFS ./imports/wasi_snapshot_preview1/
+func FuzzFS(f *testing.F) {
+	for _, seed := range [][]byte{
+		{fsOpMkdir, 0, 0, fsOpMkdir, 2, 0, fsOpWriteFile, 3, 5, fsOpReadFile, 3, 0, fsOpRmdir, 0, 0},
+		{fsOpWriteFile, 0, 3, fsOpMkdir, 0, 0, fsOpOpenDir, 0, 0, fsOpUnlink, 0, 0, fsOpStat, 0, 0},
+		{fsOpMkdir, 0, 0, fsOpWriteFile, 1, 4, fsOpRename, 1, 2, fsOpStat, 2, 0, fsOpRename, 0, 1, fsOpReadFile, 4, 0},
+		{fsOpMkdir, 0, 0, fsOpMkdir, 2, 0, fsOpRename, 0, 5, fsOpRename, 2, 0, fsOpRmdir, 0, 0},
+		{fsOpCreateExcl, 1, 0, fsOpCreateExcl, 1, 0, fsOpAppendFile, 1, 2, fsOpAppendFile, 1, 7, fsOpStat, 1, 0},
+		{fsOpMkdir, 1, 0, fsOpWriteFile, 1, 1, fsOpUnlink, 1, 0, fsOpReadFile, 1, 0, fsOpUnlink, 0, 0},
+		{fsOpRename, 0, 0, fsOpMkdir, 0, 0, fsOpRename, 0, 0, fsOpStat, 0, 0},
+		{fsOpCreateExcl, 0, 0, fsOpMkdir, 2, 0, fsOpMkdir, 5, 0, fsOpRename, 1, 2},
+		{fsOpMkdir, 0, 0, fsOpCreateExcl, 2, 0, fsOpRename, 2, 0, fsOpRename, 0, 5},
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		if len(ops) > 3*64 {
+			t.Skip("too many calls")
+		}
+
+		mod, r := requireFuzzFSModule(t)
+		defer r.Close(testCtx)
+
+		model := newModelFS()
+		var history []string
+		for i := 0; i+2 < len(ops); i += 3 {
+			op, a, b := ops[i]%fsOpCount, ops[i+1], ops[i+2]
+			call := fsCallString(op, a, b)
+			history = append(history, call)
+
+			expected := model.call(op, a, b)
+			actual := wasiCallWithTimeout(t, mod, op, a, b)
+			if expected != actual {
+				t.Fatalf("%s: expected %s, but was %s\ncalls:\n\t%s",
+					call, expected, actual, strings.Join(history, "\n\t"))
+			}
+		}
+	})
+}
+
+// requireFuzzFSModule returns a proxy of the WASI functions, with a new
+// directory mounted as the root. Unlike requireProxyModule, this doesn't log
+// calls, as formatting them would dominate the time of each input.
+func requireFuzzFSModule(t *testing.T) (api.Module, api.Closer) {
+	r := wazero.NewRuntime(testCtx)
+	config := wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(t.TempDir(), "/"))
+
+	wasiCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, wasiCompiled, config)
+	require.NoError(t, err)
+
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiCompiled))
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(testCtx, proxyCompiled, config)
+	require.NoError(t, err)
+	return mod, r
+}
+
+// fuzzCallTimeout bounds each call, which should only take microseconds.
+const fuzzCallTimeout = 5 * time.Second
+
+// wasiCallWithTimeout runs wasiCall, failing if it doesn't return within
+// fuzzCallTimeout. The call is left running, as a blocked system call can't be
+// interrupted.
+func wasiCallWithTimeout(t *testing.T, mod api.Module, op, a, b byte) string {
+	type result struct {
+		actual, failure string
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- result{failure: fmt.Sprint(recovered)}
+			}
+		}()
+		done <- result{actual: wasiCall(fuzzCallT{}, mod, op, a, b)}
+	}()
+
+	select {
+	case res := <-done:
+		if res.failure != "" {
+			t.Fatal(res.failure)
+		}
+		return res.actual
+	case <-time.After(fuzzCallTimeout):
+		t.Fatalf("%s: blocked for %v", fsCallString(op, a, b), fuzzCallTimeout)
+		return ""
+	}
+}
+
+// fuzzCallT panics on failure, so that wasiCall can run outside the test
+// goroutine, where testing.T FailNow isn't allowed.
+type fuzzCallT struct{}
+
+// Fatal implements require.TestingT
+func (fuzzCallT) Fatal(args ...interface{}) {
+	panic(fmt.Sprint(args...))
+}
+
+const (
+	fsOpMkdir byte = iota
+	fsOpRmdir
+	fsOpUnlink
+	fsOpRename
+	fsOpWriteFile
+	fsOpAppendFile
+	fsOpReadFile
+	fsOpStat
+	fsOpCreateExcl
+	fsOpOpenDir
+	fsOpCount
+)
+
+// fuzzPaths are few, so that calls often collide.
+var fuzzPaths = []string{"a", "b", "a/a", "a/b", "b/a", "a/a/a"}
+
+func fuzzPath(i byte) string {
+	return fuzzPaths[int(i)%len(fuzzPaths)]
+}
+
+func fsCallString(op, a, b byte) string {
+	switch op {
+	case fsOpMkdir:
+		return fmt.Sprintf("mkdir(%s)", fuzzPath(a))
+	case fsOpRmdir:
+		return fmt.Sprintf("rmdir(%s)", fuzzPath(a))
+	case fsOpUnlink:
+		return fmt.Sprintf("unlink(%s)", fuzzPath(a))
+	case fsOpRename:
+		return fmt.Sprintf("rename(%s, %s)", fuzzPath(a), fuzzPath(b))
+	case fsOpWriteFile:
+		return fmt.Sprintf("writeFile(%s, %q)", fuzzPath(a), fuzzData(a, b))
+	case fsOpAppendFile:
+		return fmt.Sprintf("appendFile(%s, %q)", fuzzPath(a), fuzzData(a, b))
+	case fsOpReadFile:
+		return fmt.Sprintf("readFile(%s)", fuzzPath(a))
+	case fsOpStat:
+		return fmt.Sprintf("stat(%s)", fuzzPath(a))
+	case fsOpCreateExcl:
+		return fmt.Sprintf("createExcl(%s)", fuzzPath(a))
+	default:
+		return fmt.Sprintf("openDir(%s)", fuzzPath(a))
+	}
+}
+
+// fuzzData returns up to 15 bytes to write.
+func fuzzData(a, b byte) []byte {
+	return bytes.Repeat([]byte{'a' + a%26}, int(b%16))
+}
+
+// Memory offsets of the parameters and results of wasiCall.
+const (
+	fuzzPathOffset     = 0
+	fuzzToPathOffset   = 16
+	fuzzIovsOffset     = 32
+	fuzzResultOffset   = 64
+	fuzzFilestatOffset = 128
+	fuzzDataOffset     = 256
+	fuzzDataLen        = 64
+)
+
+// wasiCall makes the call with the WASI functions, and returns the same
+// format as modelFS.call.
+func wasiCall(t require.TestingT, mod api.Module, op, a, b byte) string {
+	mem := mod.Memory()
+	p, to := fuzzPath(a), fuzzPath(b)
+	require.True(t, mem.WriteString(fuzzPathOffset, p))
+	require.True(t, mem.WriteString(fuzzToPathOffset, to))
+	preopen := uint64(3)
+
+	call := func(name string, params ...uint64) wasip1.Errno {
+		results, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+		return wasip1.Errno(results[0])
+	}
+	pathParams := []uint64{preopen, fuzzPathOffset, uint64(len(p))}
+
+	switch op {
+	case fsOpMkdir:
+		return fuzzErrno(call(wasip1.PathCreateDirectoryName, pathParams...))
+	case fsOpRmdir:
+		return fuzzErrno(call(wasip1.PathRemoveDirectoryName, pathParams...))
+	case fsOpUnlink:
+		return fuzzErrno(call(wasip1.PathUnlinkFileName, pathParams...))
+	case fsOpRename:
+		return fuzzErrno(call(wasip1.PathRenameName,
+			preopen, fuzzPathOffset, uint64(len(p)), preopen, fuzzToPathOffset, uint64(len(to))))
+	case fsOpStat:
+		if errno := call(wasip1.PathFilestatGetName, preopen, 0, fuzzPathOffset, uint64(len(p)), fuzzFilestatOffset); errno != 0 {
+			return fuzzErrno(errno)
+		}
+		filetype, _ := mem.ReadByte(fuzzFilestatOffset + 16)
+		if filetype == wasip1.FILETYPE_DIRECTORY {
+			return "directory"
+		}
+		size, _ := mem.ReadUint64Le(fuzzFilestatOffset + 32)
+		return fmt.Sprintf("file of %d bytes", size)
+	}
+
+	// The rest open a file. This is non-blocking, in case the path is ever
+	// something other than a regular file or directory, such as a FIFO.
+	var oflags uint16
+	fdflags := wasip1.FD_NONBLOCK
+	rights := uint64(wasip1.RIGHT_FD_READ)
+	switch op {
+	case fsOpWriteFile:
+		oflags, rights = wasip1.O_CREAT|wasip1.O_TRUNC, uint64(wasip1.RIGHT_FD_WRITE)
+	case fsOpAppendFile:
+		fdflags, rights = fdflags|wasip1.FD_APPEND, uint64(wasip1.RIGHT_FD_WRITE)
+	case fsOpCreateExcl:
+		oflags = wasip1.O_CREAT | wasip1.O_EXCL
+	case fsOpOpenDir:
+		oflags = wasip1.O_DIRECTORY
+	}
+	if errno := call(wasip1.PathOpenName, preopen, 0, fuzzPathOffset, uint64(len(p)),
+		uint64(oflags), rights, 0, uint64(fdflags), fuzzResultOffset); errno != 0 {
+		return fuzzErrno(errno)
+	}
+	fd, _ := mem.ReadUint32Le(fuzzResultOffset)
+	defer func() {
+		require.Equal(t, wasip1.ErrnoSuccess, call(wasip1.FdCloseName, uint64(fd)))
+	}()
+
+	switch op {
+	case fsOpWriteFile, fsOpAppendFile:
+		data := fuzzData(a, b)
+		require.True(t, mem.Write(fuzzDataOffset, data))
+		require.True(t, mem.WriteUint32Le(fuzzIovsOffset, fuzzDataOffset))
+		require.True(t, mem.WriteUint32Le(fuzzIovsOffset+4, uint32(len(data))))
+		if errno := call(wasip1.FdWriteName, uint64(fd), fuzzIovsOffset, 1, fuzzResultOffset); errno != 0 {
+			return fuzzErrno(errno)
+		}
+		n, _ := mem.ReadUint32Le(fuzzResultOffset)
+		return fmt.Sprintf("wrote %d bytes", n)
+	case fsOpReadFile:
+		require.True(t, mem.WriteUint32Le(fuzzIovsOffset, fuzzDataOffset))
+		require.True(t, mem.WriteUint32Le(fuzzIovsOffset+4, fuzzDataLen))
+		if errno := call(wasip1.FdReadName, uint64(fd), fuzzIovsOffset, 1, fuzzResultOffset); errno != 0 {
+			return fuzzErrno(errno)
+		}
+		n, _ := mem.ReadUint32Le(fuzzResultOffset)
+		data, _ := mem.Read(fuzzDataOffset, n)
+		return fmt.Sprintf("read %q", data)
+	default:
+		return "opened"
+	}
+}
+
+func fuzzErrno(errno wasip1.Errno) string {
+	return wasip1.ErrnoName(errno)
+}
+
+// modelFS is a model of the file system WASI guests expect, which is POSIX
+// as implemented by Linux.
+type modelFS struct {
+	// nodes are files and directories by path. Directories have nil data.
+	nodes map[string][]byte
+}
+
+func newModelFS() *modelFS {
+	return &modelFS{nodes: map[string][]byte{}}
+}
+
+func (m *modelFS) isDir(p string) bool {
+	data, ok := m.nodes[p]
+	return p == "." || (ok && data == nil)
+}
+
+func (m *modelFS) exists(p string) bool {
+	_, ok := m.nodes[p]
+	return p == "." || ok
+}
+
+func (m *modelFS) isEmptyDir(p string) bool {
+	for n := range m.nodes {
+		if strings.HasPrefix(n, p+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the errno looking up the path, ignoring its last element.
+func (m *modelFS) lookup(p string) wasip1.Errno {
+	parent := "."
+	if i := strings.LastIndexByte(p, '/'); i >= 0 {
+		parent = p[:i]
+	}
+	if parent == "." {
+		return 0
+	} else if errno := m.lookup(parent); errno != 0 {
+		return errno
+	} else if !m.exists(parent) {
+		return wasip1.ErrnoNoent
+	} else if !m.isDir(parent) {
+		return wasip1.ErrnoNotdir
+	}
+	return 0
+}
+
+// call makes the call on the model, and returns its errno or result.
+func (m *modelFS) call(op, a, b byte) string {
+	p, to := fuzzPath(a), fuzzPath(b)
+	if errno := m.lookup(p); errno != 0 {
+		return fuzzErrno(errno)
+	}
+
+	switch op {
+	case fsOpMkdir:
+		if m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoExist)
+		}
+		m.nodes[p] = nil
+		return fuzzErrno(0)
+	case fsOpRmdir:
+		if !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		} else if !m.isDir(p) {
+			return fuzzErrno(wasip1.ErrnoNotdir)
+		} else if !m.isEmptyDir(p) {
+			return fuzzErrno(wasip1.ErrnoNotempty)
+		}
+		delete(m.nodes, p)
+		return fuzzErrno(0)
+	case fsOpUnlink:
+		if !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		} else if m.isDir(p) {
+			return fuzzErrno(wasip1.ErrnoIsdir)
+		}
+		delete(m.nodes, p)
+		return fuzzErrno(0)
+	case fsOpRename:
+		return fuzzErrno(m.rename(p, to))
+	case fsOpStat:
+		if !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		} else if m.isDir(p) {
+			return "directory"
+		}
+		return fmt.Sprintf("file of %d bytes", len(m.nodes[p]))
+	case fsOpWriteFile, fsOpAppendFile:
+		if m.isDir(p) {
+			return fuzzErrno(wasip1.ErrnoIsdir)
+		} else if op == fsOpAppendFile && !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		}
+		data := fuzzData(a, b)
+		if op == fsOpWriteFile {
+			m.nodes[p] = append([]byte{}, data...)
+		} else {
+			m.nodes[p] = append(m.nodes[p], data...)
+		}
+		return fmt.Sprintf("wrote %d bytes", len(data))
+	case fsOpReadFile:
+		if !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		} else if m.isDir(p) {
+			return fuzzErrno(wasip1.ErrnoIsdir)
+		}
+		return fmt.Sprintf("read %q", m.nodes[p])
+	case fsOpCreateExcl:
+		if m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoExist)
+		}
+		m.nodes[p] = []byte{}
+		return "opened"
+	default: // fsOpOpenDir
+		if !m.exists(p) {
+			return fuzzErrno(wasip1.ErrnoNoent)
+		} else if !m.isDir(p) {
+			return fuzzErrno(wasip1.ErrnoNotdir)
+		}
+		return "opened"
+	}
+}
+
+func (m *modelFS) rename(from, to string) wasip1.Errno {
+	// Both parents are looked up before either name.
+	if errno := m.lookup(to); errno != 0 {
+		return errno
+	} else if !m.exists(from) {
+		return wasip1.ErrnoNoent
+	} else if from == to {
+		return 0
+	} else if strings.HasPrefix(to, from+"/") {
+		return wasip1.ErrnoInval // can't move a directory into itself
+	} else if strings.HasPrefix(from, to+"/") {
+		return wasip1.ErrnoNotempty // to contains from
+	}
+
+	if m.isDir(from) {
+		if m.exists(to) && !m.isDir(to) {
+			return wasip1.ErrnoNotdir
+		} else if m.exists(to) && !m.isEmptyDir(to) {
+			return wasip1.ErrnoNotempty
+		}
+	} else if m.isDir(to) {
+		return wasip1.ErrnoIsdir
+	}
+
+	// Move the node and any children, in a stable order.
+	var moved []string
+	for n := range m.nodes {
+		if n == from || strings.HasPrefix(n, from+"/") {
+			moved = append(moved, n)
+		}
+	}
+	sort.Strings(moved)
+	delete(m.nodes, to)
+	for _, n := range moved {
+		m.nodes[to+n[len(from):]] = m.nodes[n]
+		delete(m.nodes, n)
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package wasi_snapshot_preview1_test
 import (
 	"bytes"
 	_ "embed"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"io/fs"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/fstest"
@@ -76,6 +78,8 @@ func Test_fdAllocate(t *testing.T) {
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), uint64(minusOne), uint64(minusOne))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), 0, uint64(minusOne))
 		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), uint64(minusOne), 0)
+		// offset + len exceeds math.MaxInt64
+		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdAllocateName, uint64(fd), math.MaxInt64, 1)
 	})
 
 	t.Run("do not change size", func(t *testing.T) {
@@ -113,6 +117,8 @@ func Test_fdAllocate(t *testing.T) {
 <== errno=EINVAL
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=-1,len=0)
 <== errno=EINVAL
+==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=9223372036854775807,len=1)
+<== errno=EINVAL
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=0,len=10)
 <== errno=ESUCCESS
 ==> wasi_snapshot_preview1.fd_allocate(fd=4,offset=5,len=5)
@@ -688,8 +694,8 @@ func Test_fdFilestatGet(t *testing.T) {
 			name: "root",
 			fd:   sys.FdPreopen,
 			expectedMemory: []byte{
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xb1, 0x8b, 0x1, 0x86, 0x4c, 0xa3, 0x63, 0xaf, // ino
 				3, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				0, 0, 0, 0, 0, 0, 0, 0, // size
@@ -706,8 +712,8 @@ func Test_fdFilestatGet(t *testing.T) {
 			name: "file",
 			fd:   fileFD,
 			expectedMemory: []byte{
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xcc, 0xdd, 0x0, 0xf5, 0xa1, 0x2c, 0x99, 0x97, // ino
 				4, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				30, 0, 0, 0, 0, 0, 0, 0, // size
@@ -724,8 +730,8 @@ func Test_fdFilestatGet(t *testing.T) {
 			name: "dir",
 			fd:   dirFD,
 			expectedMemory: []byte{
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xf5, 0xc2, 0xf, 0x5d, 0x19, 0x9d, 0x71, 0x82, // ino
 				3, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				0, 0, 0, 0, 0, 0, 0, 0, // size
@@ -1278,10 +1284,10 @@ func Test_fdPread_Errors(t *testing.T) {
 				'?', '?', '?', '?',
 			},
 			offset:        int64(-1),
-			expectedErrno: wasip1.ErrnoIo,
+			expectedErrno: wasip1.ErrnoInval,
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=65523,iovs_len=1,offset=-1)
-<== (nread=,errno=EIO)
+<== (nread=,errno=EINVAL)
 `,
 		},
 	}
@@ -1330,6 +1336,57 @@ func Test_fdPrestatGet(t *testing.T) {
 	require.Equal(t, expectedMemory, actual)
 }
 
+// Test_fdPrestatGet_preopenOrder ensures wasi-libc finds pre-opens in the
+// configured order, as it reads them from sys.FdPreopen until EBADF.
+func Test_fdPrestatGet_preopenOrder(t *testing.T) {
+	fsConfig := wazero.NewFSConfig().
+		WithDirMount(t.TempDir(), "/").
+		WithDirMount(t.TempDir(), "/tmp").
+		WithPreopenOrder("/tmp")
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPrestatGetName, uint64(sys.FdPreopen), 0)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPrestatGetName, uint64(sys.FdPreopen+1), 0)
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdPrestatGetName, uint64(sys.FdPreopen+2), 0)
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_prestat_get(fd=3)
+<== (prestat={pr_name_len=4},errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_prestat_get(fd=4)
+<== (prestat={pr_name_len=1},errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_prestat_get(fd=5)
+<== (prestat=,errno=EBADF)
+`, "\n"+log.String())
+}
+
+func Test_fdFdstatGet_preopenRights(t *testing.T) {
+	fsConfig := wazero.NewFSConfig().
+		WithDirMount(t.TempDir(), "/").
+		WithReadOnlyDirMount(t.TempDir(), "/data").
+		WithPreopenRights("/data", uint64(wasip1.RIGHT_PATH_OPEN|wasip1.RIGHT_FD_READDIR), uint64(wasip1.RIGHT_FD_READ))
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+
+	expectedMemory := []byte{
+		3, 0, // fs_filetype
+		0, 0, 0, 0, 0, 0, // fs_flags
+		0x0, 0x60, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // fs_rights_base
+		0x2, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // fs_rights_inheriting
+	}
+	maskMemory(t, mod, len(expectedMemory))
+
+	// The root has the default rights, so only check the configured mount.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatGetName, uint64(sys.FdPreopen+1), 0)
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_fdstat_get(fd=4)
+<== (stat={filetype=DIRECTORY,fdflags=,fs_rights_base=PATH_OPEN|FD_READDIR,fs_rights_inheriting=FD_READ},errno=ESUCCESS)
+`, "\n"+log.String())
+
+	actual, ok := mod.Memory().Read(0, uint32(len(expectedMemory)))
+	require.True(t, ok)
+	require.Equal(t, expectedMemory, actual)
+}
+
 func Test_fdPrestatGet_Errors(t *testing.T) {
 	mod, dirFD, log, r := requireOpenFile(t, t.TempDir(), "tmp", nil, true)
 	defer r.Close(testCtx)
@@ -1583,6 +1640,74 @@ func Test_fdPwrite(t *testing.T) {
 	}
 }
 
+// Test_fdPwrite_largeFile ensures offsets past 4GiB aren't truncated to 32
+// bits. This uses a sparse file, so doesn't write gigabytes of data.
+func Test_fdPwrite_largeFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("NTFS zero-fills the gap unless the file is marked sparse")
+	}
+
+	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
+	pathName := "test_path"
+	mod, fd, log, r := requireOpenFile(t, tmpDir, pathName, []byte{}, false)
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	const (
+		iovs      = uint32(0)
+		iovsCount = uint32(1)
+		result    = uint32(16)
+		offset    = int64(5 << 30) // 5GiB
+	)
+	writeIovs := func(text string) {
+		mem := mod.Memory()
+		require.True(t, mem.WriteUint32Le(iovs, 32))
+		require.True(t, mem.WriteUint32Le(iovs+4, uint32(len(text))))
+		require.True(t, mem.WriteString(32, text))
+	}
+	readIovs := func(n uint32) string {
+		buf, ok := mod.Memory().Read(32, n)
+		require.True(t, ok)
+		return string(buf)
+	}
+
+	writeIovs("wazero")
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPwriteName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(offset), uint64(result))
+	nwritten, _ := mod.Memory().ReadUint32Le(result)
+	require.Equal(t, uint32(6), nwritten)
+
+	t.Run("fd_seek to end", func(t *testing.T) {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdSeekName, uint64(fd), 0, uint64(io.SeekEnd), uint64(result))
+		newOffset, _ := mod.Memory().ReadUint64Le(result)
+		require.Equal(t, uint64(offset+6), newOffset)
+	})
+
+	t.Run("fd_pread data", func(t *testing.T) {
+		writeIovs("??????")
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(offset), uint64(result))
+		require.Equal(t, "wazero", readIovs(6))
+	})
+
+	t.Run("fd_pread hole", func(t *testing.T) {
+		writeIovs("??????")
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(4<<30), uint64(result))
+		require.Equal(t, string(make([]byte, 6)), readIovs(6))
+	})
+
+	t.Run("fd_pread offset exceeds int64", func(t *testing.T) {
+		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(1<<63), uint64(result))
+	})
+
+	t.Run("fd_filestat_set_size", func(t *testing.T) {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatSetSizeName, uint64(fd), uint64(6<<30))
+		st, err := os.Stat(joinPath(tmpDir, pathName))
+		require.NoError(t, err)
+		require.Equal(t, int64(6<<30), st.Size())
+
+		requireErrnoResult(t, wasip1.ErrnoInval, mod, wasip1.FdFilestatSetSizeName, uint64(fd), uint64(1<<63))
+	})
+}
+
 func Test_fdPwrite_offset(t *testing.T) {
 	tmpDir := t.TempDir()
 	pathName := "test_path"
@@ -1770,10 +1895,10 @@ func Test_fdPwrite_Errors(t *testing.T) {
 				'?', '?', '?', '?',
 			},
 			offset:        int64(-1),
-			expectedErrno: wasip1.ErrnoIo,
+			expectedErrno: wasip1.ErrnoInval,
 			expectedLog: `
 ==> wasi_snapshot_preview1.fd_pwrite(fd=4,iovs=65523,iovs_len=1,offset=-1)
-<== (nwritten=,errno=EIO)
+<== (nwritten=,errno=EINVAL)
 `,
 		},
 	}
@@ -1951,51 +2076,51 @@ func Test_fdRead_Errors(t *testing.T) {
 }
 
 var (
-	direntDot = []byte{
-		1, 0, 0, 0, 0, 0, 0, 0, // d_next = 1
-		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
+	direntDot = append(u64.LeBytes(sys.DirentCookie(".")), // d_next
+		0x54, 0x5f, 0x6e, 0xf4, 0x18, 0x3e, 0xa8, 0xca, // d_ino = synthetic inode of "dir"
 		1, 0, 0, 0, // d_namlen = 1 character
 		3, 0, 0, 0, // d_type =  directory
 		'.', // name
-	}
-	direntDotDot = []byte{
-		2, 0, 0, 0, 0, 0, 0, 0, // d_next = 2
+	)
+	direntDotEmpty = append(u64.LeBytes(sys.DirentCookie(".")), // d_next
+		0xc5, 0xb9, 0x1d, 0xf9, 0x48, 0x98, 0x50, 0xf1, // d_ino = synthetic inode of "emptydir"
+		1, 0, 0, 0, // d_namlen = 1 character
+		3, 0, 0, 0, // d_type =  directory
+		'.', // name
+	)
+	direntDotDot = append(u64.LeBytes(sys.DirentCookie("..")), // d_next
 		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
 		2, 0, 0, 0, // d_namlen = 2 characters
 		3, 0, 0, 0, // d_type =  directory
 		'.', '.', // name
-	}
-	dirent1 = []byte{
-		3, 0, 0, 0, 0, 0, 0, 0, // d_next = 3
-		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
+	)
+	dirent1 = append(u64.LeBytes(sys.DirentCookie("-")), // d_next
+		0xc4, 0xa4, 0x4d, 0xc3, 0x99, 0x28, 0xb0, 0x38, // d_ino = synthetic inode of "dir/-"
 		1, 0, 0, 0, // d_namlen = 1 character
 		4, 0, 0, 0, // d_type = regular_file
 		'-', // name
-	}
-	dirent2 = []byte{
-		4, 0, 0, 0, 0, 0, 0, 0, // d_next = 4
-		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
+	)
+	dirent2 = append(u64.LeBytes(sys.DirentCookie("a-")), // d_next
+		0x17, 0xfd, 0x84, 0xdd, 0x46, 0x66, 0xaa, 0xa1, // d_ino = synthetic inode of "dir/a-"
 		2, 0, 0, 0, // d_namlen = 1 character
 		3, 0, 0, 0, // d_type =  directory
 		'a', '-', // name
-	}
-	dirent3 = []byte{
-		5, 0, 0, 0, 0, 0, 0, 0, // d_next = 5
-		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
+	)
+	dirent3 = append(u64.LeBytes(sys.DirentCookie("ab-")), // d_next
+		0xb1, 0xe7, 0x92, 0x69, 0x6a, 0xe4, 0x3c, 0x3a, // d_ino = synthetic inode of "dir/ab-"
 		3, 0, 0, 0, // d_namlen = 3 characters
 		4, 0, 0, 0, // d_type = regular_file
 		'a', 'b', '-', // name
-	}
+	)
 
 	// TODO: this entry is intended to test reading of a symbolic link entry,
 	// tho it requires modifying fstest.FS to contain this file.
-	// dirent4 = []byte{
-	// 	6, 0, 0, 0, 0, 0, 0, 0, // d_next = 6
+	// dirent4 = append(u64.LeBytes(sys.DirentCookie("ln")), // d_next
 	// 	0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
 	// 	2, 0, 0, 0, // d_namlen = 2 characters
 	// 	7, 0, 0, 0, // d_type = symbolic_link
 	// 	'l', 'n', // name
-	// }
+	// )
 
 	dirents = bytes.Join([][]byte{
 		direntDot,
@@ -2020,7 +2145,7 @@ func Test_fdReaddir(t *testing.T) {
 		initialDir      string
 		dir             func()
 		bufLen          uint32
-		cookie          int64
+		cookie          uint64
 		expectedMem     []byte
 		expectedMemSize int
 		expectedBufused uint32
@@ -2031,7 +2156,7 @@ func Test_fdReaddir(t *testing.T) {
 			bufLen:          wasip1.DirentSize + 1, // size of one entry
 			cookie:          0,
 			expectedBufused: wasip1.DirentSize + 1, // one dot entry
-			expectedMem:     direntDot,
+			expectedMem:     direntDotEmpty,
 		},
 		{
 			name:            "full read",
@@ -2065,9 +2190,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			bufLen:          27, // length is long enough for exactly second.
-			cookie:          1,  // d_next of first
-			expectedBufused: 27, // length to read exactly second.
+			bufLen:          27,                    // length is long enough for exactly second.
+			cookie:          sys.DirentCookie("."), // d_next of first
+			expectedBufused: 27,                    // length to read exactly second.
 			expectedMem:     direntDotDot,
 		},
 		{
@@ -2078,9 +2203,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			bufLen:          30, // length is longer than the second entry, but not long enough for a header.
-			cookie:          1,  // d_next of first
-			expectedBufused: 30, // length to read some more, but not enough for a header, so buf was exhausted.
+			bufLen:          30,                    // length is longer than the second entry, but not long enough for a header.
+			cookie:          sys.DirentCookie("."), // d_next of first
+			expectedBufused: 30,                    // length to read some more, but not enough for a header, so buf was exhausted.
 			expectedMem:     direntDotDot,
 			expectedMemSize: len(direntDotDot), // we do not want to compare the full buffer since we don't know what the leftover 4 bytes will contain.
 		},
@@ -2092,9 +2217,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			bufLen:          50, // length is longer than the second entry + enough for the header of third.
-			cookie:          1,  // d_next of first
-			expectedBufused: 50, // length to read exactly second and the header of third.
+			bufLen:          50,                    // length is longer than the second entry + enough for the header of third.
+			cookie:          sys.DirentCookie("."), // d_next of first
+			expectedBufused: 50,                    // length to read exactly second and the header of third.
 			expectedMem:     append(direntDotDot, dirent1[0:24]...),
 		},
 		{
@@ -2105,9 +2230,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			bufLen:          53, // length is long enough for second and third.
-			cookie:          1,  // d_next of first
-			expectedBufused: 53, // length to read exactly one second and third.
+			bufLen:          53,                    // length is long enough for second and third.
+			cookie:          sys.DirentCookie("."), // d_next of first
+			expectedBufused: 53,                    // length to read exactly one second and third.
 			expectedMem:     append(direntDotDot, dirent1...),
 		},
 		{
@@ -2118,9 +2243,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 2)
 			},
-			bufLen:          27, // length is long enough for exactly third.
-			cookie:          2,  // d_next of second.
-			expectedBufused: 27, // length to read exactly third.
+			bufLen:          27,                     // length is long enough for exactly third.
+			cookie:          sys.DirentCookie(".."), // d_next of second.
+			expectedBufused: 27,                     // length to read exactly third.
 			expectedMem:     dirent1,
 		},
 		{
@@ -2131,9 +2256,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 2)
 			},
-			bufLen:          300, // length is long enough for third and more
-			cookie:          2,   // d_next of second.
-			expectedBufused: 78,  // length to read the rest
+			bufLen:          300,                    // length is long enough for third and more
+			cookie:          sys.DirentCookie(".."), // d_next of second.
+			expectedBufused: 78,                     // length to read the rest
 			expectedMem:     append(dirent1, dirent2...),
 		},
 		{
@@ -2144,9 +2269,9 @@ func Test_fdReaddir(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 5)
 			},
-			bufLen:          300, // length is long enough for third and more
-			cookie:          5,   // d_next after entries.
-			expectedBufused: 0,   // nothing read
+			bufLen:          300,                     // length is long enough for third and more
+			cookie:          sys.DirentCookie("ab-"), // d_next of last.
+			expectedBufused: 0,                       // nothing read
 		},
 	}
 
@@ -2168,7 +2293,7 @@ func Test_fdReaddir(t *testing.T) {
 			resultBufused := uint32(0) // where to write the amount used out of bufLen
 			buf := uint32(8)           // where to start the dirents
 			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReaddirName,
-				uint64(fd), uint64(buf), uint64(tc.bufLen), uint64(tc.cookie), uint64(resultBufused))
+				uint64(fd), uint64(buf), uint64(tc.bufLen), tc.cookie, uint64(resultBufused))
 
 			// read back the bufused and compare memory against it
 			bufused, ok := mod.Memory().ReadUint32Le(resultBufused)
@@ -2224,18 +2349,148 @@ func Test_fdReaddir_Rewind(t *testing.T) {
 	bufused = fdReaddir(0)
 	require.Equal(t, dotDirentsLen+fileDirentLen, bufused)
 
-	// Read it again, using the file position.
-	bufused = fdReaddir(2)
+	// Read it again, using the cookie of the last dot entry.
+	dotDotCookie := sys.DirentCookie("..")
+	bufused = fdReaddir(dotDotCookie)
 	require.Equal(t, fileDirentLen, bufused)
 
-	require.Equal(t, `
+	require.Equal(t, fmt.Sprintf(`
 ==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=256,cookie=0)
 <== (bufused=51,errno=ESUCCESS)
 ==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=256,cookie=0)
 <== (bufused=79,errno=ESUCCESS)
-==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=256,cookie=2)
+==> wasi_snapshot_preview1.fd_readdir(fd=4,buf=8,buf_len=256,cookie=%d)
 <== (bufused=28,errno=ESUCCESS)
-`, "\n"+log.String())
+`, dotDotCookie), "\n"+log.String())
+}
+
+// Test_fdReaddir_largeDirectory ensures paging through a directory larger
+// than the buffer returns each entry exactly once, like wasi-libc readdir,
+// even when the directory is modified while being read.
+func Test_fdReaddir_largeDirectory(t *testing.T) {
+	const fileCount = 10_001
+
+	tmpDir := t.TempDir()
+	for i := 0; i < fileCount; i++ {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, fmt.Sprintf("file%05d", i)), nil, 0o0666))
+	}
+
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(tmpDir, "/")))
+	defer r.Close(testCtx)
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	mem := mod.Memory()
+
+	// readdir reads entries starting at the cookie into a 4KiB buffer. It
+	// returns the names of all complete entries, and the cookie to continue
+	// from, or zero at the end of the directory.
+	const resultBufused, buf, bufLen = 0, 8, 4096
+	readdir := func(fd int32, cookie uint64) (names []string, next uint64) {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReaddirName,
+			uint64(fd), buf, bufLen, cookie, resultBufused)
+		bufused, ok := mem.ReadUint32Le(resultBufused)
+		require.True(t, ok)
+		dirents, ok := mem.Read(buf, bufused)
+		require.True(t, ok)
+
+		for len(dirents) >= int(wasip1.DirentSize) {
+			dNext := binary.LittleEndian.Uint64(dirents)
+			dNamlen := binary.LittleEndian.Uint32(dirents[16:])
+			entryLen := wasip1.DirentSize + dNamlen
+			if uint32(len(dirents)) < entryLen {
+				break // truncated, so re-read it with the current cookie.
+			}
+			names = append(names, string(dirents[wasip1.DirentSize:entryLen]))
+			cookie = dNext
+			dirents = dirents[entryLen:]
+		}
+		if bufused < bufLen {
+			return names, 0 // EOF
+		}
+		return names, cookie
+	}
+
+	readAll := func(t *testing.T, fd int32, between func()) map[string]int {
+		seen := map[string]int{}
+		for cookie := uint64(0); ; {
+			names, next := readdir(fd, cookie)
+			for _, name := range names {
+				seen[name]++
+			}
+			if next == 0 {
+				return seen
+			}
+			cookie = next
+			if between != nil {
+				between()
+			}
+		}
+	}
+
+	t.Run("each entry once", func(t *testing.T) {
+		fd, errno := fsc.OpenFile(fsc.RootFS(), ".", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		seen := readAll(t, fd, nil)
+		require.Equal(t, fileCount+2, len(seen))
+		for name, count := range seen {
+			require.Equal(t, 1, count, name)
+		}
+	})
+
+	t.Run("concurrent modification", func(t *testing.T) {
+		fd, errno := fsc.OpenFile(fsc.RootFS(), ".", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		// Between reads, add a new file, and remove an existing file which
+		// may or may not have been read yet. POSIX allows either to be
+		// returned or not, but all other entries must be returned once.
+		var added, removed []string
+		seen := readAll(t, fd, func() {
+			name := fmt.Sprintf("new%05d", len(added))
+			require.NoError(t, os.WriteFile(path.Join(tmpDir, name), nil, 0o0666))
+			added = append(added, name)
+
+			name = fmt.Sprintf("file%05d", fileCount-1-len(removed))
+			require.NoError(t, os.Remove(path.Join(tmpDir, name)))
+			removed = append(removed, name)
+		})
+		for name, count := range seen {
+			require.Equal(t, 1, count, name)
+		}
+		modified := map[string]struct{}{}
+		for _, name := range append(added, removed...) {
+			modified[name] = struct{}{}
+		}
+		for i := 0; i < fileCount; i++ {
+			name := fmt.Sprintf("file%05d", i)
+			if _, ok := modified[name]; !ok {
+				require.Equal(t, 1, seen[name], name)
+			}
+		}
+	})
+
+	t.Run("cookie before the cache", func(t *testing.T) {
+		fd, errno := fsc.OpenFile(fsc.RootFS(), ".", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer fsc.CloseFile(fd) //nolint
+
+		first, _ := readdir(fd, 0)
+
+		// Read past the first page, so that it is no longer cached.
+		cookie := uint64(0)
+		for i := 0; i < 10; i++ {
+			_, cookie = readdir(fd, cookie)
+			require.NotEqual(t, uint64(0), cookie)
+		}
+
+		// Re-reading an earlier position, like seekdir, must not fail.
+		again, _ := readdir(fd, sys.DirentCookie(first[2]))
+		require.Equal(t, first[3], again[0])
+	})
 }
 
 func Test_fdReaddir_Errors(t *testing.T) {
@@ -2328,8 +2583,8 @@ func Test_fdReaddir_Errors(t *testing.T) {
 `,
 		},
 		{
-			// cookie should be treated opaquely. When negative, it is a
-			// position not yet read,
+			// cookie should be treated opaquely. When negative, it is not
+			// the cookie of any entry.
 			name: "negative cookie invalid",
 			fd:   dirFD,
 			buf:  0, bufLen: 1000,
@@ -2817,6 +3072,93 @@ func Test_fdWrite(t *testing.T) {
 	require.Equal(t, []byte("wazero"), buf) // verify the file was actually written
 }
 
+// Test_fdWrite_fdPread_pinnedMemory ensures vectored IO, used when memory is
+// pinned, has the same results as Test_fdWrite and Test_fdPread.
+func Test_fdWrite_fdPread_pinnedMemory(t *testing.T) {
+	tmpDir := t.TempDir()
+	pathName := "test_path"
+	require.NoError(t, os.WriteFile(joinPath(tmpDir, pathName), nil, 0o600))
+
+	fsConfig := wazero.NewFSConfig().WithDirMount(tmpDir, "/")
+	ctx := experimental.WithPinnedMemory(testCtx)
+	mod, r, log := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig().WithFSConfig(fsConfig))
+	defer r.Close(testCtx)
+	if _, ok := experimental.MemoryBaseAddress(mod.Memory()); !ok {
+		t.Skip("pinned memory unsupported on this platform")
+	}
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	fd, errno := fsc.OpenFile(fsc.RootFS(), pathName, experimentalsys.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+
+	iovs := uint32(1) // arbitrary offset
+	initialMemory := []byte{
+		'?',         // `iovs` is after this
+		18, 0, 0, 0, // = iovs[0].offset
+		4, 0, 0, 0, // = iovs[0].length
+		23, 0, 0, 0, // = iovs[1].offset
+		2, 0, 0, 0, // = iovs[1].length
+		'?',                // iovs[0].offset is after this
+		'w', 'a', 'z', 'e', // iovs[0].length bytes
+		'?',      // iovs[1].offset is after this
+		'r', 'o', // iovs[1].length bytes
+		'?',
+	}
+	iovsCount := uint32(2) // The count of iovs
+	resultN := uint32(26)  // arbitrary offset
+
+	maskMemory(t, mod, len(initialMemory)+5)
+	ok := mod.Memory().Write(0, initialMemory)
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(fd), uint64(iovs), uint64(iovsCount), uint64(resultN))
+	buf, err := os.ReadFile(joinPath(tmpDir, pathName))
+	require.NoError(t, err)
+	require.Equal(t, []byte("wazero"), buf) // verify the file was actually written
+
+	// Clear the buffers, then read the file back into them, at offset 2.
+	ok = mod.Memory().Write(18, []byte{'?', '?', '?', '?', '?', '?', '?'})
+	require.True(t, ok)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdPreadName, uint64(fd), uint64(iovs), uint64(iovsCount), 2, uint64(resultN))
+	require.Equal(t, `
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=1,iovs_len=2)
+<== (nwritten=6,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_pread(fd=4,iovs=1,iovs_len=2,offset=2)
+<== (nread=4,errno=ESUCCESS)
+`, "\n"+log.String())
+
+	actual, ok := mod.Memory().Read(18, 9)
+	require.True(t, ok)
+	require.Equal(t, []byte{'z', 'e', 'r', 'o', '?', '?', '?', '?', 4}, actual)
+}
+
+func Test_fdWrite_fdRead_ioRateLimit(t *testing.T) {
+	var stdout bytes.Buffer
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+		WithStdin(bytes.NewReader([]byte("wazero"))).
+		WithStdout(&stdout).
+		WithIORateLimit(1<<20, 0))
+	defer r.Close(testCtx)
+
+	iovs, iovsCount, resultN := uint32(0), uint32(1), uint32(8)
+	buf := uint32(16)
+	ok := mod.Memory().Write(iovs, []byte{
+		byte(buf), 0, 0, 0, // = iovs[0].offset
+		6, 0, 0, 0, // = iovs[0].length
+	})
+	require.True(t, ok)
+
+	// Read "wazero" from stdin into memory, then write it to stdout.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdReadName, uint64(sys.FdStdin), uint64(iovs), uint64(iovsCount), uint64(resultN))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdWriteName, uint64(sys.FdStdout), uint64(iovs), uint64(iovsCount), uint64(resultN))
+	require.Equal(t, "wazero", stdout.String())
+
+	m, ok := experimental.GetRateLimitMetrics(mod)
+	require.True(t, ok)
+	require.Equal(t, experimental.RateLimitMetrics{IOBytes: 12}, m)
+}
+
 func Test_fdWrite_Errors(t *testing.T) {
 	tmpDir := t.TempDir() // open before loop to ensure no locking problems.
 	pathName := "test_path"
@@ -3084,8 +3426,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(file)) + 1,
 			expectedMemory: append(
 				initialMemoryFile,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xcc, 0xdd, 0x0, 0xf5, 0xa1, 0x2c, 0x99, 0x97, // ino
 				4, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				30, 0, 0, 0, 0, 0, 0, 0, // size
@@ -3106,8 +3448,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(fileInDir)) + 1,
 			expectedMemory: append(
 				initialMemoryFileInDir,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0x90, 0xcc, 0xf2, 0x22, 0x6c, 0xd0, 0xca, 0x4f, // ino
 				4, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				14, 0, 0, 0, 0, 0, 0, 0, // size
@@ -3128,8 +3470,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(dir)) + 1,
 			expectedMemory: append(
 				initialMemoryDir,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xf5, 0xc2, 0xf, 0x5d, 0x19, 0x9d, 0x71, 0x82, // ino
 				3, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				0, 0, 0, 0, 0, 0, 0, 0, // size
@@ -3207,8 +3549,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(file)) + 1,
 			expectedMemory: append(
 				initialMemoryFile,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xcc, 0xdd, 0x0, 0xf5, 0xa1, 0x2c, 0x99, 0x97, // ino
 				4, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				30, 0, 0, 0, 0, 0, 0, 0, // size
@@ -3230,8 +3572,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(fileInDir)) + 1,
 			expectedMemory: append(
 				initialMemoryFileInDir,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0x90, 0xcc, 0xf2, 0x22, 0x6c, 0xd0, 0xca, 0x4f, // ino
 				4, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				14, 0, 0, 0, 0, 0, 0, 0, // size
@@ -3253,8 +3595,8 @@ func Test_pathFilestatGet(t *testing.T) {
 			resultFilestat: uint32(len(dir)) + 1,
 			expectedMemory: append(
 				initialMemoryDir,
-				0, 0, 0, 0, 0, 0, 0, 0, // dev
-				0, 0, 0, 0, 0, 0, 0, 0, // ino
+				0x25, 0x23, 0x22, 0x84, 0xe4, 0x9c, 0xf2, 0xcb, // dev
+				0xf5, 0xc2, 0xf, 0x5d, 0x19, 0x9d, 0x71, 0x82, // ino
 				3, 0, 0, 0, 0, 0, 0, 0, // filetype + padding
 				1, 0, 0, 0, 0, 0, 0, 0, // nlink
 				0, 0, 0, 0, 0, 0, 0, 0, // size
@@ -4914,14 +5256,14 @@ func Test_fdReaddir_dotEntryHasARealInode(t *testing.T) {
 	// get the real inode of the current directory
 	st, errno := preopen.Stat(readDirTarget)
 	require.EqualErrno(t, 0, errno)
-	dirents := []byte{1, 0, 0, 0, 0, 0, 0, 0}         // d_next = 1
+	dirents := u64.LeBytes(sys.DirentCookie("."))     // d_next
 	dirents = append(dirents, u64.LeBytes(st.Ino)...) // d_ino
 	dirents = append(dirents, 1, 0, 0, 0)             // d_namlen = 1 character
 	dirents = append(dirents, 3, 0, 0, 0)             // d_type = directory
 	dirents = append(dirents, '.')                    // name
 
 	require.EqualErrno(t, 0, errno)
-	dirents = append(dirents, 2, 0, 0, 0, 0, 0, 0, 0) // d_next = 2
+	dirents = append(dirents, u64.LeBytes(sys.DirentCookie(".."))...) // d_next
 	// See /RATIONALE.md for why we don't attempt to get an inode for ".."
 	dirents = append(dirents, 0, 0, 0, 0, 0, 0, 0, 0) // d_ino
 	dirents = append(dirents, 2, 0, 0, 0)             // d_namlen = 2 characters
@@ -4976,7 +5318,7 @@ func Test_fdReaddir_opened_file_written(t *testing.T) {
 	// get the real inode of the current directory
 	st, errno := preopen.Stat(dirName)
 	require.EqualErrno(t, 0, errno)
-	dirents := []byte{1, 0, 0, 0, 0, 0, 0, 0}         // d_next = 1
+	dirents := u64.LeBytes(sys.DirentCookie("."))     // d_next
 	dirents = append(dirents, u64.LeBytes(st.Ino)...) // d_ino
 	dirents = append(dirents, 1, 0, 0, 0)             // d_namlen = 1 character
 	dirents = append(dirents, 3, 0, 0, 0)             // d_type = directory
@@ -4985,7 +5327,7 @@ func Test_fdReaddir_opened_file_written(t *testing.T) {
 	// get the real inode of the parent directory
 	st, errno = preopen.Stat(".")
 	require.EqualErrno(t, 0, errno)
-	dirents = append(dirents, 2, 0, 0, 0, 0, 0, 0, 0) // d_next = 2
+	dirents = append(dirents, u64.LeBytes(sys.DirentCookie(".."))...) // d_next
 	// See /RATIONALE.md for why we don't attempt to get an inode for ".."
 	dirents = append(dirents, 0, 0, 0, 0, 0, 0, 0, 0) // d_ino
 	dirents = append(dirents, 2, 0, 0, 0)             // d_namlen = 2 characters
@@ -4995,11 +5337,11 @@ func Test_fdReaddir_opened_file_written(t *testing.T) {
 	// get the real inode of the file
 	st, errno = f.Stat()
 	require.EqualErrno(t, 0, errno)
-	dirents = append(dirents, 3, 0, 0, 0, 0, 0, 0, 0) // d_next = 3
-	dirents = append(dirents, u64.LeBytes(st.Ino)...) // d_ino
-	dirents = append(dirents, 4, 0, 0, 0)             // d_namlen = 4 characters
-	dirents = append(dirents, 4, 0, 0, 0)             // d_type = regular_file
-	dirents = append(dirents, 'f', 'i', 'l', 'e')     // name
+	dirents = append(dirents, u64.LeBytes(sys.DirentCookie("file"))...) // d_next
+	dirents = append(dirents, u64.LeBytes(st.Ino)...)                   // d_ino
+	dirents = append(dirents, 4, 0, 0, 0)                               // d_namlen = 4 characters
+	dirents = append(dirents, 4, 0, 0, 0)                               // d_type = regular_file
+	dirents = append(dirents, 'f', 'i', 'l', 'e')                       // name
 
 	// Try to list them!
 	resultBufused := uint32(0) // where to write the amount used out of bufLen
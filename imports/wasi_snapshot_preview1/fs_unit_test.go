@@ -9,6 +9,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/u64"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 )
 
@@ -121,27 +122,24 @@ var (
 		return dirents
 	}()
 
-	dirent1 = []byte{
-		1, 0, 0, 0, 0, 0, 0, 0, // d_next = 1
+	dirent1 = append(u64.LeBytes(sys.DirentCookie("-")), // d_next
 		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
 		1, 0, 0, 0, // d_namlen = 1 character
 		4, 0, 0, 0, // d_type = regular_file
 		'-', // name
-	}
-	dirent2 = []byte{
-		2, 0, 0, 0, 0, 0, 0, 0, // d_next = 2
+	)
+	dirent2 = append(u64.LeBytes(sys.DirentCookie("a-")), // d_next
 		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
 		2, 0, 0, 0, // d_namlen = 1 character
 		3, 0, 0, 0, // d_type =  directory
 		'a', '-', // name
-	}
-	dirent3 = []byte{
-		3, 0, 0, 0, 0, 0, 0, 0, // d_next = 3
+	)
+	dirent3 = append(u64.LeBytes(sys.DirentCookie("ab-")), // d_next
 		0, 0, 0, 0, 0, 0, 0, 0, // d_ino = 0
 		3, 0, 0, 0, // d_namlen = 3 characters
 		4, 0, 0, 0, // d_type = regular_file
 		'a', 'b', '-', // name
-	}
+	)
 )
 
 func Test_writeDirents(t *testing.T) {
@@ -194,9 +192,8 @@ func Test_writeDirents(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			d_next := uint64(1)
 			buf := make([]byte, len(tc.expected))
-			writeDirents(buf, tc.dirents, d_next, tc.entryCount, tc.truncatedLen)
+			writeDirents(buf, tc.dirents, tc.entryCount, tc.truncatedLen)
 			require.Equal(t, tc.expected, buf)
 		})
 	}
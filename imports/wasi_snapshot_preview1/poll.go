@@ -2,9 +2,11 @@ package wasi_snapshot_preview1
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
@@ -29,11 +31,22 @@ import (
 //   - sys.ENOTSUP: a parameters is valid, but not yet supported.
 //   - sys.EFAULT: there is not enough memory to read the subscriptions or
 //     write results.
+//   - sys.EINTR: the timeout was cut short as the context.Context of the
+//     call is done.
 //
 // # Notes
 //
-//   - Since the `out` pointer nests Errno, the result is always 0.
+//   - Since the `out` pointer nests Errno, errors of each subscription are
+//     written to its event instead of the result.
 //   - This is similar to `poll` in POSIX.
+//   - The timeout is shortened to the deadline of the context.Context of the
+//     call, and by experimental.SleepHook if set. If the module closes when
+//     the context is done, e.g. wazero.RuntimeConfig WithCloseOnContextDone,
+//     this exits with the corresponding sys.ExitError instead of sys.EINTR.
+//   - Reading event files, such as the watch descriptor of
+//     experimental/dynconfig, is only ready once the host notified them.
+//     Likewise, reading pipes of experimental/pipe is only ready once the
+//     other end wrote to, or closed, them.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#poll_oneoff
 // See https://linux.die.net/man/3/poll
@@ -49,7 +62,13 @@ type event struct {
 	errno     wasip1.Errno
 }
 
-func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+func pollOneoffFn(ctx context.Context, mod api.Module, params []uint64) sys.Errno {
+	return pollOneoffFunc(ctx, mod, params, false)
+}
+
+// pollOneoffFunc implements pollOneoffFn, or its variant in wasi_unstable,
+// whose clock subscriptions begin with an identifier, so are 56 bytes.
+func pollOneoffFunc(ctx context.Context, mod api.Module, params []uint64, unstable bool) sys.Errno {
 	in := uint32(params[0])
 	out := uint32(params[1])
 	nsubscriptions := uint32(params[2])
@@ -59,10 +78,15 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		return sys.EINVAL
 	}
 
+	subscriptionSize, clockOffset := uint32(48), uint32(0)
+	if unstable {
+		subscriptionSize, clockOffset = 56, 8 // +8 past identifier
+	}
+
 	mem := mod.Memory()
 
 	// Ensure capacity prior to the read loop to reduce error handling.
-	inBuf, ok := mem.Read(in, nsubscriptions*48)
+	inBuf, ok := mem.Read(in, nsubscriptions*subscriptionSize)
 	if !ok {
 		return sys.EFAULT
 	}
@@ -88,6 +112,9 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	// Slice of events that are processed out of the loop (blocking stdin subscribers).
 	var blockingStdinSubs []*event
+	// Slice of events on blocking event files which weren't ready, e.g. from
+	// experimental/dynconfig. These are also processed out of the loop.
+	var blockingEventSubs []*eventFileSub
 	// The timeout is initialized at max Duration, the loop will find the minimum.
 	var timeout time.Duration = 1<<63 - 1
 	// Count of all the subscriptions that have been already written back to outBuf.
@@ -98,7 +125,7 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	// Layout is subscription_u: Union
 	// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#subscription_u
 	for i := uint32(0); i < nsubscriptions; i++ {
-		inOffset := i * 48
+		inOffset := i * subscriptionSize
 		outOffset := nevents * 32
 
 		eventType := inBuf[inOffset+8] // +8 past userdata
@@ -114,7 +141,7 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 
 		switch eventType {
 		case wasip1.EventTypeClock: // handle later
-			newTimeout, err := processClockEvent(argBuf)
+			newTimeout, err := processClockEvent(argBuf[clockOffset:])
 			if err != 0 {
 				return err
 			}
@@ -137,6 +164,16 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 			} else if fd != internalsys.FdStdin && file.File.IsNonblock() {
 				writeEvent(outBuf[outOffset:], evt)
 				nevents++
+			} else if isEventFile(file.File) {
+				// Unlike other files, event files are only ready when notified.
+				if ready, errno := file.File.Poll(fsapi.POLLIN, 0); errno != 0 {
+					return errno
+				} else if ready {
+					writeEvent(outBuf[outOffset:], evt)
+					nevents++
+				} else {
+					blockingEventSubs = append(blockingEventSubs, &eventFileSub{evt, file.File})
+				}
 			} else {
 				// if the fd is Stdin, and it is in blocking mode,
 				// do not ack yet, append to a slice for delayed evaluation.
@@ -159,6 +196,8 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		}
 	}
 
+	timeout, capped := capTimeout(ctx, timeout)
+
 	sysCtx := mod.(*wasm.ModuleInstance).Sys
 	if nevents == nsubscriptions {
 		// We already wrote back all the results. We already wrote this number
@@ -168,7 +207,7 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		if timeout > 0 {
 			sysCtx.Nanosleep(int64(timeout))
 		}
-		return 0
+		return ctxDoneErrno(ctx, mod, capped)
 	}
 
 	// If there are blocking stdin subscribers, check for data with given timeout.
@@ -178,7 +217,12 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	}
 	// Wait for the timeout to expire, or for some data to become available on Stdin.
 
-	if stdinReady, errno := stdin.File.Poll(fsapi.POLLIN, int32(timeout.Milliseconds())); errno != 0 {
+	if len(blockingEventSubs) > 0 {
+		var errno sys.Errno
+		if nevents, errno = pollEventFiles(outBuf, nevents, stdin.File, blockingStdinSubs, blockingEventSubs, timeout); errno != 0 {
+			return errno
+		}
+	} else if stdinReady, errno := stdin.File.Poll(fsapi.POLLIN, int32(timeout.Milliseconds())); errno != 0 {
 		return errno
 	} else if stdinReady {
 		// stdin has data ready to for reading, write back all the events
@@ -196,7 +240,114 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 		}
 	}
 
-	return 0
+	return ctxDoneErrno(ctx, mod, capped)
+}
+
+// isEventFile returns true if the file is only readable once the host, or
+// another module, writes to it: the read end of an internalsys.NewPipe, or an
+// internalsys.EventFile, including files which embed one, such as the watch
+// of experimental/mailbox.
+func isEventFile(f fsapi.File) bool {
+	if _, ok := f.(*internalsys.PipeReader); ok {
+		return true
+	}
+	_, ok := f.(interface{ Notify() bool })
+	return ok
+}
+
+// eventFileSub is a subscription to read a file where isEventFile is true.
+type eventFileSub struct {
+	evt  *event
+	file fsapi.File
+}
+
+// eventFilePollInterval is the longest time pollEventFiles blocks on one file
+// when it must also check others.
+const eventFilePollInterval = 10 * time.Millisecond
+
+// pollEventFiles waits up to `timeout` for any of the event files, or stdin
+// if there are `stdinSubs`, to be ready. The events of those which are ready
+// are written to `outBuf`, starting at `nevents`, and the new count of
+// events is returned.
+func pollEventFiles(outBuf []byte, nevents uint32, stdin fsapi.File, stdinSubs []*event, eventSubs []*eventFileSub, timeout time.Duration) (uint32, sys.Errno) {
+	for {
+		ready := false
+		for _, sub := range eventSubs {
+			if r, errno := sub.file.Poll(fsapi.POLLIN, 0); errno != 0 {
+				return nevents, errno
+			} else if r {
+				writeEvent(outBuf[nevents*32:], sub.evt)
+				nevents++
+				ready = true
+			}
+		}
+		if len(stdinSubs) > 0 {
+			if r, errno := stdin.Poll(fsapi.POLLIN, 0); errno != 0 {
+				return nevents, errno
+			} else if r {
+				for _, evt := range stdinSubs {
+					writeEvent(outBuf[nevents*32:], evt)
+					nevents++
+				}
+				ready = true
+			}
+		}
+		if ready || timeout <= 0 {
+			return nevents, 0
+		}
+
+		// Block on the first event file, waking periodically if there are
+		// other files to check.
+		wait := timeout
+		if (len(stdinSubs) > 0 || len(eventSubs) > 1) && wait > eventFilePollInterval {
+			wait = eventFilePollInterval
+		}
+		waitMillis := int32(math.MaxInt32)
+		if ms := wait.Milliseconds(); ms < math.MaxInt32 {
+			waitMillis = int32(ms)
+		}
+		start := time.Now()
+		if _, errno := eventSubs[0].file.Poll(fsapi.POLLIN, waitMillis); errno != 0 {
+			return nevents, errno
+		}
+		timeout -= time.Since(start)
+	}
+}
+
+// capTimeout applies the experimental.SleepHook in `ctx`, if any, and the
+// deadline of `ctx` to `timeout`. `capped` is true if the deadline shortened
+// it.
+func capTimeout(ctx context.Context, timeout time.Duration) (_ time.Duration, capped bool) {
+	if hook, ok := ctx.Value(experimental.SleepHookKey{}).(experimental.SleepHook); ok && hook != nil && timeout > 0 {
+		if d := hook(ctx, timeout); d < timeout {
+			timeout = d
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if d := time.Until(deadline); d < timeout {
+			timeout, capped = d, true
+		}
+	}
+	if timeout < 0 {
+		timeout = 0
+	}
+	return timeout, capped
+}
+
+// ctxDoneErrno returns sys.EINTR if the timeout was `capped` to the deadline
+// of `ctx`, which has passed. If the module closes when the context is done,
+// this closes it and exits instead.
+func ctxDoneErrno(ctx context.Context, mod api.Module, capped bool) sys.Errno {
+	if !capped || ctx.Err() == nil {
+		return 0
+	}
+	if m := mod.(*wasm.ModuleInstance); m.CloseOnContextDone {
+		// Close now, instead of racing the goroutine which closes the module
+		// when the context is done.
+		m.CloseWithCtxErr(ctx)
+		panic(m.FailIfClosed())
+	}
+	return sys.EINTR
 }
 
 // processClockEvent supports only relative name events, as that's what's used
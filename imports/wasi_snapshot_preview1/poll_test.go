@@ -1,6 +1,7 @@
 package wasi_snapshot_preview1_test
 
 import (
+	"context"
 	"io/fs"
 	"os"
 	"strings"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/sys"
@@ -538,6 +540,90 @@ func Test_pollOneoff_Zero(t *testing.T) {
 	require.Equal(t, uint32(1), nevents)
 }
 
+func Test_pollOneoff_eventFile(t *testing.T) {
+	tests := []struct {
+		name            string
+		notify          func(*sys.EventFile)
+		timeout         uint64
+		expectedNevents uint32
+	}{
+		{
+			name:            "not ready",
+			notify:          func(*sys.EventFile) {},
+			timeout:         uint64(20 * time.Millisecond),
+			expectedNevents: 1, // only the clock
+		},
+		{
+			name:            "ready",
+			notify:          func(f *sys.EventFile) { f.Notify() },
+			timeout:         uint64(time.Hour),
+			expectedNevents: 2,
+		},
+		{
+			name: "ready while waiting",
+			notify: func(f *sys.EventFile) {
+				go func() {
+					time.Sleep(10 * time.Millisecond)
+					f.Notify()
+				}()
+			},
+			timeout:         uint64(time.Hour),
+			expectedNevents: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig())
+			defer r.Close(testCtx)
+
+			f := sys.NewEventFile()
+			fd, errno := mod.(*wasm.ModuleInstance).Sys.FS().InsertFile(f, "event")
+			require.EqualErrno(t, 0, errno)
+			tc.notify(f)
+
+			const out, nsubscriptions, resultNevents = 128, 2, 512
+			mod.Memory().Write(0, concat(clockNsSub(tc.timeout), fdReadSubFd(byte(fd))))
+
+			start := time.Now()
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName, uint64(0), uint64(out),
+				uint64(nsubscriptions), uint64(resultNevents))
+			require.True(t, time.Since(start) < time.Minute)
+
+			nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+			require.True(t, ok)
+			require.Equal(t, tc.expectedNevents, nevents)
+		})
+	}
+}
+
+func Test_pollOneoff_pipe(t *testing.T) {
+	mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	pr, pw := sys.NewPipe(8)
+	fd, errno := mod.(*wasm.ModuleInstance).Sys.FS().InsertFile(pr, "pipe")
+	require.EqualErrno(t, 0, errno)
+
+	const out, nsubscriptions, resultNevents = 128, 2, 512
+	mod.Memory().Write(0, concat(clockNsSub(uint64(20*time.Millisecond)), fdReadSubFd(byte(fd))))
+
+	poll := func() uint32 {
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName, uint64(0), uint64(out),
+			uint64(nsubscriptions), uint64(resultNevents))
+		nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+		require.True(t, ok)
+		return nevents
+	}
+
+	require.Equal(t, uint32(1), poll()) // only the clock
+
+	_, errno = pw.Write([]byte("hi"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint32(2), poll())
+}
+
 func concat(bytes ...[]byte) []byte {
 	var res []byte
 	for i := range bytes {
@@ -546,6 +632,87 @@ func concat(bytes ...[]byte) []byte {
 	return res
 }
 
+func Test_pollOneoff_sleepLimits(t *testing.T) {
+	const hour = uint64(time.Hour)
+	expired, cancel := context.WithDeadline(testCtx, time.Now().Add(-time.Second))
+	defer cancel()
+	inAnHour, cancel := context.WithTimeout(testCtx, time.Hour)
+	defer cancel()
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		timeout       uint64
+		expectedSlept []int64
+		expectedErrno wasip1.Errno
+	}{
+		{
+			name:          "no deadline",
+			ctx:           testCtx,
+			timeout:       10 * hour,
+			expectedSlept: []int64{int64(10 * hour)},
+		},
+		{
+			name:          "deadline expired",
+			ctx:           expired,
+			timeout:       10 * hour,
+			expectedErrno: wasip1.ErrnoIntr,
+		},
+		{
+			name: "hook shortens",
+			ctx: experimental.WithSleepHook(testCtx, func(_ context.Context, d time.Duration) time.Duration {
+				return d / 2
+			}),
+			timeout:       10 * hour,
+			expectedSlept: []int64{int64(5 * hour)},
+		},
+		{
+			name: "hook vetoes",
+			ctx: experimental.WithSleepHook(testCtx, func(context.Context, time.Duration) time.Duration {
+				return 0
+			}),
+			timeout: 10 * hour,
+		},
+		{
+			name: "hook can't lengthen",
+			ctx: experimental.WithSleepHook(testCtx, func(_ context.Context, d time.Duration) time.Duration {
+				return 2 * d
+			}),
+			timeout:       hour,
+			expectedSlept: []int64{int64(hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			var slept []int64
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+				WithNanosleep(func(ns int64) { slept = append(slept, ns) }))
+			defer r.Close(testCtx)
+
+			mod.Memory().Write(0, clockNsSub(tc.timeout))
+			results, err := mod.ExportedFunction(wasip1.PollOneoffName).Call(tc.ctx, 0, 128, 1, 512)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedErrno, wasip1.Errno(results[0]))
+			require.Equal(t, tc.expectedSlept, slept)
+		})
+	}
+
+	t.Run("deadline shortens", func(t *testing.T) {
+		var slept int64
+		mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().
+			WithNanosleep(func(ns int64) { slept = ns }))
+		defer r.Close(testCtx)
+
+		mod.Memory().Write(0, clockNsSub(10*hour))
+		results, err := mod.ExportedFunction(wasip1.PollOneoffName).Call(inAnHour, 0, 128, 1, 512)
+		require.NoError(t, err)
+		require.Equal(t, wasip1.ErrnoSuccess, wasip1.Errno(results[0])) // the fake sleep didn't reach the deadline
+		require.True(t, slept <= int64(hour) && slept > int64(hour-uint64(time.Minute)))
+	})
+}
+
 // subscription for a given timeout in ns
 func clockNsSub(ns uint64) []byte {
 	return []byte{
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/sys"
@@ -38,7 +40,33 @@ func procExitFn(ctx context.Context, mod api.Module, params []uint64) {
 	panic(sys.NewExitError(exitCode))
 }
 
-// procRaise is stubbed and will never be supported, as it was removed.
+// procRaise is the WASI function named ProcRaiseName which sends a signal to
+// the module. It was removed after wasi_snapshot_preview1, but older
+// versions of wasi-libc use it to implement `raise`.
 //
+// # Parameters
+//
+//   - sig: signal number, e.g. SIGINT (2)
+//
+// Result (Errno)
+//
+// The return value is 0 except the following error conditions:
+//   - sys.EINVAL: `sig` is not a valid signal number
+//
+// If the guest exports the signal handler configured with
+// wazero.ModuleConfig WithSignalHandler, this calls it with `sig`, except
+// for SIGKILL. Otherwise, signals whose default action is to terminate the
+// process exit the module with code 128+sig, and others are ignored.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-proc_raisesig-signal---errno
 // See https://github.com/WebAssembly/WASI/pull/136
-var procRaise = stubFunction(wasip1.ProcRaiseName, []api.ValueType{i32}, "sig")
+var procRaise = newHostFunc(wasip1.ProcRaiseName, procRaiseFn, []api.ValueType{i32}, "sig")
+
+func procRaiseFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	sig := uint32(params[0])
+	if errno := internalsys.ValidateSignal(sig); errno != 0 {
+		return errno
+	}
+	mod.(*wasm.ModuleInstance).Signal(ctx, sig)
+	return 0
+}
@@ -51,11 +51,55 @@ func Test_procExit(t *testing.T) {
 	}
 }
 
-// Test_procRaise only tests it is stubbed for GrainLang per #271
 func Test_procRaise(t *testing.T) {
-	log := requireErrnoNosys(t, wasip1.ProcRaiseName, 0)
-	require.Equal(t, `
+	tests := []struct {
+		name             string
+		sig              uint64
+		expectedExitCode uint32
+		expectedLog      string
+	}{
+		{
+			name: "invalid",
+			sig:  0,
+			expectedLog: `
 ==> wasi_snapshot_preview1.proc_raise(sig=0)
-<== errno=ENOSYS
-`, log)
+<== errno=EINVAL
+`,
+		},
+		{
+			name: "ignored by default",
+			sig:  27, // SIGWINCH
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=27)
+<== errno=ESUCCESS
+`,
+		},
+		{
+			name:             "terminates by default",
+			sig:              2, // SIGINT
+			expectedExitCode: 130,
+			expectedLog: `
+==> wasi_snapshot_preview1.proc_raise(sig=2)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
+			defer r.Close(testCtx)
+
+			_, err := mod.ExportedFunction(wasip1.ProcRaiseName).Call(testCtx, tc.sig)
+			if tc.expectedExitCode == 0 {
+				require.NoError(t, err)
+			} else {
+				sysErr, ok := err.(*sys.ExitError)
+				require.True(t, ok, err)
+				require.Equal(t, tc.expectedExitCode, sysErr.ExitCode())
+			}
+			require.Equal(t, tc.expectedLog, "\n"+log.String())
+		})
+	}
 }
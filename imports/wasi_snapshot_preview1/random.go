@@ -5,6 +5,7 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -22,8 +23,14 @@ import (
 //
 // The return value is ErrnoSuccess except the following error conditions:
 //   - sys.EFAULT: `buf` or `bufLen` point to an offset out of memory
+//   - sys.EINVAL: `bufLen` exceeds the limit of wazero.ModuleConfig
+//     WithRandLimit
 //   - sys.EIO: a file system error
 //
+// If wazero.ModuleConfig WithRandLimit limits the bytes per second, this
+// waits until the limit allows `bufLen` bytes. Each call is reported to the
+// experimental.RandomHook of the context.Context, if any.
+//
 // For example, if underlying random source was seeded like
 // `rand.NewSource(42)`, we expect api.Memory to contain:
 //
@@ -36,16 +43,30 @@ import (
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-random_getbuf-pointeru8-bufLen-size---errno
 var randomGet = newHostFunc(wasip1.RandomGetName, randomGetFn, []api.ValueType{i32, i32}, "buf", "buf_len")
 
-func randomGetFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
+func randomGetFn(ctx context.Context, mod api.Module, params []uint64) (errno sys.Errno) {
 	sysCtx := mod.(*wasm.ModuleInstance).Sys
 	randSource := sysCtx.RandSource()
 	buf, bufLen := uint32(params[0]), uint32(params[1])
 
+	if hook, ok := ctx.Value(experimental.RandomHookKey{}).(experimental.RandomHook); ok && hook != nil {
+		defer func() {
+			var err error
+			if errno != 0 {
+				err = errno
+			}
+			hook(ctx, mod, bufLen, err)
+		}()
+	}
+
 	randomBytes, ok := mod.Memory().Read(buf, bufLen)
 	if !ok { // out-of-range
 		return sys.EFAULT
 	}
 
+	if errno = sysCtx.ThrottleRandom(ctx, int64(bufLen)); errno != 0 {
+		return errno
+	}
+
 	// We can ignore the returned n as it only != byteCount on error
 	if _, err := io.ReadAtLeast(randSource, randomBytes, int(bufLen)); err != nil {
 		return sys.EIO
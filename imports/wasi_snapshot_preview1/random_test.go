@@ -2,12 +2,16 @@ package wasi_snapshot_preview1_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	"testing"
 	"testing/iotest"
 
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 )
@@ -118,3 +122,47 @@ func Test_randomGet_SourceError(t *testing.T) {
 		})
 	}
 }
+
+func Test_randomGet_RandLimit(t *testing.T) {
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithRandLimit(4, 0))
+	defer r.Close(testCtx)
+
+	type call struct {
+		n   uint32
+		err error
+	}
+	var calls []call
+	ctx := experimental.WithRandomHook(testCtx, func(_ context.Context, _ api.Module, n uint32, err error) {
+		calls = append(calls, call{n, err})
+	})
+
+	randomGet := mod.ExportedFunction(wasip1.RandomGetName)
+	for _, n := range []uint64{5, 4} {
+		_, err := randomGet.Call(ctx, 0, n)
+		require.NoError(t, err)
+	}
+	require.Equal(t, `
+==> wasi_snapshot_preview1.random_get(buf=0,buf_len=5)
+<== errno=EINVAL
+==> wasi_snapshot_preview1.random_get(buf=0,buf_len=4)
+<== errno=ESUCCESS
+`, "\n"+log.String())
+	require.Equal(t, []call{{5, experimentalsys.EINVAL}, {4, nil}}, calls)
+}
+
+func Test_randomGet_RandSeed(t *testing.T) {
+	read := func(config wazero.ModuleConfig) []byte {
+		mod, r, _ := requireProxyModule(t, config)
+		defer r.Close(testCtx)
+
+		requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.RandomGetName, 0, 16)
+		buf, ok := mod.Memory().Read(0, 16)
+		require.True(t, ok)
+		return append([]byte(nil), buf...)
+	}
+
+	a := read(wazero.NewModuleConfig().WithRandSeed([]byte("a")))
+	require.Equal(t, a, read(wazero.NewModuleConfig().WithRandSeed([]byte("a"))))
+	require.NotEqual(t, a, read(wazero.NewModuleConfig().WithRandSeed([]byte("b"))))
+	require.NotEqual(t, a, read(wazero.NewModuleConfig())) // the fake source
+}
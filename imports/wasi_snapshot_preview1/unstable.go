@@ -0,0 +1,122 @@
+package wasi_snapshot_preview1
+
+import (
+	"context"
+	"io"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	sysapi "github.com/tetratelabs/wazero/sys"
+)
+
+// UnstableModuleName is the module name of the legacy WASI snapshot, which
+// modules built by old toolchains import functions from.
+//
+// See https://github.com/WebAssembly/WASI/blob/main/legacy/preview0/docs.md
+const UnstableModuleName = "wasi_unstable"
+
+// NewUnstableBuilder returns a new Builder of the UnstableModuleName module.
+//
+// Its functions are those of ModuleName, except where the legacy snapshot
+// differs, which are adapted:
+//
+//   - "fd_seek" numbers `whence` differently: SEEK_CUR=0, SEEK_END=1 and
+//     SEEK_SET=2.
+//   - "fd_filestat_get" and "path_filestat_get" write a 56-byte filestat,
+//     whose nlink is 32-bit.
+//   - "poll_oneoff" reads 56-byte subscriptions, as clock subscriptions
+//     begin with an identifier.
+//
+// For example, this runs a module which imports "wasi_unstable":
+//
+//	wasi_snapshot_preview1.NewUnstableBuilder(r).Instantiate(ctx)
+//	mod, _ := r.Instantiate(ctx, wasm)
+//
+// Note: Both ModuleName and UnstableModuleName can be instantiated in the
+// same runtime, for modules which import either.
+func NewUnstableBuilder(r wazero.Runtime) Builder {
+	return &builder{r: r, unstable: true}
+}
+
+// exportUnstableFunctions adds the functions of UnstableModuleName, which
+// are those of exportFunctions, except those whose signature or layout
+// changed in ModuleName.
+func exportUnstableFunctions(builder wazero.HostModuleBuilder, mode ErrnoMode) {
+	exportFunctions(builder, mode)
+
+	// Subsequent exports replace those of the same name.
+	var exporter wasm.HostFuncExporter = &errnoModeExporter{builder.(wasm.HostFuncExporter), mode}
+	exporter.ExportHostFunc(unstableFdFilestatGet)
+	exporter.ExportHostFunc(unstableFdSeek)
+	exporter.ExportHostFunc(unstablePathFilestatGet)
+	exporter.ExportHostFunc(unstablePollOneoff)
+}
+
+// unstableFdFilestatGet is fdFilestatGet, writing the filestat of
+// UnstableModuleName.
+var unstableFdFilestatGet = newHostFunc(wasip1.FdFilestatGetName, unstableFdFilestatGetFn, []api.ValueType{i32, i32}, "fd", "result.filestat")
+
+func unstableFdFilestatGetFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return fdFilestatGetFunc(mod, int32(params[0]), uint32(params[1]), true)
+}
+
+// unstablePathFilestatGet is pathFilestatGet, writing the filestat of
+// UnstableModuleName.
+var unstablePathFilestatGet = newHostFunc(
+	wasip1.PathFilestatGetName, unstablePathFilestatGetFn,
+	[]api.ValueType{i32, i32, i32, i32, i32},
+	"fd", "flags", "path", "path_len", "result.filestat",
+)
+
+func unstablePathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return pathFilestatGetFunc(mod, params, true)
+}
+
+// writeUnstableFilestat writes the filestat of UnstableModuleName, which
+// differs from that of ModuleName in that nlink is 32-bit.
+func writeUnstableFilestat(buf []byte, st *sysapi.Stat_t, ftype uint8) experimentalsys.Errno {
+	le.PutUint64(buf, st.Dev)
+	le.PutUint64(buf[8:], st.Ino)
+	le.PutUint32(buf[16:], uint32(ftype)) // padded to align nlink
+	le.PutUint32(buf[20:], uint32(st.Nlink))
+	le.PutUint64(buf[24:], uint64(st.Size))
+	le.PutUint64(buf[32:], uint64(st.Atim))
+	le.PutUint64(buf[40:], uint64(st.Mtim))
+	le.PutUint64(buf[48:], uint64(st.Ctim))
+	return 0
+}
+
+// unstableFdSeek is fdSeek, with the `whence` of UnstableModuleName.
+var unstableFdSeek = newHostFunc(
+	wasip1.FdSeekName, unstableFdSeekFn,
+	[]api.ValueType{i32, i64, i32, i32},
+	"fd", "offset", "whence", "result.newoffset",
+)
+
+func unstableFdSeekFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	whence := params[2]
+	switch whence {
+	case 0: // SEEK_CUR
+		whence = io.SeekCurrent
+	case 1: // SEEK_END
+		whence = io.SeekEnd
+	case 2: // SEEK_SET
+		whence = io.SeekStart
+	} // otherwise, fdSeekFn returns EINVAL.
+	return fdSeekFn(ctx, mod, []uint64{params[0], params[1], whence, params[3]})
+}
+
+// unstablePollOneoff is pollOneoff, with the subscriptions of
+// UnstableModuleName.
+var unstablePollOneoff = newHostFunc(
+	wasip1.PollOneoffName, unstablePollOneoffFn,
+	[]api.ValueType{i32, i32, i32, i32},
+	"in", "out", "nsubscriptions", "result.nevents",
+)
+
+func unstablePollOneoffFn(ctx context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	return pollOneoffFunc(ctx, mod, params, true)
+}
@@ -0,0 +1,151 @@
+package wasi_snapshot_preview1_test
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestNewUnstableBuilder(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := wasi_snapshot_preview1.NewUnstableBuilder(r).Instantiate(testCtx)
+	require.NoError(t, err)
+
+	// Both versions can be instantiated side by side.
+	wasi_snapshot_preview1.MustInstantiate(testCtx, r)
+
+	// Instantiate our test binary, which uses the old import names.
+	_, err = r.Instantiate(testCtx, exitOnStartUnstableWasm)
+
+	// Ensure the test binary worked. It should return exit code 2.
+	require.Equal(t, uint32(2), err.(*sys.ExitError).ExitCode())
+}
+
+// requireUnstableProxyModule is like requireProxyModule, except it proxies
+// the wasi_unstable module.
+func requireUnstableProxyModule(t *testing.T, config wazero.ModuleConfig) (api.Module, api.Closer) {
+	r := wazero.NewRuntime(testCtx)
+
+	compiled, err := wasi_snapshot_preview1.NewUnstableBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+
+	_, err = r.InstantiateModule(testCtx, compiled, config)
+	require.NoError(t, err)
+
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.UnstableModuleName, compiled))
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, proxyCompiled, config)
+	require.NoError(t, err)
+	return mod, r
+}
+
+func Test_unstableFdSeek(t *testing.T) {
+	mod, r := requireUnstableProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
+	defer r.Close(testCtx)
+
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	fd, errno := fsc.OpenFile(fsc.RootFS(), "animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	resultNewoffset := uint32(1) // arbitrary offset in api.Memory for the new offset value
+
+	tests := []struct {
+		name           string
+		offset         int64
+		whence         uint64
+		expectedOffset uint64
+		expectedErrno  wasip1.Errno
+	}{
+		{name: "SEEK_SET", offset: 4, whence: 2, expectedOffset: 4},
+		{name: "SEEK_CUR", offset: 1, whence: 0, expectedOffset: 5},
+		{name: "SEEK_END", offset: -1, whence: 1, expectedOffset: 29}, // = 30 (the size of animals.txt) + -1
+		{name: "invalid", offset: 0, whence: 3, expectedErrno: wasip1.ErrnoInval},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.FdSeekName,
+				uint64(fd), uint64(tc.offset), tc.whence, uint64(resultNewoffset))
+			if tc.expectedErrno == wasip1.ErrnoSuccess {
+				newOffset, ok := mod.Memory().ReadUint64Le(resultNewoffset)
+				require.True(t, ok)
+				require.Equal(t, tc.expectedOffset, newOffset)
+			}
+		})
+	}
+}
+
+func Test_unstableFdFilestatGet(t *testing.T) {
+	mod, r := requireUnstableProxyModule(t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	resultFilestat := uint32(1) // arbitrary offset in api.Memory for the filestat
+	maskMemory(t, mod, 64)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFilestatGetName, uint64(internalsys.FdStdin), uint64(resultFilestat))
+
+	actual, ok := mod.Memory().Read(0, 58)
+	require.True(t, ok)
+	require.Equal(t, append(append([]byte{'?'},
+		0, 0, 0, 0, 0, 0, 0, 0, // dev
+		0, 0, 0, 0, 0, 0, 0, 0, // ino
+		// expect block device because stdin isn't a real file
+		1, 0, 0, 0, // filetype + padding
+		1, 0, 0, 0, // nlink is 32-bit
+		0, 0, 0, 0, 0, 0, 0, 0, // size
+		0, 0, 0, 0, 0, 0, 0, 0, // atim
+		0, 0, 0, 0, 0, 0, 0, 0, // mtim
+		0, 0, 0, 0, 0, 0, 0, 0, // ctim
+	), '?'), actual)
+}
+
+func Test_unstablePollOneoff(t *testing.T) {
+	mod, r := requireUnstableProxyModule(t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	in := uint32(0)    // subscriptions are 56 bytes in wasi_unstable
+	out := uint32(128) // events are 32 bytes, like wasi_snapshot_preview1
+	resultNevents := uint32(512)
+
+	mem := mod.Memory()
+	require.True(t, mem.Write(in, []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, // userdata
+		wasip1.EventTypeClock, 0, 0, 0, 0, 0, 0, 0, // tag + padding
+		9, 9, 9, 9, 9, 9, 9, 9, // identifier
+		wasip1.ClockIDMonotonic, 0, 0, 0, 0, 0, 0, 0, // clock id + padding
+		0, 0, 0, 0, 0, 0, 0, 0, // timeout: no wait
+		0, 0, 0, 0, 0, 0, 0, 0, // precision
+		0, 0, 0, 0, 0, 0, 0, 0, // flags: relative + padding
+	}))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(in), uint64(out), 1, uint64(resultNevents))
+
+	nevents, ok := mem.ReadUint32Le(resultNevents)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), nevents)
+
+	event, ok := mem.Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, // userdata
+		byte(wasip1.ErrnoSuccess), 0, // errno
+		wasip1.EventTypeClock, 0, 0, 0, 0, 0, // type + padding
+		0, 0, 0, 0, 0, 0, 0, 0, // nbytes
+		0, 0, 0, 0, 0, 0, 0, 0, // flags + padding
+	}, event)
+}
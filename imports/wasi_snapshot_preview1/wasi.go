@@ -72,19 +72,48 @@ type Builder interface {
 	//
 	// Note: This has the same effect as the same function on wazero.HostModuleBuilder.
 	Instantiate(context.Context) (api.Closer, error)
+
+	// WithErrnoMode selects how errors without a defined WASI mapping are
+	// returned. Defaults to ErrnoModeLinux.
+	//
+	// For example, this returns ENOTCAPABLE to guests compiled with older
+	// versions of wasi-libc when a path escapes its pre-opened directory:
+	//
+	//	wasi_snapshot_preview1.NewBuilder(r).
+	//		WithErrnoMode(wasi_snapshot_preview1.ErrnoModeStrict).
+	//		Instantiate(ctx)
+	WithErrnoMode(ErrnoMode) Builder
 }
 
 // NewBuilder returns a new Builder.
 func NewBuilder(r wazero.Runtime) Builder {
-	return &builder{r}
+	return &builder{r: r}
+}
+
+type builder struct {
+	r         wazero.Runtime
+	errnoMode ErrnoMode
+	// unstable is true for the UnstableModuleName module.
+	unstable bool
 }
 
-type builder struct{ r wazero.Runtime }
+// WithErrnoMode implements Builder.WithErrnoMode
+func (b *builder) WithErrnoMode(mode ErrnoMode) Builder {
+	ret := *b // copy
+	ret.errnoMode = mode
+	return &ret
+}
 
-// hostModuleBuilder returns a new wazero.HostModuleBuilder for ModuleName
+// hostModuleBuilder returns a new wazero.HostModuleBuilder for ModuleName,
+// or UnstableModuleName.
 func (b *builder) hostModuleBuilder() wazero.HostModuleBuilder {
+	if b.unstable {
+		ret := b.r.NewHostModuleBuilder(UnstableModuleName)
+		exportUnstableFunctions(ret, b.errnoMode)
+		return ret
+	}
 	ret := b.r.NewHostModuleBuilder(ModuleName)
-	exportFunctions(ret)
+	exportFunctions(ret, b.errnoMode)
 	return ret
 }
 
@@ -113,6 +142,9 @@ type FunctionExporter interface {
 //   - Overriding a builtin function with an alternate implementation.
 //   - Exporting functions to the module "wasi_unstable" for legacy code.
 //
+// Note: Prefer NewUnstableBuilder for legacy code, as this exports functions
+// whose signature or layout differs in "wasi_unstable" as-is.
+//
 // # Example of overriding default behavior
 //
 //	// Export the default WASI functions.
@@ -140,7 +172,7 @@ type functionExporter struct{}
 
 // ExportFunctions implements FunctionExporter.ExportFunctions
 func (functionExporter) ExportFunctions(builder wazero.HostModuleBuilder) {
-	exportFunctions(builder)
+	exportFunctions(builder, ErrnoModeLinux)
 }
 
 // ## Translation notes
@@ -172,8 +204,8 @@ func (functionExporter) ExportFunctions(builder wazero.HostModuleBuilder) {
 
 // exportFunctions adds all go functions that implement wasi.
 // These should be exported in the module named ModuleName.
-func exportFunctions(builder wazero.HostModuleBuilder) {
-	exporter := builder.(wasm.HostFuncExporter)
+func exportFunctions(builder wazero.HostModuleBuilder, mode ErrnoMode) {
+	var exporter wasm.HostFuncExporter = &errnoModeExporter{builder.(wasm.HostFuncExporter), mode}
 
 	// Note: these are ordered per spec for consistency even if the resulting
 	// map can't guarantee that.
@@ -0,0 +1,103 @@
+package wasi_snapshot_preview2
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+var environmentFuncs = []hostFunc{
+	{"get-environment", []api.ValueType{i32}, nil, func(ctx context.Context, mod api.Module, stack []uint64) {
+		environ := sysContext(mod).Environ()
+		list := alloc(ctx, mod, 4, uint32(len(environ))*16)
+		for i, kv := range environ {
+			k, v := kv, []byte(nil)
+			if eq := bytes.IndexByte(kv, '='); eq >= 0 {
+				k, v = kv[:eq], kv[eq+1:]
+			}
+			writeBytes(ctx, mod, list+uint32(i)*16, k)
+			writeBytes(ctx, mod, list+uint32(i)*16+8, v)
+		}
+		writeList(mod.Memory(), uint32(stack[0]), list, len(environ))
+	}},
+	{"get-arguments", []api.ValueType{i32}, nil, func(ctx context.Context, mod api.Module, stack []uint64) {
+		args := sysContext(mod).Args()
+		list := alloc(ctx, mod, 4, uint32(len(args))*8)
+		for i, arg := range args {
+			writeBytes(ctx, mod, list+uint32(i)*8, arg)
+		}
+		writeList(mod.Memory(), uint32(stack[0]), list, len(args))
+	}},
+	{"initial-cwd", []api.ValueType{i32}, nil, func(_ context.Context, mod api.Module, stack []uint64) {
+		writeUint8(mod.Memory(), uint32(stack[0]), 0) // none, as there's no working directory.
+	}},
+}
+
+// writeList writes the pointer and length of a list at `offset`.
+func writeList(mem api.Memory, offset, list uint32, length int) {
+	writeUint32(mem, offset, list)
+	writeUint32(mem, offset+4, uint32(length))
+}
+
+var exitFuncs = []hostFunc{
+	{"exit", []api.ValueType{i32}, nil, func(ctx context.Context, mod api.Module, stack []uint64) {
+		exitCode := uint32(stack[0]) // zero is ok and one is err.
+
+		// Ensure other callers see the exit code.
+		_ = mod.CloseWithExitCode(ctx, exitCode)
+
+		// Prevent any code from executing after this function, like
+		// wasi_snapshot_preview1 proc_exit.
+		panic(sys.NewExitError(exitCode))
+	}},
+}
+
+var (
+	stdinFuncs  = []hostFunc{{"get-stdin", nil, []api.ValueType{i32}, stdioFn(internalsys.FdStdin)}}
+	stdoutFuncs = []hostFunc{{"get-stdout", nil, []api.ValueType{i32}, stdioFn(internalsys.FdStdout)}}
+	stderrFuncs = []hostFunc{{"get-stderr", nil, []api.ValueType{i32}, stdioFn(internalsys.FdStderr)}}
+)
+
+// stdioFn returns a new stream of the file descriptor, as configured by
+// wazero.ModuleConfig, e.g. WithStdout.
+func stdioFn(fd int32) api.GoModuleFunc {
+	return func(_ context.Context, mod api.Module, stack []uint64) {
+		var s interface{}
+		if fd == internalsys.FdStdin {
+			s = &inputStream{f: stdioFile(mod, fd), offset: -1}
+		} else {
+			s = &outputStream{f: stdioFile(mod, fd), offset: -1}
+		}
+		stack[0] = uint64(newHandle(mod, s))
+	}
+}
+
+// terminal is the "terminal-input" or "terminal-output" resource of
+// "wasi:cli/terminal-input" and "wasi:cli/terminal-output", which have no
+// methods.
+type terminal struct{}
+
+var (
+	terminalInputFuncs  = []hostFunc{dropFunc("terminal-input")}
+	terminalOutputFuncs = []hostFunc{dropFunc("terminal-output")}
+	terminalStdinFuncs  = []hostFunc{{"get-terminal-stdin", []api.ValueType{i32}, nil, terminalFn(internalsys.FdStdin)}}
+	terminalStdoutFuncs = []hostFunc{{"get-terminal-stdout", []api.ValueType{i32}, nil, terminalFn(internalsys.FdStdout)}}
+	terminalStderrFuncs = []hostFunc{{"get-terminal-stderr", []api.ValueType{i32}, nil, terminalFn(internalsys.FdStderr)}}
+)
+
+// terminalFn returns a new terminal if the file descriptor is one, as
+// configured by wazero.ModuleConfig WithTerminal.
+func terminalFn(fd int32) api.GoModuleFunc {
+	return func(_ context.Context, mod api.Module, stack []uint64) {
+		retptr, mem := uint32(stack[0]), mod.Memory()
+		if _, errno := sysContext(mod).FS().Terminal(fd); errno != 0 {
+			writeUint8(mem, retptr, 0)
+			return
+		}
+		writeUint8(mem, retptr, 1)
+		writeUint32(mem, retptr+4, newHandle(mod, &terminal{}))
+	}
+}
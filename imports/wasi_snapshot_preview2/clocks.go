@@ -0,0 +1,40 @@
+package wasi_snapshot_preview2
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+var monotonicClockFuncs = []hostFunc{
+	{"now", nil, []api.ValueType{i64}, func(_ context.Context, mod api.Module, stack []uint64) {
+		stack[0] = uint64(sysContext(mod).Nanotime())
+	}},
+	{"resolution", nil, []api.ValueType{i64}, func(_ context.Context, mod api.Module, stack []uint64) {
+		stack[0] = uint64(sysContext(mod).NanotimeResolution())
+	}},
+	{"subscribe-instant", []api.ValueType{i64}, []api.ValueType{i32}, func(_ context.Context, mod api.Module, stack []uint64) {
+		stack[0] = uint64(newHandle(mod, &pollable{deadline: int64(stack[0])}))
+	}},
+	{"subscribe-duration", []api.ValueType{i64}, []api.ValueType{i32}, func(_ context.Context, mod api.Module, stack []uint64) {
+		deadline := sysContext(mod).Nanotime() + int64(stack[0])
+		stack[0] = uint64(newHandle(mod, &pollable{deadline: deadline}))
+	}},
+}
+
+var wallClockFuncs = []hostFunc{
+	{"now", []api.ValueType{i32}, nil, func(_ context.Context, mod api.Module, stack []uint64) {
+		sec, nsec := sysContext(mod).Walltime()
+		writeDatetime(mod.Memory(), uint32(stack[0]), uint64(sec), uint32(nsec))
+	}},
+	{"resolution", []api.ValueType{i32}, nil, func(_ context.Context, mod api.Module, stack []uint64) {
+		res := uint64(sysContext(mod).WalltimeResolution())
+		writeDatetime(mod.Memory(), uint32(stack[0]), res/1e9, uint32(res%1e9))
+	}},
+}
+
+// writeDatetime writes a "datetime" record of "wasi:clocks/wall-clock".
+func writeDatetime(mem api.Memory, offset uint32, sec uint64, nsec uint32) {
+	writeUint64(mem, offset, sec)
+	writeUint32(mem, offset+8, nsec)
+}
@@ -0,0 +1,739 @@
+package wasi_snapshot_preview2
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// descriptor is the "descriptor" resource of "wasi:filesystem/types", which
+// refers to a file in the table shared with wasi_snapshot_preview1.
+type descriptor struct {
+	fd int32
+	// flags are the descriptor-flags it was opened with.
+	flags uint8
+}
+
+// close closes the file, unless it is pre-opened, as "get-directories"
+// returns a new descriptor of the same file on each call.
+func (d *descriptor) close(mod api.Module) {
+	fsc := sysContext(mod).FS()
+	if f, ok := fsc.LookupFile(d.fd); ok && !f.IsPreopen {
+		_ = fsc.CloseFile(d.fd)
+	}
+}
+
+// directoryEntryStream is the "directory-entry-stream" resource of
+// "wasi:filesystem/types".
+type directoryEntryStream struct {
+	f       experimentalsys.File
+	dirents []experimentalsys.Dirent
+	eof     bool
+}
+
+// direntBatch is the count of entries read at a time by
+// "read-directory-entry".
+const direntBatch = 64
+
+// Values of the descriptor-flags, path-flags and open-flags flags.
+const (
+	flagRead            = 1 << 0
+	flagWrite           = 1 << 1
+	flagFileSync        = 1 << 2
+	flagDataSync        = 1 << 3
+	flagReadSync        = 1 << 4
+	flagMutateDirectory = 1 << 5
+
+	pathFlagSymlinkFollow = 1 << 0
+
+	openFlagCreate    = 1 << 0
+	openFlagDirectory = 1 << 1
+	openFlagExclusive = 1 << 2
+	openFlagTruncate  = 1 << 3
+)
+
+// Values of the descriptor-type enum.
+const (
+	typeUnknown uint8 = iota
+	typeBlockDevice
+	typeCharacterDevice
+	typeDirectory
+	typeFifo
+	typeSymbolicLink
+	typeRegularFile
+	typeSocket
+)
+
+var filesystemTypesFuncs = []hostFunc{
+	dropFunc("descriptor"),
+	dropFunc("directory-entry-stream"),
+	{"[method]descriptor.read-via-stream", []api.ValueType{i32, i64, i32}, nil, descriptorReadViaStreamFn},
+	{"[method]descriptor.write-via-stream", []api.ValueType{i32, i64, i32}, nil, descriptorWriteViaStreamFn},
+	{"[method]descriptor.append-via-stream", []api.ValueType{i32, i32}, nil, descriptorAppendViaStreamFn},
+	{"[method]descriptor.advise", []api.ValueType{i32, i64, i64, i32, i32}, nil, descriptorAdviseFn},
+	{"[method]descriptor.sync-data", []api.ValueType{i32, i32}, nil, descriptorSyncDataFn},
+	{"[method]descriptor.get-flags", []api.ValueType{i32, i32}, nil, descriptorGetFlagsFn},
+	{"[method]descriptor.get-type", []api.ValueType{i32, i32}, nil, descriptorGetTypeFn},
+	{"[method]descriptor.set-size", []api.ValueType{i32, i64, i32}, nil, descriptorSetSizeFn},
+	{"[method]descriptor.set-times", []api.ValueType{i32, i32, i64, i32, i32, i64, i32, i32}, nil, descriptorSetTimesFn},
+	{"[method]descriptor.read", []api.ValueType{i32, i64, i64, i32}, nil, descriptorReadFn},
+	{"[method]descriptor.write", []api.ValueType{i32, i32, i32, i64, i32}, nil, descriptorWriteFn},
+	{"[method]descriptor.read-directory", []api.ValueType{i32, i32}, nil, descriptorReadDirectoryFn},
+	{"[method]descriptor.sync", []api.ValueType{i32, i32}, nil, descriptorSyncFn},
+	{"[method]descriptor.create-directory-at", []api.ValueType{i32, i32, i32, i32}, nil, descriptorCreateDirectoryAtFn},
+	{"[method]descriptor.stat", []api.ValueType{i32, i32}, nil, descriptorStatFn},
+	{"[method]descriptor.stat-at", []api.ValueType{i32, i32, i32, i32, i32}, nil, descriptorStatAtFn},
+	{"[method]descriptor.set-times-at", []api.ValueType{i32, i32, i32, i32, i32, i64, i32, i32, i64, i32, i32}, nil, descriptorSetTimesAtFn},
+	{"[method]descriptor.link-at", []api.ValueType{i32, i32, i32, i32, i32, i32, i32, i32}, nil, descriptorLinkAtFn},
+	{"[method]descriptor.open-at", []api.ValueType{i32, i32, i32, i32, i32, i32, i32}, nil, descriptorOpenAtFn},
+	{"[method]descriptor.readlink-at", []api.ValueType{i32, i32, i32, i32}, nil, descriptorReadlinkAtFn},
+	{"[method]descriptor.remove-directory-at", []api.ValueType{i32, i32, i32, i32}, nil, descriptorRemoveDirectoryAtFn},
+	{"[method]descriptor.rename-at", []api.ValueType{i32, i32, i32, i32, i32, i32, i32}, nil, descriptorRenameAtFn},
+	{"[method]descriptor.symlink-at", []api.ValueType{i32, i32, i32, i32, i32, i32}, nil, descriptorSymlinkAtFn},
+	{"[method]descriptor.unlink-file-at", []api.ValueType{i32, i32, i32, i32}, nil, descriptorUnlinkFileAtFn},
+	{"[method]descriptor.is-same-object", []api.ValueType{i32, i32}, []api.ValueType{i32}, descriptorIsSameObjectFn},
+	{"[method]descriptor.metadata-hash", []api.ValueType{i32, i32}, nil, descriptorMetadataHashFn},
+	{"[method]descriptor.metadata-hash-at", []api.ValueType{i32, i32, i32, i32, i32}, nil, descriptorMetadataHashAtFn},
+	{"[method]directory-entry-stream.read-directory-entry", []api.ValueType{i32, i32}, nil, readDirectoryEntryFn},
+	{"filesystem-error-code", []api.ValueType{i32, i32}, nil, filesystemErrorCodeFn},
+}
+
+var filesystemPreopensFuncs = []hostFunc{
+	{"get-directories", []api.ValueType{i32}, nil, getDirectoriesFn},
+}
+
+// getDirectoriesFn returns a new descriptor for each directory configured
+// by wazero.ModuleConfig WithFSConfig, with its guest path.
+func getDirectoriesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	fsc := sysContext(mod).FS()
+	var fds []int32
+	var names []string
+	for fd := internalsys.FdPreopen; ; fd++ {
+		f, ok := fsc.LookupFile(fd)
+		if !ok || !f.IsPreopen {
+			break
+		}
+		if isDir, errno := f.File.IsDir(); errno == 0 && isDir {
+			fds = append(fds, fd)
+			names = append(names, f.Name)
+		}
+	}
+
+	mem := mod.Memory()
+	list := alloc(ctx, mod, 4, uint32(len(fds))*12)
+	for i, fd := range fds {
+		handle := newHandle(mod, &descriptor{fd: fd, flags: flagRead | flagMutateDirectory})
+		writeUint32(mem, list+uint32(i)*12, handle)
+		writeBytes(ctx, mod, list+uint32(i)*12+4, []byte(names[i]))
+	}
+	writeList(mem, uint32(stack[0]), list, len(fds))
+}
+
+// lookupFile returns the file of the descriptor, or sys.EBADF if it was
+// closed, e.g. by wasi_snapshot_preview1.
+func lookupFile(mod api.Module, handle uint32) (*descriptor, *internalsys.FileEntry, experimentalsys.Errno) {
+	d := lookup[*descriptor](mod, handle)
+	if f, ok := sysContext(mod).FS().LookupFile(d.fd); ok {
+		return d, f, 0
+	}
+	return d, nil, experimentalsys.EBADF
+}
+
+// atPath returns the filesystem and path of `p` relative to the directory
+// of the descriptor, like wasi_snapshot_preview1 does for its "path_"
+// functions.
+func atPath(mod api.Module, handle, ptr, length uint32) (experimentalsys.FS, string, experimentalsys.Errno) {
+	_, f, errno := lookupFile(mod, handle)
+	if errno != 0 {
+		return nil, "", errno
+	}
+	pathName := string(readBytes(mod.Memory(), ptr, length))
+	hasTrailingSlash := strings.HasSuffix(pathName, "/")
+	pathName = path.Clean(pathName)
+	if !fs.ValidPath(pathName) {
+		return nil, "", experimentalsys.EPERM
+	}
+	if hasTrailingSlash {
+		pathName = pathName + "/"
+	}
+
+	if isDir, errno := f.File.IsDir(); errno != 0 {
+		return nil, "", errno
+	} else if !isDir {
+		return nil, "", experimentalsys.ENOTDIR
+	} else if f.IsPreopen || f.Name == "" {
+		return f.FS, pathName, 0
+	}
+	return f.FS, f.Name + "/" + pathName, 0
+}
+
+func descriptorReadViaStreamFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	if errno != 0 {
+		writeErrorCode(mod, uint32(stack[2]), 4, errno)
+		return
+	}
+	writeHandleResult(mod, uint32(stack[2]), &inputStream{f: f.File, offset: int64(stack[1])})
+}
+
+func descriptorWriteViaStreamFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	if errno != 0 {
+		writeErrorCode(mod, uint32(stack[2]), 4, errno)
+		return
+	}
+	writeHandleResult(mod, uint32(stack[2]), &outputStream{f: f.File, offset: int64(stack[1])})
+}
+
+func descriptorAppendViaStreamFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	if errno != 0 {
+		writeErrorCode(mod, uint32(stack[1]), 4, errno)
+		return
+	}
+	writeHandleResult(mod, uint32(stack[1]), &outputStream{f: f.File, append: true})
+}
+
+// descriptorAdviseFn ignores the advice, as it is only a hint.
+func descriptorAdviseFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, _, errno := lookupFile(mod, uint32(stack[0]))
+	writeUnitResult(mod, uint32(stack[4]), errno)
+}
+
+func descriptorSyncDataFn(_ context.Context, mod api.Module, stack []uint64) {
+	d := lookup[*descriptor](mod, uint32(stack[0]))
+	writeUnitResult(mod, uint32(stack[1]), sysContext(mod).FS().Datasync(d.fd))
+}
+
+func descriptorSyncFn(_ context.Context, mod api.Module, stack []uint64) {
+	d := lookup[*descriptor](mod, uint32(stack[0]))
+	writeUnitResult(mod, uint32(stack[1]), sysContext(mod).FS().Sync(d.fd))
+}
+
+func descriptorGetFlagsFn(_ context.Context, mod api.Module, stack []uint64) {
+	d, _, errno := lookupFile(mod, uint32(stack[0]))
+	retptr := uint32(stack[1])
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 1, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint8(mem, retptr+1, d.flags)
+}
+
+func descriptorGetTypeFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	retptr := uint32(stack[1])
+	var st sys.Stat_t
+	if errno == 0 {
+		st, errno = f.File.Stat()
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 1, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint8(mem, retptr+1, descriptorType(st.Mode))
+}
+
+func descriptorSetSizeFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	if errno == 0 {
+		errno = f.File.Truncate(int64(stack[1]))
+	}
+	writeUnitResult(mod, uint32(stack[2]), errno)
+}
+
+func descriptorSetTimesFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	if errno == 0 {
+		walltime := sysContext(mod).WalltimeNanos
+		atim := toTimestamp(walltime, stack[1], stack[2], stack[3])
+		mtim := toTimestamp(walltime, stack[4], stack[5], stack[6])
+		errno = f.File.Utimens(atim, mtim)
+	}
+	writeUnitResult(mod, uint32(stack[7]), errno)
+}
+
+func descriptorSetTimesAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[2]), uint32(stack[3]))
+	if errno == 0 {
+		walltime := sysContext(mod).WalltimeNanos
+		atim := toTimestamp(walltime, stack[4], stack[5], stack[6])
+		mtim := toTimestamp(walltime, stack[7], stack[8], stack[9])
+		if uint32(stack[1])&pathFlagSymlinkFollow == 0 {
+			errno = experimentalsys.ENOSYS // Utimens always follows links.
+		} else {
+			errno = preopen.Utimens(pathName, atim, mtim)
+		}
+	}
+	writeUnitResult(mod, uint32(stack[10]), errno)
+}
+
+// toTimestamp converts a flattened new-timestamp variant to epoch nanoseconds
+// for sys.FS Utimens.
+func toTimestamp(walltime func() int64, disc, sec, nsec uint64) int64 {
+	switch disc {
+	case 0: // no-change
+		return experimentalsys.UTIME_OMIT
+	case 1: // now
+		return walltime()
+	default: // timestamp
+		return int64(sec)*1e9 + int64(uint32(nsec))
+	}
+}
+
+func descriptorReadFn(ctx context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	length, offset, retptr := stack[1], int64(stack[2]), uint32(stack[3])
+	if length > maxReadSize {
+		length = maxReadSize
+	}
+	var n int
+	buf := make([]byte, length)
+	if errno == 0 {
+		n, errno = f.File.Pread(buf, offset)
+		sysContext(mod).ThrottleIO(ctx, int64(n))
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 4, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeBytes(ctx, mod, retptr+4, buf[:n])
+	if n == 0 && length > 0 {
+		writeUint8(mem, retptr+12, 1) // end of stream
+	} else {
+		writeUint8(mem, retptr+12, 0)
+	}
+}
+
+func descriptorWriteFn(ctx context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	buf := readBytes(mod.Memory(), uint32(stack[1]), uint32(stack[2]))
+	offset, retptr := int64(stack[3]), uint32(stack[4])
+	var n int
+	if errno == 0 {
+		n, errno = f.File.Pwrite(buf, offset)
+		sysContext(mod).ThrottleIO(ctx, int64(n))
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 8, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint64(mem, retptr+8, uint64(n))
+}
+
+func descriptorReadDirectoryFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	retptr := uint32(stack[1])
+	if errno == 0 {
+		var isDir bool
+		if isDir, errno = f.File.IsDir(); errno == 0 && !isDir {
+			errno = experimentalsys.ENOTDIR
+		}
+	}
+	if errno == 0 {
+		_, errno = f.File.Seek(0, io.SeekStart)
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 4, errno)
+		return
+	}
+	writeHandleResult(mod, retptr, &directoryEntryStream{f: f.File})
+}
+
+// readDirectoryEntryFn returns the next entry, excluding "." and "..", or
+// none at the end of the directory.
+func readDirectoryEntryFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*directoryEntryStream](mod, uint32(stack[0]))
+	retptr := uint32(stack[1])
+	for len(s.dirents) == 0 && !s.eof {
+		dirents, errno := s.f.Readdir(direntBatch)
+		if errno != 0 {
+			writeErrorCode(mod, retptr, 4, errno)
+			return
+		}
+		s.eof = len(dirents) == 0
+		for _, d := range dirents {
+			if d.Name != "." && d.Name != ".." {
+				s.dirents = append(s.dirents, d)
+			}
+		}
+	}
+
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	if len(s.dirents) == 0 {
+		writeUint8(mem, retptr+4, 0) // none
+		return
+	}
+	d := s.dirents[0]
+	s.dirents = s.dirents[1:]
+	writeUint8(mem, retptr+4, 1)
+	writeUint8(mem, retptr+8, descriptorType(d.Type))
+	writeBytes(ctx, mod, retptr+12, []byte(d.Name))
+}
+
+func descriptorCreateDirectoryAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[1]), uint32(stack[2]))
+	if errno == 0 {
+		errno = preopen.Mkdir(pathName, 0o700)
+	}
+	writeUnitResult(mod, uint32(stack[3]), errno)
+}
+
+func descriptorStatFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	var st sys.Stat_t
+	if errno == 0 {
+		st, errno = f.File.Stat()
+	}
+	writeStatResult(mod, uint32(stack[1]), &st, errno)
+}
+
+func descriptorStatAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	st, errno := statAt(mod, stack)
+	writeStatResult(mod, uint32(stack[4]), &st, errno)
+}
+
+// statAt stats the path of the parameters shared by "stat-at" and
+// "metadata-hash-at": the descriptor, path-flags and path.
+func statAt(mod api.Module, stack []uint64) (st sys.Stat_t, errno experimentalsys.Errno) {
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[2]), uint32(stack[3]))
+	if errno != 0 {
+		return
+	} else if uint32(stack[1])&pathFlagSymlinkFollow != 0 {
+		return preopen.Stat(pathName)
+	}
+	return preopen.Lstat(pathName)
+}
+
+func descriptorLinkAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	oldFS, oldPath, errno := atPath(mod, uint32(stack[0]), uint32(stack[2]), uint32(stack[3]))
+	if errno == 0 {
+		var newFS experimentalsys.FS
+		var newPath string
+		if newFS, newPath, errno = atPath(mod, uint32(stack[4]), uint32(stack[5]), uint32(stack[6])); errno == 0 {
+			if oldFS != newFS {
+				errno = experimentalsys.ENOSYS // cross-device
+			} else {
+				errno = oldFS.Link(oldPath, newPath)
+			}
+		}
+	}
+	writeUnitResult(mod, uint32(stack[7]), errno)
+}
+
+func descriptorOpenAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	pathFlags, openFlags, flags := uint32(stack[1]), uint32(stack[4]), uint8(stack[5])
+	retptr := uint32(stack[6])
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[2]), uint32(stack[3]))
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 4, errno)
+		return
+	}
+
+	oflag := toOflag(pathFlags, openFlags, flags)
+	isDir := oflag&experimentalsys.O_DIRECTORY != 0
+	if isDir && oflag&experimentalsys.O_CREAT != 0 {
+		writeErrorCode(mod, retptr, 4, experimentalsys.EINVAL)
+		return
+	}
+
+	fsc := sysContext(mod).FS()
+	fd, errno := fsc.OpenFile(preopen, pathName, oflag, 0o600)
+	if errno == 0 && isDir {
+		if f, ok := fsc.LookupFile(fd); !ok {
+			errno = experimentalsys.EBADF // unexpected
+		} else if isDir, errno = f.File.IsDir(); errno == 0 && !isDir {
+			errno = experimentalsys.ENOTDIR
+		}
+		if errno != 0 {
+			_ = fsc.CloseFile(fd)
+		}
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 4, errno)
+		return
+	}
+	writeHandleResult(mod, retptr, &descriptor{fd: fd, flags: flags})
+}
+
+// toOflag converts the path-flags, open-flags and descriptor-flags of
+// "open-at" to the flags of sys.FS OpenFile.
+func toOflag(pathFlags, openFlags uint32, flags uint8) (oflag experimentalsys.Oflag) {
+	if pathFlags&pathFlagSymlinkFollow == 0 {
+		oflag |= experimentalsys.O_NOFOLLOW
+	}
+	if openFlags&openFlagDirectory != 0 {
+		oflag |= experimentalsys.O_DIRECTORY
+	}
+	if openFlags&openFlagCreate != 0 {
+		oflag |= experimentalsys.O_CREAT
+	}
+	if openFlags&openFlagExclusive != 0 {
+		oflag |= experimentalsys.O_EXCL
+	}
+	if openFlags&openFlagTruncate != 0 {
+		oflag |= experimentalsys.O_TRUNC
+	}
+	if flags&flagFileSync != 0 {
+		oflag |= experimentalsys.O_SYNC
+	}
+	if flags&flagDataSync != 0 {
+		oflag |= experimentalsys.O_DSYNC
+	}
+	if flags&flagReadSync != 0 {
+		oflag |= experimentalsys.O_RSYNC
+	}
+	switch {
+	case flags&(flagRead|flagWrite) == flagRead|flagWrite:
+		oflag |= experimentalsys.O_RDWR
+	case flags&flagWrite != 0:
+		oflag |= experimentalsys.O_WRONLY
+	default:
+		oflag |= experimentalsys.O_RDONLY
+	}
+	return
+}
+
+func descriptorReadlinkAtFn(ctx context.Context, mod api.Module, stack []uint64) {
+	retptr := uint32(stack[3])
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[1]), uint32(stack[2]))
+	var dst string
+	if errno == 0 {
+		dst, errno = preopen.Readlink(pathName)
+	}
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 4, errno)
+		return
+	}
+	writeUint8(mod.Memory(), retptr, 0)
+	writeBytes(ctx, mod, retptr+4, []byte(dst))
+}
+
+func descriptorRemoveDirectoryAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[1]), uint32(stack[2]))
+	if errno == 0 {
+		errno = preopen.Rmdir(pathName)
+	}
+	writeUnitResult(mod, uint32(stack[3]), errno)
+}
+
+func descriptorRenameAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	oldFS, oldPath, errno := atPath(mod, uint32(stack[0]), uint32(stack[1]), uint32(stack[2]))
+	if errno == 0 {
+		var newFS experimentalsys.FS
+		var newPath string
+		if newFS, newPath, errno = atPath(mod, uint32(stack[3]), uint32(stack[4]), uint32(stack[5])); errno == 0 {
+			if oldFS != newFS {
+				errno = experimentalsys.ENOSYS // cross-device
+			} else {
+				errno = oldFS.Rename(oldPath, newPath)
+			}
+		}
+	}
+	writeUnitResult(mod, uint32(stack[6]), errno)
+}
+
+func descriptorSymlinkAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	oldPath := string(readBytes(mod.Memory(), uint32(stack[1]), uint32(stack[2])))
+	preopen, newPath, errno := atPath(mod, uint32(stack[0]), uint32(stack[3]), uint32(stack[4]))
+	if errno == 0 {
+		errno = preopen.Symlink(oldPath, newPath)
+	}
+	writeUnitResult(mod, uint32(stack[5]), errno)
+}
+
+func descriptorUnlinkFileAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	preopen, pathName, errno := atPath(mod, uint32(stack[0]), uint32(stack[1]), uint32(stack[2]))
+	if errno == 0 {
+		errno = preopen.Unlink(pathName)
+	}
+	writeUnitResult(mod, uint32(stack[3]), errno)
+}
+
+func descriptorIsSameObjectFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, a, errno := lookupFile(mod, uint32(stack[0]))
+	stack[0] = 0
+	if errno != 0 {
+		return
+	}
+	_, b, errno := lookupFile(mod, uint32(stack[1]))
+	if errno != 0 {
+		return
+	}
+	aSt, aErrno := a.File.Stat()
+	bSt, bErrno := b.File.Stat()
+	if aErrno == 0 && bErrno == 0 && aSt.Ino != 0 && aSt.Dev == bSt.Dev && aSt.Ino == bSt.Ino {
+		stack[0] = 1
+	}
+}
+
+func descriptorMetadataHashFn(_ context.Context, mod api.Module, stack []uint64) {
+	_, f, errno := lookupFile(mod, uint32(stack[0]))
+	var st sys.Stat_t
+	if errno == 0 {
+		st, errno = f.File.Stat()
+	}
+	writeMetadataHashResult(mod, uint32(stack[1]), &st, errno)
+}
+
+func descriptorMetadataHashAtFn(_ context.Context, mod api.Module, stack []uint64) {
+	st, errno := statAt(mod, stack)
+	writeMetadataHashResult(mod, uint32(stack[4]), &st, errno)
+}
+
+// writeMetadataHashResult writes a result<metadata-hash-value, error-code>,
+// whose hash identifies the file by its device and inode, and changes with
+// its modification time and size.
+func writeMetadataHashResult(mod api.Module, retptr uint32, st *sys.Stat_t, errno experimentalsys.Errno) {
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 8, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint64(mem, retptr+8, st.Dev^uint64(st.Ino)*0x9e3779b97f4a7c15)
+	writeUint64(mem, retptr+16, uint64(st.Mtim)^uint64(st.Size)*0x9e3779b97f4a7c15)
+}
+
+func filesystemErrorCodeFn(_ context.Context, mod api.Module, stack []uint64) {
+	e := lookup[*ioError](mod, uint32(stack[0]))
+	mem, retptr := mod.Memory(), uint32(stack[1])
+	writeUint8(mem, retptr, 1)
+	writeUint8(mem, retptr+1, errorCode(e.errno))
+}
+
+// writeStatResult writes a result<descriptor-stat, error-code>.
+func writeStatResult(mod api.Module, retptr uint32, st *sys.Stat_t, errno experimentalsys.Errno) {
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 8, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint8(mem, retptr+8, descriptorType(st.Mode))
+	writeUint64(mem, retptr+16, st.Nlink)
+	writeUint64(mem, retptr+24, uint64(st.Size))
+	for i, tim := range [3]int64{st.Atim, st.Mtim, st.Ctim} {
+		offset := retptr + 32 + uint32(i)*24 // option<datetime>
+		writeUint8(mem, offset, 1)
+		writeDatetime(mem, offset+8, uint64(tim/1e9), uint32(tim%1e9))
+	}
+}
+
+// writeHandleResult writes a result<own<T>, error-code> for a new resource.
+func writeHandleResult(mod api.Module, retptr uint32, resource interface{}) {
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint32(mem, retptr+4, newHandle(mod, resource))
+}
+
+// writeUnitResult writes a result<_, error-code>.
+func writeUnitResult(mod api.Module, retptr uint32, errno experimentalsys.Errno) {
+	if errno != 0 {
+		writeErrorCode(mod, retptr, 1, errno)
+		return
+	}
+	writeUint8(mod.Memory(), retptr, 0)
+}
+
+// writeErrorCode writes the error case of a result whose error is an
+// error-code at `payload` bytes after `retptr`.
+func writeErrorCode(mod api.Module, retptr, payload uint32, errno experimentalsys.Errno) {
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 1)
+	writeUint8(mem, retptr+payload, errorCode(errno))
+}
+
+// errorCode converts the errno to the error-code enum.
+func errorCode(errno experimentalsys.Errno) uint8 {
+	switch errno {
+	case experimentalsys.EACCES:
+		return 0 // access
+	case experimentalsys.EAGAIN:
+		return 1 // would-block
+	case experimentalsys.EBADF:
+		return 3 // bad-descriptor
+	case experimentalsys.EEXIST:
+		return 7 // exist
+	case experimentalsys.EINTR:
+		return 11 // interrupted
+	case experimentalsys.EIO:
+		return 13 // io
+	case experimentalsys.EISDIR:
+		return 14 // is-directory
+	case experimentalsys.ELOOP:
+		return 15 // loop
+	case experimentalsys.ENAMETOOLONG:
+		return 18 // name-too-long
+	case experimentalsys.ENOENT:
+		return 20 // no-entry
+	case experimentalsys.ENOSPC:
+		return 23 // insufficient-space
+	case experimentalsys.ENOTDIR:
+		return 24 // not-directory
+	case experimentalsys.ENOTEMPTY:
+		return 25 // not-empty
+	case experimentalsys.ENOSYS, experimentalsys.ENOTSUP:
+		return 27 // unsupported
+	case experimentalsys.ENOTTY:
+		return 28 // no-tty
+	case experimentalsys.ERANGE:
+		return 30 // overflow
+	case experimentalsys.EPERM:
+		return 31 // not-permitted
+	case experimentalsys.EPIPE:
+		return 32 // pipe
+	case experimentalsys.EROFS:
+		return 33 // read-only
+	default:
+		return 12 // invalid
+	}
+}
+
+// descriptorType converts the type bits of the mode to the descriptor-type
+// enum.
+func descriptorType(mode fs.FileMode) uint8 {
+	switch mode & fs.ModeType {
+	case 0:
+		return typeRegularFile
+	case fs.ModeDir:
+		return typeDirectory
+	case fs.ModeSymlink:
+		return typeSymbolicLink
+	case fs.ModeNamedPipe:
+		return typeFifo
+	case fs.ModeSocket:
+		return typeSocket
+	case fs.ModeDevice | fs.ModeCharDevice, fs.ModeCharDevice:
+		return typeCharacterDevice
+	case fs.ModeDevice:
+		return typeBlockDevice
+	default:
+		return typeUnknown
+	}
+}
+
+// stdioFile returns the file of the stdio descriptor, or nil if it was
+// closed, e.g. by wasi_snapshot_preview1.
+func stdioFile(mod api.Module, fd int32) experimentalsys.File {
+	if f, ok := sysContext(mod).FS().LookupFile(fd); ok {
+		return f.File
+	}
+	return nil
+}
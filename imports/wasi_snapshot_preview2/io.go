@@ -0,0 +1,319 @@
+package wasi_snapshot_preview2
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// maxReadSize bounds the memory allocated by a read, regardless of the
+// length requested by the guest.
+const maxReadSize = 1 << 16
+
+// ioError is the "error" resource of "wasi:io/error".
+type ioError struct {
+	errno experimentalsys.Errno
+}
+
+var ioErrorFuncs = []hostFunc{
+	dropFunc("error"),
+	{"[method]error.to-debug-string", []api.ValueType{i32, i32}, nil, func(ctx context.Context, mod api.Module, stack []uint64) {
+		e := lookup[*ioError](mod, uint32(stack[0]))
+		writeBytes(ctx, mod, uint32(stack[1]), []byte(e.errno.Error()))
+	}},
+}
+
+// pollable is the "pollable" resource of "wasi:io/poll", which is ready once
+// the monotonic clock reaches deadline. Streams block instead of returning
+// zero bytes, so their pollables are always ready.
+type pollable struct {
+	deadline int64
+}
+
+var ioPollFuncs = []hostFunc{
+	dropFunc("pollable"),
+	{"[method]pollable.ready", []api.ValueType{i32}, []api.ValueType{i32}, func(_ context.Context, mod api.Module, stack []uint64) {
+		p := lookup[*pollable](mod, uint32(stack[0]))
+		if p.deadline <= sysContext(mod).Nanotime() {
+			stack[0] = 1
+		} else {
+			stack[0] = 0
+		}
+	}},
+	{"[method]pollable.block", []api.ValueType{i32}, nil, func(_ context.Context, mod api.Module, stack []uint64) {
+		p := lookup[*pollable](mod, uint32(stack[0]))
+		sysCtx := sysContext(mod)
+		if d := p.deadline - sysCtx.Nanotime(); d > 0 {
+			sysCtx.Nanosleep(d)
+		}
+	}},
+	{"poll", []api.ValueType{i32, i32, i32}, nil, pollFn},
+}
+
+// pollFn blocks until at least one of the pollables is ready, then returns
+// the indices of the ready ones.
+func pollFn(ctx context.Context, mod api.Module, stack []uint64) {
+	ptr, length, retptr := uint32(stack[0]), uint32(stack[1]), uint32(stack[2])
+	if length == 0 {
+		panic(errors.New("poll requires at least one pollable"))
+	}
+	mem := mod.Memory()
+	handles := readBytes(mem, ptr, length*4)
+	pollables := make([]*pollable, length)
+	for i := range pollables {
+		pollables[i] = lookup[*pollable](mod, le.Uint32(handles[i*4:]))
+	}
+
+	sysCtx := sysContext(mod)
+	var ready []uint32
+	for {
+		now, next := sysCtx.Nanotime(), int64(-1)
+		for i, p := range pollables {
+			if p.deadline <= now {
+				ready = append(ready, uint32(i))
+			} else if next == -1 || p.deadline < next {
+				next = p.deadline
+			}
+		}
+		if len(ready) > 0 {
+			break
+		}
+		sysCtx.Nanosleep(next - now)
+	}
+
+	list := alloc(ctx, mod, 4, uint32(len(ready))*4)
+	for i, index := range ready {
+		writeUint32(mem, list+uint32(i)*4, index)
+	}
+	writeUint32(mem, retptr, list)
+	writeUint32(mem, retptr+4, uint32(len(ready)))
+}
+
+// inputStream is the "input-stream" resource of "wasi:io/streams".
+type inputStream struct {
+	// f is nil when the file was closed, e.g. stdin by
+	// wasi_snapshot_preview1.
+	f experimentalsys.File
+	// offset is the position of the next read, or -1 to read from the
+	// position of f, such as for stdin.
+	offset int64
+}
+
+// read reads up to len(buf) bytes, where zero means EOF.
+func (s *inputStream) read(buf []byte) (n int, errno experimentalsys.Errno) {
+	if s.f == nil {
+		return 0, experimentalsys.EBADF
+	}
+	if s.offset < 0 {
+		return s.f.Read(buf)
+	}
+	n, errno = s.f.Pread(buf, s.offset)
+	s.offset += int64(n)
+	return
+}
+
+// outputStream is the "output-stream" resource of "wasi:io/streams".
+type outputStream struct {
+	// f is nil when the file was closed, e.g. stdout by
+	// wasi_snapshot_preview1.
+	f experimentalsys.File
+	// offset is the position of the next write, or -1 to write at the
+	// position of f, such as for stdout.
+	offset int64
+	// append writes at the end of f, regardless of offset.
+	append bool
+}
+
+// write writes all of buf, unless there's an error.
+func (s *outputStream) write(buf []byte) (n int, errno experimentalsys.Errno) {
+	if s.f == nil {
+		return 0, experimentalsys.EBADF
+	}
+	if s.append {
+		if _, errno = s.f.Seek(0, io.SeekEnd); errno != 0 {
+			return
+		}
+	}
+	for n < len(buf) {
+		var written int
+		if s.offset < 0 || s.append {
+			written, errno = s.f.Write(buf[n:])
+		} else {
+			written, errno = s.f.Pwrite(buf[n:], s.offset)
+			s.offset += int64(written)
+		}
+		n += written
+		if errno != 0 {
+			return
+		} else if written == 0 {
+			return n, experimentalsys.EIO
+		}
+	}
+	return
+}
+
+// checkWriteSize is the count of bytes "check-write" permits. Writes don't
+// buffer, so this only bounds the size of each write.
+const checkWriteSize = 1 << 16
+
+var ioStreamsFuncs = []hostFunc{
+	dropFunc("input-stream"),
+	dropFunc("output-stream"),
+	{"[method]input-stream.read", []api.ValueType{i32, i64, i32}, nil, inputStreamReadFn},
+	{"[method]input-stream.blocking-read", []api.ValueType{i32, i64, i32}, nil, inputStreamReadFn},
+	{"[method]input-stream.skip", []api.ValueType{i32, i64, i32}, nil, inputStreamSkipFn},
+	{"[method]input-stream.blocking-skip", []api.ValueType{i32, i64, i32}, nil, inputStreamSkipFn},
+	{"[method]input-stream.subscribe", []api.ValueType{i32}, []api.ValueType{i32}, subscribeStreamFn},
+	{"[method]output-stream.check-write", []api.ValueType{i32, i32}, nil, outputStreamCheckWriteFn},
+	{"[method]output-stream.write", []api.ValueType{i32, i32, i32, i32}, nil, outputStreamWriteFn},
+	{"[method]output-stream.blocking-write-and-flush", []api.ValueType{i32, i32, i32, i32}, nil, outputStreamWriteFn},
+	{"[method]output-stream.flush", []api.ValueType{i32, i32}, nil, outputStreamFlushFn},
+	{"[method]output-stream.blocking-flush", []api.ValueType{i32, i32}, nil, outputStreamFlushFn},
+	{"[method]output-stream.subscribe", []api.ValueType{i32}, []api.ValueType{i32}, subscribeStreamFn},
+	{"[method]output-stream.write-zeroes", []api.ValueType{i32, i64, i32}, nil, outputStreamWriteZeroesFn},
+	{"[method]output-stream.blocking-write-zeroes-and-flush", []api.ValueType{i32, i64, i32}, nil, outputStreamWriteZeroesFn},
+	{"[method]output-stream.splice", []api.ValueType{i32, i32, i64, i32}, nil, outputStreamSpliceFn},
+	{"[method]output-stream.blocking-splice", []api.ValueType{i32, i32, i64, i32}, nil, outputStreamSpliceFn},
+}
+
+// inputStreamReadFn implements both "read" and "blocking-read", as reads
+// block until at least one byte is available.
+func inputStreamReadFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*inputStream](mod, uint32(stack[0]))
+	length, retptr := stack[1], uint32(stack[2])
+	if length > maxReadSize {
+		length = maxReadSize
+	}
+
+	buf := make([]byte, length)
+	n, errno := s.read(buf)
+	sysContext(mod).ThrottleIO(ctx, int64(n))
+
+	mem := mod.Memory()
+	if n == 0 && length > 0 {
+		writeStreamError(mod, retptr, 4, errno)
+		return
+	}
+	writeUint8(mem, retptr, 0)
+	writeBytes(ctx, mod, retptr+4, buf[:n])
+}
+
+func inputStreamSkipFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*inputStream](mod, uint32(stack[0]))
+	length, retptr := stack[1], uint32(stack[2])
+	if length > maxReadSize {
+		length = maxReadSize
+	}
+
+	n, errno := s.read(make([]byte, length))
+	sysContext(mod).ThrottleIO(ctx, int64(n))
+
+	if n == 0 && length > 0 {
+		writeStreamError(mod, retptr, 8, errno)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint64(mem, retptr+8, uint64(n))
+}
+
+func subscribeStreamFn(_ context.Context, mod api.Module, stack []uint64) {
+	stack[0] = uint64(newHandle(mod, &pollable{}))
+}
+
+func outputStreamCheckWriteFn(_ context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*outputStream](mod, uint32(stack[0]))
+	retptr := uint32(stack[1])
+	if s.f == nil {
+		writeStreamError(mod, retptr, 8, experimentalsys.EBADF)
+		return
+	}
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint64(mem, retptr+8, checkWriteSize)
+}
+
+// outputStreamWriteFn implements both "write" and "blocking-write-and-flush",
+// as writes don't buffer.
+func outputStreamWriteFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*outputStream](mod, uint32(stack[0]))
+	ptr, length, retptr := uint32(stack[1]), uint32(stack[2]), uint32(stack[3])
+
+	n, errno := s.write(readBytes(mod.Memory(), ptr, length))
+	sysContext(mod).ThrottleIO(ctx, int64(n))
+	writeUnitStreamResult(mod, retptr, errno)
+}
+
+func outputStreamFlushFn(_ context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*outputStream](mod, uint32(stack[0]))
+	var errno experimentalsys.Errno
+	if s.f == nil {
+		errno = experimentalsys.EBADF
+	}
+	writeUnitStreamResult(mod, uint32(stack[1]), errno)
+}
+
+func outputStreamWriteZeroesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*outputStream](mod, uint32(stack[0]))
+	length, retptr := stack[1], uint32(stack[2])
+	if length > checkWriteSize {
+		length = checkWriteSize
+	}
+
+	n, errno := s.write(make([]byte, length))
+	sysContext(mod).ThrottleIO(ctx, int64(n))
+	writeUnitStreamResult(mod, retptr, errno)
+}
+
+// outputStreamSpliceFn implements both "splice" and "blocking-splice", as
+// reads block until at least one byte is available.
+func outputStreamSpliceFn(ctx context.Context, mod api.Module, stack []uint64) {
+	s := lookup[*outputStream](mod, uint32(stack[0]))
+	src := lookup[*inputStream](mod, uint32(stack[1]))
+	length, retptr := stack[2], uint32(stack[3])
+	if length > maxReadSize {
+		length = maxReadSize
+	}
+
+	buf := make([]byte, length)
+	n, errno := src.read(buf)
+	if n == 0 && length > 0 {
+		writeStreamError(mod, retptr, 8, errno)
+		return
+	}
+	if n, errno = s.write(buf[:n]); errno != 0 {
+		writeStreamError(mod, retptr, 8, errno)
+		return
+	}
+	sysContext(mod).ThrottleIO(ctx, int64(2*n))
+
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 0)
+	writeUint64(mem, retptr+8, uint64(n))
+}
+
+// writeUnitStreamResult writes a result<_, stream-error>.
+func writeUnitStreamResult(mod api.Module, retptr uint32, errno experimentalsys.Errno) {
+	if errno != 0 {
+		writeStreamError(mod, retptr, 4, errno)
+		return
+	}
+	writeUint8(mod.Memory(), retptr, 0)
+}
+
+// writeStreamError writes the error case of a result whose error is a
+// stream-error at `payload` bytes after `retptr`. A zero errno is EOF, which
+// is the "closed" case, and others are the "last-operation-failed" case.
+func writeStreamError(mod api.Module, retptr, payload uint32, errno experimentalsys.Errno) {
+	mem := mod.Memory()
+	writeUint8(mem, retptr, 1)
+	if errno == 0 || errno == experimentalsys.EPIPE {
+		writeUint8(mem, retptr+payload, 1)
+		return
+	}
+	writeUint8(mem, retptr+payload, 0)
+	writeUint32(mem, retptr+payload+4, newHandle(mod, &ioError{errno: errno}))
+}
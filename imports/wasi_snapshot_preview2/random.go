@@ -0,0 +1,73 @@
+package wasi_snapshot_preview2
+
+import (
+	"context"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// randomBytesFn implements "get-random-bytes" and "get-insecure-random-bytes"
+// with the source configured by wazero.ModuleConfig WithRandSource. These
+// can't fail, so they trap when the length exceeds the limit of
+// wazero.ModuleConfig WithRandLimit.
+func randomBytesFn(ctx context.Context, mod api.Module, stack []uint64) {
+	length, retptr := stack[0], uint32(stack[1])
+	if length > uint64(^uint32(0)) {
+		length = uint64(^uint32(0)) // Fails the limit or the allocation.
+	}
+	buf := readRandom(ctx, mod, uint32(length))
+	writeBytes(ctx, mod, retptr, buf)
+}
+
+// randomU64Fn implements "get-random-u64" and "get-insecure-random-u64".
+func randomU64Fn(ctx context.Context, mod api.Module, stack []uint64) {
+	stack[0] = le.Uint64(readRandom(ctx, mod, 8))
+}
+
+// readRandom reads `n` random bytes like wasi_snapshot_preview1 random_get,
+// including calling the experimental.RandomHook, if any.
+func readRandom(ctx context.Context, mod api.Module, n uint32) []byte {
+	sysCtx := sysContext(mod)
+	errno := sysCtx.ThrottleRandom(ctx, int64(n))
+
+	var buf []byte
+	if errno == 0 {
+		buf = make([]byte, n)
+		if _, err := io.ReadAtLeast(sysCtx.RandSource(), buf, int(n)); err != nil {
+			errno = experimentalsys.EIO
+		}
+	}
+	if hook, ok := ctx.Value(experimental.RandomHookKey{}).(experimental.RandomHook); ok && hook != nil {
+		var err error
+		if errno != 0 {
+			err = errno
+		}
+		hook(ctx, mod, n, err)
+	}
+	if errno != 0 {
+		panic(errno)
+	}
+	return buf
+}
+
+var randomFuncs = []hostFunc{
+	{"get-random-bytes", []api.ValueType{i64, i32}, nil, randomBytesFn},
+	{"get-random-u64", nil, []api.ValueType{i64}, randomU64Fn},
+}
+
+var insecureFuncs = []hostFunc{
+	{"get-insecure-random-bytes", []api.ValueType{i64, i32}, nil, randomBytesFn},
+	{"get-insecure-random-u64", nil, []api.ValueType{i64}, randomU64Fn},
+}
+
+var insecureSeedFuncs = []hostFunc{
+	{"insecure-seed", []api.ValueType{i32}, nil, func(ctx context.Context, mod api.Module, stack []uint64) {
+		seed := readRandom(ctx, mod, 16)
+		mem := mod.Memory()
+		writeUint64(mem, uint32(stack[0]), le.Uint64(seed))
+		writeUint64(mem, uint32(stack[0])+8, le.Uint64(seed[8:]))
+	}},
+}
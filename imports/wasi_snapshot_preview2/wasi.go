@@ -0,0 +1,244 @@
+// Package wasi_snapshot_preview2 contains Go-defined functions implementing
+// the interfaces of WASI 0.2, also known as preview2: wasi-io, wasi-clocks,
+// wasi-random, wasi-filesystem and the parts of wasi-cli they depend on.
+//
+// wazero doesn't implement the component model, so these functions are
+// imported by core modules, using the canonical ABI to lower their
+// parameters and results. This is the case of the core module of a component
+// targeting WASI 0.2, e.g. as built by wit-bindgen, before it is wrapped in a
+// component. Such a module must export "cabi_realloc", which is called to
+// allocate the memory of results such as lists and strings.
+//
+// Call Instantiate before instantiating any wasm binary that imports these
+// interfaces, e.g. "wasi:io/streams@0.2.0":
+//
+//	ctx := context.Background()
+//	r := wazero.NewRuntime(ctx)
+//	defer r.Close(ctx) // This closes everything this Runtime created.
+//
+//	wasi_snapshot_preview2.MustInstantiate(ctx, r)
+//	mod, _ := r.InstantiateWithConfig(ctx, wasm, wazero.NewModuleConfig().
+//		WithStartFunctions(wasi_snapshot_preview2.RunFunction))
+//
+// Resources, such as streams and descriptors, are scoped to the module which
+// imports these functions. Resources backed by files, including stdio and
+// pre-opened directories, are configured by wazero.ModuleConfig, like for
+// wasi_snapshot_preview1.
+//
+// See https://github.com/WebAssembly/WASI/tree/main/preview2
+package wasi_snapshot_preview2
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+)
+
+// Version is the version of the WASI interfaces implemented, which is the
+// suffix of their module names, e.g. "wasi:io/streams@0.2.0".
+const Version = "0.2.0"
+
+// RunFunction is the name of the function exported by a module implementing
+// the "wasi:cli/command" world, which returns zero on success.
+//
+// Note: A module exporting this usually doesn't export "_start", so pass it
+// to wazero.ModuleConfig WithStartFunctions.
+const RunFunction = "wasi:cli/run@" + Version + "#run"
+
+const i32, i64 = api.ValueTypeI32, api.ValueTypeI64
+
+var le = binary.LittleEndian
+
+// MustInstantiate calls Instantiate or panics on error.
+//
+// This is a simpler function for those who know the WASI 0.2 modules are not
+// already instantiated, and don't need to unload them.
+func MustInstantiate(ctx context.Context, r wazero.Runtime) {
+	if _, err := Instantiate(ctx, r); err != nil {
+		panic(err)
+	}
+}
+
+// Instantiate instantiates a module for each WASI 0.2 interface into the
+// runtime, e.g. "wasi:io/streams@0.2.0", and returns a closer of all of them.
+//
+// # Notes
+//
+//   - Failure cases are documented on wazero.Runtime InstantiateModule.
+//   - Closing the wazero.Runtime has the same effect as closing the result.
+func Instantiate(ctx context.Context, r wazero.Runtime) (api.Closer, error) {
+	var ret modules
+	for _, i := range interfaces {
+		builder := r.NewHostModuleBuilder(i.name + "@" + Version)
+		for _, f := range i.funcs {
+			builder.NewFunctionBuilder().
+				WithGoModuleFunction(f.fn, f.params, f.results).
+				Export(f.name)
+		}
+		mod, err := builder.Instantiate(ctx)
+		if err != nil {
+			_ = ret.Close(ctx)
+			return nil, err
+		}
+		ret = append(ret, mod)
+	}
+	return ret, nil
+}
+
+// modules closes each module in reverse order of instantiation.
+type modules []api.Module
+
+// Close implements api.Closer.
+func (m modules) Close(ctx context.Context) (err error) {
+	for i := len(m) - 1; i >= 0; i-- {
+		if e := m[i].Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// wasiInterface is a WASI interface, such as "wasi:io/streams", which is
+// instantiated as a module.
+type wasiInterface struct {
+	name  string
+	funcs []hostFunc
+}
+
+// hostFunc is a function of a wasiInterface, whose name is as defined by the
+// canonical ABI, e.g. "[method]output-stream.write".
+type hostFunc struct {
+	name            string
+	params, results []api.ValueType
+	fn              api.GoModuleFunc
+}
+
+// interfaces are in dependency order, e.g. "wasi:io/streams" uses resources
+// of "wasi:io/error" and "wasi:io/poll".
+var interfaces = []wasiInterface{
+	{"wasi:io/error", ioErrorFuncs},
+	{"wasi:io/poll", ioPollFuncs},
+	{"wasi:io/streams", ioStreamsFuncs},
+	{"wasi:clocks/monotonic-clock", monotonicClockFuncs},
+	{"wasi:clocks/wall-clock", wallClockFuncs},
+	{"wasi:random/random", randomFuncs},
+	{"wasi:random/insecure", insecureFuncs},
+	{"wasi:random/insecure-seed", insecureSeedFuncs},
+	{"wasi:cli/environment", environmentFuncs},
+	{"wasi:cli/exit", exitFuncs},
+	{"wasi:cli/stdin", stdinFuncs},
+	{"wasi:cli/stdout", stdoutFuncs},
+	{"wasi:cli/stderr", stderrFuncs},
+	{"wasi:cli/terminal-input", terminalInputFuncs},
+	{"wasi:cli/terminal-output", terminalOutputFuncs},
+	{"wasi:cli/terminal-stdin", terminalStdinFuncs},
+	{"wasi:cli/terminal-stdout", terminalStdoutFuncs},
+	{"wasi:cli/terminal-stderr", terminalStderrFuncs},
+	{"wasi:filesystem/types", filesystemTypesFuncs},
+	{"wasi:filesystem/preopens", filesystemPreopensFuncs},
+}
+
+// dropFunc returns the "[resource-drop]" function of a resource, which
+// removes its handle from the table of the module. The resource is closed,
+// if it needs to be.
+func dropFunc(resource string) hostFunc {
+	return hostFunc{"[resource-drop]" + resource, []api.ValueType{i32}, nil, func(_ context.Context, mod api.Module, stack []uint64) {
+		r, ok := resources(mod).Delete(uint32(stack[0]))
+		if !ok {
+			panic(fmt.Errorf("unknown handle %d", uint32(stack[0])))
+		}
+		if c, ok := r.(interface{ close(api.Module) }); ok {
+			c.close(mod)
+		}
+	}}
+}
+
+func resources(mod api.Module) *sys.ResourceTable {
+	return mod.(*wasm.ModuleInstance).Sys.Resources()
+}
+
+func sysContext(mod api.Module) *sys.Context {
+	return mod.(*wasm.ModuleInstance).Sys
+}
+
+// newHandle inserts the resource in the table of the module and returns its
+// handle. This traps if the table is full.
+func newHandle(mod api.Module, resource interface{}) uint32 {
+	handle, ok := resources(mod).Insert(resource)
+	if !ok {
+		panic(errors.New("too many resources"))
+	}
+	return handle
+}
+
+// lookup returns the resource of the handle, trapping if it isn't of type T.
+func lookup[T any](mod api.Module, handle uint32) T {
+	if r, ok := resources(mod).Lookup(handle); ok {
+		if t, ok := r.(T); ok {
+			return t
+		}
+	}
+	panic(fmt.Errorf("unknown handle %d", handle))
+}
+
+// alloc calls the "cabi_realloc" function exported by the module to allocate
+// memory for a result, trapping if it fails.
+func alloc(ctx context.Context, mod api.Module, align, size uint32) uint32 {
+	if size == 0 {
+		return align // A valid, aligned pointer, which is never dereferenced.
+	}
+	fn := mod.ExportedFunction("cabi_realloc")
+	if fn == nil {
+		panic(errors.New("module doesn't export cabi_realloc"))
+	}
+	results, err := fn.Call(ctx, 0, 0, uint64(align), uint64(size))
+	if err != nil {
+		panic(err)
+	}
+	return uint32(results[0])
+}
+
+// writeBytes allocates and writes a list<u8> or string, then writes its
+// pointer and length at `offset`.
+func writeBytes(ctx context.Context, mod api.Module, offset uint32, b []byte) {
+	ptr := alloc(ctx, mod, 1, uint32(len(b)))
+	mem := mod.Memory()
+	if !mem.Write(ptr, b) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	writeUint32(mem, offset, ptr)
+	writeUint32(mem, offset+4, uint32(len(b)))
+}
+
+func readBytes(mem api.Memory, ptr, length uint32) []byte {
+	b, ok := mem.Read(ptr, length)
+	if !ok {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+	return b
+}
+
+func writeUint8(mem api.Memory, offset uint32, v uint8) {
+	if !mem.WriteByte(offset, v) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+}
+
+func writeUint32(mem api.Memory, offset, v uint32) {
+	if !mem.WriteUint32Le(offset, v) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+}
+
+func writeUint64(mem api.Memory, offset uint32, v uint64) {
+	if !mem.WriteUint64Le(offset, v) {
+		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	}
+}
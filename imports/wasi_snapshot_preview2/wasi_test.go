@@ -0,0 +1,259 @@
+package wasi_snapshot_preview2
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+// retptr is where results are written in the memory of the guest, which
+// allocates from heapBase.
+const retptr, heapBase = 16, 1024
+
+// guestWasm imports every function of each interface, and exports a function
+// calling it named like "wasi:io/streams#[method]output-stream.write". It also
+// exports its memory and a "cabi_realloc" which bumps a heap pointer.
+var guestWasm = func() []byte {
+	m := &wasm.Module{
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: i32, Mutable: true},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(heapBase)},
+		}},
+		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory}},
+	}
+
+	var count wasm.Index
+	for _, i := range interfaces {
+		for _, f := range i.funcs {
+			m.TypeSection = append(m.TypeSection, wasm.FunctionType{Params: f.params, Results: f.results})
+			m.ImportSection = append(m.ImportSection, wasm.Import{
+				Module: i.name + "@" + Version, Name: f.name, Type: wasm.ExternTypeFunc, DescFunc: count,
+			})
+			count++
+		}
+	}
+
+	for idx := wasm.Index(0); idx < count; idx++ {
+		imp := m.ImportSection[idx]
+		var body []byte
+		for p := range m.TypeSection[idx].Params {
+			body = append(body, wasm.OpcodeLocalGet, byte(p))
+		}
+		body = append(body, wasm.OpcodeCall)
+		body = append(body, leb128.EncodeUint32(idx)...)
+		body = append(body, wasm.OpcodeEnd)
+		m.FunctionSection = append(m.FunctionSection, idx)
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{
+			Name: imp.Module[:len(imp.Module)-len("@"+Version)] + "#" + imp.Name, Type: wasm.ExternTypeFunc, Index: count + idx,
+		})
+	}
+
+	// cabi_realloc returns the heap pointer, then bumps it by the size
+	// rounded up to eight bytes.
+	m.TypeSection = append(m.TypeSection, wasm.FunctionType{Params: []api.ValueType{i32, i32, i32, i32}, Results: []api.ValueType{i32}})
+	m.FunctionSection = append(m.FunctionSection, count)
+	m.CodeSection = append(m.CodeSection, wasm.Code{Body: []byte{
+		wasm.OpcodeGlobalGet, 0,
+		wasm.OpcodeGlobalGet, 0,
+		wasm.OpcodeLocalGet, 3,
+		wasm.OpcodeI32Const, 7,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeI32Const, 0x78, // -8
+		wasm.OpcodeI32And,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeGlobalSet, 0,
+		wasm.OpcodeEnd,
+	}})
+	m.ExportSection = append(m.ExportSection, wasm.Export{Name: "cabi_realloc", Type: wasm.ExternTypeFunc, Index: 2 * count})
+	return binaryencoding.EncodeModule(m)
+}()
+
+func newGuest(t *testing.T, config wazero.ModuleConfig) (api.Module, func()) {
+	r := wazero.NewRuntime(testCtx)
+	MustInstantiate(testCtx, r)
+	mod, err := r.InstantiateWithConfig(testCtx, guestWasm, config)
+	require.NoError(t, err)
+	return mod, func() { require.NoError(t, r.Close(testCtx)) }
+}
+
+func call(t *testing.T, mod api.Module, name string, params ...uint64) []uint64 {
+	fn := mod.ExportedFunction(name)
+	require.NotNil(t, fn, name)
+	results, err := fn.Call(testCtx, params...)
+	require.NoError(t, err)
+	return results
+}
+
+func readUint8(t *testing.T, mod api.Module, offset uint32) uint8 {
+	v, ok := mod.Memory().ReadByte(offset)
+	require.True(t, ok)
+	return v
+}
+
+func readUint32(t *testing.T, mod api.Module, offset uint32) uint32 {
+	v, ok := mod.Memory().ReadUint32Le(offset)
+	require.True(t, ok)
+	return v
+}
+
+func readUint64(t *testing.T, mod api.Module, offset uint32) uint64 {
+	v, ok := mod.Memory().ReadUint64Le(offset)
+	require.True(t, ok)
+	return v
+}
+
+// readString reads the pointer and length of a string or list<u8> at offset.
+func readString(t *testing.T, mod api.Module, offset uint32) string {
+	b, ok := mod.Memory().Read(readUint32(t, mod, offset), readUint32(t, mod, offset+4))
+	require.True(t, ok)
+	return string(b)
+}
+
+func TestInstantiate(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	closer, err := Instantiate(testCtx, r)
+	require.NoError(t, err)
+	require.NotNil(t, r.Module("wasi:io/streams@0.2.0"))
+
+	// Instantiating again fails, as the module names are taken.
+	_, err = Instantiate(testCtx, r)
+	require.Error(t, err)
+
+	require.NoError(t, closer.Close(testCtx))
+	require.Nil(t, r.Module("wasi:io/streams@0.2.0"))
+}
+
+func TestStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	mod, closer := newGuest(t, wazero.NewModuleConfig().WithStdout(&stdout))
+	defer closer()
+
+	require.True(t, mod.Memory().Write(heapBase-8, []byte("wasi 0.2")))
+	handle := call(t, mod, "wasi:cli/stdout#get-stdout")[0]
+	call(t, mod, "wasi:io/streams#[method]output-stream.blocking-write-and-flush", handle, heapBase-8, 8, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	require.Equal(t, "wasi 0.2", stdout.String())
+
+	call(t, mod, "wasi:io/streams#[resource-drop]output-stream", handle)
+
+	// The handle is no longer valid, so using it traps.
+	_, err := mod.ExportedFunction("wasi:io/streams#[resource-drop]output-stream").Call(testCtx, handle)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown handle 1")
+}
+
+func TestEnvironment(t *testing.T) {
+	mod, closer := newGuest(t, wazero.NewModuleConfig().WithEnv("a", "b").WithArgs("prog", "arg"))
+	defer closer()
+
+	call(t, mod, "wasi:cli/environment#get-environment", retptr)
+	list := readUint32(t, mod, retptr)
+	require.Equal(t, uint32(1), readUint32(t, mod, retptr+4))
+	require.Equal(t, "a", readString(t, mod, list))
+	require.Equal(t, "b", readString(t, mod, list+8))
+
+	call(t, mod, "wasi:cli/environment#get-arguments", retptr)
+	list = readUint32(t, mod, retptr)
+	require.Equal(t, uint32(2), readUint32(t, mod, retptr+4))
+	require.Equal(t, "prog", readString(t, mod, list))
+	require.Equal(t, "arg", readString(t, mod, list+8))
+}
+
+func TestRandom(t *testing.T) {
+	mod, closer := newGuest(t, wazero.NewModuleConfig().WithRandSource(bytes.NewReader(bytes.Repeat([]byte{7}, 16))))
+	defer closer()
+
+	call(t, mod, "wasi:random/random#get-random-bytes", 4, retptr)
+	require.Equal(t, "\x07\x07\x07\x07", readString(t, mod, retptr))
+	require.Equal(t, []uint64{0x0707070707070707}, call(t, mod, "wasi:random/random#get-random-u64"))
+}
+
+func TestClocks(t *testing.T) {
+	mod, closer := newGuest(t, wazero.NewModuleConfig())
+	defer closer()
+
+	// The default clocks are fake, so deterministic.
+	call(t, mod, "wasi:clocks/wall-clock#now", retptr)
+	require.Equal(t, uint64(1640995200), readUint64(t, mod, retptr))
+	require.Equal(t, uint32(0), readUint32(t, mod, retptr+8))
+
+	require.Equal(t, []uint64{0}, call(t, mod, "wasi:clocks/monotonic-clock#now"))
+	require.Equal(t, []uint64{1}, call(t, mod, "wasi:clocks/monotonic-clock#resolution"))
+}
+
+func TestFilesystem(t *testing.T) {
+	testFS := fstest.MapFS{
+		"dir/file.txt": {Data: []byte("hello")},
+	}
+	mod, closer := newGuest(t, wazero.NewModuleConfig().WithFSConfig(wazero.NewFSConfig().WithFSMount(testFS, "/")))
+	defer closer()
+
+	call(t, mod, "wasi:filesystem/preopens#get-directories", retptr)
+	require.Equal(t, uint32(1), readUint32(t, mod, retptr+4))
+	list := readUint32(t, mod, retptr)
+	dir := uint64(readUint32(t, mod, list))
+	require.Equal(t, "/", readString(t, mod, list+4))
+
+	require.True(t, mod.Memory().Write(heapBase-16, []byte("dir/file.txt")))
+	// open-at(dir, symlink-follow, path, open-flags: none, descriptor-flags: read)
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.open-at", dir, 1, heapBase-16, 12, 0, flagRead, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	file := uint64(readUint32(t, mod, retptr+4))
+
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.get-type", file, retptr)
+	require.Equal(t, typeRegularFile, readUint8(t, mod, retptr+1))
+
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.read", file, 3, 2, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	require.Equal(t, "llo", readString(t, mod, retptr+4))
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr+12)) // not end of stream
+
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.stat", file, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	require.Equal(t, typeRegularFile, readUint8(t, mod, retptr+8))
+	require.Equal(t, uint64(5), readUint64(t, mod, retptr+24))
+
+	// The file system is read-only.
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.write", file, heapBase-16, 3, 0, retptr)
+	require.Equal(t, uint8(1), readUint8(t, mod, retptr))    // err
+	require.Equal(t, uint8(27), readUint8(t, mod, retptr+8)) // unsupported
+
+	// open-at a missing file, "dir/file"
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.open-at", dir, 1, heapBase-16, 8, 0, flagRead, retptr)
+	require.Equal(t, uint8(1), readUint8(t, mod, retptr))    // err
+	require.Equal(t, uint8(20), readUint8(t, mod, retptr+4)) // no-entry
+
+	// read-directory excludes "." and ".."
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.open-at", dir, 1, heapBase-16, 3, openFlagDirectory, flagRead, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	sub := uint64(readUint32(t, mod, retptr+4))
+	call(t, mod, "wasi:filesystem/types#[method]descriptor.read-directory", sub, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr)) // ok
+	stream := uint64(readUint32(t, mod, retptr+4))
+	call(t, mod, "wasi:filesystem/types#[method]directory-entry-stream.read-directory-entry", stream, retptr)
+	require.Equal(t, uint8(1), readUint8(t, mod, retptr+4)) // some
+	require.Equal(t, typeRegularFile, readUint8(t, mod, retptr+8))
+	require.Equal(t, "file.txt", readString(t, mod, retptr+12))
+	call(t, mod, "wasi:filesystem/types#[method]directory-entry-stream.read-directory-entry", stream, retptr)
+	require.Equal(t, uint8(0), readUint8(t, mod, retptr+4)) // none
+
+	call(t, mod, "wasi:filesystem/types#[resource-drop]directory-entry-stream", stream)
+	call(t, mod, "wasi:filesystem/types#[resource-drop]descriptor", sub)
+	call(t, mod, "wasi:filesystem/types#[resource-drop]descriptor", file)
+	call(t, mod, "wasi:filesystem/types#[resource-drop]descriptor", dir)
+}
@@ -0,0 +1,93 @@
+package wazero
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Initializer is a step of the initialization pipeline configured by
+// ModuleConfig.WithInitializers.
+type Initializer struct {
+	// Name is the name of the exported function to call, which must have no
+	// parameters.
+	Name string
+
+	// Timeout bounds the duration of the call, or zero for no timeout.
+	//
+	// Note: A guest function only stops at the timeout when
+	// RuntimeConfig.WithCloseOnContextDone is enabled, in which case the
+	// module is closed.
+	Timeout time.Duration
+
+	// Required fails instantiation when Name isn't exported, instead of
+	// skipping the step.
+	Required bool
+
+	// unless skips the step when the named initializer was called.
+	unless string
+}
+
+// ReactorInitializers returns the conventional initialization of a reactor
+// module, which is a module with no "_start" function, but exports functions
+// to call after initialization. Each step has the given timeout, or zero for
+// none.
+//
+// The steps are:
+//   - "_initialize", which is what WASI reactors export to run constructors.
+//   - "__wasm_call_ctors", unless "_initialize" was called, as it calls this.
+//   - `exports` in order, which are required.
+//
+// Here's an example:
+//
+//	config := wazero.NewModuleConfig().
+//		WithStartFunctions(). // reactors don't have a "_start" function.
+//		WithInitializers(wazero.ReactorInitializers(time.Second, "init_plugin")...)
+func ReactorInitializers(timeout time.Duration, exports ...string) []Initializer {
+	ret := []Initializer{
+		{Name: "_initialize", Timeout: timeout},
+		{Name: "__wasm_call_ctors", Timeout: timeout, unless: "_initialize"},
+	}
+	for _, e := range exports {
+		ret = append(ret, Initializer{Name: e, Timeout: timeout, Required: true})
+	}
+	return ret
+}
+
+// callInitializers calls each initializer in order, failing at first error.
+func callInitializers(ctx context.Context, mod api.Module, initializers []Initializer) error {
+	called := map[string]struct{}{}
+	for _, i := range initializers {
+		if _, ok := called[i.unless]; ok && i.unless != "" {
+			continue
+		}
+		fn := mod.ExportedFunction(i.Name)
+		if fn == nil {
+			if i.Required {
+				return fmt.Errorf("module[%s] initializer[%s] is not exported", mod.Name(), i.Name)
+			}
+			continue
+		}
+		if err := callInitializer(ctx, fn, i.Timeout); err != nil {
+			if se, ok := err.(*sys.ExitError); ok {
+				return se // Don't wrap an exit error
+			}
+			return fmt.Errorf("module[%s] initializer[%s] failed: %w", mod.Name(), i.Name, err)
+		}
+		called[i.Name] = struct{}{}
+	}
+	return nil
+}
+
+func callInitializer(ctx context.Context, fn api.Function, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err := fn.Call(ctx)
+	return err
+}
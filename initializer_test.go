@@ -0,0 +1,128 @@
+package wazero
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// initializerWasm exports functions which call the host function "record"
+// with their name, and "loop" which never returns.
+func initializerWasm(exports ...string) []byte {
+	m := &wasm.Module{
+		TypeSection:   []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}}, {}},
+		ImportSection: []wasm.Import{{Module: "host", Name: "record", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	}
+	for i, name := range exports {
+		m.FunctionSection = append(m.FunctionSection, 1)
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: []byte{
+			wasm.OpcodeI32Const, byte(i), wasm.OpcodeCall, 0, wasm.OpcodeEnd,
+		}})
+		m.ExportSection = append(m.ExportSection, wasm.Export{Name: name, Type: wasm.ExternTypeFunc, Index: wasm.Index(i + 1)})
+	}
+	m.FunctionSection = append(m.FunctionSection, 1)
+	m.CodeSection = append(m.CodeSection, wasm.Code{Body: []byte{
+		wasm.OpcodeLoop, 0x40, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd,
+	}})
+	m.ExportSection = append(m.ExportSection, wasm.Export{Name: "loop", Type: wasm.ExternTypeFunc, Index: wasm.Index(len(exports) + 1)})
+	return binaryencoding.EncodeModule(m)
+}
+
+func TestModuleConfig_WithInitializers(t *testing.T) {
+	tests := []struct {
+		name         string
+		exports      []string
+		initializers []Initializer
+		expected     []string
+		expectedErr  string
+	}{
+		{
+			name:     "none",
+			exports:  []string{"_initialize"},
+			expected: nil,
+		},
+		{
+			name:         "reactor _initialize",
+			exports:      []string{"_initialize", "__wasm_call_ctors", "b", "a"},
+			initializers: ReactorInitializers(0, "a", "b"),
+			expected:     []string{"_initialize", "a", "b"},
+		},
+		{
+			name:         "reactor __wasm_call_ctors",
+			exports:      []string{"__wasm_call_ctors", "a"},
+			initializers: ReactorInitializers(0, "a"),
+			expected:     []string{"__wasm_call_ctors", "a"},
+		},
+		{
+			name:         "skips missing",
+			exports:      []string{"a"},
+			initializers: []Initializer{{Name: "b"}, {Name: "a"}},
+			expected:     []string{"a"},
+		},
+		{
+			name:         "required missing",
+			exports:      []string{"_initialize"},
+			initializers: ReactorInitializers(0, "a"),
+			expected:     []string{"_initialize"},
+			expectedErr:  "module[] initializer[a] is not exported",
+		},
+		{
+			name:         "timeout",
+			exports:      []string{"a", "b"},
+			initializers: []Initializer{{Name: "a"}, {Name: "loop", Timeout: time.Millisecond}, {Name: "b"}},
+			expected:     []string{"a"},
+			expectedErr:  "module closed with context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithCloseOnContextDone(true))
+			defer r.Close(testCtx)
+
+			var called []string
+			_, err := r.NewHostModuleBuilder("host").
+				NewFunctionBuilder().WithFunc(func(i uint32) {
+				called = append(called, tc.exports[i])
+			}).Export("record").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			config := NewModuleConfig().WithInitializers(tc.initializers...)
+			mod, err := r.InstantiateWithConfig(testCtx, initializerWasm(tc.exports...), config)
+			require.Equal(t, tc.expected, called)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				require.True(t, mod.IsClosed())
+			} else {
+				require.NoError(t, err)
+				require.False(t, mod.IsClosed())
+			}
+		})
+	}
+}
+
+func TestModuleConfig_WithInitializers_exit(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, _ []uint64) {
+		_ = mod.CloseWithExitCode(ctx, 0)
+		panic(sys.NewExitError(0))
+	}), []api.ValueType{api.ValueTypeI32}, nil).Export("record").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	// Like start functions, an exit code of zero isn't an error.
+	config := NewModuleConfig().WithInitializers(Initializer{Name: "a"})
+	_, err = r.InstantiateWithConfig(testCtx, initializerWasm("a"), config)
+	require.NoError(t, err)
+}
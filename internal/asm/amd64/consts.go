@@ -45,6 +45,8 @@ const (
 const (
 	// NONE is not a real instruction but represents the lack of an instruction
 	NONE asm.Instruction = iota
+	// ADCQ is the ADC instruction in 64-bit mode. https://www.felixcloutier.com/x86/adc
+	ADCQ
 	// ADDL is the ADD instruction in 32-bit mode. https://www.felixcloutier.com/x86/add
 	ADDL
 	// ADDQ is the ADD instruction in 64-bit mode. https://www.felixcloutier.com/x86/add
@@ -239,6 +241,8 @@ const (
 	SARL
 	// SARQ is the SAR instruction in 64-bit mode. https://www.felixcloutier.com/x86/sal:sar:shl:shr
 	SARQ
+	// SBBQ is the SBB instruction in 64-bit mode. https://www.felixcloutier.com/x86/sbb
+	SBBQ
 	// SETCC is the SETAE (set if above or equal) instruction. https://www.felixcloutier.com/x86/setcc
 	SETCC
 	// SETCS is the SETB (set if below) instruction. https://www.felixcloutier.com/x86/setcc
@@ -315,6 +319,8 @@ const (
 	RET
 	// JMP is the JMP instruction. https://www.felixcloutier.com/x86/jmp
 	JMP
+	// CALL is the CALL instruction. https://www.felixcloutier.com/x86/call
+	CALL
 	// NOP is the NOP instruction. https://www.felixcloutier.com/x86/nop
 	NOP
 	// UD2 is the UD2 instruction. https://www.felixcloutier.com/x86/ud
@@ -575,6 +581,8 @@ const (
 // InstructionName returns the name for an instruction
 func InstructionName(instruction asm.Instruction) string {
 	switch instruction {
+	case ADCQ:
+		return "ADCQ"
 	case ADDL:
 		return "ADDL"
 	case ADDQ:
@@ -767,6 +775,8 @@ func InstructionName(instruction asm.Instruction) string {
 		return "SARL"
 	case SARQ:
 		return "SARQ"
+	case SBBQ:
+		return "SBBQ"
 	case SETCC:
 		return "SETCC"
 	case SETCS:
@@ -843,6 +853,8 @@ func InstructionName(instruction asm.Instruction) string {
 		return "RET"
 	case JMP:
 		return "JMP"
+	case CALL:
+		return "CALL"
 	case NOP:
 		return "NOP"
 	case UD2:
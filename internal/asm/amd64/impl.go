@@ -980,6 +980,9 @@ func (a *AssemblerImpl) encodeNoneToRegister(buf asm.Buffer, n *nodeImpl) (err e
 		// JMP's opcode is defined as "FF /4" meaning that we have to have "4"
 		// in 4-6th bits in the ModRM byte. https://www.felixcloutier.com/x86/jmp
 		modRM |= 0b00_100_000
+	} else if n.instruction == CALL {
+		// CALL's opcode is defined as "FF /2". https://www.felixcloutier.com/x86/call
+		modRM |= 0b00_010_000
 	} else if n.instruction == NEGQ {
 		prefix |= rexPrefixW
 		modRM |= 0b00_011_000
@@ -1008,6 +1011,9 @@ func (a *AssemblerImpl) encodeNoneToRegister(buf asm.Buffer, n *nodeImpl) (err e
 	case JMP:
 		// https://www.felixcloutier.com/x86/jmp
 		code = append(code, 0xff, modRM)
+	case CALL:
+		// https://www.felixcloutier.com/x86/call
+		code = append(code, 0xff, modRM)
 	case SETCC:
 		// https://www.felixcloutier.com/x86/setcc
 		code = append(code, 0x0f, 0x93, modRM)
@@ -1236,6 +1242,11 @@ func (a *AssemblerImpl) encodeRegisterToNone(buf asm.Buffer, n *nodeImpl) (err e
 		prefix |= rexPrefixW
 		modRM |= 0b00_111_000
 		opcode = 0xf7
+	case IMULQ:
+		// https://www.felixcloutier.com/x86/imul
+		prefix |= rexPrefixW
+		modRM |= 0b00_101_000
+		opcode = 0xf7
 	case MULL:
 		// https://www.felixcloutier.com/x86/mul
 		modRM |= 0b00_100_000
@@ -1270,6 +1281,8 @@ var registerToRegisterOpcode = [instructionEnd]*struct {
 	isSrc8bit       bool
 	needArg         bool
 }{
+	// https://www.felixcloutier.com/x86/adc
+	ADCQ: {opcode: []byte{0x11}, rPrefix: rexPrefixW, srcOnModRMReg: true},
 	// https://www.felixcloutier.com/x86/add
 	ADDL: {opcode: []byte{0x1}, srcOnModRMReg: true},
 	ADDQ: {opcode: []byte{0x1}, rPrefix: rexPrefixW, srcOnModRMReg: true},
@@ -1367,6 +1380,8 @@ var registerToRegisterOpcode = [instructionEnd]*struct {
 	SQRTSS: {mandatoryPrefix: 0xf3, opcode: []byte{0x0f, 0x51}},
 	// https://www.felixcloutier.com/x86/sqrtsd
 	SQRTSD: {mandatoryPrefix: 0xf2, opcode: []byte{0x0f, 0x51}},
+	// https://www.felixcloutier.com/x86/sbb
+	SBBQ: {opcode: []byte{0x19}, rPrefix: rexPrefixW, srcOnModRMReg: true},
 	// https://www.felixcloutier.com/x86/sub
 	SUBL: {opcode: []byte{0x29}, srcOnModRMReg: true},
 	SUBQ: {opcode: []byte{0x29}, rPrefix: rexPrefixW, srcOnModRMReg: true},
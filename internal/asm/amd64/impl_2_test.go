@@ -60,6 +60,9 @@ func TestAssemblerImpl_EncodeNoneToRegister(t *testing.T) {
 		{name: "inst=JMP/reg=R13", inst: JMP, dst: RegR13, exp: []byte{0x41, 0xff, 0xe5}},
 		{name: "inst=JMP/reg=R14", inst: JMP, dst: RegR14, exp: []byte{0x41, 0xff, 0xe6}},
 		{name: "inst=JMP/reg=R15", inst: JMP, dst: RegR15, exp: []byte{0x41, 0xff, 0xe7}},
+		{name: "inst=CALL/reg=AX", inst: CALL, dst: RegAX, exp: []byte{0xff, 0xd0}},
+		{name: "inst=CALL/reg=CX", inst: CALL, dst: RegCX, exp: []byte{0xff, 0xd1}},
+		{name: "inst=CALL/reg=R11", inst: CALL, dst: RegR11, exp: []byte{0x41, 0xff, 0xd3}},
 		{name: "inst=SETCC/reg=AX", inst: SETCC, dst: RegAX, exp: []byte{0xf, 0x93, 0xc0}},
 		{name: "inst=SETCC/reg=BX", inst: SETCC, dst: RegBX, exp: []byte{0xf, 0x93, 0xc3}},
 		{name: "inst=SETCC/reg=SP", inst: SETCC, dst: RegSP, exp: []byte{0x40, 0xf, 0x93, 0xc4}},
@@ -574,6 +577,9 @@ func TestAssemblerImpl_EncodeRegisterToNone(t *testing.T) {
 		{name: "MULL/reg=R13/", reg: RegR13, inst: MULL, exp: []byte{0x41, 0xf7, 0xe5}},
 		{name: "MULL/reg=R14/", reg: RegR14, inst: MULL, exp: []byte{0x41, 0xf7, 0xe6}},
 		{name: "MULL/reg=R15/", reg: RegR15, inst: MULL, exp: []byte{0x41, 0xf7, 0xe7}},
+		{name: "IMULQ/reg=AX/", reg: RegAX, inst: IMULQ, exp: []byte{0x48, 0xf7, 0xe8}},
+		{name: "IMULQ/reg=BX/", reg: RegBX, inst: IMULQ, exp: []byte{0x48, 0xf7, 0xeb}},
+		{name: "IMULQ/reg=R8/", reg: RegR8, inst: IMULQ, exp: []byte{0x49, 0xf7, 0xe8}},
 		{name: "MULQ/reg=AX/", reg: RegAX, inst: MULQ, exp: []byte{0x48, 0xf7, 0xe0}},
 		{name: "MULQ/reg=BX/", reg: RegBX, inst: MULQ, exp: []byte{0x48, 0xf7, 0xe3}},
 		{name: "MULQ/reg=SP/", reg: RegSP, inst: MULQ, exp: []byte{0x48, 0xf7, 0xe4}},
@@ -834,6 +840,10 @@ func TestAssemblerImpl_EncodeRegisterToRegister(t *testing.T) {
 		{name: "ADDL/src=AX/dst=R8/arg=0", n: &nodeImpl{instruction: ADDL, srcReg: RegAX, dstReg: RegR8, arg: 0x0}, exp: []byte{0x41, 0x1, 0xc0}},
 		{name: "ADDL/src=R8/dst=AX/arg=0", n: &nodeImpl{instruction: ADDL, srcReg: RegR8, dstReg: RegAX, arg: 0x0}, exp: []byte{0x44, 0x1, 0xc0}},
 		{name: "ADDL/src=R8/dst=R8/arg=0", n: &nodeImpl{instruction: ADDL, srcReg: RegR8, dstReg: RegR8, arg: 0x0}, exp: []byte{0x45, 0x1, 0xc0}},
+		{name: "ADCQ/src=AX/dst=CX/arg=0", n: &nodeImpl{instruction: ADCQ, srcReg: RegAX, dstReg: RegCX, arg: 0x0}, exp: []byte{0x48, 0x11, 0xc1}},
+		{name: "ADCQ/src=R8/dst=R15/arg=0", n: &nodeImpl{instruction: ADCQ, srcReg: RegR8, dstReg: RegR15, arg: 0x0}, exp: []byte{0x4d, 0x11, 0xc7}},
+		{name: "SBBQ/src=AX/dst=CX/arg=0", n: &nodeImpl{instruction: SBBQ, srcReg: RegAX, dstReg: RegCX, arg: 0x0}, exp: []byte{0x48, 0x19, 0xc1}},
+		{name: "SBBQ/src=R8/dst=R15/arg=0", n: &nodeImpl{instruction: SBBQ, srcReg: RegR8, dstReg: RegR15, arg: 0x0}, exp: []byte{0x4d, 0x19, 0xc7}},
 		{name: "ADDQ/src=AX/dst=AX/arg=0", n: &nodeImpl{instruction: ADDQ, srcReg: RegAX, dstReg: RegAX, arg: 0x0}, exp: []byte{0x48, 0x1, 0xc0}},
 		{name: "ADDQ/src=AX/dst=R8/arg=0", n: &nodeImpl{instruction: ADDQ, srcReg: RegAX, dstReg: RegR8, arg: 0x0}, exp: []byte{0x49, 0x1, 0xc0}},
 		{name: "ADDQ/src=R8/dst=AX/arg=0", n: &nodeImpl{instruction: ADDQ, srcReg: RegR8, dstReg: RegAX, arg: 0x0}, exp: []byte{0x4c, 0x1, 0xc0}},
@@ -453,6 +453,8 @@ const (
 	NOP asm.Instruction = iota
 	// RET is the RET instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/RET
 	RET
+	// ADC is the ADC instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/ADC
+	ADC
 	// ADD is the ADD instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/ADD--shifted-register-
 	ADD
 	// ADDS is the ADDS instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/ADDS--shifted-register-
@@ -677,6 +679,8 @@ const (
 	ROR
 	// RORW is the RORW instruction, in 64-bit mode. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/ROR--register-
 	RORW
+	// SBC is the SBC instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/SBC
+	SBC
 	// SCVTFD is the SCVTF instruction, for double precision. https://developer.arm.com/documentation/dui0802/a/A64-Floating-point-Instructions/SCVTF--scalar--integer-
 	SCVTFD
 	// SCVTFS is the SCVTF instruction, for single precision. https://developer.arm.com/documentation/dui0802/a/A64-Floating-point-Instructions/SCVTF--scalar--integer-
@@ -689,6 +693,8 @@ const (
 	SDIV
 	// SDIVW is the SDIV instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/SDIV
 	SDIVW
+	// SMULH is the SMULH instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/SMULH
+	SMULH
 	// SUB is the SUB instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/SUB--shifted-register-
 	SUB
 	// SUBS is the SUBS instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/SUBS--shifted-register-
@@ -717,6 +723,8 @@ const (
 	UDIV
 	// UDIVW is the UDIV instruction, in 64-bit mode. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/UDIV
 	UDIVW
+	// UMULH is the UMULH instruction. https://developer.arm.com/documentation/dui0802/a/A64-General-Instructions/UMULH
+	UMULH
 	// VBIT is the BIT instruction. https://developer.arm.com/documentation/dui0802/a/A64-Advanced-SIMD-Vector-Instructions/BIT--vector-
 	VBIT
 	// VCNT is the CNT instruction. https://developer.arm.com/documentation/dui0802/a/A64-Advanced-SIMD-Vector-Instructions/CNT--vector-
@@ -1027,6 +1035,8 @@ func InstructionName(i asm.Instruction) string {
 		return "NOP"
 	case RET:
 		return "RET"
+	case ADC:
+		return "ADC"
 	case ADD:
 		return "ADD"
 	case ADDS:
@@ -1233,6 +1243,8 @@ func InstructionName(i asm.Instruction) string {
 		return "ROR"
 	case RORW:
 		return "RORW"
+	case SBC:
+		return "SBC"
 	case SCVTFD:
 		return "SCVTFD"
 	case SCVTFS:
@@ -1245,6 +1257,8 @@ func InstructionName(i asm.Instruction) string {
 		return "SDIV"
 	case SDIVW:
 		return "SDIVW"
+	case SMULH:
+		return "SMULH"
 	case SUB:
 		return "SUB"
 	case SUBS:
@@ -1273,6 +1287,8 @@ func InstructionName(i asm.Instruction) string {
 		return "UDIV"
 	case UDIVW:
 		return "UDIVW"
+	case UMULH:
+		return "UMULH"
 	case VBIT:
 		return "VBIT"
 	case VCNT:
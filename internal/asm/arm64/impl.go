@@ -1550,6 +1550,54 @@ func (a *AssemblerImpl) encodeTwoRegistersToRegister(buf asm.Buffer, n *nodeImpl
 			srcRegBits,
 			sf<<7|0b0_10_01011,
 		)
+	case ADDS, SUBS:
+		srcRegBits, srcReg2Bits, dstRegBits := registerBits(n.srcReg), registerBits(n.srcReg2), registerBits(n.dstReg)
+
+		// See "Add/subtract (shifted register)" in
+		// https://developer.arm.com/documentation/ddi0596/2021-12/Index-by-Encoding/Data-Processing----Register?lang=en
+		var op byte
+		if inst == SUBS {
+			op = 0b1
+		}
+
+		buf.Append4Bytes(
+			(srcReg2Bits<<5)|dstRegBits,
+			srcReg2Bits>>3,
+			srcRegBits,
+			0b1<<7|op<<6|0b1_01011,
+		)
+	case ADC, SBC:
+		srcRegBits, srcReg2Bits, dstRegBits := registerBits(n.srcReg), registerBits(n.srcReg2), registerBits(n.dstReg)
+
+		// See "Add/subtract (with carry)" in
+		// https://developer.arm.com/documentation/ddi0596/2021-12/Index-by-Encoding/Data-Processing----Register?lang=en
+		var op byte
+		if inst == SBC {
+			op = 0b1
+		}
+
+		buf.Append4Bytes(
+			(srcReg2Bits<<5)|dstRegBits,
+			srcReg2Bits>>3,
+			srcRegBits,
+			0b1<<7|op<<6|0b0_11010,
+		)
+	case SMULH, UMULH:
+		srcRegBits, srcReg2Bits, dstRegBits := registerBits(n.srcReg), registerBits(n.srcReg2), registerBits(n.dstReg)
+
+		// See "Data-processing (3 source)" in
+		// https://developer.arm.com/documentation/ddi0596/2021-12/Index-by-Encoding/Data-Processing----Register?lang=en
+		var u byte
+		if inst == UMULH {
+			u = 0b1
+		}
+
+		buf.Append4Bytes(
+			(srcReg2Bits<<5)|dstRegBits,
+			zeroRegisterBits<<2|srcReg2Bits>>3,
+			u<<7|0b10<<5|srcRegBits,
+			0b1_00_11011,
+		)
 	case FSUBD, FSUBS:
 		srcRegBits, srcReg2Bits, dstRegBits := registerBits(n.srcReg), registerBits(n.srcReg2), registerBits(n.dstReg)
 
@@ -529,6 +529,14 @@ func TestAssemblerImpl_EncodeTwoRegistersToRegister(t *testing.T) {
 		{name: "src=R30,src2=R30,dst=RZR", inst: UDIVW, src: RegR30, src2: RegR30, dst: RegRZR, exp: []byte{0xdf, 0xb, 0xde, 0x1a}},
 		{name: "src=R30,src2=R30,dst=R10", inst: UDIVW, src: RegR30, src2: RegR30, dst: RegR10, exp: []byte{0xca, 0xb, 0xde, 0x1a}},
 		{name: "src=R30,src2=R30,dst=R30", inst: UDIVW, src: RegR30, src2: RegR30, dst: RegR30, exp: []byte{0xde, 0xb, 0xde, 0x1a}},
+		{name: "src=R2,src2=R1,dst=R0", inst: ADDS, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x0, 0x2, 0xab}},
+		{name: "src=R2,src2=R1,dst=R0", inst: SUBS, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x0, 0x2, 0xeb}},
+		{name: "src=R2,src2=R1,dst=R0", inst: ADC, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x0, 0x2, 0x9a}},
+		{name: "src=R30,src2=R10,dst=R11", inst: ADC, src: RegR30, src2: RegR10, dst: RegR11, exp: []byte{0x4b, 0x1, 0x1e, 0x9a}},
+		{name: "src=R2,src2=R1,dst=R0", inst: SBC, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x0, 0x2, 0xda}},
+		{name: "src=R2,src2=R1,dst=R0", inst: UMULH, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x7c, 0xc2, 0x9b}},
+		{name: "src=R30,src2=R10,dst=R11", inst: UMULH, src: RegR30, src2: RegR10, dst: RegR11, exp: []byte{0x4b, 0x7d, 0xde, 0x9b}},
+		{name: "src=R2,src2=R1,dst=R0", inst: SMULH, src: RegR2, src2: RegR1, dst: RegR0, exp: []byte{0x20, 0x7c, 0x42, 0x9b}},
 		{name: "src=RZR,src2=RZR,dst=RZR", inst: SUB, src: RegRZR, src2: RegRZR, dst: RegRZR, exp: []byte{0xff, 0x3, 0x1f, 0xcb}},
 		{name: "src=RZR,src2=RZR,dst=R10", inst: SUB, src: RegRZR, src2: RegRZR, dst: RegR10, exp: []byte{0xea, 0x3, 0x1f, 0xcb}},
 		{name: "src=RZR,src2=RZR,dst=R30", inst: SUB, src: RegRZR, src2: RegRZR, dst: RegR30, exp: []byte{0xfe, 0x3, 0x1f, 0xcb}},
@@ -0,0 +1,52 @@
+package emscripten
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// FunctionFlock is the syscall Emscripten imports to implement `flock`.
+//
+// Emscripten's default implementation succeeds without locking, which
+// corrupts data such as SQLite databases when multiple module instances, or
+// processes, share a mounted file. This implementation places an advisory
+// lock on the host file instead. Files which don't support locks, such as
+// those in an fs.FS, behave like the default.
+//
+//	int __syscall_flock(int fd, int operation);
+//
+// See https://github.com/emscripten-core/emscripten/blob/3.1.44/src/library_syscall.js
+const FunctionFlock = "__syscall_flock"
+
+var Flock = &wasm.HostFunc{
+	ExportName:  FunctionFlock,
+	Name:        FunctionFlock,
+	ParamTypes:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32},
+	ParamNames:  []string{"fd", "operation"},
+	ResultTypes: []api.ValueType{api.ValueTypeI32},
+	Code:        wasm.Code{GoFunc: api.GoModuleFunc(flock)},
+}
+
+func flock(_ context.Context, mod api.Module, stack []uint64) {
+	fd, how := int32(stack[0]), int(int32(stack[1]))
+	stack[0] = uint64(negErrno(flockFile(mod, fd, how)))
+}
+
+func flockFile(mod api.Module, fd int32, how int) experimentalsys.Errno {
+	f, errno := lookupFile(mod, fd)
+	if errno != 0 {
+		return errno
+	}
+	lf, ok := f.(sysfs.LockFile)
+	if !ok {
+		return 0 // not lockable, so pretend like Emscripten does.
+	}
+	if errno = lf.Flock(how); errno == experimentalsys.ENOSYS {
+		return 0 // the platform can't lock, so pretend like Emscripten does.
+	}
+	return errno
+}
@@ -0,0 +1,120 @@
+package emscripten
+
+import (
+	"context"
+	"encoding/binary"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// FunctionIoctl is the syscall Emscripten imports to implement `ioctl`.
+//
+// This only supports terminal requests on stdio configured by
+// wazero.ModuleConfig WithTerminal, and returns ENOTTY for other files, like
+// Emscripten does for files which aren't TTYs.
+//
+//	int __syscall_ioctl(int fd, int op, ...);
+//
+// See https://github.com/emscripten-core/emscripten/blob/3.1.44/src/library_syscall.js#L400-L468
+const FunctionIoctl = "__syscall_ioctl"
+
+// Terminal ioctl requests, as defined in musl's bits/ioctl.h.
+const (
+	tcgets     = 0x5401
+	tcsets     = 0x5402
+	tcsetsw    = 0x5403
+	tcsetsf    = 0x5404
+	tiocgwinsz = 0x5413
+	tiocswinsz = 0x5414
+)
+
+// termios field offsets in musl's struct termios. There is a one byte
+// c_line between c_lflag and c_cc.
+const (
+	termiosCcOffset = 17
+	termiosSize     = termiosCcOffset + 32
+)
+
+var Ioctl = &wasm.HostFunc{
+	ExportName:  FunctionIoctl,
+	Name:        FunctionIoctl,
+	ParamTypes:  []api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+	ParamNames:  []string{"fd", "op", "varargs"},
+	ResultTypes: []api.ValueType{api.ValueTypeI32},
+	Code:        wasm.Code{GoFunc: api.GoModuleFunc(ioctl)},
+}
+
+func ioctl(_ context.Context, mod api.Module, stack []uint64) {
+	fd, op, varargs := int32(stack[0]), uint32(stack[1]), uint32(stack[2])
+	stack[0] = uint64(negErrno(ioctlTerminal(mod, fd, op, varargs)))
+}
+
+func ioctlTerminal(mod api.Module, fd int32, op, varargs uint32) experimentalsys.Errno {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return experimentalsys.EBADF
+	}
+	t, errno := sysCtx.FS().Terminal(fd)
+	if errno != 0 {
+		return errno
+	}
+
+	mem := mod.Memory()
+	argp, ok := mem.ReadUint32Le(varargs)
+	if !ok {
+		return experimentalsys.EFAULT
+	}
+
+	switch op {
+	case tcgets:
+		if buf, ok := mem.Read(argp, termiosSize); !ok {
+			return experimentalsys.EFAULT
+		} else {
+			writeTermios(buf, t.Termios())
+		}
+	case tcsets, tcsetsw, tcsetsf:
+		if buf, ok := mem.Read(argp, termiosSize); !ok {
+			return experimentalsys.EFAULT
+		} else {
+			t.SetTermios(readTermios(buf))
+		}
+	case tiocgwinsz:
+		rows, cols := t.Winsize()
+		if !mem.WriteUint16Le(argp, rows) || !mem.WriteUint16Le(argp+2, cols) {
+			return experimentalsys.EFAULT
+		}
+	case tiocswinsz:
+		rows, ok := mem.ReadUint16Le(argp)
+		if !ok {
+			return experimentalsys.EFAULT
+		}
+		cols, ok := mem.ReadUint16Le(argp + 2)
+		if !ok {
+			return experimentalsys.EFAULT
+		}
+		t.SetWinsize(rows, cols)
+	default:
+		return experimentalsys.EINVAL
+	}
+	return 0
+}
+
+func writeTermios(buf []byte, termios internalsys.Termios) {
+	binary.LittleEndian.PutUint32(buf, termios.Iflag)
+	binary.LittleEndian.PutUint32(buf[4:], termios.Oflag)
+	binary.LittleEndian.PutUint32(buf[8:], termios.Cflag)
+	binary.LittleEndian.PutUint32(buf[12:], termios.Lflag)
+	copy(buf[termiosCcOffset:], termios.Cc[:])
+}
+
+func readTermios(buf []byte) (termios internalsys.Termios) {
+	termios.Iflag = binary.LittleEndian.Uint32(buf)
+	termios.Oflag = binary.LittleEndian.Uint32(buf[4:])
+	termios.Cflag = binary.LittleEndian.Uint32(buf[8:])
+	termios.Lflag = binary.LittleEndian.Uint32(buf[12:])
+	copy(termios.Cc[:], buf[termiosCcOffset:])
+	return
+}
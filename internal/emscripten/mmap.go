@@ -0,0 +1,227 @@
+package emscripten
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Emscripten implements `mmap`, `munmap` and `msync` of files with these
+// host functions, as the guest can't map files itself. The guest allocates
+// and frees the memory of the mapping, and the host copies the file into or
+// out of it.
+//
+// Memory can't be mapped lazily by the host, as there is no way to intercept
+// access to it. Hence, `_mmap_js` reads the whole range eagerly. Changes to a
+// shared, writable mapping are written back to the file on `msync` or
+// `munmap`, up to the end of the file when it was mapped.
+//
+// See https://github.com/emscripten-core/emscripten/blob/3.1.44/src/library_syscall.js
+const (
+	FunctionMmapJs   = "_mmap_js"
+	FunctionMunmapJs = "_munmap_js"
+	FunctionMsyncJs  = "_msync_js"
+)
+
+// Emscripten uses the same errno values as WASI, and the same values as
+// Linux for mmap flags.
+const (
+	protWrite      = 0x2
+	mapShared      = 0x01
+	mmapAlign      = 65536
+	exportMemalign = "emscripten_builtin_memalign"
+)
+
+// NewMmapJs returns the function `_mmap_js` for the given signature, which
+// has an i64 offset, or two i32 (low and high bits) when legalized.
+//
+//	int _mmap_js(size_t len, int prot, int flags, int fd, off_t offset, int* allocated, void** addr);
+func NewMmapJs(params []api.ValueType) *wasm.HostFunc {
+	return &wasm.HostFunc{
+		ExportName:  FunctionMmapJs,
+		Name:        FunctionMmapJs,
+		ParamTypes:  params,
+		ParamNames:  mmapParamNames(params, "len", "prot", "flags", "fd", "offset", "allocated", "addr"),
+		ResultTypes: []api.ValueType{api.ValueTypeI32},
+		Code:        wasm.Code{GoFunc: api.GoModuleFunc(mmapJs)},
+	}
+}
+
+// NewMunmapJs returns the function `_munmap_js` for the given signature. See
+// NewMmapJs for notes on the offset.
+//
+//	int _munmap_js(void* addr, size_t len, int prot, int flags, int fd, off_t offset);
+func NewMunmapJs(params []api.ValueType) *wasm.HostFunc {
+	return newWritebackFunc(FunctionMunmapJs, params, true)
+}
+
+// NewMsyncJs returns the function `_msync_js` for the given signature. See
+// NewMmapJs for notes on the offset.
+//
+//	int _msync_js(void* addr, size_t len, int prot, int flags, int fd, off_t offset);
+func NewMsyncJs(params []api.ValueType) *wasm.HostFunc {
+	return newWritebackFunc(FunctionMsyncJs, params, false)
+}
+
+func newWritebackFunc(name string, params []api.ValueType, unmap bool) *wasm.HostFunc {
+	return &wasm.HostFunc{
+		ExportName:  name,
+		Name:        name,
+		ParamTypes:  params,
+		ParamNames:  mmapParamNames(params, "addr", "len", "prot", "flags", "fd", "offset"),
+		ResultTypes: []api.ValueType{api.ValueTypeI32},
+		Code: wasm.Code{GoFunc: api.GoModuleFunc(func(_ context.Context, mod api.Module, stack []uint64) {
+			writeback(mod, stack, unmap)
+		})},
+	}
+}
+
+// mmapParamNames returns `names`, splitting "offset" into two when the
+// signature has more parameters than names.
+func mmapParamNames(params []api.ValueType, names ...string) []string {
+	if len(params) == len(names) {
+		return names
+	}
+	ret := make([]string, 0, len(names)+1)
+	for _, n := range names {
+		if n == "offset" {
+			ret = append(ret, "offset_low", "offset_high")
+		} else {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+// mmapOffset reads the offset at `stack[i]`, returning the index after it.
+func mmapOffset(stack []uint64, i, legalizedLen int) (int64, int) {
+	if len(stack) == legalizedLen {
+		return int64(uint32(stack[i])) | int64(uint32(stack[i+1]))<<32, i + 2
+	}
+	return int64(stack[i]), i + 1
+}
+
+func mmapJs(ctx context.Context, mod api.Module, stack []uint64) {
+	length, fd := uint32(stack[0]), int32(stack[3]) // prot and flags are ignored
+	offset, i := mmapOffset(stack, 4, 8)
+	allocated, addr := uint32(stack[i]), uint32(stack[i+1])
+	stack[0] = uint64(-int32(mmapFile(ctx, mod, length, fd, offset, allocated, addr)))
+}
+
+func mmapFile(ctx context.Context, mod api.Module, length uint32, fd int32, offset int64, allocated, addr uint32) wasip1.Errno {
+	f, errno := lookupFileEntry(mod, fd)
+	if errno != 0 {
+		return wasip1.ToErrno(errno)
+	}
+
+	memalign := mod.ExportedFunction(exportMemalign)
+	if memalign == nil {
+		return wasip1.ErrnoNosys
+	}
+	results, err := memalign.Call(ctx, mmapAlign, uint64(length))
+	if err != nil {
+		panic(err)
+	}
+	ptr := uint32(results[0])
+	if ptr == 0 {
+		return wasip1.ErrnoNomem
+	}
+
+	mem := mod.Memory()
+	buf, ok := mem.Read(ptr, length)
+	if !ok {
+		return wasip1.ErrnoFault
+	}
+	n, errno := f.File.Pread(buf, offset)
+	if errno != 0 {
+		return wasip1.ToErrno(errno)
+	}
+	// Zero any bytes after the end of the file, as the allocation may have
+	// been used before.
+	for j := range buf[n:] {
+		buf[n+j] = 0
+	}
+
+	if !mem.WriteUint32Le(allocated, 1) || !mem.WriteUint32Le(addr, ptr) {
+		return wasip1.ErrnoFault
+	}
+	if f.Mappings == nil {
+		f.Mappings = map[uint32]uint32{}
+	}
+	f.Mappings[ptr] = uint32(n)
+	return 0
+}
+
+func writeback(mod api.Module, stack []uint64, unmap bool) {
+	addr, length, prot, flags, fd := uint32(stack[0]), uint32(stack[1]), uint32(stack[2]), uint32(stack[3]), int32(stack[4])
+	offset, _ := mmapOffset(stack, 5, 7)
+	f, errno := lookupFileEntry(mod, fd)
+	// Only shared, writable mappings write changes to the file.
+	if flags&mapShared != 0 && prot&protWrite != 0 {
+		if errno == 0 {
+			errno = writebackFile(mod, f, addr, length, offset)
+		}
+	} else {
+		errno = 0
+	}
+	if f != nil && unmap {
+		delete(f.Mappings, addr)
+	}
+	stack[0] = uint64(negErrno(errno))
+}
+
+func writebackFile(mod api.Module, f *internalsys.FileEntry, addr, length uint32, offset int64) experimentalsys.Errno {
+	// Don't write the part of the mapping past the end of the file, as of
+	// when it was mapped, as that would grow the file.
+	if size, ok := f.Mappings[addr]; ok && length > size {
+		length = size
+	}
+	buf, ok := mod.Memory().Read(addr, length)
+	if !ok {
+		return experimentalsys.EFAULT
+	}
+	for len(buf) > 0 {
+		n, errno := f.File.Pwrite(buf, offset)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return experimentalsys.EIO
+		}
+		buf = buf[n:]
+		offset += int64(n)
+	}
+	return 0
+}
+
+func lookupFile(mod api.Module, fd int32) (experimentalsys.File, experimentalsys.Errno) {
+	if f, errno := lookupFileEntry(mod, fd); errno != 0 {
+		return nil, errno
+	} else {
+		return f.File, 0
+	}
+}
+
+func lookupFileEntry(mod api.Module, fd int32) (*internalsys.FileEntry, experimentalsys.Errno) {
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	if sysCtx == nil {
+		return nil, experimentalsys.EBADF
+	}
+	if f, ok := sysCtx.FS().LookupFile(fd); !ok {
+		return nil, experimentalsys.EBADF
+	} else {
+		return f, 0
+	}
+}
+
+// negErrno returns the negative WASI errno, which is how Emscripten host
+// functions report errors, or zero on success.
+func negErrno(errno experimentalsys.Errno) int32 {
+	if errno == 0 {
+		return 0
+	}
+	return -int32(wasip1.ToErrno(errno))
+}
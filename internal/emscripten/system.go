@@ -0,0 +1,130 @@
+package emscripten
+
+import (
+	"context"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/spawn"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// FunctionSystem is the function Emscripten imports to implement `system`.
+//
+// Emscripten's default implementation fails with ENOSYS outside Node.js.
+// This implementation runs the command with the spawn.Spawner of the
+// context, as "sh -c command", and waits for it. The child inherits the stdio
+// of the caller. Without a spawn.Spawner, this behaves like the default.
+//
+//	int system(const char *command);
+//
+// See https://github.com/emscripten-core/emscripten/blob/3.1.44/src/library.js#L336-L382
+const FunctionSystem = "system"
+
+var System = &wasm.HostFunc{
+	ExportName:  FunctionSystem,
+	Name:        FunctionSystem,
+	ParamTypes:  []api.ValueType{api.ValueTypeI32},
+	ParamNames:  []string{"command"},
+	ResultTypes: []api.ValueType{api.ValueTypeI32},
+	Code:        wasm.Code{GoFunc: api.GoModuleFunc(system)},
+}
+
+func system(ctx context.Context, mod api.Module, stack []uint64) {
+	command := uint32(stack[0])
+	spawner, _ := ctx.Value(spawn.SpawnerKey{}).(spawn.Spawner)
+
+	// A NULL command checks whether a shell is available.
+	if command == 0 {
+		if spawner != nil {
+			stack[0] = 1
+		} else {
+			stack[0] = 0
+		}
+		return
+	}
+
+	if spawner == nil {
+		stack[0] = api.EncodeI32(-1)
+		return
+	}
+	cmd, ok := readCString(mod.Memory(), command)
+	if !ok {
+		panic("out of memory reading command")
+	}
+
+	var stdio [3]experimentalsys.File
+	for fd := range stdio {
+		f, errno := lookupFile(mod, int32(fd))
+		if errno != 0 {
+			stack[0] = api.EncodeI32(-1)
+			return
+		}
+		stdio[fd] = f
+	}
+
+	wait, err := spawner(ctx, spawn.Request{
+		Parent: mod,
+		Path:   "sh",
+		Args:   []string{"sh", "-c", cmd},
+		Stdin:  &fileReader{stdio[0]},
+		Stdout: &fileWriter{stdio[1]},
+		Stderr: &fileWriter{stdio[2]},
+	})
+	if err != nil {
+		stack[0] = api.EncodeI32(-1)
+		return
+	}
+	// Encode the exit code like the wait status of WEXITSTATUS.
+	stack[0] = uint64((wait() & 0xff) << 8)
+}
+
+// readCString reads the NUL-terminated string at `offset`.
+func readCString(mem api.Memory, offset uint32) (string, bool) {
+	for end := offset; ; end++ {
+		b, ok := mem.ReadByte(end)
+		if !ok {
+			return "", false
+		} else if b == 0 {
+			buf, _ := mem.Read(offset, end-offset)
+			return string(buf), true
+		}
+	}
+}
+
+// fileReader adapts a guest file to an io.Reader for a child process.
+type fileReader struct {
+	f experimentalsys.File
+}
+
+// Read implements io.Reader
+func (r *fileReader) Read(buf []byte) (int, error) {
+	n, errno := r.f.Read(buf)
+	if errno != 0 {
+		return n, errno
+	} else if n == 0 && len(buf) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// fileWriter adapts a guest file to an io.Writer for a child process.
+type fileWriter struct {
+	f experimentalsys.File
+}
+
+// Write implements io.Writer
+func (w *fileWriter) Write(buf []byte) (int, error) {
+	var written int
+	for written < len(buf) {
+		n, errno := w.f.Write(buf[written:])
+		written += n
+		if errno != 0 {
+			return written, errno
+		} else if n == 0 {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,210 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/asm"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/u32"
+	"github.com/tetratelabs/wazero/internal/u64"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// AOTEmitKey is a context.Context key of an AOTEmitter, which CompileModule
+// calls with each module it compiles or finds in memory. This is used by
+// experimental/aot.
+type AOTEmitKey struct{}
+
+// AOTEmitter receives the header and the native code of a compiled module.
+// Both are only valid during the call.
+type AOTEmitter func(id wasm.ModuleID, header, code []byte)
+
+// AOTLoadKey is a context.Context key of an AOTLibrary, which CompileModule
+// loads instead of compiling a module. This is used by experimental/aot.
+type AOTLoadKey struct{}
+
+// AOTLibrary holds what was previously passed to an AOTEmitter.
+type AOTLibrary struct {
+	// Name identifies the library in errors.
+	Name   string
+	ID     wasm.ModuleID
+	Header []byte
+	// MapCode returns the native code, which must already be mapped as
+	// read-only, executable memory backed by a file, which the engine unmaps
+	// when the compiled module is released. This isn't called if the code is
+	// empty, or the library can't be loaded.
+	MapCode func() ([]byte, error)
+}
+
+// IsAOTSupported returns true if the engine can emit and load an AOTLibrary,
+// which is the case of this engine.
+func IsAOTSupported(e wasm.Engine) bool {
+	_, ok := e.(*engine)
+	return ok
+}
+
+// checkAOT returns an error if the module can't be emitted or loaded, which
+// is the case of modules with intrinsics, as their code is defined in Go.
+func checkAOT(module *wasm.Module) error {
+	for i := range module.CodeSection {
+		if module.CodeSection[i].GoFunc != nil {
+			def := module.FunctionDefinition(module.ImportFunctionCount + wasm.Index(i))
+			return fmt.Errorf("aot: func[%s] is an intrinsic", def.DebugName())
+		}
+	}
+	return nil
+}
+
+// emitAOT calls the AOTEmitter of the context, if any.
+//
+// Besides the code of the module, this emits a trampoline for the type of
+// each imported function, which ResolveImportedFunction uses when it
+// imports a Go-defined host function, so that loadAOT never compiles code.
+// The header of the library starts with the offset of the trampoline of each
+// imported function, followed by the header of serializeCompiledModule.
+func (e *engine) emitAOT(ctx context.Context, module *wasm.Module, cm *compiledModule, withListener bool) error {
+	emit, ok := ctx.Value(AOTEmitKey{}).(AOTEmitter)
+	if !ok || module.IsHostModule {
+		return nil
+	}
+
+	code := cm.executable.Bytes()
+	base := (len(code) + 15) &^ 15
+	trampolines, offsets, err := compileTrampolines(module, withListener)
+	defer func() {
+		if err := trampolines.Unmap(); err != nil {
+			panic(fmt.Errorf("compiler: failed to munmap code segment: %w", err))
+		}
+	}()
+	if err != nil {
+		return err
+	}
+	if n := int(trampolines.Size()); n > 0 {
+		code = append(append(code[:len(code):len(code)], make([]byte, base-len(code))...), trampolines.Bytes()[:n]...)
+	}
+
+	var header bytes.Buffer
+	header.Write(u32.LeBytes(uint32(len(offsets))))
+	for _, offset := range offsets {
+		header.Write(u64.LeBytes(uint64(base) + uint64(offset)))
+	}
+	writeCompiledModuleHeader(&header, e.wazeroVersion, cm, len(code))
+	emit(module.ID, header.Bytes(), code)
+	return nil
+}
+
+// compileTrampolines compiles a trampoline which calls a Go-defined host
+// function for each distinct type of the imported functions, returning the
+// offset of the trampoline of each imported function.
+func compileTrampolines(module *wasm.Module, withListener bool) (executable asm.CodeSegment, offsets []uintptr, err error) {
+	offsets = make([]uintptr, 0, module.ImportFunctionCount)
+	byType := map[wasm.Index]uintptr{}
+	cmp := newCompiler()
+	for i := range module.ImportSection {
+		imp := &module.ImportSection[i]
+		if imp.Type != wasm.ExternTypeFunc {
+			continue
+		}
+		offset, ok := byType[imp.DescFunc]
+		if !ok {
+			buf := executable.NextCodeSection()
+			offset = executable.Size()
+			cmp.Init(&module.TypeSection[imp.DescFunc], nil, withListener)
+			if err = compileGoDefinedHostFunction(buf, cmp, false); err != nil {
+				err = fmt.Errorf("error compiling trampoline of import[%d]: %w", i, err)
+				return
+			}
+			byType[imp.DescFunc] = offset
+		}
+		offsets = append(offsets, offset)
+	}
+	return
+}
+
+// loadAOT adds the compiled module of the library, instead of compiling it.
+func (e *engine) loadAOT(module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool, lib *AOTLibrary) error {
+	header := lib.Header
+	if len(header) < 4 {
+		return fmt.Errorf("aot: %s has an invalid header", lib.Name)
+	}
+	trampolinesNum := int(binary.LittleEndian.Uint32(header))
+	if header = header[4:]; len(header) < trampolinesNum*8 {
+		return fmt.Errorf("aot: %s has an invalid header", lib.Name)
+	}
+	trampolines := make([]uintptr, trampolinesNum)
+	for i := range trampolines {
+		trampolines[i] = uintptr(binary.LittleEndian.Uint64(header[i*8:]))
+	}
+	header = header[trampolinesNum*8:]
+
+	// Check the configuration before the module, as it's part of its ID.
+	versionEnd := len(wazeroMagic) + 1 + len(e.wazeroVersion)
+	if len(header) < versionEnd+1+4+8 /* ensure termination, number of functions, code size */ {
+		return fmt.Errorf("aot: %s has an invalid header", lib.Name)
+	} else if string(header[len(wazeroMagic)+1:versionEnd]) != e.wazeroVersion {
+		return fmt.Errorf("aot: %s was emitted by a different version of wazero", lib.Name)
+	} else if emitted := header[versionEnd] != 0; emitted != ensureTermination {
+		return fmt.Errorf("aot: %s was emitted by a runtime configured WithCloseOnContextDone(%t)", lib.Name, emitted)
+	} else if lib.ID != module.ID {
+		return fmt.Errorf("aot: %s was emitted for a different module", lib.Name)
+	} else if len(trampolines) != int(module.ImportFunctionCount) {
+		return fmt.Errorf("aot: %s has an invalid header", lib.Name)
+	}
+
+	// The header ends with the size of the code. Check it here, as otherwise
+	// deserializeCompiledModule would copy missing code into anonymous,
+	// writable memory, instead of using the read-only mapping of the file.
+	codeSize := binary.LittleEndian.Uint64(header[len(header)-8:])
+	var code []byte
+	if codeSize > 0 {
+		var err error
+		if code, err = lib.MapCode(); err != nil {
+			return err
+		}
+	}
+
+	var cm *compiledModule
+	var err error
+	if uint64(len(code)) != codeSize {
+		err = fmt.Errorf("aot: code size doesn't match the header: %d", len(code))
+	} else {
+		var staleCache bool
+		cm, staleCache, err = deserializeCompiledModule(e.wazeroVersion, io.NopCloser(bytes.NewReader(header)), module, code)
+		if err == nil && staleCache {
+			err = errors.New("aot: emitted by a different version of wazero")
+		}
+	}
+	if err != nil {
+		if code != nil {
+			_ = platform.MunmapCodeSegment(code)
+		}
+		return err
+	}
+	cm.source = module
+	for i := range cm.functions {
+		if i < len(listeners) {
+			cm.functions[i].listener = listeners[i]
+		}
+	}
+	cm.trampolines = trampolines
+	cm.trampolinesWithListener = len(listeners) > 0
+	e.setFinalizer(cm, releaseCompiledModule)
+	e.addCompiledModuleToMemory(module, cm)
+	return nil
+}
+
+// trampoline returns the address of the trampoline loaded by loadAOT, which
+// calls the Go-defined host function `f` imported at `index`, if any.
+func (cm *compiledModule) trampoline(index wasm.Index, f *compiledFunction) (uintptr, bool) {
+	if int(index) >= len(cm.trampolines) || f.goFunc == nil || f.goFuncLeaf || f.goFuncIntrinsic ||
+		(f.listener != nil) != cm.trampolinesWithListener {
+		return 0, false
+	}
+	return cm.executable.Addr() + cm.trampolines[index], true
+}
@@ -21,7 +21,11 @@ type compiler interface {
 	compile(buf asm.Buffer) (stackPointerCeil uint64, err error)
 	// compileGoHostFunction adds the trampoline code from which native code can jump into the Go-defined host function.
 	// TODO: maybe we wouldn't need to have trampoline for host functions.
-	compileGoDefinedHostFunction() error
+	// leaf is true when the function can't change the module state. See wasm.NativeFunction.
+	compileGoDefinedHostFunction(leaf bool) error
+	// compileNativeHostFunction adds the code which calls the machine code at `address` with the address of the
+	// host function's stack. See wasm.NativeFunction.
+	compileNativeHostFunction(address uintptr) error
 	// compileLabel notify compilers of the beginning of a label.
 	// Return true if the compiler decided to skip the entire label.
 	// See wazeroir.NewOperationLabel
@@ -56,6 +60,12 @@ type compiler interface {
 	compileSub(o *wazeroir.UnionOperation) error
 	// compileMul adds instructions to perform wazeroir.OperationMul.
 	compileMul(o *wazeroir.UnionOperation) error
+	// compileI64Add128 adds instructions to perform wazeroir.NewOperationI64Add128.
+	compileI64Add128() error
+	// compileI64Sub128 adds instructions to perform wazeroir.NewOperationI64Sub128.
+	compileI64Sub128() error
+	// compileI64MulWide adds instructions to perform wazeroir.NewOperationI64MulWide.
+	compileI64MulWide(o *wazeroir.UnionOperation) error
 	// compileClz adds instructions to perform wazeroir.OperationClz.
 	compileClz(o *wazeroir.UnionOperation) error
 	// compileCtz adds instructions to perform wazeroir.OperationCtz.
@@ -153,7 +163,7 @@ type compiler interface {
 	// compileMemorySize adds instruction to perform wazeroir.OperationMemoryGrow.
 	compileMemoryGrow() error
 	// compileMemorySize adds instruction to perform wazeroir.OperationMemorySize.
-	compileMemorySize() error
+	compileMemorySize(o *wazeroir.UnionOperation) error
 	// compileConstI32 adds instruction to perform wazeroir.NewOperationConstI32.
 	compileConstI32(o *wazeroir.UnionOperation) error
 	// compileConstI64 adds instruction to perform wazeroir.NewOperationConstI64.
@@ -303,6 +313,9 @@ type compiler interface {
 
 	// compileBuiltinFunctionCheckExitCode adds instructions to perform wazeroir.OperationBuiltinFunctionCheckExitCode.
 	compileBuiltinFunctionCheckExitCode() error
+	// compileAtomic adds instructions to perform the atomic operations of the threads proposal, e.g.
+	// wazeroir.NewOperationAtomicLoad, by calling builtinFunctionIndexAtomic.
+	compileAtomic(o *wazeroir.UnionOperation) error
 
 	// compileReleaseRegisterToStack adds instructions to write the value on a register back to memory stack region.
 	compileReleaseRegisterToStack(loc *runtimeValueLocation)
@@ -2,49 +2,123 @@ package compiler
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/internal/asm"
+	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wazeroir"
 )
 
 func TestCompiler_compileHostFunction(t *testing.T) {
+	for _, leaf := range []bool{false, true} {
+		leaf := leaf
+		t.Run(fmt.Sprintf("leaf=%v", leaf), func(t *testing.T) {
+			env := newCompilerEnvironment()
+			compiler := env.requireNewCompiler(t, &wasm.FunctionType{}, newCompiler, nil)
+
+			err := compiler.compileGoDefinedHostFunction(leaf)
+			require.NoError(t, err)
+
+			// Get the location of caller function's location stored in the stack, which depends on the type.
+			// In this test, the host function has empty sig.
+			_, _, callerFuncLoc := compiler.runtimeValueLocationStack().getCallFrameLocations(&wasm.FunctionType{})
+
+			code := asm.CodeSegment{}
+			defer func() { require.NoError(t, code.Unmap()) }()
+
+			// Generate the machine code for the test.
+			_, err = compiler.compile(code.NextCodeSection())
+			require.NoError(t, err)
+
+			// Set the caller's function which always exists in the real usecase.
+			f := &function{moduleInstance: &wasm.ModuleInstance{}}
+			env.stack()[callerFuncLoc.stackPointer] = uint64(uintptr(unsafe.Pointer(f)))
+			env.exec(code.Bytes())
+
+			// On the return, the code must exit with the host call status.
+			require.Equal(t, nativeCallStatusCodeCallGoHostFunction, env.compilerStatus())
+			// Plus, the exitContext holds the caller's wasm.FunctionInstance.
+			require.Equal(t, f.moduleInstance, env.ce.exitContext.callerModuleInstance)
+
+			// Re-enter the return address, with the module context initialized by the caller.
+			require.NotEqual(t, uintptr(0), uintptr(env.ce.returnAddress))
+			env.ce.moduleContext.moduleInstance = f.moduleInstance
+			nativecall(env.ce.returnAddress, env.callEngine(), env.module())
+
+			// After that, the code must exit with returned status.
+			require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+
+			// Only non-leaf functions force the module context initialization.
+			if leaf {
+				require.Equal(t, f.moduleInstance, env.ce.moduleContext.moduleInstance)
+			} else {
+				require.Nil(t, env.ce.moduleContext.moduleInstance)
+			}
+		})
+	}
+}
+
+// nativeAdd returns the machine code which writes stack[0]+stack[1] to stack[0], with the calling convention of
+// wasm.NativeFunction.
+func nativeAdd() []byte {
+	if runtime.GOARCH == "amd64" {
+		return []byte{
+			0x48, 0x8b, 0x08, // MOVQ (AX), CX
+			0x48, 0x03, 0x48, 0x08, // ADDQ 8(AX), CX
+			0x48, 0x89, 0x08, // MOVQ CX, (AX)
+			0xc3, // RET
+		}
+	}
+	return []byte{
+		0x01, 0x00, 0x40, 0xf9, // LDR X1, [X0]
+		0x02, 0x04, 0x40, 0xf9, // LDR X2, [X0, #8]
+		0x21, 0x00, 0x02, 0x8b, // ADD X1, X1, X2
+		0x01, 0x00, 0x00, 0xf9, // STR X1, [X0]
+		0xc0, 0x03, 0x5f, 0xd6, // RET
+	}
+}
+
+func TestCompiler_compileNativeHostFunction(t *testing.T) {
+	i64 := wasm.ValueTypeI64
+	typ := &wasm.FunctionType{
+		Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64},
+		ParamNumInUint64: 2, ResultNumInUint64: 1,
+	}
+
 	env := newCompilerEnvironment()
-	compiler := env.requireNewCompiler(t, &wasm.FunctionType{}, newCompiler, nil)
+	compiler := env.requireNewCompiler(t, typ, newCompiler, nil)
 
-	err := compiler.compileGoDefinedHostFunction()
+	native := requireExecutable(nativeAdd())
+	defer func() { require.NoError(t, platform.MunmapCodeSegment(native)) }()
+
+	err := compiler.compileNativeHostFunction(uintptr(unsafe.Pointer(&native[0])))
 	require.NoError(t, err)
 
-	// Get the location of caller function's location stored in the stack, which depends on the type.
-	// In this test, the host function has empty sig.
-	_, _, callerFuncLoc := compiler.runtimeValueLocationStack().getCallFrameLocations(&wasm.FunctionType{})
+	_, _, callerFuncLoc := compiler.runtimeValueLocationStack().getCallFrameLocations(typ)
 
 	code := asm.CodeSegment{}
 	defer func() { require.NoError(t, code.Unmap()) }()
 
-	// Generate the machine code for the test.
 	_, err = compiler.compile(code.NextCodeSection())
 	require.NoError(t, err)
 
-	// Set the caller's function which always exists in the real usecase.
 	f := &function{moduleInstance: &wasm.ModuleInstance{}}
 	env.stack()[callerFuncLoc.stackPointer] = uint64(uintptr(unsafe.Pointer(f)))
+	env.stack()[0], env.stack()[1] = 1, 41
 	env.exec(code.Bytes())
 
-	// On the return, the code must exit with the host call status.
-	require.Equal(t, nativeCallStatusCodeCallGoHostFunction, env.compilerStatus())
-	// Plus, the exitContext holds the caller's wasm.FunctionInstance.
-	require.Equal(t, f.moduleInstance, env.ce.exitContext.callerModuleInstance)
-
-	// Re-enter the return address.
-	require.NotEqual(t, uintptr(0), uintptr(env.ce.returnAddress))
-	nativecall(env.ce.returnAddress, env.callEngine(), env.module())
-
-	// After that, the code must exit with returned status.
-	require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+	if runtime.GOARCH == "amd64" {
+		// The native function is called without exiting the native code.
+		require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+		require.Equal(t, uint64(42), env.stack()[0])
+	} else {
+		// Otherwise, the engine calls it via Go.
+		require.Equal(t, nativeCallStatusCodeCallGoHostFunction, env.compilerStatus())
+	}
 }
 
 func TestCompiler_compileLabel(t *testing.T) {
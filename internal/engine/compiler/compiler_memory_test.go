@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"unsafe"
 
 	"github.com/tetratelabs/wazero/internal/asm"
 	"github.com/tetratelabs/wazero/internal/testing/require"
@@ -61,7 +62,7 @@ func TestCompiler_compileMemorySize(t *testing.T) {
 	require.NoError(t, err)
 
 	// Emit memory.size instructions.
-	err = compiler.compileMemorySize()
+	err = compiler.compileMemorySize(operationPtr(wazeroir.NewOperationMemorySize(wasm.MemoryPageSizeInBits)))
 	require.NoError(t, err)
 	// At this point, the size of memory should be pushed onto the stack.
 	requireRuntimeLocationStackPointerEqual(t, uint64(1), compiler)
@@ -495,3 +496,69 @@ func TestCompiler_MemoryOutOfBounds(t *testing.T) {
 		}
 	}
 }
+
+// TestCompiler_sharedMemoryGrown ensures the cached length of a shared memory is reloaded, as another goroutine may
+// have grown it since the module context was initialized.
+func TestCompiler_sharedMemoryGrown(t *testing.T) {
+	const expValue uint32 = 0xdeadbeef
+	for _, tc := range []struct {
+		name   string
+		shared bool
+		loadFn bool // otherwise memory.size
+	}{
+		{name: "load", shared: true, loadFn: true},
+		{name: "load not shared", shared: false, loadFn: true},
+		{name: "memory.size", shared: true},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			env := newCompilerEnvironment()
+			mem := env.module().MemoryInstance
+			mem.Buffer = make([]byte, 2*wasm.MemoryPageSize)
+			binary.LittleEndian.PutUint32(mem.Buffer[wasm.MemoryPageSize:], expValue)
+
+			// Initialize the module context as if the memory had one page, so that the preamble doesn't.
+			ce := env.callEngine()
+			ce.moduleContext.moduleInstance = env.module()
+			ce.moduleContext.memoryInstance = mem
+			ce.moduleContext.memoryElement0Address = uintptr(unsafe.Pointer(&mem.Buffer[0]))
+			ce.moduleContext.memorySliceLen = uint64(wasm.MemoryPageSize)
+
+			compiler := env.requireNewCompiler(t, &wasm.FunctionType{}, newCompiler, &wazeroir.CompilationResult{
+				HasMemory: true, HasSharedMemory: tc.shared, LabelCallers: map[wazeroir.Label]uint32{},
+			})
+			err := compiler.compilePreamble()
+			require.NoError(t, err)
+
+			if tc.loadFn {
+				err = compiler.compileConstI32(operationPtr(wazeroir.NewOperationConstI32(wasm.MemoryPageSize)))
+				require.NoError(t, err)
+				err = compiler.compileLoad(operationPtr(wazeroir.NewOperationLoad(wazeroir.UnsignedTypeI32, wazeroir.MemoryArg{})))
+			} else {
+				err = compiler.compileMemorySize(operationPtr(wazeroir.NewOperationMemorySize(wasm.MemoryPageSizeInBits)))
+			}
+			require.NoError(t, err)
+			require.NoError(t, compiler.compileReturnFunction())
+
+			code := asm.CodeSegment{}
+			defer func() { require.NoError(t, code.Unmap()) }()
+
+			// Generate and run the code under test.
+			_, err = compiler.compile(code.NextCodeSection())
+			require.NoError(t, err)
+			env.exec(code.Bytes())
+
+			if !tc.shared {
+				require.Equal(t, nativeCallStatusCodeMemoryOutOfBounds, env.compilerStatus())
+				return
+			}
+			require.Equal(t, nativeCallStatusCodeReturned, env.compilerStatus())
+			if tc.loadFn {
+				require.Equal(t, expValue, env.stackTopAsUint32())
+			} else {
+				require.Equal(t, uint32(2), env.stackTopAsUint32())
+			}
+			require.Equal(t, uint64(2*wasm.MemoryPageSize), ce.moduleContext.memorySliceLen)
+		})
+	}
+}
@@ -60,6 +60,7 @@ func init() {
 	requireEqual(int(unsafe.Offsetof(ce.builtinFunctionCallIndex)), callEngineExitContextBuiltinFunctionCallIndexOffset, "callEngineExitContextBuiltinFunctionCallIndexOffset")
 	requireEqual(int(unsafe.Offsetof(ce.returnAddress)), callEngineExitContextReturnAddressOffset, "callEngineExitContextReturnAddressOffset")
 	requireEqual(int(unsafe.Offsetof(ce.callerModuleInstance)), callEngineExitContextCallerModuleInstanceOffset, "callEngineExitContextCallerModuleInstanceOffset")
+	requireEqual(int(unsafe.Offsetof(ce.memoryOutOfBoundsAddress)), callEngineExitContextMemoryOutOfBoundsAddressOffset, "callEngineExitContextMemoryOutOfBoundsAddressOffset")
 
 	// Size and offsets for callFrame.
 	var frame callFrame
@@ -52,6 +52,10 @@ type (
 		// Keep a reference to the compiled module to prevent the GC from reclaiming
 		// it while the code may still be needed.
 		module *compiledModule
+
+		// hostOnce sets the code of the functions of a host module, which is
+		// compiled lazily. See moduleEngine.initHostFunctions.
+		hostOnce sync.Once
 	}
 
 	// callEngine holds context per moduleEngine.Call, and shared across all the
@@ -141,6 +145,13 @@ type (
 		// stackIterator provides a way to iterate over the stack for Listeners.
 		// It is setup and valid only during a call to a Listener hook.
 		stackIterator stackIterator
+
+		// introspectionDepth is experimental.GuestStack Depth, set only when
+		// experimental.WithIntrospection was used.
+		introspectionDepth int
+
+		// yieldCounter counts the exit code checks to periodically yield to the Go scheduler.
+		yieldCounter wasm.YieldCounter
 	}
 
 	// moduleContext holds the per-function call specific module information.
@@ -231,6 +242,11 @@ type (
 
 		// callerModuleInstance holds the caller's wasm.ModuleInstance, and is only valid if currently executing a host function.
 		callerModuleInstance *wasm.ModuleInstance
+
+		// memoryOutOfBoundsAddress is the effective address plus one of the
+		// memory access which exited with nativeCallStatusCodeMemoryOutOfBounds,
+		// or zero if unknown, e.g. for bulk memory instructions.
+		memoryOutOfBoundsAddress uint64
 	}
 
 	// callFrame holds the information to which the caller function can return.
@@ -284,6 +300,20 @@ type (
 		functions []compiledFunction
 
 		ensureTermination bool
+
+		// trampolines are the offsets in the executable of the code, loaded by
+		// loadAOT, which calls the Go-defined host function imported at each
+		// index. See compiledModule.trampoline.
+		trampolines []uintptr
+		// trampolinesWithListener is true when the trampolines call the
+		// function listener of the host function.
+		trampolinesWithListener bool
+
+		// hostOnce compiles the code of a host module the first time it's
+		// needed, as a module loaded by loadAOT uses its own trampolines. See
+		// compiledModule.compileHostFunctions.
+		hostOnce sync.Once
+		hostErr  error
 	}
 
 	compiledCode struct {
@@ -300,8 +330,14 @@ type (
 		// stackPointerCeil is the max of the stack pointer this function can reach. Lazily applied via maybeGrowStack.
 		stackPointerCeil uint64
 
-		index           wasm.Index
-		goFunc          interface{}
+		index  wasm.Index
+		goFunc interface{}
+		// goFuncLeaf is true when goFunc was compiled without resetting the
+		// module context on return, as it is a wasm.NativeFunction.
+		goFuncLeaf bool
+		// goFuncIntrinsic is true when goFunc replaces a guest function. See
+		// wasm.Code Intrinsic.
+		goFuncIntrinsic bool
 		listener        experimental.FunctionListener
 		parent          *compiledCode
 		sourceOffsetMap sourceOffsetMap
@@ -368,6 +404,7 @@ const (
 	callEngineExitContextBuiltinFunctionCallIndexOffset = 124
 	callEngineExitContextReturnAddressOffset            = 128
 	callEngineExitContextCallerModuleInstanceOffset     = 136
+	callEngineExitContextMemoryOutOfBoundsAddressOffset = 144
 
 	// Offsets for function.
 	functionCodeInitialAddressOffset = 0
@@ -512,6 +549,21 @@ func (e *engine) DeleteCompiledModule(module *wasm.Module) {
 	e.deleteCompiledModule(module)
 }
 
+// CompiledSize implements the same method as documented on wasm.SizeEstimator.
+func (e *engine) CompiledSize(module *wasm.Module) (uint64, bool) {
+	cm, ok := e.getCompiledModuleFromMemory(module)
+	if !ok {
+		return 0, false
+	}
+	return uint64(cm.executable.Len()) + uint64(len(cm.functions))*uint64(unsafe.Sizeof(compiledFunction{})), true
+}
+
+// ModuleEngineSize implements the same method as documented on wasm.SizeEstimator.
+func (e *engine) ModuleEngineSize(module *wasm.Module) uint64 {
+	functionCount := uint64(len(module.FunctionSection)) + uint64(module.ImportFunctionCount)
+	return uint64(unsafe.Sizeof(moduleEngine{})) + functionCount*uint64(unsafe.Sizeof(function{}))
+}
+
 // Close implements the same method as documented on wasm.Engine.
 func (e *engine) Close() (err error) {
 	e.mux.Lock()
@@ -522,11 +574,26 @@ func (e *engine) Close() (err error) {
 }
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
-	if _, ok, err := e.getCompiledModule(module, listeners); ok { // cache hit!
-		return nil
+func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+	if lib, ok := ctx.Value(AOTLoadKey{}).(*AOTLibrary); ok && !module.IsHostModule {
+		if err := checkAOT(module); err != nil {
+			return err
+		}
+		return e.loadAOT(module, listeners, ensureTermination, lib)
+	} else if _, ok = ctx.Value(AOTEmitKey{}).(AOTEmitter); ok && !module.IsHostModule {
+		if err := checkAOT(module); err != nil {
+			return err
+		}
+	}
+	if cm, ok, err := e.getCompiledModule(ctx, module, listeners); ok { // cache hit!
+		wasm.SetCompiledFromCache(ctx)
+		return e.emitAOT(ctx, module, cm, len(listeners) > 0)
 	} else if err != nil {
 		return err
+	} else if platform.ExecutableMemoryForbidden() && !isGoDefined(module) {
+		// Host modules are compiled lazily, so can be imported by a module
+		// loaded by loadAOT.
+		return platform.ErrExecutableMemoryForbidden
 	}
 
 	irCompiler, err := wazeroir.NewCompiler(e.enabledFeatures, callFrameDataSizeInUint64, module, ensureTermination)
@@ -545,12 +612,34 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 	}
 
 	if localFuncs == 0 {
-		return e.addCompiledModule(module, cm, withGoFunc)
+		if err = e.emitAOT(ctx, module, cm, len(listeners) > 0); err != nil {
+			return err
+		}
+		return e.addCompiledModule(ctx, module, cm, withGoFunc)
 	}
 
 	// As this uses mmap, we need to munmap on the compiled machine code when it's GCed.
 	e.setFinalizer(cm, releaseCompiledModule)
 	ln := len(listeners)
+
+	if isGoDefined(module) {
+		// The code of a host module is compiled the first time it's needed, so
+		// that a module loaded by loadAOT, using its own trampolines, doesn't
+		// cause code to be compiled. See compileHostFunctions.
+		for i := range cm.functions {
+			compiledFn := &cm.functions[i]
+			compiledFn.parent = cm.compiledCode
+			compiledFn.index = importedFuncs + wasm.Index(i)
+			if i < ln {
+				compiledFn.listener = listeners[i]
+			}
+			codeSeg := &module.CodeSection[i]
+			_, compiledFn.goFuncLeaf = codeSeg.GoFunc.(wasm.NativeFunction)
+			compiledFn.goFunc = codeSeg.GoFunc
+			compiledFn.goFuncIntrinsic = codeSeg.Intrinsic
+		}
+		return e.addCompiledModule(ctx, module, cm, true)
+	}
 	cmp := newCompiler()
 	asmNodes := new(asmNodes)
 	offsets := new(offsets)
@@ -584,11 +673,21 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		if codeSeg := &module.CodeSection[i]; codeSeg.GoFunc != nil {
 			cmp.Init(typ, nil, compiledFn.listener != nil)
 			withGoFunc = true
-			if err = compileGoDefinedHostFunction(buf, cmp); err != nil {
+			// Native functions can't change the module state, so they are leaves.
+			var leaf bool
+			if nf, ok := codeSeg.GoFunc.(wasm.NativeFunction); ok {
+				leaf = true
+				err = compileNativeHostFunction(buf, cmp, nf.NativeFunctionAddress())
+			} else {
+				err = compileGoDefinedHostFunction(buf, cmp, false)
+			}
+			if err != nil {
 				def := module.FunctionDefinition(compiledFn.index)
 				return fmt.Errorf("error compiling host go func[%s]: %w", def.DebugName(), err)
 			}
 			compiledFn.goFunc = codeSeg.GoFunc
+			compiledFn.goFuncLeaf = leaf
+			compiledFn.goFuncIntrinsic = codeSeg.Intrinsic
 		} else {
 			ir, err := irCompiler.Next()
 			if err != nil {
@@ -611,7 +710,66 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		}
 	}
 	cm.executable, executable = executable, asm.CodeSegment{}
-	return e.addCompiledModule(module, cm, withGoFunc)
+	if err = e.emitAOT(ctx, module, cm, ln > 0); err != nil {
+		return err
+	}
+	return e.addCompiledModule(ctx, module, cm, withGoFunc)
+}
+
+// isGoDefined returns true if the module is a host module, which only
+// defines functions in Go.
+func isGoDefined(module *wasm.Module) bool {
+	if !module.IsHostModule {
+		return false
+	}
+	for i := range module.CodeSection {
+		if module.CodeSection[i].GoFunc == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// compileHostFunctions compiles the trampolines of the Go-defined functions
+// of a host module, which CompileModule defers, once.
+func (cm *compiledModule) compileHostFunctions() error {
+	cm.hostOnce.Do(func() {
+		var executable asm.CodeSegment
+		if cm.hostErr = cm.doCompileHostFunctions(&executable); cm.hostErr != nil {
+			if err := executable.Unmap(); err != nil {
+				panic(fmt.Errorf("compiler: failed to munmap code segment: %w", err))
+			}
+			return
+		}
+		cm.executable = executable
+	})
+	return cm.hostErr
+}
+
+func (cm *compiledModule) doCompileHostFunctions(executable *asm.CodeSegment) error {
+	module := cm.source
+	cmp := newCompiler()
+	for i := range cm.functions {
+		compiledFn := &cm.functions[i]
+		buf := executable.NextCodeSection()
+		compiledFn.executableOffset = executable.Size()
+		cmp.Init(&module.TypeSection[module.FunctionSection[i]], nil, compiledFn.listener != nil)
+		var err error
+		if nf, ok := compiledFn.goFunc.(wasm.NativeFunction); ok {
+			err = compileNativeHostFunction(buf, cmp, nf.NativeFunctionAddress())
+		} else {
+			err = compileGoDefinedHostFunction(buf, cmp, false)
+		}
+		if err != nil {
+			def := module.FunctionDefinition(compiledFn.index)
+			return fmt.Errorf("error compiling host go func[%s]: %w", def.DebugName(), err)
+		}
+	}
+	if runtime.GOARCH == "arm64" {
+		// On arm64, we cannot give all of rwx at the same time, so we change it to exec.
+		return platform.MprotectRX(executable.Bytes())
+	}
+	return nil
 }
 
 // NewModuleEngine implements the same method as documented on wasm.Engine.
@@ -622,7 +780,7 @@ func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInsta
 
 	// Note: imported functions are resolved in moduleEngine.ResolveImportedFunction.
 
-	cm, ok, err := e.getCompiledModule(module,
+	cm, ok, err := e.getCompiledModule(context.Background(), module,
 		// listeners arg is not needed here since NewModuleEngine is called after CompileModule which
 		// ensures the association of listener with *code.
 		nil)
@@ -637,23 +795,58 @@ func (e *engine) NewModuleEngine(module *wasm.Module, instance *wasm.ModuleInsta
 		offset := int(module.ImportFunctionCount) + i
 		typeIndex := module.FunctionSection[i]
 		me.functions[offset] = function{
-			codeInitialAddress: cm.executable.Addr() + c.executableOffset,
-			moduleInstance:     instance,
-			typeID:             instance.TypeIDs[typeIndex],
-			funcType:           &module.TypeSection[typeIndex],
-			parent:             c,
+			moduleInstance: instance,
+			typeID:         instance.TypeIDs[typeIndex],
+			funcType:       &module.TypeSection[typeIndex],
+			parent:         c,
 		}
 	}
 
 	me.module = cm
+	if !isGoDefined(module) {
+		me.setCodeInitialAddresses()
+	}
 	return me, nil
 }
 
+// setCodeInitialAddresses sets function.codeInitialAddress of the functions
+// defined by the module.
+func (e *moduleEngine) setCodeInitialAddresses() {
+	imported := len(e.functions) - len(e.module.functions)
+	for i := range e.module.functions {
+		e.functions[imported+i].codeInitialAddress = e.module.executable.Addr() + e.module.functions[i].executableOffset
+	}
+}
+
+// initHostFunctions sets the code of the functions of a host module, once,
+// compiling it if needed. This panics if the code can't be compiled, which
+// only happens if the host is out of memory.
+func (e *moduleEngine) initHostFunctions() {
+	e.hostOnce.Do(func() {
+		if !isGoDefined(e.module.source) {
+			return
+		}
+		if err := e.module.compileHostFunctions(); err != nil {
+			panic(err)
+		}
+		e.setCodeInitialAddresses()
+	})
+}
+
 // ResolveImportedFunction implements wasm.ModuleEngine.
 func (e *moduleEngine) ResolveImportedFunction(index, indexInImportedModule wasm.Index, importedModuleEngine wasm.ModuleEngine) {
 	imported := importedModuleEngine.(*moduleEngine)
-	// Copies the content from the import target moduleEngine.
-	e.functions[index] = imported.functions[indexInImportedModule]
+	// Copies the content from the import target moduleEngine, using the
+	// trampoline of a module loaded by loadAOT, if any, to call a Go-defined
+	// host function.
+	f := imported.functions[indexInImportedModule]
+	if addr, ok := e.module.trampoline(index, f.parent); ok {
+		f.codeInitialAddress = addr
+	} else {
+		imported.initHostFunctions()
+		f = imported.functions[indexInImportedModule]
+	}
+	e.functions[index] = f
 }
 
 // ResolveImportedMemory implements wasm.ModuleEngine.
@@ -669,6 +862,7 @@ func (e *moduleEngine) DoneInstantiation() {}
 
 // NewFunction implements wasm.ModuleEngine.
 func (e *moduleEngine) NewFunction(index wasm.Index) api.Function {
+	e.initHostFunctions()
 	return e.newFunction(&e.functions[index])
 }
 
@@ -728,6 +922,15 @@ func (ce *callEngine) Call(ctx context.Context, params ...uint64) (results []uin
 	return ce.call(ctx, params, nil)
 }
 
+// CallWithOptions implements the same method as documented on api.Function.
+func (ce *callEngine) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) (results []uint64, err error) {
+	err = ce.initialFn.moduleInstance.CallWithOptions(opts, func() (err error) {
+		results, err = ce.Call(ctx, params...)
+		return
+	})
+	return
+}
+
 // CallWithStack implements the same method as documented on wasm.ModuleEngine.
 func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
 	params, results, err := wasm.SplitCallStack(ce.initialFn.funcType, stack)
@@ -740,6 +943,13 @@ func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
 
 func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []uint64, err error) {
 	m := ce.initialFn.moduleInstance
+	if v := ctx.Value(experimental.IntrospectionKey{}); v != nil {
+		ce.introspectionDepth = 1
+		if outer, ok := v.(*callEngine); ok { // called back from a host function
+			ce.introspectionDepth += outer.introspectionDepth
+		}
+		ctx = context.WithValue(ctx, experimental.IntrospectionKey{}, ce)
+	}
 	if ce.module.ensureTermination {
 		select {
 		case <-ctx.Done():
@@ -882,9 +1092,14 @@ func (ce *callEngine) deferredOnCall(ctx context.Context, m *wasm.ModuleInstance
 		}
 
 		err = builder.FromRecovered(recovered)
+		err = wasmdebug.WithLabels(err, m.Labels)
+		if wasmdebug.IsPanic(recovered) {
+			err = m.Panicked(ctx, recovered, err)
+		}
 		for i := range functionListeners {
 			functionListeners[i].Abort(ctx, m, functionListeners[i].def, err)
 		}
+		m.Trapped(ctx, err)
 	}
 
 	// Allows the reuse of CallEngine.
@@ -1018,6 +1233,7 @@ const (
 	builtinFunctionIndexFunctionListenerBefore
 	builtinFunctionIndexFunctionListenerAfter
 	builtinFunctionIndexCheckExitCode
+	builtinFunctionIndexAtomic
 	// builtinFunctionIndexBreakPoint is internal (only for wazero developers). Disabled by default.
 	builtinFunctionIndexBreakPoint
 )
@@ -1048,11 +1264,21 @@ entry:
 			stack := ce.stack[base : base+stackLen]
 
 			fn := calleeHostFunction.parent.goFunc
-			switch fn := fn.(type) {
-			case api.GoModuleFunction:
-				fn.Call(ctx, ce.callerModuleInstance, stack)
-			case api.GoFunction:
-				fn.Call(ctx, stack)
+			if calleeHostFunction.parent.goFuncIntrinsic {
+				// Intrinsics replace guest code, so they run against the
+				// module which defines them, whichever calls them.
+				fn.(api.GoModuleFunction).Call(ctx, calleeHostFunction.moduleInstance, stack)
+			} else if calleeHostFunction.parent.goFuncLeaf {
+				ce.callerModuleInstance.BeforeLeafHostCall(ctx)
+				fn.(api.GoFunction).Call(ctx, stack)
+			} else {
+				ce.callerModuleInstance.BeforeHostCall(ctx)
+				switch fn := fn.(type) {
+				case api.GoModuleFunction:
+					fn.Call(ctx, ce.callerModuleInstance, stack)
+				case api.GoFunction:
+					fn.Call(ctx, stack)
+				}
 			}
 
 			codeAddr, modAddr = ce.returnAddress, ce.moduleInstance
@@ -1077,6 +1303,9 @@ entry:
 				if err := m.FailIfClosed(); err != nil {
 					panic(err)
 				}
+				ce.yieldCounter.Checkpoint()
+			case builtinFunctionIndexAtomic:
+				ce.builtinFunctionAtomic(ctx, caller.moduleInstance.MemoryInstance)
 			}
 			if false {
 				if ce.exitContext.builtinFunctionCallIndex == builtinFunctionIndexBreakPoint {
@@ -1087,6 +1316,10 @@ entry:
 			codeAddr, modAddr = ce.returnAddress, ce.moduleInstance
 			goto entry
 		default:
+			if addr := ce.memoryOutOfBoundsAddress; addr != 0 && status == nativeCallStatusCodeMemoryOutOfBounds {
+				ce.memoryOutOfBoundsAddress = 0
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(addr - 1))
+			}
 			status.causePanic()
 		}
 	}
@@ -1120,6 +1353,10 @@ func (ce *callEngine) builtinFunctionGrowStack(stackPointerCeil uint64) {
 func (ce *callEngine) builtinFunctionMemoryGrow(mem *wasm.MemoryInstance) {
 	newPages := ce.popValue()
 
+	if mem.ExceedsGrowthRate(uint32(newPages)) {
+		panic(wasmruntime.ErrRuntimeMemoryGrowthRateExceeded)
+	}
+
 	if res, ok := mem.Grow(uint32(newPages)); !ok {
 		ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
 	} else {
@@ -1141,6 +1378,103 @@ func (ce *callEngine) builtinFunctionTableGrow(tables []*wasm.TableInstance) {
 	ce.pushValue(uint64(res))
 }
 
+// atomicDescriptor encodes the static parts of an atomic operation, which is
+// pushed for builtinFunctionAtomic as its last operand.
+func atomicDescriptor(o *wazeroir.UnionOperation) uint64 {
+	return uint64(o.Kind)<<48 | uint64(o.B2)<<40 | o.U3<<32 | uint64(uint32(o.U2))
+}
+
+// atomicOperandCount returns the number of operands of an atomic operation,
+// excluding its descriptor, and whether it pushes a result.
+func atomicOperandCount(kind wazeroir.OperationKind) (operands int, hasResult bool) {
+	switch kind {
+	case wazeroir.OperationKindAtomicFence:
+		return 0, false
+	case wazeroir.OperationKindAtomicLoad:
+		return 1, true
+	case wazeroir.OperationKindAtomicStore:
+		return 2, false
+	case wazeroir.OperationKindAtomicMemoryNotify, wazeroir.OperationKindAtomicRMW:
+		return 2, true
+	default: // wazeroir.OperationKindAtomicMemoryWait, wazeroir.OperationKindAtomicRMWCmpxchg
+		return 3, true
+	}
+}
+
+// builtinFunctionAtomic performs the atomic operation described by the
+// descriptor on the top of the stack. Atomic operations are done in Go, as
+// they may block, and share their implementation with the interpreter.
+func (ce *callEngine) builtinFunctionAtomic(ctx context.Context, mem *wasm.MemoryInstance) {
+	desc := ce.popValue()
+	kind, size := wazeroir.OperationKind(desc>>48), uint32(desc>>40)&0xff
+	staticOffset := uint32(desc)
+	offset := func() uint32 {
+		return mem.AtomicOffset(uint32(ce.popValue()), staticOffset, size)
+	}
+
+	switch kind {
+	case wazeroir.OperationKindAtomicMemoryWait:
+		timeout := int64(ce.popValue())
+		expected := ce.popValue()
+		ce.pushValue(mem.AtomicWait(ctx, offset(), size, expected, timeout))
+	case wazeroir.OperationKindAtomicMemoryNotify:
+		count := uint32(ce.popValue())
+		ce.pushValue(uint64(mem.AtomicNotify(offset(), count)))
+	case wazeroir.OperationKindAtomicFence:
+		wasm.AtomicFence()
+	case wazeroir.OperationKindAtomicLoad:
+		ce.pushValue(mem.AtomicLoad(offset(), size))
+	case wazeroir.OperationKindAtomicStore:
+		v := ce.popValue()
+		mem.AtomicStore(offset(), size, v)
+	case wazeroir.OperationKindAtomicRMW:
+		v := ce.popValue()
+		arithmeticOp := wazeroir.AtomicArithmeticOp(desc >> 32 & 0xff)
+		ce.pushValue(mem.AtomicRMW(offset(), size, func(old uint64) uint64 {
+			return arithmeticOp.Apply(old, v)
+		}))
+	case wazeroir.OperationKindAtomicRMWCmpxchg:
+		replacement := ce.popValue()
+		expected := ce.popValue()
+		ce.pushValue(mem.AtomicCompareExchange(offset(), size, expected, replacement))
+	}
+}
+
+// Stack is used by experimental.Stack.
+//
+// Used is the top of the stack. Limit is the length the stack grows to before
+// builtinFunctionGrowStack overflows: as the stack at least doubles until its
+// length exceeds callStackCeiling, this is a lower bound.
+func (ce *callEngine) Stack() experimental.GuestStack {
+	limit := uint64(len(ce.stack))
+	if limit == 0 {
+		limit = 1
+	}
+	for limit <= callStackCeiling {
+		limit <<= 1
+	}
+	return experimental.GuestStack{
+		Used:  ce.stackTopIndex(),
+		Limit: limit,
+		Depth: ce.introspectionDepth,
+	}
+}
+
+// Caller is used by experimental.Caller to return the caller of the host
+// function currently called, from its call frame.
+func (ce *callEngine) Caller() api.FunctionDefinition {
+	fn := ce.moduleContext.fn
+	if fn == nil || fn.parent.goFunc == nil {
+		return nil
+	}
+	frame := int(ce.stackBasePointerInBytes>>3) + callFrameOffset(fn.funcType)
+	// *function lives in the third field of callFrame struct.
+	if caller := *(**function)(unsafe.Pointer(&ce.stack[frame+2])); caller != nil {
+		return caller.definition()
+	}
+	return nil
+}
+
 // stackIterator implements experimental.StackIterator.
 type stackIterator struct {
 	stack   []uint64
@@ -1233,8 +1567,16 @@ func (ce *callEngine) builtinFunctionFunctionListenerAfter(ctx context.Context,
 	fn.parent.listener.After(ctx, mod, fn.definition(), ce.stack[base:base+fn.funcType.ResultNumInUint64])
 }
 
-func compileGoDefinedHostFunction(buf asm.Buffer, cmp compiler) error {
-	if err := cmp.compileGoDefinedHostFunction(); err != nil {
+func compileGoDefinedHostFunction(buf asm.Buffer, cmp compiler, leaf bool) error {
+	if err := cmp.compileGoDefinedHostFunction(leaf); err != nil {
+		return err
+	}
+	_, err := cmp.compile(buf)
+	return err
+}
+
+func compileNativeHostFunction(buf asm.Buffer, cmp compiler, address uintptr) error {
+	if err := cmp.compileNativeHostFunction(address); err != nil {
 		return err
 	}
 	_, err := cmp.compile(buf)
@@ -1329,7 +1671,7 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 		case wazeroir.OperationKindStore32:
 			err = cmp.compileStore32(op)
 		case wazeroir.OperationKindMemorySize:
-			err = cmp.compileMemorySize()
+			err = cmp.compileMemorySize(op)
 		case wazeroir.OperationKindMemoryGrow:
 			err = cmp.compileMemoryGrow()
 		case wazeroir.OperationKindConstI32:
@@ -1360,6 +1702,12 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileSub(op)
 		case wazeroir.OperationKindMul:
 			err = cmp.compileMul(op)
+		case wazeroir.OperationKindI64Add128:
+			err = cmp.compileI64Add128()
+		case wazeroir.OperationKindI64Sub128:
+			err = cmp.compileI64Sub128()
+		case wazeroir.OperationKindI64MulWide:
+			err = cmp.compileI64MulWide(op)
 		case wazeroir.OperationKindClz:
 			err = cmp.compileClz(op)
 		case wazeroir.OperationKindCtz:
@@ -1564,6 +1912,10 @@ func compileWasmFunction(buf asm.Buffer, cmp compiler, ir *wazeroir.CompilationR
 			err = cmp.compileV128ITruncSatFromF(op)
 		case wazeroir.OperationKindBuiltinFunctionCheckExitCode:
 			err = cmp.compileBuiltinFunctionCheckExitCode()
+		case wazeroir.OperationKindAtomicMemoryWait, wazeroir.OperationKindAtomicMemoryNotify,
+			wazeroir.OperationKindAtomicFence, wazeroir.OperationKindAtomicLoad, wazeroir.OperationKindAtomicStore,
+			wazeroir.OperationKindAtomicRMW, wazeroir.OperationKindAtomicRMWCmpxchg:
+			err = cmp.compileAtomic(op)
 		default:
 			err = errors.New("unsupported")
 		}
@@ -2,12 +2,15 @@ package compiler
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"runtime"
 
 	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/asm"
+	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/u32"
 	"github.com/tetratelabs/wazero/internal/u64"
@@ -24,20 +27,23 @@ func (e *engine) deleteCompiledModule(module *wasm.Module) {
 	// the content is up to the implementation of extencache.Cache interface.
 }
 
-func (e *engine) addCompiledModule(module *wasm.Module, cm *compiledModule, withGoFunc bool) (err error) {
+func (e *engine) addCompiledModule(ctx context.Context, module *wasm.Module, cm *compiledModule, withGoFunc bool) (err error) {
 	e.addCompiledModuleToMemory(module, cm)
+	filecache.Notify(ctx, experimental.CompilationCacheEventStore, module.ID, true, "", nil)
 	if !withGoFunc {
-		err = e.addCompiledModuleToCache(module, cm)
+		err = e.addCompiledModuleToCache(ctx, module, cm)
 	}
 	return
 }
 
-func (e *engine) getCompiledModule(module *wasm.Module, listeners []experimental.FunctionListener) (cm *compiledModule, ok bool, err error) {
+func (e *engine) getCompiledModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener) (cm *compiledModule, ok bool, err error) {
 	cm, ok = e.getCompiledModuleFromMemory(module)
 	if ok {
+		filecache.Notify(ctx, experimental.CompilationCacheEventHit, module.ID, true, "", nil)
 		return
 	}
-	cm, ok, err = e.getCompiledModuleFromCache(module)
+	filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, true, "", nil)
+	cm, ok, err = e.getCompiledModuleFromCache(ctx, module)
 	if ok {
 		e.addCompiledModuleToMemory(module, cm)
 		if len(listeners) > 0 {
@@ -63,15 +69,16 @@ func (e *engine) getCompiledModuleFromMemory(module *wasm.Module) (cm *compiledM
 	return
 }
 
-func (e *engine) addCompiledModuleToCache(module *wasm.Module, cm *compiledModule) (err error) {
+func (e *engine) addCompiledModuleToCache(ctx context.Context, module *wasm.Module, cm *compiledModule) (err error) {
 	if e.fileCache == nil || module.IsHostModule {
 		return
 	}
-	err = e.fileCache.Add(module.ID, serializeCompiledModule(e.wazeroVersion, cm))
+	err = filecache.Add(ctx, e.fileCache, module.ID, serializeCompiledModule(e.wazeroVersion, cm))
+	filecache.Notify(ctx, experimental.CompilationCacheEventStore, module.ID, false, "", err)
 	return
 }
 
-func (e *engine) getCompiledModuleFromCache(module *wasm.Module) (cm *compiledModule, hit bool, err error) {
+func (e *engine) getCompiledModuleFromCache(ctx context.Context, module *wasm.Module) (cm *compiledModule, hit bool, err error) {
 	if e.fileCache == nil || module.IsHostModule {
 		return
 	}
@@ -80,6 +87,7 @@ func (e *engine) getCompiledModuleFromCache(module *wasm.Module) (cm *compiledMo
 	var cached io.ReadCloser
 	cached, hit, err = e.fileCache.Get(module.ID)
 	if !hit || err != nil {
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, false, "", err)
 		return
 	}
 
@@ -87,14 +95,19 @@ func (e *engine) getCompiledModuleFromCache(module *wasm.Module) (cm *compiledMo
 	// We retrieve *code structures from `cached`.
 	var staleCache bool
 	// Note: cached.Close is ensured to be called in deserializeCodes.
-	cm, staleCache, err = deserializeCompiledModule(e.wazeroVersion, cached, module)
+	cm, staleCache, err = deserializeCompiledModule(e.wazeroVersion, cached, module, nil)
 	if err != nil {
 		hit = false
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, false, "", err)
 		return
 	} else if staleCache {
-		return nil, false, e.fileCache.Delete(module.ID)
+		err = e.fileCache.Delete(module.ID)
+		filecache.Notify(ctx, experimental.CompilationCacheEventEvict, module.ID, false, filecache.ReasonStale, err)
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, false, filecache.ReasonStale, nil)
+		return nil, false, err
 	}
 
+	filecache.Notify(ctx, experimental.CompilationCacheEventHit, module.ID, false, "", nil)
 	cm.source = module
 	return
 }
@@ -103,6 +116,15 @@ var wazeroMagic = "WAZERO" // version must be synced with the tag of the wazero
 
 func serializeCompiledModule(wazeroVersion string, cm *compiledModule) io.Reader {
 	buf := bytes.NewBuffer(nil)
+	writeCompiledModuleHeader(buf, wazeroVersion, cm, cm.executable.Len())
+	// Append the native code.
+	buf.Write(cm.executable.Bytes())
+	return bytes.NewReader(buf.Bytes())
+}
+
+// writeCompiledModuleHeader writes everything serializeCompiledModule does,
+// except the native code, which is `executableLen` bytes long.
+func writeCompiledModuleHeader(buf *bytes.Buffer, wazeroVersion string, cm *compiledModule, executableLen int) {
 	// First 6 byte: WAZERO header.
 	buf.WriteString(wazeroMagic)
 	// Next 1 byte: length of version:
@@ -124,13 +146,14 @@ func serializeCompiledModule(wazeroVersion string, cm *compiledModule) io.Reader
 		buf.Write(u64.LeBytes(uint64(f.executableOffset)))
 	}
 	// The length of code segment (8 bytes).
-	buf.Write(u64.LeBytes(uint64(cm.executable.Len())))
-	// Append the native code.
-	buf.Write(cm.executable.Bytes())
-	return bytes.NewReader(buf.Bytes())
+	buf.Write(u64.LeBytes(uint64(executableLen)))
 }
 
-func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, module *wasm.Module) (cm *compiledModule, staleCache bool, err error) {
+// deserializeCompiledModule reads a compiled module written by
+// serializeCompiledModule. When `executable` is not nil, the reader only
+// includes the header, and `executable` is the native code, already mapped
+// as executable memory.
+func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, module *wasm.Module, executable []byte) (cm *compiledModule, staleCache bool, err error) {
 	defer reader.Close()
 	cacheHeaderSize := len(wazeroMagic) + 1 /* version size */ + len(wazeroVersion) + 1 /* ensure termination */ + 4 /* number of functions */
 
@@ -194,7 +217,13 @@ func deserializeCompiledModule(wazeroVersion string, reader io.ReadCloser, modul
 		return
 	}
 
-	if executableLen > 0 {
+	if executable != nil {
+		if uint64(len(executable)) != executableLen {
+			err = fmt.Errorf("compilationcache: invalid executable size: %d != %d", len(executable), executableLen)
+			return
+		}
+		cm.executable = *asm.NewCodeSegment(executable)
+	} else if executableLen > 0 {
 		if err = cm.executable.Map(int(executableLen)); err != nil {
 			err = fmt.Errorf("compilationcache: error mmapping executable (len=%d): %v", executableLen, err)
 			return
@@ -286,7 +286,7 @@ func TestDeserializeCompiledModule(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			cm, staleCache, err := deserializeCompiledModule(testVersion, io.NopCloser(bytes.NewReader(tc.in)),
-				&wasm.Module{ImportFunctionCount: tc.importedFunctionCount})
+				&wasm.Module{ImportFunctionCount: tc.importedFunctionCount}, nil)
 
 			if tc.expCompiledModule != nil {
 				require.Equal(t, len(tc.expCompiledModule.functions), len(cm.functions))
@@ -405,7 +405,7 @@ func TestEngine_getCompiledModuleFromCache(t *testing.T) {
 				}
 			}
 
-			codes, hit, err := e.getCompiledModuleFromCache(m)
+			codes, hit, err := e.getCompiledModuleFromCache(testCtx, m)
 			if tc.expErr != "" {
 				require.EqualError(t, err, tc.expErr)
 			} else {
@@ -427,7 +427,7 @@ func TestEngine_getCompiledModuleFromCache(t *testing.T) {
 func TestEngine_addCompiledModuleToCache(t *testing.T) {
 	t.Run("not defined", func(t *testing.T) {
 		e := engine{}
-		err := e.addCompiledModuleToCache(nil, nil)
+		err := e.addCompiledModuleToCache(testCtx, nil, nil)
 		require.NoError(t, err)
 	})
 	t.Run("host module", func(t *testing.T) {
@@ -440,7 +440,7 @@ func TestEngine_addCompiledModuleToCache(t *testing.T) {
 			functions: []compiledFunction{{stackPointerCeil: 123}},
 		}
 		m := &wasm.Module{ID: sha256.Sum256(nil), IsHostModule: true} // Host module!
-		err := e.addCompiledModuleToCache(m, cm)
+		err := e.addCompiledModuleToCache(testCtx, m, cm)
 		require.NoError(t, err)
 		// Check the host module not cached.
 		_, hit, err := tc.Get(m.ID)
@@ -457,7 +457,7 @@ func TestEngine_addCompiledModuleToCache(t *testing.T) {
 			},
 			functions: []compiledFunction{{stackPointerCeil: 123}},
 		}
-		err := e.addCompiledModuleToCache(m, cm)
+		err := e.addCompiledModuleToCache(testCtx, m, cm)
 		require.NoError(t, err)
 
 		content, ok, err := tc.Get(m.ID)
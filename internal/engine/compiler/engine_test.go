@@ -382,6 +382,25 @@ func TestCallEngine_builtinFunctionTableGrow(t *testing.T) {
 	require.Equal(t, uintptr(0xff), table.References[0])
 }
 
+func TestCallEngine_Stack(t *testing.T) {
+	defer func(ceiling uint64) { callStackCeiling = ceiling }(callStackCeiling)
+	callStackCeiling = 100
+
+	ce := &callEngine{
+		stack:        make([]uint64, 30),
+		stackContext: stackContext{stackPointer: 5, stackBasePointerInBytes: 10 << 3},
+	}
+	s := ce.Stack()
+	require.Equal(t, uint64(15), s.Used)
+	// The stack grows from 30 to at least 60 then 120, which overflows.
+	require.Equal(t, uint64(120), s.Limit)
+	require.Equal(t, uint64(105), s.Remaining())
+
+	// The stack can't grow after exceeding the ceiling.
+	ce.stack = make([]uint64, 130)
+	require.Equal(t, uint64(130), ce.Stack().Limit)
+}
+
 func ptrAsUint64(f *function) uint64 {
 	return uint64(uintptr(unsafe.Pointer(f)))
 }
@@ -282,7 +282,7 @@ func (c *amd64Compiler) compileBuiltinFunctionCheckExitCode() error {
 
 // compileGoDefinedHostFunction constructs the entire code to enter the host function implementation,
 // and return to the caller.
-func (c *amd64Compiler) compileGoDefinedHostFunction() error {
+func (c *amd64Compiler) compileGoDefinedHostFunction(leaf bool) error {
 	// First we must update the location stack to reflect the number of host function inputs.
 	c.locationStack.init(c.typ)
 
@@ -322,9 +322,32 @@ func (c *amd64Compiler) compileGoDefinedHostFunction() error {
 
 	// Go function can change the module state in arbitrary way, so we have to force
 	// the callEngine.moduleContext initialization on the function return. To do so,
-	// we zero-out callEngine.moduleInstance.
-	c.assembler.CompileConstToMemory(amd64.MOVQ,
-		0, amd64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset)
+	// we zero-out callEngine.moduleInstance. Native functions can't.
+	if !leaf {
+		c.assembler.CompileConstToMemory(amd64.MOVQ,
+			0, amd64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset)
+	}
+	return c.compileReturnFunction()
+}
+
+// compileNativeHostFunction implements compiler.compileNativeHostFunction for the amd64 architecture.
+func (c *amd64Compiler) compileNativeHostFunction(address uintptr) error {
+	if c.withListener {
+		// Listeners are Go functions, so there's no point avoiding the exit from the native code.
+		return c.compileGoDefinedHostFunction(true)
+	}
+
+	c.locationStack.init(c.typ)
+
+	// The native function is called with AX = &callEngine.stack[callEngine.stackBasePointer], where the params are
+	// placed and the results are written, the same as the stack passed to Go functions. It preserves the
+	// reserved registers as it follows the convention documented on wasm.NativeFunction.
+	c.compileReservedStackBasePointerInitialization()
+	c.assembler.CompileRegisterToRegister(amd64.MOVQ, amd64ReservedRegisterForStackBasePointerAddress, amd64.RegAX)
+	c.assembler.CompileConstToRegister(amd64.MOVQ, int64(address), amd64.RegCX)
+	c.assembler.CompileJumpToRegister(amd64.CALL, amd64.RegCX)
+
+	// The native function can't change the module state, so the caller's module context is still valid.
 	return c.compileReturnFunction()
 }
 
@@ -1160,8 +1183,9 @@ func (c *amd64Compiler) compileMul(o *wazeroir.UnionOperation) (err error) {
 // multiplication on x86, see https://www.felixcloutier.com/x86/mul.
 //
 // In summary, one of the values must be on the AX register,
-// and the mul instruction stores the overflow info in DX register which we don't use.
-// Here, we mean "the overflow info" by 65 bit or higher part of the result for 64 bit case.
+// and the mul instruction stores the overflow info in DX register which we don't use
+// except for compileI64MulWide. Here, we mean "the overflow info" by 65 bit or higher
+// part of the result for 64 bit case.
 //
 // So, we have to ensure that
 //  1. Previously located value on DX must be saved to memory stack. That is because
@@ -1240,6 +1264,65 @@ func (c *amd64Compiler) compileMulForInts(is32Bit bool, mulInstruction asm.Instr
 	return nil
 }
 
+// compileI64Add128 implements compiler.compileI64Add128 for the amd64 architecture.
+func (c *amd64Compiler) compileI64Add128() error {
+	return c.compileI64AddOrSub128(amd64.ADDQ, amd64.ADCQ)
+}
+
+// compileI64Sub128 implements compiler.compileI64Sub128 for the amd64 architecture.
+func (c *amd64Compiler) compileI64Sub128() error {
+	return c.compileI64AddOrSub128(amd64.SUBQ, amd64.SBBQ)
+}
+
+// compileI64AddOrSub128 performs the 128-bit addition or subtraction of the two
+// operands, each of which is a pair of i64 with the lower half pushed first, by
+// computing the lower halves with `lowInst` and the higher halves, with the carry
+// (or borrow) from the lower halves, with `highInst`.
+func (c *amd64Compiler) compileI64AddOrSub128(lowInst, highInst asm.Instruction) error {
+	x2Hi := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x2Hi); err != nil {
+		return err
+	}
+	x2Lo := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x2Lo); err != nil {
+		return err
+	}
+	x1Hi := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x1Hi); err != nil {
+		return err
+	}
+	x1Lo := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x1Lo); err != nil {
+		return err
+	}
+
+	// x1Lo += x2Lo, then x1Hi += x2Hi + carry (or -= for subtraction), so
+	// nothing can be emitted between them which modifies the flags.
+	c.assembler.CompileRegisterToRegister(lowInst, x2Lo.register, x1Lo.register)
+	c.assembler.CompileRegisterToRegister(highInst, x2Hi.register, x1Hi.register)
+
+	c.locationStack.releaseRegister(x2Lo)
+	c.locationStack.releaseRegister(x2Hi)
+	c.pushRuntimeValueLocationOnRegister(x1Lo.register, runtimeValueTypeI64)
+	c.pushRuntimeValueLocationOnRegister(x1Hi.register, runtimeValueTypeI64)
+	return nil
+}
+
+// compileI64MulWide implements compiler.compileI64MulWide for the amd64 architecture.
+func (c *amd64Compiler) compileI64MulWide(o *wazeroir.UnionOperation) error {
+	inst := amd64.MULQ
+	if o.B3 { // signed
+		inst = amd64.IMULQ
+	}
+	if err := c.compileMulForInts(false, inst); err != nil {
+		return err
+	}
+	// The lower half is pushed on AX by compileMulForInts, and the higher half is
+	// on DX which is unused after the multiplication.
+	c.pushRuntimeValueLocationOnRegister(amd64.RegDX, runtimeValueTypeI64)
+	return nil
+}
+
 func (c *amd64Compiler) compileMulForFloats(instruction asm.Instruction) error {
 	x2 := c.locationStack.pop()
 	if err := c.compileEnsureOnRegister(x2); err != nil {
@@ -3448,17 +3531,61 @@ func (c *amd64Compiler) compileMemoryAccessCeilSetup(offsetArg uint32, targetSiz
 		return result, nil
 	}
 
+	// A shared memory may have been grown by another goroutine since the memory length was cached, so
+	// allocate a register to reload it before trapping.
+	var tmp asm.Register
+	if c.ir.HasSharedMemory {
+		var err error
+		if tmp, err = c.allocateRegister(registerTypeGeneralPurpose); err != nil {
+			return asm.NilRegister, err
+		}
+	}
+
 	// Now we compare the value with the memory length which is held by callEngine.
 	c.assembler.CompileMemoryToRegister(amd64.CMPQ,
 		amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset, result)
 
 	// Trap if the value is out-of-bounds of memory length.
-	c.compileMaybeExitFromNativeCode(amd64.JCC, nativeCallStatusCodeMemoryOutOfBounds)
+	skip := c.assembler.CompileJump(amd64.JCC)
+	var skipAfterReload asm.Node
+	if c.ir.HasSharedMemory {
+		c.compileMaybeReloadSharedMemoryLen(tmp)
+		c.assembler.CompileRegisterToRegister(amd64.CMPQ, tmp, result)
+		skipAfterReload = c.assembler.CompileJump(amd64.JCC)
+		c.locationStack.markRegisterUnused(tmp)
+	}
+
+	// Before exiting, save "base+offsetArg+1" as the address to report in the error.
+	if targetSizeInBytes > 1 {
+		c.assembler.CompileConstToRegister(amd64.ADDQ, 1-targetSizeInBytes, result)
+	}
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ, result,
+		amd64ReservedRegisterForCallEngine, callEngineExitContextMemoryOutOfBoundsAddressOffset)
+	c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
+	c.assembler.SetJumpTargetOnNext(skip)
+	if skipAfterReload != nil {
+		c.assembler.SetJumpTargetOnNext(skipAfterReload)
+	}
 
 	c.locationStack.markRegisterUnused(result)
 	return result, nil
 }
 
+// compileMaybeReloadSharedMemoryLen reloads callEngine.moduleContext.memorySliceLen into itself and `tmp`, if the
+// memory is shared, as another goroutine may have grown it since it was cached. The buffer of a shared memory never
+// moves, so memoryElement0Address is still valid.
+func (c *amd64Compiler) compileMaybeReloadSharedMemoryLen(tmp asm.Register) {
+	if !c.ir.HasSharedMemory {
+		return
+	}
+	// "tmp = len(callEngine.moduleContext.memoryInstance.Buffer)"
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ,
+		amd64ReservedRegisterForCallEngine, callEngineModuleContextMemoryInstanceOffset, tmp)
+	c.assembler.CompileMemoryToRegister(amd64.MOVQ, tmp, memoryInstanceBufferLenOffset, tmp)
+	c.assembler.CompileRegisterToMemory(amd64.MOVQ, tmp,
+		amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset)
+}
+
 // compileStore implements compiler.compileStore for the amd64 architecture.
 func (c *amd64Compiler) compileStore(o *wazeroir.UnionOperation) error {
 	var movInst asm.Instruction
@@ -3530,7 +3657,7 @@ func (c *amd64Compiler) compileMemoryGrow() error {
 }
 
 // compileMemorySize implements compiler.compileMemorySize for the amd64 architecture.
-func (c *amd64Compiler) compileMemorySize() error {
+func (c *amd64Compiler) compileMemorySize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -3541,12 +3668,15 @@ func (c *amd64Compiler) compileMemorySize() error {
 	}
 	loc := c.pushRuntimeValueLocationOnRegister(reg, runtimeValueTypeI32)
 
+	c.compileMaybeReloadSharedMemoryLen(loc.register)
 	c.assembler.CompileMemoryToRegister(amd64.MOVQ, amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset, loc.register)
 
-	// WebAssembly's memory.size returns the page size (65536) of memory region.
+	// WebAssembly's memory.size returns the page size (65536 by default) of memory region.
 	// That is equivalent to divide the len of memory slice by 65536 and
 	// that can be calculated as SHR by 16 bits as 65536 = 2^16.
-	c.assembler.CompileConstToRegister(amd64.SHRQ, wasm.MemoryPageSizeInBits, loc.register)
+	if pageSizeInBits := int64(o.U1); pageSizeInBits != 0 { // zero for 1-byte pages.
+		c.assembler.CompileConstToRegister(amd64.SHRQ, pageSizeInBits, loc.register)
+	}
 	return nil
 }
 
@@ -3617,6 +3747,7 @@ func (c *amd64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32)
 		// Compare length.
 		c.assembler.CompileMemoryToRegister(amd64.CMPQ, tmp, tableInstanceTableLenOffset, destinationOffset.register)
 	} else {
+		c.compileMaybeReloadSharedMemoryLen(tmp)
 		c.assembler.CompileMemoryToRegister(amd64.CMPQ,
 			amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
 			destinationOffset.register)
@@ -3828,6 +3959,7 @@ func (c *amd64Compiler) compileMemoryCopy() error {
 		return err
 	}
 	c.locationStack.markRegisterUsed(tmp)
+	c.compileMaybeReloadSharedMemoryLen(tmp)
 
 	// sourceOffset += size.
 	c.assembler.CompileRegisterToRegister(amd64.ADDQ, copySize.register, sourceOffset.register)
@@ -3958,6 +4090,7 @@ func (c *amd64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 			tmp, tableInstanceTableLenOffset,
 			destinationOffset.register)
 	} else {
+		c.compileMaybeReloadSharedMemoryLen(tmp)
 		c.assembler.CompileMemoryToRegister(amd64.CMPQ,
 			amd64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
 			destinationOffset.register)
@@ -4283,6 +4416,42 @@ func (c *amd64Compiler) compileTableGrow(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileAtomic implements compiler.compileAtomic for the amd64 architecture.
+func (c *amd64Compiler) compileAtomic(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	// Pushes the descriptor of this operation.
+	if err := c.compileConstI64(&wazeroir.UnionOperation{U1: atomicDescriptor(o)}); err != nil {
+		return err
+	}
+
+	// Atomic operations are done in Go as they may block, e.g. memory.atomic.wait32.
+	if err := c.compileCallBuiltinFunction(builtinFunctionIndexAtomic); err != nil {
+		return err
+	}
+
+	// The builtin consumes the operands and the descriptor.
+	operands, hasResult := atomicOperandCount(o.Kind)
+	for i := 0; i < operands+1; i++ {
+		c.locationStack.pop()
+	}
+
+	if hasResult {
+		loc := c.locationStack.pushRuntimeValueLocationOnStack()
+		loc.valueType = runtimeValueTypeI32
+		if wazeroir.UnsignedType(o.B1) == wazeroir.UnsignedTypeI64 && o.Kind != wazeroir.OperationKindAtomicMemoryWait {
+			loc.valueType = runtimeValueTypeI64
+		}
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerInitialization()
+	c.compileReservedMemoryPointerInitialization()
+	return nil
+}
+
 // compileTableSize implements compiler.compileTableSize for the amd64 architecture.
 func (c *amd64Compiler) compileTableSize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
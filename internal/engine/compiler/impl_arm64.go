@@ -124,11 +124,11 @@ var arm64CallingConventionModuleInstanceAddressRegister = arm64.RegR29
 
 const (
 	// arm64CallEngineArchContextCompilerCallReturnAddressOffset is the offset of archContext.nativeCallReturnAddress in callEngine.
-	arm64CallEngineArchContextCompilerCallReturnAddressOffset = 144
+	arm64CallEngineArchContextCompilerCallReturnAddressOffset = 152
 	// arm64CallEngineArchContextMinimum32BitSignedIntOffset is the offset of archContext.minimum32BitSignedIntAddress in callEngine.
-	arm64CallEngineArchContextMinimum32BitSignedIntOffset = 152
+	arm64CallEngineArchContextMinimum32BitSignedIntOffset = 160
 	// arm64CallEngineArchContextMinimum64BitSignedIntOffset is the offset of archContext.minimum64BitSignedIntAddress in callEngine.
-	arm64CallEngineArchContextMinimum64BitSignedIntOffset = 160
+	arm64CallEngineArchContextMinimum64BitSignedIntOffset = 168
 )
 
 func isZeroRegister(r asm.Register) bool {
@@ -444,8 +444,15 @@ func (c *arm64Compiler) compileExitFromNativeCode(status nativeCallStatusCode) {
 	c.assembler.CompileJumpToRegister(arm64.RET, arm64ReservedRegisterForTemporary)
 }
 
+// compileNativeHostFunction implements compiler.compileNativeHostFunction for the arm64 architecture.
+//
+// TODO: call the native function directly instead of exiting to Go, which calls it via wasm.NativeFunction's Call.
+func (c *arm64Compiler) compileNativeHostFunction(uintptr) error {
+	return c.compileGoDefinedHostFunction(true)
+}
+
 // compileGoHostFunction implements compiler.compileHostFunction for the arm64 architecture.
-func (c *arm64Compiler) compileGoDefinedHostFunction() error {
+func (c *arm64Compiler) compileGoDefinedHostFunction(leaf bool) error {
 	// First we must update the location stack to reflect the number of host function inputs.
 	c.locationStack.init(c.typ)
 
@@ -486,10 +493,12 @@ func (c *arm64Compiler) compileGoDefinedHostFunction() error {
 
 	// Go function can change the module state in arbitrary way, so we have to force
 	// the callEngine.moduleContext initialization on the function return. To do so,
-	// we zero-out callEngine.moduleInstance.
-	c.assembler.CompileRegisterToMemory(arm64.STRD,
-		arm64.RegRZR,
-		arm64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset)
+	// we zero-out callEngine.moduleInstance. Native functions can't.
+	if !leaf {
+		c.assembler.CompileRegisterToMemory(arm64.STRD,
+			arm64.RegRZR,
+			arm64ReservedRegisterForCallEngine, callEngineModuleContextModuleInstanceOffset)
+	}
 
 	return c.compileReturnFunction()
 }
@@ -1514,6 +1523,97 @@ func (c *arm64Compiler) compileMul(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileI64Add128 implements compiler.compileI64Add128 for the arm64 architecture.
+func (c *arm64Compiler) compileI64Add128() error {
+	return c.compileI64AddOrSub128(arm64.ADDS, arm64.ADC)
+}
+
+// compileI64Sub128 implements compiler.compileI64Sub128 for the arm64 architecture.
+func (c *arm64Compiler) compileI64Sub128() error {
+	return c.compileI64AddOrSub128(arm64.SUBS, arm64.SBC)
+}
+
+// compileI64AddOrSub128 performs the 128-bit addition or subtraction of the two
+// operands, each of which is a pair of i64 with the lower half pushed first, by
+// computing the lower halves with `lowInst`, which sets the carry flag, and the
+// higher halves with `highInst`, which consumes it.
+func (c *arm64Compiler) compileI64AddOrSub128(lowInst, highInst asm.Instruction) error {
+	x2Hi := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x2Hi); err != nil {
+		return err
+	}
+	x2Lo := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x2Lo); err != nil {
+		return err
+	}
+	x1Hi := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x1Hi); err != nil {
+		return err
+	}
+	x1Lo := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x1Lo); err != nil {
+		return err
+	}
+
+	// The results are placed on new registers, as the operands might be on the zero
+	// register, and the lower half must not overwrite the operands of the higher half.
+	lo, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.markRegisterUsed(lo)
+	hi, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.markRegisterUnused(x1Lo.register, x1Hi.register, x2Lo.register, x2Hi.register)
+
+	c.assembler.CompileTwoRegistersToRegister(lowInst, x2Lo.register, x1Lo.register, lo)
+	c.assembler.CompileTwoRegistersToRegister(highInst, x2Hi.register, x1Hi.register, hi)
+
+	c.pushRuntimeValueLocationOnRegister(lo, runtimeValueTypeI64)
+	c.pushRuntimeValueLocationOnRegister(hi, runtimeValueTypeI64)
+	return nil
+}
+
+// compileI64MulWide implements compiler.compileI64MulWide for the arm64 architecture.
+func (c *arm64Compiler) compileI64MulWide(o *wazeroir.UnionOperation) error {
+	x2 := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x2); err != nil {
+		return err
+	}
+	x1 := c.locationStack.pop()
+	if err := c.compileEnsureOnRegister(x1); err != nil {
+		return err
+	}
+
+	// Both halves are zero if one of operands is zero.
+	if isZeroRegister(x1.register) || isZeroRegister(x2.register) {
+		c.markRegisterUnused(x1.register, x2.register)
+		c.pushRuntimeValueLocationOnRegister(arm64.RegRZR, runtimeValueTypeI64)
+		c.pushRuntimeValueLocationOnRegister(arm64.RegRZR, runtimeValueTypeI64)
+		return nil
+	}
+
+	hi, err := c.allocateRegister(registerTypeGeneralPurpose)
+	if err != nil {
+		return err
+	}
+	c.markRegisterUnused(x2.register)
+
+	inst := arm64.UMULH
+	if o.B3 { // signed
+		inst = arm64.SMULH
+	}
+	// The higher half is computed first, as the lower half is placed on the register for x1.
+	c.assembler.CompileTwoRegistersToRegister(inst, x2.register, x1.register, hi)
+	c.assembler.CompileRegisterToRegister(arm64.MUL, x2.register, x1.register)
+
+	c.pushRuntimeValueLocationOnRegister(x1.register, runtimeValueTypeI64)
+	c.pushRuntimeValueLocationOnRegister(hi, runtimeValueTypeI64)
+	return nil
+}
+
 // compileClz implements compiler.compileClz for the arm64 architecture.
 func (c *arm64Compiler) compileClz(o *wazeroir.UnionOperation) error {
 	v, err := c.popValueOnRegister()
@@ -2861,13 +2961,52 @@ func (c *arm64Compiler) compileMemoryAccessOffsetSetup(offsetArg uint32, targetS
 
 	// If offsetRegister(= base+offsetArg+targetSizeInBytes) exceeds the memory length,
 	//  we exit the function with nativeCallStatusCodeMemoryOutOfBounds.
-	c.compileMaybeExitFromNativeCode(arm64.BCONDLS, nativeCallStatusCodeMemoryOutOfBounds)
+	skip := c.assembler.CompileJump(arm64.BCONDLS)
+	var skipAfterReload asm.Node
+	if c.ir.HasSharedMemory {
+		// A shared memory may have been grown by another goroutine since the memory length was cached,
+		// so reload it before trapping.
+		c.compileLoadMemorySliceLen(arm64ReservedRegisterForTemporary)
+		c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64ReservedRegisterForTemporary, offsetRegister)
+		skipAfterReload = c.assembler.CompileJump(arm64.BCONDLS)
+	}
+
+	// Before exiting, save "base+offsetArg+1" as the address to report in the error.
+	if targetSizeInBytes > 1 {
+		c.assembler.CompileConstToRegister(arm64.SUB, targetSizeInBytes-1, offsetRegister)
+	}
+	c.assembler.CompileRegisterToMemory(arm64.STRD, offsetRegister,
+		arm64ReservedRegisterForCallEngine, callEngineExitContextMemoryOutOfBoundsAddressOffset)
+	c.compileExitFromNativeCode(nativeCallStatusCodeMemoryOutOfBounds)
+	c.assembler.SetJumpTargetOnNext(skip)
+	if skipAfterReload != nil {
+		c.assembler.SetJumpTargetOnNext(skipAfterReload)
+	}
 
 	// Otherwise, we subtract targetSizeInBytes from offsetRegister.
 	c.assembler.CompileConstToRegister(arm64.SUB, targetSizeInBytes, offsetRegister)
 	return offsetRegister, nil
 }
 
+// compileLoadMemorySliceLen loads len(memory.Buffer) into `dst`. If the memory is shared, this reloads it from the
+// memory instance into callEngine.moduleContext.memorySliceLen, as another goroutine may have grown it since it was
+// cached. The buffer of a shared memory never moves, so memoryElement0Address is still valid.
+func (c *arm64Compiler) compileLoadMemorySliceLen(dst asm.Register) {
+	if !c.ir.HasSharedMemory {
+		c.assembler.CompileMemoryToRegister(arm64.LDRD,
+			arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
+			dst)
+		return
+	}
+	// "dst = len(callEngine.moduleContext.memoryInstance.Buffer)"
+	c.assembler.CompileMemoryToRegister(arm64.LDRD,
+		arm64ReservedRegisterForCallEngine, callEngineModuleContextMemoryInstanceOffset,
+		dst)
+	c.assembler.CompileMemoryToRegister(arm64.LDRD, dst, memoryInstanceBufferLenOffset, dst)
+	c.assembler.CompileRegisterToMemory(arm64.STRD, dst,
+		arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset)
+}
+
 // compileMemoryGrow implements compileMemoryGrow variants for arm64 architecture.
 func (c *arm64Compiler) compileMemoryGrow() error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
@@ -2885,7 +3024,7 @@ func (c *arm64Compiler) compileMemoryGrow() error {
 }
 
 // compileMemorySize implements compileMemorySize variants for arm64 architecture.
-func (c *arm64Compiler) compileMemorySize() error {
+func (c *arm64Compiler) compileMemorySize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
 		return err
 	}
@@ -2896,19 +3035,17 @@ func (c *arm64Compiler) compileMemorySize() error {
 	}
 
 	// "reg = len(memory.Buffer)"
-	c.assembler.CompileMemoryToRegister(
-		arm64.LDRD,
-		arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
-		reg,
-	)
+	c.compileLoadMemorySliceLen(reg)
 
 	// memory.size loads the page size of memory, so we have to divide by the page size.
 	// "reg = reg >> wasm.MemoryPageSizeInBits (== reg / wasm.MemoryPageSize) "
-	c.assembler.CompileConstToRegister(
-		arm64.LSR,
-		wasm.MemoryPageSizeInBits,
-		reg,
-	)
+	if pageSizeInBits := int64(o.U1); pageSizeInBits != 0 { // zero for 1-byte pages.
+		c.assembler.CompileConstToRegister(
+			arm64.LSR,
+			pageSizeInBits,
+			reg,
+		)
+	}
 
 	c.pushRuntimeValueLocationOnRegister(reg, runtimeValueTypeI32)
 	return nil
@@ -3142,9 +3279,7 @@ func (c *arm64Compiler) compileInitImpl(isTable bool, index, tableIndex uint32)
 			tableInstanceAddressReg, tableInstanceTableLenOffset,
 			arm64ReservedRegisterForTemporary)
 	} else {
-		c.assembler.CompileMemoryToRegister(arm64.LDRD,
-			arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
-			arm64ReservedRegisterForTemporary)
+		c.compileLoadMemorySliceLen(arm64ReservedRegisterForTemporary)
 	}
 
 	c.assembler.CompileTwoRegistersToNone(arm64.CMP, arm64ReservedRegisterForTemporary, destinationOffset.register)
@@ -3328,9 +3463,7 @@ func (c *arm64Compiler) compileCopyImpl(isTable bool, srcTableIndex, dstTableInd
 			arm64ReservedRegisterForTemporary)
 	} else {
 		// arm64ReservedRegisterForTemporary = len(memoryInst.Buffer).
-		c.assembler.CompileMemoryToRegister(arm64.LDRD,
-			arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
-			arm64ReservedRegisterForTemporary)
+		c.compileLoadMemorySliceLen(arm64ReservedRegisterForTemporary)
 	}
 
 	// Check memory len >= sourceOffset.
@@ -3577,9 +3710,7 @@ func (c *arm64Compiler) compileFillImpl(isTable bool, tableIndex uint32) error {
 			arm64ReservedRegisterForTemporary)
 	} else {
 		// arm64ReservedRegisterForTemporary = len(memoryInst.Buffer).
-		c.assembler.CompileMemoryToRegister(arm64.LDRD,
-			arm64ReservedRegisterForCallEngine, callEngineModuleContextMemorySliceLenOffset,
-			arm64ReservedRegisterForTemporary)
+		c.compileLoadMemorySliceLen(arm64ReservedRegisterForTemporary)
 	}
 
 	// Check  len >= destinationOffset.
@@ -3874,6 +4005,42 @@ func (c *arm64Compiler) compileTableGrow(o *wazeroir.UnionOperation) error {
 	return nil
 }
 
+// compileAtomic implements compiler.compileAtomic for the arm64 architecture.
+func (c *arm64Compiler) compileAtomic(o *wazeroir.UnionOperation) error {
+	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
+		return err
+	}
+
+	// Pushes the descriptor of this operation.
+	if err := c.compileIntConstant(false, atomicDescriptor(o)); err != nil {
+		return err
+	}
+
+	// Atomic operations are done in Go as they may block, e.g. memory.atomic.wait32.
+	if err := c.compileCallGoFunction(nativeCallStatusCodeCallBuiltInFunction, builtinFunctionIndexAtomic); err != nil {
+		return err
+	}
+
+	// The builtin consumes the operands and the descriptor.
+	operands, hasResult := atomicOperandCount(o.Kind)
+	for i := 0; i < operands+1; i++ {
+		c.locationStack.pop()
+	}
+
+	if hasResult {
+		v := c.locationStack.pushRuntimeValueLocationOnStack()
+		v.valueType = runtimeValueTypeI32
+		if wazeroir.UnsignedType(o.B1) == wazeroir.UnsignedTypeI64 && o.Kind != wazeroir.OperationKindAtomicMemoryWait {
+			v.valueType = runtimeValueTypeI64
+		}
+	}
+
+	// After return, we re-initialize reserved registers just like preamble of functions.
+	c.compileReservedStackBasePointerRegisterInitialization()
+	c.compileReservedMemoryRegisterInitialization()
+	return nil
+}
+
 // compileTableSize implements compiler.compileTableSize for the arm64 architecture.
 func (c *arm64Compiler) compileTableSize(o *wazeroir.UnionOperation) error {
 	if err := c.maybeCompileMoveTopConditionalToGeneralPurposeRegister(); err != nil {
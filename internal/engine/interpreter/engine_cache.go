@@ -0,0 +1,214 @@
+package interpreter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/filecache"
+	"github.com/tetratelabs/wazero/internal/u32"
+	"github.com/tetratelabs/wazero/internal/u64"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wazeroir"
+)
+
+// interpreterMagic distinguishes the compiled functions of the interpreter
+// from the native code which the compiler stores in the same file cache.
+var interpreterMagic = "WAZEROIR"
+
+// fileCacheKey returns the key of the module in the file cache, which is also
+// the key of its experimental.CompilationCacheEvent. This differs from the
+// module ID, which the compiler uses as its key, so that runtimes with
+// different engines can share a cache directory.
+func fileCacheKey(module *wasm.Module) filecache.Key {
+	return sha256.Sum256(append(module.ID[:], interpreterMagic...))
+}
+
+func (e *engine) addCompiledFunctionsToCache(ctx context.Context, module *wasm.Module, fs []compiledFunction, ensureTermination bool) {
+	if e.fileCache == nil || module.IsHostModule {
+		return
+	}
+	// Go functions, such as intrinsics, can't be serialized.
+	for i := range fs {
+		if fs[i].hostFn != nil {
+			return
+		}
+	}
+	key := fileCacheKey(module)
+	err := filecache.Add(ctx, e.fileCache, key, serializeCompiledFunctions(e.wazeroVersion, fs, ensureTermination))
+	filecache.Notify(ctx, experimental.CompilationCacheEventStore, key, false, "", err)
+}
+
+func (e *engine) getCompiledFunctionsFromCache(ctx context.Context, module *wasm.Module, ensureTermination bool) (fs []compiledFunction, hit bool) {
+	if e.fileCache == nil || module.IsHostModule {
+		return
+	}
+
+	key := fileCacheKey(module)
+	cached, hit, err := e.fileCache.Get(key)
+	if !hit || err != nil {
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, key, false, "", err)
+		return nil, false
+	}
+
+	var staleCache bool
+	// Note: cached.Close is ensured to be called in deserializeCompiledFunctions.
+	fs, staleCache, err = deserializeCompiledFunctions(e.wazeroVersion, cached, module, ensureTermination)
+	if err != nil {
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, key, false, "", err)
+		return nil, false
+	} else if staleCache {
+		err = e.fileCache.Delete(key)
+		filecache.Notify(ctx, experimental.CompilationCacheEventEvict, key, false, filecache.ReasonStale, err)
+		filecache.Notify(ctx, experimental.CompilationCacheEventMiss, key, false, filecache.ReasonStale, nil)
+		return nil, false
+	}
+	filecache.Notify(ctx, experimental.CompilationCacheEventHit, key, false, "", nil)
+	return fs, true
+}
+
+// serializeCompiledFunctions encodes the lowered operations of each function,
+// which only reference each other by index, so they can be loaded as data.
+func serializeCompiledFunctions(wazeroVersion string, fs []compiledFunction, ensureTermination bool) io.Reader {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteString(interpreterMagic)
+	buf.WriteByte(byte(len(wazeroVersion)))
+	buf.WriteString(wazeroVersion)
+	if ensureTermination {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+	buf.Write(u32.LeBytes(uint32(len(fs))))
+	for i := range fs {
+		f := &fs[i]
+		buf.Write(u32.LeBytes(uint32(len(f.body))))
+		for j := range f.body {
+			op := &f.body[j]
+			buf.Write([]byte{byte(op.Kind), byte(op.Kind >> 8), op.B1, op.B2})
+			if op.B3 {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+			buf.Write(u64.LeBytes(op.U1))
+			buf.Write(u64.LeBytes(op.U2))
+			buf.Write(u64.LeBytes(op.U3))
+			buf.Write(u32.LeBytes(uint32(len(op.Us))))
+			for _, u := range op.Us {
+				buf.Write(u64.LeBytes(u))
+			}
+		}
+		buf.Write(u32.LeBytes(uint32(len(f.offsetsInWasmBinary))))
+		for _, offset := range f.offsetsInWasmBinary {
+			buf.Write(u64.LeBytes(offset))
+		}
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// deserializeCompiledFunctions reads the functions written by
+// serializeCompiledFunctions. staleCache is true when they were written by
+// another version of wazero, or with a different ensureTermination.
+func deserializeCompiledFunctions(wazeroVersion string, reader io.ReadCloser, module *wasm.Module, ensureTermination bool) (fs []compiledFunction, staleCache bool, err error) {
+	defer reader.Close()
+
+	header := make([]byte, len(interpreterMagic)+1+len(wazeroVersion)+1)
+	if _, err = io.ReadFull(reader, header); err != nil {
+		return nil, false, fmt.Errorf("compilationcache: error reading header: %v", err)
+	}
+	if string(header[:len(interpreterMagic)]) != interpreterMagic {
+		return nil, false, fmt.Errorf("compilationcache: invalid header: %q", header[:len(interpreterMagic)])
+	}
+	versionBegin := len(interpreterMagic) + 1
+	if int(header[versionBegin-1]) != len(wazeroVersion) ||
+		string(header[versionBegin:versionBegin+len(wazeroVersion)]) != wazeroVersion ||
+		(header[len(header)-1] != 0) != ensureTermination {
+		return nil, true, nil
+	}
+
+	b, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("compilationcache: error reading functions: %v", err)
+	}
+	r := &cacheReader{b: b}
+	functionsNum := r.len()
+	if r.err == nil && functionsNum != len(module.CodeSection) {
+		return nil, false, fmt.Errorf("compilationcache: invalid function count: %d != %d", functionsNum, len(module.CodeSection))
+	}
+	fs = make([]compiledFunction, functionsNum)
+	for i := range fs {
+		f := &fs[i]
+		f.body = make([]wazeroir.UnionOperation, r.len())
+		for j := range f.body {
+			op := &f.body[j]
+			op.Kind = wazeroir.OperationKind(r.u16())
+			op.B1, op.B2, op.B3 = r.u8(), r.u8(), r.u8() != 0
+			op.U1, op.U2, op.U3 = r.u64(), r.u64(), r.u64()
+			if n := r.len(); n > 0 {
+				op.Us = make([]uint64, n)
+				for k := range op.Us {
+					op.Us[k] = r.u64()
+				}
+			}
+		}
+		if n := r.len(); n > 0 {
+			f.offsetsInWasmBinary = make([]uint64, n)
+			for k := range f.offsetsInWasmBinary {
+				f.offsetsInWasmBinary[k] = r.u64()
+			}
+		}
+		if r.err != nil {
+			return nil, false, fmt.Errorf("compilationcache: error reading func[%d]: %v", i, r.err)
+		}
+	}
+	return fs, false, nil
+}
+
+// cacheReader reads little-endian integers, remembering the first error.
+type cacheReader struct {
+	b   []byte
+	err error
+}
+
+func (r *cacheReader) next(n int) []byte {
+	if r.err == nil && len(r.b) < n {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	ret := r.b[:n]
+	r.b = r.b[n:]
+	return ret
+}
+
+func (r *cacheReader) u8() byte {
+	return r.next(1)[0]
+}
+
+func (r *cacheReader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.next(2))
+}
+
+func (r *cacheReader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+// len reads the length of a slice. As each element is at least one byte,
+// this fails instead of returning more than the bytes remaining, so that a
+// corrupt entry can't allocate excessively.
+func (r *cacheReader) len() int {
+	n := int(binary.LittleEndian.Uint32(r.next(4)))
+	if r.err == nil && n > len(r.b) {
+		r.err = io.ErrUnexpectedEOF
+	}
+	if r.err != nil {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wazeroir"
+)
+
+func TestSerializeCompiledFunctions(t *testing.T) {
+	module := &wasm.Module{CodeSection: []wasm.Code{{}, {}}}
+	fs := []compiledFunction{
+		{
+			body: []wazeroir.UnionOperation{
+				{Kind: wazeroir.OperationKindConstI32, U1: 42},
+				{Kind: wazeroir.OperationKindBrTable, B1: 1, B2: 2, B3: true, U1: 1, U2: 2, U3: 3, Us: []uint64{4, 5}},
+			},
+			offsetsInWasmBinary: []uint64{10, 20},
+		},
+		{body: []wazeroir.UnionOperation{{Kind: wazeroir.OperationKindUnreachable}}},
+	}
+
+	serialized, err := io.ReadAll(serializeCompiledFunctions("1.0.0", fs, true))
+	require.NoError(t, err)
+
+	actual, stale, err := deserializeCompiledFunctions("1.0.0", io.NopCloser(bytes.NewReader(serialized)), module, true)
+	require.NoError(t, err)
+	require.False(t, stale)
+	require.Equal(t, fs, actual)
+
+	t.Run("stale version", func(t *testing.T) {
+		_, stale, err := deserializeCompiledFunctions("1.0.1", io.NopCloser(bytes.NewReader(serialized)), module, true)
+		require.NoError(t, err)
+		require.True(t, stale)
+	})
+
+	t.Run("stale ensureTermination", func(t *testing.T) {
+		_, stale, err := deserializeCompiledFunctions("1.0.0", io.NopCloser(bytes.NewReader(serialized)), module, false)
+		require.NoError(t, err)
+		require.True(t, stale)
+	})
+
+	t.Run("different module", func(t *testing.T) {
+		_, _, err := deserializeCompiledFunctions("1.0.0", io.NopCloser(bytes.NewReader(serialized)), &wasm.Module{}, true)
+		require.EqualError(t, err, "compilationcache: invalid function count: 2 != 0")
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		truncated := serialized[:len(serialized)-1]
+		_, _, err := deserializeCompiledFunctions("1.0.0", io.NopCloser(bytes.NewReader(truncated)), module, true)
+		require.EqualError(t, err, "compilationcache: error reading func[1]: unexpected EOF")
+	})
+
+	t.Run("compiler entry", func(t *testing.T) {
+		_, _, err := deserializeCompiledFunctions("1.0.0", io.NopCloser(bytes.NewReader([]byte("WAZERO\x051.0.0\x01"))), module, true)
+		require.EqualError(t, err, "compilationcache: error reading header: unexpected EOF")
+	})
+}
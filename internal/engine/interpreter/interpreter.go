@@ -15,6 +15,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/moremath"
+	"github.com/tetratelabs/wazero/internal/version"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
@@ -34,6 +35,11 @@ type engine struct {
 	mux               sync.RWMutex
 	// labelAddressResolutionCache is the temporary cache used to map LabelKind -> FrameID -> the index to the body.
 	labelAddressResolutionCache [wazeroir.LabelKindNum][]uint64
+
+	// fileCache is only set by NewEngineExecForbidden, as otherwise lowering
+	// functions to wazeroir is fast enough to not be worth caching.
+	fileCache     filecache.Cache
+	wazeroVersion string
 }
 
 func NewEngine(_ context.Context, enabledFeatures api.CoreFeatures, _ filecache.Cache) wasm.Engine {
@@ -43,6 +49,19 @@ func NewEngine(_ context.Context, enabledFeatures api.CoreFeatures, _ filecache.
 	}
 }
 
+// NewEngineExecForbidden returns an engine for a runtime which forbids
+// executable memory, so the interpreter is its only engine. Unlike NewEngine,
+// this stores compiled functions in the file cache, which are later loaded
+// as data instead of native code.
+func NewEngineExecForbidden(_ context.Context, enabledFeatures api.CoreFeatures, fileCache filecache.Cache) wasm.Engine {
+	return &engine{
+		enabledFeatures:   enabledFeatures,
+		compiledFunctions: map[wasm.ModuleID][]compiledFunction{},
+		fileCache:         fileCache,
+		wazeroVersion:     version.GetWazeroVersion(),
+	}
+}
+
 // Close implements the same method as documented on wasm.Engine.
 func (e *engine) Close() (err error) {
 	return
@@ -58,6 +77,26 @@ func (e *engine) DeleteCompiledModule(m *wasm.Module) {
 	e.deleteCompiledFunctions(m)
 }
 
+// CompiledSize implements the same method as documented on wasm.SizeEstimator.
+func (e *engine) CompiledSize(m *wasm.Module) (uint64, bool) {
+	fs, ok := e.getCompiledFunctions(m)
+	if !ok {
+		return 0, false
+	}
+	size := uint64(len(fs)) * uint64(unsafe.Sizeof(compiledFunction{}))
+	for i := range fs {
+		size += uint64(len(fs[i].body)) * uint64(unsafe.Sizeof(wazeroir.UnionOperation{}))
+		size += uint64(len(fs[i].offsetsInWasmBinary)) * 8
+	}
+	return size, true
+}
+
+// ModuleEngineSize implements the same method as documented on wasm.SizeEstimator.
+func (e *engine) ModuleEngineSize(m *wasm.Module) uint64 {
+	functionCount := uint64(len(m.FunctionSection)) + uint64(m.ImportFunctionCount)
+	return uint64(unsafe.Sizeof(moduleEngine{})) + functionCount*uint64(unsafe.Sizeof(function{}))
+}
+
 func (e *engine) deleteCompiledFunctions(module *wasm.Module) {
 	e.mux.Lock()
 	defer e.mux.Unlock()
@@ -106,6 +145,13 @@ type callEngine struct {
 
 	// stackiterator for Listeners to walk frames and stack.
 	stackIterator stackIterator
+
+	// introspectionDepth is experimental.GuestStack Depth, set only when
+	// experimental.WithIntrospection was used.
+	introspectionDepth int
+
+	// yieldCounter counts the exit code checks to periodically yield to the Go scheduler.
+	yieldCounter wasm.YieldCounter
 }
 
 func (e *moduleEngine) newCallEngine(compiled *function) *callEngine {
@@ -168,6 +214,22 @@ func (ce *callEngine) pushFrame(frame *callFrame) {
 	ce.frames = append(ce.frames, frame)
 }
 
+// newFrame pushes a callFrame for the function f onto the call stack, and returns it.
+//
+// The callFrame objects left above the top of the call stack by popFrame are reused,
+// so that calling a function doesn't allocate once the call stack is warmed up.
+func (ce *callEngine) newFrame(f *function) (frame *callFrame) {
+	if n := len(ce.frames); n < cap(ce.frames) {
+		frame = ce.frames[:n+1][n]
+	}
+	if frame == nil {
+		frame = &callFrame{}
+	}
+	*frame = callFrame{f: f, base: len(ce.stack)}
+	ce.pushFrame(frame)
+	return
+}
+
 func (ce *callEngine) popFrame() (frame *callFrame) {
 	// No need to check stack bound as we can assume that all the operations are valid thanks to validateFunction at
 	// module validation phase and wazeroir translation before compilation.
@@ -193,8 +255,11 @@ type compiledFunction struct {
 	listener            experimental.FunctionListener
 	offsetsInWasmBinary []uint64
 	hostFn              interface{}
-	ensureTermination   bool
-	index               wasm.Index
+	// intrinsic is true when hostFn replaces a guest function. See
+	// wasm.Code Intrinsic.
+	intrinsic         bool
+	ensureTermination bool
+	index             wasm.Index
 }
 
 type function struct {
@@ -302,8 +367,27 @@ func (f internalFunction) SourceOffsetForPC(pc experimental.ProgramCounter) uint
 const callFrameStackSize = 0
 
 // CompileModule implements the same method as documented on wasm.Engine.
-func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
 	if _, ok := e.getCompiledFunctions(module); ok { // cache hit!
+		filecache.Notify(ctx, experimental.CompilationCacheEventHit, module.ID, true, "", nil)
+		wasm.SetCompiledFromCache(ctx)
+		return nil
+	}
+	filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, true, "", nil)
+
+	if funcs, ok := e.getCompiledFunctionsFromCache(ctx, module, ensureTermination); ok {
+		imported := module.ImportFunctionCount
+		for i := range funcs {
+			compiled := &funcs[i]
+			compiled.source = module
+			compiled.ensureTermination = ensureTermination
+			if i < len(listeners) {
+				compiled.listener = listeners[i]
+			}
+			compiled.index = imported + uint32(i)
+		}
+		e.addCompiledFunctions(module, funcs)
+		wasm.SetCompiledFromCache(ctx)
 		return nil
 	}
 
@@ -325,6 +409,7 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		// which need to be compiled down to wazeroir.
 		if codeSeg := &module.CodeSection[i]; codeSeg.GoFunc != nil {
 			compiled.hostFn = codeSeg.GoFunc
+			compiled.intrinsic = codeSeg.Intrinsic
 		} else {
 			ir, err := irCompiler.Next()
 			if err != nil {
@@ -342,6 +427,8 @@ func (e *engine) CompileModule(_ context.Context, module *wasm.Module, listeners
 		compiled.index = imported + uint32(i)
 	}
 	e.addCompiledFunctions(module, funcs)
+	filecache.Notify(ctx, experimental.CompilationCacheEventStore, module.ID, true, "", nil)
+	e.addCompiledFunctionsToCache(ctx, module, funcs, ensureTermination)
 	return nil
 }
 
@@ -497,6 +584,15 @@ func (ce *callEngine) Call(ctx context.Context, params ...uint64) (results []uin
 	return ce.call(ctx, params, nil)
 }
 
+// CallWithOptions implements the same method as documented on api.Function.
+func (ce *callEngine) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) (results []uint64, err error) {
+	err = ce.f.moduleInstance.CallWithOptions(opts, func() (err error) {
+		results, err = ce.Call(ctx, params...)
+		return
+	})
+	return
+}
+
 // CallWithStack implements the same method as documented on api.Function.
 func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
 	params, results, err := wasm.SplitCallStack(ce.f.funcType, stack)
@@ -509,6 +605,13 @@ func (ce *callEngine) CallWithStack(ctx context.Context, stack []uint64) error {
 
 func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []uint64, err error) {
 	m := ce.f.moduleInstance
+	if v := ctx.Value(experimental.IntrospectionKey{}); v != nil {
+		ce.introspectionDepth = 1
+		if outer, ok := v.(*callEngine); ok { // called back from a host function
+			ce.introspectionDepth += outer.introspectionDepth
+		}
+		ctx = context.WithValue(ctx, experimental.IntrospectionKey{}, ce)
+	}
 	if ce.f.parent.ensureTermination {
 		select {
 		case <-ctx.Done():
@@ -529,6 +632,7 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 
 		if v := recover(); v != nil {
 			err = ce.recoverOnCall(ctx, m, v)
+			m.Trapped(ctx, err)
 		}
 	}()
 
@@ -556,6 +660,7 @@ func (ce *callEngine) call(ctx context.Context, params, results []uint64) (_ []u
 // listener invocations when unwinding the call stack.
 type functionListenerInvocation struct {
 	experimental.FunctionListener
+	mod api.Module
 	def api.FunctionDefinition
 }
 
@@ -577,16 +682,26 @@ func (ce *callEngine) recoverOnCall(ctx context.Context, m *wasm.ModuleInstance,
 		}
 		builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), sources)
 		if f.parent.listener != nil {
+			// Abort with the calling module, like Before.
+			caller := m
+			if n := len(ce.frames); n > 0 {
+				caller = ce.frames[n-1].f.moduleInstance
+			}
 			functionListeners = append(functionListeners, functionListenerInvocation{
 				FunctionListener: f.parent.listener,
+				mod:              caller,
 				def:              f.definition(),
 			})
 		}
 	}
 
 	err = builder.FromRecovered(v)
+	err = wasmdebug.WithLabels(err, m.Labels)
+	if wasmdebug.IsPanic(v) {
+		err = m.Panicked(ctx, v, err)
+	}
 	for i := range functionListeners {
-		functionListeners[i].Abort(ctx, m, functionListeners[i].def, err)
+		functionListeners[i].Abort(ctx, functionListeners[i].mod, functionListeners[i].def, err)
 	}
 
 	// Allows the reuse of CallEngine.
@@ -604,7 +719,31 @@ func (ce *callEngine) callFunction(ctx context.Context, m *wasm.ModuleInstance,
 	}
 }
 
+// Stack is used by experimental.Stack.
+func (ce *callEngine) Stack() experimental.GuestStack {
+	return experimental.GuestStack{
+		Used:  uint64(len(ce.frames)),
+		Limit: uint64(callStackCeiling),
+		Depth: ce.introspectionDepth,
+	}
+}
+
+// Caller is used by experimental.Caller to return the caller of the host
+// function currently called, whose frame is at the top of the stack.
+func (ce *callEngine) Caller() api.FunctionDefinition {
+	if n := len(ce.frames); n >= 2 {
+		return ce.frames[n-2].f.definition()
+	}
+	return nil
+}
+
 func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f *function, stack []uint64) {
+	intrinsic := f.parent.intrinsic
+	if intrinsic {
+		// Intrinsics replace guest code, so they run against the module
+		// which defines them, whichever calls them.
+		m = f.moduleInstance
+	}
 	typ := f.funcType
 	lsn := f.parent.listener
 	if lsn != nil {
@@ -613,9 +752,11 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 		lsn.Before(ctx, m, f.definition(), params, &ce.stackIterator)
 		ce.stackIterator.clear()
 	}
-	frame := &callFrame{f: f, base: len(ce.stack)}
-	ce.pushFrame(frame)
+	ce.newFrame(f)
 
+	if !intrinsic {
+		m.BeforeHostCall(ctx)
+	}
 	fn := f.parent.hostFn
 	switch fn := fn.(type) {
 	case api.GoModuleFunction:
@@ -633,7 +774,7 @@ func (ce *callEngine) callGoFunc(ctx context.Context, m *wasm.ModuleInstance, f
 }
 
 func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance, f *function) {
-	frame := &callFrame{f: f, base: len(ce.stack)}
+	frame := ce.newFrame(f)
 	moduleInst := f.moduleInstance
 	functions := moduleInst.Engine.(*moduleEngine).functions
 	memoryInst := moduleInst.MemoryInstance
@@ -642,7 +783,6 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 	typeIDs := moduleInst.TypeIDs
 	dataInstances := moduleInst.DataInstances
 	elementInstances := moduleInst.ElementInstances
-	ce.pushFrame(frame)
 	body := frame.f.parent.body
 	bodyLen := uint64(len(body))
 	for frame.pc < bodyLen {
@@ -655,6 +795,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			if err := m.FailIfClosed(); err != nil {
 				panic(err)
 			}
+			ce.yieldCounter.Checkpoint()
 			frame.pc++
 		case wazeroir.OperationKindUnreachable:
 			panic(wasmruntime.ErrRuntimeUnreachable)
@@ -754,22 +895,23 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			switch wazeroir.UnsignedType(op.B1) {
 			case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
 				if val, ok := memoryInst.ReadUint32Le(offset); !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				} else {
 					ce.pushValue(uint64(val))
 				}
 			case wazeroir.UnsignedTypeI64, wazeroir.UnsignedTypeF64:
 				if val, ok := memoryInst.ReadUint64Le(offset); !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				} else {
 					ce.pushValue(val)
 				}
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad8:
-			val, ok := memoryInst.ReadByte(ce.popMemoryOffset(op))
+			offset := ce.popMemoryOffset(op)
+			val, ok := memoryInst.ReadByte(offset)
 			if !ok {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 
 			switch wazeroir.SignedInt(op.B1) {
@@ -783,9 +925,10 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			frame.pc++
 		case wazeroir.OperationKindLoad16:
 
-			val, ok := memoryInst.ReadUint16Le(ce.popMemoryOffset(op))
+			offset := ce.popMemoryOffset(op)
+			val, ok := memoryInst.ReadUint16Le(offset)
 			if !ok {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 
 			switch wazeroir.SignedInt(op.B1) {
@@ -798,9 +941,10 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			}
 			frame.pc++
 		case wazeroir.OperationKindLoad32:
-			val, ok := memoryInst.ReadUint32Le(ce.popMemoryOffset(op))
+			offset := ce.popMemoryOffset(op)
+			val, ok := memoryInst.ReadUint32Le(offset)
 			if !ok {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 
 			if op.B1 == 1 { // Signed
@@ -815,11 +959,11 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			switch wazeroir.UnsignedType(op.B1) {
 			case wazeroir.UnsignedTypeI32, wazeroir.UnsignedTypeF32:
 				if !memoryInst.WriteUint32Le(offset, uint32(val)) {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 			case wazeroir.UnsignedTypeI64, wazeroir.UnsignedTypeF64:
 				if !memoryInst.WriteUint64Le(offset, val) {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 			}
 			frame.pc++
@@ -827,21 +971,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			val := byte(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteByte(offset, val) {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore16:
 			val := uint16(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteUint16Le(offset, val) {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 			frame.pc++
 		case wazeroir.OperationKindStore32:
 			val := uint32(ce.popValue())
 			offset := ce.popMemoryOffset(op)
 			if !memoryInst.WriteUint32Le(offset, val) {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 			frame.pc++
 		case wazeroir.OperationKindMemorySize:
@@ -849,12 +993,83 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			frame.pc++
 		case wazeroir.OperationKindMemoryGrow:
 			n := ce.popValue()
+			if memoryInst.ExceedsGrowthRate(uint32(n)) {
+				panic(wasmruntime.ErrRuntimeMemoryGrowthRateExceeded)
+			}
 			if res, ok := memoryInst.Grow(uint32(n)); !ok {
 				ce.pushValue(uint64(0xffffffff)) // = -1 in signed 32-bit integer.
 			} else {
 				ce.pushValue(uint64(res))
 			}
 			frame.pc++
+		case wazeroir.OperationKindI64Add128, wazeroir.OperationKindI64Sub128:
+			rhsHi, rhsLo := ce.popValue(), ce.popValue()
+			lhsHi, lhsLo := ce.popValue(), ce.popValue()
+			var lo, hi uint64
+			if op.Kind == wazeroir.OperationKindI64Add128 {
+				var carry uint64
+				lo, carry = bits.Add64(lhsLo, rhsLo, 0)
+				hi, _ = bits.Add64(lhsHi, rhsHi, carry)
+			} else {
+				var borrow uint64
+				lo, borrow = bits.Sub64(lhsLo, rhsLo, 0)
+				hi, _ = bits.Sub64(lhsHi, rhsHi, borrow)
+			}
+			ce.pushValue(lo)
+			ce.pushValue(hi)
+			frame.pc++
+		case wazeroir.OperationKindI64MulWide:
+			rhs, lhs := ce.popValue(), ce.popValue()
+			hi, lo := bits.Mul64(lhs, rhs)
+			if op.B3 { // signed
+				// Adjust the unsigned high bits for negative operands, as (2^64 + x) * y = 2^64 * y + x * y.
+				if int64(lhs) < 0 {
+					hi -= rhs
+				}
+				if int64(rhs) < 0 {
+					hi -= lhs
+				}
+			}
+			ce.pushValue(lo)
+			ce.pushValue(hi)
+			frame.pc++
+		case wazeroir.OperationKindAtomicMemoryWait:
+			timeout := int64(ce.popValue())
+			expected := ce.popValue()
+			offset := ce.popAtomicOffset(memoryInst, op)
+			ce.pushValue(memoryInst.AtomicWait(ctx, offset, uint32(op.B2), expected, timeout))
+			frame.pc++
+		case wazeroir.OperationKindAtomicMemoryNotify:
+			count := uint32(ce.popValue())
+			offset := ce.popAtomicOffset(memoryInst, op)
+			ce.pushValue(uint64(memoryInst.AtomicNotify(offset, count)))
+			frame.pc++
+		case wazeroir.OperationKindAtomicFence:
+			wasm.AtomicFence()
+			frame.pc++
+		case wazeroir.OperationKindAtomicLoad:
+			offset := ce.popAtomicOffset(memoryInst, op)
+			ce.pushValue(memoryInst.AtomicLoad(offset, uint32(op.B2)))
+			frame.pc++
+		case wazeroir.OperationKindAtomicStore:
+			val := ce.popValue()
+			offset := ce.popAtomicOffset(memoryInst, op)
+			memoryInst.AtomicStore(offset, uint32(op.B2), val)
+			frame.pc++
+		case wazeroir.OperationKindAtomicRMW:
+			val := ce.popValue()
+			offset := ce.popAtomicOffset(memoryInst, op)
+			arithmeticOp := wazeroir.AtomicArithmeticOp(op.U3)
+			ce.pushValue(memoryInst.AtomicRMW(offset, uint32(op.B2), func(old uint64) uint64 {
+				return arithmeticOp.Apply(old, val)
+			}))
+			frame.pc++
+		case wazeroir.OperationKindAtomicRMWCmpxchg:
+			replacement := ce.popValue()
+			expected := ce.popValue()
+			offset := ce.popAtomicOffset(memoryInst, op)
+			ce.pushValue(memoryInst.AtomicCompareExchange(offset, uint32(op.B2), expected, replacement))
+			frame.pc++
 		case wazeroir.OperationKindConstI32, wazeroir.OperationKindConstI64,
 			wazeroir.OperationKindConstF32, wazeroir.OperationKindConstF64:
 			ce.pushValue(op.U1)
@@ -1832,18 +2047,18 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType128:
 				lo, ok := memoryInst.ReadUint64Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(lo)
 				hi, ok := memoryInst.ReadUint64Le(offset + 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(hi)
 			case wazeroir.V128LoadType8x8s:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(
 					uint64(uint16(int8(data[3])))<<48 | uint64(uint16(int8(data[2])))<<32 | uint64(uint16(int8(data[1])))<<16 | uint64(uint16(int8(data[0]))),
@@ -1854,7 +2069,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType8x8u:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(
 					uint64(data[3])<<48 | uint64(data[2])<<32 | uint64(data[1])<<16 | uint64(data[0]),
@@ -1865,7 +2080,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType16x4s:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(
 					uint64(int16(binary.LittleEndian.Uint16(data[2:])))<<32 |
@@ -1878,7 +2093,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType16x4u:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(
 					uint64(binary.LittleEndian.Uint16(data[2:]))<<32 | uint64(binary.LittleEndian.Uint16(data)),
@@ -1889,21 +2104,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType32x2s:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data))))
 				ce.pushValue(uint64(int32(binary.LittleEndian.Uint32(data[4:]))))
 			case wazeroir.V128LoadType32x2u:
 				data, ok := memoryInst.Read(offset, 8)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data)))
 				ce.pushValue(uint64(binary.LittleEndian.Uint32(data[4:])))
 			case wazeroir.V128LoadType8Splat:
 				v, ok := memoryInst.ReadByte(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				v8 := uint64(v)<<56 | uint64(v)<<48 | uint64(v)<<40 | uint64(v)<<32 |
 					uint64(v)<<24 | uint64(v)<<16 | uint64(v)<<8 | uint64(v)
@@ -1912,7 +2127,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType16Splat:
 				v, ok := memoryInst.ReadUint16Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				v4 := uint64(v)<<48 | uint64(v)<<32 | uint64(v)<<16 | uint64(v)
 				ce.pushValue(v4)
@@ -1920,7 +2135,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType32Splat:
 				v, ok := memoryInst.ReadUint32Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				vv := uint64(v)<<32 | uint64(v)
 				ce.pushValue(vv)
@@ -1928,21 +2143,21 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case wazeroir.V128LoadType64Splat:
 				lo, ok := memoryInst.ReadUint64Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(lo)
 				ce.pushValue(lo)
 			case wazeroir.V128LoadType32zero:
 				lo, ok := memoryInst.ReadUint32Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(uint64(lo))
 				ce.pushValue(0)
 			case wazeroir.V128LoadType64zero:
 				lo, ok := memoryInst.ReadUint64Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				ce.pushValue(lo)
 				ce.pushValue(0)
@@ -1955,7 +2170,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case 8:
 				b, ok := memoryInst.ReadByte(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				if op.B2 < 8 {
 					s := op.B2 << 3
@@ -1967,7 +2182,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case 16:
 				b, ok := memoryInst.ReadUint16Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				if op.B2 < 4 {
 					s := op.B2 << 4
@@ -1979,7 +2194,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case 32:
 				b, ok := memoryInst.ReadUint32Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				if op.B2 < 2 {
 					s := op.B2 << 5
@@ -1991,7 +2206,7 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			case 64:
 				b, ok := memoryInst.ReadUint64Le(offset)
 				if !ok {
-					panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+					panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 				}
 				if op.B2 == 0 {
 					lo = b
@@ -2006,10 +2221,10 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 			hi, lo := ce.popValue(), ce.popValue()
 			offset := ce.popMemoryOffset(op)
 			if ok := memoryInst.WriteUint64Le(offset, lo); !ok {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset)))
 			}
 			if ok := memoryInst.WriteUint64Le(offset+8, hi); !ok {
-				panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+				panic(wasmruntime.NewOutOfBoundsMemoryAccessError(uint64(offset + 8)))
 			}
 			frame.pc++
 		case wazeroir.OperationKindV128StoreLane:
@@ -4116,11 +4331,17 @@ func (ce *callEngine) callNativeFuncWithListener(ctx context.Context, m *wasm.Mo
 func (ce *callEngine) popMemoryOffset(op *wazeroir.UnionOperation) uint32 {
 	offset := op.U2 + ce.popValue()
 	if offset > math.MaxUint32 {
-		panic(wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+		panic(wasmruntime.NewOutOfBoundsMemoryAccessError(offset))
 	}
 	return uint32(offset)
 }
 
+// popAtomicOffset pops the address of an atomic operation, and returns its
+// effective address validated by wasm.MemoryInstance AtomicOffset.
+func (ce *callEngine) popAtomicOffset(mem *wasm.MemoryInstance, op *wazeroir.UnionOperation) uint32 {
+	return mem.AtomicOffset(uint32(ce.popValue()), uint32(op.U2), uint32(op.B2))
+}
+
 func (ce *callEngine) callGoFuncWithStack(ctx context.Context, m *wasm.ModuleInstance, f *function) {
 	typ := f.funcType
 	paramLen := typ.ParamNumInUint64
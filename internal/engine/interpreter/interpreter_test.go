@@ -43,6 +43,29 @@ func TestInterpreter_CallEngine_PushFrame(t *testing.T) {
 	require.Equal(t, []*callFrame{f1, f2}, ce.frames)
 }
 
+func TestInterpreter_CallEngine_NewFrame(t *testing.T) {
+	f1, f2 := &function{}, &function{}
+
+	ce := callEngine{stack: []uint64{1, 2}}
+	frame1 := ce.newFrame(f1)
+	require.Equal(t, &callFrame{f: f1, base: 2}, frame1)
+	require.Equal(t, []*callFrame{frame1}, ce.frames)
+
+	// The popped frame must be reused by the subsequent call.
+	frame1.pc = 10
+	ce.popFrame()
+	ce.stack = ce.stack[:1]
+	frame2 := ce.newFrame(f2)
+	require.Same(t, frame1, frame2)
+	require.Equal(t, &callFrame{f: f2, base: 1}, frame2)
+	require.Equal(t, []*callFrame{frame2}, ce.frames)
+
+	// Frames above the reused one must be new.
+	frame3 := ce.newFrame(f1)
+	require.NotSame(t, frame2, frame3)
+	require.Equal(t, []*callFrame{frame2, frame3}, ce.frames)
+}
+
 func TestInterpreter_CallEngine_PushFrame_StackOverflow(t *testing.T) {
 	saved := callStackCeiling
 	defer func() { callStackCeiling = saved }()
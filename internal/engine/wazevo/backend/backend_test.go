@@ -81,9 +81,9 @@ L1 (SSA Block: blk0):
 			name: "consts", m: testcases.Constants.Module,
 			afterLoweringARM64: `
 L1 (SSA Block: blk0):
-	ldr d5?, #8; b 16; data.f64 64.000000
+	ldr d5?, =64.000000
 	mov v1.8b, v5?.8b
-	ldr s4?, #8; b 8; data.f32 32.000000
+	ldr s4?, =32.000000
 	mov v0.8b, v4?.8b
 	orr x3?, xzr, #0x2
 	mov x1, x3?
@@ -94,8 +94,8 @@ L1 (SSA Block: blk0):
 			afterFinalizeARM64: `
 L1 (SSA Block: blk0):
 	str x30, [sp, #-0x10]!
-	ldr d1, #8; b 16; data.f64 64.000000
-	ldr s0, #8; b 8; data.f32 32.000000
+	ldr d1, =64.000000
+	ldr s0, =32.000000
 	orr x1, xzr, #0x2
 	orr w0, wzr, #0x1
 	ldr x30, [sp], #0x10
@@ -447,26 +447,44 @@ L2 (SSA Block: blk1):
 L1 (SSA Block: blk0):
 	mov x2?, x2
 	mov x3?, x3
-	cbnz w2?, L2
-L3 (SSA Block: blk2):
-	mov x4?, x3?
-	b L4
-L2 (SSA Block: blk1):
-	mov x4?, x2?
-L4 (SSA Block: blk3):
+	subs wzr, w2?, wzr
+	csel w4?, w2?, w3?, ne
+L2 (SSA Block: blk3):
 	mov x0, x4?
 	ret
 `,
 			afterFinalizeARM64: `
 L1 (SSA Block: blk0):
 	str x30, [sp, #-0x10]!
-	cbnz w2, #0xc (L2)
-L3 (SSA Block: blk2):
-	mov x0, x3
-	b #0x8 (L4)
-L2 (SSA Block: blk1):
-	mov x0, x2
-L4 (SSA Block: blk3):
+	subs wzr, w2, wzr
+	csel w0, w2, w3, ne
+L2 (SSA Block: blk3):
+	ldr x30, [sp], #0x10
+	ret
+`,
+		},
+		{
+			name: "select_diamond", m: testcases.SelectDiamond.Module,
+			afterLoweringARM64: `
+L1 (SSA Block: blk0):
+	mov x2?, x2
+	mov x3?, x3
+	sub w6?, w3?, #0x1
+	add w8?, w2?, #0x1
+	subs wzr, w2?, w3?
+	csel w9?, w8?, w6?, lt
+L2 (SSA Block: blk3):
+	mov x0, x9?
+	ret
+`,
+			afterFinalizeARM64: `
+L1 (SSA Block: blk0):
+	str x30, [sp, #-0x10]!
+	sub w9, w3, #0x1
+	add w8, w2, #0x1
+	subs wzr, w2, w3
+	csel w0, w8, w9, lt
+L2 (SSA Block: blk3):
 	ldr x30, [sp], #0x10
 	ret
 `,
@@ -1825,45 +1843,45 @@ L1 (SSA Block: blk0):
 	add w60?, w9?, w59?
 	add w61?, w7?, w60?
 	add w62?, w5?, w61?
-	ldr s141?, #8; b 8; data.f32 1.000000
+	ldr s141?, =1.000000
 	fmul s64?, s3?, s141?
-	ldr s140?, #8; b 8; data.f32 2.000000
+	ldr s140?, =2.000000
 	fmul s66?, s3?, s140?
-	ldr s139?, #8; b 8; data.f32 3.000000
+	ldr s139?, =3.000000
 	fmul s68?, s3?, s139?
-	ldr s138?, #8; b 8; data.f32 4.000000
+	ldr s138?, =4.000000
 	fmul s70?, s3?, s138?
-	ldr s137?, #8; b 8; data.f32 5.000000
+	ldr s137?, =5.000000
 	fmul s72?, s3?, s137?
-	ldr s136?, #8; b 8; data.f32 6.000000
+	ldr s136?, =6.000000
 	fmul s74?, s3?, s136?
-	ldr s135?, #8; b 8; data.f32 7.000000
+	ldr s135?, =7.000000
 	fmul s76?, s3?, s135?
-	ldr s134?, #8; b 8; data.f32 8.000000
+	ldr s134?, =8.000000
 	fmul s78?, s3?, s134?
-	ldr s133?, #8; b 8; data.f32 9.000000
+	ldr s133?, =9.000000
 	fmul s80?, s3?, s133?
-	ldr s132?, #8; b 8; data.f32 10.000000
+	ldr s132?, =10.000000
 	fmul s82?, s3?, s132?
-	ldr s131?, #8; b 8; data.f32 11.000000
+	ldr s131?, =11.000000
 	fmul s84?, s3?, s131?
-	ldr s130?, #8; b 8; data.f32 12.000000
+	ldr s130?, =12.000000
 	fmul s86?, s3?, s130?
-	ldr s129?, #8; b 8; data.f32 13.000000
+	ldr s129?, =13.000000
 	fmul s88?, s3?, s129?
-	ldr s128?, #8; b 8; data.f32 14.000000
+	ldr s128?, =14.000000
 	fmul s90?, s3?, s128?
-	ldr s127?, #8; b 8; data.f32 15.000000
+	ldr s127?, =15.000000
 	fmul s92?, s3?, s127?
-	ldr s126?, #8; b 8; data.f32 16.000000
+	ldr s126?, =16.000000
 	fmul s94?, s3?, s126?
-	ldr s125?, #8; b 8; data.f32 17.000000
+	ldr s125?, =17.000000
 	fmul s96?, s3?, s125?
-	ldr s124?, #8; b 8; data.f32 18.000000
+	ldr s124?, =18.000000
 	fmul s98?, s3?, s124?
-	ldr s123?, #8; b 8; data.f32 19.000000
+	ldr s123?, =19.000000
 	fmul s100?, s3?, s123?
-	ldr s122?, #8; b 8; data.f32 20.000000
+	ldr s122?, =20.000000
 	fmul s102?, s3?, s122?
 	fadd s103?, s100?, s102?
 	fadd s104?, s98?, s103?
@@ -1968,45 +1986,45 @@ L1 (SSA Block: blk0):
 	add w10, w10, w11
 	add w9, w9, w10
 	add w0, w8, w9
-	ldr s8, #8; b 8; data.f32 1.000000
+	ldr s8, =1.000000
 	fmul s8, s0, s8
-	ldr s9, #8; b 8; data.f32 2.000000
+	ldr s9, =2.000000
 	fmul s9, s0, s9
-	ldr s10, #8; b 8; data.f32 3.000000
+	ldr s10, =3.000000
 	fmul s10, s0, s10
-	ldr s11, #8; b 8; data.f32 4.000000
+	ldr s11, =4.000000
 	fmul s11, s0, s11
-	ldr s12, #8; b 8; data.f32 5.000000
+	ldr s12, =5.000000
 	fmul s12, s0, s12
-	ldr s13, #8; b 8; data.f32 6.000000
+	ldr s13, =6.000000
 	fmul s13, s0, s13
-	ldr s14, #8; b 8; data.f32 7.000000
+	ldr s14, =7.000000
 	fmul s14, s0, s14
-	ldr s15, #8; b 8; data.f32 8.000000
+	ldr s15, =8.000000
 	fmul s15, s0, s15
-	ldr s16, #8; b 8; data.f32 9.000000
+	ldr s16, =9.000000
 	fmul s16, s0, s16
-	ldr s17, #8; b 8; data.f32 10.000000
+	ldr s17, =10.000000
 	fmul s17, s0, s17
-	ldr s18, #8; b 8; data.f32 11.000000
+	ldr s18, =11.000000
 	fmul s18, s0, s18
-	ldr s19, #8; b 8; data.f32 12.000000
+	ldr s19, =12.000000
 	fmul s19, s0, s19
-	ldr s20, #8; b 8; data.f32 13.000000
+	ldr s20, =13.000000
 	fmul s20, s0, s20
-	ldr s21, #8; b 8; data.f32 14.000000
+	ldr s21, =14.000000
 	fmul s21, s0, s21
-	ldr s22, #8; b 8; data.f32 15.000000
+	ldr s22, =15.000000
 	fmul s22, s0, s22
-	ldr s23, #8; b 8; data.f32 16.000000
+	ldr s23, =16.000000
 	fmul s23, s0, s23
-	ldr s24, #8; b 8; data.f32 17.000000
+	ldr s24, =17.000000
 	fmul s24, s0, s24
-	ldr s25, #8; b 8; data.f32 18.000000
+	ldr s25, =18.000000
 	fmul s25, s0, s25
-	ldr s26, #8; b 8; data.f32 19.000000
+	ldr s26, =19.000000
 	fmul s26, s0, s26
-	ldr s27, #8; b 8; data.f32 20.000000
+	ldr s27, =20.000000
 	fmul s27, s0, s27
 	fadd s26, s26, s27
 	fadd s25, s25, s26
@@ -2166,7 +2184,8 @@ L1 (SSA Block: blk0):
 	add x6?, x4?, #0x4
 	subs xzr, x5?, x6?
 	b.hs L2
-	movz x27, #0x4, lsl 0
+	str x6?, [x0?, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x0?]
 	exit_sequence x0?
 L2:
@@ -2183,8 +2202,9 @@ L1 (SSA Block: blk0):
 	ldr w10, [x1, #0x10]
 	add x9, x8, #0x4
 	subs xzr, x10, x9
-	b.hs #0x24, (L2)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L2)
+	str x9, [x0, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L2:
@@ -2205,8 +2225,9 @@ L1 (SSA Block: blk0):
 	ldr w8, [x1, #0x10]
 	add x9, x10, #0x4
 	subs xzr, x8, x9
-	b.hs #0x24, (L10)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L10)
+	str x9, [x0, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L10:
@@ -2217,8 +2238,9 @@ L10:
 	uxtw x11, w10
 	add x10, x11, #0x8
 	subs xzr, x8, x10
-	b.hs #0x24, (L9)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L9)
+	str x10, [x0, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L9:
@@ -2228,8 +2250,9 @@ L9:
 	uxtw x11, w10
 	add x10, x11, #0x4
 	subs xzr, x8, x10
-	b.hs #0x24, (L8)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L8)
+	str x10, [x0, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L8:
@@ -2239,8 +2262,9 @@ L8:
 	uxtw x11, w10
 	add x10, x11, #0x8
 	subs xzr, x8, x10
-	b.hs #0x24, (L7)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L7)
+	str x10, [x0, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L7:
@@ -2250,8 +2274,9 @@ L7:
 	uxtw x11, w10
 	add x10, x11, #0x1
 	subs xzr, x8, x10
-	b.hs #0x24, (L6)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L6)
+	str x10, [x0, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L6:
@@ -2261,8 +2286,9 @@ L6:
 	uxtw x11, w10
 	add x10, x11, #0x2
 	subs xzr, x8, x10
-	b.hs #0x24, (L5)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L5)
+	str x10, [x0, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L5:
@@ -2272,8 +2298,9 @@ L5:
 	uxtw x11, w10
 	add x10, x11, #0x1
 	subs xzr, x8, x10
-	b.hs #0x24, (L4)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L4)
+	str x10, [x0, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L4:
@@ -2283,8 +2310,9 @@ L4:
 	uxtw x11, w10
 	add x10, x11, #0x2
 	subs xzr, x8, x10
-	b.hs #0x24, (L3)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L3)
+	str x10, [x0, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L3:
@@ -2294,8 +2322,9 @@ L3:
 	uxtw x11, w10
 	add x10, x11, #0x4
 	subs xzr, x8, x10
-	b.hs #0x24, (L2)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L2)
+	str x10, [x0, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x0]
 	exit_sequence x0
 L2:
@@ -2323,8 +2352,9 @@ L1 (SSA Block: blk0):
 	ldr w9, [x1, #0x10]
 	add x10, x11, #0x4
 	subs xzr, x9, x10
-	b.hs #0x24, (L29)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L29)
+	str x10, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L29:
@@ -2334,8 +2364,9 @@ L29:
 	uxtw x12, w2
 	add x11, x12, #0x8
 	subs xzr, x9, x11
-	b.hs #0x24, (L28)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L28)
+	str x11, [x8, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L28:
@@ -2344,8 +2375,9 @@ L28:
 	uxtw x12, w2
 	add x11, x12, #0x4
 	subs xzr, x9, x11
-	b.hs #0x24, (L27)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L27)
+	str x11, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L27:
@@ -2354,8 +2386,9 @@ L27:
 	uxtw x12, w2
 	add x11, x12, #0x8
 	subs xzr, x9, x11
-	b.hs #0x24, (L26)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L26)
+	str x11, [x8, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L26:
@@ -2364,8 +2397,9 @@ L26:
 	uxtw x12, w2
 	add x11, x12, #0x13
 	subs xzr, x9, x11
-	b.hs #0x24, (L25)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L25)
+	str x11, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L25:
@@ -2374,8 +2408,9 @@ L25:
 	uxtw x13, w2
 	add x12, x13, #0x17
 	subs xzr, x9, x12
-	b.hs #0x24, (L24)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L24)
+	str x12, [x8, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L24:
@@ -2384,8 +2419,9 @@ L24:
 	uxtw x13, w2
 	add x12, x13, #0x13
 	subs xzr, x9, x12
-	b.hs #0x24, (L23)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L23)
+	str x12, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L23:
@@ -2394,8 +2430,9 @@ L23:
 	uxtw x13, w2
 	add x12, x13, #0x17
 	subs xzr, x9, x12
-	b.hs #0x24, (L22)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L22)
+	str x12, [x8, #0x868]
+	movz x27, #0x804, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L22:
@@ -2404,8 +2441,9 @@ L22:
 	uxtw x13, w2
 	add x12, x13, #0x1
 	subs xzr, x9, x12
-	b.hs #0x24, (L21)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L21)
+	str x12, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L21:
@@ -2414,8 +2452,9 @@ L21:
 	uxtw x13, w2
 	add x12, x13, #0x10
 	subs xzr, x9, x12
-	b.hs #0x24, (L20)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L20)
+	str x12, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L20:
@@ -2424,8 +2463,9 @@ L20:
 	uxtw x13, w2
 	add x12, x13, #0x1
 	subs xzr, x9, x12
-	b.hs #0x24, (L19)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L19)
+	str x12, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L19:
@@ -2434,8 +2474,9 @@ L19:
 	uxtw x13, w2
 	add x12, x13, #0x10
 	subs xzr, x9, x12
-	b.hs #0x24, (L18)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L18)
+	str x12, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L18:
@@ -2444,8 +2485,9 @@ L18:
 	uxtw x13, w2
 	add x12, x13, #0x2
 	subs xzr, x9, x12
-	b.hs #0x24, (L17)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L17)
+	str x12, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L17:
@@ -2454,8 +2496,9 @@ L17:
 	uxtw x14, w2
 	add x13, x14, #0x11
 	subs xzr, x9, x13
-	b.hs #0x24, (L16)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L16)
+	str x13, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L16:
@@ -2464,8 +2507,9 @@ L16:
 	uxtw x15, w2
 	add x14, x15, #0x2
 	subs xzr, x9, x14
-	b.hs #0x24, (L15)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L15)
+	str x14, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L15:
@@ -2474,8 +2518,9 @@ L15:
 	uxtw x16, w2
 	add x15, x16, #0x11
 	subs xzr, x9, x15
-	b.hs #0x24, (L14)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L14)
+	str x15, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L14:
@@ -2484,8 +2529,9 @@ L14:
 	uxtw x17, w2
 	add x16, x17, #0x1
 	subs xzr, x9, x16
-	b.hs #0x24, (L13)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L13)
+	str x16, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L13:
@@ -2494,8 +2540,9 @@ L13:
 	uxtw x18, w2
 	add x17, x18, #0x10
 	subs xzr, x9, x17
-	b.hs #0x24, (L12)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L12)
+	str x17, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L12:
@@ -2504,8 +2551,9 @@ L12:
 	uxtw x19, w2
 	add x18, x19, #0x1
 	subs xzr, x9, x18
-	b.hs #0x24, (L11)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L11)
+	str x18, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L11:
@@ -2514,8 +2562,9 @@ L11:
 	uxtw x20, w2
 	add x19, x20, #0x10
 	subs xzr, x9, x19
-	b.hs #0x24, (L10)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L10)
+	str x19, [x8, #0x868]
+	movz x27, #0x104, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L10:
@@ -2524,8 +2573,9 @@ L10:
 	uxtw x21, w2
 	add x20, x21, #0x2
 	subs xzr, x9, x20
-	b.hs #0x24, (L9)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L9)
+	str x20, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L9:
@@ -2534,8 +2584,9 @@ L9:
 	uxtw x22, w2
 	add x21, x22, #0x11
 	subs xzr, x9, x21
-	b.hs #0x24, (L8)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L8)
+	str x21, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L8:
@@ -2544,8 +2595,9 @@ L8:
 	uxtw x23, w2
 	add x22, x23, #0x2
 	subs xzr, x9, x22
-	b.hs #0x24, (L7)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L7)
+	str x22, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L7:
@@ -2554,8 +2606,9 @@ L7:
 	uxtw x24, w2
 	add x23, x24, #0x11
 	subs xzr, x9, x23
-	b.hs #0x24, (L6)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L6)
+	str x23, [x8, #0x868]
+	movz x27, #0x204, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L6:
@@ -2564,8 +2617,9 @@ L6:
 	uxtw x25, w2
 	add x24, x25, #0x4
 	subs xzr, x9, x24
-	b.hs #0x24, (L5)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L5)
+	str x24, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L5:
@@ -2574,8 +2628,9 @@ L5:
 	uxtw x26, w2
 	add x25, x26, #0x13
 	subs xzr, x9, x25
-	b.hs #0x24, (L4)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L4)
+	str x25, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L4:
@@ -2584,8 +2639,9 @@ L4:
 	uxtw x29, w2
 	add x26, x29, #0x4
 	subs xzr, x9, x26
-	b.hs #0x24, (L3)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L3)
+	str x26, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L3:
@@ -2594,8 +2650,9 @@ L3:
 	uxtw x30, w2
 	add x29, x30, #0x13
 	subs xzr, x9, x29
-	b.hs #0x24, (L2)
-	movz x27, #0x4, lsl 0
+	b.hs #0x28, (L2)
+	str x29, [x8, #0x868]
+	movz x27, #0x404, lsl 0
 	str w27, [x8]
 	exit_sequence x8
 L2:
@@ -2722,10 +2779,10 @@ L1 (SSA Block: blk0):
 	orr x12?, xzr, #0x2
 	str x12?, [x5?, #0x8]
 	ldr x7?, [x1?, #0x18]
-	ldr s11?, #8; b 8; data.f32 3.000000
+	ldr s11?, =3.000000
 	str s11?, [x7?, #0x8]
 	ldr x9?, [x1?, #0x20]
-	ldr d10?, #8; b 16; data.f64 4.000000
+	ldr d10?, =4.000000
 	str d10?, [x9?, #0x8]
 	ret
 `,
@@ -2739,10 +2796,10 @@ L1 (SSA Block: blk0):
 	orr x8, xzr, #0x2
 	str x8, [x9, #0x8]
 	ldr x8, [x1, #0x18]
-	ldr s8, #8; b 8; data.f32 3.000000
+	ldr s8, =3.000000
 	str s8, [x8, #0x8]
 	ldr x8, [x1, #0x20]
-	ldr d8, #8; b 16; data.f64 4.000000
+	ldr d8, =4.000000
 	str d8, [x8, #0x8]
 	ldr x30, [sp], #0x10
 	ret
@@ -2837,7 +2894,7 @@ L9 (SSA Block: blk6):
 		t.Run(tc.name, func(t *testing.T) {
 			ssab := ssa.NewBuilder()
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
-			fc := frontend.NewFrontendCompiler(tc.m, ssab, &offset)
+			fc := frontend.NewFrontendCompiler(tc.m, ssab, &offset, false)
 			machine := newMachine()
 			machine.DisableStackCheck()
 			be := backend.NewCompiler(context.Background(), machine, ssab)
@@ -187,6 +187,7 @@ func (c *compiler) RegAlloc() {
 
 // Finalize implements Compiler.Finalize.
 func (c *compiler) Finalize() {
+	c.mach.PostRegAlloc()
 	c.mach.SetupPrologue()
 	c.mach.SetupEpilogue()
 	c.mach.ResolveRelativeAddresses()
@@ -270,6 +270,6 @@ func TestAbiImpl_callerGenVRegToFunctionArg_constant_inlining(t *testing.T) {
 	abi.callerGenVRegToFunctionArg(1, regalloc.VReg(50).SetRegType(regalloc.RegTypeFloat), &backend.SSAValueDefinition{Instr: f64, RefCount: 1})
 	require.Equal(t, `movz x100?, #0xa, lsl 0
 mov x0, x100?
-ldr d50?, #8; b 16; data.f64 3.140000
+ldr d50?, =3.140000
 mov v0.8b, v50?.8b`, formatEmittedInstructionsInCurrentBlock(m))
 }
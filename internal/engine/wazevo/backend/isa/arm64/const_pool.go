@@ -0,0 +1,226 @@
+package arm64
+
+import (
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/backend"
+	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
+)
+
+// constPoolEntry is a literal in the constant pool, which is emitted right after the function body,
+// and loaded by PC-relative "ldr (literal)" instructions.
+//
+// Identical literals are deduplicated, so that a constant used many times in a function
+// is only emitted once, instead of being materialized by a long sequence of movz/movk each time.
+type constPoolEntry struct {
+	lo, hi uint64
+	// size is the size of the literal in bytes: 4, 8 or 16.
+	size int64
+}
+
+// maxConstPoolOffset is the maximum distance in bytes between a "ldr (literal)" and its literal.
+const maxConstPoolOffset = maxSignedInt19 * 4
+
+// constPoolRangeMargin is subtracted from maxConstPoolOffset when checking if the constant pool is in range,
+// which accounts for the alignment of the pool and the Go entry preamble emitted before the function body.
+const constPoolRangeMargin = 64 * 1024
+
+// addToConstPool assigns the literal loaded by the instruction an entry in the constant pool.
+// The instruction must be one of loadFpuConst32, loadFpuConst64, loadFpuConst128 or loadIntConst64.
+func (m *machine) addToConstPool(i *instruction) {
+	if !i.usesConstPool() {
+		return
+	}
+
+	e := i.constPoolEntry()
+	index, ok := m.constPoolIndexes[e]
+	if !ok {
+		index = len(m.constPool)
+		m.constPool = append(m.constPool, e)
+		m.constPoolIndexes[e] = index
+	}
+	i.u3 = uint64(index)
+}
+
+// constPoolEntry returns the literal loaded by the instruction, which must use the constant pool.
+func (i *instruction) constPoolEntry() constPoolEntry {
+	e := constPoolEntry{lo: i.u1}
+	switch i.kind {
+	case loadFpuConst32:
+		e.size = 4
+	case loadFpuConst64, loadIntConst64:
+		e.size = 8
+	case loadFpuConst128:
+		e.hi, e.size = i.u2, 16
+	}
+	return e
+}
+
+// usesConstPool returns true if the instruction loads its literal from the constant pool.
+// Zero floating-point constants are not, as they are materialized by a single instruction.
+func (i *instruction) usesConstPool() bool {
+	switch i.kind {
+	case loadFpuConst32, loadFpuConst64:
+		return i.u1 != 0
+	case loadFpuConst128:
+		return i.u1 != 0 || i.u2 != 0
+	case loadIntConst64:
+		return true
+	default:
+		return false
+	}
+}
+
+// constPoolOffsetResolve is called when the offset from the instruction to its literal is resolved.
+func (i *instruction) constPoolOffsetResolve(offset int64) {
+	i.rn.data = uint64(offset)
+	i.rn.data2 = 1 // indicate that the offset is resolved.
+}
+
+func (i *instruction) constPoolOffset() int64 {
+	if i.rn.data2 != 1 {
+		panic("BUG: the offset to the constant pool is not resolved")
+	}
+	return int64(i.rn.data)
+}
+
+// constPoolInline makes the instruction inline its literal instead of loading it from the constant pool.
+func (i *instruction) constPoolInline() {
+	i.rn.data2 = 2
+}
+
+// constPoolInlined returns true if the instruction inlines its literal. See constPoolInline.
+func (i *instruction) constPoolInlined() bool {
+	return i.usesConstPool() && i.rn.data2 == 2
+}
+
+// inlineConstPoolIfOutOfRange makes all the instructions inline their literals when the constant pool emitted
+// after the function body might be out of the range of "ldr (literal)", i.e. for functions larger than about 1MiB.
+//
+// This must be called before the sizes of instructions are used to resolve relative addresses, as inlining
+// makes these instructions larger.
+func (m *machine) inlineConstPoolIfOutOfRange() {
+	if len(m.constPool) == 0 {
+		return
+	}
+	var size int64
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		size += cur.size()
+	}
+	for _, e := range m.constPool {
+		size += e.size
+	}
+	if size <= maxConstPoolOffset-constPoolRangeMargin {
+		return
+	}
+
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		if cur.usesConstPool() {
+			cur.constPoolInline()
+		}
+	}
+	for _, e := range m.constPool {
+		delete(m.constPoolIndexes, e)
+	}
+	m.constPool = m.constPool[:0]
+}
+
+// encodeLoadConst encodes the load of the instruction's literal either from the constant pool, or inlined as
+//
+//	ldr rd, #8   ;; literal load of the data
+//	b (4+size)   ;; skip the data
+//	data
+//
+// where opc and vec are as documented on encodeLoadLiteral.
+func (i *instruction) encodeLoadConst(c backend.Compiler, opc uint32, vec bool, rd uint32) {
+	if !i.constPoolInlined() {
+		c.Emit4Bytes(encodeLoadLiteral(opc, vec, rd, i.constPoolOffset()))
+		return
+	}
+	e := i.constPoolEntry()
+	c.Emit4Bytes(encodeLoadLiteral(opc, vec, rd, 8))
+	c.Emit4Bytes(encodeUnconditionalBranch(false, 4+e.size))
+	encodeConstPoolEntry(c, e)
+}
+
+// encodeWithConstPool encodes the current function followed by its constant pool.
+//
+// Unlike branches, the offsets to literals are resolved here rather than in ResolveRelativeAddresses, as the
+// pool is aligned relative to the beginning of the buffer, which might contain the Go entry preamble.
+func (m *machine) encodeWithConstPool() {
+	c := m.compiler
+	var bodySize int64
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		bodySize += cur.size()
+	}
+
+	// Larger literals are placed first so that each literal is aligned to its size.
+	poolBegin := (int64(len(c.Buf())) + bodySize + 15) &^ 15
+	offset := poolBegin
+	m.constPoolOffsets = m.constPoolOffsets[:0]
+	for range m.constPool {
+		m.constPoolOffsets = append(m.constPoolOffsets, 0)
+	}
+	for _, size := range [...]int64{16, 8, 4} {
+		for index, e := range m.constPool {
+			if e.size == size {
+				m.constPoolOffsets[index] = offset
+				offset += size
+			}
+		}
+	}
+
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		if cur.usesConstPool() {
+			diff := m.constPoolOffsets[cur.u3] - int64(len(c.Buf()))
+			if diff > maxConstPoolOffset {
+				panic("BUG: the constant pool must be inlined when out of range")
+			}
+			cur.constPoolOffsetResolve(diff)
+		}
+		cur.encode(c)
+	}
+
+	// The padding is never executed, as the function body ends with a return or a branch.
+	for int64(len(c.Buf())) < poolBegin {
+		if wazevoapi.PrintMachineCodeHexPerFunctionDisassemblable {
+			c.Emit4Bytes(dummyInstruction)
+		} else {
+			c.Emit4Bytes(0) // udf
+		}
+	}
+	for _, size := range [...]int64{16, 8, 4} {
+		for _, e := range m.constPool {
+			if e.size == size {
+				encodeConstPoolEntry(c, e)
+			}
+		}
+	}
+}
+
+func encodeConstPoolEntry(c backend.Compiler, e constPoolEntry) {
+	for i := int64(0); i < e.size; i += 4 {
+		if wazevoapi.PrintMachineCodeHexPerFunctionDisassemblable {
+			// Literals cannot be disassembled, so we add dummy instructions here.
+			c.Emit4Bytes(dummyInstruction)
+			continue
+		}
+		v := e.lo
+		if i >= 8 {
+			v = e.hi
+		}
+		c.Emit4Bytes(uint32(v >> ((i % 8) * 8)))
+	}
+}
+
+// encodeLoadLiteral encodes a "ldr (literal)" instruction which loads the literal at `offset` bytes from it.
+// opc is 0b00, 0b01, 0b10 for 32, 64, 128-bit loads into a vector register if `vec` is true,
+// and 0b01 for 64-bit loads into a general purpose register otherwise.
+//
+// See https://developer.arm.com/documentation/ddi0596/2020-12/Base-Instructions/LDR--literal---Load-Register--literal--?lang=en
+// and https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/LDR--literal--SIMD-FP---Load-SIMD-FP-Register--PC-relative-literal--?lang=en
+func encodeLoadLiteral(opc uint32, vec bool, rt uint32, offset int64) uint32 {
+	ret := opc<<30 | 0b011<<27 | uint32(offset>>2)&0x7ffff<<5 | rt
+	if vec {
+		ret |= 1 << 26
+	}
+	return ret
+}
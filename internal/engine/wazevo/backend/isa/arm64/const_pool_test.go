@@ -0,0 +1,114 @@
+package arm64
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMachine_addToConstPool(t *testing.T) {
+	_, _, m := newSetupWithMockContext()
+
+	i64 := m.allocateInstr()
+	i64.asLoadIntConst64(x1VReg, 0x1234_5678_9abc_def0)
+	m.addToConstPool(i64)
+	f64 := m.allocateInstr()
+	f64.asLoadFpuConst64(v0VReg, 0x1234_5678_9abc_def0)
+	m.addToConstPool(f64)
+	f32 := m.allocateInstr()
+	f32.asLoadFpuConst32(v1VReg, uint64(math.Float32bits(1.0)))
+	m.addToConstPool(f32)
+	zero := m.allocateInstr()
+	zero.asLoadFpuConst64(v1VReg, 0)
+	m.addToConstPool(zero)
+
+	// The same 64-bit literal is shared regardless of the type, and zero doesn't need any.
+	require.Equal(t, []constPoolEntry{
+		{lo: 0x1234_5678_9abc_def0, size: 8},
+		{lo: uint64(math.Float32bits(1.0)), size: 4},
+	}, m.constPool)
+	require.Equal(t, uint64(0), i64.u3)
+	require.Equal(t, uint64(0), f64.u3)
+	require.Equal(t, uint64(1), f32.u3)
+	require.False(t, zero.usesConstPool())
+
+	m.Reset()
+	require.Equal(t, 0, len(m.constPool))
+	require.Equal(t, 0, len(m.constPoolIndexes))
+}
+
+func TestMachine_encodeWithConstPool(t *testing.T) {
+	ctx, _, m := newSetupWithMockContext()
+
+	f32 := m.allocateInstr()
+	f32.asLoadFpuConst32(v1VReg, uint64(math.Float32bits(1.0)))
+	m.addToConstPool(f32)
+	i64 := m.allocateInstr()
+	i64.asLoadIntConst64(x1VReg, 0x1234_5678_9abc_def0)
+	m.addToConstPool(i64)
+	f64 := m.allocateInstr()
+	f64.asLoadFpuConst64(v0VReg, 0x1234_5678_9abc_def0)
+	m.addToConstPool(f64)
+	ret := m.allocateInstr()
+	ret.asRet(nil)
+
+	m.rootInstr = f32
+	linkInstr(linkInstr(linkInstr(f32, i64), f64), ret)
+	m.Encode()
+
+	require.Equal(t, ""+
+		"c100001c"+ // ldr s1, #24
+		"61000058"+ // ldr x1, #12
+		"4000005c"+ // ldr d0, #8
+		"c0035fd6"+ // ret
+		"f0debc9a78563412"+ // 0x1234_5678_9abc_def0
+		"0000803f", // 1.0
+		hex.EncodeToString(ctx.buf))
+}
+
+func TestMachine_inlineConstPoolIfOutOfRange(t *testing.T) {
+	ctx, _, m := newSetupWithMockContext()
+
+	f32 := m.allocateInstr()
+	f32.asLoadFpuConst32(v1VReg, uint64(math.Float32bits(1.0)))
+	m.addToConstPool(f32)
+	i64 := m.allocateInstr()
+	i64.asLoadIntConst64(x1VReg, 0x1234_5678_9abc_def0)
+	m.addToConstPool(i64)
+	ret := m.allocateInstr()
+	ret.asRet(nil)
+	m.rootInstr = f32
+	linkInstr(linkInstr(f32, i64), ret)
+
+	// Within range, the constant pool is kept.
+	m.inlineConstPoolIfOutOfRange()
+	require.Equal(t, 2, len(m.constPool))
+	require.False(t, f32.constPoolInlined())
+
+	// Pad the function so that the pool after the body is out of the range of "ldr (literal)".
+	cur := ret
+	for i := int64(0); i < maxConstPoolOffset/4; i++ {
+		cur = linkInstr(cur, m.allocateInstr().asUDF())
+	}
+	m.inlineConstPoolIfOutOfRange()
+	require.Equal(t, 0, len(m.constPool))
+	require.Equal(t, 0, len(m.constPoolIndexes))
+	require.True(t, f32.constPoolInlined())
+	require.True(t, i64.constPoolInlined())
+	require.Equal(t, int64(12), f32.size())
+	require.Equal(t, int64(16), i64.size())
+
+	ret.next = nil
+	m.Encode()
+	require.Equal(t, ""+
+		"4100001c"+ // ldr s1, #8
+		"02000014"+ // b 8
+		"0000803f"+ // 1.0
+		"41000058"+ // ldr x1, #8
+		"03000014"+ // b 12
+		"f0debc9a78563412"+ // 0x1234_5678_9abc_def0
+		"c0035fd6", // ret
+		hex.EncodeToString(ctx.buf))
+}
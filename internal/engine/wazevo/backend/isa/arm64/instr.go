@@ -90,6 +90,7 @@ var defKinds = [numInstructionKinds]defKind{
 	loadFpuConst32:  defKindRD,
 	loadFpuConst64:  defKindRD,
 	loadFpuConst128: defKindRD,
+	loadIntConst64:  defKindRD,
 	fpuStore32:      defKindNone,
 	fpuStore64:      defKindNone,
 	fpuStore128:     defKindNone,
@@ -200,6 +201,7 @@ var useKinds = [numInstructionKinds]useKind{
 	loadFpuConst32:  useKindNone,
 	loadFpuConst64:  useKindNone,
 	loadFpuConst128: useKindNone,
+	loadIntConst64:  useKindNone,
 	cSel:            useKindRNRM,
 	fpuCSel:         useKindRNRM,
 	movToVec:        useKindRN,
@@ -616,6 +618,12 @@ func (i *instruction) asLoadFpuConst128(rd regalloc.VReg, lo, hi uint64) {
 	i.rd = operandNR(rd)
 }
 
+func (i *instruction) asLoadIntConst64(rd regalloc.VReg, raw uint64) {
+	i.kind = loadIntConst64
+	i.u1 = raw
+	i.rd = operandNR(rd)
+}
+
 func (i *instruction) asFpuCmp(rn, rm operand, is64bit bool) {
 	i.kind = fpuCmp
 	i.rn, i.rm = rn, rm
@@ -994,12 +1002,13 @@ func (i *instruction) String() (str string) {
 	case fpuStore128:
 		str = fmt.Sprintf("str %s, %s", formatVRegSized(i.rn.nr(), 128), i.amode.format(64))
 	case loadFpuConst32:
-		str = fmt.Sprintf("ldr %s, #8; b 8; data.f32 %f", formatVRegSized(i.rd.nr(), 32), math.Float32frombits(uint32(i.u1)))
+		str = fmt.Sprintf("ldr %s, =%f", formatVRegSized(i.rd.nr(), 32), math.Float32frombits(uint32(i.u1)))
 	case loadFpuConst64:
-		str = fmt.Sprintf("ldr %s, #8; b 16; data.f64 %f", formatVRegSized(i.rd.nr(), 64), math.Float64frombits(i.u1))
+		str = fmt.Sprintf("ldr %s, =%f", formatVRegSized(i.rd.nr(), 64), math.Float64frombits(i.u1))
 	case loadFpuConst128:
-		str = fmt.Sprintf("ldr %s, #8; b 32; data.v128  %016x %016x",
-			formatVRegSized(i.rd.nr(), 128), i.u1, i.u2)
+		str = fmt.Sprintf("ldr %s, =0x%016x%016x", formatVRegSized(i.rd.nr(), 128), i.u2, i.u1)
+	case loadIntConst64:
+		str = fmt.Sprintf("ldr %s, =%#x", formatVRegSized(i.rd.nr(), 64), i.u1)
 	case fpuToInt:
 		var op, src, dst string
 		if signed := i.u1 == 1; signed {
@@ -1305,6 +1314,8 @@ const (
 	loadFpuConst64
 	// loadFpuConst128 represents a load of a 128-bit floating-point constant.
 	loadFpuConst128
+	// loadIntConst64 represents a load of a 64-bit integer constant from the constant pool.
+	loadIntConst64
 	// fpuToInt represents a conversion from FP to integer.
 	fpuToInt
 	// intToFpu represents a conversion from integer to FP.
@@ -1777,23 +1788,13 @@ func (i *instruction) size() int64 {
 		return exitSequenceSize // 5 instructions as in encodeExitSequence.
 	case nop0:
 		return 0
-	case loadFpuConst32:
-		if i.u1 == 0 {
-			return 4 // zero loading can be encoded as a single instruction.
-		}
-		return 4 + 4 + 4
-	case loadFpuConst64:
-		if i.u1 == 0 {
-			return 4 // zero loading can be encoded as a single instruction.
-		}
-		return 4 + 4 + 8
-	case loadFpuConst128:
-		if i.u1 == 0 && i.u2 == 0 {
-			return 4 // zero loading can be encoded as a single instruction.
-		}
-		return 4 + 4 + 16
 	case brTableSequence:
 		return 4*4 + int64(len(i.targets))*4
+	case loadFpuConst32, loadFpuConst64, loadFpuConst128, loadIntConst64:
+		if i.constPoolInlined() {
+			return 4 + 4 + i.constPoolEntry().size
+		}
+		return 4
 	default:
 		return 4
 	}
@@ -10,6 +10,10 @@ import (
 
 // Encode implements backend.Machine Encode.
 func (m *machine) Encode() {
+	if len(m.constPool) > 0 {
+		m.encodeWithConstPool()
+		return
+	}
 	m.encode(m.rootInstr)
 }
 
@@ -100,23 +104,24 @@ func (i *instruction) encode(c backend.Compiler) {
 		if i.u1 == 0 {
 			c.Emit4Bytes(encodeVecRRR(vecOpEOR, rd, rd, rd, vecArrangement8B))
 		} else {
-			encodeLoadFpuConst32(c, rd, i.u1)
+			i.encodeLoadConst(c, 0b00, true, rd)
 		}
 	case loadFpuConst64:
 		rd := regNumberInEncoding[i.rd.realReg()]
 		if i.u1 == 0 {
 			c.Emit4Bytes(encodeVecRRR(vecOpEOR, rd, rd, rd, vecArrangement8B))
 		} else {
-			encodeLoadFpuConst64(c, regNumberInEncoding[i.rd.realReg()], i.u1)
+			i.encodeLoadConst(c, 0b01, true, rd)
 		}
 	case loadFpuConst128:
 		rd := regNumberInEncoding[i.rd.realReg()]
-		lo, hi := i.u1, i.u2
-		if lo == 0 && hi == 0 {
+		if i.u1 == 0 && i.u2 == 0 {
 			c.Emit4Bytes(encodeVecRRR(vecOpEOR, rd, rd, rd, vecArrangement16B))
 		} else {
-			encodeLoadFpuConst128(c, rd, lo, hi)
+			i.encodeLoadConst(c, 0b10, true, rd)
 		}
+	case loadIntConst64:
+		i.encodeLoadConst(c, 0b01, false, regNumberInEncoding[i.rd.realReg()])
 	case aluRRRR:
 		c.Emit4Bytes(encodeAluRRRR(
 			aluOp(i.u1),
@@ -609,73 +614,6 @@ func encodeConditionalSelect(kind instructionKind, rd, rn, rm uint32, c condFlag
 
 const dummyInstruction uint32 = 0x14000000 // "b 0"
 
-// encodeLoadFpuConst32 encodes the following three instructions:
-//
-//	ldr s8, #8  ;; literal load of data.f32
-//	b 8           ;; skip the data
-//	data.f32 xxxxxxx
-func encodeLoadFpuConst32(c backend.Compiler, rd uint32, rawF32 uint64) {
-	c.Emit4Bytes(
-		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/LDR--literal--SIMD-FP---Load-SIMD-FP-Register--PC-relative-literal--?lang=en
-		0b111<<26 | (0x8/4)<<5 | rd,
-	)
-	c.Emit4Bytes(encodeUnconditionalBranch(false, 8)) // b 8
-	if wazevoapi.PrintMachineCodeHexPerFunctionDisassemblable {
-		// Inlined data.f32 cannot be disassembled, so we add a dummy instruction here.
-		c.Emit4Bytes(dummyInstruction)
-	} else {
-		c.Emit4Bytes(uint32(rawF32)) // data.f32 xxxxxxx
-	}
-}
-
-// encodeLoadFpuConst64 encodes the following three instructions:
-//
-//	ldr d8, #8  ;; literal load of data.f64
-//	b 12           ;; skip the data
-//	data.f64 xxxxxxx
-func encodeLoadFpuConst64(c backend.Compiler, rd uint32, rawF64 uint64) {
-	c.Emit4Bytes(
-		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/LDR--literal--SIMD-FP---Load-SIMD-FP-Register--PC-relative-literal--?lang=en
-		0b1<<30 | 0b111<<26 | (0x8/4)<<5 | rd,
-	)
-	c.Emit4Bytes(encodeUnconditionalBranch(false, 12)) // b 12
-	if wazevoapi.PrintMachineCodeHexPerFunctionDisassemblable {
-		// Inlined data.f64 cannot be disassembled, so we add dummy instructions here.
-		c.Emit4Bytes(dummyInstruction)
-		c.Emit4Bytes(dummyInstruction)
-	} else {
-		// data.f64 xxxxxxx
-		c.Emit4Bytes(uint32(rawF64))
-		c.Emit4Bytes(uint32(rawF64 >> 32))
-	}
-}
-
-// encodeLoadFpuConst128 encodes the following three instructions:
-//
-//	ldr v8, #8  ;; literal load of data.f64
-//	b 20           ;; skip the data
-//	data.v128 xxxxxxx
-func encodeLoadFpuConst128(c backend.Compiler, rd uint32, lo, hi uint64) {
-	c.Emit4Bytes(
-		// https://developer.arm.com/documentation/ddi0596/2020-12/SIMD-FP-Instructions/LDR--literal--SIMD-FP---Load-SIMD-FP-Register--PC-relative-literal--?lang=en
-		0b1<<31 | 0b111<<26 | (0x8/4)<<5 | rd,
-	)
-	c.Emit4Bytes(encodeUnconditionalBranch(false, 20)) // b 20
-	if wazevoapi.PrintMachineCodeHexPerFunctionDisassemblable {
-		// Inlined data.v128 cannot be disassembled, so we add dummy instructions here.
-		c.Emit4Bytes(dummyInstruction)
-		c.Emit4Bytes(dummyInstruction)
-		c.Emit4Bytes(dummyInstruction)
-		c.Emit4Bytes(dummyInstruction)
-	} else {
-		// data.v128 xxxxxxx
-		c.Emit4Bytes(uint32(lo))
-		c.Emit4Bytes(uint32(lo >> 32))
-		c.Emit4Bytes(uint32(hi))
-		c.Emit4Bytes(uint32(hi >> 32))
-	}
-}
-
 // encodeAluRRRR encodes as Data-processing (3 source) in
 // https://developer.arm.com/documentation/ddi0596/2020-12/Index-by-Encoding/Data-Processing----Register?lang=en
 func encodeAluRRRR(op aluOp, rd, rn, rm, ra, _64bit uint32) uint32 {
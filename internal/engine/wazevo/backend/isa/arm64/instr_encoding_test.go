@@ -188,15 +188,28 @@ func TestInstruction_encode(t *testing.T) {
 		{want: "30000010", setup: func(i *instruction) { i.asAdr(v16VReg, 4) }},
 		{want: "50050030", setup: func(i *instruction) { i.asAdr(v16VReg, 169) }},
 		{want: "101e302e", setup: func(i *instruction) { i.asLoadFpuConst32(v16VReg, uint64(math.Float32bits(0))) }},
-		{want: "5000001c020000140000803f", setup: func(i *instruction) {
+		{want: "5000001c", setup: func(i *instruction) {
 			i.asLoadFpuConst32(v16VReg, uint64(math.Float32bits(1.0)))
+			i.constPoolOffsetResolve(8)
 		}},
 		{want: "101e302e", setup: func(i *instruction) { i.asLoadFpuConst64(v16VReg, uint64(math.Float32bits(0))) }},
-		{want: "5000005c03000014000000000000f03f", setup: func(i *instruction) {
+		{want: "5000005c", setup: func(i *instruction) {
 			i.asLoadFpuConst64(v16VReg, math.Float64bits(1.0))
+			i.constPoolOffsetResolve(8)
 		}},
 		{want: "101e306e", setup: func(i *instruction) { i.asLoadFpuConst128(v16VReg, 0, 0) }},
-		{want: "5000009c05000014ffffffffffffffffaaaaaaaaaaaaaaaa", setup: func(i *instruction) { i.asLoadFpuConst128(v16VReg, 0xffffffff_ffffffff, 0xaaaaaaaa_aaaaaaaa) }},
+		{want: "5000009c", setup: func(i *instruction) {
+			i.asLoadFpuConst128(v16VReg, 0xffffffff_ffffffff, 0xaaaaaaaa_aaaaaaaa)
+			i.constPoolOffsetResolve(8)
+		}},
+		{want: "41000058", setup: func(i *instruction) {
+			i.asLoadIntConst64(x1VReg, 0x1234_5678_9abc_def0)
+			i.constPoolOffsetResolve(8)
+		}},
+		{want: "e1ffff58", setup: func(i *instruction) {
+			i.asLoadIntConst64(x1VReg, 0x1234_5678_9abc_def0)
+			i.constPoolOffsetResolve(-4)
+		}},
 		{want: "8220061b", setup: func(i *instruction) {
 			i.asALURRRR(aluOpMAdd, operandNR(x2VReg), operandNR(x4VReg), operandNR(x6VReg), operandNR(x8VReg), false)
 		}},
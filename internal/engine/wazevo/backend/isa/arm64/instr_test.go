@@ -51,7 +51,7 @@ func TestInstruction_String(t *testing.T) {
 				u1:   uint64(math.Float32bits(3.0)),
 				rd:   operandNR(regalloc.VReg(0).SetRegType(regalloc.RegTypeFloat)),
 			},
-			exp: "ldr s0?, #8; b 8; data.f32 3.000000",
+			exp: "ldr s0?, =3.000000",
 		},
 		{
 			i: &instruction{
@@ -59,7 +59,7 @@ func TestInstruction_String(t *testing.T) {
 				u1:   math.Float64bits(12345.987491),
 				rd:   operandNR(regalloc.VReg(0).SetRegType(regalloc.RegTypeFloat)),
 			},
-			exp: "ldr d0?, #8; b 16; data.f64 12345.987491",
+			exp: "ldr d0?, =12345.987491",
 		},
 		{exp: "nop0", i: &instruction{kind: nop0}},
 		{exp: "b L0", i: &instruction{kind: br, u1: uint64(label(0))}},
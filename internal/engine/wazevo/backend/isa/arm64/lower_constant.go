@@ -29,10 +29,12 @@ func (m *machine) InsertLoadConstant(instr *ssa.Instruction, vr regalloc.VReg) {
 	case ssa.TypeF32:
 		loadF := m.allocateInstr()
 		loadF.asLoadFpuConst32(vr, v)
+		m.addToConstPool(loadF)
 		m.insert(loadF)
 	case ssa.TypeF64:
 		loadF := m.allocateInstr()
 		loadF.asLoadFpuConst64(vr, v)
+		m.addToConstPool(loadF)
 		m.insert(loadF)
 	case ssa.TypeI32:
 		if v == 0 {
@@ -43,8 +45,16 @@ func (m *machine) InsertLoadConstant(instr *ssa.Instruction, vr regalloc.VReg) {
 	case ssa.TypeI64:
 		if v == 0 {
 			m.InsertMove(vr, xzrVReg, ssa.TypeI64)
+		} else if c := int64(v); const16bitAligned(c) < 0 && const16bitAligned(^c) < 0 && !isBitMaskImmediate(v) &&
+			load64bitConstInstructions(v) > 2 {
+			// Long sequences of movz/movk are replaced by a single load from the constant pool, where
+			// the same constant is shared by all the loads in the function.
+			load := m.allocateInstr()
+			load.asLoadIntConst64(vr, v)
+			m.addToConstPool(load)
+			m.insert(load)
 		} else {
-			m.lowerConstantI64(vr, int64(v))
+			m.lowerConstantI64(vr, c)
 		}
 	default:
 		panic("TODO")
@@ -271,6 +281,28 @@ func (m *machine) load64bitConst(c int64, dst regalloc.VReg) {
 	}
 }
 
+// load64bitConstInstructions returns the number of instructions emitted by load64bitConst for the constant.
+func load64bitConstInstructions(c uint64) int {
+	var zeros, negs int
+	for i := 0; i < 4; i++ {
+		if v := c >> uint(i*16) & 0xffff; v == 0 {
+			zeros++
+		} else if v == 0xffff {
+			negs++
+		}
+	}
+	switch {
+	case zeros == 3, negs == 3:
+		return 1
+	case zeros == 2, negs == 2:
+		return 2
+	case zeros == 1, negs == 1:
+		return 3
+	default:
+		return 4
+	}
+}
+
 func (m *machine) insertMOVZ(dst regalloc.VReg, v uint64, shift int, dst64 bool) {
 	instr := m.allocateInstr()
 	instr.asMOVZ(dst, v, uint64(shift), dst64)
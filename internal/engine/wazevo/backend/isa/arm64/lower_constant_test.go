@@ -52,7 +52,7 @@ func TestMachine_lowerConstant(t *testing.T) {
 		require.Equal(t, loadFpuConst32, machInstr.kind)
 		require.Equal(t, uint64(math.Float32bits(1.1234)), machInstr.u1)
 
-		require.Equal(t, "ldr s0?, #8; b 8; data.f32 1.123400", formatEmittedInstructionsInCurrentBlock(m))
+		require.Equal(t, "ldr s0?, =1.123400", formatEmittedInstructionsInCurrentBlock(m))
 	})
 
 	t.Run("TypeF64", func(t *testing.T) {
@@ -68,7 +68,20 @@ func TestMachine_lowerConstant(t *testing.T) {
 		require.Equal(t, loadFpuConst64, machInstr.kind)
 		require.Equal(t, math.Float64bits(-9471.2), machInstr.u1)
 
-		require.Equal(t, "ldr d0?, #8; b 16; data.f64 -9471.200000", formatEmittedInstructionsInCurrentBlock(m))
+		require.Equal(t, "ldr d0?, =-9471.200000", formatEmittedInstructionsInCurrentBlock(m))
+	})
+
+	t.Run("TypeI64 constant pool", func(t *testing.T) {
+		ssaB, m := newSetup()
+		for i := 0; i < 2; i++ {
+			ssaConstInstr := ssaB.AllocateInstruction()
+			ssaConstInstr.AsIconst64(0x1234_5678_9abc_def0)
+			ssaB.InsertInstruction(ssaConstInstr)
+			m.lowerConstant(ssaConstInstr)
+		}
+
+		require.Equal(t, "ldr x0?, =0x123456789abcdef0\nldr x1?, =0x123456789abcdef0", formatEmittedInstructionsInCurrentBlock(m))
+		require.Equal(t, []constPoolEntry{{lo: 0x1234_5678_9abc_def0, size: 8}}, m.constPool)
 	})
 }
 
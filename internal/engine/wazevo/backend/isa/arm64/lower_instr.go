@@ -296,6 +296,7 @@ func (m *machine) LowerInstr(instr *ssa.Instruction) {
 		lo, hi := instr.VconstData()
 		v := m.allocateInstr()
 		v.asLoadFpuConst128(result, lo, hi)
+		m.addToConstPool(v)
 		m.insert(v)
 	case ssa.OpcodeVIadd:
 		x, y, lane := instr.Arg2WithLane()
@@ -826,6 +827,17 @@ func (m *machine) lowerExitIfTrueWithCode(execCtxVReg regalloc.VReg, cond ssa.Va
 	// We have to skip the entire exit sequence if the condition is false.
 	cbr := m.allocateInstr()
 	m.insert(cbr)
+	if code&wazevoapi.ExitCodeMask == wazevoapi.ExitCodeMemoryOutOfBounds {
+		// The condition is `memLen < baseAddrPlusCeil`: save the latter to report the address in the error.
+		ceil := m.getOperand_NR(m.compiler.ValueDefinition(y), extModeNone)
+		saveCeil := m.allocateInstr()
+		saveCeil.asStore(ceil,
+			addressMode{
+				kind: addressModeKindRegUnsignedImm12,
+				rn:   execCtxVReg, imm: wazevoapi.ExecutionContextOffsets.MemoryOutOfBoundsAddress.I64(),
+			}, 64)
+		m.insert(saveCeil)
+	}
 	m.lowerExitWithCode(execCtxVReg, code)
 	// conditional branch target is after exit.
 	l := m.insertBrTargetLabel()
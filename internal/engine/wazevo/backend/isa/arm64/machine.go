@@ -81,6 +81,13 @@ type (
 
 		maxRequiredStackSizeForCalls int64
 		stackBoundsCheckDisabled     bool
+
+		// constPool holds the literals of the currently-compiled function, which are emitted after its body.
+		constPool []constPoolEntry
+		// constPoolIndexes maps a literal to its index in constPool, in order to deduplicate them.
+		constPoolIndexes map[constPoolEntry]int
+		// constPoolOffsets is used during encoding, defined here for reuse.
+		constPoolOffsets []int64
 	}
 
 	addend32 struct {
@@ -115,6 +122,7 @@ func NewBackend() backend.Machine {
 		labelPositions:    make(map[label]*labelPosition),
 		spillSlots:        make(map[regalloc.VRegID]int64),
 		nextLabel:         invalidLabel,
+		constPoolIndexes:  make(map[constPoolEntry]int),
 	}
 	m.regAllocFn.m = m
 	m.regAllocFn.labelToRegAllocBlockIndex = make(map[label]int)
@@ -142,6 +150,10 @@ func (m *machine) Reset() {
 	m.regAllocFn.reset()
 	m.spillSlotSize = 0
 	m.unresolvedAddressModes = m.unresolvedAddressModes[:0]
+	for _, e := range m.constPool {
+		delete(m.constPoolIndexes, e)
+	}
+	m.constPool = m.constPool[:0]
 	m.rootInstr = nil
 	m.ssaBlockIDToLabels = m.ssaBlockIDToLabels[:0]
 	m.perBlockHead, m.perBlockEnd = nil, nil
@@ -337,6 +349,8 @@ func (m *machine) ResolveRelativeAddresses() {
 		}
 	}
 
+	m.inlineConstPoolIfOutOfRange()
+
 	// Next, in order to determine the offsets of relative jumps, we have to calculate the size of each label.
 	var offset int64
 	for _, pos := range m.orderedBlockLabels {
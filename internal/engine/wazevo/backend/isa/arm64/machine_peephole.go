@@ -0,0 +1,72 @@
+package arm64
+
+// PostRegAlloc implements backend.Machine.
+//
+// This runs the peephole optimizations which are only possible once the real registers are known:
+//
+//   - Copies between the same register, e.g. "mov x1, x1", are removed.
+//   - Zero extensions from 32 to 64-bit right after a 32-bit operation, which already zeros the upper 32 bits
+//     of the destination, are removed. e.g. "add w1, w1, w2; mov w1, w1 (uxtw)".
+//   - Loads followed by the extension of the loaded value are merged. e.g. "ldrb w1, [x2]; sxtb x1, w1" becomes
+//     "ldrsb x1, [x2]", and "ldrb w1, [x2]; uxtb w1, w1" becomes "ldrb w1, [x2]".
+func (m *machine) PostRegAlloc() {
+	for cur := m.rootInstr; cur != nil; cur = cur.next {
+		switch {
+		case cur.isCopy() && cur.rn.realReg() == cur.rd.realReg():
+			m.removeInstr(cur)
+		case cur.kind == extend && cur.rn.realReg() == cur.rd.realReg():
+			if prev := cur.prev; prev != nil && prev.rd.kind == operandKindNR && prev.rd.realReg() == cur.rn.realReg() {
+				if m.mergeExtend(prev, cur) {
+					m.removeInstr(cur)
+				}
+			}
+		}
+	}
+}
+
+// mergeExtend returns true if the extension `ext` of the value defined by `prev` is redundant,
+// possibly after modifying `prev`.
+func (m *machine) mergeExtend(prev, ext *instruction) bool {
+	fromBits, toBits, signed := byte(ext.u1), byte(ext.u2), ext.u3 == 1
+	switch prev.kind {
+	case uLoad8, uLoad16, uLoad32:
+		var loadBits byte
+		switch prev.kind {
+		case uLoad8:
+			loadBits = 8
+		case uLoad16:
+			loadBits = 16
+		case uLoad32:
+			loadBits = 32
+		}
+		if fromBits != loadBits {
+			return false
+		}
+		if !signed {
+			// The unsigned loads already zero the upper bits.
+			return true
+		}
+		if toBits == 64 {
+			// The signed loads sign-extend the loaded value to 64-bit.
+			prev.asSLoad(prev.rd, prev.amode, loadBits)
+			return true
+		}
+		return false
+	case aluRRR, aluRRRShift, aluRRRExtend, aluRRImm12, aluRRBitmaskImm, aluRRRR, movZ, movN, movK, cSel:
+		// The 32-bit variants of these zero the upper 32 bits of the destination.
+		return !signed && fromBits == 32 && toBits == 64 && prev.u3 == 0
+	case mov32:
+		return !signed && fromBits == 32 && toBits == 64
+	default:
+		return false
+	}
+}
+
+// removeInstr removes the instruction from the instruction list. The instruction must not be the root.
+func (m *machine) removeInstr(i *instruction) {
+	prev, next := i.prev, i.next
+	prev.next = next
+	if next != nil {
+		next.prev = prev
+	}
+}
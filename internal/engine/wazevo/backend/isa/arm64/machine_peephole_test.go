@@ -0,0 +1,129 @@
+package arm64
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMachine_PostRegAlloc(t *testing.T) {
+	amode := addressMode{kind: addressModeKindRegUnsignedImm12, rn: x2VReg}
+	for _, tc := range []struct {
+		name  string
+		setup func(*machine) []*instruction
+		exp   string
+	}{
+		{
+			name: "copies",
+			setup: func(m *machine) []*instruction {
+				same, diff := m.allocateInstr(), m.allocateInstr()
+				same.asMove64(x1VReg, x1VReg)
+				diff.asMove64(x1VReg, x2VReg)
+				return []*instruction{same, diff}
+			},
+			exp: `
+	mov x1, x2
+`,
+		},
+		{
+			name: "zero extension after 32-bit op",
+			setup: func(m *machine) []*instruction {
+				add, ext := m.allocateInstr(), m.allocateInstr()
+				add.asALU(aluOpAdd, operandNR(x1VReg), operandNR(x1VReg), operandNR(x2VReg), false)
+				ext.asExtend(x1VReg, x1VReg, 32, 64, false)
+				return []*instruction{add, ext}
+			},
+			exp: `
+	add w1, w1, w2
+`,
+		},
+		{
+			name: "zero extension after 64-bit op",
+			setup: func(m *machine) []*instruction {
+				add, ext := m.allocateInstr(), m.allocateInstr()
+				add.asALU(aluOpAdd, operandNR(x1VReg), operandNR(x1VReg), operandNR(x2VReg), true)
+				ext.asExtend(x1VReg, x1VReg, 32, 64, false)
+				return []*instruction{add, ext}
+			},
+			exp: `
+	add x1, x1, x2
+	uxtw x1, w1
+`,
+		},
+		{
+			name: "sign extension after 32-bit op",
+			setup: func(m *machine) []*instruction {
+				add, ext := m.allocateInstr(), m.allocateInstr()
+				add.asALU(aluOpAdd, operandNR(x1VReg), operandNR(x1VReg), operandNR(x2VReg), false)
+				ext.asExtend(x1VReg, x1VReg, 32, 64, true)
+				return []*instruction{add, ext}
+			},
+			exp: `
+	add w1, w1, w2
+	sxtw x1, w1
+`,
+		},
+		{
+			name: "unsigned load and zero extension",
+			setup: func(m *machine) []*instruction {
+				load, ext := m.allocateInstr(), m.allocateInstr()
+				load.asULoad(operandNR(x1VReg), amode, 16)
+				ext.asExtend(x1VReg, x1VReg, 16, 32, false)
+				return []*instruction{load, ext}
+			},
+			exp: `
+	ldrh w1, [x2]
+`,
+		},
+		{
+			name: "unsigned load and sign extension to 64-bit",
+			setup: func(m *machine) []*instruction {
+				load, ext := m.allocateInstr(), m.allocateInstr()
+				load.asULoad(operandNR(x1VReg), amode, 8)
+				ext.asExtend(x1VReg, x1VReg, 8, 64, true)
+				return []*instruction{load, ext}
+			},
+			exp: `
+	ldrsb w1, [x2]
+`,
+		},
+		{
+			name: "unsigned load and sign extension to 32-bit",
+			setup: func(m *machine) []*instruction {
+				load, ext := m.allocateInstr(), m.allocateInstr()
+				load.asULoad(operandNR(x1VReg), amode, 8)
+				ext.asExtend(x1VReg, x1VReg, 8, 32, true)
+				return []*instruction{load, ext}
+			},
+			exp: `
+	ldrb w1, [x2]
+	sxtb w1, w1
+`,
+		},
+		{
+			name: "extension to another register",
+			setup: func(m *machine) []*instruction {
+				load, ext := m.allocateInstr(), m.allocateInstr()
+				load.asULoad(operandNR(x1VReg), amode, 32)
+				ext.asExtend(x3VReg, x1VReg, 32, 64, true)
+				return []*instruction{load, ext}
+			},
+			exp: `
+	ldr w1, [x2]
+	sxtw x3, w1
+`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewBackend().(*machine)
+			m.rootInstr = m.allocateInstr().asNop0()
+			cur := m.rootInstr
+			for _, i := range tc.setup(m) {
+				cur = linkInstr(cur, i)
+			}
+			m.PostRegAlloc()
+			require.Equal(t, tc.exp, m.Format())
+		})
+	}
+}
@@ -82,6 +82,10 @@ type (
 		// Function returns the currently compiled state as regalloc.Function so that we can perform register allocation.
 		Function() regalloc.Function
 
+		// PostRegAlloc does the peephole optimizations which are possible after register allocations,
+		// e.g. removing the copies between the same registers.
+		PostRegAlloc()
+
 		// SetupPrologue inserts the prologue after register allocations.
 		SetupPrologue()
 
@@ -39,6 +39,9 @@ func (m mockMachine) Encode() {}
 // ResolveRelocations implements Machine.ResolveRelocations.
 func (m mockMachine) ResolveRelocations(map[ssa.FuncRef]int, []byte, []RelocationInfo) {}
 
+// PostRegAlloc implements Machine.PostRegAlloc.
+func (m mockMachine) PostRegAlloc() {}
+
 // SetupPrologue implements Machine.SetupPrologue.
 func (m mockMachine) SetupPrologue() {}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmdebug"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
@@ -35,6 +36,8 @@ type (
 		// execCtxPtr holds the pointer to the executionContext which doesn't change after callEngine is created.
 		execCtxPtr      uintptr
 		numberOfResults int
+		// yieldCounter counts the exit code checks to periodically yield to the Go scheduler.
+		yieldCounter wasm.YieldCounter
 	}
 
 	// executionContext is the struct to be read/written by assembly functions.
@@ -61,14 +64,18 @@ type (
 		memoryGrowTrampolineAddress *byte
 		// stackGrowCallSequenceAddress holds the address of stack grow call sequence function.
 		stackGrowCallSequenceAddress *byte
+		// checkModuleExitCodeTrampolineAddress holds the address of check-module-exit-code function.
+		checkModuleExitCodeTrampolineAddress *byte
 		// savedRegisters is the opaque spaces for save/restore registers.
 		// We want to align 16 bytes for each register, so we use [64][2]uint64.
-		_              uint64
 		savedRegisters [64][2]uint64
 		// goFunctionCallCalleeModuleContextOpaque is the pointer to the target Go function's moduleContextOpaque.
 		goFunctionCallCalleeModuleContextOpaque uintptr
 		// goFunctionCallStack is used to pass/receive parameters/results for Go function calls.
 		goFunctionCallStack [goFunctionCallStackSize]uint64
+		// memoryOutOfBoundsAddress holds the end, i.e. the effective address plus the size, of the memory access
+		// which exited with wazevoapi.ExitCodeMemoryOutOfBounds.
+		memoryOutOfBoundsAddress uint64
 	}
 )
 
@@ -110,8 +117,41 @@ func (c *callEngine) Call(ctx context.Context, params ...uint64) ([]uint64, erro
 	return paramResultSlice[:c.numberOfResults], nil
 }
 
+// CallWithOptions implements api.Function.
+func (c *callEngine) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) (results []uint64, err error) {
+	err = c.parent.module.CallWithOptions(opts, func() (err error) {
+		results, err = c.Call(ctx, params...)
+		return
+	})
+	return
+}
+
 // CallWithStack implements api.Function.
-func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint64) error {
+func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint64) (err error) {
+	m := c.parent.module
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = c.recoverOnCall(ctx, m, recovered)
+		} else if err != nil {
+			err = wasmdebug.WithLabels(err, m.Labels)
+		}
+		if err != nil {
+			m.Trapped(ctx, err)
+		}
+	}()
+
+	if c.parent.parent.ensureTermination {
+		select {
+		case <-ctx.Done():
+			// If the provided context is already done, close the module and return the error.
+			m.CloseWithCtxErr(ctx)
+			return m.FailIfClosed()
+		default:
+		}
+		done := m.CloseModuleOnCanceledOrTimeout(ctx)
+		defer done()
+	}
+
 	var paramResultPtr *uint64
 	if len(paramResultStack) > 0 {
 		paramResultPtr = &paramResultStack[0]
@@ -132,11 +172,15 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 		case wazevoapi.ExitCodeUnreachable:
 			return wasmruntime.ErrRuntimeUnreachable
 		case wazevoapi.ExitCodeMemoryOutOfBounds:
-			return wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess
+			size := wazevoapi.MemoryAccessSizeFromExitCode(ec)
+			return wasmruntime.NewOutOfBoundsMemoryAccessError(c.execCtx.memoryOutOfBoundsAddress - size)
 		case wazevoapi.ExitCodeGrowMemory:
 			mod := c.callerModuleInstance()
 			mem := mod.MemoryInstance
 			argRes := &c.execCtx.goFunctionCallStack[0]
+			if mem.ExceedsGrowthRate(uint32(*argRes)) {
+				return wasmruntime.ErrRuntimeMemoryGrowthRateExceeded
+			}
 			if res, ok := mem.Grow(uint32(*argRes)); !ok {
 				*argRes = uint64(0xffffffff) // = -1 in signed 32-bit integer.
 			} else {
@@ -156,6 +200,7 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 		case wazevoapi.ExitCodeCallGoFunction:
 			index := wazevoapi.GoFunctionIndexFromExitCode(ec)
 			f := hostModuleGoFuncFromOpaque[api.GoFunction](index, c.execCtx.goFunctionCallCalleeModuleContextOpaque)
+			c.callerModuleInstance().BeforeHostCall(ctx)
 			f.Call(ctx, c.execCtx.goFunctionCallStack[:])
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, c.execCtx.stackPointerBeforeGoCall)
@@ -163,6 +208,7 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 			index := wazevoapi.GoFunctionIndexFromExitCode(ec)
 			f := hostModuleGoFuncFromOpaque[api.GoModuleFunction](index, c.execCtx.goFunctionCallCalleeModuleContextOpaque)
 			mod := c.callerModuleInstance()
+			mod.BeforeHostCall(ctx)
 			f.Call(ctx, mod, c.execCtx.goFunctionCallStack[:])
 			c.execCtx.exitCode = wazevoapi.ExitCodeOK
 			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, c.execCtx.stackPointerBeforeGoCall)
@@ -178,12 +224,39 @@ func (c *callEngine) CallWithStack(ctx context.Context, paramResultStack []uint6
 			return wasmruntime.ErrRuntimeIntegerDivideByZero
 		case wazevoapi.ExitCodeInvalidConversionToInteger:
 			return wasmruntime.ErrRuntimeInvalidConversionToInteger
+		case wazevoapi.ExitCodeCheckModuleExitCode:
+			// Note: this is only reached when the module is compiled to ensure termination or to yield cooperatively.
+			if err := m.FailIfClosed(); err != nil {
+				return err
+			}
+			c.yieldCounter.Checkpoint()
+			c.execCtx.exitCode = wazevoapi.ExitCodeOK
+			afterGoFunctionCallEntrypoint(c.execCtx.goCallReturnAddress, c.execCtxPtr, c.execCtx.stackPointerBeforeGoCall)
 		default:
 			panic("BUG")
 		}
 	}
 }
 
+// recoverOnCall converts the value recovered from a panic of a host function
+// into an error, and resets the state of callEngine so that it can be used
+// for subsequent calls.
+//
+// Note: Unlike the other engines, the stack trace only has the function
+// called, as the frames of the native stack aren't walked yet.
+func (c *callEngine) recoverOnCall(ctx context.Context, m *wasm.ModuleInstance, recovered interface{}) (err error) {
+	def := c.Definition()
+	builder := wasmdebug.NewErrorBuilder()
+	builder.AddFrame(def.DebugName(), def.ParamTypes(), def.ResultTypes(), nil)
+	err = builder.FromRecovered(recovered)
+	err = wasmdebug.WithLabels(err, m.Labels)
+	if wasmdebug.IsPanic(recovered) {
+		err = m.Panicked(ctx, recovered, err)
+	}
+	c.execCtx.exitCode = wazevoapi.ExitCodeOK
+	return
+}
+
 func (c *callEngine) callerModuleInstance() *wasm.ModuleInstance {
 	return *(**wasm.ModuleInstance)(unsafe.Pointer(c.execCtx.callerModuleContextPtr))
 }
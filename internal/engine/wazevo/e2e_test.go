@@ -3,12 +3,16 @@ package wazevo_test
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/testcases"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
@@ -18,6 +22,8 @@ import (
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 const (
@@ -350,6 +356,16 @@ func TestE2E(t *testing.T) {
 				{params: []uint64{1, 200}, expResults: []uint64{1}},
 			},
 		},
+		{
+			name: "select_diamond",
+			m:    testcases.SelectDiamond.Module,
+			calls: []callCase{
+				{params: []uint64{1, 100}, expResults: []uint64{2}},
+				{params: []uint64{100, 1}, expResults: []uint64{0}},
+				{params: []uint64{math.MaxUint32, 0}, expResults: []uint64{0}}, // -1 < 0
+				{params: []uint64{5, 5}, expResults: []uint64{4}},
+			},
+		},
 	} {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
@@ -600,3 +616,304 @@ func TestE2E_reexported_memory(t *testing.T) {
 	require.Equal(t, mem, m2Inst.Memory())
 	require.Equal(t, uint32(11), mem.Size()/65536)
 }
+
+func TestE2E_ensureTermination(t *testing.T) {
+	// count returns the count of loop iterations, which is the param, and never returns if the param is zero.
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{i32}, Body: []byte{
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0,
+			wasm.OpcodeBrIf, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "count", Type: wasm.ExternTypeFunc, Index: 0}},
+	}
+
+	t.Run("WithCloseOnContextDone", func(t *testing.T) {
+		config := wazero.NewRuntimeConfigCompiler().WithCloseOnContextDone(true)
+		wazevo.ConfigureWazevo(config)
+
+		ctx := context.Background()
+		r := wazero.NewRuntimeWithConfig(ctx, config)
+		defer r.Close(ctx)
+
+		inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(m))
+		require.NoError(t, err)
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = inst.ExportedFunction("count").Call(timeoutCtx, 0)
+		require.Equal(t, sys.NewExitError(sys.ExitCodeDeadlineExceeded), err)
+	})
+
+	t.Run("WithCooperativeYield", func(t *testing.T) {
+		config := wazero.NewRuntimeConfigCompiler().WithCooperativeYield(true)
+		wazevo.ConfigureWazevo(config)
+
+		ctx := context.Background()
+		r := wazero.NewRuntimeWithConfig(ctx, config)
+		defer r.Close(ctx)
+
+		inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(m))
+		require.NoError(t, err)
+
+		// Unlike WithCloseOnContextDone, the module isn't closed even if the context is done.
+		canceledCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		res, err := inst.ExportedFunction("count").Call(canceledCtx, 10000)
+		require.NoError(t, err)
+		require.Equal(t, uint64(10000), res[0])
+
+		// The loop yields to the Go scheduler, so another goroutine can close the module
+		// even if there's a single P.
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = inst.CloseWithExitCode(ctx, 2)
+		}()
+		_, err = inst.ExportedFunction("count").Call(ctx, 0)
+		require.Equal(t, sys.NewExitError(2), err)
+	})
+}
+
+// BenchmarkE2E_select_diamond calls a function whose if-else is lowered as a conditional select
+// with an unpredictable condition, which would otherwise be dominated by branch mispredictions.
+func BenchmarkE2E_select_diamond(b *testing.B) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	inst, err := r.Instantiate(ctx, binaryencoding.EncodeModule(testcases.SelectDiamond.Module))
+	if err != nil {
+		b.Fatal(err)
+	}
+	f := inst.ExportedFunction(testcases.ExportedFunctionName)
+
+	// A linear congruential generator makes the condition unpredictable.
+	x := uint32(1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x = x*1664525 + 1013904223
+		if _, err = f.Call(ctx, uint64(x>>16), uint64(x&0xffff)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// hostCallModule imports "env.now", and exports "now" which calls it.
+func hostCallModule(r wazero.Runtime, now api.GoModuleFunc) (api.Module, error) {
+	ctx := context.Background()
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithGoModuleFunction(now, nil, []api.ValueType{i64}).Export("now").
+		Instantiate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Instantiate(ctx, binaryencoding.EncodeModule(&wasm.Module{
+		ImportFunctionCount: 1,
+		ImportSection:       []wasm.Import{{Module: "env", Name: "now", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		TypeSection:         []wasm.FunctionType{{Results: []wasm.ValueType{i64}}},
+		FunctionSection:     []wasm.Index{0},
+		CodeSection:         []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:       []wasm.Export{{Name: "now", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+}
+
+func TestE2E_host_function_panic(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	var fail func()
+	inst, err := hostCallModule(r, func(_ context.Context, _ api.Module, stack []uint64) {
+		if fail != nil {
+			fail()
+		}
+		stack[0] = 42
+	})
+	require.NoError(t, err)
+
+	f := inst.ExportedFunction("now")
+
+	fail = func() { panic(errors.New("boom")) }
+	_, err = f.Call(ctx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "boom (recovered by wazero)\nwasm stack trace:")
+
+	// A Go runtime error is also a PanicError.
+	fail = func() {
+		var m map[string]int
+		m["a"] = 1
+	}
+	_, err = f.Call(ctx)
+	var pe *experimental.PanicError
+	require.True(t, errors.As(err, &pe))
+	require.Contains(t, err.Error(), "assignment to entry in nil map (recovered by wazero)")
+
+	// So is a panic with a value other than an error.
+	fail = func() { panic("BUG") }
+	_, err = f.Call(ctx)
+	require.True(t, errors.As(err, &pe))
+	require.Equal(t, "BUG", pe.Recovered)
+
+	// The function can be called again after recovering.
+	fail = nil
+	res, err := f.Call(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, res)
+}
+
+func TestE2E_labels(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, binaryencoding.EncodeModule(testcases.Unreachable.Module))
+	require.NoError(t, err)
+	inst, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithLabel("tenant", "acme"))
+	require.NoError(t, err)
+
+	_, err = inst.ExportedFunction(testcases.ExportedFunctionName).Call(ctx)
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+	require.Contains(t, err.Error(), "\nwasm module labels: tenant=acme")
+}
+
+func TestE2E_compilationCacheListener(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	var events []experimental.CompilationCacheEventType
+	ctx := experimental.WithCompilationCacheListener(context.Background(),
+		experimental.CompilationCacheListenerFunc(func(_ context.Context, e experimental.CompilationCacheEvent) {
+			require.True(t, e.InMemory)
+			events = append(events, e.Type)
+		}))
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	bin := binaryencoding.EncodeModule(testcases.Unreachable.Module)
+	_, err := r.CompileModule(ctx, bin)
+	require.NoError(t, err)
+	require.Equal(t, []experimental.CompilationCacheEventType{
+		experimental.CompilationCacheEventMiss,
+		experimental.CompilationCacheEventStore,
+	}, events)
+
+	events = nil
+	_, err = r.CompileModule(ctx, bin)
+	require.NoError(t, err)
+	require.Equal(t, []experimental.CompilationCacheEventType{experimental.CompilationCacheEventHit}, events)
+}
+
+func TestE2E_metrics(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := experimental.WithMetrics(context.Background())
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	bin := binaryencoding.EncodeModule(testcases.Unreachable.Module)
+	_, err := r.CompileModule(ctx, bin)
+	require.NoError(t, err)
+	_, err = r.CompileModule(ctx, bin) // cache hit
+	require.NoError(t, err)
+
+	m, ok := experimental.GetMetrics(r)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), m.Compilations)
+	require.Equal(t, uint64(1), m.CacheHits)
+}
+
+func TestE2E_memoryOutOfBounds(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	mod, err := r.Instantiate(ctx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{Body: []byte{
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Load, 0x2, 0x8, // align=2, offset=8
+			wasm.OpcodeEnd,
+		}}},
+		MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+		ExportSection: []wasm.Export{{Name: "load", Type: wasm.ExternTypeFunc, Index: 0}},
+	}))
+	require.NoError(t, err)
+
+	for _, base := range []uint64{65530, 0xffffffff} {
+		_, err = mod.ExportedFunction("load").Call(ctx, base)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+
+		var oob *wasmruntime.OutOfBoundsMemoryAccessError
+		require.True(t, errors.As(err, &oob))
+		require.Equal(t, base+8, oob.Address)
+	}
+}
+
+func TestE2E_ssaInspector(t *testing.T) {
+	config := wazero.NewRuntimeConfigCompiler()
+	wazevo.ConfigureWazevo(config)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, config)
+	defer r.Close(ctx)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	var inspected int
+	inspectorCtx := experimental.WithSSAInspector(ctx, experimental.SSAInspectorFunc(func(phase experimental.SSAPhase, fn experimental.SSAFunction) {
+		if phase != experimental.SSAPhaseOptimized {
+			return
+		}
+		inspected++
+		fn.Visit(func(_ experimental.SSABlock, inst experimental.SSAInstruction) {
+			if _, ok := inst.Constant(); ok {
+				require.True(t, inst.ReplaceWithConstant(42))
+			}
+		})
+	}))
+
+	call := func(ctx context.Context) uint64 {
+		compiled, err := r.CompileModule(ctx, bin)
+		require.NoError(t, err)
+		mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName(""))
+		require.NoError(t, err)
+		defer mod.Close(ctx)
+		res, err := mod.ExportedFunction("f").Call(ctx)
+		require.NoError(t, err)
+		return res[0]
+	}
+
+	// The edits apply to modules compiled with the inspector only, which is
+	// called on each compilation.
+	require.Equal(t, uint64(42), call(inspectorCtx))
+	require.Equal(t, uint64(1), call(ctx))
+	require.Equal(t, uint64(42), call(inspectorCtx))
+	require.Equal(t, 2, inspected)
+}
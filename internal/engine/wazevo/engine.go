@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"runtime"
 	"sync"
+	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
@@ -34,6 +35,8 @@ type (
 		memoryGrowExecutable []byte
 		// stackGrowExecutable is a compiled executable for growing stack builtin function.
 		stackGrowExecutable []byte
+		// checkModuleExitCodeExecutable is a compiled executable for checking the module exit code builtin function.
+		checkModuleExitCodeExecutable []byte
 	}
 
 	// compiledModule is a compiled variant of a wasm.Module and ready to be used for instantiation.
@@ -45,6 +48,9 @@ type (
 
 		offsets          wazevoapi.ModuleContextOffsetData
 		builtinFunctions *builtinFunctions
+		// ensureTermination is true if the module must be closed when the context of a call is done.
+		// See wazero.RuntimeConfig WithCloseOnContextDone.
+		ensureTermination bool
 	}
 
 	// compiledFunctionOffset tells us that where in the executable a function begins.
@@ -73,6 +79,16 @@ func NewEngine(ctx context.Context, _ api.CoreFeatures, _ filecache.Cache) wasm.
 
 // CompileModule implements wasm.Engine.
 func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) error {
+	if platform.ExecutableMemoryForbidden() {
+		return platform.ErrExecutableMemoryForbidden
+	}
+	if _, ok := e.getCompiledModule(module); ok { // cache hit!
+		filecache.Notify(ctx, experimental.CompilationCacheEventHit, module.ID, true, "", nil)
+		wasm.SetCompiledFromCache(ctx)
+		return nil
+	}
+	filecache.Notify(ctx, experimental.CompilationCacheEventMiss, module.ID, true, "", nil)
+
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		ctx = wazevoapi.NewDeterministicCompilationVerifierContext(ctx, len(module.CodeSection))
 	}
@@ -81,6 +97,7 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listene
 		return err
 	}
 	e.addCompiledModule(module, cm)
+	filecache.Notify(ctx, experimental.CompilationCacheEventStore, module.ID, true, "", nil)
 
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		for i := 0; i < wazevoapi.DeterministicCompilationVerifyingIter; i++ {
@@ -95,7 +112,7 @@ func (e *engine) CompileModule(ctx context.Context, module *wasm.Module, listene
 
 func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool) (*compiledModule, error) {
 	e.rels = e.rels[:0]
-	cm := &compiledModule{offsets: wazevoapi.NewModuleContextOffsetData(module)}
+	cm := &compiledModule{offsets: wazevoapi.NewModuleContextOffsetData(module), ensureTermination: ensureTermination}
 
 	if module.IsHostModule {
 		return e.compileHostModule(ctx, module)
@@ -122,7 +139,8 @@ func (e *engine) compileModule(ctx context.Context, module *wasm.Module, listene
 
 	// Creates new compiler instances which are reused for each function.
 	ssaBuilder := ssa.NewBuilder()
-	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets)
+	// Like the other engines, the exit code checks in loops also serve as the cooperative yield points.
+	fe := frontend.NewFrontendCompiler(module, ssaBuilder, &cm.offsets, ensureTermination || module.CooperativeYield)
 	machine := newMachine()
 	be := backend.NewCompiler(ctx, machine, ssaBuilder)
 
@@ -224,6 +242,7 @@ func (e *engine) compileLocalWasmFunction(
 ) (body []byte, rels []backend.RelocationInfo, goPreambleSize int, err error) {
 	typ := &module.TypeSection[module.FunctionSection[localFunctionIndex]]
 	codeSeg := &module.CodeSection[localFunctionIndex]
+	inspector, _ := ctx.Value(experimental.SSAInspectorKey{}).(experimental.SSAInspector)
 
 	// Initializes both frontend and backend compilers.
 	fe.Init(localFunctionIndex, typ, codeSeg.LocalTypes, codeSeg.Body)
@@ -239,6 +258,10 @@ func (e *engine) compileLocalWasmFunction(
 		fmt.Printf("[[[SSA for %s]]]%s\n", wazevoapi.GetCurrentFunctionName(ctx), ssaBuilder.Format())
 	}
 
+	if inspector != nil {
+		ssa.Inspect(ssaBuilder, functionIndex, experimental.SSAPhaseLowered, inspector)
+	}
+
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		wazevoapi.VerifyOrSetDeterministicCompilationContextValue(ctx, "SSA", ssaBuilder.Format())
 	}
@@ -250,6 +273,10 @@ func (e *engine) compileLocalWasmFunction(
 		fmt.Printf("[[[Optimized SSA for %s]]]%s\n", wazevoapi.GetCurrentFunctionName(ctx), ssaBuilder.Format())
 	}
 
+	if inspector != nil {
+		ssa.Inspect(ssaBuilder, functionIndex, experimental.SSAPhaseOptimized, inspector)
+	}
+
 	if wazevoapi.DeterministicCompilationVerifierEnabled {
 		wazevoapi.VerifyOrSetDeterministicCompilationContextValue(ctx, "Optimized SSA", ssaBuilder.Format())
 	}
@@ -395,6 +422,35 @@ func (e *engine) DeleteCompiledModule(m *wasm.Module) {
 	delete(e.compiledModules, m.ID)
 }
 
+// CompiledSize implements wasm.SizeEstimator.
+func (e *engine) CompiledSize(m *wasm.Module) (uint64, bool) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	cm, ok := e.compiledModules[m.ID]
+	if !ok {
+		return 0, false
+	}
+	return uint64(len(cm.executable)) + uint64(len(cm.functionOffsets))*uint64(unsafe.Sizeof(compiledFunctionOffset{})), true
+}
+
+// ModuleEngineSize implements wasm.SizeEstimator.
+func (e *engine) ModuleEngineSize(m *wasm.Module) uint64 {
+	size := uint64(unsafe.Sizeof(moduleEngine{})) + uint64(m.ImportFunctionCount)*uint64(unsafe.Sizeof(importedFunction{}))
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	if cm, ok := e.compiledModules[m.ID]; ok {
+		size += uint64(cm.offsets.TotalSize)
+	}
+	return size
+}
+
+func (e *engine) getCompiledModule(m *wasm.Module) (cm *compiledModule, ok bool) {
+	e.mux.RLock()
+	defer e.mux.RUnlock()
+	cm, ok = e.compiledModules[m.ID]
+	return
+}
+
 func (e *engine) addCompiledModule(m *wasm.Module, cm *compiledModule) {
 	e.mux.Lock()
 	defer e.mux.Unlock()
@@ -440,6 +496,12 @@ func (e *engine) compileBuiltinFunctions(ctx context.Context) {
 		}, false)
 		e.builtinFunctions.memoryGrowExecutable = mmapExecutable(src)
 	}
+	{
+		src := machine.CompileGoFunctionTrampoline(wazevoapi.ExitCodeCheckModuleExitCode, &ssa.Signature{
+			Params: []ssa.Type{ssa.TypeI32 /* exec context */},
+		}, false)
+		e.builtinFunctions.checkModuleExitCodeExecutable = mmapExecutable(src)
+	}
 
 	// TODO: table grow, etc.
 
@@ -20,6 +20,13 @@ type Compiler struct {
 	ssaBuilder    ssa.Builder
 	signatures    map[*wasm.FunctionType]*ssa.Signature
 	memoryGrowSig ssa.Signature
+	// checkModuleExitCodeSig is the signature of the function checking the module exit code in loops.
+	checkModuleExitCodeSig ssa.Signature
+	// ensureTermination is true if loops must check the module exit code. See wazero.RuntimeConfig
+	// WithCloseOnContextDone and WithCooperativeYield.
+	ensureTermination bool
+	// memoryPageSizeInBits is the log2 of the page size of the memory, if any.
+	memoryPageSizeInBits uint32
 
 	// Followings are reset by per function.
 
@@ -38,18 +45,22 @@ type Compiler struct {
 	// br is reused during lowering.
 	br            *bytes.Reader
 	loweringState loweringState
+	// unsupported is the error of an instruction which can't be lowered yet,
+	// which stops lowering the function.
+	unsupported error
 
 	execCtxPtrValue, moduleCtxPtrValue ssa.Value
 }
 
 // NewFrontendCompiler returns a frontend Compiler.
-func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData) *Compiler {
+func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoapi.ModuleContextOffsetData, ensureTermination bool) *Compiler {
 	c := &Compiler{
 		m:                   m,
 		ssaBuilder:          ssaBuilder,
 		br:                  bytes.NewReader(nil),
 		wasmLocalToVariable: make(map[wasm.Index]ssa.Variable),
 		offset:              offset,
+		ensureTermination:   ensureTermination,
 	}
 
 	c.signatures = make(map[*wasm.FunctionType]*ssa.Signature, len(m.TypeSection)+1)
@@ -82,6 +93,22 @@ func NewFrontendCompiler(m *wasm.Module, ssaBuilder ssa.Builder, offset *wazevoa
 	}
 	c.ssaBuilder.DeclareSignature(&c.memoryGrowSig)
 
+	c.checkModuleExitCodeSig = ssa.Signature{
+		ID: c.memoryGrowSig.ID + 1,
+		// Only takes execution context.
+		Params: []ssa.Type{ssa.TypeI64},
+	}
+	c.ssaBuilder.DeclareSignature(&c.checkModuleExitCodeSig)
+
+	c.memoryPageSizeInBits = wasm.MemoryPageSizeInBits
+	if m.MemorySection != nil {
+		c.memoryPageSizeInBits = m.MemorySection.PageSizeInBits()
+	}
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == wasm.ExternTypeMemory {
+			c.memoryPageSizeInBits = imp.DescMem.PageSizeInBits()
+		}
+	}
 	return c
 }
 
@@ -94,6 +121,7 @@ func (c *Compiler) Init(idx wasm.Index, typ *wasm.FunctionType, localTypes []was
 	c.wasmFunctionTyp = typ
 	c.wasmFunctionLocalTypes = localTypes
 	c.wasmFunctionBody = body
+	c.unsupported = nil
 }
 
 // Note: this assumes 64-bit platform (I believe we won't have 32-bit backend ;)).
@@ -144,7 +172,7 @@ func (c *Compiler) LowerToSSA() error {
 	c.declareNecessaryVariables()
 
 	c.lowerBody(entryBlock)
-	return nil
+	return c.unsupported
 }
 
 // localVariable returns the SSA variable for the given Wasm local index.
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/ssa"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/testcases"
 	"github.com/tetratelabs/wazero/internal/engine/wazevo/wazevoapi"
@@ -26,6 +27,8 @@ func TestCompiler_LowerToSSA(t *testing.T) {
 		exp string
 		// expAfterOpt is not empty when we want to check the result after optimization passes.
 		expAfterOpt string
+		// ensureTermination is true when the loops must check the module exit code.
+		ensureTermination bool
 	}{
 		{
 			name: "empty", m: testcases.Empty.Module,
@@ -246,6 +249,29 @@ blk1: () <-- (blk0,blk1)
 	Brnz v2, blk1
 	Jump blk3
 
+blk3: () <-- (blk1)
+	Return
+`,
+		},
+		{
+			name: "loop - br_if - ensure termination", m: testcases.LoopBrIf.Module,
+			ensureTermination: true,
+			exp: `
+signatures:
+	sig2: i64_v
+
+blk0: (exec_ctx:i64, module_ctx:i64)
+	Jump blk1
+
+blk1: () <-- (blk0,blk1)
+	v2:i64 = Load exec_ctx, 0x58
+	CallIndirect v2:sig2, exec_ctx
+	v3:i32 = Iconst_32 0x1
+	Brnz v3, blk1
+	Jump blk3
+
+blk2: ()
+
 blk3: () <-- (blk1)
 	Return
 `,
@@ -365,17 +391,11 @@ blk3: (v5:i32) <-- (blk1,blk2)
 `,
 			expAfterOpt: `
 blk0: (exec_ctx:i64, module_ctx:i64, v2:i32, v3:i32)
-	Brz v2, blk2
-	Jump blk1
-
-blk1: () <-- (blk0)
-	Jump blk3, v2
-
-blk2: () <-- (blk0)
-	Jump blk3, v3
+	v6:i32 = Select v2, v2, v3
+	Jump blk3
 
-blk3: (v5:i32) <-- (blk1,blk2)
-	Jump blk_ret, v5
+blk3: () <-- (blk0)
+	Jump blk_ret, v6
 `,
 		},
 		{
@@ -1689,7 +1709,7 @@ blk13: () <-- (blk0)
 			b := ssa.NewBuilder()
 
 			offset := wazevoapi.NewModuleContextOffsetData(tc.m)
-			fc := NewFrontendCompiler(tc.m, b, &offset)
+			fc := NewFrontendCompiler(tc.m, b, &offset, tc.ensureTermination)
 			typeIndex := tc.m.FunctionSection[tc.targetIndex]
 			code := &tc.m.CodeSection[tc.targetIndex]
 			fc.Init(tc.targetIndex, &tc.m.TypeSection[typeIndex], code.LocalTypes, code.Body)
@@ -1713,3 +1733,25 @@ blk13: () <-- (blk0)
 		})
 	}
 }
+
+func TestCompiler_LowerToSSA_unsupported(t *testing.T) {
+	v128Const := append([]byte{wasm.OpcodeVecPrefix, wasm.OpcodeVecV128Const}, make([]byte, 16)...)
+	var body []byte
+	for i := 0; i < 3; i++ {
+		body = append(body, v128Const...)
+	}
+	// f32x4.relaxed_madd is encoded with two bytes of LEB128.
+	body = append(body, wasm.OpcodeVecPrefix, 0x85, 0x02, wasm.OpcodeDrop, wasm.OpcodeEnd)
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: body}},
+	}
+	require.NoError(t, m.Validate(api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD))
+
+	offset := wazevoapi.NewModuleContextOffsetData(m)
+	fc := NewFrontendCompiler(m, ssa.NewBuilder(), &offset, false)
+	fc.Init(0, &m.TypeSection[0], nil, body)
+	err := fc.LowerToSSA()
+	require.EqualError(t, err, "unsupported vector instruction: f32x4.relaxed_madd")
+}
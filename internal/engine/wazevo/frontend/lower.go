@@ -138,7 +138,7 @@ func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
 		followingBlock: c.ssaBuilder.ReturnBlock(),
 	})
 
-	for c.loweringState.pc < len(c.wasmFunctionBody) {
+	for c.loweringState.pc < len(c.wasmFunctionBody) && c.unsupported == nil {
 		c.lowerCurrentOpcode()
 	}
 }
@@ -780,7 +780,7 @@ func (c *Compiler) lowerCurrentOpcode() {
 		}
 
 		amount := builder.AllocateInstruction()
-		amount.AsIconst32(uint32(wasm.MemoryPageSizeInBits))
+		amount.AsIconst32(c.memoryPageSizeInBits)
 		builder.InsertInstruction(amount)
 		memSize := builder.AllocateInstruction().
 			AsUshr(memSizeInBytes, amount.Return()).
@@ -988,6 +988,18 @@ func (c *Compiler) lowerCurrentOpcode() {
 
 		c.switchTo(originalLen, loopHeader)
 
+		if c.ensureTermination {
+			checkModuleExitCodePtr := builder.AllocateInstruction().
+				AsLoad(c.execCtxPtrValue,
+					wazevoapi.ExecutionContextOffsets.CheckModuleExitCodeTrampolineAddress.U32(),
+					ssa.TypeI64,
+				).Insert(builder).Return()
+
+			builder.AllocateInstruction().
+				AsCallIndirect(checkModuleExitCodePtr, &c.checkModuleExitCodeSig, []ssa.Value{c.execCtxPtrValue}).
+				Insert(builder)
+		}
+
 	case wasm.OpcodeIf:
 		bt := c.readBlockType()
 
@@ -1300,6 +1312,11 @@ func (c *Compiler) lowerCurrentOpcode() {
 		state.push(cvt.Return())
 
 	case wasm.OpcodeVecPrefix:
+		if relaxedOp, ok := wasm.LoadVecRelaxedOpcode(c.wasmFunctionBody[state.pc+1:]); ok {
+			// Fail the compilation, as these aren't lowered yet.
+			c.unsupported = fmt.Errorf("unsupported vector instruction: %s", wasm.VecRelaxedInstructionName(relaxedOp))
+			break
+		}
 		state.pc++
 		vecOp := c.wasmFunctionBody[state.pc]
 		state.pc++
@@ -1498,7 +1515,7 @@ func (c *Compiler) memOpSetup(baseAddr ssa.Value, constOffset, operationSizeInBy
 	cmp.AsIcmp(memLen, baseAddrPlusCeil.Return(), ssa.IntegerCmpCondUnsignedLessThan)
 	builder.InsertInstruction(cmp)
 	exitIfNZ := builder.AllocateInstruction()
-	exitIfNZ.AsExitIfTrueWithCode(c.execCtxPtrValue, cmp.Return(), wazevoapi.ExitCodeMemoryOutOfBounds)
+	exitIfNZ.AsExitIfTrueWithCode(c.execCtxPtrValue, cmp.Return(), wazevoapi.ExitCodeMemoryOutOfBoundsWithSize(operationSizeInBytes))
 	builder.InsertInstruction(exitIfNZ)
 
 	// Load the value from memBase + extBaseAddr.
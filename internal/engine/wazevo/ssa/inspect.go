@@ -0,0 +1,157 @@
+package ssa
+
+import (
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// Inspect calls the inspector with the function currently constructed in the Builder, which is the
+// function at `index` of the module, including imported functions.
+func Inspect(b Builder, index uint32, phase experimental.SSAPhase, inspector experimental.SSAInspector) {
+	builder := b.(*builder)
+	f := &inspectedFunction{b: builder, index: index, definitions: make(map[ValueID]*Instruction)}
+	// Collect blocks upfront, so that the inspector can call Format while visiting, which iterates
+	// over the blocks with the same iterator.
+	for bb := builder.blockIteratorBegin(); bb != nil; bb = builder.blockIteratorNext() {
+		f.blocks = append(f.blocks, bb)
+		for cur := bb.rootInstr; cur != nil; cur = cur.next {
+			r1, rs := cur.Returns()
+			if r1.Valid() {
+				f.definitions[r1.ID()] = cur
+			}
+			for _, r := range rs {
+				f.definitions[r.ID()] = cur
+			}
+		}
+	}
+	inspector.InspectSSA(phase, f)
+}
+
+// inspectedFunction implements experimental.SSAFunction.
+type inspectedFunction struct {
+	b           *builder
+	index       uint32
+	blocks      []*basicBlock
+	definitions map[ValueID]*Instruction
+}
+
+// Index implements experimental.SSAFunction.Index.
+func (f *inspectedFunction) Index() uint32 {
+	return f.index
+}
+
+// Format implements experimental.SSAFunction.Format.
+func (f *inspectedFunction) Format() string {
+	return f.b.Format()
+}
+
+// Visit implements experimental.SSAFunction.Visit.
+func (f *inspectedFunction) Visit(visitor func(block experimental.SSABlock, inst experimental.SSAInstruction)) {
+	for _, bb := range f.blocks {
+		blk := &inspectedBlock{f: f, bb: bb}
+		for cur := bb.rootInstr; cur != nil; {
+			// The visitor might edit the instruction, so read the next one first.
+			next := cur.next
+			visitor(blk, &inspectedInstruction{f: f, i: cur})
+			cur = next
+		}
+	}
+}
+
+// inspectedBlock implements experimental.SSABlock.
+type inspectedBlock struct {
+	f  *inspectedFunction
+	bb *basicBlock
+}
+
+// Name implements experimental.SSABlock.Name.
+func (b *inspectedBlock) Name() string {
+	return b.bb.Name()
+}
+
+// Params implements experimental.SSABlock.Params.
+func (b *inspectedBlock) Params() []experimental.SSAValue {
+	ret := make([]experimental.SSAValue, len(b.bb.params))
+	for i := range b.bb.params {
+		ret[i] = &inspectedValue{f: b.f, v: b.bb.params[i].value}
+	}
+	return ret
+}
+
+// inspectedInstruction implements experimental.SSAInstruction.
+type inspectedInstruction struct {
+	f *inspectedFunction
+	i *Instruction
+}
+
+// Opcode implements experimental.SSAInstruction.Opcode.
+func (i *inspectedInstruction) Opcode() string {
+	return i.i.opcode.String()
+}
+
+// Format implements experimental.SSAInstruction.Format.
+func (i *inspectedInstruction) Format() string {
+	return i.i.Format(i.f.b)
+}
+
+// Args implements experimental.SSAInstruction.Args.
+func (i *inspectedInstruction) Args() (ret []experimental.SSAValue) {
+	v1, v2, v3, vs := i.i.Args()
+	for _, v := range [...]Value{v1, v2, v3} {
+		if v.Valid() {
+			ret = append(ret, &inspectedValue{f: i.f, v: v})
+		}
+	}
+	for _, v := range vs {
+		ret = append(ret, &inspectedValue{f: i.f, v: v})
+	}
+	return
+}
+
+// Results implements experimental.SSAInstruction.Results.
+func (i *inspectedInstruction) Results() (ret []experimental.SSAValue) {
+	r1, rs := i.i.Returns()
+	if r1.Valid() {
+		ret = append(ret, &inspectedValue{f: i.f, v: r1})
+	}
+	for _, r := range rs {
+		ret = append(ret, &inspectedValue{f: i.f, v: r})
+	}
+	return
+}
+
+// Constant implements experimental.SSAInstruction.Constant.
+func (i *inspectedInstruction) Constant() (bits uint64, ok bool) {
+	if !i.i.Constant() {
+		return 0, false
+	}
+	return i.i.ConstantVal(), true
+}
+
+// ReplaceWithConstant implements experimental.SSAInstruction.ReplaceWithConstant.
+func (i *inspectedInstruction) ReplaceWithConstant(bits uint64) bool {
+	return i.i.ReplaceWithConstant(bits)
+}
+
+// inspectedValue implements experimental.SSAValue.
+type inspectedValue struct {
+	f *inspectedFunction
+	v Value
+}
+
+// Format implements experimental.SSAValue.Format.
+func (v *inspectedValue) Format() string {
+	return v.v.Format(v.f.b)
+}
+
+// Type implements experimental.SSAValue.Type.
+func (v *inspectedValue) Type() string {
+	return v.v.Type().String()
+}
+
+// Definition implements experimental.SSAValue.Definition.
+func (v *inspectedValue) Definition() experimental.SSAInstruction {
+	if def, ok := v.f.definitions[v.v.ID()]; ok {
+		return &inspectedInstruction{f: v.f, i: def}
+	}
+	return nil
+}
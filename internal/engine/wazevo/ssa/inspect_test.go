@@ -0,0 +1,159 @@
+package ssa
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// newInspectTestFunction constructs a function which returns x + (1 + 2), where x is its parameter.
+func newInspectTestFunction() *builder {
+	b := NewBuilder().(*builder)
+	entry := b.AllocateBasicBlock()
+	b.SetCurrentBlock(entry)
+	x := entry.AddParam(b, TypeI32)
+	one := b.AllocateInstruction().AsIconst32(1).Insert(b)
+	two := b.AllocateInstruction().AsIconst32(2).Insert(b)
+	sum := b.AllocateInstruction().AsIadd(one.Return(), two.Return()).Insert(b)
+	add := b.AllocateInstruction().AsIadd(x, sum.Return()).Insert(b)
+	b.AllocateInstruction().AsReturn([]Value{add.Return()}).Insert(b)
+	b.Seal(entry)
+	return b
+}
+
+// foldConstants is an example of an external pass, which folds additions of constants.
+func foldConstants(fn experimental.SSAFunction) {
+	fn.Visit(func(_ experimental.SSABlock, inst experimental.SSAInstruction) {
+		if inst.Opcode() != "Iadd" {
+			return
+		}
+		var sum uint64
+		for _, arg := range inst.Args() {
+			def := arg.Definition()
+			if def == nil {
+				return
+			}
+			c, ok := def.Constant()
+			if !ok {
+				return
+			}
+			sum += c
+		}
+		inst.ReplaceWithConstant(sum)
+	})
+}
+
+func TestInspect(t *testing.T) {
+	b := newInspectTestFunction()
+
+	var phases []experimental.SSAPhase
+	var formats []string
+	inspector := experimental.SSAInspectorFunc(func(phase experimental.SSAPhase, fn experimental.SSAFunction) {
+		require.Equal(t, uint32(3), fn.Index())
+		phases = append(phases, phase)
+		formats = append(formats, fn.Format())
+		if phase == experimental.SSAPhaseLowered {
+			foldConstants(fn)
+			formats = append(formats, fn.Format())
+		}
+	})
+
+	Inspect(b, 3, experimental.SSAPhaseLowered, inspector)
+	b.RunPasses()
+	Inspect(b, 3, experimental.SSAPhaseOptimized, inspector)
+
+	require.Equal(t, []experimental.SSAPhase{experimental.SSAPhaseLowered, experimental.SSAPhaseOptimized}, phases)
+	require.Equal(t, []string{`
+blk0: (v0:i32)
+	v1:i32 = Iconst_32 0x1
+	v2:i32 = Iconst_32 0x2
+	v3:i32 = Iadd v1, v2
+	v4:i32 = Iadd v0, v3
+	Return v4
+`, `
+blk0: (v0:i32)
+	v1:i32 = Iconst_32 0x1
+	v2:i32 = Iconst_32 0x2
+	v3:i32 = Iconst_32 0x3
+	v4:i32 = Iadd v0, v3
+	Return v4
+`, `
+blk0: (v0:i32)
+	v3:i32 = Iconst_32 0x3
+	v4:i32 = Iadd v0, v3
+	Return v4
+`}, formats)
+}
+
+func TestInspect_visit(t *testing.T) {
+	b := newInspectTestFunction()
+
+	var visited []string
+	Inspect(b, 0, experimental.SSAPhaseLowered, experimental.SSAInspectorFunc(func(_ experimental.SSAPhase, fn experimental.SSAFunction) {
+		fn.Visit(func(block experimental.SSABlock, inst experimental.SSAInstruction) {
+			// Formatting while visiting must not interfere with the visit.
+			_ = fn.Format()
+
+			require.Equal(t, "blk0", block.Name())
+			params := block.Params()
+			require.Equal(t, 1, len(params))
+			require.Equal(t, "v0", params[0].Format())
+			require.Equal(t, "i32", params[0].Type())
+			require.Nil(t, params[0].Definition())
+
+			var args, results []string
+			for _, v := range inst.Args() {
+				args = append(args, v.Format())
+			}
+			for _, v := range inst.Results() {
+				results = append(results, v.Format()+":"+v.Type())
+				require.Equal(t, inst.Format(), v.Definition().Format())
+			}
+			visited = append(visited, fmt.Sprintf("%s %v %v", inst.Opcode(), args, results))
+		})
+	}))
+
+	require.Equal(t, []string{
+		"Iconst [] [v1:i32]",
+		"Iconst [] [v2:i32]",
+		"Iadd [v1 v2] [v3:i32]",
+		"Iadd [v0 v3] [v4:i32]",
+		"Return [v4] []",
+	}, visited)
+}
+
+func TestInstruction_ReplaceWithConstant(t *testing.T) {
+	b := NewBuilder().(*builder)
+	entry := b.AllocateBasicBlock()
+	b.SetCurrentBlock(entry)
+	x := entry.AddParam(b, TypeI64)
+	f := entry.AddParam(b, TypeF32)
+
+	add := b.AllocateInstruction().AsIadd(x, x).Insert(b)
+	require.True(t, add.ReplaceWithConstant(5))
+	require.Equal(t, "v2:i64 = Iconst_64 0x5", add.Format(b))
+	require.True(t, add.Constant())
+	require.Equal(t, uint64(5), add.ConstantVal())
+	v1, v2, _, _ := add.Args()
+	require.False(t, v1.Valid())
+	require.False(t, v2.Valid())
+
+	fadd := b.AllocateInstruction()
+	fadd.AsFadd(f, f)
+	b.InsertInstruction(fadd)
+	require.True(t, fadd.ReplaceWithConstant(uint64(math.Float32bits(1.5))))
+	require.Equal(t, OpcodeF32const, fadd.Opcode())
+	require.Equal(t, uint64(math.Float32bits(1.5)), fadd.ConstantVal())
+
+	// Instructions with side effects are not replaced.
+	div := b.AllocateInstruction().AsSDiv(x, x, Value(0)).Insert(b)
+	require.False(t, div.ReplaceWithConstant(0))
+	require.Equal(t, OpcodeSdiv, div.Opcode())
+
+	ret := b.AllocateInstruction().AsReturn([]Value{x}).Insert(b)
+	require.False(t, ret.ReplaceWithConstant(0))
+	require.Equal(t, OpcodeReturn, ret.Opcode())
+}
@@ -1780,6 +1780,38 @@ func (i *Instruction) ConstantVal() (ret uint64) {
 	return
 }
 
+// ReplaceWithConstant replaces this instruction with the constant instruction of the type of its only
+// result, keeping the result Value. `bits` is interpreted in the same way as ConstantVal.
+//
+// Returns false without any change if this instruction has side effects, or doesn't produce exactly one
+// integer or floating-point value.
+func (i *Instruction) ReplaceWithConstant(bits uint64) bool {
+	if i.sideEffect() != sideEffectNone || !i.rValue.Valid() || len(i.rValues) > 0 {
+		return false
+	}
+
+	typ := i.rValue.Type()
+	switch typ {
+	case TypeI32, TypeI64, TypeF32, TypeF64:
+	default:
+		return false
+	}
+
+	i.v, i.v2, i.v3 = ValueInvalid, ValueInvalid, ValueInvalid
+	i.vs, i.targets, i.u2 = nil, nil, 0
+	switch typ {
+	case TypeI32:
+		i.AsIconst32(uint32(bits))
+	case TypeI64:
+		i.AsIconst64(bits)
+	case TypeF32:
+		i.AsF32const(math.Float32frombits(uint32(bits)))
+	case TypeF64:
+		i.AsF64const(math.Float64frombits(bits))
+	}
+	return true
+}
+
 // String implements fmt.Stringer.
 func (o Opcode) String() (ret string) {
 	switch o {
@@ -14,6 +14,7 @@ import (
 // while the other passes are not, like passEstimateBranchProbabilities does not edit them, but only calculates the additional information.
 func (b *builder) RunPasses() {
 	passDeadBlockEliminationOpt(b)
+	passSelectLoweringOpt(b)
 	passRedundantPhiEliminationOpt(b)
 	// The result of passCalculateImmediateDominators will be used by various passes below.
 	passCalculateImmediateDominators(b)
@@ -303,3 +304,155 @@ func (b *builder) clearBlkVisited() {
 	}
 	b.blkStack2 = b.blkStack2[:0]
 }
+
+// selectLoweringMaxSpeculatedInstructions is the maximum count of instructions, excluding the jump,
+// in each arm of a diamond which passSelectLoweringOpt executes unconditionally.
+const selectLoweringMaxSpeculatedInstructions = 4
+
+// passSelectLoweringOpt converts small diamonds into Select instructions, which are lowered to
+// conditional selects (e.g. CSEL on arm64) instead of branches. For example,
+//
+//	blk0: ()
+//		Brz v0, blk2
+//		Jump blk1
+//	blk1: () <-- (blk0)
+//		v1:i32 = Iconst_32 0x1
+//		Jump blk3, v1
+//	blk2: () <-- (blk0)
+//		v2:i32 = Iconst_32 0x2
+//		Jump blk3, v2
+//
+// becomes
+//
+//	blk0: ()
+//		v1:i32 = Iconst_32 0x1
+//		v2:i32 = Iconst_32 0x2
+//		v4:i32 = Select v0, v1, v2
+//		Jump blk3, v4
+//
+// where both blk1 and blk2 are made invalid. This is only done when both arms are cheap and side effect free,
+// since both are executed, but it avoids branch mispredictions, which are costly on unpredictable conditions.
+//
+// This must run before passCalculateImmediateDominators, since this edits the CFG, and before
+// passRedundantPhiEliminationOpt, which removes the parameters of merge blocks left with a single predecessor.
+func passSelectLoweringOpt(b *builder) {
+	for blk := b.blockIteratorBegin(); blk != nil; blk = b.blockIteratorNext() {
+		tail := blk.currentInstr
+		if tail == nil || tail.opcode != OpcodeJump || len(tail.vs) != 0 {
+			continue
+		}
+		cond := tail.prev
+		if cond == nil || (cond.opcode != OpcodeBrz && cond.opcode != OpcodeBrnz) || len(cond.vs) != 0 {
+			continue
+		}
+
+		condArm, jumpArm := cond.blk.(*basicBlock), tail.blk.(*basicBlock)
+		if condArm == jumpArm || !isSpeculatableArm(condArm) || !isSpeculatableArm(jumpArm) {
+			continue
+		}
+		condJmp, jumpJmp := condArm.currentInstr, jumpArm.currentInstr
+		merge := condJmp.blk.(*basicBlock)
+		if merge != jumpJmp.blk || merge.ReturnBlock() || !isSelectable(condJmp.vs, jumpJmp.vs) {
+			continue
+		}
+
+		// Hoist the arms into blk, before the branches.
+		if blk.currentInstr = cond.prev; blk.currentInstr != nil {
+			blk.currentInstr.next = nil
+		} else {
+			blk.rootInstr = nil
+		}
+		for _, arm := range [2]*basicBlock{condArm, jumpArm} {
+			for cur := arm.rootInstr; cur != arm.currentInstr; {
+				next := cur.next
+				cur.prev, cur.next = nil, nil
+				blk.InsertInstruction(cur)
+				cur = next
+			}
+		}
+
+		// Brz branches to condArm when the condition is zero, and Select chooses the first value when it is not.
+		trueArgs, falseArgs := jumpJmp.vs, condJmp.vs
+		if cond.opcode == OpcodeBrnz {
+			trueArgs, falseArgs = condJmp.vs, jumpJmp.vs
+		}
+		for i := range trueArgs {
+			if x, y := trueArgs[i], falseArgs[i]; x != y {
+				sel := b.AllocateInstruction().AsSelect(cond.v, x, y)
+				sel.rValue = b.allocateValue(x.Type())
+				blk.InsertInstruction(sel)
+				trueArgs[i] = sel.rValue
+			}
+		}
+
+		// Reuse the jump of an arm to branch from blk to merge instead. This is linked directly, since
+		// InsertInstruction would add a predecessor to the sealed merge block.
+		jmp := condJmp
+		jmp.vs = trueArgs
+		jmp.prev, jmp.next = blk.currentInstr, nil
+		if blk.currentInstr != nil {
+			blk.currentInstr.next = jmp
+		} else {
+			blk.rootInstr = jmp
+		}
+		blk.currentInstr = jmp
+
+		preds := merge.preds[:0]
+		for _, pred := range merge.preds {
+			switch pred.blk {
+			case condArm:
+				preds = append(preds, basicBlockPredecessorInfo{blk: blk, branch: jmp})
+			case jumpArm:
+			default:
+				preds = append(preds, pred)
+			}
+		}
+		merge.preds = preds
+		blk.success = append(blk.success[:0], merge)
+		condArm.invalid, jumpArm.invalid = true, true
+	}
+}
+
+// isSpeculatableArm returns true if the block is an arm of a diamond which can be executed unconditionally:
+// it has a single predecessor, no parameters, and a few instructions without side effects followed by a jump.
+func isSpeculatableArm(blk *basicBlock) bool {
+	if len(blk.preds) != 1 || len(blk.params) != 0 {
+		return false
+	}
+	tail := blk.currentInstr
+	if tail == nil || tail.opcode != OpcodeJump {
+		return false
+	}
+	var n int
+	for cur := blk.rootInstr; cur != tail; cur = cur.next {
+		if n++; n > selectLoweringMaxSpeculatedInstructions {
+			return false
+		}
+		switch cur.opcode {
+		case OpcodeIconst, OpcodeF32const, OpcodeF64const,
+			OpcodeIadd, OpcodeIsub, OpcodeBand, OpcodeBor, OpcodeBxor,
+			OpcodeIshl, OpcodeSshr, OpcodeUshr, OpcodeIcmp,
+			OpcodeSExtend, OpcodeUExtend, OpcodeIreduce:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isSelectable returns true if some arguments differ between the arms, and all of them can be chosen by a Select.
+func isSelectable(xs, ys []Value) bool {
+	if len(xs) != len(ys) {
+		return false
+	}
+	var differ bool
+	for i := range xs {
+		if xs[i] != ys[i] {
+			if xs[i].Type() == TypeV128 {
+				return false
+			}
+			differ = true
+		}
+	}
+	return differ
+}
@@ -189,6 +189,89 @@ blk1: (v0:i32) <-- (blk0,blk1)
 
 blk2: () <-- (blk1)
 	Return
+`,
+		},
+		{
+			name: "select lowering",
+			pass: passSelectLoweringOpt,
+			setup: func(b *builder) func(*testing.T) {
+				entry, then, els, end := b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock(), b.AllocateBasicBlock()
+				cond, x := entry.AddParam(b, TypeI32), entry.AddParam(b, TypeI32)
+				end.AddParam(b, TypeI32)
+
+				b.SetCurrentBlock(entry)
+				{
+					brz := b.AllocateInstruction()
+					brz.AsBrz(cond, nil, els)
+					b.InsertInstruction(brz)
+
+					jmp := b.AllocateInstruction()
+					jmp.AsJump(nil, then)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(then)
+				{
+					one := b.AllocateInstruction().AsIconst32(1)
+					b.InsertInstruction(one)
+					add := b.AllocateInstruction().AsIadd(x, one.Return())
+					b.InsertInstruction(add)
+
+					jmp := b.AllocateInstruction()
+					jmp.AsJump([]Value{add.Return()}, end)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(els)
+				{
+					jmp := b.AllocateInstruction()
+					jmp.AsJump([]Value{x}, end)
+					b.InsertInstruction(jmp)
+				}
+
+				b.SetCurrentBlock(end)
+				{
+					ret := b.AllocateInstruction()
+					ret.AsReturn([]Value{end.Param(0)})
+					b.InsertInstruction(ret)
+				}
+
+				b.Seal(entry)
+				b.Seal(then)
+				b.Seal(els)
+				b.Seal(end)
+				return func(t *testing.T) {
+					require.False(t, then.Valid())
+					require.False(t, els.Valid())
+					require.Equal(t, 1, end.Preds())
+					require.Equal(t, entry, end.Pred(0))
+				}
+			},
+			before: `
+blk0: (v0:i32, v1:i32)
+	Brz v0, blk2
+	Jump blk1
+
+blk1: () <-- (blk0)
+	v3:i32 = Iconst_32 0x1
+	v4:i32 = Iadd v1, v3
+	Jump blk3, v4
+
+blk2: () <-- (blk0)
+	Jump blk3, v1
+
+blk3: (v2:i32) <-- (blk1,blk2)
+	Return v2
+`,
+			after: `
+blk0: (v0:i32, v1:i32)
+	v3:i32 = Iconst_32 0x1
+	v4:i32 = Iadd v1, v3
+	v5:i32 = Select v0, v4, v1
+	Jump blk3, v5
+
+blk3: (v2:i32) <-- (blk0)
+	Return v2
 `,
 		},
 		{
@@ -294,6 +294,25 @@ var (
 			wasm.OpcodeEnd,
 		}, []wasm.ValueType{i32}),
 	}
+	SelectDiamond = TestCase{
+		Name: "select_diamond",
+		Module: SingleFunctionModule(i32i32_i32, []byte{
+			// Returns (x < y) ? x + 1 : y - 1, where both arms are cheap enough to be lowered as a select.
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32LtS,
+			wasm.OpcodeIf, i32,
+			wasm.OpcodeLocalGet, 0,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Add,
+			wasm.OpcodeElse,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeI32Const, 1,
+			wasm.OpcodeI32Sub,
+			wasm.OpcodeEnd,
+			wasm.OpcodeEnd,
+		}, nil),
+	}
 	ReferenceValueFromUnsealedBlock = TestCase{
 		Name: "reference_value_from_unsealed_block",
 		Module: SingleFunctionModule(i32_i32, []byte{
@@ -19,6 +19,9 @@ const (
 	ExitCodeIntegerDivisionByZero
 	ExitCodeIntegerOverflow
 	ExitCodeInvalidConversionToInteger
+	// ExitCodeCheckModuleExitCode is an exit code for checking if the module is closed, which is done
+	// periodically in loops when the module is compiled to ensure termination or to yield cooperatively.
+	ExitCodeCheckModuleExitCode
 	exitCodeMax
 )
 
@@ -26,7 +29,7 @@ const ExitCodeMask = 0xff
 
 // String implements fmt.Stringer.
 func (e ExitCode) String() string {
-	switch e {
+	switch e & ExitCodeMask {
 	case ExitCodeOK:
 		return "ok"
 	case ExitCodeGrowStack:
@@ -51,6 +54,8 @@ func (e ExitCode) String() string {
 		return "integer_overflow"
 	case ExitCodeInvalidConversionToInteger:
 		return "invalid_conversion_to_integer"
+	case ExitCodeCheckModuleExitCode:
+		return "check_module_exit_code"
 	}
 	panic("TODO")
 }
@@ -66,3 +71,13 @@ func ExitCodeCallGoFunctionWithIndex(index int) ExitCode {
 func GoFunctionIndexFromExitCode(exitCode ExitCode) int {
 	return int(exitCode >> 8)
 }
+
+// ExitCodeMemoryOutOfBoundsWithSize returns ExitCodeMemoryOutOfBounds for a memory access of `size` bytes.
+func ExitCodeMemoryOutOfBoundsWithSize(size uint64) ExitCode {
+	return ExitCodeMemoryOutOfBounds | ExitCode(size<<8)
+}
+
+// MemoryAccessSizeFromExitCode returns the size of the memory access of ExitCodeMemoryOutOfBoundsWithSize.
+func MemoryAccessSizeFromExitCode(exitCode ExitCode) uint64 {
+	return uint64(exitCode >> 8)
+}
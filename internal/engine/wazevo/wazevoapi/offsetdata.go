@@ -25,9 +25,11 @@ var ExecutionContextOffsets = ExecutionContextOffsetData{
 	StackGrowRequiredSize:                   64,
 	MemoryGrowTrampolineAddress:             72,
 	StackGrowCallSequenceAddress:            80,
+	CheckModuleExitCodeTrampolineAddress:    88,
 	SavedRegistersBegin:                     96,
 	GoFunctionCallCalleeModuleContextOpaque: 1120,
 	GoFunctionCallStackBegin:                1128,
+	MemoryOutOfBoundsAddress:                2152,
 }
 
 // ExecutionContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.executionContext,
@@ -55,12 +57,16 @@ type ExecutionContextOffsetData struct {
 	MemoryGrowTrampolineAddress Offset
 	// StackGrowCallSequenceAddress is an offset of `stackGrowCallSequenceAddress` field in wazevo.executionContext
 	StackGrowCallSequenceAddress Offset
+	// CheckModuleExitCodeTrampolineAddress is an offset of `checkModuleExitCodeTrampolineAddress` field in wazevo.executionContext
+	CheckModuleExitCodeTrampolineAddress Offset
 	// GoCallReturnAddress is an offset of the first element of `savedRegisters` field in wazevo.executionContext
 	SavedRegistersBegin Offset
 	// GoFunctionCallCalleeModuleContextOpaque is an offset of `goFunctionCallCalleeModuleContextOpaque` field in wazevo.executionContext
 	GoFunctionCallCalleeModuleContextOpaque Offset
 	// GoFunctionCallStackBegin is an offset of the first element of `goFunctionCallStack` field in wazevo.executionContext
 	GoFunctionCallStackBegin Offset
+	// MemoryOutOfBoundsAddress is an offset of `memoryOutOfBoundsAddress` field in wazevo.executionContext
+	MemoryOutOfBoundsAddress Offset
 }
 
 // ModuleContextOffsetData allows the compilers to get the information about offsets to the fields of wazevo.moduleContextOpaque,
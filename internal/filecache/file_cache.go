@@ -6,7 +6,9 @@ import (
 	"io"
 	"os"
 	"path"
+	"sort"
 	"sync"
+	"time"
 )
 
 // New returns a new Cache implemented by fileCache.
@@ -14,18 +16,41 @@ func New(dir string) Cache {
 	return newFileCache(dir)
 }
 
+// NewWithLimits is like New, except the least recently used entries are
+// deleted when adding an entry exceeds `maxBytes` or `maxEntries`. A limit of
+// zero is not enforced.
+func NewWithLimits(dir string, maxBytes int64, maxEntries int) Cache {
+	fc := newFileCache(dir)
+	fc.maxBytes, fc.maxEntries = maxBytes, maxEntries
+	return fc
+}
+
 func newFileCache(dir string) *fileCache {
 	return &fileCache{dirPath: dir}
 }
 
 // fileCache persists compiled functions into dirPath.
 //
+// Entries are written to a temporary file and renamed, so that readers never
+// see a partial entry. Mutations take an exclusive lock on lockFileName,
+// which allows processes to share the directory.
+//
+// The modification time of an entry is updated when it is read, which makes
+// it the time of last use for eviction.
+//
 // Note: this can be expanded to do binary signing/verification, set TTL on each entry, etc.
 type fileCache struct {
 	dirPath string
 	mux     sync.RWMutex
+
+	// maxBytes and maxEntries bound the directory when positive.
+	maxBytes   int64
+	maxEntries int
 }
 
+// lockFileName is the file in dirPath locked by lockDir.
+const lockFileName = ".lock"
+
 type fileReadCloser struct {
 	*os.File
 	fc *fileCache
@@ -35,6 +60,29 @@ func (fc *fileCache) path(key Key) string {
 	return path.Join(fc.dirPath, hex.EncodeToString(key[:]))
 }
 
+// lockDir locks the directory against other processes until the result is
+// called.
+//
+// A shared lock is best efforts: when the lock file can't be created, e.g.
+// as the directory is read-only, reading proceeds without it.
+func (fc *fileCache) lockDir(exclusive bool) (unlock func(), err error) {
+	name := path.Join(fc.dirPath, lockFileName)
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil && !exclusive {
+		if f, err = os.Open(name); err != nil {
+			return func() {}, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(f, exclusive); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return func() { _ = f.Close() }, nil
+}
+
 func (fc *fileCache) Get(key Key) (content io.ReadCloser, ok bool, err error) {
 	// TODO: take lock per key for more efficiency vs the complexity of impl.
 	fc.mux.RLock()
@@ -45,12 +93,24 @@ func (fc *fileCache) Get(key Key) (content io.ReadCloser, ok bool, err error) {
 		}
 	}()
 
-	f, err := os.Open(fc.path(key))
+	unlockDir, err := fc.lockDir(false)
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlockDir()
+
+	p := fc.path(key)
+	f, err := os.Open(p)
 	if errors.Is(err, os.ErrNotExist) {
 		return nil, false, nil
 	} else if err != nil {
 		return nil, false, err
 	} else {
+		// Mark the entry as recently used. This is best efforts, as the
+		// directory may be read-only.
+		now := time.Now()
+		_ = os.Chtimes(p, now, now)
+
 		// Unlock is done inside the content.Close() at the call site.
 		unlock = nil
 		return &fileReadCloser{File: f, fc: fc}, true, nil
@@ -65,17 +125,44 @@ func (f *fileReadCloser) Close() (err error) {
 }
 
 func (fc *fileCache) Add(key Key, content io.Reader) (err error) {
+	_, err = fc.add(key, content)
+	return
+}
+
+// add implements evictingCache.
+func (fc *fileCache) add(key Key, content io.Reader) (evicted []Key, err error) {
+	// Write the content before taking locks, as it can be large.
+	tmp, err := os.CreateTemp(fc.dirPath, ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	_, err = io.Copy(tmp, content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
 	// TODO: take lock per key for more efficiency vs the complexity of impl.
 	fc.mux.Lock()
 	defer fc.mux.Unlock()
 
-	file, err := os.Create(fc.path(key))
+	unlockDir, err := fc.lockDir(true)
 	if err != nil {
 		return
 	}
-	defer file.Close()
-	_, err = io.Copy(file, content)
-	return
+	defer unlockDir()
+
+	if err = os.Rename(tmp.Name(), fc.path(key)); err != nil {
+		return
+	}
+	return fc.evict()
 }
 
 func (fc *fileCache) Delete(key Key) (err error) {
@@ -83,9 +170,116 @@ func (fc *fileCache) Delete(key Key) (err error) {
 	fc.mux.Lock()
 	defer fc.mux.Unlock()
 
+	unlockDir, err := fc.lockDir(true)
+	if err != nil {
+		return
+	}
+	defer unlockDir()
+
 	err = os.Remove(fc.path(key))
 	if errors.Is(err, os.ErrNotExist) {
 		err = nil
 	}
 	return
 }
+
+// Purge deletes the entries for which `keep` returns false. `keep` is called
+// with the hex encoded key of each entry and the time it was last used.
+func (fc *fileCache) Purge(keep func(key string, lastUsed time.Time) bool) error {
+	fc.mux.Lock()
+	defer fc.mux.Unlock()
+
+	unlockDir, err := fc.lockDir(true)
+	if err != nil {
+		return err
+	}
+	defer unlockDir()
+
+	entries, err := fc.entries()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !keep(e.name, e.lastUsed) {
+			if err = os.Remove(path.Join(fc.dirPath, e.name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evict deletes the least recently used entries while the directory exceeds
+// the limits, and returns their keys. This must be called with the exclusive
+// locks held.
+func (fc *fileCache) evict() (evicted []Key, err error) {
+	if fc.maxBytes <= 0 && fc.maxEntries <= 0 {
+		return
+	}
+	entries, err := fc.entries()
+	if err != nil {
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastUsed.Before(entries[j].lastUsed) })
+
+	var size int64
+	for _, e := range entries {
+		size += e.size
+	}
+	count := len(entries)
+	for _, e := range entries {
+		if (fc.maxEntries <= 0 || count <= fc.maxEntries) && (fc.maxBytes <= 0 || size <= fc.maxBytes) {
+			break
+		}
+		// Windows can't delete an entry which is being read, so skip it.
+		if err = os.Remove(path.Join(fc.dirPath, e.name)); err == nil {
+			count--
+			size -= e.size
+			var key Key
+			_, _ = hex.Decode(key[:], []byte(e.name)) // entries only returns valid keys.
+			evicted = append(evicted, key)
+		} else if errors.Is(err, os.ErrNotExist) {
+			count--
+			size -= e.size
+		}
+	}
+	return evicted, nil
+}
+
+type entry struct {
+	name     string
+	size     int64
+	lastUsed time.Time
+}
+
+// entries returns the entries in the directory, skipping other files such as
+// the lock file and temporary files.
+func (fc *fileCache) entries() ([]entry, error) {
+	dirents, err := os.ReadDir(fc.dirPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(dirents))
+	for _, d := range dirents {
+		if !isKey(d.Name()) || !d.Type().IsRegular() {
+			continue
+		}
+		info, err := d.Info()
+		if errors.Is(err, os.ErrNotExist) {
+			continue // deleted concurrently
+		} else if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{name: d.Name(), size: info.Size(), lastUsed: info.ModTime()})
+	}
+	return entries, nil
+}
+
+// isKey returns true if `name` is the hex encoding of a Key.
+func isKey(name string) bool {
+	if len(name) != hex.EncodedLen(len(Key{})) {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
@@ -4,9 +4,13 @@ package filecache
 
 import (
 	"bytes"
+	"encoding/hex"
 	"io"
 	"os"
+	"path"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -136,6 +140,86 @@ func TestFileCache_Get(t *testing.T) {
 	})
 }
 
+func TestFileCache_evict(t *testing.T) {
+	// add adds an entry of `size` bytes last used `age` ago.
+	add := func(t *testing.T, fc *fileCache, key Key, size int, age time.Duration) {
+		require.NoError(t, fc.Add(key, bytes.NewReader(make([]byte, size))))
+		lastUsed := time.Now().Add(-age)
+		require.NoError(t, os.Chtimes(fc.path(key), lastUsed, lastUsed))
+	}
+	exists := func(fc *fileCache, key Key) bool {
+		_, err := os.Stat(fc.path(key))
+		return err == nil
+	}
+
+	t.Run("maxEntries", func(t *testing.T) {
+		fc := NewWithLimits(t.TempDir(), 0, 2).(*fileCache)
+		add(t, fc, Key{1}, 1, 3*time.Hour)
+		add(t, fc, Key{2}, 1, 2*time.Hour)
+
+		// Reading an entry makes it the most recently used.
+		c, ok, err := fc.Get(Key{1})
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.NoError(t, c.Close())
+
+		add(t, fc, Key{3}, 1, 0)
+		require.True(t, exists(fc, Key{1}))
+		require.False(t, exists(fc, Key{2}))
+		require.True(t, exists(fc, Key{3}))
+	})
+
+	t.Run("maxBytes", func(t *testing.T) {
+		fc := NewWithLimits(t.TempDir(), 10, 0).(*fileCache)
+		add(t, fc, Key{1}, 4, 3*time.Hour)
+		add(t, fc, Key{2}, 4, 2*time.Hour)
+		add(t, fc, Key{3}, 4, time.Hour)
+		require.False(t, exists(fc, Key{1}))
+		require.True(t, exists(fc, Key{2}))
+		require.True(t, exists(fc, Key{3}))
+	})
+
+	t.Run("ignores other files", func(t *testing.T) {
+		fc := NewWithLimits(t.TempDir(), 0, 1).(*fileCache)
+		require.NoError(t, os.WriteFile(path.Join(fc.dirPath, "other"), nil, 0o600))
+		add(t, fc, Key{1}, 1, time.Hour)
+		add(t, fc, Key{2}, 1, 0)
+		require.False(t, exists(fc, Key{1}))
+		require.True(t, exists(fc, Key{2}))
+
+		_, err := os.Stat(path.Join(fc.dirPath, "other"))
+		require.NoError(t, err)
+		_, err = os.Stat(path.Join(fc.dirPath, lockFileName))
+		require.NoError(t, err)
+	})
+}
+
+func TestFileCache_Purge(t *testing.T) {
+	fc := newFileCache(t.TempDir())
+	require.NoError(t, fc.Add(Key{1}, bytes.NewReader([]byte{1})))
+	require.NoError(t, fc.Add(Key{2}, bytes.NewReader([]byte{2})))
+
+	var purged []string
+	err := fc.Purge(func(key string, lastUsed time.Time) bool {
+		require.False(t, lastUsed.IsZero())
+		if key == hex.EncodeToString([]byte{2})+strings.Repeat("00", len(Key{})-1) {
+			purged = append(purged, key)
+			return false
+		}
+		return true
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(purged))
+
+	_, ok, err := fc.Get(Key{2})
+	require.NoError(t, err)
+	require.False(t, ok)
+	c, ok, err := fc.Get(Key{1})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, c.Close())
+}
+
 func TestFileCache_path(t *testing.T) {
 	fc := &fileCache{dirPath: "/tmp/.wazero"}
 	actual := fc.path(Key{1, 2, 3, 4, 5})
@@ -0,0 +1,50 @@
+package filecache
+
+import (
+	"context"
+	"io"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// ReasonStale is the experimental.CompilationCacheEvent Reason of an entry
+// evicted because it can't be used by this version of wazero.
+const ReasonStale = "written by a different version of wazero"
+
+// ReasonLimit is the experimental.CompilationCacheEvent Reason of an entry
+// evicted to keep the cache within its limits.
+const ReasonLimit = "exceeded the limits of the cache"
+
+// evictingCache is implemented by a Cache which evicts entries to stay within
+// its limits, so that Add can notify of them.
+type evictingCache interface {
+	add(key Key, content io.Reader) (evicted []Key, err error)
+}
+
+// Add is the same as Cache.Add, except the experimental.CompilationCacheListener
+// in `ctx`, if any, is notified of the entries evicted to make room for `key`.
+func Add(ctx context.Context, c Cache, key Key, content io.Reader) error {
+	ec, ok := c.(evictingCache)
+	if !ok {
+		return c.Add(key, content)
+	}
+	evicted, err := ec.add(key, content)
+	for _, k := range evicted {
+		Notify(ctx, experimental.CompilationCacheEventEvict, k, false, ReasonLimit, nil)
+	}
+	return err
+}
+
+// Notify calls the experimental.CompilationCacheListener in `ctx`, if any,
+// with an event of type `t` for `key`.
+func Notify(ctx context.Context, t experimental.CompilationCacheEventType, key Key, inMemory bool, reason string, err error) {
+	if l, ok := ctx.Value(experimental.CompilationCacheListenerKey{}).(experimental.CompilationCacheListener); ok && l != nil {
+		l.OnCompilationCacheEvent(ctx, experimental.CompilationCacheEvent{
+			Type:     t,
+			Key:      key,
+			InMemory: inMemory,
+			Reason:   reason,
+			Err:      err,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package filecache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestAdd(t *testing.T) {
+	var events []experimental.CompilationCacheEvent
+	ctx := experimental.WithCompilationCacheListener(context.Background(),
+		experimental.CompilationCacheListenerFunc(func(_ context.Context, e experimental.CompilationCacheEvent) {
+			events = append(events, e)
+		}))
+	evict := func(key Key) experimental.CompilationCacheEvent {
+		return experimental.CompilationCacheEvent{Type: experimental.CompilationCacheEventEvict, Key: key, Reason: ReasonLimit}
+	}
+
+	tests := []struct {
+		name     string
+		cache    Cache
+		expected []experimental.CompilationCacheEvent
+	}{
+		{
+			name:     "memory",
+			cache:    NewMemory(8),
+			expected: []experimental.CompilationCacheEvent{evict(Key{1})},
+		},
+		{
+			name:     "dir maxBytes",
+			cache:    NewWithLimits(t.TempDir(), 8, 0),
+			expected: []experimental.CompilationCacheEvent{evict(Key{1})},
+		},
+		{
+			name:     "dir maxEntries",
+			cache:    NewWithLimits(t.TempDir(), 0, 1),
+			expected: []experimental.CompilationCacheEvent{evict(Key{1}), evict(Key{2})},
+		},
+		{
+			name:  "dir without limits",
+			cache: New(t.TempDir()),
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			events = nil
+			for i, key := range []Key{{1}, {2}, {3}} {
+				require.NoError(t, Add(ctx, tc.cache, key, bytes.NewReader(make([]byte, 4))))
+				if fc, ok := tc.cache.(*fileCache); ok {
+					// Make the order of last use independent of the resolution of the clock.
+					lastUsed := time.Now().Add(time.Duration(i-3) * time.Hour)
+					require.NoError(t, os.Chtimes(fc.path(key), lastUsed, lastUsed))
+				}
+			}
+			require.Equal(t, tc.expected, events)
+		})
+	}
+}
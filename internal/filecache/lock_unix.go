@@ -0,0 +1,22 @@
+//go:build darwin || linux || freebsd
+
+package filecache
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on `f`, which is released when `f` is
+// closed.
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		if err := syscall.Flock(int(f.Fd()), how); err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package filecache
+
+import "os"
+
+// lockFile does nothing, as file locks aren't supported on this platform.
+// Concurrent processes must not share the directory.
+func lockFile(*os.File, bool) error {
+	return nil
+}
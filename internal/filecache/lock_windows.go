@@ -0,0 +1,33 @@
+package filecache
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// _LOCKFILE_EXCLUSIVE_LOCK is a flag of LockFileEx.
+const _LOCKFILE_EXCLUSIVE_LOCK = 0x2 //nolint
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// lockFile takes a lock on `f`, which is released when `f` is closed.
+func lockFile(f *os.File, exclusive bool) error {
+	var flags uintptr
+	if exclusive {
+		flags = _LOCKFILE_EXCLUSIVE_LOCK
+	}
+	var overlapped syscall.Overlapped
+	r, _, err := syscall.SyscallN(
+		procLockFileEx.Addr(),
+		f.Fd(),                               // [in]      HANDLE       hFile,
+		flags,                                // [in]      DWORD        dwFlags,
+		0,                                    //           DWORD        dwReserved,
+		1,                                    // [in]      DWORD        nNumberOfBytesToLockLow,
+		0,                                    // [in]      DWORD        nNumberOfBytesToLockHigh,
+		uintptr(unsafe.Pointer(&overlapped))) // [in, out] LPOVERLAPPED lpOverlapped
+	if r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package filecache
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"io"
+	"sync"
+	"time"
+)
+
+// NewMemory returns a new Cache which holds entries in memory, up to a total
+// of `maxBytes`. When adding an entry exceeds it, the least recently used
+// entries are deleted.
+func NewMemory(maxBytes int64) Cache {
+	return newMemoryCache(maxBytes)
+}
+
+func newMemoryCache(maxBytes int64) *memoryCache {
+	return &memoryCache{maxBytes: maxBytes, entries: map[Key]*list.Element{}}
+}
+
+// memoryCache is a Cache which is bounded by the size of its entries, for
+// use when there is no directory to persist them.
+type memoryCache struct {
+	mux      sync.Mutex
+	maxBytes int64
+	size     int64
+
+	// lru holds *memoryEntry, from the most recently used to the least.
+	lru     list.List
+	entries map[Key]*list.Element
+}
+
+type memoryEntry struct {
+	key      Key
+	content  []byte
+	lastUsed time.Time
+}
+
+func (mc *memoryCache) Get(key Key) (content io.ReadCloser, ok bool, err error) {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	e, ok := mc.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	mc.lru.MoveToFront(e)
+	entry := e.Value.(*memoryEntry)
+	entry.lastUsed = time.Now()
+	// Entries are never modified, so the content can be read without a lock.
+	return io.NopCloser(bytes.NewReader(entry.content)), true, nil
+}
+
+func (mc *memoryCache) Add(key Key, content io.Reader) (err error) {
+	_, err = mc.add(key, content)
+	return
+}
+
+// add implements evictingCache.
+func (mc *memoryCache) add(key Key, content io.Reader) (evicted []Key, err error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return
+	}
+
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	mc.remove(key)
+	if int64(len(b)) > mc.maxBytes {
+		return // Don't evict everything for an entry which can't fit.
+	}
+	mc.entries[key] = mc.lru.PushFront(&memoryEntry{key: key, content: b, lastUsed: time.Now()})
+	mc.size += int64(len(b))
+	for mc.size > mc.maxBytes {
+		lru := mc.lru.Back().Value.(*memoryEntry).key
+		mc.remove(lru)
+		evicted = append(evicted, lru)
+	}
+	return
+}
+
+func (mc *memoryCache) Delete(key Key) (err error) {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	mc.remove(key)
+	return
+}
+
+// Purge is the same as fileCache.Purge.
+func (mc *memoryCache) Purge(keep func(key string, lastUsed time.Time) bool) error {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+
+	for key, e := range mc.entries {
+		if !keep(hex.EncodeToString(key[:]), e.Value.(*memoryEntry).lastUsed) {
+			mc.remove(key)
+		}
+	}
+	return nil
+}
+
+// remove deletes the entry of `key`, if any. This must be called with the
+// lock held.
+func (mc *memoryCache) remove(key Key) {
+	if e, ok := mc.entries[key]; ok {
+		mc.lru.Remove(e)
+		delete(mc.entries, key)
+		mc.size -= int64(len(e.Value.(*memoryEntry).content))
+	}
+}
@@ -0,0 +1,55 @@
+package filecache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	mc := newMemoryCache(10)
+	get := func(key Key) []byte {
+		c, ok, err := mc.Get(key)
+		require.NoError(t, err)
+		if !ok {
+			return nil
+		}
+		defer c.Close()
+		b, err := io.ReadAll(c)
+		require.NoError(t, err)
+		return b
+	}
+
+	require.NoError(t, mc.Add(Key{1}, bytes.NewReader([]byte{1, 1, 1, 1})))
+	require.NoError(t, mc.Add(Key{2}, bytes.NewReader([]byte{2, 2, 2, 2})))
+	require.Equal(t, []byte{1, 1, 1, 1}, get(Key{1})) // Key{2} is now the least recently used.
+
+	require.NoError(t, mc.Add(Key{3}, bytes.NewReader([]byte{3, 3, 3, 3})))
+	require.Equal(t, []byte{1, 1, 1, 1}, get(Key{1}))
+	require.Nil(t, get(Key{2}))
+	require.Equal(t, []byte{3, 3, 3, 3}, get(Key{3}))
+	require.Equal(t, int64(8), mc.size)
+
+	// An entry larger than the limit is not added.
+	require.NoError(t, mc.Add(Key{4}, bytes.NewReader(make([]byte, 11))))
+	require.Nil(t, get(Key{4}))
+	require.Equal(t, 2, len(mc.entries))
+
+	// Replacing an entry updates the size.
+	require.NoError(t, mc.Add(Key{3}, bytes.NewReader([]byte{3})))
+	require.Equal(t, []byte{3}, get(Key{3}))
+	require.Equal(t, int64(5), mc.size)
+
+	require.NoError(t, mc.Delete(Key{3}))
+	require.NoError(t, mc.Delete(Key{3}))
+	require.Nil(t, get(Key{3}))
+	require.Equal(t, int64(4), mc.size)
+
+	require.NoError(t, mc.Purge(func(string, time.Time) bool { return false }))
+	require.Nil(t, get(Key{1}))
+	require.Equal(t, int64(0), mc.size)
+	require.Equal(t, 0, mc.lru.Len())
+}
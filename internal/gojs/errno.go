@@ -45,6 +45,8 @@ var (
 	ErrnoNametoolong = &Errno{"ENAMETOOLONG"}
 	// ErrnoNoent No such file or directory.
 	ErrnoNoent = &Errno{"ENOENT"}
+	// ErrnoNospc No space left on device.
+	ErrnoNospc = &Errno{"ENOSPC"}
 	// ErrnoNosys function not supported.
 	ErrnoNosys = &Errno{"ENOSYS"}
 	// ErrnoNotdir Not a directory or a symbolic link to a directory.
@@ -53,8 +55,12 @@ var (
 	ErrnoNotempty = &Errno{"ENOTEMPTY"}
 	// ErrnoNotsup Not supported, or operation not supported on socket.
 	ErrnoNotsup = &Errno{"ENOTSUP"}
+	// ErrnoNotty Inappropriate I/O control operation.
+	ErrnoNotty = &Errno{"ENOTTY"}
 	// ErrnoPerm Operation not permitted.
 	ErrnoPerm = &Errno{"EPERM"}
+	// ErrnoPipe Broken pipe.
+	ErrnoPipe = &Errno{"EPIPE"}
 	// ErrnoRofs read-only file system.
 	ErrnoRofs = &Errno{"EROFS"}
 )
@@ -94,6 +100,8 @@ func ToErrno(err error) *Errno {
 		return ErrnoNametoolong
 	case sys.ENOENT:
 		return ErrnoNoent
+	case sys.ENOSPC:
+		return ErrnoNospc
 	case sys.ENOSYS:
 		return ErrnoNosys
 	case sys.ENOTDIR:
@@ -102,8 +110,12 @@ func ToErrno(err error) *Errno {
 		return ErrnoNotempty
 	case sys.ENOTSUP:
 		return ErrnoNotsup
+	case sys.ENOTTY:
+		return ErrnoNotty
 	case sys.EPERM:
 		return ErrnoPerm
+	case sys.EPIPE:
+		return ErrnoPipe
 	case sys.EROFS:
 		return ErrnoRofs
 	default:
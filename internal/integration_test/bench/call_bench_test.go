@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental/typedcall"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// addWasm exports "add" which returns the sum of its two i64 params.
+var addWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64}}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI64Add, wasm.OpcodeEnd,
+	}}},
+	ExportSection: []wasm.Export{{Name: "add", Type: wasm.ExternTypeFunc, Index: 0}},
+})
+
+const i64 = wasm.ValueTypeI64
+
+// BenchmarkFunctionCall measures the overhead of calling a trivial Wasm function from Go,
+// which dominates when a host calls into the guest millions of times per second.
+func BenchmarkFunctionCall(b *testing.B) {
+	b.Run("interpreter", func(b *testing.B) {
+		runFunctionCallBench(b, wazero.NewRuntimeConfigInterpreter())
+	})
+	if platform.CompilerSupported() {
+		b.Run("compiler", func(b *testing.B) {
+			runFunctionCallBench(b, wazero.NewRuntimeConfigCompiler())
+		})
+	}
+}
+
+func runFunctionCallBench(b *testing.B, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config)
+	defer r.Close(testCtx)
+
+	m, err := r.Instantiate(testCtx, addWasm)
+	if err != nil {
+		b.Fatal(err)
+	}
+	add := m.ExportedFunction("add")
+
+	b.Run("Call", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			res, err := add.Call(testCtx, uint64(i), 1)
+			if err != nil {
+				b.Fatal(err)
+			} else if res[0] != uint64(i)+1 {
+				b.Fatal(res[0])
+			}
+		}
+	})
+
+	b.Run("CallWithStack", func(b *testing.B) {
+		b.ReportAllocs()
+		stack := make([]uint64, 2)
+		for i := 0; i < b.N; i++ {
+			stack[0], stack[1] = uint64(i), 1
+			if err := add.CallWithStack(testCtx, stack); err != nil {
+				b.Fatal(err)
+			} else if stack[0] != uint64(i)+1 {
+				b.Fatal(stack[0])
+			}
+		}
+	})
+
+	b.Run("typedcall", func(b *testing.B) {
+		b.ReportAllocs()
+		typedAdd, err := typedcall.I64I64ToI64(add)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if res, err := typedAdd(testCtx, uint64(i), 1); err != nil {
+				b.Fatal(err)
+			} else if res != uint64(i)+1 {
+				b.Fatal(res)
+			}
+		}
+	})
+
+	b.Run("CallContext", func(b *testing.B) {
+		b.ReportAllocs()
+		cc := typedcall.NewCallContext(add)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			params := cc.Params()
+			params[0], params[1] = uint64(i), 1
+			if res, err := cc.CallEx(testCtx); err != nil {
+				b.Fatal(err)
+			} else if res[0] != uint64(i)+1 {
+				b.Fatal(res[0])
+			}
+		}
+	})
+}
+
+// BenchmarkExportedFunction measures looking up and calling an exported
+// function from concurrent goroutines. This includes counting the call for
+// wazero.Runtime Shutdown, in a counter shared across the runtime.
+func BenchmarkExportedFunction(b *testing.B) {
+	b.Run("interpreter", func(b *testing.B) {
+		runExportedFunctionBench(b, wazero.NewRuntimeConfigInterpreter())
+	})
+	if platform.CompilerSupported() {
+		b.Run("compiler", func(b *testing.B) {
+			runExportedFunctionBench(b, wazero.NewRuntimeConfigCompiler())
+		})
+	}
+}
+
+func runExportedFunctionBench(b *testing.B, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config)
+	defer r.Close(testCtx)
+
+	m, err := r.Instantiate(testCtx, addWasm)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		stack := make([]uint64, 2)
+		for pb.Next() {
+			stack[0], stack[1] = 1, 1
+			if err := m.ExportedFunction("add").CallWithStack(testCtx, stack); err != nil {
+				b.Fatal(err)
+			} else if stack[0] != 2 {
+				b.Fatal(stack[0])
+			}
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package bench
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/intrinsics"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// mathWasm exports "ops", which runs float instructions in a loop, and
+// "libm", which calls "sqrt" in a loop. "sqrt" is implemented in software,
+// like libm functions are in guests compiled without builtins.
+var mathWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{f64}},
+		{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{f64}},
+	},
+	FunctionSection: []wasm.Index{0, 1, 1},
+	CodeSection: []wasm.Code{
+		{
+			// sqrt(x) with 16 iterations of Newton's method: y = (y + x/y) * 0.5
+			LocalTypes: []wasm.ValueType{f64, i32},
+			Body: concat(
+				[]byte{
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalSet, 1,
+					wasm.OpcodeI32Const, 16, wasm.OpcodeLocalSet, 2,
+					wasm.OpcodeLoop, 0x40,
+					wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeF64Div, wasm.OpcodeF64Add,
+				},
+				f64Const(0.5),
+				[]byte{
+					wasm.OpcodeF64Mul, wasm.OpcodeLocalSet, 1,
+				},
+				decrementAndLoop(2),
+				[]byte{wasm.OpcodeEnd, wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd},
+			),
+		},
+		{
+			// ops(n): acc = nearest(trunc(ceil(floor((acc + sqrt(n)) * 0.5)))), n times.
+			LocalTypes: []wasm.ValueType{f64},
+			Body: concat(
+				[]byte{
+					wasm.OpcodeLoop, 0x40,
+					wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 0, wasm.OpcodeF64ConvertI32U, wasm.OpcodeF64Sqrt, wasm.OpcodeF64Add,
+				},
+				f64Const(0.5),
+				[]byte{
+					wasm.OpcodeF64Mul, wasm.OpcodeF64Floor, wasm.OpcodeF64Ceil, wasm.OpcodeF64Trunc, wasm.OpcodeF64Nearest,
+					wasm.OpcodeLocalSet, 1,
+				},
+				decrementAndLoop(0),
+				[]byte{wasm.OpcodeEnd, wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd},
+			),
+		},
+		{
+			// libm(n): acc = acc * 0.5 + sqrt(n), n times.
+			LocalTypes: []wasm.ValueType{f64},
+			Body: concat(
+				[]byte{
+					wasm.OpcodeLoop, 0x40,
+					wasm.OpcodeLocalGet, 1,
+				},
+				f64Const(0.5),
+				[]byte{
+					wasm.OpcodeF64Mul,
+					wasm.OpcodeLocalGet, 0, wasm.OpcodeF64ConvertI32U, wasm.OpcodeCall, 0, wasm.OpcodeF64Add,
+					wasm.OpcodeLocalSet, 1,
+				},
+				decrementAndLoop(0),
+				[]byte{wasm.OpcodeEnd, wasm.OpcodeLocalGet, 1, wasm.OpcodeEnd},
+			),
+		},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "ops", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "libm", Type: wasm.ExternTypeFunc, Index: 2},
+	},
+	NameSection: &wasm.NameSection{FunctionNames: wasm.NameMap{
+		{Index: 0, Name: "sqrt"},
+		{Index: 1, Name: "ops"},
+		{Index: 2, Name: "libm"},
+	}},
+})
+
+const (
+	i32 = wasm.ValueTypeI32
+	f64 = wasm.ValueTypeF64
+)
+
+func concat(bodies ...[]byte) (ret []byte) {
+	for _, b := range bodies {
+		ret = append(ret, b...)
+	}
+	return
+}
+
+func f64Const(v float64) []byte {
+	ret := []byte{wasm.OpcodeF64Const, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint64(ret[1:], math.Float64bits(v))
+	return ret
+}
+
+// decrementAndLoop decrements the i32 local, and branches to the enclosing
+// loop unless it is zero.
+func decrementAndLoop(local byte) []byte {
+	return []byte{
+		wasm.OpcodeLocalGet, local, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, local,
+		wasm.OpcodeBrIf, 0,
+	}
+}
+
+// BenchmarkMath compares float instructions, and calls to a libm function
+// with and without intrinsics.LibM.
+func BenchmarkMath(b *testing.B) {
+	configs := []struct {
+		name   string
+		config wazero.RuntimeConfig
+	}{
+		{name: "interpreter", config: wazero.NewRuntimeConfigInterpreter()},
+	}
+	if platform.CompilerSupported() {
+		configs = append(configs, struct {
+			name   string
+			config wazero.RuntimeConfig
+		}{name: "compiler", config: wazero.NewRuntimeConfigCompiler()})
+	}
+
+	for _, c := range configs {
+		config := c.config
+		b.Run(c.name, func(b *testing.B) {
+			for _, bc := range []struct {
+				name       string
+				fn         string
+				intrinsics []experimental.Intrinsic
+			}{
+				{name: "ops", fn: "ops"},
+				{name: "libm", fn: "libm"},
+				{name: "libm intrinsics", fn: "libm", intrinsics: intrinsics.LibM},
+			} {
+				bc := bc
+				b.Run(bc.name, func(b *testing.B) {
+					r := wazero.NewRuntimeWithConfig(testCtx, config)
+					defer r.Close(testCtx)
+
+					ctx := testCtx
+					if bc.intrinsics != nil {
+						ctx = experimental.WithIntrinsics(ctx, bc.intrinsics...)
+					}
+					compiled, err := r.CompileModule(ctx, mathWasm)
+					if err != nil {
+						b.Fatal(err)
+					}
+					mod, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig())
+					if err != nil {
+						b.Fatal(err)
+					}
+					fn := mod.ExportedFunction(bc.fn)
+
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						if _, err = fn.Call(testCtx, 1000); err != nil {
+							b.Fatal(err)
+						}
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// BenchmarkInstantiation_elements measures the instantiation of a module
+// whose element segment fills a huge table, as emitted by compilers for
+// languages with many virtual methods.
+func BenchmarkInstantiation_elements(b *testing.B) {
+	const elements = 200_000
+	init := make([]wasm.Index, elements)
+	for i := range init {
+		init[i] = wasm.Index(i % 2)
+	}
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeEnd}}},
+		TableSection:    []wasm.Table{{Min: elements, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:       init,
+			Type:       wasm.RefTypeFuncref,
+			Mode:       wasm.ElementModeActive,
+		}},
+	})
+
+	b.Run("interpreter", func(b *testing.B) {
+		benchmarkInstantiation(b, wazero.NewRuntimeConfigInterpreter(), bin)
+	})
+	if platform.CompilerSupported() {
+		b.Run("compiler", func(b *testing.B) {
+			benchmarkInstantiation(b, wazero.NewRuntimeConfigCompiler(), bin)
+		})
+	}
+}
+
+func benchmarkInstantiation(b *testing.B, config wazero.RuntimeConfig, bin []byte) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, bin)
+	if err != nil {
+		b.Fatal(err)
+	}
+	moduleConfig := wazero.NewModuleConfig().WithName("")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mod, err := r.InstantiateModule(testCtx, compiled, moduleConfig)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err = mod.Close(testCtx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
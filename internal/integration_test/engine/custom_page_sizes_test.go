@@ -0,0 +1,93 @@
+package adhoc
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestCustomPageSizes_Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testCustomPageSizes(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestCustomPageSizes_Interpreter(t *testing.T) {
+	testCustomPageSizes(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// testCustomPageSizes grows memories whose pages are smaller than 64KiB.
+func testCustomPageSizes(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesCustomPageSizes))
+	defer r.Close(testCtx)
+
+	for _, pageSizeLog2 := range []uint32{0, 12} {
+		mem := &wasm.Memory{Min: 3, Max: 10, IsMaxEncoded: true, PageSizeLog2: pageSizeLog2, IsPageSizeEncoded: true}
+		mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection: []wasm.FunctionType{
+				{Results: []wasm.ValueType{i32}},
+				{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+			},
+			MemorySection:   mem,
+			FunctionSection: []wasm.Index{0, 1},
+			CodeSection: []wasm.Code{
+				{Body: []byte{wasm.OpcodeMemorySize, 0, wasm.OpcodeEnd}},
+				{Body: []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeMemoryGrow, 0, wasm.OpcodeEnd}},
+			},
+			ExportSection: []wasm.Export{
+				{Name: "size", Type: wasm.ExternTypeFunc, Index: 0},
+				{Name: "grow", Type: wasm.ExternTypeFunc, Index: 1},
+			},
+		}))
+		require.NoError(t, err)
+
+		require.Equal(t, uint32(1)<<pageSizeLog2, mod.Memory().Definition().PageSize())
+
+		size, grow := mod.ExportedFunction("size"), mod.ExportedFunction("grow")
+
+		results, err := size.Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), results[0])
+		require.Equal(t, uint32(3)<<pageSizeLog2, mod.Memory().Size())
+
+		results, err = grow.Call(testCtx, 5)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), results[0])
+		results, err = size.Call(testCtx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(8), results[0])
+		require.Equal(t, uint32(8)<<pageSizeLog2, mod.Memory().Size())
+
+		// Growing over the maximum fails.
+		results, err = grow.Call(testCtx, 3)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0xffffffff), results[0])
+
+		require.NoError(t, mod.Close(testCtx))
+	}
+
+	t.Run("import page size mismatch", func(t *testing.T) {
+		_, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+			ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory}},
+			NameSection:   &wasm.NameSection{ModuleName: "env"},
+		}))
+		require.NoError(t, err)
+
+		_, err = r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			ImportSection: []wasm.Import{{
+				Module: "env", Name: "memory", Type: wasm.ExternTypeMemory,
+				DescMem: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsPageSizeEncoded: true},
+			}},
+		}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "page size mismatch: 1 != 65536")
+	})
+}
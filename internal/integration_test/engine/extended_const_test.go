@@ -0,0 +1,119 @@
+package adhoc
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestExtendedConst_Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testExtendedConst(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestExtendedConst_Interpreter(t *testing.T) {
+	testExtendedConst(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// testExtendedConst offsets a global, a data segment and an element segment
+// from an imported base address, like LLVM does when dynamic linking.
+func testExtendedConst(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesExtendedConst))
+	defer r.Close(testCtx)
+
+	const base = 1024
+	_, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		GlobalSection: []wasm.Global{{
+			Type: wasm.GlobalType{ValType: i32},
+			Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: leb128.EncodeInt32(base)},
+		}},
+		ExportSection: []wasm.Export{{Name: "__memory_base", Type: wasm.ExternTypeGlobal}},
+		NameSection:   &wasm.NameSection{ModuleName: "env"},
+	}))
+	require.NoError(t, err)
+
+	// baseplus returns an expression of the imported base plus `offset`.
+	baseplus := func(offset byte) wasm.ConstantExpression {
+		return wasm.ConstantExpression{
+			Opcode: wasm.OpcodeI32Add,
+			Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, offset, wasm.OpcodeI32Add},
+		}
+	}
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{i32}}},
+		ImportSection: []wasm.Import{{
+			Module: "env", Name: "__memory_base", Type: wasm.ExternTypeGlobal,
+			DescGlobal: wasm.GlobalType{ValType: i32},
+		}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+			{Body: []byte{
+				// call_indirect the function at the offset of the element.
+				wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 3, wasm.OpcodeI32Sub,
+				wasm.OpcodeCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Max: 1},
+		TableSection:  []wasm.Table{{Min: base, Type: wasm.RefTypeFuncref}},
+		GlobalSection: []wasm.Global{
+			{Type: wasm.GlobalType{ValType: i32}, Init: baseplus(16)},
+			{Type: wasm.GlobalType{ValType: i64}, Init: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI64Mul,
+				Data:   []byte{wasm.OpcodeI64Const, 6, wasm.OpcodeI64Const, 7, wasm.OpcodeI64Mul},
+			}},
+		},
+		DataSection: []wasm.DataSegment{{OffsetExpression: baseplus(8), Init: []byte("hello")}},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Sub,
+				Data:   []byte{wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 3, wasm.OpcodeI32Sub},
+			},
+			Init: []wasm.Index{0}, Type: wasm.RefTypeFuncref, Mode: wasm.ElementModeActive,
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "memory", Type: wasm.ExternTypeMemory},
+			{Name: "address", Type: wasm.ExternTypeGlobal, Index: 1},
+			{Name: "product", Type: wasm.ExternTypeGlobal, Index: 2},
+			{Name: "call", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	}))
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(base+16), mod.ExportedGlobal("address").Get())
+	require.Equal(t, uint64(42), mod.ExportedGlobal("product").Get())
+
+	data, ok := mod.Memory().Read(base+8, 5)
+	require.True(t, ok)
+	require.Equal(t, "hello", string(data))
+
+	results, err := mod.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	t.Run("disabled", func(t *testing.T) {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2))
+		defer r.Close(testCtx)
+
+		_, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			GlobalSection: []wasm.Global{{
+				Type: wasm.GlobalType{ValType: i32},
+				Init: wasm.ConstantExpression{
+					Opcode: wasm.OpcodeI32Add,
+					Data:   []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Add},
+				},
+			}},
+		}))
+		require.Error(t, err)
+	})
+}
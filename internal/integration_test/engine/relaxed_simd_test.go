@@ -0,0 +1,209 @@
+package adhoc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestRelaxedSIMD_Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testRelaxedSIMD(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestRelaxedSIMD_Interpreter(t *testing.T) {
+	testRelaxedSIMD(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// testRelaxedSIMD calls each relaxed vector instruction with the parameters
+// as operands, which are two uint64 per v128, the lower 64-bits first.
+func testRelaxedSIMD(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesRelaxedSIMD))
+	defer r.Close(testCtx)
+
+	v128 := wasm.ValueTypeV128
+	ops := []struct {
+		op       wasm.OpcodeVecRelaxed
+		operands int
+	}{
+		{op: wasm.OpcodeVecI8x16RelaxedSwizzle, operands: 2},
+		{op: wasm.OpcodeVecI32x4RelaxedTruncF32x4S, operands: 1},
+		{op: wasm.OpcodeVecI32x4RelaxedTruncF32x4U, operands: 1},
+		{op: wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, operands: 1},
+		{op: wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero, operands: 1},
+		{op: wasm.OpcodeVecF32x4RelaxedMadd, operands: 3},
+		{op: wasm.OpcodeVecF32x4RelaxedNmadd, operands: 3},
+		{op: wasm.OpcodeVecF64x2RelaxedMadd, operands: 3},
+		{op: wasm.OpcodeVecF64x2RelaxedNmadd, operands: 3},
+		{op: wasm.OpcodeVecI8x16RelaxedLaneselect, operands: 3},
+		{op: wasm.OpcodeVecI64x2RelaxedLaneselect, operands: 3},
+		{op: wasm.OpcodeVecF32x4RelaxedMin, operands: 2},
+		{op: wasm.OpcodeVecF32x4RelaxedMax, operands: 2},
+		{op: wasm.OpcodeVecF64x2RelaxedMin, operands: 2},
+		{op: wasm.OpcodeVecF64x2RelaxedMax, operands: 2},
+		{op: wasm.OpcodeVecI16x8RelaxedQ15mulrS, operands: 2},
+		{op: wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S, operands: 2},
+		{op: wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS, operands: 3},
+	}
+	m := &wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{v128}, Results: []wasm.ValueType{v128}},
+			{Params: []wasm.ValueType{v128, v128}, Results: []wasm.ValueType{v128}},
+			{Params: []wasm.ValueType{v128, v128, v128}, Results: []wasm.ValueType{v128}},
+		},
+	}
+	for i, o := range ops {
+		var body []byte
+		for j := 0; j < o.operands; j++ {
+			body = append(body, wasm.OpcodeLocalGet, byte(j))
+		}
+		// Relaxed vector opcodes are encoded with two bytes of LEB128.
+		body = append(body, wasm.OpcodeVecPrefix, byte(o.op)|0x80, byte(o.op>>7), wasm.OpcodeEnd)
+		m.FunctionSection = append(m.FunctionSection, wasm.Index(o.operands-1))
+		m.CodeSection = append(m.CodeSection, wasm.Code{Body: body})
+		m.ExportSection = append(m.ExportSection, wasm.Export{
+			Name: wasm.VecRelaxedInstructionName(o.op), Type: wasm.ExternTypeFunc, Index: wasm.Index(i),
+		})
+	}
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(m))
+	require.NoError(t, err)
+
+	nan32, nan64 := float32(math.NaN()), math.NaN()
+	tests := []struct {
+		fn       string
+		params   [][2]uint64
+		expected [2]uint64
+	}{
+		{
+			fn:       "i8x16.relaxed_swizzle",
+			params:   [][2]uint64{{0x0706050403020100, 0x0f0e0d0c0b0a0908}, {0x08090a0b0c0d0e0f, 0x0001020304050607}},
+			expected: [2]uint64{0x08090a0b0c0d0e0f, 0x0001020304050607},
+		},
+		{
+			fn:       "i32x4.relaxed_trunc_f32x4_s",
+			params:   [][2]uint64{f32x4(1.5, -1.5, 3e9, nan32)},
+			expected: i32x4(1, -1, math.MaxInt32, 0),
+		},
+		{
+			fn:       "i32x4.relaxed_trunc_f32x4_u",
+			params:   [][2]uint64{f32x4(1.5, -1.5, 5e9, nan32)},
+			expected: i32x4(1, 0, -1, 0),
+		},
+		{
+			fn:       "i32x4.relaxed_trunc_f64x2_s_zero",
+			params:   [][2]uint64{f64x2(-2.5, 1e10)},
+			expected: i32x4(-2, math.MaxInt32, 0, 0),
+		},
+		{
+			fn:       "i32x4.relaxed_trunc_f64x2_u_zero",
+			params:   [][2]uint64{f64x2(nan64, 2.5)},
+			expected: i32x4(0, 2, 0, 0),
+		},
+		{
+			fn:       "f32x4.relaxed_madd",
+			params:   [][2]uint64{f32x4(1, 2, 3, 4), f32x4(2, 2, 2, -2), f32x4(0.5, 0.5, 0.5, 0.5)},
+			expected: f32x4(2.5, 4.5, 6.5, -7.5),
+		},
+		{
+			fn:       "f32x4.relaxed_nmadd",
+			params:   [][2]uint64{f32x4(1, 2, 3, 4), f32x4(2, 2, 2, -2), f32x4(0.5, 0.5, 0.5, 0.5)},
+			expected: f32x4(-1.5, -3.5, -5.5, 8.5),
+		},
+		{
+			fn:       "f64x2.relaxed_madd",
+			params:   [][2]uint64{f64x2(1.5, -2), f64x2(2, 3), f64x2(1, 1)},
+			expected: f64x2(4, -5),
+		},
+		{
+			fn:       "f64x2.relaxed_nmadd",
+			params:   [][2]uint64{f64x2(1.5, -2), f64x2(2, 3), f64x2(1, 1)},
+			expected: f64x2(-2, 7),
+		},
+		{
+			fn:       "i8x16.relaxed_laneselect",
+			params:   [][2]uint64{{0x1111111111111111, 0x1111111111111111}, {0x2222222222222222, 0x2222222222222222}, {0xff00ff00ff00ff00, 0x00000000ffffffff}},
+			expected: [2]uint64{0x1122112211221122, 0x2222222211111111},
+		},
+		{
+			fn:       "i64x2.relaxed_laneselect",
+			params:   [][2]uint64{{1, 2}, {3, 4}, {math.MaxUint64, 0}},
+			expected: [2]uint64{1, 4},
+		},
+		{
+			fn:       "f32x4.relaxed_min",
+			params:   [][2]uint64{f32x4(1, -1, 3, 0), f32x4(2, -2, 0, 5)},
+			expected: f32x4(1, -2, 0, 0),
+		},
+		{
+			fn:       "f32x4.relaxed_max",
+			params:   [][2]uint64{f32x4(1, -1, 3, 0), f32x4(2, -2, 0, 5)},
+			expected: f32x4(2, -1, 3, 5),
+		},
+		{
+			fn:       "f64x2.relaxed_min",
+			params:   [][2]uint64{f64x2(1, -1), f64x2(2, -2)},
+			expected: f64x2(1, -2),
+		},
+		{
+			fn:       "f64x2.relaxed_max",
+			params:   [][2]uint64{f64x2(1, -1), f64x2(2, -2)},
+			expected: f64x2(2, -1),
+		},
+		{
+			fn:       "i16x8.relaxed_q15mulr_s",
+			params:   [][2]uint64{{0x4000400040004000, 0x4000400040004000}, {0x4000400040004000, 0xc000c000c000c000}},
+			expected: [2]uint64{0x2000200020002000, 0xe000e000e000e000},
+		},
+		{
+			// Each lane is -3*5 + -3*5 = -30.
+			fn:       "i16x8.relaxed_dot_i8x16_i7x16_s",
+			params:   [][2]uint64{{0xfdfdfdfdfdfdfdfd, 0xfdfdfdfdfdfdfdfd}, {0x0505050505050505, 0x0505050505050505}},
+			expected: [2]uint64{0xffe2ffe2ffe2ffe2, 0xffe2ffe2ffe2ffe2},
+		},
+		{
+			// Each lane is 4 * (-3*5) + 100 = 40, except the last, which is
+			// 2 * (-3*5) + 2 * (1*5) + 100 = 80.
+			fn: "i32x4.relaxed_dot_i8x16_i7x16_add_s",
+			params: [][2]uint64{
+				{0xfdfdfdfdfdfdfdfd, 0x0101fdfdfdfdfdfd},
+				{0x0505050505050505, 0x0505050505050505},
+				i32x4(100, 100, 100, 100),
+			},
+			expected: i32x4(40, 40, 40, 80),
+		},
+	}
+
+	for _, tc := range tests {
+		var params []uint64
+		for _, p := range tc.params {
+			params = append(params, p[0], p[1])
+		}
+		results, err := mod.ExportedFunction(tc.fn).Call(testCtx, params...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected[:], results, tc.fn)
+	}
+}
+
+func f32x4(a, b, c, d float32) [2]uint64 {
+	return [2]uint64{
+		uint64(math.Float32bits(a)) | uint64(math.Float32bits(b))<<32,
+		uint64(math.Float32bits(c)) | uint64(math.Float32bits(d))<<32,
+	}
+}
+
+func f64x2(a, b float64) [2]uint64 {
+	return [2]uint64{math.Float64bits(a), math.Float64bits(b)}
+}
+
+func i32x4(a, b, c, d int32) [2]uint64 {
+	return [2]uint64{uint64(uint32(a)) | uint64(uint32(b))<<32, uint64(uint32(c)) | uint64(uint32(d))<<32}
+}
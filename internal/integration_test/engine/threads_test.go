@@ -0,0 +1,162 @@
+package adhoc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestThreads_Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testThreads(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestThreads_Interpreter(t *testing.T) {
+	testThreads(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// testThreads calls atomic instructions on a shared memory, imported by two
+// instances, from several goroutines.
+func testThreads(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads))
+	defer r.Close(testCtx)
+
+	_, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+		ExportSection: []wasm.Export{{Name: "memory", Type: wasm.ExternTypeMemory}},
+		NameSection:   &wasm.NameSection{ModuleName: "env"},
+	}))
+	require.NoError(t, err)
+
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i64}},
+		},
+		ImportSection: []wasm.Import{{
+			Module: "env", Name: "memory", Type: wasm.ExternTypeMemory,
+			DescMem: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+		}},
+		FunctionSection: []wasm.Index{0, 1, 1, 2},
+		CodeSection: []wasm.Code{
+			{Body: []byte{ // inc(addr) adds one to the i32 at addr.
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI32RmwAdd, 0x2, 0x0,
+				wasm.OpcodeDrop, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{ // wait(addr, expected) waits without timeout.
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI64Const, 0x7f,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryWait32, 0x2, 0x0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{ // notify(addr, count)
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicMemoryNotify, 0x2, 0x0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{ // load(addr) loads the i64 at addr.
+				wasm.OpcodeLocalGet, 0,
+				wasm.OpcodeAtomicPrefix, wasm.OpcodeAtomicI64Load, 0x3, 0x0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "inc", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "wait", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "notify", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "load", Type: wasm.ExternTypeFunc, Index: 3},
+		},
+	}))
+	require.NoError(t, err)
+
+	var instances [2]api.Module
+	for i := range instances {
+		instances[i], err = r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName(""))
+		require.NoError(t, err)
+	}
+
+	t.Run("rmw", func(t *testing.T) {
+		const goroutines, calls = 8, 500
+		var wg sync.WaitGroup
+		for i := 0; i < goroutines; i++ {
+			wg.Add(1)
+			go func(mod api.Module) {
+				defer wg.Done()
+				inc := mod.ExportedFunction("inc")
+				for j := 0; j < calls; j++ {
+					if _, err := inc.Call(testCtx, 8); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}(instances[i%2])
+		}
+		wg.Wait()
+
+		results, err := instances[0].ExportedFunction("load").Call(testCtx, 8)
+		require.NoError(t, err)
+		require.Equal(t, uint64(goroutines*calls), results[0])
+	})
+
+	t.Run("wait notify", func(t *testing.T) {
+		done := make(chan uint64)
+		go func() {
+			results, err := instances[0].ExportedFunction("wait").Call(testCtx, 64, 0)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- results[0]
+		}()
+
+		// Notify from the other instance until the waiter was woken.
+		notify := instances[1].ExportedFunction("notify")
+		for {
+			results, err := notify.Call(testCtx, 64, 1)
+			require.NoError(t, err)
+			if results[0] == 1 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		require.Equal(t, uint64(0), <-done)
+
+		// The value differs, so this doesn't wait.
+		results, err := instances[0].ExportedFunction("wait").Call(testCtx, 8, 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), results[0])
+	})
+
+	t.Run("wait canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(testCtx, time.Millisecond)
+		defer cancel()
+
+		// Without a timeout, this waits until the context is done.
+		_, err := instances[0].ExportedFunction("wait").Call(ctx, 64, 0)
+		require.Equal(t, sys.NewExitError(sys.ExitCodeDeadlineExceeded), err)
+	})
+
+	t.Run("unaligned", func(t *testing.T) {
+		_, err := instances[0].ExportedFunction("inc").Call(testCtx, 2)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unaligned atomic")
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+		_, err := instances[0].ExportedFunction("load").Call(testCtx, uint64(wasm.MemoryPageSize))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "out of bounds memory access")
+	})
+}
@@ -0,0 +1,99 @@
+package adhoc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestWideArithmetic_Compiler(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	testWideArithmetic(t, wazero.NewRuntimeConfigCompiler())
+}
+
+func TestWideArithmetic_Interpreter(t *testing.T) {
+	testWideArithmetic(t, wazero.NewRuntimeConfigInterpreter())
+}
+
+// testWideArithmetic calls the 128-bit arithmetic instructions with both
+// parameters and constants as operands, as the compiler places the latter on
+// the zero register on arm64.
+func testWideArithmetic(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config.WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesWideArithmetic))
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i64, i64, i64, i64}, Results: []wasm.ValueType{i64, i64}},
+			{Params: []wasm.ValueType{i64, i64}, Results: []wasm.ValueType{i64, i64}},
+		},
+		FunctionSection: []wasm.Index{0, 0, 1, 1, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Add128, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2, wasm.OpcodeLocalGet, 3,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Sub128, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64MulWideS, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{
+				wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64MulWideU, wasm.OpcodeEnd,
+			}},
+			{Body: []byte{ // neg(lo, hi) subtracts the operand from a constant zero.
+				wasm.OpcodeI64Const, 0, wasm.OpcodeI64Const, 0, wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1,
+				wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64Sub128, wasm.OpcodeEnd,
+			}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "add128", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "sub128", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "mul_wide_s", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "mul_wide_u", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "neg", Type: wasm.ExternTypeFunc, Index: 4},
+		},
+	}))
+	require.NoError(t, err)
+
+	const max = math.MaxUint64
+	tests := []struct {
+		fn       string
+		params   []uint64
+		expected []uint64
+	}{
+		{fn: "add128", params: []uint64{1, 2, 3, 4}, expected: []uint64{4, 6}},
+		{fn: "add128", params: []uint64{max, 0, 1, 0}, expected: []uint64{0, 1}},
+		{fn: "add128", params: []uint64{max, max, 1, 0}, expected: []uint64{0, 0}},
+		{fn: "sub128", params: []uint64{4, 6, 1, 2}, expected: []uint64{3, 4}},
+		{fn: "sub128", params: []uint64{0, 1, 1, 0}, expected: []uint64{max, 0}},
+		{fn: "sub128", params: []uint64{0, 0, 1, 0}, expected: []uint64{max, max}},
+		{fn: "mul_wide_u", params: []uint64{max, max}, expected: []uint64{1, max - 1}},
+		{fn: "mul_wide_u", params: []uint64{1 << 32, 1 << 32}, expected: []uint64{0, 1}},
+		{fn: "mul_wide_u", params: []uint64{0, max}, expected: []uint64{0, 0}},
+		{fn: "mul_wide_s", params: []uint64{max, max}, expected: []uint64{1, 0}},       // -1 * -1
+		{fn: "mul_wide_s", params: []uint64{max, 2}, expected: []uint64{max - 1, max}}, // -1 * 2
+		{fn: "mul_wide_s", params: []uint64{1 << 63, 1 << 63}, expected: []uint64{0, 1 << 62}},
+		{fn: "neg", params: []uint64{1, 0}, expected: []uint64{max, max}},
+		{fn: "neg", params: []uint64{0, 1}, expected: []uint64{0, max}},
+	}
+
+	for _, tc := range tests {
+		results, err := mod.ExportedFunction(tc.fn).Call(testCtx, tc.params...)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, results, "%s%v", tc.fn, tc.params)
+	}
+}
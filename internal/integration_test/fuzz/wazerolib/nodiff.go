@@ -125,6 +125,11 @@ func requireNoDiff(wasmBin []byte, checkMemory bool, requireNoError func(err err
 			}
 		}
 	}
+
+	// The reference can't provide the dummy imports, so only modules without imports are compared with it.
+	if ref := referenceFromEnv(); ref != nil && len(internalMod.ImportSection) == 0 {
+		requireNoError(requireNoDiffWithReference(ref, wasmBin, interpreter, interpreterCompiled, checkMemory))
+	}
 }
 
 // ensureDummyImports instantiates the modules which are required imports by `origin` *wasm.Module.
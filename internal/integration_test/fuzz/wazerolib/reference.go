@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// referenceEnv is the environment variable of the command of a reference
+// runtime, e.g. an adapter over the C API of wasmtime, or over the binary of
+// the spec interpreter. When set, the behavior of wazero is also compared with
+// it, for modules which don't import anything.
+//
+// The command reads a referenceRequest as JSON from stdin, and writes a
+// referenceOutcome as JSON to stdout. A v128 value is two uint64s: the lower
+// then the higher 64 bits.
+const referenceEnv = "WAZERO_FUZZ_REFERENCE"
+
+// reference runs modules in a runtime other than wazero.
+type reference interface {
+	// run instantiates wasmBin, then invokes the functions in order.
+	run(wasmBin []byte, invocations []invocation, withMemory bool) (*referenceOutcome, error)
+}
+
+// invocation is a call to an exported function.
+type invocation struct {
+	Name   string   `json:"name"`
+	Params []uint64 `json:"params"`
+
+	resultTypes []api.ValueType
+}
+
+// referenceRequest is what a command reference reads from stdin.
+type referenceRequest struct {
+	// Wasm is the path to the binary of the module.
+	Wasm        string       `json:"wasm"`
+	Invocations []invocation `json:"invocations"`
+	// Memory is true to return the memory after the invocations.
+	Memory bool `json:"memory"`
+}
+
+// referenceOutcome is the outcome of instantiating a module, then invoking
+// its exported functions in order.
+type referenceOutcome struct {
+	// InstantiationError is empty unless the instantiation failed, including
+	// a trap in the start function. Then, there are no results.
+	InstantiationError string `json:"instantiation_error"`
+	// Results are in the order of the invocations.
+	Results []invocationResult `json:"results"`
+	// Memory is the first memory after the invocations, if requested.
+	Memory []byte `json:"memory"`
+}
+
+// invocationResult is the result of an invocation.
+type invocationResult struct {
+	Values []uint64 `json:"values"`
+	// Trap is empty unless the invocation trapped. The message is only for
+	// debugging, as it isn't the same between runtimes.
+	Trap string `json:"trap"`
+}
+
+// referenceFromEnv returns the reference configured by referenceEnv, or nil.
+func referenceFromEnv() reference {
+	if cmd := strings.Fields(os.Getenv(referenceEnv)); len(cmd) > 0 {
+		return &commandReference{cmd: cmd}
+	}
+	return nil
+}
+
+// commandReference is a reference run as a command.
+type commandReference struct {
+	cmd []string
+}
+
+// run implements reference.run
+func (c *commandReference) run(wasmBin []byte, invocations []invocation, withMemory bool) (*referenceOutcome, error) {
+	dir, err := os.MkdirTemp("", "wazero-fuzz-reference")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	wasmPath := path.Join(dir, "module.wasm")
+	if err = os.WriteFile(wasmPath, wasmBin, 0o600); err != nil {
+		return nil, err
+	}
+	req, err := json.Marshal(&referenceRequest{Wasm: wasmPath, Invocations: invocations, Memory: withMemory})
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(c.cmd[0], c.cmd[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(req), &stdout, &stderr
+	if err = cmd.Run(); err != nil {
+		return nil, fmt.Errorf("reference %s: %v\n%s", c.cmd[0], err, stderr.Bytes())
+	}
+	var ret referenceOutcome
+	if err = json.Unmarshal(stdout.Bytes(), &ret); err != nil {
+		return nil, fmt.Errorf("reference %s: invalid outcome: %v", c.cmd[0], err)
+	}
+	return &ret, nil
+}
+
+// newInvocations returns an invocation with zero parameters of each exported
+// function with basic result types, in the order of their names.
+func newInvocations(exportedFunctions map[string]api.FunctionDefinition) []invocation {
+	names := make([]string, 0, len(exportedFunctions))
+outer:
+	for name, def := range exportedFunctions {
+		for _, rt := range def.ResultTypes() {
+			switch rt {
+			case api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeF32, api.ValueTypeF64, valueTypeVector:
+			default:
+				continue outer
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ret := make([]invocation, len(names))
+	for i, name := range names {
+		def := exportedFunctions[name]
+		ret[i] = invocation{Name: name, Params: getDummyValues(def.ParamTypes()), resultTypes: def.ResultTypes()}
+	}
+	return ret
+}
+
+// runInvocations is the equivalent of reference.run with wazero.
+func runInvocations(r wazero.Runtime, compiled wazero.CompiledModule, invocations []invocation, withMemory bool) *referenceOutcome {
+	ctx := context.Background()
+	var ret referenceOutcome
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("reference"))
+	if err != nil {
+		ret.InstantiationError = err.Error()
+		return &ret
+	}
+	defer mod.Close(ctx)
+
+	ret.Results = make([]invocationResult, len(invocations))
+	for i, inv := range invocations {
+		values, err := mod.ExportedFunction(inv.Name).Call(ctx, inv.Params...)
+		if err != nil {
+			ret.Results[i].Trap = err.Error()
+		} else {
+			ret.Results[i].Values = values
+		}
+	}
+	if mem := mod.Memory(); withMemory && mem != nil {
+		ret.Memory, _ = mem.Read(0, mem.Size())
+	}
+	return &ret
+}
+
+// requireNoDiffWithReference ensures that the behavior of wazero is the same
+// as the reference for the compiled module, which must not import anything.
+func requireNoDiffWithReference(ref reference, wasmBin []byte, r wazero.Runtime, compiled wazero.CompiledModule, withMemory bool) error {
+	invocations := newInvocations(compiled.ExportedFunctions())
+	expected, err := ref.run(wasmBin, invocations, withMemory)
+	if err != nil {
+		return err
+	}
+	actual := runInvocations(r, compiled, invocations, withMemory)
+	return compareOutcomes(invocations, expected, actual)
+}
+
+// compareOutcomes returns an error if the outcome of wazero differs from the
+// reference. Trap messages, and the bits of NaN results aren't compared, as
+// they differ between runtimes.
+func compareOutcomes(invocations []invocation, expected, actual *referenceOutcome) error {
+	if (expected.InstantiationError == "") != (actual.InstantiationError == "") {
+		return fmt.Errorf("instantiation mismatch:\n\treference: %q\n\twazero: %q",
+			expected.InstantiationError, actual.InstantiationError)
+	} else if expected.InstantiationError != "" {
+		return nil
+	}
+
+	if len(expected.Results) != len(invocations) {
+		return fmt.Errorf("reference returned %d results, but %d invocations", len(expected.Results), len(invocations))
+	}
+	for i, inv := range invocations {
+		e, a := expected.Results[i], actual.Results[i]
+		if (e.Trap == "") != (a.Trap == "") {
+			return fmt.Errorf("trap mismatch on invoking '%s':\n\treference: %q\n\twazero: %q", inv.Name, e.Trap, a.Trap)
+		} else if e.Trap != "" {
+			continue
+		}
+		if !valuesEqual(inv.resultTypes, e.Values, a.Values) {
+			return fmt.Errorf("result mismatch on invoking '%s':\n\treference: %v\n\twazero: %v", inv.Name, e.Values, a.Values)
+		}
+	}
+
+	if expected.Memory != nil && !bytes.Equal(expected.Memory, actual.Memory) {
+		return errors.New("memory state mismatch with the reference")
+	}
+	return nil
+}
+
+// valuesEqual compares results of the types, treating any NaN as equal.
+func valuesEqual(types []api.ValueType, expected, actual []uint64) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	i := 0
+	for _, vt := range types {
+		if i >= len(expected) {
+			return false
+		}
+		e, a := expected[i], actual[i]
+		switch vt {
+		case api.ValueTypeF32:
+			ef, af := math.Float32frombits(uint32(e)), math.Float32frombits(uint32(a))
+			if ef != ef && af != af { // both NaN
+				break
+			}
+			if uint32(e) != uint32(a) {
+				return false
+			}
+		case api.ValueTypeF64:
+			if math.IsNaN(math.Float64frombits(e)) && math.IsNaN(math.Float64frombits(a)) {
+				break
+			}
+			if e != a {
+				return false
+			}
+		case api.ValueTypeI32:
+			if uint32(e) != uint32(a) {
+				return false
+			}
+		case valueTypeVector:
+			if i+1 >= len(expected) || e != a || expected[i+1] != actual[i+1] {
+				return false
+			}
+			i++
+		default:
+			if e != a {
+				return false
+			}
+		}
+		i++
+	}
+	return true
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// referenceWasm exports "answer" which returns 42, "nan" which returns a NaN,
+// "store" which writes to the memory and "trap" which traps.
+var referenceWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{
+		{Results: []wasm.ValueType{wasm.ValueTypeI32}},
+		{Results: []wasm.ValueType{wasm.ValueTypeF32}},
+		{},
+	},
+	FunctionSection: []wasm.Index{0, 1, 2, 2},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeF32Const, 0, 0, 0xc0, 0x7f, wasm.OpcodeEnd}},
+		{Body: []byte{
+			wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 0xff, 0x01,
+			wasm.OpcodeI32Store8, 0, 0, wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+	ExportSection: []wasm.Export{
+		{Name: "answer", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "nan", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "store", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "trap", Type: wasm.ExternTypeFunc, Index: 3},
+	},
+})
+
+// TestReferenceHelperProcess is not a real test: it is the command of a
+// reference run by the other tests, which runs modules with the interpreter.
+func TestReferenceHelperProcess(t *testing.T) {
+	if os.Getenv("WAZERO_FUZZ_REFERENCE_HELPER") != "1" {
+		t.Skip("only run as the command of a reference")
+	}
+
+	var req referenceRequest
+	require.NoError(t, json.NewDecoder(os.Stdin).Decode(&req))
+	wasmBin, err := os.ReadFile(req.Wasm)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(ctx)
+	compiled, err := r.CompileModule(ctx, wasmBin)
+	require.NoError(t, err)
+
+	require.NoError(t, json.NewEncoder(os.Stdout).Encode(runInvocations(r, compiled, req.Invocations, req.Memory)))
+	os.Exit(0)
+}
+
+func TestRequireNoDiff_reference(t *testing.T) {
+	t.Setenv("WAZERO_FUZZ_REFERENCE_HELPER", "1")
+	t.Setenv(referenceEnv, os.Args[0]+" -test.run=^TestReferenceHelperProcess$")
+
+	requireNoDiff(referenceWasm, true, func(err error) { require.NoError(t, err) })
+}
+
+// funcReference is a reference which returns a fixed outcome.
+type funcReference func(invocations []invocation) *referenceOutcome
+
+// run implements reference.run
+func (f funcReference) run(_ []byte, invocations []invocation, _ bool) (*referenceOutcome, error) {
+	return f(invocations), nil
+}
+
+func TestRequireNoDiffWithReference(t *testing.T) {
+	nan := uint64(math.Float32bits(float32(math.NaN())))
+	otherNaN := uint64(0x7fc00001)
+	memory := make([]byte, wasm.MemoryPageSize)
+	memory[1] = 0xff
+
+	tests := []struct {
+		name        string
+		outcome     *referenceOutcome
+		expectedErr string
+	}{
+		{
+			name: "same",
+			outcome: &referenceOutcome{Results: []invocationResult{
+				{Values: []uint64{42}}, {Values: []uint64{nan}}, {}, {Trap: "unreachable"},
+			}, Memory: memory},
+		},
+		{
+			name: "different NaN and trap message",
+			outcome: &referenceOutcome{Results: []invocationResult{
+				{Values: []uint64{42}}, {Values: []uint64{otherNaN}}, {}, {Trap: "wasm trap: unreachable"},
+			}},
+		},
+		{
+			name: "result mismatch",
+			outcome: &referenceOutcome{Results: []invocationResult{
+				{Values: []uint64{41}}, {Values: []uint64{nan}}, {}, {Trap: "unreachable"},
+			}},
+			expectedErr: "result mismatch on invoking 'answer'",
+		},
+		{
+			name: "trap mismatch",
+			outcome: &referenceOutcome{Results: []invocationResult{
+				{Values: []uint64{42}}, {Values: []uint64{nan}}, {}, {},
+			}},
+			expectedErr: "trap mismatch on invoking 'trap'",
+		},
+		{
+			name: "memory mismatch",
+			outcome: &referenceOutcome{Results: []invocationResult{
+				{Values: []uint64{42}}, {Values: []uint64{nan}}, {}, {Trap: "unreachable"},
+			}, Memory: make([]byte, wasm.MemoryPageSize)},
+			expectedErr: "memory state mismatch with the reference",
+		},
+		{
+			name:        "instantiation mismatch",
+			outcome:     &referenceOutcome{InstantiationError: "out of bounds"},
+			expectedErr: "instantiation mismatch",
+		},
+		{
+			name:        "missing results",
+			outcome:     &referenceOutcome{},
+			expectedErr: "reference returned 0 results, but 4 invocations",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+			defer r.Close(ctx)
+			compiled, err := r.CompileModule(ctx, referenceWasm)
+			require.NoError(t, err)
+
+			ref := funcReference(func(invocations []invocation) *referenceOutcome {
+				require.Equal(t, []string{"answer", "nan", "store", "trap"}, invocationNames(invocations))
+				return tc.outcome
+			})
+			err = requireNoDiffWithReference(ref, referenceWasm, r, compiled, true)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
+
+func invocationNames(invocations []invocation) []string {
+	ret := make([]string, len(invocations))
+	for i, inv := range invocations {
+		ret[i] = inv.Name
+	}
+	return ret
+}
+
+func Test_valuesEqual(t *testing.T) {
+	f64NaN := math.Float64bits(math.NaN())
+	tests := []struct {
+		name             string
+		types            []api.ValueType
+		expected, actual []uint64
+		equal            bool
+	}{
+		{name: "i32 ignores upper bits", types: []api.ValueType{api.ValueTypeI32}, expected: []uint64{1}, actual: []uint64{1 | 1<<32}, equal: true},
+		{name: "i64", types: []api.ValueType{api.ValueTypeI64}, expected: []uint64{1}, actual: []uint64{1 | 1<<32}},
+		{name: "f64 NaN", types: []api.ValueType{api.ValueTypeF64}, expected: []uint64{f64NaN}, actual: []uint64{f64NaN | 1}, equal: true},
+		{name: "f64 NaN and number", types: []api.ValueType{api.ValueTypeF64}, expected: []uint64{f64NaN}, actual: []uint64{0}},
+		{name: "v128", types: []api.ValueType{valueTypeVector}, expected: []uint64{1, 2}, actual: []uint64{1, 2}, equal: true},
+		{name: "v128 higher bits", types: []api.ValueType{valueTypeVector}, expected: []uint64{1, 2}, actual: []uint64{1, 3}},
+		{name: "length", types: []api.ValueType{api.ValueTypeI32}, expected: []uint64{1}, actual: nil},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.equal, valuesEqual(tc.types, tc.expected, tc.actual))
+		})
+	}
+}
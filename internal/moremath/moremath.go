@@ -113,25 +113,8 @@ func WasmCompatMax32(x, y float32) float32 {
 //
 // See https://llvm.org/docs/LangRef.html#llvm-rint-intrinsic.
 func WasmCompatNearestF32(f float32) float32 {
-	var res float32
-	// TODO: look at https://github.com/bytecodealliance/wasmtime/pull/2171 and reconsider this algorithm
-	if f != 0 {
-		ceil := float32(math.Ceil(float64(f)))
-		floor := float32(math.Floor(float64(f)))
-		distToCeil := math.Abs(float64(f - ceil))
-		distToFloor := math.Abs(float64(f - floor))
-		h := ceil / 2.0
-		if distToCeil < distToFloor {
-			res = ceil
-		} else if distToCeil == distToFloor && float32(math.Floor(float64(h))) == h {
-			res = ceil
-		} else {
-			res = floor
-		}
-	} else {
-		res = f
-	}
-	return returnF32UniOp(f, res)
+	// Rounding in 64-bit is exact, as float32 values are exactly representable.
+	return returnF32UniOp(f, float32(math.RoundToEven(float64(f))))
 }
 
 // WasmCompatNearestF64 is the Wasm spec compatible variant of math.Round, used for Nearest instruction.
@@ -139,27 +122,12 @@ func WasmCompatNearestF32(f float32) float32 {
 //
 // e.g. math.Round(-4.5) results in -5 while this results in -4.
 //
+// Note: math.RoundToEven lowers to an instruction on platforms which have
+// one, such as arm64.
+//
 // See https://llvm.org/docs/LangRef.html#llvm-rint-intrinsic.
 func WasmCompatNearestF64(f float64) float64 {
-	// TODO: look at https://github.com/bytecodealliance/wasmtime/pull/2171 and reconsider this algorithm
-	var res float64
-	if f != 0 {
-		ceil := math.Ceil(f)
-		floor := math.Floor(f)
-		distToCeil := math.Abs(f - ceil)
-		distToFloor := math.Abs(f - floor)
-		h := ceil / 2.0
-		if distToCeil < distToFloor {
-			res = ceil
-		} else if distToCeil == distToFloor && math.Floor(h) == h {
-			res = ceil
-		} else {
-			res = floor
-		}
-	} else {
-		res = f
-	}
-	return returnF64UniOp(f, res)
+	return returnF64UniOp(f, math.RoundToEven(f))
 }
 
 // WasmCompatCeilF32 is the same as math.Ceil on 32-bit except that
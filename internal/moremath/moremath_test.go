@@ -77,6 +77,11 @@ func TestWasmCompatNearestF32(t *testing.T) {
 	require.Equal(t, WasmCompatNearestF32(-4.5), float32(-4.0))
 	require.Equal(t, float32(math.Round(-4.5)), float32(-5.0))
 
+	// Ties round to even, and large values are already integral.
+	require.Equal(t, WasmCompatNearestF32(2.5), float32(2.0))
+	require.True(t, math.Signbit(float64(WasmCompatNearestF32(-0.5))))
+	require.Equal(t, WasmCompatNearestF32(16777215.0), float32(16777215.0))
+
 	// Prevent constant folding by using two variables. -float32(0) is not actually negative.
 	// https://github.com/golang/go/issues/2196
 	zero := float32(0)
@@ -96,6 +101,11 @@ func TestWasmCompatNearestF64(t *testing.T) {
 	require.Equal(t, WasmCompatNearestF64(-4.5), -4.0)
 	require.Equal(t, math.Round(-4.5), -5.0)
 
+	// Ties round to even, and large values are already integral.
+	require.Equal(t, WasmCompatNearestF64(2.5), 2.0)
+	require.True(t, math.Signbit(WasmCompatNearestF64(-0.5)))
+	require.Equal(t, WasmCompatNearestF64(4503599627370497.0), 4503599627370497.0)
+
 	// Prevent constant folding by using two variables. -float64(0) is not actually negative.
 	// https://github.com/golang/go/issues/2196
 	zero := float64(0)
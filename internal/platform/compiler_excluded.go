@@ -0,0 +1,5 @@
+//go:build wazero_nocompiler || tinygo || wasm
+
+package platform
+
+const compilerExcluded = true
@@ -0,0 +1,7 @@
+//go:build !wazero_nocompiler && !tinygo && !wasm
+
+package platform
+
+// compilerExcluded is true when the build tag "wazero_nocompiler" excludes
+// the compiler, or when compiled by TinyGo or for GOARCH=wasm.
+const compilerExcluded = false
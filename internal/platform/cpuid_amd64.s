@@ -1,3 +1,5 @@
+//go:build !tinygo
+
 #include "textflag.h"
 
 // lifted from github.com/intel-go/cpuid and src/internal/cpu/cpu_x86.s
@@ -0,0 +1,10 @@
+// This is the opposite constraint of cpuid_asm_amd64.go
+//go:build tinygo
+
+package platform
+
+// loadCpuFeatureFlags reports no capabilities, as TinyGo can't compile the
+// CPUID instruction. This is only used by the compiler, which TinyGo excludes.
+func loadCpuFeatureFlags() CpuFeatureFlags {
+	return &cpuFeatureFlags{}
+}
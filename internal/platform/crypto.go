@@ -1,6 +1,9 @@
 package platform
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
 	"io"
 	"math/rand"
 )
@@ -15,3 +18,31 @@ const seed = int64(42)
 func NewFakeRandSource() io.Reader {
 	return rand.New(rand.NewSource(seed))
 }
+
+// NewSeededRandSource returns a deterministic source of cryptographically
+// strong random values, which differs for each `instance` name. This is
+// AES-256 in counter mode, keyed by a SHA-256 hash of the seed and instance.
+func NewSeededRandSource(seed []byte, instance string) io.Reader {
+	h := sha256.New()
+	seedHash := sha256.Sum256(seed)
+	h.Write(seedHash[:]) // fixed length, so the instance can't be confused with the seed.
+	h.Write([]byte(instance))
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		panic(err) // BUG: a SHA-256 hash is a valid AES-256 key.
+	}
+	return &seededRandSource{stream: cipher.NewCTR(block, make([]byte, aes.BlockSize))}
+}
+
+type seededRandSource struct {
+	stream cipher.Stream
+}
+
+// Read implements io.Reader
+func (s *seededRandSource) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	s.stream.XORKeyStream(p, p)
+	return len(p), nil
+}
@@ -0,0 +1,22 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewSeededRandSource(t *testing.T) {
+	read := func(seed, instance string) []byte {
+		buf := make([]byte, 32)
+		n, err := NewSeededRandSource([]byte(seed), instance).Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, len(buf), n)
+		return buf
+	}
+
+	require.Equal(t, read("seed", "a"), read("seed", "a"))
+	require.NotEqual(t, read("seed", "a"), read("seed", "b"))
+	require.NotEqual(t, read("seed", "a"), read("other", "a"))
+	require.NotEqual(t, make([]byte, 32), read("", ""))
+}
@@ -13,6 +13,10 @@ const (
 	// https://man7.org/linux/man-pages/man2/mmap.2.html
 	__MAP_HUGE_SHIFT = 26
 	__MAP_HUGETLB    = 0x40000
+
+	// mmapNoReserve avoids accounting memory mappings which may never be
+	// written, such as the maximum size of a linear memory.
+	mmapNoReserve = syscall.MAP_NORESERVE
 )
 
 var hugePagesConfigs []hugePagesConfig
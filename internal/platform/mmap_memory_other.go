@@ -0,0 +1,26 @@
+//go:build !(darwin || linux || freebsd)
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+var errMmapMemoryUnsupported = fmt.Errorf("mmap of memory unsupported on GOOS=%s", runtime.GOOS)
+
+// MmapMemory is unsupported on this platform.
+func MmapMemory(int) ([]byte, error) {
+	return nil, errMmapMemoryUnsupported
+}
+
+// MmapFilePrivate is unsupported on this platform.
+func MmapFilePrivate(*os.File, int) ([]byte, error) {
+	return nil, errMmapMemoryUnsupported
+}
+
+// MunmapMemory is unsupported on this platform.
+func MunmapMemory([]byte) error {
+	return errMmapMemoryUnsupported
+}
@@ -0,0 +1,28 @@
+//go:build darwin || linux || freebsd
+
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+const mmapProtMemory = syscall.PROT_READ | syscall.PROT_WRITE
+
+// MmapMemory maps `size` bytes of zeroed, read-write memory. Unlike memory
+// allocated by Go, its address is stable and it is never scanned by the GC.
+func MmapMemory(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, mmapProtMemory, syscall.MAP_ANON|syscall.MAP_PRIVATE|mmapNoReserve)
+}
+
+// MmapFilePrivate maps the first `size` bytes of `f` as read-write memory.
+// The mapping is private: pages are shared with other mappings of the same
+// file until written, at which point they are copied.
+func MmapFilePrivate(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, mmapProtMemory, syscall.MAP_PRIVATE|mmapNoReserve)
+}
+
+// MunmapMemory releases memory returned by MmapMemory or MmapFilePrivate.
+func MunmapMemory(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -5,6 +5,9 @@ package platform
 
 import "syscall"
 
+// mmapNoReserve is only defined on linux.
+const mmapNoReserve = 0
+
 func mmapCodeSegment(size, prot int) ([]byte, error) {
 	return syscall.Mmap(
 		-1,
@@ -3,6 +3,7 @@
 package platform
 
 import (
+	"os"
 	"syscall"
 	"unsafe"
 )
@@ -32,8 +33,16 @@ func mmapCodeSegmentARM64(size int) ([]byte, error) {
 	return mmapCodeSegment(size, mmapProtARM64)
 }
 
-// MprotectRX is like syscall.Mprotect with RX permission, defined locally so that freebsd compiles.
-func MprotectRX(b []byte) (err error) {
+// MmapFileRX maps `size` bytes of `f`, from `offset`, which must be a
+// multiple of the page size, as read-only, executable memory. Unlike
+// MmapCodeSegment, the memory is never writable. Unmap it with
+// MunmapCodeSegment.
+func MmapFileRX(f *os.File, offset int64, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), offset, size, syscall.PROT_READ|syscall.PROT_EXEC, syscall.MAP_PRIVATE)
+}
+
+// mprotectRX is like syscall.Mprotect with RX permission, defined locally so that freebsd compiles.
+func mprotectRX(b []byte) (err error) {
 	var _p0 unsafe.Pointer
 	if len(b) > 0 {
 		_p0 = unsafe.Pointer(&b[0])
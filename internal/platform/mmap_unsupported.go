@@ -4,6 +4,7 @@ package platform
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 )
 
@@ -21,6 +22,10 @@ func mmapCodeSegmentARM64(size int) ([]byte, error) {
 	panic(errUnsupported)
 }
 
-func MprotectRX(b []byte) (err error) {
+func MmapFileRX(*os.File, int64, int) ([]byte, error) {
+	return nil, errUnsupported
+}
+
+func mprotectRX(b []byte) (err error) {
 	panic(errUnsupported)
 }
@@ -1,7 +1,9 @@
 package platform
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"reflect"
 	"syscall"
 	"unsafe"
@@ -87,7 +89,13 @@ func mmapCodeSegmentARM64(size int) ([]byte, error) {
 
 var old = uint32(windows_PAGE_READWRITE)
 
-func MprotectRX(b []byte) (err error) {
+// MmapFileRX isn't implemented on windows, as VirtualFree can't release a
+// mapped view of a file.
+func MmapFileRX(*os.File, int64, int) ([]byte, error) {
+	return nil, errors.New("mapping executable files is unsupported on windows")
+}
+
+func mprotectRX(b []byte) (err error) {
 	err = virtualProtect(uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), windows_PAGE_EXECUTE_READ, &old)
 	return
 }
@@ -5,8 +5,11 @@
 package platform
 
 import (
+	"errors"
 	"regexp"
 	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
 // IsAtLeastGo120 checks features added in 1.20. We can remove this when Go
@@ -28,7 +31,32 @@ func CompilerSupported() bool {
 		return false
 	}
 
-	return archRequirementsVerified
+	return archRequirementsVerified && !compilerExcluded
+}
+
+// ErrExecutableMemoryForbidden is returned by functions which would map
+// executable memory while ForbidExecutableMemory is in effect.
+var ErrExecutableMemoryForbidden = errors.New("executable memory is forbidden")
+
+// execForbidders counts the callers of ForbidExecutableMemory which haven't
+// released it yet.
+var execForbidders atomic.Int32
+
+// ForbidExecutableMemory makes MmapCodeSegment, RemapCodeSegment and
+// MprotectRX fail with ErrExecutableMemoryForbidden in the whole process,
+// until `release` is called. Calling `release` more than once has no effect.
+func ForbidExecutableMemory() (release func()) {
+	execForbidders.Add(1)
+	var once sync.Once
+	return func() {
+		once.Do(func() { execForbidders.Add(-1) })
+	}
+}
+
+// ExecutableMemoryForbidden returns true while ForbidExecutableMemory is in
+// effect.
+func ExecutableMemoryForbidden() bool {
+	return execForbidders.Load() > 0
 }
 
 // MmapCodeSegment copies the code into the executable region and returns the byte slice of the region.
@@ -38,6 +66,9 @@ func MmapCodeSegment(size int) ([]byte, error) {
 	if size == 0 {
 		panic("BUG: MmapCodeSegment with zero length")
 	}
+	if ExecutableMemoryForbidden() {
+		return nil, ErrExecutableMemoryForbidden
+	}
 	if runtime.GOARCH == "amd64" {
 		return mmapCodeSegmentAMD64(size)
 	} else {
@@ -60,6 +91,9 @@ func RemapCodeSegment(code []byte, size int) ([]byte, error) {
 	if code == nil {
 		return MmapCodeSegment(size)
 	}
+	if ExecutableMemoryForbidden() {
+		return nil, ErrExecutableMemoryForbidden
+	}
 	if runtime.GOARCH == "amd64" {
 		return remapCodeSegmentAMD64(code, size)
 	} else {
@@ -67,6 +101,15 @@ func RemapCodeSegment(code []byte, size int) ([]byte, error) {
 	}
 }
 
+// MprotectRX makes the memory region, returned by MmapCodeSegment, readable
+// and executable, but not writable.
+func MprotectRX(b []byte) error {
+	if ExecutableMemoryForbidden() {
+		return ErrExecutableMemoryForbidden
+	}
+	return mprotectRX(b)
+}
+
 // MunmapCodeSegment unmaps the given memory region.
 func MunmapCodeSegment(code []byte) error {
 	if len(code) == 0 {
@@ -41,3 +41,31 @@ func Test_isAtLeastGo120(t *testing.T) {
 		require.Equal(t, tc.expected, isAtLeastGo120(tc.input), tc.input)
 	}
 }
+
+func TestForbidExecutableMemory(t *testing.T) {
+	require.False(t, ExecutableMemoryForbidden())
+
+	release1 := ForbidExecutableMemory()
+	release2 := ForbidExecutableMemory()
+	require.True(t, ExecutableMemoryForbidden())
+
+	_, err := MmapCodeSegment(1234)
+	require.Equal(t, ErrExecutableMemoryForbidden, err)
+	_, err = RemapCodeSegment(make([]byte, 1), 1234)
+	require.Equal(t, ErrExecutableMemoryForbidden, err)
+	require.Equal(t, ErrExecutableMemoryForbidden, MprotectRX(make([]byte, 1)))
+
+	// Releasing twice doesn't release the other caller.
+	release1()
+	release1()
+	require.True(t, ExecutableMemoryForbidden())
+
+	release2()
+	require.False(t, ExecutableMemoryForbidden())
+
+	if CompilerSupported() {
+		code, err := MmapCodeSegment(1234)
+		require.NoError(t, err)
+		require.NoError(t, MunmapCodeSegment(code))
+	}
+}
@@ -1,4 +1,5 @@
-//go:build !cgo && !windows
+// TinyGo doesn't support linking runtime.nanotime, even with cgo.
+//go:build (!cgo || tinygo) && !windows
 
 package platform
 
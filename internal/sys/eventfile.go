@@ -0,0 +1,156 @@
+package sys
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"sync"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// EventFile is a file which becomes readable when the host notifies it, like
+// eventfd on Linux. This lets a guest wait for host events, such as
+// configuration changes, with "poll_oneoff" alongside other subscriptions.
+//
+// Reading returns the count of notifications since the last read, as a
+// little-endian uint64, and resets it to zero. The buffer must be at least
+// eight bytes.
+type EventFile struct {
+	experimentalsys.UnimplementedFile
+
+	mux      sync.Mutex
+	count    uint64
+	nonblock bool
+	closed   bool
+	// changed is closed and replaced on Notify or Close, to wake waiters.
+	changed chan struct{}
+}
+
+// NewEventFile returns a blocking EventFile with no pending notifications.
+func NewEventFile() *EventFile {
+	return &EventFile{changed: make(chan struct{})}
+}
+
+// Notify makes the file readable, waking any reader or poller. This returns
+// false if the file was closed, so the caller can stop notifying it.
+func (f *EventFile) Notify() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if f.closed {
+		return false
+	}
+	f.count++
+	f.wake()
+	return true
+}
+
+// Reset discards pending notifications, so that the file isn't readable
+// until the next Notify.
+func (f *EventFile) Reset() {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.count = 0
+}
+
+// wake must be called while holding the lock.
+func (f *EventFile) wake() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// Read implements the same method as documented on sys.File
+func (f *EventFile) Read(buf []byte) (int, experimentalsys.Errno) {
+	if len(buf) < 8 {
+		return 0, experimentalsys.EINVAL
+	}
+	for {
+		f.mux.Lock()
+		if f.closed {
+			f.mux.Unlock()
+			return 0, experimentalsys.EBADF
+		} else if f.count > 0 {
+			binary.LittleEndian.PutUint64(buf, f.count)
+			f.count = 0
+			f.mux.Unlock()
+			return 8, 0
+		} else if f.nonblock {
+			f.mux.Unlock()
+			return 0, experimentalsys.EAGAIN
+		}
+		changed := f.changed
+		f.mux.Unlock()
+		<-changed
+	}
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (f *EventFile) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLIN {
+		return false, experimentalsys.ENOTSUP
+	}
+
+	var timeout <-chan time.Time
+	if timeoutMillis >= 0 {
+		t := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	for {
+		f.mux.Lock()
+		if f.closed {
+			f.mux.Unlock()
+			return false, experimentalsys.EBADF
+		} else if f.count > 0 {
+			f.mux.Unlock()
+			return true, 0
+		}
+		changed := f.changed
+		f.mux.Unlock()
+
+		select {
+		case <-changed:
+		case <-timeout:
+			return false, 0
+		}
+	}
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (f *EventFile) IsNonblock() bool {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.nonblock
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (f *EventFile) SetNonblock(enable bool) experimentalsys.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.nonblock = enable
+	return 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *EventFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{Mode: fs.ModeIrregular | 0o400, Nlink: 1}, 0
+}
+
+// IsDir implements the same method as documented on sys.File
+func (f *EventFile) IsDir() (bool, experimentalsys.Errno) {
+	return false, 0
+}
+
+// Close implements the same method as documented on sys.File
+func (f *EventFile) Close() experimentalsys.Errno {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	if !f.closed {
+		f.closed = true
+		f.wake()
+	}
+	return 0
+}
@@ -3,6 +3,7 @@ package sys
 import (
 	"io"
 	"io/fs"
+	"math"
 	"net"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
@@ -53,10 +54,31 @@ type FileEntry struct {
 	// File is always non-nil.
 	File fsapi.File
 
+	// Rights, when non-nil, are the WASI rights reported for this file instead
+	// of the defaults for its type. These are informational: they don't
+	// restrict what the guest can do with the file.
+	Rights *Rights
+
+	// Mappings are the sizes, in bytes, of the file content in each memory
+	// mapping of this file, keyed by the guest address of the mapping. Sizes
+	// are as of when the file was mapped, so writing back a mapping that
+	// extends past the end of the file doesn't grow it.
+	//
+	// This is nil until the guest maps the file, e.g. with Emscripten mmap.
+	Mappings map[uint32]uint32
+
 	// direntCache is nil until DirentCache was called.
 	direntCache *DirentCache
 }
 
+// Rights are the base and inheriting WASI rights of a file, in the bit layout
+// of `rights` in wasi_snapshot_preview1.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+type Rights struct {
+	Base, Inheriting uint64
+}
+
 // DirentCache gets or creates a DirentCache for this file or returns an error.
 //
 // # Errors
@@ -95,9 +117,10 @@ func (f *FileEntry) DirentCache() (*DirentCache, sys.Errno) {
 //
 // This is special-cased for "wasi_snapshot_preview1.fd_readdir", and may be
 // unneeded, or require changes, to support preview1 or preview2.
-//   - The position of the dirents are serialized as `d_next`. For reasons
-//     described below, any may need to be re-read. This accepts any positions
-//     in the cache, rather than track the position of the last dirent.
+//   - The position after each dirent is serialized as `d_next`, which is its
+//     DirentCookie. For reasons described below, any may need to be re-read.
+//     This accepts any cookie, rather than track the position of the last
+//     dirent.
 //   - dot entries ("." and "..") must be returned. See /RATIONALE.md for why.
 //   - An sys.Dirent Name is variable length, it could exceed memory size and
 //     need to be re-read.
@@ -107,7 +130,8 @@ func (f *FileEntry) DirentCache() (*DirentCache, sys.Errno) {
 // The last results returned by Read are cached, but entries before that
 // position are not. This support re-reading entries that couldn't fit into
 // memory without accidentally caching all entries in a large directory. This
-// approach is sometimes called a sliding window.
+// approach is sometimes called a sliding window. Cookies before the window
+// are still valid, but re-read the directory up to that entry.
 type DirentCache struct {
 	// f is the underlying file
 	f sys.File
@@ -122,15 +146,36 @@ type DirentCache struct {
 	// exhausted directory (eof). nil means the re-read.
 	dirents []sys.Dirent
 
-	// countRead is the total count of dirents read since last rewind.
-	countRead uint64
+	// windowCookie is the cookie which resumes reading at the first entry of
+	// dirents, or zero when that is the beginning of the directory.
+	windowCookie uint64
 
 	// eof is true when the underlying file is at EOF. This avoids re-reading
 	// the directory when it is exhausted. Entires in an exhausted directory
-	// are not visible until it is rewound via calling Read with `pos==0`.
+	// are not visible until it is rewound via calling Read with `cookie==0`.
 	eof bool
 }
 
+// DirentCookie returns the cookie which resumes reading a directory after the
+// entry named `name`, serialized as its `d_next` in fd_readdir.
+//
+// This is a hash of the name, rather than the index of the entry, so that it
+// still refers to the same entry after others are added or removed. Cookies
+// are never zero, which means the beginning of the directory, and never
+// negative when interpreted as int64, like `off_t`.
+func DirentCookie(name string) uint64 {
+	// FNV-1a, inlined to avoid allocating a hash.Hash64 per entry.
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(name); i++ {
+		h ^= uint64(name[i])
+		h *= 1099511628211
+	}
+	if h &= math.MaxInt64; h == 0 {
+		h = 1
+	}
+	return h
+}
+
 // synthesizeDotEntries generates a slice of the two elements "." and "..".
 func synthesizeDotEntries(f *FileEntry) ([]sys.Dirent, sys.Errno) {
 	dotIno, errno := f.File.Ino()
@@ -150,80 +195,69 @@ var exhaustedDirents = [0]sys.Dirent{}
 
 // Read is similar to and returns the same errors as `Readdir` on sys.File.
 // The main difference is this caches entries returned, resulting in multiple
-// valid positions to read from.
+// valid cookies to read from.
 //
-// When zero, `pos` means rewind to the beginning of this directory. This
+// When zero, `cookie` means rewind to the beginning of this directory. This
 // implies a rewind (Seek to zero on the underlying sys.File), unless the
 // initial entries are still cached.
 //
-// When non-zero, `pos` is the zero based index of all dirents returned since
-// last rewind. Only entries beginning at `pos` are cached for subsequent
-// calls. A non-zero `pos` before the cache returns sys.ENOENT for reasons
-// described on DirentCache documentation.
+// When non-zero, `cookie` is the DirentCookie of an entry returned since the
+// directory was opened, and reading resumes after that entry. Only entries
+// after it are cached for subsequent calls. A cookie of an entry before the
+// cache re-reads the directory from the beginning, skipping entries up to and
+// including that entry. This returns sys.ENOENT if there is no such entry,
+// e.g. the cookie is invalid or the entry was since deleted.
 //
 // Up to `n` entries are cached and returned. When `n` exceeds the cache, the
 // difference are read from the underlying sys.File via `Readdir`. EOF is
 // when `len(dirents)` returned are less than `n`.
-func (d *DirentCache) Read(pos uint64, n uint32) (dirents []sys.Dirent, errno sys.Errno) {
-	switch {
-	case pos > d.countRead: // farther than read or negative coerced to uint64.
-		return nil, sys.ENOENT
-	case pos == 0 && d.dirents != nil:
+func (d *DirentCache) Read(cookie uint64, n uint32) (dirents []sys.Dirent, errno sys.Errno) {
+	if cookie == 0 && d.dirents != nil {
 		// Rewind if we have already read entries. This allows us to see new
 		// entries added after the directory was opened.
 		if _, errno = d.f.Seek(0, io.SeekStart); errno != 0 {
 			return
 		}
 		d.dirents = nil // dump cache
-		d.countRead = 0
 	}
 
 	if n == 0 {
 		return // special case no entries.
 	}
 
-	if d.dirents == nil {
+	if d.dirents == nil && cookie == 0 {
 		// Always populate dot entries, which makes min len(dirents) == 2.
 		d.dirents = d.dotEntries
-		d.countRead = 2
+		d.windowCookie = 0
 		d.eof = false
 
 		if countToRead := int(n - 2); countToRead <= 0 {
 			return
-		} else if dirents, errno = d.f.Readdir(countToRead); errno != 0 {
+		} else if dirents, errno = d.readdir(countToRead); errno != 0 {
 			return
 		} else if countRead := len(dirents); countRead > 0 {
 			d.eof = countRead < countToRead
 			d.dirents = append(d.dotEntries, dirents...)
-			d.countRead += uint64(countRead)
 		}
 
 		return d.cachedDirents(n), 0
 	}
 
-	// Reset our cache to the first entry being read.
-	cacheStart := d.countRead - uint64(len(d.dirents))
-	if pos < cacheStart {
-		// We don't currently allow reads before our cache because Seek(0) is
-		// the only portable way. Doing otherwise requires skipping, which we
-		// won't do unless wasi-testsuite starts requiring it. Implementing
-		// this would allow re-reading a large directory, so care would be
-		// needed to not buffer the entire directory in memory while skipping.
-		errno = sys.ENOENT
-		return
-	} else if posInCache := pos - cacheStart; posInCache != 0 {
-		if uint64(len(d.dirents)) == posInCache {
-			// Avoid allocation re-slicing to zero length.
-			d.dirents = exhaustedDirents[:]
-		} else {
-			d.dirents = d.dirents[posInCache:]
+	// Slide the cache to the entry after the cookie, unless it is already
+	// there. Seek(0) is the only portable way to read before our cache, so
+	// if the cookie isn't cached, re-read the directory up to its entry.
+	if d.dirents == nil || cookie != d.windowCookie {
+		if i := indexOfCookie(d.dirents, cookie); i != -1 {
+			d.slideWindow(cookie, i)
+		} else if errno = d.skipTo(cookie); errno != 0 {
+			return
 		}
 	}
 
 	// See if we need more entries.
 	if countToRead := int(n) - len(d.dirents); countToRead > 0 && !d.eof {
 		// Try to read more, which could fail.
-		if dirents, errno = d.f.Readdir(countToRead); errno != 0 {
+		if dirents, errno = d.readdir(countToRead); errno != 0 {
 			return
 		}
 
@@ -231,13 +265,97 @@ func (d *DirentCache) Read(pos uint64, n uint32) (dirents []sys.Dirent, errno sy
 		if countRead := len(dirents); countRead > 0 {
 			d.eof = countRead < countToRead
 			d.dirents = append(d.dirents, dirents...)
-			d.countRead += uint64(countRead)
 		}
 	}
 
 	return d.cachedDirents(n), 0
 }
 
+// indexOfCookie returns the index of the entry in `dirents` whose
+// DirentCookie is `cookie`, or -1 if there is none.
+func indexOfCookie(dirents []sys.Dirent, cookie uint64) int {
+	for i := range dirents {
+		if DirentCookie(dirents[i].Name) == cookie {
+			return i
+		}
+	}
+	return -1
+}
+
+// slideWindow drops the cached entries up to and including the one at index
+// `i`, whose DirentCookie is `cookie`.
+func (d *DirentCache) slideWindow(cookie uint64, i int) {
+	if i+1 == len(d.dirents) {
+		// Avoid allocation re-slicing to zero length.
+		d.dirents = exhaustedDirents[:]
+	} else {
+		d.dirents = d.dirents[i+1:]
+	}
+	d.windowCookie = cookie
+}
+
+// direntSkipBatch is the maximum count of entries read at a time when
+// skipping entries, so that a large directory isn't buffered in memory.
+const direntSkipBatch = 256
+
+// skipTo rewinds the directory and reads until the entry whose DirentCookie
+// is `cookie`, keeping only the entries after it in the cache. This returns
+// sys.ENOENT if the directory has no such entry, e.g. due to it being
+// deleted.
+func (d *DirentCache) skipTo(cookie uint64) sys.Errno {
+	if _, errno := d.f.Seek(0, io.SeekStart); errno != 0 {
+		return errno
+	}
+	d.dirents = d.dotEntries
+	d.eof = false
+
+	for {
+		if i := indexOfCookie(d.dirents, cookie); i != -1 {
+			d.slideWindow(cookie, i)
+			return 0
+		} else if d.eof {
+			break
+		}
+		dirents, errno := d.readdir(direntSkipBatch)
+		if errno != 0 {
+			d.invalidate()
+			return errno
+		}
+		d.eof = len(dirents) < direntSkipBatch
+		d.dirents = dirents
+	}
+	d.invalidate()
+	return sys.ENOENT
+}
+
+// invalidate leaves the cache empty after a failed skipTo, so that the next
+// Read either rewinds or skips again.
+func (d *DirentCache) invalidate() {
+	d.dirents = exhaustedDirents[:]
+	d.windowCookie = 0
+	d.eof = true
+}
+
+// readdir reads up to `n` entries from the underlying file, where less than
+// `n` means EOF. This isn't the case for sys.File Readdir, e.g. os.File skips
+// files deleted between listing the directory and reading their info, which
+// would otherwise cause the remaining entries to be missed.
+func (d *DirentCache) readdir(n int) (dirents []sys.Dirent, errno sys.Errno) {
+	for len(dirents) < n {
+		var next []sys.Dirent
+		if next, errno = d.f.Readdir(n - len(dirents)); errno != 0 {
+			return nil, errno
+		} else if len(next) == 0 {
+			break // EOF
+		} else if dirents == nil {
+			dirents = next
+		} else {
+			dirents = append(dirents, next...)
+		}
+	}
+	return
+}
+
 // cachedDirents returns up to `n` dirents from the cache.
 func (d *DirentCache) cachedDirents(n uint32) []sys.Dirent {
 	direntCount := uint32(len(d.dirents))
@@ -258,6 +376,21 @@ type FSContext struct {
 	// (or directories) and defaults to empty.
 	// TODO: This is unguarded, so not goroutine-safe!
 	openedFiles FileTable
+
+	// spliceEnabled is true when Splice is allowed. See EnableSplice.
+	spliceEnabled bool
+
+	// syncDisabled is true when Sync, Datasync and SyncRange do nothing. See
+	// DisableSync.
+	syncDisabled bool
+
+	// ring is used for IO on files opened by OpenFile, when non-nil. See
+	// EnableIOUring.
+	ring *sysfs.Uring
+
+	// fsClosers are the pre-opened filesystems which need to be closed with
+	// this context, such as sysfs.TempFS.
+	fsClosers []io.Closer
 }
 
 // FileTable is a specialization of the descriptor.Table type used to map file
@@ -282,12 +415,101 @@ func (c *FSContext) LookupFile(fd int32) (*FileEntry, bool) {
 	return c.openedFiles.Lookup(fd)
 }
 
+// InsertFile adds the file to the file table and returns its descriptor.
+func (c *FSContext) InsertFile(f fsapi.File, name string) (int32, sys.Errno) {
+	if newFD, ok := c.openedFiles.Insert(&FileEntry{Name: name, File: f}); !ok {
+		return 0, sys.EBADF
+	} else {
+		return newFD, 0
+	}
+}
+
+// EnableSplice allows Splice, as configured by wazero.FSConfig WithSplice.
+func (c *FSContext) EnableSplice() {
+	c.spliceEnabled = true
+}
+
+// EnableIOUring makes files opened by OpenFile read and write with io_uring,
+// as configured by wazero.FSConfig WithIOUring. This does nothing if the
+// platform or kernel doesn't support io_uring.
+func (c *FSContext) EnableIOUring() {
+	if r, errno := sysfs.NewUring(); errno == 0 {
+		c.ring = r
+	}
+}
+
+// Splice copies up to `n` bytes from the file descriptor `inFd` to `outFd`,
+// without copying through guest memory. See sysfs.Splice for details.
+//
+// This returns sys.ENOSYS unless EnableSplice was called, or when the files
+// don't support it. In either case, the guest should fall back to copying
+// with read and write.
+func (c *FSContext) Splice(outFd, inFd int32, n int64) (int64, sys.Errno) {
+	if !c.spliceEnabled {
+		return 0, sys.ENOSYS
+	}
+	out, ok := c.LookupFile(outFd)
+	if !ok {
+		return 0, sys.EBADF
+	}
+	in, ok := c.LookupFile(inFd)
+	if !ok {
+		return 0, sys.EBADF
+	}
+	return sysfs.Splice(out.File, in.File, n)
+}
+
+// DisableSync makes Sync, Datasync and SyncRange succeed without writing to
+// storage, as configured by wazero.FSConfig WithSync.
+func (c *FSContext) DisableSync() {
+	c.syncDisabled = true
+}
+
+// Sync writes the data and metadata of the file `fd` to storage, like fsync
+// in POSIX, unless DisableSync was called.
+func (c *FSContext) Sync(fd int32) sys.Errno {
+	if f, ok := c.LookupFile(fd); !ok {
+		return sys.EBADF
+	} else if c.syncDisabled {
+		return 0
+	} else {
+		return f.File.Sync()
+	}
+}
+
+// Datasync writes the data of the file `fd` to storage, like fdatasync in
+// POSIX, unless DisableSync was called.
+func (c *FSContext) Datasync(fd int32) sys.Errno {
+	if f, ok := c.LookupFile(fd); !ok {
+		return sys.EBADF
+	} else if c.syncDisabled {
+		return 0
+	} else {
+		return f.File.Datasync()
+	}
+}
+
+// SyncRange starts or waits for writing a range of the file `fd` to storage,
+// unless DisableSync was called. See sysfs.SyncRange for details.
+func (c *FSContext) SyncRange(fd int32, off, n int64, flags int) sys.Errno {
+	if f, ok := c.LookupFile(fd); !ok {
+		return sys.EBADF
+	} else if c.syncDisabled {
+		return 0
+	} else {
+		return sysfs.SyncRange(f.File, off, n, flags)
+	}
+}
+
 // OpenFile opens the file into the table and returns its file descriptor.
 // The result must be closed by CloseFile or Close.
 func (c *FSContext) OpenFile(fs sys.FS, path string, flag sys.Oflag, perm fs.FileMode) (int32, sys.Errno) {
 	if f, errno := fs.OpenFile(path, flag, perm); errno != 0 {
 		return 0, errno
 	} else {
+		if c.ring != nil {
+			sysfs.UseUring(f, c.ring)
+		}
 		fe := &FileEntry{FS: fs, File: fsapi.Adapt(f)}
 		if path == "/" || path == "." {
 			fe.Name = ""
@@ -385,6 +607,17 @@ func (c *FSContext) Close() (err error) {
 	})
 	// A closed FSContext cannot be reused so clear the state.
 	c.openedFiles = FileTable{}
+	if c.ring != nil {
+		_ = c.ring.Close()
+		c.ring = nil
+	}
+	// Close filesystems after their files, as Windows can't remove open files.
+	for _, fs := range c.fsClosers {
+		if e := fs.Close(); e != nil {
+			err = e
+		}
+	}
+	c.fsClosers = nil
 	return
 }
 
@@ -420,6 +653,9 @@ func (c *Context) InitFSContext(
 			guestPath = "/"
 			c.fsc.rootFS = fs
 		}
+		if closer, ok := fs.(io.Closer); ok {
+			c.fsc.fsClosers = append(c.fsc.fsClosers, closer)
+		}
 		c.fsc.openedFiles.Insert(&FileEntry{
 			FS:        fs,
 			Name:      guestPath,
@@ -11,6 +11,7 @@ import (
 	gofstest "testing/fstest"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
 	"github.com/tetratelabs/wazero/internal/fstest"
 	"github.com/tetratelabs/wazero/internal/sysfs"
 	testfs "github.com/tetratelabs/wazero/internal/testing/fs"
@@ -129,6 +130,59 @@ func TestFSContext_CloseFile(t *testing.T) {
 	})
 }
 
+// syncFile counts calls to Sync and Datasync.
+type syncFile struct {
+	sys.UnimplementedFile
+	syncs, datasyncs int
+}
+
+// Sync implements the same method as documented on sys.File
+func (f *syncFile) Sync() sys.Errno {
+	f.syncs++
+	return 0
+}
+
+// Datasync implements the same method as documented on sys.File
+func (f *syncFile) Datasync() sys.Errno {
+	f.datasyncs++
+	return 0
+}
+
+func TestFSContext_Sync(t *testing.T) {
+	for _, disabled := range []bool{false, true} {
+		disabled := disabled
+		t.Run(fmt.Sprintf("disabled=%v", disabled), func(t *testing.T) {
+			c := Context{}
+			err := c.InitFSContext(nil, nil, nil, nil, nil, nil)
+			require.NoError(t, err)
+			fsc := c.fsc
+			defer fsc.Close()
+			if disabled {
+				fsc.DisableSync()
+			}
+
+			f := &syncFile{}
+			fd, ok := fsc.openedFiles.Insert(&FileEntry{File: fsapi.Adapt(f)})
+			require.True(t, ok)
+
+			require.EqualErrno(t, 0, fsc.Sync(fd))
+			require.EqualErrno(t, 0, fsc.Datasync(fd))
+			require.EqualErrno(t, 0, fsc.SyncRange(fd, 0, 0, sysfs.SYNC_FILE_RANGE_WAIT_AFTER))
+			if disabled {
+				require.Equal(t, 0, f.syncs)
+				require.Equal(t, 0, f.datasyncs)
+			} else {
+				require.Equal(t, 1, f.syncs)
+				require.Equal(t, 2, f.datasyncs) // SyncRange falls back to Datasync
+			}
+
+			require.EqualErrno(t, sys.EBADF, fsc.Sync(42))
+			require.EqualErrno(t, sys.EBADF, fsc.Datasync(42))
+			require.EqualErrno(t, sys.EBADF, fsc.SyncRange(42, 0, 0, 0))
+		})
+	}
+}
+
 func TestFSContext_noPreopens(t *testing.T) {
 	c := Context{}
 	err := c.InitFSContext(nil, nil, nil, nil, nil, nil)
@@ -275,7 +329,7 @@ func TestDirentCache_Read(t *testing.T) {
 		initialDir      string
 		dir             func(fd int32)
 		fd              int32
-		pos             uint64
+		cookie          uint64
 		n               uint32
 		expectedDirents []sys.Dirent
 		expectedErrno   sys.Errno
@@ -283,7 +337,7 @@ func TestDirentCache_Read(t *testing.T) {
 		{
 			name:            "empty dir has dot entries",
 			initialDir:      "emptydir",
-			pos:             0,
+			cookie:          0,
 			n:               100,
 			expectedDirents: testDirents[:2],
 		},
@@ -295,21 +349,21 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 5)
 			},
-			pos:             0,
+			cookie:          0,
 			n:               100,
 			expectedDirents: testDirents[:2],
 		},
 		{
 			name:            "full read",
 			initialDir:      "dir",
-			pos:             0,
+			cookie:          0,
 			n:               100,
 			expectedDirents: testDirents,
 		},
 		{
 			name:            "read first",
 			initialDir:      "dir",
-			pos:             0,
+			cookie:          0,
 			n:               1,
 			expectedDirents: testDirents[:1],
 		},
@@ -321,7 +375,7 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			pos:             1,
+			cookie:          DirentCookie(testDirents[0].Name),
 			n:               1,
 			expectedDirents: testDirents[1:2],
 		},
@@ -333,7 +387,7 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 1)
 			},
-			pos:             1,
+			cookie:          DirentCookie(testDirents[0].Name),
 			n:               2,
 			expectedDirents: testDirents[1:3],
 		},
@@ -345,7 +399,7 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 2)
 			},
-			pos:             2,
+			cookie:          DirentCookie(testDirents[1].Name),
 			n:               1,
 			expectedDirents: testDirents[2:3],
 		},
@@ -357,10 +411,36 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 2)
 			},
-			pos:             2,
+			cookie:          DirentCookie(testDirents[1].Name),
 			n:               5,
 			expectedDirents: testDirents[2:],
 		},
+		{
+			name:       "read before cache",
+			initialDir: "dir",
+			dir: func(fd int32) {
+				f, _ := fsc.LookupFile(fd)
+				rdd, _ := f.DirentCache()
+				_, _ = rdd.Read(0, 2)
+				_, _ = rdd.Read(DirentCookie(testDirents[3].Name), 1)
+			},
+			cookie:          DirentCookie(testDirents[0].Name),
+			n:               2,
+			expectedDirents: testDirents[1:3],
+		},
+		{
+			name:       "read before cache past dot entries",
+			initialDir: "dir",
+			dir: func(fd int32) {
+				f, _ := fsc.LookupFile(fd)
+				rdd, _ := f.DirentCache()
+				_, _ = rdd.Read(0, 5)
+				_, _ = rdd.Read(DirentCookie(testDirents[3].Name), 1)
+			},
+			cookie:          DirentCookie(testDirents[2].Name),
+			n:               5,
+			expectedDirents: testDirents[3:],
+		},
 		{
 			name:       "read exhausted directory",
 			initialDir: "dir",
@@ -369,7 +449,7 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 5)
 			},
-			pos:             5,
+			cookie:          DirentCookie(testDirents[4].Name),
 			n:               5,
 			expectedDirents: nil,
 		},
@@ -381,21 +461,21 @@ func TestDirentCache_Read(t *testing.T) {
 				rdd, _ := f.DirentCache()
 				_, _ = rdd.Read(0, 5)
 			},
-			pos:             0,
+			cookie:          0,
 			n:               5,
 			expectedDirents: testDirents,
 		},
 		{
 			name:          "DirentCache: not a dir",
 			initialDir:    "dir/-",
-			pos:           0,
+			cookie:        0,
 			n:             1,
 			expectedErrno: sys.ENOTDIR,
 		},
 		{
-			name:          "pos invalid when no prior state",
+			name:          "cookie invalid when no prior state",
 			initialDir:    "dir",
-			pos:           1,
+			cookie:        1,
 			n:             1,
 			expectedErrno: sys.ENOENT,
 		},
@@ -418,7 +498,7 @@ func TestDirentCache_Read(t *testing.T) {
 				tc.dir(fd)
 			}
 
-			dirents, errno := dir.Read(tc.pos, tc.n)
+			dirents, errno := dir.Read(tc.cookie, tc.n)
 			require.EqualErrno(t, tc.expectedErrno, errno)
 			require.Equal(t, tc.expectedDirents, dirents)
 		})
@@ -461,12 +541,34 @@ func TestDirentCache_ReadNewFile(t *testing.T) {
 	require.Equal(t, "..", dirents[1].Name)
 	require.Equal(t, "file", dirents[2].Name)
 
-	// Read it again, using the file position.
-	filePos := uint64(2)
-	dirents, errno = dir.Read(filePos, 3)
+	// Read it again, using the cookie of the last dot entry.
+	dirents, errno = dir.Read(DirentCookie(".."), 3)
 	require.EqualErrno(t, 0, errno)
 	require.Equal(t, 1, len(dirents))
 	require.Equal(t, "file", dirents[0].Name)
+
+	// Write another file, then read after the first from the beginning.
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file2"), nil, 0o0666))
+	dirents, errno = dir.Read(0, 5)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, len(dirents))
+
+	// Removing an entry doesn't affect the cookies of others, regardless of
+	// their order in the directory. Rewind only caching the dot entries, so
+	// that the removal is visible.
+	first, second := dirents[2].Name, dirents[3].Name
+	_, errno = dir.Read(0, 2)
+	require.EqualErrno(t, 0, errno)
+	require.NoError(t, os.Remove(path.Join(tmpDir, first)))
+	dirents, errno = dir.Read(DirentCookie(".."), 3)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, len(dirents))
+	require.Equal(t, second, dirents[0].Name)
+
+	// However, the cookie of the removed entry is no longer valid once it
+	// isn't cached.
+	_, errno = dir.Read(DirentCookie(first), 3)
+	require.EqualErrno(t, sys.ENOENT, errno)
 }
 
 func TestStripPrefixesAndTrailingSlash(t *testing.T) {
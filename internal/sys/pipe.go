@@ -0,0 +1,308 @@
+package sys
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+type pipeFile struct {
+	noopStdioFile
+}
+
+// Stat implements the same method as documented on sys.File
+func (pipeFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return sys.Stat_t{Mode: fs.ModeNamedPipe | 0o600, Nlink: 1}, 0
+}
+
+// NewPipe returns the read and write ends of an in-memory pipe, which
+// buffers up to `capacity` bytes. The ends can be polled, and set to
+// non-blocking, like a pipe(2) on the host.
+//
+// Writes block while the buffer is full, so a fast writer can't outpace a
+// slow reader. Reads block while it is empty, and return zero bytes once the
+// writer is closed and the buffer drained. Writes fail with sys.EPIPE once
+// the reader is closed.
+func NewPipe(capacity int) (*PipeReader, *PipeWriter) {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	p := &pipe{capacity: capacity, changed: make(chan struct{})}
+	return &PipeReader{pipe: p}, &PipeWriter{pipe: p}
+}
+
+// pipe is the state shared by both ends of NewPipe.
+type pipe struct {
+	mux         sync.Mutex
+	buf         []byte
+	capacity    int
+	readClosed  bool
+	writeClosed bool
+	// changed is closed and replaced when the state changes, to wake waiters.
+	changed chan struct{}
+}
+
+// wake must be called while holding the lock.
+func (p *pipe) wake() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// waitChanged waits for the state to change or the timeout, returning false on
+// timeout. A nil `timeout` waits forever.
+func waitChanged(changed chan struct{}, timeout <-chan time.Time) bool {
+	select {
+	case <-changed:
+		return true
+	case <-timeout:
+		return false
+	}
+}
+
+// newTimeout returns a channel which fires after `timeoutMillis`, or nil if
+// it is negative, which means wait forever.
+func newTimeout(timeoutMillis int32) (<-chan time.Time, func()) {
+	if timeoutMillis < 0 {
+		return nil, func() {}
+	}
+	t := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+	return t.C, func() { t.Stop() }
+}
+
+// PipeReader is the read end of NewPipe.
+type PipeReader struct {
+	pipeFile
+	pipe     *pipe
+	nonblock atomic.Bool
+	closed   bool
+}
+
+// Read implements the same method as documented on sys.File
+func (r *PipeReader) Read(buf []byte) (int, experimentalsys.Errno) {
+	if len(buf) == 0 {
+		return 0, 0
+	}
+	p := r.pipe
+	for {
+		p.mux.Lock()
+		if r.closed {
+			p.mux.Unlock()
+			return 0, experimentalsys.EBADF
+		} else if len(p.buf) > 0 {
+			n := copy(buf, p.buf)
+			p.buf = p.buf[:copy(p.buf, p.buf[n:])]
+			p.wake()
+			p.mux.Unlock()
+			return n, 0
+		} else if p.writeClosed {
+			p.mux.Unlock()
+			return 0, 0 // EOF
+		} else if r.nonblock.Load() {
+			p.mux.Unlock()
+			return 0, experimentalsys.EAGAIN
+		}
+		changed := p.changed
+		p.mux.Unlock()
+		waitChanged(changed, nil)
+	}
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (r *PipeReader) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLIN {
+		return false, experimentalsys.ENOTSUP
+	}
+	timeout, stop := newTimeout(timeoutMillis)
+	defer stop()
+
+	p := r.pipe
+	for {
+		p.mux.Lock()
+		if r.closed {
+			p.mux.Unlock()
+			return false, experimentalsys.EBADF
+		} else if len(p.buf) > 0 || p.writeClosed {
+			p.mux.Unlock()
+			return true, 0
+		}
+		changed := p.changed
+		p.mux.Unlock()
+		if !waitChanged(changed, timeout) {
+			return false, 0
+		}
+	}
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (r *PipeReader) IsNonblock() bool {
+	return r.nonblock.Load()
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (r *PipeReader) SetNonblock(enable bool) experimentalsys.Errno {
+	r.nonblock.Store(enable)
+	return 0
+}
+
+// Close implements the same method as documented on sys.File
+func (r *PipeReader) Close() experimentalsys.Errno {
+	p := r.pipe
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if !r.closed {
+		r.closed, p.readClosed = true, true
+		p.buf = nil
+		p.wake()
+	}
+	return 0
+}
+
+// PipeWriter is the write end of NewPipe.
+type PipeWriter struct {
+	pipeFile
+	pipe     *pipe
+	nonblock atomic.Bool
+	closed   bool
+}
+
+// Write implements the same method as documented on sys.File
+func (w *PipeWriter) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	p := w.pipe
+	for n < len(buf) {
+		p.mux.Lock()
+		if w.closed {
+			p.mux.Unlock()
+			return n, experimentalsys.EBADF
+		} else if p.readClosed {
+			p.mux.Unlock()
+			return n, experimentalsys.EPIPE
+		} else if space := p.capacity - len(p.buf); space > 0 {
+			chunk := buf[n:]
+			if len(chunk) > space {
+				chunk = chunk[:space]
+			}
+			p.buf = append(p.buf, chunk...)
+			n += len(chunk)
+			p.wake()
+			p.mux.Unlock()
+			continue
+		} else if w.nonblock.Load() {
+			p.mux.Unlock()
+			if n == 0 {
+				errno = experimentalsys.EAGAIN
+			}
+			return n, errno
+		}
+		changed := p.changed
+		p.mux.Unlock()
+		waitChanged(changed, nil)
+	}
+	return n, 0
+}
+
+// Poll implements the same method as documented on fsapi.File
+func (w *PipeWriter) Poll(flag fsapi.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != fsapi.POLLOUT {
+		return false, experimentalsys.ENOTSUP
+	}
+	timeout, stop := newTimeout(timeoutMillis)
+	defer stop()
+
+	p := w.pipe
+	for {
+		p.mux.Lock()
+		if w.closed {
+			p.mux.Unlock()
+			return false, experimentalsys.EBADF
+		} else if len(p.buf) < p.capacity || p.readClosed {
+			p.mux.Unlock()
+			return true, 0
+		}
+		changed := p.changed
+		p.mux.Unlock()
+		if !waitChanged(changed, timeout) {
+			return false, 0
+		}
+	}
+}
+
+// IsNonblock implements the same method as documented on fsapi.File
+func (w *PipeWriter) IsNonblock() bool {
+	return w.nonblock.Load()
+}
+
+// SetNonblock implements the same method as documented on fsapi.File
+func (w *PipeWriter) SetNonblock(enable bool) experimentalsys.Errno {
+	w.nonblock.Store(enable)
+	return 0
+}
+
+// Close implements the same method as documented on sys.File
+func (w *PipeWriter) Close() experimentalsys.Errno {
+	p := w.pipe
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if !w.closed {
+		w.closed, p.writeClosed = true, true
+		p.wake()
+	}
+	return 0
+}
+
+// IO returns the reader as an io.ReadCloser, for use outside the guest, such
+// as wazero.ModuleConfig WithStdin. When used as stdin, the guest reads this
+// end directly, so it can poll it.
+func (r *PipeReader) IO() io.ReadCloser {
+	return &pipeIOReader{r}
+}
+
+type pipeIOReader struct {
+	r *PipeReader
+}
+
+// Read implements io.Reader
+func (r *pipeIOReader) Read(buf []byte) (int, error) {
+	n, errno := r.r.Read(buf)
+	if errno != 0 {
+		return n, errno
+	} else if n == 0 && len(buf) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Close implements io.Closer
+func (r *pipeIOReader) Close() error {
+	return r.r.Close()
+}
+
+// IO returns the writer as an io.WriteCloser, for use outside the guest,
+// such as wazero.ModuleConfig WithStdout. When used as stdout or stderr, the
+// guest writes this end directly, so it is closed with the module.
+func (w *PipeWriter) IO() io.WriteCloser {
+	return &pipeIOWriter{w}
+}
+
+type pipeIOWriter struct {
+	w *PipeWriter
+}
+
+// Write implements io.Writer
+func (w *pipeIOWriter) Write(buf []byte) (int, error) {
+	if n, errno := w.w.Write(buf); errno != 0 {
+		return n, errno
+	} else {
+		return n, nil
+	}
+}
+
+// Close implements io.Closer
+func (w *pipeIOWriter) Close() error {
+	return w.w.Close()
+}
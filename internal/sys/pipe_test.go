@@ -0,0 +1,96 @@
+package sys
+
+import (
+	"io"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestNewPipe(t *testing.T) {
+	r, w := NewPipe(4)
+
+	// Empty: not readable, but writable.
+	ready, errno := r.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+	ready, errno = w.Poll(fsapi.POLLOUT, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	require.EqualErrno(t, 0, r.SetNonblock(true))
+	_, errno = r.Read(make([]byte, 4))
+	require.EqualErrno(t, experimentalsys.EAGAIN, errno)
+
+	// Fill the buffer: a non-blocking write is short.
+	require.EqualErrno(t, 0, w.SetNonblock(true))
+	n, errno := w.Write([]byte("hello"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+	_, errno = w.Write([]byte("o"))
+	require.EqualErrno(t, experimentalsys.EAGAIN, errno)
+	ready, errno = w.Poll(fsapi.POLLOUT, 0)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+
+	// A blocking write waits for the reader.
+	require.EqualErrno(t, 0, w.SetNonblock(false))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n, errno := w.Write([]byte("o, world"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 8, n)
+		require.EqualErrno(t, 0, w.Close())
+	}()
+
+	require.EqualErrno(t, 0, r.SetNonblock(false))
+	b, err := io.ReadAll(r.IO())
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", string(b))
+	<-done
+
+	// Closed and drained: readable, returning EOF.
+	ready, errno = r.Poll(fsapi.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+	n, errno = r.Read(make([]byte, 4))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 0, n)
+}
+
+func TestNewPipe_readerClosed(t *testing.T) {
+	r, w := NewPipe(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n, errno := w.Write([]byte("hi"))
+		require.EqualErrno(t, experimentalsys.EPIPE, errno)
+		require.Equal(t, 1, n)
+	}()
+
+	// Wait for the first byte, then close the reader to break the pipe.
+	ready, errno := r.Poll(fsapi.POLLIN, -1)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+	require.EqualErrno(t, 0, r.Close())
+	<-done
+
+	_, errno = r.Read(make([]byte, 1))
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestStdio_pipe(t *testing.T) {
+	r, w := NewPipe(8)
+
+	stdin, err := stdinFileEntry(r.IO())
+	require.NoError(t, err)
+	require.Equal(t, fsapi.File(r), stdin.File)
+
+	stdout, err := stdioWriterFileEntry("stdout", w.IO())
+	require.NoError(t, err)
+	require.Equal(t, fsapi.File(w), stdout.File)
+}
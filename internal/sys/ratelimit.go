@@ -0,0 +1,138 @@
+package sys
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// RateLimit is a token bucket which throttles by sleeping: consuming more
+// tokens than available sleeps until they are replenished.
+//
+// Tokens can be borrowed, so a single request larger than the burst succeeds,
+// but delays the ones after it.
+type RateLimit struct {
+	// perSecond is the rate tokens are replenished.
+	perSecond float64
+	// burst is the maximum tokens which can accumulate.
+	burst float64
+
+	// now and sleep are variables for testing.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration)
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// Counters of RateLimitMetrics.
+	consumed, throttled atomic.Uint64
+	throttledTime       atomic.Int64
+}
+
+// NewRateLimit returns a RateLimit which allows `perSecond` tokens per
+// second, and up to `burst` at once. A burst less than one defaults to
+// `perSecond`.
+func NewRateLimit(perSecond, burst int64) *RateLimit {
+	if burst < 1 {
+		burst = perSecond
+	}
+	l := &RateLimit{perSecond: float64(perSecond), burst: float64(burst), now: time.Now, sleep: sleep}
+	l.tokens, l.last = l.burst, l.now()
+	return l
+}
+
+// Wait consumes `n` tokens, sleeping until the rate limit allows them or
+// `ctx` is done.
+func (l *RateLimit) Wait(ctx context.Context, n int64) {
+	if n <= 0 {
+		return
+	}
+	l.consumed.Add(uint64(n))
+
+	l.mu.Lock()
+	now := l.now()
+	if l.tokens += now.Sub(l.last).Seconds() * l.perSecond; l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	tokens := l.tokens
+	l.mu.Unlock()
+
+	if tokens >= 0 {
+		return
+	}
+	d := time.Duration(-tokens / l.perSecond * float64(time.Second))
+	l.throttled.Add(1)
+	l.throttledTime.Add(int64(d))
+	l.sleep(ctx, d)
+}
+
+// Metrics returns the count of tokens consumed, the count of calls to Wait
+// which slept and the total duration they slept.
+func (l *RateLimit) Metrics() (consumed, throttled uint64, throttledTime time.Duration) {
+	return l.consumed.Load(), l.throttled.Load(), time.Duration(l.throttledTime.Load())
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// SetRateLimits sets the rate limits of IO and host calls, either of which
+// may be nil.
+//
+// See wazero.ModuleConfig WithIORateLimit and WithHostCallRateLimit
+func (c *Context) SetRateLimits(io, hostCall *RateLimit) {
+	c.ioLimit, c.hostCallLimit = io, hostCall
+}
+
+// RateLimits returns the rate limits of IO and host calls, either of which
+// may be nil.
+func (c *Context) RateLimits() (io, hostCall *RateLimit) {
+	return c.ioLimit, c.hostCallLimit
+}
+
+// ThrottleIO waits until the IO rate limit, if any, allows `n` bytes read or
+// written.
+func (c *Context) ThrottleIO(ctx context.Context, n int64) {
+	if l := c.ioLimit; l != nil {
+		l.Wait(ctx, n)
+	}
+}
+
+// ThrottleHostCall waits until the host call rate limit, if any, allows a
+// call to a host function.
+func (c *Context) ThrottleHostCall(ctx context.Context) {
+	if l := c.hostCallLimit; l != nil {
+		l.Wait(ctx, 1)
+	}
+}
+
+// SetRandLimits limits the random bytes read per call, when
+// `maxBytesPerCall` is positive, and per second, when `limit` is non-nil.
+//
+// See wazero.ModuleConfig WithRandLimit
+func (c *Context) SetRandLimits(maxBytesPerCall int64, limit *RateLimit) {
+	c.randMaxPerCall, c.randLimit = maxBytesPerCall, limit
+}
+
+// ThrottleRandom returns sys.EINVAL if `n` random bytes are more than allowed
+// per call, or waits until the random rate limit, if any, allows them.
+func (c *Context) ThrottleRandom(ctx context.Context, n int64) experimentalsys.Errno {
+	if max := c.randMaxPerCall; max > 0 && n > max {
+		return experimentalsys.EINVAL
+	}
+	if l := c.randLimit; l != nil {
+		l.Wait(ctx, n)
+	}
+	return 0
+}
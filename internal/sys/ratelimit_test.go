@@ -0,0 +1,102 @@
+package sys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func TestRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	var slept []time.Duration
+	l := NewRateLimit(100, 10)
+	l.now = func() time.Time { return now }
+	l.last = now
+	l.sleep = func(_ context.Context, d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	// The burst is available at once.
+	l.Wait(testCtx, 10)
+	require.Zero(t, len(slept))
+
+	// Past the burst, waits for the tokens to be replenished.
+	l.Wait(testCtx, 1)
+	require.Equal(t, []time.Duration{10 * time.Millisecond}, slept)
+
+	// A request larger than the burst is allowed, but delays the next.
+	l.Wait(testCtx, 20)
+	require.Equal(t, 200*time.Millisecond, slept[1])
+
+	// Tokens accumulate up to the burst.
+	now = now.Add(time.Hour)
+	l.Wait(testCtx, 10)
+	require.Equal(t, 2, len(slept))
+	l.Wait(testCtx, 5)
+	require.Equal(t, 50*time.Millisecond, slept[2])
+
+	// Zero doesn't consume.
+	l.Wait(testCtx, 0)
+	require.Equal(t, 3, len(slept))
+
+	consumed, throttled, throttledTime := l.Metrics()
+	require.Equal(t, uint64(46), consumed)
+	require.Equal(t, uint64(3), throttled)
+	require.Equal(t, 260*time.Millisecond, throttledTime)
+}
+
+func TestRateLimit_defaultBurst(t *testing.T) {
+	l := NewRateLimit(100, 0)
+	require.Equal(t, float64(100), l.burst)
+}
+
+func TestRateLimit_sleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(testCtx)
+	cancel()
+
+	// This returns instead of sleeping for an hour.
+	sleep(ctx, time.Hour)
+}
+
+func TestContext_Throttle(t *testing.T) {
+	c := &Context{}
+
+	// No limits does nothing.
+	c.ThrottleIO(testCtx, 1)
+	c.ThrottleHostCall(testCtx)
+	io, hostCall := c.RateLimits()
+	require.Nil(t, io)
+	require.Nil(t, hostCall)
+
+	c.SetRateLimits(NewRateLimit(100, 0), NewRateLimit(10, 0))
+	c.ThrottleIO(testCtx, 5)
+	c.ThrottleHostCall(testCtx)
+
+	io, hostCall = c.RateLimits()
+	consumed, _, _ := io.Metrics()
+	require.Equal(t, uint64(5), consumed)
+	consumed, _, _ = hostCall.Metrics()
+	require.Equal(t, uint64(1), consumed)
+}
+
+func TestContext_ThrottleRandom(t *testing.T) {
+	c := &Context{}
+
+	// No limits allows any size.
+	require.EqualErrno(t, 0, c.ThrottleRandom(testCtx, 1<<20))
+
+	limit := NewRateLimit(100, 0)
+	c.SetRandLimits(8, limit)
+	require.EqualErrno(t, experimentalsys.EINVAL, c.ThrottleRandom(testCtx, 9))
+	require.EqualErrno(t, 0, c.ThrottleRandom(testCtx, 8))
+
+	consumed, _, _ := limit.Metrics()
+	require.Equal(t, uint64(8), consumed) // the denied call didn't consume
+}
@@ -0,0 +1,41 @@
+package sys
+
+import "github.com/tetratelabs/wazero/internal/descriptor"
+
+// ResourceTable maps the handles of component model resources, such as the
+// streams of WASI preview2, to their values. Handles start at one, as the
+// canonical ABI reserves zero.
+//
+// Note: Like FSContext, this is unguarded, so not goroutine-safe.
+type ResourceTable struct {
+	table descriptor.Table[int32, interface{}]
+}
+
+// Insert adds the resource to the table and returns its handle, or false if
+// the table is full.
+func (t *ResourceTable) Insert(resource interface{}) (uint32, bool) {
+	key, ok := t.table.Insert(resource)
+	return uint32(key) + 1, ok
+}
+
+// Lookup returns the resource of the handle, if it is in the table.
+func (t *ResourceTable) Lookup(handle uint32) (interface{}, bool) {
+	return t.table.Lookup(int32(handle) - 1)
+}
+
+// Delete removes the resource of the handle and returns it, if it was in the
+// table.
+func (t *ResourceTable) Delete(handle uint32) (interface{}, bool) {
+	resource, ok := t.Lookup(handle)
+	if ok {
+		t.table.Delete(int32(handle) - 1)
+	}
+	return resource, ok
+}
+
+// Resources returns the table of resources of the module, which is empty
+// unless a host module using the component model, such as WASI preview2,
+// inserted some.
+func (c *Context) Resources() *ResourceTable {
+	return &c.resources
+}
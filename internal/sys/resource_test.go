@@ -0,0 +1,39 @@
+package sys
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestResourceTable(t *testing.T) {
+	var table ResourceTable
+
+	// Handles start at one, as zero is reserved.
+	h1, ok := table.Insert("a")
+	require.True(t, ok)
+	require.Equal(t, uint32(1), h1)
+	h2, ok := table.Insert("b")
+	require.True(t, ok)
+	require.Equal(t, uint32(2), h2)
+
+	r, ok := table.Lookup(h2)
+	require.True(t, ok)
+	require.Equal(t, "b", r)
+
+	_, ok = table.Lookup(0)
+	require.False(t, ok)
+	_, ok = table.Lookup(3)
+	require.False(t, ok)
+
+	r, ok = table.Delete(h1)
+	require.True(t, ok)
+	require.Equal(t, "a", r)
+	_, ok = table.Delete(h1)
+	require.False(t, ok)
+
+	// The handle of a deleted resource is reused.
+	h3, ok := table.Insert("c")
+	require.True(t, ok)
+	require.Equal(t, h1, h3)
+}
@@ -0,0 +1,106 @@
+package sys
+
+import (
+	"sync/atomic"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// Signal numbers are those of the signal enum in wasi_snapshot_preview1,
+// which are the same as Linux on x86 up to SIGSYS, except SIGCHLD.
+const (
+	sigKill  = 9
+	sigChld  = 16
+	sigCont  = 17
+	sigStop  = 18
+	sigTstp  = 19
+	sigTtin  = 20
+	sigTtou  = 21
+	sigUrg   = 22
+	sigWinch = 27
+	// sigMax is SIGSYS, the last signal.
+	sigMax = 30
+)
+
+// signals is the emulated signal state of a module. See wazero.ModuleConfig
+// WithSignalHandler.
+type signals struct {
+	// handler is the name of the function the guest exports to handle
+	// signals, or empty if it doesn't.
+	handler string
+	// pending is a bitmask of signals raised by the host, but not yet
+	// delivered to the guest.
+	pending atomic.Uint64
+	// delivering is true while pending signals are being handled, so that
+	// signals raised meanwhile are delivered after, instead of nesting.
+	delivering atomic.Bool
+}
+
+// SetSignalHandler sets the name of the function the guest exports to
+// handle signals, as configured by wazero.ModuleConfig WithSignalHandler.
+func (c *Context) SetSignalHandler(exportName string) {
+	c.signals.handler = exportName
+}
+
+// SignalHandler returns the name of the function the guest exports to handle
+// signals, or empty if not configured.
+func (c *Context) SignalHandler() string {
+	return c.signals.handler
+}
+
+// ValidateSignal returns sys.EINVAL if `sig` isn't a valid signal number.
+func ValidateSignal(sig uint32) experimentalsys.Errno {
+	if sig == 0 || sig > sigMax {
+		return experimentalsys.EINVAL
+	}
+	return 0
+}
+
+// SignalTerminates returns true if the default action of the signal is to
+// terminate the process. The others are ignored, as stopping and continuing
+// aren't emulated.
+func SignalTerminates(sig uint32) bool {
+	switch sig {
+	case sigChld, sigCont, sigStop, sigTstp, sigTtin, sigTtou, sigUrg, sigWinch:
+		return false
+	}
+	return true
+}
+
+// SignalHandled returns true if `sig` is delivered to the signal handler, if
+// any, instead of its default action. SIGKILL can't be handled.
+func SignalHandled(sig uint32) bool {
+	return sig != sigKill
+}
+
+// QueueSignal records `sig` as pending, to be delivered by TakeSignals. The
+// signal must be valid. Like POSIX, a signal raised again before it is
+// delivered is only delivered once.
+func (c *Context) QueueSignal(sig uint32) {
+	bit := uint64(1) << sig
+	for {
+		old := c.signals.pending.Load()
+		if c.signals.pending.CompareAndSwap(old, old|bit) {
+			return
+		}
+	}
+}
+
+// TakeSignals returns the bitmask of pending signals, clearing it, or zero if
+// there are none or signals are already being delivered. When non-zero, the
+// caller must call DoneSignals after delivering them.
+func (c *Context) TakeSignals() uint64 {
+	if c.signals.pending.Load() == 0 || !c.signals.delivering.CompareAndSwap(false, true) {
+		return 0
+	}
+	if pending := c.signals.pending.Swap(0); pending != 0 {
+		return pending
+	}
+	c.signals.delivering.Store(false)
+	return 0
+}
+
+// DoneSignals ends delivery of signals returned by TakeSignals.
+func (c *Context) DoneSignals() {
+	c.signals.delivering.Store(false)
+}
@@ -24,6 +24,39 @@ func (f *StdinFile) Read(buf []byte) (int, experimentalsys.Errno) {
 	return n, experimentalsys.UnwrapOSError(err)
 }
 
+// GeneratorReader is an io.Reader which reads from chunks returned by a
+// generator function, as configured by wazero.ModuleConfig
+// WithStdinGenerator. The generator is called lazily, only when the guest
+// reads and previous chunks are exhausted.
+type GeneratorReader struct {
+	next func() ([]byte, error)
+	buf  []byte
+	err  error
+}
+
+// NewGeneratorReader returns a GeneratorReader which calls next for more
+// input. next returns io.EOF to end input.
+func NewGeneratorReader(next func() ([]byte, error)) *GeneratorReader {
+	return &GeneratorReader{next: next}
+}
+
+// Read implements io.Reader
+func (r *GeneratorReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// Skip empty chunks, so that a guest doesn't see them as EOF.
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf, r.err = r.next()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
 type writerFile struct {
 	noopStdoutFile
 
@@ -108,6 +141,8 @@ func stdinFileEntry(r io.Reader) (*FileEntry, error) {
 		} else {
 			return &FileEntry{Name: "stdin", IsPreopen: true, File: f}, nil
 		}
+	} else if p, ok := r.(*pipeIOReader); ok {
+		return &FileEntry{Name: "stdin", IsPreopen: true, File: p.r}, nil
 	} else {
 		return &FileEntry{Name: "stdin", IsPreopen: true, File: &StdinFile{Reader: r}}, nil
 	}
@@ -122,6 +157,8 @@ func stdioWriterFileEntry(name string, w io.Writer) (*FileEntry, error) {
 		} else {
 			return &FileEntry{Name: name, IsPreopen: true, File: f}, nil
 		}
+	} else if p, ok := w.(*pipeIOWriter); ok {
+		return &FileEntry{Name: name, IsPreopen: true, File: p.w}, nil
 	} else {
 		return &FileEntry{Name: name, IsPreopen: true, File: &writerFile{w: w}}, nil
 	}
@@ -26,6 +26,44 @@ type Context struct {
 	osyield            sys.Osyield
 	randSource         io.Reader
 	fsc                FSContext
+
+	// capabilities are the tokens granted to the module. See HasCapability.
+	capabilities map[string]struct{}
+
+	// ioLimit and hostCallLimit are nil unless rate limited. See
+	// SetRateLimits.
+	ioLimit, hostCallLimit *RateLimit
+
+	// randMaxPerCall is zero and randLimit nil unless random bytes are
+	// limited. See SetRandLimits.
+	randMaxPerCall int64
+	randLimit      *RateLimit
+
+	signals signals
+
+	// resources are the handles of component model resources. See Resources.
+	resources ResourceTable
+}
+
+// GrantCapabilities grants the tokens to the module, in addition to any
+// already granted.
+//
+// See wazero.ModuleConfig WithCapabilities
+func (c *Context) GrantCapabilities(tokens ...string) {
+	if c.capabilities == nil {
+		c.capabilities = make(map[string]struct{}, len(tokens))
+	}
+	for _, t := range tokens {
+		c.capabilities[t] = struct{}{}
+	}
+}
+
+// HasCapability returns true if the token was granted.
+//
+// See wazero.ModuleConfig WithCapabilities
+func (c *Context) HasCapability(token string) bool {
+	_, ok := c.capabilities[token]
+	return ok
 }
 
 // Args is like os.Args and defaults to nil.
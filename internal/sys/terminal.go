@@ -0,0 +1,121 @@
+package sys
+
+import (
+	"io/fs"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fsapi"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Termios is the subset of the POSIX termios structure emulated by Terminal.
+//
+// See https://man7.org/linux/man-pages/man3/termios.3.html
+type Termios struct {
+	Iflag, Oflag, Cflag, Lflag uint32
+	Cc                         [32]byte
+}
+
+// defaultTermios is the same as Emscripten's default for a TTY, which is a
+// cooked (canonical) terminal with echo.
+//
+// See https://github.com/emscripten-core/emscripten/blob/3.1.44/src/library_tty.js#L12-L19
+var defaultTermios = Termios{
+	Iflag: 25856,
+	Oflag: 5,
+	Cflag: 191,
+	Lflag: 35387,
+	Cc: [32]byte{
+		0x03, 0x1c, 0x7f, 0x15, 0x04, 0x00, 0x01, 0x00,
+		0x11, 0x13, 0x1a, 0x00, 0x12, 0x0f, 0x17, 0x16,
+	},
+}
+
+// Terminal is the emulated state of the terminal stdio is attached to, as
+// configured by wazero.ModuleConfig WithTerminal. It is shared by stdin,
+// stdout and stderr, so changes made via one descriptor are visible from the
+// others, like a real TTY.
+type Terminal struct {
+	mux        sync.Mutex
+	rows, cols uint16
+	termios    Termios
+}
+
+// NewTerminal returns a Terminal of the given size with default termios.
+func NewTerminal(rows, cols uint16) *Terminal {
+	return &Terminal{rows: rows, cols: cols, termios: defaultTermios}
+}
+
+// Winsize returns the current size of the terminal.
+func (t *Terminal) Winsize() (rows, cols uint16) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.rows, t.cols
+}
+
+// SetWinsize changes the size of the terminal, e.g. via TIOCSWINSZ.
+func (t *Terminal) SetWinsize(rows, cols uint16) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.rows, t.cols = rows, cols
+}
+
+// Termios returns the current terminal attributes.
+func (t *Terminal) Termios() Termios {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return t.termios
+}
+
+// SetTermios changes the terminal attributes, e.g. to raw mode via TCSETS.
+//
+// Note: These are only recorded for the guest to read back. Input is neither
+// echoed nor line buffered by the host, regardless of the flags.
+func (t *Terminal) SetTermios(termios Termios) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.termios = termios
+}
+
+// ttyFile is a stdio file which reports itself as a character device, so
+// that guests such as wasi-libc consider it a TTY (isatty).
+type ttyFile struct {
+	fsapi.File
+	terminal *Terminal
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *ttyFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return st, errno
+	}
+	st.Mode = st.Mode&fs.ModePerm | fs.ModeDevice | fs.ModeCharDevice
+	return st, 0
+}
+
+// EnableTerminal makes stdin, stdout and stderr emulate a terminal of the
+// given size, as configured by wazero.ModuleConfig WithTerminal. This must be
+// called before the guest runs.
+func (c *FSContext) EnableTerminal(rows, cols uint16) {
+	t := NewTerminal(rows, cols)
+	for fd := FdStdin; fd <= FdStderr; fd++ {
+		if f, ok := c.openedFiles.Lookup(fd); ok {
+			f.File = &ttyFile{File: f.File, terminal: t}
+		}
+	}
+}
+
+// Terminal returns the terminal the file descriptor is attached to, or
+// sys.ENOTTY if it isn't one.
+func (c *FSContext) Terminal(fd int32) (*Terminal, experimentalsys.Errno) {
+	f, ok := c.openedFiles.Lookup(fd)
+	if !ok {
+		return nil, experimentalsys.EBADF
+	}
+	if tty, ok := f.File.(*ttyFile); ok {
+		return tty.terminal, 0
+	}
+	return nil, experimentalsys.ENOTTY
+}
@@ -0,0 +1,71 @@
+package sys
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFSContext_EnableTerminal(t *testing.T) {
+	sysCtx := DefaultContext(nil)
+	fsc := sysCtx.FS()
+
+	_, errno := fsc.Terminal(FdStdout)
+	require.EqualErrno(t, experimentalsys.ENOTTY, errno)
+
+	fsc.EnableTerminal(24, 80)
+
+	var terminal *Terminal
+	for fd := FdStdin; fd <= FdStderr; fd++ {
+		f, ok := fsc.LookupFile(fd)
+		require.True(t, ok)
+		st, errno := f.File.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.ModeDevice|fs.ModeCharDevice, st.Mode.Type())
+
+		tty, errno := fsc.Terminal(fd)
+		require.EqualErrno(t, 0, errno)
+		if terminal == nil {
+			terminal = tty
+		}
+		require.Equal(t, terminal, tty) // shared by stdio
+	}
+
+	rows, cols := terminal.Winsize()
+	require.Equal(t, uint16(24), rows)
+	require.Equal(t, uint16(80), cols)
+	require.Equal(t, defaultTermios, terminal.Termios())
+
+	_, errno = fsc.Terminal(FdPreopen)
+	require.EqualErrno(t, experimentalsys.ENOTTY, errno)
+	_, errno = fsc.Terminal(42)
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestGeneratorReader(t *testing.T) {
+	chunks := [][]byte{[]byte("print(1)\n"), nil, []byte("quit()\n")}
+	r := NewGeneratorReader(func() ([]byte, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+		chunk := chunks[0]
+		chunks = chunks[1:]
+		return chunk, nil
+	})
+
+	buf := make([]byte, 5)
+	var got []string
+	for {
+		n, err := r.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, string(buf[:n]))
+	}
+	// Reads don't span chunks, so that each line is read as it's generated.
+	require.Equal(t, []string{"print", "(1)\n", "quit(", ")\n"}, got)
+}
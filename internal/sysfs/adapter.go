@@ -11,6 +11,19 @@ import (
 
 type AdaptFS struct {
 	FS fs.FS
+
+	// Dev is the device ID of files which don't have one, such as those in
+	// an fstest.MapFS. When non-zero, these files are also given a stable
+	// inode derived from their path, so that guests can identify them by
+	// (Dev, Ino). See SyntheticDev
+	Dev uint64
+}
+
+// SyntheticDev returns a device ID for AdaptFS.Dev, derived from the guest
+// path the file system is mounted at. The high bit is set to avoid clashes
+// with real device IDs.
+func SyntheticDev(guestPath string) uint64 {
+	return 1<<63 | syntheticIno(guestPath)
 }
 
 // String implements fmt.Stringer
@@ -20,7 +33,11 @@ func (a *AdaptFS) String() string {
 
 // OpenFile implements the same method as documented on sys.FS
 func (a *AdaptFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
-	return OpenFSFile(a.FS, cleanPath(path), flag, perm)
+	f, errno := OpenFSFile(a.FS, cleanPath(path), flag, perm)
+	if errno == 0 {
+		f.(*fsFile).syntheticDev = a.Dev
+	}
+	return f, errno
 }
 
 // Lstat implements the same method as documented on sys.FS
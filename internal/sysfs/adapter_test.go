@@ -130,6 +130,43 @@ func TestAdaptFS_Stat(t *testing.T) {
 	testStat(t, testFS)
 }
 
+func TestAdaptFS_Stat_SyntheticIno(t *testing.T) {
+	dev := SyntheticDev("data")
+	testFS := &AdaptFS{FS: fstest.FS, Dev: dev}
+
+	st, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, dev, st.Dev)
+	require.NotEqual(t, uint64(0), st.Ino)
+
+	// The inode is stable across opens.
+	st2, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Ino, st2.Ino)
+
+	// The inode differs per path.
+	sub, errno := testFS.Stat("sub")
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, st.Ino, sub.Ino)
+
+	// The inode read from the directory is the same as its stat.
+	f, errno := testFS.OpenFile("sub", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	dirents, errno := f.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, len(dirents))
+	st, errno = testFS.Stat("sub/" + dirents[0].Name)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Ino, dirents[0].Ino)
+
+	// Without a device ID, the fs.FS is passed through as-is.
+	st, errno = (&AdaptFS{FS: fstest.FS}).Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint64(0), st.Dev)
+	require.Equal(t, uint64(0), st.Ino)
+}
+
 // hackFS cheats the fs.FS contract by opening for write (sys.O_RDWR).
 //
 // Until we have an alternate public interface for filesystems, some users will
@@ -3,6 +3,7 @@ package sysfs
 import (
 	"io/fs"
 	"os"
+	"strings"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/platform"
@@ -58,11 +59,27 @@ func (d *dirFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
 func (d *dirFS) Mkdir(path string, perm fs.FileMode) (errno experimentalsys.Errno) {
 	err := os.Mkdir(d.join(path), perm)
 	if errno = experimentalsys.UnwrapOSError(err); errno == experimentalsys.ENOTDIR {
-		errno = experimentalsys.ENOENT
+		errno = d.ancestorErrno(path)
 	}
 	return
 }
 
+// ancestorErrno returns ENOTDIR if the closest existing ancestor of the path
+// is a file, or ENOENT if it is a directory. This disambiguates ENOTDIR, as
+// Windows also returns it when an ancestor doesn't exist.
+func (d *dirFS) ancestorErrno(path string) experimentalsys.Errno {
+	for i := strings.LastIndexByte(path, '/'); i > 0; i = strings.LastIndexByte(path, '/') {
+		path = path[:i]
+		if st, errno := stat(d.join(path)); errno == 0 {
+			if st.Mode.IsDir() {
+				return experimentalsys.ENOENT
+			}
+			return experimentalsys.ENOTDIR
+		}
+	}
+	return experimentalsys.ENOENT
+}
+
 // Chmod implements the same method as documented on sys.FS
 func (d *dirFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
 	err := os.Chmod(d.join(path), perm)
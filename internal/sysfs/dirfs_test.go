@@ -107,6 +107,14 @@ func TestDirFS_MkDir(t *testing.T) {
 		err := testFS.Mkdir(filePath, fs.ModeDir)
 		require.EqualErrno(t, sys.ENOENT, err)
 	})
+	t.Run("parent is a file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), nil, 0o600))
+
+		err := testFS.Mkdir(path.Join("file", "dir"), fs.ModeDir)
+		require.EqualErrno(t, sys.ENOTDIR, err)
+		err = testFS.Mkdir(path.Join("file", "dir", "dir"), fs.ModeDir)
+		require.EqualErrno(t, sys.ENOTDIR, err)
+	})
 
 	// Remove the path so that we can test creating it with perms.
 	require.NoError(t, os.Remove(realPath))
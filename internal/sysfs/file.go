@@ -1,9 +1,11 @@
 package sysfs
 
 import (
+	"hash/fnv"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"time"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
@@ -118,6 +120,21 @@ type fsFile struct {
 
 	// cachedStat includes fields that won't change while a file is open.
 	cachedSt *cachedStat
+
+	// syntheticDev is AdaptFS.Dev. When non-zero and the file has no inode,
+	// this is its device ID and the inode is derived from its name.
+	syntheticDev uint64
+}
+
+// syntheticIno returns a stable, non-zero inode for the file name, for
+// files in an fs.FS which doesn't return inodes.
+func syntheticIno(name string) sys.Inode {
+	h := fnv.New64a()
+	h.Write([]byte(name)) //nolint
+	if ino := h.Sum64(); ino != 0 {
+		return ino
+	}
+	return 1
 }
 
 type cachedStat struct {
@@ -179,6 +196,9 @@ func (f *fsFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
 	st, errno := statFile(f.file)
 	switch errno {
 	case 0:
+		if st.Ino == 0 && f.syntheticDev != 0 {
+			st.Dev, st.Ino = f.syntheticDev, syntheticIno(f.name)
+		}
 		f.cachedSt = &cachedStat{dev: st.Dev, ino: st.Ino, isDir: st.Mode&fs.ModeDir == fs.ModeDir}
 	case experimentalsys.EIO:
 		errno = experimentalsys.EBADF
@@ -206,7 +226,9 @@ func (f *fsFile) Pread(buf []byte, off int64) (n int, errno experimentalsys.Errn
 	}
 
 	// See /RATIONALE.md "fd_pread: io.Seeker fallback when io.ReaderAt is not supported"
-	if rs, ok := f.file.(io.ReadSeeker); ok {
+	if off < 0 {
+		errno = experimentalsys.EINVAL
+	} else if rs, ok := f.file.(io.ReadSeeker); ok {
 		// Determine the current position in the file, as we need to revert it.
 		currentOffset, err := rs.Seek(0, io.SeekCurrent)
 		if err != nil {
@@ -296,8 +318,16 @@ func (f *fsFile) Readdir(n int) (dirents []experimentalsys.Dirent, errno experim
 		}
 		dirents = make([]experimentalsys.Dirent, 0, len(entries))
 		for _, e := range entries {
-			// By default, we don't attempt to read inode data
-			dirents = append(dirents, experimentalsys.Dirent{Name: e.Name(), Type: e.Type()})
+			// Use the inode from the backing FS when it is available, e.g.
+			// fs.FileInfo.Sys returns sys.Stat_t.
+			var ino sys.Inode
+			if info, err := e.Info(); err == nil {
+				ino, _ = inoFromFileInfo("", info)
+			}
+			if ino == 0 && f.syntheticDev != 0 {
+				ino = syntheticIno(path.Join(f.name, e.Name()))
+			}
+			dirents = append(dirents, experimentalsys.Dirent{Name: e.Name(), Ino: ino, Type: e.Type()})
 		}
 	} else {
 		errno = experimentalsys.EBADF // not a directory
@@ -404,7 +434,9 @@ func read(r io.Reader, buf []byte) (n int, errno experimentalsys.Errno) {
 }
 
 func pread(ra io.ReaderAt, buf []byte, off int64) (n int, errno experimentalsys.Errno) {
-	if len(buf) == 0 {
+	if off < 0 {
+		return 0, experimentalsys.EINVAL // e.g. a uint64 offset larger than math.MaxInt64
+	} else if len(buf) == 0 {
 		return 0, 0 // less overhead on zero-length reads.
 	}
 
@@ -474,7 +506,9 @@ func write(w io.Writer, buf []byte) (n int, errno experimentalsys.Errno) {
 }
 
 func pwrite(w io.WriterAt, buf []byte, off int64) (n int, errno experimentalsys.Errno) {
-	if len(buf) == 0 {
+	if off < 0 {
+		return 0, experimentalsys.EINVAL // e.g. a uint64 offset larger than math.MaxInt64
+	} else if len(buf) == 0 {
 		return 0, 0 // less overhead on zero-length writes.
 	}
 
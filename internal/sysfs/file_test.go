@@ -970,6 +970,54 @@ func TestFileTruncate(t *testing.T) {
 	})
 }
 
+// TestFile_largeFile ensures offsets past 4GiB work, using a sparse file.
+func TestFile_largeFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("NTFS zero-fills the gap unless the file is marked sparse")
+	}
+
+	const offset = int64(5 << 30) // 5GiB
+	path := path.Join(t.TempDir(), wazeroFile)
+	f := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	defer f.Close()
+
+	requirePwrite(t, f, []byte("wazero"), offset)
+
+	newOffset, errno := f.Seek(0, io.SeekEnd)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, offset+6, newOffset)
+
+	buf := make([]byte, 6)
+	n, errno := f.Pread(buf, offset)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazero", string(buf[:n]))
+
+	// Reading the hole returns zeros.
+	n, errno = f.Pread(buf, 4<<30)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, make([]byte, 6), buf[:n])
+
+	if vf, ok := f.(VectoredFile); ok {
+		bufs := [][]byte{make([]byte, 3), make([]byte, 3)}
+		n, errno = vf.Preadv(bufs, offset)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+		require.Equal(t, "wazero", string(bufs[0])+string(bufs[1]))
+	}
+
+	errno = f.Truncate(6 << 30)
+	require.EqualErrno(t, 0, errno)
+	st, errno := f.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6<<30), st.Size)
+
+	// Offsets larger than math.MaxInt64 wrap negative, so are invalid.
+	_, errno = f.Pread(buf, -1<<63)
+	require.EqualErrno(t, experimentalsys.EINVAL, errno)
+	_, errno = f.Pwrite(buf, -1<<63)
+	require.EqualErrno(t, experimentalsys.EINVAL, errno)
+}
+
 func TestFileUtimens(t *testing.T) {
 	switch runtime.GOOS {
 	case "linux", "darwin": // supported
@@ -83,6 +83,6 @@ func peekNamedPipe(handle syscall.Handle) (uint32, syscall.Errno) {
 }
 
 func rmdir(path string) sys.Errno {
-	err := syscall.Rmdir(path)
+	err := syscall.Rmdir(fixLongPath(path))
 	return sys.UnwrapOSError(err)
 }
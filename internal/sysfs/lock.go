@@ -0,0 +1,60 @@
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+// Operations of LockFile.Flock, which have the same values as Linux and
+// Emscripten.
+const (
+	// LOCK_SH places a shared lock, which more than one file can hold.
+	LOCK_SH = 0x1
+	// LOCK_EX places an exclusive lock, which only one file can hold.
+	LOCK_EX = 0x2
+	// LOCK_NB returns EAGAIN instead of blocking when the lock is held.
+	LOCK_NB = 0x4
+	// LOCK_UN removes the lock held by this file.
+	LOCK_UN = 0x8
+)
+
+// LockFile is implemented by files which support advisory locks, such as
+// those opened from a directory on the host.
+type LockFile interface {
+	// Flock places or removes an advisory lock on the whole file.
+	//
+	// # Parameters
+	//
+	// `how` is LOCK_SH, LOCK_EX or LOCK_UN, optionally combined with LOCK_NB.
+	//
+	// # Errors
+	//
+	// A zero Errno is success. The below are expected otherwise:
+	//   - ENOSYS: the platform does not support locks.
+	//   - EBADF: the file was closed.
+	//   - EINVAL: `how` is invalid.
+	//   - EAGAIN: LOCK_NB was set, and the lock is held by another file.
+	//
+	// # Notes
+	//
+	//   - This is like `flock` in BSD, and is implemented with `LockFileEx`
+	//     on Windows. See https://man7.org/linux/man-pages/man2/flock.2.html
+	//   - Like `flock`, the lock is associated with the open file, so it is
+	//     shared by module instances which share it, and released on Close.
+	//   - Locks are advisory, so don't prevent reads or writes of files which
+	//     don't also use Flock.
+	Flock(how int) experimentalsys.Errno
+}
+
+// compile-time check to ensure osFile implements LockFile.
+var _ LockFile = (*osFile)(nil)
+
+// Flock implements LockFile.Flock
+func (f *osFile) Flock(how int) experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	switch how &^ LOCK_NB {
+	case LOCK_SH, LOCK_EX, LOCK_UN:
+	default:
+		return experimentalsys.EINVAL
+	}
+	return flock(f.fd, how)
+}
@@ -0,0 +1,68 @@
+package sysfs
+
+import (
+	"path"
+	"runtime"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFileFlock(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "windows": // supported
+	default:
+		t.Skip("file locks are not supported on " + runtime.GOOS)
+	}
+
+	path := path.Join(t.TempDir(), wazeroFile)
+	f1 := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	defer f1.Close()
+	f2 := requireOpenFile(t, path, experimentalsys.O_RDWR, 0)
+	defer f2.Close()
+	l1, l2 := f1.(LockFile), f2.(LockFile)
+
+	t.Run("exclusive", func(t *testing.T) {
+		require.EqualErrno(t, 0, l1.Flock(LOCK_EX))
+		require.EqualErrno(t, experimentalsys.EAGAIN, l2.Flock(LOCK_SH|LOCK_NB))
+		require.EqualErrno(t, experimentalsys.EAGAIN, l2.Flock(LOCK_EX|LOCK_NB))
+
+		require.EqualErrno(t, 0, l1.Flock(LOCK_UN))
+		require.EqualErrno(t, 0, l2.Flock(LOCK_EX|LOCK_NB))
+		require.EqualErrno(t, 0, l2.Flock(LOCK_UN))
+	})
+
+	t.Run("shared", func(t *testing.T) {
+		require.EqualErrno(t, 0, l1.Flock(LOCK_SH))
+		require.EqualErrno(t, 0, l2.Flock(LOCK_SH|LOCK_NB))
+		require.EqualErrno(t, experimentalsys.EAGAIN, l2.Flock(LOCK_EX|LOCK_NB))
+
+		require.EqualErrno(t, 0, l1.Flock(LOCK_UN))
+		require.EqualErrno(t, 0, l2.Flock(LOCK_UN))
+	})
+
+	t.Run("unlock without lock", func(t *testing.T) {
+		require.EqualErrno(t, 0, l1.Flock(LOCK_UN))
+	})
+
+	t.Run("released on close", func(t *testing.T) {
+		f3 := requireOpenFile(t, path, experimentalsys.O_RDWR, 0)
+		require.EqualErrno(t, 0, f3.(LockFile).Flock(LOCK_EX))
+		require.EqualErrno(t, 0, f3.Close())
+
+		require.EqualErrno(t, 0, l1.Flock(LOCK_EX|LOCK_NB))
+		require.EqualErrno(t, 0, l1.Flock(LOCK_UN))
+	})
+
+	t.Run("EINVAL", func(t *testing.T) {
+		require.EqualErrno(t, experimentalsys.EINVAL, l1.Flock(0))
+		require.EqualErrno(t, experimentalsys.EINVAL, l1.Flock(LOCK_SH|LOCK_EX))
+	})
+
+	t.Run("EBADF", func(t *testing.T) {
+		f3 := requireOpenFile(t, path, experimentalsys.O_RDWR, 0)
+		require.EqualErrno(t, 0, f3.Close())
+		require.EqualErrno(t, experimentalsys.EBADF, f3.(LockFile).Flock(LOCK_EX))
+	})
+}
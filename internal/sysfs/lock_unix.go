@@ -0,0 +1,17 @@
+//go:build darwin || linux || freebsd
+
+package sysfs
+
+import (
+	"syscall"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+func flock(fd uintptr, how int) experimentalsys.Errno {
+	for {
+		if err := syscall.Flock(int(fd), how); err != syscall.EINTR {
+			return experimentalsys.UnwrapOSError(err)
+		}
+	}
+}
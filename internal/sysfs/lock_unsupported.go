@@ -0,0 +1,9 @@
+//go:build !(darwin || linux || freebsd || windows)
+
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+func flock(uintptr, int) experimentalsys.Errno {
+	return experimentalsys.ENOSYS
+}
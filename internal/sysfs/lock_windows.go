@@ -0,0 +1,81 @@
+package sysfs
+
+import (
+	"syscall"
+	"unsafe"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// Flags of LockFileEx.
+const (
+	_LOCKFILE_FAIL_IMMEDIATELY = 0x1
+	_LOCKFILE_EXCLUSIVE_LOCK   = 0x2
+)
+
+// Errors of LockFileEx and UnlockFileEx.
+const (
+	_ERROR_LOCK_VIOLATION = syscall.Errno(33)
+	_ERROR_NOT_LOCKED     = syscall.Errno(158)
+)
+
+var (
+	procLockFileEx   = kernel32.NewProc("LockFileEx")
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")
+)
+
+// flock emulates `flock` with LockFileEx. Unlike `flock`, locks on Windows
+// are mandatory: they prevent other handles from accessing the locked range.
+// To keep them advisory, this locks the last byte of the maximum file size,
+// which won't be read or written in practice.
+func flock(fd uintptr, how int) experimentalsys.Errno {
+	// Like `flock`, replace any existing lock, e.g. shared with exclusive.
+	// Unlike `flock`, this isn't atomic.
+	if errno := unlockFileEx(fd); errno != 0 || how&LOCK_UN != 0 {
+		return errno
+	}
+
+	var flags uintptr
+	if how&LOCK_EX != 0 {
+		flags |= _LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if how&LOCK_NB != 0 {
+		flags |= _LOCKFILE_FAIL_IMMEDIATELY
+	}
+	overlapped := lockOverlapped()
+	r, _, err := syscall.SyscallN(
+		procLockFileEx.Addr(),
+		fd,                                   // [in]      HANDLE       hFile,
+		flags,                                // [in]      DWORD        dwFlags,
+		0,                                    //           DWORD        dwReserved,
+		1,                                    // [in]      DWORD        nNumberOfBytesToLockLow,
+		0,                                    // [in]      DWORD        nNumberOfBytesToLockHigh,
+		uintptr(unsafe.Pointer(&overlapped))) // [in, out] LPOVERLAPPED lpOverlapped
+	if r != 0 {
+		return 0
+	} else if err == _ERROR_LOCK_VIOLATION {
+		return experimentalsys.EAGAIN
+	}
+	return experimentalsys.UnwrapOSError(err)
+}
+
+func unlockFileEx(fd uintptr) experimentalsys.Errno {
+	overlapped := lockOverlapped()
+	r, _, err := syscall.SyscallN(
+		procUnlockFileEx.Addr(),
+		fd,                                   // [in]      HANDLE       hFile,
+		0,                                    //           DWORD        dwReserved,
+		1,                                    // [in]      DWORD        nNumberOfBytesToUnlockLow,
+		0,                                    // [in]      DWORD        nNumberOfBytesToUnlockHigh,
+		uintptr(unsafe.Pointer(&overlapped))) // [in, out] LPOVERLAPPED lpOverlapped
+	if r != 0 || err == _ERROR_NOT_LOCKED {
+		return 0
+	}
+	return experimentalsys.UnwrapOSError(err)
+}
+
+// lockOverlapped returns the offset of the byte locked by flock, which is the
+// maximum file size, math.MaxInt64.
+func lockOverlapped() syscall.Overlapped {
+	return syscall.Overlapped{Offset: 0xffffffff, OffsetHigh: 0x7fffffff}
+}
@@ -15,13 +15,16 @@ func openFile(path string, oflag sys.Oflag, perm fs.FileMode) (*os.File, sys.Err
 	isDir := oflag&sys.O_DIRECTORY > 0
 	flag := toOsOpenFlag(oflag)
 
-	// TODO: document why we are opening twice
-	fd, err := open(path, flag|syscall.O_CLOEXEC, uint32(perm))
+	// Open with FILE_SHARE_DELETE first, so that the file, or directory, can
+	// be deleted or renamed while open, as on other platforms.
+	fd, err := open(path, flag|syscall.O_CLOEXEC, uint32(perm), isDir)
 	if err == nil {
 		return os.NewFile(uintptr(fd), path), 0
 	}
 
-	// TODO: Set FILE_SHARE_DELETE for directory as well.
+	// Otherwise, fall back to os.OpenFile for its errors, and as before Go
+	// 1.20, os.File can't read a directory from a handle opened by `open`.
+	// Directories opened this way can't be deleted until closed.
 	f, err := os.OpenFile(path, flag, perm)
 	errno := sys.UnwrapOSError(err)
 	if errno == 0 {
@@ -83,11 +86,15 @@ func isSymlink(path string) bool {
 //   - syscall.O_CREAT doesn't imply syscall.GENERIC_WRITE as that breaks
 //     flag expectations in wasi.
 //   - add support for setting FILE_SHARE_DELETE.
-func open(path string, mode int, perm uint32) (fd syscall.Handle, err error) {
+//   - `isDir` opens a directory handle, which is supported since Go 1.20.
+func open(path string, mode int, perm uint32, isDir bool) (fd syscall.Handle, err error) {
+	if isDir && !platform.IsAtLeastGo120 {
+		return syscall.InvalidHandle, syscall.EWINDOWS
+	}
 	if len(path) == 0 {
 		return syscall.InvalidHandle, syscall.ERROR_FILE_NOT_FOUND
 	}
-	pathp, err := syscall.UTF16PtrFromString(path)
+	pathp, err := syscall.UTF16PtrFromString(fixLongPath(path))
 	if err != nil {
 		return syscall.InvalidHandle, err
 	}
@@ -153,7 +160,7 @@ func open(path string, mode int, perm uint32) (fd syscall.Handle, err error) {
 	if platform.IsAtLeastGo120 {
 		// This shouldn't be included before 1.20 to have consistent behavior.
 		// https://github.com/golang/go/commit/0f0aa5d8a6a0253627d58b3aa083b24a1091933f
-		if createmode == syscall.OPEN_EXISTING && access == syscall.GENERIC_READ {
+		if isDir || (createmode == syscall.OPEN_EXISTING && access == syscall.GENERIC_READ) {
 			// Necessary for opening directory handles.
 			attrs |= syscall.FILE_FLAG_BACKUP_SEMANTICS
 		}
@@ -39,6 +39,20 @@ type osFile struct {
 
 	// cachedStat includes fields that won't change while a file is open.
 	cachedSt *cachedStat
+
+	// ring is used to read and write when non-nil. See UseUring.
+	ring *Uring
+}
+
+// UseUring makes `f` read and write with the io_uring instance `r`, if `f`
+// was opened by this package. Otherwise, this does nothing.
+//
+// Operations fall back to syscalls if `r` can't perform them, for example
+// on older kernels.
+func UseUring(f experimentalsys.File, r *Uring) {
+	if osF, ok := f.(*osFile); ok {
+		osF.ring = r
+	}
 }
 
 // cachedStat returns the cacheable parts of sys.Stat_t or an error if they
@@ -99,7 +113,9 @@ func (f *osFile) reopen() (errno experimentalsys.Errno) {
 	f.flag &= ^experimentalsys.O_CREAT
 
 	_ = f.close()
-	f.file, errno = OpenFile(f.path, f.flag, f.perm)
+	if f.file, errno = OpenFile(f.path, f.flag, f.perm); errno == 0 {
+		f.fd = f.file.Fd()
+	}
 	return
 }
 
@@ -142,9 +158,10 @@ func (f *osFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
 	if len(buf) == 0 {
 		return 0, 0 // Short-circuit 0-len reads.
 	}
+	var ok bool
 	if nonBlockingFileReadSupported && f.IsNonblock() {
 		n, errno = readFd(f.fd, buf)
-	} else {
+	} else if n, errno, ok = f.ring.read(f.fd, buf, uringCurrentPosition); !ok {
 		n, errno = read(f.file, buf)
 	}
 	if errno != 0 {
@@ -156,7 +173,14 @@ func (f *osFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
 
 // Pread implements the same method as documented on sys.File
 func (f *osFile) Pread(buf []byte, off int64) (n int, errno experimentalsys.Errno) {
-	if n, errno = pread(f.file, buf, off); errno != 0 {
+	var ok bool
+	if off >= 0 {
+		n, errno, ok = f.ring.read(f.fd, buf, uint64(off))
+	}
+	if !ok {
+		n, errno = pread(f.file, buf, off)
+	}
+	if errno != 0 {
 		// Defer validation overhead until we've already had an error.
 		errno = fileError(f, f.closed, errno)
 	}
@@ -205,9 +229,14 @@ func (f *osFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
 	if len(buf) == 0 {
 		return 0, 0 // Short-circuit 0-len writes.
 	}
+	var ok bool
 	if nonBlockingFileWriteSupported && f.IsNonblock() {
 		n, errno = writeFd(f.fd, buf)
-	} else if n, errno = write(f.file, buf); errno != 0 {
+		return
+	} else if n, errno, ok = f.ring.write(f.fd, buf, uringCurrentPosition); !ok {
+		n, errno = write(f.file, buf)
+	}
+	if errno != 0 {
 		// Defer validation overhead until we've already had an error.
 		errno = fileError(f, f.closed, errno)
 	}
@@ -216,7 +245,15 @@ func (f *osFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
 
 // Pwrite implements the same method as documented on sys.File
 func (f *osFile) Pwrite(buf []byte, off int64) (n int, errno experimentalsys.Errno) {
-	if n, errno = pwrite(f.file, buf, off); errno != 0 {
+	var ok bool
+	// os.File.WriteAt disallows files opened with O_APPEND, so don't bypass it.
+	if off >= 0 && !f.IsAppend() {
+		n, errno, ok = f.ring.write(f.fd, buf, uint64(off))
+	}
+	if !ok {
+		n, errno = pwrite(f.file, buf, off)
+	}
+	if errno != 0 {
 		// Defer validation overhead until we've already had an error.
 		errno = fileError(f, f.closed, errno)
 	}
@@ -258,6 +295,7 @@ func (f *osFile) Close() experimentalsys.Errno {
 		return 0
 	}
 	f.closed = true
+	f.ring = nil // don't use the file descriptor, which could be reused.
 	return f.close()
 }
 
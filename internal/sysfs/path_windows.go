@@ -0,0 +1,78 @@
+package sysfs
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// maxShortPath is the length from which fixLongPath adds the `\\?\` prefix.
+// MAX_PATH is 260, but directories must leave room for an 8.3 file name.
+// See https://learn.microsoft.com/en-us/windows/win32/fileio/maximum-file-path-limitation
+const maxShortPath = 248
+
+// fixLongPath returns the extended-length form of `path` when it is too long
+// for the Win32 API, e.g. `\\?\C:\dir\file` or `\\?\UNC\server\share\file`.
+// Otherwise, it returns `path` unchanged.
+//
+// The os package does the same for its functions, but this is needed for
+// paths passed directly to syscall functions, such as syscall.CreateFile.
+//
+// As extended-length paths are not normalized by Windows, the path is made
+// absolute first with GetFullPathName, which is what Windows would otherwise
+// do. This resolves "." and ".." lexically and converts slashes.
+func fixLongPath(path string) string {
+	if len(path) < maxShortPath || strings.HasPrefix(path, `\\?\`) || strings.HasPrefix(path, `\\.\`) {
+		return path
+	}
+	full, err := syscall.FullPath(path)
+	if err != nil {
+		return path // let the caller fail with the original path.
+	}
+	if strings.HasPrefix(full, `\\`) { // UNC path, e.g. \\server\share\file
+		return `\\?\UNC\` + full[2:]
+	}
+	return `\\?\` + full
+}
+
+// Reparse tags of reparse points that are links.
+// See https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-fscc/c8e77b37-3909-4fe6-a4ea-2b9d423b1ee4
+const (
+	_IO_REPARSE_TAG_MOUNT_POINT = 0xA0000003 //nolint
+	_IO_REPARSE_TAG_SYMLINK     = 0xA000000C //nolint
+)
+
+// _FileAttributeTagInfo is the FILE_INFO_BY_HANDLE_CLASS of
+// FILE_ATTRIBUTE_TAG_INFO.
+const _FileAttributeTagInfo = 9 //nolint
+
+// procGetFileInformationByHandleEx is the syscall.LazyProc in kernel32 for
+// GetFileInformationByHandleEx
+var procGetFileInformationByHandleEx = kernel32.NewProc("GetFileInformationByHandleEx")
+
+// isLinkReparsePoint returns true if the reparse point opened as `h` is a
+// symbolic link or a junction (mount point), which both behave like a POSIX
+// symbolic link. Other reparse points, such as deduplicated or cloud files,
+// are regular files or directories.
+func isLinkReparsePoint(h syscall.Handle) (bool, sys.Errno) {
+	var info struct {
+		FileAttributes uint32
+		ReparseTag     uint32
+	}
+	r, _, err := syscall.SyscallN(
+		procGetFileInformationByHandleEx.Addr(),
+		uintptr(h),                     // [in]  HANDLE                    hFile,
+		_FileAttributeTagInfo,          // [in]  FILE_INFO_BY_HANDLE_CLASS FileInformationClass,
+		uintptr(unsafe.Pointer(&info)), // [out] LPVOID                    lpFileInformation,
+		unsafe.Sizeof(info))            // [in]  DWORD                     dwBufferSize
+	if r == 0 {
+		return false, sys.UnwrapOSError(err)
+	}
+	switch info.ReparseTag {
+	case _IO_REPARSE_TAG_SYMLINK, _IO_REPARSE_TAG_MOUNT_POINT:
+		return true, 0
+	}
+	return false, 0
+}
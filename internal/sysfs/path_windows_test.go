@@ -0,0 +1,58 @@
+package sysfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFixLongPath(t *testing.T) {
+	long := strings.Repeat("a", maxShortPath)
+
+	tests := []struct {
+		name, path, expected string
+	}{
+		{name: "short", path: `C:\dir\file`, expected: `C:\dir\file`},
+		{name: "long", path: `C:\` + long, expected: `\\?\C:\` + long},
+		{name: "long slashes", path: `C:/dir/` + long, expected: `\\?\C:\dir\` + long},
+		{name: "long dots", path: `C:\dir\.\x\..\` + long, expected: `\\?\C:\dir\` + long},
+		{name: "long UNC", path: `\\server\share\` + long, expected: `\\?\UNC\server\share\` + long},
+		{name: "already extended", path: `\\?\C:\` + long, expected: `\\?\C:\` + long},
+		{name: "device", path: `\\.\pipe\` + long, expected: `\\.\pipe\` + long},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, fixLongPath(tc.path))
+		})
+	}
+}
+
+func TestDirFS_longPath(t *testing.T) {
+	dir := t.TempDir()
+	name := strings.Repeat("a", 100)
+	// Create a directory tree past MAX_PATH with the os package, which
+	// supports long paths.
+	nested := filepath.Join(name, name, name)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, nested), 0o700))
+
+	testFS := DirFS(dir)
+	guestPath := filepath.ToSlash(nested) + "/file"
+
+	f, errno := testFS.OpenFile(guestPath, sys.O_WRONLY|sys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	st, errno := testFS.Stat(guestPath)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, st.Mode.IsRegular())
+
+	require.EqualErrno(t, 0, testFS.Unlink(guestPath))
+	require.EqualErrno(t, 0, testFS.Rmdir(filepath.ToSlash(nested)))
+}
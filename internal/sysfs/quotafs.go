@@ -0,0 +1,290 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Quota limits changes made through a QuotaFS. Zero fields are unlimited.
+type Quota struct {
+	// MaxBytes limits the growth of regular files, in bytes.
+	MaxBytes int64
+	// MaxFiles limits the number of files, directories and symbolic links
+	// created.
+	MaxFiles int64
+	// MaxFileSize limits the size of each regular file, in bytes.
+	MaxFileSize int64
+}
+
+// QuotaFS enforces a Quota on the FS it wraps. Changes that would exceed it
+// fail with ENOSPC.
+//
+// Usage starts at zero, so only counts changes made through this. It is
+// released when files are truncated, unlinked or removed. Releasing never
+// makes usage negative, so
+// removing files that existed before doesn't increase the space available.
+//
+// Note: The accounting is approximate. For example, the space of a file
+// unlinked while open is released on unlink, not when it is closed.
+type QuotaFS struct {
+	experimentalsys.FS
+	quota Quota
+
+	// mu serializes changes to files, so that size checks are consistent.
+	mu    sync.Mutex
+	bytes int64
+	files int64
+}
+
+// NewQuotaFS returns a QuotaFS which enforces the quota on `fs`.
+func NewQuotaFS(fs experimentalsys.FS, quota Quota) *QuotaFS {
+	return &QuotaFS{FS: fs, quota: quota}
+}
+
+// String implements fmt.Stringer
+func (q *QuotaFS) String() string {
+	if s, ok := q.FS.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "QuotaFS"
+}
+
+// Close closes the wrapped FS, if it implements io.Closer.
+func (q *QuotaFS) Close() error {
+	if c, ok := q.FS.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (q *QuotaFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var truncated int64
+	var create bool
+	if flag&(experimentalsys.O_CREAT|experimentalsys.O_TRUNC) != 0 {
+		st, errno := q.FS.Lstat(path)
+		create = flag&experimentalsys.O_CREAT != 0 && errno == experimentalsys.ENOENT
+		if errno == 0 && flag&experimentalsys.O_TRUNC != 0 {
+			truncated = regularSize(st)
+		}
+	}
+	if create {
+		if errno := q.reserveFile(); errno != 0 {
+			return nil, errno
+		}
+	}
+
+	f, errno := q.FS.OpenFile(path, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	if create {
+		q.files++
+	}
+	q.releaseBytes(truncated)
+	return &quotaFile{File: f, fs: q}, 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (q *QuotaFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	return q.create(func() experimentalsys.Errno { return q.FS.Mkdir(path, perm) })
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (q *QuotaFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	return q.create(func() experimentalsys.Errno { return q.FS.Symlink(oldPath, linkName) })
+}
+
+func (q *QuotaFS) create(fn func() experimentalsys.Errno) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if errno := q.reserveFile(); errno != 0 {
+		return errno
+	}
+	if errno := fn(); errno != 0 {
+		return errno
+	}
+	q.files++
+	return 0
+}
+
+// Rename implements the same method as documented on sys.FS
+func (q *QuotaFS) Rename(from, to string) experimentalsys.Errno {
+	return q.remove(to, func() experimentalsys.Errno { return q.FS.Rename(from, to) })
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (q *QuotaFS) Rmdir(path string) experimentalsys.Errno {
+	return q.remove(path, func() experimentalsys.Errno { return q.FS.Rmdir(path) })
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (q *QuotaFS) Unlink(path string) experimentalsys.Errno {
+	return q.remove(path, func() experimentalsys.Errno { return q.FS.Unlink(path) })
+}
+
+// remove calls fn, which removes the file at path if it exists, releasing
+// its usage on success.
+func (q *QuotaFS) remove(path string, fn func() experimentalsys.Errno) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	st, lstatErrno := q.FS.Lstat(path)
+	if errno := fn(); errno != 0 {
+		return errno
+	}
+	// Directories can't have other links, but their link count includes
+	// their subdirectories.
+	if lstatErrno == 0 && (st.Mode.IsDir() || st.Nlink <= 1) {
+		q.releaseBytes(regularSize(st))
+		if q.files > 0 {
+			q.files--
+		}
+	}
+	return 0
+}
+
+func (q *QuotaFS) reserveFile() experimentalsys.Errno {
+	if q.quota.MaxFiles > 0 && q.files+1 > q.quota.MaxFiles {
+		return experimentalsys.ENOSPC
+	}
+	return 0
+}
+
+// reserveBytes returns ENOSPC if growing a file to `size` by `grow` bytes
+// would exceed the quota.
+func (q *QuotaFS) reserveBytes(size, grow int64) experimentalsys.Errno {
+	if q.quota.MaxFileSize > 0 && size > q.quota.MaxFileSize {
+		return experimentalsys.ENOSPC
+	}
+	if q.quota.MaxBytes > 0 && q.bytes+grow > q.quota.MaxBytes {
+		return experimentalsys.ENOSPC
+	}
+	return 0
+}
+
+func (q *QuotaFS) releaseBytes(n int64) {
+	if q.bytes -= n; q.bytes < 0 {
+		q.bytes = 0
+	}
+}
+
+// regularSize returns the size of a regular file, or zero for other types.
+func regularSize(st sys.Stat_t) int64 {
+	if !st.Mode.IsRegular() {
+		return 0
+	}
+	return st.Size
+}
+
+// compile-time checks to ensure quotaFile implements api.File and forwards
+// the optional interfaces of the file it wraps.
+var (
+	_ experimentalsys.File = (*quotaFile)(nil)
+	_ LockFile             = (*quotaFile)(nil)
+	_ syncRangeFile        = (*quotaFile)(nil)
+)
+
+// quotaFile enforces the quota of a QuotaFS on writes and truncation.
+type quotaFile struct {
+	experimentalsys.File
+	fs *QuotaFS
+}
+
+// Write implements the same method as documented on sys.File.
+func (f *quotaFile) Write(buf []byte) (int, experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return 0, errno
+	}
+	off := st.Size
+	if !f.File.IsAppend() {
+		if off, errno = f.File.Seek(0, io.SeekCurrent); errno != 0 {
+			return 0, errno
+		}
+	}
+	return f.write(st, off, buf, f.File.Write)
+}
+
+// Pwrite implements the same method as documented on sys.File.
+func (f *quotaFile) Pwrite(buf []byte, off int64) (int, experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return 0, errno
+	}
+	return f.write(st, off, buf, func(buf []byte) (int, experimentalsys.Errno) {
+		return f.File.Pwrite(buf, off)
+	})
+}
+
+// write checks that writing buf at off fits in the quota, and accounts for
+// any growth of the file after calling the write function.
+func (f *quotaFile) write(st sys.Stat_t, off int64, buf []byte, write func([]byte) (int, experimentalsys.Errno)) (int, experimentalsys.Errno) {
+	if end := off + int64(len(buf)); end > st.Size {
+		if errno := f.fs.reserveBytes(end, end-st.Size); errno != 0 {
+			return 0, errno
+		}
+	}
+	n, errno := write(buf)
+	if grow := off + int64(n) - st.Size; grow > 0 {
+		f.fs.bytes += grow
+	}
+	return n, errno
+}
+
+// Truncate implements the same method as documented on sys.File.
+func (f *quotaFile) Truncate(size int64) experimentalsys.Errno {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	st, errno := f.File.Stat()
+	if errno != 0 {
+		return errno
+	}
+	grow := size - st.Size
+	if grow > 0 {
+		if errno = f.fs.reserveBytes(size, grow); errno != 0 {
+			return errno
+		}
+	}
+	if errno = f.File.Truncate(size); errno != 0 {
+		return errno
+	}
+	if grow > 0 {
+		f.fs.bytes += grow
+	} else {
+		f.fs.releaseBytes(-grow)
+	}
+	return 0
+}
+
+// Flock implements LockFile.Flock by forwarding to the wrapped file, which
+// returns ENOSYS if it doesn't implement LockFile.
+func (f *quotaFile) Flock(how int) experimentalsys.Errno {
+	if lf, ok := f.File.(LockFile); ok {
+		return lf.Flock(how)
+	}
+	return experimentalsys.ENOSYS
+}
+
+// syncRange implements syncRangeFile by forwarding to the wrapped file.
+func (f *quotaFile) syncRange(off, n int64, flags int) experimentalsys.Errno {
+	if sf, ok := f.File.(syncRangeFile); ok {
+		return sf.syncRange(off, n, flags)
+	}
+	return experimentalsys.ENOSYS
+}
@@ -0,0 +1,154 @@
+package sysfs
+
+import (
+	"runtime"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestQuotaFS_MaxBytes(t *testing.T) {
+	quotaFS := NewQuotaFS(DirFS(t.TempDir()), Quota{MaxBytes: 10})
+
+	f, errno := quotaFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	// Overwriting doesn't use more space.
+	_, errno = f.Pwrite([]byte("WAZERO"), 0)
+	require.EqualErrno(t, 0, errno)
+
+	// Growing past the quota fails.
+	_, errno = f.Write([]byte("wazero"))
+	require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+	_, errno = f.Pwrite([]byte("a"), 10)
+	require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+	require.EqualErrno(t, experimentalsys.ENOSPC, f.Truncate(11))
+
+	// Up to the quota succeeds.
+	require.EqualErrno(t, 0, f.Truncate(10))
+
+	// Other files share the quota.
+	f2, errno := quotaFS.OpenFile("file2", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f2.Write([]byte("a"))
+	require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+
+	// Truncating releases space.
+	require.EqualErrno(t, 0, f.Truncate(4))
+	_, errno = f2.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f2.Close())
+
+	// Unlinking releases space.
+	require.EqualErrno(t, 0, quotaFS.Unlink("file2"))
+	_, errno = f.Pwrite([]byte("wazero"), 4)
+	require.EqualErrno(t, 0, errno)
+
+	// Truncating on open releases space.
+	f3, errno := quotaFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_TRUNC, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f3.Write(make([]byte, 10))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f3.Close())
+}
+
+func TestQuotaFS_MaxFiles(t *testing.T) {
+	quotaFS := NewQuotaFS(DirFS(t.TempDir()), Quota{MaxFiles: 2})
+
+	require.EqualErrno(t, 0, quotaFS.Mkdir("dir", 0o700))
+	f, errno := quotaFS.OpenFile("dir/file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Opening an existing file doesn't create one.
+	f, errno = quotaFS.OpenFile("dir/file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Creating past the quota fails.
+	_, errno = quotaFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+	require.EqualErrno(t, experimentalsys.ENOSPC, quotaFS.Mkdir("dir2", 0o700))
+	require.EqualErrno(t, experimentalsys.ENOSPC, quotaFS.Symlink("dir", "link"))
+
+	// Removing releases files.
+	require.EqualErrno(t, 0, quotaFS.Unlink("dir/file"))
+	require.EqualErrno(t, 0, quotaFS.Symlink("dir", "link"))
+	require.EqualErrno(t, experimentalsys.ENOSPC, quotaFS.Mkdir("dir2", 0o700))
+	require.EqualErrno(t, 0, quotaFS.Rmdir("dir"))
+	require.EqualErrno(t, 0, quotaFS.Mkdir("dir2", 0o700))
+}
+
+func TestQuotaFS_MaxFileSize(t *testing.T) {
+	quotaFS := NewQuotaFS(DirFS(t.TempDir()), Quota{MaxFileSize: 4})
+
+	for _, name := range []string{"a", "b"} {
+		f, errno := quotaFS.OpenFile(name, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+		require.EqualErrno(t, 0, errno)
+
+		_, errno = f.Write([]byte("waze"))
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("r"))
+		require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+		_, errno = f.Pwrite([]byte("ro"), 3)
+		require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+		require.EqualErrno(t, experimentalsys.ENOSPC, f.Truncate(5))
+		require.EqualErrno(t, 0, f.Truncate(4))
+		require.EqualErrno(t, 0, f.Close())
+	}
+}
+
+func TestQuotaFile_Flock(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "darwin", "freebsd", "windows": // supported
+	default:
+		t.Skip("file locks are not supported on " + runtime.GOOS)
+	}
+
+	quotaFS := NewQuotaFS(DirFS(t.TempDir()), Quota{})
+	f1, errno := quotaFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f1.Close()
+	f2, errno := quotaFS.OpenFile("file", experimentalsys.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f2.Close()
+
+	require.EqualErrno(t, 0, f1.(LockFile).Flock(LOCK_EX))
+	require.EqualErrno(t, experimentalsys.EAGAIN, f2.(LockFile).Flock(LOCK_EX|LOCK_NB))
+	require.EqualErrno(t, 0, f1.(LockFile).Flock(LOCK_UN))
+	require.EqualErrno(t, 0, f2.(LockFile).Flock(LOCK_EX|LOCK_NB))
+
+	// A file that can't be locked reports the same as an unsupported platform.
+	unlockable := &quotaFile{File: experimentalsys.UnimplementedFile{}, fs: quotaFS}
+	require.EqualErrno(t, experimentalsys.ENOSYS, unlockable.Flock(LOCK_EX))
+}
+
+// syncRangeRecorder records the calls of syncRange.
+type syncRangeRecorder struct {
+	experimentalsys.UnimplementedFile
+	calls [][3]int64
+}
+
+// syncRange implements syncRangeFile.
+func (f *syncRangeRecorder) syncRange(off, n int64, flags int) experimentalsys.Errno {
+	f.calls = append(f.calls, [3]int64{off, n, int64(flags)})
+	return 0
+}
+
+func TestQuotaFile_SyncRange(t *testing.T) {
+	recorder := &syncRangeRecorder{}
+	f := &quotaFile{File: recorder, fs: NewQuotaFS(DirFS(t.TempDir()), Quota{})}
+
+	require.EqualErrno(t, 0, SyncRange(f, 1, 2, SYNC_FILE_RANGE_WRITE))
+	require.Equal(t, [][3]int64{{1, 2, SYNC_FILE_RANGE_WRITE}}, recorder.calls)
+
+	// A file that doesn't implement it falls back like an unsupported platform.
+	f = &quotaFile{File: experimentalsys.UnimplementedFile{}, fs: f.fs}
+	require.EqualErrno(t, 0, SyncRange(f, 0, 0, SYNC_FILE_RANGE_WRITE))
+}
@@ -10,7 +10,8 @@ import (
 
 func rename(from, to string) sys.Errno {
 	if from == to {
-		return 0
+		_, errno := lstat(from) // still fail if it doesn't exist
+		return errno
 	}
 	return sys.UnwrapOSError(syscall.Rename(from, to))
 }
@@ -20,6 +20,13 @@ func TestRename(t *testing.T) {
 		err = rename(path.Join(tmpDir, "non-exist"), file1Path)
 		require.EqualErrno(t, sys.ENOENT, err)
 	})
+	t.Run("from doesn't exist to itself", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		nonExistPath := path.Join(tmpDir, "non-exist")
+		err := rename(nonExistPath, nonExistPath)
+		require.EqualErrno(t, sys.ENOENT, err)
+	})
 	t.Run("file to non-exist", func(t *testing.T) {
 		tmpDir := t.TempDir()
 
@@ -197,4 +204,20 @@ func TestRename(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, file1Contents, b)
 	})
+	t.Run("dir to itself in different case", func(t *testing.T) {
+		tmpDir := t.TempDir()
+
+		dir1Path := path.Join(tmpDir, "dir1")
+		require.NoError(t, os.Mkdir(dir1Path, 0o700))
+
+		// On a case-insensitive volume, both names are the same directory,
+		// which must not be removed as if it were an empty target.
+		errno := rename(dir1Path, path.Join(tmpDir, "DIR1"))
+		require.EqualErrno(t, 0, errno)
+
+		entries, err := os.ReadDir(tmpDir)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(entries))
+		require.Equal(t, "DIR1", entries[0].Name())
+	})
 }
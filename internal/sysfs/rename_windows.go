@@ -2,6 +2,7 @@ package sysfs
 
 import (
 	"os"
+	"strings"
 	"syscall"
 
 	"github.com/tetratelabs/wazero/experimental/sys"
@@ -9,11 +10,13 @@ import (
 
 func rename(from, to string) sys.Errno {
 	if from == to {
-		return 0
+		_, errno := lstat(from) // still fail if it doesn't exist
+		return errno
 	}
 
 	var fromIsDir, toIsDir bool
-	if fromStat, errno := stat(from); errno != 0 {
+	fromStat, errno := stat(from)
+	if errno != 0 {
 		return errno // failed to stat from
 	} else {
 		fromIsDir = fromStat.Mode.IsDir()
@@ -22,6 +25,10 @@ func rename(from, to string) sys.Errno {
 		return syscallRename(from, to) // file or dir to not-exist is ok
 	} else if errno != 0 {
 		return errno // failed to stat to
+	} else if strings.EqualFold(from, to) && toStat.Dev == fromStat.Dev && toStat.Ino == fromStat.Ino {
+		// Only the case differs and the volume is case-insensitive, so both
+		// names are the same file. Don't remove `to`, as that is `from`.
+		return syscallRename(from, to)
 	} else {
 		toIsDir = toStat.Mode.IsDir()
 	}
@@ -51,5 +58,5 @@ func rename(from, to string) sys.Errno {
 }
 
 func syscallRename(from string, to string) sys.Errno {
-	return sys.UnwrapOSError(syscall.Rename(from, to))
+	return sys.UnwrapOSError(syscall.Rename(fixLongPath(from), fixLongPath(to)))
 }
@@ -0,0 +1,33 @@
+package sysfs
+
+import (
+	"io"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// Splice copies up to `n` bytes from the current offset of `in` to `out`,
+// returning the count of bytes copied. This avoids copying through a user
+// buffer where the host supports it, e.g. with copy_file_range or sendfile on
+// Linux.
+//
+// `in` must be a file opened by DirFS. `out` must be a file opened by DirFS,
+// or on Linux, a TCP connection. Otherwise, this returns sys.ENOSYS and the
+// caller should copy with Read and Write instead.
+func Splice(out, in experimentalsys.File, n int64) (int64, experimentalsys.Errno) {
+	src, ok := in.(*osFile)
+	if !ok {
+		return 0, experimentalsys.ENOSYS
+	}
+	if n <= 0 {
+		return 0, 0 // less overhead on zero-length copies.
+	}
+	switch dst := out.(type) {
+	case *osFile:
+		// os.File uses the most efficient means the host supports.
+		written, err := dst.file.ReadFrom(io.LimitReader(src.file, n))
+		return written, experimentalsys.UnwrapOSError(err)
+	default:
+		return spliceToSocket(out, src, n)
+	}
+}
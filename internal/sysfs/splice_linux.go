@@ -0,0 +1,26 @@
+package sysfs
+
+import (
+	"syscall"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// maxSendfile is the most bytes sendfile copies in one call on Linux.
+const maxSendfile = 0x7ffff000
+
+func spliceToSocket(out experimentalsys.File, in *osFile, n int64) (int64, experimentalsys.Errno) {
+	conn, ok := out.(*tcpConnFile)
+	if !ok {
+		return 0, experimentalsys.ENOSYS
+	}
+	if n > maxSendfile {
+		n = maxSendfile
+	}
+	// A nil offset copies from, and advances, the current offset of `in`.
+	written, err := syscall.Sendfile(int(conn.fd), int(in.fd), nil, int(n))
+	if err != nil {
+		return 0, experimentalsys.UnwrapOSError(err)
+	}
+	return int64(written), 0
+}
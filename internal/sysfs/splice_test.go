@@ -0,0 +1,49 @@
+package sysfs
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestSplice(t *testing.T) {
+	tmpDir := t.TempDir()
+	inPath := path.Join(tmpDir, "in")
+	require.NoError(t, os.WriteFile(inPath, []byte("wazero"), 0o600))
+	outPath := path.Join(tmpDir, "out")
+
+	in := requireOpenFile(t, inPath, experimentalsys.O_RDONLY, 0)
+	defer in.Close()
+	out := requireOpenFile(t, outPath, experimentalsys.O_WRONLY|experimentalsys.O_CREAT, 0o600)
+	defer out.Close()
+
+	// Copies from the current offset.
+	_, errno := in.Seek(2, 0)
+	require.EqualErrno(t, 0, errno)
+
+	n, errno := Splice(out, in, 3)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(3), n)
+
+	n, errno = Splice(out, in, 100)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(1), n)
+
+	// At the end of the input.
+	n, errno = Splice(out, in, 100)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	b, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	require.Equal(t, "zero", string(b))
+
+	// Files not opened by DirFS are unsupported.
+	_, errno = Splice(out, &experimentalsys.UnimplementedFile{}, 1)
+	require.EqualErrno(t, experimentalsys.ENOSYS, errno)
+	_, errno = Splice(&experimentalsys.UnimplementedFile{}, in, 1)
+	require.EqualErrno(t, experimentalsys.ENOSYS, errno)
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+func spliceToSocket(experimentalsys.File, *osFile, int64) (int64, experimentalsys.Errno) {
+	return 0, experimentalsys.ENOSYS
+}
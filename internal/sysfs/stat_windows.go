@@ -32,7 +32,7 @@ func statPath(createFileAttrs uint32, path string) (sys.Stat_t, experimentalsys.
 	if len(path) == 0 {
 		return sys.Stat_t{}, experimentalsys.ENOENT
 	}
-	pathp, err := syscall.UTF16PtrFromString(path)
+	pathp, err := syscall.UTF16PtrFromString(fixLongPath(path))
 	if err != nil {
 		return sys.Stat_t{}, experimentalsys.EINVAL
 	}
@@ -94,8 +94,18 @@ func statHandle(h syscall.Handle) (sys.Stat_t, experimentalsys.Errno) {
 		m |= 0o666
 	}
 
+	// Only symlinks and junctions are links: other reparse points, such as
+	// deduplicated files, are regular files or directories.
+	var isLink bool
+	if fi.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0 {
+		var errno experimentalsys.Errno
+		if isLink, errno = isLinkReparsePoint(h); errno != 0 {
+			return sys.Stat_t{}, errno
+		}
+	}
+
 	switch { // check whether this is a symlink first
-	case fi.FileAttributes&syscall.FILE_ATTRIBUTE_REPARSE_POINT != 0:
+	case isLink:
 		m |= fs.ModeSymlink
 	case winFt == syscall.FILE_TYPE_PIPE:
 		m |= fs.ModeNamedPipe
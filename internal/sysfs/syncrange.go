@@ -0,0 +1,58 @@
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+// Flags of SyncRange, which have the same values as Linux.
+const (
+	// SYNC_FILE_RANGE_WAIT_BEFORE waits for writes of the range already in
+	// progress to complete.
+	SYNC_FILE_RANGE_WAIT_BEFORE = 0x1
+	// SYNC_FILE_RANGE_WRITE starts writing the range, without waiting.
+	SYNC_FILE_RANGE_WRITE = 0x2
+	// SYNC_FILE_RANGE_WAIT_AFTER waits for writes of the range to complete.
+	SYNC_FILE_RANGE_WAIT_AFTER = 0x4
+)
+
+// SyncRange starts or waits for writing `n` bytes of `f` at offset `off` to
+// storage, like sync_file_range on Linux. A zero `n` means through the end of
+// the file.
+//
+// This allows a guest to batch writeback: it can start writing each range
+// with SYNC_FILE_RANGE_WRITE as it finishes with it, so that a final
+// Datasync has less to wait for. This doesn't flush metadata or the storage
+// device's cache, so it isn't a durability guarantee on its own.
+//
+// Where sync_file_range isn't supported, or `f` wasn't opened by DirFS,
+// flags including SYNC_FILE_RANGE_WAIT_AFTER fall back to Datasync, and
+// others do nothing.
+func SyncRange(f experimentalsys.File, off, n int64, flags int) experimentalsys.Errno {
+	if off < 0 || n < 0 || flags&^(SYNC_FILE_RANGE_WAIT_BEFORE|SYNC_FILE_RANGE_WRITE|SYNC_FILE_RANGE_WAIT_AFTER) != 0 {
+		return experimentalsys.EINVAL
+	}
+	if sf, ok := f.(syncRangeFile); ok {
+		if errno := sf.syncRange(off, n, flags); errno != experimentalsys.ENOSYS {
+			return errno
+		}
+	}
+	if flags&SYNC_FILE_RANGE_WAIT_AFTER != 0 {
+		return f.Datasync()
+	}
+	return 0
+}
+
+// syncRangeFile is implemented by files which can implement SyncRange
+// natively, or forward it to the file they wrap. The parameters are already
+// validated, and ENOSYS means SyncRange should fall back to Datasync.
+type syncRangeFile interface {
+	syncRange(off, n int64, flags int) experimentalsys.Errno
+}
+
+// syncRange implements syncRangeFile.
+func (f *osFile) syncRange(off, n int64, flags int) experimentalsys.Errno {
+	errno := syncFileRange(f.fd, off, n, flags)
+	if errno != 0 && errno != experimentalsys.ENOSYS {
+		// Defer validation overhead until we've already had an error.
+		errno = fileError(f, f.closed, errno)
+	}
+	return errno
+}
@@ -0,0 +1,13 @@
+//go:build linux && (amd64 || arm64 || riscv64)
+
+package sysfs
+
+import (
+	"syscall"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+func syncFileRange(fd uintptr, off, n int64, flags int) experimentalsys.Errno {
+	return experimentalsys.UnwrapOSError(syscall.SyncFileRange(int(fd), off, n, flags))
+}
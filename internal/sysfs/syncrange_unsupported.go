@@ -0,0 +1,9 @@
+//go:build !(linux && (amd64 || arm64 || riscv64))
+
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+func syncFileRange(uintptr, int64, int64, int) experimentalsys.Errno {
+	return experimentalsys.ENOSYS
+}
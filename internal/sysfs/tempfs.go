@@ -0,0 +1,32 @@
+package sysfs
+
+import (
+	"os"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// TempFS is a DirFS of a new, empty host directory, which Close removes.
+type TempFS struct {
+	experimentalsys.FS
+	dir string
+}
+
+// NewTempFS creates a new directory in os.TempDir for a TempFS.
+func NewTempFS() (*TempFS, error) {
+	dir, err := os.MkdirTemp("", "wazero-tmp-")
+	if err != nil {
+		return nil, err
+	}
+	return &TempFS{FS: DirFS(dir), dir: dir}, nil
+}
+
+// String implements fmt.Stringer
+func (t *TempFS) String() string {
+	return t.dir
+}
+
+// Close removes the directory and the files in it.
+func (t *TempFS) Close() error {
+	return os.RemoveAll(t.dir)
+}
@@ -0,0 +1,31 @@
+package sysfs
+
+import (
+	"os"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestTempFS(t *testing.T) {
+	tempFS, err := NewTempFS()
+	require.NoError(t, err)
+
+	// The directory is new and empty.
+	dir := tempFS.String()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Zero(t, len(entries))
+
+	f, errno := tempFS.OpenFile("file", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write(make([]byte, 1024))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Close removes the directory and its files.
+	require.NoError(t, tempFS.Close())
+	_, err = os.Stat(dir)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func unlink(name string) sys.Errno {
-	err := syscall.Unlink(name)
+	err := syscall.Unlink(fixLongPath(name))
 	if err == nil {
 		return 0
 	}
@@ -0,0 +1,221 @@
+//go:build amd64 || arm64
+
+package sysfs
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// Syscall numbers of io_uring, which are the same on amd64 and arm64.
+const (
+	sysIoUringSetup = 425
+	sysIoUringEnter = 426
+)
+
+const (
+	uringEntries = 8
+
+	// mmap offsets of the rings and the submission queue entries.
+	uringOffSQRing = 0
+	uringOffSQEs   = 0x10000000
+
+	uringFeatSingleMmap  = 1 << 0
+	uringEnterGetEvents  = 1 << 0
+	uringOpRead          = 22
+	uringOpWrite         = 23
+	uringSQESize         = 64
+	uringCQESize         = 16
+	uringCurrentPosition = ^uint64(0) // -1: use and update the file position
+)
+
+// uringParams is struct io_uring_params.
+type uringParams struct {
+	sqEntries, cqEntries, flags uint32
+	sqThreadCPU, sqThreadIdle   uint32
+	features, wqFd              uint32
+	resv                        [3]uint32
+	sqOff                       uringSQOffsets
+	cqOff                       uringCQOffsets
+}
+
+// uringSQOffsets is struct io_sqring_offsets.
+type uringSQOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// uringCQOffsets is struct io_cqring_offsets.
+type uringCQOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+// uringSQE is struct io_uring_sqe, limited to the fields used here.
+type uringSQE struct {
+	opcode, flags uint8
+	ioprio        uint16
+	fd            int32
+	off, addr     uint64
+	len, rwFlags  uint32
+	userData      uint64
+	_             [24]byte
+}
+
+// uringCQE is struct io_uring_cqe.
+type uringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// Uring is an io_uring instance, used by osFile to read and write instead of
+// the corresponding syscalls. It is safe for concurrent use, though only one
+// operation is in flight at a time.
+//
+// See https://man7.org/linux/man-pages/man7/io_uring.7.html
+type Uring struct {
+	mu sync.Mutex
+	fd int
+
+	ring, sqes []byte
+
+	sqTail, sqMask, sqArray *uint32
+	cqHead, cqTail, cqMask  *uint32
+	cqes                    unsafe.Pointer
+
+	// disabled is set when an operation isn't supported by the kernel, for
+	// example IORING_OP_READ before Linux 5.6, so that callers fall back.
+	disabled atomic.Bool
+}
+
+// NewUring returns a new io_uring instance, or an error if the kernel
+// doesn't support it, or it is disallowed, e.g. by seccomp.
+func NewUring() (*Uring, experimentalsys.Errno) {
+	var p uringParams
+	fd, _, e := syscall.Syscall(sysIoUringSetup, uringEntries, uintptr(unsafe.Pointer(&p)), 0)
+	if e != 0 {
+		return nil, experimentalsys.UnwrapOSError(e)
+	}
+	r := &Uring{fd: int(fd)}
+	if p.features&uringFeatSingleMmap == 0 { // before Linux 5.4
+		_ = syscall.Close(r.fd)
+		return nil, experimentalsys.ENOSYS
+	}
+
+	// With IORING_FEAT_SINGLE_MMAP, both rings are in the same mapping.
+	size := p.sqOff.array + p.sqEntries*4
+	if cqSize := p.cqOff.cqes + p.cqEntries*uringCQESize; cqSize > size {
+		size = cqSize
+	}
+	var err error
+	if r.ring, err = syscall.Mmap(r.fd, uringOffSQRing, int(size),
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE); err != nil {
+		_ = syscall.Close(r.fd)
+		return nil, experimentalsys.UnwrapOSError(err)
+	}
+	if r.sqes, err = syscall.Mmap(r.fd, uringOffSQEs, int(p.sqEntries*uringSQESize),
+		syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_POPULATE); err != nil {
+		_ = syscall.Munmap(r.ring)
+		_ = syscall.Close(r.fd)
+		return nil, experimentalsys.UnwrapOSError(err)
+	}
+
+	r.sqTail = r.field(p.sqOff.tail)
+	r.sqMask = r.field(p.sqOff.ringMask)
+	r.sqArray = r.field(p.sqOff.array)
+	r.cqHead = r.field(p.cqOff.head)
+	r.cqTail = r.field(p.cqOff.tail)
+	r.cqMask = r.field(p.cqOff.ringMask)
+	r.cqes = unsafe.Pointer(&r.ring[p.cqOff.cqes])
+	return r, 0
+}
+
+func (r *Uring) field(offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&r.ring[offset]))
+}
+
+// Close releases the io_uring instance.
+func (r *Uring) Close() experimentalsys.Errno {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fd == -1 {
+		return 0
+	}
+	_ = syscall.Munmap(r.sqes)
+	_ = syscall.Munmap(r.ring)
+	errno := experimentalsys.UnwrapOSError(syscall.Close(r.fd))
+	r.fd = -1
+	return errno
+}
+
+// read reads into `buf` from `fd` at `off`, or at the file position if
+// `off` is uringCurrentPosition. `ok` is false when the caller must fall
+// back to a syscall.
+func (r *Uring) read(fd uintptr, buf []byte, off uint64) (n int, errno experimentalsys.Errno, ok bool) {
+	return r.rw(uringOpRead, fd, buf, off)
+}
+
+// write is like read, except it writes `buf`.
+func (r *Uring) write(fd uintptr, buf []byte, off uint64) (n int, errno experimentalsys.Errno, ok bool) {
+	return r.rw(uringOpWrite, fd, buf, off)
+}
+
+func (r *Uring) rw(op uint8, fd uintptr, buf []byte, off uint64) (n int, errno experimentalsys.Errno, ok bool) {
+	if r == nil || r.disabled.Load() || len(buf) == 0 {
+		return 0, 0, false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fd == -1 {
+		return 0, 0, false
+	}
+
+	// Only one operation is in flight, so the queues are empty.
+	tail := atomic.LoadUint32(r.sqTail)
+	idx := tail & *r.sqMask
+	*(*uringSQE)(unsafe.Pointer(&r.sqes[idx*uringSQESize])) = uringSQE{
+		opcode: op,
+		fd:     int32(fd),
+		off:    off,
+		addr:   uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		len:    uint32(len(buf)),
+	}
+	*(*uint32)(unsafe.Add(unsafe.Pointer(r.sqArray), idx*4)) = idx
+	atomic.StoreUint32(r.sqTail, tail+1)
+
+	submit := uintptr(1)
+	for {
+		_, _, e := syscall.Syscall6(sysIoUringEnter, uintptr(r.fd), submit, 1, uringEnterGetEvents, 0, 0)
+		if e == 0 {
+			submit = 0
+		} else if e != syscall.EINTR {
+			// Nothing was submitted, e.g. due to a resource limit, so stop
+			// using io_uring.
+			r.disabled.Store(true)
+			return 0, 0, false
+		}
+		if head := atomic.LoadUint32(r.cqHead); head != atomic.LoadUint32(r.cqTail) {
+			cqe := (*uringCQE)(unsafe.Add(r.cqes, (head&*r.cqMask)*uringCQESize))
+			res := cqe.res
+			atomic.StoreUint32(r.cqHead, head+1)
+			runtime.KeepAlive(buf)
+			if res < 0 {
+				errno := syscall.Errno(-res)
+				if errno == syscall.EINVAL || errno == syscall.EOPNOTSUPP {
+					// The operation isn't supported, so stop using io_uring.
+					r.disabled.Store(true)
+					return 0, 0, false
+				}
+				return 0, experimentalsys.UnwrapOSError(errno), true
+			}
+			return int(res), 0, true
+		}
+	}
+}
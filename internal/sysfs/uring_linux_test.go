@@ -0,0 +1,76 @@
+//go:build amd64 || arm64
+
+package sysfs
+
+import (
+	"io"
+	"path"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOsFile_Uring(t *testing.T) {
+	r, errno := NewUring()
+	if errno != 0 {
+		t.Skipf("io_uring unavailable: %v", errno)
+	}
+	defer r.Close()
+
+	path := path.Join(t.TempDir(), emptyFile)
+	f := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	defer f.Close()
+	UseUring(f, r)
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	n, errno = f.Pwrite([]byte("ro"), 6)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, n)
+
+	// The position was updated by Write, but not by Pwrite.
+	offset, errno := f.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), offset)
+
+	buf := make([]byte, 4)
+	n, errno = f.Pread(buf, 4)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "roro", string(buf[:n]))
+
+	_, errno = f.Seek(2, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "zero", string(buf[:n]))
+
+	// Reads at EOF return zero, like without io_uring.
+	_, errno = f.Seek(0, io.SeekEnd)
+	require.EqualErrno(t, 0, errno)
+	n, errno = f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	require.False(t, r.disabled.Load())
+
+	// Once closed, the file descriptor isn't used.
+	require.EqualErrno(t, 0, f.Close())
+	_, errno = f.Read(buf)
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestUring_Close(t *testing.T) {
+	r, errno := NewUring()
+	if errno != 0 {
+		t.Skipf("io_uring unavailable: %v", errno)
+	}
+	require.EqualErrno(t, 0, r.Close())
+	require.EqualErrno(t, 0, r.Close()) // idempotent
+
+	// Operations fall back to syscalls once closed.
+	_, _, ok := r.read(0, make([]byte, 1), 0)
+	require.False(t, ok)
+}
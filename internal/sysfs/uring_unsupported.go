@@ -0,0 +1,30 @@
+//go:build !linux || !(amd64 || arm64)
+
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+// uringCurrentPosition is the offset to use and update the file position.
+const uringCurrentPosition = ^uint64(0)
+
+// Uring is an io_uring instance, which is only supported on Linux.
+type Uring struct{}
+
+// NewUring returns experimentalsys.ENOSYS as io_uring is only supported on
+// Linux.
+func NewUring() (*Uring, experimentalsys.Errno) {
+	return nil, experimentalsys.ENOSYS
+}
+
+// Close implements the same method as documented on Uring in Linux.
+func (r *Uring) Close() experimentalsys.Errno {
+	return 0
+}
+
+func (r *Uring) read(uintptr, []byte, uint64) (int, experimentalsys.Errno, bool) {
+	return 0, 0, false
+}
+
+func (r *Uring) write(uintptr, []byte, uint64) (int, experimentalsys.Errno, bool) {
+	return 0, 0, false
+}
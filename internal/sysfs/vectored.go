@@ -0,0 +1,27 @@
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+// VectoredFile is implemented by files that support vectored IO, which
+// reads into or writes from multiple buffers with one call to the host.
+//
+// This is currently only implemented by files opened on linux.
+//
+// Note: The buffers are passed to the host by address. Callers must only use
+// memory that the Go runtime doesn't manage, such as memory allocated by mmap.
+type VectoredFile interface {
+	// Readv is like sys.File Read, except it reads into each of `bufs` in
+	// order, until one is not filled.
+	Readv(bufs [][]byte) (n int, errno experimentalsys.Errno)
+
+	// Preadv is like Readv, except it reads from the offset `off`, like
+	// sys.File Pread.
+	Preadv(bufs [][]byte, off int64) (n int, errno experimentalsys.Errno)
+
+	// Writev is like sys.File Write, except it writes each of `bufs` in order.
+	Writev(bufs [][]byte) (n int, errno experimentalsys.Errno)
+
+	// Pwritev is like Writev, except it writes to the offset `off`, like
+	// sys.File Pwrite.
+	Pwritev(bufs [][]byte, off int64) (n int, errno experimentalsys.Errno)
+}
@@ -0,0 +1,70 @@
+package sysfs
+
+import (
+	"syscall"
+	"unsafe"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// compile-time check to ensure osFile implements VectoredFile
+var _ VectoredFile = (*osFile)(nil)
+
+// Readv implements VectoredFile.Readv
+func (f *osFile) Readv(bufs [][]byte) (n int, errno experimentalsys.Errno) {
+	if n, errno = vectoredIO(syscall.SYS_READV, f.fd, bufs, 0); errno != 0 {
+		// Defer validation overhead until we've already had an error.
+		errno = fileError(f, f.closed, errno)
+	}
+	return
+}
+
+// Preadv implements VectoredFile.Preadv
+func (f *osFile) Preadv(bufs [][]byte, off int64) (n int, errno experimentalsys.Errno) {
+	if n, errno = vectoredIO(syscall.SYS_PREADV, f.fd, bufs, off); errno != 0 {
+		errno = fileError(f, f.closed, errno)
+	}
+	return
+}
+
+// Writev implements VectoredFile.Writev
+func (f *osFile) Writev(bufs [][]byte) (n int, errno experimentalsys.Errno) {
+	if n, errno = vectoredIO(syscall.SYS_WRITEV, f.fd, bufs, 0); errno != 0 {
+		errno = fileError(f, f.closed, errno)
+	}
+	return
+}
+
+// Pwritev implements VectoredFile.Pwritev
+func (f *osFile) Pwritev(bufs [][]byte, off int64) (n int, errno experimentalsys.Errno) {
+	if n, errno = vectoredIO(syscall.SYS_PWRITEV, f.fd, bufs, off); errno != 0 {
+		errno = fileError(f, f.closed, errno)
+	}
+	return
+}
+
+// vectoredIO invokes the syscall `trap`, which is one of readv, preadv,
+// writev or pwritev. `off` is ignored by readv and writev.
+func vectoredIO(trap, fd uintptr, bufs [][]byte, off int64) (int, experimentalsys.Errno) {
+	iovecs := make([]syscall.Iovec, 0, len(bufs))
+	for _, buf := range bufs {
+		if len(buf) == 0 {
+			continue // skip as there is no address to take.
+		}
+		iovec := syscall.Iovec{Base: &buf[0]}
+		iovec.SetLen(len(buf))
+		iovecs = append(iovecs, iovec)
+	}
+	if len(iovecs) == 0 {
+		return 0, 0 // Short-circuit 0-len IO.
+	}
+
+	// The offset is passed as low and high words. On 64-bit, the kernel
+	// ignores the high word.
+	n, _, e := syscall.Syscall6(trap, fd, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(len(iovecs)),
+		uintptr(off), uintptr(uint64(off)>>32), 0)
+	if e != 0 {
+		return 0, experimentalsys.UnwrapOSError(e)
+	}
+	return int(n), 0
+}
@@ -0,0 +1,51 @@
+package sysfs
+
+import (
+	"path"
+	"syscall"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOsFile_Vectored(t *testing.T) {
+	path := path.Join(t.TempDir(), emptyFile)
+	f := requireOpenFile(t, path, experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	defer f.Close()
+	vf := f.(VectoredFile)
+
+	// The buffers must not be managed by Go, as their addresses are passed.
+	mem, err := syscall.Mmap(-1, 0, 16, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	require.NoError(t, err)
+	defer syscall.Munmap(mem) //nolint
+
+	copy(mem, "wazero")
+	n, errno := vf.Writev([][]byte{mem[0:4], {}, mem[4:6]})
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	n, errno = vf.Pwritev([][]byte{mem[4:6], mem[0:2]}, 6)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+
+	reset := func() { copy(mem, "????????????????") }
+
+	reset()
+	n, errno = vf.Preadv([][]byte{mem[0:3], mem[8:16]}, 1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 9, n)
+	require.Equal(t, "aze?????rorowa??", string(mem))
+
+	_, errno = f.Seek(0, 0)
+	require.EqualErrno(t, 0, errno)
+	reset()
+	n, errno = vf.Readv([][]byte{mem[0:8], mem[8:16]})
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 10, n)
+	require.Equal(t, "wazeroro", string(mem[0:8]))
+
+	require.EqualErrno(t, 0, f.Close())
+	_, errno = vf.Readv([][]byte{mem[0:8], mem[8:16]})
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
@@ -6,10 +6,17 @@ import (
 
 // EncodeLimitsType returns the `limitsType` (min, max) encoded in WebAssembly 1.0 (20191205) Binary Format.
 //
+// When `shared` is true, the limits are encoded as shared as defined by the threads proposal.
+//
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A6
-func EncodeLimitsType(min uint32, max *uint32) []byte {
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md#spec-changes
+func EncodeLimitsType(min uint32, max *uint32, shared bool) []byte {
+	var flag uint32
+	if shared {
+		flag = 0x02
+	}
 	if max == nil {
-		return append(leb128.EncodeUint32(0x00), leb128.EncodeUint32(min)...)
+		return append(leb128.EncodeUint32(flag), leb128.EncodeUint32(min)...)
 	}
-	return append(leb128.EncodeUint32(0x01), append(leb128.EncodeUint32(min), leb128.EncodeUint32(*max)...)...)
+	return append(leb128.EncodeUint32(flag|0x01), append(leb128.EncodeUint32(min), leb128.EncodeUint32(*max)...)...)
 }
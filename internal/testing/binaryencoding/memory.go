@@ -1,6 +1,7 @@
 package binaryencoding
 
 import (
+	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -12,5 +13,12 @@ func EncodeMemory(i *wasm.Memory) []byte {
 	if !i.IsMaxEncoded {
 		maxPtr = nil
 	}
-	return EncodeLimitsType(i.Min, maxPtr)
+	ret := EncodeLimitsType(i.Min, maxPtr, i.IsShared)
+	if i.IsPageSizeEncoded {
+		// The flag is a single byte, which has a bit for the page size, as
+		// defined by the custom-page-sizes proposal.
+		ret[0] |= 0x08
+		ret = append(ret, leb128.EncodeUint32(i.PageSizeLog2)...)
+	}
+	return ret
 }
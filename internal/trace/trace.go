@@ -0,0 +1,36 @@
+//go:build !tinygo
+
+// Package trace wraps runtime/trace, which TinyGo doesn't implement. Its
+// functions have the same signatures, so callers import this package instead.
+package trace
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// Task is a runtime/trace.Task.
+type Task = trace.Task
+
+// Region is a runtime/trace.Region.
+type Region = trace.Region
+
+// IsEnabled is the same as runtime/trace.IsEnabled.
+func IsEnabled() bool {
+	return trace.IsEnabled()
+}
+
+// NewTask is the same as runtime/trace.NewTask.
+func NewTask(ctx context.Context, taskType string) (context.Context, *Task) {
+	return trace.NewTask(ctx, taskType)
+}
+
+// Log is the same as runtime/trace.Log.
+func Log(ctx context.Context, category, message string) {
+	trace.Log(ctx, category, message)
+}
+
+// StartRegion is the same as runtime/trace.StartRegion.
+func StartRegion(ctx context.Context, regionType string) *Region {
+	return trace.StartRegion(ctx, regionType)
+}
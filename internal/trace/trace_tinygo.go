@@ -0,0 +1,36 @@
+// This is the opposite constraint of trace.go
+//go:build tinygo
+
+package trace
+
+import "context"
+
+// Task is a no-op, as tracing is never enabled.
+type Task struct{}
+
+// End is a no-op.
+func (*Task) End() {}
+
+// Region is a no-op, as tracing is never enabled.
+type Region struct{}
+
+// End is a no-op.
+func (*Region) End() {}
+
+// IsEnabled returns false, as TinyGo doesn't implement runtime/trace.
+func IsEnabled() bool {
+	return false
+}
+
+// NewTask returns the context and a no-op Task.
+func NewTask(ctx context.Context, _ string) (context.Context, *Task) {
+	return ctx, &Task{}
+}
+
+// Log is a no-op.
+func Log(context.Context, string, string) {}
+
+// StartRegion returns a no-op Region.
+func StartRegion(context.Context, string) *Region {
+	return &Region{}
+}
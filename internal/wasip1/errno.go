@@ -175,8 +175,12 @@ const (
 	// ErrnoXdev Cross-device link.
 	ErrnoXdev
 
-	// Note: ErrnoNotcapable was removed by WASI maintainers.
+	// ErrnoNotcapable Extension: Capabilities insufficient.
+	//
+	// Note: This was removed by WASI maintainers, so is only returned when
+	// older guests are configured to expect it.
 	// See https://github.com/WebAssembly/wasi-libc/pull/294
+	ErrnoNotcapable
 )
 
 var errnoToString = [...]string{
@@ -292,6 +296,8 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoNametoolong
 	case sys.ENOENT:
 		return ErrnoNoent
+	case sys.ENOSPC:
+		return ErrnoNospc
 	case sys.ENOSYS:
 		return ErrnoNosys
 	case sys.ENOTDIR:
@@ -304,8 +310,12 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoNotsock
 	case sys.ENOTSUP:
 		return ErrnoNotsup
+	case sys.ENOTTY:
+		return ErrnoNotty
 	case sys.EPERM:
 		return ErrnoPerm
+	case sys.EPIPE:
+		return ErrnoPipe
 	case sys.EROFS:
 		return ErrnoRofs
 	default:
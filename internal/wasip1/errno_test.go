@@ -76,6 +76,11 @@ func TestToErrno(t *testing.T) {
 			input:    sys.ENOENT,
 			expected: ErrnoNoent,
 		},
+		{
+			name:     "sys.ENOSPC",
+			input:    sys.ENOSPC,
+			expected: ErrnoNospc,
+		},
 		{
 			name:     "sys.ENOSYS",
 			input:    sys.ENOSYS,
@@ -101,11 +106,21 @@ func TestToErrno(t *testing.T) {
 			input:    sys.ENOTSUP,
 			expected: ErrnoNotsup,
 		},
+		{
+			name:     "sys.ENOTTY",
+			input:    sys.ENOTTY,
+			expected: ErrnoNotty,
+		},
 		{
 			name:     "sys.EPERM",
 			input:    sys.EPERM,
 			expected: ErrnoPerm,
 		},
+		{
+			name:     "sys.EPIPE",
+			input:    sys.EPIPE,
+			expected: ErrnoPipe,
+		},
 		{
 			name:     "sys.EROFS",
 			input:    sys.EROFS,
@@ -0,0 +1,110 @@
+package wasm
+
+// instanceArena holds the globals, tables and element instances of a module
+// instance, so that they are reused by the next instance of the same module
+// instead of allocated again. See experimental.WithInstanceArena.
+//
+// Each slice is resliced, and zeroed, to the length needed by the instance.
+type instanceArena struct {
+	globals    []GlobalInstance
+	tables     []TableInstance
+	elements   []ElementInstance
+	references []Reference
+}
+
+// acquireArena assigns an instanceArena from the pool of the module, unless
+// the module exports globals or tables, which may outlive the instance in
+// modules which import them.
+func (m *ModuleInstance) acquireArena(module *Module) {
+	for _, exp := range module.ExportSection {
+		if exp.Type == ExternTypeGlobal || exp.Type == ExternTypeTable {
+			return
+		}
+	}
+	if a, ok := module.instanceArenas.Get().(*instanceArena); ok {
+		m.arena = a
+	} else {
+		m.arena = &instanceArena{}
+	}
+}
+
+// releaseArena returns the instanceArena, if any, to the pool of the module
+// on close.
+func (m *ModuleInstance) releaseArena() {
+	if a := m.arena; a != nil {
+		m.arena = nil
+		m.Source.instanceArenas.Put(a)
+	}
+}
+
+// allocGlobals returns `n` zero globals.
+func (a *instanceArena) allocGlobals(n int) []GlobalInstance {
+	if a == nil {
+		return make([]GlobalInstance, n)
+	}
+	if cap(a.globals) < n {
+		a.globals = make([]GlobalInstance, n)
+		return a.globals
+	}
+	a.globals = a.globals[:n]
+	for i := range a.globals {
+		a.globals[i] = GlobalInstance{}
+	}
+	return a.globals
+}
+
+// allocTables returns `n` zero tables. The references of each table are
+// left for reuse by allocReferences.
+func (a *instanceArena) allocTables(n int) []TableInstance {
+	if a == nil {
+		return make([]TableInstance, n)
+	}
+	if cap(a.tables) < n {
+		a.tables = make([]TableInstance, n)
+		return a.tables
+	}
+	a.tables = a.tables[:n]
+	for i := range a.tables {
+		t := &a.tables[i]
+		*t = TableInstance{References: t.References[:0]}
+	}
+	return a.tables
+}
+
+// allocElements returns `n` zero element instances.
+func (a *instanceArena) allocElements(n int) []ElementInstance {
+	if a == nil {
+		return make([]ElementInstance, n)
+	}
+	if cap(a.elements) < n {
+		a.elements = make([]ElementInstance, n)
+		return a.elements
+	}
+	a.elements = a.elements[:n]
+	for i := range a.elements {
+		a.elements[i] = ElementInstance{}
+	}
+	return a.elements
+}
+
+// allocElementReferences returns `n` zero references for element instances.
+func (a *instanceArena) allocElementReferences(n int) []Reference {
+	if a == nil {
+		return make([]Reference, n)
+	}
+	a.references = reuseReferences(a.references, n)
+	return a.references
+}
+
+// reuseReferences returns `refs` resliced and zeroed to `n`, or new
+// references if it is nil or its capacity is too small.
+func reuseReferences(refs []Reference, n int) []Reference {
+	if refs == nil || cap(refs) < n {
+		return make([]Reference, n)
+	}
+	refs = refs[:n]
+	for i := range refs {
+		refs[i] = 0
+	}
+	return refs
+}
@@ -0,0 +1,122 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestStore_Instantiate_arena(t *testing.T) {
+	s := newStore()
+	ctx := experimental.WithInstanceArena(context.Background())
+	m := &Module{
+		GlobalSection: []Global{{
+			Type: GlobalType{ValType: ValueTypeI32, Mutable: true},
+			Init: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(42)},
+		}},
+		TableSection: []Table{{Min: 2, Type: RefTypeFuncref}},
+		ElementSection: []ElementSegment{
+			{Mode: ElementModePassive, Type: RefTypeFuncref, Init: []Index{ElementInitNullReference}},
+		},
+	}
+
+	first, err := s.Instantiate(ctx, m, "first", nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, first.arena)
+	globals, tables := first.arena.globals, first.arena.tables
+
+	// Dirty the instance, to ensure the next one is zeroed.
+	first.Globals[0].Val = 1
+	first.Tables[0].References[1] = 0xff
+	first.ElementInstances[0].References[0] = 0xff
+	require.NoError(t, first.Close(ctx))
+	require.Nil(t, first.arena)
+
+	second, err := s.Instantiate(ctx, m, "second", nil, nil)
+	require.NoError(t, err)
+	defer second.Close(ctx)
+
+	// The pool may drop its items, e.g. on GC, so only check reuse if the
+	// arena was reused.
+	if second.arena.globals != nil && &second.arena.globals[0] == &globals[0] {
+		require.Equal(t, &tables[0], second.Tables[0])
+	}
+	require.Equal(t, uint64(42), second.Globals[0].Val)
+	require.Equal(t, []Reference{0, 0}, second.Tables[0].References)
+	require.Equal(t, []Reference{0}, second.ElementInstances[0].References)
+}
+
+func TestStore_Instantiate_arenaClosedAsynchronously(t *testing.T) {
+	s := newStore()
+	ctx := experimental.WithInstanceArena(context.Background())
+	m := &Module{
+		GlobalSection: []Global{{
+			Type: GlobalType{ValType: ValueTypeI32, Mutable: true},
+			Init: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(42)},
+		}},
+	}
+
+	mod, err := s.Instantiate(ctx, m, "async", nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, mod.arena)
+
+	// Close as CloseModuleOnCanceledOrTimeout does, then check the exit code
+	// as the code of the module does while it still runs.
+	require.NoError(t, mod.closeWithExitCodeWithoutClosingResource(1))
+	require.Error(t, mod.FailIfClosed())
+	require.Nil(t, mod.arena)
+
+	// The arena wasn't released for reuse, as the code may still use it.
+	require.Nil(t, m.instanceArenas.Get())
+}
+
+func TestStore_Instantiate_arenaExported(t *testing.T) {
+	s := newStore()
+	ctx := experimental.WithInstanceArena(context.Background())
+	m := &Module{
+		GlobalSection: []Global{{
+			Type: GlobalType{ValType: ValueTypeI32},
+			Init: ConstantExpression{Opcode: OpcodeI32Const, Data: leb128.EncodeInt32(42)},
+		}},
+		ExportSection: []Export{{Type: ExternTypeGlobal, Name: "g"}},
+	}
+
+	mod, err := s.Instantiate(ctx, m, "exported", nil, nil)
+	require.NoError(t, err)
+	defer mod.Close(ctx)
+
+	// The global can be imported by another module, so it isn't reused.
+	require.Nil(t, mod.arena)
+}
+
+func TestInstanceArena_alloc(t *testing.T) {
+	var a *instanceArena
+	require.Equal(t, 2, len(a.allocGlobals(2)))
+	require.Equal(t, 2, len(a.allocTables(2)))
+	require.Equal(t, 2, len(a.allocElements(2)))
+	require.Equal(t, 2, len(a.allocElementReferences(2)))
+
+	a = &instanceArena{}
+	globals := a.allocGlobals(2)
+	globals[1].Val = 1
+	// Shrinking reuses and zeroes the same globals.
+	require.Equal(t, []GlobalInstance{{}}, a.allocGlobals(1))
+	require.Equal(t, &globals[0], &a.allocGlobals(2)[0])
+	require.Equal(t, uint64(0), a.globals[1].Val)
+
+	tables := a.allocTables(1)
+	tables[0].References = []Reference{1, 2}
+	tables[0].Min = 2
+	tables = a.allocTables(1)
+	require.Equal(t, uint32(0), tables[0].Min)
+	// References are kept, but emptied, for reuseReferences.
+	require.Equal(t, 0, len(tables[0].References))
+	require.Equal(t, 2, cap(tables[0].References))
+
+	refs := reuseReferences([]Reference{1, 2, 3}, 2)
+	require.Equal(t, []Reference{0, 0}, refs)
+	require.Equal(t, 4, len(reuseReferences(refs, 4)))
+}
@@ -6,12 +6,14 @@ import (
 	"io"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/ieee754"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
 func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.ConstantExpression) error {
+	offsetAtOpcode := r.Size() - int64(r.Len())
 	b, err := r.ReadByte()
 	if err != nil {
 		return fmt.Errorf("read opcode: %v", err)
@@ -92,6 +94,11 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 		return fmt.Errorf("look for end opcode: %v", err)
 	}
 
+	if b != wasm.OpcodeEnd && enabledFeatures.IsEnabled(experimental.CoreFeaturesExtendedConst) &&
+		(opcode == wasm.OpcodeI32Const || opcode == wasm.OpcodeI64Const || opcode == wasm.OpcodeGlobalGet) {
+		return decodeExtendedConstantExpression(r, b, offsetAtOpcode, ret)
+	}
+
 	if b != wasm.OpcodeEnd {
 		return fmt.Errorf("constant expression has been not terminated")
 	}
@@ -103,3 +110,45 @@ func decodeConstantExpression(r *bytes.Reader, enabledFeatures api.CoreFeatures,
 	ret.Opcode = opcode
 	return nil
 }
+
+// decodeExtendedConstantExpression decodes the rest of a constant expression
+// with more than one instruction, as defined by
+// experimental.CoreFeaturesExtendedConst. `b` is the opcode of the second
+// instruction, and `offsetAtOpcode` is the offset of the first one.
+//
+// The result is validated by wasm.Module Validate, e.g. that it results in
+// one value.
+func decodeExtendedConstantExpression(r *bytes.Reader, b byte, offsetAtOpcode int64, ret *wasm.ConstantExpression) (err error) {
+	for opcode := b; opcode != wasm.OpcodeEnd; {
+		switch opcode {
+		case wasm.OpcodeI32Const:
+			_, _, err = leb128.DecodeInt32(r)
+		case wasm.OpcodeI64Const:
+			_, _, err = leb128.DecodeInt64(r)
+		case wasm.OpcodeGlobalGet:
+			_, _, err = leb128.DecodeUint32(r)
+		default:
+			if !wasm.IsExtendedConstOpcode(opcode) {
+				return fmt.Errorf("%v for const expression opt code: %#x", ErrInvalidByte, opcode)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("read value: %v", err)
+		}
+
+		ret.Opcode = opcode
+		if opcode, err = r.ReadByte(); err != nil {
+			return fmt.Errorf("look for end opcode: %v", err)
+		}
+	}
+
+	if !wasm.IsExtendedConstOpcode(ret.Opcode) {
+		return fmt.Errorf("const expression must end with an arithmetic instruction, but was %s", wasm.InstructionName(ret.Opcode))
+	}
+
+	ret.Data = make([]byte, r.Size()-int64(r.Len())-1-offsetAtOpcode)
+	if _, err = r.ReadAt(ret.Data, offsetAtOpcode); err != nil {
+		return fmt.Errorf("error re-buffering ConstantExpression.Data")
+	}
+	return nil
+}
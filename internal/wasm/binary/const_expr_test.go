@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -79,6 +80,42 @@ func TestDecodeConstantExpression(t *testing.T) {
 				},
 			},
 		},
+		{
+			in: []byte{
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 0x80, 0x01, // 128
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI32Add,
+				Data: []byte{
+					wasm.OpcodeGlobalGet, 0,
+					wasm.OpcodeI32Const, 0x80, 0x01,
+					wasm.OpcodeI32Add,
+				},
+			},
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI64Const, 2,
+				wasm.OpcodeI64Const, 3,
+				wasm.OpcodeI64Const, 4,
+				wasm.OpcodeI64Mul,
+				wasm.OpcodeI64Sub,
+				wasm.OpcodeEnd,
+			},
+			exp: wasm.ConstantExpression{
+				Opcode: wasm.OpcodeI64Sub,
+				Data: []byte{
+					wasm.OpcodeI64Const, 2,
+					wasm.OpcodeI64Const, 3,
+					wasm.OpcodeI64Const, 4,
+					wasm.OpcodeI64Mul,
+					wasm.OpcodeI64Sub,
+				},
+			},
+		},
 	}
 
 	for i, tt := range tests {
@@ -86,7 +123,7 @@ func TestDecodeConstantExpression(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			var actual wasm.ConstantExpression
 			err := decodeConstantExpression(bytes.NewReader(tc.in),
-				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD, &actual)
+				api.CoreFeatureBulkMemoryOperations|api.CoreFeatureSIMD|experimental.CoreFeaturesExtendedConst, &actual)
 			require.NoError(t, err)
 			require.Equal(t, tc.exp, actual)
 		})
@@ -178,6 +215,44 @@ func TestDecodeConstantExpression_errors(t *testing.T) {
 			expectedErr: "read vector const instruction immediates: needs 16 bytes but was 8 bytes",
 			features:    api.CoreFeatureSIMD,
 		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "constant expression has been not terminated",
+			features:    api.CoreFeaturesV2,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32DivS,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "invalid byte for const expression opt code: 0x6d",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeEnd,
+			},
+			expectedErr: "const expression must end with an arithmetic instruction, but was i32.const",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
+		{
+			in: []byte{
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeI32Const, 2,
+				wasm.OpcodeI32Add,
+			},
+			expectedErr: "look for end opcode: EOF",
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		},
 	}
 
 	for _, tt := range tests {
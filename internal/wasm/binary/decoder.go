@@ -51,7 +51,10 @@ func DecodeModule(
 
 		sectionSize, _, err := leb128.DecodeUint32(r)
 		if err != nil {
-			return nil, fmt.Errorf("get size of section %s: %v", wasm.SectionIDName(sectionID), err)
+			return nil, &wasm.InvalidModuleError{
+				Offset: int64(len(binary) - r.Len()),
+				Err:    fmt.Errorf("get size of section %s: %v", wasm.SectionIDName(sectionID), err),
+			}
 		}
 
 		sectionContentStart := r.Len()
@@ -115,7 +118,7 @@ func DecodeModule(
 		case wasm.SectionIDTable:
 			m.TableSection, err = decodeTableSection(r, enabledFeatures)
 		case wasm.SectionIDMemory:
-			m.MemorySection, err = decodeMemorySection(r, memSizer, memoryLimitPages)
+			m.MemorySection, err = decodeMemorySection(r, memSizer, memoryLimitPages, enabledFeatures)
 		case wasm.SectionIDGlobal:
 			if m.GlobalSection, err = decodeGlobalSection(r, enabledFeatures); err != nil {
 				return nil, err // avoid re-wrapping the error.
@@ -148,7 +151,11 @@ func DecodeModule(
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf("section %s: %v", wasm.SectionIDName(sectionID), err)
+			return nil, &wasm.InvalidModuleError{
+				Section: wasm.SectionIDName(sectionID),
+				Offset:  int64(len(binary) - r.Len()),
+				Err:     err,
+			}
 		}
 	}
 
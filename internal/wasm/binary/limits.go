@@ -7,36 +7,45 @@ import (
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
-// decodeLimitsType returns the `limitsType` (min, max) decoded with the WebAssembly 1.0 (20191205) Binary Format.
+// decodeLimitsType returns the `limitsType` (min, max) decoded with the WebAssembly 1.0 (20191205) Binary Format,
+// whether they are shared as defined by the threads proposal, and the log2 of the page size if encoded as defined
+// by the custom-page-sizes proposal.
 //
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#limits%E2%91%A6
-func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, err error) {
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md#spec-changes
+// See https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md#binary-encoding
+func decodeLimitsType(r *bytes.Reader) (min uint32, max *uint32, shared bool, pageSizeLog2 *uint32, err error) {
 	var flag byte
 	if flag, err = r.ReadByte(); err != nil {
 		err = fmt.Errorf("read leading byte: %v", err)
 		return
 	}
 
-	switch flag {
-	case 0x00:
-		min, _, err = leb128.DecodeUint32(r)
-		if err != nil {
-			err = fmt.Errorf("read min of limit: %v", err)
-		}
-	case 0x01:
-		min, _, err = leb128.DecodeUint32(r)
-		if err != nil {
-			err = fmt.Errorf("read min of limit: %v", err)
-			return
-		}
+	if flag&^0x0b != 0 {
+		err = fmt.Errorf("%v for limits: %#x not in (0x00, 0x01, 0x02, 0x03, 0x08, 0x09, 0x0a, 0x0b)", ErrInvalidByte, flag)
+		return
+	}
+
+	if min, _, err = leb128.DecodeUint32(r); err != nil {
+		err = fmt.Errorf("read min of limit: %v", err)
+		return
+	}
+	if flag&0x01 != 0 {
 		var m uint32
 		if m, _, err = leb128.DecodeUint32(r); err != nil {
 			err = fmt.Errorf("read max of limit: %v", err)
-		} else {
-			max = &m
+			return
+		}
+		max = &m
+	}
+	if flag&0x08 != 0 {
+		var p uint32
+		if p, _, err = leb128.DecodeUint32(r); err != nil {
+			err = fmt.Errorf("read page size of limit: %v", err)
+			return
 		}
-	default:
-		err = fmt.Errorf("%v for limits: %#x != 0x00 or 0x01", ErrInvalidByte, flag)
+		pageSizeLog2 = &p
 	}
+	shared = flag&0x02 != 0
 	return
 }
@@ -2,7 +2,10 @@ package binary
 
 import (
 	"bytes"
+	"fmt"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
@@ -13,14 +16,61 @@ func decodeMemory(
 	r *bytes.Reader,
 	memorySizer func(minPages uint32, maxPages *uint32) (min, capacity, max uint32),
 	memoryLimitPages uint32,
+	enabledFeatures api.CoreFeatures,
 ) (*wasm.Memory, error) {
-	min, maxP, err := decodeLimitsType(r)
+	min, maxP, shared, pageSizeLog2, err := decodeLimitsType(r)
 	if err != nil {
 		return nil, err
 	}
+	if shared {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesThreads); err != nil {
+			return nil, fmt.Errorf("shared memory invalid as %w", err)
+		} else if maxP == nil {
+			return nil, fmt.Errorf("shared memory requires a maximum")
+		}
+	}
 
-	min, capacity, max := memorySizer(min, maxP)
-	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil}
+	var capacity, max uint32
+	if pageSizeLog2 == nil {
+		min, capacity, max = memorySizer(min, maxP)
+	} else {
+		if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesCustomPageSizes); err != nil {
+			return nil, fmt.Errorf("custom page size invalid as %w", err)
+		} else if *pageSizeLog2 > wasm.MemoryPageSizeInBits {
+			return nil, fmt.Errorf("invalid custom page size: 2^%d bytes over 64KiB", *pageSizeLog2)
+		}
+		min, capacity, max, memoryLimitPages = sizeMemoryWithPageSize(min, maxP, memoryLimitPages, *pageSizeLog2)
+	}
+	if shared {
+		// A shared memory must not move when grown, as it's accessed
+		// concurrently, so allocate its maximum upfront.
+		capacity = max
+	}
+	mem := &wasm.Memory{Min: min, Cap: capacity, Max: max, IsMaxEncoded: maxP != nil, IsShared: shared}
+	if pageSizeLog2 != nil {
+		mem.PageSizeLog2, mem.IsPageSizeEncoded = *pageSizeLog2, true
+	}
 
 	return mem, mem.Validate(memoryLimitPages)
 }
+
+// sizeMemoryWithPageSize is like memorySizer, except for a memory with a
+// custom page size, and returns the limit of its pages. `memoryLimitPages` is
+// in pages of wasm.MemoryPageSize.
+//
+// Capacity is always the minimum, as a custom page size is meant to reduce the
+// memory allocated.
+func sizeMemoryWithPageSize(minPages uint32, maxPages *uint32, memoryLimitPages, pageSizeLog2 uint32) (min, capacity, max, limit uint32) {
+	limit = wasm.MemoryLimitPagesWithPageSize(memoryLimitPages, pageSizeLog2)
+	max = limit
+	if maxPages != nil {
+		max = *maxPages
+		// This is an invalid value: let it propagate, we will fail later.
+		if specLimit := wasm.MemoryLimitPagesWithPageSize(wasm.MemoryLimitPages, pageSizeLog2); max > specLimit {
+			return minPages, minPages, max, specLimit
+		} else if max > limit { // valid, but over the run-time limit.
+			max = limit
+		}
+	}
+	return minPages, minPages, max, limit
+}
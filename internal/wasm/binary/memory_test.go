@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -157,6 +159,21 @@ func TestMemoryType(t *testing.T) {
 			input:    &wasm.Memory{Min: max, Cap: max, Max: max, IsMaxEncoded: true},
 			expected: []byte{0x1, 0x80, 0x80, 0x4, 0x80, 0x80, 0x4},
 		},
+		{
+			name:     "shared",
+			input:    &wasm.Memory{Min: 1, Cap: 3, Max: 3, IsMaxEncoded: true, IsShared: true},
+			expected: []byte{0x3, 1, 3},
+		},
+		{
+			name:     "custom page size 1 byte",
+			input:    &wasm.Memory{Min: 1, Cap: 1, Max: 0x20000, IsMaxEncoded: true, IsPageSizeEncoded: true},
+			expected: []byte{0x9, 1, 0x80, 0x80, 0x8, 0},
+		},
+		{
+			name:     "custom page size 4KiB default max",
+			input:    &wasm.Memory{Min: 2, Cap: 2, Max: 1 << 20, PageSizeLog2: 12, IsPageSizeEncoded: true},
+			expected: []byte{0x8, 2, 12},
+		},
 		{
 			name:             "min 0, max largest, wazero limit",
 			input:            &wasm.Memory{Max: max, IsMaxEncoded: true},
@@ -182,7 +199,8 @@ func TestMemoryType(t *testing.T) {
 				expectedDecoded.Max = tmax
 			}
 
-			binary, err := decodeMemory(bytes.NewReader(b), newMemorySizer(tmax, false), tmax)
+			features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads | experimental.CoreFeaturesCustomPageSizes
+			binary, err := decodeMemory(bytes.NewReader(b), newMemorySizer(tmax, false), tmax, features)
 			require.NoError(t, err)
 			require.Equal(t, binary, expectedDecoded)
 		})
@@ -195,8 +213,36 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 	tests := []struct {
 		name        string
 		input       []byte
+		features    api.CoreFeatures
 		expectedErr string
 	}{
+		{
+			name:        "shared disabled",
+			input:       []byte{0x3, 0, 1},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `shared memory invalid as feature "threads" is disabled`,
+		},
+		{
+			name:        "shared without max",
+			input:       []byte{0x2, 0},
+			expectedErr: "shared memory requires a maximum",
+		},
+		{
+			name:        "custom page size disabled",
+			input:       []byte{0x8, 0, 0},
+			features:    api.CoreFeaturesV2,
+			expectedErr: `custom page size invalid as feature "custom-page-sizes" is disabled`,
+		},
+		{
+			name:        "custom page size over 64KiB",
+			input:       []byte{0x8, 0, 17},
+			expectedErr: "invalid custom page size: 2^17 bytes over 64KiB",
+		},
+		{
+			name:        "custom page size max > limit",
+			input:       []byte{0x9, 0, 0x80, 0x80, 0x80, 0x1, 12},
+			expectedErr: "max 2097152 pages (8 Gi) over limit of 1048576 pages (4 Gi)",
+		},
 		{
 			name:        "max < min",
 			input:       []byte{0x1, 0x80, 0x80, 0x4, 0},
@@ -218,7 +264,11 @@ func TestDecodeMemoryType_Errors(t *testing.T) {
 		tc := tt
 
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := decodeMemory(bytes.NewReader(tc.input), newMemorySizer(max, false), max)
+			features := tc.features
+			if features == 0 {
+				features = api.CoreFeaturesV2 | experimental.CoreFeaturesThreads | experimental.CoreFeaturesCustomPageSizes
+			}
+			_, err := decodeMemory(bytes.NewReader(tc.input), newMemorySizer(max, false), max, features)
 			require.EqualError(t, err, tc.expectedErr)
 		})
 	}
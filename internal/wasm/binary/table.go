@@ -23,10 +23,17 @@ func decodeTable(r *bytes.Reader, enabledFeatures api.CoreFeatures, ret *wasm.Ta
 		}
 	}
 
-	ret.Min, ret.Max, err = decodeLimitsType(r)
+	var shared bool
+	var pageSizeLog2 *uint32
+	ret.Min, ret.Max, shared, pageSizeLog2, err = decodeLimitsType(r)
 	if err != nil {
 		return fmt.Errorf("read limits: %v", err)
 	}
+	if shared {
+		return fmt.Errorf("tables cannot be shared")
+	} else if pageSizeLog2 != nil {
+		return fmt.Errorf("tables cannot have a page size")
+	}
 	if ret.Min > wasm.MaximumFunctionIndex {
 		return fmt.Errorf("table min must be at most %d", wasm.MaximumFunctionIndex)
 	}
@@ -93,6 +93,16 @@ func TestDecodeTableType_Errors(t *testing.T) {
 			expectedErr: "table min must be at most 134217728",
 			features:    api.CoreFeatureReferenceTypes,
 		},
+		{
+			name:        "shared",
+			input:       []byte{wasm.RefTypeFuncref, 0x3, 0, 1},
+			expectedErr: "tables cannot be shared",
+		},
+		{
+			name:        "page size",
+			input:       []byte{wasm.RefTypeFuncref, 0x8, 0, 0},
+			expectedErr: "tables cannot have a page size",
+		},
 	}
 
 	for _, tt := range tests {
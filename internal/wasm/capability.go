@@ -0,0 +1,31 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+// ErrCapabilityDenied is matched by errors.Is when a host function was called
+// by a module which wasn't granted its capability.
+var ErrCapabilityDenied = errors.New("capability denied")
+
+// RequireCapability returns a function which calls `fn`, an api.GoFunction or
+// api.GoModuleFunction, only if the calling module was granted the
+// `capability`. Otherwise, it panics with an error matching
+// ErrCapabilityDenied.
+func RequireCapability(debugName, capability string, fn interface{}) api.GoModuleFunc {
+	return func(ctx context.Context, mod api.Module, stack []uint64) {
+		if m, ok := mod.(*ModuleInstance); !ok || m.Sys == nil || !m.Sys.HasCapability(capability) {
+			panic(fmt.Errorf("%w: %s requires %q", ErrCapabilityDenied, debugName, capability))
+		}
+		switch fn := fn.(type) {
+		case api.GoModuleFunction:
+			fn.Call(ctx, mod, stack)
+		case api.GoFunction:
+			fn.Call(ctx, stack)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package wasm
+
+import (
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// IsExtended returns true if the expression has more than one instruction, as
+// defined by experimental.CoreFeaturesExtendedConst. In this case, Opcode is
+// the last instruction, which is arithmetic, and Data is all instructions,
+// excluding the terminating OpcodeEnd.
+func (c *ConstantExpression) IsExtended() bool {
+	return IsExtendedConstOpcode(c.Opcode)
+}
+
+// IsExtendedConstOpcode returns true if the opcode is an arithmetic
+// instruction allowed in a constant expression by
+// experimental.CoreFeaturesExtendedConst.
+func IsExtendedConstOpcode(opcode Opcode) bool {
+	switch opcode {
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		return true
+	}
+	return false
+}
+
+// validateExtendedConstExpression returns the type of the value the
+// expression results in, or an error if it isn't a sequence of valid
+// instructions resulting in one value.
+func validateExtendedConstExpression(globals []GlobalType, expr *ConstantExpression) (ValueType, error) {
+	var stack []ValueType
+	for pc := 0; pc < len(expr.Data); {
+		op := expr.Data[pc]
+		pc++
+		switch op {
+		case OpcodeI32Const:
+			_, n, err := leb128.LoadInt32(expr.Data[pc:])
+			if err != nil {
+				return 0, fmt.Errorf("read i32: %w", err)
+			}
+			pc += int(n)
+			stack = append(stack, ValueTypeI32)
+		case OpcodeI64Const:
+			_, n, err := leb128.LoadInt64(expr.Data[pc:])
+			if err != nil {
+				return 0, fmt.Errorf("read i64: %w", err)
+			}
+			pc += int(n)
+			stack = append(stack, ValueTypeI64)
+		case OpcodeGlobalGet:
+			id, n, err := leb128.LoadUint32(expr.Data[pc:])
+			if err != nil {
+				return 0, fmt.Errorf("read index of global: %w", err)
+			} else if uint32(len(globals)) <= id {
+				return 0, fmt.Errorf("global index out of range")
+			}
+			pc += int(n)
+			stack = append(stack, globals[id].ValType)
+		case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+			t := ValueTypeI32
+			if op >= OpcodeI64Add {
+				t = ValueTypeI64
+			}
+			if len(stack) < 2 || stack[len(stack)-1] != t || stack[len(stack)-2] != t {
+				return 0, fmt.Errorf("cannot pop the operands for %s", InstructionName(op))
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			return 0, fmt.Errorf("invalid opcode for const expression: 0x%x", op)
+		}
+	}
+	if len(stack) != 1 {
+		return 0, fmt.Errorf("const expression must result in one value, but was %d", len(stack))
+	}
+	return stack[0], nil
+}
+
+// executeExtendedConstExpression returns the value the expression results in,
+// which is zero-extended if it is an i32. The validity of the expression is
+// ensured by validateExtendedConstExpression.
+func executeExtendedConstExpression(globals []*GlobalInstance, expr *ConstantExpression) uint64 {
+	var stack []uint64
+	for pc := 0; pc < len(expr.Data); {
+		op := expr.Data[pc]
+		pc++
+		switch op {
+		case OpcodeI32Const:
+			v, n, _ := leb128.LoadInt32(expr.Data[pc:])
+			pc += int(n)
+			stack = append(stack, uint64(uint32(v)))
+		case OpcodeI64Const:
+			v, n, _ := leb128.LoadInt64(expr.Data[pc:])
+			pc += int(n)
+			stack = append(stack, uint64(v))
+		case OpcodeGlobalGet:
+			id, n, _ := leb128.LoadUint32(expr.Data[pc:])
+			pc += int(n)
+			g := globals[id]
+			if g.Type.ValType == ValueTypeI32 {
+				stack = append(stack, uint64(uint32(g.Val)))
+			} else {
+				stack = append(stack, g.Val)
+			}
+		default:
+			x1, x2 := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			var v uint64
+			switch op {
+			case OpcodeI32Add:
+				v = uint64(uint32(x1) + uint32(x2))
+			case OpcodeI32Sub:
+				v = uint64(uint32(x1) - uint32(x2))
+			case OpcodeI32Mul:
+				v = uint64(uint32(x1) * uint32(x2))
+			case OpcodeI64Add:
+				v = x1 + x2
+			case OpcodeI64Sub:
+				v = x1 - x2
+			case OpcodeI64Mul:
+				v = x1 * x2
+			}
+			stack[len(stack)-1] = v
+		}
+	}
+	return stack[0]
+}
+
+// importedGlobalTypes returns the types of the imported globals, which are
+// the only globals a constant expression can reference.
+func (m *Module) importedGlobalTypes() (ret []GlobalType) {
+	for i := range m.ImportSection {
+		if imp := &m.ImportSection[i]; imp.Type == ExternTypeGlobal {
+			ret = append(ret, imp.DescGlobal)
+		}
+	}
+	return
+}
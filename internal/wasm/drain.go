@@ -0,0 +1,95 @@
+package wasm
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// inFlightCalls counts the calls of exported functions which haven't
+// returned, so that Store.Drain can wait for them.
+type inFlightCalls struct {
+	count    atomic.Int64
+	draining atomic.Bool
+	// idle is signaled when count drops to zero while draining.
+	idle chan struct{}
+}
+
+func (c *inFlightCalls) begin() {
+	c.count.Add(1)
+}
+
+func (c *inFlightCalls) end() {
+	if c.count.Add(-1) == 0 && c.draining.Load() {
+		select {
+		case c.idle <- struct{}{}:
+		default: // already signaled
+		}
+	}
+}
+
+// BeginCall counts an operation which may call exported functions, such as
+// an instantiation, as in flight until EndCall.
+func (s *Store) BeginCall() {
+	s.calls.begin()
+}
+
+// EndCall ends an operation counted by BeginCall.
+func (s *Store) EndCall() {
+	s.calls.end()
+}
+
+// Drain waits until there are no calls of exported functions in flight, or
+// returns the error of the context if it is done first.
+//
+// Note: Calls made while draining are waited for as well.
+func (s *Store) Drain(ctx context.Context) error {
+	c := &s.calls
+	c.draining.Store(true)
+	for c.count.Load() != 0 {
+		select {
+		case <-c.idle: // check again, as a call may have started since
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// drainedFunction counts calls of an exported function in inFlightCalls.
+type drainedFunction struct {
+	api.Function
+	c *inFlightCalls
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *drainedFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	f.c.begin()
+	defer f.c.end()
+	return f.Function.Call(ctx, params...)
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *drainedFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	f.c.begin()
+	defer f.c.end()
+	return f.Function.CallWithStack(ctx, stack)
+}
+
+// CallWithOptions implements the same method as documented on api.Function.
+func (f *drainedFunction) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) ([]uint64, error) {
+	f.c.begin()
+	defer f.c.end()
+	return f.Function.CallWithOptions(ctx, opts, params...)
+}
+
+// SourceOffsetForPC implements the same method as documented on
+// experimental.InternalFunction.
+func (f *drainedFunction) SourceOffsetForPC(pc experimental.ProgramCounter) uint64 {
+	if fn, ok := f.Function.(experimental.InternalFunction); ok {
+		return fn.SourceOffsetForPC(pc)
+	}
+	return 0
+}
@@ -58,3 +58,16 @@ type ModuleEngine interface {
 	// the initialization via ElementSegment.
 	FunctionInstanceReference(funcIndex Index) Reference
 }
+
+// SizeEstimator is optionally implemented by an Engine to estimate the memory
+// cost of a compiled module. See EstimateInstantiation in the wazero package.
+type SizeEstimator interface {
+	// CompiledSize returns the size in bytes of the compiled code of the
+	// module, which is shared by all its instances, or false if the module is
+	// not compiled by this engine.
+	CompiledSize(module *Module) (uint64, bool)
+
+	// ModuleEngineSize returns the estimated size in bytes allocated by
+	// NewModuleEngine for an instance of the module.
+	ModuleEngineSize(module *Module) uint64
+}
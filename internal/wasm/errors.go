@@ -0,0 +1,227 @@
+package wasm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidModule is matched by errors.Is for an InvalidModuleError.
+	ErrInvalidModule = errors.New("invalid module")
+
+	// ErrUnsatisfiedImport is matched by errors.Is for an
+	// UnsatisfiedImportError.
+	ErrUnsatisfiedImport = errors.New("unsatisfied import")
+)
+
+// InvalidModuleError is returned when a module can't be decoded or is
+// invalid.
+type InvalidModuleError struct {
+	// Section is the name of the section which is invalid, e.g. "type", or
+	// empty if not specific to a section.
+	Section string
+
+	// Offset is the byte offset in the binary where decoding failed, or -1 if
+	// unknown, e.g. when validation failed.
+	Offset int64
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements error.
+func (e *InvalidModuleError) Error() string {
+	if e.Section != "" {
+		return fmt.Sprintf("section %s: %v", e.Section, e.Err)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *InvalidModuleError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if `target` is ErrInvalidModule.
+func (e *InvalidModuleError) Is(target error) bool {
+	return target == ErrInvalidModule
+}
+
+// UnsatisfiedImport is an import of a module which isn't instantiated, or an
+// export which doesn't exist or is of a different type.
+type UnsatisfiedImport struct {
+	Module, Name string
+	Type         ExternType
+}
+
+// String returns the import as shown in errors, e.g. "func[env.f]".
+func (i UnsatisfiedImport) String() string {
+	return fmt.Sprintf("%s[%s.%s]", ExternTypeName(i.Type), i.Module, i.Name)
+}
+
+// UnsatisfiedImportError is returned when instantiating a module with imports
+// which can't be resolved. It lists all of them, instead of only the first.
+type UnsatisfiedImportError struct {
+	// Imports are in the order of the modules they import, then in the order
+	// of the import section.
+	Imports []UnsatisfiedImport
+
+	// Err is the error of the first import.
+	Err error
+}
+
+// Error implements error.
+func (e *UnsatisfiedImportError) Error() string {
+	if n := len(e.Imports); n > 1 {
+		return fmt.Sprintf("%v (and %d other unsatisfied imports)", e.Err, n-1)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the first import.
+func (e *UnsatisfiedImportError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if `target` is ErrUnsatisfiedImport.
+func (e *UnsatisfiedImportError) Is(target error) bool {
+	return target == ErrUnsatisfiedImport
+}
+
+// FunctionValidationError is returned when the body of a function is invalid.
+type FunctionValidationError struct {
+	// FunctionIndex is the index of the function in the function index
+	// namespace, which includes imported functions.
+	FunctionIndex Index
+
+	// Name is the name of the function in the name section, or empty if
+	// unknown.
+	Name string
+
+	// Offset is the byte offset of the invalid instruction in the code
+	// section, or -1 if the error isn't specific to an instruction, e.g. a
+	// missing end of a block.
+	//
+	// Note: This is the same convention as DWARF, which uses offsets in the
+	// code section, not in the binary.
+	Offset int64
+
+	// Instruction is the name of the invalid instruction, e.g. "i32.add", or
+	// empty when Offset is -1.
+	Instruction string
+
+	// Err is the underlying error.
+	Err error
+
+	// desc describes the function in Error, e.g. `function[0] export["f"]`.
+	desc string
+
+	// body and pc are the function body and the offset of the instruction in
+	// it, used by Explain.
+	body []byte
+	pc   int
+}
+
+// Error implements error.
+func (e *FunctionValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.desc, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FunctionValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Explain returns a multi-line description of the error, including the bytes
+// of the invalid instruction, for example to print in a toolchain.
+func (e *FunctionValidationError) Explain() string {
+	var ret strings.Builder
+	ret.WriteString(e.Error())
+	ret.WriteString("\n\tfunction: ")
+	ret.WriteString(strconv.Itoa(int(e.FunctionIndex)))
+	if e.Name != "" {
+		ret.WriteString(" (")
+		ret.WriteString(e.Name)
+		ret.WriteByte(')')
+	}
+	if e.Offset < 0 {
+		return ret.String()
+	}
+	fmt.Fprintf(&ret, "\n\tinstruction: %s at offset 0x%x in the code section", e.Instruction, e.Offset)
+
+	// Show a few bytes after the start of the instruction, which is enough
+	// for the opcode and typical immediates.
+	end := e.pc + 8
+	if end > len(e.body) {
+		end = len(e.body)
+	}
+	fmt.Fprintf(&ret, "\n\tbytes: % x", e.body[e.pc:end])
+	if end < len(e.body) {
+		ret.WriteString(" ...")
+	}
+	return ret.String()
+}
+
+// instructionError annotates an error of validateFunction with the offset of
+// the instruction in the function body.
+type instructionError struct {
+	pc  int
+	err error
+}
+
+// Error implements error.
+func (e *instructionError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *instructionError) Unwrap() error {
+	return e.err
+}
+
+// newFunctionValidationError returns a FunctionValidationError for the
+// function at `idx` in the function section.
+func (m *Module) newFunctionValidationError(idx Index, err error) *FunctionValidationError {
+	funcIdx := idx + m.ImportFunctionCount
+	ret := &FunctionValidationError{
+		FunctionIndex: funcIdx,
+		Offset:        -1,
+		Err:           err,
+		desc:          m.funcDesc(SectionIDFunction, idx),
+	}
+	if m.NameSection != nil {
+		for _, n := range m.NameSection.FunctionNames {
+			if n.Index == funcIdx {
+				ret.Name = n.Name
+				break
+			}
+		}
+	}
+	if ie, ok := err.(*instructionError); ok {
+		code := &m.CodeSection[idx]
+		ret.Err = ie.err
+		ret.body, ret.pc = code.Body, ie.pc
+		ret.Offset = int64(code.BodyOffsetInCodeSection) + int64(ie.pc)
+		ret.Instruction = instructionNameAt(code.Body, ie.pc)
+	}
+	return ret
+}
+
+// instructionNameAt returns the name of the instruction at `pc` in `body`.
+func instructionNameAt(body []byte, pc int) string {
+	op := body[pc]
+	if pc+1 < len(body) {
+		switch op {
+		case OpcodeMiscPrefix:
+			return MiscInstructionName(body[pc+1])
+		case OpcodeVecPrefix:
+			if relaxedOp, ok := LoadVecRelaxedOpcode(body[pc+1:]); ok {
+				return VecRelaxedInstructionName(relaxedOp)
+			}
+			return VectorInstructionName(body[pc+1])
+		}
+	}
+	return InstructionName(op)
+}
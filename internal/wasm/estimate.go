@@ -0,0 +1,40 @@
+package wasm
+
+import "unsafe"
+
+// EstimateInstanceSize estimates the size in bytes allocated by Store.Instantiate for an instance of this module,
+// excluding the ModuleEngine. See SizeEstimator for the latter.
+//
+//   - memory is the capacity of the memory defined by this module, if any.
+//   - tables is the size of the references of the tables defined by this module.
+//   - metadata is the size of the other state, e.g. globals and element instances.
+//
+// Note: the data of segments, and the imported memories, tables and globals are not counted as they are shared.
+func (m *Module) EstimateInstanceSize() (memory, tables, metadata uint64) {
+	const ptrSize = uint64(unsafe.Sizeof(uintptr(0)))
+	const refSize = uint64(unsafe.Sizeof(Reference(0)))
+
+	metadata = uint64(unsafe.Sizeof(ModuleInstance{}))
+	if m.MemorySection != nil {
+		memory = m.MemorySection.PagesToBytesNum(m.MemorySection.Cap)
+		metadata += uint64(unsafe.Sizeof(MemoryInstance{}))
+	}
+
+	for i := range m.TableSection {
+		tables += uint64(m.TableSection[i].Min) * refSize
+	}
+	metadata += uint64(len(m.TableSection)) * uint64(unsafe.Sizeof(TableInstance{}))
+	metadata += (uint64(m.ImportTableCount) + uint64(len(m.TableSection))) * ptrSize
+
+	metadata += uint64(len(m.GlobalSection)) * uint64(unsafe.Sizeof(GlobalInstance{}))
+	metadata += (uint64(m.ImportGlobalCount) + uint64(len(m.GlobalSection))) * ptrSize
+
+	metadata += uint64(len(m.ElementSection)) * uint64(unsafe.Sizeof(ElementInstance{}))
+	for i := range m.ElementSection {
+		if elm := &m.ElementSection[i]; elm.Type == RefTypeFuncref && elm.Mode == ElementModePassive {
+			metadata += uint64(len(elm.Init)) * refSize
+		}
+	}
+	metadata += uint64(len(m.DataSection)) * uint64(unsafe.Sizeof([]byte(nil)))
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 )
 
@@ -65,7 +66,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	maxStackValues int,
 	declaredFunctionIndexes map[Index]struct{},
 	br *bytes.Reader,
-) error {
+) (err error) {
 	functionType := &m.TypeSection[m.FunctionSection[idx]]
 	code := &m.CodeSection[idx]
 	body := code.Body
@@ -76,9 +77,19 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	// We start with the outermost control block which is for function return if the code branches into it.
 	controlBlockStack := &sts.cs
 
+	// instStart is the offset in body of the instruction being validated, or
+	// -1 after the last one. This annotates errors with the position.
+	instStart := -1
+	defer func() {
+		if err != nil && instStart >= 0 {
+			err = &instructionError{pc: instStart, err: err}
+		}
+	}()
+
 	// Now start walking through all the instructions in the body while tracking
 	// control blocks and value types to check the validity of all instructions.
 	for pc := uint64(0); pc < uint64(len(body)); pc++ {
+		instStart = int(pc)
 		op := body[pc]
 		if false {
 			var instName string
@@ -1083,7 +1094,58 @@ func (m *Module) validateFunctionWithMaxStackValues(
 				for _, r := range results {
 					valueTypeStack.push(r)
 				}
+			} else if miscOpcode >= OpcodeMiscI64Add128 && miscOpcode <= OpcodeMiscI64MulWideU {
+				if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesWideArithmetic); err != nil {
+					return fmt.Errorf("%s invalid as %v", miscInstructionNames[miscOpcode], err)
+				}
+
+				// add128 and sub128 take two 128-bit operands, each as a pair of the low and high 64 bits, while
+				// mul_wide_s and mul_wide_u take two 64-bit operands. All push the low then the high 64 bits.
+				params := 2
+				if miscOpcode == OpcodeMiscI64Add128 || miscOpcode == OpcodeMiscI64Sub128 {
+					params = 4
+				}
+				for i := 0; i < params; i++ {
+					if err := valueTypeStack.popAndVerifyType(ValueTypeI64); err != nil {
+						return fmt.Errorf("cannot pop the operand for %s: %v", miscInstructionNames[miscOpcode], err)
+					}
+				}
+				valueTypeStack.push(ValueTypeI64)
+				valueTypeStack.push(ValueTypeI64)
+			} else {
+				return fmt.Errorf("invalid misc opcode: %#x", miscOpcode)
+			}
+		} else if relaxedOpcode, ok := LoadVecRelaxedOpcode(body[pc+1:]); op == OpcodeVecPrefix && ok {
+			pc += 2 // Relaxed vector opcodes are encoded with two bytes.
+			name := VecRelaxedInstructionName(relaxedOpcode)
+			if err := enabledFeatures.RequireEnabled(api.CoreFeatureSIMD); err != nil {
+				return fmt.Errorf("%s invalid as %v", name, err)
+			} else if err = enabledFeatures.RequireEnabled(experimental.CoreFeaturesRelaxedSIMD); err != nil {
+				return fmt.Errorf("%s invalid as %v", name, err)
+			}
+
+			var params int
+			switch relaxedOpcode {
+			case OpcodeVecI32x4RelaxedTruncF32x4S, OpcodeVecI32x4RelaxedTruncF32x4U,
+				OpcodeVecI32x4RelaxedTruncF64x2SZero, OpcodeVecI32x4RelaxedTruncF64x2UZero:
+				params = 1
+			case OpcodeVecI8x16RelaxedSwizzle, OpcodeVecF32x4RelaxedMin, OpcodeVecF32x4RelaxedMax,
+				OpcodeVecF64x2RelaxedMin, OpcodeVecF64x2RelaxedMax, OpcodeVecI16x8RelaxedQ15mulrS,
+				OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+				params = 2
+			case OpcodeVecF32x4RelaxedMadd, OpcodeVecF32x4RelaxedNmadd, OpcodeVecF64x2RelaxedMadd,
+				OpcodeVecF64x2RelaxedNmadd, OpcodeVecI8x16RelaxedLaneselect, OpcodeVecI16x8RelaxedLaneselect,
+				OpcodeVecI32x4RelaxedLaneselect, OpcodeVecI64x2RelaxedLaneselect, OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+				params = 3
+			default:
+				return fmt.Errorf("invalid vector opcode: %#x", relaxedOpcode)
 			}
+			for i := 0; i < params; i++ {
+				if err := valueTypeStack.popAndVerifyType(ValueTypeV128); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", name, err)
+				}
+			}
+			valueTypeStack.push(ValueTypeV128)
 		} else if op == OpcodeVecPrefix {
 			pc++
 			// Vector instructions come with two bytes where the first byte is always OpcodeVecPrefix,
@@ -1548,6 +1610,70 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			} else {
 				valueTypeStack.push(v1)
 			}
+		} else if op == OpcodeAtomicPrefix {
+			pc++
+			// Atomic instructions come with two bytes where the first byte is always OpcodeAtomicPrefix,
+			// and the second byte determines the actual instruction.
+			atomicOpcode := body[pc]
+			if err := enabledFeatures.RequireEnabled(experimental.CoreFeaturesThreads); err != nil {
+				return fmt.Errorf("%s invalid as %v", atomicInstructionNames[atomicOpcode], err)
+			}
+			if atomicOpcode == OpcodeAtomicFence {
+				pc++
+				if pc >= uint64(len(body)) || body[pc] != 0x00 {
+					return fmt.Errorf("fence instruction reserved byte not zero")
+				}
+				continue
+			}
+
+			var params []ValueType
+			var result ValueType
+			var size uint32
+			switch {
+			case atomicOpcode == OpcodeAtomicMemoryNotify:
+				params, result, size = []ValueType{ValueTypeI32, ValueTypeI32}, ValueTypeI32, 4
+			case atomicOpcode == OpcodeAtomicMemoryWait32:
+				params, result, size = []ValueType{ValueTypeI32, ValueTypeI32, ValueTypeI64}, ValueTypeI32, 4
+			case atomicOpcode == OpcodeAtomicMemoryWait64:
+				params, result, size = []ValueType{ValueTypeI32, ValueTypeI64, ValueTypeI64}, ValueTypeI32, 8
+			case OpcodeAtomicI32Load <= atomicOpcode && atomicOpcode <= OpcodeAtomicI64Load32U:
+				t, sz := AtomicAccessTypeAndSize(atomicOpcode - OpcodeAtomicI32Load)
+				params, result, size = []ValueType{ValueTypeI32}, t, sz
+			case OpcodeAtomicI32Store <= atomicOpcode && atomicOpcode <= OpcodeAtomicI64Store32:
+				t, sz := AtomicAccessTypeAndSize(atomicOpcode - OpcodeAtomicI32Store)
+				params, size = []ValueType{ValueTypeI32, t}, sz
+			case OpcodeAtomicI32RmwAdd <= atomicOpcode && atomicOpcode <= OpcodeAtomicI64Rmw32XchgU:
+				t, sz := AtomicAccessTypeAndSize((atomicOpcode - OpcodeAtomicI32RmwAdd) % 7)
+				params, result, size = []ValueType{ValueTypeI32, t}, t, sz
+			case OpcodeAtomicI32RmwCmpxchg <= atomicOpcode && atomicOpcode <= OpcodeAtomicI64Rmw32CmpxchgU:
+				t, sz := AtomicAccessTypeAndSize(atomicOpcode - OpcodeAtomicI32RmwCmpxchg)
+				params, result, size = []ValueType{ValueTypeI32, t, t}, t, sz
+			default:
+				return fmt.Errorf("invalid atomic opcode: 0x%x", atomicOpcode)
+			}
+
+			if memory == nil {
+				return fmt.Errorf("memory must exist for %s", atomicInstructionNames[atomicOpcode])
+			}
+			pc++
+			align, _, read, err := readMemArg(pc, body)
+			if err != nil {
+				return err
+			}
+			pc += read - 1
+			// Unlike other memory instructions, the alignment must be the natural one.
+			if 1<<align != size {
+				return fmt.Errorf("invalid memory alignment")
+			}
+
+			for i := len(params) - 1; i >= 0; i-- {
+				if err := valueTypeStack.popAndVerifyType(params[i]); err != nil {
+					return fmt.Errorf("cannot pop the operand for %s: %v", atomicInstructionNames[atomicOpcode], err)
+				}
+			}
+			if result != 0 {
+				valueTypeStack.push(result)
+			}
 		} else if op == OpcodeUnreachable {
 			// unreachable instruction is stack-polymorphic.
 			valueTypeStack.unreachable()
@@ -1556,6 +1682,7 @@ func (m *Module) validateFunctionWithMaxStackValues(
 			return fmt.Errorf("invalid instruction 0x%x", op)
 		}
 	}
+	instStart = -1
 
 	if len(controlBlockStack.stack) > 0 {
 		return fmt.Errorf("ill-nested block exists")
@@ -1566,6 +1693,28 @@ func (m *Module) validateFunctionWithMaxStackValues(
 	return nil
 }
 
+// AtomicAccessTypeAndSize returns the value type and size in bytes of an
+// atomic load, store or read-modify-write, given its index in the group of
+// seven such opcodes, e.g. OpcodeAtomicI64Load8U - OpcodeAtomicI32Load.
+func AtomicAccessTypeAndSize(index OpcodeAtomic) (ValueType, uint32) {
+	switch index {
+	case 0: // i32
+		return ValueTypeI32, 4
+	case 1: // i64
+		return ValueTypeI64, 8
+	case 2: // i32 8
+		return ValueTypeI32, 1
+	case 3: // i32 16
+		return ValueTypeI32, 2
+	case 4: // i64 8
+		return ValueTypeI64, 1
+	case 5: // i64 16
+		return ValueTypeI64, 2
+	default: // i64 32
+		return ValueTypeI64, 4
+	}
+}
+
 var vecExtractLanes = [...]struct {
 	laneCeil   byte
 	resultType ValueType
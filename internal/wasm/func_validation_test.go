@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/leb128"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
@@ -112,6 +113,231 @@ func TestModule_ValidateFunction_SignExtensionOps(t *testing.T) {
 	}
 }
 
+func TestModule_ValidateFunction_Atomics(t *testing.T) {
+	mem := &Memory{Min: 1, Max: 1, IsShared: true}
+	tests := []struct {
+		name        string
+		body        []byte
+		memory      *Memory
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     "i32.atomic.rmw.add",
+			body:     []byte{OpcodeI32Const, 0, OpcodeI32Const, 1, OpcodeAtomicPrefix, OpcodeAtomicI32RmwAdd, 0x2, 0x0, OpcodeDrop, OpcodeEnd},
+			memory:   mem,
+			features: experimental.CoreFeaturesThreads,
+		},
+		{
+			name:     "i64.atomic.rmw8.cmpxchg_u",
+			body:     []byte{OpcodeI32Const, 0, OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeAtomicPrefix, OpcodeAtomicI64Rmw8CmpxchgU, 0x0, 0x0, OpcodeDrop, OpcodeEnd},
+			memory:   mem,
+			features: experimental.CoreFeaturesThreads,
+		},
+		{
+			name:     "memory.atomic.wait64",
+			body:     []byte{OpcodeI32Const, 0, OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeAtomicPrefix, OpcodeAtomicMemoryWait64, 0x3, 0x0, OpcodeDrop, OpcodeEnd},
+			memory:   mem,
+			features: experimental.CoreFeaturesThreads,
+		},
+		{
+			name:     "atomic.fence",
+			body:     []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0x0, OpcodeEnd},
+			features: experimental.CoreFeaturesThreads,
+		},
+		{
+			name:        "disabled",
+			body:        []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0x0, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "atomic.fence invalid as feature \"threads\" is disabled",
+		},
+		{
+			name:        "fence reserved byte",
+			body:        []byte{OpcodeAtomicPrefix, OpcodeAtomicFence, 0x1, OpcodeEnd},
+			features:    experimental.CoreFeaturesThreads,
+			expectedErr: "fence instruction reserved byte not zero",
+		},
+		{
+			name:        "no memory",
+			body:        []byte{OpcodeI32Const, 0, OpcodeAtomicPrefix, OpcodeAtomicI32Load, 0x2, 0x0, OpcodeDrop, OpcodeEnd},
+			features:    experimental.CoreFeaturesThreads,
+			expectedErr: "memory must exist for i32.atomic.load",
+		},
+		{
+			name:        "unnatural alignment",
+			body:        []byte{OpcodeI32Const, 0, OpcodeAtomicPrefix, OpcodeAtomicI32Load16U, 0x0, 0x0, OpcodeDrop, OpcodeEnd},
+			memory:      mem,
+			features:    experimental.CoreFeaturesThreads,
+			expectedErr: "invalid memory alignment",
+		},
+		{
+			name:        "type mismatch",
+			body:        []byte{OpcodeI32Const, 0, OpcodeI32Const, 0, OpcodeAtomicPrefix, OpcodeAtomicI64Store, 0x3, 0x0, OpcodeEnd},
+			memory:      mem,
+			features:    experimental.CoreFeaturesThreads,
+			expectedErr: "cannot pop the operand for i64.atomic.store: type mismatch: expected i64, but was i32",
+		},
+		{
+			name:        "invalid opcode",
+			body:        []byte{OpcodeAtomicPrefix, 0x4f, 0x0, 0x0, OpcodeEnd},
+			memory:      mem,
+			features:    experimental.CoreFeaturesThreads,
+			expectedErr: "invalid atomic opcode: 0x4f",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, tc.memory, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_ValidateFunction_WideArithmetic(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name: "i64.add128",
+			body: []byte{
+				OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeI64Const, 3, OpcodeI64Const, 4,
+				OpcodeMiscPrefix, OpcodeMiscI64Add128, OpcodeDrop, OpcodeDrop, OpcodeEnd,
+			},
+			features: experimental.CoreFeaturesWideArithmetic,
+		},
+		{
+			name:     "i64.mul_wide_s",
+			body:     []byte{OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeMiscPrefix, OpcodeMiscI64MulWideS, OpcodeDrop, OpcodeDrop, OpcodeEnd},
+			features: experimental.CoreFeaturesWideArithmetic,
+		},
+		{
+			name:        "disabled",
+			body:        []byte{OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeMiscPrefix, OpcodeMiscI64MulWideU, OpcodeDrop, OpcodeDrop, OpcodeEnd},
+			features:    api.CoreFeaturesV2,
+			expectedErr: "i64.mul_wide_u invalid as feature \"wide-arithmetic\" is disabled",
+		},
+		{
+			name:        "missing operand",
+			body:        []byte{OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeMiscPrefix, OpcodeMiscI64Sub128, OpcodeDrop, OpcodeDrop, OpcodeEnd},
+			features:    experimental.CoreFeaturesWideArithmetic,
+			expectedErr: "cannot pop the operand for i64.sub128: i64 missing",
+		},
+		{
+			name:        "invalid opcode",
+			body:        []byte{OpcodeMiscPrefix, 0x17, OpcodeEnd},
+			features:    experimental.CoreFeaturesWideArithmetic,
+			expectedErr: "invalid misc opcode: 0x17",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModule_ValidateFunction_RelaxedSIMD(t *testing.T) {
+	v128Const := []byte{OpcodeVecPrefix, OpcodeVecV128Const, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	body := func(operands int, op OpcodeVecRelaxed) (ret []byte) {
+		for i := 0; i < operands; i++ {
+			ret = append(ret, v128Const...)
+		}
+		return append(ret, OpcodeVecPrefix, byte(op)|0x80, 0x02, OpcodeDrop, OpcodeEnd)
+	}
+	tests := []struct {
+		name        string
+		body        []byte
+		features    api.CoreFeatures
+		expectedErr string
+	}{
+		{
+			name:     OpcodeVecF32x4RelaxedMaddName,
+			body:     body(3, OpcodeVecF32x4RelaxedMadd),
+			features: api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD,
+		},
+		{
+			name:     OpcodeVecI32x4RelaxedTruncF32x4SName,
+			body:     body(1, OpcodeVecI32x4RelaxedTruncF32x4S),
+			features: api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD,
+		},
+		{
+			name:     OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+			body:     body(2, OpcodeVecI16x8RelaxedDotI8x16I7x16S),
+			features: api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD,
+		},
+		{
+			name:        "disabled",
+			body:        body(2, OpcodeVecF32x4RelaxedMin),
+			features:    api.CoreFeaturesV2,
+			expectedErr: "f32x4.relaxed_min invalid as feature \"relaxed-simd\" is disabled",
+		},
+		{
+			name:        "simd disabled",
+			body:        body(0, OpcodeVecF32x4RelaxedMin),
+			features:    api.CoreFeaturesV1 | experimental.CoreFeaturesRelaxedSIMD,
+			expectedErr: "f32x4.relaxed_min invalid as feature \"simd\" is disabled",
+		},
+		{
+			name:        "missing operand",
+			body:        body(2, OpcodeVecI32x4RelaxedDotI8x16I7x16AddS),
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD,
+			expectedErr: "cannot pop the operand for i32x4.relaxed_dot_i8x16_i7x16_add_s: v128 missing",
+		},
+		{
+			name:        "invalid opcode",
+			body:        body(0, 0x114),
+			features:    api.CoreFeaturesV2 | experimental.CoreFeaturesRelaxedSIMD,
+			expectedErr: "invalid vector opcode: 0x114",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Module{
+				TypeSection:     []FunctionType{v_v},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{{Body: tc.body}},
+			}
+			err := m.validateFunction(&stacks{}, tc.features,
+				0, []Index{0}, nil, nil, nil, nil, bytes.NewReader(nil))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestModule_ValidateFunction_NonTrappingFloatToIntConversion(t *testing.T) {
 	tests := []struct {
 		input                Opcode
@@ -12,6 +12,23 @@ type HostFuncExporter interface {
 	ExportHostFunc(*HostFunc)
 }
 
+// NativeFunction is implemented by a Code.GoFunc which is machine code that
+// engines can call directly, without the overhead of calling Go.
+//
+// The machine code is called with the address of the same stack as passed to
+// api.GoFunction in AX on amd64 and R0 on arm64. It must preserve the
+// callee-saved registers BX, BP and R12-R15 on amd64, and R19-R29 on arm64.
+// It returns with RET, and it must be a leaf which needs little stack, as it
+// runs on the goroutine's stack without a stack check.
+//
+// See experimental/native
+type NativeFunction interface {
+	api.GoFunction
+
+	// NativeFunctionAddress returns the address of the machine code.
+	NativeFunctionAddress() uintptr
+}
+
 // HostFunc is a function with an inlined type, used for NewHostModule.
 // Any corresponding FunctionType will be reused or added to the Module.
 type HostFunc struct {
@@ -35,6 +52,10 @@ type HostFunc struct {
 
 	// Code is the equivalent function in the SectionIDCode.
 	Code Code
+
+	// Capability is a token the calling module must be granted, or empty if
+	// none. See RequireCapability.
+	Capability string
 }
 
 // WithGoModuleFunc returns a copy of the function, replacing its Code.GoFunc.
@@ -133,7 +154,11 @@ func addFuncs(
 			return fmt.Errorf("func[%s] %v", debugName, typeErr)
 		}
 		m.FunctionSection = append(m.FunctionSection, typeIdx)
-		m.CodeSection = append(m.CodeSection, hf.Code)
+		code := hf.Code
+		if hf.Capability != "" {
+			code.GoFunc = RequireCapability(debugName, hf.Capability, code.GoFunc)
+		}
+		m.CodeSection = append(m.CodeSection, code)
 
 		export := hf.ExportName
 		m.ExportSection = append(m.ExportSection, Export{Type: ExternTypeFunc, Name: export, Index: idx})
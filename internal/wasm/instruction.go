@@ -280,6 +280,10 @@ const (
 	// OpcodeVecPrefix is the prefix of all vector isntructions introduced in
 	// CoreFeatureSIMD.
 	OpcodeVecPrefix Opcode = 0xfd
+
+	// OpcodeAtomicPrefix is the prefix of all atomic instructions introduced in
+	// experimental.CoreFeaturesThreads.
+	OpcodeAtomicPrefix Opcode = 0xfe
 )
 
 // OpcodeMisc represents opcodes of the miscellaneous operations.
@@ -317,6 +321,14 @@ const (
 	OpcodeMiscTableGrow OpcodeMisc = 0x0f
 	OpcodeMiscTableSize OpcodeMisc = 0x10
 	OpcodeMiscTableFill OpcodeMisc = 0x11
+
+	// Below are toggled with experimental.CoreFeaturesWideArithmetic
+	// https://github.com/WebAssembly/wide-arithmetic/blob/main/proposals/wide-arithmetic/Overview.md
+
+	OpcodeMiscI64Add128   OpcodeMisc = 0x13
+	OpcodeMiscI64Sub128   OpcodeMisc = 0x14
+	OpcodeMiscI64MulWideS OpcodeMisc = 0x15
+	OpcodeMiscI64MulWideU OpcodeMisc = 0x16
 )
 
 // OpcodeVec represents an opcode of a vector instructions which has
@@ -812,8 +824,9 @@ const (
 	OpcodeI64Extend16SName = "i64.extend16_s"
 	OpcodeI64Extend32SName = "i64.extend32_s"
 
-	OpcodeMiscPrefixName = "misc_prefix"
-	OpcodeVecPrefixName  = "vector_prefix"
+	OpcodeMiscPrefixName   = "misc_prefix"
+	OpcodeVecPrefixName    = "vector_prefix"
+	OpcodeAtomicPrefixName = "atomic_prefix"
 )
 
 var instructionNames = [256]string{
@@ -1006,8 +1019,9 @@ var instructionNames = [256]string{
 	OpcodeI64Extend16S: OpcodeI64Extend16SName,
 	OpcodeI64Extend32S: OpcodeI64Extend32SName,
 
-	OpcodeMiscPrefix: OpcodeMiscPrefixName,
-	OpcodeVecPrefix:  OpcodeVecPrefixName,
+	OpcodeMiscPrefix:   OpcodeMiscPrefixName,
+	OpcodeVecPrefix:    OpcodeVecPrefixName,
+	OpcodeAtomicPrefix: OpcodeAtomicPrefixName,
 }
 
 // InstructionName returns the instruction corresponding to this binary Opcode.
@@ -1036,6 +1050,11 @@ const (
 	OpcodeTableGrowName  = "table.grow"
 	OpcodeTableSizeName  = "table.size"
 	OpcodeTableFillName  = "table.fill"
+
+	OpcodeI64Add128Name   = "i64.add128"
+	OpcodeI64Sub128Name   = "i64.sub128"
+	OpcodeI64MulWideSName = "i64.mul_wide_s"
+	OpcodeI64MulWideUName = "i64.mul_wide_u"
 )
 
 var miscInstructionNames = [256]string{
@@ -1058,6 +1077,11 @@ var miscInstructionNames = [256]string{
 	OpcodeMiscTableGrow:  OpcodeTableGrowName,
 	OpcodeMiscTableSize:  OpcodeTableSizeName,
 	OpcodeMiscTableFill:  OpcodeTableFillName,
+
+	OpcodeMiscI64Add128:   OpcodeI64Add128Name,
+	OpcodeMiscI64Sub128:   OpcodeI64Sub128Name,
+	OpcodeMiscI64MulWideS: OpcodeI64MulWideSName,
+	OpcodeMiscI64MulWideU: OpcodeI64MulWideUName,
 }
 
 // MiscInstructionName returns the instruction corresponding to this miscellaneous Opcode.
@@ -1547,3 +1571,320 @@ var vectorInstructionName = map[OpcodeVec]string{
 func VectorInstructionName(oc OpcodeVec) (ret string) {
 	return vectorInstructionName[oc]
 }
+
+// OpcodeVecRelaxed represents an opcode of a relaxed vector instruction,
+// which is prefixed by OpcodeVecPrefix like OpcodeVec. Unlike OpcodeVec, it
+// is larger than a byte, so its LEB128 encoding is two bytes, the second of
+// which is always 0x02. See LoadVecRelaxedOpcode.
+//
+// These opcodes are toggled with experimental.CoreFeaturesRelaxedSIMD.
+// https://github.com/WebAssembly/relaxed-simd/blob/main/proposals/relaxed-simd/Overview.md
+type OpcodeVecRelaxed = uint32
+
+const (
+	OpcodeVecI8x16RelaxedSwizzle           OpcodeVecRelaxed = 0x100
+	OpcodeVecI32x4RelaxedTruncF32x4S       OpcodeVecRelaxed = 0x101
+	OpcodeVecI32x4RelaxedTruncF32x4U       OpcodeVecRelaxed = 0x102
+	OpcodeVecI32x4RelaxedTruncF64x2SZero   OpcodeVecRelaxed = 0x103
+	OpcodeVecI32x4RelaxedTruncF64x2UZero   OpcodeVecRelaxed = 0x104
+	OpcodeVecF32x4RelaxedMadd              OpcodeVecRelaxed = 0x105
+	OpcodeVecF32x4RelaxedNmadd             OpcodeVecRelaxed = 0x106
+	OpcodeVecF64x2RelaxedMadd              OpcodeVecRelaxed = 0x107
+	OpcodeVecF64x2RelaxedNmadd             OpcodeVecRelaxed = 0x108
+	OpcodeVecI8x16RelaxedLaneselect        OpcodeVecRelaxed = 0x109
+	OpcodeVecI16x8RelaxedLaneselect        OpcodeVecRelaxed = 0x10a
+	OpcodeVecI32x4RelaxedLaneselect        OpcodeVecRelaxed = 0x10b
+	OpcodeVecI64x2RelaxedLaneselect        OpcodeVecRelaxed = 0x10c
+	OpcodeVecF32x4RelaxedMin               OpcodeVecRelaxed = 0x10d
+	OpcodeVecF32x4RelaxedMax               OpcodeVecRelaxed = 0x10e
+	OpcodeVecF64x2RelaxedMin               OpcodeVecRelaxed = 0x10f
+	OpcodeVecF64x2RelaxedMax               OpcodeVecRelaxed = 0x110
+	OpcodeVecI16x8RelaxedQ15mulrS          OpcodeVecRelaxed = 0x111
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S    OpcodeVecRelaxed = 0x112
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS OpcodeVecRelaxed = 0x113
+)
+
+const (
+	OpcodeVecI8x16RelaxedSwizzleName           = "i8x16.relaxed_swizzle"
+	OpcodeVecI32x4RelaxedTruncF32x4SName       = "i32x4.relaxed_trunc_f32x4_s"
+	OpcodeVecI32x4RelaxedTruncF32x4UName       = "i32x4.relaxed_trunc_f32x4_u"
+	OpcodeVecI32x4RelaxedTruncF64x2SZeroName   = "i32x4.relaxed_trunc_f64x2_s_zero"
+	OpcodeVecI32x4RelaxedTruncF64x2UZeroName   = "i32x4.relaxed_trunc_f64x2_u_zero"
+	OpcodeVecF32x4RelaxedMaddName              = "f32x4.relaxed_madd"
+	OpcodeVecF32x4RelaxedNmaddName             = "f32x4.relaxed_nmadd"
+	OpcodeVecF64x2RelaxedMaddName              = "f64x2.relaxed_madd"
+	OpcodeVecF64x2RelaxedNmaddName             = "f64x2.relaxed_nmadd"
+	OpcodeVecI8x16RelaxedLaneselectName        = "i8x16.relaxed_laneselect"
+	OpcodeVecI16x8RelaxedLaneselectName        = "i16x8.relaxed_laneselect"
+	OpcodeVecI32x4RelaxedLaneselectName        = "i32x4.relaxed_laneselect"
+	OpcodeVecI64x2RelaxedLaneselectName        = "i64x2.relaxed_laneselect"
+	OpcodeVecF32x4RelaxedMinName               = "f32x4.relaxed_min"
+	OpcodeVecF32x4RelaxedMaxName               = "f32x4.relaxed_max"
+	OpcodeVecF64x2RelaxedMinName               = "f64x2.relaxed_min"
+	OpcodeVecF64x2RelaxedMaxName               = "f64x2.relaxed_max"
+	OpcodeVecI16x8RelaxedQ15mulrSName          = "i16x8.relaxed_q15mulr_s"
+	OpcodeVecI16x8RelaxedDotI8x16I7x16SName    = "i16x8.relaxed_dot_i8x16_i7x16_s"
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName = "i32x4.relaxed_dot_i8x16_i7x16_add_s"
+)
+
+var vecRelaxedInstructionName = map[OpcodeVecRelaxed]string{
+	OpcodeVecI8x16RelaxedSwizzle:           OpcodeVecI8x16RelaxedSwizzleName,
+	OpcodeVecI32x4RelaxedTruncF32x4S:       OpcodeVecI32x4RelaxedTruncF32x4SName,
+	OpcodeVecI32x4RelaxedTruncF32x4U:       OpcodeVecI32x4RelaxedTruncF32x4UName,
+	OpcodeVecI32x4RelaxedTruncF64x2SZero:   OpcodeVecI32x4RelaxedTruncF64x2SZeroName,
+	OpcodeVecI32x4RelaxedTruncF64x2UZero:   OpcodeVecI32x4RelaxedTruncF64x2UZeroName,
+	OpcodeVecF32x4RelaxedMadd:              OpcodeVecF32x4RelaxedMaddName,
+	OpcodeVecF32x4RelaxedNmadd:             OpcodeVecF32x4RelaxedNmaddName,
+	OpcodeVecF64x2RelaxedMadd:              OpcodeVecF64x2RelaxedMaddName,
+	OpcodeVecF64x2RelaxedNmadd:             OpcodeVecF64x2RelaxedNmaddName,
+	OpcodeVecI8x16RelaxedLaneselect:        OpcodeVecI8x16RelaxedLaneselectName,
+	OpcodeVecI16x8RelaxedLaneselect:        OpcodeVecI16x8RelaxedLaneselectName,
+	OpcodeVecI32x4RelaxedLaneselect:        OpcodeVecI32x4RelaxedLaneselectName,
+	OpcodeVecI64x2RelaxedLaneselect:        OpcodeVecI64x2RelaxedLaneselectName,
+	OpcodeVecF32x4RelaxedMin:               OpcodeVecF32x4RelaxedMinName,
+	OpcodeVecF32x4RelaxedMax:               OpcodeVecF32x4RelaxedMaxName,
+	OpcodeVecF64x2RelaxedMin:               OpcodeVecF64x2RelaxedMinName,
+	OpcodeVecF64x2RelaxedMax:               OpcodeVecF64x2RelaxedMaxName,
+	OpcodeVecI16x8RelaxedQ15mulrS:          OpcodeVecI16x8RelaxedQ15mulrSName,
+	OpcodeVecI16x8RelaxedDotI8x16I7x16S:    OpcodeVecI16x8RelaxedDotI8x16I7x16SName,
+	OpcodeVecI32x4RelaxedDotI8x16I7x16AddS: OpcodeVecI32x4RelaxedDotI8x16I7x16AddSName,
+}
+
+// VecRelaxedInstructionName returns the instruction name corresponding to
+// the relaxed vector Opcode.
+func VecRelaxedInstructionName(oc OpcodeVecRelaxed) (ret string) {
+	return vecRelaxedInstructionName[oc]
+}
+
+// LoadVecRelaxedOpcode returns the relaxed vector opcode at the beginning of
+// `b`, which follows OpcodeVecPrefix, or false if `b` begins with an
+// OpcodeVec instead.
+func LoadVecRelaxedOpcode(b []byte) (OpcodeVecRelaxed, bool) {
+	if len(b) < 2 || b[0] < 0x80 || b[1] != 0x02 {
+		return 0, false
+	}
+	return 0x100 | OpcodeVecRelaxed(b[0]&0x7f), true
+}
+
+// OpcodeAtomic represents an opcode of an atomic instruction which has
+// multi-byte encoding and is prefixed by OpcodeAtomicPrefix.
+//
+// These opcodes are toggled with experimental.CoreFeaturesThreads.
+type OpcodeAtomic = byte
+
+const (
+	// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+
+	OpcodeAtomicMemoryNotify     OpcodeAtomic = 0x00
+	OpcodeAtomicMemoryWait32     OpcodeAtomic = 0x01
+	OpcodeAtomicMemoryWait64     OpcodeAtomic = 0x02
+	OpcodeAtomicFence            OpcodeAtomic = 0x03
+	OpcodeAtomicI32Load          OpcodeAtomic = 0x10
+	OpcodeAtomicI64Load          OpcodeAtomic = 0x11
+	OpcodeAtomicI32Load8U        OpcodeAtomic = 0x12
+	OpcodeAtomicI32Load16U       OpcodeAtomic = 0x13
+	OpcodeAtomicI64Load8U        OpcodeAtomic = 0x14
+	OpcodeAtomicI64Load16U       OpcodeAtomic = 0x15
+	OpcodeAtomicI64Load32U       OpcodeAtomic = 0x16
+	OpcodeAtomicI32Store         OpcodeAtomic = 0x17
+	OpcodeAtomicI64Store         OpcodeAtomic = 0x18
+	OpcodeAtomicI32Store8        OpcodeAtomic = 0x19
+	OpcodeAtomicI32Store16       OpcodeAtomic = 0x1a
+	OpcodeAtomicI64Store8        OpcodeAtomic = 0x1b
+	OpcodeAtomicI64Store16       OpcodeAtomic = 0x1c
+	OpcodeAtomicI64Store32       OpcodeAtomic = 0x1d
+	OpcodeAtomicI32RmwAdd        OpcodeAtomic = 0x1e
+	OpcodeAtomicI64RmwAdd        OpcodeAtomic = 0x1f
+	OpcodeAtomicI32Rmw8AddU      OpcodeAtomic = 0x20
+	OpcodeAtomicI32Rmw16AddU     OpcodeAtomic = 0x21
+	OpcodeAtomicI64Rmw8AddU      OpcodeAtomic = 0x22
+	OpcodeAtomicI64Rmw16AddU     OpcodeAtomic = 0x23
+	OpcodeAtomicI64Rmw32AddU     OpcodeAtomic = 0x24
+	OpcodeAtomicI32RmwSub        OpcodeAtomic = 0x25
+	OpcodeAtomicI64RmwSub        OpcodeAtomic = 0x26
+	OpcodeAtomicI32Rmw8SubU      OpcodeAtomic = 0x27
+	OpcodeAtomicI32Rmw16SubU     OpcodeAtomic = 0x28
+	OpcodeAtomicI64Rmw8SubU      OpcodeAtomic = 0x29
+	OpcodeAtomicI64Rmw16SubU     OpcodeAtomic = 0x2a
+	OpcodeAtomicI64Rmw32SubU     OpcodeAtomic = 0x2b
+	OpcodeAtomicI32RmwAnd        OpcodeAtomic = 0x2c
+	OpcodeAtomicI64RmwAnd        OpcodeAtomic = 0x2d
+	OpcodeAtomicI32Rmw8AndU      OpcodeAtomic = 0x2e
+	OpcodeAtomicI32Rmw16AndU     OpcodeAtomic = 0x2f
+	OpcodeAtomicI64Rmw8AndU      OpcodeAtomic = 0x30
+	OpcodeAtomicI64Rmw16AndU     OpcodeAtomic = 0x31
+	OpcodeAtomicI64Rmw32AndU     OpcodeAtomic = 0x32
+	OpcodeAtomicI32RmwOr         OpcodeAtomic = 0x33
+	OpcodeAtomicI64RmwOr         OpcodeAtomic = 0x34
+	OpcodeAtomicI32Rmw8OrU       OpcodeAtomic = 0x35
+	OpcodeAtomicI32Rmw16OrU      OpcodeAtomic = 0x36
+	OpcodeAtomicI64Rmw8OrU       OpcodeAtomic = 0x37
+	OpcodeAtomicI64Rmw16OrU      OpcodeAtomic = 0x38
+	OpcodeAtomicI64Rmw32OrU      OpcodeAtomic = 0x39
+	OpcodeAtomicI32RmwXor        OpcodeAtomic = 0x3a
+	OpcodeAtomicI64RmwXor        OpcodeAtomic = 0x3b
+	OpcodeAtomicI32Rmw8XorU      OpcodeAtomic = 0x3c
+	OpcodeAtomicI32Rmw16XorU     OpcodeAtomic = 0x3d
+	OpcodeAtomicI64Rmw8XorU      OpcodeAtomic = 0x3e
+	OpcodeAtomicI64Rmw16XorU     OpcodeAtomic = 0x3f
+	OpcodeAtomicI64Rmw32XorU     OpcodeAtomic = 0x40
+	OpcodeAtomicI32RmwXchg       OpcodeAtomic = 0x41
+	OpcodeAtomicI64RmwXchg       OpcodeAtomic = 0x42
+	OpcodeAtomicI32Rmw8XchgU     OpcodeAtomic = 0x43
+	OpcodeAtomicI32Rmw16XchgU    OpcodeAtomic = 0x44
+	OpcodeAtomicI64Rmw8XchgU     OpcodeAtomic = 0x45
+	OpcodeAtomicI64Rmw16XchgU    OpcodeAtomic = 0x46
+	OpcodeAtomicI64Rmw32XchgU    OpcodeAtomic = 0x47
+	OpcodeAtomicI32RmwCmpxchg    OpcodeAtomic = 0x48
+	OpcodeAtomicI64RmwCmpxchg    OpcodeAtomic = 0x49
+	OpcodeAtomicI32Rmw8CmpxchgU  OpcodeAtomic = 0x4a
+	OpcodeAtomicI32Rmw16CmpxchgU OpcodeAtomic = 0x4b
+	OpcodeAtomicI64Rmw8CmpxchgU  OpcodeAtomic = 0x4c
+	OpcodeAtomicI64Rmw16CmpxchgU OpcodeAtomic = 0x4d
+	OpcodeAtomicI64Rmw32CmpxchgU OpcodeAtomic = 0x4e
+)
+
+const (
+	OpcodeAtomicMemoryNotifyName     = "memory.atomic.notify"
+	OpcodeAtomicMemoryWait32Name     = "memory.atomic.wait32"
+	OpcodeAtomicMemoryWait64Name     = "memory.atomic.wait64"
+	OpcodeAtomicFenceName            = "atomic.fence"
+	OpcodeAtomicI32LoadName          = "i32.atomic.load"
+	OpcodeAtomicI64LoadName          = "i64.atomic.load"
+	OpcodeAtomicI32Load8UName        = "i32.atomic.load8_u"
+	OpcodeAtomicI32Load16UName       = "i32.atomic.load16_u"
+	OpcodeAtomicI64Load8UName        = "i64.atomic.load8_u"
+	OpcodeAtomicI64Load16UName       = "i64.atomic.load16_u"
+	OpcodeAtomicI64Load32UName       = "i64.atomic.load32_u"
+	OpcodeAtomicI32StoreName         = "i32.atomic.store"
+	OpcodeAtomicI64StoreName         = "i64.atomic.store"
+	OpcodeAtomicI32Store8Name        = "i32.atomic.store8"
+	OpcodeAtomicI32Store16Name       = "i32.atomic.store16"
+	OpcodeAtomicI64Store8Name        = "i64.atomic.store8"
+	OpcodeAtomicI64Store16Name       = "i64.atomic.store16"
+	OpcodeAtomicI64Store32Name       = "i64.atomic.store32"
+	OpcodeAtomicI32RmwAddName        = "i32.atomic.rmw.add"
+	OpcodeAtomicI64RmwAddName        = "i64.atomic.rmw.add"
+	OpcodeAtomicI32Rmw8AddUName      = "i32.atomic.rmw8.add_u"
+	OpcodeAtomicI32Rmw16AddUName     = "i32.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw8AddUName      = "i64.atomic.rmw8.add_u"
+	OpcodeAtomicI64Rmw16AddUName     = "i64.atomic.rmw16.add_u"
+	OpcodeAtomicI64Rmw32AddUName     = "i64.atomic.rmw32.add_u"
+	OpcodeAtomicI32RmwSubName        = "i32.atomic.rmw.sub"
+	OpcodeAtomicI64RmwSubName        = "i64.atomic.rmw.sub"
+	OpcodeAtomicI32Rmw8SubUName      = "i32.atomic.rmw8.sub_u"
+	OpcodeAtomicI32Rmw16SubUName     = "i32.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw8SubUName      = "i64.atomic.rmw8.sub_u"
+	OpcodeAtomicI64Rmw16SubUName     = "i64.atomic.rmw16.sub_u"
+	OpcodeAtomicI64Rmw32SubUName     = "i64.atomic.rmw32.sub_u"
+	OpcodeAtomicI32RmwAndName        = "i32.atomic.rmw.and"
+	OpcodeAtomicI64RmwAndName        = "i64.atomic.rmw.and"
+	OpcodeAtomicI32Rmw8AndUName      = "i32.atomic.rmw8.and_u"
+	OpcodeAtomicI32Rmw16AndUName     = "i32.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw8AndUName      = "i64.atomic.rmw8.and_u"
+	OpcodeAtomicI64Rmw16AndUName     = "i64.atomic.rmw16.and_u"
+	OpcodeAtomicI64Rmw32AndUName     = "i64.atomic.rmw32.and_u"
+	OpcodeAtomicI32RmwOrName         = "i32.atomic.rmw.or"
+	OpcodeAtomicI64RmwOrName         = "i64.atomic.rmw.or"
+	OpcodeAtomicI32Rmw8OrUName       = "i32.atomic.rmw8.or_u"
+	OpcodeAtomicI32Rmw16OrUName      = "i32.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw8OrUName       = "i64.atomic.rmw8.or_u"
+	OpcodeAtomicI64Rmw16OrUName      = "i64.atomic.rmw16.or_u"
+	OpcodeAtomicI64Rmw32OrUName      = "i64.atomic.rmw32.or_u"
+	OpcodeAtomicI32RmwXorName        = "i32.atomic.rmw.xor"
+	OpcodeAtomicI64RmwXorName        = "i64.atomic.rmw.xor"
+	OpcodeAtomicI32Rmw8XorUName      = "i32.atomic.rmw8.xor_u"
+	OpcodeAtomicI32Rmw16XorUName     = "i32.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw8XorUName      = "i64.atomic.rmw8.xor_u"
+	OpcodeAtomicI64Rmw16XorUName     = "i64.atomic.rmw16.xor_u"
+	OpcodeAtomicI64Rmw32XorUName     = "i64.atomic.rmw32.xor_u"
+	OpcodeAtomicI32RmwXchgName       = "i32.atomic.rmw.xchg"
+	OpcodeAtomicI64RmwXchgName       = "i64.atomic.rmw.xchg"
+	OpcodeAtomicI32Rmw8XchgUName     = "i32.atomic.rmw8.xchg_u"
+	OpcodeAtomicI32Rmw16XchgUName    = "i32.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw8XchgUName     = "i64.atomic.rmw8.xchg_u"
+	OpcodeAtomicI64Rmw16XchgUName    = "i64.atomic.rmw16.xchg_u"
+	OpcodeAtomicI64Rmw32XchgUName    = "i64.atomic.rmw32.xchg_u"
+	OpcodeAtomicI32RmwCmpxchgName    = "i32.atomic.rmw.cmpxchg"
+	OpcodeAtomicI64RmwCmpxchgName    = "i64.atomic.rmw.cmpxchg"
+	OpcodeAtomicI32Rmw8CmpxchgUName  = "i32.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI32Rmw16CmpxchgUName = "i32.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw8CmpxchgUName  = "i64.atomic.rmw8.cmpxchg_u"
+	OpcodeAtomicI64Rmw16CmpxchgUName = "i64.atomic.rmw16.cmpxchg_u"
+	OpcodeAtomicI64Rmw32CmpxchgUName = "i64.atomic.rmw32.cmpxchg_u"
+)
+
+var atomicInstructionNames = [256]string{
+	OpcodeAtomicMemoryNotify:     OpcodeAtomicMemoryNotifyName,
+	OpcodeAtomicMemoryWait32:     OpcodeAtomicMemoryWait32Name,
+	OpcodeAtomicMemoryWait64:     OpcodeAtomicMemoryWait64Name,
+	OpcodeAtomicFence:            OpcodeAtomicFenceName,
+	OpcodeAtomicI32Load:          OpcodeAtomicI32LoadName,
+	OpcodeAtomicI64Load:          OpcodeAtomicI64LoadName,
+	OpcodeAtomicI32Load8U:        OpcodeAtomicI32Load8UName,
+	OpcodeAtomicI32Load16U:       OpcodeAtomicI32Load16UName,
+	OpcodeAtomicI64Load8U:        OpcodeAtomicI64Load8UName,
+	OpcodeAtomicI64Load16U:       OpcodeAtomicI64Load16UName,
+	OpcodeAtomicI64Load32U:       OpcodeAtomicI64Load32UName,
+	OpcodeAtomicI32Store:         OpcodeAtomicI32StoreName,
+	OpcodeAtomicI64Store:         OpcodeAtomicI64StoreName,
+	OpcodeAtomicI32Store8:        OpcodeAtomicI32Store8Name,
+	OpcodeAtomicI32Store16:       OpcodeAtomicI32Store16Name,
+	OpcodeAtomicI64Store8:        OpcodeAtomicI64Store8Name,
+	OpcodeAtomicI64Store16:       OpcodeAtomicI64Store16Name,
+	OpcodeAtomicI64Store32:       OpcodeAtomicI64Store32Name,
+	OpcodeAtomicI32RmwAdd:        OpcodeAtomicI32RmwAddName,
+	OpcodeAtomicI64RmwAdd:        OpcodeAtomicI64RmwAddName,
+	OpcodeAtomicI32Rmw8AddU:      OpcodeAtomicI32Rmw8AddUName,
+	OpcodeAtomicI32Rmw16AddU:     OpcodeAtomicI32Rmw16AddUName,
+	OpcodeAtomicI64Rmw8AddU:      OpcodeAtomicI64Rmw8AddUName,
+	OpcodeAtomicI64Rmw16AddU:     OpcodeAtomicI64Rmw16AddUName,
+	OpcodeAtomicI64Rmw32AddU:     OpcodeAtomicI64Rmw32AddUName,
+	OpcodeAtomicI32RmwSub:        OpcodeAtomicI32RmwSubName,
+	OpcodeAtomicI64RmwSub:        OpcodeAtomicI64RmwSubName,
+	OpcodeAtomicI32Rmw8SubU:      OpcodeAtomicI32Rmw8SubUName,
+	OpcodeAtomicI32Rmw16SubU:     OpcodeAtomicI32Rmw16SubUName,
+	OpcodeAtomicI64Rmw8SubU:      OpcodeAtomicI64Rmw8SubUName,
+	OpcodeAtomicI64Rmw16SubU:     OpcodeAtomicI64Rmw16SubUName,
+	OpcodeAtomicI64Rmw32SubU:     OpcodeAtomicI64Rmw32SubUName,
+	OpcodeAtomicI32RmwAnd:        OpcodeAtomicI32RmwAndName,
+	OpcodeAtomicI64RmwAnd:        OpcodeAtomicI64RmwAndName,
+	OpcodeAtomicI32Rmw8AndU:      OpcodeAtomicI32Rmw8AndUName,
+	OpcodeAtomicI32Rmw16AndU:     OpcodeAtomicI32Rmw16AndUName,
+	OpcodeAtomicI64Rmw8AndU:      OpcodeAtomicI64Rmw8AndUName,
+	OpcodeAtomicI64Rmw16AndU:     OpcodeAtomicI64Rmw16AndUName,
+	OpcodeAtomicI64Rmw32AndU:     OpcodeAtomicI64Rmw32AndUName,
+	OpcodeAtomicI32RmwOr:         OpcodeAtomicI32RmwOrName,
+	OpcodeAtomicI64RmwOr:         OpcodeAtomicI64RmwOrName,
+	OpcodeAtomicI32Rmw8OrU:       OpcodeAtomicI32Rmw8OrUName,
+	OpcodeAtomicI32Rmw16OrU:      OpcodeAtomicI32Rmw16OrUName,
+	OpcodeAtomicI64Rmw8OrU:       OpcodeAtomicI64Rmw8OrUName,
+	OpcodeAtomicI64Rmw16OrU:      OpcodeAtomicI64Rmw16OrUName,
+	OpcodeAtomicI64Rmw32OrU:      OpcodeAtomicI64Rmw32OrUName,
+	OpcodeAtomicI32RmwXor:        OpcodeAtomicI32RmwXorName,
+	OpcodeAtomicI64RmwXor:        OpcodeAtomicI64RmwXorName,
+	OpcodeAtomicI32Rmw8XorU:      OpcodeAtomicI32Rmw8XorUName,
+	OpcodeAtomicI32Rmw16XorU:     OpcodeAtomicI32Rmw16XorUName,
+	OpcodeAtomicI64Rmw8XorU:      OpcodeAtomicI64Rmw8XorUName,
+	OpcodeAtomicI64Rmw16XorU:     OpcodeAtomicI64Rmw16XorUName,
+	OpcodeAtomicI64Rmw32XorU:     OpcodeAtomicI64Rmw32XorUName,
+	OpcodeAtomicI32RmwXchg:       OpcodeAtomicI32RmwXchgName,
+	OpcodeAtomicI64RmwXchg:       OpcodeAtomicI64RmwXchgName,
+	OpcodeAtomicI32Rmw8XchgU:     OpcodeAtomicI32Rmw8XchgUName,
+	OpcodeAtomicI32Rmw16XchgU:    OpcodeAtomicI32Rmw16XchgUName,
+	OpcodeAtomicI64Rmw8XchgU:     OpcodeAtomicI64Rmw8XchgUName,
+	OpcodeAtomicI64Rmw16XchgU:    OpcodeAtomicI64Rmw16XchgUName,
+	OpcodeAtomicI64Rmw32XchgU:    OpcodeAtomicI64Rmw32XchgUName,
+	OpcodeAtomicI32RmwCmpxchg:    OpcodeAtomicI32RmwCmpxchgName,
+	OpcodeAtomicI64RmwCmpxchg:    OpcodeAtomicI64RmwCmpxchgName,
+	OpcodeAtomicI32Rmw8CmpxchgU:  OpcodeAtomicI32Rmw8CmpxchgUName,
+	OpcodeAtomicI32Rmw16CmpxchgU: OpcodeAtomicI32Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw8CmpxchgU:  OpcodeAtomicI64Rmw8CmpxchgUName,
+	OpcodeAtomicI64Rmw16CmpxchgU: OpcodeAtomicI64Rmw16CmpxchgUName,
+	OpcodeAtomicI64Rmw32CmpxchgU: OpcodeAtomicI64Rmw32CmpxchgUName,
+}
+
+// AtomicInstructionName returns the instruction name corresponding to the atomic Opcode.
+func AtomicInstructionName(oc OpcodeAtomic) (ret string) {
+	return atomicInstructionNames[oc]
+}
@@ -0,0 +1,55 @@
+package wasm
+
+import (
+	"crypto/sha256"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// BodyHash returns the SHA-256 of the local types and the body of the code.
+// This is the hash an experimental.Intrinsic verifies.
+func (c *Code) BodyHash() []byte {
+	h := sha256.New()
+	h.Write(c.LocalTypes)
+	h.Write(c.Body)
+	return h.Sum(nil)
+}
+
+// ApplyIntrinsics replaces the functions defined by the module which match an
+// intrinsic with its Go implementation, returning the count replaced. The
+// Code.Body of those is kept, for engines which can only run it.
+//
+// Note: This must be called after validation, but before AssignModuleID.
+func (m *Module) ApplyIntrinsics(intrinsics []experimental.Intrinsic) (replaced int) {
+	if m.NameSection == nil || len(intrinsics) == 0 {
+		return
+	}
+	byName := make(map[string]*experimental.Intrinsic, len(intrinsics))
+	for i := range intrinsics {
+		byName[intrinsics[i].Name] = &intrinsics[i]
+	}
+	for _, n := range m.NameSection.FunctionNames {
+		in, ok := byName[n.Name]
+		if !ok || n.Index < m.ImportFunctionCount {
+			continue
+		}
+		i := n.Index - m.ImportFunctionCount
+		if i >= Index(len(m.CodeSection)) {
+			continue
+		}
+		code := &m.CodeSection[i]
+		if code.GoFunc != nil {
+			continue
+		}
+		if !m.TypeSection[m.FunctionSection[i]].EqualsSignature(in.ParamTypes, in.ResultTypes) {
+			continue
+		}
+		if in.BodyHash != nil && string(code.BodyHash()) != string(in.BodyHash) {
+			continue
+		}
+		code.GoFunc = in.Func
+		code.Intrinsic = true
+		replaced++
+	}
+	return
+}
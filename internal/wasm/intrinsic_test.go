@@ -0,0 +1,78 @@
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModule_ApplyIntrinsics(t *testing.T) {
+	body := []byte{OpcodeUnreachable, OpcodeEnd}
+	newModule := func() *Module {
+		return &Module{
+			TypeSection:         []FunctionType{{Params: []ValueType{i32}, Results: []ValueType{i32}}},
+			ImportFunctionCount: 1,
+			ImportSection:       []Import{{Type: ExternTypeFunc, Module: "env", Name: "strlen"}},
+			FunctionSection:     []Index{0, 0},
+			CodeSection:         []Code{{Body: body}, {Body: body}},
+			NameSection: &NameSection{FunctionNames: NameMap{
+				{Index: 0, Name: "strlen"}, // imported, so not replaced.
+				{Index: 1, Name: "strlen"},
+				{Index: 2, Name: "other"},
+			}},
+		}
+	}
+	fn := api.GoModuleFunc(func(context.Context, api.Module, []uint64) {})
+	hash := (&Code{Body: body}).BodyHash()
+
+	tests := []struct {
+		name             string
+		intrinsic        experimental.Intrinsic
+		expectedReplaced int
+	}{
+		{
+			name:             "replaced",
+			intrinsic:        experimental.Intrinsic{Name: "strlen", ParamTypes: []ValueType{i32}, ResultTypes: []ValueType{i32}, Func: fn},
+			expectedReplaced: 1,
+		},
+		{
+			name:             "body hash",
+			intrinsic:        experimental.Intrinsic{Name: "strlen", ParamTypes: []ValueType{i32}, ResultTypes: []ValueType{i32}, BodyHash: hash, Func: fn},
+			expectedReplaced: 1,
+		},
+		{
+			name:      "body hash mismatch",
+			intrinsic: experimental.Intrinsic{Name: "strlen", ParamTypes: []ValueType{i32}, ResultTypes: []ValueType{i32}, BodyHash: hash[1:], Func: fn},
+		},
+		{
+			name:      "signature mismatch",
+			intrinsic: experimental.Intrinsic{Name: "strlen", ParamTypes: []ValueType{i64}, ResultTypes: []ValueType{i32}, Func: fn},
+		},
+		{
+			name:      "name mismatch",
+			intrinsic: experimental.Intrinsic{Name: "memcpy", ParamTypes: []ValueType{i32}, ResultTypes: []ValueType{i32}, Func: fn},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			m := newModule()
+			replaced := m.ApplyIntrinsics([]experimental.Intrinsic{tc.intrinsic})
+			require.Equal(t, tc.expectedReplaced, replaced)
+			require.Equal(t, tc.expectedReplaced == 1, m.CodeSection[0].GoFunc != nil)
+			require.Nil(t, m.CodeSection[1].GoFunc)
+			// The body is kept for engines which can't replace the function.
+			require.Equal(t, body, m.CodeSection[0].Body)
+
+			// The module ID differs, as the compiled code does.
+			m.AssignModuleID([]byte{1}, false, false)
+			original := newModule()
+			original.AssignModuleID([]byte{1}, false, false)
+			require.Equal(t, tc.expectedReplaced == 0, m.ID == original.ID)
+		})
+	}
+}
@@ -1,15 +1,18 @@
 package wasm
 
 import (
+	"container/list"
 	"encoding/binary"
 	"fmt"
 	"math"
 	"reflect"
+	"runtime"
 	"sync"
 	"unsafe"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/internalapi"
+	"github.com/tetratelabs/wazero/internal/platform"
 )
 
 const (
@@ -24,6 +27,19 @@ const (
 	MemoryPageSizeInBits = 16
 )
 
+// MemoryLimitPagesWithPageSize returns the maximum number of pages of a
+// memory whose page size is 1<<pageSizeInBits, given `memoryLimitPages` in
+// pages of MemoryPageSize. This is at most math.MaxUint32.
+//
+// See https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md
+func MemoryLimitPagesWithPageSize(memoryLimitPages, pageSizeInBits uint32) uint32 {
+	limit := MemoryPagesToBytesNum(memoryLimitPages) >> pageSizeInBits
+	if limit > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(limit)
+}
+
 // compile-time check to ensure MemoryInstance implements api.Memory
 var _ api.Memory = &MemoryInstance{}
 
@@ -41,17 +57,38 @@ type MemoryInstance struct {
 	mux sync.RWMutex
 	// definition is known at compile time.
 	definition api.MemoryDefinition
+	// growthWatchdog is nil unless experimental.MemoryGrowthWatchdog was set.
+	growthWatchdog *memoryGrowthWatchdog
+	// image is nil unless Buffer maps the memoryImage of the defining module.
+	image *memoryImage
+	// pinned is true when Buffer was allocated by mmap with capacity for Max
+	// pages, so that it never moves.
+	pinned bool
+	// Shared is true if the memory is shared, as defined by the threads
+	// proposal. Its capacity is Max pages, so that Buffer never moves.
+	Shared bool
+	// waiters are the goroutines blocked in AtomicWait per address, guarded
+	// by waitersMux.
+	waiters    map[uint32]*list.List
+	waitersMux sync.Mutex
+	// pageSizeInBits is the log2 of the page size when customPageSize, as
+	// defined by the custom-page-sizes proposal.
+	pageSizeInBits uint32
+	customPageSize bool
 }
 
 // NewMemoryInstance creates a new instance based on the parameters in the SectionIDMemory.
 func NewMemoryInstance(memSec *Memory) *MemoryInstance {
-	min := MemoryPagesToBytesNum(memSec.Min)
-	capacity := MemoryPagesToBytesNum(memSec.Cap)
+	min := memSec.PagesToBytesNum(memSec.Min)
+	capacity := memSec.PagesToBytesNum(memSec.Cap)
 	return &MemoryInstance{
-		Buffer: make([]byte, min, capacity),
-		Min:    memSec.Min,
-		Cap:    memSec.Cap,
-		Max:    memSec.Max,
+		Buffer:         make([]byte, min, capacity),
+		Min:            memSec.Min,
+		Cap:            memSec.Cap,
+		Max:            memSec.Max,
+		Shared:         memSec.IsShared,
+		pageSizeInBits: memSec.PageSizeLog2,
+		customPageSize: memSec.IsPageSizeEncoded,
 	}
 }
 
@@ -184,7 +221,7 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	currentPages := memoryBytesNumToPages(uint64(len(m.Buffer)))
+	currentPages := m.bytesNumToPages(uint64(len(m.Buffer)))
 	if delta == 0 {
 		return currentPages, true
 	}
@@ -194,19 +231,86 @@ func (m *MemoryInstance) Grow(delta uint32) (result uint32, ok bool) {
 	if newPages > m.Max {
 		return 0, false
 	} else if newPages > m.Cap { // grow the memory.
-		m.Buffer = append(m.Buffer, make([]byte, MemoryPagesToBytesNum(delta))...)
+		m.Buffer = append(m.Buffer, make([]byte, m.pagesToBytesNum(delta))...)
 		m.Cap = newPages
 		return currentPages, true
 	} else { // We already have the capacity we need.
 		sp := (*reflect.SliceHeader)(unsafe.Pointer(&m.Buffer))
-		sp.Len = int(MemoryPagesToBytesNum(newPages))
+		sp.Len = int(m.pagesToBytesNum(newPages))
 		return currentPages, true
 	}
 }
 
+// ExceedsGrowthRate returns true if growing by `delta` pages exceeds the
+// thresholds of experimental.MemoryGrowthWatchdog. Engines call this before
+// Grow to implement the `memory.grow` instruction, and trap with
+// wasmruntime.ErrRuntimeMemoryGrowthRateExceeded when true.
+func (m *MemoryInstance) ExceedsGrowthRate(delta uint32) bool {
+	if m.growthWatchdog == nil || delta == 0 {
+		return false
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return !m.growthWatchdog.allow(delta)
+}
+
+// BaseAddress returns the address of the first byte of Buffer, or false if
+// it may move on Grow. See experimental.WithPinnedMemory
+func (m *MemoryInstance) BaseAddress() (uintptr, bool) {
+	if !m.pinned {
+		return 0, false
+	}
+	return uintptr(unsafe.Pointer(&m.Buffer[:1][0])), true
+}
+
+// pin replaces Buffer with memory allocated by mmap, with capacity for Max
+// pages, so that it never moves. This has no effect if mmap is unsupported.
+func (m *MemoryInstance) pin() {
+	size := m.pagesToBytesNum(m.Max)
+	if size > math.MaxInt {
+		return // e.g. 4GiB on a 32-bit platform
+	}
+	mapped, err := platform.MmapMemory(int(size))
+	if err != nil {
+		return
+	}
+	copy(mapped, m.Buffer)
+	m.setMapped(mapped, m.Max)
+	m.pinned = true
+}
+
+// setMapped replaces Buffer with `mapped`, memory allocated by mmap for
+// `capPages` pages.
+func (m *MemoryInstance) setMapped(mapped []byte, capPages uint32) {
+	m.Buffer = mapped[:len(m.Buffer)]
+	m.Cap = capPages
+	// Slices of the buffer can be used by engines and hosts until the memory
+	// is unreachable, so unmap it then, as opposed to on Close.
+	runtime.SetFinalizer(m, func(*MemoryInstance) {
+		_ = platform.MunmapMemory(mapped)
+	})
+}
+
 // PageSize returns the current memory buffer size in pages.
 func (m *MemoryInstance) PageSize() (result uint32) {
-	return memoryBytesNumToPages(uint64(len(m.Buffer)))
+	return m.bytesNumToPages(uint64(len(m.Buffer)))
+}
+
+// PageSizeInBits returns the log2 of the page size in bytes, which is
+// MemoryPageSizeInBits unless the memory has a custom page size.
+func (m *MemoryInstance) PageSizeInBits() uint32 {
+	if m.customPageSize {
+		return m.pageSizeInBits
+	}
+	return MemoryPageSizeInBits
+}
+
+func (m *MemoryInstance) pagesToBytesNum(pages uint32) uint64 {
+	return uint64(pages) << m.PageSizeInBits()
+}
+
+func (m *MemoryInstance) bytesNumToPages(bytesNum uint64) uint32 {
+	return uint32(bytesNum >> m.PageSizeInBits())
 }
 
 // PagesToUnitOfBytes converts the pages to a human-readable form similar to what's specified. e.g. 1 -> "64Ki"
@@ -228,6 +332,17 @@ func PagesToUnitOfBytes(pages uint32) string {
 	return fmt.Sprintf("%d Ti", g/1024)
 }
 
+// bytesToUnitOfBytes is like PagesToUnitOfBytes, but for a number of bytes
+// which may not be a multiple of MemoryPageSize.
+func bytesToUnitOfBytes(bytesNum uint64) string {
+	if bytesNum%uint64(MemoryPageSize) == 0 && bytesNum>>MemoryPageSizeInBits <= math.MaxUint32 {
+		return PagesToUnitOfBytes(uint32(bytesNum >> MemoryPageSizeInBits))
+	} else if bytesNum < 1024 {
+		return fmt.Sprintf("%d B", bytesNum)
+	}
+	return fmt.Sprintf("%d Ki", bytesNum/1024)
+}
+
 // Below are raw functions used to implement the api.Memory API:
 
 // memoryBytesNumToPages converts the given number of bytes into the number of pages.
@@ -0,0 +1,248 @@
+package wasm
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"math/bits"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// Below are the atomic accesses of the threads proposal, which engines use to
+// implement atomic instructions. The accesses are sequentially consistent.
+//
+// The offset of an access must be validated by AtomicOffset, so that it's
+// aligned to its size, which is 1, 2, 4 or 8 bytes. 1 and 2 byte accesses
+// are implemented on the 4 byte word which contains them, as sync/atomic
+// lacks them.
+//
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md
+
+// nativeIsBigEndian is true if the host is big-endian, in which case values
+// accessed atomically are byte swapped, as memory is little-endian.
+var nativeIsBigEndian = func() bool {
+	v := uint16(1)
+	return *(*byte)(unsafe.Pointer(&v)) == 0
+}()
+
+// fence is accessed atomically by AtomicFence.
+var fence uint32
+
+// AtomicOffset returns the effective address of an atomic access of `size`
+// bytes, which is `base` plus the static `offset` of the instruction, or
+// panics to trap if it's unaligned or out of bounds.
+func (m *MemoryInstance) AtomicOffset(base, offset, size uint32) uint32 {
+	ea := uint64(base) + uint64(offset)
+	if ea%uint64(size) != 0 {
+		panic(wasmruntime.ErrRuntimeUnalignedAtomic)
+	} else if ea+uint64(size) > uint64(len(m.Buffer)) {
+		panic(wasmruntime.NewOutOfBoundsMemoryAccessError(ea))
+	}
+	return uint32(ea)
+}
+
+// AtomicLoad returns the value of the `size` bytes at `offset`, zero-extended.
+func (m *MemoryInstance) AtomicLoad(offset, size uint32) uint64 {
+	switch size {
+	case 8:
+		return le64(atomic.LoadUint64(m.uint64Ptr(offset)))
+	case 4:
+		return uint64(le32(atomic.LoadUint32(m.uint32Ptr(offset))))
+	}
+	word := le32(atomic.LoadUint32(m.uint32Ptr(offset &^ 3)))
+	return uint64(word>>subwordShift(offset)) & sizeMask(size)
+}
+
+// AtomicStore stores the low `size` bytes of `v` at `offset`.
+func (m *MemoryInstance) AtomicStore(offset, size uint32, v uint64) {
+	switch size {
+	case 8:
+		atomic.StoreUint64(m.uint64Ptr(offset), le64(v))
+	case 4:
+		atomic.StoreUint32(m.uint32Ptr(offset), le32(uint32(v)))
+	default:
+		m.AtomicRMW(offset, size, func(uint64) uint64 { return v })
+	}
+}
+
+// AtomicRMW replaces the value of the `size` bytes at `offset` with the low
+// `size` bytes of the result of `op`, and returns the value replaced.
+func (m *MemoryInstance) AtomicRMW(offset, size uint32, op func(old uint64) uint64) uint64 {
+	for {
+		old := m.AtomicLoad(offset, size)
+		if m.compareAndSwap(offset, size, old, op(old)&sizeMask(size)) {
+			return old
+		}
+	}
+}
+
+// AtomicCompareExchange replaces the value of the `size` bytes at `offset`
+// with `replacement` if it equals `expected`, both wrapped to `size` bytes.
+// This returns the value loaded, replaced or not.
+func (m *MemoryInstance) AtomicCompareExchange(offset, size uint32, expected, replacement uint64) uint64 {
+	mask := sizeMask(size)
+	expected, replacement = expected&mask, replacement&mask
+	for {
+		old := m.AtomicLoad(offset, size)
+		if old != expected || m.compareAndSwap(offset, size, old, replacement) {
+			return old
+		}
+	}
+}
+
+// AtomicWait blocks until AtomicNotify wakes it, if the value of the `size`
+// bytes at `offset` equals `expected`, with a timeout in nanoseconds unless
+// negative. This implements memory.atomic.wait32 and memory.atomic.wait64,
+// so it returns 0 if woken, 1 if the value isn't expected, or 2 if timed out.
+//
+// This panics to trap if the memory isn't shared. If `ctx` is done while
+// waiting, this panics with a sys.ExitError, like the exit code of a module
+// closed by ModuleInstance.CloseWithCtxErr, so that a wait without a timeout
+// can't block forever.
+func (m *MemoryInstance) AtomicWait(ctx context.Context, offset, size uint32, expected uint64, timeout int64) uint64 {
+	if !m.Shared {
+		panic(wasmruntime.ErrRuntimeExpectedSharedMemory)
+	}
+
+	// The value is loaded under the lock taken by AtomicNotify, so that a
+	// notification after a store of another value can't be missed.
+	m.waitersMux.Lock()
+	if m.AtomicLoad(offset, size) != expected&sizeMask(size) {
+		m.waitersMux.Unlock()
+		return 1
+	}
+	if m.waiters == nil {
+		m.waiters = map[uint32]*list.List{}
+	}
+	waiters := m.waiters[offset]
+	if waiters == nil {
+		waiters = list.New()
+		m.waiters[offset] = waiters
+	}
+	woken := make(chan struct{})
+	e := waiters.PushBack(woken)
+	m.waitersMux.Unlock()
+
+	var timedOut <-chan time.Time // nil blocks forever.
+	if timeout >= 0 {
+		timer := time.NewTimer(time.Duration(timeout))
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	select {
+	case <-woken:
+		return 0
+	case <-timedOut:
+	case <-ctx.Done():
+	}
+
+	m.waitersMux.Lock()
+	defer m.waitersMux.Unlock()
+	select {
+	case <-woken: // notified while timing out.
+		return 0
+	default:
+	}
+	waiters.Remove(e)
+	if waiters.Len() == 0 && m.waiters[offset] == waiters {
+		delete(m.waiters, offset)
+	}
+	if err := ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			panic(sys.NewExitError(sys.ExitCodeDeadlineExceeded))
+		}
+		panic(sys.NewExitError(sys.ExitCodeContextCanceled))
+	}
+	return 2
+}
+
+// AtomicNotify wakes up to `count` goroutines blocked in AtomicWait at
+// `offset`, in the order they started waiting, and returns how many it woke.
+func (m *MemoryInstance) AtomicNotify(offset, count uint32) uint32 {
+	m.waitersMux.Lock()
+	defer m.waitersMux.Unlock()
+	waiters := m.waiters[offset]
+	if waiters == nil {
+		return 0
+	}
+	var woken uint32
+	for ; woken < count && waiters.Len() > 0; woken++ {
+		close(waiters.Remove(waiters.Front()).(chan struct{}))
+	}
+	if waiters.Len() == 0 {
+		delete(m.waiters, offset)
+	}
+	return woken
+}
+
+// AtomicFence implements atomic.fence, by accessing memory atomically, which
+// is sequentially consistent.
+func AtomicFence() {
+	atomic.AddUint32(&fence, 0)
+}
+
+// compareAndSwap replaces the value of the `size` bytes at `offset` with
+// `replacement`, if it equals `old`.
+func (m *MemoryInstance) compareAndSwap(offset, size uint32, old, replacement uint64) bool {
+	switch size {
+	case 8:
+		return atomic.CompareAndSwapUint64(m.uint64Ptr(offset), le64(old), le64(replacement))
+	case 4:
+		return atomic.CompareAndSwapUint32(m.uint32Ptr(offset), le32(uint32(old)), le32(uint32(replacement)))
+	}
+
+	// Swap the word which contains the value, retrying if another part of it
+	// changed concurrently.
+	ptr, shift, mask := m.uint32Ptr(offset&^3), subwordShift(offset), uint32(sizeMask(size))
+	for {
+		native := atomic.LoadUint32(ptr)
+		word := le32(native)
+		if (word>>shift)&mask != uint32(old) {
+			return false
+		}
+		word = word&^(mask<<shift) | uint32(replacement)<<shift
+		if atomic.CompareAndSwapUint32(ptr, native, le32(word)) {
+			return true
+		}
+	}
+}
+
+func (m *MemoryInstance) uint32Ptr(offset uint32) *uint32 {
+	return (*uint32)(unsafe.Pointer(&m.Buffer[offset]))
+}
+
+func (m *MemoryInstance) uint64Ptr(offset uint32) *uint64 {
+	return (*uint64)(unsafe.Pointer(&m.Buffer[offset]))
+}
+
+// subwordShift returns the shift of a 1 or 2 byte value at `offset` in the
+// little-endian 4 byte word which contains it.
+func subwordShift(offset uint32) uint32 {
+	return (offset & 3) * 8
+}
+
+func sizeMask(size uint32) uint64 {
+	return 1<<(size*8) - 1 // 1<<64 is zero in Go, so this is all ones for 8 bytes.
+}
+
+// le32 converts between the native and little-endian byte order, which is
+// the same conversion in either direction.
+func le32(v uint32) uint32 {
+	if nativeIsBigEndian {
+		return bits.ReverseBytes32(v)
+	}
+	return v
+}
+
+// le64 is like le32, but for 64-bit values.
+func le64(v uint64) uint64 {
+	if nativeIsBigEndian {
+		return bits.ReverseBytes64(v)
+	}
+	return v
+}
@@ -0,0 +1,164 @@
+package wasm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func TestMemoryInstance_AtomicOffset(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 16)}
+
+	require.Equal(t, uint32(12), m.AtomicOffset(8, 4, 4))
+	require.Equal(t, uint32(15), m.AtomicOffset(15, 0, 1))
+
+	err := require.CapturePanic(func() { m.AtomicOffset(2, 0, 4) })
+	require.Equal(t, wasmruntime.ErrRuntimeUnalignedAtomic, err)
+	err = require.CapturePanic(func() { m.AtomicOffset(16, 0, 1) })
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+	err = require.CapturePanic(func() { m.AtomicOffset(0xffffffff, 0xffffffff, 1) })
+	require.ErrorIs(t, err, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
+}
+
+func TestMemoryInstance_AtomicLoadStore(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 16)}
+
+	m.AtomicStore(0, 8, 0x0102030405060708)
+	require.Equal(t, []byte{8, 7, 6, 5, 4, 3, 2, 1}, m.Buffer[:8])
+	require.Equal(t, uint64(0x0102030405060708), m.AtomicLoad(0, 8))
+	require.Equal(t, uint64(0x01020304), m.AtomicLoad(4, 4))
+	require.Equal(t, uint64(0x0304), m.AtomicLoad(4, 2))
+	require.Equal(t, uint64(0x06), m.AtomicLoad(2, 1))
+
+	// Narrow stores wrap the value, and leave the bytes around them.
+	m.AtomicStore(2, 1, 0xffaa)
+	m.AtomicStore(4, 2, 0xffffbbcc)
+	require.Equal(t, []byte{8, 7, 0xaa, 5, 0xcc, 0xbb, 2, 1}, m.Buffer[:8])
+	m.AtomicStore(8, 4, 0x1_0000_0001)
+	require.Equal(t, uint64(1), m.AtomicLoad(8, 8))
+}
+
+func TestMemoryInstance_AtomicRMW(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 8)}
+	m.AtomicStore(0, 8, 0x01020304050607ff)
+
+	// The result wraps to the size of the access.
+	old := m.AtomicRMW(0, 1, func(old uint64) uint64 { return old + 1 })
+	require.Equal(t, uint64(0xff), old)
+	require.Equal(t, uint64(0x0102030405060700), m.AtomicLoad(0, 8))
+
+	old = m.AtomicRMW(6, 2, func(uint64) uint64 { return 0xabcd })
+	require.Equal(t, uint64(0x0102), old)
+	require.Equal(t, uint64(0xabcd030405060700), m.AtomicLoad(0, 8))
+}
+
+func TestMemoryInstance_AtomicRMW_Concurrent(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 8)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(offset uint32) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.AtomicRMW(offset, 1, func(old uint64) uint64 { return old + 1 })
+			}
+		}(uint32(i))
+	}
+	wg.Wait()
+
+	// Each byte of the word was incremented concurrently without losing updates.
+	require.Equal(t, uint64(1000%256*0x01010101), m.AtomicLoad(0, 4))
+}
+
+func TestMemoryInstance_AtomicCompareExchange(t *testing.T) {
+	m := &MemoryInstance{Buffer: make([]byte, 8)}
+	m.AtomicStore(0, 4, 0x12345678)
+
+	require.Equal(t, uint64(0x12345678), m.AtomicCompareExchange(0, 4, 1, 2))
+	require.Equal(t, uint64(0x12345678), m.AtomicLoad(0, 4))
+
+	// The expected value is wrapped to the size of the access.
+	require.Equal(t, uint64(0x78), m.AtomicCompareExchange(0, 1, 0x1_78, 0x99))
+	require.Equal(t, uint64(0x12345699), m.AtomicLoad(0, 4))
+}
+
+func TestMemoryInstance_AtomicWaitNotify(t *testing.T) {
+	t.Run("not shared", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8)}
+		err := require.CapturePanic(func() { m.AtomicWait(testCtx, 0, 4, 0, -1) })
+		require.Equal(t, wasmruntime.ErrRuntimeExpectedSharedMemory, err)
+		require.Equal(t, uint32(0), m.AtomicNotify(0, 1))
+	})
+
+	t.Run("not equal", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+		require.Equal(t, uint64(1), m.AtomicWait(testCtx, 0, 4, 1, -1))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+		require.Equal(t, uint64(2), m.AtomicWait(testCtx, 0, 8, 0, int64(time.Millisecond)))
+		require.Equal(t, 0, len(m.waiters))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+		ctx, cancel := context.WithCancel(testCtx)
+
+		errs := make(chan error)
+		go func() { errs <- require.CapturePanic(func() { m.AtomicWait(ctx, 0, 4, 0, -1) }) }()
+		waitForWaiters(t, m, 0, 1)
+
+		cancel()
+		require.Equal(t, sys.NewExitError(sys.ExitCodeContextCanceled), <-errs)
+		require.Equal(t, 0, len(m.waiters))
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+		ctx, cancel := context.WithTimeout(testCtx, time.Millisecond)
+		defer cancel()
+
+		err := require.CapturePanic(func() { m.AtomicWait(ctx, 0, 4, 0, -1) })
+		require.Equal(t, sys.NewExitError(sys.ExitCodeDeadlineExceeded), err)
+	})
+
+	t.Run("notify", func(t *testing.T) {
+		m := &MemoryInstance{Buffer: make([]byte, 8), Shared: true}
+
+		results := make(chan uint64, 3)
+		for i := 0; i < 3; i++ {
+			go func() { results <- m.AtomicWait(testCtx, 4, 4, 0, -1) }()
+		}
+		waitForWaiters(t, m, 4, 3)
+
+		require.Equal(t, uint32(2), m.AtomicNotify(4, 2))
+		require.Equal(t, uint64(0), <-results)
+		require.Equal(t, uint64(0), <-results)
+		require.Equal(t, uint32(1), m.AtomicNotify(4, 100))
+		require.Equal(t, uint64(0), <-results)
+		require.Equal(t, uint32(0), m.AtomicNotify(4, 1))
+	})
+}
+
+// waitForWaiters blocks until `count` goroutines wait at `offset`.
+func waitForWaiters(t *testing.T, m *MemoryInstance, offset uint32, count int) {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		m.waitersMux.Lock()
+		n := 0
+		if w := m.waiters[offset]; w != nil {
+			n = w.Len()
+		}
+		m.waitersMux.Unlock()
+		if n == count {
+			return
+		}
+	}
+	t.Fatalf("expected %d waiters", count)
+}
@@ -126,3 +126,8 @@ func (f *MemoryDefinition) Max() (max uint32, encoded bool) {
 	encoded = f.memory.IsMaxEncoded
 	return
 }
+
+// PageSize implements the same method as documented on api.MemoryDefinition.
+func (f *MemoryDefinition) PageSize() uint32 {
+	return 1 << f.memory.PageSizeInBits()
+}
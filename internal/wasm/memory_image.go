@@ -0,0 +1,194 @@
+package wasm
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// memoryImage is the initial content of the memory defined by a module, after
+// applying its leading active data segments. It is written once to an
+// unlinked file, which each instance maps copy-on-write. This way, pages
+// holding data that are never written are shared by all instances.
+//
+// See experimental.WithSharedDataSegments
+type memoryImage struct {
+	f *os.File
+
+	// segments is the count of leading data segments applied to the image,
+	// which instantiation must skip.
+	segments int
+
+	// sharedBytes is the size of the OS pages which include data.
+	sharedBytes uint64
+
+	// instances is the count of open instances mapping this image.
+	instances atomic.Int32
+}
+
+// memoryImage returns the memoryImage of this module, building it on first
+// use, or nil if there's no data to share or the platform doesn't support it.
+func (m *Module) memoryImage() *memoryImage {
+	if img := m.sharedMemoryImage.Load(); img != nil {
+		return img
+	}
+	img := newMemoryImage(m)
+	if img == nil {
+		return nil
+	}
+	if !m.sharedMemoryImage.CompareAndSwap(nil, img) {
+		img.close() // lost a race with a concurrent instantiation.
+		return m.sharedMemoryImage.Load()
+	}
+	runtime.SetFinalizer(img, (*memoryImage).close)
+	return img
+}
+
+func newMemoryImage(module *Module) *memoryImage {
+	memSec := module.MemorySection
+	minBytes := memSec.PagesToBytesNum(memSec.Min)
+	if minBytes == 0 {
+		return nil
+	}
+
+	// Only the leading segments whose offset doesn't depend on imports, and
+	// which are in bounds, can be applied ahead of instantiation, as they must
+	// be applied in order.
+	var segments int
+	offsets := make([]uint64, 0, len(module.DataSection))
+	for i := range module.DataSection {
+		d := &module.DataSection[i]
+		if d.IsPassive() {
+			offsets = append(offsets, 0)
+			segments++
+			continue
+		} else if d.OffsetExpression.Opcode != OpcodeI32Const {
+			break
+		}
+		offset, _, err := leb128.LoadInt32(d.OffsetExpression.Data)
+		if err != nil || offset < 0 || uint64(offset)+uint64(len(d.Init)) > minBytes {
+			break
+		}
+		offsets = append(offsets, uint64(offset))
+		segments++
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	pages := map[uint64]struct{}{}
+	for i := 0; i < segments; i++ {
+		d := &module.DataSection[i]
+		if d.IsPassive() || len(d.Init) == 0 {
+			continue
+		}
+		end := offsets[i] + uint64(len(d.Init))
+		for page := offsets[i] / pageSize; page*pageSize < end; page++ {
+			pages[page] = struct{}{}
+		}
+	}
+	if len(pages) == 0 {
+		return nil // nothing to share
+	}
+
+	f, err := os.CreateTemp("", "wazero-memory-image-*")
+	if err != nil {
+		return nil
+	}
+	img := &memoryImage{f: f, segments: segments, sharedBytes: uint64(len(pages)) * pageSize}
+	// Unlink the file now, so that it is removed once closed and unmapped.
+	if err = os.Remove(f.Name()); err != nil {
+		img.close()
+		return nil
+	}
+	// Size the file so that it can be mapped up to the maximum memory. This
+	// doesn't use disk space, as the file is sparse.
+	if err = f.Truncate(int64(memSec.PagesToBytesNum(memSec.Max))); err != nil {
+		img.close()
+		return nil
+	}
+	for i := 0; i < segments; i++ {
+		if d := &module.DataSection[i]; !d.IsPassive() {
+			if _, err = f.WriteAt(d.Init, int64(offsets[i])); err != nil {
+				img.close()
+				return nil
+			}
+		}
+	}
+	return img
+}
+
+func (img *memoryImage) close() {
+	_ = img.f.Close()
+}
+
+// mapMemoryImage replaces the buffer of the memory defined by this module
+// with a copy-on-write mapping of the module's memoryImage, if possible. When
+// `pin` is true, the mapping has capacity for the maximum memory.
+func (m *ModuleInstance) mapMemoryImage(module *Module, pin bool) {
+	img := module.memoryImage()
+	if img == nil {
+		return
+	}
+	mem := m.MemoryInstance
+	capPages := mem.Cap
+	if pin {
+		capPages = mem.Max
+	}
+	size := mem.pagesToBytesNum(capPages)
+	if size > math.MaxInt {
+		return
+	}
+	mapped, err := platform.MmapFilePrivate(img.f, int(size))
+	if err != nil {
+		return // fall back to copying the data segments.
+	}
+	mem.setMapped(mapped, capPages)
+	mem.pinned = pin
+	mem.image = img
+	img.instances.Add(1)
+}
+
+// releaseMemoryImage is called on close to stop counting this instance in
+// the stats of the memoryImage.
+func (m *ModuleInstance) releaseMemoryImage() {
+	if mem := m.MemoryInstance; mem != nil && mem.image != nil && m.Source.MemorySection != nil {
+		mem.image.instances.Add(-1)
+	}
+}
+
+// memoryImageSegments returns the count of leading data segments already
+// applied by mapping the memoryImage.
+func (m *ModuleInstance) memoryImageSegments() int {
+	// Only skip segments if the memory is defined by this module, as an
+	// imported memory may map the image of another.
+	if mem := m.MemoryInstance; mem != nil && mem.image != nil && m.Source.MemorySection != nil {
+		return mem.image.segments
+	}
+	return 0
+}
+
+// SharedDataSegmentsStats implements the interface used by
+// experimental.GetSharedDataSegmentsStats.
+func (m *ModuleInstance) SharedDataSegmentsStats() (experimental.SharedDataSegmentsStats, bool) {
+	mem := m.MemoryInstance
+	if mem == nil || mem.image == nil {
+		return experimental.SharedDataSegmentsStats{}, false
+	}
+	img := mem.image
+	instances := img.instances.Load()
+	if instances < 0 {
+		instances = 0
+	}
+	stats := experimental.SharedDataSegmentsStats{
+		Instances:   uint32(instances),
+		SharedBytes: img.sharedBytes,
+	}
+	if instances > 1 {
+		stats.SavedBytes = img.sharedBytes * uint64(instances-1)
+	}
+	return stats, true
+}
@@ -90,6 +90,43 @@ func TestMemoryInstance_Grow_Size(t *testing.T) {
 	}
 }
 
+func TestMemoryInstance_CustomPageSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		pageSizeLog2 uint32
+	}{
+		{name: "1 byte", pageSizeLog2: 0},
+		{name: "4KiB", pageSizeLog2: 12},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewMemoryInstance(&Memory{Min: 3, Cap: 3, Max: 10, PageSizeLog2: tc.pageSizeLog2, IsPageSizeEncoded: true})
+			require.Equal(t, tc.pageSizeLog2, m.PageSizeInBits())
+			require.Equal(t, uint32(3)<<tc.pageSizeLog2, m.Size())
+			require.Equal(t, uint32(3), m.PageSize())
+
+			res, ok := m.Grow(5)
+			require.True(t, ok)
+			require.Equal(t, uint32(3), res)
+			require.Equal(t, uint32(8), m.PageSize())
+			require.Equal(t, uint32(8)<<tc.pageSizeLog2, m.Size())
+
+			_, ok = m.Grow(3)
+			require.False(t, ok)
+		})
+	}
+}
+
+func TestMemoryLimitPagesWithPageSize(t *testing.T) {
+	require.Equal(t, MemoryLimitPages, MemoryLimitPagesWithPageSize(MemoryLimitPages, MemoryPageSizeInBits))
+	require.Equal(t, uint32(1<<20), MemoryLimitPagesWithPageSize(MemoryLimitPages, 12))
+	require.Equal(t, uint32(math.MaxUint32), MemoryLimitPagesWithPageSize(MemoryLimitPages, 0))
+	require.Equal(t, uint32(2<<16), MemoryLimitPagesWithPageSize(2, 0))
+}
+
 func TestMemoryInstance_ReadByte(t *testing.T) {
 	mem := &MemoryInstance{Buffer: []byte{0, 0, 0, 0, 0, 0, 0, 16}, Min: 1}
 	v, ok := mem.ReadByte(7)
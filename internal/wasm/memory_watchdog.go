@@ -0,0 +1,45 @@
+package wasm
+
+import (
+	"math"
+	"time"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// memoryGrowthWatchdog implements experimental.MemoryGrowthWatchdog with
+// fixed windows of one second.
+type memoryGrowthWatchdog struct {
+	config experimental.MemoryGrowthWatchdog
+	// nanotime is platform.Nanotime except in tests.
+	nanotime func() int64
+
+	windowStart int64
+	grows       uint32
+	pages       uint32
+}
+
+func newMemoryGrowthWatchdog(config experimental.MemoryGrowthWatchdog) *memoryGrowthWatchdog {
+	return &memoryGrowthWatchdog{config: config, nanotime: platform.Nanotime, windowStart: platform.Nanotime()}
+}
+
+// allow records a growth of `delta` pages, returning false if that exceeds a
+// threshold in the current window.
+func (w *memoryGrowthWatchdog) allow(delta uint32) bool {
+	if now := w.nanotime(); now-w.windowStart >= int64(time.Second) {
+		w.windowStart, w.grows, w.pages = now, 0, 0
+	}
+	w.grows++
+	if w.pages += delta; w.pages < delta { // saturate on overflow
+		w.pages = math.MaxUint32
+	}
+
+	if max := w.config.MaxGrowsPerSecond; max > 0 && w.grows > max {
+		return false
+	}
+	if max := w.config.MaxPagesPerSecond; max > 0 && w.pages > max {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package wasm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMemoryInstance_ExceedsGrowthRate(t *testing.T) {
+	var now int64
+	newMemory := func(config experimental.MemoryGrowthWatchdog) *MemoryInstance {
+		now = 0
+		w := newMemoryGrowthWatchdog(config)
+		w.nanotime = func() int64 { return now }
+		w.windowStart = 0
+		return &MemoryInstance{growthWatchdog: w}
+	}
+
+	t.Run("no watchdog", func(t *testing.T) {
+		m := &MemoryInstance{}
+		for i := 0; i < 100; i++ {
+			require.False(t, m.ExceedsGrowthRate(1))
+		}
+	})
+
+	t.Run("MaxGrowsPerSecond", func(t *testing.T) {
+		m := newMemory(experimental.MemoryGrowthWatchdog{MaxGrowsPerSecond: 2})
+		require.False(t, m.ExceedsGrowthRate(1))
+		require.False(t, m.ExceedsGrowthRate(0)) // querying the size isn't growth
+		require.False(t, m.ExceedsGrowthRate(1))
+		require.True(t, m.ExceedsGrowthRate(1))
+
+		// The next window resets the count.
+		now += int64(time.Second)
+		require.False(t, m.ExceedsGrowthRate(1))
+	})
+
+	t.Run("MaxPagesPerSecond", func(t *testing.T) {
+		m := newMemory(experimental.MemoryGrowthWatchdog{MaxPagesPerSecond: 10})
+		require.False(t, m.ExceedsGrowthRate(5))
+		now += int64(time.Second / 2)
+		require.False(t, m.ExceedsGrowthRate(5))
+		require.True(t, m.ExceedsGrowthRate(1))
+
+		now += int64(time.Second / 2)
+		require.False(t, m.ExceedsGrowthRate(10))
+		require.True(t, m.ExceedsGrowthRate(1))
+	})
+
+	t.Run("MaxPagesPerSecond overflow", func(t *testing.T) {
+		m := newMemory(experimental.MemoryGrowthWatchdog{MaxPagesPerSecond: MemoryLimitPages})
+		require.False(t, m.ExceedsGrowthRate(MemoryLimitPages))
+		require.True(t, m.ExceedsGrowthRate(0xffffffff))
+	})
+}
@@ -0,0 +1,110 @@
+package wasm
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// StoreMetrics holds the counters of experimental.Metrics for a Store.
+type StoreMetrics struct {
+	Compilations, CacheHits, Instantiations, RejectedInstantiations, Calls atomic.Uint64
+
+	// Durations are in nanoseconds.
+	CompilationTime, InstantiationTime, CallTime atomic.Int64
+}
+
+// MetricsSnapshot returns the experimental.Metrics of the Store `s`.
+func (s *Store) MetricsSnapshot() experimental.Metrics {
+	m := s.Metrics
+	ret := experimental.Metrics{
+		Compilations:           m.Compilations.Load(),
+		CompilationTime:        time.Duration(m.CompilationTime.Load()),
+		CacheHits:              m.CacheHits.Load(),
+		Instantiations:         m.Instantiations.Load(),
+		InstantiationTime:      time.Duration(m.InstantiationTime.Load()),
+		RejectedInstantiations: m.RejectedInstantiations.Load(),
+		Calls:                  m.Calls.Load(),
+		CallTime:               time.Duration(m.CallTime.Load()),
+	}
+	ret.CacheMisses = ret.Compilations - ret.CacheHits
+
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+	for mi := s.moduleList; mi != nil; mi = mi.next {
+		if mi.Source != nil && mi.Source.IsHostModule {
+			continue
+		}
+		ret.ActiveInstances++
+		var pages, bytes uint64
+		if mem := mi.MemoryInstance; mem != nil {
+			pages, bytes = uint64(mem.PageSize()), uint64(mem.Size())
+			ret.MemoryPages += pages
+			ret.MemoryBytes += bytes
+		}
+		if len(mi.Labels) > 0 {
+			ret.Instances = append(ret.Instances, experimental.InstanceMetrics{
+				Module:      mi.ModuleName,
+				Labels:      mi.Labels,
+				MemoryPages: pages,
+				MemoryBytes: bytes,
+			})
+		}
+	}
+	return ret
+}
+
+// CompiledFromCacheKey is a context.Context Value key. Its associated value
+// should be a *bool.
+//
+// See SetCompiledFromCache
+type CompiledFromCacheKey struct{}
+
+// SetCompiledFromCache is called by Engine.CompileModule when the module was
+// loaded from a cache instead of being compiled.
+func SetCompiledFromCache(ctx context.Context) {
+	if hit, ok := ctx.Value(CompiledFromCacheKey{}).(*bool); ok {
+		*hit = true
+	}
+}
+
+// meteredFunction counts calls of an exported function in StoreMetrics.
+type meteredFunction struct {
+	api.Function
+	m *StoreMetrics
+}
+
+func (f *meteredFunction) observe(start time.Time) {
+	f.m.Calls.Add(1)
+	f.m.CallTime.Add(int64(time.Since(start)))
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *meteredFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	defer f.observe(time.Now())
+	return f.Function.Call(ctx, params...)
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *meteredFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	defer f.observe(time.Now())
+	return f.Function.CallWithStack(ctx, stack)
+}
+
+// CallWithOptions implements the same method as documented on api.Function.
+func (f *meteredFunction) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) ([]uint64, error) {
+	defer f.observe(time.Now())
+	return f.Function.CallWithOptions(ctx, opts, params...)
+}
+
+// SourceOffsetForPC implements the same method as documented on
+// experimental.InternalFunction.
+func (f *meteredFunction) SourceOffsetForPC(pc experimental.ProgramCounter) uint64 {
+	if fn, ok := f.Function.(experimental.InternalFunction); ok {
+		return fn.SourceOffsetForPC(pc)
+	}
+	return 0
+}
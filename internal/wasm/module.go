@@ -9,10 +9,12 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/ieee754"
 	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/u64"
 	"github.com/tetratelabs/wazero/internal/wasmdebug"
 )
 
@@ -170,6 +172,24 @@ type Module struct {
 	// IsHostModule true if this is the host module, false otherwise.
 	IsHostModule bool
 
+	// lazyElements is true when validateTable found that the active element
+	// segments can be applied on first use instead of during instantiation:
+	// their offsets and references are constant, and they only target tables
+	// defined by this module.
+	lazyElements bool
+
+	// CooperativeYield is true if the compiled functions must yield to the Go scheduler periodically in loops,
+	// regardless of whether they are compiled with ensureTermination. See wazero.RuntimeConfig WithCooperativeYield.
+	//
+	// This must be set before AssignModuleID.
+	CooperativeYield bool
+
+	// SSAInspected is true if the module is compiled with an
+	// experimental.SSAInspector, which can edit the compiled code.
+	//
+	// This must be set before AssignModuleID.
+	SSAInspected bool
+
 	// functionDefinitionSectionInitOnce guards FunctionDefinitionSection so that it is initialized exactly once.
 	functionDefinitionSectionInitOnce sync.Once
 
@@ -183,6 +203,14 @@ type Module struct {
 	// as described in https://yurydelendik.github.io/webassembly-dwarf/, though it is not specified in the Wasm
 	// specification: https://github.com/WebAssembly/debugging/issues/1
 	DWARFLines *wasmdebug.DWARFLines
+
+	// sharedMemoryImage is lazily set when instantiated with
+	// experimental.WithSharedDataSegments. See memoryImage.
+	sharedMemoryImage atomic.Pointer[memoryImage]
+
+	// instanceArenas pools the instanceArena of closed instances, when
+	// enabled by experimental.WithInstanceArena.
+	instanceArenas sync.Pool
 }
 
 // ModuleID represents sha256 hash value uniquely assigned to Module.
@@ -204,11 +232,35 @@ func (m *Module) AssignModuleID(wasm []byte, withListener, withEnsureTermination
 	// Use the pre-allocated space on m.ID to append the booleans to sha256 hash.
 	m.ID[0] = boolToByte(withListener)
 	m.ID[1] = boolToByte(withEnsureTermination)
-	h.Write(m.ID[:2])
+	n := 2
+	if m.CooperativeYield {
+		// Only appended when enabled, so that the IDs without it are unchanged.
+		m.ID[2] = 1
+		n = 3
+	}
+	h.Write(m.ID[:n])
+	// Functions replaced by intrinsics change the compiled code, but their Go
+	// implementations can't be hashed, and neither can the edits of an
+	// SSAInspector. Instead, such a module has an ID unique to this process,
+	// so its compilation is never reused.
+	unique := m.SSAInspected
+	for i := range m.CodeSection {
+		if m.CodeSection[i].Intrinsic {
+			unique = true
+			break
+		}
+	}
+	if unique {
+		h.Write(u64.LeBytes(uniqueModuleCount.Add(1)))
+	}
 	// Get checksum by passing the slice underlying m.ID.
 	h.Sum(m.ID[:0])
 }
 
+// uniqueModuleCount makes the ID of each module with intrinsics, or compiled
+// with an SSAInspector, unique.
+var uniqueModuleCount atomic.Uint64
+
 func boolToByte(b bool) (ret byte) {
 	if b {
 		ret = 1
@@ -249,12 +301,47 @@ func (m *Module) typeOfFunction(funcIdx Index) *FunctionType {
 }
 
 func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
+	return m.validate(enabledFeatures, nil)
+}
+
+// ValidateAll is like Validate, except it continues after an error to report
+// all of them. Notably, each invalid function body is reported.
+//
+// Note: Some errors prevent further validation, such as an import of an
+// unknown type, so the result may still be incomplete.
+func (m *Module) ValidateAll(enabledFeatures api.CoreFeatures) (errs []error) {
+	if err := m.validate(enabledFeatures, &errs); err != nil {
+		errs = append(errs, err)
+	}
+	return
+}
+
+// validate implements Validate and ValidateAll. When errs is nil, this
+// returns the first error. Otherwise, errors are appended to it, and only
+// errors which prevent further validation are returned.
+func (m *Module) validate(enabledFeatures api.CoreFeatures, errs *[]error) error {
 	for i := range m.TypeSection {
 		tp := &m.TypeSection[i]
 		tp.CacheNumInUint64()
 	}
 
-	if err := m.validateStartSection(); err != nil {
+	// failed returns true if err should be returned.
+	failed := func(err error) bool {
+		if err == nil {
+			return false
+		} else if errs == nil {
+			return true
+		}
+		*errs = append(*errs, err)
+		return false
+	}
+
+	// An import of an unknown type prevents further validation.
+	if err := m.validateImportTypes(); err != nil {
+		return err
+	}
+
+	if err := m.validateStartSection(); failed(err) {
 		return err
 	}
 
@@ -263,33 +350,33 @@ func (m *Module) Validate(enabledFeatures api.CoreFeatures) error {
 		return err
 	}
 
-	if err = m.validateImports(enabledFeatures); err != nil {
+	if err = m.validateImports(enabledFeatures); failed(err) {
 		return err
 	}
 
-	if err = m.validateGlobals(globals, uint32(len(functions)), MaximumGlobals); err != nil {
+	if err = m.validateGlobals(globals, uint32(len(functions)), MaximumGlobals); failed(err) {
 		return err
 	}
 
-	if err = m.validateMemory(memory, globals, enabledFeatures); err != nil {
+	if err = m.validateMemory(memory, globals, enabledFeatures); failed(err) {
 		return err
 	}
 
-	if err = m.validateExports(enabledFeatures, functions, globals, memory, tables); err != nil {
+	if err = m.validateExports(enabledFeatures, functions, globals, memory, tables); failed(err) {
 		return err
 	}
 
 	if m.CodeSection != nil {
-		if err = m.validateFunctions(enabledFeatures, functions, globals, memory, tables, MaximumFunctionIndex); err != nil {
+		if err = m.validateFunctionsWithErrors(enabledFeatures, functions, globals, memory, tables, MaximumFunctionIndex, errs); failed(err) {
 			return err
 		}
 	} // No need to validate host functions as NewHostModule validates
 
-	if err = m.validateTable(enabledFeatures, tables, MaximumTableIndex); err != nil {
+	if err = m.validateTable(enabledFeatures, tables, MaximumTableIndex); failed(err) {
 		return err
 	}
 
-	if err = m.validateDataCountSection(); err != nil {
+	if err = m.validateDataCountSection(); failed(err) {
 		return err
 	}
 	return nil
@@ -329,6 +416,12 @@ func (m *Module) validateGlobals(globals []GlobalType, numFuncts, maxGlobals uin
 }
 
 func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctionIndex uint32) error {
+	return m.validateFunctionsWithErrors(enabledFeatures, functions, globals, memory, tables, maximumFunctionIndex, nil)
+}
+
+// validateFunctionsWithErrors is like validateFunctions, except when errs is
+// non-nil, errors of each function are appended to it instead of returned.
+func (m *Module) validateFunctionsWithErrors(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table, maximumFunctionIndex uint32, errs *[]error) error {
 	if uint32(len(functions)) > maximumFunctionIndex {
 		return fmt.Errorf("too many functions (%d) in a module", len(functions))
 	}
@@ -354,17 +447,25 @@ func (m *Module) validateFunctions(enabledFeatures api.CoreFeatures, functions [
 	br := bytes.NewReader(nil)
 	// Also, we reuse the stacks across multiple function validations to reduce allocations.
 	vs := &stacks{}
+	// Check all type indexes first, even when collecting errors, as
+	// validating a call reads the type of the function it calls.
 	for idx, typeIndex := range m.FunctionSection {
 		if typeIndex >= typeCount {
 			return fmt.Errorf("invalid %s: type section index %d out of range", m.funcDesc(SectionIDFunction, Index(idx)), typeIndex)
 		}
-		c := &m.CodeSection[idx]
-		if c.GoFunc != nil {
+	}
+	for idx := range m.FunctionSection {
+		if c := &m.CodeSection[idx]; c.GoFunc != nil {
 			continue
+		} else if err = m.validateFunction(vs, enabledFeatures, Index(idx), functions, globals, memory, tables, declaredFuncIndexes, br); err != nil {
+			err = m.newFunctionValidationError(Index(idx), err)
 		}
-		if err = m.validateFunction(vs, enabledFeatures, Index(idx), functions, globals, memory, tables, declaredFuncIndexes, br); err != nil {
-			return fmt.Errorf("invalid %s: %w", m.funcDesc(SectionIDFunction, Index(idx)), err)
+		if err == nil {
+			continue
+		} else if errs == nil {
+			return err
 		}
+		*errs = append(*errs, err)
 	}
 	return nil
 }
@@ -489,6 +590,29 @@ func (m *Module) validateImports(enabledFeatures api.CoreFeatures) error {
 	return nil
 }
 
+// validateImportTypes is the part of validateImports which must pass before
+// function types are looked up, even when collecting all errors.
+func (m *Module) validateImportTypes() error {
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		if imp.Type == ExternTypeFunc && int(imp.DescFunc) >= len(m.TypeSection) {
+			return fmt.Errorf("invalid import[%q.%q] function: type index out of range", imp.Module, imp.Name)
+		}
+	}
+	return nil
+}
+
+// ExportNames returns the names of exports of the given type, in the order of
+// the export section.
+func (m *Module) ExportNames(et ExternType) (ret []string) {
+	for i := range m.ExportSection {
+		if exp := &m.ExportSection[i]; exp.Type == et {
+			ret = append(ret, exp.Name)
+		}
+	}
+	return
+}
+
 func (m *Module) validateExports(enabledFeatures api.CoreFeatures, functions []Index, globals []GlobalType, memory *Memory, tables []Table) error {
 	for i := range m.ExportSection {
 		exp := &m.ExportSection[i]
@@ -581,6 +705,10 @@ func validateConstExpression(globals []GlobalType, numFuncs uint32, expr *Consta
 			return fmt.Errorf("%s needs 16 bytes but was %d bytes", OpcodeVecV128ConstName, len(expr.Data))
 		}
 		actualType = ValueTypeV128
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		if actualType, err = validateExtendedConstExpression(globals, expr); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf("invalid opcode for const expression: 0x%x", expr.Opcode)
 	}
@@ -602,9 +730,12 @@ func (m *Module) validateDataCountSection() (err error) {
 
 func (m *ModuleInstance) buildGlobals(module *Module, funcRefResolver func(funcIndex Index) Reference) {
 	importedGlobals := m.Globals[:module.ImportGlobalCount]
+	// Allocate all globals at once, as opposed to one object per global, to
+	// reduce allocations when instantiating many modules.
+	globals := m.arena.allocGlobals(len(module.GlobalSection))
 	for i := Index(0); i < Index(len(module.GlobalSection)); i++ {
 		gs := &module.GlobalSection[i]
-		g := &GlobalInstance{}
+		g := &globals[i]
 		m.Globals[i+module.ImportGlobalCount] = g
 		g.Type = gs.Type
 		g.initialize(importedGlobals, &gs.Init, funcRefResolver)
@@ -745,11 +876,40 @@ type Import struct {
 	IndexPerType Index
 }
 
-// Memory describes the limits of pages (64KB) in a memory.
+// Memory describes the limits of pages (64KB unless IsPageSizeEncoded) in a memory.
 type Memory struct {
 	Min, Cap, Max uint32
 	// IsMaxEncoded true if the Max is encoded in the original binary.
 	IsMaxEncoded bool
+	// IsShared true if the memory is shared, as defined by the threads
+	// proposal. A shared memory always has a maximum, which is its capacity.
+	IsShared bool
+	// PageSizeLog2 is the log2 of the page size in bytes when
+	// IsPageSizeEncoded, as defined by the custom-page-sizes proposal.
+	PageSizeLog2 uint32
+	// IsPageSizeEncoded true if the page size is encoded in the original binary.
+	IsPageSizeEncoded bool
+}
+
+// PageSizeInBits returns the log2 of the page size in bytes, which is
+// MemoryPageSizeInBits unless IsPageSizeEncoded.
+func (m *Memory) PageSizeInBits() uint32 {
+	if m.IsPageSizeEncoded {
+		return m.PageSizeLog2
+	}
+	return MemoryPageSizeInBits
+}
+
+// PagesToBytesNum is like MemoryPagesToBytesNum, but for the page size of
+// this memory.
+func (m *Memory) PagesToBytesNum(pages uint32) uint64 {
+	return uint64(pages) << m.PageSizeInBits()
+}
+
+// PagesToUnitOfBytes is like PagesToUnitOfBytes, but for the page size of
+// this memory.
+func (m *Memory) PagesToUnitOfBytes(pages uint32) string {
+	return bytesToUnitOfBytes(m.PagesToBytesNum(pages))
 }
 
 // Validate ensures values assigned to Min, Cap and Max are within valid thresholds.
@@ -758,19 +918,19 @@ func (m *Memory) Validate(memoryLimitPages uint32) error {
 
 	if max > memoryLimitPages {
 		return fmt.Errorf("max %d pages (%s) over limit of %d pages (%s)",
-			max, PagesToUnitOfBytes(max), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			max, m.PagesToUnitOfBytes(max), memoryLimitPages, m.PagesToUnitOfBytes(memoryLimitPages))
 	} else if min > memoryLimitPages {
 		return fmt.Errorf("min %d pages (%s) over limit of %d pages (%s)",
-			min, PagesToUnitOfBytes(min), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			min, m.PagesToUnitOfBytes(min), memoryLimitPages, m.PagesToUnitOfBytes(memoryLimitPages))
 	} else if min > max {
 		return fmt.Errorf("min %d pages (%s) > max %d pages (%s)",
-			min, PagesToUnitOfBytes(min), max, PagesToUnitOfBytes(max))
+			min, m.PagesToUnitOfBytes(min), max, m.PagesToUnitOfBytes(max))
 	} else if capacity < min {
 		return fmt.Errorf("capacity %d pages (%s) less than minimum %d pages (%s)",
-			capacity, PagesToUnitOfBytes(capacity), min, PagesToUnitOfBytes(min))
+			capacity, m.PagesToUnitOfBytes(capacity), min, m.PagesToUnitOfBytes(min))
 	} else if capacity > memoryLimitPages {
 		return fmt.Errorf("capacity %d pages (%s) over limit of %d pages (%s)",
-			capacity, PagesToUnitOfBytes(capacity), memoryLimitPages, PagesToUnitOfBytes(memoryLimitPages))
+			capacity, m.PagesToUnitOfBytes(capacity), memoryLimitPages, m.PagesToUnitOfBytes(memoryLimitPages))
 	}
 	return nil
 }
@@ -816,12 +976,19 @@ type Code struct {
 
 	// GoFunc is non-nil when IsHostFunction and defined in go, either
 	// api.GoFunction or api.GoModuleFunction. When present, LocalTypes and Body must
-	// be nil.
+	// be nil, unless the guest function was replaced by an intrinsic. See
+	// ApplyIntrinsics.
 	//
 	// Note: This has no serialization format, so is not encodable.
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#host-functions%E2%91%A2
 	GoFunc interface{}
 
+	// Intrinsic is true when GoFunc replaces the guest function in Body. Unlike
+	// host functions, engines call it with the module which defines it, not
+	// the caller, and without ModuleInstance.BeforeHostCall. See
+	// ApplyIntrinsics.
+	Intrinsic bool
+
 	// BodyOffsetInCodeSection is the offset of the beginning of the body in the code section.
 	// This is used for DWARF based stack trace where a program counter represents an offset in code section.
 	BodyOffsetInCodeSection uint64
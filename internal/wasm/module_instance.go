@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime/debug"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -17,6 +21,10 @@ func (m *ModuleInstance) FailIfClosed() (err error) {
 		case exitCodeFlagResourceNotClosed:
 			// This happens when this module is closed asynchronously in CloseModuleOnCanceledOrTimeout,
 			// and the closure of resources have been deferred here.
+			//
+			// As this can be called while code of this module still runs, e.g. on its periodic check of
+			// the exit code, drop its arena instead of releasing it for reuse by the next instance.
+			m.arena = nil
 			_ = m.ensureResourcesClosed(context.Background())
 		}
 		return sys.NewExitError(uint32(closed >> 32)) // Unpack the high order bits as the exit code.
@@ -81,6 +89,45 @@ func (m *ModuleInstance) CloseWithCtxErr(ctx context.Context) {
 	}
 }
 
+// Trapped is called by engines when a call to a function of this module ended
+// with the error `err`, recovered from a panic. Unless `err` is a
+// sys.ExitError, this poisons the module, or closes it if CloseOnTrap is set.
+func (m *ModuleInstance) Trapped(ctx context.Context, err error) {
+	if _, ok := err.(*sys.ExitError); ok {
+		return
+	}
+	m.trapped.Store(true)
+	if m.CloseOnTrap {
+		_ = m.CloseWithExitCode(ctx, sys.ExitCodeTrapped)
+	}
+}
+
+// Panicked is called by engines when a call recovered a Go runtime panic,
+// before Trapped. This returns the error of the call as an
+// experimental.PanicError, after passing it to the PanicHandler of this
+// module, or else of the Store.
+func (m *ModuleInstance) Panicked(ctx context.Context, recovered interface{}, err error) error {
+	pe := &experimental.PanicError{Module: m.ModuleName, Recovered: recovered, Stack: debug.Stack(), Err: err}
+	handler := m.PanicHandler
+	if handler == nil && m.s != nil {
+		handler = m.s.PanicHandler
+	}
+	if handler != nil {
+		handler(ctx, m, pe)
+	}
+	return pe
+}
+
+// State implements the same method as documented on api.Module.
+func (m *ModuleInstance) State() api.ModuleState {
+	if m.IsClosed() {
+		return api.ModuleStateClosed
+	} else if m.trapped.Load() {
+		return api.ModuleStatePoisoned
+	}
+	return api.ModuleStateHealthy
+}
+
 // Name implements the same method as documented on api.Module
 func (m *ModuleInstance) Name() string {
 	return m.ModuleName
@@ -118,6 +165,13 @@ func (m *ModuleInstance) closeWithExitCodeWithoutClosingResource(exitCode uint32
 	return nil
 }
 
+// CloseDetached closes a module removed from its store with
+// Store.DetachModule. Unlike Close, this doesn't lock the store, so it can be
+// called while the store closes another module.
+func (m *ModuleInstance) CloseDetached(ctx context.Context) error {
+	return m.closeWithExitCode(ctx, 0)
+}
+
 // closeWithExitCode is the same as CloseWithExitCode besides this doesn't delete it from Store.moduleList.
 func (m *ModuleInstance) closeWithExitCode(ctx context.Context, exitCode uint32) (err error) {
 	if !m.setExitCode(exitCode, exitCodeFlagResourceClosed) {
@@ -150,6 +204,9 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 		m.CloseNotifier = nil
 	}
 
+	m.releaseMemoryImage()
+	m.releaseArena()
+
 	if sysCtx := m.Sys; sysCtx != nil { // nil if from HostModuleBuilder
 		if err = sysCtx.FS().Close(); err != nil {
 			return err
@@ -167,6 +224,112 @@ func (m *ModuleInstance) ensureResourcesClosed(ctx context.Context) (err error)
 	return
 }
 
+// BeforeHostCall is called by engines before this module calls a host
+// function. This waits until its rate limit, if any, allows it, then
+// delivers any signals the host raised with RaiseSignal.
+//
+// See wazero.ModuleConfig WithHostCallRateLimit and WithSignalHandler
+func (m *ModuleInstance) BeforeHostCall(ctx context.Context) {
+	sysCtx := m.Sys
+	if sysCtx == nil {
+		return
+	}
+	sysCtx.ThrottleHostCall(ctx)
+	if pending := sysCtx.TakeSignals(); pending != 0 {
+		defer sysCtx.DoneSignals()
+		for sig := uint32(1); pending>>sig != 0; sig++ {
+			if pending&(1<<sig) != 0 {
+				m.Signal(ctx, sig)
+			}
+		}
+	}
+}
+
+// BeforeLeafHostCall is like BeforeHostCall, for a NativeFunction called via
+// Go, whose caller doesn't reload its module state on return. This doesn't
+// deliver signals, as their handlers call functions of this module, which
+// may change it: they wait for the next call to another host function.
+func (m *ModuleInstance) BeforeLeafHostCall(ctx context.Context) {
+	if sysCtx := m.Sys; sysCtx != nil {
+		sysCtx.ThrottleHostCall(ctx)
+	}
+}
+
+// Signal synchronously handles the signal, which must be valid, e.g. for
+// "proc_raise" in wasi_snapshot_preview1. This calls the signal handler the
+// guest exports, if any, or the default action of the signal.
+//
+// This panics with the error of the handler, or a sys.ExitError when the
+// signal terminates the module. So, this must only be called while the
+// module calls a host function.
+func (m *ModuleInstance) Signal(ctx context.Context, sig uint32) {
+	if handler := m.signalHandler(); handler != nil && internalsys.SignalHandled(sig) {
+		if _, err := handler.Call(ctx, uint64(sig)); err != nil {
+			panic(err)
+		}
+		return
+	}
+	if internalsys.SignalTerminates(sig) {
+		exitCode := 128 + sig // like a shell reports a process killed by a signal.
+		_ = m.CloseWithExitCode(ctx, exitCode)
+		panic(sys.NewExitError(exitCode))
+	}
+}
+
+// RaiseSignal implements the same method as used by
+// experimental.RaiseSignal.
+func (m *ModuleInstance) RaiseSignal(ctx context.Context, sig uint32) error {
+	if errno := internalsys.ValidateSignal(sig); errno != 0 {
+		return errno
+	}
+	if m.Sys == nil {
+		return errors.New("module has no system context")
+	}
+	if m.signalHandler() != nil && internalsys.SignalHandled(sig) {
+		m.Sys.QueueSignal(sig)
+		return nil
+	}
+	if internalsys.SignalTerminates(sig) {
+		return m.CloseWithExitCode(ctx, 128+sig)
+	}
+	return nil
+}
+
+// signalHandler returns the function the guest exports to handle signals,
+// or nil if there isn't one.
+func (m *ModuleInstance) signalHandler() api.Function {
+	if sysCtx := m.Sys; sysCtx != nil {
+		if name := sysCtx.SignalHandler(); name != "" {
+			return m.ExportedFunction(name)
+		}
+	}
+	return nil
+}
+
+// RateLimitMetrics implements the same method as used by
+// experimental.GetRateLimitMetrics.
+func (m *ModuleInstance) RateLimitMetrics() (ret experimental.RateLimitMetrics, ok bool) {
+	sysCtx := m.Sys
+	if sysCtx == nil {
+		return
+	}
+	io, hostCall := sysCtx.RateLimits()
+	if io != nil {
+		ret.IOBytes, ret.IOThrottled, ret.IOThrottledTime = io.Metrics()
+		ok = true
+	}
+	if hostCall != nil {
+		ret.HostCalls, ret.HostCallsThrottled, ret.HostCallsThrottledTime = hostCall.Metrics()
+		ok = true
+	}
+	return
+}
+
+// ModuleLabels implements the same method as used by experimental.GetLabels.
+func (m *ModuleInstance) ModuleLabels() map[string]string {
+	return m.Labels
+}
+
 // Memory implements the same method as documented on api.Module.
 func (m *ModuleInstance) Memory() api.Memory {
 	return m.MemoryInstance
@@ -185,16 +348,23 @@ func (m *ModuleInstance) ExportedMemory(name string) api.Memory {
 // ExportedMemoryDefinitions implements the same method as documented on
 // api.Module.
 func (m *ModuleInstance) ExportedMemoryDefinitions() map[string]api.MemoryDefinition {
+	result := map[string]api.MemoryDefinition{}
 	// Special case as we currently only support one memory.
 	if mem := m.MemoryInstance; mem != nil {
-		// Now, find out if it is exported
+		// Now, find out if it is exported, possibly under multiple names.
 		for name, exp := range m.Exports {
 			if exp.Type == ExternTypeMemory {
-				return map[string]api.MemoryDefinition{name: mem.definition}
+				result[name] = mem.definition
 			}
 		}
 	}
-	return map[string]api.MemoryDefinition{}
+	return result
+}
+
+// ExportedMemoryNames implements the same method as documented on
+// api.Module.
+func (m *ModuleInstance) ExportedMemoryNames() []string {
+	return m.Source.ExportNames(ExternTypeMemory)
 }
 
 // ExportedFunction implements the same method as documented on api.Module.
@@ -203,7 +373,19 @@ func (m *ModuleInstance) ExportedFunction(name string) api.Function {
 	if err != nil {
 		return nil
 	}
-	return m.Engine.NewFunction(exp.Index)
+	m.initTables()
+	f := m.Engine.NewFunction(exp.Index)
+	if m.s == nil {
+		return f
+	}
+	f = &drainedFunction{Function: f, c: &m.s.calls}
+	if m.s.Metrics != nil {
+		f = &meteredFunction{Function: f, m: m.s.Metrics}
+	}
+	if m.s.Trace {
+		f = newTracedFunction(f, m.ModuleName, name)
+	}
+	return f
 }
 
 // ExportedFunctionDefinitions implements the same method as documented on
@@ -218,6 +400,12 @@ func (m *ModuleInstance) ExportedFunctionDefinitions() map[string]api.FunctionDe
 	return result
 }
 
+// ExportedFunctionNames implements the same method as documented on
+// api.Module.
+func (m *ModuleInstance) ExportedFunctionNames() []string {
+	return m.Source.ExportNames(ExternTypeFunc)
+}
+
 // GlobalVal is an internal hack to get the lower 64 bits of a global.
 func (m *ModuleInstance) GlobalVal(idx Index) uint64 {
 	return m.Globals[idx].Val
@@ -245,3 +433,42 @@ func (m *ModuleInstance) NumGlobal() int {
 func (m *ModuleInstance) Global(idx int) api.Global {
 	return constantGlobal{g: m.Globals[idx]}
 }
+
+// CallWithOptions implements api.Function CallWithOptions for engines, where
+// `call` invokes the function and `m` is the module that defines it.
+//
+// The timeout uses the same mechanism as CloseModuleOnCanceledOrTimeout: it
+// marks the module closed, which code compiled with ensureTermination checks
+// periodically.
+func (m *ModuleInstance) CallWithOptions(opts api.CallOptions, call func() error) error {
+	if opts.Timeout <= 0 {
+		return call()
+	}
+
+	var timedOut bool
+	fired := make(chan struct{})
+	timer := time.AfterFunc(opts.Timeout, func() {
+		defer close(fired)
+		timedOut = m.setExitCode(sys.ExitCodeDeadlineExceeded, exitCodeFlagResourceNotClosed)
+		if timedOut {
+			_ = m.s.deleteModule(m)
+		}
+	})
+
+	err := call()
+	if timer.Stop() {
+		return err // finished before the timeout
+	}
+	<-fired // wait for the timer function to complete
+
+	if timedOut {
+		if opts.OnTimeout != nil {
+			opts.OnTimeout()
+		}
+		// FailIfClosed also closes resources deferred by the timer function.
+		if exitErr := m.FailIfClosed(); err == nil {
+			err = exitErr
+		}
+	}
+	return err
+}
@@ -12,6 +12,7 @@ import (
 // otherwise this panics according to the same semantics as call_indirect instruction.
 // Currently, this is only used by emscripten which needs to do call_indirect-like operation in the host function.
 func (m *ModuleInstance) LookupFunction(t *TableInstance, typeId FunctionTypeID, tableOffset Index) api.Function {
+	m.initTables()
 	fm, index := m.Engine.LookupFunction(t, typeId, tableOffset)
 	if source := fm.Source; source.IsHostModule {
 		// This case, the found function is a host function stored in the table. Generally, Engine.NewFunction are only
@@ -65,6 +66,15 @@ func (l *lookedUpGoFunction) Call(ctx context.Context, params ...uint64) ([]uint
 	return stack[:rn], l.CallWithStack(ctx, stack)
 }
 
+// CallWithOptions implements api.Function.
+func (l *lookedUpGoFunction) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) (results []uint64, err error) {
+	err = l.lookedUpModule.CallWithOptions(opts, func() (err error) {
+		results, err = l.Call(ctx, params...)
+		return
+	})
+	return
+}
+
 // CallWithStack implements api.Function.
 func (l *lookedUpGoFunction) CallWithStack(ctx context.Context, stack []uint64) error {
 	// The Go host function always needs to access caller's module, in this case the one holding the table.
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tetratelabs/wazero/api"
+
 	"github.com/tetratelabs/wazero/experimental/sys"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
@@ -423,6 +425,65 @@ func TestModuleInstance_CloseWithCtxErr(t *testing.T) {
 	})
 }
 
+func TestModuleInstance_CallWithOptions(t *testing.T) {
+	s := newStore()
+
+	t.Run("no timeout", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s}
+		err := cc.CallWithOptions(api.CallOptions{}, func() error { return errors.New("ice cream") })
+		require.EqualError(t, err, "ice cream")
+		require.False(t, cc.IsClosed())
+	})
+
+	t.Run("returns before timeout", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		var onTimeout int
+		err := cc.CallWithOptions(api.CallOptions{
+			Timeout:   time.Minute,
+			OnTimeout: func() { onTimeout++ },
+		}, func() error { return nil })
+		require.NoError(t, err)
+		require.Zero(t, onTimeout)
+		require.False(t, cc.IsClosed())
+		require.NotNil(t, cc.Sys)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s, Sys: internalsys.DefaultContext(nil)}
+		var onTimeout int
+		err := cc.CallWithOptions(api.CallOptions{
+			Timeout:   time.Millisecond,
+			OnTimeout: func() { onTimeout++ },
+		}, func() error {
+			// Emulate guest code compiled with ensureTermination.
+			for !cc.IsClosed() {
+				time.Sleep(time.Millisecond)
+			}
+			return nil
+		})
+		require.EqualError(t, err, "module closed with context deadline exceeded")
+		require.Equal(t, 1, onTimeout)
+
+		// The resource must be closed before returning.
+		require.Nil(t, cc.Sys)
+	})
+
+	t.Run("closed for another reason", func(t *testing.T) {
+		cc := &ModuleInstance{ModuleName: "test", s: s}
+		var onTimeout int
+		err := cc.CallWithOptions(api.CallOptions{
+			Timeout:   time.Millisecond,
+			OnTimeout: func() { onTimeout++ },
+		}, func() error {
+			_ = cc.CloseWithExitCode(context.Background(), 2)
+			time.Sleep(10 * time.Millisecond)
+			return cc.FailIfClosed()
+		})
+		require.EqualError(t, err, "module closed with exit_code(2)")
+		require.Zero(t, onTimeout)
+	})
+}
+
 type mockCloser struct{ called int }
 
 func (m *mockCloser) Close(context.Context) error {
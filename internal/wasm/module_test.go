@@ -321,6 +321,76 @@ func TestValidateConstExpression(t *testing.T) {
 			}
 		})
 	})
+	t.Run("extended", func(t *testing.T) {
+		globals := []GlobalType{{ValType: ValueTypeI32}, {ValType: ValueTypeI64}}
+		tests := []struct {
+			name         string
+			data         []byte
+			expectedType ValueType
+			expectedErr  string
+		}{
+			{
+				name:         "i32",
+				data:         []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 2, OpcodeI32Const, 3, OpcodeI32Mul, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+			},
+			{
+				name:         "i64",
+				data:         []byte{OpcodeGlobalGet, 1, OpcodeI64Const, 2, OpcodeI64Sub},
+				expectedType: ValueTypeI64,
+			},
+			{
+				name:         "type mismatch",
+				data:         []byte{OpcodeI64Const, 1, OpcodeI64Const, 2, OpcodeI64Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "const expression type mismatch expected i32 but got i64",
+			},
+			{
+				name:         "operand type mismatch",
+				data:         []byte{OpcodeGlobalGet, 1, OpcodeI32Const, 2, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "cannot pop the operands for i32.add",
+			},
+			{
+				name:         "too few operands",
+				data:         []byte{OpcodeI32Const, 2, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "cannot pop the operands for i32.add",
+			},
+			{
+				name:         "too many values",
+				data:         []byte{OpcodeI32Const, 1, OpcodeI32Const, 2, OpcodeI32Const, 3, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "const expression must result in one value, but was 2",
+			},
+			{
+				name:         "global index out of range",
+				data:         []byte{OpcodeGlobalGet, 2, OpcodeI32Const, 2, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "global index out of range",
+			},
+			{
+				name:         "invalid opcode",
+				data:         []byte{OpcodeF32Const, 0, 0, 0, 0, OpcodeI32Const, 2, OpcodeI32Add},
+				expectedType: ValueTypeI32,
+				expectedErr:  "invalid opcode for const expression: 0x43",
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				expr := &ConstantExpression{Opcode: tc.data[len(tc.data)-1], Data: tc.data}
+				require.True(t, expr.IsExtended())
+				err := validateConstExpression(globals, 0, expr, tc.expectedType)
+				if tc.expectedErr == "" {
+					require.NoError(t, err)
+				} else {
+					require.EqualError(t, err, tc.expectedErr)
+				}
+			})
+		}
+	})
 }
 
 func TestModule_Validate_Errors(t *testing.T) {
@@ -352,6 +422,43 @@ func TestModule_Validate_Errors(t *testing.T) {
 	}
 }
 
+func TestModule_ValidateAll(t *testing.T) {
+	m := &Module{
+		TypeSection:     []FunctionType{v_v},
+		FunctionSection: []Index{0, 1},
+		CodeSection:     []Code{{Body: []byte{OpcodeF32Abs, OpcodeEnd}}, {Body: []byte{OpcodeEnd}}},
+		ExportSection:   []Export{{Name: "g", Type: ExternTypeGlobal, Index: 0}},
+	}
+
+	var errs []string
+	for _, err := range m.ValidateAll(api.CoreFeaturesV1) {
+		errs = append(errs, err.Error())
+	}
+	require.Equal(t, []string{
+		`unknown global for export["g"]`,
+		// Function bodies aren't validated when a type index is out of range.
+		"invalid function[1]: type section index 1 out of range",
+	}, errs)
+
+	// Validate returns the first error.
+	require.EqualError(t, m.Validate(api.CoreFeaturesV1), errs[0])
+
+	t.Run("invalid import type index", func(t *testing.T) {
+		m := &Module{
+			TypeSection:         []FunctionType{v_v},
+			ImportSection:       []Import{{Module: "m", Name: "f", Type: ExternTypeFunc, DescFunc: 1}},
+			ImportFunctionCount: 1,
+			FunctionSection:     []Index{0},
+			CodeSection:         []Code{{Body: []byte{OpcodeCall, 0, OpcodeEnd}}},
+			ExportSection:       []Export{{Name: "f", Type: ExternTypeFunc, Index: 0}},
+		}
+
+		errs := m.ValidateAll(api.CoreFeaturesV1)
+		require.Equal(t, 1, len(errs))
+		require.EqualError(t, errs[0], `invalid import["m"."f"] function: type index out of range`)
+	})
+}
+
 func TestModule_validateStartSection(t *testing.T) {
 	t.Run("no start section", func(t *testing.T) {
 		m := Module{}
@@ -536,6 +643,47 @@ func TestModule_validateFunctions(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), `invalid function[0] export["f1","f2"]: cannot pop the 1st f32`)
 	})
+	t.Run("FunctionValidationError", func(t *testing.T) {
+		m := Module{
+			ImportFunctionCount: 1,
+			TypeSection:         []FunctionType{v_v},
+			ImportSection:       []Import{{Type: ExternTypeFunc}},
+			FunctionSection:     []Index{0},
+			CodeSection: []Code{{
+				Body:                    []byte{OpcodeNop, OpcodeF32Abs, OpcodeEnd},
+				BodyOffsetInCodeSection: 3,
+			}},
+			NameSection: &NameSection{FunctionNames: NameMap{{Index: 1, Name: "abs"}}},
+		}
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		require.EqualError(t, err, "invalid function[0]: cannot pop the 1st f32 operand for f32.abs: f32 missing")
+
+		fve, ok := err.(*FunctionValidationError)
+		require.True(t, ok)
+		require.Equal(t, Index(1), fve.FunctionIndex)
+		require.Equal(t, "abs", fve.Name)
+		require.Equal(t, int64(4), fve.Offset)
+		require.Equal(t, "f32.abs", fve.Instruction)
+		require.Equal(t, `invalid function[0]: cannot pop the 1st f32 operand for f32.abs: f32 missing
+	function: 1 (abs)
+	instruction: f32.abs at offset 0x4 in the code section
+	bytes: 8b 0b`, fve.Explain())
+	})
+	t.Run("FunctionValidationError not an instruction", func(t *testing.T) {
+		m := Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: []byte{OpcodeBlock, 0x40, OpcodeEnd}}},
+		}
+		err := m.validateFunctions(api.CoreFeaturesV1, nil, nil, nil, nil, MaximumFunctionIndex)
+		require.EqualError(t, err, "invalid function[0]: ill-nested block exists")
+
+		fve, ok := err.(*FunctionValidationError)
+		require.True(t, ok)
+		require.Equal(t, int64(-1), fve.Offset)
+		require.Equal(t, "", fve.Instruction)
+		require.Equal(t, "invalid function[0]: ill-nested block exists\n\tfunction: 0", fve.Explain())
+	})
 }
 
 func TestModule_validateMemory(t *testing.T) {
@@ -1010,30 +1158,33 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 }
 
 func TestModule_AssignModuleID(t *testing.T) {
-	getID := func(bin []byte, withListener, withEnsureTermination bool) ModuleID {
-		m := Module{}
+	getID := func(bin []byte, withListener, withEnsureTermination, withCooperativeYield bool) ModuleID {
+		m := Module{CooperativeYield: withCooperativeYield}
 		m.AssignModuleID(bin, withListener, withEnsureTermination)
 		return m.ID
 	}
 
 	// Ensures that different args always produce the different IDs.
 	exists := map[ModuleID]struct{}{}
-	for _, tc := range []struct {
-		bin                                 []byte
-		withListener, withEnsureTermination bool
-	}{
-		{bin: []byte{1, 2, 3}, withListener: false, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3}, withListener: false, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3}, withListener: true, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3}, withListener: true, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3, 4}, withListener: false, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3, 4}, withListener: false, withEnsureTermination: true},
-		{bin: []byte{1, 2, 3, 4}, withListener: true, withEnsureTermination: false},
-		{bin: []byte{1, 2, 3, 4}, withListener: true, withEnsureTermination: true},
-	} {
-		id := getID(tc.bin, tc.withListener, tc.withEnsureTermination)
-		_, exist := exists[id]
-		require.False(t, exist)
-		exists[id] = struct{}{}
+	for _, bin := range [][]byte{{1, 2, 3}, {1, 2, 3, 4}} {
+		for _, withListener := range []bool{false, true} {
+			for _, withEnsureTermination := range []bool{false, true} {
+				for _, withCooperativeYield := range []bool{false, true} {
+					id := getID(bin, withListener, withEnsureTermination, withCooperativeYield)
+					_, exist := exists[id]
+					require.False(t, exist)
+					exists[id] = struct{}{}
+				}
+			}
+		}
 	}
+
+	// A module compiled with an SSAInspector has a unique ID.
+	m := Module{SSAInspected: true}
+	m.AssignModuleID([]byte{1, 2, 3}, false, false)
+	id := m.ID
+	m.AssignModuleID([]byte{1, 2, 3}, false, false)
+	require.NotEqual(t, id, m.ID)
+	_, exist := exists[id]
+	require.False(t, exist)
 }
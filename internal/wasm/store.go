@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/close"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/leb128"
@@ -58,6 +60,30 @@ type (
 		// Note: this is fixed to 2^27 but have this a field for testability.
 		functionMaxTypes uint32
 
+		// Metrics is non-nil when experimental.Metrics are collected.
+		Metrics *StoreMetrics
+
+		// Trace is true when the execution trace is annotated. See experimental.WithTrace.
+		Trace bool
+
+		// PanicHandler is called by ModuleInstance.Panicked, if not nil. See
+		// experimental.WithPanicHandler.
+		PanicHandler experimental.PanicHandler
+
+		// MaxInstances limits activeInstances, unless zero.
+		MaxInstances int
+
+		// OnMaxInstancesExceeded is called, if not nil, when an instantiation
+		// is rejected due to MaxInstances.
+		OnMaxInstancesExceeded func(ctx context.Context, moduleName string)
+
+		// activeInstances is the count of guest modules instantiated or being
+		// instantiated, when MaxInstances is set.
+		activeInstances int // guarded by mux
+
+		// calls are the calls of exported functions in flight. See Drain.
+		calls inFlightCalls
+
 		// mux is used to guard the fields from concurrent access.
 		mux sync.RWMutex
 	}
@@ -117,15 +143,41 @@ type (
 		// CodeCloser is non-nil when the code should be closed after this module.
 		CodeCloser api.Closer
 
+		// trapped is set once a call to a function of this module trapped. See Trapped.
+		trapped atomic.Bool
+
+		// CloseOnTrap closes this module with sys.ExitCodeTrapped instead of leaving it poisoned.
+		CloseOnTrap bool
+
+		// Labels are set by wazero.ModuleConfig WithLabel, or nil.
+		Labels map[string]string
+
+		// PanicHandler overrides the PanicHandler of the Store, if not nil.
+		PanicHandler experimental.PanicHandler
+
+		// CloseOnContextDone is true when this module is closed when the
+		// context.Context of a call is done. See wazero.RuntimeConfig WithCloseOnContextDone.
+		CloseOnContextDone bool
+
 		// s is the Store on which this module is instantiated.
 		s *Store
 		// prev and next hold the nodes in the linked list of ModuleInstance held by Store.
 		prev, next *ModuleInstance
+		// aliases are the other names of this module in Store, added with
+		// Store.AliasModule. This is guarded by the mux of the Store.
+		aliases []string
 		// Source is a pointer to the Module from which this ModuleInstance derives.
 		Source *Module
 
 		// CloseNotifier is an experimental hook called once on close.
 		CloseNotifier close.Notifier
+
+		// arena is non-nil when the globals, tables and element instances are
+		// allocated from an instanceArena, released on close.
+		arena *instanceArena
+
+		// tablesOnce guards the application of the active element segments. See initTables.
+		tablesOnce sync.Once
 	}
 
 	// DataInstance holds bytes corresponding to the data segment in a module.
@@ -164,14 +216,26 @@ func (m *ModuleInstance) GetFunctionTypeID(t *FunctionType) FunctionTypeID {
 }
 
 func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) {
-	m.ElementInstances = make([]ElementInstance, len(elements))
+	m.ElementInstances = m.arena.allocElements(len(elements))
+
+	// Allocate the references of all element instances at once.
+	var count int
+	for i := range elements {
+		if elm := &elements[i]; elm.Type == RefTypeFuncref && elm.Mode == ElementModePassive {
+			count += len(elm.Init)
+		}
+	}
+	references := m.arena.allocElementReferences(count)
+
 	for i, elm := range elements {
 		if elm.Type == RefTypeFuncref && elm.Mode == ElementModePassive {
 			// Only passive elements can be access as element instances.
 			// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/syntax/modules.html#element-segments
 			inits := elm.Init
 			elemInst := &m.ElementInstances[i]
-			elemInst.References = make([]Reference, len(inits))
+			// Limit the capacity, so that appending can't overwrite the next.
+			elemInst.References = references[:len(inits):len(inits)]
+			references = references[len(inits):]
 			elemInst.Type = RefTypeFuncref
 			for j, idx := range inits {
 				if idx != ElementInitNullReference {
@@ -182,6 +246,22 @@ func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) {
 	}
 }
 
+// initTables applies the active element segments of the source module to the
+// tables, unless already applied. This must be called before any code of this
+// module can read its tables, e.g. before returning a function to the caller.
+//
+// Instantiation defers this when Module.lazyElements allows it, so that a
+// module with huge element segments is cheap to instantiate until it's used.
+// See BenchmarkInstantiation_elements.
+func (m *ModuleInstance) initTables() {
+	m.tablesOnce.Do(func() {
+		if m.Source != nil {
+			m.applyElements(m.Source.ElementSection)
+		}
+	})
+}
+
+// applyElements initializes tables with the active element segments.
 func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 	for elemI := range elems {
 		elem := &elems[elemI]
@@ -190,17 +270,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			len(elem.Init) == 0 {
 			continue
 		}
-		var offset uint32
-		if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-			// Ignore error as it's already validated.
-			globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-			global := m.Globals[globalIdx]
-			offset = uint32(global.Val)
-		} else {
-			// Ignore error as it's already validated.
-			o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-			offset = uint32(o)
-		}
+		offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 		table := m.Tables[elem.TableIndex]
 		references := table.References
@@ -259,10 +329,11 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 // bounds memory access error here is not a validation error, but rather a runtime error.
 func (m *ModuleInstance) applyData(data []DataSegment) error {
 	m.DataInstances = make([][]byte, len(data))
+	skip := m.memoryImageSegments()
 	for i := range data {
 		d := &data[i]
 		m.DataInstances[i] = d.Init
-		if !d.IsPassive() {
+		if !d.IsPassive() && i >= skip {
 			offset := executeConstExpressionI32(m.Globals, &d.OffsetExpression)
 			if offset < 0 || int(offset)+len(d.Init) > len(m.MemoryInstance.Buffer) {
 				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
@@ -293,6 +364,7 @@ func NewStore(enabledFeatures api.CoreFeatures, engine Engine) *Store {
 		Engine:           engine,
 		typeIDs:          map[string]FunctionTypeID{},
 		functionMaxTypes: maximumFunctionTypes,
+		calls:            inFlightCalls{idle: make(chan struct{}, 1)},
 	}
 }
 
@@ -311,14 +383,20 @@ func (s *Store) Instantiate(
 	sys *internalsys.Context,
 	typeIDs []FunctionTypeID,
 ) (*ModuleInstance, error) {
+	if err := s.reserveInstance(ctx, module, name); err != nil {
+		return nil, err
+	}
+
 	// Instantiate the module and add it to the store so that other modules can import it.
 	m, err := s.instantiate(ctx, module, name, sys, typeIDs)
 	if err != nil {
+		s.releaseInstance(module)
 		return nil, err
 	}
 
 	// Now that the instantiation is complete without error, add it.
 	if err = s.registerModule(m); err != nil {
+		s.releaseInstance(module)
 		_ = m.Close(ctx)
 		return nil, err
 	}
@@ -333,6 +411,13 @@ func (s *Store) instantiate(
 	typeIDs []FunctionTypeID,
 ) (m *ModuleInstance, err error) {
 	m = &ModuleInstance{ModuleName: name, TypeIDs: typeIDs, Sys: sysCtx, s: s, Source: module}
+	if ctx != nil {
+		// Set the labels first, so that they apply to traps of the start function.
+		m.Labels, _ = ctx.Value(LabelsKey{}).(map[string]string)
+		if ctx.Value(experimental.InstanceArenaKey{}) != nil {
+			m.acquireArena(module)
+		}
+	}
 
 	m.Tables = make([]*TableInstance, int(module.ImportTableCount)+len(module.TableSection))
 	m.Globals = make([]*GlobalInstance, int(module.ImportGlobalCount)+len(module.GlobalSection))
@@ -341,7 +426,7 @@ func (s *Store) instantiate(
 		return nil, err
 	}
 
-	if err = m.resolveImports(module); err != nil {
+	if err = m.resolveImports(ctx, module); err != nil {
 		return nil, err
 	}
 
@@ -354,6 +439,18 @@ func (s *Store) instantiate(
 
 	m.buildGlobals(module, m.Engine.FunctionInstanceReference)
 	m.buildMemory(module)
+	if module.MemorySection != nil && ctx != nil {
+		if w, ok := ctx.Value(experimental.MemoryGrowthWatchdogKey{}).(experimental.MemoryGrowthWatchdog); ok {
+			m.MemoryInstance.growthWatchdog = newMemoryGrowthWatchdog(w)
+		}
+		pin := ctx.Value(experimental.PinnedMemoryKey{}) != nil
+		if ctx.Value(experimental.SharedDataSegmentsKey{}) != nil {
+			m.mapMemoryImage(module, pin)
+		}
+		if pin && !m.MemoryInstance.pinned {
+			m.MemoryInstance.pin()
+		}
+	}
 	m.Exports = module.Exports
 
 	// As of reference types proposal, data segment validation must happen after instantiation,
@@ -373,7 +470,12 @@ func (s *Store) instantiate(
 		return nil, err
 	}
 
-	m.applyElements(module.ElementSection)
+	// Element segments can only be deferred when they can't observe any change
+	// made after instantiation, and nothing can read the tables before a
+	// function of this module is returned. See initTables.
+	if !module.lazyElements || module.StartSection != nil {
+		m.initTables()
+	}
 
 	m.Engine.DoneInstantiation()
 
@@ -391,20 +493,35 @@ func (s *Store) instantiate(
 	return
 }
 
-func (m *ModuleInstance) resolveImports(module *Module) (err error) {
-	for moduleName, imports := range module.ImportPerModule {
-		var importedModule *ModuleInstance
-		importedModule, err = m.s.module(moduleName)
-		if err != nil {
-			return err
-		}
+// LabelsKey is a context.Context Value key. Its associated value should be
+// the ModuleInstance.Labels of the module to instantiate.
+type LabelsKey struct{}
+
+// ImportFallbacksKey is a context.Context Value key. Its associated value
+// should be a map[string]*ModuleInstance, whose modules satisfy imports of
+// the module names which the store doesn't, for example with stubs.
+type ImportFallbacksKey struct{}
+
+func (m *ModuleInstance) resolveImports(ctx context.Context, module *Module) (err error) {
+	var fallbacks map[string]*ModuleInstance
+	if ctx != nil {
+		fallbacks, _ = ctx.Value(ImportFallbacksKey{}).(map[string]*ModuleInstance)
+	}
+	if err = m.s.checkImports(module, fallbacks); err != nil {
+		return
+	}
 
+	for moduleName, imports := range module.ImportPerModule {
+		storeModule, moduleErr := m.s.module(moduleName)
 		for _, i := range imports {
+			var importedModule *ModuleInstance
 			var imported *Export
-			imported, err = importedModule.getExport(i.Name, i.Type)
+			importedModule, imported, err = lookupImport(storeModule, moduleErr, fallbacks[moduleName], i)
 			if err != nil {
 				return
 			}
+			// The imported functions or tables can read the tables of the imported module.
+			importedModule.initTables()
 
 			switch i.Type {
 			case ExternTypeFunc:
@@ -446,7 +563,13 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 				expected := i.DescMem
 				importedMemory := importedModule.MemoryInstance
 
-				if expected.Min > memoryBytesNumToPages(uint64(len(importedMemory.Buffer))) {
+				if expected.PageSizeInBits() != importedMemory.PageSizeInBits() {
+					err = errorInvalidImport(i, fmt.Errorf("page size mismatch: %d != %d",
+						uint64(1)<<expected.PageSizeInBits(), uint64(1)<<importedMemory.PageSizeInBits()))
+					return
+				}
+
+				if expected.Min > importedMemory.PageSize() {
 					err = errorMinSizeMismatch(i, expected.Min, importedMemory.Min)
 					return
 				}
@@ -455,6 +578,12 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 					err = errorMaxSizeMismatch(i, expected.Max, importedMemory.Max)
 					return
 				}
+
+				if expected.IsShared != importedMemory.Shared {
+					err = errorInvalidImport(i, fmt.Errorf("shared mismatch: %t != %t",
+						expected.IsShared, importedMemory.Shared))
+					return
+				}
 				m.MemoryInstance = importedMemory
 				m.Engine.ResolveImportedMemory(importedModule.Engine)
 			case ExternTypeGlobal:
@@ -479,6 +608,58 @@ func (m *ModuleInstance) resolveImports(module *Module) (err error) {
 	return
 }
 
+// lookupImport returns the module and export which satisfy the import `i`:
+// `importedModule`, unless `moduleErr` is non-nil or it doesn't export `i`,
+// otherwise `fallback`, if non-nil.
+func lookupImport(importedModule *ModuleInstance, moduleErr error, fallback *ModuleInstance, i *Import) (*ModuleInstance, *Export, error) {
+	err := moduleErr
+	if err == nil {
+		var exp *Export
+		if exp, err = importedModule.getExport(i.Name, i.Type); err == nil {
+			return importedModule, exp, nil
+		}
+	}
+	if fallback != nil {
+		if exp, fallbackErr := fallback.getExport(i.Name, i.Type); fallbackErr == nil {
+			return fallback, exp, nil
+		}
+	}
+	return nil, nil, err
+}
+
+// checkImports returns an UnsatisfiedImportError if any import of `module`
+// is of a module which isn't instantiated, or of a missing export, and isn't
+// satisfied by `fallbacks` either.
+func (s *Store) checkImports(module *Module, fallbacks map[string]*ModuleInstance) error {
+	moduleNames := make([]string, 0, len(module.ImportPerModule))
+	for moduleName := range module.ImportPerModule {
+		moduleNames = append(moduleNames, moduleName)
+	}
+	sort.Strings(moduleNames)
+
+	var e *UnsatisfiedImportError
+	for _, moduleName := range moduleNames {
+		importedModule, moduleErr := s.module(moduleName)
+		for _, i := range module.ImportPerModule[moduleName] {
+			if _, _, err := lookupImport(importedModule, moduleErr, fallbacks[moduleName], i); err != nil {
+				e = appendUnsatisfiedImport(e, moduleName, i, err)
+			}
+		}
+	}
+	if e != nil {
+		return e
+	}
+	return nil
+}
+
+func appendUnsatisfiedImport(e *UnsatisfiedImportError, moduleName string, i *Import, err error) *UnsatisfiedImportError {
+	if e == nil {
+		e = &UnsatisfiedImportError{Err: err}
+	}
+	e.Imports = append(e.Imports, UnsatisfiedImport{Module: moduleName, Name: i.Name, Type: i.Type})
+	return e
+}
+
 func errorMinSizeMismatch(i *Import, expected, actual uint32) error {
 	return errorInvalidImport(i, fmt.Errorf("minimum size mismatch: %d > %d", expected, actual))
 }
@@ -506,6 +687,8 @@ func executeConstExpressionI32(importedGlobals []*GlobalInstance, expr *Constant
 		id, _, _ := leb128.LoadUint32(expr.Data)
 		g := importedGlobals[id]
 		ret = int32(g.Val)
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul:
+		ret = int32(executeExtendedConstExpression(importedGlobals, expr))
 	}
 	return
 }
@@ -556,6 +739,8 @@ func (g *GlobalInstance) initialize(importedGlobals []*GlobalInstance, expr *Con
 		g.Val = uint64(funcRefResolver(v))
 	case OpcodeVecV128Const:
 		g.Val, g.ValHi = binary.LittleEndian.Uint64(expr.Data[0:8]), binary.LittleEndian.Uint64(expr.Data[8:16])
+	case OpcodeI32Add, OpcodeI32Sub, OpcodeI32Mul, OpcodeI64Add, OpcodeI64Sub, OpcodeI64Mul:
+		g.Val = executeExtendedConstExpression(importedGlobals, expr)
 	}
 }
 
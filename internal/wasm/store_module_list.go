@@ -1,6 +1,7 @@
 package wasm
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -12,6 +13,10 @@ func (s *Store) deleteModule(m *ModuleInstance) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
+	if m.prev != nil || m.next != nil || s.moduleList == m {
+		s.releaseInstanceLocked(m.Source)
+	}
+
 	// Remove this module name.
 	if m.prev != nil {
 		m.prev.next = m.next
@@ -27,6 +32,14 @@ func (s *Store) deleteModule(m *ModuleInstance) error {
 	m.prev = nil
 	m.next = nil
 
+	// Remove any aliases, unless the name was reused.
+	for _, alias := range m.aliases {
+		if s.nameToModule[alias] == m {
+			delete(s.nameToModule, alias)
+		}
+	}
+	m.aliases = nil
+
 	if m.ModuleName != "" {
 		delete(s.nameToModule, m.ModuleName)
 
@@ -87,6 +100,82 @@ func (s *Store) registerModule(m *ModuleInstance) error {
 	return nil
 }
 
+// AliasModule makes the module of the given name importable by `alias`
+// too, until it is closed. An error is returned if the module isn't
+// instantiated, or `alias` is already the name of a module.
+func (s *Store) AliasModule(moduleName, alias string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.nameToModule == nil {
+		return errors.New("already closed")
+	}
+
+	m, ok := s.nameToModule[moduleName]
+	if !ok {
+		return fmt.Errorf("module[%s] not instantiated", moduleName)
+	}
+	if _, ok = s.nameToModule[alias]; ok {
+		return fmt.Errorf("module[%s] has already been instantiated", alias)
+	}
+	s.nameToModule[alias] = m
+	if len(s.nameToModule) > s.nameToModuleCap {
+		s.nameToModuleCap = len(s.nameToModule)
+	}
+	m.aliases = append(m.aliases, alias)
+	return nil
+}
+
+// DetachModule removes the module from the store, without closing it, so
+// that its name is available for instantiation again. Modules which already
+// imported it are unaffected. Close it with ModuleInstance.CloseDetached.
+func (s *Store) DetachModule(m *ModuleInstance) error {
+	return s.deleteModule(m)
+}
+
+// ErrMaxInstancesExceeded is returned by Store.Instantiate when
+// Store.MaxInstances is reached.
+var ErrMaxInstancesExceeded = errors.New("maximum count of module instances exceeded")
+
+// reserveInstance counts the module in activeInstances, or returns an error
+// if it would exceed MaxInstances.
+func (s *Store) reserveInstance(ctx context.Context, module *Module, name string) error {
+	if s.MaxInstances == 0 || module.IsHostModule {
+		return nil
+	}
+
+	s.mux.Lock()
+	exceeded := s.activeInstances >= s.MaxInstances
+	if !exceeded {
+		s.activeInstances++
+	}
+	s.mux.Unlock()
+
+	if !exceeded {
+		return nil
+	}
+	if s.Metrics != nil {
+		s.Metrics.RejectedInstantiations.Add(1)
+	}
+	if f := s.OnMaxInstancesExceeded; f != nil {
+		f(ctx, name)
+	}
+	return fmt.Errorf("module[%s]: %w", name, ErrMaxInstancesExceeded)
+}
+
+// releaseInstance reverts reserveInstance.
+func (s *Store) releaseInstance(module *Module) {
+	s.mux.Lock()
+	s.releaseInstanceLocked(module)
+	s.mux.Unlock()
+}
+
+func (s *Store) releaseInstanceLocked(module *Module) {
+	if s.MaxInstances != 0 && module != nil && !module.IsHostModule {
+		s.activeInstances--
+	}
+}
+
 // Module implements wazero.Runtime Module
 func (s *Store) Module(moduleName string) api.Module {
 	m, err := s.module(moduleName)
@@ -84,6 +84,49 @@ func TestStore_deleteModule(t *testing.T) {
 	})
 }
 
+func TestStore_AliasModule(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		s, m1, m2 := newTestStore()
+
+		require.NoError(t, s.AliasModule(m1.ModuleName, "alias"))
+		require.Equal(t, m1, s.nameToModule["alias"])
+		require.Equal(t, []string{"alias"}, m1.aliases)
+
+		// Deleting the module removes its aliases.
+		require.NoError(t, s.deleteModule(m1))
+		require.Equal(t, map[string]*ModuleInstance{m2.ModuleName: m2}, s.nameToModule)
+		require.Nil(t, m1.aliases)
+	})
+	t.Run("not instantiated", func(t *testing.T) {
+		s, _, _ := newTestStore()
+		require.EqualError(t, s.AliasModule("unknown", "alias"), "module[unknown] not instantiated")
+	})
+	t.Run("alias taken", func(t *testing.T) {
+		s, m1, m2 := newTestStore()
+		require.EqualError(t, s.AliasModule(m1.ModuleName, m2.ModuleName), "module[m2] has already been instantiated")
+	})
+	t.Run("closed", func(t *testing.T) {
+		s, m1, _ := newTestStore()
+		require.NoError(t, s.CloseWithExitCode(testCtx, 0))
+		require.EqualError(t, s.AliasModule(m1.ModuleName, "alias"), "already closed")
+	})
+}
+
+func TestStore_DetachModule(t *testing.T) {
+	s, m1, m2 := newTestStore()
+
+	require.NoError(t, s.DetachModule(m1))
+	require.Equal(t, map[string]*ModuleInstance{m2.ModuleName: m2}, s.nameToModule)
+	require.Equal(t, m2, s.moduleList)
+	require.Nil(t, m2.next)
+	require.False(t, m1.IsClosed())
+
+	// Closing the store doesn't close detached modules.
+	require.NoError(t, s.CloseWithExitCode(testCtx, 0))
+	require.False(t, m1.IsClosed())
+	require.True(t, m2.IsClosed())
+}
+
 func TestStore_module(t *testing.T) {
 	s, m1, _ := newTestStore()
 
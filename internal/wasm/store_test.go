@@ -299,6 +299,23 @@ func TestStore_hammer_close(t *testing.T) {
 	require.Nil(t, s.moduleList)
 }
 
+func TestStore_Drain(t *testing.T) {
+	s := newStore()
+	require.NoError(t, s.Drain(context.Background()))
+
+	s.BeginCall()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, s.Drain(ctx))
+
+	drained := make(chan error)
+	go func() { drained <- s.Drain(context.Background()) }()
+	s.BeginCall() // a nested call
+	s.EndCall()
+	s.EndCall()
+	require.NoError(t, <-drained)
+}
+
 func TestStore_Instantiate_Errors(t *testing.T) {
 	const importedModuleName = "imported"
 	const importingModuleName = "test"
@@ -506,6 +523,11 @@ func (ce *mockCallEngine) Call(ctx context.Context, _ ...uint64) (results []uint
 	return nil, ce.CallWithStack(ctx, nil)
 }
 
+// CallWithOptions implements the same method as documented on api.Function.
+func (ce *mockCallEngine) CallWithOptions(ctx context.Context, _ api.CallOptions, params ...uint64) ([]uint64, error) {
+	return ce.Call(ctx, params...)
+}
+
 // CallWithStack implements the same method as documented on api.Function.
 func (ce *mockCallEngine) CallWithStack(_ context.Context, _ []uint64) error {
 	if ce.callFailIndex >= 0 && ce.index == Index(ce.callFailIndex) {
@@ -627,6 +649,56 @@ func TestGlobalInstance_initialize(t *testing.T) {
 		)
 		require.Equal(t, uint64(0xdeadbeaf), g.Val)
 	})
+	t.Run("extended", func(t *testing.T) {
+		importedGlobals := []*GlobalInstance{
+			{Type: GlobalType{ValType: ValueTypeI32}, Val: 0xffff_ffff_0000_0010}, // High bits are ignored.
+			{Type: GlobalType{ValType: ValueTypeI64}, Val: 1 << 40},
+		}
+		tests := []struct {
+			name     string
+			valType  ValueType
+			data     []byte
+			expected uint64
+		}{
+			{
+				name:     "i32.add",
+				valType:  ValueTypeI32,
+				data:     []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 2, OpcodeI32Add},
+				expected: 18,
+			},
+			{
+				name:     "i32.sub wraps",
+				valType:  ValueTypeI32,
+				data:     []byte{OpcodeI32Const, 2, OpcodeGlobalGet, 0, OpcodeI32Sub},
+				expected: uint64(uint32(0xffff_fff2)), // -14
+			},
+			{
+				name:     "i32.mul",
+				valType:  ValueTypeI32,
+				data:     []byte{OpcodeGlobalGet, 0, OpcodeI32Const, 3, OpcodeI32Mul, OpcodeI32Const, 1, OpcodeI32Add},
+				expected: 49,
+			},
+			{
+				name:     "i64",
+				valType:  ValueTypeI64,
+				data:     []byte{OpcodeGlobalGet, 1, OpcodeI64Const, 2, OpcodeI64Mul, OpcodeI64Const, 1, OpcodeI64Sub},
+				expected: 1<<41 - 1,
+			},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				g := GlobalInstance{Type: GlobalType{ValType: tc.valType}}
+				expr := &ConstantExpression{Opcode: tc.data[len(tc.data)-1], Data: tc.data}
+				g.initialize(importedGlobals, expr, nil)
+				require.Equal(t, tc.expected, g.Val)
+				if tc.valType == ValueTypeI32 {
+					require.Equal(t, int32(tc.expected), executeConstExpressionI32(importedGlobals, expr))
+				}
+			})
+		}
+	})
 	t.Run("global expr", func(t *testing.T) {
 		tests := []struct {
 			valueType  ValueType
@@ -688,15 +760,26 @@ func Test_resolveImports(t *testing.T) {
 
 	t.Run("module not instantiated", func(t *testing.T) {
 		m := &ModuleInstance{s: newStore()}
-		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{"unknown": {{}}}})
+		err := m.resolveImports(testCtx, &Module{ImportPerModule: map[string][]*Import{"unknown": {{}}}})
 		require.EqualError(t, err, "module[unknown] not instantiated")
 	})
 	t.Run("export instance not found", func(t *testing.T) {
 		m := &ModuleInstance{s: newStore()}
 		m.s.nameToModule[moduleName] = &ModuleInstance{Exports: map[string]*Export{}, ModuleName: moduleName}
-		err := m.resolveImports(&Module{ImportPerModule: map[string][]*Import{moduleName: {{Name: "unknown"}}}})
+		err := m.resolveImports(testCtx, &Module{ImportPerModule: map[string][]*Import{moduleName: {{Name: "unknown"}}}})
 		require.EqualError(t, err, "\"unknown\" is not exported in module \"test\"")
 	})
+	t.Run("fallback", func(t *testing.T) {
+		m := &ModuleInstance{s: newStore(), Globals: make([]*GlobalInstance, 1)}
+		m.s.nameToModule[moduleName] = &ModuleInstance{Exports: map[string]*Export{}, ModuleName: moduleName}
+		fallback := &ModuleInstance{Exports: map[string]*Export{"unknown": {Type: ExternTypeGlobal}}, Globals: []*GlobalInstance{{}}}
+		ctx := context.WithValue(testCtx, ImportFallbacksKey{}, map[string]*ModuleInstance{moduleName: fallback})
+		err := m.resolveImports(ctx, &Module{ImportPerModule: map[string][]*Import{
+			moduleName: {{Name: "unknown", Type: ExternTypeGlobal, DescGlobal: GlobalType{}}},
+		}})
+		require.NoError(t, err)
+		require.Same(t, fallback.Globals[0], m.Globals[0])
+	})
 	t.Run("func", func(t *testing.T) {
 		t.Run("ok", func(t *testing.T) {
 			s := newStore()
@@ -730,7 +813,7 @@ func Test_resolveImports(t *testing.T) {
 			}
 
 			m := &ModuleInstance{Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}, s: s, Source: module}
-			err := m.resolveImports(module)
+			err := m.resolveImports(testCtx, module)
 			require.NoError(t, err)
 
 			me := m.Engine.(*mockModuleEngine)
@@ -760,7 +843,7 @@ func Test_resolveImports(t *testing.T) {
 			}
 
 			m := &ModuleInstance{Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}, s: s, Source: module}
-			err := m.resolveImports(module)
+			err := m.resolveImports(testCtx, module)
 			require.EqualError(t, err, "import func[test.target]: signature mismatch: v_f32 != v_v")
 		})
 	})
@@ -773,7 +856,7 @@ func Test_resolveImports(t *testing.T) {
 				Globals: []*GlobalInstance{g},
 				Exports: map[string]*Export{name: {Type: ExternTypeGlobal, Index: 0}}, ModuleName: moduleName,
 			}
-			err := m.resolveImports(
+			err := m.resolveImports(testCtx,
 				&Module{
 					ImportPerModule: map[string][]*Import{moduleName: {{Name: name, Type: ExternTypeGlobal, DescGlobal: g.Type}}},
 				},
@@ -792,7 +875,7 @@ func Test_resolveImports(t *testing.T) {
 				ModuleName: moduleName,
 			}
 			m := &ModuleInstance{Globals: make([]*GlobalInstance, 1), s: s}
-			err := m.resolveImports(&Module{
+			err := m.resolveImports(testCtx, &Module{
 				ImportPerModule: map[string][]*Import{moduleName: {
 					{Module: moduleName, Name: name, Type: ExternTypeGlobal, DescGlobal: GlobalType{Mutable: true}},
 				}},
@@ -810,7 +893,7 @@ func Test_resolveImports(t *testing.T) {
 				ModuleName: moduleName,
 			}
 			m := &ModuleInstance{Globals: make([]*GlobalInstance, 1), s: s}
-			err := m.resolveImports(&Module{
+			err := m.resolveImports(testCtx, &Module{
 				ImportPerModule: map[string][]*Import{moduleName: {
 					{Module: moduleName, Name: name, Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeF64}},
 				}},
@@ -833,7 +916,7 @@ func Test_resolveImports(t *testing.T) {
 				Engine:     importedME,
 			}
 			m := &ModuleInstance{s: s, Engine: &mockModuleEngine{resolveImportsCalled: map[Index]Index{}}}
-			err := m.resolveImports(&Module{
+			err := m.resolveImports(testCtx, &Module{
 				ImportPerModule: map[string][]*Import{
 					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: &Memory{Max: max}}},
 				},
@@ -853,7 +936,7 @@ func Test_resolveImports(t *testing.T) {
 				ModuleName: moduleName,
 			}
 			m := &ModuleInstance{s: s}
-			err := m.resolveImports(&Module{
+			err := m.resolveImports(testCtx, &Module{
 				ImportPerModule: map[string][]*Import{
 					moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}},
 				},
@@ -873,7 +956,7 @@ func Test_resolveImports(t *testing.T) {
 			max := uint32(10)
 			importMemoryType := &Memory{Max: max}
 			m := &ModuleInstance{s: s}
-			err := m.resolveImports(&Module{
+			err := m.resolveImports(testCtx, &Module{
 				ImportPerModule: map[string][]*Import{moduleName: {{Module: moduleName, Name: name, Type: ExternTypeMemory, DescMem: importMemoryType}}},
 			})
 			require.EqualError(t, err, "import memory[test.target]: maximum size mismatch: 10 < 65536")
@@ -1007,3 +1090,96 @@ func TestModuleInstance_applyElements(t *testing.T) {
 			m.Tables[0].References)
 	})
 }
+
+func TestStore_Instantiate_lazyElements(t *testing.T) {
+	newModule := func(lazy bool, start *Index) *Module {
+		return &Module{
+			TypeSection:     []FunctionType{v_v},
+			FunctionSection: []Index{0},
+			CodeSection:     []Code{{Body: []byte{OpcodeEnd}}},
+			TableSection:    []Table{{Min: 2, Type: RefTypeFuncref}},
+			ElementSection: []ElementSegment{
+				{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: []byte{1}}, Init: []Index{0}, Type: RefTypeFuncref},
+			},
+			Exports:      map[string]*Export{"fn": {Type: ExternTypeFunc, Name: "fn", Index: 0}},
+			StartSection: start,
+			lazyElements: lazy,
+		}
+	}
+	// instantiate sets the function references after instantiation, so that
+	// the tables only contain them if the elements were deferred.
+	instantiate := func(t *testing.T, s *Store, module *Module, name string) *ModuleInstance {
+		m, err := s.Instantiate(testCtx, module, name, nil, []FunctionTypeID{0})
+		require.NoError(t, err)
+		m.Engine.(*mockModuleEngine).functionRefs = map[Index]Reference{0: 0xa}
+		return m
+	}
+
+	t.Run("deferred until ExportedFunction", func(t *testing.T) {
+		m := instantiate(t, newStore(), newModule(true, nil), "")
+		require.Equal(t, []Reference{0, 0}, m.Tables[0].References)
+
+		require.NotNil(t, m.ExportedFunction("fn"))
+		require.Equal(t, []Reference{0, 0xa}, m.Tables[0].References)
+	})
+	t.Run("deferred until LookupFunction", func(t *testing.T) {
+		m := instantiate(t, newStore(), newModule(true, nil), "")
+		m.Engine.(*mockModuleEngine).lookupEntries = map[Index]mockModuleEngineLookupEntry{1: {m: m, index: 0}}
+		require.Equal(t, []Reference{0, 0}, m.Tables[0].References)
+
+		require.NotNil(t, m.LookupFunction(m.Tables[0], 0, 1))
+		require.Equal(t, []Reference{0, 0xa}, m.Tables[0].References)
+	})
+	t.Run("deferred until imported", func(t *testing.T) {
+		s := newStore()
+		m := instantiate(t, s, newModule(true, nil), "imported")
+		require.Equal(t, []Reference{0, 0}, m.Tables[0].References)
+
+		_, err := s.Instantiate(testCtx, &Module{
+			ImportFunctionCount: 1,
+			TypeSection:         []FunctionType{v_v},
+			ImportSection:       []Import{{Type: ExternTypeFunc, Module: "imported", Name: "fn", DescFunc: 0}},
+			ImportPerModule: map[string][]*Import{
+				"imported": {{Type: ExternTypeFunc, Module: "imported", Name: "fn", DescFunc: 0}},
+			},
+		}, "importing", nil, []FunctionTypeID{0})
+		require.NoError(t, err)
+		require.Equal(t, []Reference{0, 0xa}, m.Tables[0].References)
+	})
+	t.Run("eager with start function", func(t *testing.T) {
+		start := Index(0)
+		m := instantiate(t, newStore(), newModule(true, &start), "")
+		require.NotNil(t, m.ExportedFunction("fn"))
+		// The element was applied before the references were set.
+		require.Equal(t, []Reference{0, 0}, m.Tables[0].References)
+	})
+	t.Run("eager unless validated", func(t *testing.T) {
+		m := instantiate(t, newStore(), newModule(false, nil), "")
+		require.NotNil(t, m.ExportedFunction("fn"))
+		require.Equal(t, []Reference{0, 0}, m.Tables[0].References)
+	})
+}
+
+func TestModuleInstance_buildElementInstances(t *testing.T) {
+	e := &mockEngine{}
+	me, err := e.NewModuleEngine(nil, nil)
+	require.NoError(t, err)
+	me.(*mockModuleEngine).functionRefs = map[Index]Reference{0: 0xa, 1: 0xaa}
+	m := &ModuleInstance{Engine: me}
+
+	m.buildElementInstances([]ElementSegment{
+		{Mode: ElementModePassive, Type: RefTypeFuncref, Init: []Index{0, ElementInitNullReference}},
+		{Mode: ElementModeActive, Type: RefTypeFuncref, Init: []Index{0}}, // not an element instance
+		{Mode: ElementModePassive, Type: RefTypeFuncref, Init: []Index{1}},
+	})
+	require.Equal(t, []ElementInstance{
+		{References: []Reference{0xa, 0}, Type: RefTypeFuncref},
+		{},
+		{References: []Reference{0xaa}, Type: RefTypeFuncref},
+	}, m.ElementInstances)
+
+	// References of each instance are allocated together, but appending to
+	// one must not overwrite the next.
+	_ = append(m.ElementInstances[0].References, 0xffff)
+	require.Equal(t, []Reference{0xaa}, m.ElementInstances[2].References)
+}
@@ -157,6 +157,8 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 	funcCount := m.ImportFunctionCount + m.SectionElementCount(SectionIDFunction)
 	globalsCount := m.ImportGlobalCount + m.SectionElementCount(SectionIDGlobal)
 
+	lazyElements := true
+
 	// Now, we have to figure out which table elements can be resolved before instantiation and also fail early if there
 	// are any imported globals that are known to be invalid by their declarations.
 	for i := range m.ElementSection {
@@ -172,6 +174,7 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 				}
 				index, ok := unwrapElementInitGlobalReference(init)
 				if ok {
+					lazyElements = false
 					if index >= globalsCount {
 						return fmt.Errorf("%s[%d].init[%d] globalidx %d out of range", SectionIDName(SectionIDElement), idx, ei, index)
 					}
@@ -203,6 +206,9 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 
 			// global.get needs to be discovered during initialization
 			oc := elem.OffsetExpr.Opcode
+			if oc != OpcodeI32Const || elem.TableIndex < importedTableCount {
+				lazyElements = false
+			}
 			if oc == OpcodeGlobalGet {
 				globalIdx, _, err := leb128.LoadUint32(elem.OffsetExpr.Data)
 				if err != nil {
@@ -225,11 +231,16 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 						return err
 					}
 				}
+			} else if elem.OffsetExpr.IsExtended() {
+				if err := validateConstExpression(m.importedGlobalTypes(), 0, &elem.OffsetExpr, ValueTypeI32); err != nil {
+					return fmt.Errorf("%s[%d] has an invalid const expression: %w", SectionIDName(SectionIDElement), idx, err)
+				}
 			} else {
 				return fmt.Errorf("%s[%d] has an invalid const expression: %s", SectionIDName(SectionIDElement), idx, InstructionName(oc))
 			}
 		}
 	}
+	m.lazyElements = lazyElements
 	return nil
 }
 
@@ -242,13 +253,15 @@ func (m *Module) validateTable(enabledFeatures api.CoreFeatures, tables []Table,
 // Note: An error is only possible when an ElementSegment.OffsetExpr is out of range of the TableInstance.Min.
 func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err error) {
 	idx := module.ImportTableCount
+	// Allocate all tables at once, similar to globals in buildGlobals.
+	tables := m.arena.allocTables(len(module.TableSection))
 	for i := range module.TableSection {
 		tsec := &module.TableSection[i]
 		// The module defining the table is the one that sets its Min/Max etc.
-		m.Tables[idx] = &TableInstance{
-			References: make([]Reference, tsec.Min), Min: tsec.Min, Max: tsec.Max,
-			Type: tsec.Type,
-		}
+		table := &tables[i]
+		table.References = reuseReferences(table.References, int(tsec.Min))
+		table.Min, table.Max, table.Type = tsec.Min, tsec.Max, tsec.Type
+		m.Tables[idx] = table
 		idx++
 	}
 
@@ -256,17 +269,7 @@ func (m *ModuleInstance) buildTables(module *Module, skipBoundCheck bool) (err e
 		for elemI := range module.ElementSection { // Do not loop over the value since elementSegments is a slice of value.
 			elem := &module.ElementSection[elemI]
 			table := m.Tables[elem.TableIndex]
-			var offset uint32
-			if elem.OffsetExpr.Opcode == OpcodeGlobalGet {
-				// Ignore error as it's already validated.
-				globalIdx, _, _ := leb128.LoadUint32(elem.OffsetExpr.Data)
-				global := m.Globals[globalIdx]
-				offset = uint32(global.Val)
-			} else { // i32.const
-				// Ignore error as it's already validated.
-				o, _, _ := leb128.LoadInt32(elem.OffsetExpr.Data)
-				offset = uint32(o)
-			}
+			offset := uint32(executeConstExpressionI32(m.Globals, &elem.OffsetExpr))
 
 			// Check to see if we are out-of-bounds
 			initCount := uint64(len(elem.Init))
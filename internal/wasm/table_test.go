@@ -29,7 +29,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ModuleName: moduleName,
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
-		err := m.resolveImports(&Module{
+		err := m.resolveImports(testCtx, &Module{
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: Table{Max: &max}}},
 			},
@@ -46,7 +46,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ModuleName: moduleName,
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
-		err := m.resolveImports(&Module{
+		err := m.resolveImports(testCtx, &Module{
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: importTableType}},
 			},
@@ -63,7 +63,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ModuleName: moduleName,
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
-		err := m.resolveImports(&Module{
+		err := m.resolveImports(testCtx, &Module{
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: importTableType}},
 			},
@@ -78,7 +78,7 @@ func Test_resolveImports_table(t *testing.T) {
 			ModuleName: moduleName,
 		}
 		m := &ModuleInstance{Tables: make([]*TableInstance, 1), s: s}
-		err := m.resolveImports(&Module{
+		err := m.resolveImports(testCtx, &Module{
 			ImportPerModule: map[string][]*Import{
 				moduleName: {{Module: moduleName, Name: name, Type: ExternTypeTable, DescTable: Table{Type: RefTypeExternref}}},
 			},
@@ -330,6 +330,90 @@ func TestModule_validateTable(t *testing.T) {
 	}
 }
 
+func TestModule_validateTable_lazyElements(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    *Module
+		expected bool
+	}{
+		{
+			name:     "no elements",
+			input:    &Module{},
+			expected: true,
+		},
+		{
+			name: "constant offset",
+			input: &Module{
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []Index{0}, Type: RefTypeFuncref},
+				},
+			},
+			expected: true,
+		},
+		{
+			name: "global offset",
+			input: &Module{
+				ImportGlobalCount: 1,
+				ImportSection: []Import{
+					{Type: ExternTypeGlobal, DescGlobal: GlobalType{ValType: ValueTypeI32}},
+				},
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeGlobalGet, Data: []byte{0x0}}, Init: []Index{0}, Type: RefTypeFuncref},
+				},
+			},
+		},
+		{
+			name: "global reference",
+			input: &Module{
+				GlobalSection:   []Global{{Type: GlobalType{ValType: ValueTypeFuncref}, Init: ConstantExpression{Opcode: OpcodeRefNull, Data: []byte{RefTypeFuncref}}}},
+				TypeSection:     []FunctionType{{}},
+				TableSection:    []Table{{Min: 1, Type: RefTypeFuncref}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []Index{0 | ElementInitImportedGlobalFunctionReference}, Type: RefTypeFuncref},
+				},
+			},
+		},
+		{
+			name: "imported table",
+			input: &Module{
+				ImportTableCount: 1,
+				ImportSection: []Import{
+					{Type: ExternTypeTable, DescTable: Table{Min: 1, Type: RefTypeFuncref}},
+				},
+				TypeSection:     []FunctionType{{}},
+				FunctionSection: []Index{0},
+				CodeSection:     []Code{codeEnd},
+				ElementSection: []ElementSegment{
+					{Mode: ElementModeActive, OffsetExpr: ConstantExpression{Opcode: OpcodeI32Const, Data: const0}, Init: []Index{0}, Type: RefTypeFuncref},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, _, tables, err := tc.input.AllDeclarations()
+			require.NoError(t, err)
+
+			err = tc.input.validateTable(api.CoreFeaturesV2, tables, 5)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, tc.input.lazyElements)
+		})
+	}
+}
+
 func TestModule_validateTable_Errors(t *testing.T) {
 	const maxTableIndex = 5
 	tests := []struct {
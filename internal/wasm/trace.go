@@ -0,0 +1,54 @@
+package wasm
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/trace"
+)
+
+// tracedFunction annotates the execution trace with a region for each call of
+// an exported function. See experimental.WithTrace.
+type tracedFunction struct {
+	api.Function
+	// regionType is the precomputed type of the regions of this function.
+	regionType string
+}
+
+func newTracedFunction(f api.Function, moduleName, exportName string) *tracedFunction {
+	return &tracedFunction{Function: f, regionType: "wazero.Call " + moduleName + "." + exportName}
+}
+
+// Call implements the same method as documented on api.Function.
+func (f *tracedFunction) Call(ctx context.Context, params ...uint64) ([]uint64, error) {
+	if trace.IsEnabled() {
+		defer trace.StartRegion(ctx, f.regionType).End()
+	}
+	return f.Function.Call(ctx, params...)
+}
+
+// CallWithStack implements the same method as documented on api.Function.
+func (f *tracedFunction) CallWithStack(ctx context.Context, stack []uint64) error {
+	if trace.IsEnabled() {
+		defer trace.StartRegion(ctx, f.regionType).End()
+	}
+	return f.Function.CallWithStack(ctx, stack)
+}
+
+// CallWithOptions implements the same method as documented on api.Function.
+func (f *tracedFunction) CallWithOptions(ctx context.Context, opts api.CallOptions, params ...uint64) ([]uint64, error) {
+	if trace.IsEnabled() {
+		defer trace.StartRegion(ctx, f.regionType).End()
+	}
+	return f.Function.CallWithOptions(ctx, opts, params...)
+}
+
+// SourceOffsetForPC implements the same method as documented on
+// experimental.InternalFunction.
+func (f *tracedFunction) SourceOffsetForPC(pc experimental.ProgramCounter) uint64 {
+	if fn, ok := f.Function.(experimental.InternalFunction); ok {
+		return fn.SourceOffsetForPC(pc)
+	}
+	return 0
+}
@@ -0,0 +1,22 @@
+package wasm
+
+import "runtime"
+
+// checkpointsPerYield is the number of checkpoints between two yields to the Go scheduler.
+//
+// Checkpoints are where the compiled functions check the exit code, which is done in Go.
+// Yielding on each of them would be expensive, while yielding periodically bounds the time the
+// other goroutines, including the ones stopping the world for the GC, wait for a long-running loop.
+const checkpointsPerYield = 1 << 10
+
+// YieldCounter counts the checkpoints reached by a call engine, and periodically yields to the Go scheduler.
+// See wazero.RuntimeConfig WithCooperativeYield.
+type YieldCounter uint32
+
+// Checkpoint is called when the compiled function reaches a checkpoint.
+func (c *YieldCounter) Checkpoint() {
+	*c++
+	if *c%checkpointsPerYield == 0 {
+		runtime.Gosched()
+	}
+}
@@ -0,0 +1,20 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestYieldCounter_Checkpoint(t *testing.T) {
+	var c YieldCounter
+	for i := 0; i < checkpointsPerYield*2+1; i++ {
+		c.Checkpoint()
+	}
+	require.Equal(t, YieldCounter(checkpointsPerYield*2+1), c)
+
+	// Wraps around without panicking.
+	c = ^YieldCounter(0)
+	c.Checkpoint()
+	require.Equal(t, YieldCounter(0), c)
+}
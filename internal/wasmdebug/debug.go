@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -130,6 +131,8 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 	// If the error was internal, don't mention it was recovered.
 	if wasmErr, ok := recovered.(*wasmruntime.Error); ok {
 		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", wasmErr, stack)
+	} else if oobErr, ok := recovered.(*wasmruntime.OutOfBoundsMemoryAccessError); ok {
+		return fmt.Errorf("wasm error: %w\nwasm stack trace:\n\t%s", oobErr, stack)
 	}
 
 	// If we have a runtime.Error, something severe happened which should include the stack trace. This could be
@@ -147,6 +150,18 @@ func (s *stackTrace) FromRecovered(recovered interface{}) error {
 	}
 }
 
+// IsPanic returns true if the value recovered from a call is an unexpected
+// panic, e.g. a Go runtime error or panic("BUG"), which engines return as an
+// experimental.PanicError. An error, such as a trap, a sys.ExitError or an
+// error a host function intentionally panicked with, isn't.
+func IsPanic(recovered interface{}) bool {
+	if _, ok := recovered.(runtime.Error); ok {
+		return true
+	}
+	_, ok := recovered.(error)
+	return !ok
+}
+
 // AddFrame implements ErrorBuilder.AddFrame
 func (s *stackTrace) AddFrame(funcName string, paramTypes, resultTypes []api.ValueType, sources []string) {
 	sig := signature(funcName, paramTypes, resultTypes)
@@ -155,3 +170,29 @@ func (s *stackTrace) AddFrame(funcName string, paramTypes, resultTypes []api.Val
 		s.frames = append(s.frames, "\t"+source)
 	}
 }
+
+// WithLabels appends the labels of the module instance to an error built by
+// ErrorBuilder, sorted by key. The error is returned as is when there are no
+// labels or it is a sys.ExitError.
+func WithLabels(err error, labels map[string]string) error {
+	if len(labels) == 0 {
+		return err
+	} else if _, ok := err.(*sys.ExitError); ok {
+		return err
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var ret strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			ret.WriteString(", ")
+		}
+		ret.WriteString(k)
+		ret.WriteByte('=')
+		ret.WriteString(labels[k])
+	}
+	return fmt.Errorf("%w\nwasm module labels: %s", err, ret.String())
+}
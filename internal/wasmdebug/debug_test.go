@@ -8,6 +8,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/sys"
 )
 
 func TestFuncName(t *testing.T) {
@@ -147,6 +148,28 @@ wasm stack trace:
 	require.Contains(t, errStr, "wazero/internal/wasmdebug/debug_test.go")
 }
 
+func TestIsPanic(t *testing.T) {
+	tests := []struct {
+		name      string
+		recovered interface{}
+		expected  bool
+	}{
+		{name: "runtime.Error", recovered: rteErr, expected: true},
+		{name: "string", recovered: "BUG", expected: true},
+		{name: "int", recovered: 1, expected: true},
+		{name: "error", recovered: errors.New("boom")},
+		{name: "trap", recovered: wasmruntime.ErrRuntimeUnreachable},
+		{name: "exit", recovered: sys.NewExitError(1)},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, IsPanic(tc.recovered))
+		})
+	}
+}
+
 // compile-time check to ensure testRuntimeErr implements runtime.Error.
 var _ runtime.Error = testRuntimeErr("")
 
@@ -157,3 +180,16 @@ func (e testRuntimeErr) RuntimeError() {}
 func (e testRuntimeErr) Error() string {
 	return string(e)
 }
+
+func TestWithLabels(t *testing.T) {
+	err := errors.New("wasm error: unreachable")
+
+	require.Equal(t, err, WithLabels(err, nil))
+
+	exitErr := sys.NewExitError(1)
+	require.Equal(t, exitErr, WithLabels(exitErr, map[string]string{"a": "b"}))
+
+	labeled := WithLabels(err, map[string]string{"tenant": "acme", "request": "1"})
+	require.ErrorIs(t, labeled, err)
+	require.EqualError(t, labeled, "wasm error: unreachable\nwasm module labels: request=1, tenant=acme")
+}
@@ -27,6 +27,15 @@ var (
 	ErrRuntimeInvalidTableAccess = New("invalid table access")
 	// ErrRuntimeIndirectCallTypeMismatch indicates that the type check failed during call_indirect.
 	ErrRuntimeIndirectCallTypeMismatch = New("indirect call type mismatch")
+	// ErrRuntimeMemoryGrowthRateExceeded indicates that the program grew its
+	// memory faster than allowed by experimental.MemoryGrowthWatchdog.
+	ErrRuntimeMemoryGrowthRateExceeded = New("memory growth rate exceeded")
+	// ErrRuntimeUnalignedAtomic indicates that an atomic instruction accessed
+	// an address which isn't a multiple of the size of the access.
+	ErrRuntimeUnalignedAtomic = New("unaligned atomic")
+	// ErrRuntimeExpectedSharedMemory indicates that memory.atomic.wait32 or
+	// memory.atomic.wait64 was executed on a memory which isn't shared.
+	ErrRuntimeExpectedSharedMemory = New("expected shared memory")
 )
 
 // Error is returned by a wasm.Engine during the execution of Wasm functions, and they indicate that the Wasm runtime
@@ -42,3 +51,27 @@ func New(text string) *Error {
 func (e *Error) Error() string {
 	return e.s
 }
+
+// OutOfBoundsMemoryAccessError is ErrRuntimeOutOfBoundsMemoryAccess, with the
+// address of the access. It is returned by engines which know the address.
+type OutOfBoundsMemoryAccessError struct {
+	// Address is the effective address of the access, i.e. including the
+	// static offset of the instruction.
+	Address uint64
+}
+
+// NewOutOfBoundsMemoryAccessError returns an OutOfBoundsMemoryAccessError for
+// the effective address `address`.
+func NewOutOfBoundsMemoryAccessError(address uint64) *OutOfBoundsMemoryAccessError {
+	return &OutOfBoundsMemoryAccessError{Address: address}
+}
+
+// Error returns the same as ErrRuntimeOutOfBoundsMemoryAccess.
+func (e *OutOfBoundsMemoryAccessError) Error() string {
+	return ErrRuntimeOutOfBoundsMemoryAccess.Error()
+}
+
+// Is returns true if `target` is ErrRuntimeOutOfBoundsMemoryAccess.
+func (e *OutOfBoundsMemoryAccessError) Is(target error) bool {
+	return target == ErrRuntimeOutOfBoundsMemoryAccess
+}
@@ -182,12 +182,16 @@ type Compiler struct {
 	funcs []uint32
 	// globals holds the global types for all declared globals in the module where the target function exists.
 	globals []wasm.GlobalType
+	// memoryPageSizeInBits is the log2 of the page size of the memory in the module where the target function exists.
+	memoryPageSizeInBits uint32
 
 	// needSourceOffset is true if this module requires DWARF based stack trace.
 	needSourceOffset bool
 	// bodyOffsetInCodeSection is the offset of the body of this function in the original Wasm binary's code section.
 	bodyOffsetInCodeSection uint64
 
+	// ensureTermination is true if the exit code checks, which also serve as the cooperative yield points, are
+	// inserted at loop headers.
 	ensureTermination bool
 	// Pre-allocated bytes.Reader to be used in various places.
 	br             *bytes.Reader
@@ -248,6 +252,8 @@ type CompilationResult struct {
 	Types []wasm.FunctionType
 	// HasMemory is true if the module from which this function is compiled has memory declaration.
 	HasMemory bool
+	// HasSharedMemory is true if the memory is shared, so may be grown concurrently by other goroutines.
+	HasSharedMemory bool
 	// HasTable is true if the module from which this function is compiled has table declaration.
 	HasTable bool
 	// HasDataInstances is true if the module has data instances which might be used by memory.init or data.drop instructions.
@@ -279,6 +285,7 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 			Functions:           functions,
 			Types:               types,
 			HasMemory:           hasMemory,
+			HasSharedMemory:     hasMemory && mem.IsShared,
 			HasTable:            hasTable,
 			HasDataInstances:    hasDataInstances,
 			HasElementInstances: hasElementInstances,
@@ -287,20 +294,28 @@ func NewCompiler(enabledFeatures api.CoreFeatures, callFrameStackSizeInUint64 in
 		globals:           globals,
 		funcs:             functions,
 		types:             types,
-		ensureTermination: ensureTermination,
+		ensureTermination: ensureTermination || module.CooperativeYield,
 		br:                bytes.NewReader(nil),
 		funcTypeToSigs: funcTypeToIRSignatures{
 			indirectCalls: make([]*signature, len(types)),
 			directCalls:   make([]*signature, len(types)),
 			wasmTypes:     types,
 		},
-		needSourceOffset: module.DWARFLines != nil,
+		needSourceOffset:     module.DWARFLines != nil,
+		memoryPageSizeInBits: wasm.MemoryPageSizeInBits,
+	}
+	if mem != nil {
+		c.memoryPageSizeInBits = mem.PageSizeInBits()
 	}
 	return c, nil
 }
 
 // Next returns the next CompilationResult for this Compiler.
 func (c *Compiler) Next() (*CompilationResult, error) {
+	// Skip functions replaced by intrinsics, as engines call their GoFunc.
+	for c.module.CodeSection[c.next].GoFunc != nil {
+		c.next++
+	}
 	funcIndex := c.next
 	code := &c.module.CodeSection[funcIndex]
 	sig := &c.types[c.module.FunctionSection[funcIndex]]
@@ -1021,7 +1036,7 @@ operatorSwitch:
 		c.result.UsesMemory = true
 		c.pc++ // Skip the reserved one byte.
 		c.emit(
-			NewOperationMemorySize(),
+			NewOperationMemorySize(c.memoryPageSizeInBits),
 		)
 	case wasm.OpcodeMemoryGrow:
 		c.result.UsesMemory = true
@@ -1753,10 +1768,33 @@ operatorSwitch:
 			c.emit(
 				NewOperationTableFill(tableIndex),
 			)
+		case wasm.OpcodeMiscI64Add128:
+			c.emit(
+				NewOperationI64Add128(),
+			)
+		case wasm.OpcodeMiscI64Sub128:
+			c.emit(
+				NewOperationI64Sub128(),
+			)
+		case wasm.OpcodeMiscI64MulWideS:
+			c.emit(
+				NewOperationI64MulWide(true),
+			)
+		case wasm.OpcodeMiscI64MulWideU:
+			c.emit(
+				NewOperationI64MulWide(false),
+			)
 		default:
 			return fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
 	case wasm.OpcodeVecPrefix:
+		if relaxedOp, ok := wasm.LoadVecRelaxedOpcode(c.body[c.pc+1:]); ok {
+			c.pc += 2 // Relaxed vector opcodes are encoded with two bytes.
+			if err := c.emitVecRelaxed(relaxedOp); err != nil {
+				return err
+			}
+			break operatorSwitch
+		}
 		c.pc++
 		switch vecOp := c.body[c.pc]; vecOp {
 		case wasm.OpcodeVecV128Const:
@@ -2848,6 +2886,44 @@ operatorSwitch:
 		default:
 			return fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VectorInstructionName(vecOp))
 		}
+	case wasm.OpcodeAtomicPrefix:
+		c.pc++
+		atomicOp := c.body[c.pc]
+		if atomicOp == wasm.OpcodeAtomicFence {
+			c.pc++ // Skip the reserved byte.
+			c.emit(NewOperationAtomicFence())
+			break
+		}
+
+		arg, err := c.readMemoryArg(wasm.AtomicInstructionName(atomicOp))
+		if err != nil {
+			return err
+		}
+		switch {
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify:
+			c.emit(NewOperationAtomicMemoryNotify(arg))
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32:
+			c.emit(NewOperationAtomicMemoryWait(UnsignedTypeI32, 4, arg))
+		case atomicOp == wasm.OpcodeAtomicMemoryWait64:
+			c.emit(NewOperationAtomicMemoryWait(UnsignedTypeI64, 8, arg))
+		case wasm.OpcodeAtomicI32Load <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Load32U:
+			t, size := atomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32Load)
+			c.emit(NewOperationAtomicLoad(t, size, arg))
+		case wasm.OpcodeAtomicI32Store <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Store32:
+			t, size := atomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32Store)
+			c.emit(NewOperationAtomicStore(t, size, arg))
+		case wasm.OpcodeAtomicI32RmwAdd <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Rmw32XchgU:
+			// The read-modify-write instructions are grouped by operation,
+			// each having the seven types and sizes in the same order.
+			group := atomicOp - wasm.OpcodeAtomicI32RmwAdd
+			t, size := atomicAccessTypeAndSize(group % 7)
+			c.emit(NewOperationAtomicRMW(t, size, AtomicArithmeticOp(group/7), arg))
+		case wasm.OpcodeAtomicI32RmwCmpxchg <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Rmw32CmpxchgU:
+			t, size := atomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32RmwCmpxchg)
+			c.emit(NewOperationAtomicRMWCmpxchg(t, size, arg))
+		default:
+			return fmt.Errorf("unsupported atomic instruction in wazeroir: %s", wasm.AtomicInstructionName(atomicOp))
+		}
 	default:
 		return fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
@@ -2857,6 +2933,16 @@ operatorSwitch:
 	return nil
 }
 
+// atomicAccessTypeAndSize is like wasm.AtomicAccessTypeAndSize, but returns
+// the UnsignedType.
+func atomicAccessTypeAndSize(index wasm.OpcodeAtomic) (UnsignedType, byte) {
+	t, size := wasm.AtomicAccessTypeAndSize(index)
+	if t == wasm.ValueTypeI64 {
+		return UnsignedTypeI64, byte(size)
+	}
+	return UnsignedTypeI32, byte(size)
+}
+
 func (c *Compiler) nextFrameID() (id uint32) {
 	id = c.currentFrameID + 1
 	c.currentFrameID++
@@ -3066,3 +3152,104 @@ func (c *Compiler) readMemoryArg(tag string) (MemoryArg, error) {
 	c.pc += num
 	return MemoryArg{Offset: offset, Alignment: alignment}, nil
 }
+
+// emitVecRelaxed emits the operations of a relaxed vector instruction. Each
+// is implemented with the equivalent SIMD operations, which is one of the
+// results the relaxed-simd proposal allows, so that engines don't need to
+// implement them.
+//
+// Operations which reuse operands copy them to the top of the stack with
+// OperationKindPick, then replace the operands with the result. The lower
+// 64-bits of the n-th vector from the top of the stack is at the depth 2n+1.
+func (c *Compiler) emitVecRelaxed(op wasm.OpcodeVecRelaxed) error {
+	switch op {
+	case wasm.OpcodeVecI8x16RelaxedSwizzle:
+		c.emit(NewOperationV128Swizzle())
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4S:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF32x4, true))
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4U:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF32x4, false))
+	case wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF64x2, true))
+	case wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+		c.emit(NewOperationV128ITruncSatFromF(ShapeF64x2, false))
+	case wasm.OpcodeVecF32x4RelaxedMadd:
+		c.emitVecRelaxedMadd(ShapeF32x4, false)
+	case wasm.OpcodeVecF32x4RelaxedNmadd:
+		c.emitVecRelaxedMadd(ShapeF32x4, true)
+	case wasm.OpcodeVecF64x2RelaxedMadd:
+		c.emitVecRelaxedMadd(ShapeF64x2, false)
+	case wasm.OpcodeVecF64x2RelaxedNmadd:
+		c.emitVecRelaxedMadd(ShapeF64x2, true)
+	case wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+		wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect:
+		c.emit(NewOperationV128Bitselect())
+	case wasm.OpcodeVecF32x4RelaxedMin:
+		c.emit(NewOperationV128Min(ShapeF32x4, false))
+	case wasm.OpcodeVecF32x4RelaxedMax:
+		c.emit(NewOperationV128Max(ShapeF32x4, false))
+	case wasm.OpcodeVecF64x2RelaxedMin:
+		c.emit(NewOperationV128Min(ShapeF64x2, false))
+	case wasm.OpcodeVecF64x2RelaxedMax:
+		c.emit(NewOperationV128Max(ShapeF64x2, false))
+	case wasm.OpcodeVecI16x8RelaxedQ15mulrS:
+		c.emit(NewOperationV128Q15mulrSatS())
+	case wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+		c.emitVecRelaxedDot()
+	case wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+		// [a, b, c] -> [a, b, c, a, b] -> [a, b, c, dot(a, b)]
+		c.emit(NewOperationPick(5, true))
+		c.emit(NewOperationPick(5, true))
+		c.emitVecRelaxedDot()
+		// -> [a, b, c + extadd_pairwise(dot(a, b))] -> [result]
+		c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+		c.emit(NewOperationV128Add(ShapeI32x4))
+		c.emitVecRelaxedReplaceOperands()
+	default:
+		return fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VecRelaxedInstructionName(op))
+	}
+	return nil
+}
+
+// emitVecRelaxedMadd emits a*b+c, or -(a*b)+c when negate is true, as a
+// multiply then an add, without fusing them.
+func (c *Compiler) emitVecRelaxedMadd(shape Shape, negate bool) {
+	// [a, b, c] -> [a, b, c, a, b] -> [a, b, c, a*b]
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationV128Mul(shape))
+	// -> [a, b, c + a*b] or [a, b, c - a*b] -> [result]
+	if negate {
+		c.emit(NewOperationV128Sub(shape))
+	} else {
+		c.emit(NewOperationV128Add(shape))
+	}
+	c.emitVecRelaxedReplaceOperands()
+}
+
+// emitVecRelaxedDot emits the i16x8 dot product of the two vectors at the
+// top of the stack, interpreting both as signed. Each lane is the sum of
+// two adjacent products, which are computed as i32x4 then narrowed with
+// saturation, which is one of the results the proposal allows.
+func (c *Compiler) emitVecRelaxedDot() {
+	// [a, b] -> [a, b, a, b] -> [a, b, low]
+	c.emit(NewOperationPick(3, true))
+	c.emit(NewOperationPick(3, true))
+	c.emit(NewOperationV128ExtMul(ShapeI8x16, true, true))
+	c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+	// -> [a, b, low, a, b] -> [a, b, low, high]
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationPick(5, true))
+	c.emit(NewOperationV128ExtMul(ShapeI8x16, true, false))
+	c.emit(NewOperationV128ExtAddPairwise(ShapeI16x8, true))
+	// -> [a, b, narrow(low, high)] -> [result]
+	c.emit(NewOperationV128Narrow(ShapeI32x4, true))
+	c.emitVecRelaxedReplaceOperands()
+}
+
+// emitVecRelaxedReplaceOperands replaces the two operands below the result
+// with the result: [a, b, result] -> [result].
+func (c *Compiler) emitVecRelaxedReplaceOperands() {
+	c.emit(NewOperationSet(5, true))
+	c.emit(NewOperationDrop(InclusiveRange{Start: 0, End: 1}))
+}
@@ -3005,13 +3005,7 @@ func TestCompiler_initializeStack(t *testing.T) {
 }
 
 func Test_ensureTermination(t *testing.T) {
-	for _, tc := range []struct {
-		ensureTermination bool
-		exp               string
-	}{
-		{
-			ensureTermination: true,
-			exp: `.entrypoint
+	const withChecks = `.entrypoint
 	ConstI32 0x0
 	Br .L2
 .L2
@@ -3026,11 +3020,8 @@ func Test_ensureTermination(t *testing.T) {
 	i32.Add
 	Drop 0..0
 	Br .return
-`,
-		},
-		{
-			ensureTermination: false,
-			exp: `.entrypoint
+`
+	const withoutChecks = `.entrypoint
 	ConstI32 0x0
 	Br .L2
 .L2
@@ -3043,10 +3034,18 @@ func Test_ensureTermination(t *testing.T) {
 	i32.Add
 	Drop 0..0
 	Br .return
-`,
-		},
+`
+
+	for _, tc := range []struct {
+		ensureTermination, cooperativeYield bool
+		exp                                 string
+	}{
+		{ensureTermination: true, exp: withChecks},
+		{ensureTermination: false, exp: withoutChecks},
+		{cooperativeYield: true, exp: withChecks},
+		{ensureTermination: true, cooperativeYield: true, exp: withChecks},
 	} {
-		t.Run(fmt.Sprintf("%v", tc.ensureTermination), func(t *testing.T) {
+		t.Run(fmt.Sprintf("%v,%v", tc.ensureTermination, tc.cooperativeYield), func(t *testing.T) {
 			mod := &wasm.Module{
 				TypeSection:     []wasm.FunctionType{v_v},
 				FunctionSection: []wasm.Index{0},
@@ -3061,6 +3060,7 @@ func Test_ensureTermination(t *testing.T) {
 						wasm.OpcodeEnd,
 					},
 				}},
+				CooperativeYield: tc.cooperativeYield,
 			}
 			c, err := NewCompiler(api.CoreFeaturesV2, 0, mod, tc.ensureTermination)
 			require.NoError(t, err)
@@ -415,6 +415,26 @@ func (o OperationKind) String() (ret string) {
 		ret = "V128ITruncSatFromF"
 	case OperationKindBuiltinFunctionCheckExitCode:
 		ret = "BuiltinFunctionCheckExitCode"
+	case OperationKindAtomicMemoryWait:
+		ret = "AtomicMemoryWait"
+	case OperationKindAtomicMemoryNotify:
+		ret = "AtomicMemoryNotify"
+	case OperationKindAtomicFence:
+		ret = "AtomicFence"
+	case OperationKindAtomicLoad:
+		ret = "AtomicLoad"
+	case OperationKindAtomicStore:
+		ret = "AtomicStore"
+	case OperationKindAtomicRMW:
+		ret = "AtomicRMW"
+	case OperationKindAtomicRMWCmpxchg:
+		ret = "AtomicRMWCmpxchg"
+	case OperationKindI64Add128:
+		ret = "I64Add128"
+	case OperationKindI64Sub128:
+		ret = "I64Sub128"
+	case OperationKindI64MulWide:
+		ret = "I64MulWide"
 	default:
 		panic(fmt.Errorf("unknown operation %d", o))
 	}
@@ -705,6 +725,32 @@ const (
 	// OperationKindBuiltinFunctionCheckExitCode is the Kind for NewOperationBuiltinFunctionCheckExitCode.
 	OperationKindBuiltinFunctionCheckExitCode
 
+	// Below are toggled with experimental.CoreFeaturesThreads.
+
+	// OperationKindAtomicMemoryWait is the kind for NewOperationAtomicMemoryWait.
+	OperationKindAtomicMemoryWait
+	// OperationKindAtomicMemoryNotify is the kind for NewOperationAtomicMemoryNotify.
+	OperationKindAtomicMemoryNotify
+	// OperationKindAtomicFence is the kind for NewOperationAtomicFence.
+	OperationKindAtomicFence
+	// OperationKindAtomicLoad is the kind for NewOperationAtomicLoad.
+	OperationKindAtomicLoad
+	// OperationKindAtomicStore is the kind for NewOperationAtomicStore.
+	OperationKindAtomicStore
+	// OperationKindAtomicRMW is the kind for NewOperationAtomicRMW.
+	OperationKindAtomicRMW
+	// OperationKindAtomicRMWCmpxchg is the kind for NewOperationAtomicRMWCmpxchg.
+	OperationKindAtomicRMWCmpxchg
+
+	// Below are toggled with experimental.CoreFeaturesWideArithmetic.
+
+	// OperationKindI64Add128 is the kind for NewOperationI64Add128.
+	OperationKindI64Add128
+	// OperationKindI64Sub128 is the kind for NewOperationI64Sub128.
+	OperationKindI64Sub128
+	// OperationKindI64MulWide is the kind for NewOperationI64MulWide.
+	OperationKindI64MulWide
+
 	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
 	operationKindEnd
 )
@@ -717,6 +763,161 @@ func NewOperationBuiltinFunctionCheckExitCode() UnionOperation {
 	return UnionOperation{Kind: OperationKindBuiltinFunctionCheckExitCode}
 }
 
+// AtomicArithmeticOp is the operation of OperationKindAtomicRMW.
+type AtomicArithmeticOp byte
+
+const (
+	// AtomicArithmeticOpAdd is the add operation.
+	AtomicArithmeticOpAdd AtomicArithmeticOp = iota
+	// AtomicArithmeticOpSub is the sub operation.
+	AtomicArithmeticOpSub
+	// AtomicArithmeticOpAnd is the and operation.
+	AtomicArithmeticOpAnd
+	// AtomicArithmeticOpOr is the or operation.
+	AtomicArithmeticOpOr
+	// AtomicArithmeticOpXor is the xor operation.
+	AtomicArithmeticOpXor
+	// AtomicArithmeticOpXchg replaces the value with the operand.
+	AtomicArithmeticOpXchg
+)
+
+// String implements fmt.Stringer.
+func (o AtomicArithmeticOp) String() (ret string) {
+	switch o {
+	case AtomicArithmeticOpAdd:
+		ret = "add"
+	case AtomicArithmeticOpSub:
+		ret = "sub"
+	case AtomicArithmeticOpAnd:
+		ret = "and"
+	case AtomicArithmeticOpOr:
+		ret = "or"
+	case AtomicArithmeticOpXor:
+		ret = "xor"
+	case AtomicArithmeticOpXchg:
+		ret = "xchg"
+	}
+	return
+}
+
+// Apply returns the result of the operation on the value `old` in memory and
+// the operand `v`.
+func (o AtomicArithmeticOp) Apply(old, v uint64) uint64 {
+	switch o {
+	case AtomicArithmeticOpAdd:
+		return old + v
+	case AtomicArithmeticOpSub:
+		return old - v
+	case AtomicArithmeticOpAnd:
+		return old & v
+	case AtomicArithmeticOpOr:
+		return old | v
+	case AtomicArithmeticOpXor:
+		return old ^ v
+	default: // AtomicArithmeticOpXchg
+		return v
+	}
+}
+
+// NewOperationAtomicMemoryWait is a constructor for UnionOperation with OperationKindAtomicMemoryWait.
+//
+// # This corresponds to wasm.OpcodeAtomicMemoryWait32Name wasm.OpcodeAtomicMemoryWait64Name
+//
+// The engines are expected to check the boundary and alignment of the address, trap if the memory isn't shared,
+// then block until notified or timed out, following the semantics of the corresponding WebAssembly instruction.
+func NewOperationAtomicMemoryWait(unsignedType UnsignedType, size byte, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicMemoryWait, B1: byte(unsignedType), B2: size, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+}
+
+// NewOperationAtomicMemoryNotify is a constructor for UnionOperation with OperationKindAtomicMemoryNotify.
+//
+// # This corresponds to wasm.OpcodeAtomicMemoryNotifyName
+//
+// The engines are expected to check the boundary and alignment of the address, then wake up to the given count of
+// waiters, following the semantics of the corresponding WebAssembly instruction.
+func NewOperationAtomicMemoryNotify(arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicMemoryNotify, B1: byte(UnsignedTypeI32), B2: 4, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+}
+
+// NewOperationAtomicFence is a constructor for UnionOperation with OperationKindAtomicFence.
+//
+// This corresponds to wasm.OpcodeAtomicFenceName
+func NewOperationAtomicFence() UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicFence}
+}
+
+// NewOperationAtomicLoad is a constructor for UnionOperation with OperationKindAtomicLoad.
+//
+// This corresponds to wasm.OpcodeAtomicI32LoadName wasm.OpcodeAtomicI64LoadName and their narrow variants, e.g.
+// wasm.OpcodeAtomicI64Load32UName, where `size` is the size of the access in bytes.
+//
+// The engines are expected to check the boundary and alignment of the address, and exit the execution if invalid,
+// otherwise load the zero-extended value atomically.
+func NewOperationAtomicLoad(unsignedType UnsignedType, size byte, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicLoad, B1: byte(unsignedType), B2: size, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+}
+
+// NewOperationAtomicStore is a constructor for UnionOperation with OperationKindAtomicStore.
+//
+// This corresponds to wasm.OpcodeAtomicI32StoreName wasm.OpcodeAtomicI64StoreName and their narrow variants, e.g.
+// wasm.OpcodeAtomicI64Store32Name, where `size` is the size of the access in bytes.
+//
+// The engines are expected to check the boundary and alignment of the address, and exit the execution if invalid,
+// otherwise store the wrapped value atomically.
+func NewOperationAtomicStore(unsignedType UnsignedType, size byte, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicStore, B1: byte(unsignedType), B2: size, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+}
+
+// NewOperationAtomicRMW is a constructor for UnionOperation with OperationKindAtomicRMW.
+//
+// This corresponds to the read-modify-write instructions except cmpxchg, e.g. wasm.OpcodeAtomicI32RmwAddName or
+// wasm.OpcodeAtomicI64Rmw8XchgUName, where `size` is the size of the access in bytes.
+//
+// The engines are expected to check the boundary and alignment of the address, and exit the execution if invalid,
+// otherwise apply `op` atomically and push the zero-extended value read.
+func NewOperationAtomicRMW(unsignedType UnsignedType, size byte, op AtomicArithmeticOp, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicRMW, B1: byte(unsignedType), B2: size, U1: uint64(arg.Alignment), U2: uint64(arg.Offset), U3: uint64(op)}
+}
+
+// NewOperationAtomicRMWCmpxchg is a constructor for UnionOperation with OperationKindAtomicRMWCmpxchg.
+//
+// This corresponds to wasm.OpcodeAtomicI32RmwCmpxchgName wasm.OpcodeAtomicI64RmwCmpxchgName and their narrow
+// variants, e.g. wasm.OpcodeAtomicI64Rmw32CmpxchgUName, where `size` is the size of the access in bytes.
+//
+// The engines are expected to check the boundary and alignment of the address, and exit the execution if invalid,
+// otherwise compare and exchange atomically and push the zero-extended value read.
+func NewOperationAtomicRMWCmpxchg(unsignedType UnsignedType, size byte, arg MemoryArg) UnionOperation {
+	return UnionOperation{Kind: OperationKindAtomicRMWCmpxchg, B1: byte(unsignedType), B2: size, U1: uint64(arg.Alignment), U2: uint64(arg.Offset)}
+}
+
+// NewOperationI64Add128 is a constructor for UnionOperation with OperationKindI64Add128.
+//
+// This corresponds to wasm.OpcodeI64Add128Name.
+//
+// The engines are expected to pop the low and high 64 bits of two 128-bit values, i.e. [lhs_lo, lhs_hi, rhs_lo, rhs_hi],
+// and push the low then the high 64 bits of their sum, wrapping on overflow.
+func NewOperationI64Add128() UnionOperation {
+	return UnionOperation{Kind: OperationKindI64Add128}
+}
+
+// NewOperationI64Sub128 is a constructor for UnionOperation with OperationKindI64Sub128.
+//
+// This corresponds to wasm.OpcodeI64Sub128Name.
+//
+// The engines are expected to behave like NewOperationI64Add128, except subtracting the second value from the first.
+func NewOperationI64Sub128() UnionOperation {
+	return UnionOperation{Kind: OperationKindI64Sub128}
+}
+
+// NewOperationI64MulWide is a constructor for UnionOperation with OperationKindI64MulWide.
+//
+// This corresponds to wasm.OpcodeI64MulWideSName and wasm.OpcodeI64MulWideUName, depending on `signed`.
+//
+// The engines are expected to pop two 64-bit values, and push the low then the high 64 bits of their 128-bit product.
+func NewOperationI64MulWide(signed bool) UnionOperation {
+	return UnionOperation{Kind: OperationKindI64MulWide, B3: signed}
+}
+
 // Label is the unique identifier for each block in a single function in wazeroir
 // where "block" consists of multiple operations, and must End with branching operations
 // (e.g. OperationKindBr or OperationKindBrIf).
@@ -828,9 +1029,15 @@ func (o UnionOperation) String() string {
 		OperationKindTableSize,
 		OperationKindTableGrow,
 		OperationKindTableFill,
-		OperationKindBuiltinFunctionCheckExitCode:
+		OperationKindBuiltinFunctionCheckExitCode,
+		OperationKindAtomicFence,
+		OperationKindI64Add128,
+		OperationKindI64Sub128:
 		return o.Kind.String()
 
+	case OperationKindI64MulWide:
+		return fmt.Sprintf("%s (signed=%v)", o.Kind, o.B3)
+
 	case OperationKindCall,
 		OperationKindGlobalGet,
 		OperationKindGlobalSet:
@@ -873,6 +1080,18 @@ func (o UnionOperation) String() string {
 	case OperationKindLoad, OperationKindStore:
 		return fmt.Sprintf("%s.%s (align=%d, offset=%d)", UnsignedType(o.B1), o.Kind, o.U1, o.U2)
 
+	case OperationKindAtomicMemoryWait,
+		OperationKindAtomicLoad,
+		OperationKindAtomicStore,
+		OperationKindAtomicRMWCmpxchg:
+		return fmt.Sprintf("%s.%s%d (align=%d, offset=%d)", UnsignedType(o.B1), o.Kind, o.B2*8, o.U1, o.U2)
+
+	case OperationKindAtomicRMW:
+		return fmt.Sprintf("%s.%s%d.%s (align=%d, offset=%d)", UnsignedType(o.B1), o.Kind, o.B2*8, AtomicArithmeticOp(o.U3), o.U1, o.U2)
+
+	case OperationKindAtomicMemoryNotify:
+		return fmt.Sprintf("%s (align=%d, offset=%d)", o.Kind, o.U1, o.U2)
+
 	case OperationKindLoad8,
 		OperationKindLoad16:
 		return fmt.Sprintf("%s.%s (align=%d, offset=%d)", SignedType(o.B1), o.Kind, o.U1, o.U2)
@@ -1290,9 +1509,10 @@ func NewOperationStore32(arg MemoryArg) UnionOperation {
 //
 // This corresponds to wasm.OpcodeMemorySize.
 //
-// The engines are expected to push the current page size of the memory onto the stack.
-func NewOperationMemorySize() UnionOperation {
-	return UnionOperation{Kind: OperationKindMemorySize}
+// The engines are expected to push the current page size of the memory onto the stack, where the size of a page is
+// 1<<pageSizeInBits bytes, which is wasm.MemoryPageSize unless the memory has a custom page size.
+func NewOperationMemorySize(pageSizeInBits uint32) UnionOperation {
+	return UnionOperation{Kind: OperationKindMemorySize, U1: uint64(pageSizeInBits)}
 }
 
 // NewOperationMemoryGrow is a constructor for UnionOperation with OperationKindMemoryGrow.
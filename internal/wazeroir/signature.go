@@ -156,6 +156,34 @@ var (
 		in:  []UnsignedType{UnsignedTypeF64, UnsignedTypeF64},
 		out: []UnsignedType{UnsignedTypeF64},
 	}
+	signature_I32I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
+	signature_I32I32I32_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I32I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I32 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI32},
+	}
+	signature_I32I64I64_I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI32, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64},
+	}
+	signature_I64I64_I64I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64, UnsignedTypeI64},
+	}
+	signature_I64I64I64I64_I64I64 = &signature{
+		in:  []UnsignedType{UnsignedTypeI64, UnsignedTypeI64, UnsignedTypeI64, UnsignedTypeI64},
+		out: []UnsignedType{UnsignedTypeI64, UnsignedTypeI64},
+	}
 	signature_I32I32I32_None = &signature{
 		in: []UnsignedType{UnsignedTypeI32, UnsignedTypeI32, UnsignedTypeI32},
 	}
@@ -473,10 +501,17 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 			return signature_None_I32, nil
 		case wasm.OpcodeMiscTableFill:
 			return signature_I32I64I32_None, nil
+		case wasm.OpcodeMiscI64Add128, wasm.OpcodeMiscI64Sub128:
+			return signature_I64I64I64I64_I64I64, nil
+		case wasm.OpcodeMiscI64MulWideS, wasm.OpcodeMiscI64MulWideU:
+			return signature_I64I64_I64I64, nil
 		default:
 			return nil, fmt.Errorf("unsupported misc instruction in wazeroir: 0x%x", op)
 		}
 	case wasm.OpcodeVecPrefix:
+		if relaxedOp, ok := wasm.LoadVecRelaxedOpcode(c.body[c.pc+1:]); ok {
+			return vecRelaxedSignature(relaxedOp)
+		}
 		switch vecOp := c.body[c.pc+1]; vecOp {
 		case wasm.OpcodeVecV128Const:
 			return signature_None_V128, nil
@@ -586,6 +621,39 @@ func (c *Compiler) wasmOpcodeSignature(op wasm.Opcode, index uint32) (*signature
 		default:
 			return nil, fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VectorInstructionName(vecOp))
 		}
+	case wasm.OpcodeAtomicPrefix:
+		switch atomicOp := c.body[c.pc+1]; {
+		case atomicOp == wasm.OpcodeAtomicMemoryNotify:
+			return signature_I32I32_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait32:
+			return signature_I32I32I64_I32, nil
+		case atomicOp == wasm.OpcodeAtomicMemoryWait64:
+			return signature_I32I64I64_I32, nil
+		case atomicOp == wasm.OpcodeAtomicFence:
+			return signature_None_None, nil
+		case wasm.OpcodeAtomicI32Load <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Load32U:
+			if t, _ := wasm.AtomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32Load); t == wasm.ValueTypeI64 {
+				return signature_I32_I64, nil
+			}
+			return signature_I32_I32, nil
+		case wasm.OpcodeAtomicI32Store <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Store32:
+			if t, _ := wasm.AtomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32Store); t == wasm.ValueTypeI64 {
+				return signature_I32I64_None, nil
+			}
+			return signature_I32I32_None, nil
+		case wasm.OpcodeAtomicI32RmwAdd <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Rmw32XchgU:
+			if t, _ := wasm.AtomicAccessTypeAndSize((atomicOp - wasm.OpcodeAtomicI32RmwAdd) % 7); t == wasm.ValueTypeI64 {
+				return signature_I32I64_I64, nil
+			}
+			return signature_I32I32_I32, nil
+		case wasm.OpcodeAtomicI32RmwCmpxchg <= atomicOp && atomicOp <= wasm.OpcodeAtomicI64Rmw32CmpxchgU:
+			if t, _ := wasm.AtomicAccessTypeAndSize(atomicOp - wasm.OpcodeAtomicI32RmwCmpxchg); t == wasm.ValueTypeI64 {
+				return signature_I32I64I64_I64, nil
+			}
+			return signature_I32I32I32_I32, nil
+		default:
+			return nil, fmt.Errorf("unsupported atomic instruction in wazeroir: %s", wasm.AtomicInstructionName(atomicOp))
+		}
 	default:
 		return nil, fmt.Errorf("unsupported instruction in wazeroir: 0x%x", op)
 	}
@@ -711,3 +779,22 @@ func wasmValueTypeToUnsignedInOutSignature(vt wasm.ValueType) *signature {
 	}
 	panic("unreachable")
 }
+
+// vecRelaxedSignature returns the signature of a relaxed vector instruction.
+func vecRelaxedSignature(op wasm.OpcodeVecRelaxed) (*signature, error) {
+	switch op {
+	case wasm.OpcodeVecI32x4RelaxedTruncF32x4S, wasm.OpcodeVecI32x4RelaxedTruncF32x4U,
+		wasm.OpcodeVecI32x4RelaxedTruncF64x2SZero, wasm.OpcodeVecI32x4RelaxedTruncF64x2UZero:
+		return signature_V128_V128, nil
+	case wasm.OpcodeVecI8x16RelaxedSwizzle, wasm.OpcodeVecF32x4RelaxedMin, wasm.OpcodeVecF32x4RelaxedMax,
+		wasm.OpcodeVecF64x2RelaxedMin, wasm.OpcodeVecF64x2RelaxedMax, wasm.OpcodeVecI16x8RelaxedQ15mulrS,
+		wasm.OpcodeVecI16x8RelaxedDotI8x16I7x16S:
+		return signature_V128V128_V128, nil
+	case wasm.OpcodeVecF32x4RelaxedMadd, wasm.OpcodeVecF32x4RelaxedNmadd, wasm.OpcodeVecF64x2RelaxedMadd,
+		wasm.OpcodeVecF64x2RelaxedNmadd, wasm.OpcodeVecI8x16RelaxedLaneselect, wasm.OpcodeVecI16x8RelaxedLaneselect,
+		wasm.OpcodeVecI32x4RelaxedLaneselect, wasm.OpcodeVecI64x2RelaxedLaneselect, wasm.OpcodeVecI32x4RelaxedDotI8x16I7x16AddS:
+		return signature_V128V128V128_V32, nil
+	default:
+		return nil, fmt.Errorf("unsupported vector instruction in wazeroir: %s", wasm.VecRelaxedInstructionName(op))
+	}
+}
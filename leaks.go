@@ -0,0 +1,122 @@
+package wazero
+
+import (
+	"context"
+	goruntime "runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
+)
+
+// leakTracker tracks the compiled modules and module instances of a runtime
+// created with experimental.WithLeakDetection.
+type leakTracker struct {
+	handler experimentalapi.LeakHandler
+
+	mux     sync.Mutex
+	nextID  uint64
+	entries map[uint64]*leakEntry
+}
+
+// leakEntry is a tracked compiled module or module instance.
+type leakEntry struct {
+	id        uint64
+	name      string
+	stack     []byte
+	collected bool
+	instance  bool
+}
+
+func newLeakTracker(handler experimentalapi.LeakHandler) *leakTracker {
+	return &leakTracker{handler: handler, entries: map[uint64]*leakEntry{}}
+}
+
+// trackCompiled tracks the compiled module until it is closed, marking it
+// collected if it is garbage collected before that.
+func (t *leakTracker) trackCompiled(c *compiledModule) {
+	c.leaks, c.leakID = t, t.track(c.Name(), false)
+	goruntime.SetFinalizer(c, func(c *compiledModule) {
+		t.mux.Lock()
+		defer t.mux.Unlock()
+		if e, ok := t.entries[c.leakID]; ok {
+			e.collected = true
+		}
+	})
+}
+
+// untrackCompiled stops tracking the compiled module, e.g. when it is closed.
+func (t *leakTracker) untrackCompiled(c *compiledModule) {
+	goruntime.SetFinalizer(c, nil)
+	t.untrack(c.leakID)
+}
+
+// trackInstance tracks the module instance until the returned api.Closer,
+// which must be closed with the instance, is closed. This doesn't reference
+// the instance, so that a closed one can be garbage collected.
+func (t *leakTracker) trackInstance(mod api.Module) api.Closer {
+	return &instanceUntracker{t: t, id: t.track(mod.Name(), true)}
+}
+
+func (t *leakTracker) track(name string, instance bool) uint64 {
+	stack := debug.Stack()
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	t.nextID++
+	t.entries[t.nextID] = &leakEntry{id: t.nextID, name: name, stack: stack, instance: instance}
+	return t.nextID
+}
+
+func (t *leakTracker) untrack(id uint64) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	delete(t.entries, id)
+}
+
+// instanceUntracker stops tracking a module instance when it is closed.
+type instanceUntracker struct {
+	t  *leakTracker
+	id uint64
+}
+
+// Close implements api.Closer
+func (u *instanceUntracker) Close(context.Context) error {
+	u.t.untrack(u.id)
+	return nil
+}
+
+// leaks returns the outstanding leaks in the order they were tracked.
+func (t *leakTracker) leaks() []experimentalapi.Leak {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	entries := make([]*leakEntry, 0, len(t.entries))
+	for _, e := range t.entries {
+		entries = append(entries, e)
+	}
+	ret := make([]experimentalapi.Leak, len(entries))
+	sort.Slice(entries, func(i, j int) bool { return entries[i].id < entries[j].id })
+	for i, e := range entries {
+		ret[i] = experimentalapi.Leak{Module: e.name, Instance: e.instance, Collected: e.collected, Stack: e.stack}
+	}
+	return ret
+}
+
+// report calls the handler, if any, with the outstanding leaks.
+func (t *leakTracker) report(ctx context.Context) {
+	if t.handler == nil {
+		return
+	}
+	if leaks := t.leaks(); len(leaks) > 0 {
+		t.handler(ctx, leaks)
+	}
+}
+
+// Leaks implements the same method as used by experimental.GetLeaks.
+func (r *runtime) Leaks() ([]experimentalapi.Leak, bool) {
+	if r.leaks == nil {
+		return nil, false
+	}
+	return r.leaks.leaks(), true
+}
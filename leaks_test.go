@@ -0,0 +1,116 @@
+package wazero
+
+import (
+	"context"
+	goruntime "runtime"
+	"strings"
+	"testing"
+
+	experimentalapi "github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestRuntime_Leaks(t *testing.T) {
+	var reported []experimentalapi.Leak
+	ctx := experimentalapi.WithLeakDetection(testCtx, func(_ context.Context, leaks []experimentalapi.Leak) {
+		reported = leaks
+	})
+	r := NewRuntimeWithConfig(ctx, NewRuntimeConfigInterpreter())
+
+	compiled, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+	closedCompiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{NameSection: &wasm.NameSection{ModuleName: "1"}}))
+	require.NoError(t, err)
+	require.NoError(t, closedCompiled.Close(testCtx))
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("leaked"))
+	require.NoError(t, err)
+	closed, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("closed"))
+	require.NoError(t, err)
+	require.NoError(t, closed.Close(testCtx))
+
+	// The compiled module of Instantiate is closed with the instance, so it
+	// isn't tracked separately.
+	_, err = r.InstantiateWithConfig(testCtx, binaryNamedZero, NewModuleConfig().WithName("implicit"))
+	require.NoError(t, err)
+
+	leaks, ok := experimentalapi.GetLeaks(r)
+	require.True(t, ok)
+	require.Equal(t, 3, len(leaks))
+	require.Equal(t, "0", leaks[0].Module)
+	require.False(t, leaks[0].Instance)
+	require.Equal(t, "leaked", leaks[1].Module)
+	require.True(t, leaks[1].Instance)
+	require.Equal(t, "implicit", leaks[2].Module)
+	require.True(t, leaks[2].Instance)
+	require.True(t, strings.Contains(string(leaks[0].Stack), "TestRuntime_Leaks"))
+
+	require.Nil(t, reported)
+	require.NoError(t, r.Close(testCtx))
+	require.Equal(t, leaks, reported)
+}
+
+func TestRuntime_Leaks_Collected(t *testing.T) {
+	ctx := experimentalapi.WithLeakDetection(testCtx, nil)
+	r := NewRuntimeWithConfig(ctx, NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx) // nil handler isn't called
+
+	_, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+
+	// Finalizers run in a separate goroutine, so retry until it marks the
+	// compiled module collected.
+	for i := 0; i < 100; i++ {
+		goruntime.GC()
+		if leaks, _ := experimentalapi.GetLeaks(r); leaks[0].Collected {
+			return
+		}
+		goruntime.Gosched()
+	}
+	t.Fatal("expected compiled module to be collected")
+}
+
+func TestRuntime_Leaks_ClosedInstanceCollected(t *testing.T) {
+	ctx := experimentalapi.WithLeakDetection(testCtx, nil)
+	r := NewRuntimeWithConfig(ctx, NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+
+	collected := make(chan struct{})
+	func() {
+		mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("closed"))
+		require.NoError(t, err)
+		require.NoError(t, mod.Close(testCtx))
+		goruntime.SetFinalizer(mod.(*wasm.ModuleInstance), func(*wasm.ModuleInstance) { close(collected) })
+	}()
+
+	// The closed instance is untracked, so the tracker doesn't keep it
+	// reachable.
+	leaks, _ := experimentalapi.GetLeaks(r)
+	require.Equal(t, 1, len(leaks))
+	require.False(t, leaks[0].Instance)
+
+	// Finalizers run in a separate goroutine, so retry until it runs.
+	for i := 0; i < 100; i++ {
+		goruntime.GC()
+		select {
+		case <-collected:
+			return
+		default:
+			goruntime.Gosched()
+		}
+	}
+	t.Fatal("expected closed instance to be collected")
+}
+
+func TestRuntime_Leaks_Disabled(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, ok := experimentalapi.GetLeaks(r)
+	require.False(t, ok)
+}
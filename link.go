@@ -0,0 +1,220 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Link implements Runtime.Link
+func (r *runtime) Link(ctx context.Context, a, b CompiledModule, aConfig, bConfig ModuleConfig) (modA, modB api.Module, err error) {
+	if err = r.failIfClosed(); err != nil {
+		return
+	}
+	aName, bName := aConfig.(*moduleConfig), bConfig.(*moduleConfig)
+	if !aName.nameSet || !bName.nameSet {
+		err = errors.New("linked modules must be named with ModuleConfig.WithName")
+		return
+	} else if aName.name == bName.name {
+		err = fmt.Errorf("linked modules must have distinct names, but both are %q", aName.name)
+		return
+	}
+
+	aCode, bCode := a.(*compiledModule), b.(*compiledModule)
+	aImportsB := len(aCode.module.ImportPerModule[bName.name]) > 0
+	bImportsA := len(bCode.module.ImportPerModule[aName.name]) > 0
+	switch {
+	case !bImportsA:
+		modB, modA, err = r.linkInOrder(ctx, b, a, bConfig, aConfig)
+	case !aImportsB:
+		modA, modB, err = r.linkInOrder(ctx, a, b, aConfig, bConfig)
+	case onlyImportsFunctions(aCode.module, bName.name):
+		modA, modB, err = r.linkMutually(ctx, aCode, b, aName, bConfig)
+	case onlyImportsFunctions(bCode.module, aName.name):
+		modB, modA, err = r.linkMutually(ctx, bCode, a, bName, aConfig)
+	default:
+		err = fmt.Errorf("module[%s] and module[%s] import more than functions from each other", aName.name, bName.name)
+	}
+	return
+}
+
+// linkInOrder instantiates `first`, then `second`, which depends on it.
+func (r *runtime) linkInOrder(ctx context.Context, first, second CompiledModule, firstConfig, secondConfig ModuleConfig) (modFirst, modSecond api.Module, err error) {
+	if modFirst, err = r.InstantiateModule(ctx, first, firstConfig); err != nil {
+		return
+	}
+	if modSecond, err = r.InstantiateModule(ctx, second, secondConfig); err != nil {
+		_ = modFirst.Close(ctx)
+		modFirst = nil
+	}
+	return
+}
+
+// onlyImportsFunctions returns true if `m` only imports functions from the
+// module `moduleName`.
+func onlyImportsFunctions(m *wasm.Module, moduleName string) bool {
+	for _, i := range m.ImportPerModule[moduleName] {
+		if i.Type != wasm.ExternTypeFunc {
+			return false
+		}
+	}
+	return true
+}
+
+// linkMutually instantiates `first`, deferring its imports from `second`,
+// then `second`.
+func (r *runtime) linkMutually(ctx context.Context, first *compiledModule, second CompiledModule, firstConfig *moduleConfig, secondConfig ModuleConfig) (modFirst, modSecond api.Module, err error) {
+	secondName := secondConfig.(*moduleConfig).name
+	deferred := firstConfig.WithDeferredImports(append([]string{secondName}, firstConfig.deferredImports...)...)
+	if modFirst, err = r.InstantiateModule(ctx, first, deferred); err != nil {
+		return
+	}
+	if modSecond, err = r.InstantiateModule(ctx, second, secondConfig); err != nil {
+		_ = modFirst.Close(ctx)
+		modFirst = nil
+		return
+	}
+
+	// Check the deferred imports, like resolving imports would have.
+	for _, i := range first.module.ImportPerModule[secondName] {
+		ft := &first.module.TypeSection[i.DescFunc]
+		if err = checkDeferredImport(modSecond, i, ft); err != nil {
+			_ = modSecond.Close(ctx)
+			_ = modFirst.Close(ctx)
+			modFirst, modSecond = nil, nil
+			return
+		}
+	}
+	return
+}
+
+// checkDeferredImport returns an error if `mod` doesn't export the function
+// `i` with the signature `ft`.
+func checkDeferredImport(mod api.Module, i *wasm.Import, ft *wasm.FunctionType) error {
+	fn := mod.ExportedFunction(i.Name)
+	if fn == nil {
+		return fmt.Errorf("import func[%s.%s]: not exported", i.Module, i.Name)
+	} else if def := fn.Definition(); !ft.EqualsSignature(def.ParamTypes(), def.ResultTypes()) {
+		return fmt.Errorf("import func[%s.%s]: signature mismatch: %s != %s",
+			i.Module, i.Name, ft, &wasm.FunctionType{Params: def.ParamTypes(), Results: def.ResultTypes()})
+	}
+	return nil
+}
+
+// closers is an api.Closer which closes each of its elements.
+type closers []api.Closer
+
+// Close implements api.Closer
+func (c closers) Close(ctx context.Context) (err error) {
+	for _, closer := range c {
+		if e := closer.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
+
+// release detaches the proxies returned by instantiateDeferredImports from
+// the store, making their names available for instantiation. They are closed
+// with the module which imports them, instead of the store.
+func (c closers) release(s *wasm.Store) {
+	for _, proxy := range c {
+		_ = s.DetachModule(proxy.(detachedModule).ModuleInstance)
+	}
+}
+
+// detachedModule closes a module detached from its store.
+type detachedModule struct {
+	*wasm.ModuleInstance
+}
+
+// Close implements api.Closer
+func (m detachedModule) Close(ctx context.Context) error {
+	return m.CloseDetached(ctx)
+}
+
+// instantiateDeferredImports instantiates a proxy module for each of
+// `moduleNames` which `module` imports from, but isn't instantiated yet.
+// Each proxy has the name of the module it stands for, until released.
+func (r *runtime) instantiateDeferredImports(ctx context.Context, module *wasm.Module, moduleNames []string) (proxies closers, err error) {
+	for _, name := range moduleNames {
+		imports := module.ImportPerModule[name]
+		if len(imports) == 0 || r.Module(name) != nil {
+			continue
+		}
+
+		var proxy api.Module
+		builder := r.NewHostModuleBuilder(name)
+		for _, i := range imports {
+			if i.Type != wasm.ExternTypeFunc {
+				err = fmt.Errorf("import %s[%s.%s]: only functions can be imported before module[%s] is instantiated",
+					wasm.ExternTypeName(i.Type), name, i.Name, name)
+				break
+			}
+			ft := &module.TypeSection[i.DescFunc]
+			builder.NewFunctionBuilder().
+				WithGoFunction(r.lateBoundFunction(i, ft, &proxy), ft.Params, ft.Results).
+				Export(i.Name)
+		}
+		var compiled CompiledModule
+		if err == nil {
+			compiled, err = builder.Compile(ctx)
+		}
+		if err == nil {
+			compiled.(*compiledModule).closeWithModule = true
+			proxy, err = r.instantiateModule(ctx, compiled, NewModuleConfig(), false)
+		}
+		if err != nil {
+			proxies.release(r.store)
+			_ = proxies.Close(ctx)
+			return nil, err
+		}
+		proxies = append(proxies, detachedModule{proxy.(*wasm.ModuleInstance)})
+	}
+	return
+}
+
+// lateBoundFunction returns a function which calls the function `i`
+// imports, once its module is instantiated, instead of the proxy holding its
+// name until then. When that module closes, the function binds the module
+// next instantiated with its name.
+func (r *runtime) lateBoundFunction(i *wasm.Import, ft *wasm.FunctionType, proxy *api.Module) api.GoFunc {
+	// api.Function isn't goroutine-safe, so pool them for concurrent calls.
+	// The pool is dropped when its module closes, so that it doesn't retain
+	// functions of the closed module.
+	var mux sync.Mutex
+	var target api.Module
+	var pool *sync.Pool
+	return func(ctx context.Context, stack []uint64) {
+		mux.Lock()
+		if target == nil || target.IsClosed() {
+			mod := r.Module(i.Module)
+			if mod == nil || mod == *proxy {
+				mux.Unlock()
+				panic(fmt.Errorf("import func[%s.%s]: module[%s] is not instantiated yet", i.Module, i.Name, i.Module))
+			}
+			if err := checkDeferredImport(mod, i, ft); err != nil {
+				mux.Unlock()
+				panic(err)
+			}
+			target, pool = mod, &sync.Pool{}
+		}
+		mod, p := target, pool
+		mux.Unlock()
+
+		fn, _ := p.Get().(api.Function)
+		if fn == nil {
+			fn = mod.ExportedFunction(i.Name)
+		}
+		if err := fn.CallWithStack(ctx, stack); err != nil {
+			panic(err)
+		}
+		if !mod.IsClosed() {
+			p.Put(fn)
+		}
+	}
+}
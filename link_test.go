@@ -0,0 +1,206 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+var (
+	// linkA imports "b"."b_fn", and exports "one", and "call_b", which calls
+	// the import.
+	linkA = binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "b", Name: "b_fn", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "one", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "call_b", Type: wasm.ExternTypeFunc, Index: 2},
+		},
+	})
+	// linkB imports "a"."one", and exports "b_fn", which adds 41 to it.
+	linkB = binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "a", Name: "one", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeI32Const, 41, wasm.OpcodeI32Add, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{{Name: "b_fn", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+	// linkC exports "b_fn", like linkB, without importing anything.
+	linkC = binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "b_fn", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+	// linkD exports nothing, but imports "a"."one".
+	linkD = binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:   []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection: []wasm.Import{{Module: "a", Name: "one", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	})
+)
+
+func TestRuntime_Link(t *testing.T) {
+	tests := []struct {
+		name        string
+		a, b        []byte
+		aName       string
+		bName       string
+		expectedErr string
+	}{
+		{name: "mutual", a: linkA, b: linkB, aName: "a", bName: "b"},
+		{name: "mutual reversed", a: linkB, b: linkA, aName: "b", bName: "a"},
+		{name: "a depends on b", a: linkA, b: linkC, aName: "a", bName: "b"},
+		{
+			name: "unnamed", a: linkA, b: linkB, aName: "a",
+			expectedErr: "linked modules must be named with ModuleConfig.WithName",
+		},
+		{
+			name: "same name", a: linkA, b: linkB, aName: "a", bName: "a",
+			expectedErr: `linked modules must have distinct names, but both are "a"`,
+		},
+		{
+			name: "missing export", a: linkA, b: linkD, aName: "a", bName: "b",
+			expectedErr: "import func[b.b_fn]: not exported",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			a, err := r.CompileModule(testCtx, tc.a)
+			require.NoError(t, err)
+			b, err := r.CompileModule(testCtx, tc.b)
+			require.NoError(t, err)
+
+			aConfig, bConfig := NewModuleConfig().WithName(tc.aName), NewModuleConfig()
+			if tc.bName != "" {
+				bConfig = bConfig.WithName(tc.bName)
+			}
+			modA, modB, err := r.Link(testCtx, a, b, aConfig, bConfig)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				require.Nil(t, modA)
+				require.Nil(t, modB)
+				// No module remains instantiated.
+				require.Nil(t, r.Module("a"))
+				require.Nil(t, r.Module("b"))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.aName, modA.Name())
+			require.Equal(t, tc.bName, modB.Name())
+
+			results, err := r.Module("a").ExportedFunction("call_b").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{42}, results)
+		})
+	}
+}
+
+func TestRuntime_Link_rebindsClosedModule(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	a, err := r.CompileModule(testCtx, linkA)
+	require.NoError(t, err)
+	b, err := r.CompileModule(testCtx, linkB)
+	require.NoError(t, err)
+
+	modA, modB, err := r.Link(testCtx, a, b, NewModuleConfig().WithName("a"), NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+	callB := modA.ExportedFunction("call_b")
+	results, err := callB.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// The function cached for "b" isn't used once it closes.
+	require.NoError(t, modB.Close(testCtx))
+	_, err = callB.Call(testCtx)
+	require.Contains(t, err.Error(), "import func[b.b_fn]: module[b] is not instantiated yet")
+
+	// Instead, the module next instantiated with its name is called.
+	_, err = r.InstantiateWithConfig(testCtx, linkC, NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+	results, err = callB.Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+}
+
+func TestModuleConfig_WithDeferredImports(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	a, err := r.CompileModule(testCtx, linkA)
+	require.NoError(t, err)
+	b, err := r.CompileModule(testCtx, linkB)
+	require.NoError(t, err)
+
+	// Without deferring, "a" can't be instantiated before "b".
+	_, err = r.InstantiateModule(testCtx, a, NewModuleConfig().WithName("a"))
+	require.EqualError(t, err, "module[b] not instantiated")
+
+	modA, err := r.InstantiateModule(testCtx, a, NewModuleConfig().WithName("a").WithDeferredImports("b"))
+	require.NoError(t, err)
+	require.Nil(t, r.Module("b")) // the proxy doesn't hold the name
+
+	// Calling a deferred import before its module is instantiated traps.
+	_, err = modA.ExportedFunction("call_b").Call(testCtx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "import func[b.b_fn]: module[b] is not instantiated yet")
+
+	_, err = r.InstantiateModule(testCtx, b, NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+
+	results, err := modA.ExportedFunction("call_b").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// Closing the module closes its proxies.
+	require.NoError(t, modA.Close(testCtx))
+	require.NotNil(t, r.Module("b"))
+}
+
+func TestModuleConfig_WithDeferredImports_Errors(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	// importsMemory imports a memory from "b", which can't be deferred.
+	importsMemory, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		ImportSection: []wasm.Import{{Module: "b", Name: "mem", Type: wasm.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1, Max: 1}}},
+	}))
+	require.NoError(t, err)
+
+	_, err = r.InstantiateModule(testCtx, importsMemory, NewModuleConfig().WithName("a").WithDeferredImports("b"))
+	require.EqualError(t, err, "import memory[b.mem]: only functions can be imported before module[b] is instantiated")
+	require.Nil(t, r.Module("a"))
+	require.Nil(t, r.Module("b"))
+
+	// A deferred import with the wrong signature traps when called.
+	a, err := r.CompileModule(testCtx, linkA)
+	require.NoError(t, err)
+	modA, err := r.InstantiateModule(testCtx, a, NewModuleConfig().WithName("a").WithDeferredImports("b"))
+	require.NoError(t, err)
+	_, err = r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "b_fn", Type: wasm.ExternTypeFunc, Index: 0}},
+		NameSection:     &wasm.NameSection{ModuleName: "b"},
+	}))
+	require.NoError(t, err)
+	_, err = modA.ExportedFunction("call_b").Call(testCtx)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "import func[b.b_fn]: signature mismatch: v_i32 != v_v")
+}
@@ -0,0 +1,95 @@
+package wazero
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// instantiateOptionalImports instantiates a module of stubs for each module
+// name which `module` imports functions from, which are missing and match
+// one of the `rules`. The stubs are anonymous, so they don't conflict with
+// the modules they stand in for, and are appended to `proxies` to be closed
+// with the module.
+func (r *runtime) instantiateOptionalImports(ctx context.Context, module *wasm.Module, rules []optionalImport, proxies *closers) (stubs map[string]*wasm.ModuleInstance, err error) {
+	for moduleName, imports := range module.ImportPerModule {
+		imported := r.Module(moduleName)
+
+		var builder HostModuleBuilder
+		for _, i := range imports {
+			if i.Type != wasm.ExternTypeFunc {
+				continue
+			} else if imported != nil && imported.ExportedFunction(i.Name) != nil {
+				continue
+			}
+			stub, ok := matchOptionalImport(rules, i)
+			if !ok {
+				continue
+			}
+			if builder == nil {
+				builder = r.NewHostModuleBuilder(moduleName)
+			}
+			ft := &module.TypeSection[i.DescFunc]
+			builder.NewFunctionBuilder().
+				WithGoFunction(stubFunction(i, ft, stub), ft.Params, ft.Results).
+				Export(i.Name)
+		}
+		if builder == nil {
+			continue
+		}
+
+		var mod api.Module
+		if mod, err = r.instantiateAnonymous(ctx, builder); err != nil {
+			return nil, err
+		}
+		*proxies = append(*proxies, detachedModule{mod.(*wasm.ModuleInstance)})
+		if stubs == nil {
+			stubs = map[string]*wasm.ModuleInstance{}
+		}
+		stubs[moduleName] = mod.(*wasm.ModuleInstance)
+	}
+	return
+}
+
+// instantiateAnonymous instantiates the host module without a name, so that
+// it isn't importable, closing its compiled code with it.
+func (r *runtime) instantiateAnonymous(ctx context.Context, builder HostModuleBuilder) (api.Module, error) {
+	compiled, err := builder.Compile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compiled.(*compiledModule).closeWithModule = true
+	return r.instantiateModule(ctx, compiled, NewModuleConfig().WithName(""), false)
+}
+
+// matchOptionalImport returns the stub of the first rule which matches the
+// import `i`, or false if none does.
+func matchOptionalImport(rules []optionalImport, i *wasm.Import) (OptionalImportStub, bool) {
+	name := i.Module + "." + i.Name
+	for _, rule := range rules {
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			return rule.stub, true
+		}
+	}
+	return 0, false
+}
+
+// stubFunction returns the function which stands in for the missing import
+// `i`, of type `ft`.
+func stubFunction(i *wasm.Import, ft *wasm.FunctionType, stub OptionalImportStub) api.GoFunc {
+	if stub == OptionalImportZero {
+		resultLen := len(ft.Results)
+		return func(_ context.Context, stack []uint64) {
+			for j := 0; j < resultLen; j++ {
+				stack[j] = 0
+			}
+		}
+	}
+	err := fmt.Errorf("import func[%s.%s]: optional import not instantiated", i.Module, i.Name)
+	return func(context.Context, []uint64) {
+		panic(err)
+	}
+}
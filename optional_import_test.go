@@ -0,0 +1,127 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// optionalImports imports "env"."present" and "env"."missing", and exports
+// functions which call them.
+var optionalImports = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection: []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+	ImportSection: []wasm.Import{
+		{Module: "env", Name: "present", Type: wasm.ExternTypeFunc, DescFunc: 0},
+		{Module: "env", Name: "missing", Type: wasm.ExternTypeFunc, DescFunc: 0},
+	},
+	FunctionSection: []wasm.Index{0, 0},
+	CodeSection: []wasm.Code{
+		{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}},
+		{Body: []byte{wasm.OpcodeCall, 1, wasm.OpcodeEnd}},
+	},
+	ExportSection: []wasm.Export{
+		{Name: "call_present", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "call_missing", Type: wasm.ExternTypeFunc, Index: 3},
+	},
+})
+
+func TestModuleConfig_WithOptionalImports(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          ModuleConfig
+		expectedErr     string
+		expectedResult  uint64
+		expectedCallErr string
+	}{
+		{
+			name:        "not optional",
+			config:      NewModuleConfig(),
+			expectedErr: `"missing" is not exported in module "env"`,
+		},
+		{
+			name:        "no match",
+			config:      NewModuleConfig().WithOptionalImports(OptionalImportZero, "other.*"),
+			expectedErr: `"missing" is not exported in module "env"`,
+		},
+		{
+			name:           "zero",
+			config:         NewModuleConfig().WithOptionalImports(OptionalImportZero, "env.*"),
+			expectedResult: 0,
+		},
+		{
+			name:            "trap",
+			config:          NewModuleConfig().WithOptionalImports(OptionalImportTrap, "env.miss*"),
+			expectedCallErr: "import func[env.missing]: optional import not instantiated",
+		},
+		{
+			name: "first rule applies",
+			config: NewModuleConfig().
+				WithOptionalImports(OptionalImportZero, "env.missing").
+				WithOptionalImports(OptionalImportTrap, "*"),
+			expectedResult: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().
+				WithFunc(func() uint32 { return 42 }).
+				Export("present").
+				Instantiate(testCtx)
+			require.NoError(t, err)
+
+			mod, err := r.InstantiateModule(testCtx, mustCompile(t, r, optionalImports), tc.config)
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// Imports which aren't missing resolve as usual.
+			results, err := mod.ExportedFunction("call_present").Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, []uint64{42}, results)
+
+			results, err = mod.ExportedFunction("call_missing").Call(testCtx)
+			if tc.expectedCallErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.expectedCallErr)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, []uint64{tc.expectedResult}, results)
+			}
+
+			// The stubs don't take the name of the module they stand in for.
+			require.NotNil(t, r.Module("env"))
+			require.NoError(t, mod.Close(testCtx))
+		})
+	}
+}
+
+func TestModuleConfig_WithOptionalImports_moduleMissing(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mod, err := r.InstantiateModule(testCtx, mustCompile(t, r, optionalImports),
+		NewModuleConfig().WithOptionalImports(OptionalImportZero, "env.*"))
+	require.NoError(t, err)
+	require.Nil(t, r.Module("env"))
+
+	results, err := mod.ExportedFunction("call_present").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0}, results)
+}
+
+func mustCompile(t *testing.T, r Runtime, bin []byte) CompiledModule {
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+	return compiled
+}
@@ -0,0 +1,338 @@
+package wazero
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// Policy declares constraints a WebAssembly binary must satisfy to be
+// compiled by a Runtime, in addition to being valid. This allows platforms
+// that run untrusted code to reject modules before any code is generated.
+//
+// Here's an example that only allows WASI imports and at most 16 pages of
+// memory:
+//
+//	policy := wazero.NewPolicy().
+//		WithAllowedImports("wasi_snapshot_preview1").
+//		WithMaxMemoryPages(16)
+//	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithPolicy(policy))
+//
+// When a module is rejected, Runtime.CompileModule returns a *PolicyError,
+// which lists every violation, not only the first.
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+//   - Policy is immutable. Each WithXXX function returns a new instance
+//     including the corresponding change.
+//   - A Policy is only evaluated against modules decoded from a binary. Host
+//     modules defined with HostModuleBuilder are not checked.
+type Policy interface {
+	// WithAllowedImports allows the module to import the given names from
+	// `moduleName`. When no names are given, any name in `moduleName` is
+	// allowed. By default, all imports are allowed, but once this is called,
+	// only imports allowed by a call to this function are.
+	WithAllowedImports(moduleName string, names ...string) Policy
+
+	// WithAllowedCoreFeatures limits the WebAssembly Core specification
+	// features a module may use to the intersection of these and the ones
+	// enabled by RuntimeConfig.WithCoreFeatures. Defaults to no restriction.
+	WithAllowedCoreFeatures(api.CoreFeatures) Policy
+
+	// WithMaxMemoryPages limits the maximum pages of a defined or imported
+	// memory. A memory without an encoded maximum is checked against
+	// RuntimeConfig.WithMemoryLimitPages. Zero means no limit.
+	//
+	// The limit is in pages of 64KiB, even for memories with a custom page
+	// size, as defined by experimental.CoreFeaturesCustomPageSizes.
+	WithMaxMemoryPages(uint32) Policy
+
+	// WithMaxTableSize limits the maximum size of each defined or imported
+	// table. A table without an encoded maximum is checked against its
+	// minimum. Zero means no limit.
+	WithMaxTableSize(uint32) Policy
+
+	// WithMaxFunctions limits the count of functions defined in the module,
+	// excluding imports. Zero means no limit.
+	WithMaxFunctions(uint32) Policy
+
+	// WithMaxFunctionSize limits the size in bytes of each function body
+	// defined in the module. Zero means no limit.
+	WithMaxFunctionSize(uint32) Policy
+}
+
+// NewPolicy returns a Policy that allows everything a Runtime would compile.
+func NewPolicy() Policy {
+	return &policy{}
+}
+
+type policy struct {
+	// allowedImports is nil when all imports are allowed. Otherwise, it is
+	// keyed on module name. A nil value allows any name in that module.
+	allowedImports  map[string]map[string]struct{}
+	allowedFeatures api.CoreFeatures
+	featuresSet     bool
+	maxMemoryPages  uint32
+	maxTableSize    uint32
+	maxFunctions    uint32
+	maxFunctionSize uint32
+}
+
+// clone makes a deep copy of this policy.
+func (p *policy) clone() *policy {
+	ret := *p // copy except maps which share a ref
+	if p.allowedImports != nil {
+		ret.allowedImports = make(map[string]map[string]struct{}, len(p.allowedImports))
+		for moduleName, names := range p.allowedImports {
+			if names == nil {
+				ret.allowedImports[moduleName] = nil
+				continue
+			}
+			copied := make(map[string]struct{}, len(names))
+			for name := range names {
+				copied[name] = struct{}{}
+			}
+			ret.allowedImports[moduleName] = copied
+		}
+	}
+	return &ret
+}
+
+// WithAllowedImports implements Policy.WithAllowedImports
+func (p *policy) WithAllowedImports(moduleName string, names ...string) Policy {
+	ret := p.clone()
+	if ret.allowedImports == nil {
+		ret.allowedImports = map[string]map[string]struct{}{}
+	}
+	existing, ok := ret.allowedImports[moduleName]
+	switch {
+	case len(names) == 0:
+		ret.allowedImports[moduleName] = nil // any name
+	case ok && existing == nil:
+		// Already allows any name.
+	default:
+		if existing == nil {
+			existing = make(map[string]struct{}, len(names))
+		}
+		for _, name := range names {
+			existing[name] = struct{}{}
+		}
+		ret.allowedImports[moduleName] = existing
+	}
+	return ret
+}
+
+// WithAllowedCoreFeatures implements Policy.WithAllowedCoreFeatures
+func (p *policy) WithAllowedCoreFeatures(features api.CoreFeatures) Policy {
+	ret := p.clone()
+	ret.allowedFeatures = features
+	ret.featuresSet = true
+	return ret
+}
+
+// WithMaxMemoryPages implements Policy.WithMaxMemoryPages
+func (p *policy) WithMaxMemoryPages(maxMemoryPages uint32) Policy {
+	ret := p.clone()
+	ret.maxMemoryPages = maxMemoryPages
+	return ret
+}
+
+// WithMaxTableSize implements Policy.WithMaxTableSize
+func (p *policy) WithMaxTableSize(maxTableSize uint32) Policy {
+	ret := p.clone()
+	ret.maxTableSize = maxTableSize
+	return ret
+}
+
+// WithMaxFunctions implements Policy.WithMaxFunctions
+func (p *policy) WithMaxFunctions(maxFunctions uint32) Policy {
+	ret := p.clone()
+	ret.maxFunctions = maxFunctions
+	return ret
+}
+
+// WithMaxFunctionSize implements Policy.WithMaxFunctionSize
+func (p *policy) WithMaxFunctionSize(maxFunctionSize uint32) Policy {
+	ret := p.clone()
+	ret.maxFunctionSize = maxFunctionSize
+	return ret
+}
+
+// PolicyRule identifies which constraint of a Policy was violated.
+type PolicyRule string
+
+const (
+	// PolicyRuleImport is violated by an import not allowed by
+	// Policy.WithAllowedImports.
+	PolicyRuleImport PolicyRule = "import"
+	// PolicyRuleCoreFeatures is violated by use of a feature not allowed by
+	// Policy.WithAllowedCoreFeatures.
+	PolicyRuleCoreFeatures PolicyRule = "core_features"
+	// PolicyRuleMaxMemoryPages is violated by a memory over
+	// Policy.WithMaxMemoryPages.
+	PolicyRuleMaxMemoryPages PolicyRule = "max_memory_pages"
+	// PolicyRuleMaxTableSize is violated by a table over
+	// Policy.WithMaxTableSize.
+	PolicyRuleMaxTableSize PolicyRule = "max_table_size"
+	// PolicyRuleMaxFunctions is violated by a module defining more functions
+	// than Policy.WithMaxFunctions.
+	PolicyRuleMaxFunctions PolicyRule = "max_functions"
+	// PolicyRuleMaxFunctionSize is violated by a function body larger than
+	// Policy.WithMaxFunctionSize.
+	PolicyRuleMaxFunctionSize PolicyRule = "max_function_size"
+)
+
+// PolicyViolation describes one reason a module was rejected by a Policy.
+type PolicyViolation struct {
+	// Rule is the constraint that was violated.
+	Rule PolicyRule
+	// Message is a human readable description of the violation, e.g.
+	// `import["env"."abort"] is not allowed`.
+	Message string
+}
+
+// String implements fmt.Stringer
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// PolicyError is returned by Runtime.CompileModule when a module is valid, but
+// violates the Policy configured with RuntimeConfig.WithPolicy.
+type PolicyError struct {
+	// Violations are all violations found, in the order they were checked.
+	Violations []PolicyViolation
+}
+
+// Error implements error
+func (e *PolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("module violates policy: %s", strings.Join(msgs, "; "))
+}
+
+// check returns a *PolicyError if the decoded and validated module `m` does
+// not comply with this policy. The `binary` is needed to check the features
+// in use, as those are only knowable during decoding and validation.
+func (p *policy) check(binary []byte, m *wasm.Module, r *runtime) error {
+	var violations []PolicyViolation
+	violate := func(rule PolicyRule, format string, args ...interface{}) {
+		violations = append(violations, PolicyViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if p.allowedImports != nil {
+		for i := range m.ImportSection {
+			imp := &m.ImportSection[i]
+			if !p.allowsImport(imp.Module, imp.Name) {
+				violate(PolicyRuleImport, "import[%q.%q] is not allowed", imp.Module, imp.Name)
+			}
+		}
+	}
+
+	if p.featuresSet {
+		// The module is already valid under the runtime features, so any error
+		// decoding or validating with fewer features is due to their use.
+		if restricted := r.enabledFeatures & p.allowedFeatures; restricted != r.enabledFeatures {
+			if err := decodeAndValidate(binary, restricted, r); err != nil {
+				violate(PolicyRuleCoreFeatures, "%v", err)
+			}
+		}
+	}
+
+	if p.maxMemoryPages > 0 {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == wasm.ExternTypeMemory {
+				p.checkMemory(fmt.Sprintf("import[%q.%q] memory", imp.Module, imp.Name), imp.DescMem, r, violate)
+			}
+		}
+		if m.MemorySection != nil {
+			p.checkMemory("memory", m.MemorySection, r, violate)
+		}
+	}
+
+	if p.maxTableSize > 0 {
+		for i := range m.ImportSection {
+			if imp := &m.ImportSection[i]; imp.Type == wasm.ExternTypeTable {
+				p.checkTable(fmt.Sprintf("import[%q.%q] table", imp.Module, imp.Name), &imp.DescTable, violate)
+			}
+		}
+		for i := range m.TableSection {
+			p.checkTable(fmt.Sprintf("table[%d]", i), &m.TableSection[i], violate)
+		}
+	}
+
+	if p.maxFunctions > 0 {
+		if count := uint32(len(m.FunctionSection)); count > p.maxFunctions {
+			violate(PolicyRuleMaxFunctions, "%d functions over limit of %d", count, p.maxFunctions)
+		}
+	}
+
+	if p.maxFunctionSize > 0 {
+		for i := range m.CodeSection {
+			if size := uint32(len(m.CodeSection[i].Body)); size > p.maxFunctionSize {
+				violate(PolicyRuleMaxFunctionSize, "function[%d] body of %d bytes over limit of %d bytes",
+					uint32(i)+m.ImportFunctionCount, size, p.maxFunctionSize)
+			}
+		}
+	}
+
+	if violations != nil {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func (p *policy) allowsImport(moduleName, name string) bool {
+	names, ok := p.allowedImports[moduleName]
+	if !ok {
+		return false
+	} else if names == nil {
+		return true
+	}
+	_, ok = names[name]
+	return ok
+}
+
+func (p *policy) checkMemory(desc string, mem *wasm.Memory, r *runtime, violate func(PolicyRule, string, ...interface{})) {
+	max := mem.Max
+	if !mem.IsMaxEncoded {
+		max = r.memoryLimitPages
+	} else if mem.IsPageSizeEncoded {
+		// Compare the size in bytes, as the limit is in pages of 64KiB.
+		if bytesNum := mem.PagesToBytesNum(max); bytesNum > wasm.MemoryPagesToBytesNum(p.maxMemoryPages) {
+			violate(PolicyRuleMaxMemoryPages, "%s max %d pages (%s) over limit of %d pages (%s)", desc,
+				max, mem.PagesToUnitOfBytes(max), p.maxMemoryPages, wasm.PagesToUnitOfBytes(p.maxMemoryPages))
+		}
+		return
+	}
+	if max > p.maxMemoryPages {
+		violate(PolicyRuleMaxMemoryPages, "%s max %d pages (%s) over limit of %d pages (%s)", desc,
+			max, wasm.PagesToUnitOfBytes(max), p.maxMemoryPages, wasm.PagesToUnitOfBytes(p.maxMemoryPages))
+	}
+}
+
+func (p *policy) checkTable(desc string, table *wasm.Table, violate func(PolicyRule, string, ...interface{})) {
+	max := table.Min
+	if table.Max != nil {
+		max = *table.Max
+	}
+	if max > p.maxTableSize {
+		violate(PolicyRuleMaxTableSize, "%s max size %d over limit of %d", desc, max, p.maxTableSize)
+	}
+}
+
+// decodeAndValidate decodes and validates `binary` with the given features,
+// discarding the result.
+func decodeAndValidate(binary []byte, features api.CoreFeatures, r *runtime) error {
+	m, err := binaryformat.DecodeModule(binary, features, r.memoryLimitPages, r.memoryCapacityFromMax, false, false)
+	if err != nil {
+		return err
+	}
+	return m.Validate(features)
+}
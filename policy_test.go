@@ -0,0 +1,165 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		with     func(Policy) Policy
+		expected *policy
+	}{
+		{
+			name: "WithAllowedImports module",
+			with: func(p Policy) Policy {
+				return p.WithAllowedImports("wasi_snapshot_preview1")
+			},
+			expected: &policy{allowedImports: map[string]map[string]struct{}{"wasi_snapshot_preview1": nil}},
+		},
+		{
+			name: "WithAllowedImports names",
+			with: func(p Policy) Policy {
+				return p.WithAllowedImports("env", "abort").WithAllowedImports("env", "seed")
+			},
+			expected: &policy{allowedImports: map[string]map[string]struct{}{
+				"env": {"abort": {}, "seed": {}},
+			}},
+		},
+		{
+			name: "WithAllowedImports module wins over names",
+			with: func(p Policy) Policy {
+				return p.WithAllowedImports("env").WithAllowedImports("env", "abort")
+			},
+			expected: &policy{allowedImports: map[string]map[string]struct{}{"env": nil}},
+		},
+		{
+			name: "WithAllowedCoreFeatures",
+			with: func(p Policy) Policy {
+				return p.WithAllowedCoreFeatures(api.CoreFeaturesV1)
+			},
+			expected: &policy{allowedFeatures: api.CoreFeaturesV1, featuresSet: true},
+		},
+		{
+			name:     "WithMaxMemoryPages",
+			with:     func(p Policy) Policy { return p.WithMaxMemoryPages(1) },
+			expected: &policy{maxMemoryPages: 1},
+		},
+		{
+			name:     "WithMaxTableSize",
+			with:     func(p Policy) Policy { return p.WithMaxTableSize(2) },
+			expected: &policy{maxTableSize: 2},
+		},
+		{
+			name:     "WithMaxFunctions",
+			with:     func(p Policy) Policy { return p.WithMaxFunctions(3) },
+			expected: &policy{maxFunctions: 3},
+		},
+		{
+			name:     "WithMaxFunctionSize",
+			with:     func(p Policy) Policy { return p.WithMaxFunctionSize(4) },
+			expected: &policy{maxFunctionSize: 4},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			input := &policy{}
+			require.Equal(t, tc.expected, tc.with(input))
+			// The source wasn't modified
+			require.Equal(t, &policy{}, input)
+		})
+	}
+}
+
+func TestRuntime_CompileModule_Policy(t *testing.T) {
+	tableMax := uint32(10)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{}, {Results: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}}},
+		ImportSection: []wasm.Import{
+			{Type: wasm.ExternTypeFunc, Module: "env", Name: "abort", DescFunc: 0},
+			{Type: wasm.ExternTypeFunc, Module: "wasi_snapshot_preview1", Name: "fd_write", DescFunc: 0},
+		},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeI32Const, 1, wasm.OpcodeI32Const, 2, wasm.OpcodeEnd}},
+		},
+		MemorySection: &wasm.Memory{Min: 1, Max: 4, IsMaxEncoded: true},
+		TableSection:  []wasm.Table{{Min: 1, Max: &tableMax}},
+	})
+
+	tests := []struct {
+		name        string
+		policy      Policy
+		expectedErr string
+	}{
+		{
+			name:   "default allows all",
+			policy: NewPolicy(),
+		},
+		{
+			name: "within limits",
+			policy: NewPolicy().
+				WithAllowedImports("env", "abort").
+				WithAllowedImports("wasi_snapshot_preview1").
+				WithAllowedCoreFeatures(api.CoreFeaturesV2).
+				WithMaxMemoryPages(4).
+				WithMaxTableSize(10).
+				WithMaxFunctions(2).
+				WithMaxFunctionSize(5),
+		},
+		{
+			name:        "import",
+			policy:      NewPolicy().WithAllowedImports("env", "seed"),
+			expectedErr: `module violates policy: import: import["env"."abort"] is not allowed; import: import["wasi_snapshot_preview1"."fd_write"] is not allowed`,
+		},
+		{
+			name:        "core features",
+			policy:      NewPolicy().WithAllowedCoreFeatures(api.CoreFeaturesV1),
+			expectedErr: "module violates policy: core_features: section type: read 1-th type: multiple result types invalid as feature \"multi-value\" is disabled",
+		},
+		{
+			name:        "memory",
+			policy:      NewPolicy().WithMaxMemoryPages(2),
+			expectedErr: "module violates policy: max_memory_pages: memory max 4 pages (256 Ki) over limit of 2 pages (128 Ki)",
+		},
+		{
+			name:        "table",
+			policy:      NewPolicy().WithMaxTableSize(5),
+			expectedErr: "module violates policy: max_table_size: table[0] max size 10 over limit of 5",
+		},
+		{
+			name: "functions",
+			policy: NewPolicy().
+				WithMaxFunctions(1).
+				WithMaxFunctionSize(1),
+			expectedErr: "module violates policy: max_functions: 2 functions over limit of 1; max_function_size: function[3] body of 5 bytes over limit of 1 bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithPolicy(tc.policy))
+			defer r.Close(testCtx)
+
+			_, err := r.CompileModule(testCtx, bin)
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedErr)
+			_, ok := err.(*PolicyError)
+			require.True(t, ok)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package wazero
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+// preInitializeMinZeroGap is the minimum count of zero bytes which splits
+// the memory snapshot into separate data segments. Smaller gaps are cheaper
+// to encode than the header of another segment.
+const preInitializeMinZeroGap = 16
+
+// PreInitialize instantiates the WebAssembly binary (%.wasm), calls its
+// exported function initName, then returns a new binary whose memory and
+// mutable globals are initialized to their resulting values. This is similar
+// to wizer: expensive guest initialization runs once, and each instantiation
+// of the result starts where it left off.
+//
+// The config configures the instantiation, e.g. the environment visible to
+// initName, but its name is ignored. initName can be empty, e.g. when
+// initialization is done by the start function or ModuleConfig.WithInitializers.
+//
+// For example:
+//
+//	bin, err = wazero.PreInitialize(ctx, r, bin, wazero.NewModuleConfig(), "wizer.initialize")
+//	compiled, err = r.CompileModule(ctx, bin)
+//
+// # Notes
+//
+//   - The result has no start function, and doesn't export initName, as they
+//     already ran.
+//   - Only state defined by the module is captured. Changes to tables, to
+//     globals with non-null references, or to anything imported, such as
+//     files or host modules, are not.
+//   - An error is returned if the module imports its memory, as its contents
+//     can't be captured in the result.
+func PreInitialize(ctx context.Context, r Runtime, bin []byte, config ModuleConfig, initName string) ([]byte, error) {
+	m, err := wasmbin.Decode(bin)
+	if err != nil {
+		return nil, err
+	}
+	var importedGlobals int
+	for i := range m.Imports {
+		switch m.Imports[i].Type {
+		case api.ExternTypeMemory:
+			return nil, errors.New("pre-initialization of an imported memory is not supported")
+		case api.ExternTypeGlobal:
+			importedGlobals++
+		}
+	}
+
+	if config == nil {
+		config = NewModuleConfig()
+	}
+	mod, err := r.InstantiateWithConfig(ctx, bin, config.WithName(""))
+	if err != nil {
+		return nil, err
+	}
+	defer mod.Close(ctx)
+
+	if initName != "" {
+		fn := mod.ExportedFunction(initName)
+		if fn == nil {
+			return nil, fmt.Errorf("function[%s] is not exported", initName)
+		}
+		if _, err = fn.Call(ctx); err != nil {
+			return nil, fmt.Errorf("function[%s] failed: %w", initName, err)
+		}
+	}
+
+	instance := mod.(*wasm.ModuleInstance)
+	for i := range m.Globals {
+		if g := &m.Globals[i]; g.Mutable {
+			if init, ok := constInstruction(instance.Globals[importedGlobals+i]); ok {
+				g.Init = init
+			}
+		}
+	}
+
+	if mem := mod.Memory(); m.Memory != nil && mem != nil {
+		m.Memory.Min = mem.Size() / wasm.MemoryPageSize
+
+		// Active segments are replaced by the snapshot. When instructions may
+		// refer to segments by index, they are retained as empty passive
+		// segments, so that the indexes of others don't change.
+		data := m.Data[:0]
+		if hasSection(m, wasmbin.SectionIDDataCount) {
+			for _, d := range m.Data {
+				if !d.Passive {
+					d = wasmbin.DataSegment{Passive: true}
+				}
+				data = append(data, d)
+			}
+		}
+		m.Data = data
+		buf, _ := mem.Read(0, mem.Size())
+		m.Data = append(m.Data, snapshotDataSegments(buf)...)
+	}
+
+	m.Start = nil
+	if initName != "" {
+		exports := m.Exports[:0]
+		for _, e := range m.Exports {
+			if e.Name != initName {
+				exports = append(exports, e)
+			}
+		}
+		m.Exports = exports
+	}
+	return wasmbin.Encode(m), nil
+}
+
+// constInstruction returns the constant instruction of the global's value,
+// or false if it can't be represented, e.g. a non-null reference.
+func constInstruction(g *wasm.GlobalInstance) (wasmbin.Instruction, bool) {
+	switch t := g.Type.ValType; t {
+	case wasm.ValueTypeI32:
+		return wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: wasmbin.EncodeInt32(int32(g.Val))}, true
+	case wasm.ValueTypeI64:
+		return wasmbin.Instruction{Opcode: wasm.OpcodeI64Const, Immediates: wasmbin.EncodeInt64(int64(g.Val))}, true
+	case wasm.ValueTypeF32:
+		return wasmbin.Instruction{Opcode: wasm.OpcodeF32Const, Immediates: binary.LittleEndian.AppendUint32(nil, uint32(g.Val))}, true
+	case wasm.ValueTypeF64:
+		return wasmbin.Instruction{Opcode: wasm.OpcodeF64Const, Immediates: binary.LittleEndian.AppendUint64(nil, g.Val)}, true
+	case wasm.ValueTypeV128:
+		v := binary.LittleEndian.AppendUint64(nil, g.Val)
+		v = binary.LittleEndian.AppendUint64(v, g.ValHi)
+		return wasmbin.Instruction{Opcode: wasmbin.OpcodeVecPrefix, Subopcode: uint32(wasm.OpcodeVecV128Const), Immediates: v}, true
+	default: // reference types
+		if g.Val == 0 {
+			return wasmbin.Instruction{Opcode: wasm.OpcodeRefNull, Immediates: []byte{t}}, true
+		}
+		return wasmbin.Instruction{}, false
+	}
+}
+
+func hasSection(m *wasmbin.Module, id wasmbin.SectionID) bool {
+	for _, s := range m.Sections {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotDataSegments returns active data segments which initialize memory
+// to `buf`, skipping runs of zeros.
+func snapshotDataSegments(buf []byte) (ret []wasmbin.DataSegment) {
+	for i := 0; i < len(buf); {
+		if buf[i] == 0 {
+			i++
+			continue
+		}
+		start, end := i, i+1
+		for i = end; i < len(buf) && i-end < preInitializeMinZeroGap; i++ {
+			if buf[i] != 0 {
+				end = i + 1
+			}
+		}
+		ret = append(ret, wasmbin.DataSegment{
+			Offset: wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: wasmbin.EncodeInt32(int32(start))},
+			Init:   buf[start:end],
+		})
+		i = end
+	}
+	return
+}
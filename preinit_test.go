@@ -0,0 +1,131 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestPreInitialize(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}, {Results: []api.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0, 1},
+		CodeSection: []wasm.Code{
+			{Body: []byte{ // init: store 42 at 70000 and set the global to 7.
+				wasm.OpcodeI32Const, 0xf0, 0xa2, 0x04, // 70000
+				wasm.OpcodeI32Const, 42,
+				wasm.OpcodeI32Store, 0x2, 0x0,
+				wasm.OpcodeI32Const, 7,
+				wasm.OpcodeGlobalSet, 0,
+				wasm.OpcodeEnd,
+			}},
+			{Body: []byte{ // get: return the global plus the value at 70000.
+				wasm.OpcodeGlobalGet, 0,
+				wasm.OpcodeI32Const, 0xf0, 0xa2, 0x04, // 70000
+				wasm.OpcodeI32Load, 0x2, 0x0,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeGlobalGet, 1,
+				wasm.OpcodeI32Add,
+				wasm.OpcodeEnd,
+			}},
+		},
+		MemorySection: &wasm.Memory{Min: 2, Max: 3, IsMaxEncoded: true},
+		GlobalSection: []wasm.Global{
+			{
+				Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+				Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			},
+			{
+				Type: wasm.GlobalType{ValType: wasm.ValueTypeI32},
+				Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+			},
+		},
+		DataSection: []wasm.DataSegment{{
+			OffsetExpression: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{0}},
+			Init:             []byte{0, 0, 1},
+		}},
+		ExportSection: []wasm.Export{
+			{Name: "init", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "get", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "memory", Type: api.ExternTypeMemory, Index: 0},
+		},
+	})
+
+	preinitialized, err := PreInitialize(testCtx, r, bin, nil, "init")
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, preinitialized)
+	require.NoError(t, err)
+	require.Nil(t, mod.ExportedFunction("init"))
+
+	results, err := mod.ExportedFunction("get").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7+42+1), results[0])
+
+	// The original data segment is in the snapshot.
+	b, ok := mod.Memory().ReadByte(2)
+	require.True(t, ok)
+	require.Equal(t, byte(1), b)
+}
+
+func TestPreInitialize_table(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	i32 := wasm.ValueTypeI32
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []api.ValueType{i32}}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}},
+			{Body: []byte{ // call: call_indirect the function at table[1].
+				wasm.OpcodeI32Const, 1,
+				wasm.OpcodeCallIndirect, 0, 0,
+				wasm.OpcodeEnd,
+			}},
+		},
+		TableSection: []wasm.Table{{Min: 2, Type: wasm.RefTypeFuncref}},
+		ElementSection: []wasm.ElementSegment{{
+			OffsetExpr: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{1}},
+			Init:       []wasm.Index{0},
+			Type:       wasm.RefTypeFuncref,
+			Mode:       wasm.ElementModeActive,
+		}},
+		ExportSection: []wasm.Export{{Name: "call", Type: api.ExternTypeFunc, Index: 1}},
+	})
+
+	preinitialized, err := PreInitialize(testCtx, r, bin, nil, "")
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, preinitialized)
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), results[0])
+}
+
+func TestPreInitialize_errors(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	t.Run("imported memory", func(t *testing.T) {
+		bin := binaryencoding.EncodeModule(&wasm.Module{
+			ImportSection: []wasm.Import{{Module: "env", Name: "memory", Type: api.ExternTypeMemory, DescMem: &wasm.Memory{Min: 1}}},
+		})
+		_, err := PreInitialize(testCtx, r, bin, nil, "")
+		require.EqualError(t, err, "pre-initialization of an imported memory is not supported")
+	})
+
+	t.Run("init not exported", func(t *testing.T) {
+		bin := binaryencoding.EncodeModule(&wasm.Module{})
+		_, err := PreInitialize(testCtx, r, bin, nil, "init")
+		require.EqualError(t, err, "function[init] is not exported")
+	})
+}
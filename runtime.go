@@ -2,14 +2,18 @@ package wazero
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	experimentalapi "github.com/tetratelabs/wazero/experimental"
 	internalclose "github.com/tetratelabs/wazero/internal/close"
+	"github.com/tetratelabs/wazero/internal/engine/interpreter"
 	internalsock "github.com/tetratelabs/wazero/internal/sock"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/trace"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	binaryformat "github.com/tetratelabs/wazero/internal/wasm/binary"
 	"github.com/tetratelabs/wazero/sys"
@@ -99,6 +103,21 @@ type Runtime interface {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#name-section%E2%91%A0
 	CompileModule(ctx context.Context, binary []byte) (CompiledModule, error)
 
+	// ValidateModule decodes and validates the WebAssembly binary (%.wasm),
+	// returning all errors found, or nil if it is valid. Unlike
+	// CompileModule, this continues after the first error, for example
+	// reporting each invalid function. Errors are InvalidModuleError.
+	//
+	// This is useful to fix machine-generated modules, as it doesn't compile.
+	//
+	// # Notes
+	//
+	//   - Decoding errors stop validation, so at most one is returned.
+	//   - Some validation errors can hide others, such as an import of an
+	//     unknown type.
+	//   - Policy isn't enforced, as it applies to compilation.
+	ValidateModule(ctx context.Context, binary []byte) []error
+
 	// InstantiateModule instantiates the module or errs for reasons including
 	// exit or validation.
 	//
@@ -120,6 +139,34 @@ type Runtime interface {
 	//     cancellation or deadline triggered before a start function returned.
 	InstantiateModule(ctx context.Context, compiled CompiledModule, config ModuleConfig) (api.Module, error)
 
+	// Link instantiates two compiled modules which import from each other,
+	// returning their instances. The module names, set with ModuleConfig
+	// WithName, are the mapping between them: imports of `a` from the name of
+	// `b` resolve to exports of `b`, and vice versa.
+	//
+	// Unlike InstantiateModule, the modules can depend on each other. If only
+	// one depends on the other, they are instantiated in dependency order.
+	// Otherwise, `a` is instantiated first, with its imports from `b` bound
+	// to `b` once it is instantiated. Only function imports can be bound
+	// late, so when `a` imports memory, a table or a global from `b`, but `b`
+	// only imports functions from `a`, `b` is instantiated first instead.
+	//
+	// Here's an example:
+	//	app, plugin, _ := r.Link(ctx, compiledApp, compiledPlugin,
+	//		wazero.NewModuleConfig().WithName("app"),
+	//		wazero.NewModuleConfig().WithName("plugin"))
+	//
+	// # Notes
+	//
+	//   - Both configs must have distinct names.
+	//   - Failure cases are documented on InstantiateModule. On failure, no
+	//     module remains instantiated.
+	//   - Start functions of the module instantiated first must not call
+	//     functions it imports from the other, as it isn't instantiated yet.
+	//   - Late-bound calls are slower than calls to imports resolved at
+	//     instantiation.
+	Link(ctx context.Context, a, b CompiledModule, aConfig, bConfig ModuleConfig) (modA, modB api.Module, err error)
+
 	// CloseWithExitCode closes all the modules that have been initialized in this Runtime with the provided exit code.
 	// An error is returned if any module returns an error when closed.
 	//
@@ -133,9 +180,77 @@ type Runtime interface {
 	//	mod, _ := r.Instantiate(ctx, wasm)
 	CloseWithExitCode(ctx context.Context, exitCode uint32) error
 
+	// Shutdown stops new instantiations, waits for calls of exported
+	// functions in flight to return, then closes the runtime, like Close.
+	//
+	// If the context is done before the calls return, the runtime is closed
+	// anyway, and the error of the context is returned. In this case, closing
+	// invalidates the code under the calls, so their results are undefined.
+	//
+	// Here's an example that allows in-flight calls up to 10 seconds:
+	//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	//	defer cancel()
+	//	err := r.Shutdown(ctx)
+	//
+	// Notes:
+	//   - Instantiations in progress, including their start functions, are
+	//     waited for as well.
+	//   - Calls made while shutting down, such as by other in-flight calls,
+	//     are allowed and waited for.
+	//   - Calling this from a host function waits until the context is done,
+	//     as the call of the host function is in flight.
+	Shutdown(ctx context.Context) error
+
 	// Module returns an instantiated module in this runtime or nil if there aren't any.
 	Module(moduleName string) api.Module
 
+	// AliasModule makes the instantiated module `moduleName` importable by
+	// `alias` too, so that one implementation satisfies guests which expect
+	// different module names. For example, this allows guests to import
+	// host functions from "wasi_ext" which are instantiated as "env":
+	//
+	//	_ = r.AliasModule("env", "wasi_ext")
+	//
+	// # Notes
+	//
+	//   - An error is returned if `moduleName` isn't instantiated, or
+	//     `alias` is empty or already the name of a module.
+	//   - Module returns the same module for `alias`, whose Name is still
+	//     `moduleName`.
+	//   - The alias is removed when the module is closed.
+	AliasModule(moduleName, alias string) error
+
+	// OnBeforeInstantiate registers a hook called by InstantiateModule before
+	// each module is instantiated, which can replace its context and
+	// configuration. This allows libraries to compose cross-cutting concerns,
+	// such as defaulting configuration, instead of every embedder wrapping
+	// InstantiateModule.
+	//
+	// # Notes
+	//
+	//   - Hooks are called in registration order, with the result of the
+	//     previous one, including for host modules. They aren't called for
+	//     modules the runtime instantiates itself, such as stubs of optional
+	//     imports.
+	//   - Hooks registered while a module is instantiated don't apply to it.
+	//   - See BeforeInstantiateHook for an example.
+	OnBeforeInstantiate(BeforeInstantiateHook)
+
+	// OnAfterInstantiate registers a hook called by InstantiateModule with
+	// each module after its start functions and initializers succeeded. This
+	// allows libraries to compose cross-cutting concerns, such as registering
+	// instances or seeding memory, instead of every embedder wrapping
+	// InstantiateModule.
+	//
+	// # Notes
+	//
+	//   - Hooks are called in registration order, including for host modules,
+	//     but not for modules the runtime instantiates itself.
+	//   - Hooks registered while a module is instantiated don't apply to it.
+	//   - An error closes the module and skips the remaining hooks.
+	//   - See AfterInstantiateHook for an example.
+	OnAfterInstantiate(AfterInstantiateHook)
+
 	// Closer closes all compiled code by delegating to CloseWithExitCode with an exit code of zero.
 	api.Closer
 }
@@ -148,6 +263,13 @@ func NewRuntime(ctx context.Context) Runtime {
 // NewRuntimeWithConfig returns a runtime with the given configuration.
 func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 	config := rConfig.(*runtimeConfig)
+	if config.execForbidden {
+		// This uses a distinct engine kind, so that a shared Cache doesn't
+		// reuse an engine which maps executable memory.
+		config = config.clone()
+		config.engineKind = engineKindInterpreterExecForbidden
+		config.newEngine = interpreter.NewEngineExecForbidden
+	}
 	var engine wasm.Engine
 	var cacheImpl *cache
 	if c := config.cache; c != nil {
@@ -159,6 +281,21 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		engine = config.newEngine(ctx, config.enabledFeatures, nil)
 	}
 	store := wasm.NewStore(config.enabledFeatures, engine)
+	if enabled, ok := ctx.Value(experimentalapi.MetricsKey{}).(bool); ok && enabled {
+		store.Metrics = &wasm.StoreMetrics{}
+	}
+	if enabled, ok := ctx.Value(experimentalapi.TraceKey{}).(bool); ok && enabled {
+		store.Trace = true
+	}
+	if handler, ok := ctx.Value(experimentalapi.PanicHandlerKey{}).(experimentalapi.PanicHandler); ok {
+		store.PanicHandler = handler
+	}
+	store.MaxInstances = config.maxInstances
+	store.OnMaxInstancesExceeded = config.onMaxInstances
+	var leaks *leakTracker
+	if handler, ok := ctx.Value(experimentalapi.LeakDetectionKey{}).(experimentalapi.LeakHandler); ok {
+		leaks = newLeakTracker(handler)
+	}
 	return &runtime{
 		cache:                 cacheImpl,
 		store:                 store,
@@ -168,6 +305,9 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 		dwarfDisabled:         config.dwarfDisabled,
 		storeCustomSections:   config.storeCustomSections,
 		ensureTermination:     config.ensureTermination,
+		cooperativeYield:      config.cooperativeYield,
+		policy:                config.policy,
+		leaks:                 leaks,
 	}
 }
 
@@ -190,6 +330,28 @@ type runtime struct {
 	closed atomic.Uint64
 
 	ensureTermination bool
+	cooperativeYield  bool
+	policy            *policy
+	hooks             instantiateHooks
+
+	// leaks is non-nil when experimental.WithLeakDetection is enabled.
+	leaks *leakTracker
+
+	// shuttingDown is set by Shutdown to stop new instantiations.
+	shuttingDown atomic.Bool
+}
+
+// Metrics implements the same method as used by experimental.GetMetrics.
+func (r *runtime) Metrics() (experimentalapi.Metrics, bool) {
+	if r.store.Metrics == nil {
+		return experimentalapi.Metrics{}, false
+	}
+	return r.store.MetricsSnapshot(), true
+}
+
+// Engine is used by experimental/aot to check the engine of the runtime.
+func (r *runtime) Engine() wasm.Engine {
+	return r.store.Engine
 }
 
 // Module implements Runtime.Module.
@@ -200,20 +362,68 @@ func (r *runtime) Module(moduleName string) api.Module {
 	return r.store.Module(moduleName)
 }
 
+// AliasModule implements Runtime.AliasModule.
+func (r *runtime) AliasModule(moduleName, alias string) error {
+	if err := r.failIfClosed(); err != nil {
+		return err
+	} else if len(alias) == 0 {
+		return errors.New("alias must not be empty")
+	}
+	return r.store.AliasModule(moduleName, alias)
+}
+
 // CompileModule implements Runtime.CompileModule
 func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledModule, error) {
+	if r.store.Trace && trace.IsEnabled() {
+		var task *trace.Task
+		ctx, task = trace.NewTask(ctx, "wazero.CompileModule")
+		defer task.End()
+	}
+
+	m := r.store.Metrics
+	if m == nil {
+		return r.compileModule(ctx, binary)
+	}
+
+	var cacheHit bool
+	start := time.Now()
+	c, err := r.compileModule(context.WithValue(ctx, wasm.CompiledFromCacheKey{}, &cacheHit), binary)
+	if err == nil {
+		m.CompilationTime.Add(int64(time.Since(start)))
+		m.Compilations.Add(1)
+		if cacheHit {
+			m.CacheHits.Add(1)
+		}
+	}
+	return c, err
+}
+
+func (r *runtime) compileModule(ctx context.Context, binary []byte) (CompiledModule, error) {
 	if err := r.failIfClosed(); err != nil {
 		return nil, err
 	}
 
+	if exports, ok := ctx.Value(experimentalapi.DeadCodeEliminationKey{}).([]string); ok {
+		var err error
+		if binary, err = r.eliminateDeadCode(binary, exports); err != nil {
+			return nil, err
+		}
+	}
+
 	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
 		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections)
 	if err != nil {
-		return nil, err
+		return nil, invalidModuleError(err)
 	} else if err = internal.Validate(r.enabledFeatures); err != nil {
 		// TODO: decoders should validate before returning, as that allows
 		// them to err with the correct position in the wasm binary.
-		return nil, err
+		return nil, invalidModuleError(err)
+	}
+
+	if r.policy != nil {
+		if err = r.policy.check(binary, internal, r); err != nil {
+			return nil, err
+		}
 	}
 
 	// Now that the module is validated, cache the memory definitions.
@@ -233,13 +443,48 @@ func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledMod
 	if err != nil {
 		return nil, err
 	}
+	if intrinsics, ok := ctx.Value(experimentalapi.IntrinsicsKey{}).([]experimentalapi.Intrinsic); ok {
+		internal.ApplyIntrinsics(intrinsics)
+	}
+	internal.CooperativeYield = r.cooperativeYield
+	internal.SSAInspected = ctx.Value(experimentalapi.SSAInspectorKey{}) != nil
 	internal.AssignModuleID(binary, len(listeners) > 0, r.ensureTermination)
 	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
 		return nil, err
 	}
+	if r.leaks != nil {
+		r.leaks.trackCompiled(c)
+	}
 	return c, nil
 }
 
+// ValidateModule implements Runtime.ValidateModule
+func (r *runtime) ValidateModule(_ context.Context, binary []byte) []error {
+	if err := r.failIfClosed(); err != nil {
+		return []error{err}
+	}
+
+	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
+		r.memoryLimitPages, r.memoryCapacityFromMax, false, false)
+	if err != nil {
+		return []error{invalidModuleError(err)}
+	}
+	errs := internal.ValidateAll(r.enabledFeatures)
+	for i, err := range errs {
+		errs[i] = invalidModuleError(err)
+	}
+	return errs
+}
+
+// invalidModuleError wraps `err` in an InvalidModuleError, unless it already
+// is one.
+func invalidModuleError(err error) error {
+	if _, ok := err.(*InvalidModuleError); ok {
+		return err
+	}
+	return &InvalidModuleError{Offset: -1, Err: err}
+}
+
 func buildFunctionListeners(ctx context.Context, internal *wasm.Module) ([]experimentalapi.FunctionListener, error) {
 	// Test to see if internal code are using an experimental feature.
 	fnlf := ctx.Value(experimentalapi.FunctionListenerFactoryKey{})
@@ -283,11 +528,67 @@ func (r *runtime) InstantiateModule(
 	ctx context.Context,
 	compiled CompiledModule,
 	mConfig ModuleConfig,
+) (mod api.Module, err error) {
+	return r.instantiateModule(ctx, compiled, mConfig, true)
+}
+
+// instantiateModule is like InstantiateModule, except the hooks registered by
+// Runtime.OnBeforeInstantiate and OnAfterInstantiate are only called when
+// `withHooks`. Modules the runtime instantiates internally, such as stubs,
+// don't call them, as users don't know of these.
+func (r *runtime) instantiateModule(
+	ctx context.Context,
+	compiled CompiledModule,
+	mConfig ModuleConfig,
+	withHooks bool,
 ) (mod api.Module, err error) {
 	if err = r.failIfClosed(); err != nil {
 		return nil, err
 	}
 
+	// Count the instantiation as in flight before checking Shutdown, so that
+	// it either fails or is waited for.
+	r.store.BeginCall()
+	defer r.store.EndCall()
+	if r.shuttingDown.Load() {
+		if code := compiled.(*compiledModule); code.closeWithModule {
+			_ = code.Close(ctx)
+		}
+		return nil, errors.New("runtime shutting down")
+	}
+
+	if r.store.Trace && trace.IsEnabled() {
+		var task *trace.Task
+		ctx, task = trace.NewTask(ctx, "wazero.InstantiateModule")
+		defer task.End()
+	}
+
+	if m := r.store.Metrics; m != nil {
+		start := time.Now()
+		defer func() {
+			if err == nil {
+				m.InstantiationTime.Add(int64(time.Since(start)))
+				m.Instantiations.Add(1)
+			}
+		}()
+	}
+
+	var before []BeforeInstantiateHook
+	var after []AfterInstantiateHook
+	if withHooks {
+		before, after = r.hooks.snapshot()
+	}
+	if len(before) > 0 {
+		var hookCtx context.Context
+		if hookCtx, mConfig, err = callBeforeInstantiate(ctx, compiled, mConfig, before); err != nil {
+			if code := compiled.(*compiledModule); code.closeWithModule {
+				_ = code.Close(ctx) // don't overwrite the error
+			}
+			return
+		}
+		ctx = hookCtx
+	}
+
 	code := compiled.(*compiledModule)
 	config := mConfig.(*moduleConfig)
 
@@ -307,10 +608,49 @@ func (r *runtime) InstantiateModule(
 	if !config.nameSet && code.module.NameSection != nil && code.module.NameSection.ModuleName != "" {
 		name = code.module.NameSection.ModuleName
 	}
+	if r.store.Trace {
+		trace.Log(ctx, "module", name)
+	}
+
+	// Bind imports from modules which aren't instantiated yet to proxies.
+	var proxies closers
+	if len(config.deferredImports) > 0 {
+		if proxies, err = r.instantiateDeferredImports(ctx, code.module, config.deferredImports); err != nil {
+			return
+		}
+	}
+
+	// Bind missing imports, which are optional, to stubs.
+	instantiateCtx := ctx
+	var stubs map[string]*wasm.ModuleInstance
+	if len(config.optionalImports) > 0 {
+		if stubs, err = r.instantiateOptionalImports(ctx, code.module, config.optionalImports, &proxies); err != nil {
+			proxies.release(r.store)
+			_ = proxies.Close(ctx)
+			return
+		}
+	}
+	// Likewise, bind missing globals to the values provided by the context.
+	if provider, ok := ctx.Value(experimentalapi.ImportedGlobalsKey{}).(experimentalapi.ImportedGlobals); ok && !code.module.IsHostModule {
+		if stubs, err = r.instantiateImportedGlobals(ctx, code.module, provider, stubs, &proxies); err != nil {
+			proxies.release(r.store)
+			_ = proxies.Close(ctx)
+			return
+		}
+	}
+	if len(stubs) > 0 {
+		instantiateCtx = context.WithValue(instantiateCtx, wasm.ImportFallbacksKey{}, stubs)
+	}
+	if config.labels != nil {
+		instantiateCtx = context.WithValue(instantiateCtx, wasm.LabelsKey{}, config.labels)
+	}
 
 	// Instantiate the module.
-	mod, err = r.store.Instantiate(ctx, code.module, name, sysCtx, code.typeIDs)
+	mod, err = r.store.Instantiate(instantiateCtx, code.module, name, sysCtx, code.typeIDs)
+	// Release the names of the proxies, so those modules can be instantiated.
+	proxies.release(r.store)
 	if err != nil {
+		_ = proxies.Close(ctx)
 		// If there was an error, don't leak the compiled module.
 		if code.closeWithModule {
 			_ = code.Close(ctx) // don't overwrite the error
@@ -318,6 +658,12 @@ func (r *runtime) InstantiateModule(
 		return
 	}
 
+	mod.(*wasm.ModuleInstance).CloseOnTrap = config.closeOnTrap
+	if handler, ok := ctx.Value(experimentalapi.PanicHandlerKey{}).(experimentalapi.PanicHandler); ok {
+		mod.(*wasm.ModuleInstance).PanicHandler = handler
+	}
+	mod.(*wasm.ModuleInstance).CloseOnContextDone = r.ensureTermination
+
 	if closeNotifier, ok := ctx.Value(internalclose.NotifierKey{}).(internalclose.Notifier); ok {
 		mod.(*wasm.ModuleInstance).CloseNotifier = closeNotifier
 	}
@@ -327,6 +673,27 @@ func (r *runtime) InstantiateModule(
 	if code.closeWithModule {
 		mod.(*wasm.ModuleInstance).CodeCloser = code
 	}
+	// Track the instance instead of the code closed with it, until it is
+	// closed.
+	if r.leaks != nil {
+		if code.closeWithModule && code.leaks != nil {
+			code.leaks.untrackCompiled(code)
+		}
+		untracker := r.leaks.trackInstance(mod)
+		if c := mod.(*wasm.ModuleInstance).CodeCloser; c != nil {
+			mod.(*wasm.ModuleInstance).CodeCloser = closers{c, untracker}
+		} else {
+			mod.(*wasm.ModuleInstance).CodeCloser = untracker
+		}
+	}
+	// Likewise, close the proxies of deferred imports and stubs of optional
+	// imports with the module.
+	if len(proxies) > 0 {
+		if c := mod.(*wasm.ModuleInstance).CodeCloser; c != nil {
+			proxies = append(proxies, c)
+		}
+		mod.(*wasm.ModuleInstance).CodeCloser = proxies
+	}
 
 	// Now, invoke any start functions, failing at first error.
 	for _, fn := range config.startFunctions {
@@ -347,6 +714,19 @@ func (r *runtime) InstantiateModule(
 			return
 		}
 	}
+
+	if err = callInitializers(ctx, mod, config.initializers); err != nil {
+		_ = mod.Close(ctx) // Don't leak the module on error.
+
+		if se, ok := err.(*sys.ExitError); ok && se.ExitCode() == 0 {
+			err = nil // Don't err on success.
+		}
+		return
+	}
+
+	if err = callAfterInstantiate(ctx, mod, after); err != nil {
+		_ = mod.Close(ctx) // Don't leak the module on error.
+	}
 	return
 }
 
@@ -355,6 +735,16 @@ func (r *runtime) Close(ctx context.Context) error {
 	return r.CloseWithExitCode(ctx, 0)
 }
 
+// Shutdown implements Runtime.Shutdown
+func (r *runtime) Shutdown(ctx context.Context) error {
+	r.shuttingDown.Store(true)
+	drainErr := r.store.Drain(ctx)
+	if err := r.Close(ctx); err != nil {
+		return err
+	}
+	return drainErr
+}
+
 // CloseWithExitCode implements Runtime.CloseWithExitCode
 //
 // Note: it also marks the internal `closed` field
@@ -363,6 +753,9 @@ func (r *runtime) CloseWithExitCode(ctx context.Context, exitCode uint32) error
 	if !r.closed.CompareAndSwap(0, closed) {
 		return nil
 	}
+	if r.leaks != nil {
+		r.leaks.report(ctx)
+	}
 	err := r.store.CloseWithExitCode(ctx, exitCode)
 	if r.cache == nil {
 		// Close the engine if the cache is not configured, which means that this engine is scoped in this runtime.
@@ -1,9 +1,12 @@
 package wazero
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"errors"
+	"fmt"
+	"runtime/trace"
 	"sync"
 	"testing"
 	"time"
@@ -16,6 +19,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -120,6 +124,7 @@ func TestRuntime_CompileModule(t *testing.T) {
 				max, ok := mem.Max()
 				require.Equal(t, uint32(3), max)
 				require.True(t, ok)
+				require.Equal(t, uint32(wasm.MemoryPageSize), mem.PageSize())
 			},
 		},
 	}
@@ -186,6 +191,76 @@ func TestRuntime_CompileModule_Errors(t *testing.T) {
 	}
 }
 
+func TestRuntime_ValidateModule(t *testing.T) {
+	invalidBody := []byte{wasm.OpcodeF32Abs, wasm.OpcodeEnd}
+	tests := []struct {
+		name         string
+		wasm         []byte
+		expectedErrs []string
+	}{
+		{
+			name:         "invalid binary",
+			wasm:         append(binaryencoding.Magic, []byte("yolo")...),
+			expectedErrs: []string{"invalid version header"},
+		},
+		{
+			name: "valid",
+			wasm: binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+			}),
+		},
+		{
+			name: "all errors",
+			wasm: binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0, 0, 0},
+				CodeSection:     []wasm.Code{{Body: invalidBody}, {Body: []byte{wasm.OpcodeEnd}}, {Body: invalidBody}},
+				ExportSection:   []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 3}},
+			}),
+			expectedErrs: []string{
+				`unknown function for export["f"]`,
+				"invalid function[0]: cannot pop the 1st f32 operand for f32.abs: f32 missing",
+				"invalid function[2]: cannot pop the 1st f32 operand for f32.abs: f32 missing",
+			},
+		},
+		{
+			name: "call of function with invalid type",
+			wasm: binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{1, 0},
+				CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+			}),
+			expectedErrs: []string{"invalid function[0]: type section index 1 out of range"},
+		},
+	}
+
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			errs := r.ValidateModule(testCtx, tc.wasm)
+			require.Equal(t, len(tc.expectedErrs), len(errs))
+			for i, err := range errs {
+				require.EqualError(t, err, tc.expectedErrs[i])
+				require.ErrorIs(t, err, ErrInvalidModule)
+			}
+
+			// CompileModule fails with the first error.
+			_, err := r.CompileModule(testCtx, tc.wasm)
+			if len(errs) == 0 {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, errs[0].Error())
+			}
+		})
+	}
+}
+
 // TestModule_Memory only covers a couple cases to avoid duplication of internal/wasm/runtime_test.go
 func TestModule_Memory(t *testing.T) {
 	tests := []struct {
@@ -235,6 +310,51 @@ func TestModule_Memory(t *testing.T) {
 	}
 }
 
+func TestModule_ExportNames(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().WithCustomSections(true))
+	defer r.Close(testCtx)
+
+	// Export names are deliberately not sorted, to show the order is that of
+	// the export section.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}, {Body: []byte{wasm.OpcodeEnd}}},
+		MemorySection:   &wasm.Memory{Min: 1},
+		ExportSection: []wasm.Export{
+			{Name: "z", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "mem", Type: api.ExternTypeMemory},
+			{Name: "a", Type: api.ExternTypeFunc, Index: 1},
+			{Name: "b", Type: api.ExternTypeMemory},
+			{Name: "m", Type: api.ExternTypeFunc, Index: 0},
+		},
+	})
+	// Append custom sections of one byte, which can have the same name.
+	for _, name := range []string{"z", "a", "z"} {
+		bin = append(bin, wasm.SectionIDCustom, byte(2+len(name)), byte(len(name)))
+		bin = append(bin, name...)
+		bin = append(bin, 0)
+	}
+	compiled, err := r.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"z", "a", "m"}, compiled.ExportedFunctionNames())
+	require.Equal(t, []string{"mem", "b"}, compiled.ExportedMemoryNames())
+	var customSectionNames []string
+	for _, s := range compiled.CustomSections() {
+		customSectionNames = append(customSectionNames, s.Name())
+	}
+	require.Equal(t, []string{"z", "a", "z"}, customSectionNames)
+
+	module, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig())
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"z", "a", "m"}, module.ExportedFunctionNames())
+	require.Equal(t, 3, len(module.ExportedFunctionDefinitions()))
+	require.Equal(t, []string{"mem", "b"}, module.ExportedMemoryNames())
+	require.Equal(t, 2, len(module.ExportedMemoryDefinitions()))
+}
+
 // TestModule_Global only covers a couple cases to avoid duplication of internal/wasm/global_test.go
 func TestModule_Global(t *testing.T) {
 	globalVal := int64(100) // intentionally a value that differs in signed vs unsigned encoding
@@ -366,6 +486,258 @@ func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
 	require.Equal(t, uint32(2), r.(*runtime).store.Engine.CompiledModuleCount())
 }
 
+func TestRuntime_Shutdown(t *testing.T) {
+	// newBlockingRuntime returns a runtime with the default configuration and a
+	// call in flight until release is closed, and the result of the call.
+	newBlockingRuntime := func(t *testing.T) (r Runtime, release chan struct{}, callErr chan error) {
+		r = NewRuntime(testCtx)
+		started, release, callErr := make(chan struct{}), make(chan struct{}), make(chan error)
+		_, err := r.NewHostModuleBuilder("env").
+			NewFunctionBuilder().WithFunc(func(context.Context) {
+			close(started)
+			<-release
+		}).Export("block").
+			Instantiate(testCtx)
+		require.NoError(t, err)
+
+		mod, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+			TypeSection:     []wasm.FunctionType{{}},
+			ImportSection:   []wasm.Import{{Module: "env", Name: "block", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+			FunctionSection: []wasm.Index{0},
+			CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+			ExportSection:   []wasm.Export{{Name: "block", Type: wasm.ExternTypeFunc, Index: 1}},
+		}))
+		require.NoError(t, err)
+
+		go func() {
+			_, err := mod.ExportedFunction("block").Call(testCtx)
+			callErr <- err
+		}()
+		<-started
+		return r, release, callErr
+	}
+
+	t.Run("drains", func(t *testing.T) {
+		r, release, callErr := newBlockingRuntime(t)
+
+		shutdownErr := make(chan error)
+		go func() { shutdownErr <- r.Shutdown(testCtx) }()
+
+		// Wait until instantiations are stopped.
+		for !r.(*runtime).shuttingDown.Load() {
+			time.Sleep(time.Millisecond)
+		}
+		_, err := r.Instantiate(testCtx, binaryNamedZero)
+		require.EqualError(t, err, "runtime shutting down")
+
+		select {
+		case <-shutdownErr:
+			t.Fatal("expected shutdown to wait for the call")
+		case <-time.After(10 * time.Millisecond):
+		}
+		require.NoError(t, r.(*runtime).failIfClosed())
+
+		close(release)
+		require.NoError(t, <-callErr)
+		require.NoError(t, <-shutdownErr)
+		require.EqualError(t, r.(*runtime).failIfClosed(), "runtime closed with exit_code(0)")
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		r, release, callErr := newBlockingRuntime(t)
+		defer func() {
+			close(release)
+			<-callErr
+		}()
+
+		ctx, cancel := context.WithTimeout(testCtx, 10*time.Millisecond)
+		defer cancel()
+		require.Equal(t, context.DeadlineExceeded, r.Shutdown(ctx))
+		require.EqualError(t, r.(*runtime).failIfClosed(), "runtime closed with exit_code(0)")
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		r := NewRuntime(testCtx)
+		_, err := r.Instantiate(testCtx, binaryNamedZero)
+		require.NoError(t, err)
+
+		require.NoError(t, r.Shutdown(testCtx))
+		require.Nil(t, r.Module("0"))
+	})
+}
+
+func TestRuntime_Metrics(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	_, ok := experimental.GetMetrics(r)
+	require.False(t, ok)
+
+	r = NewRuntimeWithConfig(experimental.WithMetrics(testCtx), NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	_, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func() {}).Export("f").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	binary := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		MemorySection:   &wasm.Memory{Min: 2, Cap: 2, Max: 2},
+		ExportSection:   []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+	compiled, err := r.CompileModule(testCtx, binary)
+	require.NoError(t, err)
+	_, err = r.CompileModule(testCtx, binary) // cache hit
+	require.NoError(t, err)
+
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+
+	f := mod.ExportedFunction("f")
+	_, err = f.Call(testCtx)
+	require.NoError(t, err)
+	require.NoError(t, f.CallWithStack(testCtx, nil))
+	require.Equal(t, []string{"f"}, f.Definition().ExportNames())
+
+	m, ok := experimental.GetMetrics(r)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), m.Compilations)
+	require.Equal(t, uint64(1), m.CacheHits)
+	require.Equal(t, uint64(1), m.CacheMisses)
+	require.Equal(t, uint64(3), m.Instantiations) // including the host module
+	require.Equal(t, uint64(2), m.ActiveInstances)
+	require.Equal(t, uint64(4), m.MemoryPages)
+	require.Equal(t, uint64(4*wasm.MemoryPageSize), m.MemoryBytes)
+	require.Equal(t, uint64(2), m.Calls)
+	require.True(t, m.CompilationTime > 0)
+	require.True(t, m.InstantiationTime > 0)
+
+	require.NoError(t, mod.Close(testCtx))
+	m, _ = experimental.GetMetrics(r)
+	require.Equal(t, uint64(1), m.ActiveInstances)
+	require.Equal(t, uint64(2), m.MemoryPages)
+	require.Equal(t, uint64(2*wasm.MemoryPageSize), m.MemoryBytes)
+}
+
+func TestRuntime_WithTrace(t *testing.T) {
+	// Metrics are also enabled to ensure the wrappers compose.
+	r := NewRuntimeWithConfig(experimental.WithTrace(experimental.WithMetrics(testCtx)), NewRuntimeConfigInterpreter())
+	defer r.Close(testCtx)
+
+	var buf bytes.Buffer
+	require.NoError(t, trace.Start(&buf))
+
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "f", Type: wasm.ExternTypeFunc, Index: 0}},
+	}))
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("traced"))
+	require.NoError(t, err)
+	f := mod.ExportedFunction("f")
+	_, err = f.Call(testCtx)
+	require.NoError(t, err)
+	require.NoError(t, f.CallWithStack(testCtx, nil))
+	_, err = f.CallWithOptions(testCtx, api.CallOptions{})
+	require.NoError(t, err)
+
+	trace.Stop()
+
+	// The strings are recorded verbatim in the trace.
+	for _, s := range []string{"wazero.CompileModule", "wazero.InstantiateModule", "traced", "wazero.Call traced.f"} {
+		require.True(t, bytes.Contains(buf.Bytes(), []byte(s)), s)
+	}
+
+	m, _ := experimental.GetMetrics(r)
+	require.Equal(t, uint64(3), m.Calls)
+}
+
+func TestRuntime_WithMaxInstances(t *testing.T) {
+	var rejected []string
+	r := NewRuntimeWithConfig(experimental.WithMetrics(testCtx), NewRuntimeConfigInterpreter().
+		WithMaxInstances(1, func(_ context.Context, moduleName string) {
+			rejected = append(rejected, moduleName)
+		}))
+	defer r.Close(testCtx)
+
+	// Host modules are not counted.
+	_, err := r.NewHostModuleBuilder("env").Instantiate(testCtx)
+	require.NoError(t, err)
+
+	compiled, err := r.CompileModule(testCtx, binaryencoding.EncodeModule(&wasm.Module{}))
+	require.NoError(t, err)
+
+	a, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("a"))
+	require.NoError(t, err)
+
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("b"))
+	require.ErrorIs(t, err, ErrMaxInstancesExceeded)
+	require.EqualError(t, err, "module[b]: maximum count of module instances exceeded")
+	require.Equal(t, []string{"b"}, rejected)
+
+	m, _ := experimental.GetMetrics(r)
+	require.Equal(t, uint64(1), m.RejectedInstantiations)
+
+	// Closing a module allows another, unless its instantiation fails.
+	require.NoError(t, a.Close(testCtx))
+	_, err = r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:   []wasm.FunctionType{{}},
+		ImportSection: []wasm.Import{{Module: "env", Name: "missing", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+	}))
+	require.ErrorIs(t, err, ErrUnsatisfiedImport)
+	_, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("b"))
+	require.NoError(t, err)
+}
+
+func TestRuntime_WithCooperativeYield(t *testing.T) {
+	// count returns the count of loop iterations, which is the param.
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{wasm.ValueTypeI32}, Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection: []wasm.Code{{LocalTypes: []wasm.ValueType{wasm.ValueTypeI32}, Body: []byte{
+			wasm.OpcodeLoop, 0x40,
+			wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeLocalSet, 1,
+			wasm.OpcodeLocalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Sub, wasm.OpcodeLocalTee, 0,
+			wasm.OpcodeBrIf, 0,
+			wasm.OpcodeEnd,
+			wasm.OpcodeLocalGet, 1,
+			wasm.OpcodeEnd,
+		}}},
+		ExportSection: []wasm.Export{{Name: "count", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+		{name: "default", config: NewRuntimeConfig()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, tc.config.WithCooperativeYield(true))
+			defer r.Close(testCtx)
+
+			m, err := r.Instantiate(testCtx, bin)
+			require.NoError(t, err)
+
+			// Unlike WithCloseOnContextDone, the module isn't closed even if the context is done.
+			ctx, cancel := context.WithCancel(testCtx)
+			cancel()
+			res, err := m.ExportedFunction("count").Call(ctx, 10000)
+			require.NoError(t, err)
+			require.Equal(t, uint64(10000), res[0])
+		})
+	}
+}
+
 // TestRuntime_Instantiate_DoesntEnforce_Start ensures wapc-go work when modules import WASI, but don't
 // export "_start".
 func TestRuntime_Instantiate_DoesntEnforce_Start(t *testing.T) {
@@ -467,6 +839,136 @@ func TestRuntime_InstantiateModule_WithName(t *testing.T) {
 	require.Nil(t, ret)
 }
 
+func TestRuntime_AliasModule(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	env, err := r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func() uint32 { return 42 }).
+		Export("answer").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	require.EqualError(t, r.AliasModule("unknown", "wasi_ext"), "module[unknown] not instantiated")
+	require.EqualError(t, r.AliasModule("env", ""), "alias must not be empty")
+	require.EqualError(t, r.AliasModule("env", "env"), "module[env] has already been instantiated")
+
+	require.NoError(t, r.AliasModule("env", "wasi_ext"))
+	require.Equal(t, env, r.Module("wasi_ext"))
+	require.Equal(t, "env", r.Module("wasi_ext").Name())
+
+	// A guest can import the module by its alias.
+	guest, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		ImportSection:   []wasm.Import{{Module: "wasi_ext", Name: "answer", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "call", Type: wasm.ExternTypeFunc, Index: 1}},
+	}))
+	require.NoError(t, err)
+	results, err := guest.ExportedFunction("call").Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+
+	// Closing the module removes its aliases.
+	require.NoError(t, env.Close(testCtx))
+	require.Nil(t, r.Module("wasi_ext"))
+}
+
+func TestRuntime_WithExecutableMemoryForbidden(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "answer", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	var events []experimental.CompilationCacheEventType
+	ctx := experimental.WithCompilationCacheListener(testCtx,
+		experimental.CompilationCacheListenerFunc(func(_ context.Context, e experimental.CompilationCacheEvent) {
+			require.NoError(t, e.Err)
+			if !e.InMemory {
+				events = append(events, e.Type)
+			}
+		}))
+	dir := t.TempDir()
+	newRuntime := func() (Runtime, CompilationCache) {
+		c, err := NewCompilationCacheWithDir(dir)
+		require.NoError(t, err)
+		// The compiler config is overridden, so modules are interpreted.
+		r := NewRuntimeWithConfig(ctx, NewRuntimeConfigCompiler().WithCompilationCache(c).WithExecutableMemoryForbidden(true))
+		require.Equal(t, "*interpreter.engine", fmt.Sprintf("%T", r.(*runtime).store.Engine))
+		return r, c
+	}
+
+	r, c := newRuntime()
+	mod, err := r.Instantiate(ctx, bin)
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("answer").Call(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+	require.Equal(t, []experimental.CompilationCacheEventType{
+		experimental.CompilationCacheEventMiss,
+		experimental.CompilationCacheEventStore,
+	}, events)
+
+	if platform.CompilerSupported() {
+		// Other runtimes, even sharing the cache, still use the compiler.
+		compiler := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithCompilationCache(c))
+		defer compiler.Close(testCtx)
+		require.NotEqual(t, "*interpreter.engine", fmt.Sprintf("%T", compiler.(*runtime).store.Engine))
+		_, err = compiler.Instantiate(testCtx, bin)
+		require.NoError(t, err)
+	}
+	require.NoError(t, r.Close(ctx))
+	require.NoError(t, c.Close(ctx))
+
+	// A new runtime loads the interpreted module from the file cache.
+	events = nil
+	r, c = newRuntime()
+	defer c.Close(ctx)
+	defer r.Close(ctx)
+	mod, err = r.Instantiate(ctx, bin)
+	require.NoError(t, err)
+	results, err = mod.ExportedFunction("answer").Call(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{42}, results)
+	require.Equal(t, []experimental.CompilationCacheEventType{
+		experimental.CompilationCacheEventHit,
+	}, events)
+}
+
+func TestRuntime_ForbidExecutableMemory(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeEnd}}},
+	})
+
+	compiler := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler())
+	defer compiler.Close(testCtx)
+	interpreter := NewRuntimeWithConfig(testCtx, NewRuntimeConfigCompiler().WithExecutableMemoryForbidden(true))
+	defer interpreter.Close(testCtx)
+
+	release := experimental.ForbidExecutableMemory()
+	defer release()
+
+	// While executable memory is forbidden, the compiler can't compile.
+	_, err := compiler.CompileModule(testCtx, bin)
+	require.Equal(t, platform.ErrExecutableMemoryForbidden, err)
+	_, err = interpreter.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+
+	release()
+	_, err = compiler.CompileModule(testCtx, bin)
+	require.NoError(t, err)
+}
+
 func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
 	r := NewRuntime(testCtx)
 	defer r.Close(testCtx)
@@ -546,6 +1048,232 @@ func TestRuntime_InstantiateModule_ExitError(t *testing.T) {
 	}
 }
 
+func TestModule_State(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}},
+		},
+		ExportSection: []wasm.Export{
+			{Name: "ok", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "trap", Type: wasm.ExternTypeFunc, Index: 1},
+		},
+	})
+
+	for _, c := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "compiler", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			r := NewRuntimeWithConfig(testCtx, config)
+			defer r.Close(testCtx)
+
+			compiled, err := r.CompileModule(testCtx, bin)
+			require.NoError(t, err)
+
+			t.Run("poisoned", func(t *testing.T) {
+				m, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName(t.Name()))
+				require.NoError(t, err)
+				require.Equal(t, api.ModuleStateHealthy, m.State())
+
+				_, err = m.ExportedFunction("ok").Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, api.ModuleStateHealthy, m.State())
+
+				// Errors before code runs don't poison the module.
+				_, err = m.ExportedFunction("ok").Call(testCtx, 1)
+				require.Error(t, err)
+				require.Equal(t, api.ModuleStateHealthy, m.State())
+
+				_, err = m.ExportedFunction("trap").Call(testCtx)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+				require.Equal(t, api.ModuleStatePoisoned, m.State())
+				require.False(t, m.IsClosed())
+
+				require.NoError(t, m.Close(testCtx))
+				require.Equal(t, api.ModuleStateClosed, m.State())
+			})
+
+			t.Run("WithCloseOnTrap", func(t *testing.T) {
+				m, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName(t.Name()).WithCloseOnTrap(true))
+				require.NoError(t, err)
+
+				_, err = m.ExportedFunction("trap").Call(testCtx)
+				require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+				require.Equal(t, api.ModuleStateClosed, m.State())
+
+				_, err = m.ExportedFunction("ok").Call(testCtx)
+				require.Equal(t, sys.NewExitError(sys.ExitCodeTrapped), err)
+			})
+		})
+	}
+}
+
+func TestRuntime_Labels(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}}},
+		MemorySection:   &wasm.Memory{Min: 1},
+		ExportSection:   []wasm.Export{{Name: "trap", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+
+	startFnIndex := wasm.Index(0)
+	startTrapBin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeUnreachable, wasm.OpcodeEnd}}},
+		StartSection:    &startFnIndex,
+	})
+
+	for _, c := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "compiler", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			ctx := experimental.WithMetrics(testCtx)
+			r := NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			compiled, err := r.CompileModule(ctx, bin)
+			require.NoError(t, err)
+
+			unlabeled, err := r.InstantiateModule(ctx, compiled, NewModuleConfig().WithName("unlabeled"))
+			require.NoError(t, err)
+			require.Nil(t, experimental.GetLabels(unlabeled))
+
+			mod, err := r.InstantiateModule(ctx, compiled, NewModuleConfig().WithName("labeled").
+				WithLabel("tenant", "acme").WithLabel("request", "1").WithLabel("request", "2"))
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{"tenant": "acme", "request": "2"}, experimental.GetLabels(mod))
+
+			_, err = mod.ExportedFunction("trap").Call(ctx)
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+			require.EqualError(t, err, `wasm error: unreachable
+wasm stack trace:
+	.$0()
+wasm module labels: request=2, tenant=acme`)
+
+			m, ok := experimental.GetMetrics(r)
+			require.True(t, ok)
+			require.Equal(t, []experimental.InstanceMetrics{
+				{Module: "labeled", Labels: map[string]string{"tenant": "acme", "request": "2"}, MemoryPages: 1, MemoryBytes: uint64(wasm.MemoryPageSize)},
+			}, m.Instances)
+
+			// The labels also apply to traps of the start function.
+			compiled, err = r.CompileModule(ctx, startTrapBin)
+			require.NoError(t, err)
+			_, err = r.InstantiateModule(ctx, compiled, NewModuleConfig().WithName("start").WithLabel("tenant", "acme"))
+			require.ErrorIs(t, err, wasmruntime.ErrRuntimeUnreachable)
+			require.Contains(t, err.Error(), "wasm module labels: tenant=acme")
+		})
+	}
+}
+
+func TestRuntime_PanicHandler(t *testing.T) {
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		ImportSection:   []wasm.Import{{Module: "env", Name: "bug", Type: wasm.ExternTypeFunc, DescFunc: 0}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeCall, 0, wasm.OpcodeEnd}}},
+		ExportSection:   []wasm.Export{{Name: "bug", Type: wasm.ExternTypeFunc, Index: 1}},
+	})
+
+	for _, c := range []struct {
+		name   string
+		config RuntimeConfig
+	}{
+		{name: "compiler", config: NewRuntimeConfig()},
+		{name: "interpreter", config: NewRuntimeConfigInterpreter()},
+	} {
+		config := c.config
+		t.Run(c.name, func(t *testing.T) {
+			var runtimeHandled, instanceHandled []*experimental.PanicError
+			ctx := experimental.WithPanicHandler(testCtx, func(_ context.Context, mod api.Module, err *experimental.PanicError) {
+				require.Equal(t, mod.Name(), err.Module)
+				runtimeHandled = append(runtimeHandled, err)
+			})
+			r := NewRuntimeWithConfig(ctx, config)
+			defer r.Close(ctx)
+
+			var zero int
+			var recovered interface{}
+			_, err := r.NewHostModuleBuilder("env").
+				NewFunctionBuilder().WithFunc(func() {
+				if recovered != nil {
+					panic(recovered)
+				}
+				_ = 1 / zero
+			}).Export("bug").
+				Instantiate(ctx)
+			require.NoError(t, err)
+
+			compiled, err := r.CompileModule(ctx, bin)
+			require.NoError(t, err)
+
+			mod, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("a"))
+			require.NoError(t, err)
+			other, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("b"))
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("bug").Call(testCtx)
+			var pe *experimental.PanicError
+			require.True(t, errors.As(err, &pe))
+			require.Equal(t, "a", pe.Module)
+			require.Contains(t, pe.Error(), "integer divide by zero (recovered by wazero)")
+			require.Contains(t, string(pe.Stack), "TestRuntime_PanicHandler")
+			require.Equal(t, []*experimental.PanicError{pe}, runtimeHandled)
+
+			// Only the instance whose function was called is poisoned.
+			require.Equal(t, api.ModuleStatePoisoned, mod.State())
+			require.Equal(t, api.ModuleStateHealthy, other.State())
+
+			// A handler passed to InstantiateModule overrides the runtime one.
+			instanceCtx := experimental.WithPanicHandler(testCtx, func(_ context.Context, _ api.Module, err *experimental.PanicError) {
+				instanceHandled = append(instanceHandled, err)
+			})
+			mod, err = r.InstantiateModule(instanceCtx, compiled, NewModuleConfig().WithName("c"))
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("bug").Call(testCtx)
+			require.True(t, errors.As(err, &pe))
+			require.Equal(t, []*experimental.PanicError{pe}, instanceHandled)
+			require.Equal(t, 1, len(runtimeHandled))
+
+			// A panic with a value other than an error is also a PanicError.
+			recovered = "BUG"
+			mod, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("d"))
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("bug").Call(testCtx)
+			require.True(t, errors.As(err, &pe))
+			require.Equal(t, "BUG", pe.Recovered)
+			require.Contains(t, pe.Error(), "BUG (recovered by wazero)")
+			require.Equal(t, 2, len(runtimeHandled))
+
+			// An error a host function intentionally panicked with isn't.
+			recovered = errors.New("boom")
+			mod, err = r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("e"))
+			require.NoError(t, err)
+
+			_, err = mod.ExportedFunction("bug").Call(testCtx)
+			require.Contains(t, err.Error(), "boom (recovered by wazero)")
+			require.False(t, errors.As(err, &pe))
+			require.Equal(t, 2, len(runtimeHandled))
+		})
+	}
+}
+
 func TestRuntime_CloseWithExitCode(t *testing.T) {
 	bin := binaryencoding.EncodeModule(&wasm.Module{
 		TypeSection:     []wasm.FunctionType{{}},
@@ -6,13 +6,16 @@ import (
 	"fmt"
 )
 
-// These two special exit codes are reserved by wazero for context Cancel and Timeout integrations.
-// The assumption here is that well-behaving Wasm programs won't use these two exit codes.
+// These special exit codes are reserved by wazero for context Cancel and Timeout integrations, and closing modules
+// after a trap. The assumption here is that well-behaving Wasm programs won't use these exit codes.
 const (
 	// ExitCodeContextCanceled corresponds to context.Canceled and returned by ExitError.ExitCode in that case.
 	ExitCodeContextCanceled uint32 = 0xffffffff
 	// ExitCodeDeadlineExceeded corresponds to context.DeadlineExceeded and returned by ExitError.ExitCode in that case.
 	ExitCodeDeadlineExceeded uint32 = 0xefffffff
+	// ExitCodeTrapped is returned by ExitError.ExitCode when a module was closed because a call trapped, and
+	// wazero.ModuleConfig WithCloseOnTrap was enabled.
+	ExitCodeTrapped uint32 = 0xdfffffff
 )
 
 // ExitError is returned to a caller of api.Function when api.Module CloseWithExitCode was invoked,
@@ -63,6 +66,8 @@ func (e *ExitError) Error() string {
 		return fmt.Sprintf("module closed with %s", context.Canceled)
 	case ExitCodeDeadlineExceeded:
 		return fmt.Sprintf("module closed with %s", context.DeadlineExceeded)
+	case ExitCodeTrapped:
+		return "module closed after trap"
 	default:
 		return fmt.Sprintf("module closed with exit_code(%d)", e.exitCode)
 	}
@@ -65,6 +65,11 @@ func TestExitError_Error(t *testing.T) {
 		require.EqualError(t, err, "module closed with context canceled")
 		require.ErrorIs(t, err, context.Canceled, "exit code context canceled should work")
 	})
+	t.Run("trapped", func(t *testing.T) {
+		err := sys.NewExitError(sys.ExitCodeTrapped)
+		require.Equal(t, sys.ExitCodeTrapped, err.ExitCode())
+		require.EqualError(t, err, "module closed after trap")
+	})
 	t.Run("normal", func(t *testing.T) {
 		err := sys.NewExitError(123)
 		require.Equal(t, uint32(123), err.ExitCode())
@@ -2,7 +2,10 @@
 
 package sys
 
-import "io/fs"
+import (
+	"io/fs"
+	"reflect"
+)
 
 // sysParseable is only used here as we define "supported" as being able to
 // parse `info.Sys()`. The above `go:build` constraints exclude 32-bit until
@@ -13,5 +16,32 @@ import "io/fs"
 const sysParseable = false
 
 func statFromFileInfo(info fs.FileInfo) Stat_t {
-	return defaultStatFromFileInfo(info)
+	st := defaultStatFromFileInfo(info)
+
+	// While not parseable, `info.Sys()` is usually a *syscall.Stat_t whose
+	// field types vary by platform. Read the fields needed to identify the
+	// file by name, so that guests can compare (Dev, Ino) as they would on
+	// supported platforms.
+	if v := reflect.ValueOf(info.Sys()); v.Kind() == reflect.Pointer && !v.IsNil() {
+		if v = v.Elem(); v.Kind() == reflect.Struct {
+			st.Dev = uintField(v, "Dev", st.Dev)
+			st.Ino = uintField(v, "Ino", st.Ino)
+			st.Nlink = uintField(v, "Nlink", st.Nlink)
+		}
+	}
+	return st
+}
+
+// uintField returns the integer field of the struct named `name`, or
+// `defaultValue` if it doesn't exist.
+func uintField(v reflect.Value, name string, defaultValue uint64) uint64 {
+	f := v.FieldByName(name)
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return f.Uint()
+	default:
+		return defaultValue
+	}
 }
@@ -0,0 +1,406 @@
+package wasmbin
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// DowngradableFeatures are the features whose instructions Downgrade replaces
+// with WebAssembly 1.0 (api.CoreFeaturesV1) instructions.
+const DowngradableFeatures = api.CoreFeatureSignExtensionOps |
+	api.CoreFeatureNonTrappingFloatToIntConversion |
+	api.CoreFeatureBulkMemoryOperations
+
+// blockTypeEmpty is the block type of a block, loop or if without parameters
+// or results.
+const blockTypeEmpty = 0x40
+
+// Downgrade rewrites the WebAssembly binary (%.wasm) to replace instructions
+// of the DowngradableFeatures which aren't in `features` with equivalent
+// sequences of WebAssembly 1.0 instructions. This allows a module compiled
+// for WebAssembly 2.0 to be serialized for stricter environments.
+//
+// For example, this replaces "i32.extend8_s", "i32.trunc_sat_f32_s" and
+// "memory.copy" of a module, so that it validates with api.CoreFeaturesV1:
+//
+//	bin, err = wasmbin.Downgrade(bin, api.CoreFeaturesV1)
+//
+// Here's how instructions are replaced:
+//
+//   - api.CoreFeatureSignExtensionOps: "i32.extend8_s" and similar are
+//     replaced with a left shift followed by an arithmetic right shift.
+//   - api.CoreFeatureNonTrappingFloatToIntConversion: "i32.trunc_sat_f32_s"
+//     and similar are replaced with comparisons which saturate NaN and values
+//     out of range before the trapping conversion, e.g. "i32.trunc_f32_s".
+//   - api.CoreFeatureBulkMemoryOperations: "memory.copy" and "memory.fill"
+//     are replaced with loops over each byte, after checking the bounds of
+//     the whole range, so they trap like the original before writing memory.
+//
+// # Notes
+//
+//   - Replacements need local variables, which are appended to the locals of
+//     the function. Other sections are copied as-is, except the data count
+//     section, which is removed unless api.CoreFeatureBulkMemoryOperations is
+//     in `features`.
+//   - An error is returned if an instruction has no replacement, such as
+//     "memory.init", which needs passive data segments, which also have
+//     no replacement.
+//   - Instructions of other features, e.g. api.CoreFeatureSIMD, are copied
+//     as-is. The module is not validated.
+func Downgrade(bin []byte, features api.CoreFeatures) ([]byte, error) {
+	m, err := Decode(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	bulkMemory := features.IsEnabled(api.CoreFeatureBulkMemoryOperations)
+	if !bulkMemory {
+		for i := range m.Data {
+			if m.Data[i].Passive {
+				return nil, fmt.Errorf("data[%d]: passive data segments can't be downgraded", i)
+			}
+		}
+	}
+
+	var importedFunctions int
+	for i := range m.Imports {
+		if m.Imports[i].Type == api.ExternTypeFunc {
+			importedFunctions++
+		}
+	}
+	for i := range m.Functions {
+		f := &m.Functions[i]
+		if int(f.TypeIndex) >= len(m.Types) {
+			return nil, fmt.Errorf("function[%d]: invalid type index %d", importedFunctions+i, f.TypeIndex)
+		}
+		d := &downgrader{features: features, f: f, localBase: uint32(len(m.Types[f.TypeIndex].Params))}
+		if err = d.downgrade(); err != nil {
+			return nil, fmt.Errorf("function[%d]: %w", importedFunctions+i, err)
+		}
+	}
+
+	ret := append(make([]byte, 0, len(bin)), magicAndVersion...)
+	start := uint64(len(magicAndVersion)) // of the current section, including its header.
+	for _, s := range m.Sections {
+		end := s.Offset + uint64(s.Size)
+		switch s.ID {
+		case SectionIDCode:
+			var b []byte
+			for i := range m.Functions {
+				b = appendCode(b, &m.Functions[i])
+			}
+			ret = appendSection(ret, SectionIDCode, len(m.Functions), b)
+		case SectionIDDataCount:
+			if bulkMemory {
+				ret = append(ret, bin[start:end]...)
+			}
+		default:
+			ret = append(ret, bin[start:end]...)
+		}
+		start = end
+	}
+	return ret, nil
+}
+
+// downgrader rewrites the body of a function for Downgrade.
+type downgrader struct {
+	features api.CoreFeatures
+	f        *Function
+
+	// localBase is the index of the first local, after the parameters.
+	localBase uint32
+
+	// scratch are the indexes of the locals appended for replacements, by
+	// their type.
+	scratch map[api.ValueType][]uint32
+
+	// b is the rewritten body.
+	b []byte
+}
+
+func (d *downgrader) downgrade() error {
+	instructions, err := DecodeInstructions(d.f.Body)
+	if err != nil {
+		return err
+	}
+
+	for i, inst := range instructions {
+		end := uint32(len(d.f.Body))
+		if i+1 < len(instructions) {
+			end = instructions[i+1].Offset
+		}
+
+		replaced, err := d.replace(inst)
+		if err != nil {
+			return err
+		} else if !replaced {
+			d.b = append(d.b, d.f.Body[inst.Offset:end]...)
+		}
+	}
+	d.f.Body = d.b
+	return nil
+}
+
+// replace appends the replacement of `inst` and returns true, or returns false
+// if it is kept as-is.
+func (d *downgrader) replace(inst Instruction) (bool, error) {
+	switch inst.Opcode {
+	case wasm.OpcodeI32Extend8S, wasm.OpcodeI32Extend16S,
+		wasm.OpcodeI64Extend8S, wasm.OpcodeI64Extend16S, wasm.OpcodeI64Extend32S:
+		if d.features.IsEnabled(api.CoreFeatureSignExtensionOps) {
+			return false, nil
+		}
+		d.signExtend(inst.Opcode)
+		return true, nil
+	case OpcodeMiscPrefix:
+	default:
+		return false, nil
+	}
+
+	switch sub := inst.Subopcode; {
+	case sub <= uint32(wasm.OpcodeMiscI64TruncSatF64U):
+		if d.features.IsEnabled(api.CoreFeatureNonTrappingFloatToIntConversion) {
+			return false, nil
+		}
+		d.truncSat(wasm.OpcodeMisc(sub))
+	case sub == uint32(wasm.OpcodeMiscMemoryCopy), sub == uint32(wasm.OpcodeMiscMemoryFill):
+		if d.features.IsEnabled(api.CoreFeatureBulkMemoryOperations) {
+			return false, nil
+		}
+		if sub == uint32(wasm.OpcodeMiscMemoryCopy) {
+			d.memoryCopy()
+		} else {
+			d.memoryFill()
+		}
+	case sub == uint32(wasm.OpcodeMiscMemoryInit), sub == uint32(wasm.OpcodeMiscDataDrop),
+		sub == uint32(wasm.OpcodeMiscTableInit), sub == uint32(wasm.OpcodeMiscElemDrop),
+		sub == uint32(wasm.OpcodeMiscTableCopy):
+		if d.features.IsEnabled(api.CoreFeatureBulkMemoryOperations) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%s at offset %d can't be downgraded", inst.Name(), inst.Offset)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// signExtend replaces a sign-extension instruction, e.g. "i32.extend8_s" is
+// "i32.const 24; i32.shl; i32.const 24; i32.shr_s".
+func (d *downgrader) signExtend(op Opcode) {
+	switch op {
+	case wasm.OpcodeI32Extend8S, wasm.OpcodeI32Extend16S:
+		bits := int32(24)
+		if op == wasm.OpcodeI32Extend16S {
+			bits = 16
+		}
+		d.i32Const(bits)
+		d.b = append(d.b, wasm.OpcodeI32Shl)
+		d.i32Const(bits)
+		d.b = append(d.b, wasm.OpcodeI32ShrS)
+	default:
+		bits := int64(56)
+		if op == wasm.OpcodeI64Extend16S {
+			bits = 48
+		} else if op == wasm.OpcodeI64Extend32S {
+			bits = 32
+		}
+		d.i64Const(bits)
+		d.b = append(d.b, wasm.OpcodeI64Shl)
+		d.i64Const(bits)
+		d.b = append(d.b, wasm.OpcodeI64ShrS)
+	}
+}
+
+// truncSat replaces a saturating conversion with the trapping conversion of
+// the same types, unless the operand is NaN, or out of the range where the
+// latter traps, in which case the result is 0, or the minimum or maximum.
+func (d *downgrader) truncSat(sub wasm.OpcodeMisc) {
+	is64Bit := sub >= wasm.OpcodeMiscI64TruncSatF32S
+	k := sub & 3
+	fromF64, unsigned := k >= 2, k&1 == 1
+
+	// The trapping conversions have the same order as the saturating ones.
+	trunc := wasm.OpcodeI32TruncF32S + k
+	resultType, bits := api.ValueTypeI32, 32.0
+	if is64Bit {
+		trunc = wasm.OpcodeI64TruncF32S + k
+		resultType, bits = api.ValueTypeI64, 64.0
+	}
+
+	// Values at or over `upper` saturate to the maximum, and values at or
+	// under `lower` to the minimum.
+	upper, lower := math.Pow(2, bits), -1.0
+	var min, max int64 = 0, -1 // max is all ones, i.e. the maximum unsigned.
+	if !unsigned {
+		upper, lower = math.Pow(2, bits-1), -math.Pow(2, bits-1)-1
+		min, max = -1<<(int(bits)-1), 1<<(int(bits)-1)-1
+	}
+
+	floatType, ne, ge, le := api.ValueTypeF32, wasm.OpcodeF32Ne, wasm.OpcodeF32Ge, wasm.OpcodeF32Le
+	if fromF64 {
+		floatType, ne, ge, le = api.ValueTypeF64, wasm.OpcodeF64Ne, wasm.OpcodeF64Ge, wasm.OpcodeF64Le
+	}
+	x := d.local(floatType, 0)
+
+	d.localSet(x)
+	d.localGet(x)
+	d.localGet(x)
+	d.b = append(d.b, ne, wasm.OpcodeIf, resultType) // NaN is the only value not equal to itself.
+	d.intConst(resultType, 0)
+	d.b = append(d.b, wasm.OpcodeElse)
+	d.localGet(x)
+	d.floatConst(floatType, upper)
+	d.b = append(d.b, ge, wasm.OpcodeIf, resultType)
+	d.intConst(resultType, max)
+	d.b = append(d.b, wasm.OpcodeElse)
+	d.localGet(x)
+	d.floatConst(floatType, lower)
+	d.b = append(d.b, le, wasm.OpcodeIf, resultType)
+	d.intConst(resultType, min)
+	d.b = append(d.b, wasm.OpcodeElse)
+	d.localGet(x)
+	d.b = append(d.b, trunc, wasm.OpcodeEnd, wasm.OpcodeEnd, wasm.OpcodeEnd)
+}
+
+// memoryFill replaces "memory.fill" with a loop which stores each byte.
+func (d *downgrader) memoryFill() {
+	dst, val, n := d.local(api.ValueTypeI32, 0), d.local(api.ValueTypeI32, 1), d.local(api.ValueTypeI32, 2)
+	d.localSet(n)
+	d.localSet(val)
+	d.localSet(dst)
+	d.boundsCheck(dst, n)
+
+	d.b = append(d.b, wasm.OpcodeBlock, blockTypeEmpty, wasm.OpcodeLoop, blockTypeEmpty)
+	d.brIfZero(n)
+	d.localGet(dst)
+	d.localGet(val)
+	d.b = append(d.b, wasm.OpcodeI32Store8, 0, 0)
+	d.increment(dst, 1)
+	d.increment(n, -1)
+	d.b = append(d.b, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd)
+}
+
+// memoryCopy replaces "memory.copy" with a loop which copies each byte,
+// forwards unless the destination is after the source, so that the result
+// is the same as if the source was copied to a temporary buffer first.
+func (d *downgrader) memoryCopy() {
+	dst, src, n := d.local(api.ValueTypeI32, 0), d.local(api.ValueTypeI32, 1), d.local(api.ValueTypeI32, 2)
+	d.localSet(n)
+	d.localSet(src)
+	d.localSet(dst)
+	d.boundsCheck(dst, n)
+	d.boundsCheck(src, n)
+
+	d.localGet(dst)
+	d.localGet(src)
+	d.b = append(d.b, wasm.OpcodeI32LeU, wasm.OpcodeIf, blockTypeEmpty)
+	d.b = append(d.b, wasm.OpcodeBlock, blockTypeEmpty, wasm.OpcodeLoop, blockTypeEmpty)
+	d.brIfZero(n)
+	d.localGet(dst)
+	d.localGet(src)
+	d.b = append(d.b, wasm.OpcodeI32Load8U, 0, 0, wasm.OpcodeI32Store8, 0, 0)
+	d.increment(dst, 1)
+	d.increment(src, 1)
+	d.increment(n, -1)
+	d.b = append(d.b, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd)
+
+	d.b = append(d.b, wasm.OpcodeElse)
+	d.b = append(d.b, wasm.OpcodeBlock, blockTypeEmpty, wasm.OpcodeLoop, blockTypeEmpty)
+	d.brIfZero(n)
+	d.increment(n, -1)
+	d.localGet(dst)
+	d.localGet(n)
+	d.b = append(d.b, wasm.OpcodeI32Add)
+	d.localGet(src)
+	d.localGet(n)
+	d.b = append(d.b, wasm.OpcodeI32Add, wasm.OpcodeI32Load8U, 0, 0, wasm.OpcodeI32Store8, 0, 0)
+	d.b = append(d.b, wasm.OpcodeBr, 0, wasm.OpcodeEnd, wasm.OpcodeEnd)
+	d.b = append(d.b, wasm.OpcodeEnd)
+}
+
+// boundsCheck traps with an out of bounds memory access if the range of `n`
+// bytes at `offset` isn't in memory, by loading the byte at 2^32, which is
+// over the maximum size of memory.
+func (d *downgrader) boundsCheck(offset, n uint32) {
+	d.localGet(offset)
+	d.b = append(d.b, wasm.OpcodeI64ExtendI32U)
+	d.localGet(n)
+	d.b = append(d.b, wasm.OpcodeI64ExtendI32U, wasm.OpcodeI64Add)
+	d.b = append(d.b, wasm.OpcodeMemorySize, 0, wasm.OpcodeI64ExtendI32U)
+	d.i64Const(16) // the shift of the page size, 64KiB.
+	d.b = append(d.b, wasm.OpcodeI64Shl, wasm.OpcodeI64GtU, wasm.OpcodeIf, blockTypeEmpty)
+	d.i32Const(-1)
+	d.b = append(d.b, wasm.OpcodeI32Load8U, 0, 1, wasm.OpcodeDrop, wasm.OpcodeEnd)
+}
+
+// brIfZero branches out of the block around a loop if the local is zero.
+func (d *downgrader) brIfZero(local uint32) {
+	d.localGet(local)
+	d.b = append(d.b, wasm.OpcodeI32Eqz, wasm.OpcodeBrIf, 1)
+}
+
+// increment adds `v` to the i32 local.
+func (d *downgrader) increment(local uint32, v int32) {
+	d.localGet(local)
+	d.i32Const(v)
+	d.b = append(d.b, wasm.OpcodeI32Add)
+	d.localSet(local)
+}
+
+// local returns the index of the nth local of the type appended for
+// replacements, appending it if needed. Replacements don't nest, so they
+// share these.
+func (d *downgrader) local(t api.ValueType, nth int) uint32 {
+	if d.scratch == nil {
+		d.scratch = map[api.ValueType][]uint32{}
+	}
+	for len(d.scratch[t]) <= nth {
+		d.scratch[t] = append(d.scratch[t], d.localBase+uint32(len(d.f.Locals)))
+		d.f.Locals = append(d.f.Locals, t)
+	}
+	return d.scratch[t][nth]
+}
+
+func (d *downgrader) localGet(local uint32) {
+	d.b = append(d.b, wasm.OpcodeLocalGet)
+	d.b = append(d.b, leb128.EncodeUint32(local)...)
+}
+
+func (d *downgrader) localSet(local uint32) {
+	d.b = append(d.b, wasm.OpcodeLocalSet)
+	d.b = append(d.b, leb128.EncodeUint32(local)...)
+}
+
+func (d *downgrader) i32Const(v int32) {
+	d.b = append(d.b, wasm.OpcodeI32Const)
+	d.b = append(d.b, leb128.EncodeInt32(v)...)
+}
+
+func (d *downgrader) i64Const(v int64) {
+	d.b = append(d.b, wasm.OpcodeI64Const)
+	d.b = append(d.b, leb128.EncodeInt64(v)...)
+}
+
+func (d *downgrader) intConst(t api.ValueType, v int64) {
+	if t == api.ValueTypeI32 {
+		d.i32Const(int32(v))
+	} else {
+		d.i64Const(v)
+	}
+}
+
+func (d *downgrader) floatConst(t api.ValueType, v float64) {
+	if t == api.ValueTypeF32 {
+		d.b = append(d.b, wasm.OpcodeF32Const)
+		d.b = binary.LittleEndian.AppendUint32(d.b, math.Float32bits(float32(v)))
+	} else {
+		d.b = append(d.b, wasm.OpcodeF64Const)
+		d.b = binary.LittleEndian.AppendUint64(d.b, math.Float64bits(v))
+	}
+}
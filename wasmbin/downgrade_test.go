@@ -0,0 +1,180 @@
+package wasmbin_test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+var testCtx = context.Background()
+
+func TestDowngrade(t *testing.T) {
+	i32, i64, f32, f64 := wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64
+	unary := func(op ...byte) wasm.Code {
+		return wasm.Code{Body: append(append([]byte{wasm.OpcodeLocalGet, 0}, op...), wasm.OpcodeEnd)}
+	}
+	bulk := func(op ...byte) wasm.Code {
+		body := []byte{wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeLocalGet, 2}
+		return wasm.Code{Body: append(append(body, op...), wasm.OpcodeEnd)}
+	}
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{
+			{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{i64}, Results: []wasm.ValueType{i64}},
+			{Params: []wasm.ValueType{f32}, Results: []wasm.ValueType{i32}},
+			{Params: []wasm.ValueType{f64}, Results: []wasm.ValueType{i64}},
+			{Params: []wasm.ValueType{i32, i32, i32}},
+		},
+		FunctionSection: []wasm.Index{0, 1, 2, 2, 3, 3, 4, 4},
+		CodeSection: []wasm.Code{
+			unary(wasm.OpcodeI32Extend8S),
+			unary(wasm.OpcodeI64Extend32S),
+			unary(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF32S),
+			unary(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF32U),
+			unary(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF64S),
+			unary(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscI64TruncSatF64U),
+			bulk(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryFill, 0),
+			bulk(wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 0),
+		},
+		MemorySection: &wasm.Memory{Min: 1, Max: 1, IsMaxEncoded: true},
+		ExportSection: []wasm.Export{
+			{Name: "i32.extend8_s", Type: wasm.ExternTypeFunc, Index: 0},
+			{Name: "i64.extend32_s", Type: wasm.ExternTypeFunc, Index: 1},
+			{Name: "i32.trunc_sat_f32_s", Type: wasm.ExternTypeFunc, Index: 2},
+			{Name: "i32.trunc_sat_f32_u", Type: wasm.ExternTypeFunc, Index: 3},
+			{Name: "i64.trunc_sat_f64_s", Type: wasm.ExternTypeFunc, Index: 4},
+			{Name: "i64.trunc_sat_f64_u", Type: wasm.ExternTypeFunc, Index: 5},
+			{Name: "memory.fill", Type: wasm.ExternTypeFunc, Index: 6},
+			{Name: "memory.copy", Type: wasm.ExternTypeFunc, Index: 7},
+			{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		},
+	})
+
+	downgraded, err := wasmbin.Downgrade(bin, api.CoreFeaturesV1)
+	require.NoError(t, err)
+
+	// The downgraded module is valid without the features, and behaves the same
+	// as the original.
+	r1 := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV1))
+	defer r1.Close(testCtx)
+	v1, err := r1.Instantiate(testCtx, downgraded)
+	require.NoError(t, err)
+
+	r2 := wazero.NewRuntime(testCtx)
+	defer r2.Close(testCtx)
+	v2, err := r2.Instantiate(testCtx, bin)
+	require.NoError(t, err)
+
+	f32s := []float32{0, -0.5, 1.9, -1, -2.5, 2147483520, 2147483648, -2147483648, -2147483904, 4294967040, 4294967296,
+		float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN())}
+	f64s := []float64{0, -0.5, 1.9, -1, -2.5, 9223372036854774784, 9223372036854775808, -9223372036854775808,
+		-9223372036854777856, 18446744073709549568, 18446744073709551616, math.Inf(1), math.Inf(-1), math.NaN()}
+	tests := []struct {
+		fn     string
+		params [][]uint64
+	}{
+		{fn: "i32.extend8_s", params: [][]uint64{{0}, {0x7f}, {0x80}, {0xffff_ff01}}},
+		{fn: "i64.extend32_s", params: [][]uint64{{0}, {0x7fff_ffff}, {0x8000_0000}, {0x1_ffff_ffff}}},
+		{fn: "i32.trunc_sat_f32_s", params: f32Params(f32s)},
+		{fn: "i32.trunc_sat_f32_u", params: f32Params(f32s)},
+		{fn: "i64.trunc_sat_f64_s", params: f64Params(f64s)},
+		{fn: "i64.trunc_sat_f64_u", params: f64Params(f64s)},
+		{fn: "memory.fill", params: [][]uint64{{10, 0xab, 5}, {65530, 1, 6}, {65530, 1, 7}, {65537, 1, 0}, {0xffff_ffff, 1, 2}}},
+		{fn: "memory.copy", params: [][]uint64{{100, 10, 8}, {12, 10, 8}, {8, 12, 8}, {65530, 0, 7}, {0, 65530, 7}, {0, 0, 0}}},
+	}
+
+	for _, tc := range tests {
+		for _, params := range tc.params {
+			expected, expectedErr := v2.ExportedFunction(tc.fn).Call(testCtx, params...)
+			actual, err := v1.ExportedFunction(tc.fn).Call(testCtx, params...)
+			if expectedErr != nil {
+				require.Error(t, err, "%s%v", tc.fn, params)
+				require.Contains(t, err.Error(), "out of bounds memory access")
+			} else {
+				require.NoError(t, err, "%s%v", tc.fn, params)
+				require.Equal(t, expected, actual, "%s%v", tc.fn, params)
+			}
+			expectedMem, _ := v2.Memory().Read(0, v2.Memory().Size())
+			actualMem, _ := v1.Memory().Read(0, v1.Memory().Size())
+			require.Equal(t, expectedMem, actualMem, "%s%v", tc.fn, params)
+		}
+	}
+
+	t.Run("features kept", func(t *testing.T) {
+		same, err := wasmbin.Downgrade(bin, api.CoreFeaturesV2)
+		require.NoError(t, err)
+		require.Equal(t, bin, same)
+
+		// Sign-extension instructions are replaced, but not the others.
+		partial, err := wasmbin.Downgrade(bin, api.CoreFeaturesV2&^api.CoreFeatureSignExtensionOps)
+		require.NoError(t, err)
+		m, err := wasmbin.Decode(partial)
+		require.NoError(t, err)
+		instructions, err := wasmbin.DecodeInstructions(m.Functions[0].Body)
+		require.NoError(t, err)
+		require.Equal(t, "i32.shr_s", instructions[len(instructions)-2].Name())
+		instructions, err = wasmbin.DecodeInstructions(m.Functions[7].Body)
+		require.NoError(t, err)
+		require.Equal(t, "memory.copy", instructions[len(instructions)-2].Name())
+	})
+}
+
+func TestDowngrade_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		bin         []byte
+		expectedErr string
+	}{
+		{
+			name: "memory.init",
+			bin: binaryencoding.EncodeModule(&wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				ImportSection:   []wasm.Import{{Module: "env", Name: "f", Type: wasm.ExternTypeFunc}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{Body: []byte{
+					wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0,
+					wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, 0, 0, wasm.OpcodeEnd,
+				}}},
+				MemorySection: &wasm.Memory{Min: 1},
+			}),
+			expectedErr: "function[1]: memory.init at offset 6 can't be downgraded",
+		},
+		{
+			name: "passive data",
+			bin: wasmbin.Encode(&wasmbin.Module{
+				Memory: &wasmbin.Memory{Min: 1},
+				Data:   []wasmbin.DataSegment{{Passive: true, Init: []byte{1}}},
+			}),
+			expectedErr: "data[0]: passive data segments can't be downgraded",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := wasmbin.Downgrade(tc.bin, api.CoreFeaturesV1)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func f32Params(values []float32) (ret [][]uint64) {
+	for _, v := range values {
+		ret = append(ret, []uint64{api.EncodeF32(v)})
+	}
+	return
+}
+
+func f64Params(values []float64) (ret [][]uint64) {
+	for _, v := range values {
+		ret = append(ret, []uint64{api.EncodeF64(v)})
+	}
+	return
+}
@@ -0,0 +1,342 @@
+package wasmbin
+
+import (
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// magicAndVersion is the header of WebAssembly 1.0 and 2.0 binaries.
+var magicAndVersion = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+// Encode encodes the module in the WebAssembly binary format (%.wasm). This
+// is the opposite of Decode, except Module.Sections is ignored, as sections
+// are always encoded in the canonical order, with custom sections last.
+//
+// Note: The module is not validated, so the result can be invalid, e.g. if a
+// function body doesn't end with the "end" opcode.
+func Encode(m *Module) []byte {
+	ret := append([]byte{}, magicAndVersion...)
+
+	if len(m.Types) > 0 {
+		var b []byte
+		for _, t := range m.Types {
+			b = append(b, 0x60)
+			b = appendValueTypes(b, t.Params)
+			b = appendValueTypes(b, t.Results)
+		}
+		ret = appendSection(ret, SectionIDType, len(m.Types), b)
+	}
+
+	if len(m.Imports) > 0 {
+		ret = appendImportSection(ret, m.Imports)
+	}
+
+	if len(m.Functions) > 0 {
+		var b []byte
+		for i := range m.Functions {
+			b = append(b, leb128.EncodeUint32(m.Functions[i].TypeIndex)...)
+		}
+		ret = appendSection(ret, SectionIDFunction, len(m.Functions), b)
+	}
+
+	if len(m.Tables) > 0 {
+		var b []byte
+		for i := range m.Tables {
+			b = appendTable(b, &m.Tables[i])
+		}
+		ret = appendSection(ret, SectionIDTable, len(m.Tables), b)
+	}
+
+	if m.Memory != nil {
+		ret = appendSection(ret, SectionIDMemory, 1, appendMemory(nil, m.Memory))
+	}
+
+	if len(m.Globals) > 0 {
+		var b []byte
+		for i := range m.Globals {
+			g := &m.Globals[i]
+			b = appendGlobalType(b, g.GlobalType)
+			b = appendConstExpr(b, g.Init)
+		}
+		ret = appendSection(ret, SectionIDGlobal, len(m.Globals), b)
+	}
+
+	if len(m.Exports) > 0 {
+		ret = appendExportSection(ret, m.Exports)
+	}
+
+	if m.Start != nil {
+		ret = appendSection(ret, SectionIDStart, -1, leb128.EncodeUint32(*m.Start))
+	}
+
+	if len(m.Elements) > 0 {
+		var b []byte
+		for i := range m.Elements {
+			b = appendElementSegment(b, &m.Elements[i])
+		}
+		ret = appendSection(ret, SectionIDElement, len(m.Elements), b)
+	}
+
+	// The data count section is required for "memory.init" and "data.drop",
+	// which are only useful with passive segments.
+	for i := range m.Data {
+		if m.Data[i].Passive {
+			ret = appendSection(ret, SectionIDDataCount, -1, leb128.EncodeUint32(uint32(len(m.Data))))
+			break
+		}
+	}
+
+	if len(m.Functions) > 0 {
+		var b []byte
+		for i := range m.Functions {
+			b = appendCode(b, &m.Functions[i])
+		}
+		ret = appendSection(ret, SectionIDCode, len(m.Functions), b)
+	}
+
+	if len(m.Data) > 0 {
+		var b []byte
+		for i := range m.Data {
+			d := &m.Data[i]
+			if d.Passive {
+				b = append(b, 0x01)
+			} else {
+				b = append(b, 0x00)
+				b = appendConstExpr(b, d.Offset)
+			}
+			b = append(b, leb128.EncodeUint32(uint32(len(d.Init)))...)
+			b = append(b, d.Init...)
+		}
+		ret = appendSection(ret, SectionIDData, len(m.Data), b)
+	}
+
+	if m.Name != "" {
+		// The name section only has the module name subsection.
+		b := appendName(nil, "name")
+		b = append(b, 0) // subsection ID of the module name
+		moduleName := appendName(nil, m.Name)
+		b = append(b, leb128.EncodeUint32(uint32(len(moduleName)))...)
+		b = append(b, moduleName...)
+		ret = appendSection(ret, SectionIDCustom, -1, b)
+	}
+
+	for i := range m.CustomSections {
+		c := &m.CustomSections[i]
+		ret = appendSection(ret, SectionIDCustom, -1, append(appendName(nil, c.Name), c.Data...))
+	}
+	return ret
+}
+
+// EncodeInstructions encodes the instructions, e.g. to use as Function.Body.
+// This is the opposite of DecodeInstructions, except Instruction.Offset is
+// ignored.
+func EncodeInstructions(instructions []Instruction) (ret []byte) {
+	for _, i := range instructions {
+		ret = append(ret, i.Opcode)
+		if hasSubopcode(i.Opcode) {
+			ret = append(ret, leb128.EncodeUint32(i.Subopcode)...)
+		}
+		ret = append(ret, i.Immediates...)
+	}
+	return
+}
+
+// EncodeUint32 encodes `v` as unsigned LEB128, which is the encoding of
+// indexes in Instruction.Immediates, e.g. for "call".
+func EncodeUint32(v uint32) []byte {
+	return leb128.EncodeUint32(v)
+}
+
+// EncodeInt32 encodes `v` as signed LEB128, which is the encoding of the
+// Instruction.Immediates of "i32.const".
+func EncodeInt32(v int32) []byte {
+	return leb128.EncodeInt32(v)
+}
+
+// EncodeInt64 encodes `v` as signed LEB128, which is the encoding of the
+// Instruction.Immediates of "i64.const".
+func EncodeInt64(v int64) []byte {
+	return leb128.EncodeInt64(v)
+}
+
+func appendImportSection(ret []byte, imports []Import) []byte {
+	var b []byte
+	for i := range imports {
+		imp := &imports[i]
+		b = appendName(b, imp.Module)
+		b = appendName(b, imp.Name)
+		b = append(b, imp.Type)
+		switch imp.Type {
+		case api.ExternTypeFunc:
+			b = append(b, leb128.EncodeUint32(imp.TypeIndex)...)
+		case api.ExternTypeTable:
+			b = appendTable(b, &imp.Table)
+		case api.ExternTypeMemory:
+			b = appendMemory(b, &imp.Memory)
+		case api.ExternTypeGlobal:
+			b = appendGlobalType(b, imp.Global)
+		}
+	}
+	return appendSection(ret, SectionIDImport, len(imports), b)
+}
+
+func appendExportSection(ret []byte, exports []Export) []byte {
+	var b []byte
+	for i := range exports {
+		e := &exports[i]
+		b = appendName(b, e.Name)
+		b = append(b, e.Type)
+		b = append(b, leb128.EncodeUint32(e.Index)...)
+	}
+	return appendSection(ret, SectionIDExport, len(exports), b)
+}
+
+// appendSection appends a section with the contents `b`, prefixed by the
+// count of its elements unless `count` is -1.
+func appendSection(ret []byte, id SectionID, count int, b []byte) []byte {
+	if count >= 0 {
+		b = append(leb128.EncodeUint32(uint32(count)), b...)
+	}
+	ret = append(ret, id)
+	ret = append(ret, leb128.EncodeUint32(uint32(len(b)))...)
+	return append(ret, b...)
+}
+
+func appendName(b []byte, name string) []byte {
+	b = append(b, leb128.EncodeUint32(uint32(len(name)))...)
+	return append(b, name...)
+}
+
+func appendValueTypes(b []byte, types []api.ValueType) []byte {
+	b = append(b, leb128.EncodeUint32(uint32(len(types)))...)
+	return append(b, types...)
+}
+
+func appendLimits(b []byte, min uint32, max *uint32) []byte {
+	if max == nil {
+		b = append(b, 0x00)
+		return append(b, leb128.EncodeUint32(min)...)
+	}
+	b = append(b, 0x01)
+	b = append(b, leb128.EncodeUint32(min)...)
+	return append(b, leb128.EncodeUint32(*max)...)
+}
+
+func appendTable(b []byte, t *Table) []byte {
+	return appendLimits(append(b, t.Type), t.Min, t.Max)
+}
+
+// appendMemory appends the limits of a memory, whose flag also encodes
+// whether it is shared or has a custom page size.
+//
+// See https://github.com/WebAssembly/threads/blob/main/proposals/threads/Overview.md#spec-changes
+// See https://github.com/WebAssembly/custom-page-sizes/blob/main/proposals/custom-page-sizes/Overview.md#binary-encoding
+func appendMemory(b []byte, m *Memory) []byte {
+	var flag byte
+	if m.HasMax {
+		flag |= 0x01
+	}
+	if m.Shared {
+		flag |= 0x02
+	}
+	if m.HasPageSize {
+		flag |= 0x08
+	}
+	b = append(b, flag)
+	b = append(b, leb128.EncodeUint32(m.Min)...)
+	if m.HasMax {
+		b = append(b, leb128.EncodeUint32(m.Max)...)
+	}
+	if m.HasPageSize {
+		b = append(b, leb128.EncodeUint32(m.PageSizeLog2)...)
+	}
+	return b
+}
+
+func appendGlobalType(b []byte, t GlobalType) []byte {
+	if t.Mutable {
+		return append(b, t.Type, 0x01)
+	}
+	return append(b, t.Type, 0x00)
+}
+
+func appendConstExpr(b []byte, i Instruction) []byte {
+	b = append(b, EncodeInstructions([]Instruction{i})...)
+	return append(b, wasm.OpcodeEnd)
+}
+
+// appendElementSegment appends the element segment in the most compact of its
+// eight encodings: the one of WebAssembly 1.0 when possible, and function
+// indexes instead of expressions when all elements are "ref.func".
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#element-section
+func appendElementSegment(b []byte, e *ElementSegment) []byte {
+	funcIndexes := e.Type == RefTypeFuncref
+	for _, init := range e.Init {
+		if init.Opcode != wasm.OpcodeRefFunc {
+			funcIndexes = false
+			break
+		}
+	}
+
+	var flags byte
+	switch e.Mode {
+	case ElementModePassive:
+		flags = 0b001
+	case ElementModeDeclarative:
+		flags = 0b011
+	default:
+		if e.TableIndex != 0 || (!funcIndexes && e.Type != RefTypeFuncref) {
+			flags = 0b010
+		}
+	}
+	if !funcIndexes {
+		flags |= 0b100
+	}
+
+	b = append(b, flags)
+	if flags&0b010 != 0 && flags&0b001 == 0 { // active with an explicit table.
+		b = append(b, leb128.EncodeUint32(e.TableIndex)...)
+	}
+	if flags&0b001 == 0 { // active
+		b = appendConstExpr(b, e.Offset)
+	}
+	if flags&0b011 != 0 { // with the element kind or type.
+		if funcIndexes {
+			b = append(b, 0x00) // elemkind of funcref
+		} else {
+			b = append(b, e.Type)
+		}
+	}
+	b = append(b, leb128.EncodeUint32(uint32(len(e.Init)))...)
+	for _, init := range e.Init {
+		if funcIndexes {
+			b = append(b, init.Immediates...)
+		} else {
+			b = appendConstExpr(b, init)
+		}
+	}
+	return b
+}
+
+// appendCode appends the code section entry of `f`, which compresses locals
+// into runs of the same type.
+func appendCode(b []byte, f *Function) []byte {
+	var locals []byte
+	var runs uint32
+	for i := 0; i < len(f.Locals); {
+		j := i + 1
+		for j < len(f.Locals) && f.Locals[j] == f.Locals[i] {
+			j++
+		}
+		locals = append(locals, leb128.EncodeUint32(uint32(j-i))...)
+		locals = append(locals, f.Locals[i])
+		runs++
+		i = j
+	}
+	code := append(leb128.EncodeUint32(runs), locals...)
+	code = append(code, f.Body...)
+	b = append(b, leb128.EncodeUint32(uint32(len(code)))...)
+	return append(b, code...)
+}
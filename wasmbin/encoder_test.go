@@ -0,0 +1,105 @@
+package wasmbin
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestEncode(t *testing.T) {
+	zero, max := uint32(0), uint32(10)
+	m := &Module{
+		Name:  "test",
+		Types: []FunctionType{{}, {Params: []api.ValueType{i32}, Results: []api.ValueType{i64}}},
+		Imports: []Import{
+			{Module: "env", Name: "f", Type: api.ExternTypeFunc, TypeIndex: 1},
+			{Module: "env", Name: "t", Type: api.ExternTypeTable, Table: Table{Type: RefTypeExternref, Min: 1}},
+			{Module: "env", Name: "g", Type: api.ExternTypeGlobal, Global: GlobalType{Type: i32, Mutable: true}},
+		},
+		Functions: []Function{{
+			Locals: []api.ValueType{i64, i64, i32},
+			Body: EncodeInstructions([]Instruction{
+				{Opcode: wasm.OpcodeI32Const, Immediates: EncodeInt32(-1)},
+				{Opcode: wasm.OpcodeCall, Immediates: EncodeUint32(0)},
+				{Opcode: wasm.OpcodeDrop},
+				{Opcode: OpcodeMiscPrefix, Subopcode: uint32(wasm.OpcodeMiscDataDrop), Immediates: EncodeUint32(1)},
+				{Opcode: wasm.OpcodeEnd},
+			}),
+		}},
+		Tables: []Table{{Type: RefTypeFuncref, Min: 1, Max: &max}},
+		Memory: &Memory{Min: 1, Max: 2, HasMax: true},
+		Globals: []Global{
+			{
+				GlobalType: GlobalType{Type: i64},
+				Init:       Instruction{Opcode: wasm.OpcodeI64Const, Immediates: EncodeInt64(1 << 40)},
+			},
+			{
+				GlobalType: GlobalType{Type: wasm.ValueTypeV128, Mutable: true},
+				Init: Instruction{
+					Opcode:     OpcodeVecPrefix,
+					Subopcode:  uint32(wasm.OpcodeVecV128Const),
+					Immediates: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				},
+			},
+		},
+		Exports: []Export{{Name: "run", Type: api.ExternTypeFunc, Index: 1}},
+		Start:   &zero,
+		Elements: []ElementSegment{
+			{
+				Type:   RefTypeFuncref,
+				Offset: Instruction{Opcode: wasm.OpcodeGlobalGet, Immediates: EncodeUint32(0)},
+				Init:   []Instruction{{Opcode: wasm.OpcodeRefFunc, Immediates: EncodeUint32(0)}},
+			},
+			{
+				Type:       RefTypeFuncref,
+				TableIndex: 1,
+				Offset:     Instruction{Opcode: wasm.OpcodeI32Const, Immediates: EncodeInt32(0)},
+				Init:       []Instruction{{Opcode: wasm.OpcodeRefFunc, Immediates: EncodeUint32(1)}},
+			},
+			{
+				Type:   RefTypeExternref,
+				Offset: Instruction{Opcode: wasm.OpcodeI32Const, Immediates: EncodeInt32(0)},
+				Init:   []Instruction{{Opcode: wasm.OpcodeRefNull, Immediates: []byte{RefTypeExternref}}},
+			},
+			{
+				Type: RefTypeFuncref,
+				Mode: ElementModePassive,
+				Init: []Instruction{
+					{Opcode: wasm.OpcodeRefFunc, Immediates: EncodeUint32(0)},
+					{Opcode: wasm.OpcodeRefNull, Immediates: []byte{RefTypeFuncref}},
+				},
+			},
+			{
+				Type: RefTypeFuncref,
+				Mode: ElementModeDeclarative,
+				Init: []Instruction{{Opcode: wasm.OpcodeRefFunc, Immediates: EncodeUint32(1)}},
+			},
+		},
+		Data: []DataSegment{
+			{Offset: Instruction{Opcode: wasm.OpcodeI32Const, Immediates: EncodeInt32(8)}, Init: []byte("hello")},
+			{Init: []byte("world"), Passive: true},
+		},
+		CustomSections: []CustomSection{{Name: "producers", Data: []byte{1}}},
+	}
+
+	decoded, err := Decode(Encode(m))
+	require.NoError(t, err)
+
+	// Clear fields which are only set when decoding.
+	decoded.Sections = nil
+	for i := range decoded.Functions {
+		decoded.Functions[i].BodyOffset = 0
+	}
+	require.Equal(t, m, decoded)
+}
+
+func TestEncode_empty(t *testing.T) {
+	bin := Encode(&Module{})
+	require.Equal(t, magicAndVersion, bin)
+
+	m, err := Decode(bin)
+	require.NoError(t, err)
+	require.Equal(t, &Module{}, m)
+}
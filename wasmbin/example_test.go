@@ -0,0 +1,54 @@
+package wasmbin_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+// This shows how to construct a module which adds two numbers, and call it.
+func Example_encode() {
+	ctx := context.Background()
+
+	const (
+		opcodeLocalGet = 0x20
+		opcodeI32Add   = 0x6a
+		opcodeEnd      = 0x0b
+	)
+	bin := wasmbin.Encode(&wasmbin.Module{
+		Types: []wasmbin.FunctionType{
+			{Params: []api.ValueType{api.ValueTypeI32, api.ValueTypeI32}, Results: []api.ValueType{api.ValueTypeI32}},
+		},
+		Functions: []wasmbin.Function{{
+			TypeIndex: 0,
+			Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{
+				{Opcode: opcodeLocalGet, Immediates: wasmbin.EncodeUint32(0)},
+				{Opcode: opcodeLocalGet, Immediates: wasmbin.EncodeUint32(1)},
+				{Opcode: opcodeI32Add},
+				{Opcode: opcodeEnd},
+			}),
+		}},
+		Exports: []wasmbin.Export{{Name: "add", Type: api.ExternTypeFunc, Index: 0}},
+	})
+
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	mod, err := r.Instantiate(ctx, bin)
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	results, err := mod.ExportedFunction("add").Call(ctx, 1, 2)
+	if err != nil {
+		log.Panicln(err)
+	}
+	fmt.Println(results[0])
+
+	// Output:
+	// 3
+}
@@ -0,0 +1,560 @@
+package wasmbin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Extract rewrites the WebAssembly binary (%.wasm) into a standalone module
+// which only has the given exports and what they transitively use. This
+// creates a minimal module per endpoint from a monolithic guest build.
+//
+// For example, this extracts the "handle" function, along with the
+// functions it calls and the imports they need:
+//
+//	bin, err = wasmbin.Extract(bin, "handle")
+//
+// Here's what is kept, and renumbered in the order of the original:
+//
+//   - Functions called, referenced by "ref.func" or in an element segment of
+//     a kept table, and the start function, as it initializes state.
+//   - Globals read or written, including by initializers and offsets.
+//   - Tables used by "call_indirect" or a table instruction, with their
+//     active element segments. Passive element segments are only kept if
+//     used by "table.init" or "elem.drop".
+//   - Types of the above, including those of "call_indirect" and blocks.
+//   - Imports of the above. Others are removed, so the result needs fewer
+//     host functions than the original.
+//
+// # Notes
+//
+//   - The memory and data segments are kept as-is, as they define the
+//     initial state of the memory.
+//   - Custom sections besides the module name are removed, as indexes and
+//     offsets they refer to, e.g. DWARF, no longer match.
+//   - An error is returned if an export doesn't exist. The module is not
+//     validated.
+func Extract(bin []byte, exports ...string) ([]byte, error) {
+	return ExtractWithOptions(bin, ExtractOptions{}, exports...)
+}
+
+// ExtractOptions are the options of ExtractWithOptions.
+type ExtractOptions struct {
+	// Features are the features to decode the binary with, or
+	// api.CoreFeaturesV2 if zero.
+	Features api.CoreFeatures
+
+	// Unavailable returns true if the function import will not be available
+	// when the result is instantiated, e.g. as the host module which should
+	// define it doesn't. Calls to it are replaced with "unreachable", so that
+	// they trap instead of the instantiation failing, and it is removed unless
+	// otherwise used, e.g. by "ref.func".
+	//
+	// If nil, all function imports are assumed available.
+	Unavailable func(module, name string) bool
+}
+
+// ExtractWithOptions is like Extract, except with options.
+//
+// For example, this extracts the "handle" function of a binary which uses
+// the threads proposal, removing calls to "env.debug", which the host doesn't
+// define:
+//
+//	bin, err = wasmbin.ExtractWithOptions(bin, wasmbin.ExtractOptions{
+//		Features: api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+//		Unavailable: func(module, name string) bool {
+//			return module == "env" && name == "debug"
+//		},
+//	}, "handle")
+func ExtractWithOptions(bin []byte, opts ExtractOptions, exports ...string) ([]byte, error) {
+	features := opts.Features
+	if features == 0 {
+		features = api.CoreFeaturesV2
+	}
+	m, err := DecodeWithFeatures(bin, features)
+	if err != nil {
+		return nil, err
+	}
+
+	x := newExtractor(m, opts.Unavailable)
+	exportIndexes := make(map[string]int, len(m.Exports))
+	for i := range m.Exports {
+		exportIndexes[m.Exports[i].Name] = i
+	}
+	kept := make(map[string]struct{}, len(exports))
+	for _, name := range exports {
+		i, ok := exportIndexes[name]
+		if !ok {
+			return nil, fmt.Errorf("export %q not found", name)
+		}
+		kept[name] = struct{}{}
+		switch e := &m.Exports[i]; e.Type {
+		case api.ExternTypeFunc:
+			x.markFunction(e.Index)
+		case api.ExternTypeTable:
+			x.markTable(e.Index)
+		case api.ExternTypeGlobal:
+			x.markGlobal(e.Index)
+		}
+	}
+	if m.Start != nil {
+		x.markFunction(*m.Start)
+	}
+	for i := range m.Data {
+		x.markConst(m.Data[i].Offset)
+	}
+	if err = x.walk(); err != nil {
+		return nil, err
+	}
+
+	ret := &Module{Name: m.Name, Imports: x.imports(), Memory: m.Memory, Data: m.Data}
+	for i := range m.Functions {
+		if !x.functions.kept(x.importedFunctions + uint32(i)) {
+			continue
+		}
+		f := m.Functions[i]
+		f.TypeIndex = x.types.get(f.TypeIndex)
+		f.Body = x.rewriteBody(x.bodies[i])
+		f.BodyOffset = 0
+		ret.Functions = append(ret.Functions, f)
+	}
+	for i := range m.Tables {
+		if x.tables.kept(x.importedTables + uint32(i)) {
+			ret.Tables = append(ret.Tables, m.Tables[i])
+		}
+	}
+	for i := range m.Globals {
+		if x.globals.kept(x.importedGlobals + uint32(i)) {
+			g := m.Globals[i]
+			g.Init = x.rewriteConst(g.Init)
+			ret.Globals = append(ret.Globals, g)
+		}
+	}
+	for i := range m.Exports {
+		e := m.Exports[i]
+		if _, ok := kept[e.Name]; !ok {
+			continue
+		}
+		switch e.Type {
+		case api.ExternTypeFunc:
+			e.Index = x.functions.get(e.Index)
+		case api.ExternTypeTable:
+			e.Index = x.tables.get(e.Index)
+		case api.ExternTypeGlobal:
+			e.Index = x.globals.get(e.Index)
+		}
+		ret.Exports = append(ret.Exports, e)
+	}
+	if m.Start != nil {
+		start := x.functions.get(*m.Start)
+		ret.Start = &start
+	}
+	ret.Elements = x.elements()
+	for i := range ret.Data {
+		if !ret.Data[i].Passive {
+			ret.Data[i].Offset = x.rewriteConst(ret.Data[i].Offset)
+		}
+	}
+	for i, t := range m.Types {
+		if x.types.kept(uint32(i)) {
+			ret.Types = append(ret.Types, t)
+		}
+	}
+	return Encode(ret), nil
+}
+
+// indexSpace tracks which indexes of functions, tables, globals, types or
+// element segments are kept, to renumber them.
+type indexSpace struct {
+	marked []bool
+	// renumbered are the new indexes, computed by renumber.
+	renumbered []uint32
+}
+
+func newIndexSpace(count int) indexSpace {
+	return indexSpace{marked: make([]bool, count)}
+}
+
+// mark returns true the first time `i` is marked.
+func (s *indexSpace) mark(i uint32) bool {
+	if int(i) >= len(s.marked) || s.marked[i] {
+		return false
+	}
+	s.marked[i] = true
+	return true
+}
+
+func (s *indexSpace) kept(i uint32) bool {
+	return int(i) < len(s.marked) && s.marked[i]
+}
+
+func (s *indexSpace) renumber() {
+	s.renumbered = make([]uint32, len(s.marked))
+	var next uint32
+	for i, marked := range s.marked {
+		if marked {
+			s.renumbered[i] = next
+			next++
+		}
+	}
+}
+
+// get returns the new index of `i`, which was kept.
+func (s *indexSpace) get(i uint32) uint32 {
+	if int(i) >= len(s.renumbered) {
+		return i // invalid indexes are left as-is, as the module isn't validated.
+	}
+	return s.renumbered[i]
+}
+
+// extractor computes the transitive closure of what is used by the exports
+// for Extract.
+type extractor struct {
+	m *Module
+
+	importedFunctions, importedTables, importedGlobals uint32
+
+	functions, tables, globals, types, elems indexSpace
+
+	// unavailable are the function imports whose calls are replaced with
+	// "unreachable", by function index.
+	unavailable []bool
+
+	// bodies are the decoded functions defined in the module, or nil until
+	// they are marked.
+	bodies [][]Instruction
+
+	// pending are the indexes of the defined functions to walk.
+	pending []uint32
+}
+
+func newExtractor(m *Module, unavailable func(module, name string) bool) *extractor {
+	x := &extractor{m: m}
+	for i := range m.Imports {
+		switch imp := &m.Imports[i]; imp.Type {
+		case api.ExternTypeFunc:
+			x.unavailable = append(x.unavailable, unavailable != nil && unavailable(imp.Module, imp.Name))
+			x.importedFunctions++
+		case api.ExternTypeTable:
+			x.importedTables++
+		case api.ExternTypeGlobal:
+			x.importedGlobals++
+		}
+	}
+	x.functions = newIndexSpace(int(x.importedFunctions) + len(m.Functions))
+	x.tables = newIndexSpace(int(x.importedTables) + len(m.Tables))
+	x.globals = newIndexSpace(int(x.importedGlobals) + len(m.Globals))
+	x.types = newIndexSpace(len(m.Types))
+	x.elems = newIndexSpace(len(m.Elements))
+	x.bodies = make([][]Instruction, len(m.Functions))
+	return x
+}
+
+func (x *extractor) markFunction(i uint32) {
+	if x.functions.mark(i) && i >= x.importedFunctions {
+		x.pending = append(x.pending, i-x.importedFunctions)
+	}
+}
+
+func (x *extractor) markTable(i uint32) {
+	if !x.tables.mark(i) {
+		return
+	}
+	for j := range x.m.Elements {
+		if e := &x.m.Elements[j]; e.Mode == ElementModeActive && e.TableIndex == i {
+			x.markElement(uint32(j))
+		}
+	}
+}
+
+func (x *extractor) markGlobal(i uint32) {
+	if x.globals.mark(i) && i >= x.importedGlobals && int(i-x.importedGlobals) < len(x.m.Globals) {
+		x.markConst(x.m.Globals[i-x.importedGlobals].Init)
+	}
+}
+
+func (x *extractor) markElement(i uint32) {
+	if !x.elems.mark(i) {
+		return
+	}
+	e := &x.m.Elements[i]
+	if e.Mode == ElementModeActive {
+		x.markTable(e.TableIndex)
+		x.markConst(e.Offset)
+	}
+	for _, init := range e.Init {
+		x.markConst(init)
+	}
+}
+
+// markConst marks what's used by a constant instruction.
+func (x *extractor) markConst(inst Instruction) {
+	for _, inst := range constInstructions(inst) {
+		switch inst.Opcode {
+		case wasm.OpcodeRefFunc:
+			x.markFunction(immediates(inst, 1)[0])
+		case wasm.OpcodeGlobalGet:
+			x.markGlobal(immediates(inst, 1)[0])
+		}
+	}
+}
+
+// isUnavailable returns true if `i` is a function import whose calls are
+// replaced with "unreachable".
+func (x *extractor) isUnavailable(i uint32) bool {
+	return int(i) < len(x.unavailable) && x.unavailable[i]
+}
+
+// walk marks what's used by the pending functions until there are none.
+func (x *extractor) walk() (err error) {
+	for len(x.pending) > 0 {
+		i := x.pending[len(x.pending)-1]
+		x.pending = x.pending[:len(x.pending)-1]
+		if int(i) >= len(x.m.Functions) {
+			return fmt.Errorf("function[%d]: not found", x.importedFunctions+i)
+		}
+		f := &x.m.Functions[i]
+		x.types.mark(f.TypeIndex)
+		if x.bodies[i], err = DecodeInstructions(f.Body); err != nil {
+			return fmt.Errorf("function[%d]: %w", x.importedFunctions+i, err)
+		}
+		for _, inst := range x.bodies[i] {
+			x.markInstruction(inst)
+		}
+	}
+
+	// Declarative segments are kept while they declare a kept function, as
+	// "ref.func" requires it.
+	for i := range x.m.Elements {
+		if e := &x.m.Elements[i]; e.Mode == ElementModeDeclarative && len(x.declared(e)) > 0 {
+			x.elems.mark(uint32(i))
+		}
+	}
+	for i := range x.m.Imports {
+		// Only imported functions have a type.
+		imp := &x.m.Imports[i]
+		if imp.Type == api.ExternTypeFunc && x.functions.kept(x.importIndex(i)) {
+			x.types.mark(imp.TypeIndex)
+		}
+	}
+	x.functions.renumber()
+	x.tables.renumber()
+	x.globals.renumber()
+	x.types.renumber()
+	x.elems.renumber()
+	return nil
+}
+
+func (x *extractor) markInstruction(inst Instruction) {
+	switch inst.Opcode {
+	case wasm.OpcodeCall:
+		if i := immediates(inst, 1)[0]; !x.isUnavailable(i) {
+			x.markFunction(i)
+		}
+	case wasm.OpcodeRefFunc:
+		x.markFunction(immediates(inst, 1)[0])
+	case wasm.OpcodeCallIndirect:
+		typeAndTable := immediates(inst, 2)
+		x.types.mark(typeAndTable[0])
+		x.markTable(typeAndTable[1])
+	case wasm.OpcodeGlobalGet, wasm.OpcodeGlobalSet:
+		x.markGlobal(immediates(inst, 1)[0])
+	case wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+		x.markTable(immediates(inst, 1)[0])
+	case wasm.OpcodeBlock, wasm.OpcodeLoop, wasm.OpcodeIf:
+		if t, ok := blockTypeIndex(inst); ok {
+			x.types.mark(t)
+		}
+	case OpcodeMiscPrefix:
+		switch inst.Subopcode {
+		case uint32(wasm.OpcodeMiscTableGrow), uint32(wasm.OpcodeMiscTableSize), uint32(wasm.OpcodeMiscTableFill):
+			x.markTable(immediates(inst, 1)[0])
+		case uint32(wasm.OpcodeMiscTableCopy):
+			tables := immediates(inst, 2)
+			x.markTable(tables[0])
+			x.markTable(tables[1])
+		case uint32(wasm.OpcodeMiscTableInit):
+			elemAndTable := immediates(inst, 2)
+			x.markElement(elemAndTable[0])
+			x.markTable(elemAndTable[1])
+		case uint32(wasm.OpcodeMiscElemDrop):
+			x.markElement(immediates(inst, 1)[0])
+		}
+	}
+}
+
+// importIndex returns the index of the import `i` in the index space of its
+// type.
+func (x *extractor) importIndex(i int) (ret uint32) {
+	for j := 0; j < i; j++ {
+		if x.m.Imports[j].Type == x.m.Imports[i].Type {
+			ret++
+		}
+	}
+	return
+}
+
+// imports returns the kept imports, and the memory, if imported.
+func (x *extractor) imports() (ret []Import) {
+	var functions, tables, globals uint32
+	for i := range x.m.Imports {
+		imp := x.m.Imports[i]
+		switch imp.Type {
+		case api.ExternTypeFunc:
+			functions++
+			if !x.functions.kept(functions - 1) {
+				continue
+			}
+			imp.TypeIndex = x.types.get(imp.TypeIndex)
+		case api.ExternTypeTable:
+			tables++
+			if !x.tables.kept(tables - 1) {
+				continue
+			}
+		case api.ExternTypeGlobal:
+			globals++
+			if !x.globals.kept(globals - 1) {
+				continue
+			}
+		}
+		ret = append(ret, imp)
+	}
+	return
+}
+
+// declared returns the instructions of a declarative segment which are of a
+// kept function.
+func (x *extractor) declared(e *ElementSegment) (ret []Instruction) {
+	for _, inst := range e.Init {
+		if inst.Opcode == wasm.OpcodeRefFunc && x.functions.kept(immediates(inst, 1)[0]) {
+			ret = append(ret, inst)
+		}
+	}
+	return
+}
+
+// elements returns the kept element segments, where declarative ones only
+// declare kept functions.
+func (x *extractor) elements() (ret []ElementSegment) {
+	for i := range x.m.Elements {
+		e := x.m.Elements[i]
+		if !x.elems.kept(uint32(i)) {
+			continue
+		}
+		switch e.Mode {
+		case ElementModeActive:
+			e.TableIndex = x.tables.get(e.TableIndex)
+			e.Offset = x.rewriteConst(e.Offset)
+		case ElementModeDeclarative:
+			e.Init = x.declared(&e)
+		}
+		init := make([]Instruction, len(e.Init))
+		for j, inst := range e.Init {
+			init[j] = x.rewriteConst(inst)
+		}
+		e.Init = init
+		ret = append(ret, e)
+	}
+	return
+}
+
+// rewriteConst renumbers the indexes of a constant instruction.
+func (x *extractor) rewriteConst(inst Instruction) Instruction {
+	instructions := constInstructions(inst)
+	for i := range instructions {
+		switch inst := &instructions[i]; inst.Opcode {
+		case wasm.OpcodeRefFunc:
+			inst.Immediates = EncodeUint32(x.functions.get(immediates(*inst, 1)[0]))
+		case wasm.OpcodeGlobalGet:
+			inst.Immediates = EncodeUint32(x.globals.get(immediates(*inst, 1)[0]))
+		}
+	}
+	if len(instructions) == 1 {
+		return instructions[0]
+	}
+	b := EncodeInstructions(instructions)
+	return Instruction{Opcode: b[0], Immediates: b[1:]}
+}
+
+// constInstructions returns the instructions of a constant instruction, which
+// are several when extended. See Instruction.Immediates
+func constInstructions(inst Instruction) []Instruction {
+	if instructions, err := DecodeInstructions(EncodeInstructions([]Instruction{inst})); err == nil && len(instructions) > 1 {
+		return instructions
+	}
+	return []Instruction{inst}
+}
+
+// rewriteBody renumbers the indexes used by the instructions of a function.
+func (x *extractor) rewriteBody(body []Instruction) []byte {
+	rewritten := make([]Instruction, len(body))
+	for i, inst := range body {
+		switch inst.Opcode {
+		case wasm.OpcodeCall:
+			if f := immediates(inst, 1)[0]; x.isUnavailable(f) {
+				// The stack is polymorphic after "unreachable", so the
+				// arguments and results of the call need no replacement.
+				inst = Instruction{Opcode: wasm.OpcodeUnreachable}
+			} else {
+				inst.Immediates = EncodeUint32(x.functions.get(f))
+			}
+		case wasm.OpcodeRefFunc:
+			inst.Immediates = EncodeUint32(x.functions.get(immediates(inst, 1)[0]))
+		case wasm.OpcodeCallIndirect:
+			typeAndTable := immediates(inst, 2)
+			inst.Immediates = append(EncodeUint32(x.types.get(typeAndTable[0])), EncodeUint32(x.tables.get(typeAndTable[1]))...)
+		case wasm.OpcodeGlobalGet, wasm.OpcodeGlobalSet:
+			inst.Immediates = EncodeUint32(x.globals.get(immediates(inst, 1)[0]))
+		case wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+			inst.Immediates = EncodeUint32(x.tables.get(immediates(inst, 1)[0]))
+		case wasm.OpcodeBlock, wasm.OpcodeLoop, wasm.OpcodeIf:
+			if t, ok := blockTypeIndex(inst); ok {
+				inst.Immediates = leb128.EncodeInt64(int64(x.types.get(t)))
+			}
+		case OpcodeMiscPrefix:
+			switch inst.Subopcode {
+			case uint32(wasm.OpcodeMiscTableGrow), uint32(wasm.OpcodeMiscTableSize), uint32(wasm.OpcodeMiscTableFill):
+				inst.Immediates = EncodeUint32(x.tables.get(immediates(inst, 1)[0]))
+			case uint32(wasm.OpcodeMiscTableCopy):
+				tables := immediates(inst, 2)
+				inst.Immediates = append(EncodeUint32(x.tables.get(tables[0])), EncodeUint32(x.tables.get(tables[1]))...)
+			case uint32(wasm.OpcodeMiscTableInit):
+				elemAndTable := immediates(inst, 2)
+				inst.Immediates = append(EncodeUint32(x.elems.get(elemAndTable[0])), EncodeUint32(x.tables.get(elemAndTable[1]))...)
+			case uint32(wasm.OpcodeMiscElemDrop):
+				inst.Immediates = EncodeUint32(x.elems.get(immediates(inst, 1)[0]))
+			}
+		}
+		rewritten[i] = inst
+	}
+	return EncodeInstructions(rewritten)
+}
+
+// immediates returns the first `n` unsigned LEB128 immediates of the
+// instruction, or zero for those missing.
+func immediates(inst Instruction, n int) []uint32 {
+	ret := make([]uint32, n)
+	b := inst.Immediates
+	for i := range ret {
+		v, num, err := leb128.LoadUint32(b)
+		if err != nil {
+			break
+		}
+		ret[i], b = v, b[num:]
+	}
+	return ret
+}
+
+// blockTypeIndex returns the type index of a block, loop or if, which is
+// only encoded when it has parameters or multiple results.
+func blockTypeIndex(inst Instruction) (uint32, bool) {
+	v, _, err := leb128.DecodeInt33AsInt64(bytes.NewReader(inst.Immediates))
+	if err != nil || v < 0 {
+		return 0, false
+	}
+	return uint32(v), true
+}
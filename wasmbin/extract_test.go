@@ -0,0 +1,220 @@
+package wasmbin_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/wasmbin"
+)
+
+func TestExtract(t *testing.T) {
+	i32 := api.ValueTypeI32
+	call := func(index uint32) wasmbin.Instruction {
+		return wasmbin.Instruction{Opcode: wasm.OpcodeCall, Immediates: wasmbin.EncodeUint32(index)}
+	}
+	i32Const := func(v int32) wasmbin.Instruction {
+		return wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: wasmbin.EncodeInt32(v)}
+	}
+	end := wasmbin.Instruction{Opcode: wasm.OpcodeEnd}
+	bin := wasmbin.Encode(&wasmbin.Module{
+		Types: []wasmbin.FunctionType{{}, {Results: []api.ValueType{i32}}, {Params: []api.ValueType{i32}, Results: []api.ValueType{i32}}},
+		Imports: []wasmbin.Import{
+			{Module: "env", Name: "a", Type: api.ExternTypeFunc, TypeIndex: 2},
+			{Module: "env", Name: "b", Type: api.ExternTypeFunc, TypeIndex: 2},
+		},
+		Functions: []wasmbin.Function{
+			{ // 2: "one" calls env.a with the global.
+				TypeIndex: 1,
+				Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{
+					{Opcode: wasm.OpcodeGlobalGet, Immediates: wasmbin.EncodeUint32(1)}, call(0), end,
+				}),
+			},
+			{ // 3: "two" calls env.b with the result of the table element 0.
+				TypeIndex: 1,
+				Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{
+					i32Const(0),
+					{Opcode: wasm.OpcodeCallIndirect, Immediates: append(wasmbin.EncodeUint32(1), 0)},
+					call(1), end,
+				}),
+			},
+			{TypeIndex: 1, Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{i32Const(40), end})}, // 4: in the table
+			{TypeIndex: 0, Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{end})},               // 5: unused
+		},
+		Tables: []wasmbin.Table{{Type: wasmbin.RefTypeFuncref, Min: 1}},
+		Memory: &wasmbin.Memory{Min: 1},
+		Globals: []wasmbin.Global{
+			{GlobalType: wasmbin.GlobalType{Type: i32}, Init: i32Const(1)},
+			{GlobalType: wasmbin.GlobalType{Type: i32}, Init: i32Const(2)},
+		},
+		Exports: []wasmbin.Export{
+			{Name: "one", Type: api.ExternTypeFunc, Index: 2},
+			{Name: "two", Type: api.ExternTypeFunc, Index: 3},
+			{Name: "unused", Type: api.ExternTypeFunc, Index: 5},
+			{Name: "memory", Type: api.ExternTypeMemory},
+		},
+		Elements: []wasmbin.ElementSegment{{
+			Type:   wasmbin.RefTypeFuncref,
+			Offset: i32Const(0),
+			Init:   []wasmbin.Instruction{{Opcode: wasm.OpcodeRefFunc, Immediates: wasmbin.EncodeUint32(4)}},
+		}},
+		Data: []wasmbin.DataSegment{{Offset: i32Const(0), Init: []byte("hello")}},
+	})
+
+	tests := []struct {
+		name            string
+		exports         []string
+		expectedImports []string
+		// expectedFunctions is the count of functions defined.
+		expectedFunctions int
+		expected          map[string]uint64
+	}{
+		{
+			name:              "one",
+			exports:           []string{"one"},
+			expectedImports:   []string{"a"},
+			expectedFunctions: 1,
+			expected:          map[string]uint64{"one": 20},
+		},
+		{
+			name:              "two",
+			exports:           []string{"two", "memory"},
+			expectedImports:   []string{"b"},
+			expectedFunctions: 2,
+			expected:          map[string]uint64{"two": 240},
+		},
+		{
+			name:              "both",
+			exports:           []string{"two", "one"},
+			expectedImports:   []string{"a", "b"},
+			expectedFunctions: 3,
+			expected:          map[string]uint64{"one": 20, "two": 240},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			extracted, err := wasmbin.Extract(bin, tc.exports...)
+			require.NoError(t, err)
+
+			m, err := wasmbin.Decode(extracted)
+			require.NoError(t, err)
+			var imports []string
+			for _, imp := range m.Imports {
+				imports = append(imports, imp.Name)
+			}
+			require.Equal(t, tc.expectedImports, imports)
+			require.Equal(t, tc.expectedFunctions, len(m.Functions))
+			require.Equal(t, len(tc.exports), len(m.Exports))
+
+			r := wazero.NewRuntime(testCtx)
+			defer r.Close(testCtx)
+
+			// Only define the imports needed, so instantiation fails if the
+			// extracted module imports more.
+			env := r.NewHostModuleBuilder("env")
+			for _, name := range tc.expectedImports {
+				factor := uint32(10)
+				if name == "b" {
+					factor = 6
+				}
+				env.NewFunctionBuilder().WithFunc(func(_ context.Context, v uint32) uint32 {
+					return v * factor
+				}).Export(name)
+			}
+			_, err = env.Instantiate(testCtx)
+			require.NoError(t, err)
+
+			mod, err := r.Instantiate(testCtx, extracted)
+			require.NoError(t, err)
+			for name, expected := range tc.expected {
+				results, err := mod.ExportedFunction(name).Call(testCtx)
+				require.NoError(t, err)
+				require.Equal(t, []uint64{expected}, results)
+			}
+			require.Nil(t, mod.ExportedFunction("unused"))
+		})
+	}
+}
+
+func TestExtractWithOptions(t *testing.T) {
+	i32 := api.ValueTypeI32
+	// "run" calls env.a when its param is non-zero, otherwise env.b. Its
+	// result is the global of the extended constant expression 1 + 2.
+	bin := wasmbin.Encode(&wasmbin.Module{
+		Types: []wasmbin.FunctionType{{}, {Params: []api.ValueType{i32}, Results: []api.ValueType{i32}}},
+		Imports: []wasmbin.Import{
+			{Module: "env", Name: "a", Type: api.ExternTypeFunc},
+			{Module: "env", Name: "b", Type: api.ExternTypeFunc},
+		},
+		Functions: []wasmbin.Function{{
+			TypeIndex: 1,
+			Body: wasmbin.EncodeInstructions([]wasmbin.Instruction{
+				{Opcode: wasm.OpcodeLocalGet, Immediates: []byte{0}},
+				{Opcode: wasm.OpcodeIf, Immediates: []byte{0x40}},
+				{Opcode: wasm.OpcodeCall, Immediates: wasmbin.EncodeUint32(0)},
+				{Opcode: wasm.OpcodeElse},
+				{Opcode: wasm.OpcodeCall, Immediates: wasmbin.EncodeUint32(1)},
+				{Opcode: wasm.OpcodeEnd},
+				{Opcode: wasm.OpcodeGlobalGet, Immediates: wasmbin.EncodeUint32(0)},
+				{Opcode: wasm.OpcodeEnd},
+			}),
+		}},
+		Globals: []wasmbin.Global{{
+			GlobalType: wasmbin.GlobalType{Type: i32},
+			Init:       wasmbin.Instruction{Opcode: wasm.OpcodeI32Const, Immediates: []byte{1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Add}},
+		}},
+		Exports: []wasmbin.Export{{Name: "run", Type: api.ExternTypeFunc, Index: 2}},
+	})
+
+	_, err := wasmbin.Extract(bin, "run")
+	require.EqualError(t, err, "global[0]: constant expression has been not terminated")
+
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst
+	extracted, err := wasmbin.ExtractWithOptions(bin, wasmbin.ExtractOptions{
+		Features: features,
+		Unavailable: func(module, name string) bool {
+			return module == "env" && name == "b"
+		},
+	}, "run")
+	require.NoError(t, err)
+
+	m, err := wasmbin.DecodeWithFeatures(extracted, features)
+	require.NoError(t, err)
+	require.Equal(t, []wasmbin.Import{{Module: "env", Name: "a", Type: api.ExternTypeFunc}}, m.Imports)
+
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().WithCoreFeatures(features))
+	defer r.Close(testCtx)
+
+	_, err = r.NewHostModuleBuilder("env").
+		NewFunctionBuilder().WithFunc(func(context.Context) {}).Export("a").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+
+	mod, err := r.Instantiate(testCtx, extracted)
+	require.NoError(t, err)
+
+	results, err := mod.ExportedFunction("run").Call(testCtx, 1)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, results)
+
+	// The call to env.b was replaced with "unreachable".
+	_, err = mod.ExportedFunction("run").Call(testCtx, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "wasm error: unreachable")
+}
+
+func TestExtract_Errors(t *testing.T) {
+	bin := wasmbin.Encode(&wasmbin.Module{
+		Exports: []wasmbin.Export{{Name: "memory", Type: api.ExternTypeMemory}},
+		Memory:  &wasmbin.Memory{Min: 1},
+	})
+
+	_, err := wasmbin.Extract(bin, "memory", "run")
+	require.EqualError(t, err, `export "run" not found`)
+}
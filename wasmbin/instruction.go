@@ -0,0 +1,245 @@
+package wasmbin
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// Opcode is the first byte of an instruction.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/instructions.html
+type Opcode = byte
+
+const (
+	// OpcodeMiscPrefix is the prefix of miscellaneous instructions, such as
+	// "memory.copy", which are identified by Instruction.Subopcode.
+	OpcodeMiscPrefix Opcode = wasm.OpcodeMiscPrefix
+
+	// OpcodeVecPrefix is the prefix of vector instructions, such as
+	// "v128.load", which are identified by Instruction.Subopcode.
+	OpcodeVecPrefix Opcode = wasm.OpcodeVecPrefix
+
+	// OpcodeAtomicPrefix is the prefix of atomic instructions, such as
+	// "memory.atomic.wait32", which are identified by Instruction.Subopcode.
+	OpcodeAtomicPrefix Opcode = wasm.OpcodeAtomicPrefix
+)
+
+// Instruction is an instruction of a function body or constant expression.
+type Instruction struct {
+	// Offset is the byte offset of the instruction in the function body.
+	Offset uint32
+
+	// Opcode is the first byte of the instruction.
+	Opcode Opcode
+
+	// Subopcode identifies the instruction when Opcode is OpcodeMiscPrefix,
+	// OpcodeVecPrefix or OpcodeAtomicPrefix. Otherwise, it is zero.
+	Subopcode uint32
+
+	// Immediates are the encoded immediate arguments, such as the LEB128
+	// index of "call", or nil if none.
+	//
+	// Note: A constant expression of several instructions, as defined by
+	// experimental.CoreFeaturesExtendedConst, is the first instruction, whose
+	// Immediates also include the instructions after it.
+	Immediates []byte
+}
+
+// Name returns the name of the instruction in the text format, e.g.
+// "i32.add", or empty if unknown.
+func (i Instruction) Name() string {
+	switch i.Opcode {
+	case OpcodeMiscPrefix:
+		if i.Subopcode <= 0xff {
+			return wasm.MiscInstructionName(byte(i.Subopcode))
+		}
+		return ""
+	case OpcodeVecPrefix:
+		if i.Subopcode <= 0xff {
+			return wasm.VectorInstructionName(byte(i.Subopcode))
+		}
+		return wasm.VecRelaxedInstructionName(i.Subopcode)
+	case OpcodeAtomicPrefix:
+		if i.Subopcode <= 0xff {
+			return wasm.AtomicInstructionName(byte(i.Subopcode))
+		}
+		return ""
+	}
+	return wasm.InstructionName(i.Opcode)
+}
+
+// DecodeInstructions decodes all instructions of a function body, such as
+// Function.Body, or returns an error if they are malformed.
+//
+// Note: Instructions are not validated, e.g. a "call" may be of an unknown
+// function.
+func DecodeInstructions(body []byte) (ret []Instruction, err error) {
+	for pc := 0; pc < len(body); {
+		var n int
+		inst := Instruction{Offset: uint32(pc), Opcode: body[pc]}
+		if inst.Subopcode, n, err = decodeInstruction(body[pc:]); err != nil {
+			return nil, fmt.Errorf("%s at offset %d: %w", inst.Name(), pc, err)
+		}
+		start := 1
+		if hasSubopcode(inst.Opcode) {
+			_, num, _ := leb128.LoadUint32(body[pc+1:])
+			start += int(num)
+		}
+		if n > start {
+			inst.Immediates = body[pc+start : pc+n]
+		}
+		ret = append(ret, inst)
+		pc += n
+	}
+	return
+}
+
+// decodeInstruction returns the subopcode and length of the instruction at
+// the beginning of `b`.
+func decodeInstruction(b []byte) (subopcode uint32, n int, err error) {
+	r := &immediateReader{b: b, pc: 1}
+	switch op := b[0]; {
+	case op == wasm.OpcodeBlock, op == wasm.OpcodeLoop, op == wasm.OpcodeIf:
+		r.s33()
+	case op == wasm.OpcodeBr, op == wasm.OpcodeBrIf, op == wasm.OpcodeCall,
+		op == wasm.OpcodeLocalGet, op == wasm.OpcodeLocalSet, op == wasm.OpcodeLocalTee,
+		op == wasm.OpcodeGlobalGet, op == wasm.OpcodeGlobalSet,
+		op == wasm.OpcodeTableGet, op == wasm.OpcodeTableSet, op == wasm.OpcodeRefFunc:
+		r.u32()
+	case op == wasm.OpcodeBrTable:
+		for count := r.u32(); count > 0 && r.err == nil; count-- {
+			r.u32()
+		}
+		r.u32() // default target
+	case op == wasm.OpcodeCallIndirect:
+		r.u32() // type index
+		r.u32() // table index
+	case op == wasm.OpcodeTypedSelect:
+		r.bytes(int(r.u32()))
+	case wasm.OpcodeI32Load <= op && op <= wasm.OpcodeI64Store32:
+		r.memArg()
+	case op == wasm.OpcodeMemorySize, op == wasm.OpcodeMemoryGrow, op == wasm.OpcodeRefNull:
+		r.bytes(1)
+	case op == wasm.OpcodeI32Const:
+		r.s32()
+	case op == wasm.OpcodeI64Const:
+		r.s64()
+	case op == wasm.OpcodeF32Const:
+		r.bytes(4)
+	case op == wasm.OpcodeF64Const:
+		r.bytes(8)
+	case op == OpcodeMiscPrefix:
+		if subopcode = r.u32(); subopcode <= 0xff {
+			decodeMiscImmediates(r, byte(subopcode))
+		}
+	case op == OpcodeVecPrefix:
+		if subopcode = r.u32(); subopcode <= 0xff {
+			decodeVecImmediates(r, byte(subopcode))
+		}
+	case op == OpcodeAtomicPrefix:
+		if subopcode = r.u32(); subopcode == uint32(wasm.OpcodeAtomicFence) {
+			r.bytes(1)
+		} else {
+			r.memArg()
+		}
+	}
+	return subopcode, r.pc, r.err
+}
+
+// hasSubopcode returns true if the opcode is a prefix of instructions which
+// are identified by a subopcode.
+func hasSubopcode(op Opcode) bool {
+	return op == OpcodeMiscPrefix || op == OpcodeVecPrefix || op == OpcodeAtomicPrefix
+}
+
+func decodeMiscImmediates(r *immediateReader, subopcode wasm.OpcodeMisc) {
+	switch subopcode {
+	case wasm.OpcodeMiscMemoryInit:
+		r.u32() // data index
+		r.bytes(1)
+	case wasm.OpcodeMiscDataDrop, wasm.OpcodeMiscElemDrop,
+		wasm.OpcodeMiscTableGrow, wasm.OpcodeMiscTableSize, wasm.OpcodeMiscTableFill:
+		r.u32()
+	case wasm.OpcodeMiscMemoryCopy:
+		r.bytes(2)
+	case wasm.OpcodeMiscMemoryFill:
+		r.bytes(1)
+	case wasm.OpcodeMiscTableInit, wasm.OpcodeMiscTableCopy:
+		r.u32()
+		r.u32()
+	}
+}
+
+func decodeVecImmediates(r *immediateReader, subopcode wasm.OpcodeVec) {
+	switch {
+	case subopcode <= wasm.OpcodeVecV128Store,
+		subopcode == wasm.OpcodeVecV128Load32zero, subopcode == wasm.OpcodeVecV128Load64zero:
+		r.memArg()
+	case subopcode == wasm.OpcodeVecV128Const, subopcode == wasm.OpcodeVecV128i8x16Shuffle:
+		r.bytes(16)
+	case wasm.OpcodeVecI8x16ExtractLaneS <= subopcode && subopcode <= wasm.OpcodeVecF64x2ReplaceLane:
+		r.bytes(1) // lane index
+	case wasm.OpcodeVecV128Load8Lane <= subopcode && subopcode <= wasm.OpcodeVecV128Store64Lane:
+		r.memArg()
+		r.bytes(1) // lane index
+	}
+}
+
+// immediateReader reads immediates, retaining the first error.
+type immediateReader struct {
+	b   []byte
+	pc  int
+	err error
+}
+
+func (r *immediateReader) u32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	v, n, err := leb128.LoadUint32(r.b[r.pc:])
+	r.pc += int(n)
+	r.err = err
+	return v
+}
+
+func (r *immediateReader) s32() {
+	if r.err == nil {
+		_, n, err := leb128.LoadInt32(r.b[r.pc:])
+		r.pc += int(n)
+		r.err = err
+	}
+}
+
+func (r *immediateReader) s33() {
+	if r.err == nil {
+		_, n, err := leb128.DecodeInt33AsInt64(bytes.NewReader(r.b[r.pc:]))
+		r.pc += int(n)
+		r.err = err
+	}
+}
+
+func (r *immediateReader) s64() {
+	if r.err == nil {
+		_, n, err := leb128.LoadInt64(r.b[r.pc:])
+		r.pc += int(n)
+		r.err = err
+	}
+}
+
+func (r *immediateReader) memArg() {
+	r.u32() // align
+	r.u32() // offset
+}
+
+func (r *immediateReader) bytes(n int) {
+	if r.err != nil {
+		return
+	} else if n > len(r.b)-r.pc {
+		r.err = fmt.Errorf("expected %d bytes, but only %d left", n, len(r.b)-r.pc)
+		return
+	}
+	r.pc += n
+}
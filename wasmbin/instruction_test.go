@@ -0,0 +1,89 @@
+package wasmbin
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+const (
+	i32 = api.ValueTypeI32
+	i64 = api.ValueTypeI64
+)
+
+func TestDecodeInstructions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     []byte
+		expected []Instruction
+		names    []string
+	}{
+		{
+			name:     "no immediates",
+			body:     []byte{wasm.OpcodeI32Add, wasm.OpcodeEnd},
+			expected: []Instruction{{Offset: 0, Opcode: wasm.OpcodeI32Add}, {Offset: 1, Opcode: wasm.OpcodeEnd}},
+			names:    []string{"i32.add", "end"},
+		},
+		{
+			name: "immediates",
+			body: []byte{
+				wasm.OpcodeBlock, 0x40,
+				wasm.OpcodeI64Const, 0x80, 0x01,
+				wasm.OpcodeBrTable, 2, 0, 1, 0,
+				wasm.OpcodeI32Load, 2, 8,
+				wasm.OpcodeF32Const, 0, 0, 0, 0,
+				wasm.OpcodeEnd,
+			},
+			expected: []Instruction{
+				{Offset: 0, Opcode: wasm.OpcodeBlock, Immediates: []byte{0x40}},
+				{Offset: 2, Opcode: wasm.OpcodeI64Const, Immediates: []byte{0x80, 0x01}},
+				{Offset: 5, Opcode: wasm.OpcodeBrTable, Immediates: []byte{2, 0, 1, 0}},
+				{Offset: 10, Opcode: wasm.OpcodeI32Load, Immediates: []byte{2, 8}},
+				{Offset: 13, Opcode: wasm.OpcodeF32Const, Immediates: []byte{0, 0, 0, 0}},
+				{Offset: 18, Opcode: wasm.OpcodeEnd},
+			},
+			names: []string{"block", "i64.const", "br_table", "i32.load", "f32.const", "end"},
+		},
+		{
+			name: "prefixed",
+			body: []byte{
+				OpcodeMiscPrefix, wasm.OpcodeMiscMemoryCopy, 0, 0,
+				OpcodeMiscPrefix, wasm.OpcodeMiscI32TruncSatF32S,
+				OpcodeVecPrefix, wasm.OpcodeVecI8x16ExtractLaneS, 3,
+				OpcodeVecPrefix, wasm.OpcodeVecV128Load8Lane, 0, 0, 1,
+			},
+			expected: []Instruction{
+				{Offset: 0, Opcode: OpcodeMiscPrefix, Subopcode: uint32(wasm.OpcodeMiscMemoryCopy), Immediates: []byte{0, 0}},
+				{Offset: 4, Opcode: OpcodeMiscPrefix, Subopcode: uint32(wasm.OpcodeMiscI32TruncSatF32S)},
+				{Offset: 6, Opcode: OpcodeVecPrefix, Subopcode: uint32(wasm.OpcodeVecI8x16ExtractLaneS), Immediates: []byte{3}},
+				{Offset: 9, Opcode: OpcodeVecPrefix, Subopcode: uint32(wasm.OpcodeVecV128Load8Lane), Immediates: []byte{0, 0, 1}},
+			},
+			names: []string{"memory.copy", "i32.trunc_sat_f32_s", "i8x16.extract_lane_s", "v128.load8_lane"},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := DecodeInstructions(tc.body)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, actual)
+
+			var names []string
+			for _, inst := range actual {
+				names = append(names, inst.Name())
+			}
+			require.Equal(t, tc.names, names)
+		})
+	}
+}
+
+func TestDecodeInstructions_Errors(t *testing.T) {
+	_, err := DecodeInstructions([]byte{wasm.OpcodeNop, wasm.OpcodeF64Const, 0, 0})
+	require.EqualError(t, err, "f64.const at offset 1: expected 8 bytes, but only 2 left")
+
+	_, err = DecodeInstructions([]byte{wasm.OpcodeCall, 0x80})
+	require.Error(t, err)
+}
@@ -0,0 +1,88 @@
+package wasmbin
+
+import "fmt"
+
+// ImportName is the module and name of an import.
+type ImportName struct {
+	Module, Name string
+}
+
+// RenameRules are the rules of Rename.
+type RenameRules struct {
+	// ImportModules renames the module of all imports from a module, e.g.
+	// from "wasi_unstable" to "wasi_snapshot_preview1".
+	ImportModules map[string]string
+
+	// Imports renames specific imports, which takes precedence over
+	// ImportModules.
+	Imports map[ImportName]ImportName
+
+	// Exports renames exports.
+	Exports map[string]string
+}
+
+// Rename rewrites the WebAssembly binary (%.wasm) to rename its imports and
+// exports according to the rules. This adapts a module to a host, or a host
+// to a module, which use different names for the same thing.
+//
+// For example, this adapts a guest compiled for "wasi_unstable" to
+// wazero's implementation of "wasi_snapshot_preview1":
+//
+//	bin, err = wasmbin.Rename(bin, wasmbin.RenameRules{
+//		ImportModules: map[string]string{"wasi_unstable": "wasi_snapshot_preview1"},
+//	})
+//
+// # Notes
+//
+//   - Only the import and export sections are rewritten. Other sections are
+//     copied as-is, so the result is the same module otherwise, including
+//     custom sections such as DWARF.
+//   - Signatures are not adapted, so renaming only works when the imported
+//     and exported functions are compatible. For example, "fd_seek" and
+//     "path_filestat_get" of "wasi_unstable" use different constants and
+//     layouts than "wasi_snapshot_preview1". To run such a guest as-is, use
+//     wasi_snapshot_preview1.NewUnstableBuilder instead.
+//   - An error is returned if renaming results in duplicate exports.
+func Rename(bin []byte, rules RenameRules) ([]byte, error) {
+	m, err := Decode(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range m.Imports {
+		imp := &m.Imports[i]
+		if to, ok := rules.Imports[ImportName{Module: imp.Module, Name: imp.Name}]; ok {
+			imp.Module, imp.Name = to.Module, to.Name
+		} else if to, ok := rules.ImportModules[imp.Module]; ok {
+			imp.Module = to
+		}
+	}
+
+	exportNames := make(map[string]struct{}, len(m.Exports))
+	for i := range m.Exports {
+		e := &m.Exports[i]
+		if to, ok := rules.Exports[e.Name]; ok {
+			e.Name = to
+		}
+		if _, ok := exportNames[e.Name]; ok {
+			return nil, fmt.Errorf("duplicate export %q after renaming", e.Name)
+		}
+		exportNames[e.Name] = struct{}{}
+	}
+
+	ret := append(make([]byte, 0, len(bin)), magicAndVersion...)
+	start := uint64(len(magicAndVersion)) // of the current section, including its header.
+	for _, s := range m.Sections {
+		end := s.Offset + uint64(s.Size)
+		switch s.ID {
+		case SectionIDImport:
+			ret = appendImportSection(ret, m.Imports)
+		case SectionIDExport:
+			ret = appendExportSection(ret, m.Exports)
+		default:
+			ret = append(ret, bin[start:end]...)
+		}
+		start = end
+	}
+	return ret, nil
+}
@@ -0,0 +1,77 @@
+package wasmbin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestRename(t *testing.T) {
+	m := &Module{
+		Types: []FunctionType{{}},
+		Imports: []Import{
+			{Module: "wasi_unstable", Name: "proc_exit", Type: api.ExternTypeFunc},
+			{Module: "wasi_unstable", Name: "sched_yield", Type: api.ExternTypeFunc},
+			{Module: "env", Name: "memory", Type: api.ExternTypeMemory, Memory: Memory{Min: 1}},
+		},
+		Functions: []Function{{Body: []byte{wasm.OpcodeEnd}}},
+		Exports: []Export{
+			{Name: "_start", Type: api.ExternTypeFunc, Index: 2},
+			{Name: "other", Type: api.ExternTypeFunc, Index: 2},
+		},
+		CustomSections: []CustomSection{{Name: "producers", Data: []byte{1, 2, 3}}},
+	}
+	bin := Encode(m)
+
+	renamed, err := Rename(bin, RenameRules{
+		ImportModules: map[string]string{"wasi_unstable": "wasi_snapshot_preview1"},
+		Imports: map[ImportName]ImportName{
+			{Module: "wasi_unstable", Name: "sched_yield"}: {Module: "env", Name: "yield"},
+		},
+		Exports: map[string]string{"_start": "run"},
+	})
+	require.NoError(t, err)
+
+	actual, err := Decode(renamed)
+	require.NoError(t, err)
+	require.Equal(t, []Import{
+		{Module: "wasi_snapshot_preview1", Name: "proc_exit", Type: api.ExternTypeFunc},
+		{Module: "env", Name: "yield", Type: api.ExternTypeFunc},
+		{Module: "env", Name: "memory", Type: api.ExternTypeMemory, Memory: Memory{Min: 1}},
+	}, actual.Imports)
+	require.Equal(t, []Export{
+		{Name: "run", Type: api.ExternTypeFunc, Index: 2},
+		{Name: "other", Type: api.ExternTypeFunc, Index: 2},
+	}, actual.Exports)
+
+	// Other sections are copied as-is.
+	require.Equal(t, m.Functions[0].Body, actual.Functions[0].Body)
+	require.Equal(t, m.CustomSections, actual.CustomSections)
+	last := actual.Sections[len(actual.Sections)-1]
+	require.True(t, bytes.HasSuffix(bin, renamed[last.Offset-1:]))
+
+	// No rules results in the same binary.
+	same, err := Rename(bin, RenameRules{})
+	require.NoError(t, err)
+	require.Equal(t, bin, same)
+}
+
+func TestRename_Errors(t *testing.T) {
+	bin := Encode(&Module{
+		Types:     []FunctionType{{}},
+		Functions: []Function{{Body: []byte{wasm.OpcodeEnd}}},
+		Exports: []Export{
+			{Name: "a", Type: api.ExternTypeFunc, Index: 0},
+			{Name: "b", Type: api.ExternTypeFunc, Index: 0},
+		},
+	})
+
+	_, err := Rename(bin, RenameRules{Exports: map[string]string{"a": "b"}})
+	require.EqualError(t, err, `duplicate export "b" after renaming`)
+
+	_, err = Rename([]byte{1, 2, 3, 4}, RenameRules{})
+	require.EqualError(t, err, "invalid magic number")
+}
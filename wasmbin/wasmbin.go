@@ -0,0 +1,449 @@
+// Package wasmbin decodes the WebAssembly binary format (%.wasm), for tools
+// which inspect modules without instantiating them.
+//
+// This is a stable subset of what wazero decodes internally: sections,
+// types, imports, exports, element and data segments and function bodies,
+// which can be decoded into instructions with DecodeInstructions. Encode does
+// the opposite, to construct modules. Unlike wazero.Runtime CompileModule,
+// Decode doesn't validate the module, so it can be used on modules which
+// wouldn't compile.
+//
+// Here's an example:
+//
+//	m, err := wasmbin.Decode(bin)
+//	if err != nil {
+//		log.Panicln(err)
+//	}
+//	for _, imp := range m.Imports {
+//		fmt.Printf("%s.%s\n", imp.Module, imp.Name)
+//	}
+package wasmbin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// SectionID identifies a section of a module.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#sections%E2%91%A0
+type SectionID = byte
+
+const (
+	SectionIDCustom    SectionID = wasm.SectionIDCustom
+	SectionIDType      SectionID = wasm.SectionIDType
+	SectionIDImport    SectionID = wasm.SectionIDImport
+	SectionIDFunction  SectionID = wasm.SectionIDFunction
+	SectionIDTable     SectionID = wasm.SectionIDTable
+	SectionIDMemory    SectionID = wasm.SectionIDMemory
+	SectionIDGlobal    SectionID = wasm.SectionIDGlobal
+	SectionIDExport    SectionID = wasm.SectionIDExport
+	SectionIDStart     SectionID = wasm.SectionIDStart
+	SectionIDElement   SectionID = wasm.SectionIDElement
+	SectionIDCode      SectionID = wasm.SectionIDCode
+	SectionIDData      SectionID = wasm.SectionIDData
+	SectionIDDataCount SectionID = wasm.SectionIDDataCount
+)
+
+// SectionIDName returns the canonical name of a section, e.g. "type".
+func SectionIDName(id SectionID) string {
+	return wasm.SectionIDName(id)
+}
+
+// Module is a decoded WebAssembly module.
+//
+// Note: Indexes of functions, tables, memories and globals include imports,
+// which are before those defined in the module.
+type Module struct {
+	// Name is the module name in the custom "name" section, or empty.
+	Name string
+
+	// Sections are in the order they appear in the binary.
+	Sections []Section
+
+	// Types are the function types, referenced by their index.
+	Types []FunctionType
+
+	// Imports are in the order of the import section.
+	Imports []Import
+
+	// Functions are the functions defined in the module, not imported.
+	Functions []Function
+
+	// Tables are the tables defined in the module, not imported.
+	Tables []Table
+
+	// Memory is the memory defined in the module, or nil if none or
+	// imported.
+	Memory *Memory
+
+	// Globals are the globals defined in the module, not imported.
+	Globals []Global
+
+	// Exports are in the order of the export section.
+	Exports []Export
+
+	// Start is the index of the start function, or nil if none.
+	Start *uint32
+
+	// Elements are the element segments, in the order of the element section.
+	Elements []ElementSegment
+
+	// Data are the data segments, in the order of the data section.
+	Data []DataSegment
+
+	// CustomSections are the custom sections besides "name", in the order
+	// they appear in the binary.
+	CustomSections []CustomSection
+}
+
+// Section is the header of a section in the binary.
+type Section struct {
+	// ID identifies the section, e.g. SectionIDType.
+	ID SectionID
+
+	// Offset is the byte offset of the section contents in the binary, after
+	// the ID and size.
+	Offset uint64
+
+	// Size is the length of the section contents in bytes.
+	Size uint32
+}
+
+// FunctionType is the signature of a function.
+type FunctionType struct {
+	Params, Results []api.ValueType
+}
+
+// String returns the signature like "i32i32_i64", or "v_v" if empty.
+func (t FunctionType) String() string {
+	return (&wasm.FunctionType{Params: t.Params, Results: t.Results}).String()
+}
+
+// Import is an import of a function, table, memory or global.
+type Import struct {
+	Module, Name string
+
+	// Type is the type of the import, which determines the field of this
+	// struct which describes it.
+	Type api.ExternType
+
+	// TypeIndex is the index in Module.Types of an api.ExternTypeFunc.
+	TypeIndex uint32
+
+	// Table describes an api.ExternTypeTable.
+	Table Table
+
+	// Memory describes an api.ExternTypeMemory.
+	Memory Memory
+
+	// Global describes an api.ExternTypeGlobal.
+	Global GlobalType
+}
+
+// Function is a function defined in the module.
+type Function struct {
+	// TypeIndex is the index in Module.Types of the signature.
+	TypeIndex uint32
+
+	// Locals are the types of local variables, not including parameters.
+	Locals []api.ValueType
+
+	// Body is the instructions of the function, ending with the "end"
+	// opcode. Use DecodeInstructions to read them.
+	Body []byte
+
+	// BodyOffset is the byte offset of Body in the code section, which is
+	// what DWARF uses as an address.
+	BodyOffset uint64
+}
+
+// RefType is the type of table elements.
+type RefType = byte
+
+const (
+	RefTypeFuncref   RefType = wasm.RefTypeFuncref
+	RefTypeExternref RefType = wasm.RefTypeExternref
+)
+
+// Table is the type of a table.
+type Table struct {
+	// Type is RefTypeFuncref or RefTypeExternref.
+	Type RefType
+
+	// Min is the minimum count of elements.
+	Min uint32
+
+	// Max is the maximum count of elements, or nil if unbounded.
+	Max *uint32
+}
+
+// Memory is the type of a memory.
+type Memory struct {
+	// Min is the minimum count of pages.
+	Min uint32
+
+	// Max is the maximum count of pages, and only valid when HasMax is true.
+	Max uint32
+
+	// HasMax is true if the maximum is in the binary.
+	HasMax bool
+
+	// Shared is true if the memory is shared, as defined by
+	// experimental.CoreFeaturesThreads.
+	Shared bool
+
+	// PageSizeLog2 is the log2 of the page size in bytes, and only valid when
+	// HasPageSize is true, as defined by experimental.CoreFeaturesCustomPageSizes.
+	PageSizeLog2 uint32
+
+	// HasPageSize is true if the page size is in the binary.
+	HasPageSize bool
+}
+
+// GlobalType is the type of a global.
+type GlobalType struct {
+	Type    api.ValueType
+	Mutable bool
+}
+
+// Global is a global defined in the module.
+type Global struct {
+	GlobalType
+
+	// Init is the constant instruction which initializes the global, e.g.
+	// "i32.const".
+	Init Instruction
+}
+
+// Export is an export of a function, table, memory or global.
+type Export struct {
+	Name string
+
+	// Type is the type of the export, which determines what Index refers to.
+	Type api.ExternType
+
+	// Index is the index of the exported item, including imports of the same
+	// Type.
+	Index uint32
+}
+
+// ElementMode is the mode of an element segment.
+type ElementMode = byte
+
+const (
+	// ElementModeActive segments initialize a table at instantiation.
+	ElementModeActive ElementMode = wasm.ElementModeActive
+	// ElementModePassive segments are only copied by "table.init".
+	ElementModePassive ElementMode = wasm.ElementModePassive
+	// ElementModeDeclarative segments declare functions referenced by
+	// "ref.func", and are otherwise unused.
+	ElementModeDeclarative ElementMode = wasm.ElementModeDeclarative
+)
+
+// ElementSegment is a segment of the element section.
+type ElementSegment struct {
+	// Type is RefTypeFuncref or RefTypeExternref.
+	Type RefType
+
+	// Mode is the mode of the segment, e.g. ElementModeActive.
+	Mode ElementMode
+
+	// TableIndex is the index of the table initialized by an
+	// ElementModeActive segment.
+	TableIndex uint32
+
+	// Offset is the constant instruction of the offset in the table of an
+	// ElementModeActive segment, e.g. "i32.const".
+	Offset Instruction
+
+	// Init are the constant instructions of the elements: "ref.func" with
+	// the function index, "ref.null" or "global.get".
+	Init []Instruction
+}
+
+// DataSegment is a segment of the data section.
+type DataSegment struct {
+	// Offset is the constant instruction of the offset in memory, e.g.
+	// "i32.const", unless Passive.
+	Offset Instruction
+
+	// Init are the bytes to initialize memory with.
+	Init []byte
+
+	// Passive is true if the segment is only copied by "memory.init".
+	Passive bool
+}
+
+// CustomSection is a custom section, except "name".
+type CustomSection struct {
+	Name string
+	Data []byte
+}
+
+// Decode decodes the WebAssembly binary (%.wasm), or returns an error if it
+// is malformed. All features defined by api.CoreFeaturesV2 are supported.
+//
+// Note: The module is not validated.
+func Decode(bin []byte) (*Module, error) {
+	return DecodeWithFeatures(bin, api.CoreFeaturesV2)
+}
+
+// DecodeWithFeatures is like Decode, except it supports the given features,
+// e.g. the result of wazero.RuntimeConfig CoreFeatures.
+func DecodeWithFeatures(bin []byte, features api.CoreFeatures) (*Module, error) {
+	m, err := binary.DecodeModule(bin, features, wasm.MemoryLimitPages, false, false, true)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Module{Start: m.StartSection}
+	if ret.Sections, err = decodeSections(bin); err != nil {
+		return nil, err
+	}
+	if m.NameSection != nil {
+		ret.Name = m.NameSection.ModuleName
+	}
+
+	for i := range m.TypeSection {
+		t := &m.TypeSection[i]
+		ret.Types = append(ret.Types, FunctionType{Params: t.Params, Results: t.Results})
+	}
+
+	for i := range m.ImportSection {
+		imp := &m.ImportSection[i]
+		ri := Import{Module: imp.Module, Name: imp.Name, Type: imp.Type}
+		switch imp.Type {
+		case api.ExternTypeFunc:
+			ri.TypeIndex = imp.DescFunc
+		case api.ExternTypeTable:
+			ri.Table = newTable(&imp.DescTable)
+		case api.ExternTypeMemory:
+			ri.Memory = newMemory(imp.DescMem)
+		case api.ExternTypeGlobal:
+			ri.Global = GlobalType{Type: imp.DescGlobal.ValType, Mutable: imp.DescGlobal.Mutable}
+		}
+		ret.Imports = append(ret.Imports, ri)
+	}
+
+	for i, typeIndex := range m.FunctionSection {
+		c := &m.CodeSection[i]
+		ret.Functions = append(ret.Functions, Function{
+			TypeIndex:  typeIndex,
+			Locals:     c.LocalTypes,
+			Body:       c.Body,
+			BodyOffset: c.BodyOffsetInCodeSection,
+		})
+	}
+
+	for i := range m.TableSection {
+		ret.Tables = append(ret.Tables, newTable(&m.TableSection[i]))
+	}
+
+	if m.MemorySection != nil {
+		mem := newMemory(m.MemorySection)
+		ret.Memory = &mem
+	}
+
+	for i := range m.GlobalSection {
+		g := &m.GlobalSection[i]
+		ret.Globals = append(ret.Globals, Global{
+			GlobalType: GlobalType{Type: g.Type.ValType, Mutable: g.Type.Mutable},
+			Init:       newConstInstruction(&g.Init),
+		})
+	}
+
+	for i := range m.ExportSection {
+		e := &m.ExportSection[i]
+		ret.Exports = append(ret.Exports, Export{Name: e.Name, Type: e.Type, Index: e.Index})
+	}
+
+	for i := range m.ElementSection {
+		e := &m.ElementSection[i]
+		re := ElementSegment{Type: e.Type, Mode: e.Mode, TableIndex: e.TableIndex}
+		if e.Mode == wasm.ElementModeActive {
+			re.Offset = newConstInstruction(&e.OffsetExpr)
+		}
+		for _, init := range e.Init {
+			re.Init = append(re.Init, newElementInstruction(e.Type, init))
+		}
+		ret.Elements = append(ret.Elements, re)
+	}
+
+	for i := range m.DataSection {
+		d := &m.DataSection[i]
+		ret.Data = append(ret.Data, DataSegment{
+			Offset:  newConstInstruction(&d.OffsetExpression),
+			Init:    d.Init,
+			Passive: d.Passive,
+		})
+	}
+
+	for _, c := range m.CustomSections {
+		ret.CustomSections = append(ret.CustomSections, CustomSection{Name: c.Name, Data: c.Data})
+	}
+	return ret, nil
+}
+
+// newConstInstruction converts a constant expression, which is decoded
+// without the prefix of "v128.const".
+func newConstInstruction(e *wasm.ConstantExpression) Instruction {
+	if e.IsExtended() {
+		return Instruction{Opcode: e.Data[0], Immediates: e.Data[1:]}
+	}
+	if e.Opcode == wasm.OpcodeVecV128Const && len(e.Data) == 16 {
+		return Instruction{Opcode: OpcodeVecPrefix, Subopcode: uint32(e.Opcode), Immediates: e.Data}
+	}
+	return Instruction{Opcode: e.Opcode, Immediates: e.Data}
+}
+
+// newElementInstruction converts an item of wasm.ElementSegment Init, which
+// is decoded into a function index, or a flagged global index or null.
+func newElementInstruction(t RefType, init wasm.Index) Instruction {
+	switch {
+	case init == wasm.ElementInitNullReference:
+		return Instruction{Opcode: wasm.OpcodeRefNull, Immediates: []byte{t}}
+	case init&wasm.ElementInitImportedGlobalFunctionReference != 0:
+		return Instruction{Opcode: wasm.OpcodeGlobalGet, Immediates: EncodeUint32(init &^ wasm.ElementInitImportedGlobalFunctionReference)}
+	}
+	return Instruction{Opcode: wasm.OpcodeRefFunc, Immediates: EncodeUint32(init)}
+}
+
+func newTable(t *wasm.Table) Table {
+	return Table{Type: t.Type, Min: t.Min, Max: t.Max}
+}
+
+func newMemory(m *wasm.Memory) Memory {
+	ret := Memory{Min: m.Min, HasMax: m.IsMaxEncoded, Shared: m.IsShared}
+	if m.IsMaxEncoded {
+		ret.Max = m.Max
+	}
+	if m.IsPageSizeEncoded {
+		ret.PageSizeLog2, ret.HasPageSize = m.PageSizeLog2, true
+	}
+	return ret
+}
+
+// decodeSections returns the section headers of a binary which was already
+// decoded, so it is well-formed.
+func decodeSections(bin []byte) (ret []Section, err error) {
+	r := bytes.NewReader(bin[8:]) // skip the magic number and version.
+	for r.Len() > 0 {
+		id, _ := r.ReadByte()
+		size, _, err := leb128.DecodeUint32(r)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %w", wasm.SectionIDName(id), err)
+		}
+		offset := uint64(len(bin) - r.Len())
+		ret = append(ret, Section{ID: id, Offset: offset, Size: size})
+		if _, err = r.Seek(int64(size), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
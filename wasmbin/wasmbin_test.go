@@ -0,0 +1,120 @@
+package wasmbin
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestDecode(t *testing.T) {
+	zero, max := uint32(0), uint32(10)
+	bin := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection: []wasm.FunctionType{{}, {Params: []api.ValueType{i32}, Results: []api.ValueType{i64}}},
+		ImportSection: []wasm.Import{
+			{Module: "env", Name: "f", Type: api.ExternTypeFunc, DescFunc: 1},
+			{Module: "env", Name: "g", Type: api.ExternTypeGlobal, DescGlobal: wasm.GlobalType{ValType: i32, Mutable: true}},
+		},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{LocalTypes: []api.ValueType{i64}, Body: []byte{wasm.OpcodeNop, wasm.OpcodeEnd}}},
+		TableSection:    []wasm.Table{{Min: 1, Max: &max, Type: wasm.RefTypeFuncref}},
+		MemorySection:   &wasm.Memory{Min: 1, Max: 2, IsMaxEncoded: true},
+		GlobalSection: []wasm.Global{
+			{Type: wasm.GlobalType{ValType: i32}, Init: wasm.ConstantExpression{Opcode: wasm.OpcodeI32Const, Data: []byte{42}}},
+		},
+		ExportSection: []wasm.Export{{Name: "run", Type: api.ExternTypeFunc, Index: 1}},
+		StartSection:  &zero,
+		NameSection:   &wasm.NameSection{ModuleName: "test"},
+	})
+	// Append a custom section, as EncodeModule only encodes the name section.
+	bin = append(bin, wasm.SectionIDCustom, 11, 9, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', 's', 1)
+
+	m, err := Decode(bin)
+	require.NoError(t, err)
+
+	require.Equal(t, "test", m.Name)
+	require.Equal(t, []FunctionType{{}, {Params: []api.ValueType{i32}, Results: []api.ValueType{i64}}}, m.Types)
+	require.Equal(t, "i32_i64", m.Types[1].String())
+	require.Equal(t, []Import{
+		{Module: "env", Name: "f", Type: api.ExternTypeFunc, TypeIndex: 1},
+		{Module: "env", Name: "g", Type: api.ExternTypeGlobal, Global: GlobalType{Type: i32, Mutable: true}},
+	}, m.Imports)
+	require.Equal(t, 1, len(m.Functions))
+	require.Equal(t, uint32(0), m.Functions[0].TypeIndex)
+	require.Equal(t, []api.ValueType{i64}, m.Functions[0].Locals)
+	require.Equal(t, []byte{wasm.OpcodeNop, wasm.OpcodeEnd}, m.Functions[0].Body)
+	require.Equal(t, []Table{{Type: RefTypeFuncref, Min: 1, Max: &max}}, m.Tables)
+	require.Equal(t, &Memory{Min: 1, Max: 2, HasMax: true}, m.Memory)
+	require.Equal(t, []Global{{
+		GlobalType: GlobalType{Type: i32},
+		Init:       Instruction{Opcode: wasm.OpcodeI32Const, Immediates: []byte{42}},
+	}}, m.Globals)
+	require.Equal(t, []Export{{Name: "run", Type: api.ExternTypeFunc, Index: 1}}, m.Exports)
+	require.Equal(t, &zero, m.Start)
+	require.Equal(t, []CustomSection{{Name: "producers", Data: []byte{1}}}, m.CustomSections)
+
+	// The custom section is the last section, and the name section before it.
+	var ids []SectionID
+	for _, s := range m.Sections {
+		ids = append(ids, s.ID)
+		require.True(t, s.Offset+uint64(s.Size) <= uint64(len(bin)))
+	}
+	require.Equal(t, []SectionID{
+		SectionIDType, SectionIDImport, SectionIDFunction, SectionIDTable, SectionIDMemory, SectionIDGlobal,
+		SectionIDExport, SectionIDStart, SectionIDCode, SectionIDCustom, SectionIDCustom,
+	}, ids)
+	last := m.Sections[len(m.Sections)-1]
+	require.Equal(t, uint64(len(bin)), last.Offset+uint64(last.Size))
+
+	// BodyOffset is the offset of the body in the code section.
+	code := m.Sections[8]
+	require.Equal(t, m.Functions[0].Body, bin[code.Offset+m.Functions[0].BodyOffset:][:2])
+}
+
+func TestDecodeWithFeatures(t *testing.T) {
+	// The global is 1 + 2, and the function notifies waiters of address 0.
+	bin := Encode(&Module{
+		Types:  []FunctionType{{}},
+		Memory: &Memory{Min: 1, Max: 2, HasMax: true, Shared: true, PageSizeLog2: 16, HasPageSize: true},
+		Globals: []Global{{
+			GlobalType: GlobalType{Type: i32},
+			Init:       Instruction{Opcode: wasm.OpcodeI32Const, Immediates: []byte{1, wasm.OpcodeI32Const, 2, wasm.OpcodeI32Add}},
+		}},
+		Functions: []Function{{Body: EncodeInstructions([]Instruction{
+			{Opcode: wasm.OpcodeI32Const, Immediates: []byte{0}},
+			{Opcode: wasm.OpcodeI32Const, Immediates: []byte{1}},
+			{Opcode: OpcodeAtomicPrefix, Subopcode: uint32(wasm.OpcodeAtomicMemoryNotify), Immediates: []byte{2, 0}},
+			{Opcode: wasm.OpcodeDrop},
+			{Opcode: OpcodeAtomicPrefix, Subopcode: uint32(wasm.OpcodeAtomicFence), Immediates: []byte{0}},
+			{Opcode: wasm.OpcodeEnd},
+		})}},
+	})
+
+	_, err := Decode(bin)
+	require.EqualError(t, err, "section memory: shared memory invalid as feature \"threads\" is disabled")
+
+	m, err := DecodeWithFeatures(bin, api.CoreFeaturesV2|experimental.CoreFeaturesThreads|
+		experimental.CoreFeaturesCustomPageSizes|experimental.CoreFeaturesExtendedConst)
+	require.NoError(t, err)
+	require.Equal(t, &Memory{Min: 1, Max: 2, HasMax: true, Shared: true, PageSizeLog2: 16, HasPageSize: true}, m.Memory)
+
+	instructions, err := DecodeInstructions(m.Functions[0].Body)
+	require.NoError(t, err)
+	require.Equal(t, "memory.atomic.notify", instructions[2].Name())
+	require.Equal(t, "atomic.fence", instructions[4].Name())
+
+	// Everything round-trips.
+	require.Equal(t, bin, Encode(m))
+}
+
+func TestDecode_Errors(t *testing.T) {
+	_, err := Decode([]byte{1, 2, 3, 4})
+	require.EqualError(t, err, "invalid magic number")
+
+	bin := binaryencoding.EncodeModule(&wasm.Module{TypeSection: []wasm.FunctionType{{}}, FunctionSection: []wasm.Index{0}})
+	_, err = Decode(bin)
+	require.EqualError(t, err, "function and code section have inconsistent lengths: 1 != 0")
+}